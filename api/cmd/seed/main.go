@@ -0,0 +1,539 @@
+// Command seed fills a local database with deterministic channel data so the
+// full stack can run without Finnhub, ESPN/api-sports, or Yahoo credentials.
+//
+// It writes, from the JSON fixtures embedded alongside this file:
+//
+//   - finance: tracked_symbols + trades
+//   - sports:  tracked_leagues + games
+//   - rss:     tracked_feeds + rss_items
+//   - fantasy: a fake yahoo_users row linked to the dev account, one league
+//     with standings and the current week's matchups
+//   - core:    user_channels rows enabling all four channels for the dev account
+//
+// Every write is an upsert keyed on the table's natural key, so re-running is
+// safe and converges on the same rows. Timestamps are offsets from an anchor
+// (the current hour by default, or -anchor) so games are live/upcoming and
+// headlines recent whenever you seed.
+//
+// Schema is not created here. Start the core API once (it applies core
+// migrations) and each channel service once (they apply theirs) before
+// seeding; missing tables are reported up front.
+//
+// Usage (from api/):
+//
+//	DATABASE_URL=postgres://... go run ./cmd/seed -user <logto sub>
+//
+// The fake Yahoo user's refresh token is deliberately not decryptable, so
+// the fantasy sync loop skips it instead of calling Yahoo.
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed fixtures/*.json
+var fixtureFS embed.FS
+
+// ─── Fixtures ────────────────────────────────────────────────────
+
+type financeFixture struct {
+	Symbols []struct {
+		Symbol        string  `json:"symbol"`
+		Name          string  `json:"name"`
+		Category      string  `json:"category"`
+		Price         float64 `json:"price"`
+		PreviousClose float64 `json:"previous_close"`
+	} `json:"symbols"`
+}
+
+type sportsFixture struct {
+	Leagues []struct {
+		Name     string `json:"name"`
+		Sport    string `json:"sport"`
+		Category string `json:"category"`
+		Country  string `json:"country"`
+		Season   string `json:"season"`
+	} `json:"leagues"`
+	Games []struct {
+		League             string `json:"league"`
+		ExternalGameID     string `json:"external_game_id"`
+		Home               string `json:"home"`
+		HomeCode           string `json:"home_code"`
+		Away               string `json:"away"`
+		AwayCode           string `json:"away_code"`
+		StartOffsetMinutes int    `json:"start_offset_minutes"`
+		State              string `json:"state"`
+		HomeScore          *int   `json:"home_score"`
+		AwayScore          *int   `json:"away_score"`
+		ShortDetail        string `json:"short_detail"`
+		Timer              string `json:"timer"`
+		Venue              string `json:"venue"`
+	} `json:"games"`
+}
+
+type rssFixture struct {
+	Feeds []struct {
+		URL      string `json:"url"`
+		Name     string `json:"name"`
+		Category string `json:"category"`
+	} `json:"feeds"`
+	Items []struct {
+		FeedURL                string `json:"feed_url"`
+		GUID                   string `json:"guid"`
+		Title                  string `json:"title"`
+		PublishedOffsetMinutes int    `json:"published_offset_minutes"`
+	} `json:"items"`
+}
+
+type fantasyFixture struct {
+	GUID   string `json:"guid"`
+	League struct {
+		LeagueKey   string `json:"league_key"`
+		Name        string `json:"name"`
+		GameCode    string `json:"game_code"`
+		Season      string `json:"season"`
+		CurrentWeek int    `json:"current_week"`
+		NumTeams    int    `json:"num_teams"`
+		ScoringType string `json:"scoring_type"`
+		TeamKey     string `json:"team_key"`
+	} `json:"league"`
+	Teams []struct {
+		TeamKey       string `json:"team_key"`
+		Name          string `json:"name"`
+		Manager       string `json:"manager"`
+		Wins          int    `json:"wins"`
+		Losses        int    `json:"losses"`
+		PointsFor     string `json:"points_for"`
+		PointsAgainst string `json:"points_against"`
+	} `json:"teams"`
+	Matchups []struct {
+		Home          string  `json:"home"`
+		Away          string  `json:"away"`
+		HomePoints    float64 `json:"home_points"`
+		AwayPoints    float64 `json:"away_points"`
+		HomeProjected float64 `json:"home_projected"`
+		AwayProjected float64 `json:"away_projected"`
+	} `json:"matchups"`
+}
+
+type fixtures struct {
+	finance financeFixture
+	sports  sportsFixture
+	rss     rssFixture
+	fantasy fantasyFixture
+}
+
+func loadFixtures() (*fixtures, error) {
+	var f fixtures
+	for name, target := range map[string]any{
+		"finance": &f.finance,
+		"sports":  &f.sports,
+		"rss":     &f.rss,
+		"fantasy": &f.fantasy,
+	} {
+		data, err := fixtureFS.ReadFile("fixtures/" + name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return nil, fmt.Errorf("fixtures/%s.json: %w", name, err)
+		}
+	}
+	return &f, nil
+}
+
+// ─── Main ────────────────────────────────────────────────────────
+
+// requiredTables maps each table the seed writes to the component whose
+// migrations create it.
+var requiredTables = map[string]string{
+	"user_channels":      "core API",
+	"trades":             "finance service",
+	"tracked_symbols":    "finance service",
+	"games":              "sports service",
+	"tracked_leagues":    "sports service",
+	"tracked_feeds":      "rss service",
+	"rss_items":          "rss service",
+	"yahoo_users":        "fantasy API",
+	"yahoo_leagues":      "fantasy API",
+	"yahoo_standings":    "fantasy API",
+	"yahoo_matchups":     "fantasy API",
+	"yahoo_user_leagues": "fantasy API",
+}
+
+func main() {
+	userSub := flag.String("user", envOr("SEED_USER_SUB", "dev-user"), "Logto sub of the dev account to enable channels for")
+	databaseURL := flag.String("database-url", os.Getenv("DATABASE_URL"), "Postgres connection string")
+	anchorFlag := flag.String("anchor", "", "RFC 3339 time that fixture offsets are relative to (default: current hour)")
+	allowRemote := flag.Bool("allow-remote", false, "allow seeding a database that isn't on localhost or a compose service")
+	flag.Parse()
+
+	if *databaseURL == "" {
+		log.Fatal("DATABASE_URL (or -database-url) must be set")
+	}
+	if !*allowRemote && !isLocalDatabase(*databaseURL) {
+		log.Fatal("Refusing to seed a non-local database; pass -allow-remote if you really mean it")
+	}
+
+	anchor := time.Now().UTC().Truncate(time.Hour)
+	if *anchorFlag != "" {
+		t, err := time.Parse(time.RFC3339, *anchorFlag)
+		if err != nil {
+			log.Fatalf("Invalid -anchor: %v", err)
+		}
+		anchor = t.UTC()
+	}
+
+	fx, err := loadFixtures()
+	if err != nil {
+		log.Fatalf("Loading fixtures: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, *databaseURL)
+	if err != nil {
+		log.Fatalf("Connecting to database: %v", err)
+	}
+	defer conn.Close(context.Background())
+
+	if err := checkTables(ctx, conn); err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Fatalf("Begin: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	steps := []struct {
+		name string
+		fn   func(context.Context, pgx.Tx) (int, error)
+	}{
+		{"finance", func(ctx context.Context, tx pgx.Tx) (int, error) { return seedFinance(ctx, tx, fx.finance) }},
+		{"sports", func(ctx context.Context, tx pgx.Tx) (int, error) { return seedSports(ctx, tx, fx.sports, anchor) }},
+		{"rss", func(ctx context.Context, tx pgx.Tx) (int, error) { return seedRSS(ctx, tx, fx.rss, anchor) }},
+		{"fantasy", func(ctx context.Context, tx pgx.Tx) (int, error) {
+			return seedFantasy(ctx, tx, fx.fantasy, *userSub)
+		}},
+		{"user_channels", func(ctx context.Context, tx pgx.Tx) (int, error) {
+			return seedUserChannels(ctx, tx, fx, *userSub)
+		}},
+	}
+	for _, step := range steps {
+		n, err := step.fn(ctx, tx)
+		if err != nil {
+			log.Fatalf("Seeding %s: %v", step.name, err)
+		}
+		log.Printf("[Seed] %s: %d rows", step.name, n)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Fatalf("Commit: %v", err)
+	}
+	log.Printf("[Seed] Done for user %s (anchor %s)", *userSub, anchor.Format(time.RFC3339))
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// isLocalDatabase reports whether the URL points at this machine or a
+// single-label host (a docker-compose service name).
+func isLocalDatabase(databaseURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(databaseURL))
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host == "" || host == "localhost" || host == "127.0.0.1" || host == "::1" || !strings.Contains(host, ".")
+}
+
+func checkTables(ctx context.Context, conn *pgx.Conn) error {
+	var missing []string
+	for table, owner := range requiredTables {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			return fmt.Errorf("checking table %s: %w", table, err)
+		}
+		if !exists {
+			missing = append(missing, fmt.Sprintf("%s (start the %s once)", table, owner))
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("missing tables, run migrations first:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+// ─── Channels ────────────────────────────────────────────────────
+
+func seedFinance(ctx context.Context, tx pgx.Tx, fx financeFixture) (int, error) {
+	for _, s := range fx.Symbols {
+		change := round2(s.Price - s.PreviousClose)
+		pct := 0.0
+		if s.PreviousClose != 0 {
+			pct = round2(change / s.PreviousClose * 100)
+		}
+		direction := "up"
+		if change < 0 {
+			direction = "down"
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tracked_symbols (symbol, name, category, is_enabled)
+			VALUES ($1, $2, $3, true)
+			ON CONFLICT (symbol) DO UPDATE
+			SET name = EXCLUDED.name, category = EXCLUDED.category, is_enabled = true`,
+			s.Symbol, s.Name, s.Category); err != nil {
+			return 0, fmt.Errorf("tracked_symbols %s: %w", s.Symbol, err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO trades (symbol, price, previous_close, price_change, percentage_change, direction, last_updated)
+			VALUES ($1, $2, $3, $4, $5, $6, now())
+			ON CONFLICT (symbol) DO UPDATE
+			SET price = EXCLUDED.price, previous_close = EXCLUDED.previous_close,
+			    price_change = EXCLUDED.price_change, percentage_change = EXCLUDED.percentage_change,
+			    direction = EXCLUDED.direction, last_updated = now()`,
+			s.Symbol, s.Price, s.PreviousClose, change, pct, direction); err != nil {
+			return 0, fmt.Errorf("trades %s: %w", s.Symbol, err)
+		}
+	}
+	return len(fx.Symbols), nil
+}
+
+func seedSports(ctx context.Context, tx pgx.Tx, fx sportsFixture, anchor time.Time) (int, error) {
+	sportByLeague := make(map[string]string, len(fx.Leagues))
+	for _, l := range fx.Leagues {
+		sportByLeague[l.Name] = l.Sport
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tracked_leagues (name, sport_api, category, country, season, is_enabled)
+			VALUES ($1, $2, $3, $4, $5, true)
+			ON CONFLICT (name) DO UPDATE
+			SET category = EXCLUDED.category, country = EXCLUDED.country,
+			    season = EXCLUDED.season, is_enabled = true`,
+			l.Name, l.Sport, l.Category, l.Country, l.Season); err != nil {
+			return 0, fmt.Errorf("tracked_leagues %s: %w", l.Name, err)
+		}
+	}
+	for _, g := range fx.Games {
+		start := anchor.Add(time.Duration(g.StartOffsetMinutes) * time.Minute)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO games (league, sport, external_game_id, home_team_name, home_team_code, home_team_score,
+			                   away_team_name, away_team_code, away_team_score, start_time, short_detail,
+			                   state, timer, venue, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, now())
+			ON CONFLICT (league, external_game_id) DO UPDATE
+			SET home_team_name = EXCLUDED.home_team_name, home_team_code = EXCLUDED.home_team_code,
+			    home_team_score = EXCLUDED.home_team_score, away_team_name = EXCLUDED.away_team_name,
+			    away_team_code = EXCLUDED.away_team_code, away_team_score = EXCLUDED.away_team_score,
+			    start_time = EXCLUDED.start_time, short_detail = EXCLUDED.short_detail,
+			    state = EXCLUDED.state, timer = EXCLUDED.timer, venue = EXCLUDED.venue, updated_at = now()`,
+			g.League, sportByLeague[g.League], g.ExternalGameID, g.Home, g.HomeCode, g.HomeScore,
+			g.Away, g.AwayCode, g.AwayScore, start, g.ShortDetail,
+			g.State, g.Timer, g.Venue); err != nil {
+			return 0, fmt.Errorf("games %s: %w", g.ExternalGameID, err)
+		}
+	}
+	return len(fx.Leagues) + len(fx.Games), nil
+}
+
+func seedRSS(ctx context.Context, tx pgx.Tx, fx rssFixture, anchor time.Time) (int, error) {
+	sourceByFeed := make(map[string]string, len(fx.Feeds))
+	for _, f := range fx.Feeds {
+		sourceByFeed[f.URL] = f.Name
+		// consecutive_failures is reset so the catalog's health filter
+		// never hides seed feeds the rss service failed to fetch.
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tracked_feeds (url, name, category, is_default, is_enabled, last_success_at)
+			VALUES ($1, $2, $3, true, true, now())
+			ON CONFLICT (url) DO UPDATE
+			SET name = EXCLUDED.name, category = EXCLUDED.category, is_default = true,
+			    is_enabled = true, consecutive_failures = 0, last_error = NULL, last_success_at = now()`,
+			f.URL, f.Name, f.Category); err != nil {
+			return 0, fmt.Errorf("tracked_feeds %s: %w", f.URL, err)
+		}
+	}
+	for _, it := range fx.Items {
+		published := anchor.Add(time.Duration(it.PublishedOffsetMinutes) * time.Minute)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO rss_items (feed_url, guid, title, link, description, source_name, published_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (feed_url, guid) DO UPDATE
+			SET title = EXCLUDED.title, link = EXCLUDED.link, description = EXCLUDED.description,
+			    source_name = EXCLUDED.source_name, published_at = EXCLUDED.published_at, updated_at = now()`,
+			it.FeedURL, it.GUID, it.Title, strings.TrimSuffix(it.FeedURL, ".xml")+"/"+it.GUID,
+			"Seed data for local development.", sourceByFeed[it.FeedURL], published); err != nil {
+			return 0, fmt.Errorf("rss_items %s: %w", it.GUID, err)
+		}
+	}
+	return len(fx.Feeds) + len(fx.Items), nil
+}
+
+func seedFantasy(ctx context.Context, tx pgx.Tx, fx fantasyFixture, userSub string) (int, error) {
+	l := fx.League
+
+	// Unlink any other Yahoo account from the dev user first;
+	// yahoo_users.logto_sub is unique.
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM yahoo_users WHERE logto_sub = $1 AND guid <> $2`, userSub, fx.GUID); err != nil {
+		return 0, fmt.Errorf("yahoo_users cleanup: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO yahoo_users (guid, logto_sub, refresh_token, last_sync)
+		VALUES ($1, $2, 'seed-not-a-token', now())
+		ON CONFLICT (guid) DO UPDATE SET logto_sub = EXCLUDED.logto_sub, last_sync = now()`,
+		fx.GUID, userSub); err != nil {
+		return 0, fmt.Errorf("yahoo_users: %w", err)
+	}
+
+	leagueData := map[string]any{
+		"league_key":   l.LeagueKey,
+		"name":         l.Name,
+		"draft_status": "postdraft",
+		"num_teams":    l.NumTeams,
+		"scoring_type": l.ScoringType,
+		"current_week": l.CurrentWeek,
+		"start_week":   1,
+		"end_week":     17,
+		"is_finished":  false,
+		"game_code":    l.GameCode,
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO yahoo_leagues (league_key, name, game_code, season, data, updated_at)
+		VALUES ($1, $2, $3, $4, $5::jsonb, now())
+		ON CONFLICT (league_key) DO UPDATE
+		SET name = EXCLUDED.name, data = EXCLUDED.data, updated_at = now()`,
+		l.LeagueKey, l.Name, l.GameCode, l.Season, jsonParam(leagueData)); err != nil {
+		return 0, fmt.Errorf("yahoo_leagues: %w", err)
+	}
+
+	teamNames := make(map[string]string, len(fx.Teams))
+	standings := make([]map[string]any, 0, len(fx.Teams))
+	for i, t := range fx.Teams {
+		teamNames[t.TeamKey] = t.Name
+		played := t.Wins + t.Losses
+		pct := 0.0
+		if played > 0 {
+			pct = float64(t.Wins) / float64(played)
+		}
+		standings = append(standings, map[string]any{
+			"team_key":       t.TeamKey,
+			"name":           t.Name,
+			"manager_name":   t.Manager,
+			"rank":           i + 1,
+			"wins":           t.Wins,
+			"losses":         t.Losses,
+			"ties":           0,
+			"percentage":     fmt.Sprintf("%.3f", pct),
+			"games_back":     "0.0",
+			"points_for":     t.PointsFor,
+			"points_against": t.PointsAgainst,
+		})
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO yahoo_standings (league_key, data, updated_at)
+		VALUES ($1, $2::jsonb, now())
+		ON CONFLICT (league_key) DO UPDATE SET data = EXCLUDED.data, updated_at = now()`,
+		l.LeagueKey, jsonParam(standings)); err != nil {
+		return 0, fmt.Errorf("yahoo_standings: %w", err)
+	}
+
+	matchups := make([]map[string]any, 0, len(fx.Matchups))
+	for _, m := range fx.Matchups {
+		matchups = append(matchups, map[string]any{
+			"week":   l.CurrentWeek,
+			"status": "midevent",
+			"teams": []map[string]any{
+				{"team_key": m.Home, "name": teamNames[m.Home], "points": m.HomePoints, "projected_points": m.HomeProjected},
+				{"team_key": m.Away, "name": teamNames[m.Away], "points": m.AwayPoints, "projected_points": m.AwayProjected},
+			},
+		})
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO yahoo_matchups (league_key, week, data, updated_at)
+		VALUES ($1, $2, $3::jsonb, now())
+		ON CONFLICT (league_key, week) DO UPDATE SET data = EXCLUDED.data, updated_at = now()`,
+		l.LeagueKey, l.CurrentWeek, jsonParam(matchups)); err != nil {
+		return 0, fmt.Errorf("yahoo_matchups: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO yahoo_user_leagues (guid, league_key, team_key, team_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guid, league_key) DO UPDATE
+		SET team_key = EXCLUDED.team_key, team_name = EXCLUDED.team_name`,
+		fx.GUID, l.LeagueKey, l.TeamKey, teamNames[l.TeamKey]); err != nil {
+		return 0, fmt.Errorf("yahoo_user_leagues: %w", err)
+	}
+	return 5, nil
+}
+
+// jsonParam marshals doc for a ::jsonb parameter.
+func jsonParam(doc any) string {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		log.Fatalf("Marshalling fixture document: %v", err)
+	}
+	return string(data)
+}
+
+// ─── Core ────────────────────────────────────────────────────────
+
+func seedUserChannels(ctx context.Context, tx pgx.Tx, fx *fixtures, userSub string) (int, error) {
+	symbols := make([]string, 0, len(fx.finance.Symbols))
+	for _, s := range fx.finance.Symbols {
+		symbols = append(symbols, s.Symbol)
+	}
+	leagues := make([]string, 0, len(fx.sports.Leagues))
+	for _, l := range fx.sports.Leagues {
+		leagues = append(leagues, l.Name)
+	}
+	feeds := make([]map[string]string, 0, len(fx.rss.Feeds))
+	for _, f := range fx.rss.Feeds {
+		feeds = append(feeds, map[string]string{"name": f.Name, "url": f.URL})
+	}
+
+	configs := []struct {
+		channelType string
+		config      map[string]any
+	}{
+		{"finance", map[string]any{"symbols": symbols}},
+		{"sports", map[string]any{"leagues": leagues}},
+		{"rss", map[string]any{"feeds": feeds}},
+		{"fantasy", map[string]any{}},
+	}
+	for _, c := range configs {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO user_channels (logto_sub, channel_type, enabled, visible, config)
+			VALUES ($1, $2, true, true, $3::jsonb)
+			ON CONFLICT (logto_sub, channel_type) DO UPDATE
+			SET enabled = true, visible = true, config = EXCLUDED.config, updated_at = now()`,
+			userSub, c.channelType, jsonParam(c.config)); err != nil {
+			return 0, fmt.Errorf("user_channels %s: %w", c.channelType, err)
+		}
+	}
+	return len(configs), nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestFixturesAreConsistent(t *testing.T) {
+	fx, err := loadFixtures()
+	if err != nil {
+		t.Fatalf("loadFixtures: %v", err)
+	}
+
+	leagues := map[string]bool{}
+	for _, l := range fx.sports.Leagues {
+		leagues[l.Name] = true
+	}
+	for _, g := range fx.sports.Games {
+		if !leagues[g.League] {
+			t.Errorf("game %s references unknown league %q", g.ExternalGameID, g.League)
+		}
+	}
+
+	feeds := map[string]bool{}
+	for _, f := range fx.rss.Feeds {
+		feeds[f.URL] = true
+	}
+	for _, it := range fx.rss.Items {
+		if !feeds[it.FeedURL] {
+			t.Errorf("rss item %s references unknown feed %q", it.GUID, it.FeedURL)
+		}
+	}
+
+	teams := map[string]bool{}
+	for _, tm := range fx.fantasy.Teams {
+		teams[tm.TeamKey] = true
+	}
+	if !teams[fx.fantasy.League.TeamKey] {
+		t.Errorf("dev team %q is not in the league", fx.fantasy.League.TeamKey)
+	}
+	for _, m := range fx.fantasy.Matchups {
+		if !teams[m.Home] || !teams[m.Away] {
+			t.Errorf("matchup %s vs %s references an unknown team", m.Home, m.Away)
+		}
+	}
+}
+
+func TestIsLocalDatabase(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"postgres://scrollr:pw@localhost:5432/scrollr", true},
+		{"postgres://scrollr:pw@127.0.0.1/scrollr?sslmode=disable", true},
+		{"postgres://scrollr:pw@postgres:5432/scrollr", true},
+		{"postgres://scrollr:pw@db.example.com:25060/scrollr", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalDatabase(tt.url); got != tt.want {
+			t.Errorf("isLocalDatabase(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
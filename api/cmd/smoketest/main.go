@@ -0,0 +1,438 @@
+// Command smoketest exercises the gateway's critical paths against a running
+// stack and exits non-zero if any of them fail. It is the post-deploy gate:
+// scripts/smoke/production-readiness.sh proves every pod answers its probe,
+// this proves a real user can do real things.
+//
+// Checks, in order:
+//
+//   - health:    GET /health reports "healthy"
+//   - auth:      the test token is accepted (GET /users/me/preferences)
+//   - channels:  create, list and update a finance channel
+//   - dashboard: GET /dashboard assembles and includes the new channel
+//   - sse:       a synthetic CDC event posted to /webhooks/sequin arrives on
+//     the user's /events stream
+//   - channels-cleanup: delete the channel and confirm it is gone
+//   - checkout:  POST /checkout/session returns a test-mode session
+//
+// Configuration is via environment (flags override):
+//
+//	SMOKE_API_URL           gateway base URL (required)
+//	SMOKE_TOKEN             Logto access token for a dedicated smoke user (required)
+//	SEQUIN_WEBHOOK_SECRET   enables the sse check
+//	SMOKE_PRICE_ID          a recurring Stripe price ID; enables the checkout check
+//
+// The smoke user needs the super_user or uplink_ultimate role (SSE is gated
+// on it) and no active paid subscription (checkout refuses a second one).
+// Its finance channel is deleted and recreated on every run, so don't point
+// this at a real person's account.
+//
+// Usage (from api/):
+//
+//	go run ./cmd/smoketest -skip checkout
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// smokeChannelType is the channel the CRUD check creates and deletes.
+	// Finance is always installed and needs no external account.
+	smokeChannelType = "finance"
+
+	requestTimeout = 15 * time.Second
+	sseTimeout     = 20 * time.Second
+
+	// sseRepostInterval re-sends the synthetic event while waiting, in
+	// case it was published before the hub registered the connection.
+	sseRepostInterval = 2 * time.Second
+)
+
+type smoke struct {
+	baseURL       string
+	token         string
+	webhookSecret string
+	priceID       string
+	http          *http.Client
+}
+
+type check struct {
+	name string
+	run  func(context.Context) error
+	// skipReason is non-empty when the check can't run with the given
+	// configuration.
+	skipReason string
+}
+
+func main() {
+	s := &smoke{http: &http.Client{Timeout: requestTimeout}}
+	flag.StringVar(&s.baseURL, "api-url", os.Getenv("SMOKE_API_URL"), "gateway base URL")
+	flag.StringVar(&s.token, "token", os.Getenv("SMOKE_TOKEN"), "access token for the smoke user")
+	flag.StringVar(&s.webhookSecret, "webhook-secret", os.Getenv("SEQUIN_WEBHOOK_SECRET"), "Sequin webhook secret for the sse check")
+	flag.StringVar(&s.priceID, "price-id", os.Getenv("SMOKE_PRICE_ID"), "Stripe price ID for the checkout check")
+	skip := flag.String("skip", "", "comma-separated checks to skip")
+	flag.Parse()
+
+	if s.baseURL == "" || s.token == "" {
+		fmt.Fprintln(os.Stderr, "SMOKE_API_URL and SMOKE_TOKEN (or -api-url and -token) are required")
+		os.Exit(2)
+	}
+	s.baseURL = strings.TrimSuffix(s.baseURL, "/")
+	skipped := strings.Split(*skip, ",")
+
+	checks := []check{
+		{name: "health", run: s.checkHealth},
+		{name: "auth", run: s.checkAuth},
+		{name: "channels", run: s.checkChannelCreate},
+		{name: "dashboard", run: s.checkDashboard},
+		{name: "sse", run: s.checkSSE},
+		{name: "channels-cleanup", run: s.checkChannelDelete},
+		{name: "checkout", run: s.checkCheckout},
+	}
+	for i := range checks {
+		switch {
+		case checks[i].name == "sse" && s.webhookSecret == "":
+			checks[i].skipReason = "SEQUIN_WEBHOOK_SECRET not set"
+		case checks[i].name == "checkout" && s.priceID == "":
+			checks[i].skipReason = "SMOKE_PRICE_ID not set"
+		case slices.Contains(skipped, checks[i].name):
+			checks[i].skipReason = "skipped by -skip"
+		}
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if c.skipReason != "" {
+			fmt.Printf("%-18s SKIP (%s)\n", c.name, c.skipReason)
+			continue
+		}
+		start := time.Now()
+		err := c.run(context.Background())
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			fmt.Printf("%-18s FAIL (%s): %v\n", c.name, elapsed, err)
+			continue
+		}
+		fmt.Printf("%-18s OK (%s)\n", c.name, elapsed)
+	}
+
+	fmt.Println(strings.Repeat("=", 50))
+	if failed > 0 {
+		fmt.Printf("%d CHECK(S) FAILED against %s\n", failed, s.baseURL)
+		os.Exit(1)
+	}
+	fmt.Printf("ALL CHECKS PASSED against %s\n", s.baseURL)
+}
+
+// ─── Checks ──────────────────────────────────────────────────────
+
+func (s *smoke) checkHealth(ctx context.Context) error {
+	var res struct {
+		Status   string            `json:"status"`
+		Services map[string]string `json:"services"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/health", nil, false, http.StatusOK, &res); err != nil {
+		return err
+	}
+	if res.Status != "healthy" {
+		return fmt.Errorf("status %q, services %v", res.Status, res.Services)
+	}
+	return nil
+}
+
+func (s *smoke) checkAuth(ctx context.Context) error {
+	return s.do(ctx, http.MethodGet, "/users/me/preferences", nil, true, http.StatusOK, nil)
+}
+
+func (s *smoke) checkChannelCreate(ctx context.Context) error {
+	// Clear leftovers from a run that died mid-way.
+	s.deleteSmokeChannel(ctx)
+
+	create := map[string]any{
+		"channel_type": smokeChannelType,
+		"config":       map[string]any{"symbols": []string{"AAPL"}},
+	}
+	if err := s.do(ctx, http.MethodPost, "/users/me/channels", create, true, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	if found, err := s.hasChannel(ctx); err != nil {
+		return fmt.Errorf("list: %w", err)
+	} else if !found {
+		return errors.New("list: created channel missing")
+	}
+
+	update := map[string]any{"config": map[string]any{"symbols": []string{"AAPL", "MSFT"}}}
+	var updated struct {
+		Config struct {
+			Symbols []string `json:"symbols"`
+		} `json:"config"`
+	}
+	if err := s.do(ctx, http.MethodPut, "/users/me/channels/"+smokeChannelType, update, true, http.StatusOK, &updated); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if !slices.Contains(updated.Config.Symbols, "MSFT") {
+		return fmt.Errorf("update: config not applied, got symbols %v", updated.Config.Symbols)
+	}
+	return nil
+}
+
+func (s *smoke) checkDashboard(ctx context.Context) error {
+	var res struct {
+		Data     map[string]json.RawMessage `json:"data"`
+		Channels []struct {
+			ChannelType string `json:"channel_type"`
+		} `json:"channels"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/dashboard", nil, true, http.StatusOK, &res); err != nil {
+		return err
+	}
+	if res.Data == nil {
+		return errors.New("response has no data object")
+	}
+	for _, ch := range res.Channels {
+		if ch.ChannelType == smokeChannelType {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s channel missing from dashboard", smokeChannelType)
+}
+
+// checkSSE opens the user's event stream, then posts a user_preferences
+// CDC record carrying a nonce until that nonce comes back on the stream.
+// user_preferences routes to the user's own core topic, so no other
+// client sees it.
+func (s *smoke) checkSSE(ctx context.Context) error {
+	sub, err := tokenSubject(s.token)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sseTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Accept", "text/event-stream")
+	// The shared client's timeout would cut the stream; ctx bounds it.
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("connect: HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	nonce := fmt.Sprintf("smoke-%d", time.Now().UnixNano())
+	received := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "data:") && strings.Contains(line, nonce) {
+				received <- nil
+				return
+			}
+		}
+		received <- fmt.Errorf("stream closed before event arrived: %v", scanner.Err())
+	}()
+
+	event := map[string]any{
+		"action": "update",
+		"record": map[string]any{"logto_sub": sub, "smoketest_nonce": nonce},
+		"metadata": map[string]any{
+			"table_schema": "public",
+			"table_name":   "user_preferences",
+		},
+	}
+	repost := time.NewTicker(sseRepostInterval)
+	defer repost.Stop()
+	for {
+		if err := s.postCDC(ctx, event); err != nil {
+			return fmt.Errorf("post event: %w", err)
+		}
+		select {
+		case err := <-received:
+			return err
+		case <-repost.C:
+		case <-ctx.Done():
+			return fmt.Errorf("event not received within %s", sseTimeout)
+		}
+	}
+}
+
+func (s *smoke) checkChannelDelete(ctx context.Context) error {
+	if err := s.do(ctx, http.MethodDelete, "/users/me/channels/"+smokeChannelType, nil, true, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if found, err := s.hasChannel(ctx); err != nil {
+		return fmt.Errorf("list: %w", err)
+	} else if found {
+		return errors.New("list: channel still present after delete")
+	}
+	return nil
+}
+
+func (s *smoke) checkCheckout(ctx context.Context) error {
+	var res struct {
+		ClientSecret   string `json:"client_secret"`
+		SessionID      string `json:"session_id"`
+		PublishableKey string `json:"publishable_key"`
+	}
+	body := map[string]any{"price_id": s.priceID}
+	if err := s.do(ctx, http.MethodPost, "/checkout/session", body, true, http.StatusOK, &res); err != nil {
+		return err
+	}
+	if res.ClientSecret == "" {
+		return errors.New("response has no client_secret")
+	}
+	if !strings.HasPrefix(res.SessionID, "cs_test_") {
+		return fmt.Errorf("session %q is not a test-mode session", res.SessionID)
+	}
+	return nil
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────
+
+// do sends a JSON request and decodes the response into out (if non-nil).
+// Any status other than want is an error that includes the body.
+func (s *smoke) do(ctx context.Context, method, path string, body any, auth bool, want int, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, truncate(data, 300))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: decode: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+func (s *smoke) postCDC(ctx context.Context, record map[string]any) error {
+	data, err := json.Marshal(map[string]any{"data": []any{record}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/webhooks/sequin", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.webhookSecret)
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func (s *smoke) hasChannel(ctx context.Context) (bool, error) {
+	var res struct {
+		Channels []struct {
+			ChannelType string `json:"channel_type"`
+		} `json:"channels"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/users/me/channels", nil, true, http.StatusOK, &res); err != nil {
+		return false, err
+	}
+	for _, ch := range res.Channels {
+		if ch.ChannelType == smokeChannelType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deleteSmokeChannel removes the smoke channel if it exists, ignoring
+// errors; checkChannelDelete is the one that asserts.
+func (s *smoke) deleteSmokeChannel(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.baseURL+"/users/me/channels/"+smokeChannelType, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if resp, err := s.http.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// tokenSubject reads the sub claim from a JWT without verifying it; the
+// gateway does the verifying.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decode token payload: %w", err)
+	}
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Sub == "" {
+		return "", errors.New("token has no sub claim")
+	}
+	return claims.Sub, nil
+}
+
+func truncate(b []byte, n int) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > n {
+		return s[:n] + "…"
+	}
+	return s
+}
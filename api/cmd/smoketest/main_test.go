@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestTokenSubject(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user_123","exp":1}`))
+	sub, err := tokenSubject("eyJhbGciOiJSUzI1NiJ9." + payload + ".sig")
+	if err != nil {
+		t.Fatalf("tokenSubject: %v", err)
+	}
+	if sub != "user_123" {
+		t.Errorf("sub = %q, want user_123", sub)
+	}
+
+	for _, bad := range []string{
+		"not-a-jwt",
+		"a.!!!.c",
+		"a." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1}`)) + ".c",
+	} {
+		if _, err := tokenSubject(bad); err == nil {
+			t.Errorf("tokenSubject(%q) succeeded, want error", bad)
+		}
+	}
+}
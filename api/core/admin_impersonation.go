@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Support mode.
+//
+// Read-only views of another user's data for support staff, served
+// through the same code paths the user's own requests take. Every read
+// is written to admin_audit before anything is returned; if the audit
+// write fails the read is refused. Nothing here writes to the user's
+// caches, snapshots or engagement counters.
+
+// Impersonated read actions recorded in admin_audit.
+const (
+	AdminAuditViewDashboard = "view_dashboard"
+	AdminAuditViewChannels  = "view_channels"
+)
+
+// adminAuditReasonMax caps the free-text reason stored per read.
+const adminAuditReasonMax = 500
+
+// auditReason trims and caps the ?reason= given with an impersonated
+// read (typically a ticket reference).
+func auditReason(raw string) string {
+	reason := strings.TrimSpace(raw)
+	if len(reason) > adminAuditReasonMax {
+		reason = reason[:adminAuditReasonMax]
+	}
+	return reason
+}
+
+func recordAdminAudit(ctx context.Context, c *fiber.Ctx, targetSub, action string) error {
+	_, err := DBPool.Exec(ctx, `
+		INSERT INTO admin_audit (admin_sub, target_sub, action, path, reason, ip)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)`,
+		GetUserID(c), targetSub, action, c.Path(), auditReason(c.Query("reason")), c.IP())
+	return err
+}
+
+// beginImpersonatedRead checks the target exists and audits the read.
+// It writes the error response itself and returns false when the read
+// must not go ahead.
+func beginImpersonatedRead(c *fiber.Ctx, action string) (string, bool) {
+	ctx := c.Context()
+	targetSub := c.Params("sub")
+
+	var exists bool
+	err := DBPool.QueryRow(ctx,
+		`SELECT true FROM user_preferences WHERE logto_sub = $1`, targetSub,
+	).Scan(&exists)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "User not found",
+		})
+		return "", false
+	}
+	if err != nil {
+		log.Printf("[SupportMode] lookup %s failed: %v", targetSub, err)
+		_ = c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load user",
+		})
+		return "", false
+	}
+
+	if err := recordAdminAudit(ctx, c, targetSub, action); err != nil {
+		log.Printf("[SupportMode] audit write failed (%s on %s by %s): %v", action, targetSub, GetUserID(c), err)
+		_ = c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to record audit entry",
+		})
+		return "", false
+	}
+	Audit(ctx, GetUserID(c), AuditAdminViewedAccount, targetSub, map[string]any{"view": action})
+	log.Printf("[SupportMode] %s read %s for %s", GetUserID(c), action, targetSub)
+	return targetSub, true
+}
+
+// HandleAdminUserDashboard returns what GET /dashboard would assemble
+// for the user right now. Always freshly assembled: the user's cache
+// and snapshot are neither read nor written.
+//
+// @Summary User dashboard (admin support mode)
+// @Tags Admin
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Param reason query string false "Why the data is being viewed, e.g. a ticket reference"
+// @Success 200 {object} DashboardResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/users/{sub}/dashboard [get]
+func HandleAdminUserDashboard(c *fiber.Ctx) error {
+	targetSub, ok := beginImpersonatedRead(c, AdminAuditViewDashboard)
+	if !ok {
+		return nil
+	}
+	data, _, _ := assembleDashboard(c.UserContext(), targetSub)
+	c.Set("Cache-Control", "no-store")
+	c.Set("Content-Type", fiber.MIMEApplicationJSON)
+	return c.Send(data)
+}
+
+// HandleAdminUserChannels returns the user's channels as
+// GET /users/me/channels would.
+//
+// @Summary User channels (admin support mode)
+// @Tags Admin
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Param reason query string false "Why the data is being viewed, e.g. a ticket reference"
+// @Success 200 {object} object{channels=[]Channel}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/users/{sub}/channels [get]
+func HandleAdminUserChannels(c *fiber.Ctx) error {
+	targetSub, ok := beginImpersonatedRead(c, AdminAuditViewChannels)
+	if !ok {
+		return nil
+	}
+	channels, err := GetUserChannels(targetSub)
+	if err != nil {
+		log.Printf("[SupportMode] channels for %s failed: %v", targetSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load channels",
+		})
+	}
+	c.Set("Cache-Control", "no-store")
+	return c.JSON(fiber.Map{"channels": channels})
+}
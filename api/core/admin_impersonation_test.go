@@ -0,0 +1,18 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditReason(t *testing.T) {
+	if got := auditReason("  ticket #4821 "); got != "ticket #4821" {
+		t.Errorf("auditReason trimmed = %q", got)
+	}
+	if got := auditReason(""); got != "" {
+		t.Errorf("auditReason empty = %q", got)
+	}
+	if got := auditReason(strings.Repeat("x", adminAuditReasonMax+20)); len(got) != adminAuditReasonMax {
+		t.Errorf("auditReason length = %d, want %d", len(got), adminAuditReasonMax)
+	}
+}
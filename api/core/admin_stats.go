@@ -0,0 +1,209 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// System stats.
+//
+// GET /admin/stats gathers in one response what is otherwise spread over
+// /metrics, /channels/status and /admin/dashboard/snapshots, in a shape
+// the landing page's status widget can eventually read instead of only
+// health booleans. Apart from the SSE total, which is summed across
+// replicas (sse_cluster.go), every figure is for the replica that served
+// the request.
+
+// ─── CDC throughput ──────────────────────────────────────────────
+
+// throughputCounter counts events in one-second buckets over a sliding
+// window.
+type throughputCounter struct {
+	mu     sync.Mutex
+	counts []int64
+	secs   []int64 // unix second each bucket was last written
+}
+
+func newThroughputCounter(window time.Duration) *throughputCounter {
+	n := int(window / time.Second)
+	return &throughputCounter{counts: make([]int64, n), secs: make([]int64, n)}
+}
+
+// cdcThroughput counts CDC records received by the webhook.
+var cdcThroughput = newThroughputCounter(CDCThroughputWindow)
+
+func (t *throughputCounter) add(now time.Time, n int) {
+	sec := now.Unix()
+	i := int(sec % int64(len(t.counts)))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.secs[i] != sec {
+		t.secs[i] = sec
+		t.counts[i] = 0
+	}
+	t.counts[i] += int64(n)
+}
+
+// total returns the events counted within the window ending at now.
+func (t *throughputCounter) total(now time.Time) int64 {
+	sec := now.Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sum int64
+	for i, s := range t.secs {
+		if sec-s < int64(len(t.secs)) {
+			sum += t.counts[i]
+		}
+	}
+	return sum
+}
+
+// ─── Route latency ───────────────────────────────────────────────
+
+// routeLatency keeps recent request latencies per "METHOD route" for
+// the percentiles in /admin/stats. MetricsMiddleware feeds it alongside
+// the Prometheus histogram, whose buckets are too coarse for a p95.
+var routeLatency = newLatencyStats(RouteLatencySamples)
+
+// ─── Snapshot ────────────────────────────────────────────────────
+
+// CacheStats is one gateway cache's lookups since the replica started.
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// cacheStats reads the cache lookup counters (recordCacheLookup).
+func cacheStats() map[string]CacheStats {
+	out := make(map[string]CacheStats)
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		return out
+	}
+	for _, f := range families {
+		if f.GetName() != "scrollr_cache_lookups_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var cache, result string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "cache":
+					cache = l.GetValue()
+				case "result":
+					result = l.GetValue()
+				}
+			}
+			s := out[cache]
+			if result == "hit" {
+				s.Hits = int64(m.GetCounter().GetValue())
+			} else {
+				s.Misses = int64(m.GetCounter().GetValue())
+			}
+			out[cache] = s
+		}
+	}
+	for cache, s := range out {
+		if n := s.Hits + s.Misses; n > 0 {
+			s.HitRate = float64(s.Hits) / float64(n)
+		}
+		out[cache] = s
+	}
+	return out
+}
+
+// PoolStats is a Postgres pool's current usage.
+type PoolStats struct {
+	Acquired      int32   `json:"acquired"`
+	Idle          int32   `json:"idle"`
+	Total         int32   `json:"total"`
+	Max           int32   `json:"max"`
+	Utilization   float64 `json:"utilization"` // acquired / max
+	EmptyAcquires int64   `json:"empty_acquires"`
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	out := PoolStats{
+		Acquired:      s.AcquiredConns(),
+		Idle:          s.IdleConns(),
+		Total:         s.TotalConns(),
+		Max:           s.MaxConns(),
+		EmptyAcquires: s.EmptyAcquireCount(),
+	}
+	if out.Max > 0 {
+		out.Utilization = float64(out.Acquired) / float64(out.Max)
+	}
+	return out
+}
+
+// ChannelFreshness is how recently this replica saw a channel's
+// registration.
+type ChannelFreshness struct {
+	Name       string    `json:"name"`
+	Registered bool      `json:"registered"`
+	Health     string    `json:"health"`
+	LastSeen   time.Time `json:"last_seen"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+func channelFreshness(now time.Time) []ChannelFreshness {
+	statuses := channelStatuses.snapshot()
+	out := make([]ChannelFreshness, 0, len(statuses))
+	for _, st := range statuses {
+		out = append(out, ChannelFreshness{
+			Name:       st.Name,
+			Registered: st.Registered,
+			Health:     st.Health,
+			LastSeen:   st.LastSeen,
+			AgeSeconds: now.Sub(st.LastSeen).Seconds(),
+		})
+	}
+	return out
+}
+
+// ─── Admin ───────────────────────────────────────────────────────
+
+// HandleAdminStats reports SSE clients, channel registration freshness,
+// CDC throughput, cache hit rates, Postgres pool usage and per-route
+// latency.
+//
+// @Summary System stats (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{sse=object{local=int,total=int},channels=[]ChannelFreshness,cdc=object{records_per_minute=number},caches=map[string]CacheStats,db_pools=map[string]PoolStats,routes=map[string]LatencySummary}
+// @Security LogtoAuth
+// @Router /admin/stats [get]
+func HandleAdminStats(c *fiber.Ctx) error {
+	now := time.Now()
+
+	sse := fiber.Map{"local": 0, "total": 0}
+	if globalHub != nil {
+		sse["local"] = ClientCount()
+		sse["total"] = ClientCount()
+		if Rdb != nil {
+			sse["total"] = TotalClientCount(c.Context())
+		}
+	}
+
+	pools := make(map[string]PoolStats)
+	if DBPool != nil {
+		pools["primary"] = poolStats(DBPool)
+	}
+	if ReplicaPool != nil {
+		pools["replica"] = poolStats(ReplicaPool)
+	}
+
+	return c.JSON(fiber.Map{
+		"sse":      sse,
+		"channels": channelFreshness(now),
+		"cdc":      fiber.Map{"records_per_minute": float64(cdcThroughput.total(now)) / CDCThroughputWindow.Minutes()},
+		"caches":   cacheStats(),
+		"db_pools": pools,
+		"routes":   routeLatency.summary(),
+	})
+}
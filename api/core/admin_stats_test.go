@@ -0,0 +1,67 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestThroughputCounterWindow(t *testing.T) {
+	tc := newThroughputCounter(time.Minute)
+	start := time.Unix(1_000_000, 0)
+	tc.add(start, 5)
+	tc.add(start.Add(500*time.Millisecond), 2)
+	tc.add(start.Add(30*time.Second), 3)
+
+	if got := tc.total(start.Add(30 * time.Second)); got != 10 {
+		t.Errorf("total within window = %d, want 10", got)
+	}
+	if got := tc.total(start.Add(75 * time.Second)); got != 3 {
+		t.Errorf("total after first bucket aged out = %d, want 3", got)
+	}
+
+	// A bucket reused a window later starts from zero.
+	tc.add(start.Add(time.Minute), 1)
+	if got := tc.total(start.Add(time.Minute)); got != 4 {
+		t.Errorf("total after bucket reuse = %d, want 4", got)
+	}
+}
+
+func TestHandleAdminStats(t *testing.T) {
+	recordCacheLookup("admin_stats_test", true)
+	recordCacheLookup("admin_stats_test", true)
+	recordCacheLookup("admin_stats_test", true)
+	recordCacheLookup("admin_stats_test", false)
+
+	app := fiber.New()
+	app.Use(MetricsMiddleware)
+	app.Get("/admin-stats-test/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/admin/stats", HandleAdminStats)
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/admin-stats-test/1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/stats", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Caches map[string]CacheStats     `json:"caches"`
+		Routes map[string]LatencySummary `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := body.Caches["admin_stats_test"]; got.Hits != 3 || got.Misses != 1 || got.HitRate != 0.75 {
+		t.Errorf("cache stats = %+v, want 3 hits, 1 miss, rate 0.75", got)
+	}
+	if got := body.Routes["GET /admin-stats-test/:id"]; got.Count != 1 {
+		t.Errorf("route latency = %+v, want 1 sample under the route template", got)
+	}
+}
@@ -86,7 +86,7 @@ type RecentTicketSummary struct {
 // required fields) returns nil. Caller must handle nil gracefully —
 // AI triage is best-effort, never blocks the ticket flow.
 func triageTicket(ctx context.Context, input TriageInput) *TriageResult {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	apiKey := Secret("ANTHROPIC_API_KEY")
 	if apiKey == "" {
 		log.Println("[Triage] ANTHROPIC_API_KEY not set; skipping triage")
 		return nil
@@ -127,8 +127,7 @@ func triageTicket(ctx context.Context, input TriageInput) *TriageResult {
 	req.Header.Set("x-api-key", apiKey)
 	req.Header.Set("anthropic-version", anthropicVersion)
 
-	client := &http.Client{Timeout: triageTimeout}
-	resp, err := client.Do(req)
+	resp, err := triageHTTPClient.Do(req)
 	if err != nil {
 		log.Printf("[Triage] HTTP request failed: %v", err)
 		return nil
@@ -0,0 +1,585 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Anonymous sessions let a signed-out client keep channel config on the
+// server before the user has a Logto identity. The client holds an
+// opaque claim token; after the Logto callback it posts the token to
+// /users/me/claim and the session is merged into the new account.
+//
+// Sessions live only in Redis (keyed by the token hash) and expire after
+// AnonSessionTTL. Besides channel config a session can hold the user's
+// saved searches and price alerts, which otherwise need an account to
+// store. The claim writes every one of them under the new account in a
+// single transaction.
+
+// AnonymousSession is the stored state behind a claim token.
+type AnonymousSession struct {
+	Channels      map[string]map[string]any `json:"channels"`
+	SavedSearches []savedSearchInput        `json:"saved_searches,omitempty"`
+	PriceAlerts   []AnonymousPriceAlert     `json:"price_alerts,omitempty"`
+	CreatedAt     time.Time                 `json:"created_at"`
+	UpdatedAt     time.Time                 `json:"updated_at"`
+}
+
+// AnonymousPriceAlert is a price alert kept in a session until claim.
+// Webhooks need the secret the finance API returns on creation, so
+// session alerts deliver over SSE only.
+type AnonymousPriceAlert struct {
+	Symbol    string  `json:"symbol"`
+	Condition string  `json:"condition"` // above, below or change_pct
+	Threshold float64 `json:"threshold"`
+}
+
+// generateClaimToken returns "anon_<43 url-safe chars>" (32 random bytes).
+func generateClaimToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return AnonClaimTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// anonSessionKey hashes the token so a Redis dump doesn't hand out
+// claimable sessions.
+func anonSessionKey(token string) string {
+	return RedisAnonSessionPrefix + hashAPIKey(token)
+}
+
+func claimTokenFromRequest(c *fiber.Ctx) string {
+	token := strings.TrimSpace(c.Get(AnonClaimTokenHeader))
+	if !strings.HasPrefix(token, AnonClaimTokenPrefix) {
+		return ""
+	}
+	return token
+}
+
+// validateAnonymousChannels checks an anonymous session's channel map.
+// Anonymous users get free-tier caps — signing up never unlocks less
+// than they already had.
+func validateAnonymousChannels(channels map[string]map[string]any, validTypes map[string]bool) error {
+	for channelType, config := range channels {
+		if !validTypes[channelType] {
+			return fmt.Errorf("invalid channel type %q", channelType)
+		}
+		// Fantasy needs a Yahoo link, which needs an account.
+		if channelType == "fantasy" {
+			return fmt.Errorf("channel type %q requires an account", channelType)
+		}
+		if err := ValidateChannelConfig("free", channelType, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAnonymousSavedSearches normalizes a session's saved searches
+// in place, with the same rules and per-user cap as signed-in users.
+func validateAnonymousSavedSearches(searches []savedSearchInput) error {
+	if len(searches) > SavedSearchMaxPerUser {
+		return fmt.Errorf("at most %d saved searches", SavedSearchMaxPerUser)
+	}
+	for i := range searches {
+		if err := validateSavedSearch(&searches[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAnonymousPriceAlerts normalizes a session's price alerts in
+// place. The rules mirror the finance API's POST /users/me/alerts, capped
+// at the free tier's allowance.
+func validateAnonymousPriceAlerts(alerts []AnonymousPriceAlert) error {
+	if len(alerts) > AnonMaxPriceAlerts {
+		return fmt.Errorf("at most %d price alerts", AnonMaxPriceAlerts)
+	}
+	for i := range alerts {
+		a := &alerts[i]
+		a.Symbol = strings.ToUpper(strings.TrimSpace(a.Symbol))
+		if a.Symbol == "" || len(a.Symbol) > 30 {
+			return fmt.Errorf("price alert symbol is required")
+		}
+		if math.IsNaN(a.Threshold) || math.IsInf(a.Threshold, 0) || a.Threshold <= 0 {
+			return fmt.Errorf("price alert threshold must be a positive number")
+		}
+		switch a.Condition {
+		case "above", "below":
+			if a.Threshold >= 1e8 {
+				return fmt.Errorf("price alert threshold is out of range")
+			}
+		case "change_pct":
+			if a.Threshold > 100 {
+				return fmt.Errorf("change_pct threshold must be at most 100")
+			}
+		default:
+			return fmt.Errorf("price alert condition must be one of above, below, change_pct")
+		}
+	}
+	return nil
+}
+
+// claimListFields names the array field in each channel's config that
+// a claim merges by union, and how list items are identified.
+var claimListFields = map[string]string{
+	"finance": "symbols",
+	"sports":  "leagues",
+	"rss":     "feeds",
+}
+
+// claimItemKey identifies a list entry for dedup: the string itself, or
+// an object's "url" (RSS feeds).
+func claimItemKey(v any) string {
+	switch item := v.(type) {
+	case string:
+		return item
+	case map[string]any:
+		u, _ := item["url"].(string)
+		return u
+	}
+	return ""
+}
+
+// mergeClaimedConfig folds an anonymous session's config into the
+// account's existing config for the same channel. The list field is a
+// union with the anonymous entries first — they're what the user picked
+// by hand, whereas anything already on the account at claim time is
+// typically an accepted onboarding default. Other keys from the session
+// win. The caller prunes the result to the account's tier, which keeps
+// the front of the list.
+func mergeClaimedConfig(channelType string, existing, claimed map[string]any) map[string]any {
+	out := cloneMap(existing)
+	for k, v := range claimed {
+		out[k] = v
+	}
+
+	field, ok := claimListFields[channelType]
+	if !ok {
+		return out
+	}
+	seen := make(map[string]bool)
+	merged := make([]any, 0)
+	for _, src := range [][]any{asArray(claimed[field]), asArray(existing[field])} {
+		for _, item := range src {
+			key := claimItemKey(item)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, item)
+		}
+	}
+	out[field] = merged
+	return out
+}
+
+// ─── Anonymous Handlers ──────────────────────────────────────────
+
+// HandleCreateAnonymousSession issues a new claim token.
+//
+// @Summary Create anonymous session
+// @Description Issues a claim token for storing channel config before sign-up
+// @Tags Anonymous
+// @Produce json
+// @Success 201 {object} object{claim_token=string,expires_at=string}
+// @Router /anonymous/session [post]
+func HandleCreateAnonymousSession(c *fiber.Ctx) error {
+	token, err := generateClaimToken()
+	if err != nil {
+		log.Printf("[Anonymous] token generation failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create session",
+		})
+	}
+
+	now := time.Now().UTC()
+	sess := AnonymousSession{
+		Channels:  map[string]map[string]any{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	data, _ := json.Marshal(sess)
+	if err := Rdb.Set(c.Context(), anonSessionKey(token), data, AnonSessionTTL).Err(); err != nil {
+		log.Printf("[Anonymous] store session failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create session",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"claim_token": token,
+		"expires_at":  now.Add(AnonSessionTTL).Format(time.RFC3339),
+	})
+}
+
+// HandleGetAnonymousSession returns the config stored behind the
+// X-Claim-Token header.
+//
+// @Summary Get anonymous session
+// @Tags Anonymous
+// @Produce json
+// @Param X-Claim-Token header string true "Claim token"
+// @Success 200 {object} AnonymousSession
+// @Failure 404 {object} ErrorResponse
+// @Router /anonymous/session [get]
+func HandleGetAnonymousSession(c *fiber.Ctx) error {
+	token := claimTokenFromRequest(c)
+	if token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found",
+		})
+	}
+	val, err := Rdb.Get(c.Context(), anonSessionKey(token)).Bytes()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found",
+		})
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(val)
+}
+
+// HandleUpdateAnonymousSession replaces the session's channel config,
+// and its saved searches and price alerts when the body includes them.
+// Each write refreshes the TTL, so an active demo user never loses
+// their setup mid-session.
+//
+// @Summary Update anonymous session
+// @Tags Anonymous
+// @Accept json
+// @Produce json
+// @Param X-Claim-Token header string true "Claim token"
+// @Param body body object true "Channel configs keyed by type, plus optional saved searches and price alerts" example({"channels":{"finance":{"symbols":["AAPL"]}},"price_alerts":[{"symbol":"AAPL","condition":"above","threshold":250}]})
+// @Success 200 {object} AnonymousSession
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /anonymous/session [put]
+func HandleUpdateAnonymousSession(c *fiber.Ctx) error {
+	token := claimTokenFromRequest(c)
+	if token == "" {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found",
+		})
+	}
+
+	var req struct {
+		Channels      map[string]map[string]any `json:"channels"`
+		SavedSearches []savedSearchInput        `json:"saved_searches"`
+		PriceAlerts   []AnonymousPriceAlert     `json:"price_alerts"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if req.Channels == nil {
+		req.Channels = map[string]map[string]any{}
+	}
+	if err := validateAnonymousChannels(req.Channels, GetValidChannelTypes()); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+	if err := validateAnonymousSavedSearches(req.SavedSearches); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+	if err := validateAnonymousPriceAlerts(req.PriceAlerts); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	key := anonSessionKey(token)
+	val, err := Rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found",
+		})
+	}
+	var sess AnonymousSession
+	if err := json.Unmarshal(val, &sess); err != nil {
+		sess.CreatedAt = time.Now().UTC()
+	}
+	sess.Channels = req.Channels
+	// Omitted lists keep what the session had; [] clears them.
+	if req.SavedSearches != nil {
+		sess.SavedSearches = req.SavedSearches
+	}
+	if req.PriceAlerts != nil {
+		sess.PriceAlerts = req.PriceAlerts
+	}
+	sess.UpdatedAt = time.Now().UTC()
+
+	data, _ := json.Marshal(sess)
+	// XX: don't resurrect a session that was claimed between GET and SET.
+	if err := Rdb.SetXX(ctx, key, data, AnonSessionTTL).Err(); err != nil {
+		log.Printf("[Anonymous] update session failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update session",
+		})
+	}
+	return c.JSON(sess)
+}
+
+// ─── Claim ───────────────────────────────────────────────────────
+
+// HandleClaimAnonymousSession merges an anonymous session into the
+// signed-in account. The client calls this right after the Logto
+// callback with the token it held while signed out.
+//
+// The session is consumed with GETDEL so a token can only be claimed
+// once; if the DB merge fails the session is put back so the client can
+// retry. All writes — channels, saved searches and price alerts — happen
+// in one transaction, so a partial failure leaves the account as it was.
+//
+// Conflicts with onboarding defaults: a proposal that's still pending
+// is left alone (accepting it later skips channel types the claim
+// created), and channels already on the account are merged per
+// mergeClaimedConfig.
+//
+// @Summary Claim anonymous session
+// @Tags Anonymous
+// @Accept json
+// @Produce json
+// @Param body body object true "Claim request" example({"claim_token":"anon_..."})
+// @Success 200 {object} object{channels=[]Channel,saved_searches=int,price_alerts=int}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/claim [post]
+func HandleClaimAnonymousSession(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		ClaimToken string `json:"claim_token"`
+	}
+	if err := c.BodyParser(&req); err != nil || !strings.HasPrefix(req.ClaimToken, AnonClaimTokenPrefix) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "claim_token is required",
+		})
+	}
+
+	ctx := c.Context()
+	key := anonSessionKey(req.ClaimToken)
+	ttl, _ := Rdb.TTL(ctx, key).Result()
+	val, err := Rdb.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found or already claimed",
+		})
+	}
+	if err != nil {
+		log.Printf("[Anonymous] claim GETDEL failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to claim session",
+		})
+	}
+
+	var sess AnonymousSession
+	if err := json.Unmarshal(val, &sess); err != nil {
+		log.Printf("[Anonymous] corrupt session claimed by %s: %v", userID, err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Session not found or already claimed",
+		})
+	}
+
+	tier := tierFromRoles(GetUserRoles(c))
+	claimed, err := mergeAnonymousSession(ctx, userID, tier, sess)
+	if err != nil {
+		log.Printf("[Anonymous] claim merge failed for %s: %v", userID, err)
+		if ttl <= 0 {
+			ttl = AnonSessionTTL
+		}
+		if rerr := Rdb.SetNX(context.Background(), key, val, ttl).Err(); rerr != nil {
+			log.Printf("[Anonymous] failed to restore session for %s: %v", userID, rerr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to claim session",
+		})
+	}
+
+	merged := claimed.channels
+	bg := context.Background()
+	for _, m := range merged {
+		if m.channel.Enabled {
+			addChannelSubscriptions(bg, userID, m.channel.ChannelType, m.channel.Config)
+		}
+		event := "updated"
+		if m.oldConfig == nil {
+			event = "created"
+		}
+		callChannelLifecycle(bg, m.channel.ChannelType, event, userID, m.channel.Config, m.oldConfig, nil)
+	}
+	if claimed.savedSearches > 0 {
+		refreshSavedSearchesAsync()
+	}
+	InvalidateDashboardCache(userID)
+	InvalidateOverviewCache(ctx, userID)
+
+	channels := make([]Channel, len(merged))
+	for i, m := range merged {
+		channels[i] = m.channel
+	}
+	log.Printf("[Anonymous] %s claimed anonymous session (%d channel(s), %d saved search(es), %d price alert(s))",
+		userID, len(channels), claimed.savedSearches, claimed.priceAlerts)
+	return c.JSON(fiber.Map{
+		"channels":       channels,
+		"saved_searches": claimed.savedSearches,
+		"price_alerts":   claimed.priceAlerts,
+	})
+}
+
+type claimedChannel struct {
+	channel   Channel
+	oldConfig map[string]any // nil when the claim created the channel
+}
+
+// claimResult is what a claim wrote to the account.
+type claimResult struct {
+	channels      []claimedChannel
+	savedSearches int
+	priceAlerts   int
+}
+
+// mergeAnonymousSession writes the session's channels into user_channels,
+// and its saved searches and price alerts into their tables, in a single
+// transaction. Channel rows are locked FOR UPDATE so a concurrent
+// channel edit can't interleave with the merge.
+func mergeAnonymousSession(ctx context.Context, logtoSub, tier string, sess AnonymousSession) (claimResult, error) {
+	validTypes := GetValidChannelTypes()
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return claimResult{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	out := make([]claimedChannel, 0, len(sess.Channels))
+	for channelType, claimed := range sess.Channels {
+		if !validTypes[channelType] {
+			continue
+		}
+
+		var existingBytes []byte
+		err := tx.QueryRow(ctx, `
+			SELECT config FROM user_channels
+			WHERE logto_sub = $1 AND channel_type = $2
+			FOR UPDATE
+		`, logtoSub, channelType).Scan(&existingBytes)
+		if err != nil && err != pgx.ErrNoRows {
+			return claimResult{}, fmt.Errorf("read %s: %w", channelType, err)
+		}
+		var existing map[string]any
+		if err == nil {
+			if uerr := json.Unmarshal(existingBytes, &existing); uerr != nil || existing == nil {
+				existing = map[string]any{}
+			}
+		}
+
+		merged, _ := PruneChannelConfig(tier, channelType, mergeClaimedConfig(channelType, existing, claimed))
+		configJSON, _ := json.Marshal(merged)
+
+		var ch Channel
+		var configBytes []byte
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO user_channels (logto_sub, channel_type, config)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (logto_sub, channel_type) DO UPDATE
+			   SET config = EXCLUDED.config, updated_at = now()
+			RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
+		`, logtoSub, channelType, configJSON).Scan(
+			&ch.ID, &ch.LogtoSub, &ch.ChannelType, &ch.Enabled, &ch.Visible,
+			&configBytes, &ch.CreatedAt, &ch.UpdatedAt,
+		); err != nil {
+			return claimResult{}, fmt.Errorf("upsert %s: %w", channelType, err)
+		}
+		if err := json.Unmarshal(configBytes, &ch.Config); err != nil {
+			ch.Config = map[string]interface{}{}
+		}
+		out = append(out, claimedChannel{channel: ch, oldConfig: existing})
+	}
+	result := claimResult{channels: out}
+
+	// Saved searches, up to what the account still has room for. Searches
+	// it already has under the same name are kept as they are.
+	if len(sess.SavedSearches) > 0 {
+		var have int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM saved_searches WHERE logto_sub = $1`, logtoSub).Scan(&have); err != nil {
+			return claimResult{}, fmt.Errorf("count saved_searches: %w", err)
+		}
+		for _, in := range sess.SavedSearches {
+			if have >= SavedSearchMaxPerUser {
+				break
+			}
+			tag, err := tx.Exec(ctx, `
+				INSERT INTO saved_searches (logto_sub, name, query, symbols, enabled)
+				SELECT $1, $2, $3, $4, $5
+				WHERE NOT EXISTS (SELECT 1 FROM saved_searches WHERE logto_sub = $1 AND name = $2)
+			`, logtoSub, in.Name, in.Query, in.Symbols, in.Enabled == nil || *in.Enabled)
+			if err != nil {
+				return claimResult{}, fmt.Errorf("insert saved_searches: %w", err)
+			}
+			n := int(tag.RowsAffected())
+			have += n
+			result.savedSearches += n
+		}
+	}
+
+	// Price alerts for symbols the finance channel still tracks. The
+	// finance API's watcher picks them up on its next index reload.
+	for _, a := range sess.PriceAlerts {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO price_alerts (logto_sub, symbol, condition, threshold)
+			SELECT $1, $2, $3, $4
+			WHERE EXISTS (SELECT 1 FROM trades WHERE symbol = $2)
+		`, logtoSub, a.Symbol, a.Condition, a.Threshold)
+		if err != nil {
+			return claimResult{}, fmt.Errorf("insert price_alerts: %w", err)
+		}
+		result.priceAlerts += int(tag.RowsAffected())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return claimResult{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return result, nil
+}
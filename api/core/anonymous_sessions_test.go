@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMergeClaimedConfig_UnionClaimedFirst(t *testing.T) {
+	existing := map[string]any{"symbols": []any{"SPY", "AAPL"}, "layout": "compact"}
+	claimed := map[string]any{"symbols": []any{"TSLA", "AAPL"}}
+
+	got := mergeClaimedConfig("finance", existing, claimed)
+	want := []any{"TSLA", "AAPL", "SPY"}
+	if !reflect.DeepEqual(got["symbols"], want) {
+		t.Errorf("symbols = %v, want %v", got["symbols"], want)
+	}
+	if got["layout"] != "compact" {
+		t.Errorf("unrelated existing key dropped: %v", got)
+	}
+}
+
+func TestMergeClaimedConfig_FeedsDedupByURL(t *testing.T) {
+	existing := map[string]any{"feeds": []any{
+		map[string]any{"name": "BBC News", "url": "https://feeds.bbci.co.uk/news/rss.xml"},
+	}}
+	claimed := map[string]any{"feeds": []any{
+		map[string]any{"name": "BBC", "url": "https://feeds.bbci.co.uk/news/rss.xml"},
+		map[string]any{"name": "NPR News", "url": "https://feeds.npr.org/1001/rss.xml"},
+	}}
+
+	feeds := asArray(mergeClaimedConfig("rss", existing, claimed)["feeds"])
+	if len(feeds) != 2 {
+		t.Fatalf("got %d feeds, want 2: %v", len(feeds), feeds)
+	}
+	if name := feeds[0].(map[string]any)["name"]; name != "BBC" {
+		t.Errorf("claimed entry should win on duplicate URL, got %v", name)
+	}
+}
+
+func TestMergeClaimedConfig_NoExisting(t *testing.T) {
+	claimed := map[string]any{"leagues": []any{"NBA"}}
+	got := mergeClaimedConfig("sports", nil, claimed)
+	if !reflect.DeepEqual(got["leagues"], []any{"NBA"}) {
+		t.Errorf("leagues = %v, want [NBA]", got["leagues"])
+	}
+}
+
+func TestValidateAnonymousChannels(t *testing.T) {
+	valid := map[string]bool{"finance": true, "fantasy": true}
+	if err := validateAnonymousChannels(map[string]map[string]any{
+		"finance": {"symbols": []any{"AAPL"}},
+	}, valid); err != nil {
+		t.Errorf("valid finance config rejected: %v", err)
+	}
+	if err := validateAnonymousChannels(map[string]map[string]any{"weather": {}}, valid); err == nil {
+		t.Error("unknown channel type accepted")
+	}
+	if err := validateAnonymousChannels(map[string]map[string]any{"fantasy": {}}, valid); err == nil {
+		t.Error("fantasy accepted without an account")
+	}
+	if err := validateAnonymousChannels(map[string]map[string]any{
+		"finance": {"symbols": []any{"A", "B", "C", "D", "E", "F"}},
+	}, valid); err == nil {
+		t.Error("config over the free-tier cap accepted")
+	}
+}
+
+func TestValidateAnonymousPriceAlerts(t *testing.T) {
+	alerts := []AnonymousPriceAlert{{Symbol: " aapl ", Condition: "above", Threshold: 250}}
+	if err := validateAnonymousPriceAlerts(alerts); err != nil {
+		t.Fatalf("valid alert rejected: %v", err)
+	}
+	if alerts[0].Symbol != "AAPL" {
+		t.Errorf("symbol = %q, want AAPL", alerts[0].Symbol)
+	}
+
+	for name, bad := range map[string]AnonymousPriceAlert{
+		"no symbol":     {Condition: "above", Threshold: 1},
+		"bad condition": {Symbol: "AAPL", Condition: "crosses", Threshold: 1},
+		"zero":          {Symbol: "AAPL", Condition: "below"},
+		"pct too large": {Symbol: "AAPL", Condition: "change_pct", Threshold: 150},
+	} {
+		if err := validateAnonymousPriceAlerts([]AnonymousPriceAlert{bad}); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if err := validateAnonymousPriceAlerts(make([]AnonymousPriceAlert, AnonMaxPriceAlerts+1)); err == nil {
+		t.Error("more than AnonMaxPriceAlerts accepted")
+	}
+}
+
+func TestMergeAnonymousSession_MovesSavedSearchesAndAlerts(t *testing.T) {
+	if !testDBAvailable(t) {
+		return
+	}
+	userID := makeTestUser()
+	ctx := context.Background()
+	defer func() {
+		DBPool.Exec(ctx, `DELETE FROM saved_searches WHERE logto_sub = $1`, userID)
+		DBPool.Exec(ctx, `DELETE FROM price_alerts WHERE logto_sub = $1`, userID)
+		cleanupTestUser(t, userID)
+	}()
+	mustExec(t, `INSERT INTO trades (symbol) VALUES ('CLAIMTEST') ON CONFLICT (symbol) DO NOTHING`)
+
+	sess := AnonymousSession{
+		Channels: map[string]map[string]any{},
+		SavedSearches: []savedSearchInput{
+			{Name: "Earnings", Query: "earnings beat"},
+		},
+		PriceAlerts: []AnonymousPriceAlert{
+			{Symbol: "CLAIMTEST", Condition: "above", Threshold: 10},
+			{Symbol: "NOT-TRACKED", Condition: "below", Threshold: 5},
+		},
+	}
+	got, err := mergeAnonymousSession(ctx, userID, "free", sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.savedSearches != 1 || got.priceAlerts != 1 {
+		t.Errorf("claimed %d searches and %d alerts, want 1 and 1 (untracked symbol skipped)", got.savedSearches, got.priceAlerts)
+	}
+
+	var searches, alerts int
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM saved_searches WHERE logto_sub = $1 AND name = 'Earnings'`, userID).Scan(&searches)
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM price_alerts WHERE logto_sub = $1 AND symbol = 'CLAIMTEST'`, userID).Scan(&alerts)
+	if searches != 1 || alerts != 1 {
+		t.Errorf("account has %d searches and %d alerts after claim, want 1 and 1", searches, alerts)
+	}
+
+	// Claiming a second session with the same search doesn't duplicate it.
+	got, err = mergeAnonymousSession(ctx, userID, "free", AnonymousSession{SavedSearches: sess.SavedSearches})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.savedSearches != 0 {
+		t.Errorf("re-claim inserted %d duplicate searches", got.savedSearches)
+	}
+}
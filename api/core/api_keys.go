@@ -0,0 +1,436 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// APIKey is the listing view of a key. The plaintext is only ever
+// returned once, from HandleCreateAPIKey.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// apiKeyOwner is what a key resolves to, and the Redis cache value.
+type apiKeyOwner struct {
+	Sub    string   `json:"sub"`
+	Scopes []string `json:"scopes"`
+}
+
+// hasScope reports whether the key may be used for scope.
+func (o apiKeyOwner) hasScope(scope string) bool {
+	for _, s := range o.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// errInvalidAPIKey is returned by resolveAPIKey for unknown or revoked keys.
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// validAPIKeyScopes is every scope a key can be minted with.
+var validAPIKeyScopes = map[string]bool{
+	APIKeyScopeChannels: true,
+	APIKeyScopeFeed:     true,
+}
+
+// parseAPIKeyScopes validates a create request's scopes, deduplicated and
+// sorted. Omitted means channels, what every key could do before scopes.
+func parseAPIKeyScopes(in []string) ([]string, error) {
+	if len(in) == 0 {
+		return []string{APIKeyScopeChannels}, nil
+	}
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		s = strings.TrimSpace(s)
+		if !validAPIKeyScopes[s] {
+			return nil, fmt.Errorf("unknown scope %q (valid: %s, %s)", s, APIKeyScopeChannels, APIKeyScopeFeed)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ─── Key Material ────────────────────────────────────────────────
+
+// generateAPIKey returns a new plaintext key of the form
+// "scrollr_<43 url-safe chars>" (32 random bytes).
+func generateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey is the at-rest form of a key. Plain SHA-256 is fine here:
+// keys are 256 bits of entropy, so there's nothing to brute-force.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyDisplayPrefix is the non-secret stub shown in the dashboard.
+func apiKeyDisplayPrefix(key string) string {
+	n := len(APIKeyPrefix) + 4
+	if len(key) < n {
+		return key
+	}
+	return key[:n]
+}
+
+// ─── Resolution (proxy hot path) ─────────────────────────────────
+
+// resolveAPIKey maps a plaintext key to its owner's logto_sub and the
+// key's scopes. Results are cached in Redis for APIKeyCacheTTL, so a
+// revoked key can keep working for up to that long unless the revoke
+// handler's cache DEL lands (it normally does). Misses are cached for
+// APIKeyMissCacheTTL.
+func resolveAPIKey(ctx context.Context, key string) (apiKeyOwner, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return apiKeyOwner{}, errInvalidAPIKey
+	}
+	hash := hashAPIKey(key)
+	cacheKey := RedisAPIKeyPrefix + hash
+	missKey := RedisAPIKeyMissPrefix + hash
+
+	if Rdb != nil {
+		if raw, err := Rdb.Get(ctx, cacheKey).Bytes(); err == nil {
+			var owner apiKeyOwner
+			if json.Unmarshal(raw, &owner) == nil && owner.Sub != "" {
+				return owner, nil
+			}
+		}
+		if n, err := Rdb.Exists(ctx, missKey).Result(); err == nil && n > 0 {
+			return apiKeyOwner{}, errInvalidAPIKey
+		}
+	}
+
+	var id int64
+	var owner apiKeyOwner
+	err := DBPool.QueryRow(ctx, `
+		SELECT id, logto_sub, scopes FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, hash).Scan(&id, &owner.Sub, &owner.Scopes)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) && Rdb != nil {
+			Rdb.Set(ctx, missKey, "1", APIKeyMissCacheTTL)
+		}
+		return apiKeyOwner{}, errInvalidAPIKey
+	}
+
+	if Rdb != nil {
+		if data, err := json.Marshal(owner); err == nil {
+			Rdb.Set(ctx, cacheKey, data, APIKeyCacheTTL)
+		}
+	}
+
+	// last_used_at is advisory; only write it on a cache miss (at most
+	// once per TTL per key) to keep the hot path read-only.
+	go func() {
+		bg, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := DBPool.Exec(bg,
+			`UPDATE api_keys SET last_used_at = now() WHERE id = $1`, id,
+		); err != nil {
+			log.Printf("[APIKeys] last_used_at update failed for key %d: %v", id, err)
+		}
+	}()
+
+	return owner, nil
+}
+
+// apiKeyRateLimits is the per-window request budget of each scope's
+// bucket. A key with both scopes has two independent budgets.
+var apiKeyRateLimits = map[string]int64{
+	APIKeyScopeChannels: APIKeyRateLimitMax,
+	APIKeyScopeFeed:     PublicFeedKeyRateLimitMax,
+}
+
+// allowAPIKeyRequest applies the per-key fixed-window rate limit for
+// scope's bucket. Soft-fails open on Redis errors, like the other
+// Redis-backed counters.
+func allowAPIKeyRequest(ctx context.Context, key, scope string) bool {
+	if Rdb == nil {
+		return true
+	}
+	rlKey := RedisAPIKeyRLPrefix + scope + ":" + hashAPIKey(key)[:16]
+	count, err := Rdb.Incr(ctx, rlKey).Result()
+	if err != nil {
+		log.Printf("[APIKeys] rate limit INCR failed (allowing): %v", err)
+		return true
+	}
+	if count == 1 {
+		Rdb.Expire(ctx, rlKey, APIKeyRateLimitWindow)
+	}
+	return count <= apiKeyRateLimits[scope]
+}
+
+// ValidateAPIKey authenticates a proxied request by its X-API-Key
+// header, setting user_id like ValidateAuth does. On failure it writes
+// the error response and returns false; the caller must stop there and
+// return the error, which is only the write's.
+func ValidateAPIKey(c *fiber.Ctx) (bool, error) {
+	key := strings.TrimSpace(c.Get(APIKeyHeader))
+	if key == "" {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Missing " + APIKeyHeader + " header",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	owner, err := resolveAPIKey(ctx, key)
+	if err != nil {
+		return false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Invalid API key",
+		})
+	}
+	if !owner.hasScope(APIKeyScopeChannels) {
+		return false, c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "API key lacks the " + APIKeyScopeChannels + " scope",
+		})
+	}
+	if !allowAPIKeyRequest(ctx, key, APIKeyScopeChannels) {
+		c.Set("Retry-After", strconv.Itoa(int(APIKeyRateLimitWindow.Seconds())))
+		return false, c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "API key rate limit exceeded",
+		})
+	}
+
+	if capped, ceiling := recordAPIKeyUsage(ctx, owner.Sub); capped {
+		_, periodEnd := apiUsagePeriodBounds(time.Now())
+		c.Set("Retry-After", strconv.Itoa(int(time.Until(periodEnd).Seconds())))
+		return false, c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Monthly API usage ceiling of %d requests reached", ceiling),
+		})
+	}
+
+	c.Locals("user_id", owner.Sub)
+	return true, nil
+}
+
+// ─── Management Handlers ─────────────────────────────────────────
+
+// HandleListAPIKeys returns the caller's active keys (never the secret).
+//
+// @Summary List API keys
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{api_keys=[]APIKey}
+// @Security LogtoAuth
+// @Router /users/me/api-keys [get]
+func HandleListAPIKeys(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT id, name, key_prefix, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE logto_sub = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[APIKeys] list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list API keys",
+		})
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.KeyPrefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt); err != nil {
+			log.Printf("[APIKeys] scan error: %v", err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return c.JSON(fiber.Map{"api_keys": keys})
+}
+
+// HandleCreateAPIKey mints a key. The plaintext is in this response and
+// nowhere else — it is not recoverable afterwards. scopes defaults to
+// ["channels"]; a key for an overlay should ask for ["feed"] only.
+//
+// @Summary Create API key
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body object{name=string,scopes=[]string} true "Key label and scopes (channels, feed)"
+// @Success 201 {object} object{api_key=APIKey,key=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/api-keys [post]
+func HandleCreateAPIKey(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Name must be 1-64 characters",
+		})
+	}
+	scopes, err := parseAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	var active int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM api_keys WHERE logto_sub = $1 AND revoked_at IS NULL`, userID,
+	).Scan(&active); err != nil {
+		log.Printf("[APIKeys] count failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create API key",
+		})
+	}
+	if active >= APIKeyMaxPerUser {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("You can have at most %d active API keys; revoke one first", APIKeyMaxPerUser),
+		})
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		log.Printf("[APIKeys] generate failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create API key",
+		})
+	}
+
+	k := APIKey{Name: req.Name, KeyPrefix: apiKeyDisplayPrefix(key), Scopes: scopes}
+	if err := DBPool.QueryRow(ctx, `
+		INSERT INTO api_keys (logto_sub, name, key_prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userID, k.Name, k.KeyPrefix, hashAPIKey(key), k.Scopes).Scan(&k.ID, &k.CreatedAt); err != nil {
+		log.Printf("[APIKeys] insert failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create API key",
+		})
+	}
+
+	log.Printf("[APIKeys] Created key %d for user=%s scopes=%v", k.ID, userID, k.Scopes)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"api_key": k, "key": key})
+}
+
+// HandleRevokeAPIKey revokes one of the caller's keys and drops its
+// resolution cache entry so it stops working immediately.
+//
+// @Summary Revoke API key
+// @Tags Users
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/api-keys/{id} [delete]
+func HandleRevokeAPIKey(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid API key id",
+		})
+	}
+
+	ctx := c.Context()
+	var hash string
+	err = DBPool.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND logto_sub = $2 AND revoked_at IS NULL
+		RETURNING key_hash
+	`, id, userID).Scan(&hash)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "API key not found",
+		})
+	}
+
+	if Rdb != nil {
+		if err := Rdb.Del(ctx, RedisAPIKeyPrefix+hash).Err(); err != nil && err != redis.Nil {
+			log.Printf("[APIKeys] cache DEL failed for key %d (expires in %s): %v", id, APIKeyCacheTTL, err)
+		}
+	}
+
+	log.Printf("[APIKeys] Revoked key %d for user=%s", id, userID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
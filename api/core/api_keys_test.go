@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParseAPIKeyScopes(t *testing.T) {
+	got, err := parseAPIKeyScopes(nil)
+	if err != nil || !reflect.DeepEqual(got, []string{APIKeyScopeChannels}) {
+		t.Errorf("omitted scopes = %v, %v; want [channels]", got, err)
+	}
+
+	got, err = parseAPIKeyScopes([]string{"feed", "channels", "feed"})
+	if err != nil || !reflect.DeepEqual(got, []string{"channels", "feed"}) {
+		t.Errorf("got %v, %v; want sorted and deduplicated", got, err)
+	}
+
+	if _, err := parseAPIKeyScopes([]string{"feed", "admin"}); err == nil {
+		t.Error("unknown scope accepted")
+	}
+}
+
+func TestAPIKeyOwnerHasScope(t *testing.T) {
+	feedOnly := apiKeyOwner{Sub: "u1", Scopes: []string{APIKeyScopeFeed}}
+	if !feedOnly.hasScope(APIKeyScopeFeed) || feedOnly.hasScope(APIKeyScopeChannels) {
+		t.Errorf("feed-only key scopes wrong: %+v", feedOnly)
+	}
+	if (apiKeyOwner{Sub: "u1"}).hasScope(APIKeyScopeChannels) {
+		t.Error("key without scopes has channels")
+	}
+}
+
+func TestAPIKeyRateLimitsCoverEveryScope(t *testing.T) {
+	for scope := range validAPIKeyScopes {
+		if apiKeyRateLimits[scope] <= 0 {
+			t.Errorf("scope %q has no rate limit bucket", scope)
+		}
+	}
+}
+
+func TestBuildUserPublicFeed(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	dash := DashboardResponse{
+		Data: map[string]interface{}{
+			"finance": "quotes",
+			"sports":  "scores",
+			"discord": nil,
+		},
+		Channels: []Channel{
+			{ChannelType: "finance", Enabled: true, Visible: true},
+			{ChannelType: "sports", Enabled: true, Visible: false},
+			{ChannelType: "discord", Enabled: true, Visible: true, Config: map[string]interface{}{
+				"webhook_url": "https://discord.com/api/webhooks/1/secret",
+			}},
+		},
+	}
+
+	got := buildUserPublicFeed(dash, now)
+	if !reflect.DeepEqual(got.Channels, []string{"finance", "discord"}) {
+		t.Errorf("channels = %v", got.Channels)
+	}
+	if got.Data["finance"] != "quotes" || got.Data["sports"] != nil {
+		t.Errorf("data = %v, want finance only", got.Data)
+	}
+	if got.GeneratedAt != now.UnixMilli() {
+		t.Errorf("generated_at = %d", got.GeneratedAt)
+	}
+
+	body, _ := json.Marshal(got)
+	if strings.Contains(string(body), "secret") {
+		t.Errorf("feed leaks channel config: %s", body)
+	}
+}
+
+func TestResolveAPIKeyRemembersMisses(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+
+	// A remembered miss is answered without touching Postgres (DBPool is
+	// nil here and would panic).
+	key := APIKeyPrefix + "unknown"
+	mr.Set(RedisAPIKeyMissPrefix+hashAPIKey(key), "1")
+	if _, err := resolveAPIKey(context.Background(), key); err != errInvalidAPIKey {
+		t.Errorf("err = %v, want errInvalidAPIKey", err)
+	}
+}
+
+func TestPublicFeedKeyOwnerOnlyForResolvedKeys(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+
+	good := APIKeyPrefix + "good"
+	data, _ := json.Marshal(apiKeyOwner{Sub: "owner-1", Scopes: []string{APIKeyScopeFeed}})
+	mr.Set(RedisAPIKeyPrefix+hashAPIKey(good), string(data))
+	bad := APIKeyPrefix + "bad"
+	mr.Set(RedisAPIKeyMissPrefix+hashAPIKey(bad), "1")
+
+	app := fiber.New()
+	app.Get("/public/feed", func(c *fiber.Ctx) error {
+		owner, ok := publicFeedKeyOwner(c)
+		if !ok {
+			return c.SendString("-")
+		}
+		return c.SendString(owner.Sub)
+	})
+	for _, tc := range []struct{ query, want string }{
+		{"", "-"},
+		{"?key=not-a-key", "-"},
+		{"?key=" + bad, "-"},
+		{"?key=" + good, "owner-1"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/public/feed"+tc.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != tc.want {
+			t.Errorf("%q: owner = %q, want %q", tc.query, body, tc.want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/billing/meterevent"
+	stripeprice "github.com/stripe/stripe-go/v82/price"
+)
+
+// Metered billing for API keys.
+//
+// Every API-key request is counted per user per calendar month (UTC) in
+// Redis. Each user gets APIUsageIncludedPerMonth requests free; beyond
+// that, subscribers are billed per request through a Stripe billing
+// meter (STRIPE_API_METER_EVENT — meter events replace the legacy usage
+// records API) and everyone else is hard-capped at the allowance.
+// Subscribers are capped too, at API_USAGE_MONTHLY_CAP requests, so a
+// runaway script can't run up an unbounded invoice.
+//
+// A background reporter flushes counts to api_usage and sends overage
+// deltas to Stripe every APIUsageReportInterval.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// APIUsageResponse is the body for GET /users/me/billing/usage.
+// ProjectedCharge is in the currency's minor unit.
+type APIUsageResponse struct {
+	Period          string    `json:"period"`
+	PeriodStart     time.Time `json:"period_start"`
+	PeriodEnd       time.Time `json:"period_end"`
+	Requests        int64     `json:"requests"`
+	Included        int64     `json:"included"`
+	OverageUnits    int64     `json:"overage_units"`
+	ReportedUnits   int64     `json:"reported_units"`
+	Metered         bool      `json:"metered"`
+	Ceiling         int64     `json:"ceiling"`
+	Capped          bool      `json:"capped"`
+	UnitAmount      float64   `json:"unit_amount,omitempty"`
+	Currency        string    `json:"currency,omitempty"`
+	ProjectedCharge int64     `json:"projected_charge"`
+}
+
+var (
+	overagePriceMu      sync.Mutex
+	overagePriceCache   *stripe.Price
+	overagePriceExpires time.Time
+)
+
+// ─── Pure helpers ────────────────────────────────────────────────
+
+// apiUsagePeriod returns the usage period key ("2026-10") for t.
+func apiUsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// apiUsagePeriodBounds returns [start, end) of the month containing t.
+func apiUsagePeriodBounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// overageUnits is the billable part of a month's request count.
+func overageUnits(requests, included int64) int64 {
+	if requests <= included {
+		return 0
+	}
+	return requests - included
+}
+
+// projectOverageCharge prices overage units against a per-unit Stripe
+// price, honouring transform_quantity (e.g. "per 1,000 requests").
+// Tiered prices aren't supported and project as zero.
+func projectOverageCharge(units int64, price *stripe.Price) int64 {
+	if price == nil || units <= 0 || len(price.Tiers) > 0 {
+		return 0
+	}
+	qty := float64(units)
+	if tq := price.TransformQuantity; tq != nil && tq.DivideBy > 0 {
+		q := units / tq.DivideBy
+		if tq.Round == stripe.PriceTransformQuantityRoundUp && units%tq.DivideBy != 0 {
+			q++
+		}
+		qty = float64(q)
+	}
+	unit := price.UnitAmountDecimal
+	if unit == 0 {
+		unit = float64(price.UnitAmount)
+	}
+	return int64(math.Round(qty * unit))
+}
+
+// apiUsageMonthlyCap is the ceiling for metered users.
+func apiUsageMonthlyCap() int64 {
+	if v := strings.TrimSpace(os.Getenv("API_USAGE_MONTHLY_CAP")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("[APIUsage] Invalid API_USAGE_MONTHLY_CAP %q, using default", v)
+	}
+	return APIUsageDefaultMonthlyCap
+}
+
+func apiUsageMeterEvent() string {
+	return strings.TrimSpace(os.Getenv("STRIPE_API_METER_EVENT"))
+}
+
+// ─── Metering (proxy hot path) ───────────────────────────────────
+
+func apiUsageKey(sub, period string) string {
+	return RedisAPIUsagePrefix + sub + ":" + period
+}
+
+// apiUsageMetered reports whether overage can be billed to the user:
+// metering is configured and they have a live subscription. Cached in
+// Redis so the hot path doesn't hit Postgres per request.
+func apiUsageMetered(ctx context.Context, sub string) bool {
+	if apiUsageMeterEvent() == "" {
+		return false
+	}
+	cacheKey := RedisAPIUsageMeteredPrefix + sub
+	if Rdb != nil {
+		if v, err := Rdb.Get(ctx, cacheKey).Result(); err == nil {
+			return v == "1"
+		}
+	}
+
+	var metered bool
+	_ = DBPool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM stripe_customers
+			 WHERE logto_sub = $1 AND stripe_subscription_id IS NOT NULL
+			   AND status IN ('active', 'trialing', 'canceling'))`, sub,
+	).Scan(&metered)
+
+	if Rdb != nil {
+		v := "0"
+		if metered {
+			v = "1"
+		}
+		Rdb.Set(ctx, cacheKey, v, APIUsageMeteredCacheTTL)
+	}
+	return metered
+}
+
+// apiUsageCeiling returns the monthly request ceiling for a user.
+func apiUsageCeiling(ctx context.Context, sub string) int64 {
+	if apiUsageMetered(ctx, sub) {
+		return apiUsageMonthlyCap()
+	}
+	return APIUsageIncludedPerMonth
+}
+
+// recordAPIKeyUsage counts one request against the user's month and
+// reports whether they're over their ceiling. Rejected requests are
+// un-counted so they're never billed. Soft-fails open on Redis errors.
+func recordAPIKeyUsage(ctx context.Context, sub string) (capped bool, ceiling int64) {
+	if Rdb == nil {
+		return false, 0
+	}
+	period := apiUsagePeriod(time.Now())
+	key := apiUsageKey(sub, period)
+
+	pipe := Rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, APIUsageCounterTTL)
+	pipe.SAdd(ctx, RedisAPIUsageActivePrefix+period, sub)
+	pipe.Expire(ctx, RedisAPIUsageActivePrefix+period, APIUsageCounterTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[APIUsage] count failed for %s (allowing): %v", sub, err)
+		return false, 0
+	}
+
+	ceiling = apiUsageCeiling(ctx, sub)
+	if incr.Val() > ceiling {
+		Rdb.Decr(ctx, key)
+		return true, ceiling
+	}
+	return false, ceiling
+}
+
+// ─── Reporter ────────────────────────────────────────────────────
+
+// StartAPIUsageReporter flushes API-key usage to Postgres and Stripe on
+// an interval for the lifetime of ctx. Replicas coordinate through a
+// Redis lock so each pass runs once.
+func StartAPIUsageReporter(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(APIUsageReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runAPIUsageReportPass(ctx)
+			}
+		}
+	}()
+	log.Printf("[APIUsage] Usage reporter started (%s interval)", APIUsageReportInterval)
+}
+
+// runAPIUsageReportPass flushes the current month and the previous one,
+// so usage from the last minutes of a month still gets reported after
+// rollover. A previous-month user is dropped from the active set once
+// fully reported.
+func runAPIUsageReportPass(ctx context.Context) {
+	ok, err := Rdb.SetNX(ctx, RedisAPIUsageReportLock, "1", APIUsageReportInterval/2).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	now := time.Now()
+	current := apiUsagePeriod(now)
+	start, _ := apiUsagePeriodBounds(now)
+	previous := apiUsagePeriod(start.Add(-time.Hour))
+
+	for _, period := range []string{previous, current} {
+		subs, err := Rdb.SMembers(ctx, RedisAPIUsageActivePrefix+period).Result()
+		if err != nil {
+			log.Printf("[APIUsage] list active users for %s failed: %v", period, err)
+			continue
+		}
+		for _, sub := range subs {
+			done := flushAPIUsage(ctx, sub, period, now)
+			if done && period == previous {
+				Rdb.SRem(ctx, RedisAPIUsageActivePrefix+period, sub)
+			}
+		}
+	}
+}
+
+// flushAPIUsage persists one user's count for a period and reports any
+// unreported overage. Returns true when nothing is left to report.
+func flushAPIUsage(ctx context.Context, sub, period string, now time.Time) bool {
+	count, err := Rdb.Get(ctx, apiUsageKey(sub, period)).Int64()
+	if err != nil && err != redis.Nil {
+		log.Printf("[APIUsage] read count for %s/%s failed: %v", sub, period, err)
+		return false
+	}
+
+	var reported int64
+	err = DBPool.QueryRow(ctx, `
+		INSERT INTO api_usage (logto_sub, period, request_count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (logto_sub, period) DO UPDATE SET
+			request_count = GREATEST(api_usage.request_count, EXCLUDED.request_count),
+			updated_at    = now()
+		RETURNING request_count, reported_units`,
+		sub, period, count,
+	).Scan(&count, &reported)
+	if err != nil {
+		log.Printf("[APIUsage] persist %s/%s failed: %v", sub, period, err)
+		return false
+	}
+
+	overage := overageUnits(count, APIUsageIncludedPerMonth)
+	delta := overage - reported
+	if delta <= 0 {
+		return true
+	}
+
+	eventName := apiUsageMeterEvent()
+	var customerID string
+	_ = DBPool.QueryRow(ctx,
+		`SELECT stripe_customer_id FROM stripe_customers WHERE logto_sub = $1`, sub,
+	).Scan(&customerID)
+	if eventName == "" || customerID == "" {
+		log.Printf("[APIUsage] %s/%s has %d unbillable overage units", sub, period, delta)
+		return true
+	}
+
+	// Stamp late reports for a closed month at its last second so they
+	// land in that month's invoice when Stripe still allows it.
+	ts := now
+	if period != apiUsagePeriod(now) {
+		start, _ := apiUsagePeriodBounds(now)
+		ts = start.Add(-time.Second)
+	}
+
+	_, err = meterevent.New(&stripe.BillingMeterEventParams{
+		EventName: stripe.String(eventName),
+		// Same identifier on retry, so Stripe dedupes a report whose
+		// acknowledgement we lost.
+		Identifier: stripe.String(fmt.Sprintf("api-%s-%s-%d", sub, period, overage)),
+		Payload: map[string]string{
+			"stripe_customer_id": customerID,
+			"value":              strconv.FormatInt(delta, 10),
+		},
+		Timestamp: stripe.Int64(ts.Unix()),
+	})
+	if err != nil {
+		log.Printf("[APIUsage] meter event for %s/%s (%d units) failed: %v", sub, period, delta, err)
+		return false
+	}
+
+	if _, err := DBPool.Exec(ctx, `
+		UPDATE api_usage SET reported_units = $3, updated_at = now()
+		 WHERE logto_sub = $1 AND period = $2`, sub, period, overage); err != nil {
+		log.Printf("[APIUsage] record reported units for %s/%s failed: %v", sub, period, err)
+		return false
+	}
+	log.Printf("[APIUsage] Reported %d overage units for %s/%s", delta, sub, period)
+	return true
+}
+
+// overagePrice returns the configured overage price, cached for an hour.
+func overagePrice() *stripe.Price {
+	priceID := os.Getenv("STRIPE_PRICE_API_OVERAGE")
+	if priceID == "" {
+		return nil
+	}
+	overagePriceMu.Lock()
+	defer overagePriceMu.Unlock()
+	if overagePriceCache != nil && time.Now().Before(overagePriceExpires) {
+		return overagePriceCache
+	}
+	p, err := stripeprice.Get(priceID, nil)
+	if err != nil {
+		log.Printf("[APIUsage] fetch overage price %s failed: %v", priceID, err)
+		return overagePriceCache
+	}
+	overagePriceCache = p
+	overagePriceExpires = time.Now().Add(time.Hour)
+	return p
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// HandleGetAPIUsage returns the caller's API-key usage for the current
+// month and the projected overage charge.
+//
+// @Summary Get API usage
+// @Tags Billing
+// @Produce json
+// @Success 200 {object} APIUsageResponse
+// @Security LogtoAuth
+// @Router /users/me/billing/usage [get]
+func HandleGetAPIUsage(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	now := time.Now()
+	period := apiUsagePeriod(now)
+	start, end := apiUsagePeriodBounds(now)
+
+	var dbCount, reported int64
+	err := DBPool.QueryRow(ctx,
+		`SELECT request_count, reported_units FROM api_usage WHERE logto_sub = $1 AND period = $2`,
+		userID, period,
+	).Scan(&dbCount, &reported)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Printf("[APIUsage] usage query for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load usage",
+		})
+	}
+
+	// Redis has the live count; the table lags by up to one report pass.
+	requests := dbCount
+	if n, err := Rdb.Get(ctx, apiUsageKey(userID, period)).Int64(); err == nil && n > requests {
+		requests = n
+	}
+
+	metered := apiUsageMetered(ctx, userID)
+	ceiling := apiUsageCeiling(ctx, userID)
+	resp := APIUsageResponse{
+		Period:        period,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		Requests:      requests,
+		Included:      APIUsageIncludedPerMonth,
+		OverageUnits:  overageUnits(requests, APIUsageIncludedPerMonth),
+		ReportedUnits: reported,
+		Metered:       metered,
+		Ceiling:       ceiling,
+		Capped:        requests >= ceiling,
+	}
+	if metered {
+		if p := overagePrice(); p != nil {
+			resp.UnitAmount = p.UnitAmountDecimal
+			resp.Currency = string(p.Currency)
+			resp.ProjectedCharge = projectOverageCharge(resp.OverageUnits, p)
+		}
+	}
+	return c.JSON(resp)
+}
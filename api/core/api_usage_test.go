@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+func TestAPIUsagePeriodBounds(t *testing.T) {
+	ts := time.Date(2026, time.December, 31, 23, 59, 0, 0, time.UTC)
+	start, end := apiUsagePeriodBounds(ts)
+	if !start.Equal(time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("start = %v", start)
+	}
+	if !end.Equal(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("end = %v", end)
+	}
+	if got := apiUsagePeriod(ts); got != "2026-12" {
+		t.Errorf("period = %q, want 2026-12", got)
+	}
+}
+
+func TestOverageUnits(t *testing.T) {
+	if got := overageUnits(500, 1000); got != 0 {
+		t.Errorf("under allowance: got %d, want 0", got)
+	}
+	if got := overageUnits(1500, 1000); got != 500 {
+		t.Errorf("over allowance: got %d, want 500", got)
+	}
+}
+
+func TestProjectOverageCharge(t *testing.T) {
+	tests := []struct {
+		name  string
+		units int64
+		price *stripe.Price
+		want  int64
+	}{
+		{"no price", 100, nil, 0},
+		{"no units", 0, &stripe.Price{UnitAmount: 1}, 0},
+		{"fractional cents", 1000, &stripe.Price{UnitAmountDecimal: 0.05}, 50},
+		{"whole cents fallback", 10, &stripe.Price{UnitAmount: 2}, 20},
+		{"per thousand rounded up", 1500, &stripe.Price{
+			UnitAmountDecimal: 40,
+			TransformQuantity: &stripe.PriceTransformQuantity{DivideBy: 1000, Round: stripe.PriceTransformQuantityRoundUp},
+		}, 80},
+		{"per thousand rounded down", 1500, &stripe.Price{
+			UnitAmountDecimal: 40,
+			TransformQuantity: &stripe.PriceTransformQuantity{DivideBy: 1000, Round: stripe.PriceTransformQuantityRoundDown},
+		}, 40},
+		{"tiered unsupported", 100, &stripe.Price{Tiers: []*stripe.PriceTier{{}}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := projectOverageCharge(tt.units, tt.price); got != tt.want {
+				t.Errorf("projectOverageCharge(%d) = %d, want %d", tt.units, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIUsageMonthlyCap(t *testing.T) {
+	t.Setenv("API_USAGE_MONTHLY_CAP", "")
+	if got := apiUsageMonthlyCap(); got != APIUsageDefaultMonthlyCap {
+		t.Errorf("default = %d", got)
+	}
+	t.Setenv("API_USAGE_MONTHLY_CAP", "50000")
+	if got := apiUsageMonthlyCap(); got != 50000 {
+		t.Errorf("configured = %d, want 50000", got)
+	}
+	t.Setenv("API_USAGE_MONTHLY_CAP", "-1")
+	if got := apiUsageMonthlyCap(); got != APIUsageDefaultMonthlyCap {
+		t.Errorf("invalid should fall back, got %d", got)
+	}
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Audit events.
+//
+// A single audit_events table records security-relevant activity:
+// failed sign-ins, Yahoo links (written by the fantasy channel), billing
+// changes, channel CRUD and admin actions. Each event names the account
+// it concerns (target) so users can review their own account activity
+// at GET /users/me/audit. billing_audit_log and admin_audit keep their
+// detailed admin-only trails (notes, reasons); the same actions show up
+// here in summary as admin.request and admin.viewed_account.
+
+// AuditActorSystem is the actor for events nobody signed in caused
+// directly: Stripe webhooks and background workers.
+const AuditActorSystem = "system"
+
+// Audit actions.
+const (
+	AuditAuthFailed           = "auth.failed"
+	AuditChannelCreated       = "channel.created"
+	AuditChannelUpdated       = "channel.updated"
+	AuditChannelDeleted       = "channel.deleted"
+	AuditBillingSubscribed    = "billing.subscribed"
+	AuditBillingPlanChanged   = "billing.plan_changed"
+	AuditBillingCanceled      = "billing.canceled"
+	AuditBillingEnded         = "billing.subscription_ended"
+	AuditAdminRequest         = "admin.request"
+	AuditAdminViewedAccount   = "admin.viewed_account"
+	AuditAccountDeleteRequest = "account.deletion_requested"
+	AuditAccountDeleteCancel  = "account.deletion_canceled"
+)
+
+// AuditEvent is one row of GET /users/me/audit. Actor is relative to
+// the caller ("you", "support" or "system"); admin identities are not
+// disclosed.
+type AuditEvent struct {
+	ID        int64           `json:"id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Meta      json.RawMessage `json:"meta"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Audit records an event. actor is the acting user's sub,
+// AuditActorSystem, or "" when unauthenticated; target is the account
+// the event concerns ("" if none). Failures are logged, never
+// returned: auditing must not fail the action it records.
+func Audit(ctx context.Context, actor, action, target string, meta map[string]any) {
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		log.Printf("[Audit] %s meta not serialisable: %v", action, err)
+		metaJSON = []byte("{}")
+	}
+	if _, err := DBPool.Exec(ctx, `
+		INSERT INTO audit_events (actor_sub, action, target_sub, meta)
+		VALUES (NULLIF($1, ''), $2, NULLIF($3, ''), $4)`,
+		actor, action, target, metaJSON); err != nil {
+		log.Printf("[Audit] write failed (%s on %s): %v", action, target, err)
+	}
+}
+
+// AuditSelf records an action the signed-in user took on their own
+// account, with the client IP.
+func AuditSelf(c *fiber.Ctx, action string, meta map[string]any) {
+	userID := GetUserID(c)
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	meta["ip"] = c.IP()
+	Audit(c.UserContext(), userID, action, userID, meta)
+}
+
+// auditAuthFailure records a rejected token, at most once per client IP
+// per AuthFailureAuditWindow. The token's claims are untrusted, so the
+// event has no actor or target.
+func auditAuthFailure(c *fiber.Ctx, reason string) {
+	if Rdb == nil || DBPool == nil {
+		return
+	}
+	ip := c.IP()
+	ok, err := Rdb.SetNX(c.UserContext(), RedisAuthFailureAuditPrefix+ip, "1", AuthFailureAuditWindow).Result()
+	if err != nil || !ok {
+		return
+	}
+	Audit(c.UserContext(), "", AuditAuthFailed, "", map[string]any{
+		"ip":     ip,
+		"path":   c.Path(),
+		"reason": reason,
+	})
+}
+
+// auditActorLabel describes an event's actor to the account owner.
+func auditActorLabel(actor *string, owner string) string {
+	switch {
+	case actor == nil || *actor == AuditActorSystem:
+		return "system"
+	case *actor == owner:
+		return "you"
+	default:
+		return "support"
+	}
+}
+
+// HandleListMyAudit returns the caller's account activity, newest first.
+// Page with ?before=<id of the last event seen>.
+//
+// @Summary Account activity
+// @Description Security-relevant events on the caller's account: sign-in, billing, channel and support activity
+// @Tags Users
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param before query int false "Return events older than this ID"
+// @Success 200 {object} object{events=[]AuditEvent,next_before=int}
+// @Security LogtoAuth
+// @Router /users/me/audit [get]
+func HandleListMyAudit(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	limit := c.QueryInt("limit", AuditListDefault)
+	if limit <= 0 || limit > AuditListMax {
+		limit = AuditListMax
+	}
+	before, _ := strconv.ParseInt(c.Query("before"), 10, 64)
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT id, actor_sub, action, meta, created_at
+		  FROM audit_events
+		 WHERE target_sub = $1 AND ($2::BIGINT = 0 OR id < $2)
+		 ORDER BY id DESC
+		 LIMIT $3`, userID, before, limit)
+	if err != nil {
+		log.Printf("[Audit] list for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load account activity",
+		})
+	}
+	defer rows.Close()
+
+	events := make([]AuditEvent, 0)
+	for rows.Next() {
+		var e AuditEvent
+		var actor *string
+		if err := rows.Scan(&e.ID, &actor, &e.Action, &e.Meta, &e.CreatedAt); err != nil {
+			log.Printf("[Audit] scan failed: %v", err)
+			continue
+		}
+		e.Actor = auditActorLabel(actor, userID)
+		events = append(events, e)
+	}
+
+	resp := fiber.Map{"events": events}
+	if len(events) == limit {
+		resp["next_before"] = events[len(events)-1].ID
+	}
+	return c.JSON(resp)
+}
+
+// StartAuditPruner deletes audit events older than AuditRetention once
+// a day.
+func StartAuditPruner(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(AuditPruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tag, err := DBPool.Exec(ctx,
+					`DELETE FROM audit_events WHERE created_at < $1`,
+					time.Now().Add(-AuditRetention))
+				if err != nil {
+					log.Printf("[Audit] prune failed: %v", err)
+				} else if n := tag.RowsAffected(); n > 0 {
+					log.Printf("[Audit] pruned %d event(s)", n)
+				}
+			}
+		}
+	}()
+}
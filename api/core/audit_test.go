@@ -0,0 +1,20 @@
+package core
+
+import "testing"
+
+func TestAuditActorLabel(t *testing.T) {
+	self, admin, system := "u1", "admin1", AuditActorSystem
+	for _, tc := range []struct {
+		actor *string
+		want  string
+	}{
+		{nil, "system"},
+		{&system, "system"},
+		{&self, "you"},
+		{&admin, "support"},
+	} {
+		if got := auditActorLabel(tc.actor, "u1"); got != tc.want {
+			t.Errorf("auditActorLabel(%v) = %q, want %q", tc.actor, got, tc.want)
+		}
+	}
+}
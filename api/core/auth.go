@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -100,8 +101,10 @@ func ValidateToken(tokenString string) (sub string, claims jwt.MapClaims, err er
 // ValidateAuth extracts and validates the JWT from the request, setting
 // user_id and user_roles in c.Locals. It does NOT call c.Next(), making it
 // safe to use inline (e.g. from the dynamic proxy) without advancing
-// Fiber's handler chain.
-func ValidateAuth(c *fiber.Ctx) error {
+// Fiber's handler chain. On failure it writes the 401 and returns false;
+// the caller must stop there and return the error, which is only the
+// write's.
+func ValidateAuth(c *fiber.Ctx) (bool, error) {
 	tokenString := ""
 	authHeader := c.Get("Authorization")
 
@@ -117,7 +120,7 @@ func ValidateAuth(c *fiber.Ctx) error {
 	}
 
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+		return false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Status: "unauthorized",
 			Error:  "Missing authentication",
 		})
@@ -126,7 +129,12 @@ func ValidateAuth(c *fiber.Ctx) error {
 	sub, claims, err := ValidateToken(tokenString)
 	if err != nil {
 		log.Printf("[Auth Error] %v", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+		// Expired tokens are routine (clients refresh on 401); anything
+		// else is a forged, foreign or malformed token worth a record.
+		if !errors.Is(err, jwt.ErrTokenExpired) {
+			auditAuthFailure(c, err.Error())
+		}
+		return false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Status: "unauthorized",
 			Error:  "Invalid or expired token",
 		})
@@ -175,15 +183,76 @@ func ValidateAuth(c *fiber.Ctx) error {
 		c.Locals("user_username", "")
 	}
 
-	return nil
+	return true, nil
 }
 
 // LogtoAuth is the Fiber middleware that validates the Logto JWT and advances
 // to the next handler. For inline auth checks (e.g. in the dynamic proxy),
 // use ValidateAuth instead.
 func LogtoAuth(c *fiber.Ctx) error {
-	if err := ValidateAuth(c); err != nil {
+	if ok, err := ValidateAuth(c); !ok {
 		return err
 	}
 	return c.Next()
 }
+
+// OptionalLogtoAuth validates the Logto JWT when one is presented and
+// otherwise lets the request through anonymously. Used by endpoints that
+// accept both signed-in and signed-out callers (e.g. POST /feedback) but
+// want the identity when it's available. A token that IS presented but
+// fails validation still 401s — silently downgrading a broken session to
+// anonymous would hide auth bugs from the client.
+func OptionalLogtoAuth(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" && c.Cookies("access_token") == "" {
+		return c.Next()
+	}
+	return LogtoAuth(c)
+}
+
+// RequireTier gates a route on the caller's plan. Must be chained AFTER
+// LogtoAuth. Callers below the required tier get a 402 naming the plan
+// they need, so clients can route straight to the upgrade flow. Panics
+// at route registration on an unknown tier slug.
+func RequireTier(tier string) fiber.Handler {
+	if _, ok := tierOrder[tier]; !ok {
+		panic(fmt.Sprintf("RequireTier: unknown tier %q", tier))
+	}
+	return func(c *fiber.Ctx) error {
+		current := tierFromRoles(GetUserRoles(c))
+		if !tierAtLeast(current, tier) {
+			return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+				"status": "upgrade_required",
+				"error":  fmt.Sprintf("This feature requires the %s plan.", TierDisplayName(tier)),
+				"detail": fiber.Map{
+					"tier":     current,
+					"required": tier,
+				},
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSuperUser gates admin-only routes. Must be chained AFTER
+// LogtoAuth so user_roles is populated. The super_user role is assigned
+// by hand in the Logto console (see invite.go) — there is no self-serve
+// path to it.
+func RequireSuperUser(c *fiber.Ctx) error {
+	if !HasRole(c, "super_user") {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "Admin access required",
+		})
+	}
+	err := c.Next()
+	// Admin writes go to the audit log, against the user the route
+	// names (if any) so it shows in their account activity.
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		Audit(c.UserContext(), GetUserID(c), AuditAdminRequest, c.Params("sub"), map[string]any{
+			"method": c.Method(),
+			"route":  c.Route().Path,
+			"status": c.Response().StatusCode(),
+		})
+	}
+	return err
+}
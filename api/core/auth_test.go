@@ -0,0 +1,107 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLogtoAuthStopsTheChainOnFailure(t *testing.T) {
+	reached := false
+	app := fiber.New()
+	app.Get("/me", LogtoAuth, func(c *fiber.Ctx) error {
+		reached = true
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for _, auth := range []string{"", "Bearer not-a-jwt"} {
+		req := httptest.NewRequest("GET", "/me", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("%q: status = %d, want 401", auth, resp.StatusCode)
+		}
+	}
+	if reached {
+		t.Error("the handler after LogtoAuth ran for an unauthenticated request")
+	}
+}
+
+// TestHandlersRequireUser calls handlers with no user_id in Locals, as
+// they'd run if LogtoAuth ever let a request through unauthenticated.
+// Each must answer 401 before touching any state.
+func TestHandlersRequireUser(t *testing.T) {
+	handlers := map[string]fiber.Handler{
+		"HandleListAPIKeys":                   HandleListAPIKeys,
+		"HandleCreateAPIKey":                  HandleCreateAPIKey,
+		"HandleRevokeAPIKey":                  HandleRevokeAPIKey,
+		"HandleGetTeam":                       HandleGetTeam,
+		"HandleSetTeamSeats":                  HandleSetTeamSeats,
+		"HandleCreateTeamInvitation":          HandleCreateTeamInvitation,
+		"HandleRevokeTeamInvitation":          HandleRevokeTeamInvitation,
+		"HandleRemoveTeamMember":              HandleRemoveTeamMember,
+		"HandleJoinTeam":                      HandleJoinTeam,
+		"HandleLeaveTeam":                     HandleLeaveTeam,
+		"HandleListOrganizations":             HandleListOrganizations,
+		"HandleCreateOrganization":            HandleCreateOrganization,
+		"HandleGetOrganization":               HandleGetOrganization,
+		"HandleRenameOrganization":            HandleRenameOrganization,
+		"HandleDeleteOrganization":            HandleDeleteOrganization,
+		"HandleAddOrgMember":                  HandleAddOrgMember,
+		"HandleUpdateOrgMember":               HandleUpdateOrgMember,
+		"HandleRemoveOrgMember":               HandleRemoveOrgMember,
+		"HandlePutOrgChannel":                 HandlePutOrgChannel,
+		"HandleDeleteOrgChannel":              HandleDeleteOrgChannel,
+		"HandleCreateShortLink":               HandleCreateShortLink,
+		"HandleListShortLinks":                HandleListShortLinks,
+		"HandleDeleteShortLink":               HandleDeleteShortLink,
+		"HandleListSavedSearches":             HandleListSavedSearches,
+		"HandleCreateSavedSearch":             HandleCreateSavedSearch,
+		"HandleUpdateSavedSearch":             HandleUpdateSavedSearch,
+		"HandleDeleteSavedSearch":             HandleDeleteSavedSearch,
+		"HandleListNotifications":             HandleListNotifications,
+		"HandleMarkNotificationRead":          HandleMarkNotificationRead,
+		"HandleMarkAllNotificationsRead":      HandleMarkAllNotificationsRead,
+		"HandleCreateDataExport":              HandleCreateDataExport,
+		"HandleGetDataExport":                 HandleGetDataExport,
+		"HandleDownloadDataExport":            HandleDownloadDataExport,
+		"HandleListSessions":                  HandleListSessions,
+		"HandleRevokeSession":                 HandleRevokeSession,
+		"HandleStartConnection":               HandleStartConnection,
+		"HandleListConnections":               HandleListConnections,
+		"HandleDeleteConnection":              HandleDeleteConnection,
+		"HandleClaimAnonymousSession":         HandleClaimAnonymousSession,
+		"HandleGetOnboardingDefaults":         HandleGetOnboardingDefaults,
+		"HandleAcceptOnboardingDefaults":      HandleAcceptOnboardingDefaults,
+		"HandleDismissOnboardingDefaults":     HandleDismissOnboardingDefaults,
+		"HandleGetAPIUsage":                   HandleGetAPIUsage,
+		"HandleListInvoices":                  HandleListInvoices,
+		"HandleRetryPayment":                  HandleRetryPayment,
+		"HandleTelemetry":                     HandleTelemetry,
+		"HandleTickerItemClick":               HandleTickerItemClick,
+		"HandleGetRecommendations":            HandleGetRecommendations,
+		"HandleCreateDisplayToken":            HandleCreateDisplayToken,
+		"HandleListDisplayTokens":             HandleListDisplayTokens,
+		"HandleRevokeDisplayToken":            HandleRevokeDisplayToken,
+		"HandleGetNotificationPreferences":    HandleGetNotificationPreferences,
+		"HandleUpdateNotificationPreferences": HandleUpdateNotificationPreferences,
+		"HandleListMyAudit":                   HandleListMyAudit,
+	}
+	for name, h := range handlers {
+		app := fiber.New()
+		app.All("/*", h)
+		resp, err := app.Test(httptest.NewRequest("POST", "/x", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("%s: status = %d without a user, want 401", name, resp.StatusCode)
+		}
+	}
+}
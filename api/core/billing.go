@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/stripe/stripe-go/v82"
 	billingportalsession "github.com/stripe/stripe-go/v82/billingportal/session"
 	checkoutsession "github.com/stripe/stripe-go/v82/checkout/session"
@@ -30,7 +31,7 @@ import (
 // stripe-mock).  In production STRIPE_API_URL is unset and the SDK uses
 // its default https://api.stripe.com endpoint.
 func initStripe() {
-	key := os.Getenv("STRIPE_SECRET_KEY")
+	key := Secret("STRIPE_SECRET_KEY")
 	if key == "" {
 		// STRIPE_DISABLED is the escape hatch for local dev or staging
 		// environments that intentionally run without billing. Production
@@ -43,6 +44,13 @@ func initStripe() {
 		log.Fatal("[Billing] STRIPE_SECRET_KEY is required (set STRIPE_DISABLED=true to run without billing)")
 	}
 	stripe.Key = key
+	// The SDK keeps its own copy; follow rotations from the secrets
+	// provider (secrets.go) without a restart.
+	OnSecretRotate("STRIPE_SECRET_KEY", func(v string) {
+		if v != "" {
+			stripe.Key = v
+		}
+	})
 
 	// Allow redirecting all Stripe SDK calls to a mock server for testing.
 	if mockURL := os.Getenv("STRIPE_API_URL"); mockURL != "" {
@@ -112,6 +120,22 @@ func planRank(plan string) int {
 	}
 }
 
+// isAnnualPlan returns true for the yearly-billed recurring plans.
+func isAnnualPlan(plan string) bool {
+	return plan == "annual" || strings.HasSuffix(plan, "_annual")
+}
+
+// isPlanUpgrade reports whether moving from current to next takes effect
+// immediately with a prorated charge: a higher tier, or the same tier
+// billed annually instead of monthly. Everything else (lower tier, or
+// annual back to monthly) waits for the end of the paid period.
+func isPlanUpgrade(current, next string) bool {
+	if planRank(next) != planRank(current) {
+		return planRank(next) > planRank(current)
+	}
+	return isAnnualPlan(next) && !isAnnualPlan(current)
+}
+
 // getOrCreateStripeCustomer looks up or creates a Stripe customer for the user.
 // If a cached customer ID is stale (e.g. Stripe mode switch, deleted customer),
 // it deletes the stale record and creates a fresh customer.
@@ -237,7 +261,7 @@ func HandleCreateCheckoutSession(c *fiber.Ctx) error {
 
 	frontendURL := getFrontendURL(c)
 
-	// Only offer a 7-day trial to first-time subscribers.
+	// Only offer a trial to first-time subscribers.
 	// Users who have had any prior paid plan (active, canceled, or past_due) skip the trial.
 	var hadPriorSub bool
 	_ = DBPool.QueryRow(context.Background(),
@@ -257,23 +281,43 @@ func HandleCreateCheckoutSession(c *fiber.Ctx) error {
 		},
 		ReturnURL: stripe.String(frontendURL + "/uplink?session_id={CHECKOUT_SESSION_ID}"),
 	}
-	if !hadPriorSub {
+	if hasTrial, trialDays := trialEligibility(hadPriorSub); hasTrial {
 		params.SubscriptionData = &stripe.CheckoutSessionSubscriptionDataParams{
-			TrialPeriodDays: stripe.Int64(7),
+			TrialPeriodDays: stripe.Int64(trialDays),
 		}
 	}
 	params.AddMetadata("logto_sub", userID)
 	params.AddMetadata("plan", plan)
 
-	// Lifetime members get 50% off Ultimate subscriptions
+	// Lifetime members get 50% off Ultimate subscriptions. Stripe rejects
+	// sessions that set both discounts and allow_promotion_codes, so the
+	// code box is only shown when no coupon is applied.
+	promoCode := strings.TrimSpace(req.PromoCode)
+	lifetimeCoupon := ""
 	if isLifetime && isUltimatePlan(plan) {
-		couponID := os.Getenv("STRIPE_LIFETIME_ULTIMATE_COUPON_ID")
-		if couponID != "" {
-			params.Discounts = []*stripe.CheckoutSessionDiscountParams{
-				{Coupon: stripe.String(couponID)},
-			}
-			log.Printf("[Billing] Applied lifetime 50%% discount coupon for %s", userID)
+		lifetimeCoupon = os.Getenv("STRIPE_LIFETIME_ULTIMATE_COUPON_ID")
+	}
+	switch {
+	case lifetimeCoupon != "" && promoCode != "":
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Promo codes can't be combined with your lifetime discount",
+		})
+	case lifetimeCoupon != "":
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+			{Coupon: stripe.String(lifetimeCoupon)},
+		}
+		log.Printf("[Billing] Applied lifetime 50%% discount coupon for %s", userID)
+	case promoCode != "":
+		pc, _, err := resolvePromoCode(promoCode, req.PriceID, customerID, hadPriorSub)
+		if err != nil {
+			return promoError(c, userID, promoCode, err)
+		}
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+			{PromotionCode: stripe.String(pc.ID)},
 		}
+		log.Printf("[Billing] Applied promo code %s for %s", pc.ID, userID)
+	default:
+		params.AllowPromotionCodes = stripe.Bool(true)
 	}
 
 	session, err := checkoutsession.New(params)
@@ -461,11 +505,7 @@ func HandleCreateSetupIntent(c *fiber.Ctx) error {
 		interval = string(p.Recurring.Interval)
 	}
 
-	hasTrial := !hadPriorSub
-	var trialDays int64
-	if hasTrial {
-		trialDays = 7
-	}
+	hasTrial, trialDays := trialEligibility(hadPriorSub)
 
 	return c.JSON(SetupIntentResponse{
 		ClientSecret:   si.ClientSecret,
@@ -606,19 +646,36 @@ func HandleConfirmSubscription(c *fiber.Ctx) error {
 	subParams.AddMetadata("logto_sub", userID)
 	subParams.AddMetadata("plan", plan)
 
-	if !hadPriorSub {
-		subParams.TrialPeriodDays = stripe.Int64(7)
+	if hasTrial, trialDays := trialEligibility(hadPriorSub); hasTrial {
+		subParams.TrialPeriodDays = stripe.Int64(trialDays)
 	}
 
-	// Lifetime members get 50% off Ultimate
+	// Lifetime members get 50% off Ultimate; otherwise apply the promo
+	// code the client previewed via /checkout/promo/validate.
+	promoCode := strings.TrimSpace(req.PromoCode)
+	lifetimeCoupon := ""
 	if isLifetime && isUltimatePlan(plan) {
-		couponID := os.Getenv("STRIPE_LIFETIME_ULTIMATE_COUPON_ID")
-		if couponID != "" {
-			subParams.Discounts = []*stripe.SubscriptionDiscountParams{
-				{Coupon: stripe.String(couponID)},
-			}
-			log.Printf("[Billing] Applied lifetime 50%% discount coupon for %s", userID)
+		lifetimeCoupon = os.Getenv("STRIPE_LIFETIME_ULTIMATE_COUPON_ID")
+	}
+	switch {
+	case lifetimeCoupon != "" && promoCode != "":
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Promo codes can't be combined with your lifetime discount",
+		})
+	case lifetimeCoupon != "":
+		subParams.Discounts = []*stripe.SubscriptionDiscountParams{
+			{Coupon: stripe.String(lifetimeCoupon)},
 		}
+		log.Printf("[Billing] Applied lifetime 50%% discount coupon for %s", userID)
+	case promoCode != "":
+		pc, _, err := resolvePromoCode(promoCode, req.PriceID, customerID, hadPriorSub)
+		if err != nil {
+			return promoError(c, userID, promoCode, err)
+		}
+		subParams.Discounts = []*stripe.SubscriptionDiscountParams{
+			{PromotionCode: stripe.String(pc.ID)},
+		}
+		log.Printf("[Billing] Applied promo code %s for %s", pc.ID, userID)
 	}
 
 	sub, err := stripesubscription.New(subParams)
@@ -793,17 +850,21 @@ func HandleGetSubscription(c *fiber.Ctx) error {
 	var sc StripeCustomer
 	err := DBPool.QueryRow(context.Background(),
 		`SELECT logto_sub, stripe_customer_id, stripe_subscription_id, plan, status,
-		        current_period_end, lifetime, created_at, updated_at
+		        current_period_end, lifetime, created_at, updated_at,
+		        grace_period_ends_at, entitlements_suspended_at
 		 FROM stripe_customers WHERE logto_sub = $1`, userID,
 	).Scan(&sc.LogtoSub, &sc.StripeCustomerID, &sc.StripeSubscriptionID,
 		&sc.Plan, &sc.Status, &sc.CurrentPeriodEnd, &sc.Lifetime,
-		&sc.CreatedAt, &sc.UpdatedAt)
+		&sc.CreatedAt, &sc.UpdatedAt, &sc.GracePeriodEndsAt, &sc.EntitlementsSuspendedAt)
 
 	if err != nil {
 		// No billing record — user is on free plan
+		eligible, days := trialEligibility(false)
 		return c.JSON(SubscriptionResponse{
-			Plan:   "free",
-			Status: "none",
+			Plan:          "free",
+			Status:        "none",
+			TrialEligible: eligible,
+			TrialDays:     days,
 		})
 	}
 
@@ -814,17 +875,27 @@ func HandleGetSubscription(c *fiber.Ctx) error {
 		userID,
 	).Scan(&hadPriorSub)
 
+	eligible, days := trialEligibility(hadPriorSub)
 	resp := SubscriptionResponse{
 		Plan:             sc.Plan,
 		Status:           sc.Status,
 		CurrentPeriodEnd: sc.CurrentPeriodEnd,
 		Lifetime:         sc.Lifetime,
 		HadPriorSub:      hadPriorSub,
+		Trialing:         sc.Status == "trialing",
+		TrialEligible:    eligible,
+		TrialDays:        days,
+
+		GracePeriodEndsAt:     sc.GracePeriodEndsAt,
+		EntitlementsSuspended: sc.EntitlementsSuspendedAt != nil,
 	}
 
 	// Fetch live subscription data from Stripe for billing details + schedule
 	if sc.StripeSubscriptionID != nil && *sc.StripeSubscriptionID != "" {
-		sub, err := stripesubscription.Get(*sc.StripeSubscriptionID, nil)
+		subGetParams := &stripe.SubscriptionParams{}
+		subGetParams.AddExpand("discounts")
+		subGetParams.AddExpand("discounts.promotion_code")
+		sub, err := stripesubscription.Get(*sc.StripeSubscriptionID, subGetParams)
 		if err != nil {
 			// Subscription no longer exists in Stripe (deleted from Dashboard, etc.)
 			// Self-heal: reset the DB record so stale data isn't served.
@@ -853,6 +924,8 @@ func HandleGetSubscription(c *fiber.Ctx) error {
 			trialEnd := sub.TrialEnd
 			resp.TrialEnd = &trialEnd
 		}
+		resp.Trialing = sub.Status == stripe.SubscriptionStatusTrialing
+		resp.Discount = subscriptionDiscountFrom(sub)
 
 		// Check for pending downgrade via subscription schedule
 		if sub.Schedule != nil && sub.Schedule.ID != "" {
@@ -862,7 +935,7 @@ func HandleGetSubscription(c *fiber.Ctx) error {
 				nextPhase := sched.Phases[1]
 				if len(nextPhase.Items) > 0 {
 					nextPlan := planFromPriceID(nextPhase.Items[0].Price.ID)
-					if nextPlan != "unknown" && planRank(nextPlan) < planRank(sc.Plan) {
+					if nextPlan != "unknown" && nextPlan != sc.Plan && !isPlanUpgrade(sc.Plan, nextPlan) {
 						resp.PendingDowngradePlan = nextPlan
 						changeAt := time.Unix(nextPhase.StartDate, 0)
 						resp.ScheduledChangeAt = &changeAt
@@ -935,6 +1008,7 @@ func HandleCancelSubscription(c *fiber.Ctx) error {
 		_ = RemoveUltimateRole(userID)
 
 		log.Printf("[Billing] Trial canceled immediately for %s", userID)
+		AuditSelf(c, AuditBillingCanceled, map[string]any{"trial": true})
 		InvalidateOverviewCache(c.Context(), userID)
 		return c.JSON(fiber.Map{
 			"status":  "canceled",
@@ -966,6 +1040,7 @@ func HandleCancelSubscription(c *fiber.Ctx) error {
 		userID, periodEnd,
 	)
 
+	AuditSelf(c, AuditBillingCanceled, map[string]any{"ends_at": periodEnd})
 	InvalidateOverviewCache(c.Context(), userID)
 
 	return c.JSON(fiber.Map{
@@ -976,7 +1051,10 @@ func HandleCancelSubscription(c *fiber.Ctx) error {
 }
 
 // HandleChangePlan upgrades or downgrades the user's existing subscription.
-// Uses Stripe proration to charge or credit the difference immediately.
+// Upgrades (see isPlanUpgrade), including monthly to annual on the same
+// tier, apply now and invoice the prorated difference; downgrades are
+// scheduled for the end of the current period. Served at both
+// PUT /users/me/subscription/plan and POST /users/me/subscription/change.
 func HandleChangePlan(c *fiber.Ctx) error {
 	userID := GetUserID(c)
 	if userID == "" {
@@ -1076,6 +1154,7 @@ func HandleChangePlan(c *fiber.Ctx) error {
 
 		trialEnd := time.Unix(sub.TrialEnd, 0)
 		log.Printf("[Billing] Trial plan switched for %s: %s → %s (billing starts %s)", userID, currentPlan, newPlan, trialEnd.Format(time.RFC3339))
+		AuditSelf(c, AuditBillingPlanChanged, map[string]any{"from": currentPlan, "to": newPlan, "when": "now"})
 
 		InvalidateOverviewCache(c.Context(), userID)
 
@@ -1087,7 +1166,7 @@ func HandleChangePlan(c *fiber.Ctx) error {
 		})
 	}
 
-	isUpgrade := planRank(newPlan) > planRank(currentPlan)
+	isUpgrade := isPlanUpgrade(currentPlan, newPlan)
 
 	if isUpgrade {
 		// ── UPGRADE: immediate charge via always_invoice ───────────
@@ -1133,6 +1212,7 @@ func HandleChangePlan(c *fiber.Ctx) error {
 		)
 
 		log.Printf("[Billing] Plan upgraded for %s: %s → %s", userID, currentPlan, newPlan)
+		AuditSelf(c, AuditBillingPlanChanged, map[string]any{"from": currentPlan, "to": newPlan, "when": "now"})
 
 		InvalidateOverviewCache(c.Context(), userID)
 
@@ -1206,6 +1286,7 @@ func HandleChangePlan(c *fiber.Ctx) error {
 	}
 
 	log.Printf("[Billing] Downgrade scheduled for %s: %s → %s at %s", userID, currentPlan, newPlan, periodEnd.Format(time.RFC3339))
+	AuditSelf(c, AuditBillingPlanChanged, map[string]any{"from": currentPlan, "to": newPlan, "when": periodEnd})
 
 	InvalidateOverviewCache(c.Context(), userID)
 
@@ -1283,8 +1364,8 @@ func HandlePreviewPlanChange(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if this is a downgrade
-	isDowngrade := planRank(newPlan) < planRank(currentPlan)
+	// Check if this is a downgrade (including annual back to monthly)
+	isDowngrade := !isPlanUpgrade(currentPlan, newPlan)
 
 	if isDowngrade {
 		// Downgrade: no charge, just return the scheduled date (current period end)
@@ -1341,8 +1422,24 @@ func HandlePreviewPlanChange(c *fiber.Ctx) error {
 // Stripe Customer Portal
 // =============================================================================
 
+// PortalSessionResponse is the body for POST /users/me/billing-portal.
+type PortalSessionResponse struct {
+	URL string `json:"url"`
+}
+
 // HandleCreatePortalSession creates a Stripe Customer Portal session so users
 // can manage payment methods, view invoices, and update billing details.
+// Served at /users/me/billing-portal and, for clients that predate it,
+// /users/me/subscription/portal.
+//
+// @Summary Open billing portal
+// @Tags Billing
+// @Produce json
+// @Success 200 {object} PortalSessionResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/billing-portal [post]
 func HandleCreatePortalSession(c *fiber.Ctx) error {
 	userID := GetUserID(c)
 	if userID == "" {
@@ -1356,12 +1453,17 @@ func HandleCreatePortalSession(c *fiber.Ctx) error {
 		`SELECT stripe_customer_id FROM stripe_customers WHERE logto_sub = $1`,
 		userID,
 	).Scan(&customerID)
-	if err != nil {
-		log.Printf("[Billing] No Stripe customer found for %s: %v", userID, err)
+	if err == pgx.ErrNoRows || (err == nil && customerID == "") {
 		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
 			Status: "error", Error: "No billing account found",
 		})
 	}
+	if err != nil {
+		log.Printf("[Billing] Failed to look up Stripe customer for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create billing portal session",
+		})
+	}
 
 	frontendURL := getFrontendURL(c)
 
@@ -1378,5 +1480,5 @@ func HandleCreatePortalSession(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{"url": session.URL})
+	return c.JSON(PortalSessionResponse{URL: session.URL})
 }
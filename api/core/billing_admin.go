@@ -0,0 +1,594 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/stripe/stripe-go/v82"
+	stripecharge "github.com/stripe/stripe-go/v82/charge"
+	stripecustomer "github.com/stripe/stripe-go/v82/customer"
+	stripeinvoice "github.com/stripe/stripe-go/v82/invoice"
+	striperefund "github.com/stripe/stripe-go/v82/refund"
+	stripesubscription "github.com/stripe/stripe-go/v82/subscription"
+	subscriptionschedule "github.com/stripe/stripe-go/v82/subscriptionschedule"
+)
+
+// Admin billing tools.
+//
+// Support actions that used to need the Stripe dashboard plus a manual
+// DB edit: refunds, complimentary time, forced plan changes, and a
+// read-only snapshot of everything Stripe knows about a user. Every
+// write lands in billing_audit_log with the acting admin.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// BillingAuditEntry is one admin billing action.
+type BillingAuditEntry struct {
+	ID        int64           `json:"id"`
+	AdminSub  string          `json:"admin_sub"`
+	Action    string          `json:"action"`
+	Details   json.RawMessage `json:"details"`
+	Note      string          `json:"note,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// BillingSnapshot is the body for GET /admin/billing/users/:sub. Stripe
+// sections are best-effort; failures are listed in Errors.
+type BillingSnapshot struct {
+	LogtoSub     string               `json:"logto_sub"`
+	Local        *StripeCustomer      `json:"local"`
+	CompUntil    *time.Time           `json:"comp_until,omitempty"`
+	Customer     *stripe.Customer     `json:"customer,omitempty"`
+	Subscription *stripe.Subscription `json:"subscription,omitempty"`
+	Invoices     []*stripe.Invoice    `json:"invoices"`
+	Charges      []*stripe.Charge     `json:"charges"`
+	Audit        []BillingAuditEntry  `json:"audit"`
+	Errors       []string             `json:"errors,omitempty"`
+}
+
+// AdminRefundRequest is the body for POST /admin/billing/users/:sub/refunds.
+// ChargeID defaults to the most recent refundable charge; Amount (minor
+// units) defaults to everything still refundable on it.
+type AdminRefundRequest struct {
+	ChargeID string `json:"charge_id"`
+	Amount   int64  `json:"amount"`
+	Reason   string `json:"reason"`
+	Note     string `json:"note"`
+}
+
+// AdminCompRequest is the body for POST /admin/billing/users/:sub/comp.
+type AdminCompRequest struct {
+	Days int    `json:"days"`
+	Note string `json:"note"`
+}
+
+// AdminPlanChangeRequest is the body for PUT /admin/billing/users/:sub/plan.
+type AdminPlanChangeRequest struct {
+	PriceID string `json:"price_id"`
+	Prorate bool   `json:"prorate"`
+	Note    string `json:"note"`
+}
+
+var validRefundReasons = map[string]bool{
+	"":                                    true,
+	string(stripe.RefundReasonDuplicate):  true,
+	string(stripe.RefundReasonFraudulent): true,
+	string(stripe.RefundReasonRequestedByCustomer): true,
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────
+
+// refundAmount resolves the amount to refund against what's left on the
+// charge. requested <= 0 means the full remainder.
+func refundAmount(requested, charged, alreadyRefunded int64) (int64, error) {
+	remaining := charged - alreadyRefunded
+	if remaining <= 0 {
+		return 0, fmt.Errorf("charge is already fully refunded")
+	}
+	if requested <= 0 {
+		return remaining, nil
+	}
+	if requested > remaining {
+		return 0, fmt.Errorf("amount exceeds the %d still refundable", remaining)
+	}
+	return requested, nil
+}
+
+// compPeriodEnd extends the paid-through date by days, counting from now
+// if the current period has already ended (or never existed).
+func compPeriodEnd(current *time.Time, now time.Time, days int) time.Time {
+	base := now
+	if current != nil && current.After(now) {
+		base = *current
+	}
+	return base.AddDate(0, 0, days)
+}
+
+func recordBillingAudit(ctx context.Context, logtoSub, adminSub, action string, details map[string]any, note string) {
+	detailsJSON, _ := json.Marshal(details)
+	if _, err := DBPool.Exec(ctx, `
+		INSERT INTO billing_audit_log (logto_sub, admin_sub, action, details, note)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))`,
+		logtoSub, adminSub, action, detailsJSON, strings.TrimSpace(note)); err != nil {
+		log.Printf("[BillingAdmin] audit write failed (%s on %s): %v", action, logtoSub, err)
+	}
+}
+
+func listBillingAudit(ctx context.Context, logtoSub string) ([]BillingAuditEntry, error) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT id, admin_sub, action, details, COALESCE(note, ''), created_at
+		  FROM billing_audit_log
+		 WHERE logto_sub = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`, logtoSub, BillingAuditListMax)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]BillingAuditEntry, 0)
+	for rows.Next() {
+		var e BillingAuditEntry
+		if err := rows.Scan(&e.ID, &e.AdminSub, &e.Action, &e.Details, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// loadBillingRow reads the target user's stripe_customers row and
+// comp_until. The row is nil when the user has never been a customer.
+func loadBillingRow(ctx context.Context, logtoSub string) (*StripeCustomer, *time.Time, error) {
+	var sc StripeCustomer
+	var compUntil *time.Time
+	err := DBPool.QueryRow(ctx,
+		`SELECT logto_sub, stripe_customer_id, stripe_subscription_id, plan, status,
+		        current_period_end, lifetime, created_at, updated_at,
+		        grace_period_ends_at, entitlements_suspended_at, comp_until
+		 FROM stripe_customers WHERE logto_sub = $1`, logtoSub,
+	).Scan(&sc.LogtoSub, &sc.StripeCustomerID, &sc.StripeSubscriptionID,
+		&sc.Plan, &sc.Status, &sc.CurrentPeriodEnd, &sc.Lifetime,
+		&sc.CreatedAt, &sc.UpdatedAt, &sc.GracePeriodEndsAt, &sc.EntitlementsSuspendedAt, &compUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &sc, compUntil, nil
+}
+
+func noBillingRow(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+		Status: "error", Error: "User has no billing record",
+	})
+}
+
+// ─── Comp time ───────────────────────────────────────────────────
+
+// deferForCompTime is called when Stripe ends a subscription. If an
+// admin granted time past that point, the row keeps its plan and roles
+// as "canceling" until comp_until and the worker finishes the downgrade.
+func deferForCompTime(ctx context.Context, logtoSub string) bool {
+	tag, err := DBPool.Exec(ctx, `
+		UPDATE stripe_customers SET
+			status = 'canceling', stripe_subscription_id = NULL,
+			current_period_end = comp_until, updated_at = now()
+		 WHERE logto_sub = $1 AND lifetime = false AND comp_until > now()`,
+		logtoSub)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false
+	}
+	log.Printf("[BillingAdmin] Subscription ended for %s; comp time keeps access", logtoSub)
+	InvalidateOverviewCache(ctx, logtoSub)
+	return true
+}
+
+// StartCompTimeWorker downgrades users whose complimentary time has run
+// out after their Stripe subscription ended.
+func StartCompTimeWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(CompTimeSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runCompTimeSweep(ctx)
+			}
+		}
+	}()
+	log.Printf("[BillingAdmin] Comp-time worker started (%s interval)", CompTimeSweepInterval)
+}
+
+func runCompTimeSweep(ctx context.Context) {
+	ok, err := Rdb.SetNX(ctx, RedisCompTimeSweepLock, "1", CompTimeSweepInterval/2).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	rows, err := DBPool.Query(ctx, `
+		SELECT logto_sub FROM stripe_customers
+		 WHERE comp_until <= now()
+		   AND stripe_subscription_id IS NULL
+		   AND plan != 'free'
+		   AND lifetime = false`)
+	if err != nil {
+		log.Printf("[BillingAdmin] comp sweep query failed: %v", err)
+		return
+	}
+	var expired []string
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err == nil {
+			expired = append(expired, sub)
+		}
+	}
+	rows.Close()
+
+	for _, sub := range expired {
+		log.Printf("[BillingAdmin] Comp time ended for %s; downgrading to free", sub)
+		downgradeToFree(sub)
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// HandleAdminBillingSnapshot returns the user's local billing row with
+// their live Stripe customer, subscription, recent invoices and charges,
+// and the admin audit trail.
+//
+// @Summary Billing snapshot (admin)
+// @Tags Admin
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Success 200 {object} BillingSnapshot
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/billing/users/{sub} [get]
+func HandleAdminBillingSnapshot(c *fiber.Ctx) error {
+	ctx := c.Context()
+	logtoSub := c.Params("sub")
+
+	sc, compUntil, err := loadBillingRow(ctx, logtoSub)
+	if err != nil {
+		log.Printf("[BillingAdmin] load row for %s failed: %v", logtoSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load billing record",
+		})
+	}
+	if sc == nil {
+		return noBillingRow(c)
+	}
+
+	snap := BillingSnapshot{
+		LogtoSub:  logtoSub,
+		Local:     sc,
+		CompUntil: compUntil,
+		Invoices:  []*stripe.Invoice{},
+		Charges:   []*stripe.Charge{},
+	}
+
+	if snap.Audit, err = listBillingAudit(ctx, logtoSub); err != nil {
+		snap.Errors = append(snap.Errors, "audit: "+err.Error())
+		snap.Audit = []BillingAuditEntry{}
+	}
+
+	customerParams := &stripe.CustomerParams{}
+	customerParams.AddExpand("invoice_settings.default_payment_method")
+	if snap.Customer, err = stripecustomer.Get(sc.StripeCustomerID, customerParams); err != nil {
+		snap.Errors = append(snap.Errors, "customer: "+err.Error())
+	}
+
+	if sc.StripeSubscriptionID != nil && *sc.StripeSubscriptionID != "" {
+		subParams := &stripe.SubscriptionParams{}
+		subParams.AddExpand("discounts")
+		subParams.AddExpand("schedule")
+		if snap.Subscription, err = stripesubscription.Get(*sc.StripeSubscriptionID, subParams); err != nil {
+			snap.Errors = append(snap.Errors, "subscription: "+err.Error())
+		}
+	}
+
+	invParams := &stripe.InvoiceListParams{Customer: stripe.String(sc.StripeCustomerID)}
+	invParams.Limit = stripe.Int64(BillingSnapshotListLimit)
+	invIter := stripeinvoice.List(invParams)
+	for invIter.Next() && len(snap.Invoices) < BillingSnapshotListLimit {
+		snap.Invoices = append(snap.Invoices, invIter.Invoice())
+	}
+	if err := invIter.Err(); err != nil {
+		snap.Errors = append(snap.Errors, "invoices: "+err.Error())
+	}
+
+	chParams := &stripe.ChargeListParams{Customer: stripe.String(sc.StripeCustomerID)}
+	chParams.Limit = stripe.Int64(BillingSnapshotListLimit)
+	chIter := stripecharge.List(chParams)
+	for chIter.Next() && len(snap.Charges) < BillingSnapshotListLimit {
+		snap.Charges = append(snap.Charges, chIter.Charge())
+	}
+	if err := chIter.Err(); err != nil {
+		snap.Errors = append(snap.Errors, "charges: "+err.Error())
+	}
+
+	return c.JSON(snap)
+}
+
+// HandleAdminRefund refunds all or part of one of the user's charges.
+// Refunds don't touch entitlements — cancel or change the plan
+// separately if access should end too.
+//
+// @Summary Issue refund (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Param body body AdminRefundRequest true "Refund details"
+// @Success 200 {object} object{refund_id=string,charge_id=string,amount=int,status=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/billing/users/{sub}/refunds [post]
+func HandleAdminRefund(c *fiber.Ctx) error {
+	ctx := c.Context()
+	logtoSub := c.Params("sub")
+
+	var req AdminRefundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	if !validRefundReasons[req.Reason] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "reason must be one of duplicate, fraudulent, requested_by_customer",
+		})
+	}
+
+	sc, _, err := loadBillingRow(ctx, logtoSub)
+	if err != nil || sc == nil {
+		return noBillingRow(c)
+	}
+
+	var ch *stripe.Charge
+	if req.ChargeID != "" {
+		ch, err = stripecharge.Get(req.ChargeID, nil)
+		if err != nil || ch.Customer == nil || ch.Customer.ID != sc.StripeCustomerID {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Status: "error", Error: "Charge not found for this user",
+			})
+		}
+	} else {
+		params := &stripe.ChargeListParams{Customer: stripe.String(sc.StripeCustomerID)}
+		params.Limit = stripe.Int64(BillingSnapshotListLimit)
+		iter := stripecharge.List(params)
+		for iter.Next() {
+			if cand := iter.Charge(); cand.Paid && !cand.Refunded {
+				ch = cand
+				break
+			}
+		}
+		if ch == nil {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Status: "error", Error: "No refundable charge found",
+			})
+		}
+	}
+
+	amount, err := refundAmount(req.Amount, ch.Amount, ch.AmountRefunded)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: err.Error(),
+		})
+	}
+
+	adminSub := GetUserID(c)
+	params := &stripe.RefundParams{
+		Charge: stripe.String(ch.ID),
+		Amount: stripe.Int64(amount),
+	}
+	if req.Reason != "" {
+		params.Reason = stripe.String(req.Reason)
+	}
+	params.AddMetadata("logto_sub", logtoSub)
+	params.AddMetadata("admin_sub", adminSub)
+
+	refund, err := striperefund.New(params)
+	if err != nil {
+		log.Printf("[BillingAdmin] refund of %s for %s failed: %v", ch.ID, logtoSub, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Stripe rejected the refund",
+		})
+	}
+
+	recordBillingAudit(ctx, logtoSub, adminSub, "refund", map[string]any{
+		"refund_id": refund.ID,
+		"charge_id": ch.ID,
+		"amount":    amount,
+		"currency":  string(ch.Currency),
+		"partial":   amount < ch.Amount-ch.AmountRefunded,
+		"reason":    req.Reason,
+	}, req.Note)
+	log.Printf("[BillingAdmin] %s refunded %d %s on %s for %s", adminSub, amount, ch.Currency, ch.ID, logtoSub)
+
+	return c.JSON(fiber.Map{
+		"refund_id": refund.ID,
+		"charge_id": ch.ID,
+		"amount":    amount,
+		"status":    string(refund.Status),
+	})
+}
+
+// HandleAdminGrantCompTime extends the user's paid-through date locally.
+// Stripe is not told: an active subscription still bills on its own
+// schedule, but if it ends before comp_until the user keeps their plan
+// until then.
+//
+// @Summary Grant complimentary time (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Param body body AdminCompRequest true "Days to grant"
+// @Success 200 {object} object{current_period_end=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/billing/users/{sub}/comp [post]
+func HandleAdminGrantCompTime(c *fiber.Ctx) error {
+	ctx := c.Context()
+	logtoSub := c.Params("sub")
+
+	var req AdminCompRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	if req.Days < 1 || req.Days > MaxCompDays {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("days must be 1-%d", MaxCompDays),
+		})
+	}
+
+	sc, _, err := loadBillingRow(ctx, logtoSub)
+	if err != nil || sc == nil {
+		return noBillingRow(c)
+	}
+	if sc.Lifetime {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "Lifetime plans don't expire",
+		})
+	}
+	if sc.Plan == "free" {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "User is on the free plan; change their plan first",
+		})
+	}
+
+	newEnd := compPeriodEnd(sc.CurrentPeriodEnd, time.Now(), req.Days)
+	if _, err := DBPool.Exec(ctx, `
+		UPDATE stripe_customers SET current_period_end = $2, comp_until = $2, updated_at = now()
+		 WHERE logto_sub = $1`, logtoSub, newEnd); err != nil {
+		log.Printf("[BillingAdmin] comp time for %s failed: %v", logtoSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to grant time",
+		})
+	}
+
+	adminSub := GetUserID(c)
+	recordBillingAudit(ctx, logtoSub, adminSub, "comp_time", map[string]any{
+		"days":                   req.Days,
+		"previous_period_end":    sc.CurrentPeriodEnd,
+		"new_current_period_end": newEnd,
+	}, req.Note)
+	InvalidateOverviewCache(ctx, logtoSub)
+	log.Printf("[BillingAdmin] %s granted %d comp day(s) to %s (until %s)", adminSub, req.Days, logtoSub, newEnd.Format(time.RFC3339))
+
+	return c.JSON(fiber.Map{"current_period_end": newEnd})
+}
+
+// HandleAdminChangePlan moves the user's subscription to another price
+// immediately, releasing any scheduled downgrade. Roles follow via the
+// customer.subscription.updated webhook.
+//
+// @Summary Force plan change (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param sub path string true "Logto user ID"
+// @Param body body AdminPlanChangeRequest true "Target price"
+// @Success 200 {object} object{plan=string,status=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/billing/users/{sub}/plan [put]
+func HandleAdminChangePlan(c *fiber.Ctx) error {
+	ctx := c.Context()
+	logtoSub := c.Params("sub")
+
+	var req AdminPlanChangeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	newPlan := planFromPriceID(req.PriceID)
+	if newPlan == "" || newPlan == "lifetime" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "price_id must be a recurring plan price",
+		})
+	}
+
+	sc, _, err := loadBillingRow(ctx, logtoSub)
+	if err != nil || sc == nil {
+		return noBillingRow(c)
+	}
+	if sc.StripeSubscriptionID == nil || *sc.StripeSubscriptionID == "" {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "User has no active subscription",
+		})
+	}
+	subID := *sc.StripeSubscriptionID
+
+	sub, err := stripesubscription.Get(subID, nil)
+	if err != nil || sub.Items == nil || len(sub.Items.Data) == 0 {
+		log.Printf("[BillingAdmin] get subscription %s for %s failed: %v", subID, logtoSub, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load subscription from Stripe",
+		})
+	}
+
+	// A pending downgrade schedule owns the subscription's items; drop it
+	// so the admin's choice isn't overwritten at period end.
+	if sub.Schedule != nil && sub.Schedule.ID != "" {
+		if _, err := subscriptionschedule.Release(sub.Schedule.ID, nil); err != nil {
+			log.Printf("[BillingAdmin] release schedule %s for %s failed: %v", sub.Schedule.ID, logtoSub, err)
+			return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+				Status: "error", Error: "Failed to release pending plan change",
+			})
+		}
+	}
+
+	proration := "none"
+	if req.Prorate {
+		proration = "create_prorations"
+	}
+	updated, err := stripesubscription.Update(subID, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{{
+			ID:    stripe.String(sub.Items.Data[0].ID),
+			Price: stripe.String(req.PriceID),
+		}},
+		ProrationBehavior: stripe.String(proration),
+	})
+	if err != nil {
+		log.Printf("[BillingAdmin] plan change for %s to %s failed: %v", logtoSub, newPlan, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Stripe rejected the plan change",
+		})
+	}
+
+	_, _ = DBPool.Exec(ctx,
+		`UPDATE stripe_customers SET plan = $2, updated_at = now() WHERE logto_sub = $1`,
+		logtoSub, newPlan)
+
+	adminSub := GetUserID(c)
+	recordBillingAudit(ctx, logtoSub, adminSub, "plan_change", map[string]any{
+		"from_plan": sc.Plan,
+		"to_plan":   newPlan,
+		"price_id":  req.PriceID,
+		"prorate":   req.Prorate,
+	}, req.Note)
+	InvalidateOverviewCache(ctx, logtoSub)
+	log.Printf("[BillingAdmin] %s changed %s from %s to %s", adminSub, logtoSub, sc.Plan, newPlan)
+
+	return c.JSON(fiber.Map{"plan": newPlan, "status": string(updated.Status)})
+}
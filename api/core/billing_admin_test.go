@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefundAmount(t *testing.T) {
+	tests := []struct {
+		name                         string
+		requested, charged, refunded int64
+		want                         int64
+		ok                           bool
+	}{
+		{"full by default", 0, 1000, 0, 1000, true},
+		{"remainder after partial", 0, 1000, 300, 700, true},
+		{"partial", 250, 1000, 0, 250, true},
+		{"exactly remaining", 700, 1000, 300, 700, true},
+		{"over remaining", 701, 1000, 300, 0, false},
+		{"already refunded", 0, 1000, 1000, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := refundAmount(tt.requested, tt.charged, tt.refunded)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%s: refundAmount = %d, %v; want %d, ok=%v", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompPeriodEnd(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	future := now.AddDate(0, 0, 10)
+	past := now.AddDate(0, 0, -10)
+
+	if got := compPeriodEnd(&future, now, 30); !got.Equal(future.AddDate(0, 0, 30)) {
+		t.Errorf("active period: got %s, want %s", got, future.AddDate(0, 0, 30))
+	}
+	if got := compPeriodEnd(&past, now, 30); !got.Equal(now.AddDate(0, 0, 30)) {
+		t.Errorf("lapsed period: got %s, want %s", got, now.AddDate(0, 0, 30))
+	}
+	if got := compPeriodEnd(nil, now, 7); !got.Equal(now.AddDate(0, 0, 7)) {
+		t.Errorf("no period: got %s, want %s", got, now.AddDate(0, 0, 7))
+	}
+}
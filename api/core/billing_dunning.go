@@ -0,0 +1,369 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v82"
+	stripeinvoice "github.com/stripe/stripe-go/v82/invoice"
+)
+
+// Dunning — recovery from failed subscription payments.
+//
+// The first invoice.payment_failed for a billing cycle opens a grace
+// period (BillingGracePeriod). The user keeps their plan's roles, gets an
+// email and an SSE event, and can retry the charge from the app while
+// Stripe's own smart retries keep running. If the grace period lapses
+// without a successful payment, the dunning worker strips paid roles and
+// marks the row suspended. invoice.paid clears the state and restores
+// access.
+
+// ─── State ───────────────────────────────────────────────────────
+
+// graceDeadline returns when the current grace period ends. A retry
+// failure inside an open grace period keeps the original deadline, so
+// repeated failures can't extend access indefinitely.
+func graceDeadline(existing *time.Time, now time.Time) time.Time {
+	if existing != nil {
+		return *existing
+	}
+	return now.Add(BillingGracePeriod)
+}
+
+// graceExpired reports whether a delinquent row should lose its paid
+// roles: the grace period has ended and it isn't already suspended.
+// Stripe moves past_due to unpaid once its own retries are exhausted.
+func graceExpired(status string, graceEnd, suspendedAt *time.Time, now time.Time) bool {
+	delinquent := status == "past_due" || status == "unpaid"
+	return delinquent && graceEnd != nil && suspendedAt == nil && !now.Before(*graceEnd)
+}
+
+// startGracePeriod records a failed invoice and opens the grace period
+// if one isn't already running. Returns the deadline and whether this
+// call opened it (i.e. the first failure of the cycle).
+func startGracePeriod(ctx context.Context, logtoSub, invoiceID string) (time.Time, bool, error) {
+	var existing *time.Time
+	err := DBPool.QueryRow(ctx,
+		`SELECT grace_period_ends_at FROM stripe_customers WHERE logto_sub = $1 AND lifetime = false`,
+		logtoSub,
+	).Scan(&existing)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	deadline := graceDeadline(existing, time.Now())
+	_, err = DBPool.Exec(ctx, `
+		UPDATE stripe_customers SET
+			status               = 'past_due',
+			payment_failed_at    = COALESCE(payment_failed_at, now()),
+			grace_period_ends_at = $2,
+			failed_invoice_id    = $3,
+			updated_at           = now()
+		 WHERE logto_sub = $1 AND lifetime = false`,
+		logtoSub, deadline, invoiceID,
+	)
+	return deadline, existing == nil, err
+}
+
+// clearDunningState resets the dunning columns after a successful
+// payment. Returns true when the user had been suspended, so the caller
+// knows team members need their roles back too.
+func clearDunningState(ctx context.Context, logtoSub string) (wasSuspended bool) {
+	var suspendedAt *time.Time
+	_ = DBPool.QueryRow(ctx,
+		`SELECT entitlements_suspended_at FROM stripe_customers WHERE logto_sub = $1`, logtoSub,
+	).Scan(&suspendedAt)
+
+	if _, err := DBPool.Exec(ctx, `
+		UPDATE stripe_customers SET
+			payment_failed_at         = NULL,
+			grace_period_ends_at      = NULL,
+			failed_invoice_id         = NULL,
+			entitlements_suspended_at = NULL
+		 WHERE logto_sub = $1`, logtoSub); err != nil {
+		log.Printf("[Dunning] Failed to clear state for %s: %v", logtoSub, err)
+	}
+	return suspendedAt != nil
+}
+
+// ─── Notifications ───────────────────────────────────────────────
+
+// publishBillingState pushes the user's dunning state over their SSE
+// topic using the CDC envelope, so an open dashboard can show the
+// "update your card" banner without polling.
+func publishBillingState(ctx context.Context, logtoSub, status string, graceEnd *time.Time, suspended bool) {
+	envelope := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"action": "update",
+				"record": map[string]interface{}{
+					"logto_sub":              logtoSub,
+					"status":                 status,
+					"grace_period_ends_at":   graceEnd,
+					"entitlements_suspended": suspended,
+				},
+				"metadata": map[string]string{
+					"table_schema": "public",
+					"table_name":   "stripe_customers",
+				},
+			},
+		},
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq := nextEventSeq(ctx); seq > 0 {
+		envelope["seq"] = seq
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[Dunning] marshal SSE payload failed: %v", err)
+		return
+	}
+	if err := PublishRaw(TopicPrefixCore+logtoSub, payload); err != nil {
+		log.Printf("[Dunning] publish for %s failed: %v", logtoSub, err)
+	}
+}
+
+// sendPaymentFailedEmail tells the user their renewal failed and how
+// long they have to fix it. payURL is Stripe's hosted invoice page when
+// available, which lets them pay with a new card in one step.
+func sendPaymentFailedEmail(ctx context.Context, toEmail, payURL string, graceEnd time.Time) error {
+	apiKey := Secret("RESEND_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("RESEND_API_KEY not configured")
+	}
+	from := os.Getenv("RESEND_FROM_EMAIL")
+	if from == "" {
+		from = "MyScrollr <noreply@myscrollr.com>"
+	}
+	if payURL == "" {
+		payURL = os.Getenv("FRONTEND_URL")
+		if payURL == "" {
+			payURL = DefaultFrontendURL
+		}
+		payURL += "/account"
+	}
+
+	body := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;background:#0b0d10;color:#e6e6e6;padding:24px;">
+  <div style="max-width:520px;margin:0 auto;background:#14181d;border:1px solid #1e252d;border-radius:12px;padding:32px;">
+    <h2 style="margin:0 0 16px;font-size:20px;color:#fff;">Your payment didn't go through</h2>
+    <p style="margin:0 0 16px;line-height:1.6;color:#b8b8b8;">We couldn't charge your card for your MyScrollr subscription. Your plan stays active until <strong>%s</strong> while we retry.</p>
+    <p style="margin:0 0 16px;line-height:1.6;color:#b8b8b8;">Update your payment method before then to keep your features.</p>
+    <p style="margin:24px 0;text-align:center;">
+      <a href="%s" style="display:inline-block;padding:12px 28px;background:#10b981;color:#fff;text-decoration:none;border-radius:8px;font-weight:600;">Update payment</a>
+    </p>
+  </div>
+  <p style="text-align:center;margin-top:16px;color:#5a5a5a;font-size:11px;">— The MyScrollr Team</p>
+</body>
+</html>`, graceEnd.UTC().Format("January 2, 2006"), html.EscapeString(payURL))
+
+	return postToResend(ctx, apiKey, map[string]any{
+		"from":    from,
+		"to":      []string{toEmail},
+		"subject": "Action needed: your MyScrollr payment failed",
+		"html":    body,
+	})
+}
+
+// ─── Webhook hooks ───────────────────────────────────────────────
+
+// handleDunningPaymentFailed is called from invoice.payment_failed. It
+// opens the grace period and notifies the user once per cycle; later
+// retry failures only refresh failed_invoice_id.
+func handleDunningPaymentFailed(ctx context.Context, logtoSub, invoiceID, email, payURL string) {
+	deadline, first, err := startGracePeriod(ctx, logtoSub, invoiceID)
+	if err != nil {
+		// No row (or lifetime): nothing to degrade.
+		return
+	}
+	InvalidateOverviewCache(ctx, logtoSub)
+	publishBillingState(ctx, logtoSub, "past_due", &deadline, false)
+
+	if !first {
+		return
+	}
+	log.Printf("[Dunning] Grace period opened for %s until %s", logtoSub, deadline.Format(time.RFC3339))
+	if email == "" {
+		log.Printf("[Dunning] No email on invoice %s; skipping notice for %s", invoiceID, logtoSub)
+		return
+	}
+	if err := sendPaymentFailedEmail(ctx, email, payURL, deadline); err != nil {
+		log.Printf("[Dunning] Failed to email %s: %v", logtoSub, err)
+	}
+}
+
+// handleDunningRecovered is called from invoice.paid after the owner's
+// role has been re-assigned. Members of a suspended owner's team are
+// restored here, since the owner's plan didn't change.
+func handleDunningRecovered(ctx context.Context, logtoSub, plan string) {
+	if !clearDunningState(ctx, logtoSub) {
+		return
+	}
+	log.Printf("[Dunning] Restored entitlements for %s after payment", logtoSub)
+	SyncTeamMemberTiers(ctx, logtoSub, tierForPlan(plan))
+	InvalidateOverviewCache(ctx, logtoSub)
+	publishBillingState(ctx, logtoSub, "active", nil, false)
+}
+
+// ─── Worker ──────────────────────────────────────────────────────
+
+// StartDunningWorker suspends paid roles for users whose grace period
+// has lapsed. Replicas coordinate through a Redis lock.
+func StartDunningWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(DunningSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runDunningSweep(ctx)
+			}
+		}
+	}()
+	log.Printf("[Dunning] Grace-period worker started (%s interval)", DunningSweepInterval)
+}
+
+func runDunningSweep(ctx context.Context) {
+	ok, err := Rdb.SetNX(ctx, RedisDunningSweepLock, "1", DunningSweepInterval/2).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	rows, err := DBPool.Query(ctx, `
+		SELECT logto_sub, status, grace_period_ends_at, entitlements_suspended_at
+		  FROM stripe_customers
+		 WHERE grace_period_ends_at <= now()
+		   AND entitlements_suspended_at IS NULL
+		   AND lifetime = false`)
+	if err != nil {
+		log.Printf("[Dunning] sweep query failed: %v", err)
+		return
+	}
+	var due []string
+	now := time.Now()
+	for rows.Next() {
+		var sub, status string
+		var graceEnd, suspendedAt *time.Time
+		if err := rows.Scan(&sub, &status, &graceEnd, &suspendedAt); err != nil {
+			log.Printf("[Dunning] sweep scan failed: %v", err)
+			continue
+		}
+		if graceExpired(status, graceEnd, suspendedAt, now) {
+			due = append(due, sub)
+		}
+	}
+	rows.Close()
+
+	for _, sub := range due {
+		suspendEntitlements(ctx, sub)
+	}
+}
+
+// suspendEntitlements drops a user (and their team) to the free tier at
+// the end of the grace period. The subscription itself is left to
+// Stripe, whose retry schedule decides when to cancel it.
+func suspendEntitlements(ctx context.Context, logtoSub string) {
+	tag, err := DBPool.Exec(ctx, `
+		UPDATE stripe_customers SET entitlements_suspended_at = now(), updated_at = now()
+		 WHERE logto_sub = $1 AND status IN ('past_due', 'unpaid') AND entitlements_suspended_at IS NULL`,
+		logtoSub)
+	if err != nil || tag.RowsAffected() == 0 {
+		return
+	}
+
+	if err := RemoveUplinkRole(logtoSub); err != nil {
+		log.Printf("[Dunning] Failed to remove uplink role from %s: %v", logtoSub, err)
+	}
+	if err := RemoveProRole(logtoSub); err != nil {
+		log.Printf("[Dunning] Failed to remove uplink_pro role from %s: %v", logtoSub, err)
+	}
+	if err := RemoveUltimateRole(logtoSub); err != nil {
+		log.Printf("[Dunning] Failed to remove uplink_ultimate role from %s: %v", logtoSub, err)
+	}
+	PruneUserChannelsForTier(ctx, logtoSub, "free")
+	SyncTeamMemberTiers(ctx, logtoSub, "free")
+	InvalidateOverviewCache(ctx, logtoSub)
+	publishBillingState(ctx, logtoSub, "past_due", nil, true)
+	log.Printf("[Dunning] Grace period lapsed; suspended paid entitlements for %s", logtoSub)
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// RetryPaymentResponse is the body for POST /users/me/billing/retry.
+type RetryPaymentResponse struct {
+	Status    string `json:"status"`
+	InvoiceID string `json:"invoice_id"`
+}
+
+// HandleRetryPayment asks Stripe to charge the outstanding invoice now,
+// against the customer's current default payment method. Clients call
+// it after the user updates their card in the billing portal.
+//
+// @Summary Retry failed payment
+// @Tags Billing
+// @Produce json
+// @Success 200 {object} RetryPaymentResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/billing/retry [post]
+func HandleRetryPayment(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var invoiceID *string
+	err := DBPool.QueryRow(ctx,
+		`SELECT failed_invoice_id FROM stripe_customers WHERE logto_sub = $1 AND status IN ('past_due', 'unpaid')`,
+		userID,
+	).Scan(&invoiceID)
+	if err != nil || invoiceID == nil || *invoiceID == "" {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "No failed payment to retry",
+		})
+	}
+
+	if Rdb != nil {
+		ok, err := Rdb.SetNX(ctx, RedisPaymentRetryPrefix+userID, "1", PaymentRetryCooldown).Result()
+		if err == nil && !ok {
+			return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+				Status: "error", Error: "A retry was just attempted; please wait a few minutes",
+			})
+		}
+	}
+
+	inv, err := stripeinvoice.Pay(*invoiceID, &stripe.InvoicePayParams{})
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeCard {
+			log.Printf("[Dunning] Retry for %s declined: %s", userID, stripeErr.Code)
+			return c.Status(fiber.StatusPaymentRequired).JSON(ErrorResponse{
+				Status: "error", Error: "Your card was declined. Update your payment method and try again.",
+			})
+		}
+		log.Printf("[Dunning] Retry for %s (invoice %s) failed: %v", userID, *invoiceID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Could not retry the payment",
+		})
+	}
+
+	// invoice.paid does the bookkeeping; the response is just feedback.
+	log.Printf("[Dunning] Retry for %s: invoice %s is %s", userID, inv.ID, inv.Status)
+	return c.JSON(RetryPaymentResponse{Status: string(inv.Status), InvoiceID: inv.ID})
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGraceDeadline_KeepsOpenPeriod(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := graceDeadline(nil, now); !got.Equal(now.Add(BillingGracePeriod)) {
+		t.Errorf("new grace period ends %s, want %s", got, now.Add(BillingGracePeriod))
+	}
+	existing := now.Add(-48 * time.Hour)
+	if got := graceDeadline(&existing, now); !got.Equal(existing) {
+		t.Errorf("open grace period moved to %s, want %s", got, existing)
+	}
+}
+
+func TestGraceExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		status      string
+		graceEnd    *time.Time
+		suspendedAt *time.Time
+		want        bool
+	}{
+		{"lapsed", "past_due", &past, nil, true},
+		{"stripe gave up", "unpaid", &past, nil, true},
+		{"exactly at deadline", "past_due", &now, nil, true},
+		{"still in grace", "past_due", &future, nil, false},
+		{"already suspended", "past_due", &past, &past, false},
+		{"recovered", "active", &past, nil, false},
+		{"no grace period", "past_due", nil, nil, false},
+	}
+	for _, tt := range tests {
+		if got := graceExpired(tt.status, tt.graceEnd, tt.suspendedAt, now); got != tt.want {
+			t.Errorf("%s: graceExpired = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
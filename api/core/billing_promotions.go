@@ -0,0 +1,327 @@
+package core
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v82"
+	stripeprice "github.com/stripe/stripe-go/v82/price"
+	"github.com/stripe/stripe-go/v82/promotioncode"
+)
+
+// Trials and promotion codes.
+//
+// First-time subscribers get a free trial (STRIPE_TRIAL_DAYS, default 7;
+// 0 turns trials off). Promotion codes are created in the Stripe
+// Dashboard; the embedded Checkout shows its own code box, and the
+// Payment Element flow validates codes through POST /checkout/promo/validate
+// before passing promo_code to /checkout/subscribe.
+
+// ─── Trials ─────────────────────────────────────────────────────────
+
+// trialPeriodDays returns the configured trial length. Invalid values
+// fall back to the default rather than silently disabling trials.
+func trialPeriodDays() int64 {
+	raw := strings.TrimSpace(os.Getenv("STRIPE_TRIAL_DAYS"))
+	if raw == "" {
+		return DefaultTrialDays
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		log.Printf("[Billing] Invalid STRIPE_TRIAL_DAYS %q, using %d", raw, DefaultTrialDays)
+		return DefaultTrialDays
+	}
+	if n > MaxTrialDays {
+		return MaxTrialDays
+	}
+	return n
+}
+
+// trialEligibility reports whether a user who has (or hasn't) had a paid
+// plan gets a trial, and for how many days.
+func trialEligibility(hadPriorSub bool) (bool, int64) {
+	days := trialPeriodDays()
+	if hadPriorSub || days == 0 {
+		return false, 0
+	}
+	return true, days
+}
+
+// ─── Promotion codes ────────────────────────────────────────────────
+
+// PromoValidateRequest is the body for POST /checkout/promo/validate.
+type PromoValidateRequest struct {
+	PromoCode string `json:"promo_code"`
+	PriceID   string `json:"price_id"`
+}
+
+// PromoValidateResponse previews the price after a promotion code.
+type PromoValidateResponse struct {
+	Valid            bool    `json:"valid"`
+	PromoCode        string  `json:"promo_code"`
+	PromotionCodeID  string  `json:"promotion_code_id"`
+	Plan             string  `json:"plan"`
+	OriginalAmount   int64   `json:"original_amount"`
+	DiscountedAmount int64   `json:"discounted_amount"`
+	Currency         string  `json:"currency"`
+	Interval         string  `json:"interval,omitempty"`
+	PercentOff       float64 `json:"percent_off,omitempty"`
+	AmountOff        int64   `json:"amount_off,omitempty"`
+	Duration         string  `json:"duration"`
+	DurationInMonths int64   `json:"duration_in_months,omitempty"`
+	TrialDays        int64   `json:"trial_days,omitempty"`
+}
+
+// SubscriptionDiscount summarises the discount on a live subscription.
+type SubscriptionDiscount struct {
+	PromoCode        string  `json:"promo_code,omitempty"`
+	PercentOff       float64 `json:"percent_off,omitempty"`
+	AmountOff        int64   `json:"amount_off,omitempty"`
+	Duration         string  `json:"duration"`
+	DurationInMonths int64   `json:"duration_in_months,omitempty"`
+	End              *int64  `json:"end,omitempty"`
+}
+
+// errPromoInvalid is the user-facing error for any code we refuse. The
+// specific reason is only logged so codes can't be probed.
+type errPromoInvalid struct{ reason string }
+
+func (e errPromoInvalid) Error() string { return e.reason }
+
+// lookupPromotionCode resolves a customer-facing code to its active
+// Stripe promotion code. Returns errPromoInvalid when no active code
+// matches.
+func lookupPromotionCode(code string) (*stripe.PromotionCode, error) {
+	params := &stripe.PromotionCodeListParams{
+		Code:   stripe.String(code),
+		Active: stripe.Bool(true),
+	}
+	params.AddExpand("data.coupon.applies_to")
+	params.Limit = stripe.Int64(1)
+
+	iter := promotioncode.List(params)
+	for iter.Next() {
+		return iter.PromotionCode(), nil
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errPromoInvalid{"no active promotion code"}
+}
+
+// checkPromotionCode applies the restrictions Stripe would enforce at
+// payment time so the preview never promises a discount Stripe then
+// rejects. customerID may be empty for customers not yet created.
+func checkPromotionCode(pc *stripe.PromotionCode, productID string, amount int64, currency string, customerID string, hadPriorSub bool, now time.Time) error {
+	if pc == nil || !pc.Active || pc.Coupon == nil || !pc.Coupon.Valid {
+		return errPromoInvalid{"inactive"}
+	}
+	if pc.ExpiresAt > 0 && now.Unix() >= pc.ExpiresAt {
+		return errPromoInvalid{"expired"}
+	}
+	if pc.MaxRedemptions > 0 && pc.TimesRedeemed >= pc.MaxRedemptions {
+		return errPromoInvalid{"fully redeemed"}
+	}
+	if pc.Customer != nil && pc.Customer.ID != "" && pc.Customer.ID != customerID {
+		return errPromoInvalid{"restricted to another customer"}
+	}
+	if ap := pc.Coupon.AppliesTo; ap != nil && len(ap.Products) > 0 {
+		ok := false
+		for _, p := range ap.Products {
+			if p == productID {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errPromoInvalid{"not valid for this plan"}
+		}
+	}
+	if pc.Coupon.AmountOff > 0 && !strings.EqualFold(string(pc.Coupon.Currency), currency) {
+		return errPromoInvalid{"currency mismatch"}
+	}
+	if r := pc.Restrictions; r != nil {
+		if r.FirstTimeTransaction && hadPriorSub {
+			return errPromoInvalid{"first-time customers only"}
+		}
+		if r.MinimumAmount > 0 && strings.EqualFold(string(r.MinimumAmountCurrency), currency) && amount < r.MinimumAmount {
+			return errPromoInvalid{"below minimum amount"}
+		}
+	}
+	return nil
+}
+
+// applyCouponToAmount returns amount after the coupon, never below zero.
+// Percent discounts round half-up to the minor unit, matching Stripe.
+func applyCouponToAmount(amount int64, coupon *stripe.Coupon) int64 {
+	if coupon == nil {
+		return amount
+	}
+	out := amount
+	if coupon.PercentOff > 0 {
+		off := int64(math.Round(float64(amount) * coupon.PercentOff / 100))
+		out = amount - off
+	} else if coupon.AmountOff > 0 {
+		out = amount - coupon.AmountOff
+	}
+	if out < 0 {
+		return 0
+	}
+	return out
+}
+
+// resolvePromoCode looks up a customer-facing code and checks it against
+// the price. Errors of type errPromoInvalid mean the code was refused;
+// anything else is a Stripe failure.
+func resolvePromoCode(code, priceID, customerID string, hadPriorSub bool) (*stripe.PromotionCode, *stripe.Price, error) {
+	pc, err := lookupPromotionCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := stripeprice.Get(priceID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	productID := ""
+	if p.Product != nil {
+		productID = p.Product.ID
+	}
+	if err := checkPromotionCode(pc, productID, p.UnitAmount, string(p.Currency), customerID, hadPriorSub, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	return pc, p, nil
+}
+
+// promoError writes the response for a resolvePromoCode failure.
+func promoError(c *fiber.Ctx, userID, code string, err error) error {
+	if _, ok := err.(errPromoInvalid); ok {
+		log.Printf("[Billing] Promo code %q rejected for %s: %v", code, userID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid or expired promo code",
+		})
+	}
+	log.Printf("[Billing] Promo code %q lookup failed for %s: %v", code, userID, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+		Status: "error", Error: "Failed to validate promo code",
+	})
+}
+
+// subscriptionDiscountFrom summarises the first discount on a
+// subscription fetched with discounts expanded.
+func subscriptionDiscountFrom(sub *stripe.Subscription) *SubscriptionDiscount {
+	if sub == nil {
+		return nil
+	}
+	for _, d := range sub.Discounts {
+		if d == nil || d.Coupon == nil {
+			continue
+		}
+		out := &SubscriptionDiscount{
+			PercentOff:       d.Coupon.PercentOff,
+			AmountOff:        d.Coupon.AmountOff,
+			Duration:         string(d.Coupon.Duration),
+			DurationInMonths: d.Coupon.DurationInMonths,
+		}
+		if d.PromotionCode != nil {
+			out.PromoCode = d.PromotionCode.Code
+		}
+		if d.End > 0 {
+			end := d.End
+			out.End = &end
+		}
+		return out
+	}
+	return nil
+}
+
+// HandleValidatePromoCode previews the discounted price for a promo code.
+//
+// @Summary Validate a promo code
+// @Description Checks a promotion code against a price and returns the discounted amount
+// @Tags Billing
+// @Accept json
+// @Produce json
+// @Param body body PromoValidateRequest true "Promo code and price"
+// @Success 200 {object} PromoValidateResponse
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /checkout/promo/validate [post]
+func HandleValidatePromoCode(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	var req PromoValidateRequest
+	if err := c.BodyParser(&req); err != nil || req.PriceID == "" || strings.TrimSpace(req.PromoCode) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "promo_code and price_id are required",
+		})
+	}
+	code := strings.TrimSpace(req.PromoCode)
+	if len(code) > MaxPromoCodeLength {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid or expired promo code",
+		})
+	}
+
+	plan := planFromPriceID(req.PriceID)
+	if plan == "unknown" || plan == "lifetime" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid price_id for subscription checkout",
+		})
+	}
+
+	var customerID string
+	var isLifetime bool
+	_ = DBPool.QueryRow(context.Background(),
+		`SELECT stripe_customer_id, COALESCE(lifetime, false) FROM stripe_customers WHERE logto_sub = $1`, userID,
+	).Scan(&customerID, &isLifetime)
+
+	if isLifetime && isUltimatePlan(plan) && os.Getenv("STRIPE_LIFETIME_ULTIMATE_COUPON_ID") != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Promo codes can't be combined with your lifetime discount",
+		})
+	}
+
+	var hadPriorSub bool
+	_ = DBPool.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM stripe_customers WHERE logto_sub = $1 AND plan != 'free')`,
+		userID,
+	).Scan(&hadPriorSub)
+
+	pc, p, err := resolvePromoCode(code, req.PriceID, customerID, hadPriorSub)
+	if err != nil {
+		return promoError(c, userID, code, err)
+	}
+
+	interval := ""
+	if p.Recurring != nil {
+		interval = string(p.Recurring.Interval)
+	}
+	_, trialDays := trialEligibility(hadPriorSub)
+
+	return c.JSON(PromoValidateResponse{
+		Valid:            true,
+		PromoCode:        pc.Code,
+		PromotionCodeID:  pc.ID,
+		Plan:             plan,
+		OriginalAmount:   p.UnitAmount,
+		DiscountedAmount: applyCouponToAmount(p.UnitAmount, pc.Coupon),
+		Currency:         string(p.Currency),
+		Interval:         interval,
+		PercentOff:       pc.Coupon.PercentOff,
+		AmountOff:        pc.Coupon.AmountOff,
+		Duration:         string(pc.Coupon.Duration),
+		DurationInMonths: pc.Coupon.DurationInMonths,
+		TrialDays:        trialDays,
+	})
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+func TestTrialEligibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		hadPriorSub bool
+		wantTrial   bool
+		wantDays    int64
+	}{
+		{"default", "", false, true, DefaultTrialDays},
+		{"prior sub", "", true, false, 0},
+		{"configured", "14", false, true, 14},
+		{"disabled", "0", false, false, 0},
+		{"invalid falls back", "abc", false, true, DefaultTrialDays},
+		{"capped", "9999", false, true, MaxTrialDays},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STRIPE_TRIAL_DAYS", tt.env)
+			gotTrial, gotDays := trialEligibility(tt.hadPriorSub)
+			if gotTrial != tt.wantTrial || gotDays != tt.wantDays {
+				t.Errorf("trialEligibility(%v) = %v, %d; want %v, %d",
+					tt.hadPriorSub, gotTrial, gotDays, tt.wantTrial, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestApplyCouponToAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		coupon *stripe.Coupon
+		want   int64
+	}{
+		{"no coupon", 999, nil, 999},
+		{"percent rounds half up", 999, &stripe.Coupon{PercentOff: 50}, 499},
+		{"percent 100", 999, &stripe.Coupon{PercentOff: 100}, 0},
+		{"amount off", 999, &stripe.Coupon{AmountOff: 200}, 799},
+		{"amount off floors at zero", 500, &stripe.Coupon{AmountOff: 1000}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyCouponToAmount(tt.amount, tt.coupon); got != tt.want {
+				t.Errorf("applyCouponToAmount(%d) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPromotionCode(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	base := func() *stripe.PromotionCode {
+		return &stripe.PromotionCode{
+			ID:     "promo_1",
+			Active: true,
+			Coupon: &stripe.Coupon{Valid: true, PercentOff: 20},
+		}
+	}
+
+	if err := checkPromotionCode(base(), "prod_ultimate", 999, "usd", "", false, now); err != nil {
+		t.Fatalf("plain code rejected: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		mutate      func(pc *stripe.PromotionCode)
+		hadPriorSub bool
+	}{
+		{"inactive", func(pc *stripe.PromotionCode) { pc.Active = false }, false},
+		{"invalid coupon", func(pc *stripe.PromotionCode) { pc.Coupon.Valid = false }, false},
+		{"expired", func(pc *stripe.PromotionCode) { pc.ExpiresAt = now.Unix() - 1 }, false},
+		{"fully redeemed", func(pc *stripe.PromotionCode) { pc.MaxRedemptions = 5; pc.TimesRedeemed = 5 }, false},
+		{"other customer", func(pc *stripe.PromotionCode) { pc.Customer = &stripe.Customer{ID: "cus_other"} }, false},
+		{"other product", func(pc *stripe.PromotionCode) {
+			pc.Coupon.AppliesTo = &stripe.CouponAppliesTo{Products: []string{"prod_pro"}}
+		}, false},
+		{"currency mismatch", func(pc *stripe.PromotionCode) {
+			pc.Coupon = &stripe.Coupon{Valid: true, AmountOff: 100, Currency: "eur"}
+		}, false},
+		{"first time only", func(pc *stripe.PromotionCode) {
+			pc.Restrictions = &stripe.PromotionCodeRestrictions{FirstTimeTransaction: true}
+		}, true},
+		{"below minimum", func(pc *stripe.PromotionCode) {
+			pc.Restrictions = &stripe.PromotionCodeRestrictions{MinimumAmount: 1000, MinimumAmountCurrency: "usd"}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := base()
+			tt.mutate(pc)
+			err := checkPromotionCode(pc, "prod_ultimate", 999, "usd", "", tt.hadPriorSub, now)
+			if _, ok := err.(errPromoInvalid); !ok {
+				t.Errorf("want errPromoInvalid, got %v", err)
+			}
+		})
+	}
+}
@@ -156,3 +156,25 @@ func TestPlanRankUpgradeDowngrade(t *testing.T) {
 		}
 	}
 }
+
+func TestIsPlanUpgrade(t *testing.T) {
+	tests := []struct {
+		current, next string
+		want          bool
+	}{
+		{"monthly", "annual", true},
+		{"pro_monthly", "pro_annual", true},
+		{"ultimate_monthly", "ultimate_annual", true},
+		{"annual", "monthly", false},
+		{"pro_annual", "pro_monthly", false},
+		{"annual", "pro_monthly", true},
+		{"pro_annual", "monthly", false},
+		{"ultimate_annual", "pro_annual", false},
+		{"monthly", "monthly", false},
+	}
+	for _, tt := range tests {
+		if got := isPlanUpgrade(tt.current, tt.next); got != tt.want {
+			t.Errorf("isPlanUpgrade(%q, %q) = %v, want %v", tt.current, tt.next, got, tt.want)
+		}
+	}
+}
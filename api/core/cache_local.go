@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// Hot-key local cache.
+//
+// A few cache keys are shared by every caller and read on every poll:
+// the public feed and the health summary. Each gateway keeps them in an
+// in-process LRU for at most LocalCacheTTL in front of Redis, so a burst
+// of polls costs one Redis GET per replica per LocalCacheTTL instead of
+// one per request. Only keys in hotCacheKeys take this path; per-user
+// keys hit Redis as before (they're invalidated on every CDC dispatch and
+// rarely read twice on the same replica within the TTL).
+//
+// Deleting a hot key publishes it on TopicCacheInvalidate; every gateway
+// and channel API drops it from its local cache. A replica that read the
+// old value just before the delete can still serve it until its entry
+// expires, so LocalCacheTTL bounds the staleness.
+
+// hotCacheKeys are the keys GetCache/SetCache keep locally.
+var hotCacheKeys = map[string]bool{
+	PublicFeedCacheKey: true,
+	HealthCacheKey:     true,
+}
+
+var localCache = newLRUCache(LocalCacheMaxBytes)
+
+// publishCacheInvalidation tells every process to drop the hot keys among
+// keys from its local cache.
+func publishCacheInvalidation(ctx context.Context, keys ...string) {
+	var hot []string
+	for _, k := range keys {
+		if hotCacheKeys[k] {
+			hot = append(hot, k)
+		}
+	}
+	if len(hot) == 0 || Rdb == nil {
+		return
+	}
+	payload, _ := json.Marshal(hot)
+	if err := Rdb.Publish(ctx, TopicCacheInvalidate, payload).Err(); err != nil {
+		log.Printf("[Cache] Failed to publish invalidation for %v: %v", hot, err)
+	}
+}
+
+// StartCacheInvalidationListener drops local entries named on
+// TopicCacheInvalidate, from this or any other process, until ctx ends.
+func StartCacheInvalidationListener(ctx context.Context) {
+	sub := Rdb.Subscribe(ctx, TopicCacheInvalidate)
+	go func() {
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var keys []string
+				if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+					log.Printf("[Cache] Malformed invalidation %q: %v", msg.Payload, err)
+					continue
+				}
+				localCache.del(keys...)
+			}
+		}
+	}()
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetCacheServesHotKeysLocally(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	defer localCache.del(PublicFeedCacheKey)
+
+	ctx := context.Background()
+	SetCache(ctx, PublicFeedCacheKey, []byte("v1"), time.Minute)
+
+	// The Redis copy changing underneath isn't seen until the local entry
+	// goes; that's the trade.
+	mr.Set(PublicFeedCacheKey, "v2")
+	if v, ok := GetCache(ctx, PublicFeedCacheKey); !ok || string(v) != "v1" {
+		t.Fatalf("hot key read = %q, %v; want the local v1", v, ok)
+	}
+
+	// Per-user keys always go to Redis.
+	SetCache(ctx, "cache:dashboard:u1", []byte("a"), time.Minute)
+	mr.Set("cache:dashboard:u1", "b")
+	if v, _ := GetCache(ctx, "cache:dashboard:u1"); string(v) != "b" {
+		t.Errorf("cold key read = %q, want Redis's b", v)
+	}
+}
+
+func TestCacheInvalidationReachesLocalCache(t *testing.T) {
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	defer localCache.del(HealthCacheKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartCacheInvalidationListener(ctx)
+
+	localCache.set(HealthCacheKey, []byte("x"), time.Minute, time.Now())
+	// Wait for the subscription to be live before publishing.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n, _ := Rdb.PubSubNumSub(ctx, TopicCacheInvalidate).Result()
+		if n[TopicCacheInvalidate] > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	publishCacheInvalidation(ctx, HealthCacheKey, "cache:not-hot")
+	for time.Now().Before(deadline) {
+		if _, ok := localCache.get(HealthCacheKey, time.Now()); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("published invalidation never dropped the local entry")
+}
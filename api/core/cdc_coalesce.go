@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CDC coalescing.
+//
+// On a game night Sequin delivers many small webhook batches in quick
+// succession, and routing each record on its own costs one PUBLISH, one
+// seq and one SSE frame per record per client. The webhook instead hands
+// its records to cdcCoalescer, which holds each topic's records for up
+// to CDCCoalesceWindow and publishes them as a single envelope whose
+// data array carries every record (clients already treat data as a
+// list). Records from concurrent deliveries for the same topic share the
+// envelope. Each delivery waits for its records' flush, so a publish
+// failure still reaches the dead-letter queue and Sequin's retry.
+//
+// The DLQ worker routes records one at a time through routeCDCRecord
+// and doesn't wait on the window.
+
+type cdcTopicBatch struct {
+	records []map[string]interface{}
+	waiters []chan error
+	timer   *time.Timer
+	flushed bool
+}
+
+type cdcCoalescer struct {
+	window     time.Duration
+	maxRecords int
+	publish    func(ctx context.Context, topic string, records []map[string]interface{}) error
+
+	mu      sync.Mutex
+	pending map[string]*cdcTopicBatch
+}
+
+func newCDCCoalescer(window time.Duration, maxRecords int, publish func(context.Context, string, []map[string]interface{}) error) *cdcCoalescer {
+	return &cdcCoalescer{
+		window:     window,
+		maxRecords: maxRecords,
+		publish:    publish,
+		pending:    make(map[string]*cdcTopicBatch),
+	}
+}
+
+var cdcCoalesce = newCDCCoalescer(CDCCoalesceWindow, CDCCoalesceMaxRecords, publishCDCEnvelope)
+
+// add queues a record for topic. The returned channel receives the
+// publish result once the record's batch is flushed.
+func (c *cdcCoalescer) add(topic string, record map[string]interface{}) <-chan error {
+	done := make(chan error, 1)
+	c.mu.Lock()
+	b, ok := c.pending[topic]
+	if !ok {
+		b = &cdcTopicBatch{}
+		b.timer = time.AfterFunc(c.window, func() { c.flush(topic, b) })
+		c.pending[topic] = b
+	}
+	b.records = append(b.records, record)
+	b.waiters = append(b.waiters, done)
+	full := len(b.records) >= c.maxRecords
+	c.mu.Unlock()
+
+	if full {
+		b.timer.Stop()
+		c.flush(topic, b)
+	}
+	return done
+}
+
+// flush publishes a topic's batch, once, and reports the result to
+// every record in it.
+func (c *cdcCoalescer) flush(topic string, b *cdcTopicBatch) {
+	c.mu.Lock()
+	if b.flushed {
+		c.mu.Unlock()
+		return
+	}
+	b.flushed = true
+	if c.pending[topic] == b {
+		delete(c.pending, topic)
+	}
+	c.mu.Unlock()
+
+	err := c.publish(context.Background(), topic, b.records)
+	for _, done := range b.waiters {
+		done <- err
+	}
+}
+
+// routeCDCRecords routes a webhook delivery's records through the
+// coalescer and returns each record's result, in order. Records for
+// tables no topic covers succeed without being published.
+func routeCDCRecords(records []CDCRecord) []error {
+	results := make([]<-chan error, len(records))
+	for i, rec := range records {
+		if topic := topicForRecord(rec.Metadata.TableName, rec.Record); topic != "" {
+			results[i] = cdcCoalesce.add(topic, cdcEnvelopeEntry(rec))
+		}
+	}
+	errs := make([]error, len(records))
+	for i, done := range results {
+		if done != nil {
+			errs[i] = <-done
+		}
+	}
+	return errs
+}
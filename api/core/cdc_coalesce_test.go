@@ -0,0 +1,106 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type recordingPublisher struct {
+	mu      sync.Mutex
+	batches map[string][]int // topic -> records per publish
+	err     error
+}
+
+func (p *recordingPublisher) publish(_ context.Context, topic string, records []map[string]interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.batches == nil {
+		p.batches = make(map[string][]int)
+	}
+	p.batches[topic] = append(p.batches[topic], len(records))
+	return p.err
+}
+
+func TestCDCCoalescerBatchesPerTopic(t *testing.T) {
+	pub := &recordingPublisher{}
+	c := newCDCCoalescer(20*time.Millisecond, 100, pub.publish)
+
+	var waits []<-chan error
+	for i := 0; i < 3; i++ {
+		waits = append(waits, c.add("finance:AAPL", map[string]interface{}{"i": i}))
+	}
+	waits = append(waits, c.add("sports:NFL", map[string]interface{}{}))
+	for _, done := range waits {
+		if err := <-done; err != nil {
+			t.Fatalf("publish error: %v", err)
+		}
+	}
+
+	if got := pub.batches["finance:AAPL"]; len(got) != 1 || got[0] != 3 {
+		t.Errorf("finance:AAPL publishes = %v, want one of 3 records", got)
+	}
+	if got := pub.batches["sports:NFL"]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("sports:NFL publishes = %v, want one of 1 record", got)
+	}
+}
+
+func TestCDCCoalescerFlushesFullBatchEarly(t *testing.T) {
+	pub := &recordingPublisher{err: errors.New("redis down")}
+	c := newCDCCoalescer(time.Hour, 2, pub.publish)
+
+	first := c.add("finance:AAPL", map[string]interface{}{})
+	second := c.add("finance:AAPL", map[string]interface{}{})
+	for _, done := range []<-chan error{first, second} {
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Error("publish error was not passed to the waiter")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("full batch was not flushed before the window")
+		}
+	}
+	if got := pub.batches["finance:AAPL"]; len(got) != 1 || got[0] != 2 {
+		t.Errorf("publishes = %v, want one of 2 records", got)
+	}
+}
+
+func TestCDCWebhookBodyGzip(t *testing.T) {
+	payload := []byte(`{"data":[]}`)
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write(payload)
+	zw.Close()
+
+	app := fiber.New()
+	var got []byte
+	var gotErr error
+	app.Post("/", func(c *fiber.Ctx) error {
+		got, gotErr = cdcWebhookBody(c)
+		return nil
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(zipped.Bytes()))
+	req.Header.Set("Content-Encoding", "gzip")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if gotErr != nil || !bytes.Equal(got, payload) {
+		t.Errorf("body = %q, err = %v; want %q", got, gotErr, payload)
+	}
+
+	req = httptest.NewRequest("POST", "/", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+	app.Test(req)
+	if gotErr == nil {
+		t.Error("corrupt gzip body was accepted")
+	}
+}
@@ -0,0 +1,355 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// CDC dead-letter queue.
+//
+// CDC fan-out is the PUBLISH in publishCDCEnvelope; channels don't
+// receive records over HTTP any more (topicForRecord). A record whose publish
+// failed was logged and lost, and Sequin saw a 200 so never redelivered.
+// Failed records now go to the cdc:dlq Redis stream and a worker retries
+// them with exponential backoff. Each retry re-routes the record, so it
+// goes out with a fresh server_ts and seq. After CDCDLQMaxAttempts an
+// entry is marked dead and kept for GET/POST /admin/cdc/dlq. If the record
+// can't be queued either, the webhook answers 503 and Sequin redelivers
+// the batch.
+//
+// A retry deletes the entry and appends a new one, so entry IDs change
+// between attempts.
+
+// CDCDeadLetter is one queued record.
+type CDCDeadLetter struct {
+	ID            string    `json:"id"`
+	Table         string    `json:"table"`
+	Action        string    `json:"action"`
+	Topic         string    `json:"topic"`
+	Reason        string    `json:"reason"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Dead          bool      `json:"dead"`
+	Record        CDCRecord `json:"record"`
+}
+
+var cdcDLQEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "scrollr",
+	Subsystem: "cdc",
+	Name:      "dlq_events_total",
+	Help:      "CDC dead-letter queue transitions: queued, replayed, dead, or lost (could not be queued).",
+}, []string{"outcome"})
+
+func init() {
+	metricsRegistry.MustRegister(cdcDLQEvents)
+}
+
+// cdcDLQBackoff is the delay before the next attempt after the given
+// number of failures (1-based).
+func cdcDLQBackoff(attempts int) time.Duration {
+	d := CDCDLQBaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= CDCDLQMaxDelay {
+			return CDCDLQMaxDelay
+		}
+	}
+	return d
+}
+
+// addCDCDeadLetter appends dl to the stream, trimming the oldest entries
+// past CDCDLQMaxLen.
+func addCDCDeadLetter(ctx context.Context, dl CDCDeadLetter) error {
+	rec, err := json.Marshal(dl.Record)
+	if err != nil {
+		return fmt.Errorf("marshal record: %w", err)
+	}
+	dead := "0"
+	if dl.Dead {
+		dead = "1"
+	}
+	return Rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: RedisCDCDLQStream,
+		MaxLen: CDCDLQMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"record":          rec,
+			"reason":          dl.Reason,
+			"attempts":        dl.Attempts,
+			"first_failed_at": dl.FirstFailedAt.UnixMilli(),
+			"next_attempt_at": dl.NextAttemptAt.UnixMilli(),
+			"dead":            dead,
+		},
+	}).Err()
+}
+
+// enqueueCDCDeadLetter queues a record routeCDCRecord failed on and
+// reports whether it was queued.
+func enqueueCDCDeadLetter(ctx context.Context, rec CDCRecord, cause error) bool {
+	if Rdb == nil {
+		cdcDLQEvents.WithLabelValues("lost").Inc()
+		return false
+	}
+	now := time.Now()
+	err := addCDCDeadLetter(ctx, CDCDeadLetter{
+		Reason:        cause.Error(),
+		Attempts:      1,
+		FirstFailedAt: now,
+		NextAttemptAt: now.Add(cdcDLQBackoff(1)),
+		Record:        rec,
+	})
+	if err != nil {
+		cdcDLQEvents.WithLabelValues("lost").Inc()
+		log.Printf("[CDCDLQ] Failed to queue %s record (%v): %v", rec.Metadata.TableName, cause, err)
+		return false
+	}
+	cdcDLQEvents.WithLabelValues("queued").Inc()
+	log.Printf("[CDCDLQ] Queued %s/%s record: %v", rec.Metadata.TableName, rec.Action, cause)
+	return true
+}
+
+// parseCDCDeadLetter decodes a stream entry.
+func parseCDCDeadLetter(msg redis.XMessage) (CDCDeadLetter, error) {
+	str := func(k string) string { s, _ := msg.Values[k].(string); return s }
+	ms := func(k string) time.Time { n, _ := strconv.ParseInt(str(k), 10, 64); return time.UnixMilli(n).UTC() }
+
+	dl := CDCDeadLetter{
+		ID:            msg.ID,
+		Reason:        str("reason"),
+		FirstFailedAt: ms("first_failed_at"),
+		NextAttemptAt: ms("next_attempt_at"),
+		Dead:          str("dead") == "1",
+	}
+	dl.Attempts, _ = strconv.Atoi(str("attempts"))
+	if err := json.Unmarshal([]byte(str("record")), &dl.Record); err != nil {
+		return dl, fmt.Errorf("entry %s: %w", msg.ID, err)
+	}
+	dl.Table = dl.Record.Metadata.TableName
+	dl.Action = dl.Record.Action
+	dl.Topic = topicForRecord(dl.Table, dl.Record.Record)
+	return dl, nil
+}
+
+// retryCDCDeadLetter routes one entry. On success the entry is removed;
+// on failure it is re-queued with the next backoff, or marked dead once
+// it has used CDCDLQMaxAttempts (a dead entry stays dead).
+func retryCDCDeadLetter(ctx context.Context, dl CDCDeadLetter, route func(context.Context, CDCRecord) error) error {
+	routeErr := route(ctx, dl.Record)
+	if routeErr == nil {
+		if err := Rdb.XDel(ctx, RedisCDCDLQStream, dl.ID).Err(); err != nil {
+			return fmt.Errorf("delete %s: %w", dl.ID, err)
+		}
+		cdcDLQEvents.WithLabelValues("replayed").Inc()
+		return nil
+	}
+
+	now := time.Now()
+	next := dl
+	next.Attempts++
+	next.Reason = routeErr.Error()
+	next.NextAttemptAt = now.Add(cdcDLQBackoff(next.Attempts))
+	if !dl.Dead && next.Attempts >= CDCDLQMaxAttempts {
+		next.Dead = true
+		cdcDLQEvents.WithLabelValues("dead").Inc()
+		log.Printf("[CDCDLQ] Giving up on %s/%s record after %d attempts: %v", dl.Table, dl.Action, next.Attempts, routeErr)
+	}
+	// Append before deleting so a Redis error can't lose the record.
+	if err := addCDCDeadLetter(ctx, next); err != nil {
+		return fmt.Errorf("requeue %s: %w", dl.ID, err)
+	}
+	Rdb.XDel(ctx, RedisCDCDLQStream, dl.ID)
+	return routeErr
+}
+
+// StartCDCDeadLetterWorker retries due entries every CDCDLQInterval for
+// the lifetime of ctx.
+func StartCDCDeadLetterWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(CDCDLQInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processCDCDeadLetters(ctx, routeCDCRecord)
+			}
+		}
+	}()
+	log.Printf("[CDCDLQ] Worker started (%s interval)", CDCDLQInterval)
+}
+
+// processCDCDeadLetters walks the stream once and retries every live
+// entry whose backoff has elapsed. A lock keeps gateway replicas from
+// retrying the same entries concurrently.
+func processCDCDeadLetters(ctx context.Context, route func(context.Context, CDCRecord) error) {
+	if Rdb == nil {
+		return
+	}
+	ok, err := Rdb.SetNX(ctx, RedisCDCDLQLock, "1", CDCDLQLockTTL).Result()
+	if err != nil || !ok {
+		return
+	}
+	defer Rdb.Del(context.Background(), RedisCDCDLQLock)
+
+	ctx, cancel := context.WithTimeout(ctx, CDCDLQLockTTL)
+	defer cancel()
+
+	now := time.Now()
+	var replayed, failed int
+	start := "-"
+	for {
+		msgs, err := Rdb.XRangeN(ctx, RedisCDCDLQStream, start, "+", CDCDLQScanBatch).Result()
+		if err != nil {
+			log.Printf("[CDCDLQ] Failed to read queue: %v", err)
+			break
+		}
+		for _, msg := range msgs {
+			dl, err := parseCDCDeadLetter(msg)
+			if err != nil {
+				log.Printf("[CDCDLQ] Dropping unreadable %v", err)
+				Rdb.XDel(ctx, RedisCDCDLQStream, msg.ID)
+				continue
+			}
+			if dl.Dead || dl.NextAttemptAt.After(now) {
+				continue
+			}
+			if err := retryCDCDeadLetter(ctx, dl, route); err != nil {
+				failed++
+			} else {
+				replayed++
+			}
+		}
+		if len(msgs) < CDCDLQScanBatch {
+			break
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+	if replayed > 0 || failed > 0 {
+		log.Printf("[CDCDLQ] Retried %d record(s): %d replayed, %d failed", replayed+failed, replayed, failed)
+	}
+}
+
+// HandleAdminListCDCDeadLetters lists queued CDC records, oldest first.
+//
+// @Summary List CDC dead letters (admin)
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Max entries (default 100, max 1000)"
+// @Success 200 {object} object{length=int,entries=[]CDCDeadLetter}
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/cdc/dlq [get]
+func HandleAdminListCDCDeadLetters(c *fiber.Ctx) error {
+	limit := CDCDLQListDefault
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error", Error: "limit must be a positive integer",
+			})
+		}
+		limit = min(n, CDCDLQListMax)
+	}
+
+	ctx := c.Context()
+	length, err := Rdb.XLen(ctx, RedisCDCDLQStream).Result()
+	if err != nil {
+		log.Printf("[CDCDLQ] Failed to read queue length: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to read dead-letter queue",
+		})
+	}
+	msgs, err := Rdb.XRangeN(ctx, RedisCDCDLQStream, "-", "+", int64(limit)).Result()
+	if err != nil {
+		log.Printf("[CDCDLQ] Failed to read queue: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to read dead-letter queue",
+		})
+	}
+	entries := make([]CDCDeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		if dl, err := parseCDCDeadLetter(msg); err == nil {
+			entries = append(entries, dl)
+		}
+	}
+	return c.JSON(fiber.Map{"length": length, "entries": entries})
+}
+
+// cdcDLQReplayRequest selects entries to replay. No IDs means all of
+// them, dead or not.
+type cdcDLQReplayRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// HandleAdminReplayCDCDeadLetters routes the selected entries now,
+// ignoring backoff and dead marks. Entries that fail again are re-queued.
+//
+// @Summary Replay CDC dead letters (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body cdcDLQReplayRequest false "Entry IDs; empty replays everything"
+// @Success 200 {object} object{replayed=int,failed=int,missing=int}
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/cdc/dlq [post]
+func HandleAdminReplayCDCDeadLetters(c *fiber.Ctx) error {
+	var req cdcDLQReplayRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error", Error: "Invalid request body",
+			})
+		}
+	}
+
+	ctx := c.Context()
+	var msgs []redis.XMessage
+	missing := 0
+	if len(req.IDs) == 0 {
+		all, err := Rdb.XRangeN(ctx, RedisCDCDLQStream, "-", "+", CDCDLQMaxLen).Result()
+		if err != nil {
+			log.Printf("[CDCDLQ] Failed to read queue: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error", Error: "Failed to read dead-letter queue",
+			})
+		}
+		msgs = all
+	} else {
+		for _, id := range req.IDs {
+			found, err := Rdb.XRangeN(ctx, RedisCDCDLQStream, id, id, 1).Result()
+			if err != nil || len(found) == 0 {
+				missing++
+				continue
+			}
+			msgs = append(msgs, found[0])
+		}
+	}
+
+	var replayed, failed int
+	for _, msg := range msgs {
+		dl, err := parseCDCDeadLetter(msg)
+		if err != nil {
+			failed++
+			continue
+		}
+		if err := retryCDCDeadLetter(ctx, dl, routeCDCRecord); err != nil {
+			failed++
+		} else {
+			replayed++
+		}
+	}
+	log.Printf("[CDCDLQ] Admin replay by %s: %d replayed, %d failed, %d missing",
+		GetUserID(c), replayed, failed, missing)
+	return c.JSON(fiber.Map{"replayed": replayed, "failed": failed, "missing": missing})
+}
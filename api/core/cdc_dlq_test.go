@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCDCDLQBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  CDCDLQBaseDelay,
+		2:  2 * CDCDLQBaseDelay,
+		3:  4 * CDCDLQBaseDelay,
+		20: CDCDLQMaxDelay,
+	}
+	for attempts, want := range cases {
+		if got := cdcDLQBackoff(attempts); got != want {
+			t.Errorf("cdcDLQBackoff(%d) = %s; want %s", attempts, got, want)
+		}
+	}
+}
+
+func readCDCDeadLetters(t *testing.T) []CDCDeadLetter {
+	t.Helper()
+	msgs, err := Rdb.XRange(context.Background(), RedisCDCDLQStream, "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]CDCDeadLetter, 0, len(msgs))
+	for _, m := range msgs {
+		dl, err := parseCDCDeadLetter(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, dl)
+	}
+	return out
+}
+
+// makeDue rewrites every entry's next_attempt_at into the past.
+func makeDue(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	for _, dl := range readCDCDeadLetters(t) {
+		dl.NextAttemptAt = time.Now().Add(-time.Second)
+		if err := addCDCDeadLetter(ctx, dl); err != nil {
+			t.Fatal(err)
+		}
+		Rdb.XDel(ctx, RedisCDCDLQStream, dl.ID)
+	}
+}
+
+func TestCDCDeadLetterRetry(t *testing.T) {
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	rec := CDCRecord{Action: "update", Record: map[string]interface{}{"symbol": "AAPL", "price": 1.5}}
+	rec.Metadata.TableName = "trades"
+	if !enqueueCDCDeadLetter(ctx, rec, errors.New("publish: connection reset")) {
+		t.Fatal("enqueue failed")
+	}
+
+	entries := readCDCDeadLetters(t)
+	if len(entries) != 1 {
+		t.Fatalf("queued %d entries; want 1", len(entries))
+	}
+	got := entries[0]
+	if got.Attempts != 1 || got.Dead || got.Topic != TopicPrefixFinance+"AAPL" || got.Record.Record["symbol"] != "AAPL" {
+		t.Errorf("queued entry = %+v", got)
+	}
+
+	// Not due yet: the route must not be called.
+	calls := 0
+	failing := func(context.Context, CDCRecord) error { calls++; return errors.New("still down") }
+	processCDCDeadLetters(ctx, failing)
+	if calls != 0 {
+		t.Fatalf("retried %d times before backoff elapsed", calls)
+	}
+
+	// Fail until dead.
+	for i := 1; i < CDCDLQMaxAttempts; i++ {
+		makeDue(t)
+		processCDCDeadLetters(ctx, failing)
+	}
+	entries = readCDCDeadLetters(t)
+	if len(entries) != 1 || !entries[0].Dead || entries[0].Attempts != CDCDLQMaxAttempts || entries[0].Reason != "still down" {
+		t.Fatalf("after %d failures: %+v", CDCDLQMaxAttempts, entries)
+	}
+
+	// Dead entries are left alone by the worker.
+	calls = 0
+	makeDue(t)
+	processCDCDeadLetters(ctx, failing)
+	if calls != 0 {
+		t.Errorf("worker retried a dead entry")
+	}
+
+	// A successful retry removes the entry.
+	var routed CDCRecord
+	ok := func(_ context.Context, r CDCRecord) error { routed = r; return nil }
+	if err := retryCDCDeadLetter(ctx, readCDCDeadLetters(t)[0], ok); err != nil {
+		t.Fatal(err)
+	}
+	if routed.Metadata.TableName != "trades" || routed.Record["symbol"] != "AAPL" {
+		t.Errorf("routed %+v", routed)
+	}
+	if n := len(readCDCDeadLetters(t)); n != 0 {
+		t.Errorf("%d entries left after successful replay", n)
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Channel circuit breakers.
+//
+// A channel API that is down otherwise costs every dashboard request its
+// full fan-out budget and every /health miss a HealthCheckTimeout. Each
+// channel gets a circuitBreaker (redis_breaker.go) shared by the
+// dashboard fan-out and the health check: after
+// ChannelBreakerFailureThreshold consecutive failures it opens, and for
+// ChannelBreakerCooldown the channel is reported unavailable without a
+// request being made. Then one call goes through as a probe; if it
+// succeeds the channel is back.
+
+// errChannelStatus wraps a 5xx reply so it counts as a failure.
+type errChannelStatus int
+
+func (e errChannelStatus) Error() string { return fmt.Sprintf("status %d", int(e)) }
+
+var channelBreakers = struct {
+	mu sync.Mutex
+	m  map[string]*circuitBreaker
+}{m: make(map[string]*circuitBreaker)}
+
+// channelBreaker returns the named channel's breaker, creating it on
+// first use.
+func channelBreaker(name string) *circuitBreaker {
+	channelBreakers.mu.Lock()
+	defer channelBreakers.mu.Unlock()
+	b, ok := channelBreakers.m[name]
+	if !ok {
+		b = &circuitBreaker{
+			name:      "Channel:" + name,
+			threshold: ChannelBreakerFailureThreshold,
+			cooldown:  ChannelBreakerCooldown,
+			isFailure: isChannelFailure,
+			now:       time.Now,
+		}
+		channelBreakers.m[name] = b
+	}
+	return b
+}
+
+// isChannelFailure counts transport errors, timeouts and 5xx replies. A
+// caller giving up (client disconnect) says nothing about the channel.
+func isChannelFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Channel registration status.
+//
+// A channel's registration is one Redis key with a TTL; when the process
+// dies the key just expires and discovery quietly drops the channel. The
+// tracker below remembers every channel this replica has seen and records
+// what happened to it: (re)registrations, version changes, expiry, and
+// health-check transitions. An expiry is logged, reported to Sentry, and
+// counted in scrollr_discovery_channel_expirations_total; the
+// scrollr_discovery_channel_up gauge drops to 0 until the channel comes
+// back. GET /channels/status exposes the lot to admins.
+//
+// State is per gateway replica and starts empty on boot.
+
+// ChannelStatusHistoryLen is how many events each channel keeps.
+const ChannelStatusHistoryLen = 20
+
+// Channel status events.
+const (
+	ChannelEventRegistered = "registered" // first seen, or back after expiring
+	ChannelEventRestarted  = "restarted"  // new started_at, same version
+	ChannelEventDeployed   = "deployed"   // new version
+	ChannelEventExpired    = "expired"    // registration key disappeared
+	ChannelEventHealthDown = "health_down"
+	ChannelEventHealthUp   = "health_up"
+)
+
+// ChannelStatusEvent is one entry in a channel's history.
+type ChannelStatusEvent struct {
+	At     time.Time `json:"at"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// ChannelStatus is what this replica knows about one channel.
+type ChannelStatus struct {
+	Name            string               `json:"name"`
+	DisplayName     string               `json:"display_name"`
+	Version         string               `json:"version"`
+	Registered      bool                 `json:"registered"`
+	StartedAt       *time.Time           `json:"started_at"`
+	FirstSeen       time.Time            `json:"first_seen"`
+	LastSeen        time.Time            `json:"last_seen"`
+	ExpiredAt       *time.Time           `json:"expired_at"`
+	Health          string               `json:"health"` // healthy, down, or "" before the first check
+	LastHealthCheck *time.Time           `json:"last_health_check"`
+	History         []ChannelStatusEvent `json:"history"`
+}
+
+func (s *ChannelStatus) record(at time.Time, event, detail string) {
+	s.History = append(s.History, ChannelStatusEvent{At: at, Event: event, Detail: detail})
+	if over := len(s.History) - ChannelStatusHistoryLen; over > 0 {
+		s.History = append(s.History[:0:0], s.History[over:]...)
+	}
+}
+
+type channelStatusTracker struct {
+	mu       sync.Mutex
+	channels map[string]*ChannelStatus
+}
+
+var channelStatuses = &channelStatusTracker{channels: make(map[string]*ChannelStatus)}
+
+var (
+	channelUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "scrollr",
+		Subsystem: "discovery",
+		Name:      "channel_up",
+		Help:      "1 while a previously seen channel is registered, 0 after its registration expires.",
+	}, []string{"channel"})
+
+	channelExpirations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "scrollr",
+		Subsystem: "discovery",
+		Name:      "channel_expirations_total",
+		Help:      "Channel registrations that expired without the channel re-registering.",
+	}, []string{"channel"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(channelUp, channelExpirations)
+}
+
+func channelStartedAt(info *ChannelInfo) *time.Time {
+	if info.StartedAt <= 0 {
+		return nil
+	}
+	t := time.UnixMilli(info.StartedAt).UTC()
+	return &t
+}
+
+// observe reconciles the tracker with a discovery scan taken at now and
+// returns the names of channels that expired since the last scan.
+func (t *channelStatusTracker) observe(current map[string]*ChannelInfo, now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, info := range current {
+		st, seen := t.channels[name]
+		if !seen {
+			st = &ChannelStatus{Name: name, FirstSeen: now}
+			t.channels[name] = st
+		}
+		switch {
+		case !seen || !st.Registered:
+			st.record(now, ChannelEventRegistered, info.Version)
+		case info.Version != st.Version:
+			st.record(now, ChannelEventDeployed, fmt.Sprintf("%s -> %s", st.Version, info.Version))
+		case info.StartedAt != 0 && (st.StartedAt == nil || info.StartedAt != st.StartedAt.UnixMilli()):
+			st.record(now, ChannelEventRestarted, "")
+		}
+		st.DisplayName = info.DisplayName
+		st.Version = info.Version
+		st.StartedAt = channelStartedAt(info)
+		st.Registered = true
+		st.ExpiredAt = nil
+		st.LastSeen = now
+		channelUp.WithLabelValues(name).Set(1)
+	}
+
+	var expired []string
+	for name, st := range t.channels {
+		if _, ok := current[name]; ok || !st.Registered {
+			continue
+		}
+		at := now
+		st.Registered = false
+		st.ExpiredAt = &at
+		st.record(now, ChannelEventExpired, fmt.Sprintf("last seen %s", st.LastSeen.Format(time.RFC3339)))
+		channelUp.WithLabelValues(name).Set(0)
+		channelExpirations.WithLabelValues(name).Inc()
+		expired = append(expired, name)
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+// recordHealth notes a health-check result, adding a history event only
+// when the channel's health changes.
+func (t *channelStatusTracker) recordHealth(name string, healthy bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.channels[name]
+	if !ok {
+		return
+	}
+	health := "healthy"
+	if !healthy {
+		health = "down"
+	}
+	if health != st.Health {
+		event := ChannelEventHealthUp
+		if !healthy {
+			event = ChannelEventHealthDown
+		}
+		// The first check only logs an event when it fails.
+		if st.Health != "" || !healthy {
+			st.record(now, event, "")
+		}
+		st.Health = health
+	}
+	at := now
+	st.LastHealthCheck = &at
+}
+
+// snapshot returns copies of every tracked channel, sorted by name.
+func (t *channelStatusTracker) snapshot() []ChannelStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]ChannelStatus, 0, len(t.channels))
+	for _, st := range t.channels {
+		cp := *st
+		cp.History = append([]ChannelStatusEvent(nil), st.History...)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// reportChannelExpired logs and alerts on a channel whose registration
+// lapsed.
+func reportChannelExpired(name string) {
+	var lastSeen time.Time
+	var version string
+	channelStatuses.mu.Lock()
+	if st, ok := channelStatuses.channels[name]; ok {
+		lastSeen, version = st.LastSeen, st.Version
+	}
+	channelStatuses.mu.Unlock()
+
+	log.Printf("[Discovery] Channel %s registration expired (version %s, last seen %s)",
+		name, version, lastSeen.Format(time.RFC3339))
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetTag("channel", name)
+		scope.SetTag("channel_version", version)
+		scope.SetLevel(sentry.LevelWarning)
+		sentry.CaptureMessage(fmt.Sprintf("channel %s registration expired", name))
+	})
+}
+
+// HandleChannelStatus lists every channel this gateway replica has seen,
+// with registration state, last-seen times and recent history.
+// @Summary Channel registration status
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{channels=[]ChannelStatus}
+// @Failure 403 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /channels/status [get]
+func HandleChannelStatus(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"channels": channelStatuses.snapshot()})
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func channelEvents(st ChannelStatus) []string {
+	out := make([]string, len(st.History))
+	for i, e := range st.History {
+		out[i] = e.Event
+	}
+	return out
+}
+
+func TestChannelStatusTracker(t *testing.T) {
+	tr := &channelStatusTracker{channels: make(map[string]*ChannelStatus)}
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tick := func(n int) time.Time { return t0.Add(time.Duration(n) * 10 * time.Second) }
+
+	finance := &ChannelInfo{Name: "finance", Version: "abc", StartedAt: 1000}
+	rss := &ChannelInfo{Name: "rss", Version: "r1", StartedAt: 1000}
+
+	if exp := tr.observe(map[string]*ChannelInfo{"finance": finance, "rss": rss}, tick(0)); len(exp) != 0 {
+		t.Fatalf("first scan expired %v", exp)
+	}
+	// Same registration again: no new events.
+	tr.observe(map[string]*ChannelInfo{"finance": finance, "rss": rss}, tick(1))
+
+	// rss restarts, finance disappears.
+	rss2 := &ChannelInfo{Name: "rss", Version: "r1", StartedAt: 2000}
+	if exp := tr.observe(map[string]*ChannelInfo{"rss": rss2}, tick(2)); len(exp) != 1 || exp[0] != "finance" {
+		t.Fatalf("expired = %v, want [finance]", exp)
+	}
+	// Expiry is reported once, not on every scan it stays missing.
+	if exp := tr.observe(map[string]*ChannelInfo{"rss": rss2}, tick(3)); len(exp) != 0 {
+		t.Errorf("expired again: %v", exp)
+	}
+	// finance comes back on a new build.
+	tr.observe(map[string]*ChannelInfo{"rss": rss2, "finance": {Name: "finance", Version: "def", StartedAt: 3000}}, tick(4))
+	tr.observe(map[string]*ChannelInfo{"rss": {Name: "rss", Version: "r2", StartedAt: 4000}, "finance": {Name: "finance", Version: "def", StartedAt: 3000}}, tick(5))
+
+	tr.recordHealth("rss", true, tick(5))
+	tr.recordHealth("rss", false, tick(6))
+	tr.recordHealth("rss", false, tick(7))
+	tr.recordHealth("rss", true, tick(8))
+	tr.recordHealth("weather", false, tick(8))
+
+	snap := tr.snapshot()
+	if len(snap) != 2 || snap[0].Name != "finance" || snap[1].Name != "rss" {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+	fin, rs := snap[0], snap[1]
+
+	wantFin := []string{ChannelEventRegistered, ChannelEventExpired, ChannelEventRegistered}
+	if got := channelEvents(fin); !slices.Equal(got, wantFin) {
+		t.Errorf("finance events = %v, want %v", got, wantFin)
+	}
+	if !fin.Registered || fin.ExpiredAt != nil || fin.Version != "def" || !fin.LastSeen.Equal(tick(5)) || !fin.FirstSeen.Equal(tick(0)) {
+		t.Errorf("finance status = %+v", fin)
+	}
+
+	wantRSS := []string{ChannelEventRegistered, ChannelEventRestarted, ChannelEventDeployed, ChannelEventHealthDown, ChannelEventHealthUp}
+	if got := channelEvents(rs); !slices.Equal(got, wantRSS) {
+		t.Errorf("rss events = %v, want %v", got, wantRSS)
+	}
+	if rs.Health != "healthy" || rs.LastHealthCheck == nil || !rs.LastHealthCheck.Equal(tick(8)) {
+		t.Errorf("rss health = %q at %v", rs.Health, rs.LastHealthCheck)
+	}
+	if rs.StartedAt == nil || rs.StartedAt.UnixMilli() != 4000 {
+		t.Errorf("rss started_at = %v", rs.StartedAt)
+	}
+}
+
+func TestChannelStatusHistoryCap(t *testing.T) {
+	var st ChannelStatus
+	for i := 0; i < ChannelStatusHistoryLen+5; i++ {
+		st.record(time.Unix(int64(i), 0), ChannelEventRestarted, "")
+	}
+	if len(st.History) != ChannelStatusHistoryLen || st.History[0].At.Unix() != 5 {
+		t.Errorf("history len %d starting at %d", len(st.History), st.History[0].At.Unix())
+	}
+}
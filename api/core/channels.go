@@ -1,23 +1,18 @@
 package core
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 )
 
-var lifecycleClient = &http.Client{
-	Timeout: 10 * time.Second,
-}
+var lifecycleClient = NewInternalHTTPClient("channel_lifecycle", ChannelLifecycleTimeout)
 
 // GetUserChannels fetches all channels for a user.
 func GetUserChannels(logtoSub string) ([]Channel, error) {
@@ -123,6 +118,18 @@ func addChannelSubscriptions(ctx context.Context, logtoSub, channelType string,
 // For sports, this also removes the user from all per-league subscriber sets.
 // Also updates the in-memory topic registry for active SSE connections.
 func removeChannelSubscriptions(ctx context.Context, logtoSub, channelType string, config map[string]interface{}) {
+	removeSubscriberSets(ctx, logtoSub, channelType, config)
+
+	// Rebuild topic subscriptions so active SSE connections stop receiving this channel
+	UpdateUserTopicSubscriptions(logtoSub)
+
+	enabled := false
+	callChannelLifecycle(ctx, channelType, "sync", logtoSub, config, nil, &enabled)
+}
+
+// removeSubscriberSets drops the user from the channel's Redis subscriber
+// set and, for sports, the per-league sets.
+func removeSubscriberSets(ctx context.Context, logtoSub, channelType string, config map[string]interface{}) {
 	RemoveSubscriber(ctx, RedisChannelSubscribersPrefix+channelType, logtoSub)
 
 	// Sports: remove from per-league subscriber sets for user's configured leagues.
@@ -138,57 +145,37 @@ func removeChannelSubscriptions(ctx context.Context, logtoSub, channelType strin
 			}
 		}
 	}
-
-	// Rebuild topic subscriptions so active SSE connections stop receiving this channel
-	UpdateUserTopicSubscriptions(logtoSub)
-
-	enabled := false
-	callChannelLifecycle(ctx, channelType, "sync", logtoSub, config, nil, &enabled)
 }
 
 // callChannelLifecycle sends a lifecycle event to a channel if it has the channel_lifecycle capability.
+// Events that can't be delivered — channel not registered, transport error,
+// non-200, or earlier events still queued — go to the retry queue
+// (lifecycle_retry.go) instead of being dropped.
 func callChannelLifecycle(ctx context.Context, channelType, event, userSub string, config, oldConfig map[string]interface{}, enabled *bool) {
-	ch := GetChannel(channelType)
-	if ch == nil || !ch.HasCapability("channel_lifecycle") {
-		return
+	ev := lifecycleEvent{
+		Event:     event,
+		User:      userSub,
+		Config:    config,
+		OldConfig: oldConfig,
+		Enabled:   enabled,
 	}
 
-	body := map[string]interface{}{
-		"event":  event,
-		"user":   userSub,
-		"config": config,
-	}
-	if oldConfig != nil {
-		body["old_config"] = oldConfig
-	}
-	if enabled != nil {
-		body["enabled"] = *enabled
-	}
-
-	reqBody, err := json.Marshal(body)
-	if err != nil {
-		log.Printf("[Channels] Failed to marshal lifecycle request: %v", err)
+	ch := GetChannel(channelType)
+	if ch == nil {
+		enqueueLifecycleRetry(channelType, ev, "channel not registered")
 		return
 	}
-
-	url := ch.InternalURL + "/internal/channel-lifecycle"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
-	if err != nil {
-		log.Printf("[Channels] Failed to create lifecycle request: %v", err)
+	if !ch.HasCapability("channel_lifecycle") {
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := lifecycleClient.Do(req)
-	if err != nil {
-		log.Printf("[Channels] Lifecycle call to %s/%s failed: %v", ch.Name, event, err)
+	if lifecycleRetryPending(ctx, channelType) {
+		enqueueLifecycleRetry(channelType, ev, "earlier events pending")
 		return
 	}
-	defer resp.Body.Close()
-	io.Copy(io.Discard, resp.Body)
 
-	if resp.StatusCode != 200 {
-		log.Printf("[Channels] Lifecycle call to %s/%s returned status %d", ch.Name, event, resp.StatusCode)
+	if err := deliverLifecycleEvent(ctx, ch, ev); err != nil {
+		log.Printf("[Channels] Lifecycle call to %s/%s failed: %v", ch.Name, event, err)
+		enqueueLifecycleRetry(channelType, ev, err.Error())
 	}
 }
 
@@ -231,7 +218,8 @@ func GetChannels(c *fiber.Ctx) error {
 // @Produce json
 // @Param body body object true "Channel creation request" example({"channel_type":"rss","config":{}})
 // @Success 201 {object} Channel
-// @Failure 400 {object} ErrorResponse
+// @Failure 400 {object} ConfigValidationErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
 // @Security LogtoAuth
 // @Router /users/me/channels [post]
@@ -268,28 +256,37 @@ func CreateChannel(c *fiber.Ctx) error {
 		req.Config = map[string]interface{}{}
 	}
 
+	if err := validateChannelConfigSchema(req.ChannelType, req.Config); err != nil {
+		return channelConfigSchemaFailure(c, err)
+	}
+
 	// Tier-gate the config shape. Frontend already enforces these caps
 	// but the API is the only place that actually matters — the Rust
 	// ingestion services trust user_channels.config verbatim.
 	tier := tierFromRoles(GetUserRoles(c))
 	if err := ValidateChannelConfig(tier, req.ChannelType, req.Config); err != nil {
-		var tle *TierLimitError
-		if errors.As(err, &tle) {
-			log.Printf("[Channels] Tier limit exceeded for %s: %s", userID, tle.Error())
-			return c.Status(fiber.StatusForbidden).JSON(tierLimitErrorResponse(tle))
-		}
-		// Defensive — the validator should only return *TierLimitError.
-		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+		return channelTierLimitFailure(c, userID, err)
+	}
+
+	// New channels start enabled, so they count against the tier's
+	// channel cap.
+	enabled, _, err := countEnabledChannels(context.Background(), userID, req.ChannelType)
+	if err != nil {
+		log.Printf("[Channels] Count error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 			Status: "error",
-			Error:  err.Error(),
+			Error:  "Failed to create channel",
 		})
 	}
+	if err := ValidateChannelCount(tier, enabled, enabled+1); err != nil {
+		return channelTierLimitFailure(c, userID, err)
+	}
 
 	configJSON, _ := json.Marshal(req.Config)
 
 	var ch Channel
 	var configBytes []byte
-	err := DBPool.QueryRow(context.Background(), `
+	err = DBPool.QueryRow(context.Background(), `
 		INSERT INTO user_channels (logto_sub, channel_type, config)
 		VALUES ($1, $2, $3)
 		RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
@@ -323,6 +320,7 @@ func CreateChannel(c *fiber.Ctx) error {
 
 	// Call OnChannelCreated hook via HTTP
 	callChannelLifecycle(ctx, ch.ChannelType, "created", userID, ch.Config, nil, nil)
+	AuditSelf(c, AuditChannelCreated, map[string]any{"channel_type": ch.ChannelType})
 
 	// Invalidate dashboard cache so next poll gets fresh data
 	InvalidateDashboardCache(userID)
@@ -343,6 +341,8 @@ func CreateChannel(c *fiber.Ctx) error {
 // @Param type path string true "Channel type (finance, sports, fantasy, rss)"
 // @Param body body object true "Channel update request"
 // @Success 200 {object} Channel
+// @Failure 400 {object} ConfigValidationErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Security LogtoAuth
 // @Router /users/me/channels/{type} [put]
@@ -388,19 +388,31 @@ func UpdateChannel(c *fiber.Ctx) error {
 	// provided — updates that only toggle enabled/visible should not
 	// re-validate (they're expected to be cheap + frequent, e.g. pause
 	// the channel).
+	tier := tierFromRoles(GetUserRoles(c))
 	if req.Config != nil {
-		tier := tierFromRoles(GetUserRoles(c))
+		if err := validateChannelConfigSchema(channelType, req.Config); err != nil {
+			return channelConfigSchemaFailure(c, err)
+		}
 		if err := ValidateChannelConfig(tier, channelType, req.Config); err != nil {
-			var tle *TierLimitError
-			if errors.As(err, &tle) {
-				log.Printf("[Channels] Tier limit exceeded for %s: %s", userID, tle.Error())
-				return c.Status(fiber.StatusForbidden).JSON(tierLimitErrorResponse(tle))
-			}
-			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			return channelTierLimitFailure(c, userID, err)
+		}
+	}
+
+	// Enabling a channel counts against the tier's channel cap.
+	// Re-enabling one that is already on leaves the count unchanged and
+	// always passes.
+	if req.Enabled != nil && *req.Enabled {
+		enabled, others, err := countEnabledChannels(context.Background(), userID, channelType)
+		if err != nil {
+			log.Printf("[Channels] Count error: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 				Status: "error",
-				Error:  err.Error(),
+				Error:  "Failed to update channel",
 			})
 		}
+		if err := ValidateChannelCount(tier, enabled, others+1); err != nil {
+			return channelTierLimitFailure(c, userID, err)
+		}
 	}
 
 	// Fetch old config before UPDATE so channels can diff
@@ -415,34 +427,7 @@ func UpdateChannel(c *fiber.Ctx) error {
 		}
 	}
 
-	// Build dynamic UPDATE query
-	setClauses := []string{"updated_at = now()"}
-	args := []interface{}{userID, channelType}
-	argIdx := 3
-
-	if req.Enabled != nil {
-		setClauses = append(setClauses, fmt.Sprintf("enabled = $%d", argIdx))
-		args = append(args, *req.Enabled)
-		argIdx++
-	}
-	if req.Visible != nil {
-		setClauses = append(setClauses, fmt.Sprintf("visible = $%d", argIdx))
-		args = append(args, *req.Visible)
-		argIdx++
-	}
-	if req.Config != nil {
-		configJSON, _ := json.Marshal(req.Config)
-		setClauses = append(setClauses, fmt.Sprintf("config = $%d", argIdx))
-		args = append(args, configJSON)
-		argIdx++
-	}
-
-	query := fmt.Sprintf(`
-		UPDATE user_channels
-		SET %s
-		WHERE logto_sub = $1 AND channel_type = $2
-		RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
-	`, strings.Join(setClauses, ", "))
+	query, args := channelUpdateQuery(userID, channelType, req.Enabled, req.Visible, req.Config)
 
 	var ch Channel
 	var configBytes []byte
@@ -479,6 +464,7 @@ func UpdateChannel(c *fiber.Ctx) error {
 
 	// Call OnChannelUpdated hook via HTTP
 	callChannelLifecycle(ctx, channelType, "updated", userID, ch.Config, oldConfig, nil)
+	AuditSelf(c, AuditChannelUpdated, map[string]any{"channel_type": channelType})
 
 	// Invalidate dashboard cache so next poll gets fresh data
 	InvalidateDashboardCache(userID)
@@ -488,6 +474,39 @@ func UpdateChannel(c *fiber.Ctx) error {
 	return c.JSON(ch)
 }
 
+// channelUpdateQuery builds the UPDATE for a channel, setting only the
+// fields that were provided.
+func channelUpdateQuery(userID, channelType string, enabled, visible *bool, config map[string]interface{}) (string, []interface{}) {
+	setClauses := []string{"updated_at = now()"}
+	args := []interface{}{userID, channelType}
+	argIdx := 3
+
+	if enabled != nil {
+		setClauses = append(setClauses, fmt.Sprintf("enabled = $%d", argIdx))
+		args = append(args, *enabled)
+		argIdx++
+	}
+	if visible != nil {
+		setClauses = append(setClauses, fmt.Sprintf("visible = $%d", argIdx))
+		args = append(args, *visible)
+		argIdx++
+	}
+	if config != nil {
+		configJSON, _ := json.Marshal(config)
+		setClauses = append(setClauses, fmt.Sprintf("config = $%d", argIdx))
+		args = append(args, configJSON)
+		argIdx++
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE user_channels
+		SET %s
+		WHERE logto_sub = $1 AND channel_type = $2
+		RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
+	`, strings.Join(setClauses, ", "))
+	return query, args
+}
+
 // DeleteChannel removes a channel by type for the authenticated user.
 //
 // @Summary Delete a channel
@@ -547,6 +566,7 @@ func DeleteChannel(c *fiber.Ctx) error {
 
 	// Call OnChannelDeleted hook via HTTP
 	callChannelLifecycle(ctx, channelType, "deleted", userID, config, nil, nil)
+	AuditSelf(c, AuditChannelDeleted, map[string]any{"channel_type": channelType})
 
 	// Invalidate dashboard cache so next poll gets fresh data
 	InvalidateDashboardCache(userID)
@@ -556,6 +576,352 @@ func DeleteChannel(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "ok", "message": "Channel removed"})
 }
 
+// channelBatchOp is one operation in a POST /users/me/channels/batch body.
+// Fields mirror the single-channel endpoints: create takes config, update
+// takes any of enabled/visible/ticker_enabled/config, delete takes nothing
+// beyond the type.
+type channelBatchOp struct {
+	Op            string                 `json:"op"` // create | update | delete
+	ChannelType   string                 `json:"channel_type"`
+	Enabled       *bool                  `json:"enabled,omitempty"`
+	Visible       *bool                  `json:"visible,omitempty"`
+	TickerEnabled *bool                  `json:"ticker_enabled,omitempty"`
+	Config        map[string]interface{} `json:"config,omitempty"`
+}
+
+// channelBatchError rejects a batch, naming the operation responsible.
+// Index is -1 when the batch as a whole is invalid. Fields carries the
+// operation's schema violations, if that's what failed.
+type channelBatchError struct {
+	Index  int
+	Status int
+	Msg    string
+	Fields []ConfigFieldError
+}
+
+func (e *channelBatchError) Error() string {
+	if e.Index < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Msg)
+}
+
+// appliedChannelOp is a committed batch operation, kept for the
+// post-commit hooks.
+type appliedChannelOp struct {
+	op        string
+	channel   Channel
+	oldConfig map[string]interface{} // update with config: the row before; delete: the deleted config
+}
+
+// validateChannelBatch checks every operation before anything is written,
+// so a bad entry late in the batch fails fast instead of rolling back.
+// Tier limits apply to each config exactly as on the single endpoints.
+func validateChannelBatch(tier string, ops []channelBatchOp, validTypes map[string]bool) error {
+	if len(ops) == 0 {
+		return &channelBatchError{Index: -1, Status: fiber.StatusBadRequest, Msg: "operations is required"}
+	}
+	if len(ops) > ChannelBatchMaxOps {
+		return &channelBatchError{Index: -1, Status: fiber.StatusBadRequest,
+			Msg: fmt.Sprintf("At most %d operations per batch", ChannelBatchMaxOps)}
+	}
+	for i := range ops {
+		op := &ops[i]
+		if op.Op != "delete" && !validTypes[op.ChannelType] {
+			return &channelBatchError{Index: i, Status: fiber.StatusBadRequest, Msg: "Invalid channel type"}
+		}
+		switch op.Op {
+		case "create":
+			if op.Config == nil {
+				op.Config = map[string]interface{}{}
+			}
+		case "update":
+			if op.TickerEnabled != nil {
+				op.Visible = op.TickerEnabled
+			}
+		case "delete":
+			if op.ChannelType == "" {
+				return &channelBatchError{Index: i, Status: fiber.StatusBadRequest, Msg: "channel_type is required"}
+			}
+			continue
+		default:
+			return &channelBatchError{Index: i, Status: fiber.StatusBadRequest, Msg: "op must be create, update or delete"}
+		}
+		if op.Config == nil {
+			continue
+		}
+		if err := validateChannelConfigSchema(op.ChannelType, op.Config); err != nil {
+			be := &channelBatchError{Index: i, Status: fiber.StatusBadRequest, Msg: "Invalid channel config"}
+			var cve *ConfigValidationError
+			if errors.As(err, &cve) {
+				be.Fields = cve.Fields
+			}
+			return be
+		}
+		if err := ValidateChannelConfig(tier, op.ChannelType, op.Config); err != nil {
+			var tle *TierLimitError
+			if errors.As(err, &tle) {
+				return tle
+			}
+			return &channelBatchError{Index: i, Status: fiber.StatusBadRequest, Msg: err.Error()}
+		}
+	}
+	return nil
+}
+
+// BatchChannels applies several channel creates, updates and deletes
+// atomically. The onboarding wizard sets up every channel at once; doing
+// it through the single endpoints costs N round-trips, N subscription
+// syncs and N dashboard invalidations, and leaves a half-configured
+// account if one call fails midway.
+//
+// @Summary Apply channel operations in one transaction
+// @Description Applies an ordered list of create/update/delete operations. Either all succeed or none are applied. Returns the user's channels afterwards.
+// @Tags Channels
+// @Accept json
+// @Produce json
+// @Param body body object true "Batch request" example({"operations":[{"op":"create","channel_type":"finance","config":{"symbols":["AAPL"]}},{"op":"update","channel_type":"rss","enabled":false},{"op":"delete","channel_type":"sports"}]})
+// @Success 200 {object} object{channels=[]Channel}
+// @Failure 400 {object} ConfigValidationErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/channels/batch [post]
+func BatchChannels(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		Operations []channelBatchOp `json:"operations"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	tier := tierFromRoles(GetUserRoles(c))
+	if err := validateChannelBatch(tier, req.Operations, GetValidChannelTypes()); err != nil {
+		return channelBatchFailure(c, userID, err)
+	}
+	applied, err := applyChannelBatch(context.Background(), userID, tier, req.Operations)
+	if err != nil {
+		return channelBatchFailure(c, userID, err)
+	}
+	return finishChannelBatch(c, userID, applied)
+}
+
+// channelTierLimitFailure answers a tier validation error from the single
+// channel endpoints: 403 with the structured limit body, or 400 for
+// anything else.
+func channelTierLimitFailure(c *fiber.Ctx, userID string, err error) error {
+	var tle *TierLimitError
+	if errors.As(err, &tle) {
+		log.Printf("[Channels] Tier limit exceeded for %s: %s", userID, tle.Error())
+		return c.Status(fiber.StatusForbidden).JSON(tierLimitErrorResponse(tle))
+	}
+	// Defensive — the validators should only return *TierLimitError.
+	return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+		Status: "error",
+		Error:  err.Error(),
+	})
+}
+
+// channelConfigSchemaFailure answers a config that fails the channel's
+// published schema, listing each offending field.
+func channelConfigSchemaFailure(c *fiber.Ctx, err error) error {
+	var cve *ConfigValidationError
+	if errors.As(err, &cve) {
+		return c.Status(fiber.StatusBadRequest).JSON(ConfigValidationErrorResponse{
+			Status: "error",
+			Error:  "Invalid channel config",
+			Fields: cve.Fields,
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+		Status: "error",
+		Error:  err.Error(),
+	})
+}
+
+// channelBatchFailure maps a validation or apply error to a response.
+func channelBatchFailure(c *fiber.Ctx, userID string, err error) error {
+	var tle *TierLimitError
+	if errors.As(err, &tle) {
+		log.Printf("[Channels] Tier limit exceeded for %s: %s", userID, tle.Error())
+		return c.Status(fiber.StatusForbidden).JSON(tierLimitErrorResponse(tle))
+	}
+	var be *channelBatchError
+	if errors.As(err, &be) {
+		if be.Fields != nil {
+			return c.Status(be.Status).JSON(ConfigValidationErrorResponse{
+				Status: "error",
+				Error:  be.Error(),
+				Fields: be.Fields,
+			})
+		}
+		return c.Status(be.Status).JSON(ErrorResponse{
+			Status: "error",
+			Error:  be.Error(),
+		})
+	}
+	log.Printf("[Channels] Batch error for %s: %v", userID, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+		Status: "error",
+		Error:  "Failed to apply channel changes",
+	})
+}
+
+// applyChannelBatch runs the operations in order inside one transaction.
+// Side effects (Redis sets, lifecycle hooks, caches) wait for the commit.
+func applyChannelBatch(ctx context.Context, userID, tier string, ops []channelBatchOp) ([]appliedChannelOp, error) {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	enabledBefore, err := countEnabledChannelsTx(ctx, tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count channels: %w", err)
+	}
+
+	applied := make([]appliedChannelOp, 0, len(ops))
+	for i, op := range ops {
+		a := appliedChannelOp{op: op.Op}
+		var configBytes []byte
+
+		switch op.Op {
+		case "create":
+			configJSON, _ := json.Marshal(op.Config)
+			err = tx.QueryRow(ctx, `
+				INSERT INTO user_channels (logto_sub, channel_type, config)
+				VALUES ($1, $2, $3)
+				RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
+			`, userID, op.ChannelType, configJSON).Scan(
+				&a.channel.ID, &a.channel.LogtoSub, &a.channel.ChannelType, &a.channel.Enabled, &a.channel.Visible,
+				&configBytes, &a.channel.CreatedAt, &a.channel.UpdatedAt,
+			)
+			if err != nil && (strings.Contains(err.Error(), "unique") || strings.Contains(err.Error(), "duplicate")) {
+				return nil, &channelBatchError{Index: i, Status: fiber.StatusConflict, Msg: "Channel of this type already exists"}
+			}
+
+		case "update":
+			if op.Config != nil {
+				var oldConfigBytes []byte
+				_ = tx.QueryRow(ctx, `
+					SELECT config FROM user_channels WHERE logto_sub = $1 AND channel_type = $2
+					FOR UPDATE
+				`, userID, op.ChannelType).Scan(&oldConfigBytes)
+				if len(oldConfigBytes) > 0 {
+					json.Unmarshal(oldConfigBytes, &a.oldConfig)
+				}
+			}
+			query, args := channelUpdateQuery(userID, op.ChannelType, op.Enabled, op.Visible, op.Config)
+			err = tx.QueryRow(ctx, query, args...).Scan(
+				&a.channel.ID, &a.channel.LogtoSub, &a.channel.ChannelType, &a.channel.Enabled, &a.channel.Visible,
+				&configBytes, &a.channel.CreatedAt, &a.channel.UpdatedAt,
+			)
+
+		case "delete":
+			a.channel = Channel{LogtoSub: userID, ChannelType: op.ChannelType}
+			err = tx.QueryRow(ctx, `
+				DELETE FROM user_channels WHERE logto_sub = $1 AND channel_type = $2
+				RETURNING config
+			`, userID, op.ChannelType).Scan(&configBytes)
+		}
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &channelBatchError{Index: i, Status: fiber.StatusNotFound, Msg: "Channel not found"}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", op.Op, op.ChannelType, err)
+		}
+		if err := json.Unmarshal(configBytes, &a.channel.Config); err != nil || a.channel.Config == nil {
+			a.channel.Config = map[string]interface{}{}
+		}
+		if op.Op == "delete" {
+			a.oldConfig = a.channel.Config
+		}
+		applied = append(applied, a)
+	}
+
+	// The channel cap is checked on the batch's net effect, so swapping
+	// one channel for another at the cap is allowed.
+	enabledAfter, err := countEnabledChannelsTx(ctx, tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count channels: %w", err)
+	}
+	if err := ValidateChannelCount(tier, enabledBefore, enabledAfter); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	return applied, nil
+}
+
+// countEnabledChannelsTx counts the user's enabled channels as seen by tx.
+func countEnabledChannelsTx(ctx context.Context, tx pgx.Tx, userID string) (int, error) {
+	var n int
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_channels WHERE logto_sub = $1 AND enabled
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// finishChannelBatch runs the committed batch's side effects once rather
+// than per operation: deleted channels leave their subscriber sets first
+// (so a delete-then-create of the same type ends subscribed), then a
+// single SyncChannelSubscriptions and topic rebuild cover everything that
+// remains, then each operation's lifecycle hook fires in order.
+func finishChannelBatch(c *fiber.Ctx, userID string, applied []appliedChannelOp) error {
+	ctx := context.Background()
+	disabled := false
+	for _, a := range applied {
+		if a.op == "delete" {
+			removeSubscriberSets(ctx, userID, a.channel.ChannelType, a.oldConfig)
+			callChannelLifecycle(ctx, a.channel.ChannelType, "sync", userID, a.oldConfig, nil, &disabled)
+		}
+	}
+	SyncChannelSubscriptions(userID)
+	UpdateUserTopicSubscriptions(userID)
+
+	for _, a := range applied {
+		switch a.op {
+		case "create":
+			callChannelLifecycle(ctx, a.channel.ChannelType, "created", userID, a.channel.Config, nil, nil)
+			AuditSelf(c, AuditChannelCreated, map[string]any{"channel_type": a.channel.ChannelType, "batch": true})
+		case "update":
+			callChannelLifecycle(ctx, a.channel.ChannelType, "updated", userID, a.channel.Config, a.oldConfig, nil)
+			AuditSelf(c, AuditChannelUpdated, map[string]any{"channel_type": a.channel.ChannelType, "batch": true})
+		case "delete":
+			callChannelLifecycle(ctx, a.channel.ChannelType, "deleted", userID, a.oldConfig, nil, nil)
+			AuditSelf(c, AuditChannelDeleted, map[string]any{"channel_type": a.channel.ChannelType, "batch": true})
+		}
+	}
+
+	InvalidateDashboardCache(userID)
+	InvalidateOverviewCache(ctx, userID)
+
+	channels, err := GetUserChannels(userID)
+	if err != nil {
+		// Committed already; the client can refetch.
+		log.Printf("[Channels] Batch applied for %s but re-read failed: %v", userID, err)
+		channels = []Channel{}
+	}
+	log.Printf("[Channels] Batch applied for %s (%d operation(s))", userID, len(applied))
+	return c.JSON(fiber.Map{"channels": channels})
+}
+
 // PruneUserChannelsForTier walks all user_channels rows for a user and
 // trims each config to the caps of the given tier. UPDATEs are skipped
 // for rows that were already within-cap. Intended to be called from the
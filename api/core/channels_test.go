@@ -1,7 +1,12 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 func TestExtractSportsLeaguesFromConfig(t *testing.T) {
@@ -99,3 +104,120 @@ func TestExtractSportsLeaguesFromConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLifecycleRetryBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, LifecycleRetryBaseDelay},
+		{1, LifecycleRetryBaseDelay},
+		{2, 2 * LifecycleRetryBaseDelay},
+		{3, 4 * LifecycleRetryBaseDelay},
+		{50, LifecycleRetryMaxDelay},
+	}
+	for _, tc := range cases {
+		if got := lifecycleRetryBackoff(tc.attempts); got != tc.want {
+			t.Errorf("lifecycleRetryBackoff(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestLifecycleEventExpired(t *testing.T) {
+	now := time.Now()
+	fresh := lifecycleEvent{FirstFailedAt: now.Add(-time.Hour).UnixMilli()}
+	old := lifecycleEvent{FirstFailedAt: now.Add(-LifecycleRetryMaxAge - time.Minute).UnixMilli()}
+	if lifecycleEventExpired(fresh, now) {
+		t.Error("1h-old event should not be expired")
+	}
+	if !lifecycleEventExpired(old, now) {
+		t.Error("event older than max age should be expired")
+	}
+}
+
+func TestLifecycleRetryLockOnlyReleasedByHolder(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	first, ok := acquireLifecycleRetryLock(ctx, "fantasy")
+	if !ok {
+		t.Fatal("first acquire failed")
+	}
+	if _, ok := acquireLifecycleRetryLock(ctx, "fantasy"); ok {
+		t.Fatal("second acquire succeeded while the lock was held")
+	}
+
+	// The first holder's TTL runs out and another instance takes over.
+	mr.FastForward(LifecycleRetryLockTTL + time.Second)
+	second, ok := acquireLifecycleRetryLock(ctx, "fantasy")
+	if !ok {
+		t.Fatal("acquire after expiry failed")
+	}
+
+	if first.extend(ctx) {
+		t.Error("expired holder extended a lock it no longer owns")
+	}
+	first.release()
+	if got, _ := mr.Get(RedisLifecycleRetryLock + "fantasy"); got != second.token {
+		t.Errorf("lock value = %q after the old holder's release, want the new holder's token", got)
+	}
+	if !second.extend(ctx) {
+		t.Error("current holder could not extend its lock")
+	}
+	second.release()
+	if mr.Exists(RedisLifecycleRetryLock + "fantasy") {
+		t.Error("lock still held after its holder released it")
+	}
+}
+
+func TestValidateChannelBatch(t *testing.T) {
+	valid := map[string]bool{"finance": true, "sports": true, "rss": true}
+	yes := true
+
+	ops := []channelBatchOp{
+		{Op: "create", ChannelType: "sports"},
+		{Op: "update", ChannelType: "finance", TickerEnabled: &yes},
+		{Op: "delete", ChannelType: "retired_channel"},
+	}
+	if err := validateChannelBatch("free", ops, valid); err != nil {
+		t.Fatalf("valid batch rejected: %v", err)
+	}
+	if ops[0].Config == nil {
+		t.Error("create without config should default to {}")
+	}
+	if ops[1].Visible != &yes {
+		t.Error("ticker_enabled should map onto visible, as on PUT")
+	}
+
+	cases := []struct {
+		name   string
+		ops    []channelBatchOp
+		index  int
+		status int
+	}{
+		{"empty", nil, -1, fiber.StatusBadRequest},
+		{"too many", make([]channelBatchOp, ChannelBatchMaxOps+1), -1, fiber.StatusBadRequest},
+		{"unknown op", []channelBatchOp{{Op: "create", ChannelType: "rss"}, {Op: "upsert", ChannelType: "rss"}}, 1, fiber.StatusBadRequest},
+		{"invalid type", []channelBatchOp{{Op: "update", ChannelType: "weather"}}, 0, fiber.StatusBadRequest},
+		{"delete without type", []channelBatchOp{{Op: "delete"}}, 0, fiber.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		var be *channelBatchError
+		err := validateChannelBatch("free", tc.ops, valid)
+		if !errors.As(err, &be) {
+			t.Errorf("%s: err = %v, want *channelBatchError", tc.name, err)
+			continue
+		}
+		if be.Index != tc.index || be.Status != tc.status {
+			t.Errorf("%s: index=%d status=%d, want index=%d status=%d", tc.name, be.Index, be.Status, tc.index, tc.status)
+		}
+	}
+
+	// Tier limits apply per operation, exactly as on the single endpoints.
+	over := []channelBatchOp{{Op: "update", ChannelType: "finance", Config: financeCfg(1000)}}
+	var tle *TierLimitError
+	if err := validateChannelBatch("free", over, valid); !errors.As(err, &tle) {
+		t.Errorf("over-cap config: err = %v, want *TierLimitError", err)
+	}
+}
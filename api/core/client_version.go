@@ -0,0 +1,283 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Client version gating.
+//
+// Desktop and extension builds send X-Client-Version (EventSource can't
+// set headers, so /events also accepts ?client_version=). When an admin
+// sets a minimum, requests from older builds get a structured 426 and
+// open SSE streams receive a `client-upgrade-required` event and are
+// closed. Requests without a version are let through — web clients
+// don't send one, and we can't tell an old build from a browser.
+//
+// The minimum lives in Redis so every gateway replica sees the same
+// value; each replica caches it for MinClientVersionCacheTTL.
+
+// ClientUpgradeRequiredResponse is the 426 body (and SSE event payload).
+type ClientUpgradeRequiredResponse struct {
+	Status        string `json:"status"`
+	Error         string `json:"error"`
+	MinVersion    string `json:"min_version"`
+	ClientVersion string `json:"client_version"`
+	DownloadURL   string `json:"download_url"`
+}
+
+var (
+	minClientVersionMu      sync.RWMutex
+	minClientVersionCache   string
+	minClientVersionExpires time.Time
+)
+
+// parseClientVersion parses "1.2.3" (optionally "v"-prefixed, with any
+// "-beta"/"+build" suffix ignored) into numeric components.
+func parseClientVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return nil, false
+	}
+	out := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// compareClientVersions returns -1, 0 or 1. Missing components count as
+// zero, so "1.2" == "1.2.0".
+func compareClientVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// getMinClientVersion returns the configured minimum, or "" when none is
+// set. Redis errors fall back to the last cached value so a blip never
+// locks every client out (or lets every client in).
+func getMinClientVersion(ctx context.Context) string {
+	minClientVersionMu.RLock()
+	if time.Now().Before(minClientVersionExpires) {
+		v := minClientVersionCache
+		minClientVersionMu.RUnlock()
+		return v
+	}
+	minClientVersionMu.RUnlock()
+
+	if Rdb == nil {
+		return ""
+	}
+	v, err := Rdb.Get(ctx, RedisMinClientVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		log.Printf("[ClientVersion] read minimum failed (using cached): %v", err)
+		minClientVersionMu.RLock()
+		defer minClientVersionMu.RUnlock()
+		return minClientVersionCache
+	}
+
+	minClientVersionMu.Lock()
+	minClientVersionCache = v
+	minClientVersionExpires = time.Now().Add(MinClientVersionCacheTTL)
+	minClientVersionMu.Unlock()
+	return v
+}
+
+// setMinClientVersionCache primes the local cache after an admin write so
+// the replica that handled it enforces the new value immediately.
+func setMinClientVersionCache(v string) {
+	minClientVersionMu.Lock()
+	minClientVersionCache = v
+	minClientVersionExpires = time.Now().Add(MinClientVersionCacheTTL)
+	minClientVersionMu.Unlock()
+}
+
+// clientBelowMinimum reports whether version is older than min. Either
+// side failing to parse means "not below" — an unrecognised build is
+// let through rather than bricked.
+func clientBelowMinimum(version, min string) bool {
+	if version == "" || min == "" {
+		return false
+	}
+	v, ok := parseClientVersion(version)
+	if !ok {
+		return false
+	}
+	m, ok := parseClientVersion(min)
+	if !ok {
+		return false
+	}
+	return compareClientVersions(v, m) < 0
+}
+
+func clientDownloadURL() string {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = DefaultFrontendURL
+	}
+	return frontendURL + "/download"
+}
+
+func clientUpgradeRequired(version, min string) ClientUpgradeRequiredResponse {
+	return ClientUpgradeRequiredResponse{
+		Status:        "upgrade_required",
+		Error:         fmt.Sprintf("This version of Scrollr (%s) is no longer supported. Please update to %s or newer.", version, min),
+		MinVersion:    min,
+		ClientVersion: version,
+		DownloadURL:   clientDownloadURL(),
+	}
+}
+
+// writeClientUpgradeEvent sends the named SSE event telling a stream
+// client it must upgrade. The caller closes the stream afterwards.
+func writeClientUpgradeEvent(w *bufio.Writer, version, min string) {
+	data, _ := json.Marshal(clientUpgradeRequired(version, min))
+	fmt.Fprintf(w, "event: client-upgrade-required\ndata: %s\n\n", data)
+	w.Flush()
+}
+
+// clientVersionExemptPrefixes are never gated: infrastructure probes,
+// server-to-server webhooks, and admin routes (so a bad minimum can
+// always be rolled back). /events and /ws do their own check so they can
+// answer with a stream event instead of a 426. Unattended displays have
+// nobody to act on an upgrade prompt, so /display/ is never gated.
+var clientVersionExemptPrefixes = []string{
+	"/health",
+	"/time",
+	"/webhooks/",
+	"/admin/",
+	"/swagger",
+	"/events",
+	"/ws",
+	"/display/",
+}
+
+// ClientVersionGate rejects requests from clients older than the
+// configured minimum with 426 Upgrade Required.
+func ClientVersionGate(c *fiber.Ctx) error {
+	version := c.Get(ClientVersionHeader)
+	if version == "" {
+		return c.Next()
+	}
+	path := c.Path()
+	for _, p := range clientVersionExemptPrefixes {
+		if strings.HasPrefix(path, p) {
+			return c.Next()
+		}
+	}
+	min := getMinClientVersion(c.Context())
+	if clientBelowMinimum(version, min) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(clientUpgradeRequired(version, min))
+	}
+	return c.Next()
+}
+
+// ─── Admin ───────────────────────────────────────────────────────
+
+// HandleAdminGetClientVersion returns the current minimum client version.
+//
+// @Summary Get minimum client version (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{min_version=string}
+// @Security LogtoAuth
+// @Router /admin/client-version [get]
+func HandleAdminGetClientVersion(c *fiber.Ctx) error {
+	v, err := Rdb.Get(c.Context(), RedisMinClientVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		log.Printf("[ClientVersion] admin read failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to read minimum client version",
+		})
+	}
+	return c.JSON(fiber.Map{"min_version": v})
+}
+
+// HandleAdminSetClientVersion sets (or, with an empty string, clears)
+// the minimum supported client version.
+//
+// @Summary Set minimum client version (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body object true "Minimum version" example({"min_version":"1.0.4"})
+// @Success 200 {object} object{min_version=string}
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/client-version [put]
+func HandleAdminSetClientVersion(c *fiber.Ctx) error {
+	var req struct {
+		MinVersion *string `json:"min_version"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.MinVersion == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "min_version is required",
+		})
+	}
+	v := strings.TrimSpace(*req.MinVersion)
+	if v != "" {
+		if _, ok := parseClientVersion(v); !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "min_version must look like 1.2.3",
+			})
+		}
+	}
+
+	ctx := c.Context()
+	var err error
+	if v == "" {
+		err = Rdb.Del(ctx, RedisMinClientVersionKey).Err()
+	} else {
+		err = Rdb.Set(ctx, RedisMinClientVersionKey, v, 0).Err()
+	}
+	if err != nil {
+		log.Printf("[ClientVersion] admin write failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update minimum client version",
+		})
+	}
+	setMinClientVersionCache(v)
+
+	log.Printf("[ClientVersion] Minimum client version set to %q by %s", v, GetUserID(c))
+	return c.JSON(fiber.Map{"min_version": v})
+}
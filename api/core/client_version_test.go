@@ -0,0 +1,27 @@
+package core
+
+import "testing"
+
+func TestClientBelowMinimum(t *testing.T) {
+	cases := []struct {
+		version, min string
+		want         bool
+	}{
+		{"1.0.3", "1.0.4", true},
+		{"1.0.4", "1.0.4", false},
+		{"1.0.10", "1.0.4", false},
+		{"v1.0.3", "1.0.4", true},
+		{"1.0.4-beta.2", "1.0.4", false}, // suffix ignored
+		{"1.0", "1.0.0", false},
+		{"0.9.9", "1", true},
+		{"", "1.0.4", false},          // no header → let through
+		{"1.0.3", "", false},          // no minimum set
+		{"nightly", "1.0.4", false},   // unparseable → let through
+		{"1.0.3.1.2", "1.0.4", false}, // too many components
+	}
+	for _, tc := range cases {
+		if got := clientBelowMinimum(tc.version, tc.min); got != tc.want {
+			t.Errorf("clientBelowMinimum(%q, %q) = %v, want %v", tc.version, tc.min, got, tc.want)
+		}
+	}
+}
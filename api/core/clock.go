@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Clock sync hints.
+//
+// Ticker animations and "starts in 2 minutes" labels are computed from the
+// client's clock, which on desktops can drift by minutes. The gateway
+// gives clients two anchors:
+//
+//   - GET /time returns the server's wall clock so the client can estimate
+//     its offset (NTP-style: offset ≈ server_ts - (sent + received) / 2).
+//   - Every SSE envelope carries `server_ts` (ms) and a monotonic `seq`,
+//     so a long-lived stream keeps the offset fresh and the client can
+//     detect dropped events as gaps in seq.
+//
+// Strings the server renders itself use formatRelativeTime (helpers.go)
+// against the server clock, so they never depend on the client's.
+
+// TimeResponse is the payload of GET /time.
+type TimeResponse struct {
+	ServerTS int64  `json:"server_ts"` // Unix milliseconds
+	ISO      string `json:"iso"`
+	Seq      int64  `json:"seq,omitempty"` // latest SSE envelope seq, if known
+}
+
+// nextEventSeq returns the next SSE envelope sequence number. Returns 0
+// when Redis is unavailable — callers omit seq rather than fall back to a
+// per-process counter, which would go backwards across gateway instances.
+func nextEventSeq(ctx context.Context) int64 {
+	if Rdb == nil {
+		return 0
+	}
+	seq, err := Rdb.Incr(ctx, RedisEventSeqKey).Result()
+	if err != nil {
+		log.Printf("[Clock] seq INCR failed (omitting seq): %v", err)
+		return 0
+	}
+	return seq
+}
+
+// currentEventSeq reads the latest issued seq without advancing it.
+func currentEventSeq(ctx context.Context) int64 {
+	if Rdb == nil {
+		return 0
+	}
+	seq, err := Rdb.Get(ctx, RedisEventSeqKey).Int64()
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// HandleGetTime returns the server clock for client offset estimation.
+//
+// @Summary Server time
+// @Description Server wall clock (ms) and latest SSE sequence for client clock sync
+// @Tags Health
+// @Produce json
+// @Success 200 {object} TimeResponse
+// @Router /time [get]
+func HandleGetTime(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 500*time.Millisecond)
+	defer cancel()
+	seq := currentEventSeq(ctx)
+
+	// Stamp after the Redis read so the timestamp is as close to the
+	// response write as possible.
+	now := time.Now()
+	c.Set("Cache-Control", "no-store")
+	return c.JSON(TimeResponse{
+		ServerTS: now.UnixMilli(),
+		ISO:      now.UTC().Format(time.RFC3339Nano),
+		Seq:      seq,
+	})
+}
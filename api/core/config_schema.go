@@ -0,0 +1,475 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Channel config schemas.
+//
+// A channel may publish a JSON Schema for its user_channels.config in the
+// config_schema field of its registration payload. CreateChannel,
+// UpdateChannel and BatchChannels validate incoming config against it
+// before tier limits are checked, and reject bad input with one entry per
+// offending field:
+//
+//	{"status":"error","error":"Invalid channel config",
+//	 "fields":[{"field":"config.feeds[0].url","message":"must be a valid URL"}]}
+//
+// Only the keywords below are understood. A schema using anything that
+// changes what validates (composition, $ref, conditionals) is rejected
+// when discovered rather than half-enforced; annotations such as title
+// and description are ignored. Channels without a schema are unvalidated,
+// as before. GET /channels/:name/schema serves the schema, annotations
+// included, so clients can render settings forms from it.
+//
+//	type (string or array)   properties   required   additionalProperties
+//	items   minItems   maxItems   uniqueItems   enum   const
+//	minLength   maxLength   pattern   format ("uri")   minimum   maximum
+
+const (
+	// ConfigSchemaMaxErrors caps how many field errors one response reports.
+	ConfigSchemaMaxErrors = 20
+
+	// ChannelSchemaCacheMaxAge (seconds) is how long clients may cache
+	// GET /channels/:name/schema. Schemas only change on redeploy.
+	ChannelSchemaCacheMaxAge = 300
+)
+
+// configSchemaUnsupported lists keywords whose absence from the validator
+// would silently let invalid config through.
+var configSchemaUnsupported = []string{
+	"$ref", "$dynamicRef", "allOf", "anyOf", "oneOf", "not", "if", "then", "else",
+	"dependentRequired", "dependentSchemas", "patternProperties", "propertyNames",
+	"prefixItems", "contains", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"minProperties", "maxProperties", "unevaluatedProperties", "unevaluatedItems",
+}
+
+// configSchema is a compiled schema node.
+type configSchema struct {
+	types                []string
+	properties           map[string]*configSchema
+	required             []string
+	additionalProperties *bool         // nil: allowed
+	additionalSchema     *configSchema // set when additionalProperties is a schema
+	items                *configSchema
+	minItems, maxItems   *int
+	uniqueItems          bool
+	enum                 []any
+	constVal             any
+	hasConst             bool
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+	minimum, maximum     *float64
+}
+
+// ConfigFieldError is one failed constraint, located by a path such as
+// "config.symbols[3]" or "config.feeds[0].url".
+type ConfigFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ConfigValidationError is returned when config does not match the
+// channel's schema.
+type ConfigValidationError struct {
+	Fields []ConfigFieldError
+}
+
+func (e *ConfigValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return "invalid channel config"
+	}
+	return fmt.Sprintf("invalid channel config: %s %s", e.Fields[0].Field, e.Fields[0].Message)
+}
+
+// ConfigValidationErrorResponse is the 400 body for a schema violation.
+type ConfigValidationErrorResponse struct {
+	Status string             `json:"status"`
+	Error  string             `json:"error"`
+	Fields []ConfigFieldError `json:"fields"`
+}
+
+// compileConfigSchema parses a channel's published schema.
+func compileConfigSchema(raw json.RawMessage) (*configSchema, error) {
+	var node any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&node); err != nil {
+		return nil, fmt.Errorf("config_schema: %w", err)
+	}
+	return compileSchemaNode(node, "#")
+}
+
+func compileSchemaNode(node any, at string) (*configSchema, error) {
+	if b, ok := node.(bool); ok {
+		// true accepts anything; false accepts nothing.
+		if b {
+			return &configSchema{}, nil
+		}
+		return &configSchema{types: []string{}}, nil
+	}
+	m, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("config_schema %s: schema must be an object or boolean", at)
+	}
+	for _, kw := range configSchemaUnsupported {
+		if _, ok := m[kw]; ok {
+			return nil, fmt.Errorf("config_schema %s: keyword %q is not supported", at, kw)
+		}
+	}
+
+	s := &configSchema{}
+	var err error
+
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("config_schema %s: type entries must be strings", at)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("config_schema %s: type must be a string or array", at)
+	}
+	for _, t := range s.types {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return nil, fmt.Errorf("config_schema %s: unknown type %q", at, t)
+		}
+	}
+
+	if props, ok := m["properties"].(map[string]any); ok {
+		s.properties = make(map[string]*configSchema, len(props))
+		for name, sub := range props {
+			if s.properties[name], err = compileSchemaNode(sub, at+"/properties/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req, ok := m["required"].([]any); ok {
+		for _, v := range req {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("config_schema %s: required entries must be strings", at)
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	switch ap := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.additionalProperties = &ap
+	default:
+		if s.additionalSchema, err = compileSchemaNode(ap, at+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if items, ok := m["items"]; ok {
+		if s.items, err = compileSchemaNode(items, at+"/items"); err != nil {
+			return nil, err
+		}
+	}
+	s.uniqueItems, _ = m["uniqueItems"].(bool)
+	if enum, ok := m["enum"].([]any); ok {
+		s.enum = normalizeSchemaValues(enum)
+	}
+	if c, ok := m["const"]; ok {
+		s.hasConst = true
+		s.constVal = normalizeSchemaValue(c)
+	}
+	for kw, dst := range map[string]**int{
+		"minItems": &s.minItems, "maxItems": &s.maxItems,
+		"minLength": &s.minLength, "maxLength": &s.maxLength,
+	} {
+		if *dst, err = schemaInt(m, kw, at); err != nil {
+			return nil, err
+		}
+	}
+	for kw, dst := range map[string]**float64{"minimum": &s.minimum, "maximum": &s.maximum} {
+		if *dst, err = schemaFloat(m, kw, at); err != nil {
+			return nil, err
+		}
+	}
+	if p, ok := m["pattern"].(string); ok {
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("config_schema %s: pattern: %w", at, err)
+		}
+	}
+	if f, ok := m["format"].(string); ok {
+		s.format = f
+	}
+	return s, nil
+}
+
+func schemaInt(m map[string]any, kw, at string) (*int, error) {
+	raw, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("config_schema %s: %s must be a number", at, kw)
+	}
+	v, err := strconv.Atoi(n.String())
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("config_schema %s: %s must be a non-negative integer", at, kw)
+	}
+	return &v, nil
+}
+
+func schemaFloat(m map[string]any, kw, at string) (*float64, error) {
+	raw, ok := m[kw]
+	if !ok {
+		return nil, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("config_schema %s: %s must be a number", at, kw)
+	}
+	v, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("config_schema %s: %s must be a number", at, kw)
+	}
+	return &v, nil
+}
+
+// normalizeSchemaValue turns json.Number into float64 so schema literals
+// compare equal to config decoded without UseNumber.
+func normalizeSchemaValue(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case []any:
+		return normalizeSchemaValues(t)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, sub := range t {
+			out[k] = normalizeSchemaValue(sub)
+		}
+		return out
+	}
+	return v
+}
+
+func normalizeSchemaValues(vs []any) []any {
+	out := make([]any, len(vs))
+	for i, v := range vs {
+		out[i] = normalizeSchemaValue(v)
+	}
+	return out
+}
+
+// validateChannelConfigSchema checks config against the channel's
+// published schema. It returns nil when the channel has none.
+func validateChannelConfigSchema(channelType string, config map[string]any) error {
+	info := GetChannel(channelType)
+	if info == nil || info.configSchema == nil {
+		return nil
+	}
+	if fields := info.configSchema.validate(config); len(fields) > 0 {
+		return &ConfigValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validate returns every failed constraint under value, in path order,
+// capped at ConfigSchemaMaxErrors.
+func (s *configSchema) validate(value any) []ConfigFieldError {
+	var errs []ConfigFieldError
+	s.check(value, "config", &errs)
+	if len(errs) > ConfigSchemaMaxErrors {
+		errs = errs[:ConfigSchemaMaxErrors]
+	}
+	return errs
+}
+
+func (s *configSchema) check(value any, path string, errs *[]ConfigFieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ConfigFieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.types != nil && !schemaTypeMatches(s.types, value) {
+		if len(s.types) == 0 {
+			fail("is not allowed")
+		} else {
+			fail("must be %s", strings.Join(s.types, " or "))
+		}
+		return
+	}
+	if s.hasConst && !reflect.DeepEqual(value, s.constVal) {
+		fail("must be %s", schemaLiteral(s.constVal))
+	}
+	if s.enum != nil && !schemaContains(s.enum, value) {
+		literals := make([]string, len(s.enum))
+		for i, v := range s.enum {
+			literals[i] = schemaLiteral(v)
+		}
+		fail("must be one of %s", strings.Join(literals, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := len([]rune(v))
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("has an invalid format")
+		}
+		if s.format == "uri" || s.format == "url" {
+			if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+				fail("must be a valid URL")
+			}
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be at least %s", strconv.FormatFloat(*s.minimum, 'f', -1, 64))
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be at most %s", strconv.FormatFloat(*s.maximum, 'f', -1, 64))
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+			for i := 1; i < len(v); i++ {
+				if schemaContains(v[:i], v[i]) {
+					*errs = append(*errs, ConfigFieldError{
+						Field:   fmt.Sprintf("%s[%d]", path, i),
+						Message: "is a duplicate",
+					})
+				}
+			}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.check(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, ConfigFieldError{Field: path + "." + name, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if sub, ok := s.properties[k]; ok {
+				sub.check(v[k], path+"."+k, errs)
+				continue
+			}
+			if s.additionalSchema != nil {
+				s.additionalSchema.check(v[k], path+"."+k, errs)
+			} else if s.additionalProperties != nil && !*s.additionalProperties {
+				*errs = append(*errs, ConfigFieldError{Field: path + "." + k, Message: "is not a recognized setting"})
+			}
+		}
+	}
+}
+
+func schemaTypeMatches(types []string, value any) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func schemaContains(list []any, value any) bool {
+	for _, v := range list {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaLiteral(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// HandleGetChannelSchema serves a channel's config schema so clients can
+// render its settings form without per-channel UI code. schema is null
+// for channels that publish none (or one the gateway couldn't compile,
+// since that config isn't validated either).
+// @Summary Get a channel's config schema
+// @Tags Channels
+// @Produce json
+// @Param name path string true "Channel name"
+// @Success 200 {object} object{name=string,display_name=string,schema=object}
+// @Failure 404 {object} ErrorResponse
+// @Router /channels/{name}/schema [get]
+func HandleGetChannelSchema(c *fiber.Ctx) error {
+	info := GetChannel(c.Params("name"))
+	if info == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Channel not found",
+		})
+	}
+	var schema json.RawMessage
+	if info.configSchema != nil {
+		schema = info.ConfigSchema
+	}
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", ChannelSchemaCacheMaxAge))
+	return c.JSON(fiber.Map{
+		"name":         info.Name,
+		"display_name": info.DisplayName,
+		"schema":       schema,
+	})
+}
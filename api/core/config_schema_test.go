@@ -0,0 +1,177 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testFeedSchema = `{
+	"type": "object",
+	"properties": {
+		"feeds": {
+			"type": "array",
+			"maxItems": 3,
+			"items": {
+				"type": "object",
+				"required": ["url"],
+				"additionalProperties": false,
+				"properties": {
+					"url": {"type": "string", "format": "uri"},
+					"name": {"type": "string", "maxLength": 10},
+					"is_custom": {"type": "boolean"}
+				}
+			}
+		},
+		"symbols": {"type": "array", "uniqueItems": true, "items": {"type": "string", "pattern": "^[A-Z.^-]{1,10}$"}},
+		"refresh": {"type": "integer", "minimum": 30},
+		"mode": {"enum": ["compact", "full"]}
+	}
+}`
+
+func mustCompileSchema(t *testing.T, raw string) *configSchema {
+	t.Helper()
+	s, err := compileConfigSchema(json.RawMessage(raw))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return s
+}
+
+func decodeConfig(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestConfigSchemaValidate(t *testing.T) {
+	s := mustCompileSchema(t, testFeedSchema)
+
+	ok := decodeConfig(t, `{"feeds":[{"url":"https://example.com/rss","name":"Ex"}],"symbols":["AAPL","BRK.B"],"refresh":60,"mode":"full","display":{"anything":true}}`)
+	if errs := s.validate(ok); len(errs) != 0 {
+		t.Fatalf("valid config rejected: %+v", errs)
+	}
+
+	bad := decodeConfig(t, `{
+		"feeds":[{"name":"a very long name"},{"url":"not a url","extra":1}],
+		"symbols":["AAPL","aapl","AAPL"],
+		"refresh":30.5,
+		"mode":"tiny"
+	}`)
+	got := s.validate(bad)
+	want := []ConfigFieldError{
+		{"config.feeds[0].url", "is required"},
+		{"config.feeds[0].name", "must be at most 10 characters"},
+		{"config.feeds[1].extra", "is not a recognized setting"},
+		{"config.feeds[1].url", "must be a valid URL"},
+		{"config.mode", `must be one of "compact", "full"`},
+		{"config.refresh", "must be integer"},
+		{"config.symbols[2]", "is a duplicate"},
+		{"config.symbols[1]", "has an invalid format"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if errs := s.validate(decodeConfig(t, `{"feeds":[{"url":"https://a.b"},{"url":"https://a.b"},{"url":"https://a.b"},{"url":"https://a.b"}]}`)); len(errs) != 1 || errs[0].Message != "must have at most 3 items" {
+		t.Errorf("maxItems errors = %+v", errs)
+	}
+}
+
+func TestCompileConfigSchemaRejects(t *testing.T) {
+	for _, raw := range []string{
+		`not json`,
+		`"object"`,
+		`{"type":"map"}`,
+		`{"properties":{"a":{"anyOf":[{"type":"string"}]}}}`,
+		`{"items":{"$ref":"#/defs/x"}}`,
+		`{"pattern":"("}`,
+		`{"maxItems":-1}`,
+	} {
+		if _, err := compileConfigSchema(json.RawMessage(raw)); err == nil {
+			t.Errorf("compile(%s) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestValidateChannelConfigSchema(t *testing.T) {
+	globalDiscovery.mu.Lock()
+	prev := globalDiscovery.channels
+	globalDiscovery.channels = map[string]*ChannelInfo{
+		"rss":    {Name: "rss", configSchema: mustCompileSchema(t, testFeedSchema)},
+		"custom": {Name: "custom"},
+	}
+	globalDiscovery.mu.Unlock()
+	t.Cleanup(func() {
+		globalDiscovery.mu.Lock()
+		globalDiscovery.channels = prev
+		globalDiscovery.mu.Unlock()
+	})
+
+	if err := validateChannelConfigSchema("custom", map[string]any{"feeds": "whatever"}); err != nil {
+		t.Errorf("channel without schema: %v", err)
+	}
+	err := validateChannelConfigSchema("rss", map[string]any{"feeds": "whatever"})
+	var cve *ConfigValidationError
+	if !errors.As(err, &cve) || len(cve.Fields) != 1 || cve.Fields[0].Field != "config.feeds" {
+		t.Fatalf("err = %v, want one config.feeds error", err)
+	}
+	if !strings.Contains(err.Error(), "must be array") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	ops := []channelBatchOp{{Op: "create", ChannelType: "rss", Config: map[string]any{"refresh": "soon"}}}
+	var be *channelBatchError
+	if err := validateChannelBatch("free", ops, map[string]bool{"rss": true}); !errors.As(err, &be) || len(be.Fields) != 1 {
+		t.Errorf("batch err = %v, want channelBatchError with fields", err)
+	}
+}
+
+func TestHandleGetChannelSchema(t *testing.T) {
+	raw := json.RawMessage(`{"type":"object","title":"Feeds","properties":{"feeds":{"type":"array","description":"RSS feeds"}}}`)
+	globalDiscovery.mu.Lock()
+	prev := globalDiscovery.channels
+	globalDiscovery.channels = map[string]*ChannelInfo{
+		"rss":    {Name: "rss", DisplayName: "RSS", ConfigSchema: raw, configSchema: mustCompileSchema(t, string(raw))},
+		"broken": {Name: "broken", ConfigSchema: json.RawMessage(`{"anyOf":[]}`)},
+	}
+	globalDiscovery.mu.Unlock()
+	t.Cleanup(func() {
+		globalDiscovery.mu.Lock()
+		globalDiscovery.channels = prev
+		globalDiscovery.mu.Unlock()
+	})
+
+	app := fiber.New()
+	app.Get("/channels/:name/schema", HandleGetChannelSchema)
+
+	get := func(name string) (int, map[string]json.RawMessage) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/channels/"+name+"/schema", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	status, body := get("rss")
+	if status != fiber.StatusOK || string(body["schema"]) != string(raw) {
+		t.Errorf("rss: status %d schema %s", status, body["schema"])
+	}
+	if status, body = get("broken"); status != fiber.StatusOK || string(body["schema"]) != "null" {
+		t.Errorf("uncompiled schema should be served as null, got %d %s", status, body["schema"])
+	}
+	if status, _ = get("weather"); status != fiber.StatusNotFound {
+		t.Errorf("unknown channel status = %d, want 404", status)
+	}
+}
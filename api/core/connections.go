@@ -0,0 +1,294 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brandon-relentnet/myscrollr/api/core/oauth"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Third-party account connections.
+//
+// The oauth package runs the flow (state, code exchange, encrypted token
+// storage, refresh); this file exposes it under one set of routes for
+// every provider registered in registerOAuthProviders:
+//
+//	GET    /users/me/connections                   list providers + the caller's links
+//	GET    /users/me/connections/:provider/start   consent URL for a popup
+//	DELETE /users/me/connections/:provider         forget the link
+//	GET    /oauth/:provider/callback               provider redirect target (public)
+//
+// Core code that needs to call a provider on a user's behalf uses
+// OAuth.Token, which refreshes transparently and returns
+// oauth.ErrReauthRequired once the user has to reconnect.
+//
+// Tokens are sealed with OAUTH_ENCRYPTION_KEY (base64, 32 bytes), falling
+// back to ENCRYPTION_KEY. Without either, connections are disabled and
+// the routes answer 503.
+
+// OAuth is the connection manager, nil when no encryption key is set.
+var OAuth *oauth.Manager
+
+// DefaultOAuthCallbackBaseURL is the public gateway origin providers
+// redirect back to when neither OAUTH_CALLBACK_BASE_URL nor API_URL is set.
+const DefaultOAuthCallbackBaseURL = "https://api.myscrollr.com"
+
+// InitOAuth builds the connection manager and registers providers.
+// Call after ConnectDB and ConnectRedis.
+func InitOAuth() {
+	key := Secret("OAUTH_ENCRYPTION_KEY")
+	if key == "" {
+		key = Secret("ENCRYPTION_KEY")
+	}
+	if key == "" {
+		log.Println("[OAuth] No OAUTH_ENCRYPTION_KEY or ENCRYPTION_KEY; account connections disabled")
+		return
+	}
+	sealer, err := oauth.NewSealer(key)
+	if err != nil {
+		log.Printf("[OAuth] %v; account connections disabled", err)
+		return
+	}
+
+	base := os.Getenv("OAUTH_CALLBACK_BASE_URL")
+	if base == "" {
+		base = os.Getenv("API_URL")
+	}
+	if base == "" {
+		base = DefaultOAuthCallbackBaseURL
+	}
+	m := oauth.NewManager(base,
+		oauth.RedisStateStore{Rdb: Rdb},
+		&oauth.PGTokenStore{DB: DBPool, Sealer: sealer},
+	)
+	for _, p := range registerOAuthProviders() {
+		if err := m.Register(p); err != nil {
+			log.Printf("[OAuth] Skipping provider: %v", err)
+		}
+	}
+	OAuth = m
+	log.Printf("[OAuth] Account connections ready (%d provider(s))", len(m.Providers()))
+}
+
+// registerOAuthProviders returns the providers offered to users. A
+// provider whose client credentials aren't configured should be left
+// out rather than registered half-empty. Add new providers here:
+//
+//	if id := Secret("STRAVA_CLIENT_ID"); id != "" {
+//		providers = append(providers, oauth.Provider{Name: "strava", ...})
+//	}
+func registerOAuthProviders() []oauth.Provider {
+	var providers []oauth.Provider
+	return providers
+}
+
+// ConnectionInfo is one provider as shown on the connections page.
+type ConnectionInfo struct {
+	Provider    string     `json:"provider"`
+	Connected   bool       `json:"connected"`
+	Status      string     `json:"status,omitempty"`
+	AccountID   string     `json:"account_id,omitempty"`
+	Scope       string     `json:"scope,omitempty"`
+	ConnectedAt *time.Time `json:"connected_at,omitempty"`
+}
+
+func oauthDisabled(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{
+		Status: "error",
+		Error:  "Account connections are not configured",
+	})
+}
+
+// HandleListConnections lists every registered provider with the
+// caller's connection state.
+// @Summary List account connections
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{connections=[]ConnectionInfo}
+// @Failure 503 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/connections [get]
+func HandleListConnections(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	if OAuth == nil {
+		return oauthDisabled(c)
+	}
+	conns, err := OAuth.Connections(c.Context(), userID)
+	if err != nil {
+		log.Printf("[OAuth] list connections failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load connections",
+		})
+	}
+	byProvider := make(map[string]oauth.Connection, len(conns))
+	for _, conn := range conns {
+		byProvider[conn.Provider] = conn
+	}
+
+	names := OAuth.Providers()
+	sort.Strings(names)
+	out := make([]ConnectionInfo, 0, len(names))
+	for _, name := range names {
+		info := ConnectionInfo{Provider: name}
+		if conn, ok := byProvider[name]; ok {
+			created := conn.CreatedAt
+			info.Connected = true
+			info.Status = conn.Status
+			info.AccountID = conn.AccountID
+			info.Scope = conn.Scope
+			info.ConnectedAt = &created
+		}
+		out = append(out, info)
+	}
+	return c.JSON(fiber.Map{"connections": out})
+}
+
+// HandleStartConnection returns the provider's consent URL. The client
+// opens it in a popup and listens for the callback's postMessage.
+// @Summary Start an account connection
+// @Tags Users
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} object{auth_url=string}
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/connections/{provider}/start [get]
+func HandleStartConnection(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	if OAuth == nil {
+		return oauthDisabled(c)
+	}
+	authURL, err := OAuth.Start(c.Context(), c.Params("provider"), userID)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Unknown provider",
+		})
+	}
+	if err != nil {
+		log.Printf("[OAuth] start %s failed: %v", c.Params("provider"), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to start connection",
+		})
+	}
+	return c.JSON(fiber.Map{"auth_url": authURL})
+}
+
+// HandleDeleteConnection forgets the caller's link to a provider. It
+// does not revoke the grant on the provider's side.
+// @Summary Disconnect an account
+// @Tags Users
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/connections/{provider} [delete]
+func HandleDeleteConnection(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	if OAuth == nil {
+		return oauthDisabled(c)
+	}
+	err := OAuth.Disconnect(c.Context(), c.Params("provider"), userID)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Unknown provider",
+		})
+	}
+	if err != nil {
+		log.Printf("[OAuth] disconnect %s failed: %v", c.Params("provider"), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to disconnect",
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleOAuthCallback is the redirect target for every provider. It
+// answers with a popup page that notifies the opener and closes itself;
+// the user is identified by the state issued at start, not a session.
+// @Summary OAuth provider callback
+// @Tags Auth
+// @Produce html
+// @Param provider path string true "Provider name"
+// @Param state query string true "State issued at start"
+// @Param code query string false "Authorization code"
+// @Success 200 {string} string "HTML popup page"
+// @Router /oauth/{provider}/callback [get]
+func HandleOAuthCallback(c *fiber.Ctx) error {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = DefaultFrontendURL
+	}
+	c.Set("Content-Type", "text/html")
+
+	if OAuth == nil {
+		return c.Status(fiber.StatusServiceUnavailable).SendString(
+			oauth.PopupHTML(nil, frontendURL, false, "Account connections are not available right now."))
+	}
+	provider, err := OAuth.Provider(c.Params("provider"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString(
+			oauth.PopupHTML(nil, frontendURL, false, "Unknown provider."))
+	}
+
+	if denied := c.Query("error"); denied != "" {
+		log.Printf("[OAuth] %s consent declined: %s", provider.Name, denied)
+		return c.Status(fiber.StatusOK).SendString(
+			oauth.PopupHTML(provider, frontendURL, false, provider.DisplayName+" access was not granted."))
+	}
+
+	conn, err := OAuth.Complete(c.Context(), provider.Name, c.Query("state"), c.Query("code"))
+	if errors.Is(err, oauth.ErrInvalidState) {
+		return c.Status(fiber.StatusBadRequest).SendString(
+			oauth.PopupHTML(provider, frontendURL, false, "This sign-in link has expired. Please connect again from Scrollr."))
+	}
+	if err != nil {
+		log.Printf("[OAuth] %s callback failed: %v", provider.Name, err)
+		return c.Status(fiber.StatusBadGateway).SendString(
+			oauth.PopupHTML(provider, frontendURL, false, "We couldn't finish connecting "+provider.DisplayName+". Please try again."))
+	}
+
+	log.Printf("[OAuth] %s connected for %s", provider.Name, conn.LogtoSub)
+	return c.Status(fiber.StatusOK).SendString(
+		oauth.PopupHTML(provider, frontendURL, true, "Connected. You can close this window."))
+}
+
+// isOAuthCallbackPath reports whether path is /oauth/{provider}/callback.
+func isOAuthCallbackPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/oauth/")
+	if !ok {
+		return false
+	}
+	name, tail, _ := strings.Cut(rest, "/")
+	return name != "" && tail == "callback"
+}
@@ -19,6 +19,31 @@ const (
 const (
 	HealthCheckTimeout = 2 * time.Second
 	LogtoProxyTimeout  = 10 * time.Second
+
+	// Per-purpose client timeouts (httpclient.go).
+	ChannelProxyTimeout     = 70 * time.Second
+	ChannelLifecycleTimeout = 10 * time.Second
+	EmailSendTimeout        = 15 * time.Second
+	OSTicketTimeout         = 15 * time.Second
+	OSTicketReplyTimeout    = 20 * time.Second
+)
+
+// =============================================================================
+// HTTP Connection Pooling
+// =============================================================================
+
+const (
+	HTTPMaxIdleConns        = 200
+	HTTPMaxIdleConnsPerHost = 32
+	HTTPIdleConnTimeout     = 90 * time.Second
+	HTTPDialTimeout         = 5 * time.Second
+	HTTPTLSHandshakeTimeout = 10 * time.Second
+
+	// DNS cache and egress policy (egress.go).
+	DNSCacheTTL           = 30 * time.Second
+	DNSCacheStaleTTL      = 5 * time.Minute
+	DNSCacheMaxEntries    = 1024
+	EgressBlockedMaxHosts = 200
 )
 
 // =============================================================================
@@ -26,11 +51,20 @@ const (
 // =============================================================================
 
 const (
+	// Defaults; DB_MAX_CONNS, DB_MIN_CONNS, DB_MAX_CONN_IDLE_TIME and
+	// DB_MAX_CONN_LIFETIME override them (database.go).
 	DBMaxConns        = 20
 	DBMinConns        = 2
 	DBMaxConnIdleTime = 30 * time.Minute
+	DBMaxConnLifetime = time.Hour
 	DBMaxRetries      = 5
 	DBRetryDelay      = 2 * time.Second
+
+	// Read replica (DATABASE_REPLICA_URL): reads fall back to the primary
+	// while it is unreachable or more than ReplicaMaxLag behind.
+	ReplicaCheckInterval = 10 * time.Second
+	ReplicaCheckTimeout  = 2 * time.Second
+	ReplicaMaxLag        = 30 * time.Second
 )
 
 // =============================================================================
@@ -43,6 +77,42 @@ const (
 	SSEClientBufferSize  = 100
 	SSEDispatchWorkers   = 8
 	SSEDispatchQueueSize = 4096
+
+	// Budget for reading a connecting user's persisted topics.
+	SSETopicRestoreTimeout = 500 * time.Millisecond
+
+	// Each replica reports its client count this often; reports older
+	// than SSEClientCountStale are ignored and removed.
+	SSEClientCountInterval = 10 * time.Second
+	SSEClientCountStale    = 30 * time.Second
+
+	// Last-Event-ID replay (replay.go): envelopes kept per topic, and how
+	// long a disconnected client can be away and still resume.
+	SSEReplayPerTopic  = 64
+	SSEReplayRetention = 5 * time.Minute
+)
+
+// =============================================================================
+// WebSocket
+// =============================================================================
+
+const (
+	WSPingInterval   = 25 * time.Second
+	WSPongWait       = 60 * time.Second
+	WSWriteTimeout   = 10 * time.Second
+	WSMaxMessageSize = 4096 // clients don't send data; this only bounds junk
+)
+
+// =============================================================================
+// Background Work Pools
+// =============================================================================
+
+const (
+	BackgroundPoolWorkers   = 32
+	BackgroundPoolQueueSize = 4096
+	ExternalPoolWorkers     = 8
+	ExternalPoolQueueSize   = 256
+	WorkPoolDropLogInterval = 60 * time.Second
 )
 
 // =============================================================================
@@ -57,6 +127,22 @@ const (
 	TopicPrefixRSS     = "cdc:rss:"       // cdc:rss:{feed_url_fnv_hash}
 	TopicPrefixFantasy = "cdc:fantasy:"   // cdc:fantasy:{league_key}
 	TopicPrefixCore    = "cdc:core:user:" // cdc:core:user:{logto_sub}
+
+	// TopicBroadcast reaches every connected SSE client on every gateway
+	// replica (incident banners).
+	TopicBroadcast = "sse:broadcast"
+
+	// TopicDisconnect closes every SSE/WebSocket connection of the user
+	// named in the payload, on every replica (account deletion).
+	TopicDisconnect = "sse:disconnect"
+
+	// TopicRefreshUserTopics makes every replica holding a connection of
+	// the user named in the payload re-read their topics (sse_cluster.go).
+	TopicRefreshUserTopics = "sse:topics:refresh"
+
+	// TopicCacheInvalidate carries a JSON array of cache keys every
+	// gateway and channel API drops from its local hot-key cache.
+	TopicCacheInvalidate = "cache:invalidate"
 )
 
 // =============================================================================
@@ -64,14 +150,204 @@ const (
 // =============================================================================
 
 const (
+	// RateLimitMax is the per-IP budget of anonymous callers. Signed-in
+	// callers are counted per account at their tier's budget below
+	// (rate_limit.go).
 	RateLimitMax        = 120
 	RateLimitExpiration = 1 * time.Minute
 
+	RateLimitFreeMax      = 180
+	RateLimitUplinkMax    = 300
+	RateLimitProMax       = 600
+	RateLimitUltimateMax  = 1200
+	RateLimitSuperUserMax = 3000
+
 	// Stricter rate limit for OAuth initiation endpoints to prevent abuse.
 	// 10 attempts per 5 minutes per IP is generous for legitimate users
 	// but blocks automated abuse.
 	OAuthRateLimitMax        = 10
 	OAuthRateLimitExpiration = 5 * time.Minute
+
+	// Per-key limit for API-key authenticated channel routes. Keyed on
+	// the key, not the IP, so a script on a shared CI runner isn't
+	// throttled by its neighbours.
+	APIKeyRateLimitMax    = 120
+	APIKeyRateLimitWindow = 1 * time.Minute
+
+	// Per-key limit for GET /public/feed?key=, a separate bucket from the
+	// channel routes so an overlay polling the feed can't starve the
+	// owner's scripts (or the reverse).
+	PublicFeedKeyRateLimitMax = 60
+)
+
+// =============================================================================
+// API Keys
+// =============================================================================
+
+const (
+	APIKeyHeader        = "X-API-Key"
+	APIKeyPrefix        = "scrollr_"
+	APIKeyMaxPerUser    = 5
+	APIKeyCacheTTL      = 60 * time.Second
+	RedisAPIKeyPrefix   = "apikey:"    // apikey:{sha256} -> {sub, scopes}
+	RedisAPIKeyRLPrefix = "apikey:rl:" // apikey:rl:{scope}:{sha256[:16]}
+
+	// Unknown and revoked key hashes are remembered briefly so repeated
+	// bad keys don't each cost a Postgres lookup. Keys are random, so a
+	// new key never collides with a remembered miss.
+	APIKeyMissCacheTTL    = 30 * time.Second
+	RedisAPIKeyMissPrefix = "apikey:miss:" // apikey:miss:{sha256} -> "1"
+
+	// Scopes a key can carry. Keys minted before scopes existed have
+	// only "channels".
+	APIKeyScopeChannels = "channels" // channel routes registered with api_key: true
+	APIKeyScopeFeed     = "feed"     // GET /public/feed?key=, the owner's ticker read-only
+
+	// The keyed public feed is cached per owner. Like the display ticker
+	// it is flushed by InvalidateDashboardCache on channel changes.
+	RedisPublicFeedUserPrefix = "cache:public:feed:user:"
+	PublicFeedUserCacheTTL    = 15 * time.Second
+)
+
+// =============================================================================
+// Display Tokens
+// =============================================================================
+
+const (
+	DisplayTokenPrefix      = "scrollr_display_"
+	DisplayTokenMaxPerUser  = 10
+	DisplayTokenCacheTTL    = 5 * time.Minute
+	RedisDisplayTokenPrefix = "display:" // display:{sha256} -> logto_sub
+
+	// The display ticker is cached per owner and, unlike /dashboard, not
+	// invalidated by CDC events: the display stream carries live updates,
+	// so the snapshot only has to be good enough to paint the first frame.
+	// Channel and preference changes still flush it (InvalidateDashboardCache).
+	DisplayTickerCacheTTL    = 2 * time.Minute
+	DisplayTickerMaxAge      = 30               // seconds, Cache-Control on the ticker
+	RedisDisplayTickerPrefix = "cache:display:" // cache:display:{logto_sub}
+
+	// DisplayTokenRecheckInterval is how often an open display stream
+	// re-resolves its token, so revocation or a downgrade ends the stream
+	// without anyone touching the TV.
+	DisplayTokenRecheckInterval = 5 * time.Minute
+)
+
+// =============================================================================
+// Short Links
+// =============================================================================
+
+const (
+	ShortLinkCodeLength   = 7    // base62, ~3.5e12 codes
+	ShortLinkMaxURLLength = 4096 // characters in target_url
+	ShortLinkDefaultTTL   = 90 * 24 * time.Hour
+	ShortLinkMaxTTL       = 365 * 24 * time.Hour
+
+	// ShortLinkCreateMaxPerHour caps POST /links per user; sharing is a
+	// click-at-a-time action, so anything above this is a script.
+	ShortLinkCreateMaxPerHour = 60
+	RedisShortLinkRLPrefix    = "links:rl:" // links:rl:{logto_sub}
+)
+
+// =============================================================================
+// Saved Searches & Notifications
+// =============================================================================
+
+const (
+	SavedSearchMaxPerUser = 20
+	SavedSearchMaxTerms   = 10
+	SavedSearchMaxSymbols = 20
+
+	// SavedSearchRefreshInterval is how often each gateway reloads the
+	// enabled searches it matches CDC items against; edits made on
+	// another replica take effect within one interval.
+	SavedSearchRefreshInterval = time.Minute
+
+	// SavedSearchNotifyMaxPerHour caps notifications per search, so a
+	// too-broad query can't flood the inbox.
+	SavedSearchNotifyMaxPerHour = 30
+	RedisSavedSearchRLPrefix    = "savedsearch:rl:" // savedsearch:rl:{id}
+
+	NotificationsPageLimit = 50
+	NotificationRetention  = 90 * 24 * time.Hour
+
+	// FantasyScoreNotifyEvery spaces fantasy_score notifications per user
+	// and matchup; live polling publishes a delta on every score change.
+	FantasyScoreNotifyEvery   = 30 * time.Minute
+	RedisFantasyScoreRLPrefix = "notif:fantasy:rl:" // notif:fantasy:rl:{sub}:{team}:{week}
+
+	// DigestCheckInterval is how often the digest worker looks for users
+	// whose digest hour has come round.
+	DigestCheckInterval = 10 * time.Minute
+	RedisDigestLock     = "notif:digest:lock"
+	DigestLockTTL       = 5 * time.Minute
+)
+
+// =============================================================================
+// Email Digests
+// =============================================================================
+
+const (
+	// EmailDigestCheckInterval is how often the email digest worker looks
+	// for schedules that have come due. Must stay under an hour: a
+	// schedule is only due during its hour.
+	EmailDigestCheckInterval = 10 * time.Minute
+	RedisEmailDigestLock     = "email:digest:lock"
+	EmailDigestLockTTL       = 10 * time.Minute
+
+	// EmailDigestBatchSize caps the digests sent per check; the rest go
+	// out on the next check within the same hour.
+	EmailDigestBatchSize = 200
+
+	// Per-section caps on what one email lists.
+	EmailDigestTopMovers = 5
+	EmailDigestMaxGames  = 10
+	EmailDigestMaxItems  = 10
+)
+
+// =============================================================================
+// API Usage Metering
+// =============================================================================
+
+const (
+	// Free requests per user per calendar month before overage applies.
+	APIUsageIncludedPerMonth = 10_000
+	// Default ceiling for metered users; API_USAGE_MONTHLY_CAP overrides.
+	APIUsageDefaultMonthlyCap = 1_000_000
+
+	APIUsageReportInterval  = 5 * time.Minute
+	APIUsageMeteredCacheTTL = 5 * time.Minute
+	// Counters outlive their month so the reporter can flush late usage.
+	APIUsageCounterTTL = 40 * 24 * time.Hour
+
+	RedisAPIUsagePrefix        = "apikey:usage:"        // apikey:usage:{sub}:{YYYY-MM}
+	RedisAPIUsageActivePrefix  = "apikey:usage:active:" // apikey:usage:active:{YYYY-MM} -> set of subs
+	RedisAPIUsageMeteredPrefix = "apikey:metered:"      // apikey:metered:{sub} -> "1" | "0"
+	RedisAPIUsageReportLock    = "apikey:usage:report:lock"
+)
+
+// =============================================================================
+// Anonymous Sessions
+// =============================================================================
+
+const (
+	AnonClaimTokenHeader   = "X-Claim-Token"
+	AnonClaimTokenPrefix   = "anon_"
+	AnonSessionTTL         = 30 * 24 * time.Hour
+	RedisAnonSessionPrefix = "anon:session:" // anon:session:{sha256(token)}
+
+	// AnonMaxPriceAlerts matches the finance API's free-tier AlertCap.
+	AnonMaxPriceAlerts = 3
+)
+
+// =============================================================================
+// Client Version Gating
+// =============================================================================
+
+const (
+	ClientVersionHeader      = "X-Client-Version"
+	RedisMinClientVersionKey = "config:min_client_version"
+	MinClientVersionCacheTTL = 30 * time.Second
 )
 
 // =============================================================================
@@ -82,12 +358,27 @@ const (
 	RedisChannelSubscribersPrefix = "channel:subscribers:"
 	RedisEventsUserPrefix         = "events:user:"
 	RedisDashboardCachePrefix     = "cache:dashboard:"
+	RedisDashboardShapedPrefix    = "cache:dashboard:shaped:"
 
 	// SportsLeagueSubscribersPrefix is the per-league subscriber set prefix.
 	// Keys: sports:subscribers:league:{NFL}, sports:subscribers:league:{NBA}, etc.
 	// Used by the core API for subscriber management and the sports channel for
 	// per-league CDC fan-out routing.
 	SportsLeagueSubscribersPrefix = "sports:subscribers:league:"
+
+	// RedisEventSeqKey is the global SSE envelope sequence counter.
+	// Shared in Redis (not per-process) because the gateway instance that
+	// receives a Sequin webhook is rarely the one holding the client's
+	// SSE connection.
+	RedisEventSeqKey = "sse:seq"
+
+	// RedisSSETopicsKey is a hash of user -> JSON topic list, restored into
+	// the registry when the user connects (registry_store.go).
+	RedisSSETopicsKey = "sse:topics"
+
+	// RedisSSEClientsKey is a hash of replica -> "count:unix", summed for
+	// /events/count (sse_cluster.go).
+	RedisSSEClientsKey = "sse:clients"
 )
 
 // SportsLeagues was a hardcoded list of league identifiers used before per-user
@@ -99,14 +390,175 @@ const (
 // Current leagues: NFL, NCAA Football, NBA, NCAA Basketball, NHL, MLB,
 // Premier League, La Liga, MLS, Champions League, Formula 1
 
+// =============================================================================
+// Channel Lifecycle Retry
+// =============================================================================
+
+const (
+	// Failed lifecycle events are queued per channel in a sorted set
+	// (lifecycle:retry:{channel}) scored by an enqueue sequence, so replay
+	// preserves the order the gateway originally sent them in.
+	RedisLifecycleRetryPrefix = "lifecycle:retry:"
+	RedisLifecycleRetrySeqKey = "lifecycle:retry:seq"
+	RedisLifecycleRetryMeta   = "lifecycle:retry:meta:"
+	RedisLifecycleRetryLock   = "lifecycle:retry:lock:"
+
+	LifecycleRetryInterval   = 10 * time.Second
+	LifecycleRetryBaseDelay  = 15 * time.Second
+	LifecycleRetryMaxDelay   = 10 * time.Minute
+	LifecycleRetryMaxAge     = 24 * time.Hour
+	LifecycleRetryBatchSize  = 100
+	LifecycleRetryLockTTL    = 60 * time.Second
+	LifecycleRetryMaxPending = 10000 // per channel; oldest dropped beyond this
+)
+
+// =============================================================================
+// CDC Webhook
+// =============================================================================
+
+const (
+	// Records for the same topic arriving within this window, across
+	// webhook deliveries, go out as one envelope (cdc_coalesce.go). A
+	// topic's batch is flushed early once it holds CDCCoalesceMaxRecords.
+	CDCCoalesceWindow     = 50 * time.Millisecond
+	CDCCoalesceMaxRecords = 200
+	// Cap on a gzip-encoded webhook body once decompressed.
+	CDCWebhookMaxBodyBytes = 32 << 20
+)
+
+// =============================================================================
+// CDC Dead-Letter Queue
+// =============================================================================
+
+const (
+	// CDC records that fail to route are appended to one Redis stream and
+	// retried with backoff; after CDCDLQMaxAttempts they stay in the
+	// stream, marked dead, until an admin replays them (cdc_dlq.go).
+	RedisCDCDLQStream = "cdc:dlq"
+	RedisCDCDLQLock   = "cdc:dlq:lock"
+
+	CDCDLQInterval    = 5 * time.Second
+	CDCDLQBaseDelay   = 5 * time.Second
+	CDCDLQMaxDelay    = 5 * time.Minute
+	CDCDLQMaxAttempts = 8
+	CDCDLQScanBatch   = 200
+	CDCDLQLockTTL     = 30 * time.Second
+	CDCDLQMaxLen      = 10000 // oldest entries trimmed beyond this
+	CDCDLQListDefault = 100
+	CDCDLQListMax     = 1000
+)
+
+// =============================================================================
+// Channel Batch
+// =============================================================================
+
+const (
+	// ChannelBatchMaxOps caps POST /users/me/channels/batch. There is one
+	// row per channel type, so a real batch is a handful of operations.
+	ChannelBatchMaxOps = 16
+)
+
 // =============================================================================
 // Dashboard Cache
 // =============================================================================
 
 const (
 	DashboardCacheTTL = 30 * time.Second
-	HealthCacheTTL    = 10 * time.Second
-	HealthCacheKey    = "cache:health"
+	// Dashboards missing a channel (see DashboardResponse.Errors) are
+	// cached briefly, so a recovered channel is back within a poll or two.
+	DashboardPartialCacheTTL = 5 * time.Second
+	// Budget for each channel's /internal/dashboard during the fan-out.
+	// A channel may register dashboard_timeout_ms to ask for more, up to
+	// DashboardChannelMaxTimeout.
+	DashboardChannelTimeout    = 2 * time.Second
+	DashboardChannelMaxTimeout = 5 * time.Second
+	HealthCacheTTL             = 10 * time.Second
+	HealthCacheKey             = "cache:health"
+
+	// Bounds on GET /dashboard shaping parameters (dashboard_shape.go).
+	DashboardShapeMaxLimit  = 100
+	DashboardShapeMaxFields = 20
+
+	// Gateway cache for proxied public routes that opt in with cache_ttl.
+	// Keys: cache:proxy:{channel}:{gen}:{sha256(method path?query)}. Bumping
+	// cache:proxy:gen:{channel} invalidates every entry for the channel.
+	RedisProxyCachePrefix    = "cache:proxy:"
+	RedisProxyCacheGenPrefix = "cache:proxy:gen:"
+	ProxyCacheMaxTTL         = 10 * time.Minute
+	ProxyCacheMaxBodyBytes   = 1 << 20
+)
+
+// =============================================================================
+// Secrets
+// =============================================================================
+
+const (
+	// How often a file or Vault secrets provider is re-read (secrets.go).
+	SecretsRefreshInterval = time.Minute
+	SecretsFetchTimeout    = 10 * time.Second
+)
+
+// =============================================================================
+// Signing Keys
+// =============================================================================
+
+const (
+	// Keysets for gateway-signed tokens (signing_keys.go). Active keys are
+	// rotated on a schedule; a retired key keeps verifying for the grace
+	// period, which must outlast the longest-lived token it signed.
+	SigningKeyRotationInterval = 30 * 24 * time.Hour
+	SigningKeyGracePeriod      = 7 * 24 * time.Hour
+	SigningKeyCheckInterval    = time.Hour
+	RedisSigningKeyRotateLock  = "signing_keys:rotate:lock"
+
+	// Each replica caches its keysets; another replica's rotation is seen
+	// within the TTL. An unknown kid forces a reload, at most once per
+	// SigningKeyReloadMinGap so junk kids can't hammer Postgres.
+	SigningKeyCacheTTL     = time.Minute
+	SigningKeyReloadMinGap = 5 * time.Second
+)
+
+// =============================================================================
+// Redis Circuit Breaker
+// =============================================================================
+
+const (
+	// Consecutive connection-level failures (timeouts, refused dials) that
+	// open the breaker. Replies like redis.Nil or WRONGTYPE don't count.
+	RedisBreakerFailureThreshold = 5
+	// How long the breaker stays open before letting one probe command through.
+	RedisBreakerCooldown = 5 * time.Second
+
+	// In-process fallback for GetCache/SetCache while Redis is unreachable.
+	// Entries are capped at RedisFallbackMaxTTL so a long outage serves data
+	// no staler than a normal cache would.
+	RedisFallbackCacheMaxBytes = 64 << 20
+	RedisFallbackMaxTTL        = time.Minute
+
+	// Hot-key local cache in front of Redis (cache_local.go).
+	LocalCacheMaxBytes = 16 << 20
+	LocalCacheTTL      = 2 * time.Second
+)
+
+// =============================================================================
+// Channel Circuit Breakers
+// =============================================================================
+
+const (
+	// Consecutive failed dashboard fetches or health checks (transport
+	// errors, timeouts, 5xx) that mark a channel down (channel_breaker.go).
+	ChannelBreakerFailureThreshold = 3
+	// How long a down channel fails fast before one call probes it again.
+	ChannelBreakerCooldown = 10 * time.Second
+)
+
+// =============================================================================
+// Incidents
+// =============================================================================
+
+const (
+	RedisIncidentsActiveKey = "cache:incidents:active"
+	IncidentsCacheTTL       = 15 * time.Second
 )
 
 // =============================================================================
@@ -118,8 +570,280 @@ const (
 	LogtoM2MTokenBufferSecs = 60
 	LogtoM2MTokenTimeout    = 10 * time.Second
 
-	// Stripe webhook signature tolerance.
-	StripeWebhookTolerance = 300 // seconds
+	// Stripe webhook signature tolerance: signed timestamps older than
+	// this are rejected as replays. STRIPE_WEBHOOK_TOLERANCE_SECONDS
+	// overrides the default within [1, StripeWebhookMaxTolerance].
+	StripeWebhookTolerance    = 300  // seconds
+	StripeWebhookMaxTolerance = 3600 // seconds
+
+	// An event claimed but never marked processed (pod died mid-dispatch)
+	// may be reclaimed by a Stripe retry after this long.
+	StripeWebhookClaimTimeout = 5 * time.Minute
+
+	// Free trial for first-time subscribers; STRIPE_TRIAL_DAYS overrides
+	// the default, capped at Stripe's 730-day maximum.
+	DefaultTrialDays = 7
+	MaxTrialDays     = 730
+
+	// Longest customer-facing promo code we'll look up.
+	MaxPromoCodeLength = 64
+
+	// Invoice history listing and Stripe backfill throttle.
+	InvoiceListDefault         = 24
+	InvoiceListMax             = 100
+	InvoiceBackfillInterval    = 24 * time.Hour
+	RedisInvoiceBackfillPrefix = "invoices:backfilled:" // invoices:backfilled:{sub}
+
+	// Dunning: paid roles survive a failed renewal for the grace period,
+	// then the sweep drops the user to free until an invoice is paid.
+	BillingGracePeriod      = 7 * 24 * time.Hour
+	DunningSweepInterval    = time.Hour
+	RedisDunningSweepLock   = "billing:dunning:lock"
+	PaymentRetryCooldown    = 5 * time.Minute
+	RedisPaymentRetryPrefix = "billing:retry:" // billing:retry:{sub}
+
+	// Admin billing tools.
+	MaxCompDays              = 365
+	BillingSnapshotListLimit = 10
+	BillingAuditListMax      = 50
+	CompTimeSweepInterval    = time.Hour
+	RedisCompTimeSweepLock   = "billing:comp:lock"
+)
+
+// =============================================================================
+// Team Seats
+// =============================================================================
+
+const (
+	// Seats per subscription, owner included. Sized for a household;
+	// larger teams go through the business channel.
+	MaxTeamSeats  = 6
+	TeamInviteTTL = 7 * 24 * time.Hour
+)
+
+// =============================================================================
+// Organizations
+// =============================================================================
+
+const (
+	// Shared channel configs are stored in user_channels under this
+	// synthetic owner (org:{id}), so channel APIs read them like a user's.
+	OrgChannelOwnerPrefix = "org:"
+
+	MaxOrgNameLength      = 80
+	MaxOrgsPerUser        = 10
+	MaxOrgMembersPro      = 10 // member cap when the acting admin is on Uplink Pro
+	MaxOrgMembersUltimate = 50 // ... on Uplink Ultimate; super users are uncapped
+)
+
+// =============================================================================
+// Telemetry
+// =============================================================================
+
+const (
+	// Intake limits for POST /telemetry.
+	TelemetryMaxEventsPerRequest = 50
+	TelemetryMaxPropertiesBytes  = 4 << 10
+	TelemetryMaxFieldLen         = 64
+	// Client timestamps further than this from server time are replaced.
+	TelemetryClockSkew = 24 * time.Hour
+
+	// Buffering: flush on the interval or once a batch fills. The buffer
+	// drops its oldest events past TelemetryBufferMax.
+	TelemetryBatchSize        = 500
+	TelemetryBufferMax        = 20_000
+	TelemetryFlushInterval    = 10 * time.Second
+	TelemetryCollectorTimeout = 10 * time.Second
+
+	TelemetryConsentCacheTTL    = 5 * time.Minute
+	RedisTelemetryConsentPrefix = "telemetry:consent:" // telemetry:consent:{sub}
+)
+
+// =============================================================================
+// Engagement
+// =============================================================================
+
+const (
+	// Longest item ID or source key accepted on a click.
+	EngagementMaxKeyLen = 256
+
+	// Raw clicks are kept this long; rollups are kept indefinitely.
+	EngagementClickRetentionDays = 90
+	EngagementDefaultDays        = 7
+	EngagementTopSources         = 100
+
+	// The aggregator checks hourly whether yesterday has been rolled up.
+	EngagementAggregateInterval     = time.Hour
+	EngagementViewersTTL            = 72 * time.Hour
+	RedisEngagementViewersPrefix    = "engagement:viewers:"    // engagement:viewers:{channel}:{YYYY-MM-DD}
+	RedisEngagementAggregatedPrefix = "engagement:aggregated:" // engagement:aggregated:{YYYY-MM-DD}
+)
+
+// =============================================================================
+// Recommendations
+// =============================================================================
+
+const (
+	// Items followed by fewer users are never suggested.
+	RecommendationMinSupport = 3
+	RecommendationsPerKind   = 10
+
+	// Popularity boost per log-click over the trailing window.
+	RecommendationClickWeight     = 0.5
+	RecommendationClickWindowDays = 30
+
+	RecommendationJobInterval          = time.Hour
+	RecommendationsCacheTTL            = time.Hour
+	RedisRecommendationsPrefix         = "recommendations:"          // recommendations:{sub}
+	RedisRecommendationsComputedPrefix = "recommendations:computed:" // recommendations:computed:{YYYY-MM-DD}
+)
+
+// =============================================================================
+// Trending
+// =============================================================================
+
+const (
+	// Counts below the minimum are hidden; the rest are rounded down to
+	// a multiple of the bucket so no single user's action is visible.
+	TrendingMinCount    = 5
+	TrendingCountBucket = 5
+	TrendingListSize    = 10
+
+	TrendingSnapshotRetentionDays = 30
+
+	TrendingJobInterval         = time.Hour
+	TrendingCacheTTL            = 26 * time.Hour
+	TrendingHTTPMaxAge          = 15 * time.Minute
+	RedisTrendingKey            = "trending"
+	RedisTrendingComputedPrefix = "trending:computed:" // trending:computed:{YYYY-MM-DD}
+)
+
+// =============================================================================
+// Dashboard Snapshots
+// =============================================================================
+
+const (
+	// Users with at least this many enabled channels are served from a
+	// materialized snapshot; enrollment stops at the user cap.
+	DashboardSnapshotMinChannels = 3
+	DashboardSnapshotMaxUsers    = 2000
+
+	DashboardSnapshotDebounce        = 250 * time.Millisecond
+	DashboardSnapshotRefreshInterval = DashboardCacheTTL
+	DashboardSnapshotActiveWindow    = 10 * time.Minute
+	DashboardSnapshotTTL             = 15 * time.Minute
+	DashboardSnapshotConcurrency     = 8
+
+	DashboardLatencySamples     = 1024
+	DashboardLatencyLogInterval = 5 * time.Minute
+
+	RedisDashboardSnapshotPrefix     = "dashboard:snapshot:"       // dashboard:snapshot:{sub}
+	RedisDashboardSnapshotActive     = "dashboard:snapshot:active" // ZSET sub → last request (unix)
+	RedisDashboardSnapshotBuilt      = "dashboard:snapshot:built"  // ZSET sub → last rebuild (unix)
+	RedisDashboardSnapshotLockPrefix = "dashboard:snapshot:lock:"  // dashboard:snapshot:lock:{sub}
+)
+
+// =============================================================================
+// Spoiler Windows
+// =============================================================================
+
+const (
+	// Per-league spoiler windows: hours after a game (or fantasy week)
+	// ends during which its scores stay hidden.
+	SpoilerWindowMaxHours   = 7 * 24
+	SpoilerWindowMaxLeagues = 50
+	SpoilerWindowCacheTTL   = time.Minute
+
+	// Games carry only a start time; the window is counted from start +
+	// the sport's typical length, or this when the sport is unknown.
+	SpoilerDefaultGameLength = 3 * time.Hour
+)
+
+// =============================================================================
+// SLOs
+// =============================================================================
+
+const (
+	// Targets (slo.go). Each SLO counts events as good or bad against its
+	// threshold; burn rate is the bad fraction over the error budget.
+	SLODashboardLatencyThreshold = 400 * time.Millisecond
+	SLODashboardLatencyObjective = 0.95
+	SLODeliveryLagThreshold      = 2 * time.Second
+	SLODeliveryLagObjective      = 0.99
+	SLOCDCRoutingObjective       = 0.999
+
+	// Events are bucketed per minute; the longest alert window bounds
+	// how much history is kept.
+	SLOBucketWidth = time.Minute
+	SLOHistory     = 6 * time.Hour
+)
+
+// =============================================================================
+// Data Export
+// =============================================================================
+
+const (
+	// Finished archives are kept this long for download, then deleted.
+	DataExportRetention = 7 * 24 * time.Hour
+
+	// The worker picks up queued jobs (and jobs abandoned by a replica
+	// that died mid-run) on this interval; a new request also starts it.
+	DataExportWorkerInterval = time.Minute
+	DataExportStaleAfter     = 10 * time.Minute
+	DataExportMaxAttempts    = 3
+	DataExportBuildTimeout   = 2 * time.Minute
+
+	// Requests per user per rolling day; exports are cheap but not free.
+	DataExportDailyLimit = 5
+)
+
+// =============================================================================
+// Audit Events
+// =============================================================================
+
+const (
+	// GET /users/me/audit page size.
+	AuditListDefault = 50
+	AuditListMax     = 200
+
+	// Audit rows are kept this long; the pruner runs daily.
+	AuditRetention     = 365 * 24 * time.Hour
+	AuditPruneInterval = 24 * time.Hour
+
+	// At most one auth-failure event per client IP per window, so a
+	// credential-stuffing burst can't flood the table.
+	AuthFailureAuditWindow      = time.Minute
+	RedisAuthFailureAuditPrefix = "audit:authfail:" // audit:authfail:{ip}
+)
+
+// =============================================================================
+// Extension Sessions
+// =============================================================================
+
+const (
+	// Sessions unused this long are hidden and then pruned; Logto's
+	// refresh tokens have expired well before.
+	ExtensionSessionIdleExpiry    = 30 * 24 * time.Hour
+	ExtensionSessionPruneInterval = 24 * time.Hour
+	ExtensionSessionLabelMax      = 100 // characters
+
+	// Revoked sessions are kept well past the refresh token TTL set for
+	// the desktop and extension apps in Logto (14 days by default), so
+	// the proxy keeps refusing a revoked token for as long as it could
+	// still work, instead of adopting it as a new session.
+	ExtensionSessionRevokedRetention = 180 * 24 * time.Hour
+)
+
+// =============================================================================
+// Admin Stats
+// =============================================================================
+
+const (
+	// Latency samples kept per route for /admin/stats percentiles.
+	RouteLatencySamples = 256
+
+	// CDC throughput is counted in one-second buckets over this window.
+	CDCThroughputWindow = time.Minute
 )
 
 // =============================================================================
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Dashboard response shaping.
+//
+// Low-bandwidth clients (the browser extension) can ask GET /dashboard
+// for just what they render:
+//
+//	?channels=finance,rss            only these channels' data
+//	&limit.rss=10                    at most 10 items per list
+//	&fields.finance=symbol,price     only these fields per item
+//
+// The limit and fields hints are passed on to each channel's
+// /internal/dashboard as ?limit= and ?fields=, so a channel can skip work
+// it would throw away (rss and sports already serve ?limit= as their
+// first page). The gateway then enforces them on the channel's response,
+// so channels that ignore the hints are shaped too: each top-level list
+// is truncated and its objects keep only the listed fields.
+//
+// Shaped dashboards bypass the per-user cache and snapshot, which hold
+// the full dashboard. They're cached per shape for
+// DashboardPartialCacheTTL instead, short enough that a config change or
+// CDC invalidation shows up without tracking every shape a user asked for.
+
+// dashboardShape is a parsed set of shaping parameters. The zero value
+// (and nil) means the full dashboard.
+type dashboardShape struct {
+	channels map[string]bool     // nil = every enabled channel
+	limits   map[string]int      // channel -> max items per list
+	fields   map[string][]string // channel -> fields kept per item
+}
+
+var dashboardFieldRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// parseDashboardShape reads the shaping parameters from the query string.
+// It returns nil when none are present.
+func parseDashboardShape(c *fiber.Ctx) (*dashboardShape, error) {
+	var shape dashboardShape
+	var parseErr error
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		if parseErr != nil {
+			return
+		}
+		key, val := string(k), strings.TrimSpace(string(v))
+		switch {
+		case key == "channels":
+			for _, name := range strings.Split(val, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					if shape.channels == nil {
+						shape.channels = make(map[string]bool)
+					}
+					shape.channels[name] = true
+				}
+			}
+		case strings.HasPrefix(key, "limit."):
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > DashboardShapeMaxLimit {
+				parseErr = fmt.Errorf("%s must be between 1 and %d", key, DashboardShapeMaxLimit)
+				return
+			}
+			if shape.limits == nil {
+				shape.limits = make(map[string]int)
+			}
+			shape.limits[strings.TrimPrefix(key, "limit.")] = n
+		case strings.HasPrefix(key, "fields."):
+			var fields []string
+			for _, f := range strings.Split(val, ",") {
+				f = strings.TrimSpace(f)
+				if f == "" {
+					continue
+				}
+				if !dashboardFieldRe.MatchString(f) {
+					parseErr = fmt.Errorf("%s: invalid field %q", key, f)
+					return
+				}
+				fields = append(fields, f)
+			}
+			if len(fields) == 0 || len(fields) > DashboardShapeMaxFields {
+				parseErr = fmt.Errorf("%s must list 1 to %d fields", key, DashboardShapeMaxFields)
+				return
+			}
+			sort.Strings(fields)
+			if shape.fields == nil {
+				shape.fields = make(map[string][]string)
+			}
+			shape.fields[strings.TrimPrefix(key, "fields.")] = fields
+		}
+	})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if shape.channels == nil && shape.limits == nil && shape.fields == nil {
+		return nil, nil
+	}
+	return &shape, nil
+}
+
+// includes reports whether the shape keeps a channel's data.
+func (s *dashboardShape) includes(channel string) bool {
+	return s == nil || s.channels == nil || s.channels[channel]
+}
+
+// hintQuery is the query suffix passed on to a channel's
+// /internal/dashboard ("" when the shape has no hints for it).
+func (s *dashboardShape) hintQuery(channel string) string {
+	if s == nil {
+		return ""
+	}
+	q := url.Values{}
+	if n, ok := s.limits[channel]; ok {
+		q.Set("limit", strconv.Itoa(n))
+	}
+	if f, ok := s.fields[channel]; ok {
+		q.Set("fields", strings.Join(f, ","))
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "&" + q.Encode()
+}
+
+// apply enforces the channel's limit and fields on its dashboard data.
+// Only top-level lists are shaped; other values pass through.
+func (s *dashboardShape) apply(channel string, data map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	limit, hasLimit := s.limits[channel]
+	fields, hasFields := s.fields[channel]
+	if !hasLimit && !hasFields {
+		return
+	}
+	for key, v := range data {
+		list, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+		if hasLimit && len(list) > limit {
+			list = list[:limit]
+		}
+		if hasFields {
+			for i, item := range list {
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				kept := make(map[string]interface{}, len(fields))
+				for _, f := range fields {
+					if fv, ok := obj[f]; ok {
+						kept[f] = fv
+					}
+				}
+				list[i] = kept
+			}
+		}
+		data[key] = list
+	}
+}
+
+// cacheKey is the per-user cache key for the shaped dashboard.
+func (s *dashboardShape) cacheKey(userID string) string {
+	var parts []string
+	for name := range s.channels {
+		parts = append(parts, "channels."+name)
+	}
+	for name, n := range s.limits {
+		parts = append(parts, fmt.Sprintf("limit.%s=%d", name, n))
+	}
+	for name, f := range s.fields {
+		parts = append(parts, "fields."+name+"="+strings.Join(f, ","))
+	}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strings.Join(parts, ";")))
+	return RedisDashboardShapedPrefix + userID + ":" + hex.EncodeToString(sum[:8])
+}
+
+type dashboardShapeKey struct{}
+
+// withDashboardShape carries a shape through assembleDashboard to the
+// channel fan-out.
+func withDashboardShape(ctx context.Context, shape *dashboardShape) context.Context {
+	return context.WithValue(ctx, dashboardShapeKey{}, shape)
+}
+
+func dashboardShapeFrom(ctx context.Context) *dashboardShape {
+	shape, _ := ctx.Value(dashboardShapeKey{}).(*dashboardShape)
+	return shape
+}
+
+// getShapedDashboard serves GET /dashboard with shaping parameters:
+// assembled with the shape applied, cached per user and shape.
+func getShapedDashboard(c *fiber.Ctx, userID string, shape *dashboardShape) error {
+	start := time.Now()
+	cacheKey := shape.cacheKey(userID)
+	if val, ok := GetCache(context.Background(), cacheKey); ok {
+		dashboardLatency.observe(dashboardSourceCache, time.Since(start))
+		recordDashboardLatency(time.Since(start))
+		recordCacheLookup("dashboard", true)
+		c.Set("X-Cache", "HIT")
+		return sendTickerBody(c, val)
+	}
+
+	userRoles := GetUserRoles(c)
+	ctx := withDashboardShape(c.UserContext(), shape)
+	result, err, _ := dashboardGroup.Do(cacheKey, func() (interface{}, error) {
+		if val, ok := GetCache(context.Background(), cacheKey); ok {
+			return val, nil
+		}
+		cacheData, enabledChannels, _ := assembleDashboard(ctx, userID, userRoles)
+
+		BackgroundPool.SubmitOrRun("sync-subscriptions", func(context.Context) {
+			SyncChannelSubscriptions(userID)
+		})
+		viewed := make(map[string]bool, len(enabledChannels))
+		for name := range enabledChannels {
+			if shape.includes(name) {
+				viewed[name] = true
+			}
+		}
+		BackgroundPool.Submit("record-viewers", func(ctx context.Context) {
+			recordChannelViewers(ctx, userID, viewed)
+		})
+
+		SetCache(context.Background(), cacheKey, cacheData, DashboardPartialCacheTTL)
+		return cacheData, nil
+	})
+	if err != nil {
+		sloDashboardLatency.Record(false, time.Now())
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "dashboard fetch failed"})
+	}
+
+	dashboardLatency.observe(dashboardSourceAssembled, time.Since(start))
+	recordDashboardLatency(time.Since(start))
+	recordCacheLookup("dashboard", false)
+	c.Set("X-Cache", "MISS")
+	return sendTickerBody(c, result.([]byte))
+}
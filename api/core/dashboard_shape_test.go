@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseShapeQuery runs parseDashboardShape against a request with the
+// given query string.
+func parseShapeQuery(t *testing.T, query string) (*dashboardShape, error) {
+	t.Helper()
+	app := fiber.New()
+	var shape *dashboardShape
+	var parseErr error
+	app.Get("/dashboard", func(c *fiber.Ctx) error {
+		shape, parseErr = parseDashboardShape(c)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", "/dashboard?"+query, nil)); err != nil {
+		t.Fatal(err)
+	}
+	return shape, parseErr
+}
+
+func TestParseDashboardShape(t *testing.T) {
+	shape, err := parseShapeQuery(t, "")
+	if err != nil || shape != nil {
+		t.Fatalf("no params: shape = %+v, err = %v; want nil, nil", shape, err)
+	}
+
+	shape, err = parseShapeQuery(t, "channels=finance,rss&limit.rss=10&fields.finance=symbol,price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shape.includes("finance") || !shape.includes("rss") || shape.includes("sports") {
+		t.Errorf("channels = %v, want finance and rss only", shape.channels)
+	}
+	if shape.limits["rss"] != 10 {
+		t.Errorf("limits = %v, want rss=10", shape.limits)
+	}
+	if !reflect.DeepEqual(shape.fields["finance"], []string{"price", "symbol"}) {
+		t.Errorf("fields = %v, want finance=[price symbol]", shape.fields)
+	}
+	if got := shape.hintQuery("finance"); got != "&fields=price%2Csymbol" {
+		t.Errorf("hintQuery(finance) = %q", got)
+	}
+	if got := shape.hintQuery("sports"); got != "" {
+		t.Errorf("hintQuery(sports) = %q, want empty", got)
+	}
+
+	same, _ := parseShapeQuery(t, "fields.finance=price,symbol&limit.rss=10&channels=rss,finance")
+	if shape.cacheKey("u1") != same.cacheKey("u1") {
+		t.Error("equivalent shapes got different cache keys")
+	}
+
+	for _, bad := range []string{"limit.rss=0", "limit.rss=abc", "limit.rss=101", "fields.finance=price;drop", "fields.finance=,"} {
+		if _, err := parseShapeQuery(t, bad); err == nil {
+			t.Errorf("%s: want an error", bad)
+		}
+	}
+}
+
+func TestDashboardShapeApply(t *testing.T) {
+	shape := &dashboardShape{
+		limits: map[string]int{"finance": 1},
+		fields: map[string][]string{"finance": {"price", "symbol"}},
+	}
+	data := map[string]interface{}{
+		"finance": []interface{}{
+			map[string]interface{}{"symbol": "AAPL", "price": 1.0, "volume": 10.0},
+			map[string]interface{}{"symbol": "MSFT", "price": 2.0, "volume": 20.0},
+		},
+		"finance_meta": map[string]interface{}{"market": "open"},
+	}
+	shape.apply("finance", data)
+
+	want := []interface{}{map[string]interface{}{"symbol": "AAPL", "price": 1.0}}
+	if !reflect.DeepEqual(data["finance"], want) {
+		t.Errorf("finance = %v, want %v", data["finance"], want)
+	}
+	if _, ok := data["finance_meta"].(map[string]interface{}); !ok {
+		t.Errorf("non-list value was changed: %v", data["finance_meta"])
+	}
+}
+
+func TestFetchChannelDashboardsShaped(t *testing.T) {
+	var gotQuery string
+	finance := dashboardChannel("finance", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Write([]byte(`{"finance":[{"symbol":"AAPL","price":1,"volume":10},{"symbol":"MSFT","price":2,"volume":20}]}`))
+	}, t)
+	sports := dashboardChannel("sports", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("sports was fetched but not requested")
+	}, t)
+	withDiscoveredChannels(t, finance, sports)
+
+	shape := &dashboardShape{
+		channels: map[string]bool{"finance": true},
+		limits:   map[string]int{"finance": 1},
+	}
+	ctx := withDashboardShape(context.Background(), shape)
+	data, errs := fetchChannelDashboards(ctx, "user-1", map[string]bool{"finance": true, "sports": true})
+	if errs != nil {
+		t.Fatalf("errs = %v", errs)
+	}
+	if gotQuery != "user=user-1&limit=1" {
+		t.Errorf("channel query = %q, want the limit hint passed through", gotQuery)
+	}
+	if list, _ := data["finance"].([]interface{}); len(list) != 1 {
+		t.Errorf("finance = %v, want 1 item", data["finance"])
+	}
+}
@@ -0,0 +1,373 @@
+package core
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Materialized dashboard snapshots.
+//
+// Assembling /dashboard fans out to every enabled channel's
+// /internal/dashboard, which gets expensive for users with many channels
+// who poll every 30s. When an on-demand build shows a user has at least
+// DashboardSnapshotMinChannels enabled, they are enrolled: the built JSON
+// is stored as a snapshot and GET /dashboard serves it straight from
+// Redis from then on.
+//
+// Snapshots are kept fresh by a background worker rather than by request
+// traffic. CDC dispatch for an enrolled user marks them pending; the
+// worker drains pending users every DashboardSnapshotDebounce, so a burst
+// of ticks becomes one rebuild. Active users are also rebuilt at least
+// every DashboardSnapshotRefreshInterval, matching the old cache TTL for
+// users whose channels see no CDC. Users who stop polling for
+// DashboardSnapshotActiveWindow are dropped.
+//
+// Every GET /dashboard records its latency by source (snapshot, cache,
+// assembled), and background rebuilds record theirs, so the two paths can
+// be compared at GET /admin/dashboard/snapshots. Samples are per replica.
+
+// ─── Latency instrumentation ─────────────────────────────────────
+
+const (
+	dashboardSourceSnapshot  = "snapshot"
+	dashboardSourceCache     = "cache"
+	dashboardSourceAssembled = "assembled"
+	dashboardSourceRebuild   = "rebuild"
+)
+
+// LatencySummary describes one source's recent latencies. Percentiles
+// cover the most recent observations (DashboardLatencySamples for
+// /dashboard); Count and MeanMs cover the process lifetime.
+type LatencySummary struct {
+	Count  int64   `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+type latencyWindow struct {
+	count   int64
+	total   time.Duration
+	samples []time.Duration // ring buffer
+	next    int
+}
+
+type latencyStats struct {
+	mu       sync.Mutex
+	size     int // samples kept per source
+	bySource map[string]*latencyWindow
+}
+
+func newLatencyStats(size int) *latencyStats {
+	return &latencyStats{size: size, bySource: make(map[string]*latencyWindow)}
+}
+
+var dashboardLatency = newLatencyStats(DashboardLatencySamples)
+
+func (s *latencyStats) observe(source string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.bySource[source]
+	if w == nil {
+		w = &latencyWindow{samples: make([]time.Duration, 0, s.size)}
+		s.bySource[source] = w
+	}
+	w.count++
+	w.total += d
+	if len(w.samples) < s.size {
+		w.samples = append(w.samples, d)
+	} else {
+		w.samples[w.next] = d
+	}
+	w.next = (w.next + 1) % s.size
+}
+
+func (s *latencyStats) summary() map[string]LatencySummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]LatencySummary, len(s.bySource))
+	for source, w := range s.bySource {
+		sorted := slices.Clone(w.samples)
+		slices.Sort(sorted)
+		out[source] = LatencySummary{
+			Count:  w.count,
+			MeanMs: durationMs(w.total) / float64(max(w.count, 1)),
+			P50Ms:  durationMs(percentile(sorted, 0.50)),
+			P95Ms:  durationMs(percentile(sorted, 0.95)),
+			P99Ms:  durationMs(percentile(sorted, 0.99)),
+		}
+	}
+	return out
+}
+
+// percentile returns the nearest-rank percentile of an ascending slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p*float64(len(sorted)) + 0.5)
+	return sorted[min(max(idx-1, 0), len(sorted)-1)]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ─── Reads and enrollment ────────────────────────────────────────
+
+// loadDashboardSnapshot returns the user's snapshot if they are enrolled,
+// and marks them active in the same round-trip.
+func loadDashboardSnapshot(ctx context.Context, userID string) ([]byte, bool) {
+	if Rdb == nil {
+		return nil, false
+	}
+	pipe := Rdb.Pipeline()
+	get := pipe.Get(ctx, RedisDashboardSnapshotPrefix+userID)
+	pipe.ZAddXX(ctx, RedisDashboardSnapshotActive, redis.Z{
+		Score: float64(time.Now().Unix()), Member: userID,
+	})
+	pipe.Exec(ctx)
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// maybeEnrollDashboardSnapshot stores a freshly assembled dashboard as
+// the user's snapshot when they have enough channels to be worth it.
+func maybeEnrollDashboardSnapshot(ctx context.Context, userID string, enabledCount int, data []byte) {
+	if Rdb == nil || enabledCount < DashboardSnapshotMinChannels {
+		return
+	}
+	if _, err := Rdb.ZScore(ctx, RedisDashboardSnapshotActive, userID).Result(); err == redis.Nil {
+		if n, err := Rdb.ZCard(ctx, RedisDashboardSnapshotActive).Result(); err != nil || n >= DashboardSnapshotMaxUsers {
+			return
+		}
+	}
+	storeDashboardSnapshot(ctx, userID, data, true)
+}
+
+func storeDashboardSnapshot(ctx context.Context, userID string, data []byte, touch bool) {
+	now := float64(time.Now().Unix())
+	pipe := Rdb.TxPipeline()
+	pipe.Set(ctx, RedisDashboardSnapshotPrefix+userID, data, DashboardSnapshotTTL)
+	pipe.ZAdd(ctx, RedisDashboardSnapshotBuilt, redis.Z{Score: now, Member: userID})
+	if touch {
+		pipe.ZAdd(ctx, RedisDashboardSnapshotActive, redis.Z{Score: now, Member: userID})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Snapshot] store for %s failed: %v", userID, err)
+	}
+}
+
+// dropDashboardSnapshot unenrolls a user and deletes their snapshot.
+func dropDashboardSnapshot(ctx context.Context, userID string) {
+	if Rdb == nil {
+		return
+	}
+	pipe := Rdb.TxPipeline()
+	pipe.ZRem(ctx, RedisDashboardSnapshotActive, userID)
+	pipe.ZRem(ctx, RedisDashboardSnapshotBuilt, userID)
+	pipe.Del(ctx, RedisDashboardSnapshotPrefix+userID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Snapshot] drop for %s failed: %v", userID, err)
+	}
+}
+
+// ─── Rebuild worker ──────────────────────────────────────────────
+
+var (
+	snapshotPendingMu sync.Mutex
+	snapshotPending   = make(map[string]struct{})
+)
+
+// scheduleDashboardSnapshot queues a rebuild for the user if they turn
+// out to be enrolled. Called on every CDC dispatch, so it only touches
+// memory; the worker checks enrollment in one batch.
+func scheduleDashboardSnapshot(userID string) {
+	snapshotPendingMu.Lock()
+	snapshotPending[userID] = struct{}{}
+	snapshotPendingMu.Unlock()
+}
+
+func takePendingSnapshots() []string {
+	snapshotPendingMu.Lock()
+	defer snapshotPendingMu.Unlock()
+	if len(snapshotPending) == 0 {
+		return nil
+	}
+	users := make([]string, 0, len(snapshotPending))
+	for userID := range snapshotPending {
+		users = append(users, userID)
+	}
+	clear(snapshotPending)
+	return users
+}
+
+// StartDashboardSnapshotWorker rebuilds snapshots for enrolled users on
+// CDC (debounced) and on a refresh interval, and logs latency summaries.
+func StartDashboardSnapshotWorker(ctx context.Context) {
+	go func() {
+		debounce := time.NewTicker(DashboardSnapshotDebounce)
+		refresh := time.NewTicker(DashboardSnapshotRefreshInterval)
+		report := time.NewTicker(DashboardLatencyLogInterval)
+		defer debounce.Stop()
+		defer refresh.Stop()
+		defer report.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-debounce.C:
+				if users := takePendingSnapshots(); len(users) > 0 {
+					rebuildPendingSnapshots(ctx, users)
+				}
+			case <-refresh.C:
+				refreshActiveSnapshots(ctx)
+			case <-report.C:
+				for source, s := range dashboardLatency.summary() {
+					log.Printf("[Snapshot] latency %s: n=%d mean=%.1fms p50=%.1fms p95=%.1fms p99=%.1fms",
+						source, s.Count, s.MeanMs, s.P50Ms, s.P95Ms, s.P99Ms)
+				}
+			}
+		}
+	}()
+	log.Printf("[Snapshot] Worker started (%s debounce, %s refresh)",
+		DashboardSnapshotDebounce, DashboardSnapshotRefreshInterval)
+}
+
+// rebuildPendingSnapshots rebuilds the enrolled, still-active users
+// among those that saw CDC since the last tick.
+func rebuildPendingSnapshots(ctx context.Context, users []string) {
+	scores, err := Rdb.ZMScore(ctx, RedisDashboardSnapshotActive, users...).Result()
+	if err != nil {
+		log.Printf("[Snapshot] enrollment lookup failed: %v", err)
+		return
+	}
+	cutoff := float64(time.Now().Add(-DashboardSnapshotActiveWindow).Unix())
+	var due []string
+	for i, userID := range users {
+		if scores[i] > cutoff {
+			due = append(due, userID)
+		}
+	}
+	rebuildSnapshots(ctx, due, false)
+}
+
+// refreshActiveSnapshots drops users who stopped polling and rebuilds
+// any active snapshot older than the refresh interval.
+func refreshActiveSnapshots(ctx context.Context) {
+	cutoff := strconv.FormatInt(time.Now().Add(-DashboardSnapshotActiveWindow).Unix(), 10)
+	stale, err := Rdb.ZRangeByScore(ctx, RedisDashboardSnapshotActive, &redis.ZRangeBy{
+		Min: "-inf", Max: "(" + cutoff,
+	}).Result()
+	if err != nil {
+		log.Printf("[Snapshot] list inactive failed: %v", err)
+		return
+	}
+	for _, userID := range stale {
+		dropDashboardSnapshot(ctx, userID)
+	}
+
+	active, err := Rdb.ZRangeByScore(ctx, RedisDashboardSnapshotActive, &redis.ZRangeBy{
+		Min: cutoff, Max: "+inf",
+	}).Result()
+	if err != nil || len(active) == 0 {
+		return
+	}
+	built, err := Rdb.ZMScore(ctx, RedisDashboardSnapshotBuilt, active...).Result()
+	if err != nil {
+		log.Printf("[Snapshot] built lookup failed: %v", err)
+		return
+	}
+	builtBefore := float64(time.Now().Add(-DashboardSnapshotRefreshInterval).Unix())
+	var due []string
+	for i, userID := range active {
+		if built[i] <= builtBefore {
+			due = append(due, userID)
+		}
+	}
+	rebuildSnapshots(ctx, due, true)
+}
+
+// rebuildSnapshots rebuilds users with bounded concurrency. A short
+// per-user lock keeps replicas that all saw the same CDC from rebuilding
+// the same user at once.
+func rebuildSnapshots(ctx context.Context, users []string, periodic bool) {
+	sem := NewSemaphore(DashboardSnapshotConcurrency)
+	var wg sync.WaitGroup
+	for _, userID := range users {
+		ok, err := Rdb.SetNX(ctx, RedisDashboardSnapshotLockPrefix+userID, "1", DashboardSnapshotDebounce).Result()
+		if err != nil || !ok {
+			continue
+		}
+		if sem.Acquire(ctx) != nil {
+			break
+		}
+		wg.Add(1)
+		go func(userID string) {
+			defer func() { sem.Release(); wg.Done() }()
+			rebuildDashboardSnapshot(ctx, userID, periodic)
+		}(userID)
+	}
+	wg.Wait()
+}
+
+// rebuildDashboardSnapshot assembles and stores one user's snapshot.
+// Periodic rebuilds stand in for the cache misses snapshot users no
+// longer have, so they also warm subscription sets and count the user
+// as a viewer.
+func rebuildDashboardSnapshot(ctx context.Context, userID string, periodic bool) {
+	start := time.Now()
+	data, enabledChannels, _ := assembleDashboard(ctx, userID)
+	dashboardLatency.observe(dashboardSourceRebuild, time.Since(start))
+
+	if len(enabledChannels) < DashboardSnapshotMinChannels {
+		dropDashboardSnapshot(ctx, userID)
+		return
+	}
+	storeDashboardSnapshot(ctx, userID, data, false)
+
+	if periodic {
+		BackgroundPool.SubmitOrRun("sync-subscriptions", func(context.Context) {
+			SyncChannelSubscriptions(userID)
+		})
+		BackgroundPool.Submit("record-viewers", func(ctx context.Context) {
+			recordChannelViewers(ctx, userID, enabledChannels)
+		})
+	}
+}
+
+// ─── Admin ───────────────────────────────────────────────────────
+
+// HandleAdminDashboardSnapshots reports how many users are enrolled and
+// this replica's /dashboard latency by source.
+//
+// @Summary Dashboard snapshot stats (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{enrolled=int,latency=map[string]LatencySummary}
+// @Security LogtoAuth
+// @Router /admin/dashboard/snapshots [get]
+func HandleAdminDashboardSnapshots(c *fiber.Ctx) error {
+	enrolled, err := Rdb.ZCard(c.Context(), RedisDashboardSnapshotActive).Result()
+	if err != nil {
+		log.Printf("[Snapshot] enrolled count failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load snapshot stats",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"enrolled": enrolled,
+		"latency":  dashboardLatency.summary(),
+	})
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	if got := percentile(sorted, 0.50); got != 50*time.Millisecond {
+		t.Errorf("p50 = %v, want 50ms", got)
+	}
+	if got := percentile(sorted, 0.99); got != 99*time.Millisecond {
+		t.Errorf("p99 = %v, want 99ms", got)
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("empty percentile = %v, want 0", got)
+	}
+}
+
+func TestLatencyStats_RingBuffer(t *testing.T) {
+	s := newLatencyStats(DashboardLatencySamples)
+	for i := 0; i < DashboardLatencySamples+10; i++ {
+		s.observe("x", time.Millisecond)
+	}
+	w := s.bySource["x"]
+	if len(w.samples) != DashboardLatencySamples {
+		t.Errorf("kept %d samples, want %d", len(w.samples), DashboardLatencySamples)
+	}
+	sum := s.summary()["x"]
+	if sum.Count != int64(DashboardLatencySamples+10) || sum.MeanMs != 1 {
+		t.Errorf("summary = %+v", sum)
+	}
+}
+
+func TestTakePendingSnapshots_Dedupes(t *testing.T) {
+	scheduleDashboardSnapshot("user_a")
+	scheduleDashboardSnapshot("user_a")
+	scheduleDashboardSnapshot("user_b")
+	if got := takePendingSnapshots(); len(got) != 2 {
+		t.Errorf("took %v, want 2 distinct users", got)
+	}
+	if got := takePendingSnapshots(); got != nil {
+		t.Errorf("second take = %v, want nil", got)
+	}
+}
+
+func TestDashboardSnapshot_EnrollAndLoad(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	maybeEnrollDashboardSnapshot(ctx, "light", DashboardSnapshotMinChannels-1, []byte(`{}`))
+	if _, ok := loadDashboardSnapshot(ctx, "light"); ok {
+		t.Error("user below the channel threshold was enrolled")
+	}
+
+	maybeEnrollDashboardSnapshot(ctx, "heavy", DashboardSnapshotMinChannels, []byte(`{"data":{}}`))
+	data, ok := loadDashboardSnapshot(ctx, "heavy")
+	if !ok || string(data) != `{"data":{}}` {
+		t.Fatalf("loadDashboardSnapshot = %q, %v", data, ok)
+	}
+
+	// Channel CRUD drops the snapshot but keeps the user enrolled.
+	InvalidateDashboardCache("heavy")
+	if _, ok := loadDashboardSnapshot(ctx, "heavy"); ok {
+		t.Error("snapshot survived InvalidateDashboardCache")
+	}
+	if _, err := mr.ZScore(RedisDashboardSnapshotActive, "heavy"); err != nil {
+		t.Errorf("user unenrolled by InvalidateDashboardCache: %v", err)
+	}
+
+	dropDashboardSnapshot(ctx, "heavy")
+	if mr.Exists(RedisDashboardSnapshotActive) {
+		t.Error("active set not empty after drop")
+	}
+}
@@ -0,0 +1,643 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Data export (GDPR portability).
+//
+// GET /users/me/export still answers synchronously with the JSON archive,
+// which is what the desktop app and the website call today. Larger or
+// scripted exports use the job flow instead:
+//
+//   - POST /users/me/export?format=json|zip queues a job (202)
+//   - GET  /users/me/export/:id reports its status
+//   - GET  /users/me/export/:id/download serves the finished archive
+//
+// Both paths assemble the same archive (buildUserDataExport). Jobs are
+// rows in data_exports, claimed with SKIP LOCKED so any replica can run
+// them; a job abandoned by a replica that died is picked up again once
+// stale. Archives are deleted after DataExportRetention.
+
+// Export formats.
+const (
+	DataExportJSON = "json"
+	DataExportZip  = "zip"
+)
+
+// Job states.
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExportIdentity is who an export is for. The token claims are
+// captured when the export is requested; the worker has no token.
+type DataExportIdentity struct {
+	Sub      string   `json:"logto_sub"`
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+// DataExportJob is the status of an export job.
+type DataExportJob struct {
+	ID          string     `json:"id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	SizeBytes   *int       `json:"size_bytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+// exportIdentityFromContext reads the identity LogtoAuth parked in
+// c.Locals.
+func exportIdentityFromContext(c *fiber.Ctx) DataExportIdentity {
+	id := buildIdentityFromContext(c)
+	return DataExportIdentity{
+		Sub:      id.Sub,
+		Email:    id.Email,
+		Name:     id.Name,
+		Username: id.Username,
+		Roles:    GetUserRoles(c),
+	}
+}
+
+// ─── Archive ────────────────────────────────────────────────────────
+
+// buildUserDataExport assembles everything we store about a user.
+// Security-sensitive fields (Yahoo OAuth tokens, alert webhook secrets,
+// server-internal Stripe IDs) are left out. A section that fails to load
+// is logged and exported empty rather than failing the whole archive.
+func buildUserDataExport(ctx context.Context, id DataExportIdentity) map[string]any {
+	userID := id.Sub
+	archive := map[string]any{
+		"exported_at": time.Now().UTC().Format(time.RFC3339),
+		"user": map[string]any{
+			"logto_sub": userID,
+			"email":     id.Email,
+			"name":      id.Name,
+			"username":  id.Username,
+			"roles":     id.Roles,
+		},
+		"notes": "Yahoo OAuth tokens and price alert webhook secrets are omitted from this export for security.",
+	}
+
+	// preferences
+	if prefs, err := GetOrCreatePreferences(userID); err == nil {
+		archive["preferences"] = prefs
+	} else {
+		log.Printf("[Export] preferences for %s: %v", userID, err)
+	}
+
+	// channels
+	if chans, err := GetUserChannels(userID); err == nil {
+		archive["channels"] = chans
+	} else {
+		log.Printf("[Export] channels for %s: %v", userID, err)
+		archive["channels"] = []any{}
+	}
+
+	// billing summary (stripe_customers minus server-internal IDs)
+	subscription := map[string]any{}
+	if sc, compUntil, err := loadBillingRow(ctx, userID); err != nil {
+		log.Printf("[Export] subscription for %s: %v", userID, err)
+	} else if sc != nil {
+		subscription["plan"] = sc.Plan
+		subscription["status"] = sc.Status
+		subscription["lifetime"] = sc.Lifetime
+		subscription["customer_since"] = sc.CreatedAt
+		if sc.CurrentPeriodEnd != nil {
+			subscription["current_period_end"] = sc.CurrentPeriodEnd
+		}
+		if sc.GracePeriodEndsAt != nil {
+			subscription["grace_period_ends_at"] = sc.GracePeriodEndsAt
+		}
+		if compUntil != nil {
+			subscription["comp_until"] = compUntil
+		}
+	}
+	archive["subscription"] = subscription
+
+	archive["fantasy_leagues"] = exportFantasyLeagues(ctx, userID)
+	if sleeper := exportSleeperAccount(ctx, userID); sleeper != nil {
+		archive["sleeper_account"] = sleeper
+	}
+	archive["price_alerts"] = exportPriceAlerts(ctx, userID)
+
+	// deletion status, if any
+	if status, _ := getUserDeletionStatus(ctx, userID); status != nil {
+		archive["account_deletion"] = status
+	}
+	return archive
+}
+
+// exportFantasyLeagues lists the user's linked Yahoo and Sleeper leagues
+// (key, name, season; no tokens). Sleeper keys carry the fantasy
+// channel's "sleeper.l." prefix, as on the dashboard.
+func exportFantasyLeagues(ctx context.Context, userID string) []map[string]any {
+	leagues := make([]map[string]any, 0)
+	rows, err := DBPool.Query(ctx, `
+		SELECT yul.league_key, COALESCE(yl.name, '') AS name, COALESCE(yl.season, '') AS season
+		FROM yahoo_user_leagues yul
+		LEFT JOIN yahoo_users yu ON yu.guid = yul.guid
+		LEFT JOIN yahoo_leagues yl ON yl.league_key = yul.league_key
+		WHERE yu.logto_sub = $1
+	`, userID)
+	if err != nil {
+		log.Printf("[Export] fantasy leagues for %s: %v", userID, err)
+		return leagues
+	}
+	for rows.Next() {
+		var key, name, season string
+		if err := rows.Scan(&key, &name, &season); err == nil {
+			leagues = append(leagues, map[string]any{
+				"provider":   "yahoo",
+				"league_key": key,
+				"name":       name,
+				"season":     season,
+			})
+		}
+	}
+	rows.Close()
+
+	rows, err = DBPool.Query(ctx, `
+		SELECT 'sleeper.l.' || sl.league_id, sl.name, sl.season, sl.sport,
+		       COALESCE(sul.team_name, ''), sul.created_at
+		FROM sleeper_user_leagues sul
+		JOIN sleeper_leagues sl ON sl.league_id = sul.league_id
+		WHERE sul.logto_sub = $1
+		ORDER BY sul.created_at
+	`, userID)
+	if err != nil {
+		log.Printf("[Export] sleeper leagues for %s: %v", userID, err)
+		return leagues
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, name, season, sport, teamName string
+		var importedAt *time.Time
+		if err := rows.Scan(&key, &name, &season, &sport, &teamName, &importedAt); err == nil {
+			leagues = append(leagues, map[string]any{
+				"provider":    "sleeper",
+				"league_key":  key,
+				"name":        name,
+				"season":      season,
+				"sport":       sport,
+				"team_name":   teamName,
+				"imported_at": importedAt,
+			})
+		}
+	}
+	return leagues
+}
+
+// exportSleeperAccount returns the user's Sleeper link, or nil when they
+// haven't linked one. Sleeper has no tokens; this is all we store.
+func exportSleeperAccount(ctx context.Context, userID string) map[string]any {
+	var sleeperID, username, displayName string
+	var lastSync, linkedAt *time.Time
+	err := DBPool.QueryRow(ctx, `
+		SELECT user_id, username, display_name, last_sync, created_at
+		FROM sleeper_users WHERE logto_sub = $1
+	`, userID).Scan(&sleeperID, &username, &displayName, &lastSync, &linkedAt)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("[Export] sleeper account for %s: %v", userID, err)
+		}
+		return nil
+	}
+	return map[string]any{
+		"user_id":      sleeperID,
+		"username":     username,
+		"display_name": displayName,
+		"last_sync":    lastSync,
+		"linked_at":    linkedAt,
+	}
+}
+
+// exportPriceAlerts lists the user's finance price alerts. The table is
+// owned by the finance channel; core already reads it for the purge.
+func exportPriceAlerts(ctx context.Context, userID string) []map[string]any {
+	alerts := make([]map[string]any, 0)
+	rows, err := DBPool.Query(ctx, `
+		SELECT id, symbol, condition, threshold::FLOAT8, webhook_url, armed,
+		       trigger_count, last_triggered_at, created_at
+		FROM price_alerts WHERE logto_sub = $1 ORDER BY id
+	`, userID)
+	if err != nil {
+		log.Printf("[Export] price alerts for %s: %v", userID, err)
+		return alerts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id                int64
+			symbol, condition string
+			threshold         float64
+			webhookURL        *string
+			armed             bool
+			triggerCount      int
+			lastTriggeredAt   *time.Time
+			createdAt         time.Time
+		)
+		if err := rows.Scan(&id, &symbol, &condition, &threshold, &webhookURL, &armed,
+			&triggerCount, &lastTriggeredAt, &createdAt); err != nil {
+			continue
+		}
+		alerts = append(alerts, map[string]any{
+			"id":                id,
+			"symbol":            symbol,
+			"condition":         condition,
+			"threshold":         threshold,
+			"webhook_url":       webhookURL,
+			"armed":             armed,
+			"trigger_count":     triggerCount,
+			"last_triggered_at": lastTriggeredAt,
+			"created_at":        createdAt,
+		})
+	}
+	return alerts
+}
+
+// encodeDataExport serialises an archive. A zip holds one JSON file per
+// section plus manifest.json with the export time and notes.
+func encodeDataExport(archive map[string]any, format string) ([]byte, error) {
+	if format != DataExportZip {
+		return json.MarshalIndent(archive, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	manifest := map[string]any{}
+	sections := make([]string, 0, len(archive))
+	for key := range archive {
+		sections = append(sections, key)
+	}
+	sort.Strings(sections)
+	files := make([]string, 0, len(sections))
+	for _, key := range sections {
+		switch key {
+		case "exported_at", "notes":
+			manifest[key] = archive[key]
+		default:
+			if err := add(key+".json", archive[key]); err != nil {
+				return nil, err
+			}
+			files = append(files, key+".json")
+		}
+	}
+	manifest["files"] = files
+	if err := add("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dataExportFilename is the download name of an archive.
+func dataExportFilename(format string, at time.Time) string {
+	ext := "json"
+	if format == DataExportZip {
+		ext = "zip"
+	}
+	return fmt.Sprintf("myscrollr-export-%s.%s", at.UTC().Format("2006-01-02"), ext)
+}
+
+// ─── Handlers ───────────────────────────────────────────────────────
+
+// HandleCreateDataExport queues an export job. A job already queued or
+// running for the user is returned instead of starting another.
+//
+// @Summary Request a data export
+// @Description Queues an asynchronous export of the user's data as JSON or a zip of JSON files
+// @Tags Users
+// @Produce json
+// @Param format query string false "json (default) or zip"
+// @Success 202 {object} DataExportJob
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /users/me/export [post]
+func HandleCreateDataExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	format := c.Query("format", DataExportJSON)
+	if format != DataExportJSON && format != DataExportZip {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "format must be json or zip",
+		})
+	}
+
+	ctx := context.Background()
+	if job, err := getActiveDataExport(ctx, userID); err == nil {
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Export] active job lookup for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to start export",
+		})
+	}
+
+	var recent int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT count(*) FROM data_exports WHERE logto_sub = $1 AND created_at > now() - interval '1 day'`,
+		userID,
+	).Scan(&recent); err != nil {
+		log.Printf("[Export] count for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to start export",
+		})
+	}
+	if recent >= DataExportDailyLimit {
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("At most %d exports per day", DataExportDailyLimit),
+		})
+	}
+
+	identity, _ := json.Marshal(exportIdentityFromContext(c))
+	job := DataExportJob{ID: uuid.NewString(), Format: format, Status: DataExportPending}
+	err := DBPool.QueryRow(ctx, `
+		INSERT INTO data_exports (id, logto_sub, format, identity, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, expires_at`,
+		job.ID, userID, format, identity, time.Now().Add(DataExportRetention),
+	).Scan(&job.CreatedAt, &job.ExpiresAt)
+	if err != nil {
+		log.Printf("[Export] insert for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to start export",
+		})
+	}
+
+	// Start now rather than on the next worker tick.
+	go processDataExports(context.Background())
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// HandleGetDataExport reports an export job's status.
+//
+// @Summary Data export status
+// @Tags Users
+// @Produce json
+// @Param id path string true "Export job ID"
+// @Success 200 {object} DataExportJob
+// @Failure 404 {object} ErrorResponse
+// @Router /users/me/export/{id} [get]
+func HandleGetDataExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	job, err := getDataExport(context.Background(), userID, c.Params("id"))
+	if err != nil {
+		return dataExportLookupError(c, err)
+	}
+	return c.JSON(job)
+}
+
+// HandleDownloadDataExport serves a finished archive as an attachment.
+//
+// @Summary Download a data export
+// @Tags Users
+// @Produce json
+// @Produce application/zip
+// @Param id path string true "Export job ID"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} DataExportJob
+// @Router /users/me/export/{id}/download [get]
+func HandleDownloadDataExport(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := context.Background()
+	job, err := getDataExport(ctx, userID, c.Params("id"))
+	if err != nil {
+		return dataExportLookupError(c, err)
+	}
+	if job.Status != DataExportReady {
+		return c.Status(fiber.StatusConflict).JSON(job)
+	}
+
+	var archive []byte
+	if err := DBPool.QueryRow(ctx,
+		`SELECT archive FROM data_exports WHERE id = $1`, job.ID,
+	).Scan(&archive); err != nil {
+		log.Printf("[Export] archive read for %s: %v", job.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to read export",
+		})
+	}
+
+	contentType := "application/json"
+	if job.Format == DataExportZip {
+		contentType = "application/zip"
+	}
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", dataExportFilename(job.Format, *job.CompletedAt)))
+	c.Set("Cache-Control", "no-store")
+	return c.Send(archive)
+}
+
+// dataExportLookupError maps a getDataExport failure to a response.
+func dataExportLookupError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Export not found",
+		})
+	}
+	log.Printf("[Export] lookup failed: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+		Status: "error",
+		Error:  "Failed to read export",
+	})
+}
+
+const dataExportColumns = `id::text, format, status, size_bytes, error, created_at, completed_at, expires_at`
+
+func scanDataExport(row pgx.Row) (DataExportJob, error) {
+	var j DataExportJob
+	err := row.Scan(&j.ID, &j.Format, &j.Status, &j.SizeBytes, &j.Error, &j.CreatedAt, &j.CompletedAt, &j.ExpiresAt)
+	if err == nil && j.Status == DataExportReady {
+		j.DownloadURL = "/users/me/export/" + j.ID + "/download"
+	}
+	return j, err
+}
+
+// getDataExport reads one of the user's unexpired jobs. Another user's
+// job, a malformed ID and an expired job all read as pgx.ErrNoRows.
+func getDataExport(ctx context.Context, userID, id string) (DataExportJob, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return DataExportJob{}, pgx.ErrNoRows
+	}
+	return scanDataExport(DBPool.QueryRow(ctx,
+		`SELECT `+dataExportColumns+` FROM data_exports
+		 WHERE id = $1 AND logto_sub = $2 AND expires_at > now()`, id, userID))
+}
+
+// getActiveDataExport reads the user's queued or running job, if any.
+func getActiveDataExport(ctx context.Context, userID string) (DataExportJob, error) {
+	return scanDataExport(DBPool.QueryRow(ctx,
+		`SELECT `+dataExportColumns+` FROM data_exports
+		 WHERE logto_sub = $1 AND status IN ('pending', 'running')
+		 ORDER BY created_at DESC LIMIT 1`, userID))
+}
+
+// ─── Worker ─────────────────────────────────────────────────────────
+
+// StartDataExportWorker runs queued export jobs and deletes expired
+// archives every DataExportWorkerInterval.
+func StartDataExportWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(DataExportWorkerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pruneDataExports(ctx)
+				processDataExports(ctx)
+			}
+		}
+	}()
+}
+
+// processDataExports runs claimable jobs until none are left.
+func processDataExports(ctx context.Context) {
+	for ctx.Err() == nil {
+		var (
+			id, userID, format string
+			identityRaw        []byte
+			attempts           int
+		)
+		err := DBPool.QueryRow(ctx, `
+			UPDATE data_exports
+			SET status = 'running', started_at = now(), attempts = attempts + 1
+			WHERE id = (
+				SELECT id FROM data_exports
+				WHERE (status = 'pending' OR (status = 'running' AND started_at < $1))
+				  AND attempts < $2
+				ORDER BY created_at
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id::text, logto_sub, format, identity, attempts`,
+			time.Now().Add(-DataExportStaleAfter), DataExportMaxAttempts,
+		).Scan(&id, &userID, &format, &identityRaw, &attempts)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			log.Printf("[Export] claim failed: %v", err)
+			return
+		}
+		runDataExport(ctx, id, userID, format, identityRaw, attempts)
+	}
+}
+
+// runDataExport builds and stores one claimed job. A failed attempt is
+// requeued until DataExportMaxAttempts.
+func runDataExport(ctx context.Context, id, userID, format string, identityRaw []byte, attempts int) {
+	identity := DataExportIdentity{Sub: userID}
+	_ = json.Unmarshal(identityRaw, &identity)
+	identity.Sub = userID
+
+	buildCtx, cancel := context.WithTimeout(ctx, DataExportBuildTimeout)
+	defer cancel()
+
+	data, err := encodeDataExport(buildUserDataExport(buildCtx, identity), format)
+	if err == nil {
+		_, err = DBPool.Exec(ctx, `
+			UPDATE data_exports
+			SET status = 'ready', archive = $2, size_bytes = $3, error = NULL, completed_at = now()
+			WHERE id = $1`, id, data, len(data))
+		if err == nil {
+			log.Printf("[Export] %s ready for %s (%s, %d bytes)", id, userID, format, len(data))
+			return
+		}
+	}
+
+	log.Printf("[Export] %s failed for %s (attempt %d): %v", id, userID, attempts, err)
+	status := DataExportPending
+	if attempts >= DataExportMaxAttempts {
+		status = DataExportFailed
+	}
+	if _, uerr := DBPool.Exec(ctx,
+		`UPDATE data_exports SET status = $2, error = $3 WHERE id = $1`,
+		id, status, "export failed, please try again",
+	); uerr != nil {
+		log.Printf("[Export] %s status update failed: %v", id, uerr)
+	}
+}
+
+// pruneDataExports deletes expired jobs and fails jobs whose last
+// attempt went stale.
+func pruneDataExports(ctx context.Context) {
+	if _, err := DBPool.Exec(ctx, `DELETE FROM data_exports WHERE expires_at <= now()`); err != nil {
+		log.Printf("[Export] prune failed: %v", err)
+	}
+	if _, err := DBPool.Exec(ctx, `
+		UPDATE data_exports SET status = 'failed', error = 'export timed out, please try again'
+		WHERE status = 'running' AND started_at < $1 AND attempts >= $2`,
+		time.Now().Add(-DataExportStaleAfter), DataExportMaxAttempts,
+	); err != nil {
+		log.Printf("[Export] stale sweep failed: %v", err)
+	}
+}
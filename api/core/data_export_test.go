@@ -0,0 +1,120 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEncodeDataExportJSON(t *testing.T) {
+	archive := map[string]any{
+		"exported_at": "2026-10-16T00:00:00Z",
+		"channels":    []any{},
+	}
+	data, err := encodeDataExport(archive, DataExportJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if got["exported_at"] != "2026-10-16T00:00:00Z" {
+		t.Errorf("exported_at = %v", got["exported_at"])
+	}
+}
+
+func TestEncodeDataExportZip(t *testing.T) {
+	archive := map[string]any{
+		"exported_at":  "2026-10-16T00:00:00Z",
+		"notes":        "tokens omitted",
+		"user":         map[string]any{"logto_sub": "u1"},
+		"price_alerts": []map[string]any{{"symbol": "AAPL"}},
+	}
+	data, err := encodeDataExport(archive, DataExportZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = b
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"manifest.json", "price_alerts.json", "user.json"}
+	if len(names) != len(want) {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("files = %v, want %v", names, want)
+		}
+	}
+
+	var manifest struct {
+		ExportedAt string   `json:"exported_at"`
+		Notes      string   `json:"notes"`
+		Files      []string `json:"files"`
+	}
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.ExportedAt != "2026-10-16T00:00:00Z" || manifest.Notes != "tokens omitted" || len(manifest.Files) != 2 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+func TestDataExportFilename(t *testing.T) {
+	at := time.Date(2026, 10, 16, 23, 30, 0, 0, time.FixedZone("X", -5*3600))
+	if got := dataExportFilename(DataExportJSON, at); got != "myscrollr-export-2026-10-17.json" {
+		t.Errorf("json filename = %q", got)
+	}
+	if got := dataExportFilename(DataExportZip, at); got != "myscrollr-export-2026-10-17.zip" {
+		t.Errorf("zip filename = %q", got)
+	}
+}
+
+func TestExportFantasyIncludesSleeper(t *testing.T) {
+	if !testDBAvailable(t) {
+		return
+	}
+	userID := makeTestUser()
+	ctx := context.Background()
+	defer func() {
+		DBPool.Exec(ctx, `DELETE FROM sleeper_users WHERE logto_sub = $1`, userID)
+		DBPool.Exec(ctx, `DELETE FROM sleeper_leagues WHERE league_id = 'export-test'`)
+	}()
+	mustExec(t, `INSERT INTO sleeper_users (logto_sub, user_id, username, display_name) VALUES ($1, 'u9', 'sam', 'Sam')`, userID)
+	mustExec(t, `INSERT INTO sleeper_leagues (league_id, name, sport, season, data) VALUES ('export-test', 'Dynasty', 'nfl', '2026', '{}')`)
+	mustExec(t, `INSERT INTO sleeper_user_leagues (logto_sub, league_id, roster_id, team_name) VALUES ($1, 'export-test', 3, 'Sam''s Squad')`, userID)
+
+	leagues := exportFantasyLeagues(ctx, userID)
+	if len(leagues) != 1 || leagues[0]["provider"] != "sleeper" || leagues[0]["league_key"] != "sleeper.l.export-test" || leagues[0]["team_name"] != "Sam's Squad" {
+		t.Errorf("fantasy_leagues = %v, want the Sleeper league", leagues)
+	}
+	if acct := exportSleeperAccount(ctx, userID); acct == nil || acct["username"] != "sam" {
+		t.Errorf("sleeper_account = %v, want username sam", acct)
+	}
+	if acct := exportSleeperAccount(ctx, userID+"-none"); acct != nil {
+		t.Errorf("sleeper_account for an unlinked user = %v, want nil", acct)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -16,35 +18,38 @@ import (
 // DBPool is the global PostgreSQL connection pool.
 var DBPool *pgxpool.Pool
 
+// ReplicaPool is the optional read replica (DATABASE_REPLICA_URL); nil
+// when none is configured. Query through ReadPool, not directly.
+var ReplicaPool *pgxpool.Pool
+
+// replicaHealthy is maintained by StartReplicaMonitor.
+var replicaHealthy atomic.Bool
+
+// ReadPool returns the pool for read-only queries that tolerate a few
+// seconds of replication lag: the replica while it's reachable and
+// caught up, the primary otherwise. Anything that reads back what the
+// caller just wrote stays on DBPool.
+func ReadPool() *pgxpool.Pool {
+	if ReplicaPool != nil && replicaHealthy.Load() {
+		return ReplicaPool
+	}
+	return DBPool
+}
+
 // ConnectDB initialises the PostgreSQL connection pool and runs migrations.
 func ConnectDB() {
-	databaseURL := os.Getenv("DATABASE_URL")
+	databaseURL := Secret("DATABASE_URL")
 	if databaseURL == "" {
 		log.Fatal("DATABASE_URL must be set")
 	}
 
-	databaseURL = strings.TrimSpace(databaseURL)
-	databaseURL = strings.Trim(databaseURL, "\"")
-	databaseURL = strings.Trim(databaseURL, "'")
-
-	if strings.HasPrefix(databaseURL, "postgres:") && !strings.HasPrefix(databaseURL, "postgres://") {
-		databaseURL = strings.Replace(databaseURL, "postgres:", "postgres://", 1)
-	} else if strings.HasPrefix(databaseURL, "postgresql:") && !strings.HasPrefix(databaseURL, "postgresql://") {
-		databaseURL = strings.Replace(databaseURL, "postgresql:", "postgresql://", 1)
-	}
+	databaseURL = normalizeDatabaseURL(databaseURL)
 
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		log.Fatalf("Unable to parse DATABASE_URL (redacted)")
 	}
-
-	config.MaxConns = DBMaxConns
-	config.MinConns = DBMinConns
-	config.MaxConnIdleTime = DBMaxConnIdleTime
-	// Cap individual connection attempts at 5 seconds. Without this, a
-	// transient Postgres blip lets requests pile up behind indefinitely
-	// pending connection dials — the default is effectively unbounded.
-	config.ConnConfig.ConnectTimeout = 5 * time.Second
+	configurePool(config)
 
 	var pool *pgxpool.Pool
 	retries := DBMaxRetries
@@ -66,7 +71,9 @@ func ConnectDB() {
 	}
 
 	DBPool = pool
-	log.Println("[Database] Connected to PostgreSQL")
+	log.Printf("[Database] Connected to PostgreSQL (max %d conns)", config.MaxConns)
+
+	connectReplica()
 
 	// golang-migrate uses pq driver which requires sslmode to be explicit.
 	// Use a dedicated migrations table so core and channel APIs (e.g. fantasy)
@@ -105,6 +112,145 @@ func ConnectDB() {
 	pruneWebhookEvents(context.Background())
 }
 
+// normalizeDatabaseURL strips quoting left by some secret stores and
+// fixes "postgres:" URLs missing their slashes.
+func normalizeDatabaseURL(databaseURL string) string {
+	databaseURL = strings.TrimSpace(databaseURL)
+	databaseURL = strings.Trim(databaseURL, "\"")
+	databaseURL = strings.Trim(databaseURL, "'")
+
+	if strings.HasPrefix(databaseURL, "postgres:") && !strings.HasPrefix(databaseURL, "postgres://") {
+		databaseURL = strings.Replace(databaseURL, "postgres:", "postgres://", 1)
+	} else if strings.HasPrefix(databaseURL, "postgresql:") && !strings.HasPrefix(databaseURL, "postgresql://") {
+		databaseURL = strings.Replace(databaseURL, "postgresql:", "postgresql://", 1)
+	}
+	return databaseURL
+}
+
+// configurePool applies the pool sizing: the DB* defaults, overridden by
+// DB_MAX_CONNS, DB_MIN_CONNS, DB_MAX_CONN_IDLE_TIME and
+// DB_MAX_CONN_LIFETIME (Go durations, e.g. "15m").
+func configurePool(config *pgxpool.Config) {
+	config.MaxConns = int32(envPositiveInt("DB_MAX_CONNS", DBMaxConns))
+	config.MinConns = int32(envPositiveInt("DB_MIN_CONNS", DBMinConns))
+	if config.MinConns > config.MaxConns {
+		config.MinConns = config.MaxConns
+	}
+	config.MaxConnIdleTime = envPositiveDuration("DB_MAX_CONN_IDLE_TIME", DBMaxConnIdleTime)
+	config.MaxConnLifetime = envPositiveDuration("DB_MAX_CONN_LIFETIME", DBMaxConnLifetime)
+	// Cap individual connection attempts at 5 seconds. Without this, a
+	// transient Postgres blip lets requests pile up behind indefinitely
+	// pending connection dials — the default is effectively unbounded.
+	config.ConnConfig.ConnectTimeout = 5 * time.Second
+}
+
+// envPositiveInt reads a positive integer, falling back (with a log line
+// for a bad value) to def.
+func envPositiveInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("[Database] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return n
+}
+
+// envPositiveDuration is envPositiveInt for durations.
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("[Database] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return d
+}
+
+// connectReplica opens the DATABASE_REPLICA_URL pool, sized like the
+// primary. A replica that can't be reached is not fatal: reads stay on
+// the primary until StartReplicaMonitor sees it come up.
+func connectReplica() {
+	replicaURL := Secret("DATABASE_REPLICA_URL")
+	if replicaURL == "" {
+		return
+	}
+	config, err := pgxpool.ParseConfig(normalizeDatabaseURL(replicaURL))
+	if err != nil {
+		log.Printf("[Database] Unable to parse DATABASE_REPLICA_URL (redacted); reads stay on the primary")
+		return
+	}
+	configurePool(config)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		log.Printf("[Database] Replica pool failed: %v; reads stay on the primary", err)
+		return
+	}
+	ReplicaPool = pool
+	if checkReplica(context.Background()) {
+		log.Println("[Database] Connected to read replica")
+	} else {
+		log.Println("[Database] Read replica not ready; reads stay on the primary")
+	}
+}
+
+// checkReplica pings the replica and checks its replay lag, updating
+// replicaHealthy. A replica that has replayed everything it received
+// counts as caught up even when the primary has been idle.
+func checkReplica(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, ReplicaCheckTimeout)
+	defer cancel()
+
+	var lagSeconds float64
+	err := ReplicaPool.QueryRow(ctx, `
+		SELECT CASE
+		         WHEN NOT pg_is_in_recovery() THEN 0
+		         WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		         ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		       END`).Scan(&lagSeconds)
+	healthy := err == nil && time.Duration(lagSeconds*float64(time.Second)) <= ReplicaMaxLag
+
+	if was := replicaHealthy.Swap(healthy); was != healthy {
+		switch {
+		case healthy:
+			log.Println("[Database] Read replica healthy; routing reads to it")
+		case err != nil:
+			log.Printf("[Database] Read replica unreachable, reads fall back to the primary: %v", err)
+		default:
+			log.Printf("[Database] Read replica %.0fs behind, reads fall back to the primary", lagSeconds)
+		}
+	}
+	return healthy
+}
+
+// StartReplicaMonitor re-checks the replica every ReplicaCheckInterval so
+// ReadPool follows it going down, falling behind and recovering. A no-op
+// without DATABASE_REPLICA_URL.
+func StartReplicaMonitor(ctx context.Context) {
+	if ReplicaPool == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(ReplicaCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkReplica(ctx)
+			}
+		}
+	}()
+}
+
 // pruneWebhookEvents deletes Stripe webhook event rows older than 7 days.
 // Stripe re-delivers events for up to ~3 days on failure, so 7 days is
 // a generous idempotency window that still keeps the table bounded.
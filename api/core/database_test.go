@@ -0,0 +1,54 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestConfigurePoolEnv(t *testing.T) {
+	parse := func() *pgxpool.Config {
+		config, err := pgxpool.ParseConfig("postgres://u:p@localhost:5432/db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		configurePool(config)
+		return config
+	}
+
+	config := parse()
+	if config.MaxConns != DBMaxConns || config.MinConns != DBMinConns ||
+		config.MaxConnIdleTime != DBMaxConnIdleTime || config.MaxConnLifetime != DBMaxConnLifetime {
+		t.Errorf("defaults not applied: max=%d min=%d idle=%s life=%s",
+			config.MaxConns, config.MinConns, config.MaxConnIdleTime, config.MaxConnLifetime)
+	}
+
+	t.Setenv("DB_MAX_CONNS", "4")
+	t.Setenv("DB_MIN_CONNS", "8")
+	t.Setenv("DB_MAX_CONN_IDLE_TIME", "90s")
+	t.Setenv("DB_MAX_CONN_LIFETIME", "-5m")
+	config = parse()
+	if config.MaxConns != 4 || config.MinConns != 4 {
+		t.Errorf("max/min = %d/%d, want 4/4 (min capped at max)", config.MaxConns, config.MinConns)
+	}
+	if config.MaxConnIdleTime != 90*time.Second {
+		t.Errorf("idle = %s, want 90s", config.MaxConnIdleTime)
+	}
+	if config.MaxConnLifetime != DBMaxConnLifetime {
+		t.Errorf("invalid lifetime not ignored: %s", config.MaxConnLifetime)
+	}
+}
+
+func TestNormalizeDatabaseURL(t *testing.T) {
+	cases := map[string]string{
+		` "postgres://u@h/db" `: "postgres://u@h/db",
+		"postgres:u@h/db":       "postgres://u@h/db",
+		"'postgresql:u@h/db'":   "postgresql://u@h/db",
+	}
+	for in, want := range cases {
+		if got := normalizeDatabaseURL(in); got != want {
+			t.Errorf("normalizeDatabaseURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -63,7 +63,7 @@ type DiscordConfig struct {
 // (zero-value, false). Callers should treat false as "Discord disabled".
 func loadDiscordConfig() (DiscordConfig, bool) {
 	cfg := DiscordConfig{
-		BotToken:         os.Getenv("DISCORD_BOT_TOKEN"),
+		BotToken:         Secret("DISCORD_BOT_TOKEN"),
 		PublicKey:        os.Getenv("DISCORD_PUBLIC_KEY"),
 		ApplicationID:    os.Getenv("DISCORD_APPLICATION_ID"),
 		GuildID:          os.Getenv("DISCORD_GUILD_ID"),
@@ -131,7 +131,7 @@ func shouldNotifyDiscord() bool {
 // =============================================================================
 
 // discordHTTPClient is shared across requests.
-var discordHTTPClient = &http.Client{Timeout: discordHTTPTimeout}
+var discordHTTPClient = NewHTTPClient("discord", discordHTTPTimeout)
 
 // discordRequest performs an authenticated REST call to Discord.
 // Returns the response body bytes and HTTP status code.
@@ -15,6 +15,12 @@ type ChannelRoute struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 	Auth   bool   `json:"auth"`
+	// APIKey routes authenticate with an X-API-Key header instead of a
+	// Logto JWT. Mutually exclusive with Auth; Auth wins if both are set.
+	APIKey bool `json:"api_key,omitempty"`
+	// CacheTTL (seconds) opts a public GET route into the gateway response
+	// cache. Ignored on Auth and APIKey routes.
+	CacheTTL int `json:"cache_ttl,omitempty"`
 }
 
 // ChannelInfo describes a discovered channel from Redis.
@@ -25,6 +31,21 @@ type ChannelInfo struct {
 	Capabilities []string       `json:"capabilities"`
 	CDCTables    []string       `json:"cdc_tables"`
 	Routes       []ChannelRoute `json:"routes"`
+	// StartedAt (Unix ms) changes on every channel process start, letting
+	// discovery notice restarts that are quicker than the refresh interval.
+	StartedAt int64 `json:"started_at,omitempty"`
+	// Version is the channel's build (GIT_SHA), shown by /channels/status.
+	Version string `json:"version,omitempty"`
+	// DashboardTimeoutMs lets a slow dashboard provider ask for more than
+	// DashboardChannelTimeout during the /dashboard fan-out.
+	DashboardTimeoutMs int `json:"dashboard_timeout_ms,omitempty"`
+	// ConfigSchema is a JSON Schema that user_channels.config must match
+	// on write (config_schema.go). Optional.
+	ConfigSchema json.RawMessage `json:"config_schema,omitempty"`
+
+	// configSchema is ConfigSchema compiled at discovery; nil when the
+	// channel publishes none or it failed to compile.
+	configSchema *configSchema
 }
 
 // Discovery manages runtime channel discovery via Redis.
@@ -71,6 +92,7 @@ func (d *Discovery) refresh() {
 	var cursor uint64
 	channels := make(map[string]*ChannelInfo)
 	tableIndex := make(map[string]string)
+	schemaErrs := make(map[string]error)
 
 	for {
 		keys, nextCursor, err := Rdb.Scan(ctx, cursor, "channel:*", 100).Result()
@@ -89,6 +111,11 @@ func (d *Discovery) refresh() {
 				log.Printf("[Discovery] Failed to parse channel %s: %v", key, err)
 				continue
 			}
+			if len(info.ConfigSchema) > 0 {
+				if info.configSchema, err = compileConfigSchema(info.ConfigSchema); err != nil {
+					schemaErrs[info.Name] = err
+				}
+			}
 			channels[info.Name] = &info
 			for _, table := range info.CDCTables {
 				tableIndex[table] = info.Name
@@ -111,6 +138,16 @@ func (d *Discovery) refresh() {
 
 	d.mu.Lock()
 	changed := summary != d.lastSummary
+	var started, restarted []string
+	for name, info := range channels {
+		prev, ok := d.channels[name]
+		if !ok || prev.StartedAt != info.StartedAt {
+			started = append(started, name)
+		}
+		if ok && prev.StartedAt != info.StartedAt {
+			restarted = append(restarted, name)
+		}
+	}
 	d.channels = channels
 	d.tableIndex = tableIndex
 	d.lastSummary = summary
@@ -119,6 +156,38 @@ func (d *Discovery) refresh() {
 	if changed {
 		log.Printf("[Discovery] Channels updated: %d active [%s]", len(channels), summary)
 	}
+
+	// A channel that was registered last scan and isn't now has crashed
+	// or been scaled away without re-registering (channel_status.go).
+	for _, name := range channelStatuses.observe(channels, time.Now()) {
+		reportChannelExpired(name)
+	}
+
+	// An unusable schema leaves the channel's config unvalidated; say so
+	// once per registration rather than every refresh.
+	for _, name := range started {
+		if err := schemaErrs[name]; err != nil {
+			log.Printf("[Discovery] Ignoring config schema for %s: %v", name, err)
+		}
+	}
+
+	// A channel that just (re)registered may have missed lifecycle events
+	// while it was down; replay its queue now rather than on the backoff.
+	for _, name := range started {
+		if channels[name].HasCapability("channel_lifecycle") {
+			BackgroundPool.Submit("lifecycle-replay", func(ctx context.Context) {
+				replayLifecycleRetriesNow(ctx, name)
+			})
+		}
+	}
+
+	// A restarted channel may have been redeployed with a new response
+	// shape; don't keep serving its old responses from the gateway cache.
+	for _, name := range restarted {
+		if err := InvalidateProxyCache(ctx, name); err != nil {
+			log.Printf("[Discovery] Failed to invalidate proxy cache for %s: %v", name, err)
+		}
+	}
 }
 
 // GetAllChannels returns a snapshot of all discovered channels.
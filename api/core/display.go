@@ -0,0 +1,609 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/singleflight"
+)
+
+// Kiosk / TV display mode.
+//
+// A display token is a long-lived, revocable secret that lets a
+// wall-mounted screen run the owner's ticker without a signed-in browser
+// profile. It only unlocks two read-only routes:
+//
+//	GET /display/:token/ticker  — snapshot for the first frame
+//	GET /display/:token/events  — SSE stream of live updates
+//
+// There's no refresh dance: the token never expires, EventSource
+// reconnects with the same URL, and the stream re-resolves the token
+// every DisplayTokenRecheckInterval so a revoke or a downgrade ends it
+// with a display-revoked event (EventSource then stops retrying on the
+// 401/403). The stream carries no user interaction semantics: no client
+// version gate, and events about the owner's account (billing) or their
+// channel configs are filtered out because the screen is in a shared
+// space.
+//
+// Entitlement follows /events: Uplink Ultimate or super_user, checked
+// from JWT roles when the token is minted and from the owner's synced
+// user_preferences.subscription_tier when it's used.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// DisplayToken is the listing view of a display token. The plaintext is
+// only ever returned once, from HandleCreateDisplayToken.
+type DisplayToken struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// DisplayTicker is the GET /display/:token/ticker body: the owner's
+// ticker channels and their data, with nothing a shared screen
+// shouldn't show or can't act on.
+type DisplayTicker struct {
+	Channels      []Channel              `json:"channels"`
+	Data          map[string]interface{} `json:"data"`
+	Organizations []OrgDashboard         `json:"organizations,omitempty"`
+	Incidents     []Incident             `json:"incidents,omitempty"`
+	FeedMode      string                 `json:"feed_mode,omitempty"`
+	FeedPosition  string                 `json:"feed_position,omitempty"`
+	GeneratedAt   int64                  `json:"generated_at"`
+}
+
+var (
+	// errInvalidDisplayToken is returned for unknown or revoked tokens.
+	errInvalidDisplayToken = errors.New("invalid display token")
+	// errDisplayNotEntitled is returned when the owner's plan no longer
+	// includes real-time updates.
+	errDisplayNotEntitled = errors.New("display mode requires Uplink Ultimate")
+)
+
+// displayPrivateTables are CDC tables about the owner's account rather
+// than their ticker, or whose rows carry channel configs, which can hold
+// credentials (a Discord webhook URL). Their events never reach a display.
+var displayPrivateTables = map[string]bool{
+	"stripe_customers": true,
+	"notifications":    true,
+	"user_channels":    true,
+}
+
+var displayTickerGroup singleflight.Group
+
+// ─── Tokens ──────────────────────────────────────────────────────
+
+// generateDisplayToken returns a new plaintext token of the form
+// "scrollr_display_<43 url-safe chars>" (32 random bytes).
+func generateDisplayToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return DisplayTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// displayTokenDisplayPrefix is the non-secret stub shown in the dashboard.
+func displayTokenDisplayPrefix(token string) string {
+	n := len(DisplayTokenPrefix) + 4
+	if len(token) < n {
+		return token
+	}
+	return token[:n]
+}
+
+// displayTierAllowed reports whether a tier may run a display.
+func displayTierAllowed(tier string) bool {
+	return tier == "uplink_ultimate" || tier == "super_user"
+}
+
+// resolveDisplayToken maps a plaintext token to its owner's logto_sub.
+// Like resolveAPIKey it caches hits in Redis, here for
+// DisplayTokenCacheTTL; only entitled owners are cached, so a lapsed plan
+// is re-checked on every use until it's renewed. Hashing reuses
+// hashAPIKey — the tokens have the same entropy.
+func resolveDisplayToken(ctx context.Context, token string) (string, error) {
+	if !strings.HasPrefix(token, DisplayTokenPrefix) {
+		return "", errInvalidDisplayToken
+	}
+	hash := hashAPIKey(token)
+	cacheKey := RedisDisplayTokenPrefix + hash
+
+	if Rdb != nil {
+		if sub, err := Rdb.Get(ctx, cacheKey).Result(); err == nil && sub != "" {
+			return sub, nil
+		}
+	}
+
+	var id int64
+	var sub, tier string
+	err := DBPool.QueryRow(ctx, `
+		SELECT d.id, d.logto_sub, COALESCE(p.subscription_tier, 'free')
+		FROM display_tokens d
+		LEFT JOIN user_preferences p ON p.logto_sub = d.logto_sub
+		WHERE d.token_hash = $1 AND d.revoked_at IS NULL
+	`, hash).Scan(&id, &sub, &tier)
+	if err != nil {
+		return "", errInvalidDisplayToken
+	}
+	if !displayTierAllowed(tier) {
+		return "", errDisplayNotEntitled
+	}
+
+	if Rdb != nil {
+		Rdb.Set(ctx, cacheKey, sub, DisplayTokenCacheTTL)
+	}
+
+	go func() {
+		bg, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := DBPool.Exec(bg,
+			`UPDATE display_tokens SET last_used_at = now() WHERE id = $1`, id,
+		); err != nil {
+			log.Printf("[Display] last_used_at update failed for token %d: %v", id, err)
+		}
+	}()
+
+	return sub, nil
+}
+
+// authorizeDisplay resolves the :token route param, writing a 401 or 403
+// on failure. Mirrors authorizeEventStream.
+func authorizeDisplay(c *fiber.Ctx) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	owner, err := resolveDisplayToken(ctx, c.Params("token"))
+	switch {
+	case errors.Is(err, errDisplayNotEntitled):
+		return "", false, c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "Display mode requires an Uplink Ultimate subscription",
+		})
+	case err != nil:
+		return "", false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Invalid display token",
+		})
+	}
+	// The token is in the URL; keep it out of Referer headers from
+	// anything the display links to.
+	c.Set("Referrer-Policy", "no-referrer")
+	return owner, true, nil
+}
+
+// ─── Ticker Snapshot ─────────────────────────────────────────────
+
+// buildDisplayTicker reduces a dashboard to what a display shows: enabled
+// channels that are on the ticker, their data, the organizations' shared
+// channels, incidents and the layout preferences. Channel configs are
+// dropped; they can hold credentials and a display sits in a shared space.
+func buildDisplayTicker(dash DashboardResponse, now time.Time) DisplayTicker {
+	out := DisplayTicker{
+		Channels:    make([]Channel, 0, len(dash.Channels)),
+		Data:        make(map[string]interface{}),
+		Incidents:   dash.Incidents,
+		GeneratedAt: now.UnixMilli(),
+	}
+	for _, ch := range dash.Channels {
+		if !ch.Enabled || !ch.Visible {
+			continue
+		}
+		ch.Config = nil
+		out.Channels = append(out.Channels, ch)
+		if data, ok := dash.Data[ch.ChannelType]; ok {
+			out.Data[ch.ChannelType] = data
+		}
+	}
+	for _, org := range dash.Organizations {
+		// Members see shared channels read-only; role is meaningless here.
+		org.Role = ""
+		channels := make([]Channel, len(org.Channels))
+		for i, ch := range org.Channels {
+			ch.Config = nil
+			channels[i] = ch
+		}
+		org.Channels = channels
+		out.Organizations = append(out.Organizations, org)
+	}
+	if dash.Preferences != nil {
+		out.FeedMode = dash.Preferences.FeedMode
+		out.FeedPosition = dash.Preferences.FeedPosition
+	}
+	return out
+}
+
+// loadDisplayTicker returns the owner's display ticker JSON, from the
+// Redis cache when warm. Concurrent misses (a wall of TVs booting on the
+// same account) share one assembly.
+func loadDisplayTicker(owner string) ([]byte, bool, error) {
+	cacheKey := RedisDisplayTickerPrefix + owner
+	if val, ok := GetCache(context.Background(), cacheKey); ok {
+		return val, true, nil
+	}
+
+	result, err, _ := displayTickerGroup.Do(owner, func() (interface{}, error) {
+		if val, ok := GetCache(context.Background(), cacheKey); ok {
+			return val, nil
+		}
+		// No roles: a display must never sync the owner's tier.
+		raw, _, _ := assembleDashboard(context.Background(), owner)
+		var dash DashboardResponse
+		if err := json.Unmarshal(raw, &dash); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(buildDisplayTicker(dash, time.Now()))
+		if err != nil {
+			return nil, err
+		}
+		SetCache(context.Background(), cacheKey, data, DisplayTickerCacheTTL)
+		return data, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]byte), false, nil
+}
+
+// HandleDisplayTicker returns the display's ticker snapshot.
+//
+// @Summary Display ticker snapshot
+// @Description Read-only ticker for a kiosk / TV display, authenticated by a display token in the path
+// @Tags Display
+// @Produce json
+// @Param token path string true "Display token"
+// @Success 200 {object} DisplayTicker
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /display/{token}/ticker [get]
+func HandleDisplayTicker(c *fiber.Ctx) error {
+	owner, ok, err := authorizeDisplay(c)
+	if !ok {
+		return err
+	}
+
+	data, hit, err := loadDisplayTicker(owner)
+	if err != nil {
+		log.Printf("[Display] ticker assembly failed for %s: %v", owner, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load display ticker",
+		})
+	}
+
+	c.Set("Cache-Control", "private, max-age="+strconv.Itoa(DisplayTickerMaxAge))
+	recordCacheLookup("display", hit)
+	if hit {
+		c.Set("X-Cache", "HIT")
+	} else {
+		c.Set("X-Cache", "MISS")
+	}
+	return sendTickerBody(c, data)
+}
+
+// ─── Stream ──────────────────────────────────────────────────────
+
+// displayForwardable reports whether an event may be shown on a display.
+// Payloads that aren't CDC envelopes (broadcasts) pass through.
+func displayForwardable(payload []byte) bool {
+	var env struct {
+		Data []struct {
+			Metadata struct {
+				TableName string `json:"table_name"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(payload, &env) != nil {
+		return true
+	}
+	for _, rec := range env.Data {
+		if displayPrivateTables[rec.Metadata.TableName] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDisplayRevokedEvent ends a display stream whose token stopped
+// resolving.
+func writeDisplayRevokedEvent(w *bufio.Writer, err error) {
+	reason := "revoked"
+	if errors.Is(err, errDisplayNotEntitled) {
+		reason = "not-entitled"
+	}
+	fmt.Fprintf(w, "event: display-revoked\ndata: {\"reason\":%q}\n\n", reason)
+	w.Flush()
+}
+
+// HandleDisplayEvents streams the display owner's live updates over SSE.
+// Same envelopes and Last-Event-ID resume as /events.
+//
+// @Summary Display event stream
+// @Description Server-Sent Events for a kiosk / TV display, authenticated by a display token in the path
+// @Tags Display
+// @Produce text/event-stream
+// @Param token path string true "Display token"
+// @Param Last-Event-ID header string false "Seq of the last event received; missed events are replayed"
+// @Param last_event_id query string false "Resume cursor (fallback if no Last-Event-ID header)"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /display/{token}/events [get]
+func HandleDisplayEvents(c *fiber.Ctx) error {
+	owner, ok, err := authorizeDisplay(c)
+	if !ok {
+		return err
+	}
+	token := c.Params("token")
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+
+	client := RegisterClient(owner)
+	lastSeq := lastEventID(c)
+
+	log.Printf("[Display] Stream connected: user=%s ip=%s last_event_id=%d", owner, c.IP(), lastSeq)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		ticker := time.NewTicker(SSEHeartbeatInterval)
+		defer ticker.Stop()
+		defer UnregisterClient(client)
+
+		fmt.Fprintf(w, "retry: %d\n\n", SSERetryIntervalMs)
+		w.Flush()
+
+		var cursor *replayCursor
+		if lastSeq > 0 {
+			events, complete := replayEvents(owner, lastSeq)
+			for _, e := range events {
+				if displayForwardable(e.payload) {
+					writeSSEData(w, e.seq, e.payload)
+				}
+			}
+			if !complete {
+				fmt.Fprintf(w, "event: resync\ndata: {\"reason\":%q}\n\n", replayResyncReason)
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+			cursor = newReplayCursor(events)
+		}
+
+		lastCheck := time.Now()
+		for {
+			select {
+			case msg, ok := <-client.Ch:
+				if !ok {
+					return
+				}
+				seq := envelopeSeq(msg)
+				if cursor.duplicate(seq) || !displayForwardable(msg) {
+					continue
+				}
+				writeSSEData(w, seq, msg)
+				if err := w.Flush(); err != nil {
+					return
+				}
+
+			case now := <-ticker.C:
+				if now.Sub(lastCheck) >= DisplayTokenRecheckInterval {
+					lastCheck = now
+					ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+					_, err := resolveDisplayToken(ctx, token)
+					cancel()
+					if err != nil {
+						log.Printf("[Display] Stream closed for user=%s: %v", owner, err)
+						writeDisplayRevokedEvent(w, err)
+						return
+					}
+				}
+				fmt.Fprintf(w, ": ping\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
+
+// ─── Management Handlers ─────────────────────────────────────────
+
+// HandleListDisplayTokens returns the caller's active display tokens
+// (never the secret).
+//
+// @Summary List display tokens
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{display_tokens=[]DisplayToken}
+// @Security LogtoAuth
+// @Router /users/me/display-tokens [get]
+func HandleListDisplayTokens(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT id, name, token_prefix, created_at, last_used_at
+		FROM display_tokens
+		WHERE logto_sub = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[Display] list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list display tokens",
+		})
+	}
+	defer rows.Close()
+
+	tokens := make([]DisplayToken, 0)
+	for rows.Next() {
+		var t DisplayToken
+		if err := rows.Scan(&t.ID, &t.Name, &t.TokenPrefix, &t.CreatedAt, &t.LastUsedAt); err != nil {
+			log.Printf("[Display] scan error: %v", err)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return c.JSON(fiber.Map{"display_tokens": tokens})
+}
+
+// HandleCreateDisplayToken mints a display token. The plaintext is in
+// this response and nowhere else. The route is gated to Uplink Ultimate
+// by RequireTier.
+//
+// @Summary Create display token
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body object{name=string} true "Display label, e.g. the room it hangs in"
+// @Success 201 {object} object{display_token=DisplayToken,token=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 402 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/display-tokens [post]
+func HandleCreateDisplayToken(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 64 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Name must be 1-64 characters",
+		})
+	}
+
+	ctx := c.Context()
+	var active int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM display_tokens WHERE logto_sub = $1 AND revoked_at IS NULL`, userID,
+	).Scan(&active); err != nil {
+		log.Printf("[Display] count failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create display token",
+		})
+	}
+	if active >= DisplayTokenMaxPerUser {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("You can have at most %d active display tokens; revoke one first", DisplayTokenMaxPerUser),
+		})
+	}
+
+	token, err := generateDisplayToken()
+	if err != nil {
+		log.Printf("[Display] generate failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create display token",
+		})
+	}
+
+	t := DisplayToken{Name: req.Name, TokenPrefix: displayTokenDisplayPrefix(token)}
+	if err := DBPool.QueryRow(ctx, `
+		INSERT INTO display_tokens (logto_sub, name, token_prefix, token_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, userID, t.Name, t.TokenPrefix, hashAPIKey(token)).Scan(&t.ID, &t.CreatedAt); err != nil {
+		log.Printf("[Display] insert failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create display token",
+		})
+	}
+
+	log.Printf("[Display] Created token %d for user=%s", t.ID, userID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"display_token": t, "token": token})
+}
+
+// HandleRevokeDisplayToken revokes one of the caller's display tokens.
+// The resolution cache entry is dropped, so requests fail immediately and
+// an open stream closes at its next recheck.
+//
+// @Summary Revoke display token
+// @Tags Users
+// @Produce json
+// @Param id path int true "Display token ID"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/display-tokens/{id} [delete]
+func HandleRevokeDisplayToken(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid display token id",
+		})
+	}
+
+	ctx := c.Context()
+	var hash string
+	err = DBPool.QueryRow(ctx, `
+		UPDATE display_tokens SET revoked_at = now()
+		WHERE id = $1 AND logto_sub = $2 AND revoked_at IS NULL
+		RETURNING token_hash
+	`, id, userID).Scan(&hash)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Display token not found",
+		})
+	}
+
+	if Rdb != nil {
+		if err := Rdb.Del(ctx, RedisDisplayTokenPrefix+hash).Err(); err != nil && err != redis.Nil {
+			log.Printf("[Display] cache DEL failed for token %d (expires in %s): %v", id, DisplayTokenCacheTTL, err)
+		}
+	}
+
+	log.Printf("[Display] Revoked token %d for user=%s", id, userID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateDisplayToken(t *testing.T) {
+	a, err := generateDisplayToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generateDisplayToken()
+	if a == b {
+		t.Error("two tokens are identical")
+	}
+	if !strings.HasPrefix(a, DisplayTokenPrefix) {
+		t.Errorf("token %q lacks prefix %q", a, DisplayTokenPrefix)
+	}
+	if got := displayTokenDisplayPrefix(a); got != a[:len(DisplayTokenPrefix)+4] {
+		t.Errorf("display prefix = %q", got)
+	}
+}
+
+func TestResolveDisplayTokenRejectsAPIKeys(t *testing.T) {
+	// API keys share the scrollr_ prefix but must never open a display.
+	key, _ := generateAPIKey()
+	if _, err := resolveDisplayToken(context.Background(), key); !errors.Is(err, errInvalidDisplayToken) {
+		t.Errorf("resolveDisplayToken(api key) err = %v, want errInvalidDisplayToken", err)
+	}
+	if _, err := resolveDisplayToken(context.Background(), ""); !errors.Is(err, errInvalidDisplayToken) {
+		t.Errorf("resolveDisplayToken(\"\") err = %v, want errInvalidDisplayToken", err)
+	}
+}
+
+func TestDisplayTierAllowed(t *testing.T) {
+	for tier, want := range map[string]bool{
+		"free": false, "uplink": false, "uplink_pro": false,
+		"uplink_ultimate": true, "super_user": true,
+	} {
+		if got := displayTierAllowed(tier); got != want {
+			t.Errorf("displayTierAllowed(%q) = %v, want %v", tier, got, want)
+		}
+	}
+}
+
+func TestBuildDisplayTicker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	dash := DashboardResponse{
+		Data: map[string]interface{}{
+			"finance": "quotes",
+			"sports":  "scores",
+			"rss":     "items",
+		},
+		Preferences: &UserPreferences{FeedMode: "compact", FeedPosition: "bottom", SubscriptionTier: "uplink_ultimate"},
+		Channels: []Channel{
+			{ChannelType: "finance", Enabled: true, Visible: true, Config: map[string]interface{}{"webhook_url": "https://discord.com/api/webhooks/1/secret"}},
+			{ChannelType: "sports", Enabled: true, Visible: false}, // off the ticker
+			{ChannelType: "rss", Enabled: false, Visible: true},    // disabled
+		},
+		Organizations: []OrgDashboard{{ID: 7, Name: "Desk", Role: "admin", Channels: []Channel{
+			{ChannelType: "rss", Enabled: true, Visible: true, Config: map[string]interface{}{"feeds": []string{"https://example.com/rss"}}},
+		}}},
+	}
+
+	got := buildDisplayTicker(dash, now)
+	if len(got.Channels) != 1 || got.Channels[0].ChannelType != "finance" {
+		t.Fatalf("channels = %+v, want only finance", got.Channels)
+	}
+	if got.Channels[0].Config != nil || len(got.Organizations) != 1 || got.Organizations[0].Channels[0].Config != nil {
+		t.Error("channel configs reached the display")
+	}
+	if dash.Channels[0].Config == nil || dash.Organizations[0].Channels[0].Config == nil {
+		t.Error("buildDisplayTicker mutated the dashboard's channel configs")
+	}
+	if len(got.Data) != 1 || got.Data["finance"] != "quotes" {
+		t.Errorf("data = %v, want only finance", got.Data)
+	}
+	if got.FeedMode != "compact" || got.FeedPosition != "bottom" {
+		t.Errorf("layout = %q/%q", got.FeedMode, got.FeedPosition)
+	}
+	if len(got.Organizations) != 1 || got.Organizations[0].Role != "" {
+		t.Errorf("organizations = %+v, want one with role cleared", got.Organizations)
+	}
+	if dash.Organizations[0].Role != "admin" {
+		t.Error("buildDisplayTicker mutated the dashboard's organizations")
+	}
+	if got.GeneratedAt != now.UnixMilli() {
+		t.Errorf("generated_at = %d", got.GeneratedAt)
+	}
+}
+
+func TestDisplayForwardable(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		want    bool
+	}{
+		{"channel CDC", `{"data":[{"metadata":{"table_name":"trades"}}],"seq":1}`, true},
+		{"channel config", `{"data":[{"metadata":{"table_name":"user_channels"}}],"seq":2}`, false},
+		{"billing", `{"data":[{"metadata":{"table_name":"stripe_customers"}}],"seq":3}`, false},
+		{"broadcast", `{"type":"incident","incident":{"id":1}}`, true},
+		{"not json", `ping`, true},
+	}
+	for _, tc := range cases {
+		if got := displayForwardable([]byte(tc.payload)); got != tc.want {
+			t.Errorf("%s: displayForwardable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
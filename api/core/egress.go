@@ -0,0 +1,314 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Egress policy and DNS caching for the shared transports (httpclient.go).
+//
+// Every outbound dial resolves through dnsCache, so the hot paths (channel
+// fan-out, Logto, Resend) stop paying a lookup per connection. Entries
+// live for DNSCacheTTL; when a refresh fails, the last good answer is
+// served for up to DNSCacheStaleTTL so a resolver blip doesn't fail calls
+// to hosts we reached a minute ago.
+//
+// External clients also check the destination host against an egress
+// policy before the request leaves the process:
+//
+//   - EGRESS_DENYLIST:  always refused.
+//   - EGRESS_ALLOWLIST: when set, anything not on it is refused.
+//
+// Both are comma-separated. An entry is a hostname ("api.resend.com"), a
+// wildcard suffix ("*.discord.com", which also matches discord.com), an
+// IP, or a CIDR; the last two only match IP-literal URLs. Empty lists
+// allow everything, which is the default outside production. Refusals
+// are counted per host and reported at GET /admin/http-clients.
+
+var errEgressBlocked = errors.New("egress blocked by policy")
+
+// ─── Policy ──────────────────────────────────────────────────────
+
+type egressRules struct {
+	hosts    map[string]bool
+	suffixes []string // ".discord.com" for "*.discord.com"
+	prefixes []netip.Prefix
+}
+
+func (r egressRules) empty() bool {
+	return len(r.hosts) == 0 && len(r.suffixes) == 0 && len(r.prefixes) == 0
+}
+
+func (r egressRules) match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if addr, err := netip.ParseAddr(host); err == nil {
+		for _, p := range r.prefixes {
+			if p.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+	if r.hosts[host] {
+		return true
+	}
+	for _, s := range r.suffixes {
+		if strings.HasSuffix(host, s) || host == s[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEgressRules parses a comma-separated rule list. Entries that are
+// neither a host, a wildcard nor an IP/CIDR are logged and skipped.
+func parseEgressRules(raw string) egressRules {
+	r := egressRules{hosts: map[string]bool{}}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.HasPrefix(entry, "*."):
+			r.suffixes = append(r.suffixes, entry[1:])
+		case strings.Contains(entry, "/"):
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				log.Printf("[Egress] ignoring invalid CIDR %q", entry)
+				continue
+			}
+			r.prefixes = append(r.prefixes, p.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				r.prefixes = append(r.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			if strings.ContainsAny(entry, " *:") {
+				log.Printf("[Egress] ignoring invalid host %q", entry)
+				continue
+			}
+			r.hosts[entry] = true
+		}
+	}
+	return r
+}
+
+type egressPolicy struct {
+	allow egressRules
+	deny  egressRules
+}
+
+// allowed reports whether an external client may call host.
+func (p *egressPolicy) allowed(host string) bool {
+	if p.deny.match(host) {
+		return false
+	}
+	return p.allow.empty() || p.allow.match(host)
+}
+
+var (
+	egressOnce sync.Once
+	egress     *egressPolicy
+)
+
+// currentEgressPolicy loads the policy from the environment on first use
+// (after main has loaded .env).
+func currentEgressPolicy() *egressPolicy {
+	egressOnce.Do(func() {
+		egress = &egressPolicy{
+			allow: parseEgressRules(os.Getenv("EGRESS_ALLOWLIST")),
+			deny:  parseEgressRules(os.Getenv("EGRESS_DENYLIST")),
+		}
+		if !egress.allow.empty() || !egress.deny.empty() {
+			log.Printf("[Egress] policy active: %d allow / %d deny rule(s)",
+				egress.allow.size(), egress.deny.size())
+		}
+	})
+	return egress
+}
+
+func (r egressRules) size() int {
+	return len(r.hosts) + len(r.suffixes) + len(r.prefixes)
+}
+
+// ─── Blocked destinations ────────────────────────────────────────
+
+var (
+	egressBlockedMu sync.Mutex
+	egressBlocked   = map[string]int64{}
+)
+
+// recordEgressBlocked counts a refusal. The first refusal per host is
+// logged; later ones only count, so a misconfigured caller can't flood
+// the log. Distinct hosts are capped at EgressBlockedMaxHosts.
+func recordEgressBlocked(purpose, host string) {
+	egressBlockedMu.Lock()
+	defer egressBlockedMu.Unlock()
+	n, seen := egressBlocked[host]
+	if !seen && len(egressBlocked) >= EgressBlockedMaxHosts {
+		host = "(other)"
+		n = egressBlocked[host]
+	}
+	egressBlocked[host] = n + 1
+	if !seen {
+		log.Printf("[Egress] blocked %s request to %s", purpose, host)
+	}
+}
+
+// EgressBlockedHost is one row of the blocked-destination report.
+type EgressBlockedHost struct {
+	Host  string `json:"host"`
+	Count int64  `json:"count"`
+}
+
+// EgressBlockedStats reports blocked hosts, most-blocked first.
+func EgressBlockedStats() []EgressBlockedHost {
+	egressBlockedMu.Lock()
+	defer egressBlockedMu.Unlock()
+	out := make([]EgressBlockedHost, 0, len(egressBlocked))
+	for h, n := range egressBlocked {
+		out = append(out, EgressBlockedHost{Host: h, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+// ─── DNS cache ───────────────────────────────────────────────────
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+type dnsCache struct {
+	lookup   func(ctx context.Context, host string) ([]string, error)
+	ttl      time.Duration
+	staleTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	stale  atomic.Int64
+}
+
+func newDNSCache(ttl, staleTTL time.Duration) *dnsCache {
+	return &dnsCache{
+		lookup:   net.DefaultResolver.LookupHost,
+		ttl:      ttl,
+		staleTTL: staleTTL,
+		entries:  map[string]dnsEntry{},
+	}
+}
+
+var sharedDNSCache = newDNSCache(DNSCacheTTL, DNSCacheStaleTTL)
+
+// resolve returns the addresses for host, from cache when fresh.
+func (d *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	now := time.Now()
+	d.mu.Lock()
+	e, ok := d.entries[host]
+	d.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		d.hits.Add(1)
+		return e.addrs, nil
+	}
+
+	d.misses.Add(1)
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		if ok && now.Before(e.expires.Add(d.staleTTL)) {
+			d.stale.Add(1)
+			return e.addrs, nil
+		}
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if len(d.entries) >= DNSCacheMaxEntries {
+		d.evictExpiredLocked(now)
+	}
+	if len(d.entries) < DNSCacheMaxEntries {
+		d.entries[host] = dnsEntry{addrs: addrs, expires: now.Add(d.ttl)}
+	}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+func (d *dnsCache) evictExpiredLocked(now time.Time) {
+	for h, e := range d.entries {
+		if now.After(e.expires.Add(d.staleTTL)) {
+			delete(d.entries, h)
+		}
+	}
+}
+
+// DNSCacheStats is a point-in-time view of the resolver cache.
+type DNSCacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Stale   int64 `json:"stale_served"`
+}
+
+func (d *dnsCache) Stats() DNSCacheStats {
+	d.mu.Lock()
+	n := len(d.entries)
+	d.mu.Unlock()
+	return DNSCacheStats{
+		Entries: n,
+		Hits:    d.hits.Load(),
+		Misses:  d.misses.Load(),
+		Stale:   d.stale.Load(),
+	}
+}
+
+// cachingDialContext resolves through d and tries each address in turn.
+// IP literals skip the cache.
+func cachingDialContext(d *dnsCache, dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		ips, err := d.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no addresses for %s", host)
+		}
+		return nil, firstErr
+	}
+}
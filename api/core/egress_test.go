@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEgressPolicy(t *testing.T) {
+	p := &egressPolicy{
+		allow: parseEgressRules("api.resend.com, *.discord.com, 10.0.0.0/8, bogus host"),
+		deny:  parseEgressRules("canary.discord.com,10.0.0.5"),
+	}
+	cases := []struct {
+		host string
+		want bool
+	}{
+		{"api.resend.com", true},
+		{"API.Resend.com.", true},
+		{"resend.com", false},
+		{"discord.com", true},
+		{"cdn.discord.com", true},
+		{"notdiscord.com", false},
+		{"canary.discord.com", false},
+		{"10.1.2.3", true},
+		{"10.0.0.5", false},
+		{"192.168.1.1", false},
+		{"bogus host", false},
+	}
+	for _, tc := range cases {
+		if got := p.allowed(tc.host); got != tc.want {
+			t.Errorf("allowed(%q) = %v, want %v", tc.host, got, tc.want)
+		}
+	}
+
+	open := &egressPolicy{allow: parseEgressRules(""), deny: parseEgressRules("*.internal")}
+	if !open.allowed("example.com") {
+		t.Error("an empty allowlist should allow everything not denied")
+	}
+	if open.allowed("db.internal") {
+		t.Error("denylist should apply without an allowlist")
+	}
+}
+
+func TestEgressBlockedRoundTrip(t *testing.T) {
+	policy := &egressPolicy{allow: parseEgressRules("allowed.example")}
+	called := false
+	client := &http.Client{Transport: &instrumentedTransport{
+		base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			called = true
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+		purpose: "test_egress",
+		stats:   httpClientStatsFor("test_egress"),
+		policy:  func() *egressPolicy { return policy },
+	}}
+
+	_, err := client.Get("https://blocked.example/path")
+	if !errors.Is(err, errEgressBlocked) {
+		t.Fatalf("err = %v, want errEgressBlocked", err)
+	}
+	if called {
+		t.Fatal("blocked request reached the transport")
+	}
+	resp, err := client.Get("https://allowed.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	s := httpClientStatsFor("test_egress")
+	if s.blocked.Load() != 1 || s.requests.Load() != 1 {
+		t.Errorf("blocked=%d requests=%d, want 1 and 1", s.blocked.Load(), s.requests.Load())
+	}
+	var found bool
+	for _, b := range EgressBlockedStats() {
+		if b.Host == "blocked.example" && b.Count >= 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("blocked host missing from EgressBlockedStats")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestDNSCache(t *testing.T) {
+	lookups := 0
+	fail := false
+	d := newDNSCache(20*time.Millisecond, time.Minute)
+	d.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if fail {
+			return nil, errors.New("resolver down")
+		}
+		return []string{"192.0.2.1"}, nil
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := d.resolve(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("lookups = %d, want 1 while fresh", lookups)
+	}
+
+	// Expired and the resolver fails: the last good answer is served.
+	time.Sleep(30 * time.Millisecond)
+	fail = true
+	addrs, err := d.resolve(ctx, "example.com")
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("stale lookup = %v, %v", addrs, err)
+	}
+	if _, err := d.resolve(ctx, "other.example"); err == nil {
+		t.Fatal("uncached host should surface the resolver error")
+	}
+
+	s := d.Stats()
+	if s.Hits != 2 || s.Stale != 1 || s.Entries != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
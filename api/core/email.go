@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Outbound email for workers that send on a schedule.
+//
+// The one-off transactional senders (password reset, team invites,
+// dunning) call postToResend directly. The digest worker goes through
+// EmailSender instead so self-hosted deployments can point it at an SMTP
+// relay: EMAIL_PROVIDER=smtp with SMTP_HOST, SMTP_PORT (default 587),
+// SMTP_USERNAME and SMTP_PASSWORD. Anything else uses Resend.
+
+// ErrEmailNotConfigured is returned by newEmailSender when the selected
+// provider has no credentials.
+var ErrEmailNotConfigured = errors.New("email provider not configured")
+
+// EmailMessage is one outbound HTML email.
+type EmailMessage struct {
+	To      string
+	Subject string
+	HTML    string
+}
+
+// EmailSender delivers an EmailMessage.
+type EmailSender interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
+
+// emailFrom is the From address shared by the default senders.
+func emailFrom() string {
+	if from := os.Getenv("RESEND_FROM_EMAIL"); from != "" {
+		return from
+	}
+	return "MyScrollr <noreply@myscrollr.com>"
+}
+
+// newEmailSender picks the provider named by EMAIL_PROVIDER.
+func newEmailSender() (EmailSender, error) {
+	switch strings.ToLower(os.Getenv("EMAIL_PROVIDER")) {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("%w: SMTP_HOST is empty", ErrEmailNotConfigured)
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &smtpSender{
+			addr:     net.JoinHostPort(host, port),
+			host:     host,
+			username: os.Getenv("SMTP_USERNAME"),
+			password: Secret("SMTP_PASSWORD"),
+			from:     emailFrom(),
+		}, nil
+	default:
+		apiKey := Secret("RESEND_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("%w: RESEND_API_KEY is empty", ErrEmailNotConfigured)
+		}
+		return &resendSender{apiKey: apiKey, from: emailFrom()}, nil
+	}
+}
+
+// resendSender sends through the Resend API (postToResend).
+type resendSender struct {
+	apiKey string
+	from   string
+}
+
+func (s *resendSender) Send(ctx context.Context, msg EmailMessage) error {
+	return postToResend(ctx, s.apiKey, map[string]any{
+		"from":    s.from,
+		"to":      []string{msg.To},
+		"subject": msg.Subject,
+		"html":    msg.HTML,
+	})
+}
+
+// smtpSender sends through an SMTP relay. net/smtp upgrades to STARTTLS
+// when the server offers it and refuses PLAIN auth without TLS.
+type smtpSender struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg EmailMessage) error {
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("parse from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("parse to address: %w", err)
+	}
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	// smtp.SendMail takes no context; run it aside so a hung relay
+	// doesn't outlive the caller.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, auth, from.Address, []string{to.Address}, buildSMTPMessage(from, to, msg.Subject, msg.HTML, time.Now()))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("smtp send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// buildSMTPMessage renders a single-part HTML message. The subject is
+// RFC 2047 encoded, which also keeps CR/LF out of the headers.
+func buildSMTPMessage(from, to *mail.Address, subject, htmlBody string, now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(htmlBody, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,433 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email digests.
+//
+// A user who sets user_preferences.email_digest gets a daily or weekly
+// email summing up their channels: the biggest movers among their
+// symbols, games that finished, and RSS headlines published since the
+// last email. The content comes from the same dashboard fan-out as GET
+// /dashboard (assembleDashboard), so spoiler windows and organization
+// channels apply exactly as they do in the app.
+//
+// Schedules are in UTC. A schedule is due during its hour (and, for
+// weekly, its weekday) once enough time has passed since the last send;
+// the worker checks every EmailDigestCheckInterval and a Redis lock keeps
+// each check to one replica. A digest with nothing in it is skipped but
+// still stamped, so the user isn't rebuilt on every check that hour.
+
+// Email digest frequencies.
+const (
+	EmailDigestOff    = "off"
+	EmailDigestDaily  = "daily"
+	EmailDigestWeekly = "weekly"
+)
+
+// EmailDigestSchedule is a user's email digest setting.
+type EmailDigestSchedule struct {
+	Frequency string `json:"frequency"` // off, daily or weekly
+	Hour      int    `json:"hour"`      // UTC, 0-23
+	Weekday   int    `json:"weekday"`   // 0 = Sunday; weekly only
+}
+
+// defaultEmailDigestSchedule matches the column default.
+var defaultEmailDigestSchedule = EmailDigestSchedule{Frequency: EmailDigestOff, Hour: 8, Weekday: int(time.Monday)}
+
+// parseEmailDigestSchedule validates an email_digest value from a
+// preferences update. Omitted fields take the defaults.
+func parseEmailDigestSchedule(v interface{}) (EmailDigestSchedule, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return EmailDigestSchedule{}, errors.New("email_digest must be an object with frequency, hour and weekday")
+	}
+	s := defaultEmailDigestSchedule
+	if f, ok := raw["frequency"]; ok {
+		str, isStr := f.(string)
+		if !isStr || (str != EmailDigestOff && str != EmailDigestDaily && str != EmailDigestWeekly) {
+			return EmailDigestSchedule{}, errors.New("email_digest.frequency must be 'off', 'daily' or 'weekly'")
+		}
+		s.Frequency = str
+	}
+	wholeIn := func(key string, lo, hi int, dst *int) error {
+		x, ok := raw[key]
+		if !ok {
+			return nil
+		}
+		n, isNum := x.(float64)
+		if !isNum || n != math.Trunc(n) || n < float64(lo) || n > float64(hi) {
+			return fmt.Errorf("email_digest.%s must be a whole number between %d and %d", key, lo, hi)
+		}
+		*dst = int(n)
+		return nil
+	}
+	if err := wholeIn("hour", 0, 23, &s.Hour); err != nil {
+		return EmailDigestSchedule{}, err
+	}
+	if err := wholeIn("weekday", 0, 6, &s.Weekday); err != nil {
+		return EmailDigestSchedule{}, err
+	}
+	return s, nil
+}
+
+// decodeEmailDigestSchedule reads the email_digest column; anything
+// unreadable is treated as off.
+func decodeEmailDigestSchedule(b []byte) EmailDigestSchedule {
+	s := defaultEmailDigestSchedule
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return defaultEmailDigestSchedule
+		}
+	}
+	return s
+}
+
+// emailDigestPeriod is how far back a digest looks and, less a margin,
+// the minimum gap between two sends. The margin lets a schedule moved
+// an hour later still send the next day.
+func emailDigestPeriod(frequency string) time.Duration {
+	if frequency == EmailDigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+const emailDigestMargin = 4 * time.Hour
+
+// emailDigestDue reports whether s should send at now, given the last
+// send (nil if never).
+func emailDigestDue(s EmailDigestSchedule, lastSent *time.Time, now time.Time) bool {
+	now = now.UTC()
+	switch s.Frequency {
+	case EmailDigestDaily:
+	case EmailDigestWeekly:
+		if int(now.Weekday()) != s.Weekday {
+			return false
+		}
+	default:
+		return false
+	}
+	if now.Hour() != s.Hour {
+		return false
+	}
+	return lastSent == nil || now.Sub(*lastSent) >= emailDigestPeriod(s.Frequency)-emailDigestMargin
+}
+
+// ─── Worker ──────────────────────────────────────────────────────
+
+// StartEmailDigestWorker sends due email digests every
+// EmailDigestCheckInterval. Without an email provider it logs once and
+// does nothing.
+func StartEmailDigestWorker(ctx context.Context) {
+	sender, err := newEmailSender()
+	if err != nil {
+		log.Printf("[EmailDigest] Disabled: %v", err)
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(EmailDigestCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sendDueEmailDigests(ctx, sender, time.Now())
+			}
+		}
+	}()
+}
+
+// sendDueEmailDigests sends every digest due at now, up to
+// EmailDigestBatchSize.
+func sendDueEmailDigests(ctx context.Context, sender EmailSender, now time.Time) {
+	if Rdb != nil {
+		ok, err := Rdb.SetNX(ctx, RedisEmailDigestLock, "1", EmailDigestLockTTL).Result()
+		if err != nil || !ok {
+			return
+		}
+		defer Rdb.Del(context.Background(), RedisEmailDigestLock)
+	}
+
+	// The SQL narrows to this hour's schedules; emailDigestDue has the
+	// final say on weekday and spacing.
+	rows, err := DBPool.Query(ctx, `
+		SELECT logto_sub, email_digest, email_digest_last_sent_at
+		FROM user_preferences
+		WHERE email_digest->>'frequency' IN ('daily', 'weekly')
+		  AND (email_digest->>'hour')::int = $1
+		  AND (email_digest_last_sent_at IS NULL OR email_digest_last_sent_at < $2)
+		ORDER BY email_digest_last_sent_at NULLS FIRST
+		LIMIT $3
+	`, now.UTC().Hour(), now.Add(-(emailDigestPeriod(EmailDigestDaily) - emailDigestMargin)), EmailDigestBatchSize)
+	if err != nil {
+		log.Printf("[EmailDigest] Scan failed: %v", err)
+		return
+	}
+	type due struct {
+		sub      string
+		schedule EmailDigestSchedule
+		lastSent *time.Time
+	}
+	var batch []due
+	for rows.Next() {
+		var d due
+		var raw []byte
+		if err := rows.Scan(&d.sub, &raw, &d.lastSent); err != nil {
+			continue
+		}
+		d.schedule = decodeEmailDigestSchedule(raw)
+		if emailDigestDue(d.schedule, d.lastSent, now) {
+			batch = append(batch, d)
+		}
+	}
+	rows.Close()
+
+	sent := 0
+	for _, d := range batch {
+		if ctx.Err() != nil {
+			return
+		}
+		ok, err := sendEmailDigest(ctx, sender, d.sub, d.schedule, d.lastSent, now)
+		if err != nil {
+			log.Printf("[EmailDigest] Digest for %s failed: %v", d.sub, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	if sent > 0 {
+		log.Printf("[EmailDigest] Sent %d digest(s)", sent)
+	}
+}
+
+// sendEmailDigest builds and sends one user's digest and stamps the
+// send. Returns false when there was nothing to send or no address.
+func sendEmailDigest(ctx context.Context, sender EmailSender, sub string, s EmailDigestSchedule, lastSent *time.Time, now time.Time) (bool, error) {
+	since := now.Add(-emailDigestPeriod(s.Frequency))
+	if lastSent != nil && lastSent.After(since) {
+		since = *lastSent
+	}
+
+	body, _, _ := assembleDashboard(ctx, sub)
+	var dash DashboardResponse
+	if err := json.Unmarshal(body, &dash); err != nil {
+		return false, fmt.Errorf("decode dashboard: %w", err)
+	}
+	digest := buildEmailDigest(&dash, since)
+
+	sentOK := false
+	if !digest.empty() {
+		to, err := GetLogtoUserEmail(sub)
+		if err != nil {
+			// Logto down: leave it unstamped to retry on the next check.
+			return false, fmt.Errorf("look up email: %w", err)
+		}
+		if to != "" {
+			subject, htmlBody := renderEmailDigest(digest, s.Frequency, emailDigestSettingsURL())
+			if err := sender.Send(ctx, EmailMessage{To: to, Subject: subject, HTML: htmlBody}); err != nil {
+				return false, fmt.Errorf("send: %w", err)
+			}
+			sentOK = true
+		}
+	}
+
+	if _, err := DBPool.Exec(ctx,
+		`UPDATE user_preferences SET email_digest_last_sent_at = $2 WHERE logto_sub = $1`, sub, now,
+	); err != nil {
+		return sentOK, fmt.Errorf("stamp digest: %w", err)
+	}
+	return sentOK, nil
+}
+
+func emailDigestSettingsURL() string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = DefaultFrontendURL
+	}
+	return base + "/account"
+}
+
+// ─── Content ─────────────────────────────────────────────────────
+
+// digestTrade, digestGame and digestRSSItem mirror the channel model
+// fields the email uses (see ticker_text.go for the same convention).
+type digestTrade struct {
+	Symbol           string  `json:"symbol"`
+	Price            float64 `json:"price"`
+	PercentageChange float64 `json:"percentage_change"`
+}
+
+type digestGame struct {
+	League        string    `json:"league"`
+	HomeTeamName  string    `json:"home_team_name"`
+	HomeTeamScore string    `json:"home_team_score"`
+	AwayTeamName  string    `json:"away_team_name"`
+	AwayTeamScore string    `json:"away_team_score"`
+	StartTime     time.Time `json:"start_time"`
+	State         string    `json:"state"`
+	SpoilerHidden bool      `json:"spoiler_hidden"`
+}
+
+type digestRSSItem struct {
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	SourceName  string     `json:"source_name"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// emailDigest is what one email reports.
+type emailDigest struct {
+	Movers    []digestTrade
+	Games     []digestGame
+	Headlines []digestRSSItem
+}
+
+func (d emailDigest) empty() bool {
+	return len(d.Movers) == 0 && len(d.Games) == 0 && len(d.Headlines) == 0
+}
+
+// buildEmailDigest picks the digest content out of a dashboard: the
+// largest absolute moves, finished games that started since since
+// (spoiler-hidden ones left out), and headlines published since since.
+// Personal and organization channels are pooled and de-duplicated.
+func buildEmailDigest(dash *DashboardResponse, since time.Time) emailDigest {
+	var d emailDigest
+	seenSymbol := make(map[string]bool)
+	seenGame := make(map[string]bool)
+	seenLink := make(map[string]bool)
+
+	collect := func(data map[string]interface{}) {
+		var trades []digestTrade
+		decodeChannelData(data["finance"], &trades)
+		for _, t := range trades {
+			if t.Symbol != "" && t.PercentageChange != 0 && !seenSymbol[t.Symbol] {
+				seenSymbol[t.Symbol] = true
+				d.Movers = append(d.Movers, t)
+			}
+		}
+
+		var games []digestGame
+		decodeChannelData(data["sports"], &games)
+		for _, g := range games {
+			key := g.League + "|" + g.AwayTeamName + "|" + g.HomeTeamName + "|" + g.StartTime.String()
+			if g.State != "post" || g.SpoilerHidden || g.StartTime.Before(since) || seenGame[key] {
+				continue
+			}
+			seenGame[key] = true
+			d.Games = append(d.Games, g)
+		}
+
+		var items []digestRSSItem
+		decodeChannelData(data["rss"], &items)
+		for _, it := range items {
+			if it.Title == "" || it.published().Before(since) || seenLink[it.Link+it.Title] {
+				continue
+			}
+			seenLink[it.Link+it.Title] = true
+			d.Headlines = append(d.Headlines, it)
+		}
+	}
+	collect(dash.Data)
+	for _, org := range dash.Organizations {
+		collect(org.Data)
+	}
+
+	sort.SliceStable(d.Movers, func(i, j int) bool {
+		return math.Abs(d.Movers[i].PercentageChange) > math.Abs(d.Movers[j].PercentageChange)
+	})
+	sort.SliceStable(d.Games, func(i, j int) bool { return d.Games[i].StartTime.After(d.Games[j].StartTime) })
+	sort.SliceStable(d.Headlines, func(i, j int) bool { return d.Headlines[i].published().After(d.Headlines[j].published()) })
+
+	d.Movers = d.Movers[:min(len(d.Movers), EmailDigestTopMovers)]
+	d.Games = d.Games[:min(len(d.Games), EmailDigestMaxGames)]
+	d.Headlines = d.Headlines[:min(len(d.Headlines), EmailDigestMaxItems)]
+	return d
+}
+
+func (it digestRSSItem) published() time.Time {
+	if it.PublishedAt != nil {
+		return *it.PublishedAt
+	}
+	return it.CreatedAt
+}
+
+// ─── Rendering ───────────────────────────────────────────────────
+
+// renderEmailDigest returns the subject and HTML body, in the style of
+// the other transactional emails.
+func renderEmailDigest(d emailDigest, frequency, settingsURL string) (string, string) {
+	period := "daily"
+	if frequency == EmailDigestWeekly {
+		period = "weekly"
+	}
+	subject := "Your " + period + " MyScrollr digest"
+
+	var sections strings.Builder
+	section := func(title string, rows []string) {
+		if len(rows) == 0 {
+			return
+		}
+		fmt.Fprintf(&sections, `    <h3 style="margin:24px 0 8px;font-size:14px;color:#fff;text-transform:uppercase;letter-spacing:0.04em;">%s</h3>
+    <table style="width:100%%;border-collapse:collapse;">
+%s    </table>
+`, title, strings.Join(rows, ""))
+	}
+
+	var rows []string
+	for _, t := range d.Movers {
+		color, sign := "#ef4444", ""
+		if t.PercentageChange > 0 {
+			color, sign = "#10b981", "+"
+		}
+		rows = append(rows, fmt.Sprintf(`      <tr><td style="padding:6px 0;color:#e6e6e6;font-weight:600;">%s</td><td style="padding:6px 0;text-align:right;color:#b8b8b8;">%s</td><td style="padding:6px 0;text-align:right;color:%s;">%s%s%%</td></tr>
+`, html.EscapeString(t.Symbol), strconv.FormatFloat(t.Price, 'f', 2, 64), color, sign, strconv.FormatFloat(t.PercentageChange, 'f', 2, 64)))
+	}
+	section("Top movers", rows)
+
+	rows = nil
+	for _, g := range d.Games {
+		rows = append(rows, fmt.Sprintf(`      <tr><td style="padding:6px 0;color:#7a7a7a;font-size:12px;">%s</td><td style="padding:6px 0;color:#e6e6e6;">%s %s, %s %s</td></tr>
+`, html.EscapeString(g.League), html.EscapeString(g.AwayTeamName), html.EscapeString(g.AwayTeamScore), html.EscapeString(g.HomeTeamName), html.EscapeString(g.HomeTeamScore)))
+	}
+	section("Final scores", rows)
+
+	rows = nil
+	for _, it := range d.Headlines {
+		title := html.EscapeString(it.Title)
+		if it.Link != "" {
+			title = fmt.Sprintf(`<a href="%s" style="color:#e6e6e6;text-decoration:none;">%s</a>`, html.EscapeString(it.Link), title)
+		}
+		rows = append(rows, fmt.Sprintf(`      <tr><td style="padding:6px 0;line-height:1.5;">%s<br><span style="color:#7a7a7a;font-size:12px;">%s</span></td></tr>
+`, title, html.EscapeString(it.SourceName)))
+	}
+	section("Headlines", rows)
+
+	body := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;background:#0b0d10;color:#e6e6e6;padding:24px;">
+  <div style="max-width:520px;margin:0 auto;background:#14181d;border:1px solid #1e252d;border-radius:12px;padding:32px;">
+    <h2 style="margin:0 0 8px;font-size:20px;color:#fff;">Your %s digest</h2>
+    <p style="margin:0;line-height:1.6;color:#b8b8b8;">Here's what happened on your channels.</p>
+%s  </div>
+  <p style="text-align:center;margin-top:16px;color:#5a5a5a;font-size:11px;">— The MyScrollr Team · <a href="%s" style="color:#5a5a5a;">Change digest settings</a></p>
+</body>
+</html>`, period, sections.String(), html.EscapeString(settingsURL))
+
+	return subject, body
+}
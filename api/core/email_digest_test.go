@@ -0,0 +1,165 @@
+package core
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEmailDigestSchedule(t *testing.T) {
+	got, err := parseEmailDigestSchedule(map[string]interface{}{"frequency": "weekly", "hour": float64(18), "weekday": float64(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (EmailDigestSchedule{Frequency: EmailDigestWeekly, Hour: 18, Weekday: 5}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got, err = parseEmailDigestSchedule(map[string]interface{}{"frequency": "daily"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hour != 8 || got.Weekday != int(time.Monday) {
+		t.Errorf("omitted fields = %+v, want defaults", got)
+	}
+
+	for _, bad := range []interface{}{
+		"daily",
+		map[string]interface{}{"frequency": "hourly"},
+		map[string]interface{}{"hour": float64(24)},
+		map[string]interface{}{"hour": 7.5},
+		map[string]interface{}{"weekday": float64(-1)},
+		map[string]interface{}{"weekday": "monday"},
+	} {
+		if _, err := parseEmailDigestSchedule(bad); err == nil {
+			t.Errorf("parseEmailDigestSchedule(%v) accepted", bad)
+		}
+	}
+}
+
+func TestDecodeEmailDigestSchedule(t *testing.T) {
+	if got := decodeEmailDigestSchedule([]byte(`{"frequency":"daily","hour":6}`)); got.Frequency != EmailDigestDaily || got.Hour != 6 {
+		t.Errorf("got %+v", got)
+	}
+	if got := decodeEmailDigestSchedule([]byte(`not json`)); got != defaultEmailDigestSchedule {
+		t.Errorf("unreadable column = %+v, want default", got)
+	}
+}
+
+func TestEmailDigestDue(t *testing.T) {
+	// Wednesday 2026-10-14 08:20 UTC.
+	now := time.Date(2026, 10, 14, 8, 20, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+
+	daily := EmailDigestSchedule{Frequency: EmailDigestDaily, Hour: 8}
+	weekly := EmailDigestSchedule{Frequency: EmailDigestWeekly, Hour: 8, Weekday: int(time.Wednesday)}
+
+	for _, tc := range []struct {
+		name     string
+		s        EmailDigestSchedule
+		lastSent *time.Time
+		want     bool
+	}{
+		{"daily never sent", daily, nil, true},
+		{"daily sent yesterday", daily, ago(24 * time.Hour), true},
+		{"daily moved an hour later", daily, ago(23 * time.Hour), true},
+		{"daily already sent this hour", daily, ago(10 * time.Minute), false},
+		{"daily wrong hour", EmailDigestSchedule{Frequency: EmailDigestDaily, Hour: 9}, nil, false},
+		{"off", EmailDigestSchedule{Frequency: EmailDigestOff, Hour: 8}, nil, false},
+		{"weekly on its day", weekly, ago(7 * 24 * time.Hour), true},
+		{"weekly sent two days ago", weekly, ago(48 * time.Hour), false},
+		{"weekly wrong day", EmailDigestSchedule{Frequency: EmailDigestWeekly, Hour: 8, Weekday: int(time.Monday)}, nil, false},
+	} {
+		if got := emailDigestDue(tc.s, tc.lastSent, now); got != tc.want {
+			t.Errorf("%s: due = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBuildEmailDigest(t *testing.T) {
+	since := time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC)
+	fresh := since.Add(2 * time.Hour).Format(time.RFC3339)
+	stale := since.Add(-2 * time.Hour).Format(time.RFC3339)
+
+	dash := &DashboardResponse{
+		Data: map[string]interface{}{
+			"finance": []interface{}{
+				map[string]interface{}{"symbol": "AAPL", "price": 232.1, "percentage_change": 1.2},
+				map[string]interface{}{"symbol": "TSLA", "price": 180.0, "percentage_change": -6.5},
+				map[string]interface{}{"symbol": "MSFT", "price": 410.0, "percentage_change": 0.0},
+			},
+			"sports": []interface{}{
+				map[string]interface{}{"league": "NFL", "away_team_name": "Bills", "away_team_score": "24", "home_team_name": "Jets", "home_team_score": "10", "state": "post", "start_time": fresh},
+				map[string]interface{}{"league": "NFL", "away_team_name": "Bears", "home_team_name": "Lions", "state": "in", "start_time": fresh},
+				map[string]interface{}{"league": "NBA", "away_team_name": "Celtics", "home_team_name": "Knicks", "state": "post", "start_time": fresh, "spoiler_hidden": true},
+				map[string]interface{}{"league": "NHL", "away_team_name": "Bruins", "home_team_name": "Rangers", "state": "post", "start_time": stale},
+			},
+			"rss": []interface{}{
+				map[string]interface{}{"title": "New", "link": "https://a.example/1", "source_name": "A", "published_at": fresh},
+				map[string]interface{}{"title": "Old", "link": "https://a.example/2", "source_name": "A", "published_at": stale},
+			},
+		},
+		Organizations: []OrgDashboard{{Data: map[string]interface{}{
+			"finance": []interface{}{
+				map[string]interface{}{"symbol": "AAPL", "price": 232.1, "percentage_change": 1.2},
+				map[string]interface{}{"symbol": "NVDA", "price": 120.0, "percentage_change": 3.1},
+			},
+		}}},
+	}
+
+	d := buildEmailDigest(dash, since)
+
+	var movers []string
+	for _, m := range d.Movers {
+		movers = append(movers, m.Symbol)
+	}
+	if got, want := strings.Join(movers, ","), "TSLA,NVDA,AAPL"; got != want {
+		t.Errorf("movers = %s, want %s", got, want)
+	}
+	if len(d.Games) != 1 || d.Games[0].AwayTeamName != "Bills" {
+		t.Errorf("games = %+v, want only Bills at Jets", d.Games)
+	}
+	if len(d.Headlines) != 1 || d.Headlines[0].Title != "New" {
+		t.Errorf("headlines = %+v, want only New", d.Headlines)
+	}
+
+	if !buildEmailDigest(&DashboardResponse{}, since).empty() {
+		t.Error("empty dashboard should give an empty digest")
+	}
+}
+
+func TestRenderEmailDigest(t *testing.T) {
+	d := emailDigest{
+		Movers:    []digestTrade{{Symbol: "TSLA", Price: 180, PercentageChange: -6.5}},
+		Headlines: []digestRSSItem{{Title: "<script>x</script>", Link: "https://a.example/1", SourceName: "A & B"}},
+	}
+	subject, body := renderEmailDigest(d, EmailDigestWeekly, "https://myscrollr.com/account")
+	if subject != "Your weekly MyScrollr digest" {
+		t.Errorf("subject = %q", subject)
+	}
+	for _, want := range []string{"Top movers", "TSLA", "-6.50%", "Headlines", "&lt;script&gt;", "A &amp; B", "https://myscrollr.com/account"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+	if strings.Contains(body, "Final scores") {
+		t.Error("body has an empty Final scores section")
+	}
+}
+
+func TestBuildSMTPMessage(t *testing.T) {
+	from := &mail.Address{Name: "MyScrollr", Address: "noreply@myscrollr.com"}
+	to := &mail.Address{Address: "user@example.com"}
+	msg := string(buildSMTPMessage(from, to, "Digest\r\nBcc: evil@example.com", "<p>hi</p>\n", time.Unix(0, 0).UTC()))
+
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Fatalf("subject injected a header:\n%s", msg)
+	}
+	if !strings.Contains(msg, "Content-Type: text/html; charset=UTF-8\r\n") {
+		t.Errorf("missing content type:\n%s", msg)
+	}
+	if !strings.HasSuffix(msg, "\r\n\r\n<p>hi</p>\r\n") {
+		t.Errorf("body not CRLF-terminated:\n%q", msg)
+	}
+}
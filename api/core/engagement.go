@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Ticker engagement.
+//
+// Clients report clicks on ticker items to POST /ticker/items/:id/click.
+// Clicks are deduped per user, item and UTC day in ticker_clicks. Every
+// dashboard build also adds the user to a per-channel, per-day Redis
+// HyperLogLog of viewers. A nightly pass rolls both into
+// channel_engagement_daily: clicks and unique clickers per channel and
+// per source (symbol, feed, league), and CTR as the share of that day's
+// viewers who clicked. Admins read the rollup at GET /admin/engagement.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// TickerClickRequest is the body for POST /ticker/items/:id/click.
+// Source is the item's origin within the channel — a symbol, feed URL or
+// league key — and is what per-source rankings group by.
+type TickerClickRequest struct {
+	ChannelType string `json:"channel_type"`
+	Source      string `json:"source"`
+}
+
+// ChannelEngagement is one row of GET /admin/engagement. ClickerDays and
+// ViewerDays sum each day's unique users, so CTR weights every day by
+// its audience.
+type ChannelEngagement struct {
+	ChannelType   string  `json:"channel_type"`
+	Source        string  `json:"source,omitempty"`
+	Clicks        int64   `json:"clicks"`
+	ViewerDays    int64   `json:"viewer_days"`
+	ClickerDays   int64   `json:"clicker_days"`
+	CTR           float64 `json:"ctr"`
+	FirstDay      string  `json:"first_day"`
+	LastDay       string  `json:"last_day"`
+	DaysCollected int     `json:"days_collected"`
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────
+
+func engagementDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func engagementViewersKey(channelType, day string) string {
+	return RedisEngagementViewersPrefix + channelType + ":" + day
+}
+
+// clickThroughRate is clickers/viewers, clamped to [0, 1]. Viewers come
+// from a HyperLogLog, so a small channel can estimate fewer viewers than
+// clickers.
+func clickThroughRate(clickers, viewers int64) float64 {
+	if viewers <= 0 || clickers <= 0 {
+		return 0
+	}
+	return min(float64(clickers)/float64(viewers), 1)
+}
+
+// recordChannelViewers notes that the user's ticker showed each enabled
+// channel today. Called from the dashboard build.
+func recordChannelViewers(ctx context.Context, userID string, enabled map[string]bool) {
+	if Rdb == nil || len(enabled) == 0 {
+		return
+	}
+	day := engagementDay(time.Now())
+	pipe := Rdb.Pipeline()
+	for channelType := range enabled {
+		key := engagementViewersKey(channelType, day)
+		pipe.PFAdd(ctx, key, userID)
+		pipe.Expire(ctx, key, EngagementViewersTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Engagement] record viewers for %s failed: %v", userID, err)
+	}
+}
+
+// ─── Aggregation ─────────────────────────────────────────────────
+
+// StartEngagementAggregator rolls up each finished UTC day once, shortly
+// after midnight, and prunes raw clicks older than EngagementClickRetentionDays.
+// Replicas coordinate through a per-day Redis marker.
+func StartEngagementAggregator(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(EngagementAggregateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				yesterday := engagementDay(time.Now().AddDate(0, 0, -1))
+				ok, err := Rdb.SetNX(ctx, RedisEngagementAggregatedPrefix+yesterday, "1", EngagementViewersTTL).Result()
+				if err != nil || !ok {
+					continue
+				}
+				if err := aggregateEngagementDay(ctx, yesterday); err != nil {
+					log.Printf("[Engagement] aggregate %s failed: %v", yesterday, err)
+					Rdb.Del(ctx, RedisEngagementAggregatedPrefix+yesterday)
+				}
+			}
+		}
+	}()
+	log.Printf("[Engagement] Aggregator started (%s interval)", EngagementAggregateInterval)
+}
+
+// aggregateEngagementDay writes channel-wide (empty source) and per-source
+// rows for one day. Rerunning a day overwrites its rows.
+func aggregateEngagementDay(ctx context.Context, day string) error {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO channel_engagement_daily (day, channel_type, source, clicks, unique_users)
+		SELECT click_date, channel_type, source, COUNT(*), COUNT(DISTINCT logto_sub)
+		  FROM ticker_clicks
+		 WHERE click_date = $1::date AND source != ''
+		 GROUP BY click_date, channel_type, source
+		UNION ALL
+		SELECT click_date, channel_type, '', COUNT(*), COUNT(DISTINCT logto_sub)
+		  FROM ticker_clicks
+		 WHERE click_date = $1::date
+		 GROUP BY click_date, channel_type
+		ON CONFLICT (day, channel_type, source) DO UPDATE SET
+			clicks       = EXCLUDED.clicks,
+			unique_users = EXCLUDED.unique_users`, day); err != nil {
+		return fmt.Errorf("rollup clicks: %w", err)
+	}
+
+	// Viewers are only known per channel, so per-source rows share the
+	// channel's denominator.
+	for channelType := range GetValidChannelTypes() {
+		viewers, err := Rdb.PFCount(ctx, engagementViewersKey(channelType, day)).Result()
+		if err != nil || viewers == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO channel_engagement_daily (day, channel_type, source, viewers)
+			VALUES ($1::date, $2, '', $3)
+			ON CONFLICT (day, channel_type, source) DO UPDATE SET viewers = EXCLUDED.viewers`,
+			day, channelType, viewers); err != nil {
+			return fmt.Errorf("record viewers for %s: %w", channelType, err)
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE channel_engagement_daily SET viewers = $3
+			 WHERE day = $1::date AND channel_type = $2 AND source != ''`,
+			day, channelType, viewers); err != nil {
+			return fmt.Errorf("share viewers for %s: %w", channelType, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM ticker_clicks WHERE click_date < $1::date - $2::int`,
+		day, EngagementClickRetentionDays); err != nil {
+		return fmt.Errorf("prune clicks: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("[Engagement] Aggregated %s", day)
+	return nil
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// HandleTickerItemClick records a click on a ticker item. Repeat clicks
+// by the same user on the same item within a UTC day count once.
+//
+// @Summary Record ticker item click
+// @Tags Engagement
+// @Accept json
+// @Produce json
+// @Param id path string true "Channel-specific item ID"
+// @Param body body TickerClickRequest true "Channel and source of the item"
+// @Success 202 {object} object{recorded=bool}
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /ticker/items/{id}/click [post]
+func HandleTickerItemClick(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	itemID := c.Params("id")
+	if itemID == "" || len(itemID) > EngagementMaxKeyLen {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid item id",
+		})
+	}
+
+	var req TickerClickRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	if !GetValidChannelTypes()[req.ChannelType] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Unknown channel_type",
+		})
+	}
+	source := strings.TrimSpace(req.Source)
+	if len(source) > EngagementMaxKeyLen {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("source must be at most %d characters", EngagementMaxKeyLen),
+		})
+	}
+
+	tag, err := DBPool.Exec(c.Context(), `
+		INSERT INTO ticker_clicks (logto_sub, channel_type, item_id, source, click_date)
+		VALUES ($1, $2, $3, $4, (now() AT TIME ZONE 'UTC')::date)
+		ON CONFLICT (logto_sub, channel_type, item_id, click_date) DO NOTHING`,
+		userID, req.ChannelType, itemID, source)
+	if err != nil {
+		log.Printf("[Engagement] record click for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to record click",
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"recorded": tag.RowsAffected() > 0})
+}
+
+// HandleAdminEngagement returns per-channel engagement over the last
+// ?days (default 7), or per-source rankings for ?channel_type.
+//
+// @Summary Channel engagement metrics (admin)
+// @Tags Admin
+// @Produce json
+// @Param days query int false "Days to include (default 7, max 90)"
+// @Param channel_type query string false "Rank sources within this channel"
+// @Success 200 {object} object{metrics=[]ChannelEngagement}
+// @Security LogtoAuth
+// @Router /admin/engagement [get]
+func HandleAdminEngagement(c *fiber.Ctx) error {
+	days := EngagementDefaultDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > EngagementClickRetentionDays {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error", Error: fmt.Sprintf("days must be 1-%d", EngagementClickRetentionDays),
+			})
+		}
+		days = n
+	}
+	channelType := c.Query("channel_type")
+
+	// Channel-wide rows when no channel is given; that channel's
+	// sources, busiest first, otherwise.
+	query := `
+		SELECT channel_type, source, SUM(clicks)::bigint, SUM(unique_users)::bigint, SUM(viewers)::bigint,
+		       MIN(day)::text, MAX(day)::text, COUNT(*)
+		  FROM channel_engagement_daily
+		 WHERE day > (now() AT TIME ZONE 'UTC')::date - $1::int
+		   AND source = ''
+		 GROUP BY channel_type, source
+		 ORDER BY SUM(clicks) DESC`
+	args := []any{days}
+	if channelType != "" {
+		query = `
+		SELECT channel_type, source, SUM(clicks)::bigint, SUM(unique_users)::bigint, SUM(viewers)::bigint,
+		       MIN(day)::text, MAX(day)::text, COUNT(*)
+		  FROM channel_engagement_daily
+		 WHERE day > (now() AT TIME ZONE 'UTC')::date - $1::int
+		   AND channel_type = $2 AND source != ''
+		 GROUP BY channel_type, source
+		 ORDER BY SUM(clicks) DESC
+		 LIMIT $3`
+		args = append(args, channelType, EngagementTopSources)
+	}
+
+	rows, err := DBPool.Query(c.Context(), query, args...)
+	if err != nil {
+		log.Printf("[Engagement] admin query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load engagement",
+		})
+	}
+	defer rows.Close()
+
+	metrics := make([]ChannelEngagement, 0)
+	for rows.Next() {
+		var m ChannelEngagement
+		if err := rows.Scan(&m.ChannelType, &m.Source, &m.Clicks, &m.ClickerDays, &m.ViewerDays,
+			&m.FirstDay, &m.LastDay, &m.DaysCollected); err != nil {
+			log.Printf("[Engagement] admin scan failed: %v", err)
+			continue
+		}
+		m.CTR = clickThroughRate(m.ClickerDays, m.ViewerDays)
+		metrics = append(metrics, m)
+	}
+	return c.JSON(fiber.Map{"metrics": metrics})
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClickThroughRate(t *testing.T) {
+	tests := []struct {
+		clickers, viewers int64
+		want              float64
+	}{
+		{0, 100, 0},
+		{25, 100, 0.25},
+		{5, 0, 0},
+		// HyperLogLog undercount on a tiny channel.
+		{3, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := clickThroughRate(tt.clickers, tt.viewers); got != tt.want {
+			t.Errorf("clickThroughRate(%d, %d) = %v, want %v", tt.clickers, tt.viewers, got, tt.want)
+		}
+	}
+}
+
+func TestEngagementDay_UTC(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*3600)
+	late := time.Date(2026, 3, 1, 22, 0, 0, 0, loc)
+	if got := engagementDay(late); got != "2026-03-02" {
+		t.Errorf("engagementDay = %q, want 2026-03-02", got)
+	}
+	if got := engagementViewersKey("finance", "2026-03-02"); got != "engagement:viewers:finance:2026-03-02" {
+		t.Errorf("engagementViewersKey = %q", got)
+	}
+}
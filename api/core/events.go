@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,10 +33,15 @@ func logDispatchDrop() {
 	log.Printf("[CDC] Dispatch queue full, dropped event (rate-limited log)")
 }
 
-// Client represents a single SSE connection tied to an authenticated user.
+// Client represents a single SSE or WebSocket connection tied to an
+// authenticated user.
 type Client struct {
 	UserID string
 	Ch     chan []byte
+
+	// Dropped counts events discarded because Ch was full. The WebSocket
+	// transport drains it to tell the client to resync.
+	Dropped atomic.Int64
 }
 
 // clientList wraps a []*Client slice so it can be stored in sync.Map.
@@ -53,6 +59,7 @@ func trySend(client *Client, payload []byte) bool {
 	case client.Ch <- payload:
 		return true
 	default:
+		client.Dropped.Add(1)
 		return false
 	}
 }
@@ -76,6 +83,9 @@ type Hub struct {
 
 	// Worker pool dispatch channel
 	dispatchCh chan dispatchJob
+
+	// Recent envelopes per topic for Last-Event-ID resume (replay.go)
+	replay *replayBuffer
 }
 
 var globalHub *Hub
@@ -85,6 +95,7 @@ func InitHub(ctx context.Context) {
 	globalHub = &Hub{
 		registry:   &topicRegistry{},
 		dispatchCh: make(chan dispatchJob, SSEDispatchQueueSize),
+		replay:     newReplayBuffer(currentEventSeq(ctx)),
 	}
 
 	// Start dispatch worker pool
@@ -93,6 +104,7 @@ func InitHub(ctx context.Context) {
 	}
 
 	go globalHub.listenToTopics(ctx)
+	go globalHub.replay.sweepLoop(ctx)
 
 	// Shutdown watcher
 	go func() {
@@ -136,14 +148,18 @@ func (h *Hub) listenToTopics(ctx context.Context) {
 		TopicPrefixRSS+"*",
 		TopicPrefixFantasy+"*",
 		TopicPrefixCore+"*",
+		TopicBroadcast,
+		TopicDisconnect,
+		TopicRefreshUserTopics,
 	)
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
 
-	log.Printf("[EventHub] Listening to topic patterns: %s* %s* %s* %s* %s*",
+	log.Printf("[EventHub] Listening to topic patterns: %s* %s* %s* %s* %s* %s %s %s",
 		TopicPrefixFinance, TopicPrefixSports, TopicPrefixRSS,
-		TopicPrefixFantasy, TopicPrefixCore)
+		TopicPrefixFantasy, TopicPrefixCore, TopicBroadcast, TopicDisconnect,
+		TopicRefreshUserTopics)
 
 	for {
 		select {
@@ -157,10 +173,30 @@ func (h *Hub) listenToTopics(ctx context.Context) {
 			topic := msg.Channel
 			payload := []byte(msg.Payload)
 
+			// Control message, not an event: never replayed.
+			if topic == TopicDisconnect {
+				h.disconnectUser(msg.Payload)
+				continue
+			}
+			if topic == TopicRefreshUserTopics {
+				h.refreshLocalUserTopics(msg.Payload)
+				continue
+			}
+
+			h.replay.record(topic, payload, time.Now())
+
+			// Broadcast: every connected client, no registry lookup.
+			if topic == TopicBroadcast {
+				h.broadcast(payload)
+				continue
+			}
+
 			// Special case: core user-specific topics (user_preferences, user_channels).
 			// These target a single user directly -- no registry lookup needed.
 			if strings.HasPrefix(topic, TopicPrefixCore) {
 				userID := topic[len(TopicPrefixCore):]
+				forgetSpoilerWindows(userID)
+				observeUserTopicEvent(userID, payload)
 				select {
 				case h.dispatchCh <- dispatchJob{userID: userID, payload: payload}:
 				default:
@@ -219,18 +255,37 @@ func (h *Hub) listenToTopics(ctx context.Context) {
 // All DELs are pipelined into a single Redis round-trip and executed on
 // a goroutine so this never blocks the dispatch hot path.
 func (h *Hub) dispatchToUser(userID string, payload []byte) {
-	go InvalidateUserCaches(userID)
+	BackgroundPool.SubmitOrRun("invalidate-user-caches", func(context.Context) {
+		InvalidateUserCaches(userID)
+	})
+	scheduleDashboardSnapshot(userID)
 
 	value, ok := h.clients.Load(userID)
 	if !ok {
 		return
 	}
+	payload, ok = userSpoilerFilter(context.Background(), userID).filterEventSpoilers(payload)
+	if !ok {
+		return
+	}
 	list := value.(*clientList)
 	for _, client := range list.entries {
 		trySend(client, payload)
 	}
 }
 
+// broadcast sends a payload to every connected client. Unlike
+// dispatchToUser it doesn't touch per-user caches — broadcasts carry
+// product-wide state, not user data.
+func (h *Hub) broadcast(payload []byte) {
+	h.clients.Range(func(_, value any) bool {
+		for _, client := range value.(*clientList).entries {
+			trySend(client, payload)
+		}
+		return true
+	})
+}
+
 // register adds an authenticated client to the hub.
 func (h *Hub) register(client *Client) {
 	for {
@@ -270,7 +325,6 @@ func (h *Hub) unregister(client *Client) {
 		for _, c := range old.entries {
 			if c == client {
 				found = true
-				close(c.Ch)
 			} else {
 				newEntries = append(newEntries, c)
 			}
@@ -291,6 +345,10 @@ func (h *Hub) unregister(client *Client) {
 		}
 		// CAS failed; retry
 	}
+	// Closed only after the CAS won, so a concurrent unregister of the
+	// same client (disconnectUser racing the handler's defer) can't
+	// close it twice.
+	close(client.Ch)
 	h.clientCount.Add(-1)
 
 	// Clean up topic subscriptions when the user's last connection closes
@@ -299,6 +357,19 @@ func (h *Hub) unregister(client *Client) {
 	}
 }
 
+// disconnectUser unregisters every connection of a user on this
+// replica. Closing Ch ends the SSE/WebSocket handler loops.
+func (h *Hub) disconnectUser(userID string) {
+	value, ok := h.clients.Load(userID)
+	if !ok {
+		return
+	}
+	for _, client := range value.(*clientList).entries {
+		h.unregister(client)
+	}
+	log.Printf("[EventHub] Disconnected all connections for %s", userID)
+}
+
 // --- Public API ---
 
 // RegisterClient adds an authenticated client to the hub and subscribes
@@ -310,9 +381,13 @@ func RegisterClient(userID string) *Client {
 	}
 	globalHub.register(client)
 
-	// Subscribe to topics on first connection for this user.
-	// If the user already has connections, this is a no-op (idempotent).
-	go subscribeUserToTopics(userID)
+	// Route events at once from the topics persisted last time
+	// (registry_store.go), then refresh them from the DB. If the user
+	// already has connections, both are no-ops.
+	restoreUserTopics(userID)
+	BackgroundPool.SubmitOrRun("subscribe-topics", func(context.Context) {
+		subscribeUserToTopics(userID)
+	})
 
 	return client
 }
@@ -322,7 +397,14 @@ func UnregisterClient(client *Client) {
 	globalHub.unregister(client)
 }
 
-// ClientCount returns the total number of connected SSE clients.
+// DisconnectUser closes every live connection of a user on all gateway
+// replicas.
+func DisconnectUser(userID string) error {
+	return PublishRaw(TopicDisconnect, []byte(userID))
+}
+
+// ClientCount returns the number of SSE clients connected to this
+// replica. TotalClientCount (sse_cluster.go) covers every replica.
 func ClientCount() int {
 	return int(globalHub.clientCount.Load())
 }
@@ -339,13 +421,16 @@ func UnsubscribeFromTopic(userID, topic string) {
 
 // UpdateUserTopicSubscriptions rebuilds a user's topic subscriptions.
 // Called from channel CRUD handlers when a user modifies their channels.
-// Only operates if the user has an active SSE connection.
+// The persisted topics are dropped and every replica holding one of the
+// user's connections re-reads them, re-persisting the result; with no
+// connections anywhere, the next one looks them up afresh.
 func UpdateUserTopicSubscriptions(userID string) {
-	if _, ok := globalHub.clients.Load(userID); !ok {
-		return // No active connection, nothing to update
+	ctx := context.Background()
+	forgetUserTopics(ctx, userID)
+	if err := PublishRaw(TopicRefreshUserTopics, []byte(userID)); err != nil {
+		// Other replicas miss it; at least keep this one current.
+		globalHub.refreshLocalUserTopics(userID)
 	}
-	globalHub.registry.unsubscribeAll(userID)
-	go subscribeUserToTopics(userID)
 }
 
 // RouteToRecordOwner sends a CDC event directly to the user identified in the record.
@@ -362,10 +447,12 @@ func RouteToRecordOwner(record map[string]interface{}, field string, payload []b
 
 // PublishToTopic publishes a CDC payload to a topic channel.
 // This is the Phase 3 replacement for SendToUsers.
-func PublishToTopic(topic string, payload []byte) {
+func PublishToTopic(topic string, payload []byte) error {
 	if err := PublishRaw(topic, payload); err != nil {
 		log.Printf("[EventHub] Failed to publish to topic %s: %v", topic, err)
+		return err
 	}
+	return nil
 }
 
 // TopicForRSSFeed returns the topic channel for an RSS feed URL.
@@ -377,57 +464,104 @@ func TopicForRSSFeed(feedURL string) string {
 	return fmt.Sprintf("%s%08x", TopicPrefixRSS, h.Sum32())
 }
 
-// subscribeUserToTopics reads the user's channel subscriptions from the DB
-// and registers them in the Hub's topic registry.
+// subscribeUserToTopics reads the user's channel subscriptions from the DB,
+// replaces their entry in the Hub's topic registry and persists it.
 func subscribeUserToTopics(userID string) {
-	ctx := context.Background()
-
 	// Core user-specific topics (user_preferences, user_channels) are handled
 	// by direct dispatch in listenToTopics -- no registry entry needed.
+	ctx := context.Background()
+	topics, err := loadUserTopics(ctx, userID)
+	if err != nil {
+		// Keep whatever was restored rather than routing nothing.
+		return
+	}
+	persistUserTopics(ctx, userID, topics)
+	// The user may have disconnected while the lookup ran.
+	if _, ok := globalHub.clients.Load(userID); ok {
+		globalHub.registry.setUserTopics(userID, topics)
+	}
+}
 
+// userTopics returns the topic channels a user's enabled channels map to,
+// including the shared channels of every organization they belong to.
+func userTopics(ctx context.Context, userID string) []string {
+	topics, _ := loadUserTopics(ctx, userID)
+	return topics
+}
+
+// loadUserTopics is userTopics, failing if the user's own channels can't
+// be read.
+func loadUserTopics(ctx context.Context, userID string) ([]string, error) {
 	channels, err := GetUserChannels(userID)
 	if err != nil {
 		log.Printf("[EventHub] Failed to load channels for %s: %v", userID, err)
-		return
+		return nil, err
 	}
 
+	var topics []string
 	for _, ch := range channels {
-		if !ch.Enabled {
+		if ch.Enabled {
+			topics = append(topics, channelTopics(ctx, userID, ch)...)
+		}
+	}
+
+	orgs, err := userOrganizations(ctx, userID)
+	if err != nil {
+		log.Printf("[EventHub] Failed to load organizations for %s: %v", userID, err)
+		return topics, nil
+	}
+	for _, org := range orgs {
+		shared, err := orgSharedChannels(org.ID)
+		if err != nil {
+			log.Printf("[EventHub] Failed to load channels for org %d: %v", org.ID, err)
 			continue
 		}
+		for _, ch := range shared {
+			topics = append(topics, channelTopics(ctx, orgChannelOwner(org.ID), ch)...)
+		}
+	}
 
-		switch ch.ChannelType {
-		case "finance":
-			symbols := extractSymbolsFromConfig(ch.Config)
-			for _, sym := range symbols {
-				globalHub.registry.subscribe(userID, TopicPrefixFinance+sym)
-			}
+	// An org and a member often follow the same symbol; replay reads each
+	// topic once.
+	slices.Sort(topics)
+	return slices.Compact(topics), nil
+}
 
-		case "sports":
-			// Subscribe only to the user's configured leagues.
-			// Config shape: {"leagues": ["NFL", "NBA", ...]}
-			leagues := extractLeaguesFromConfig(ch.Config)
-			for _, league := range leagues {
-				globalHub.registry.subscribe(userID, TopicPrefixSports+league)
-			}
+// channelTopics returns the topic channels one channel config maps to.
+func channelTopics(ctx context.Context, owner string, ch Channel) []string {
+	var topics []string
+	switch ch.ChannelType {
+	case "finance":
+		symbols := extractSymbolsFromConfig(ch.Config)
+		for _, sym := range symbols {
+			topics = append(topics, TopicPrefixFinance+sym)
+		}
 
-		case "rss":
-			feeds := extractFeedURLsFromConfig(ch.Config)
-			for _, feedURL := range feeds {
-				globalHub.registry.subscribe(userID, TopicForRSSFeed(feedURL))
-			}
+	case "sports":
+		// Subscribe only to the user's configured leagues.
+		// Config shape: {"leagues": ["NFL", "NBA", ...]}
+		leagues := extractLeaguesFromConfig(ch.Config)
+		for _, league := range leagues {
+			topics = append(topics, TopicPrefixSports+league)
+		}
 
-		case "fantasy":
-			leagueKeys, err := getUserFantasyLeagues(ctx, userID)
-			if err != nil {
-				log.Printf("[EventHub] Failed to load fantasy leagues for %s: %v", userID, err)
-				continue
-			}
-			for _, lk := range leagueKeys {
-				globalHub.registry.subscribe(userID, TopicPrefixFantasy+lk)
-			}
+	case "rss":
+		feeds := extractFeedURLsFromConfig(ch.Config)
+		for _, feedURL := range feeds {
+			topics = append(topics, TopicForRSSFeed(feedURL))
+		}
+
+	case "fantasy":
+		leagueKeys, err := getUserFantasyLeagues(ctx, owner)
+		if err != nil {
+			log.Printf("[EventHub] Failed to load fantasy leagues for %s: %v", owner, err)
+			return nil
+		}
+		for _, lk := range leagueKeys {
+			topics = append(topics, TopicPrefixFantasy+lk)
 		}
 	}
+	return topics
 }
 
 // extractSymbolsFromConfig reads the "symbols" array from a channel's config JSONB.
@@ -230,3 +230,38 @@ func TestInvalidateIdempotent(t *testing.T) {
 		t.Errorf("Redis ping failed after no-op invalidate: %v", err)
 	}
 }
+
+// TestHubDisconnectUser covers account deletion: every connection of
+// the deleted user closes, other users stay connected, and the
+// handler's deferred UnregisterClient afterwards is a harmless no-op.
+func TestHubDisconnectUser(t *testing.T) {
+	h := &Hub{registry: &topicRegistry{}}
+	a1 := &Client{UserID: "a", Ch: make(chan []byte, 1)}
+	a2 := &Client{UserID: "a", Ch: make(chan []byte, 1)}
+	b := &Client{UserID: "b", Ch: make(chan []byte, 1)}
+	for _, c := range []*Client{a1, a2, b} {
+		h.register(c)
+	}
+
+	h.disconnectUser("a")
+
+	for _, c := range []*Client{a1, a2} {
+		if _, ok := <-c.Ch; ok {
+			t.Error("deleted user's channel still open")
+		}
+	}
+	if _, ok := h.clients.Load("a"); ok {
+		t.Error("deleted user still registered")
+	}
+	if got := h.clientCount.Load(); got != 1 {
+		t.Errorf("clientCount = %d, want 1", got)
+	}
+
+	h.unregister(a1) // the stream handler's defer
+	if got := h.clientCount.Load(); got != 1 {
+		t.Errorf("clientCount after late unregister = %d, want 1", got)
+	}
+	if !trySend(b, []byte("x")) {
+		t.Error("other user's connection was closed")
+	}
+}
@@ -0,0 +1,547 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// ExperimentVariant is one arm of an experiment. Weight is relative to
+// the other variants (e.g. 1/1 is a 50/50 split, 3/1 is 75/25).
+type ExperimentVariant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Experiment is the admin-managed definition stored in `experiments`.
+type Experiment struct {
+	Key            string              `json:"key"`
+	Description    string              `json:"description"`
+	Status         string              `json:"status"`
+	TrafficPercent int                 `json:"traffic_percent"`
+	Variants       []ExperimentVariant `json:"variants"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// ExperimentWithExposures is the admin listing row: the definition plus
+// per-variant exposure counts so the dashboard can show arm sizes.
+type ExperimentWithExposures struct {
+	Experiment
+	Exposures map[string]int64 `json:"exposures"`
+}
+
+// ExperimentInput is the body for admin create/update.
+type ExperimentInput struct {
+	Key            string              `json:"key"`
+	Description    *string             `json:"description"`
+	Status         *string             `json:"status"`
+	TrafficPercent *int                `json:"traffic_percent"`
+	Variants       []ExperimentVariant `json:"variants"`
+}
+
+// ─── Bucketing ───────────────────────────────────────────────────
+
+// experimentBuckets is the resolution of the enrollment hash. 10000
+// gives 0.01% granularity, finer than traffic_percent needs, so a
+// later move to fractional percentages won't reshuffle anyone.
+const experimentBuckets = 10000
+
+var experimentKeyRegex = regexp.MustCompile(`^[a-z0-9_]{2,64}$`)
+
+var validExperimentStatuses = map[string]bool{
+	"draft":   true,
+	"running": true,
+	"stopped": true,
+}
+
+// experimentHash maps (salt, key, sub) to a uniform uint64. Enrollment
+// and variant selection use different salts so that raising
+// traffic_percent doesn't bias which variant the new users land in.
+func experimentHash(salt, key, sub string) uint64 {
+	sum := sha256.Sum256([]byte(salt + ":" + key + ":" + sub))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// assignExperimentVariant returns the variant a user is bucketed into,
+// or "" when the user falls outside the experiment's traffic allocation.
+// Pure and deterministic — the same inputs always give the same answer,
+// which is what lets us skip storing assignments.
+func assignExperimentVariant(exp Experiment, sub string) string {
+	if sub == "" || exp.TrafficPercent <= 0 || len(exp.Variants) == 0 {
+		return ""
+	}
+	bucket := experimentHash("enroll", exp.Key, sub) % experimentBuckets
+	if bucket >= uint64(exp.TrafficPercent)*experimentBuckets/100 {
+		return ""
+	}
+
+	total := 0
+	for _, v := range exp.Variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	pick := int(experimentHash("variant", exp.Key, sub) % uint64(total))
+	for _, v := range exp.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if pick < v.Weight {
+			return v.Name
+		}
+		pick -= v.Weight
+	}
+	return ""
+}
+
+// validateExperimentVariants checks the admin-supplied arm list.
+func validateExperimentVariants(variants []ExperimentVariant) error {
+	if len(variants) < 2 {
+		return fmt.Errorf("at least two variants are required")
+	}
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if !experimentKeyRegex.MatchString(v.Name) {
+			return fmt.Errorf("invalid variant name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variant name %q", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %q weight must be positive", v.Name)
+		}
+	}
+	return nil
+}
+
+// ─── Data Access ─────────────────────────────────────────────────
+
+const experimentColumns = `key, description, status, traffic_percent, variants, created_at, updated_at`
+
+func scanExperiment(row pgx.Row) (Experiment, error) {
+	var e Experiment
+	var variants []byte
+	if err := row.Scan(&e.Key, &e.Description, &e.Status, &e.TrafficPercent,
+		&variants, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return e, err
+	}
+	if err := json.Unmarshal(variants, &e.Variants); err != nil {
+		return e, fmt.Errorf("decode variants for %s: %w", e.Key, err)
+	}
+	return e, nil
+}
+
+func getExperiment(ctx context.Context, key string) (Experiment, error) {
+	return scanExperiment(DBPool.QueryRow(ctx,
+		`SELECT `+experimentColumns+` FROM experiments WHERE key = $1`, key))
+}
+
+func listRunningExperiments(ctx context.Context) ([]Experiment, error) {
+	rows, err := DBPool.Query(ctx,
+		`SELECT `+experimentColumns+` FROM experiments WHERE status = 'running' ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Experiment
+	for rows.Next() {
+		e, err := scanExperiment(rows)
+		if err != nil {
+			log.Printf("[Experiments] scan error: %v", err)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ─── User Handlers ───────────────────────────────────────────────
+
+// HandleGetMyExperiments returns the caller's variant for every running
+// experiment they're enrolled in, as a {key: variant} map. Experiments
+// the user is outside the traffic allocation for are omitted.
+//
+// @Summary Get experiment assignments
+// @Tags Experiments
+// @Produce json
+// @Success 200 {object} object{experiments=map[string]string}
+// @Security LogtoAuth
+// @Router /users/me/experiments [get]
+func HandleGetMyExperiments(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	exps, err := listRunningExperiments(c.Context())
+	if err != nil {
+		log.Printf("[Experiments] list running failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load experiments",
+		})
+	}
+
+	assignments := make(map[string]string, len(exps))
+	for _, e := range exps {
+		if v := assignExperimentVariant(e, userID); v != "" {
+			assignments[e.Key] = v
+		}
+	}
+
+	c.Set("Cache-Control", "private, no-store")
+	return c.JSON(fiber.Map{"experiments": assignments})
+}
+
+// HandleLogExperimentExposure records that the client rendered the
+// user's variant. The variant is recomputed server-side rather than
+// trusted from the body, and only the first exposure per user is kept.
+//
+// @Summary Log experiment exposure
+// @Tags Experiments
+// @Produce json
+// @Param key path string true "Experiment key"
+// @Success 200 {object} object{status=string,variant=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/experiments/{key}/exposure [post]
+func HandleLogExperimentExposure(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	key := c.Params("key")
+	exp, err := getExperiment(c.Context(), key)
+	if err == pgx.ErrNoRows || (err == nil && exp.Status != "running") {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Experiment not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Experiments] load %s failed: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to log exposure",
+		})
+	}
+
+	variant := assignExperimentVariant(exp, userID)
+	if variant == "" {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "User is not enrolled in this experiment",
+		})
+	}
+
+	if _, err := DBPool.Exec(c.Context(), `
+		INSERT INTO experiment_exposures (experiment_key, logto_sub, variant)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (experiment_key, logto_sub) DO NOTHING
+	`, exp.Key, userID, variant); err != nil {
+		log.Printf("[Experiments] exposure insert failed for %s: %v", exp.Key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to log exposure",
+		})
+	}
+
+	return c.JSON(fiber.Map{"status": "ok", "variant": variant})
+}
+
+// ─── Admin Handlers ──────────────────────────────────────────────
+
+// HandleAdminListExperiments returns every experiment with per-variant
+// exposure counts.
+//
+// @Summary List experiments (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{experiments=[]ExperimentWithExposures}
+// @Security LogtoAuth
+// @Router /admin/experiments [get]
+func HandleAdminListExperiments(c *fiber.Ctx) error {
+	ctx := c.Context()
+	rows, err := DBPool.Query(ctx,
+		`SELECT `+experimentColumns+` FROM experiments ORDER BY created_at DESC`)
+	if err != nil {
+		log.Printf("[Experiments] admin list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list experiments",
+		})
+	}
+	out := make([]ExperimentWithExposures, 0)
+	index := make(map[string]int)
+	for rows.Next() {
+		e, err := scanExperiment(rows)
+		if err != nil {
+			log.Printf("[Experiments] admin scan error: %v", err)
+			continue
+		}
+		index[e.Key] = len(out)
+		out = append(out, ExperimentWithExposures{Experiment: e, Exposures: map[string]int64{}})
+	}
+	rows.Close()
+
+	countRows, err := DBPool.Query(ctx, `
+		SELECT experiment_key, variant, COUNT(*)
+		FROM experiment_exposures
+		GROUP BY experiment_key, variant
+	`)
+	if err != nil {
+		// Definitions are still useful without counts.
+		log.Printf("[Experiments] exposure counts failed: %v", err)
+		return c.JSON(fiber.Map{"experiments": out})
+	}
+	defer countRows.Close()
+	for countRows.Next() {
+		var key, variant string
+		var n int64
+		if err := countRows.Scan(&key, &variant, &n); err != nil {
+			continue
+		}
+		if i, ok := index[key]; ok {
+			out[i].Exposures[variant] = n
+		}
+	}
+
+	return c.JSON(fiber.Map{"experiments": out})
+}
+
+// HandleAdminCreateExperiment creates an experiment in draft status.
+//
+// @Summary Create experiment (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body ExperimentInput true "Experiment"
+// @Success 201 {object} Experiment
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/experiments [post]
+func HandleAdminCreateExperiment(c *fiber.Ctx) error {
+	var in ExperimentInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	in.Key = strings.TrimSpace(in.Key)
+	if !experimentKeyRegex.MatchString(in.Key) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Key must be 2-64 characters of a-z, 0-9, _",
+		})
+	}
+	if err := validateExperimentVariants(in.Variants); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+	traffic := 0
+	if in.TrafficPercent != nil {
+		traffic = *in.TrafficPercent
+	}
+	if traffic < 0 || traffic > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "traffic_percent must be between 0 and 100",
+		})
+	}
+	description := ""
+	if in.Description != nil {
+		description = *in.Description
+	}
+
+	variantsJSON, _ := json.Marshal(in.Variants)
+	exp, err := scanExperiment(DBPool.QueryRow(c.Context(), `
+		INSERT INTO experiments (key, description, traffic_percent, variants)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING `+experimentColumns,
+		in.Key, description, traffic, variantsJSON))
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "An experiment with that key already exists",
+		})
+	}
+	if err != nil {
+		log.Printf("[Experiments] create %s failed: %v", in.Key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create experiment",
+		})
+	}
+
+	log.Printf("[Experiments] Created %s (%d variants, %d%% traffic)", exp.Key, len(exp.Variants), exp.TrafficPercent)
+	return c.Status(fiber.StatusCreated).JSON(exp)
+}
+
+// HandleAdminUpdateExperiment edits an experiment. Variants and traffic
+// allocation are frozen once the experiment has left draft — changing
+// either would silently move users between arms mid-measurement. To
+// re-run with a different split, stop it and create a new key.
+//
+// @Summary Update experiment (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Experiment key"
+// @Param body body ExperimentInput true "Fields to change"
+// @Success 200 {object} Experiment
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/experiments/{key} [put]
+func HandleAdminUpdateExperiment(c *fiber.Ctx) error {
+	key := c.Params("key")
+	var in ExperimentInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	ctx := c.Context()
+	exp, err := getExperiment(ctx, key)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Experiment not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Experiments] load %s failed: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update experiment",
+		})
+	}
+
+	if (in.Variants != nil || in.TrafficPercent != nil) && exp.Status != "draft" {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Variants and traffic allocation can only be changed while the experiment is a draft",
+		})
+	}
+	if in.Description != nil {
+		exp.Description = *in.Description
+	}
+	if in.Status != nil {
+		if !validExperimentStatuses[*in.Status] {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "status must be one of draft, running, stopped",
+			})
+		}
+		// Back to draft would let the allocation be edited after users
+		// have already been exposed under the old one.
+		if *in.Status == "draft" && exp.Status != "draft" {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "A started experiment cannot return to draft",
+			})
+		}
+		exp.Status = *in.Status
+	}
+	if in.TrafficPercent != nil {
+		if *in.TrafficPercent < 0 || *in.TrafficPercent > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "traffic_percent must be between 0 and 100",
+			})
+		}
+		exp.TrafficPercent = *in.TrafficPercent
+	}
+	if in.Variants != nil {
+		if err := validateExperimentVariants(in.Variants); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  err.Error(),
+			})
+		}
+		exp.Variants = in.Variants
+	}
+
+	variantsJSON, _ := json.Marshal(exp.Variants)
+	updated, err := scanExperiment(DBPool.QueryRow(ctx, `
+		UPDATE experiments
+		   SET description = $2, status = $3, traffic_percent = $4,
+		       variants = $5, updated_at = now()
+		 WHERE key = $1
+		RETURNING `+experimentColumns,
+		key, exp.Description, exp.Status, exp.TrafficPercent, variantsJSON))
+	if err != nil {
+		log.Printf("[Experiments] update %s failed: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update experiment",
+		})
+	}
+
+	log.Printf("[Experiments] Updated %s status=%s traffic=%d%%", updated.Key, updated.Status, updated.TrafficPercent)
+	return c.JSON(updated)
+}
+
+// HandleAdminDeleteExperiment removes an experiment and, via ON DELETE
+// CASCADE, its exposure log. Prefer stopping over deleting once any
+// results matter.
+//
+// @Summary Delete experiment (admin)
+// @Tags Admin
+// @Produce json
+// @Param key path string true "Experiment key"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/experiments/{key} [delete]
+func HandleAdminDeleteExperiment(c *fiber.Ctx) error {
+	key := c.Params("key")
+	tag, err := DBPool.Exec(c.Context(), `DELETE FROM experiments WHERE key = $1`, key)
+	if err != nil {
+		log.Printf("[Experiments] delete %s failed: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete experiment",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Experiment not found",
+		})
+	}
+	log.Printf("[Experiments] Deleted %s", key)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
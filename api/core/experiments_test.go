@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestAssignExperimentVariant_Deterministic(t *testing.T) {
+	exp := Experiment{
+		Key:            "ticker_composer",
+		TrafficPercent: 100,
+		Variants:       []ExperimentVariant{{"control", 1}, {"composer", 1}},
+	}
+	first := assignExperimentVariant(exp, "user-123")
+	if first == "" {
+		t.Fatal("100% traffic should always enroll")
+	}
+	for i := 0; i < 10; i++ {
+		if got := assignExperimentVariant(exp, "user-123"); got != first {
+			t.Fatalf("assignment changed between calls: %q then %q", first, got)
+		}
+	}
+}
+
+func TestAssignExperimentVariant_Edges(t *testing.T) {
+	base := Experiment{
+		Key:            "exp",
+		TrafficPercent: 100,
+		Variants:       []ExperimentVariant{{"a", 1}, {"b", 1}},
+	}
+	if got := assignExperimentVariant(base, ""); got != "" {
+		t.Errorf("empty sub: got %q, want \"\"", got)
+	}
+	zero := base
+	zero.TrafficPercent = 0
+	if got := assignExperimentVariant(zero, "u"); got != "" {
+		t.Errorf("0%% traffic: got %q, want \"\"", got)
+	}
+	noArms := base
+	noArms.Variants = nil
+	if got := assignExperimentVariant(noArms, "u"); got != "" {
+		t.Errorf("no variants: got %q, want \"\"", got)
+	}
+}
+
+// Over a large synthetic population the enrolled fraction and the
+// weighted split should land near their targets. Tolerances are loose;
+// this guards against an off-by-100 in the bucket math, not hash quality.
+func TestAssignExperimentVariant_Distribution(t *testing.T) {
+	exp := Experiment{
+		Key:            "split",
+		TrafficPercent: 40,
+		Variants:       []ExperimentVariant{{"control", 3}, {"treatment", 1}},
+	}
+	const n = 20000
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		counts[assignExperimentVariant(exp, fmt.Sprintf("user-%d", i))]++
+	}
+	enrolled := n - counts[""]
+	if frac := float64(enrolled) / n; math.Abs(frac-0.40) > 0.02 {
+		t.Errorf("enrolled fraction = %.3f, want ~0.40", frac)
+	}
+	if frac := float64(counts["control"]) / float64(enrolled); math.Abs(frac-0.75) > 0.03 {
+		t.Errorf("control share = %.3f, want ~0.75", frac)
+	}
+}
+
+// Raising traffic must only add users — nobody already enrolled should
+// drop out or switch arms.
+func TestAssignExperimentVariant_RampIsStable(t *testing.T) {
+	low := Experiment{Key: "ramp", TrafficPercent: 10, Variants: []ExperimentVariant{{"a", 1}, {"b", 1}}}
+	high := low
+	high.TrafficPercent = 50
+	for i := 0; i < 5000; i++ {
+		sub := fmt.Sprintf("u%d", i)
+		if v := assignExperimentVariant(low, sub); v != "" {
+			if got := assignExperimentVariant(high, sub); got != v {
+				t.Fatalf("%s: variant %q at 10%% became %q at 50%%", sub, v, got)
+			}
+		}
+	}
+}
+
+func TestValidateExperimentVariants(t *testing.T) {
+	cases := []struct {
+		name     string
+		variants []ExperimentVariant
+		wantErr  bool
+	}{
+		{"ok", []ExperimentVariant{{"control", 1}, {"treatment", 1}}, false},
+		{"single arm", []ExperimentVariant{{"control", 1}}, true},
+		{"duplicate", []ExperimentVariant{{"a", 1}, {"a", 2}}, true},
+		{"zero weight", []ExperimentVariant{{"a", 1}, {"b", 0}}, true},
+		{"bad name", []ExperimentVariant{{"A B", 1}, {"b", 1}}, true},
+	}
+	for _, c := range cases {
+		if err := validateExperimentVariants(c.variants); (err != nil) != c.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
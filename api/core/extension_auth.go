@@ -3,7 +3,6 @@ package core
 import (
 	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -69,7 +68,7 @@ func setCORSHeaders(c *fiber.Ctx) {
 // @Tags Extension Auth
 // @Accept json
 // @Produce json
-// @Param body body object true "Token exchange request" example({"code":"abc","redirect_uri":"https://...","code_verifier":"..."})
+// @Param body body object true "Token exchange request; device_label is optional and names the session" example({"code":"abc","redirect_uri":"https://...","code_verifier":"...","device_label":"Work laptop"})
 // @Success 200 {object} object "Token response from Logto"
 // @Failure 400 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
@@ -90,6 +89,7 @@ func HandleExtensionTokenExchange(c *fiber.Ctx) error {
 		Code         string `json:"code"`
 		RedirectURI  string `json:"redirect_uri"`
 		CodeVerifier string `json:"code_verifier"`
+		DeviceLabel  string `json:"device_label"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
@@ -114,7 +114,9 @@ func HandleExtensionTokenExchange(c *fiber.Ctx) error {
 		"resource":      {getAPIResource()},
 	}
 
-	return proxyLogtoToken(c, formData)
+	return proxyLogtoToken(c, formData, func(body []byte) {
+		startExtensionSession(c, body, req.DeviceLabel)
+	})
 }
 
 // HandleExtensionTokenRefresh proxies a refresh_token grant to Logto
@@ -159,6 +161,18 @@ func HandleExtensionTokenRefresh(c *fiber.Ctx) error {
 		})
 	}
 
+	// Revoked sessions get the same error Logto gives for a revoked
+	// token, so clients sign out as they already do. A lookup failure
+	// lets the refresh through rather than signing everyone out.
+	if revoked, err := extensionSessionRevoked(c.UserContext(), req.RefreshToken); err != nil {
+		log.Printf("[ExtAuth] Session lookup failed: %v", err)
+	} else if revoked {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":             "invalid_grant",
+			"error_description": "session has been revoked",
+		})
+	}
+
 	formData := url.Values{
 		"grant_type":    {"refresh_token"},
 		"client_id":     {extensionAppID},
@@ -166,7 +180,9 @@ func HandleExtensionTokenRefresh(c *fiber.Ctx) error {
 		"resource":      {getAPIResource()},
 	}
 
-	return proxyLogtoToken(c, formData)
+	return proxyLogtoToken(c, formData, func(body []byte) {
+		rotateExtensionSession(c, req.RefreshToken, body)
+	})
 }
 
 // HandleExtensionAuthPreflight handles OPTIONS requests for extension auth endpoints.
@@ -176,8 +192,9 @@ func HandleExtensionAuthPreflight(c *fiber.Ctx) error {
 }
 
 // proxyLogtoToken forwards a form-encoded token request to the Logto OIDC
-// token endpoint and streams the response back to the caller.
-func proxyLogtoToken(c *fiber.Ctx, formData url.Values) error {
+// token endpoint and streams the response back to the caller. onSuccess
+// sees the body of a 200 before it's sent (session tracking).
+func proxyLogtoToken(c *fiber.Ctx, formData url.Values, onSuccess func(body []byte)) error {
 	tokenURL := getLogtoTokenURL()
 	if tokenURL == "" || tokenURL == "/token" {
 		log.Println("[ExtAuth] Cannot derive Logto token URL")
@@ -187,8 +204,7 @@ func proxyLogtoToken(c *fiber.Ctx, formData url.Values) error {
 		})
 	}
 
-	httpClient := &http.Client{Timeout: LogtoProxyTimeout}
-	resp, err := httpClient.PostForm(tokenURL, formData)
+	resp, err := logtoProxyHTTPClient.PostForm(tokenURL, formData)
 	if err != nil {
 		log.Printf("[ExtAuth] Logto request failed: %v", err)
 		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
@@ -207,6 +223,10 @@ func proxyLogtoToken(c *fiber.Ctx, formData url.Values) error {
 		})
 	}
 
+	if resp.StatusCode == fiber.StatusOK && onSuccess != nil {
+		onSuccess(body)
+	}
+
 	c.Set("Content-Type", "application/json")
 	return c.Status(resp.StatusCode).Send(body)
 }
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Extension sessions.
+//
+// The desktop app and browser extension get their tokens through the
+// /extension/token proxy, so the gateway sees every refresh token Logto
+// hands them. Each code exchange starts a session keyed by the hash of
+// its current refresh token; each refresh rotates the hash and bumps
+// last_used_at. Users list their sessions at GET /users/me/sessions and
+// revoke one with DELETE /users/me/sessions/:id, after which the proxy
+// refuses that session's refresh token. An access token already issued
+// keeps working until it expires (Logto's access token TTL).
+
+// ExtensionSession is one row of GET /users/me/sessions.
+type ExtensionSession struct {
+	ID          string    `json:"id"`
+	DeviceLabel string    `json:"device_label"`
+	IP          *string   `json:"ip,omitempty"`
+	UserAgent   *string   `json:"user_agent,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
+
+// Audit actions for sessions.
+const (
+	AuditSessionCreated = "session.created"
+	AuditSessionRevoked = "session.revoked"
+)
+
+// logtoTokenResponse is the part of Logto's token response the session
+// tracking needs.
+type logtoTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// deviceLabel picks the label shown for a session: the client's own
+// label if it sent one, else a "Browser on OS" guess from the User-Agent.
+func deviceLabel(requested, userAgent string) string {
+	if label := strings.TrimSpace(requested); label != "" {
+		if runes := []rune(label); len(runes) > ExtensionSessionLabelMax {
+			label = string(runes[:ExtensionSessionLabelMax])
+		}
+		return label
+	}
+
+	ua := userAgent
+	client := ""
+	switch {
+	case strings.Contains(ua, "Tauri") || strings.Contains(ua, "myscrollr-desktop"):
+		client = "Scrollr desktop"
+	case strings.Contains(ua, "Edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "Firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		client = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		client = "Safari"
+	}
+	platform := ""
+	switch {
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "CrOS"):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "Mac OS X") || strings.Contains(ua, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case client != "" && platform != "":
+		return client + " on " + platform
+	case client != "":
+		return client
+	case platform != "":
+		return "Unknown client on " + platform
+	}
+	return "Unknown device"
+}
+
+// startExtensionSession records the session a successful code exchange
+// opened. Best-effort: the tokens have already been issued.
+func startExtensionSession(c *fiber.Ctx, body []byte, requestedLabel string) {
+	var tok logtoTokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil || tok.RefreshToken == "" {
+		return
+	}
+	sub, _, err := ValidateToken(tok.AccessToken)
+	if err != nil {
+		log.Printf("[ExtSessions] Exchange returned an unreadable access token: %v", err)
+		return
+	}
+
+	label := deviceLabel(requestedLabel, c.Get(fiber.HeaderUserAgent))
+	if err := insertExtensionSession(c.UserContext(), sub, tok.RefreshToken, label, c.IP(), c.Get(fiber.HeaderUserAgent)); err != nil {
+		log.Printf("[ExtSessions] Failed to record session for %s: %v", sub, err)
+		return
+	}
+	Audit(c.UserContext(), sub, AuditSessionCreated, sub, map[string]any{"device": label, "ip": c.IP()})
+}
+
+func insertExtensionSession(ctx context.Context, sub, refreshToken, label, ip, userAgent string) error {
+	_, err := DBPool.Exec(ctx, `
+		INSERT INTO extension_sessions (id, logto_sub, refresh_token_hash, device_label, ip, user_agent)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		ON CONFLICT (refresh_token_hash) DO NOTHING`,
+		uuid.NewString(), sub, hashAPIKey(refreshToken), label, ip, userAgent)
+	return err
+}
+
+// extensionSessionRevoked reports whether the refresh token belongs to a
+// revoked session. Unknown tokens (issued before sessions were tracked)
+// are not revoked; they're adopted on their next successful refresh.
+// Revoked rows outlive the tokens they name (the pruner keeps them for
+// ExtensionSessionRevokedRetention), so a revoked token is never unknown.
+func extensionSessionRevoked(ctx context.Context, refreshToken string) (bool, error) {
+	var revokedAt *time.Time
+	err := DBPool.QueryRow(ctx,
+		`SELECT revoked_at FROM extension_sessions WHERE refresh_token_hash = $1`,
+		hashAPIKey(refreshToken),
+	).Scan(&revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revokedAt != nil, nil
+}
+
+// rotateExtensionSession moves a session onto the refresh token Logto
+// just issued, or adopts an untracked token as a new session. A refresh
+// that raced a revoke (checked before the revoke, rotated after it)
+// carries the revoked row onto the new token, so that token is refused
+// too instead of being adopted.
+func rotateExtensionSession(c *fiber.Ctx, oldRefreshToken string, body []byte) {
+	var tok logtoTokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return
+	}
+	newRefresh := tok.RefreshToken
+	if newRefresh == "" {
+		newRefresh = oldRefreshToken // rotation disabled in Logto
+	}
+
+	ctx := c.UserContext()
+	tag, err := DBPool.Exec(ctx, `
+		UPDATE extension_sessions
+		   SET refresh_token_hash = $2, last_used_at = now(), ip = COALESCE(NULLIF($3, ''), ip)
+		 WHERE refresh_token_hash = $1 AND revoked_at IS NULL`,
+		hashAPIKey(oldRefreshToken), hashAPIKey(newRefresh), c.IP())
+	if err != nil {
+		log.Printf("[ExtSessions] Failed to rotate session: %v", err)
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		return
+	}
+
+	tag, err = DBPool.Exec(ctx, `
+		UPDATE extension_sessions SET refresh_token_hash = $2
+		 WHERE refresh_token_hash = $1 AND revoked_at IS NOT NULL`,
+		hashAPIKey(oldRefreshToken), hashAPIKey(newRefresh))
+	if err != nil {
+		log.Printf("[ExtSessions] Failed to check for a revoked session: %v", err)
+		return
+	}
+	if tag.RowsAffected() > 0 {
+		log.Printf("[ExtSessions] Refresh raced a revoke; the new token stays revoked")
+		return
+	}
+
+	sub, _, err := ValidateToken(tok.AccessToken)
+	if err != nil {
+		return
+	}
+	ua := c.Get(fiber.HeaderUserAgent)
+	if err := insertExtensionSession(ctx, sub, newRefresh, deviceLabel("", ua), c.IP(), ua); err != nil {
+		log.Printf("[ExtSessions] Failed to adopt session for %s: %v", sub, err)
+	}
+}
+
+// HandleListSessions lists the caller's active extension/desktop
+// sessions, most recently used first.
+//
+// @Summary List sessions
+// @Description Desktop app and browser extension sessions that can still refresh their tokens
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{sessions=[]ExtensionSession}
+// @Security LogtoAuth
+// @Router /users/me/sessions [get]
+func HandleListSessions(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT id::text, device_label, ip, user_agent, created_at, last_used_at
+		  FROM extension_sessions
+		 WHERE logto_sub = $1 AND revoked_at IS NULL AND last_used_at > $2
+		 ORDER BY last_used_at DESC`,
+		userID, time.Now().Add(-ExtensionSessionIdleExpiry))
+	if err != nil {
+		log.Printf("[ExtSessions] list for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load sessions",
+		})
+	}
+	defer rows.Close()
+
+	sessions := make([]ExtensionSession, 0)
+	for rows.Next() {
+		var s ExtensionSession
+		if err := rows.Scan(&s.ID, &s.DeviceLabel, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastUsedAt); err != nil {
+			log.Printf("[ExtSessions] scan failed: %v", err)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return c.JSON(fiber.Map{"sessions": sessions})
+}
+
+// HandleRevokeSession revokes one of the caller's sessions. Its refresh
+// token stops working immediately; its current access token lapses at
+// expiry.
+//
+// @Summary Revoke a session
+// @Tags Users
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/sessions/{id} [delete]
+func HandleRevokeSession(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	id := c.Params("id")
+	if _, err := uuid.Parse(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found", Error: "Session not found",
+		})
+	}
+
+	var label string
+	err := DBPool.QueryRow(c.Context(), `
+		UPDATE extension_sessions SET revoked_at = now()
+		 WHERE id = $1 AND logto_sub = $2 AND revoked_at IS NULL
+		 RETURNING device_label`, id, userID,
+	).Scan(&label)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found", Error: "Session not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[ExtSessions] revoke %s for %s failed: %v", id, userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to revoke session",
+		})
+	}
+
+	log.Printf("[ExtSessions] %s revoked session %s (%s)", userID, id, label)
+	AuditSelf(c, AuditSessionRevoked, map[string]any{"session_id": id, "device": label})
+	return c.JSON(fiber.Map{"status": "revoked"})
+}
+
+// StartExtensionSessionPruner deletes, once a day, live sessions idle
+// for more than ExtensionSessionIdleExpiry and revoked sessions older
+// than ExtensionSessionRevokedRetention.
+func StartExtensionSessionPruner(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ExtensionSessionPruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now()
+				if _, err := DBPool.Exec(ctx, `
+					DELETE FROM extension_sessions
+					 WHERE (revoked_at IS NULL AND last_used_at < $1)
+					    OR revoked_at < $2`,
+					now.Add(-ExtensionSessionIdleExpiry), now.Add(-ExtensionSessionRevokedRetention)); err != nil {
+					log.Printf("[ExtSessions] prune failed: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestDeviceLabel(t *testing.T) {
+	cases := []struct {
+		requested, ua, want string
+	}{
+		{"  Work laptop ", "Mozilla/5.0 (Windows NT 10.0) Chrome/126.0", "Work laptop"},
+		{"", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/126.0 Safari/537.36", "Chrome on Windows"},
+		{"", "Mozilla/5.0 (Windows NT 10.0) Chrome/126.0 Safari/537.36 Edg/126.0", "Edge on Windows"},
+		{"", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5; rv:127.0) Gecko/20100101 Firefox/127.0", "Firefox on macOS"},
+		{"", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/605.1.15 Tauri/2.0", "Scrollr desktop on Linux"},
+		{"", "curl/8.5.0", "Unknown device"},
+	}
+	for _, tc := range cases {
+		if got := deviceLabel(tc.requested, tc.ua); got != tc.want {
+			t.Errorf("deviceLabel(%q, %q) = %q, want %q", tc.requested, tc.ua, got, tc.want)
+		}
+	}
+
+	if got := deviceLabel(strings.Repeat("x", ExtensionSessionLabelMax+10), ""); len(got) != ExtensionSessionLabelMax {
+		t.Errorf("deviceLabel length = %d, want %d", len(got), ExtensionSessionLabelMax)
+	}
+
+	// Truncation counts characters and never splits one.
+	got := deviceLabel(strings.Repeat("é", ExtensionSessionLabelMax+10), "")
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != ExtensionSessionLabelMax {
+		t.Errorf("deviceLabel = %q, want %d whole characters", got, ExtensionSessionLabelMax)
+	}
+}
+
+func TestRotateExtensionSession_RevokedTokenIsNotAdopted(t *testing.T) {
+	if !testDBAvailable(t) {
+		return
+	}
+	userID := makeTestUser()
+	ctx := context.Background()
+	defer DBPool.Exec(ctx, `DELETE FROM extension_sessions WHERE logto_sub = $1`, userID)
+
+	oldToken, newToken := "rt-old-"+userID, "rt-new-"+userID
+	mustExec(t, `
+		INSERT INTO extension_sessions (id, logto_sub, refresh_token_hash, device_label, revoked_at)
+		VALUES ($1, $2, $3, 'Laptop', now())`, uuid.NewString(), userID, hashAPIKey(oldToken))
+
+	// The refresh passed the revoked check just before the revoke landed.
+	app := fiber.New()
+	app.Post("/refresh", func(c *fiber.Ctx) error {
+		rotateExtensionSession(c, oldToken, []byte(`{"access_token":"x","refresh_token":"`+newToken+`"}`))
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("POST", "/refresh", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := extensionSessionRevoked(ctx, newToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revoked {
+		t.Error("the token issued by a refresh that raced a revoke was not refused")
+	}
+}
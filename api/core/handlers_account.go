@@ -245,8 +245,7 @@ func updateUserName(endpoint, token, userID, name string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("name request failed: %w", err)
 	}
@@ -274,8 +273,7 @@ func updateUserEmail(endpoint, token, userID, email string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("email request failed: %w", err)
 	}
@@ -354,7 +352,7 @@ func HandleRequestPasswordReset(c *fiber.Ctx) error {
 // the only required setting — the From address falls back to a sane
 // default if unset.
 func sendPasswordResetEmail(toEmail, signInURL string) error {
-	apiKey := os.Getenv("RESEND_API_KEY")
+	apiKey := Secret("RESEND_API_KEY")
 	if apiKey == "" {
 		return fmt.Errorf("RESEND_API_KEY not configured")
 	}
@@ -398,8 +396,7 @@ func sendPasswordResetEmail(toEmail, signInURL string) error {
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := emailHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("resend request failed: %w", err)
 	}
@@ -296,7 +296,7 @@ func sendBusinessLeadNotification(
 	useCaseLabel string,
 	ipRedacted string,
 ) error {
-	apiKey := strings.TrimSpace(os.Getenv("RESEND_API_KEY"))
+	apiKey := strings.TrimSpace(Secret("RESEND_API_KEY"))
 	if apiKey == "" {
 		return fmt.Errorf("RESEND_API_KEY not configured")
 	}
@@ -359,7 +359,7 @@ func sendBusinessLeadAutoReply(
 	req BusinessLeadRequest,
 	useCaseLabel string,
 ) error {
-	apiKey := strings.TrimSpace(os.Getenv("RESEND_API_KEY"))
+	apiKey := strings.TrimSpace(Secret("RESEND_API_KEY"))
 	if apiKey == "" {
 		return fmt.Errorf("RESEND_API_KEY not configured")
 	}
@@ -451,8 +451,7 @@ func postToResend(ctx context.Context, apiKey string, payload map[string]any) er
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := emailHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("resend request: %w", err)
 	}
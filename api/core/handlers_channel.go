@@ -2,6 +2,7 @@ package core
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -11,24 +12,11 @@ import (
 	"github.com/valyala/fasthttp"
 )
 
-// GetActiveViewers returns the count of connected SSE clients.
-func GetActiveViewers(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"count": ClientCount()})
-}
-
-// StreamEvents handles authenticated Server-Sent Events (SSE).
-// Accepts token via Authorization: Bearer header (preferred) or
-// ?token= query parameter (fallback for browser EventSource).
-//
-// @Summary Real-time event stream (authenticated)
-// @Description Server-Sent Events endpoint for per-user CDC updates
-// @Tags Events
-// @Produce text/event-stream
-// @Param token query string false "JWT access token (fallback if no Authorization header)"
-// @Param Authorization header string false "Bearer token (preferred)"
-// @Router /events [get]
-func StreamEvents(c *fiber.Ctx) error {
-	// 1. Extract token — prefer Authorization header, fall back to query param
+// authorizeEventStream authenticates a real-time stream request (/events,
+// /ws). The token comes from the Authorization header or, for browser
+// EventSource and WebSocket which can't set headers, ?token=. When ok is
+// false an error response has been written and err should be returned.
+func authorizeEventStream(c *fiber.Ctx) (userID string, ok bool, err error) {
 	tokenString := ""
 	if authHeader := c.Get("Authorization"); authHeader != "" {
 		parts := strings.Split(authHeader, " ")
@@ -40,23 +28,21 @@ func StreamEvents(c *fiber.Ctx) error {
 		tokenString = c.Query("token")
 	}
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+		return "", false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Status: "unauthorized",
 			Error:  "Missing token parameter",
 		})
 	}
 
-	// 2. Validate JWT and get user ID
 	userID, claims, err := ValidateToken(tokenString)
 	if err != nil {
 		log.Printf("[SSE] Auth failed: %v", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+		return "", false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Status: "unauthorized",
 			Error:  "Invalid or expired token",
 		})
 	}
 
-	// 2b. Extract roles and enforce Uplink Ultimate requirement
 	var roles []string
 	if rawRoles, ok := claims["roles"]; ok {
 		if roleSlice, ok := rawRoles.([]interface{}); ok {
@@ -69,11 +55,40 @@ func StreamEvents(c *fiber.Ctx) error {
 	}
 	tier := tierFromRoles(roles)
 	if tier != "uplink_ultimate" && tier != "super_user" {
-		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+		return "", false, c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
 			Status: "forbidden",
-			Error:  "SSE requires an Uplink Ultimate subscription",
+			Error:  "Real-time updates require an Uplink Ultimate subscription",
 		})
 	}
+	return userID, true, nil
+}
+
+// GetActiveViewers returns the count of connected SSE clients across
+// every gateway replica.
+func GetActiveViewers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"count": TotalClientCount(c.Context())})
+}
+
+// StreamEvents handles authenticated Server-Sent Events (SSE).
+// Accepts token via Authorization: Bearer header (preferred) or
+// ?token= query parameter (fallback for browser EventSource).
+//
+// @Summary Real-time event stream (authenticated)
+// @Description Server-Sent Events endpoint for per-user CDC updates
+// @Tags Events
+// @Produce text/event-stream
+// @Param token query string false "JWT access token (fallback if no Authorization header)"
+// @Param Authorization header string false "Bearer token (preferred)"
+// @Param client_version query string false "Client build version (fallback if no X-Client-Version header)"
+// @Param Last-Event-ID header string false "Seq of the last event received; missed events are replayed"
+// @Param last_event_id query string false "Resume cursor (fallback if no Last-Event-ID header)"
+// @Router /events [get]
+func StreamEvents(c *fiber.Ctx) error {
+	// 1-2. Authenticate and enforce the Uplink Ultimate requirement
+	userID, ok, err := authorizeEventStream(c)
+	if !ok {
+		return err
+	}
 
 	// 3. Set headers for SSE
 	c.Set("Content-Type", "text/event-stream")
@@ -81,10 +96,28 @@ func StreamEvents(c *fiber.Ctx) error {
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
-	// 4. Register this authenticated client
+	// 3b. Retired builds get a single client-upgrade-required event
+	// instead of a stream. EventSource surfaces a 426 only as a generic
+	// error, so the event is the only way to tell it why.
+	clientVersion := c.Get(ClientVersionHeader)
+	if clientVersion == "" {
+		clientVersion = c.Query("client_version")
+	}
+	if min := getMinClientVersion(c.Context()); clientBelowMinimum(clientVersion, min) {
+		log.Printf("[SSE] Rejected outdated client: user=%s version=%s min=%s", userID, clientVersion, min)
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			writeClientUpgradeEvent(w, clientVersion, min)
+		}))
+		return nil
+	}
+
+	// 4. Register this authenticated client. Registering before the replay
+	// is read means nothing published in between is lost; the cursor
+	// filters out anything that arrives both ways.
 	client := RegisterClient(userID)
+	lastSeq := lastEventID(c)
 
-	log.Printf("[SSE] Client connected: user=%s ip=%s", userID, c.IP())
+	log.Printf("[SSE] Client connected: user=%s ip=%s last_event_id=%d", userID, c.IP(), lastSeq)
 
 	// 5. Stream events to the client
 	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
@@ -96,18 +129,45 @@ func StreamEvents(c *fiber.Ctx) error {
 		fmt.Fprintf(w, "retry: %d\n\n", SSERetryIntervalMs)
 		w.Flush()
 
+		// Resume from Last-Event-ID, or tell the client it can't.
+		var cursor *replayCursor
+		if lastSeq > 0 {
+			events, complete := replayEvents(userID, lastSeq)
+			for _, e := range events {
+				writeSSEData(w, e.seq, e.payload)
+			}
+			if !complete {
+				fmt.Fprintf(w, "event: resync\ndata: {\"reason\":%q}\n\n", replayResyncReason)
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+			cursor = newReplayCursor(events)
+		}
+
 		for {
 			select {
 			case msg, ok := <-client.Ch:
 				if !ok {
 					return
 				}
-				fmt.Fprintf(w, "data: %s\n\n", msg)
+				seq, serverTS := envelopeHeader(msg)
+				if cursor.duplicate(seq) {
+					continue
+				}
+				writeSSEData(w, seq, msg)
 				if err := w.Flush(); err != nil {
 					return // Client disconnected
 				}
+				recordDeliveryLag(serverTS, time.Now())
 
 			case <-ticker.C:
+				// A minimum raised mid-stream takes effect at the next
+				// heartbeat rather than on the client's next reconnect.
+				if min := getMinClientVersion(context.Background()); clientBelowMinimum(clientVersion, min) {
+					writeClientUpgradeEvent(w, clientVersion, min)
+					return
+				}
 				// Heartbeat to keep connection alive
 				fmt.Fprintf(w, ": ping\n\n")
 				if err := w.Flush(); err != nil {
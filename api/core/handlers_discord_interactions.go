@@ -227,8 +227,8 @@ func handleDiscordSendAction(c *fiber.Ctx, ix *discordInteraction, draftID int64
 	// On send success: flip thread to "sent" tag, prefix [SENT] in name.
 	// If close-flag also set: append "closed" tag, archive thread.
 	// On send failure: leave thread in "pending" so partner can retry.
-	go func() {
-		bgCtx, bgCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ExternalPool.Submit("send-approved-reply", func(ctx context.Context) {
+		bgCtx, bgCancel := context.WithTimeout(ctx, 30*time.Second)
 		defer bgCancel()
 		if err := sendApprovedReply(bgCtx, draft, draft.DraftBodyHTML); err != nil {
 			log.Printf("[DiscordInteraction] sendApprovedReply for ticket %s: %v",
@@ -236,7 +236,7 @@ func handleDiscordSendAction(c *fiber.Ctx, ix *discordInteraction, draftID int64
 			return
 		}
 		applySendStateToThread(bgCtx, draft, draft.ShouldClose)
-	}()
+	})
 
 	// Render a richer confirmation than just "✅ Sent" — give the
 	// partner the recipient + ticket-number + auto-close indicator
@@ -425,11 +425,11 @@ func handleDiscordSkipAction(c *fiber.Ctx, ix *discordInteraction, draftID int64
 	}
 
 	// Update thread cosmetics fire-and-forget.
-	go func() {
-		bgCtx, bgCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ExternalPool.Submit("apply-skip-state", func(ctx context.Context) {
+		bgCtx, bgCancel := context.WithTimeout(ctx, 10*time.Second)
 		defer bgCancel()
 		applySkipStateToThread(bgCtx, draft)
-	}()
+	})
 
 	return discordVisibleResponse(c,
 		fmt.Sprintf("⏭️ Skipped — ticket #%s left without an AI reply.", draft.TicketNumber))
@@ -494,8 +494,8 @@ func handleDiscordModalSubmit(c *fiber.Ctx, ix *discordInteraction) error {
 	}
 	draft, _ = loadSupportDraft(ctx, draftID)
 
-	go func() {
-		bgCtx, bgCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ExternalPool.Submit("send-edited-reply", func(ctx context.Context) {
+		bgCtx, bgCancel := context.WithTimeout(ctx, 30*time.Second)
 		defer bgCancel()
 		if err := sendApprovedReply(bgCtx, draft, editedBodyHTML); err != nil {
 			log.Printf("[DiscordInteraction] sendApprovedReply (edited) for ticket %s: %v",
@@ -506,7 +506,7 @@ func handleDiscordModalSubmit(c *fiber.Ctx, ix *discordInteraction) error {
 		// transitions as plain Send. Tag flips to "edited" instead of
 		// "sent" so we can distinguish them in /stats.
 		applyEditStateToThread(bgCtx, draft, draft.ShouldClose)
-	}()
+	})
 
 	confirmation := buildEditConfirmation(draft)
 	return discordVisibleResponse(c, confirmation)
@@ -781,10 +781,9 @@ func handleDiscordInboxCommand(c *fiber.Ctx, ix *discordInteraction) error {
 		if priority != nil && *priority != "" {
 			priorityText = " · `" + *priority + "`"
 		}
-		age := time.Since(createdAt).Round(time.Minute)
 		lines = append(lines,
-			fmt.Sprintf("• **#%s**%s — %s _(%s ago)_",
-				ticketNumber, priorityText, summaryText, age))
+			fmt.Sprintf("• **#%s**%s — %s _(%s)_",
+				ticketNumber, priorityText, summaryText, formatRelativeTime(createdAt, time.Now())))
 	}
 	if err := rows.Err(); err != nil {
 		log.Printf("[DiscordInteraction] /inbox rows: %v", err)
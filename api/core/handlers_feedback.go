@@ -0,0 +1,425 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// FeedbackRequest is the JSON body for POST /feedback. Identity comes
+// from the (optional) JWT, never from the body.
+//
+// Diagnostics is only persisted when Consent is true. The desktop shows
+// the user exactly what's in the snapshot before they tick the box, so
+// the server must not keep anything the user didn't agree to send.
+type FeedbackRequest struct {
+	Category      string               `json:"category"`
+	Message       string               `json:"message"`
+	ClientVersion string               `json:"client_version"`
+	Consent       bool                 `json:"consent"`
+	Diagnostics   *FeedbackDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// FeedbackDiagnostics is the consented context snapshot attached to a
+// report. Every field is optional — older clients send a subset.
+type FeedbackDiagnostics struct {
+	ChannelConfig map[string]interface{} `json:"channel_config,omitempty"`
+	LastSSESeq    *int64                 `json:"last_sse_seq,omitempty"`
+	RecentErrors  []string               `json:"recent_errors,omitempty"`
+}
+
+// FeedbackEntry is one row of the admin listing.
+type FeedbackEntry struct {
+	ID            int64                `json:"id"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	LogtoSub      *string              `json:"logto_sub"`
+	Category      string               `json:"category"`
+	Message       string               `json:"message"`
+	ClientVersion *string              `json:"client_version"`
+	Diagnostics   *FeedbackDiagnostics `json:"diagnostics"`
+	Status        string               `json:"status"`
+	AdminNote     *string              `json:"admin_note"`
+}
+
+// feedbackRateLimitKey scopes submissions per caller. Signed-in users are
+// keyed by sub so a shared NAT doesn't lock out a whole office; anonymous
+// callers fall back to IP.
+func feedbackRateLimitKey(userID, ip string) string {
+	if userID != "" {
+		return "feedback:user:" + userID + ":hour"
+	}
+	return "feedback:ip:" + ip + ":hour"
+}
+
+const (
+	feedbackMaxPerHour = 10
+	feedbackRateWindow = time.Hour
+
+	feedbackMessageMin       = 5
+	feedbackMessageMax       = 5000
+	feedbackClientVersionMax = 64
+
+	// Diagnostics caps. The snapshot is client-assembled, so bound it
+	// before it lands in a JSONB column an admin page will render.
+	feedbackMaxRecentErrors   = 20
+	feedbackRecentErrorMax    = 1000
+	feedbackDiagnosticsMaxLen = 64 * 1024
+
+	feedbackAdminPageSize = 50
+)
+
+// allowedFeedbackCategories restricts the category field. Values mirror
+// the desktop's feedback form dropdown.
+var allowedFeedbackCategories = map[string]bool{
+	"bug":      true,
+	"feature":  true,
+	"data":     true,
+	"billing":  true,
+	"general":  true,
+	"praise":   true,
+	"accounts": true,
+}
+
+// feedbackStatusTransitions is the admin triage workflow. A status may
+// move to any of its listed successors; resolved and wont_fix can be
+// reopened back to triaged when a report turns out to still reproduce.
+var feedbackStatusTransitions = map[string][]string{
+	"new":         {"triaged", "wont_fix"},
+	"triaged":     {"in_progress", "resolved", "wont_fix"},
+	"in_progress": {"triaged", "resolved", "wont_fix"},
+	"resolved":    {"triaged"},
+	"wont_fix":    {"triaged"},
+}
+
+// canTransitionFeedback reports whether an admin may move a report from
+// one status to another.
+func canTransitionFeedback(from, to string) bool {
+	for _, next := range feedbackStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFeedbackDiagnostics trims a client-supplied snapshot to the
+// caps above. Returns nil when nothing useful remains so the column
+// stays NULL rather than holding an empty object.
+func sanitizeFeedbackDiagnostics(d *FeedbackDiagnostics) *FeedbackDiagnostics {
+	if d == nil {
+		return nil
+	}
+	out := &FeedbackDiagnostics{
+		ChannelConfig: d.ChannelConfig,
+		LastSSESeq:    d.LastSSESeq,
+	}
+	errs := d.RecentErrors
+	if len(errs) > feedbackMaxRecentErrors {
+		// Keep the most recent entries — clients append chronologically.
+		errs = errs[len(errs)-feedbackMaxRecentErrors:]
+	}
+	for _, e := range errs {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if len(e) > feedbackRecentErrorMax {
+			e = e[:feedbackRecentErrorMax]
+		}
+		out.RecentErrors = append(out.RecentErrors, e)
+	}
+	if out.ChannelConfig == nil && out.LastSSESeq == nil && len(out.RecentErrors) == 0 {
+		return nil
+	}
+	return out
+}
+
+// HandleSubmitFeedback accepts in-product feedback and bug reports.
+// Authentication is optional (OptionalLogtoAuth): signed-in reports are
+// linked to the user's sub so support can look at their account;
+// anonymous reports from the marketing site are stored without one.
+//
+// @Summary Submit feedback
+// @Description Store a feedback or bug report, optionally with a consented diagnostic snapshot
+// @Tags Feedback
+// @Accept json
+// @Produce json
+// @Param body body FeedbackRequest true "Feedback"
+// @Success 201 {object} object{status=string,id=int}
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /feedback [post]
+func HandleSubmitFeedback(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+
+	var req FeedbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	req.Category = strings.TrimSpace(strings.ToLower(req.Category))
+	req.Message = strings.TrimSpace(req.Message)
+	req.ClientVersion = strings.TrimSpace(req.ClientVersion)
+
+	if !allowedFeedbackCategories[req.Category] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid category",
+		})
+	}
+	if len(req.Message) < feedbackMessageMin || len(req.Message) > feedbackMessageMax {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Message must be %d-%d characters", feedbackMessageMin, feedbackMessageMax),
+		})
+	}
+	if len(req.ClientVersion) > feedbackClientVersionMax {
+		req.ClientVersion = req.ClientVersion[:feedbackClientVersionMax]
+	}
+
+	// Soft-fail on Redis errors, same as the public support form: losing
+	// a bug report to an infra blip is worse than letting one extra in.
+	ip := c.IP()
+	if Rdb != nil && (userID != "" || ip != "") {
+		key := feedbackRateLimitKey(userID, ip)
+		count, err := Rdb.Incr(c.Context(), key).Result()
+		if err == nil {
+			if count == 1 {
+				if expErr := Rdb.Expire(c.Context(), key, feedbackRateWindow).Err(); expErr != nil {
+					log.Printf("[Feedback] Redis EXPIRE failed for key=%s (key has no TTL): %v", key, expErr)
+				}
+			}
+			if count > feedbackMaxPerHour {
+				return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+					Status: "error",
+					Error:  "Too many submissions; please try again later",
+				})
+			}
+		} else {
+			log.Printf("[Feedback] Redis INCR failed (continuing): %v", err)
+		}
+	}
+
+	var diagnosticsJSON []byte
+	if req.Consent {
+		if d := sanitizeFeedbackDiagnostics(req.Diagnostics); d != nil {
+			b, err := json.Marshal(d)
+			if err == nil && len(b) <= feedbackDiagnosticsMaxLen {
+				diagnosticsJSON = b
+			} else if err == nil {
+				log.Printf("[Feedback] Dropping oversized diagnostics (%d bytes)", len(b))
+			}
+		}
+	}
+
+	var sub, clientVersion *string
+	if userID != "" {
+		sub = &userID
+	}
+	if req.ClientVersion != "" {
+		clientVersion = &req.ClientVersion
+	}
+	userAgent := c.Get("User-Agent")
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	var id int64
+	err := DBPool.QueryRow(context.Background(), `
+		INSERT INTO feedback
+		  (logto_sub, category, message, client_version, diagnostics, ip_redacted, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, sub, req.Category, req.Message, clientVersion, diagnosticsJSON, redactIP(ip), userAgent).Scan(&id)
+	if err != nil {
+		log.Printf("[Feedback] Insert failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to save feedback; please try again",
+		})
+	}
+
+	log.Printf("[Feedback] #%d captured category=%s authenticated=%t diagnostics=%t",
+		id, req.Category, userID != "", diagnosticsJSON != nil)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"status": "ok", "id": id})
+}
+
+// HandleAdminListFeedback returns feedback newest-first, optionally
+// filtered by ?status=. Paginate with ?before_id= (keyset on id).
+//
+// @Summary List feedback (admin)
+// @Tags Admin
+// @Produce json
+// @Param status query string false "Filter by status"
+// @Param before_id query int false "Return rows with id < before_id"
+// @Success 200 {object} object{feedback=[]FeedbackEntry}
+// @Security LogtoAuth
+// @Router /admin/feedback [get]
+func HandleAdminListFeedback(c *fiber.Ctx) error {
+	status := strings.TrimSpace(c.Query("status"))
+	if status != "" {
+		if _, ok := feedbackStatusTransitions[status]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Invalid status filter",
+			})
+		}
+	}
+	var beforeID *int64
+	if raw := c.Query("before_id"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "before_id must be a positive integer",
+			})
+		}
+		beforeID = &v
+	}
+
+	rows, err := DBPool.Query(context.Background(), `
+		SELECT id, created_at, updated_at, logto_sub, category, message,
+		       client_version, diagnostics, status, admin_note
+		FROM feedback
+		WHERE ($1 = '' OR status = $1)
+		  AND ($2::bigint IS NULL OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`, status, beforeID, feedbackAdminPageSize)
+	if err != nil {
+		log.Printf("[Feedback] Admin list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list feedback",
+		})
+	}
+	defer rows.Close()
+
+	entries := make([]FeedbackEntry, 0)
+	for rows.Next() {
+		var e FeedbackEntry
+		var diag []byte
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt, &e.LogtoSub, &e.Category,
+			&e.Message, &e.ClientVersion, &diag, &e.Status, &e.AdminNote); err != nil {
+			log.Printf("[Feedback] Admin list scan error: %v", err)
+			continue
+		}
+		if len(diag) > 0 {
+			var d FeedbackDiagnostics
+			if json.Unmarshal(diag, &d) == nil {
+				e.Diagnostics = &d
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	return c.JSON(fiber.Map{"feedback": entries})
+}
+
+// HandleAdminUpdateFeedback moves a report through the triage workflow
+// and/or sets the internal admin note.
+//
+// @Summary Update feedback status (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Feedback ID"
+// @Param body body object{status=string,admin_note=string} true "Update"
+// @Success 200 {object} object{status=string,id=int,feedback_status=string}
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/feedback/{id} [patch]
+func HandleAdminUpdateFeedback(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid feedback id",
+		})
+	}
+
+	var req struct {
+		Status    *string `json:"status"`
+		AdminNote *string `json:"admin_note"`
+	}
+	if err := c.BodyParser(&req); err != nil || (req.Status == nil && req.AdminNote == nil) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Body must include status and/or admin_note",
+		})
+	}
+
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		log.Printf("[Feedback] Begin tx failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update feedback",
+		})
+	}
+	defer tx.Rollback(ctx)
+
+	var current string
+	err = tx.QueryRow(ctx, `SELECT status FROM feedback WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feedback not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Feedback] Read #%d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update feedback",
+		})
+	}
+
+	next := current
+	if req.Status != nil && *req.Status != current {
+		if !canTransitionFeedback(current, *req.Status) {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Cannot move feedback from %s to %s", current, *req.Status),
+			})
+		}
+		next = *req.Status
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE feedback
+		   SET status = $2,
+		       admin_note = COALESCE($3, admin_note),
+		       updated_at = now()
+		 WHERE id = $1
+	`, id, next, req.AdminNote); err != nil {
+		log.Printf("[Feedback] Update #%d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update feedback",
+		})
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("[Feedback] Commit #%d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update feedback",
+		})
+	}
+
+	return c.JSON(fiber.Map{"status": "ok", "id": id, "feedback_status": next})
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCanTransitionFeedback(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{"new", "triaged", true},
+		{"new", "wont_fix", true},
+		{"new", "resolved", false},
+		{"triaged", "in_progress", true},
+		{"in_progress", "resolved", true},
+		{"resolved", "triaged", true},
+		{"resolved", "new", false},
+		{"wont_fix", "in_progress", false},
+		{"bogus", "triaged", false},
+		{"new", "bogus", false},
+	}
+	for _, c := range cases {
+		if got := canTransitionFeedback(c.from, c.to); got != c.want {
+			t.Errorf("canTransitionFeedback(%q, %q) = %v, want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
+
+// Every status the migration's CHECK constraint allows must be a key in
+// the transition map, otherwise the admin filter would reject it.
+func TestFeedbackStatusTransitions_CoverAllStatuses(t *testing.T) {
+	for _, s := range []string{"new", "triaged", "in_progress", "resolved", "wont_fix"} {
+		if _, ok := feedbackStatusTransitions[s]; !ok {
+			t.Errorf("status %q missing from feedbackStatusTransitions", s)
+		}
+	}
+}
+
+func TestSanitizeFeedbackDiagnostics(t *testing.T) {
+	if got := sanitizeFeedbackDiagnostics(nil); got != nil {
+		t.Errorf("nil input: got %+v, want nil", got)
+	}
+	if got := sanitizeFeedbackDiagnostics(&FeedbackDiagnostics{RecentErrors: []string{"  ", ""}}); got != nil {
+		t.Errorf("blank-only input: got %+v, want nil", got)
+	}
+
+	errs := make([]string, feedbackMaxRecentErrors+5)
+	for i := range errs {
+		errs[i] = string(rune('a' + i))
+	}
+	errs[len(errs)-1] = strings.Repeat("x", feedbackRecentErrorMax+10)
+
+	got := sanitizeFeedbackDiagnostics(&FeedbackDiagnostics{RecentErrors: errs})
+	if got == nil {
+		t.Fatal("got nil, want trimmed diagnostics")
+	}
+	if len(got.RecentErrors) != feedbackMaxRecentErrors {
+		t.Fatalf("len(RecentErrors) = %d, want %d", len(got.RecentErrors), feedbackMaxRecentErrors)
+	}
+	if got.RecentErrors[0] != "f" {
+		t.Errorf("oldest kept error = %q, want %q (most recent entries kept)", got.RecentErrors[0], "f")
+	}
+	if n := len(got.RecentErrors[len(got.RecentErrors)-1]); n != feedbackRecentErrorMax {
+		t.Errorf("last error length = %d, want %d", n, feedbackRecentErrorMax)
+	}
+}
+
+func TestFeedbackRateLimitKey(t *testing.T) {
+	if got := feedbackRateLimitKey("sub-1", "1.2.3.4"); got != "feedback:user:sub-1:hour" {
+		t.Errorf("authenticated key = %q", got)
+	}
+	if got := feedbackRateLimitKey("", "1.2.3.4"); got != "feedback:ip:1.2.3.4:hour" {
+		t.Errorf("anonymous key = %q", got)
+	}
+}
+
+func TestSubmitFeedbackRejectsBadToken(t *testing.T) {
+	app := fiber.New()
+	app.Post("/feedback", OptionalLogtoAuth, HandleSubmitFeedback)
+
+	// A presented token that doesn't validate must not be filed anonymously.
+	req := httptest.NewRequest("POST", "/feedback", strings.NewReader(`{"category":"bug","message":"broken"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+}
@@ -65,7 +65,7 @@ type githubPRClosedEvent struct {
 func HandleGitHubPRClosed(c *fiber.Ctx) error {
 	// 1. Auth — constant-time secret comparison. Mirrors
 	//    HandleOSTicketThreadMessage's pattern.
-	expected := os.Getenv("GITHUB_PR_WEBHOOK_SECRET")
+	expected := Secret("GITHUB_PR_WEBHOOK_SECRET")
 	if expected == "" {
 		log.Println("[GitHubWebhook] GITHUB_PR_WEBHOOK_SECRET not set; rejecting")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{
@@ -115,7 +115,9 @@ func HandleGitHubPRClosed(c *fiber.Ctx) error {
 	// 3. Fire-and-forget close calls. We respond 200 to the Action
 	//    immediately so a slow osTicket doesn't time out the
 	//    GitHub workflow.
-	go closePRReferencedTickets(ev)
+	ExternalPool.Submit("close-pr-tickets", func(ctx context.Context) {
+		closePRReferencedTickets(ctx, ev)
+	})
 
 	return c.JSON(fiber.Map{
 		"status":    "accepted",
@@ -129,9 +131,9 @@ func HandleGitHubPRClosed(c *fiber.Ctx) error {
 // reply endpoint for each ticket number, with close_ticket=true and a
 // templated message body. Any individual close failure is logged but
 // not retried — this is best-effort cleanup, not a guaranteed delivery.
-func closePRReferencedTickets(ev githubPRClosedEvent) {
+func closePRReferencedTickets(ctx context.Context, ev githubPRClosedEvent) {
 	osticketURL := strings.TrimRight(os.Getenv("OSTICKET_URL"), "/")
-	osticketKey := os.Getenv("OSTICKET_API_KEY")
+	osticketKey := Secret("OSTICKET_API_KEY")
 	if osticketURL == "" || osticketKey == "" {
 		log.Println("[GitHubWebhook] OSTICKET_URL or OSTICKET_API_KEY missing; cannot close tickets")
 		return
@@ -149,7 +151,7 @@ func closePRReferencedTickets(ev githubPRClosedEvent) {
 
 	successes := 0
 	failures := 0
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	for _, num := range ev.TicketNumbers {
@@ -206,8 +208,7 @@ func callPluginReplyAndClose(
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-API-Key", apiKey)
 
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := osTicketReplyHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("plugin call: %w", err)
 	}
@@ -5,7 +5,6 @@ import (
 	"crypto/subtle"
 	"encoding/json"
 	"log"
-	"os"
 	"strings"
 	"time"
 
@@ -62,7 +61,7 @@ type osTicketThreadMessageEvent struct {
 // an existing ticket. Auth via shared secret.
 func HandleOSTicketThreadMessage(c *fiber.Ctx) error {
 	// 1. Auth — constant-time secret comparison.
-	expected := os.Getenv("SCROLLR_WEBHOOK_SECRET")
+	expected := Secret("SCROLLR_WEBHOOK_SECRET")
 	if expected == "" {
 		log.Println("[OSTicketWebhook] SCROLLR_WEBHOOK_SECRET not set; rejecting")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{
@@ -120,7 +119,9 @@ func HandleOSTicketThreadMessage(c *fiber.Ctx) error {
 	//    osTicket plugin's HTTP request open while Anthropic ponders.
 	//    Plugin's curl is configured with a 5s timeout; triage can
 	//    take 5-10s. Fire-and-forget is the right shape.
-	go processReplyTriageAsync(ev)
+	ExternalPool.Submit("reply-triage", func(ctx context.Context) {
+		processReplyTriageAsync(ctx, ev)
+	})
 
 	return c.JSON(fiber.Map{
 		"status":          "accepted",
@@ -129,13 +130,13 @@ func HandleOSTicketThreadMessage(c *fiber.Ctx) error {
 	})
 }
 
-// processReplyTriageAsync runs in a detached goroutine after the
-// webhook returns 200. Failures are logged but never surfaced — the
+// processReplyTriageAsync runs on ExternalPool after the webhook
+// returns 200. Failures are logged but never surfaced — the
 // user's message is already persisted in osTicket; the worst case is
 // the partner doesn't get an AI-drafted reply for this round and has
 // to handle it manually inside osTicket.
-func processReplyTriageAsync(ev osTicketThreadMessageEvent) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func processReplyTriageAsync(ctx context.Context, ev osTicketThreadMessageEvent) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	// Pull the most recent SENT reply on this ticket so the triage
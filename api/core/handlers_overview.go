@@ -54,6 +54,9 @@ type OverviewTier struct {
 	IsSuperUser bool          `json:"is_super_user"`
 	Label       string        `json:"label"`
 	Limits      ChannelLimits `json:"limits"`
+	// Trialing is true when the tier comes from a free trial rather than
+	// a paid subscription — the limits apply until the trial ends.
+	Trialing bool `json:"trialing"`
 }
 
 // OverviewChannels summarises the user_channels table for the account
@@ -227,8 +230,7 @@ func fetchFantasySummary(ctx context.Context, userID string) *OverviewFantasy {
 	// identity into channel APIs (see proxy.go).
 	req.Header.Set("X-User-Sub", userID)
 
-	client := &http.Client{Timeout: FantasyFanoutTimeout}
-	resp, err := client.Do(req)
+	resp, err := fantasyFanoutClient.Do(req)
 	if err != nil {
 		log.Printf("[Overview] fantasy fan-out failed (timeout/network): %v", err)
 		return nil
@@ -315,11 +317,12 @@ func getSubscriptionForOverview(ctx context.Context, userID string) *Subscriptio
 	var sc StripeCustomer
 	err := DBPool.QueryRow(ctx,
 		`SELECT logto_sub, stripe_customer_id, stripe_subscription_id, plan, status,
-		        current_period_end, lifetime, created_at, updated_at
+		        current_period_end, lifetime, created_at, updated_at,
+		        grace_period_ends_at, entitlements_suspended_at
 		 FROM stripe_customers WHERE logto_sub = $1`, userID,
 	).Scan(&sc.LogtoSub, &sc.StripeCustomerID, &sc.StripeSubscriptionID,
 		&sc.Plan, &sc.Status, &sc.CurrentPeriodEnd, &sc.Lifetime,
-		&sc.CreatedAt, &sc.UpdatedAt)
+		&sc.CreatedAt, &sc.UpdatedAt, &sc.GracePeriodEndsAt, &sc.EntitlementsSuspendedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil
@@ -333,6 +336,10 @@ func getSubscriptionForOverview(ctx context.Context, userID string) *Subscriptio
 		Status:           sc.Status,
 		CurrentPeriodEnd: sc.CurrentPeriodEnd,
 		Lifetime:         sc.Lifetime,
+		Trialing:         sc.Status == "trialing",
+
+		GracePeriodEndsAt:     sc.GracePeriodEndsAt,
+		EntitlementsSuspended: sc.EntitlementsSuspendedAt != nil,
 	}
 }
 
@@ -373,6 +380,9 @@ func assembleOverview(ctx context.Context, c *fiber.Ctx, userID string) (*Overvi
 	identity := buildIdentityFromContext(c)
 	tier := buildTierFromContext(c)
 	subscription := getSubscriptionForOverview(ctx, userID)
+	if subscription != nil && subscription.Trialing {
+		tier.Trialing = true
+	}
 
 	channels, err := getChannelSummary(ctx, userID)
 	if err != nil {
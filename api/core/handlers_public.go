@@ -6,6 +6,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,42 +23,57 @@ const (
 )
 
 // PublicFeedResponse is the response shape for GET /public/feed.
-// It mirrors the DashboardResponse data map but without preferences/channels.
+// It mirrors the DashboardResponse data map but without preferences or
+// channel configs. Channels and GeneratedAt are only set on a keyed feed.
 type PublicFeedResponse struct {
-	Data map[string]interface{} `json:"data"`
+	Data        map[string]interface{} `json:"data"`
+	Channels    []string               `json:"channels,omitempty"`
+	GeneratedAt int64                  `json:"generated_at,omitempty"`
 }
 
 // HandlePublicFeed returns an aggregated feed of finance + sports data.
 // No authentication required. Results are cached in Redis for 30s.
 //
+// With ?key= (or an X-API-Key header) holding an API key with the feed
+// scope, it returns the key owner's ticker instead: the data of their
+// enabled, visible channels, for OBS overlays and other embeds that
+// can't sign in. Requests with a valid key skip the IP rate limiter and
+// are limited per key instead (PublicFeedKeyRateLimitMax).
+//
 // @Summary Public feed
-// @Description Returns finance and sports data for anonymous/free-tier polling
+// @Description Returns finance and sports data for anonymous/free-tier polling, or the key owner's ticker when an API key with the feed scope is given
 // @Tags Public
 // @Produce json
+// @Param key query string false "API key with the feed scope"
 // @Success 200 {object} PublicFeedResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Router /public/feed [get]
 func HandlePublicFeed(c *fiber.Ctx) error {
-	// Check Redis cache first
-	val, err := Rdb.Get(context.Background(), PublicFeedCacheKey).Result()
-	if err == nil {
+	if key := publicFeedKey(c); key != "" {
+		return handleKeyedPublicFeed(c, key)
+	}
+
+	// Check the cache first
+	if val, ok := GetCache(context.Background(), PublicFeedCacheKey); ok {
 		c.Set("Content-Type", "application/json")
 		c.Set("X-Cache", "HIT")
-		return c.SendString(val)
+		recordCacheLookup("public_feed", true)
+		return c.Send(val)
 	}
 
 	// Singleflight: only one goroutine fetches; others share the result
 	result, err, _ := publicFeedGroup.Do("public-feed", func() (interface{}, error) {
 		// Double-check cache
-		if val, err := Rdb.Get(context.Background(), PublicFeedCacheKey).Result(); err == nil {
-			return []byte(val), nil
+		if val, ok := GetCache(context.Background(), PublicFeedCacheKey); ok {
+			return val, nil
 		}
 
 		res := PublicFeedResponse{
 			Data: make(map[string]interface{}),
 		}
 
-		httpClient := &http.Client{Timeout: HealthCheckTimeout}
-
 		type publicResult struct {
 			data map[string]interface{}
 		}
@@ -79,7 +96,7 @@ func HandlePublicFeed(c *fiber.Ctx) error {
 		for i, t := range targets {
 			go func(idx int, tgt publicTarget) {
 				defer wg.Done()
-				results[idx] = publicResult{data: fetchChannelPublic(httpClient, tgt.intg, tgt.path)}
+				results[idx] = publicResult{data: fetchChannelPublic(channelPublicClient, tgt.intg, tgt.path)}
 			}(i, t)
 		}
 		wg.Wait()
@@ -91,7 +108,7 @@ func HandlePublicFeed(c *fiber.Ctx) error {
 		}
 
 		cacheData, _ := json.Marshal(res)
-		Rdb.Set(context.Background(), PublicFeedCacheKey, cacheData, PublicFeedCacheTTL)
+		SetCache(context.Background(), PublicFeedCacheKey, cacheData, PublicFeedCacheTTL)
 		return cacheData, nil
 	})
 
@@ -101,6 +118,7 @@ func HandlePublicFeed(c *fiber.Ctx) error {
 
 	c.Set("Content-Type", "application/json")
 	c.Set("X-Cache", "MISS")
+	recordCacheLookup("public_feed", false)
 	return c.Send(result.([]byte))
 }
 
@@ -134,3 +152,134 @@ func fetchChannelPublic(client *http.Client, intg *ChannelInfo, path string) map
 		intg.Name: items,
 	}
 }
+
+// publicFeedKey returns the API key a /public/feed request carries, if
+// any. The query parameter is what an overlay URL uses; the header is
+// accepted too so scripts can keep the key out of URLs.
+func publicFeedKey(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Query("key")); key != "" {
+		return key
+	}
+	return strings.TrimSpace(c.Get(APIKeyHeader))
+}
+
+// publicFeedKeyOwner resolves the key a /public/feed request carries.
+// The general limiter exempts a request only once this succeeds, so a
+// made-up key stays under the per-IP limit; the owner is kept in Locals
+// for handleKeyedPublicFeed.
+func publicFeedKeyOwner(c *fiber.Ctx) (apiKeyOwner, bool) {
+	if owner, ok := c.Locals("public_feed_key_owner").(apiKeyOwner); ok {
+		return owner, true
+	}
+	key := publicFeedKey(c)
+	if key == "" {
+		return apiKeyOwner{}, false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	owner, err := resolveAPIKey(ctx, key)
+	if err != nil {
+		return apiKeyOwner{}, false
+	}
+	c.Locals("public_feed_key_owner", owner)
+	return owner, true
+}
+
+// handleKeyedPublicFeed serves the key owner's ticker. Unlike channel
+// routes, these requests don't count toward the metered API usage: the
+// feed is the owner's own ticker, like a display token's.
+func handleKeyedPublicFeed(c *fiber.Ctx, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// The key may be in the URL; keep it out of Referer headers.
+	c.Set("Referrer-Policy", "no-referrer")
+
+	owner, ok := publicFeedKeyOwner(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Invalid API key",
+		})
+	}
+	if !owner.hasScope(APIKeyScopeFeed) {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "API key lacks the " + APIKeyScopeFeed + " scope",
+		})
+	}
+	if !allowAPIKeyRequest(ctx, key, APIKeyScopeFeed) {
+		c.Set("Retry-After", strconv.Itoa(int(APIKeyRateLimitWindow.Seconds())))
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "API key rate limit exceeded",
+		})
+	}
+
+	data, hit, err := loadUserPublicFeed(owner.Sub)
+	if err != nil {
+		log.Printf("[PublicFeed] keyed feed assembly failed for %s: %v", owner.Sub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "public feed fetch failed",
+		})
+	}
+
+	c.Set("Content-Type", "application/json")
+	c.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(PublicFeedUserCacheTTL.Seconds())))
+	recordCacheLookup("public_feed_user", hit)
+	if hit {
+		c.Set("X-Cache", "HIT")
+	} else {
+		c.Set("X-Cache", "MISS")
+	}
+	return c.Send(data)
+}
+
+// loadUserPublicFeed returns an owner's keyed feed JSON, from the Redis
+// cache when warm. Concurrent misses share one assembly.
+func loadUserPublicFeed(owner string) ([]byte, bool, error) {
+	cacheKey := RedisPublicFeedUserPrefix + owner
+	if val, ok := GetCache(context.Background(), cacheKey); ok {
+		return val, true, nil
+	}
+
+	result, err, _ := publicFeedGroup.Do("user:"+owner, func() (interface{}, error) {
+		if val, ok := GetCache(context.Background(), cacheKey); ok {
+			return val, nil
+		}
+		// No roles: a feed request must never sync the owner's tier.
+		raw, _, _ := assembleDashboard(context.Background(), owner)
+		var dash DashboardResponse
+		if err := json.Unmarshal(raw, &dash); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(buildUserPublicFeed(dash, time.Now()))
+		if err != nil {
+			return nil, err
+		}
+		SetCache(context.Background(), cacheKey, data, PublicFeedUserCacheTTL)
+		return data, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]byte), false, nil
+}
+
+// buildUserPublicFeed reduces a dashboard to the keyed feed: the data of
+// the channels on the owner's ticker, in ticker order. Like the display
+// ticker it carries no channel configs; an overlay URL is easy to leak on
+// stream.
+func buildUserPublicFeed(dash DashboardResponse, now time.Time) PublicFeedResponse {
+	ticker := buildDisplayTicker(dash, now)
+	out := PublicFeedResponse{
+		Data:        ticker.Data,
+		Channels:    make([]string, 0, len(ticker.Channels)),
+		GeneratedAt: ticker.GeneratedAt,
+	}
+	for _, ch := range ticker.Channels {
+		out.Channels = append(out.Channels, ch.ChannelType)
+	}
+	return out
+}
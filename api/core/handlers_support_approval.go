@@ -3,7 +3,6 @@ package core
 import (
 	"context"
 	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -23,9 +22,9 @@ import (
 //
 // Each support draft generates three single-use, HMAC-signed URLs the
 // partner clicks from email. Tokens are tiny custom JWTs (header-less)
-// signed with HMAC-SHA256 — no JWT library dependency for what is a
-// 4-field claim set. Single-use enforcement lives in the DB
-// (markDraftDecided's `WHERE status = 'pending'`).
+// signed with the support_approval keyset (signing_keys.go) — no JWT
+// library dependency for what is a 4-field claim set. Single-use
+// enforcement lives in the DB (markDraftDecided's `WHERE status = 'pending'`).
 
 // SupportApprovalToken is the signed claim set carried in the URL.
 // Field names are 1-letter to keep the encoded URL short.
@@ -36,56 +35,34 @@ type SupportApprovalToken struct {
 	JTI     string `json:"j"` // unique nonce — useful for log correlation, not enforced
 }
 
-// supportApprovalSecret returns the HMAC key used to sign approval
-// tokens. Falls back to LOGTO_M2M_APP_SECRET so dev environments work
-// without an extra env var, but production should set
-// SUPPORT_APPROVAL_HMAC_SECRET to a high-entropy random value.
+// supportApprovalSecret returns the static HMAC key approval tokens were
+// signed with before keysets. It only verifies those older two-part
+// tokens, which expire within a day of minting; once none are
+// outstanding SUPPORT_APPROVAL_HMAC_SECRET can be unset.
 func supportApprovalSecret() []byte {
-	s := os.Getenv("SUPPORT_APPROVAL_HMAC_SECRET")
+	s := Secret("SUPPORT_APPROVAL_HMAC_SECRET")
 	if s == "" {
-		s = os.Getenv("LOGTO_M2M_APP_SECRET")
+		s = Secret("LOGTO_M2M_APP_SECRET")
 	}
 	return []byte(s)
 }
 
-// signApprovalToken produces a base64url-encoded payload + signature
-// joined by ".". Format: <base64(json claims)>.<base64(hmac)>
-func signApprovalToken(t SupportApprovalToken) (string, error) {
-	if len(supportApprovalSecret()) == 0 {
-		return "", fmt.Errorf("SUPPORT_APPROVAL_HMAC_SECRET not configured")
-	}
-	body, err := json.Marshal(t)
-	if err != nil {
-		return "", err
-	}
-	encoded := base64.RawURLEncoding.EncodeToString(body)
-	mac := hmac.New(sha256.New, supportApprovalSecret())
-	mac.Write([]byte(encoded))
-	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	return encoded + "." + sig, nil
+// signApprovalToken produces <kid>.<base64(json claims)>.<base64(hmac)>.
+func signApprovalToken(ctx context.Context, t SupportApprovalToken) (string, error) {
+	return signToken(ctx, signingPurposeSupportApproval, t)
 }
 
 // verifyApprovalToken parses + validates a token. Returns parsed
 // claims on success. Errors are intentionally generic so we don't
 // leak which step failed.
-func verifyApprovalToken(raw string) (*SupportApprovalToken, error) {
-	parts := strings.SplitN(raw, ".", 2)
-	if len(parts) != 2 {
-		return nil, fmt.Errorf("malformed token")
-	}
-	mac := hmac.New(sha256.New, supportApprovalSecret())
-	mac.Write([]byte(parts[0]))
-	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	if !hmac.Equal([]byte(parts[1]), []byte(expected)) {
-		return nil, fmt.Errorf("invalid signature")
-	}
-	body, err := base64.RawURLEncoding.DecodeString(parts[0])
-	if err != nil {
-		return nil, fmt.Errorf("decode body: %w", err)
-	}
+func verifyApprovalToken(ctx context.Context, raw string) (*SupportApprovalToken, error) {
 	var t SupportApprovalToken
-	if err := json.Unmarshal(body, &t); err != nil {
-		return nil, fmt.Errorf("parse claims: %w", err)
+	if strings.Count(raw, ".") == 1 {
+		if err := verifyLegacyApprovalToken(raw, &t); err != nil {
+			return nil, err
+		}
+	} else if err := verifyToken(ctx, signingPurposeSupportApproval, raw, &t); err != nil {
+		return nil, err
 	}
 	if t.Exp > 0 && time.Now().Unix() > t.Exp {
 		return nil, fmt.Errorf("token expired")
@@ -93,18 +70,38 @@ func verifyApprovalToken(raw string) (*SupportApprovalToken, error) {
 	return &t, nil
 }
 
+// verifyLegacyApprovalToken checks a pre-keyset <claims>.<hmac> token.
+func verifyLegacyApprovalToken(raw string, t *SupportApprovalToken) error {
+	secret := supportApprovalSecret()
+	if len(secret) == 0 {
+		return errUnknownSigningKey
+	}
+	parts := strings.SplitN(raw, ".", 2)
+	if !hmac.Equal([]byte(parts[1]), []byte(signingMAC(secret, parts[0]))) {
+		return errInvalidSignature
+	}
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("decode body: %w", err)
+	}
+	if err := json.Unmarshal(body, t); err != nil {
+		return fmt.Errorf("parse claims: %w", err)
+	}
+	return nil
+}
+
 // buildApprovalURLs returns the three URLs to embed in the partner
 // notification email. All three share the same expiry; one click on
 // any of them flips the draft's status, invalidating the other two
 // (single-use is enforced at the DB layer).
-func buildApprovalURLs(draftID int64) (sendURL, editURL, skipURL string, err error) {
+func buildApprovalURLs(ctx context.Context, draftID int64) (sendURL, editURL, skipURL string, err error) {
 	base := os.Getenv("APPROVAL_BASE_URL")
 	if base == "" {
 		base = "https://api.myscrollr.com"
 	}
 	exp := time.Now().Add(24 * time.Hour).Unix()
 	mk := func(action string) (string, error) {
-		t, e := signApprovalToken(SupportApprovalToken{
+		t, e := signApprovalToken(ctx, SupportApprovalToken{
 			DraftID: draftID,
 			Action:  action,
 			Exp:     exp,
@@ -277,7 +274,7 @@ func loadAndVerifyApprovalToken(c *fiber.Ctx, expectedAction string) (*SupportAp
 	if raw == "" {
 		return nil, nil, fmt.Errorf("missing token")
 	}
-	t, err := verifyApprovalToken(raw)
+	t, err := verifyApprovalToken(c.Context(), raw)
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid token: %w", err)
 	}
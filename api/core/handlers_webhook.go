@@ -1,11 +1,14 @@
 package core
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,7 +24,9 @@ type CDCRecord struct {
 	} `json:"metadata"`
 }
 
-// HandleSequinWebhook processes incoming CDC events from Sequin.
+// HandleSequinWebhook processes incoming CDC events from Sequin. Bodies
+// may be gzip-encoded (Content-Encoding: gzip). Records are published
+// through cdcCoalescer (cdc_coalesce.go).
 //
 // @Summary Receive Sequin CDC events
 // @Description Webhook for Sequin to push database changes (authenticated, per-user routing)
@@ -31,7 +36,7 @@ type CDCRecord struct {
 // @Router /webhooks/sequin [post]
 func HandleSequinWebhook(c *fiber.Ctx) error {
 	// Verify webhook secret (mandatory)
-	secret := os.Getenv("SEQUIN_WEBHOOK_SECRET")
+	secret := Secret("SEQUIN_WEBHOOK_SECRET")
 	if secret == "" {
 		log.Println("[Sequin] SEQUIN_WEBHOOK_SECRET not set — rejecting request")
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -39,16 +44,27 @@ func HandleSequinWebhook(c *fiber.Ctx) error {
 			Error:  "Webhook authentication not configured",
 		})
 	}
+	// Sequin sends the secret as a static bearer header; it has no
+	// request-signing option. Compare in constant time.
 	auth := c.Get("Authorization")
-	if auth != "Bearer "+secret {
+	if !secretMatches(auth, "Bearer "+secret) {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Status: "unauthorized",
 			Error:  "Invalid webhook secret",
 		})
 	}
 
-	records, err := parseCDCRecords(c.Body())
+	body, err := cdcWebhookBody(c)
 	if err != nil {
+		log.Printf("[Sequin] Failed to decode body: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid CDC payload",
+		})
+	}
+	records, err := parseCDCRecords(body)
+	if err != nil {
+		sloCDCRouting.Record(false, time.Now())
 		log.Printf("[Sequin] Failed to parse CDC records: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
 			Status: "error",
@@ -56,14 +72,56 @@ func HandleSequinWebhook(c *fiber.Ctx) error {
 		})
 	}
 
+	cdcBatchSize.Observe(float64(len(records)))
+	cdcThroughput.add(time.Now(), len(records))
 	ctx := context.Background()
-	for _, rec := range records {
-		routeCDCRecord(ctx, rec)
+	lost := 0
+	errs := routeCDCRecords(records)
+	for i, rec := range records {
+		err := errs[i]
+		sloCDCRouting.Record(err == nil, time.Now())
+		// Failed records are retried from the dead-letter queue (cdc_dlq.go).
+		if err != nil && !enqueueCDCDeadLetter(ctx, rec, err) {
+			lost++
+		}
+		MatchSavedSearches(rec)
+		NotifyBreakingNews(rec)
 	}
 
+	// Records that could be neither routed nor queued: fail the delivery
+	// so Sequin retries the batch.
+	if lost > 0 {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("%d of %d records could not be routed", lost, len(records)),
+		})
+	}
 	return c.JSON(fiber.Map{"status": "ok", "processed": len(records)})
 }
 
+// cdcWebhookBody returns the request body, gunzipped when the delivery is
+// gzip-encoded. Fiber's c.Body() would gunzip too, but with no limit on
+// the decompressed size; here it's capped at CDCWebhookMaxBodyBytes.
+func cdcWebhookBody(c *fiber.Ctx) ([]byte, error) {
+	raw := c.Request().Body()
+	if c.Get(fiber.HeaderContentEncoding) != "gzip" {
+		return raw, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	body, err := io.ReadAll(io.LimitReader(zr, CDCWebhookMaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > CDCWebhookMaxBodyBytes {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", CDCWebhookMaxBodyBytes)
+	}
+	return body, nil
+}
+
 func parseCDCRecords(body []byte) ([]CDCRecord, error) {
 	// Try batched format: {"data": [...]}
 	var batched struct {
@@ -82,45 +140,58 @@ func parseCDCRecords(body []byte) ([]CDCRecord, error) {
 	return nil, fmt.Errorf("unrecognized CDC payload format")
 }
 
-// routeCDCRecord publishes a CDC event to the appropriate topic channel.
-// The Hub's listenToTopics goroutine receives the message and fans out
-// to all subscribed clients in-memory.
-func routeCDCRecord(ctx context.Context, rec CDCRecord) {
-	table := rec.Metadata.TableName
+// routeCDCRecord publishes a CDC event to the appropriate topic channel
+// on its own, without coalescing (the DLQ worker's path). The Hub's
+// listenToTopics goroutine receives the message and fans out to all
+// subscribed clients in-memory. Records for tables no topic covers are
+// skipped without error.
+func routeCDCRecord(ctx context.Context, rec CDCRecord) error {
+	// Determine the topic channel based on the table and record content
+	topic := topicForRecord(rec.Metadata.TableName, rec.Record)
+	if topic == "" {
+		return nil
+	}
+	return publishCDCEnvelope(ctx, topic, []map[string]interface{}{cdcEnvelopeEntry(rec)})
+}
 
-	// Build the SSE payload envelope
+// cdcEnvelopeEntry is a record as it appears in an envelope's data list.
+func cdcEnvelopeEntry(rec CDCRecord) map[string]interface{} {
+	return map[string]interface{}{
+		"action":   rec.Action,
+		"record":   rec.Record,
+		"changes":  rec.Changes,
+		"metadata": rec.Metadata,
+	}
+}
+
+// publishCDCEnvelope publishes records to a topic as one SSE payload
+// envelope. server_ts + seq let clients correct for local clock skew and
+// spot gaps; see clock.go.
+func publishCDCEnvelope(ctx context.Context, topic string, records []map[string]interface{}) error {
 	envelope := map[string]interface{}{
-		"data": []map[string]interface{}{
-			{
-				"action":   rec.Action,
-				"record":   rec.Record,
-				"changes":  rec.Changes,
-				"metadata": rec.Metadata,
-			},
-		},
+		"data":      records,
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq := nextEventSeq(ctx); seq > 0 {
+		envelope["seq"] = seq
 	}
 	payload, err := json.Marshal(envelope)
 	if err != nil {
-		log.Printf("[Sequin] Failed to marshal payload for table %s: %v", table, err)
-		return
-	}
-
-	// Determine the topic channel based on the table and record content
-	topic := topicForRecord(table, rec.Record)
-	if topic == "" {
-		return
+		log.Printf("[Sequin] Failed to marshal payload for topic %s: %v", topic, err)
+		return err
 	}
 
 	// Single PUBLISH to the topic channel -- Hub handles fan-out in memory
-	PublishToTopic(topic, payload)
+	return PublishToTopic(topic, payload)
 }
 
 // topicForRecord maps a CDC table + record to the correct topic channel.
 // This replaces the HTTP call to channel APIs and the per-user PUBLISH loop.
 func topicForRecord(table string, record map[string]interface{}) string {
 	switch table {
-	// Core-owned tables: route to specific user
-	case "user_preferences", "user_channels":
+	// Per-user tables: route to specific user. custom_items is owned by
+	// the custom channel but every row belongs to exactly one user.
+	case "user_preferences", "user_channels", "custom_items":
 		sub, ok := record["logto_sub"].(string)
 		if !ok || sub == "" {
 			return ""
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebSocket transport for the event hub.
+//
+// /ws carries the same payloads as /events for clients behind proxies
+// that buffer SSE (some corporate proxies hold a text/event-stream
+// response until it completes, which for /events is never). Both
+// transports register a *Client with the Hub, so topic subscriptions,
+// dispatch and /events/count are shared.
+//
+// Frames are text JSON, server to client only. CDC envelopes are sent
+// unchanged ({"data":[...],"server_ts":...}). Control messages carry an
+// "event" key instead:
+//
+//	{"event":"client-upgrade-required","data":{...}}  sent before closing a retired build
+//	{"event":"resync","data":{"dropped":N}}           N events were dropped; refetch /dashboard
+//	{"event":"resync","data":{"reason":"replay-gap"}} ?last_event_id= couldn't be fully replayed
+//
+// Reconnecting clients pass the seq of the last envelope they received as
+// ?last_event_id= and get what they missed first, as /events does with
+// Last-Event-ID (see replay.go).
+//
+// Keepalive is protocol-level ping/pong: the server pings every
+// WSPingInterval and closes the connection if nothing, pong included,
+// arrives within WSPongWait. A connection whose writes take longer than
+// WSWriteTimeout is closed as a slow consumer; one that merely falls
+// behind has events dropped at the Hub (the buffer is SSEClientBufferSize
+// deep, as for SSE) and is told to resync.
+
+const (
+	wsLocalUserID          = "ws_user_id"
+	wsLocalVersion         = "ws_client_version"
+	wsLocalLastEventID     = "ws_last_event_id"
+	wsEventResync          = "resync"
+	wsEventUpgradeRequired = "client-upgrade-required"
+)
+
+// wsControlMessage is a non-CDC frame sent to WebSocket clients.
+type wsControlMessage struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// HandleWebSocketUpgrade authenticates a /ws request and hands it to
+// StreamWebSocket. Plain HTTP requests get 426.
+//
+// @Summary Real-time event stream over WebSocket (authenticated)
+// @Description WebSocket alternative to /events for clients whose proxies buffer SSE. Sends the same CDC envelopes as text frames.
+// @Tags Events
+// @Param token query string false "JWT access token (browsers can't set headers on WebSocket)"
+// @Param Authorization header string false "Bearer token"
+// @Param client_version query string false "Client build version (fallback if no X-Client-Version header)"
+// @Param last_event_id query string false "Seq of the last event received; missed events are replayed"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 426 {object} ErrorResponse
+// @Router /ws [get]
+func HandleWebSocketUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "WebSocket upgrade required",
+		})
+	}
+	userID, ok, err := authorizeEventStream(c)
+	if !ok {
+		return err
+	}
+
+	clientVersion := c.Get(ClientVersionHeader)
+	if clientVersion == "" {
+		clientVersion = c.Query("client_version")
+	}
+	c.Locals(wsLocalUserID, userID)
+	c.Locals(wsLocalVersion, clientVersion)
+	c.Locals(wsLocalLastEventID, lastEventID(c))
+	return c.Next()
+}
+
+// StreamWebSocket pumps hub events to an upgraded connection until either
+// side closes it.
+var StreamWebSocket = websocket.New(func(conn *websocket.Conn) {
+	userID, _ := conn.Locals(wsLocalUserID).(string)
+	clientVersion, _ := conn.Locals(wsLocalVersion).(string)
+	lastSeq, _ := conn.Locals(wsLocalLastEventID).(int64)
+
+	// Retired builds get one upgrade event and a close, mirroring /events.
+	if min := getMinClientVersion(context.Background()); clientBelowMinimum(clientVersion, min) {
+		log.Printf("[WS] Rejected outdated client: user=%s version=%s min=%s", userID, clientVersion, min)
+		writeWSControl(conn, wsEventUpgradeRequired, clientUpgradeRequired(clientVersion, min))
+		closeWS(conn, websocket.ClosePolicyViolation, "client upgrade required")
+		return
+	}
+
+	client := RegisterClient(userID)
+	defer UnregisterClient(client)
+	log.Printf("[WS] Client connected: user=%s ip=%s last_event_id=%d", userID, conn.IP(), lastSeq)
+
+	var cursor *replayCursor
+	if lastSeq > 0 {
+		events, complete := replayEvents(userID, lastSeq)
+		for _, e := range events {
+			conn.SetWriteDeadline(time.Now().Add(WSWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, e.payload); err != nil {
+				return
+			}
+		}
+		if !complete && !writeWSControl(conn, wsEventResync, fiber.Map{"reason": replayResyncReason}) {
+			return
+		}
+		cursor = newReplayCursor(events)
+	}
+
+	// Reader: clients send nothing meaningful, but reading is how pongs
+	// and close frames are processed. It signals done when the peer goes
+	// away or stops answering pings.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(WSMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(WSPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(WSPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(WSPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case msg, ok := <-client.Ch:
+			if !ok {
+				closeWS(conn, websocket.CloseGoingAway, "server shutting down")
+				return
+			}
+			seq, serverTS := envelopeHeader(msg)
+			if cursor != nil && cursor.duplicate(seq) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(WSWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				log.Printf("[WS] Closing slow or broken connection: user=%s err=%v", userID, err)
+				return
+			}
+			recordDeliveryLag(serverTS, time.Now())
+			if dropped := client.Dropped.Swap(0); dropped > 0 {
+				if !writeWSControl(conn, wsEventResync, fiber.Map{"dropped": dropped}) {
+					return
+				}
+			}
+
+		case <-ping.C:
+			// A minimum raised mid-stream takes effect at the next ping,
+			// as it does at the SSE heartbeat.
+			if min := getMinClientVersion(context.Background()); clientBelowMinimum(clientVersion, min) {
+				writeWSControl(conn, wsEventUpgradeRequired, clientUpgradeRequired(clientVersion, min))
+				closeWS(conn, websocket.ClosePolicyViolation, "client upgrade required")
+				return
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WSWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+})
+
+// writeWSControl sends a control frame and reports whether it was written.
+func writeWSControl(conn *websocket.Conn, event string, data any) bool {
+	payload, err := json.Marshal(wsControlMessage{Event: event, Data: data})
+	if err != nil {
+		return false
+	}
+	conn.SetWriteDeadline(time.Now().Add(WSWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, payload) == nil
+}
+
+func closeWS(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(WSWriteTimeout))
+}
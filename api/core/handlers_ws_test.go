@@ -0,0 +1,52 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWebSocketRejectsPlainHTTP(t *testing.T) {
+	app := fiber.New()
+	app.Get("/ws", HandleWebSocketUpgrade, StreamWebSocket)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ws?token=abc", nil))
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusUpgradeRequired {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusUpgradeRequired)
+	}
+}
+
+func TestTrySendCountsDrops(t *testing.T) {
+	client := &Client{UserID: "u1", Ch: make(chan []byte, 1)}
+	if !trySend(client, []byte("a")) {
+		t.Fatal("first send failed with buffer space")
+	}
+	if trySend(client, []byte("b")) || trySend(client, []byte("c")) {
+		t.Fatal("send succeeded on a full buffer")
+	}
+	if got := client.Dropped.Load(); got != 2 {
+		t.Errorf("Dropped = %d, want 2", got)
+	}
+}
+
+func TestWSControlMessageShape(t *testing.T) {
+	data, err := json.Marshal(wsControlMessage{Event: wsEventResync, Data: fiber.Map{"dropped": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	json.Unmarshal(data, &got)
+	if got["event"] != "resync" {
+		t.Errorf("event = %v, want resync", got["event"])
+	}
+	// CDC envelopes are told apart from control frames by the absence of
+	// "event"; control frames must not look like envelopes.
+	if _, ok := got["server_ts"]; ok {
+		t.Error("control frame carries server_ts")
+	}
+}
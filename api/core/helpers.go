@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"strings"
+	"time"
 )
 
 // ValidateURL cleans a URL string, ensuring it has a scheme prefix.
@@ -16,3 +18,32 @@ func ValidateURL(urlStr, fallback string) string {
 	}
 	return strings.TrimSuffix(urlStr, "/")
 }
+
+// formatRelativeTime renders t relative to now ("just now", "5m ago",
+// "in 2h", "3d ago"). Used for strings the server renders itself so the
+// wording never depends on a skewed client clock — pass the server's
+// time.Now(), not a client-supplied timestamp.
+func formatRelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	var s string
+	switch {
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		s = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
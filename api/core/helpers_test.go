@@ -2,6 +2,7 @@ package core
 
 import (
 	"testing"
+	"time"
 )
 
 func TestValidateURL(t *testing.T) {
@@ -30,3 +31,29 @@ func TestValidateURL(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"same instant", now, "just now"},
+		{"seconds ago", now.Add(-30 * time.Second), "just now"},
+		{"seconds ahead", now.Add(45 * time.Second), "just now"},
+		{"minutes ago", now.Add(-5 * time.Minute), "5m ago"},
+		{"minutes ahead", now.Add(2 * time.Minute), "in 2m"},
+		{"hours ago", now.Add(-3*time.Hour - 10*time.Minute), "3h ago"},
+		{"days ago", now.Add(-50 * time.Hour), "2d ago"},
+		{"days ahead", now.Add(72 * time.Hour), "in 3d"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatRelativeTime(tc.t, now); got != tc.want {
+				t.Errorf("formatRelativeTime(%v) = %q, want %q", tc.t, got, tc.want)
+			}
+		})
+	}
+}
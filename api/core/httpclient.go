@@ -0,0 +1,223 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Shared HTTP clients.
+//
+// Outbound calls used to build an http.Client per request, so nothing
+// reused connections and there was no single place to point egress at a
+// proxy. Every caller now takes a client from NewHTTPClient or
+// NewInternalHTTPClient. Clients are cheap wrappers sharing one of two
+// pooled transports:
+//
+//   - external: third parties (Logto, Discord, Resend, osTicket, the
+//     triage model, the telemetry collector). Honours HTTP_PROXY,
+//     HTTPS_PROXY and NO_PROXY.
+//   - internal: channel APIs inside the cluster. Never proxied. Calls to
+//     /internal/* routes are HMAC-signed (internal_signing.go).
+//
+// Both transports resolve through a shared DNS cache, and external clients
+// enforce the egress policy (egress.go). Each client is tagged with a
+// purpose; per-purpose counters are reported at GET /admin/http-clients.
+
+// ─── Transports ──────────────────────────────────────────────────
+
+var (
+	externalTransport = newPooledTransport(http.ProxyFromEnvironment)
+	internalTransport = newPooledTransport(nil)
+)
+
+func newPooledTransport(proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: cachingDialContext(sharedDNSCache, &net.Dialer{
+			Timeout:   HTTPDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          HTTPMaxIdleConns,
+		MaxIdleConnsPerHost:   HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:       HTTPIdleConnTimeout,
+		TLSHandshakeTimeout:   HTTPTLSHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// ─── Clients ─────────────────────────────────────────────────────
+
+// NewHTTPClient returns a client for calls leaving the cluster.
+func NewHTTPClient(purpose string, timeout time.Duration) *http.Client {
+	return newHTTPClient(purpose, timeout, externalTransport, currentEgressPolicy)
+}
+
+// NewInternalHTTPClient returns a client for calls to channel APIs.
+func NewInternalHTTPClient(purpose string, timeout time.Duration) *http.Client {
+	c := newHTTPClient(purpose, timeout, internalTransport, nil)
+	c.Transport.(*instrumentedTransport).sign = true
+	return c
+}
+
+func newHTTPClient(purpose string, timeout time.Duration, base http.RoundTripper, policy func() *egressPolicy) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &instrumentedTransport{
+			base:    base,
+			purpose: purpose,
+			stats:   httpClientStatsFor(purpose),
+			policy:  policy,
+		},
+	}
+}
+
+// instrumentedTransport counts requests per purpose, traces them
+// (tracing.go), signs internal /internal/* calls (internal_signing.go)
+// and, for external clients, refuses hosts the egress policy blocks.
+// Latency is measured to response headers; body reads are the caller's.
+type instrumentedTransport struct {
+	base    http.RoundTripper
+	purpose string
+	stats   *httpClientStats
+	policy  func() *egressPolicy // nil: no policy (internal clients)
+	sign    bool                 // internal clients
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := t.stats
+	if t.policy != nil && !t.policy().allowed(req.URL.Hostname()) {
+		s.blocked.Add(1)
+		recordEgressBlocked(t.purpose, req.URL.Hostname())
+		return nil, fmt.Errorf("%w: %s", errEgressBlocked, req.URL.Hostname())
+	}
+	if t.sign {
+		signed, err := signInternalRequest(req, time.Now())
+		if err != nil {
+			s.errors.Add(1)
+			return nil, fmt.Errorf("sign internal request: %w", err)
+		}
+		req = signed
+	}
+	req, span := startClientSpan(req, t.purpose)
+	s.requests.Add(1)
+	s.inFlight.Add(1)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	s.inFlight.Add(-1)
+	s.latencyMs.Add(time.Since(start).Milliseconds())
+	endClientSpan(span, resp, err)
+	if err != nil {
+		s.errors.Add(1)
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		s.status5xx.Add(1)
+	}
+	return resp, nil
+}
+
+// ─── Stats ───────────────────────────────────────────────────────
+
+type httpClientStats struct {
+	requests  atomic.Int64
+	errors    atomic.Int64
+	status5xx atomic.Int64
+	inFlight  atomic.Int64
+	latencyMs atomic.Int64
+	blocked   atomic.Int64
+}
+
+// HTTPClientStats is a point-in-time view of one purpose's traffic.
+type HTTPClientStats struct {
+	Purpose      string  `json:"purpose"`
+	Requests     int64   `json:"requests"`
+	Errors       int64   `json:"errors"`
+	Status5xx    int64   `json:"status_5xx"`
+	InFlight     int64   `json:"in_flight"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	Blocked      int64   `json:"blocked"`
+}
+
+var (
+	httpClientStatsMu sync.Mutex
+	httpClientStatsBy = map[string]*httpClientStats{}
+)
+
+// httpClientStatsFor returns the counters for purpose, so clients that
+// share a purpose share a row.
+func httpClientStatsFor(purpose string) *httpClientStats {
+	httpClientStatsMu.Lock()
+	defer httpClientStatsMu.Unlock()
+	s, ok := httpClientStatsBy[purpose]
+	if !ok {
+		s = &httpClientStats{}
+		httpClientStatsBy[purpose] = s
+	}
+	return s
+}
+
+// HTTPClientsStats reports every purpose, sorted by name.
+func HTTPClientsStats() []HTTPClientStats {
+	httpClientStatsMu.Lock()
+	defer httpClientStatsMu.Unlock()
+	out := make([]HTTPClientStats, 0, len(httpClientStatsBy))
+	for purpose, s := range httpClientStatsBy {
+		st := HTTPClientStats{
+			Purpose:   purpose,
+			Requests:  s.requests.Load(),
+			Errors:    s.errors.Load(),
+			Status5xx: s.status5xx.Load(),
+			InFlight:  s.inFlight.Load(),
+			Blocked:   s.blocked.Load(),
+		}
+		if st.Requests > 0 {
+			st.AvgLatencyMs = float64(s.latencyMs.Load()) / float64(st.Requests)
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Purpose < out[j].Purpose })
+	return out
+}
+
+// HandleAdminHTTPClients reports outbound request counters for each
+// client purpose on this replica, along with the DNS cache and the hosts
+// the egress policy has refused.
+//
+// @Summary Outbound HTTP client stats (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{clients=[]HTTPClientStats,dns=DNSCacheStats,egress_blocked=[]EgressBlockedHost}
+// @Security LogtoAuth
+// @Router /admin/http-clients [get]
+func HandleAdminHTTPClients(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"clients":        HTTPClientsStats(),
+		"dns":            sharedDNSCache.Stats(),
+		"egress_blocked": EgressBlockedStats(),
+	})
+}
+
+// ─── Gateway clients ─────────────────────────────────────────────
+
+var (
+	logtoHTTPClient         = NewHTTPClient("logto", LogtoM2MTokenTimeout)
+	logtoProxyHTTPClient    = NewHTTPClient("logto_proxy", LogtoProxyTimeout)
+	emailHTTPClient         = NewHTTPClient("resend", EmailSendTimeout)
+	osTicketHTTPClient      = NewHTTPClient("osticket", OSTicketTimeout)
+	osTicketReplyHTTPClient = NewHTTPClient("osticket_reply", OSTicketReplyTimeout)
+	triageHTTPClient        = NewHTTPClient("triage", triageTimeout)
+
+	channelHealthClient    = NewInternalHTTPClient("channel_health", HealthCheckTimeout)
+	channelDashboardClient = NewInternalHTTPClient("channel_dashboard", DashboardChannelMaxTimeout)
+	channelPublicClient    = NewInternalHTTPClient("channel_public", HealthCheckTimeout)
+	fantasyFanoutClient    = NewInternalHTTPClient("fantasy_fanout", FantasyFanoutTimeout)
+)
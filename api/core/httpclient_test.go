@@ -0,0 +1,66 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := NewInternalHTTPClient("test_stats", time.Second)
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	// Unreachable: counted as a transport error.
+	if _, err := client.Get("http://127.0.0.1:1/"); err == nil {
+		t.Fatal("expected a dial error")
+	}
+
+	var got *HTTPClientStats
+	for _, s := range HTTPClientsStats() {
+		if s.Purpose == "test_stats" {
+			got = &s
+		}
+	}
+	if got == nil {
+		t.Fatal("purpose missing from stats")
+	}
+	if got.Requests != 4 || got.Status5xx != 1 || got.Errors != 1 || got.InFlight != 0 {
+		t.Fatalf("stats = %+v", *got)
+	}
+}
+
+func TestHTTPClientSharesPurposeAndTransport(t *testing.T) {
+	a := NewHTTPClient("test_shared", time.Second)
+	b := NewHTTPClient("test_shared", 2*time.Second)
+
+	ta := a.Transport.(*instrumentedTransport)
+	tb := b.Transport.(*instrumentedTransport)
+	if ta.stats != tb.stats {
+		t.Error("clients with the same purpose should share counters")
+	}
+	if ta.base != externalTransport || tb.base != externalTransport {
+		t.Error("external clients should share the pooled transport")
+	}
+	if internalTransport.Proxy != nil {
+		t.Error("internal transport must never use a proxy")
+	}
+	if b.Timeout != 2*time.Second {
+		t.Errorf("timeout = %v, want per-client value", b.Timeout)
+	}
+}
@@ -0,0 +1,451 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// Incident is an admin-posted service banner ("Finance data delayed").
+// AffectedChannels empty means the whole product is affected.
+type Incident struct {
+	ID               int64      `json:"id"`
+	Severity         string     `json:"severity"`
+	Message          string     `json:"message"`
+	AffectedChannels []string   `json:"affected_channels"`
+	ETA              *time.Time `json:"eta,omitempty"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+}
+
+// IncidentInput is the body for admin create/update. On update, nil
+// fields are left unchanged; ClearETA removes an existing ETA.
+type IncidentInput struct {
+	Severity         *string    `json:"severity"`
+	Message          *string    `json:"message"`
+	AffectedChannels []string   `json:"affected_channels"`
+	ETA              *time.Time `json:"eta"`
+	ClearETA         bool       `json:"clear_eta"`
+}
+
+var validIncidentSeverities = map[string]bool{
+	"info":     true,
+	"minor":    true,
+	"major":    true,
+	"critical": true,
+}
+
+const incidentMessageMax = 500
+
+// ─── Data Access ─────────────────────────────────────────────────
+
+const incidentColumns = `id, severity, message, affected_channels, eta, status, created_at, updated_at, resolved_at`
+
+func scanIncident(row pgx.Row) (Incident, error) {
+	var inc Incident
+	var affected []byte
+	if err := row.Scan(&inc.ID, &inc.Severity, &inc.Message, &affected, &inc.ETA,
+		&inc.Status, &inc.CreatedAt, &inc.UpdatedAt, &inc.ResolvedAt); err != nil {
+		return inc, err
+	}
+	if err := json.Unmarshal(affected, &inc.AffectedChannels); err != nil || inc.AffectedChannels == nil {
+		inc.AffectedChannels = []string{}
+	}
+	return inc, nil
+}
+
+// ListActiveIncidents returns active incidents newest-first. Served from
+// a short Redis cache because every dashboard build reads it; admin
+// writes drop the cache.
+func ListActiveIncidents(ctx context.Context) ([]Incident, error) {
+	if Rdb != nil {
+		if val, err := Rdb.Get(ctx, RedisIncidentsActiveKey).Bytes(); err == nil {
+			var cached []Incident
+			if json.Unmarshal(val, &cached) == nil {
+				return cached, nil
+			}
+		}
+	}
+
+	rows, err := DBPool.Query(ctx, `
+		SELECT `+incidentColumns+` FROM incidents
+		WHERE status = 'active'
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := make([]Incident, 0)
+	for rows.Next() {
+		inc, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, inc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if Rdb != nil {
+		data, _ := json.Marshal(incidents)
+		Rdb.Set(ctx, RedisIncidentsActiveKey, data, IncidentsCacheTTL)
+	}
+	return incidents, nil
+}
+
+// incidentsForChannels filters incidents to those relevant to a user:
+// product-wide incidents plus any touching one of their enabled channels.
+func incidentsForChannels(incidents []Incident, enabled map[string]bool) []Incident {
+	out := make([]Incident, 0, len(incidents))
+	for _, inc := range incidents {
+		if len(inc.AffectedChannels) == 0 {
+			out = append(out, inc)
+			continue
+		}
+		for _, ch := range inc.AffectedChannels {
+			if enabled[ch] {
+				out = append(out, inc)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// normalizeAffectedChannels lower-cases, dedups and validates channel
+// types against discovered channels.
+func normalizeAffectedChannels(in []string, validTypes map[string]bool) ([]string, error) {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, ch := range in {
+		ch = strings.ToLower(strings.TrimSpace(ch))
+		if ch == "" || seen[ch] {
+			continue
+		}
+		if !validTypes[ch] {
+			return nil, fmt.Errorf("unknown channel %q", ch)
+		}
+		seen[ch] = true
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// publishIncident drops the active-incident cache and pushes the change
+// to every connected SSE client. The envelope mirrors CDC events (a
+// data[] of {action, record, metadata.table_name}) so clients route it
+// with the same code path; "delete" means the banner should come down.
+func publishIncident(ctx context.Context, action string, inc Incident) {
+	if Rdb != nil {
+		if err := Rdb.Del(ctx, RedisIncidentsActiveKey).Err(); err != nil {
+			log.Printf("[Incidents] cache invalidation failed: %v", err)
+		}
+	}
+
+	envelope := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"action": action,
+				"record": inc,
+				"metadata": map[string]string{
+					"table_schema": "public",
+					"table_name":   "incidents",
+				},
+			},
+		},
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq := nextEventSeq(ctx); seq > 0 {
+		envelope["seq"] = seq
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[Incidents] marshal SSE payload failed: %v", err)
+		return
+	}
+	if err := PublishRaw(TopicBroadcast, payload); err != nil {
+		log.Printf("[Incidents] publish failed: %v", err)
+	}
+}
+
+// ─── Public Handlers ─────────────────────────────────────────────
+
+// HandleGetActiveIncidents returns all active incidents.
+//
+// @Summary Active incidents
+// @Description Service banners currently in effect (e.g. upstream data outages)
+// @Tags Incidents
+// @Produce json
+// @Success 200 {object} object{incidents=[]Incident}
+// @Router /incidents/active [get]
+func HandleGetActiveIncidents(c *fiber.Ctx) error {
+	incidents, err := ListActiveIncidents(c.Context())
+	if err != nil {
+		log.Printf("[Incidents] list active failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to fetch incidents",
+		})
+	}
+	c.Set("Cache-Control", "public, max-age=15")
+	return c.JSON(fiber.Map{"incidents": incidents})
+}
+
+// ─── Admin Handlers ──────────────────────────────────────────────
+
+// HandleAdminListIncidents returns incidents newest-first, optionally
+// filtered by ?status=active|resolved. Capped at 100 rows.
+//
+// @Summary List incidents (admin)
+// @Tags Admin
+// @Produce json
+// @Param status query string false "active or resolved"
+// @Success 200 {object} object{incidents=[]Incident}
+// @Security LogtoAuth
+// @Router /admin/incidents [get]
+func HandleAdminListIncidents(c *fiber.Ctx) error {
+	status := c.Query("status")
+	if status != "" && status != "active" && status != "resolved" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "status must be active or resolved",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT `+incidentColumns+` FROM incidents
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, status)
+	if err != nil {
+		log.Printf("[Incidents] admin list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list incidents",
+		})
+	}
+	defer rows.Close()
+
+	incidents := make([]Incident, 0)
+	for rows.Next() {
+		inc, err := scanIncident(rows)
+		if err != nil {
+			log.Printf("[Incidents] admin list scan failed: %v", err)
+			continue
+		}
+		incidents = append(incidents, inc)
+	}
+	return c.JSON(fiber.Map{"incidents": incidents})
+}
+
+// HandleAdminCreateIncident opens a new incident and pushes it to
+// connected clients.
+//
+// @Summary Create incident (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body IncidentInput true "Incident"
+// @Success 201 {object} Incident
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/incidents [post]
+func HandleAdminCreateIncident(c *fiber.Ctx) error {
+	var in IncidentInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if in.Severity == nil || !validIncidentSeverities[*in.Severity] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "severity must be one of info, minor, major, critical",
+		})
+	}
+	message := ""
+	if in.Message != nil {
+		message = strings.TrimSpace(*in.Message)
+	}
+	if message == "" || len(message) > incidentMessageMax {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("message must be 1-%d characters", incidentMessageMax),
+		})
+	}
+	affected, err := normalizeAffectedChannels(in.AffectedChannels, GetValidChannelTypes())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	affectedJSON, _ := json.Marshal(affected)
+	inc, err := scanIncident(DBPool.QueryRow(ctx, `
+		INSERT INTO incidents (severity, message, affected_channels, eta, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+incidentColumns,
+		*in.Severity, message, affectedJSON, in.ETA, GetUserID(c)))
+	if err != nil {
+		log.Printf("[Incidents] create failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create incident",
+		})
+	}
+
+	publishIncident(ctx, "insert", inc)
+	log.Printf("[Incidents] Opened #%d severity=%s channels=%v", inc.ID, inc.Severity, inc.AffectedChannels)
+	return c.Status(fiber.StatusCreated).JSON(inc)
+}
+
+// HandleAdminUpdateIncident edits an active incident (message, severity,
+// affected channels, ETA). Resolved incidents are read-only.
+//
+// @Summary Update incident (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Incident ID"
+// @Param body body IncidentInput true "Fields to change"
+// @Success 200 {object} Incident
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/incidents/{id} [put]
+func HandleAdminUpdateIncident(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid incident id",
+		})
+	}
+	var in IncidentInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if in.Severity != nil && !validIncidentSeverities[*in.Severity] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "severity must be one of info, minor, major, critical",
+		})
+	}
+	var message *string
+	if in.Message != nil {
+		m := strings.TrimSpace(*in.Message)
+		if m == "" || len(m) > incidentMessageMax {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("message must be 1-%d characters", incidentMessageMax),
+			})
+		}
+		message = &m
+	}
+	var affectedJSON []byte
+	if in.AffectedChannels != nil {
+		affected, err := normalizeAffectedChannels(in.AffectedChannels, GetValidChannelTypes())
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  err.Error(),
+			})
+		}
+		affectedJSON, _ = json.Marshal(affected)
+	}
+
+	ctx := c.Context()
+	inc, err := scanIncident(DBPool.QueryRow(ctx, `
+		UPDATE incidents
+		   SET severity          = COALESCE($2, severity),
+		       message           = COALESCE($3, message),
+		       affected_channels = COALESCE($4, affected_channels),
+		       eta               = CASE WHEN $6 THEN NULL ELSE COALESCE($5, eta) END,
+		       updated_at        = now()
+		 WHERE id = $1 AND status = 'active'
+		RETURNING `+incidentColumns,
+		id, in.Severity, message, affectedJSON, in.ETA, in.ClearETA))
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Active incident not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Incidents] update #%d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update incident",
+		})
+	}
+
+	publishIncident(ctx, "update", inc)
+	log.Printf("[Incidents] Updated #%d severity=%s", inc.ID, inc.Severity)
+	return c.JSON(inc)
+}
+
+// HandleAdminResolveIncident closes an incident and tells connected
+// clients to take the banner down.
+//
+// @Summary Resolve incident (admin)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Incident ID"
+// @Success 200 {object} Incident
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/incidents/{id}/resolve [post]
+func HandleAdminResolveIncident(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid incident id",
+		})
+	}
+
+	ctx := c.Context()
+	inc, err := scanIncident(DBPool.QueryRow(ctx, `
+		UPDATE incidents
+		   SET status = 'resolved', resolved_at = now(), updated_at = now()
+		 WHERE id = $1 AND status = 'active'
+		RETURNING `+incidentColumns, id))
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Active incident not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Incidents] resolve #%d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to resolve incident",
+		})
+	}
+
+	publishIncident(ctx, "delete", inc)
+	log.Printf("[Incidents] Resolved #%d", inc.ID)
+	return c.JSON(inc)
+}
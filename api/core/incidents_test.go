@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func TestIncidentsForChannels(t *testing.T) {
+	incidents := []Incident{
+		{ID: 1, AffectedChannels: []string{}},
+		{ID: 2, AffectedChannels: []string{"finance"}},
+		{ID: 3, AffectedChannels: []string{"sports", "rss"}},
+	}
+
+	got := incidentsForChannels(incidents, map[string]bool{"rss": true})
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 3 {
+		t.Errorf("rss user: got %+v, want incidents 1 and 3", got)
+	}
+
+	got = incidentsForChannels(incidents, nil)
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("no channels: got %+v, want only the product-wide incident", got)
+	}
+}
+
+func TestNormalizeAffectedChannels(t *testing.T) {
+	valid := map[string]bool{"finance": true, "sports": true}
+
+	got, err := normalizeAffectedChannels([]string{" Finance ", "finance", "", "sports"}, valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "finance" || got[1] != "sports" {
+		t.Errorf("got %v, want [finance sports]", got)
+	}
+
+	if _, err := normalizeAffectedChannels([]string{"weather"}, valid); err == nil {
+		t.Error("unknown channel accepted")
+	}
+}
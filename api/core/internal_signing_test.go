@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestInternalSignatureVector pins the canonical string; each channel's
+// internal_auth_test.go checks the same vector.
+func TestInternalSignatureVector(t *testing.T) {
+	got := internalSignature("test-secret", 1700000000, "POST", "/internal/channel-lifecycle?x=1", []byte(`{"event":"enabled"}`))
+	want := "v1=f8d4a55d975064413765cb4223b1795c68ef010c5249c389cba6528a25e96193"
+	if got != want {
+		t.Errorf("internalSignature = %s; want %s", got, want)
+	}
+}
+
+func TestSignInternalRequest(t *testing.T) {
+	t.Setenv("INTERNAL_SIGNING_SECRET", "test-secret")
+	now := time.Unix(1700000000, 0)
+
+	body := []byte(`{"event":"enabled"}`)
+	req, _ := http.NewRequest("POST", "http://finance-api:8081/internal/channel-lifecycle?x=1", bytes.NewReader(body))
+	signed, err := signInternalRequest(req, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed == req {
+		t.Fatal("signInternalRequest modified the caller's request in place")
+	}
+	if got := signed.Header.Get(InternalTimestampHeader); got != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("timestamp = %q", got)
+	}
+	if got, want := signed.Header.Get(InternalSignatureHeader), internalSignature("test-secret", now.Unix(), "POST", "/internal/channel-lifecycle?x=1", body); got != want {
+		t.Errorf("signature = %q; want %q", got, want)
+	}
+	if req.Header.Get(InternalSignatureHeader) != "" {
+		t.Error("original request gained a signature header")
+	}
+	sent, _ := io.ReadAll(signed.Body)
+	if !bytes.Equal(sent, body) {
+		t.Errorf("signed body = %q; want %q", sent, body)
+	}
+
+	// Only /internal/* is signed.
+	pub, _ := http.NewRequest("GET", "http://finance-api:8081/finance", nil)
+	if out, _ := signInternalRequest(pub, now); out.Header.Get(InternalSignatureHeader) != "" {
+		t.Error("public route was signed")
+	}
+
+	// No secret, no headers: the channel answers 503 and the caller logs it.
+	t.Setenv("INTERNAL_SIGNING_SECRET", "")
+	get, _ := http.NewRequest("GET", "http://finance-api:8081/internal/dashboard?user=u1", nil)
+	if out, _ := signInternalRequest(get, now); out.Header.Get(InternalSignatureHeader) != "" {
+		t.Error("signed without a secret")
+	}
+}
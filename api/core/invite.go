@@ -245,8 +245,7 @@ func findActiveOneTimeToken(endpoint, m2mToken, email, ott string) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+m2mToken)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("list request failed: %w", err)
 	}
@@ -299,8 +298,7 @@ func findUserByEmail(endpoint, token, email string) (string, string, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return "", "", fmt.Errorf("search request failed: %w", err)
 	}
@@ -338,8 +336,7 @@ func userHasRole(endpoint, token, userID, roleID string) (bool, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("roles request failed: %w", err)
 	}
@@ -375,8 +372,7 @@ func checkUsernameAvailable(endpoint, token, username string) (bool, error) {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("username search request failed: %w", err)
 	}
@@ -415,8 +411,7 @@ func updateUserPassword(endpoint, token, userID, password string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("password update request failed: %w", err)
 	}
@@ -445,8 +440,7 @@ func updateUserIdentity(endpoint, token, userID, username, name string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("identity update request failed: %w", err)
 	}
@@ -488,8 +482,7 @@ func updateUserProfile(endpoint, token, userID, gender, birthdate, givenName, fa
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("profile update request failed: %w", err)
 	}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v82"
+	stripeinvoice "github.com/stripe/stripe-go/v82/invoice"
+)
+
+// Invoice history.
+//
+// Stripe invoices are mirrored into the invoices table from invoice.*
+// webhooks so users can download receipts without a Stripe round-trip.
+// The first listing for a user with no cached rows backfills from the
+// Stripe API, covering invoices issued before the cache existed.
+// Lifetime purchases are one-off PaymentIntents and have no invoice.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// Invoice is one row of GET /users/me/billing/invoices. Amounts are in
+// the currency's minor unit.
+type Invoice struct {
+	ID               string     `json:"id"`
+	Number           string     `json:"number"`
+	Status           string     `json:"status"`
+	BillingReason    string     `json:"billing_reason,omitempty"`
+	Total            int64      `json:"total"`
+	AmountPaid       int64      `json:"amount_paid"`
+	Currency         string     `json:"currency"`
+	HostedInvoiceURL string     `json:"hosted_invoice_url,omitempty"`
+	InvoicePDF       string     `json:"invoice_pdf,omitempty"`
+	PeriodStart      *time.Time `json:"period_start,omitempty"`
+	PeriodEnd        *time.Time `json:"period_end,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ─── Cache ───────────────────────────────────────────────────────
+
+func unixOrNil(ts int64) *time.Time {
+	if ts <= 0 {
+		return nil
+	}
+	t := time.Unix(ts, 0).UTC()
+	return &t
+}
+
+// upsertInvoice mirrors a Stripe invoice into the cache. Drafts are
+// skipped; later events for the same invoice overwrite earlier ones.
+func upsertInvoice(ctx context.Context, logtoSub string, inv *stripe.Invoice) error {
+	if inv == nil || inv.ID == "" || inv.Status == stripe.InvoiceStatusDraft {
+		return nil
+	}
+	customerID := ""
+	if inv.Customer != nil {
+		customerID = inv.Customer.ID
+	}
+	_, err := DBPool.Exec(ctx, `
+		INSERT INTO invoices (stripe_invoice_id, logto_sub, stripe_customer_id, number, status,
+		                      billing_reason, total, amount_paid, currency, hosted_invoice_url,
+		                      invoice_pdf, period_start, period_end, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (stripe_invoice_id) DO UPDATE SET
+			number             = EXCLUDED.number,
+			status             = EXCLUDED.status,
+			total              = EXCLUDED.total,
+			amount_paid        = EXCLUDED.amount_paid,
+			hosted_invoice_url = EXCLUDED.hosted_invoice_url,
+			invoice_pdf        = EXCLUDED.invoice_pdf,
+			updated_at         = now()`,
+		inv.ID, logtoSub, customerID, inv.Number, string(inv.Status),
+		string(inv.BillingReason), inv.Total, inv.AmountPaid, string(inv.Currency),
+		inv.HostedInvoiceURL, inv.InvoicePDF,
+		unixOrNil(inv.PeriodStart), unixOrNil(inv.PeriodEnd), time.Unix(inv.Created, 0).UTC(),
+	)
+	return err
+}
+
+// cacheInvoiceFromEvent is called from the Stripe webhook for every
+// invoice.* event.
+func cacheInvoiceFromEvent(event stripe.Event) {
+	var inv stripe.Invoice
+	if err := json.Unmarshal(event.Data.Raw, &inv); err != nil {
+		log.Printf("[Stripe Webhook] Failed to parse %s for invoice cache: %v", event.Type, err)
+		return
+	}
+	if inv.Customer == nil {
+		return
+	}
+	logtoSub := lookupLogtoSub(inv.Customer.ID)
+	if logtoSub == "" {
+		return
+	}
+	if err := upsertInvoice(context.Background(), logtoSub, &inv); err != nil {
+		log.Printf("[Stripe Webhook] Failed to cache invoice %s for %s: %v", inv.ID, logtoSub, err)
+	}
+}
+
+// backfillInvoices pulls a customer's invoice history from Stripe into
+// the cache. Runs at most once per InvoiceBackfillInterval per user.
+func backfillInvoices(ctx context.Context, logtoSub string) {
+	if Rdb != nil {
+		ok, err := Rdb.SetNX(ctx, RedisInvoiceBackfillPrefix+logtoSub, "1", InvoiceBackfillInterval).Result()
+		if err == nil && !ok {
+			return
+		}
+	}
+
+	var customerID string
+	if err := DBPool.QueryRow(ctx,
+		`SELECT stripe_customer_id FROM stripe_customers WHERE logto_sub = $1`, logtoSub,
+	).Scan(&customerID); err != nil || customerID == "" {
+		return
+	}
+
+	params := &stripe.InvoiceListParams{Customer: stripe.String(customerID)}
+	params.Limit = stripe.Int64(InvoiceListMax)
+	iter := stripeinvoice.List(params)
+	n := 0
+	for iter.Next() && n < InvoiceListMax {
+		if err := upsertInvoice(ctx, logtoSub, iter.Invoice()); err != nil {
+			log.Printf("[Invoices] backfill upsert failed for %s: %v", logtoSub, err)
+			return
+		}
+		n++
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("[Invoices] backfill list failed for %s: %v", logtoSub, err)
+		return
+	}
+	log.Printf("[Invoices] Backfilled %d invoice(s) for %s", n, logtoSub)
+}
+
+func listCachedInvoices(ctx context.Context, logtoSub string, limit int) ([]Invoice, error) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT stripe_invoice_id, number, status, billing_reason, total, amount_paid, currency,
+		       hosted_invoice_url, invoice_pdf, period_start, period_end, created_at
+		  FROM invoices
+		 WHERE logto_sub = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`, logtoSub, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]Invoice, 0)
+	for rows.Next() {
+		var inv Invoice
+		if err := rows.Scan(&inv.ID, &inv.Number, &inv.Status, &inv.BillingReason,
+			&inv.Total, &inv.AmountPaid, &inv.Currency, &inv.HostedInvoiceURL,
+			&inv.InvoicePDF, &inv.PeriodStart, &inv.PeriodEnd, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// HandleListInvoices returns the caller's invoices, newest first, with
+// links to Stripe's hosted page and PDF.
+//
+// @Summary List invoices
+// @Tags Billing
+// @Produce json
+// @Param limit query int false "Max invoices (default 24, max 100)"
+// @Success 200 {object} object{invoices=[]Invoice}
+// @Security LogtoAuth
+// @Router /users/me/billing/invoices [get]
+func HandleListInvoices(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	limit := InvoiceListDefault
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error", Error: "limit must be a positive integer",
+			})
+		}
+		limit = min(n, InvoiceListMax)
+	}
+
+	invoices, err := listCachedInvoices(ctx, userID, limit)
+	if err == nil && len(invoices) == 0 {
+		backfillInvoices(ctx, userID)
+		invoices, err = listCachedInvoices(ctx, userID, limit)
+	}
+	if err != nil {
+		log.Printf("[Invoices] list failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load invoices",
+		})
+	}
+	return c.JSON(fiber.Map{"invoices": invoices})
+}
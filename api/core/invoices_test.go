@@ -0,0 +1,16 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnixOrNil(t *testing.T) {
+	if unixOrNil(0) != nil {
+		t.Error("zero timestamp should be nil")
+	}
+	got := unixOrNil(1_700_000_000)
+	if got == nil || !got.Equal(time.Unix(1_700_000_000, 0)) || got.Location() != time.UTC {
+		t.Errorf("unixOrNil = %v", got)
+	}
+}
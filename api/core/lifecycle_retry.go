@@ -0,0 +1,362 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Channel lifecycle retry queue.
+//
+// callChannelLifecycle used to be fire-and-forget: if a channel was
+// restarting when a user changed their config, the event was logged and
+// lost, and the channel's subscriber state silently diverged from
+// user_channels. Failed events are now queued per channel in Redis and
+// replayed in order:
+//
+//   - by a background worker, with exponential backoff per channel, and
+//   - immediately when discovery sees a channel (re)register, so a
+//     restarted channel catches up on startup.
+//
+// Channels advertising the lifecycle_replay capability receive the backlog
+// in batches via POST /internal/channel-lifecycle/replay; older channels
+// get the events one at a time on /internal/channel-lifecycle. Events older
+// than LifecycleRetryMaxAge are dropped — by then the next sync from the
+// dashboard will have reconciled the user anyway.
+
+// lifecycleEvent is both the queued form and the wire body of a lifecycle
+// call. Channels ignore id and first_failed_at.
+type lifecycleEvent struct {
+	ID            string                 `json:"id,omitempty"`
+	Event         string                 `json:"event"`
+	User          string                 `json:"user"`
+	Config        map[string]interface{} `json:"config"`
+	OldConfig     map[string]interface{} `json:"old_config,omitempty"`
+	Enabled       *bool                  `json:"enabled,omitempty"`
+	FirstFailedAt int64                  `json:"first_failed_at,omitempty"` // Unix ms
+}
+
+// lifecycleReplayResponse is returned by a channel's replay endpoint.
+// Processed counts events applied in order before the first failure.
+type lifecycleReplayResponse struct {
+	Processed int `json:"processed"`
+}
+
+// ─── Delivery ────────────────────────────────────────────────────
+
+// deliverLifecycleEvent POSTs a single event to the channel.
+func deliverLifecycleEvent(ctx context.Context, ch *ChannelInfo, ev lifecycleEvent) error {
+	ev.ID, ev.FirstFailedAt = "", 0
+	resp, err := postLifecycle(withTraceChannel(ctx, ch.Name), ch.InternalURL+"/internal/channel-lifecycle", ev)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// replayLifecycleBatch sends events to the channel's replay endpoint and
+// returns how many were applied.
+func replayLifecycleBatch(ctx context.Context, ch *ChannelInfo, events []lifecycleEvent) (int, error) {
+	resp, err := postLifecycle(withTraceChannel(ctx, ch.Name), ch.InternalURL+"/internal/channel-lifecycle/replay",
+		map[string]interface{}{"events": events})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var out lifecycleReplayResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decode replay response (status %d): %w", resp.StatusCode, err)
+	}
+	if out.Processed < 0 || out.Processed > len(events) {
+		return 0, fmt.Errorf("replay reported %d processed of %d", out.Processed, len(events))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return out.Processed, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return out.Processed, nil
+}
+
+func postLifecycle(ctx context.Context, url string, body interface{}) (*http.Response, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return lifecycleClient.Do(req)
+}
+
+// ─── Queue ───────────────────────────────────────────────────────
+
+// enqueueLifecycleRetry appends ev to the channel's retry queue. Runs on
+// a detached context: the caller's request context may already be done.
+func enqueueLifecycleRetry(channelType string, ev lifecycleEvent, reason string) {
+	if Rdb == nil {
+		log.Printf("[LifecycleRetry] Dropping %s/%s for %s (no Redis): %s", channelType, ev.Event, ev.User, reason)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("[LifecycleRetry] Failed to generate event id: %v", err)
+		return
+	}
+	ev.ID = hex.EncodeToString(b)
+	if ev.FirstFailedAt == 0 {
+		ev.FirstFailedAt = time.Now().UnixMilli()
+	}
+	member, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[LifecycleRetry] Failed to marshal event: %v", err)
+		return
+	}
+
+	seq, err := Rdb.Incr(ctx, RedisLifecycleRetrySeqKey).Result()
+	if err != nil {
+		log.Printf("[LifecycleRetry] Dropping %s/%s for %s (seq INCR failed: %v): %s", channelType, ev.Event, ev.User, err, reason)
+		return
+	}
+
+	key := RedisLifecycleRetryPrefix + channelType
+	pipe := Rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(seq), Member: member})
+	pipe.ZRemRangeByRank(ctx, key, 0, -int64(LifecycleRetryMaxPending)-1)
+	pipe.Expire(ctx, key, LifecycleRetryMaxAge)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[LifecycleRetry] Dropping %s/%s for %s (enqueue failed: %v): %s", channelType, ev.Event, ev.User, err, reason)
+		return
+	}
+	log.Printf("[LifecycleRetry] Queued %s/%s for %s: %s", channelType, ev.Event, ev.User, reason)
+}
+
+// lifecycleRetryPending reports whether the channel has queued events. A
+// live call must queue behind them, or a replayed "updated" could undo a
+// newer config. Errors report false so a Redis outage doesn't block
+// delivery.
+func lifecycleRetryPending(ctx context.Context, channelType string) bool {
+	if Rdb == nil {
+		return false
+	}
+	n, err := Rdb.ZCard(ctx, RedisLifecycleRetryPrefix+channelType).Result()
+	return err == nil && n > 0
+}
+
+// lifecycleRetryBackoff is the delay before the next attempt after the
+// given number of consecutive failures (1-based).
+func lifecycleRetryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := LifecycleRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= LifecycleRetryMaxDelay {
+			return LifecycleRetryMaxDelay
+		}
+	}
+	return d
+}
+
+// lifecycleEventExpired reports whether ev has been failing for longer
+// than LifecycleRetryMaxAge.
+func lifecycleEventExpired(ev lifecycleEvent, now time.Time) bool {
+	return now.Sub(time.UnixMilli(ev.FirstFailedAt)) > LifecycleRetryMaxAge
+}
+
+// ─── Worker ──────────────────────────────────────────────────────
+
+// StartLifecycleRetryWorker drains the retry queues of all discovered
+// lifecycle channels every LifecycleRetryInterval for the lifetime of ctx.
+func StartLifecycleRetryWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(LifecycleRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, ch := range GetAllChannels() {
+					if ch.HasCapability("channel_lifecycle") {
+						processLifecycleRetries(ctx, ch.Name, false)
+					}
+				}
+			}
+		}
+	}()
+	log.Printf("[LifecycleRetry] Worker started (%s interval)", LifecycleRetryInterval)
+}
+
+// replayLifecycleRetriesNow is called by discovery when a channel
+// (re)registers. It skips the backoff: the channel was likely down, and
+// it's up now.
+func replayLifecycleRetriesNow(ctx context.Context, channelType string) {
+	ctx, cancel := context.WithTimeout(ctx, LifecycleRetryLockTTL)
+	defer cancel()
+	processLifecycleRetries(ctx, channelType, true)
+}
+
+// processLifecycleRetries replays the channel's queue in order until it is
+// empty or a delivery fails. A per-channel lock, renewed every batch,
+// keeps gateway instances from replaying the same events concurrently.
+func processLifecycleRetries(ctx context.Context, channelType string, force bool) {
+	if Rdb == nil || !lifecycleRetryPending(ctx, channelType) {
+		return
+	}
+
+	lock, ok := acquireLifecycleRetryLock(ctx, channelType)
+	if !ok {
+		return
+	}
+	defer lock.release()
+
+	metaKey := RedisLifecycleRetryMeta + channelType
+	if !force {
+		next, _ := Rdb.HGet(ctx, metaKey, "next_attempt_at").Int64()
+		if next > time.Now().UnixMilli() {
+			return
+		}
+	}
+
+	ch := GetChannel(channelType)
+	if ch == nil || !ch.HasCapability("channel_lifecycle") {
+		return
+	}
+
+	queueKey := RedisLifecycleRetryPrefix + channelType
+	for {
+		if !lock.extend(ctx) {
+			log.Printf("[LifecycleRetry] Lost the %s replay lock, stopping", channelType)
+			return
+		}
+		raw, err := Rdb.ZRange(ctx, queueKey, 0, LifecycleRetryBatchSize-1).Result()
+		if err != nil {
+			log.Printf("[LifecycleRetry] Failed to read %s queue: %v", channelType, err)
+			return
+		}
+		if len(raw) == 0 {
+			Rdb.Del(ctx, metaKey)
+			return
+		}
+
+		now := time.Now()
+		events := make([]lifecycleEvent, 0, len(raw))
+		members := make([]interface{}, 0, len(raw))
+		var stale []interface{}
+		for _, m := range raw {
+			var ev lifecycleEvent
+			if err := json.Unmarshal([]byte(m), &ev); err != nil || lifecycleEventExpired(ev, now) {
+				stale = append(stale, m)
+				continue
+			}
+			events = append(events, ev)
+			members = append(members, m)
+		}
+		if len(stale) > 0 {
+			Rdb.ZRem(ctx, queueKey, stale...)
+			log.Printf("[LifecycleRetry] Dropped %d expired/invalid %s events", len(stale), channelType)
+		}
+
+		processed, err := replayLifecycleEvents(ctx, ch, events)
+		if processed > 0 {
+			Rdb.ZRem(ctx, queueKey, members[:processed]...)
+			log.Printf("[LifecycleRetry] Replayed %d %s events", processed, channelType)
+		}
+		if err != nil {
+			attempts, _ := Rdb.HIncrBy(ctx, metaKey, "attempts", 1).Result()
+			delay := lifecycleRetryBackoff(int(attempts))
+			Rdb.HSet(ctx, metaKey, "next_attempt_at", strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10))
+			Rdb.Expire(ctx, metaKey, LifecycleRetryMaxAge)
+			log.Printf("[LifecycleRetry] Replay to %s failed (attempt %d, next in %s): %v", channelType, attempts, delay, err)
+			return
+		}
+		Rdb.Del(ctx, metaKey)
+	}
+}
+
+// lifecycleRetryLock is a held per-channel replay lock. Its value is a
+// random token so it is only ever extended or released by its holder: a
+// replay that outlives LifecycleRetryLockTTL must not delete a lock
+// another instance has since taken.
+type lifecycleRetryLock struct {
+	key   string
+	token string
+}
+
+var (
+	lifecycleRetryExtendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	lifecycleRetryReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+func acquireLifecycleRetryLock(ctx context.Context, channelType string) (*lifecycleRetryLock, bool) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, false
+	}
+	lock := &lifecycleRetryLock{key: RedisLifecycleRetryLock + channelType, token: hex.EncodeToString(b)}
+	ok, err := Rdb.SetNX(ctx, lock.key, lock.token, LifecycleRetryLockTTL).Result()
+	if err != nil || !ok {
+		return nil, false
+	}
+	return lock, true
+}
+
+// extend renews the lock's TTL before each batch. It reports false once
+// the lock has expired or passed to another instance.
+func (l *lifecycleRetryLock) extend(ctx context.Context) bool {
+	n, err := lifecycleRetryExtendScript.Run(ctx, Rdb, []string{l.key}, l.token, LifecycleRetryLockTTL.Milliseconds()).Int()
+	return err == nil && n == 1
+}
+
+func (l *lifecycleRetryLock) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lifecycleRetryReleaseScript.Run(ctx, Rdb, []string{l.key}, l.token)
+}
+
+// replayLifecycleEvents delivers events in order, batched when the
+// channel supports it, and returns how many were applied.
+func replayLifecycleEvents(ctx context.Context, ch *ChannelInfo, events []lifecycleEvent) (int, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+	if ch.HasCapability("lifecycle_replay") {
+		return replayLifecycleBatch(ctx, ch, events)
+	}
+	for i, ev := range events {
+		if err := deliverLifecycleEvent(ctx, ch, ev); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
@@ -59,7 +59,7 @@ func getM2MConfig() logtoM2MConfig {
 	return logtoM2MConfig{
 		Endpoint:       endpoint,
 		AppID:          os.Getenv("LOGTO_M2M_APP_ID"),
-		AppSecret:      os.Getenv("LOGTO_M2M_APP_SECRET"),
+		AppSecret:      Secret("LOGTO_M2M_APP_SECRET"),
 		RoleID:         os.Getenv("LOGTO_UPLINK_ROLE_ID"),
 		ProRoleID:      os.Getenv("LOGTO_PRO_ROLE_ID"),
 		UltimateRoleID: os.Getenv("LOGTO_ULTIMATE_ROLE_ID"),
@@ -67,6 +67,17 @@ func getM2MConfig() logtoM2MConfig {
 	}
 }
 
+// A rotated app secret leaves the cached token valid at Logto, but drop it
+// so the next call authenticates with the new secret and a bad rotation
+// shows up immediately rather than at token expiry.
+func init() {
+	OnSecretRotate("LOGTO_M2M_APP_SECRET", func(string) {
+		m2mMu.Lock()
+		m2mToken = ""
+		m2mMu.Unlock()
+	})
+}
+
 // readCachedM2MToken returns the currently-cached token if it's still valid,
 // or the empty string otherwise. Uses a read lock so repeated fast-path
 // lookups don't serialize on the token mutex.
@@ -100,8 +111,7 @@ func refreshM2MToken() (string, error) {
 	req.SetBasicAuth(cfg.AppID, cfg.AppSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("M2M token request failed: %w", err)
 	}
@@ -180,8 +190,7 @@ func AssignUplinkRole(logtoSub string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("assign role request failed: %w", err)
 	}
@@ -221,8 +230,7 @@ func AssignProRole(logtoSub string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("assign pro role request failed: %w", err)
 	}
@@ -262,8 +270,7 @@ func AssignUltimateRole(logtoSub string) error {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("assign ultimate role request failed: %w", err)
 	}
@@ -298,8 +305,7 @@ func RemoveProRole(logtoSub string) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("remove pro role request failed: %w", err)
 	}
@@ -334,8 +340,7 @@ func RemoveUltimateRole(logtoSub string) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("remove ultimate role request failed: %w", err)
 	}
@@ -370,8 +375,7 @@ func RemoveUplinkRole(logtoSub string) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("remove role request failed: %w", err)
 	}
@@ -407,8 +411,7 @@ func DeleteLogtoUser(logtoSub string) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{Timeout: LogtoM2MTokenTimeout}
-	resp, err := client.Do(req)
+	resp, err := logtoHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("delete user request failed: %w", err)
 	}
@@ -423,3 +426,42 @@ func DeleteLogtoUser(logtoSub string) error {
 	log.Printf("[Logto M2M] Deleted user %s", logtoSub)
 	return nil
 }
+
+// GetLogtoUserEmail returns a user's primary email, or "" when they have
+// none (social sign-ins without an email scope).
+func GetLogtoUserEmail(logtoSub string) (string, error) {
+	cfg := getM2MConfig()
+
+	token, err := getM2MToken()
+	if err != nil {
+		return "", err
+	}
+
+	reqURL := fmt.Sprintf("%s/api/users/%s", cfg.Endpoint, url.PathEscape(logtoSub))
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create get user request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := logtoHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("get user request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get user returned %d: %s", resp.StatusCode, string(body))
+	}
+	var user struct {
+		PrimaryEmail *string `json:"primaryEmail"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil {
+		return "", fmt.Errorf("parse user response: %w", err)
+	}
+	if user.PrimaryEmail == nil {
+		return "", nil
+	}
+	return *user.PrimaryEmail, nil
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics.
+//
+// GET /metrics serves this replica's metrics in the Prometheus text
+// format. It is for the in-cluster scraper only: RequireInternalNetwork
+// hides it from anything that came through the ingress. Each channel API
+// serves the same endpoint, and every deployment carries the
+// prometheus.io/* pod annotations, so one kubernetes_sd scrape job covers
+// the fleet.
+//
+// Metrics live in a private registry rather than the global default so
+// nothing a dependency registers leaks onto the endpoint.
+
+var metricsRegistry = prometheus.NewRegistry()
+
+// metricsRouteLocal overrides the route label for requests that the
+// catch-all proxy serves, so they report the channel's route template
+// instead of the middleware mount point.
+const metricsRouteLocal = "metrics_route"
+
+// metricsRouteUnmatched labels requests no route handled.
+const metricsRouteUnmatched = "unmatched"
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "scrollr",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Gateway request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	cdcBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "scrollr",
+		Subsystem: "cdc",
+		Name:      "batch_size",
+		Help:      "Records per Sequin webhook delivery.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "scrollr",
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Gateway response cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		cdcBatchSize,
+		cacheLookups,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "scrollr",
+			Subsystem: "sse",
+			Name:      "clients",
+			Help:      "Connected /events and /ws clients on this replica.",
+		}, func() float64 {
+			if globalHub == nil {
+				return 0
+			}
+			return float64(ClientCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "scrollr",
+			Subsystem: "sse",
+			Name:      "dispatch_queue_depth",
+			Help:      "Fan-out jobs waiting for a dispatch worker.",
+		}, func() float64 {
+			if globalHub == nil {
+				return 0
+			}
+			return float64(len(globalHub.dispatchCh))
+		}),
+		poolStatsCollector{},
+	)
+}
+
+// recordCacheLookup counts one lookup against a named gateway cache.
+func recordCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// MetricsMiddleware records request latency, in the Prometheus histogram
+// and in routeLatency for /admin/stats. The route label is the
+// registered template (/users/:username), never the raw path, to keep
+// cardinality bounded.
+func MetricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			status = fe.Code
+		} else {
+			status = fiber.StatusInternalServerError
+		}
+	}
+	route, _ := c.Locals(metricsRouteLocal).(string)
+	if route == "" {
+		route = c.Route().Path
+	}
+	elapsed := time.Since(start)
+	httpRequestDuration.WithLabelValues(c.Method(), route, strconv.Itoa(status)).
+		Observe(elapsed.Seconds())
+	routeLatency.observe(c.Method()+" "+route, elapsed)
+	return err
+}
+
+// RequireInternalNetwork admits only requests that reached the pod
+// directly from a private or loopback address. The ingress always sets
+// X-Forwarded-For, so anything carrying it came from outside the cluster
+// no matter what the socket address says. Rejections are a plain 404 so
+// the endpoint isn't advertised.
+func RequireInternalNetwork(c *fiber.Ctx) error {
+	forwarded := c.Get(fiber.HeaderXForwardedFor) != "" || c.Get("X-Real-IP") != ""
+	if forwarded || !isInternalIP(c.Context().RemoteIP()) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Not found",
+		})
+	}
+	return c.Next()
+}
+
+func isInternalIP(ip net.IP) bool {
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// HandleMetrics serves the Prometheus exposition.
+var HandleMetrics = adaptor.HTTPHandler(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+// ─── Pool stats ──────────────────────────────────────────────────
+
+var (
+	dbPoolConnsDesc = prometheus.NewDesc("scrollr_db_pool_conns",
+		"Postgres pool connections by state (acquired, idle, constructing, total, max).", []string{"state"}, nil)
+	dbPoolAcquiresDesc = prometheus.NewDesc("scrollr_db_pool_acquires_total",
+		"Connections acquired from the Postgres pool.", nil, nil)
+	dbPoolEmptyAcquiresDesc = prometheus.NewDesc("scrollr_db_pool_empty_acquires_total",
+		"Acquires that had to wait because the Postgres pool was empty.", nil, nil)
+	dbPoolAcquireSecondsDesc = prometheus.NewDesc("scrollr_db_pool_acquire_seconds_total",
+		"Total time spent acquiring Postgres connections.", nil, nil)
+
+	redisPoolConnsDesc = prometheus.NewDesc("scrollr_redis_pool_conns",
+		"Redis pool connections by state (idle, stale, total).", []string{"state"}, nil)
+	redisPoolLookupsDesc = prometheus.NewDesc("scrollr_redis_pool_lookups_total",
+		"Redis pool connection lookups by result (hit, miss, timeout).", []string{"result"}, nil)
+)
+
+// poolStatsCollector reads the Postgres and Redis pool counters at scrape
+// time. Either pool may be unset (tests, early startup); it is skipped.
+type poolStatsCollector struct{}
+
+func (poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbPoolConnsDesc
+	ch <- dbPoolAcquiresDesc
+	ch <- dbPoolEmptyAcquiresDesc
+	ch <- dbPoolAcquireSecondsDesc
+	ch <- redisPoolConnsDesc
+	ch <- redisPoolLookupsDesc
+}
+
+func (poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	if DBPool != nil {
+		s := DBPool.Stat()
+		for state, n := range map[string]int32{
+			"acquired":     s.AcquiredConns(),
+			"idle":         s.IdleConns(),
+			"constructing": s.ConstructingConns(),
+			"total":        s.TotalConns(),
+			"max":          s.MaxConns(),
+		} {
+			ch <- prometheus.MustNewConstMetric(dbPoolConnsDesc, prometheus.GaugeValue, float64(n), state)
+		}
+		ch <- prometheus.MustNewConstMetric(dbPoolAcquiresDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+		ch <- prometheus.MustNewConstMetric(dbPoolEmptyAcquiresDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+		ch <- prometheus.MustNewConstMetric(dbPoolAcquireSecondsDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	}
+	if Rdb != nil {
+		s := Rdb.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.IdleConns), "idle")
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.StaleConns), "stale")
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.TotalConns), "total")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Hits), "hit")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Misses), "miss")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Timeouts), "timeout")
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":   true,
+		"10.244.1.7":  true,
+		"172.16.0.3":  true,
+		"192.168.1.1": true,
+		"::1":         true,
+		"fd00::1":     true,
+		"0.0.0.0":     false,
+		"8.8.8.8":     false,
+		"2001:db8::1": false,
+	}
+	for addr, want := range cases {
+		if got := isInternalIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isInternalIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if isInternalIP(nil) {
+		t.Error("nil IP should not be internal")
+	}
+}
+
+func TestRequireInternalNetworkRejectsOutsideCallers(t *testing.T) {
+	app := fiber.New()
+	app.Get("/metrics", RequireInternalNetwork, func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	// fiber's test connection reports 0.0.0.0, which is not internal.
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("direct non-private caller: status = %d, want 404", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.5")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("forwarded caller: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMetricsMiddlewareLabelsRouteTemplate(t *testing.T) {
+	app := fiber.New()
+	app.Use(MetricsMiddleware)
+	app.Get("/metrics-test/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(metricsRouteLocal, "/metrics-test/proxied/:symbol")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/proxied/AAPL"} {
+		if _, err := app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := requestCount(t, "/metrics-test/:id", "204"); got != 2 {
+		t.Errorf("template route count = %d, want 2", got)
+	}
+	if got := requestCount(t, "/metrics-test/proxied/:symbol", "200"); got != 1 {
+		t.Errorf("proxied route count = %d, want 1", got)
+	}
+}
+
+func TestRecordCacheLookup(t *testing.T) {
+	recordCacheLookup("metrics_test", true)
+	recordCacheLookup("metrics_test", false)
+	recordCacheLookup("metrics_test", false)
+
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "scrollr_cache_lookups_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["cache"] == "metrics_test" {
+				counts[labels["result"]] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	if counts["hit"] != 1 || counts["miss"] != 2 {
+		t.Errorf("counts = %v, want hit=1 miss=2", counts)
+	}
+}
+
+// requestCount returns the histogram sample count for a GET route/status.
+func requestCount(t *testing.T, route, status string) uint64 {
+	t.Helper()
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "scrollr_http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["method"] == "GET" && labels["route"] == route && labels["status"] == status {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
@@ -15,7 +15,22 @@ type UserPreferences struct {
 	EnabledSites     []string `json:"enabled_sites"`
 	DisabledSites    []string `json:"disabled_sites"`
 	SubscriptionTier string   `json:"subscription_tier"`
-	UpdatedAt        string   `json:"updated_at"`
+	// AnalyticsOptIn gates POST /telemetry; off until the user opts in.
+	AnalyticsOptIn bool `json:"analytics_opt_in"`
+	// ShowTrending adds platform trending lists to the dashboard.
+	ShowTrending bool `json:"show_trending"`
+	// SpoilerWindows maps a sports league name or fantasy league key to
+	// the hours its scores stay hidden after the game ends; see spoilers.go.
+	SpoilerWindows map[string]int `json:"spoiler_windows"`
+	// Locale is a BCP 47 tag ("en-US"), empty when unset. The RSS channel
+	// defaults its content-language filter to it.
+	Locale string `json:"locale"`
+	// IsPublic exposes GET /users/:username/watchlist; each channel still
+	// opts in separately via "show_on_profile" in its config.
+	IsPublic bool `json:"is_public"`
+	// EmailDigest schedules the channel summary email; see email_digest.go.
+	EmailDigest EmailDigestSchedule `json:"email_digest"`
+	UpdatedAt   string              `json:"updated_at"`
 }
 
 // Channel represents a user's subscription to a data channel.
@@ -57,6 +72,27 @@ type DashboardResponse struct {
 	Data        map[string]interface{} `json:"data"`
 	Preferences *UserPreferences       `json:"preferences,omitempty"`
 	Channels    []Channel              `json:"channels,omitempty"`
+	Incidents   []Incident             `json:"incidents,omitempty"`
+	// Trending is set only when the user enables show_trending.
+	Trending map[string][]TrendingItem `json:"trending,omitempty"`
+	// Organizations carries the shared channels of each org the user
+	// belongs to. Read-only: they're edited under /users/me/orgs.
+	Organizations []OrgDashboard `json:"organizations,omitempty"`
+	// Errors names the channels missing from Data because their fetch
+	// failed, with the reason ("timeout", "unavailable", ...). The rest
+	// of the dashboard is still served.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// OrgDashboard is one organization's shared channels and their data, in
+// the same shape as the personal Channels and Data fields.
+type OrgDashboard struct {
+	ID       int64                  `json:"id"`
+	Name     string                 `json:"name"`
+	Role     string                 `json:"role"`
+	Channels []Channel              `json:"channels"`
+	Data     map[string]interface{} `json:"data"`
+	Errors   map[string]string      `json:"errors,omitempty"`
 }
 
 // HealthResponse represents the aggregated health status.
@@ -65,6 +101,9 @@ type HealthResponse struct {
 	Database string            `json:"database"`
 	Redis    string            `json:"redis"`
 	Services map[string]string `json:"services"`
+	// DegradedMode is true while Redis is unreachable and the gateway is
+	// serving from its in-process fallback cache (redis_breaker.go).
+	DegradedMode bool `json:"degraded_mode"`
 }
 
 // ErrorResponse represents a standard API error.
@@ -88,14 +127,19 @@ type StripeCustomer struct {
 	Lifetime             bool       `json:"lifetime"`
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
+	// Dunning state; see billing_dunning.go.
+	GracePeriodEndsAt       *time.Time `json:"grace_period_ends_at,omitempty"`
+	EntitlementsSuspendedAt *time.Time `json:"entitlements_suspended_at,omitempty"`
 }
 
 // CheckoutRequest is the body for POST /checkout/session.
 type CheckoutRequest struct {
-	PriceID string `json:"price_id"`
+	PriceID   string `json:"price_id"`
+	PromoCode string `json:"promo_code,omitempty"`
 }
 
-// PlanChangeRequest is the body for PUT /users/me/subscription/plan.
+// PlanChangeRequest is the body for PUT /users/me/subscription/plan and
+// POST /users/me/subscription/change.
 type PlanChangeRequest struct {
 	PriceID       string `json:"price_id"`
 	ProrationDate int64  `json:"proration_date,omitempty"`
@@ -135,6 +179,7 @@ type SetupIntentResponse struct {
 type SubscribeRequest struct {
 	SetupIntentID string `json:"setup_intent_id"`
 	PriceID       string `json:"price_id"`
+	PromoCode     string `json:"promo_code,omitempty"`
 }
 
 // SubscribeResponse returns the newly created subscription details.
@@ -166,6 +211,16 @@ type SubscriptionResponse struct {
 	Interval             string     `json:"interval,omitempty"`
 	TrialEnd             *int64     `json:"trial_end,omitempty"`
 	HadPriorSub          bool       `json:"had_prior_sub"`
+	// Trialing is true while the subscription is in its free trial.
+	// TrialEligible/TrialDays describe the trial a new checkout would get.
+	Trialing      bool                  `json:"trialing"`
+	TrialEligible bool                  `json:"trial_eligible"`
+	TrialDays     int64                 `json:"trial_days,omitempty"`
+	Discount      *SubscriptionDiscount `json:"discount,omitempty"`
+	// GracePeriodEndsAt is set while a failed renewal is being retried;
+	// paid features are suspended once it passes unpaid.
+	GracePeriodEndsAt     *time.Time `json:"grace_period_ends_at,omitempty"`
+	EntitlementsSuspended bool       `json:"entitlements_suspended"`
 }
 
 // CheckoutReturnResponse tells the frontend about the checkout outcome.
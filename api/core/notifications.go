@@ -0,0 +1,652 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Notification sources and preferences.
+//
+// saved_searches.go owns the inbox: the notifications table, listing and
+// mark-read. This file adds the other sources and lets each user choose
+// how every kind reaches them:
+//
+//   - price_alert: finance publishes price_alert_events on the owner's
+//     core topic when an alert fires.
+//   - fantasy_score: fantasy publishes fantasy_matchup_scores deltas
+//     while matchups are live. One notification per matchup every
+//     FantasyScoreNotifyEvery, plus one when it goes final.
+//   - breaking_news: new rss_items from feeds the user follows whose
+//     title starts with "Breaking" ("BREAKING:", "Breaking News -").
+//   - saved_search: saved_searches.go.
+//
+// A kind is instant (stored and pushed down the core topic as a
+// "notifications" insert), digest (stored without a push, then summed up
+// in one notification at the user's digest_hour UTC) or off.
+//
+// Every replica receives the channel events through its topic
+// subscription, so every replica tries to notify; the dedupe keys and
+// the fantasy SETNX leave exactly one notification.
+
+// Notification kinds.
+const (
+	NotificationKindSavedSearch  = "saved_search"
+	NotificationKindPriceAlert   = "price_alert"
+	NotificationKindFantasyScore = "fantasy_score"
+	NotificationKindBreakingNews = "breaking_news"
+	NotificationKindDigest       = "digest"
+)
+
+// Notification delivery modes.
+const (
+	NotificationModeInstant = "instant"
+	NotificationModeDigest  = "digest"
+	NotificationModeOff     = "off"
+)
+
+// defaultDigestHour matches the notification_preferences column default.
+const defaultDigestHour = 8
+
+// defaultNotificationModes covers every kind a user can configure.
+// Fantasy scores and breaking news are opt-in. The breaking-news index
+// only loads users with a stored preference, so making that kind default
+// on would need a different index.
+var defaultNotificationModes = map[string]string{
+	NotificationKindSavedSearch:  NotificationModeInstant,
+	NotificationKindPriceAlert:   NotificationModeInstant,
+	NotificationKindFantasyScore: NotificationModeOff,
+	NotificationKindBreakingNews: NotificationModeOff,
+}
+
+// NotificationPreferences is a user's effective settings.
+type NotificationPreferences struct {
+	Modes      map[string]string `json:"modes"`
+	DigestHour int               `json:"digest_hour"`
+}
+
+// notificationPreferencesInput is the PUT body. Kinds left out of modes
+// keep their current setting.
+type notificationPreferencesInput struct {
+	Modes      map[string]string `json:"modes"`
+	DigestHour *int              `json:"digest_hour"`
+}
+
+func validNotificationMode(mode string) bool {
+	switch mode {
+	case NotificationModeInstant, NotificationModeDigest, NotificationModeOff:
+		return true
+	}
+	return false
+}
+
+// loadNotificationPreferences returns sub's stored settings over the
+// defaults. On error the defaults are returned with it.
+func loadNotificationPreferences(ctx context.Context, sub string) (NotificationPreferences, error) {
+	prefs := NotificationPreferences{Modes: maps.Clone(defaultNotificationModes), DigestHour: defaultDigestHour}
+	var stored map[string]string
+	var hour int
+	err := DBPool.QueryRow(ctx,
+		`SELECT modes, digest_hour FROM notification_preferences WHERE logto_sub = $1`, sub,
+	).Scan(&stored, &hour)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, err
+	}
+	for kind, mode := range stored {
+		if _, ok := prefs.Modes[kind]; ok && validNotificationMode(mode) {
+			prefs.Modes[kind] = mode
+		}
+	}
+	prefs.DigestHour = hour
+	return prefs, nil
+}
+
+// modeFor returns the delivery mode for kind. Kinds users can't
+// configure (digest) are always instant.
+func (p NotificationPreferences) modeFor(kind string) string {
+	if mode, ok := p.Modes[kind]; ok {
+		return mode
+	}
+	return NotificationModeInstant
+}
+
+// notifyUser stores n for sub according to their preference for n.Kind.
+// created is false when the kind is off or dedupeKey already produced a
+// notification. If the preferences can't be read the defaults apply.
+func notifyUser(ctx context.Context, sub, dedupeKey string, n *Notification) (created bool, err error) {
+	prefs, err := loadNotificationPreferences(ctx, sub)
+	if err != nil {
+		log.Printf("[Notifications] preferences for %s unavailable, using defaults: %v", sub, err)
+	}
+	return deliverNotification(ctx, sub, dedupeKey, n, prefs.modeFor(n.Kind))
+}
+
+// deliverNotification stores n in the given mode and pushes it when the
+// mode is instant.
+func deliverNotification(ctx context.Context, sub, dedupeKey string, n *Notification, mode string) (bool, error) {
+	if mode == NotificationModeOff {
+		return false, nil
+	}
+	created, err := insertNotification(ctx, sub, dedupeKey, n, mode == NotificationModeDigest)
+	if err != nil || !created {
+		return false, err
+	}
+	if mode == NotificationModeInstant {
+		publishNotification(ctx, sub, *n)
+	}
+	return true, nil
+}
+
+// ─── Channel events ──────────────────────────────────────────────
+
+// userTopicEnvelope is the part of a core-topic payload notifications
+// read.
+type userTopicEnvelope struct {
+	Data []struct {
+		Record   json.RawMessage `json:"record"`
+		Metadata struct {
+			TableName string `json:"table_name"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// Synthetic tables channels publish on users' core topics.
+const (
+	priceAlertEventsTable     = "price_alert_events"     // finance alerts.go
+	fantasyMatchupScoresTable = "fantasy_matchup_scores" // fantasy matchup_live.go
+)
+
+// observeUserTopicEvent is called by the hub for every message on a
+// user's core topic. Payloads that can't carry a notification source are
+// skipped without parsing.
+func observeUserTopicEvent(sub string, payload []byte) {
+	if !bytes.Contains(payload, []byte(`"`+priceAlertEventsTable+`"`)) &&
+		!bytes.Contains(payload, []byte(`"`+fantasyMatchupScoresTable+`"`)) {
+		return
+	}
+	BackgroundPool.Submit("notify-user-event", func(ctx context.Context) {
+		notifyFromUserEvent(ctx, sub, payload)
+	})
+}
+
+func notifyFromUserEvent(ctx context.Context, sub string, payload []byte) {
+	var env userTopicEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return
+	}
+	prefs, err := loadNotificationPreferences(ctx, sub)
+	if err != nil {
+		log.Printf("[Notifications] preferences for %s unavailable, using defaults: %v", sub, err)
+	}
+	for _, d := range env.Data {
+		var kind string
+		switch d.Metadata.TableName {
+		case priceAlertEventsTable:
+			kind = NotificationKindPriceAlert
+		case fantasyMatchupScoresTable:
+			kind = NotificationKindFantasyScore
+		default:
+			continue
+		}
+		mode := prefs.modeFor(kind)
+		if mode == NotificationModeOff {
+			continue
+		}
+
+		var key string
+		var n *Notification
+		if kind == NotificationKindPriceAlert {
+			key, n = priceAlertNotification(d.Record)
+		} else {
+			key, n = fantasyScoreNotification(ctx, sub, d.Record)
+		}
+		if n == nil {
+			continue
+		}
+		if _, err := deliverNotification(ctx, sub, key, n, mode); err != nil {
+			log.Printf("[Notifications] %s notification for %s failed: %v", kind, sub, err)
+		}
+	}
+}
+
+// priceAlertEvent mirrors finance's AlertEvent.
+type priceAlertEvent struct {
+	AlertID          int64     `json:"alert_id"`
+	Symbol           string    `json:"symbol"`
+	Condition        string    `json:"condition"`
+	Threshold        float64   `json:"threshold"`
+	Price            float64   `json:"price"`
+	PercentageChange float64   `json:"percentage_change"`
+	TriggeredAt      time.Time `json:"triggered_at"`
+}
+
+func priceAlertNotification(raw json.RawMessage) (string, *Notification) {
+	var ev priceAlertEvent
+	if err := json.Unmarshal(raw, &ev); err != nil || ev.AlertID == 0 || ev.Symbol == "" {
+		return "", nil
+	}
+	n := &Notification{
+		Kind: NotificationKindPriceAlert,
+		Body: fmt.Sprintf("Last trade %.2f (%+.2f%% today)", ev.Price, ev.PercentageChange),
+	}
+	switch ev.Condition {
+	case "above":
+		n.Title = fmt.Sprintf("%s rose above %.2f", ev.Symbol, ev.Threshold)
+	case "below":
+		n.Title = fmt.Sprintf("%s fell below %.2f", ev.Symbol, ev.Threshold)
+	default:
+		n.Title = fmt.Sprintf("%s moved %+.2f%% today", ev.Symbol, ev.PercentageChange)
+	}
+	return fmt.Sprintf("price_alert:%d:%d", ev.AlertID, ev.TriggeredAt.UnixMilli()), n
+}
+
+// fantasyScoreEvent mirrors fantasy's MatchupScoreDelta.
+type fantasyScoreEvent struct {
+	LeagueKey      string   `json:"league_key"`
+	Week           int      `json:"week"`
+	Status         string   `json:"status"`
+	TeamKey        string   `json:"team_key"`
+	Points         *float64 `json:"points"`
+	OpponentPoints *float64 `json:"opponent_points"`
+}
+
+func fantasyScoreNotification(ctx context.Context, sub string, raw json.RawMessage) (string, *Notification) {
+	var ev fantasyScoreEvent
+	if err := json.Unmarshal(raw, &ev); err != nil || ev.TeamKey == "" || ev.Points == nil || ev.OpponentPoints == nil {
+		return "", nil
+	}
+	us, them := *ev.Points, *ev.OpponentPoints
+	n := &Notification{
+		Kind: NotificationKindFantasyScore,
+		Body: fmt.Sprintf("Week %d matchup", ev.Week),
+	}
+
+	if ev.Status == "postevent" {
+		switch {
+		case us > them:
+			n.Title = fmt.Sprintf("Final: you won %.2f–%.2f", us, them)
+		case us < them:
+			n.Title = fmt.Sprintf("Final: you lost %.2f–%.2f", us, them)
+		default:
+			n.Title = fmt.Sprintf("Final: tied at %.2f", us)
+		}
+		return fmt.Sprintf("fantasy_final:%s:%d", ev.TeamKey, ev.Week), n
+	}
+
+	if !allowFantasyScoreNotification(ctx, sub, ev) {
+		return "", nil
+	}
+	switch {
+	case us > them:
+		n.Title = fmt.Sprintf("You lead %.2f–%.2f", us, them)
+	case us < them:
+		n.Title = fmt.Sprintf("You trail %.2f–%.2f", us, them)
+	default:
+		n.Title = fmt.Sprintf("Tied at %.2f", us)
+	}
+	return fmt.Sprintf("fantasy_score:%s:%d:%.2f:%.2f", ev.TeamKey, ev.Week, us, them), n
+}
+
+// allowFantasyScoreNotification claims the user's FantasyScoreNotifyEvery
+// slot for a matchup. Redis errors let the notification through; the
+// dedupe key still stops replicas doubling it.
+func allowFantasyScoreNotification(ctx context.Context, sub string, ev fantasyScoreEvent) bool {
+	if Rdb == nil {
+		return true
+	}
+	key := fmt.Sprintf("%s%s:%s:%d", RedisFantasyScoreRLPrefix, sub, ev.TeamKey, ev.Week)
+	ok, err := Rdb.SetNX(ctx, key, "1", FantasyScoreNotifyEvery).Result()
+	if err != nil {
+		log.Printf("[Notifications] Redis SETNX failed (continuing): %v", err)
+		return true
+	}
+	return ok
+}
+
+// ─── Breaking news ───────────────────────────────────────────────
+
+var breakingHeadline = regexp.MustCompile(`(?i)^\W*breaking(\s+news)?\s*[:|\-–—]`)
+
+// isBreakingHeadline reports whether an RSS title is flagged as breaking
+// news by its publisher.
+func isBreakingHeadline(title string) bool {
+	return breakingHeadline.MatchString(title)
+}
+
+// breakingNewsIndex maps feed URL to the users who want breaking news
+// from it.
+var breakingNewsIndex struct {
+	sync.RWMutex
+	feeds map[string][]string
+}
+
+// reloadBreakingNewsIndex rebuilds the index from users who turned
+// breaking news on.
+func reloadBreakingNewsIndex(ctx context.Context) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT logto_sub FROM notification_preferences
+		WHERE modes->>'breaking_news' IN ('instant', 'digest')
+	`)
+	if err != nil {
+		log.Printf("[Notifications] breaking news reload failed: %v", err)
+		return
+	}
+	var subs []string
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err == nil {
+			subs = append(subs, sub)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("[Notifications] breaking news reload failed: %v", err)
+		return
+	}
+
+	feeds := make(map[string][]string)
+	for _, sub := range subs {
+		for u := range userFeedURLs(sub) {
+			feeds[u] = append(feeds[u], sub)
+		}
+	}
+	breakingNewsIndex.Lock()
+	breakingNewsIndex.feeds = feeds
+	breakingNewsIndex.Unlock()
+}
+
+// NotifyBreakingNews notifies followers of the item's feed when a new
+// rss_items record is breaking news. Runs on BackgroundPool so the Sequin
+// webhook isn't held up.
+func NotifyBreakingNews(rec CDCRecord) {
+	if rec.Metadata.TableName != "rss_items" || rec.Action != "insert" {
+		return
+	}
+	if title, _ := rec.Record["title"].(string); !isBreakingHeadline(title) {
+		return
+	}
+	BackgroundPool.Submit("breaking-news", func(ctx context.Context) {
+		notifyBreakingNews(ctx, rec.Record)
+	})
+}
+
+func notifyBreakingNews(ctx context.Context, record map[string]interface{}) {
+	feedURL, title, _, link, source, key := rssItemFields(record)
+	if feedURL == "" || key == "" {
+		return
+	}
+	breakingNewsIndex.RLock()
+	subs := append([]string(nil), breakingNewsIndex.feeds[feedURL]...)
+	breakingNewsIndex.RUnlock()
+
+	for _, sub := range subs {
+		n := Notification{Kind: NotificationKindBreakingNews, Title: title, Body: source}
+		if link != "" {
+			n.Link = &link
+		}
+		// Prefixed so a saved-search match on the same article doesn't
+		// swallow this one.
+		if _, err := notifyUser(ctx, sub, "breaking:"+key, &n); err != nil {
+			log.Printf("[Notifications] breaking news for %s failed: %v", sub, err)
+		}
+	}
+}
+
+// ─── Digest ──────────────────────────────────────────────────────
+
+// StartNotificationWorker keeps the breaking-news index fresh and sends
+// daily digests. Every replica reloads the index; a Redis lock keeps
+// digests to one replica per run.
+func StartNotificationWorker(ctx context.Context) {
+	go func() {
+		reloadBreakingNewsIndex(ctx)
+
+		reload := time.NewTicker(SavedSearchRefreshInterval)
+		defer reload.Stop()
+		digest := time.NewTicker(DigestCheckInterval)
+		defer digest.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload.C:
+				reloadBreakingNewsIndex(ctx)
+			case <-digest.C:
+				sendDueDigests(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// sendDueDigests sends the digest to every user whose digest hour is now
+// and who has notifications waiting for it.
+func sendDueDigests(ctx context.Context, now time.Time) {
+	if Rdb != nil {
+		ok, err := Rdb.SetNX(ctx, RedisDigestLock, "1", DigestLockTTL).Result()
+		if err != nil || !ok {
+			return
+		}
+		defer Rdb.Del(context.Background(), RedisDigestLock)
+	}
+
+	// The 20h floor keeps a digest_hour change from sending twice in a day.
+	rows, err := DBPool.Query(ctx, `
+		SELECT p.logto_sub FROM notification_preferences p
+		WHERE p.digest_hour = $1
+		  AND (p.last_digest_at IS NULL OR p.last_digest_at < $2)
+		  AND EXISTS (SELECT 1 FROM notifications n WHERE n.logto_sub = p.logto_sub AND n.digest_pending)
+	`, now.UTC().Hour(), now.Add(-20*time.Hour))
+	if err != nil {
+		log.Printf("[Notifications] digest scan failed: %v", err)
+		return
+	}
+	var subs []string
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err == nil {
+			subs = append(subs, sub)
+		}
+	}
+	rows.Close()
+
+	for _, sub := range subs {
+		if err := sendDigest(ctx, sub, now); err != nil {
+			log.Printf("[Notifications] digest for %s failed: %v", sub, err)
+		}
+	}
+	if len(subs) > 0 {
+		log.Printf("[Notifications] Sent %d digest(s)", len(subs))
+	}
+}
+
+// sendDigest releases sub's pending notifications and pushes one
+// notification summarizing them.
+func sendDigest(ctx context.Context, sub string, now time.Time) error {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE notifications SET digest_pending = false
+		WHERE logto_sub = $1 AND digest_pending
+		RETURNING kind
+	`, sub)
+	if err != nil {
+		return fmt.Errorf("release pending: %w", err)
+	}
+	counts := make(map[string]int)
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err == nil {
+			counts[kind]++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("release pending: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE notification_preferences SET last_digest_at = $2 WHERE logto_sub = $1`, sub, now,
+	); err != nil {
+		return fmt.Errorf("stamp digest: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	n := Notification{Kind: NotificationKindDigest, Title: "Your daily digest", Body: digestSummary(counts)}
+	if _, err := deliverNotification(ctx, sub, "digest:"+now.UTC().Format("2006-01-02"), &n, NotificationModeInstant); err != nil {
+		return fmt.Errorf("store digest: %w", err)
+	}
+	return nil
+}
+
+// digestNouns names each kind in a digest summary (singular, plural).
+var digestNouns = []struct{ kind, one, many string }{
+	{NotificationKindPriceAlert, "price alert", "price alerts"},
+	{NotificationKindBreakingNews, "breaking story", "breaking stories"},
+	{NotificationKindSavedSearch, "saved search match", "saved search matches"},
+	{NotificationKindFantasyScore, "fantasy score update", "fantasy score updates"},
+}
+
+// digestSummary renders counts as "2 price alerts · 1 breaking story".
+func digestSummary(counts map[string]int) string {
+	var parts []string
+	for _, d := range digestNouns {
+		switch n := counts[d.kind]; {
+		case n == 1:
+			parts = append(parts, "1 "+d.one)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", n, d.many))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// ─── Preference handlers ─────────────────────────────────────────
+
+// HandleGetNotificationPreferences returns the caller's notification
+// settings, defaults filled in.
+//
+// @Summary Get notification preferences
+// @Tags Users
+// @Produce json
+// @Success 200 {object} NotificationPreferences
+// @Security LogtoAuth
+// @Router /users/me/notifications/preferences [get]
+func HandleGetNotificationPreferences(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	prefs, err := loadNotificationPreferences(c.Context(), userID)
+	if err != nil {
+		log.Printf("[Notifications] load preferences failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load notification preferences",
+		})
+	}
+	return c.JSON(prefs)
+}
+
+// HandleUpdateNotificationPreferences changes the caller's settings.
+// Kinds left out of modes keep their current mode.
+//
+// @Summary Update notification preferences
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body notificationPreferencesInput true "Modes per kind (instant, digest, off) and digest hour (UTC)"
+// @Success 200 {object} NotificationPreferences
+// @Failure 400 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/notifications/preferences [put]
+func HandleUpdateNotificationPreferences(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req notificationPreferencesInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	for kind, mode := range req.Modes {
+		if _, ok := defaultNotificationModes[kind]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Unknown notification kind %q", kind),
+			})
+		}
+		if !validNotificationMode(mode) {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  fmt.Sprintf("Mode for %s must be instant, digest or off", kind),
+			})
+		}
+	}
+	if req.DigestHour != nil && (*req.DigestHour < 0 || *req.DigestHour > 23) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "digest_hour must be between 0 and 23",
+		})
+	}
+	if req.Modes == nil {
+		req.Modes = map[string]string{}
+	}
+
+	ctx := c.Context()
+	if _, err := DBPool.Exec(ctx, `
+		INSERT INTO notification_preferences (logto_sub, modes, digest_hour)
+		VALUES ($1, $2, COALESCE($3, $4))
+		ON CONFLICT (logto_sub) DO UPDATE SET
+			modes       = notification_preferences.modes || EXCLUDED.modes,
+			digest_hour = COALESCE($3, notification_preferences.digest_hour),
+			updated_at  = now()
+	`, userID, req.Modes, req.DigestHour, defaultDigestHour); err != nil {
+		log.Printf("[Notifications] save preferences failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to save notification preferences",
+		})
+	}
+	if _, ok := req.Modes[NotificationKindBreakingNews]; ok {
+		BackgroundPool.Submit("breaking-news-reload", reloadBreakingNewsIndex)
+	}
+
+	prefs, err := loadNotificationPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("[Notifications] load preferences failed: %v", err)
+	}
+	return c.JSON(prefs)
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestIsBreakingHeadline(t *testing.T) {
+	cases := map[string]bool{
+		"BREAKING: Fed cuts rates":             true,
+		"Breaking News - Storm makes landfall": true,
+		"breaking | Markets halt":              true,
+		"** BREAKING: Tower fire":              true,
+		"Breaking Bad star returns":            false,
+		"Ice breaking records in Arctic":       false,
+		"":                                     false,
+	}
+	for title, want := range cases {
+		if got := isBreakingHeadline(title); got != want {
+			t.Errorf("isBreakingHeadline(%q) = %v; want %v", title, got, want)
+		}
+	}
+}
+
+func TestDigestSummary(t *testing.T) {
+	got := digestSummary(map[string]int{
+		NotificationKindBreakingNews: 1,
+		NotificationKindPriceAlert:   3,
+		"unknown":                    5,
+	})
+	if want := "3 price alerts · 1 breaking story"; got != want {
+		t.Errorf("digestSummary = %q; want %q", got, want)
+	}
+}
+
+func TestNotificationPreferencesModeFor(t *testing.T) {
+	p := NotificationPreferences{Modes: map[string]string{NotificationKindPriceAlert: NotificationModeDigest}}
+	if got := p.modeFor(NotificationKindPriceAlert); got != NotificationModeDigest {
+		t.Errorf("price_alert mode = %s", got)
+	}
+	if got := p.modeFor(NotificationKindDigest); got != NotificationModeInstant {
+		t.Errorf("digest mode = %s; digests are always instant", got)
+	}
+	if defaultNotificationModes[NotificationKindFantasyScore] != NotificationModeOff ||
+		defaultNotificationModes[NotificationKindBreakingNews] != NotificationModeOff {
+		t.Error("fantasy_score and breaking_news must be opt-in")
+	}
+}
+
+func TestPriceAlertNotification(t *testing.T) {
+	raw := json.RawMessage(`{"event":"price_alert","alert_id":7,"symbol":"AAPL","condition":"above",
+		"threshold":200,"price":201.5,"percentage_change":1.25,"triggered_at":"2026-10-16T14:00:00Z"}`)
+	key, n := priceAlertNotification(raw)
+	if n == nil {
+		t.Fatal("no notification")
+	}
+	if key != "price_alert:7:1792159200000" {
+		t.Errorf("key = %q", key)
+	}
+	if n.Kind != NotificationKindPriceAlert || n.Title != "AAPL rose above 200.00" || n.Body != "Last trade 201.50 (+1.25% today)" {
+		t.Errorf("notification = %+v", n)
+	}
+
+	if _, n := priceAlertNotification(json.RawMessage(`{"symbol":"AAPL"}`)); n != nil {
+		t.Error("event without alert_id produced a notification")
+	}
+}
+
+func TestFantasyScoreNotification(t *testing.T) {
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	live := json.RawMessage(`{"league_key":"449.l.1","week":6,"status":"midevent","team_key":"449.l.1.t.3","points":54.2,"opponent_points":41}`)
+	key, n := fantasyScoreNotification(ctx, "user-1", live)
+	if n == nil || n.Title != "You lead 54.20–41.00" || key != "fantasy_score:449.l.1.t.3:6:54.20:41.00" {
+		t.Fatalf("first live update: key=%q n=%+v", key, n)
+	}
+	// Throttled until FantasyScoreNotifyEvery passes.
+	if _, n := fantasyScoreNotification(ctx, "user-1", live); n != nil {
+		t.Error("second live update was not throttled")
+	}
+	// Another user in the same matchup has their own slot.
+	if _, n := fantasyScoreNotification(ctx, "user-2", live); n == nil {
+		t.Error("throttle leaked across users")
+	}
+
+	final := json.RawMessage(`{"league_key":"449.l.1","week":6,"status":"postevent","team_key":"449.l.1.t.3","points":98.1,"opponent_points":112.4}`)
+	key, n = fantasyScoreNotification(ctx, "user-1", final)
+	if n == nil || n.Title != "Final: you lost 98.10–112.40" || key != "fantasy_final:449.l.1.t.3:6" {
+		t.Errorf("final: key=%q n=%+v", key, n)
+	}
+
+	pre := json.RawMessage(`{"league_key":"449.l.1","week":7,"status":"preevent","team_key":"449.l.1.t.3","points":null}`)
+	if _, n := fantasyScoreNotification(ctx, "user-1", pre); n != nil {
+		t.Error("pre-game delta produced a notification")
+	}
+}
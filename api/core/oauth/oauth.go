@@ -0,0 +1,341 @@
+// Package oauth runs third-party OAuth2 account connections for the core
+// gateway: CSRF state, the code exchange, encrypted token storage,
+// refresh, and the popup page the callback renders.
+//
+// A provider is added by registering a Provider config:
+//
+//	m.Register(oauth.Provider{
+//		Name:         "strava",
+//		AuthURL:      "https://www.strava.com/oauth/authorize",
+//		TokenURL:     "https://www.strava.com/oauth/token",
+//		ClientID:     core.Secret("STRAVA_CLIENT_ID"),
+//		ClientSecret: core.Secret("STRAVA_CLIENT_SECRET"),
+//		Scopes:       []string{"read"},
+//		Identify:     fetchStravaAthleteID,
+//	})
+//
+// The gateway exposes every registered provider through the same routes
+// (core/connections.go). Channel services keep their own OAuth code: the
+// module-isolation rule means they cannot import this package.
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUnknownProvider is returned for a provider name nobody registered.
+	ErrUnknownProvider = errors.New("oauth: unknown provider")
+
+	// ErrInvalidState is returned when a callback's state is missing,
+	// expired, already used, or was issued for another provider.
+	ErrInvalidState = errors.New("oauth: invalid or expired state")
+
+	// ErrNotConnected is returned when the user has no stored connection.
+	ErrNotConnected = errors.New("oauth: not connected")
+
+	// ErrReauthRequired is returned when the provider rejected the stored
+	// refresh token. The connection is marked revoked until the user
+	// connects again.
+	ErrReauthRequired = errors.New("oauth: provider rejected the stored token; reconnect required")
+)
+
+// providerNameRegex keeps provider names safe for URLs, Redis keys and
+// postMessage types.
+var providerNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// Provider configures one OAuth2 provider.
+type Provider struct {
+	// Name is the URL segment and storage key ("strava").
+	Name string
+	// DisplayName is shown on the popup page; defaults to Name.
+	DisplayName string
+
+	AuthURL      string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL defaults to Manager.CallbackBaseURL + "/oauth/{name}/callback".
+	RedirectURL string
+	Scopes      []string
+	// AuthParams are extra query parameters on the consent URL, such as
+	// prompt=login or access_type=offline.
+	AuthParams map[string]string
+	// AuthStyle is how the client credentials are sent to TokenURL;
+	// zero lets x/oauth2 auto-detect.
+	AuthStyle oauth2.AuthStyle
+
+	// Identify returns the provider-side account ID for a fresh token.
+	// Optional; without it connections record no account ID.
+	Identify func(ctx context.Context, token *oauth2.Token) (string, error)
+}
+
+func (p *Provider) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   p.AuthURL,
+			TokenURL:  p.TokenURL,
+			AuthStyle: p.AuthStyle,
+		},
+	}
+}
+
+// MessageType is the postMessage type the popup sends its opener on
+// success ("strava-auth-complete").
+func (p *Provider) MessageType() string {
+	return p.Name + "-auth-complete"
+}
+
+// Connection is one user's stored link to a provider.
+type Connection struct {
+	Provider  string
+	LogtoSub  string
+	AccountID string
+	Scope     string
+	Status    string // "active" or "revoked"
+	Token     *oauth2.Token
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Connection statuses.
+const (
+	StatusActive  = "active"
+	StatusRevoked = "revoked"
+)
+
+// Manager runs the flow for every registered provider.
+type Manager struct {
+	// CallbackBaseURL is the public gateway origin used to derive each
+	// provider's default RedirectURL.
+	CallbackBaseURL string
+	// StateTTL bounds how long a user has to finish consent.
+	StateTTL time.Duration
+
+	states StateStore
+	tokens TokenStore
+
+	mu        sync.RWMutex
+	providers map[string]*Provider
+}
+
+// DefaultStateTTL matches the Yahoo flow's ten-minute window.
+const DefaultStateTTL = 10 * time.Minute
+
+// NewManager builds a Manager over the given state and token stores.
+func NewManager(callbackBaseURL string, states StateStore, tokens TokenStore) *Manager {
+	return &Manager{
+		CallbackBaseURL: strings.TrimRight(callbackBaseURL, "/"),
+		StateTTL:        DefaultStateTTL,
+		states:          states,
+		tokens:          tokens,
+		providers:       make(map[string]*Provider),
+	}
+}
+
+// Register adds a provider. It fails on an invalid or duplicate name or a
+// config missing its endpoints or client credentials.
+func (m *Manager) Register(p Provider) error {
+	if !providerNameRegex.MatchString(p.Name) {
+		return fmt.Errorf("oauth: invalid provider name %q", p.Name)
+	}
+	if p.AuthURL == "" || p.TokenURL == "" || p.ClientID == "" || p.ClientSecret == "" {
+		return fmt.Errorf("oauth: provider %s needs AuthURL, TokenURL, ClientID and ClientSecret", p.Name)
+	}
+	if p.RedirectURL == "" {
+		if m.CallbackBaseURL == "" {
+			return fmt.Errorf("oauth: provider %s has no RedirectURL and the manager no CallbackBaseURL", p.Name)
+		}
+		p.RedirectURL = m.CallbackBaseURL + "/oauth/" + p.Name + "/callback"
+	}
+	if p.DisplayName == "" {
+		p.DisplayName = p.Name
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, dup := m.providers[p.Name]; dup {
+		return fmt.Errorf("oauth: provider %s registered twice", p.Name)
+	}
+	m.providers[p.Name] = &p
+	return nil
+}
+
+// Provider returns a registered provider by name.
+func (m *Manager) Provider(name string) (*Provider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Providers lists registered provider names.
+func (m *Manager) Providers() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.providers))
+	for name := range m.providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Start issues a state bound to logtoSub and returns the consent URL.
+func (m *Manager) Start(ctx context.Context, provider, logtoSub string) (string, error) {
+	p, err := m.Provider(provider)
+	if err != nil {
+		return "", err
+	}
+	state, err := m.states.Issue(ctx, PendingAuth{Provider: p.Name, LogtoSub: logtoSub}, m.StateTTL)
+	if err != nil {
+		return "", fmt.Errorf("oauth: store state: %w", err)
+	}
+
+	opts := make([]oauth2.AuthCodeOption, 0, len(p.AuthParams))
+	for k, v := range p.AuthParams {
+		opts = append(opts, oauth2.SetAuthURLParam(k, v))
+	}
+	return p.config().AuthCodeURL(state, opts...), nil
+}
+
+// Complete consumes the state, exchanges the code, identifies the
+// account, and stores the connection. The state is single-use: a replayed
+// callback fails with ErrInvalidState.
+func (m *Manager) Complete(ctx context.Context, provider, state, code string) (*Connection, error) {
+	p, err := m.Provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" || code == "" {
+		return nil, ErrInvalidState
+	}
+	pending, err := m.states.Consume(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if pending.Provider != p.Name || pending.LogtoSub == "" {
+		return nil, ErrInvalidState
+	}
+
+	token, err := p.config().Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: %s code exchange: %w", p.Name, err)
+	}
+
+	conn := &Connection{
+		Provider: p.Name,
+		LogtoSub: pending.LogtoSub,
+		Scope:    grantedScope(token, p.Scopes),
+		Status:   StatusActive,
+		Token:    token,
+	}
+	if p.Identify != nil {
+		if conn.AccountID, err = p.Identify(ctx, token); err != nil {
+			return nil, fmt.Errorf("oauth: %s identify: %w", p.Name, err)
+		}
+	}
+	if err := m.tokens.Save(ctx, conn); err != nil {
+		return nil, fmt.Errorf("oauth: save %s connection: %w", p.Name, err)
+	}
+	return conn, nil
+}
+
+// Token returns a valid access token for the user, refreshing and
+// persisting it when expired. A refresh the provider rejects marks the
+// connection revoked and returns ErrReauthRequired.
+func (m *Manager) Token(ctx context.Context, provider, logtoSub string) (*oauth2.Token, error) {
+	p, err := m.Provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := m.tokens.Load(ctx, p.Name, logtoSub)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Status != StatusActive {
+		return nil, ErrReauthRequired
+	}
+
+	fresh, err := p.config().TokenSource(ctx, conn.Token).Token()
+	if err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) && re.ErrorCode == "invalid_grant" {
+			if markErr := m.tokens.SetStatus(ctx, p.Name, logtoSub, StatusRevoked); markErr != nil {
+				return nil, fmt.Errorf("oauth: mark %s revoked: %w", p.Name, markErr)
+			}
+			return nil, ErrReauthRequired
+		}
+		return nil, fmt.Errorf("oauth: %s refresh: %w", p.Name, err)
+	}
+	if fresh.AccessToken != conn.Token.AccessToken || fresh.RefreshToken != conn.Token.RefreshToken {
+		if fresh.RefreshToken == "" {
+			fresh.RefreshToken = conn.Token.RefreshToken
+		}
+		conn.Token = fresh
+		if err := m.tokens.Save(ctx, conn); err != nil {
+			return nil, fmt.Errorf("oauth: save refreshed %s token: %w", p.Name, err)
+		}
+	}
+	return fresh, nil
+}
+
+// Disconnect deletes the user's connection. Deleting one that doesn't
+// exist is not an error.
+func (m *Manager) Disconnect(ctx context.Context, provider, logtoSub string) error {
+	p, err := m.Provider(provider)
+	if err != nil {
+		return err
+	}
+	return m.tokens.Delete(ctx, p.Name, logtoSub)
+}
+
+// Connections lists the user's connections to registered providers.
+// Tokens are omitted.
+func (m *Manager) Connections(ctx context.Context, logtoSub string) ([]Connection, error) {
+	all, err := m.tokens.List(ctx, logtoSub)
+	if err != nil {
+		return nil, err
+	}
+	out := all[:0]
+	for _, c := range all {
+		if _, err := m.Provider(c.Provider); err == nil {
+			c.Token = nil
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// grantedScope prefers the scope the provider reports granting over the
+// one requested.
+func grantedScope(token *oauth2.Token, requested []string) string {
+	if s, ok := token.Extra("scope").(string); ok && s != "" {
+		return s
+	}
+	return strings.Join(requested, " ")
+}
+
+// callbackOrigin returns scheme://host for a URL, or "" when it has none.
+func callbackOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,276 @@
+package oauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// memTokens is an in-memory TokenStore.
+type memTokens struct {
+	mu    sync.Mutex
+	conns map[string]Connection
+}
+
+func (s *memTokens) key(provider, sub string) string { return provider + "|" + sub }
+
+func (s *memTokens) Save(_ context.Context, c *Connection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok := *c.Token
+	cp := *c
+	cp.Token = &tok
+	s.conns[s.key(c.Provider, c.LogtoSub)] = cp
+	return nil
+}
+
+func (s *memTokens) Load(_ context.Context, provider, sub string) (*Connection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.conns[s.key(provider, sub)]
+	if !ok {
+		return nil, ErrNotConnected
+	}
+	tok := *c.Token
+	c.Token = &tok
+	return &c, nil
+}
+
+func (s *memTokens) List(_ context.Context, sub string) ([]Connection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Connection
+	for _, c := range s.conns {
+		if c.LogtoSub == sub {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *memTokens) SetStatus(_ context.Context, provider, sub, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.conns[s.key(provider, sub)]; ok {
+		c.Status = status
+		s.conns[s.key(provider, sub)] = c
+	}
+	return nil
+}
+
+func (s *memTokens) Delete(_ context.Context, provider, sub string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, s.key(provider, sub))
+	return nil
+}
+
+// newTestManager wires a Manager to miniredis, an in-memory token store,
+// and a fake provider whose token endpoint runs tokenHandler.
+func newTestManager(t *testing.T, tokenHandler http.HandlerFunc) (*Manager, *memTokens) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	srv := httptest.NewServer(tokenHandler)
+	t.Cleanup(srv.Close)
+
+	tokens := &memTokens{conns: make(map[string]Connection)}
+	m := NewManager("https://api.example.com/", RedisStateStore{Rdb: rdb}, tokens)
+	if err := m.Register(Provider{
+		Name:         "acme",
+		AuthURL:      "https://acme.example.com/authorize",
+		TokenURL:     srv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "profile"},
+		AuthParams:   map[string]string{"prompt": "login"},
+		AuthStyle:    oauth2.AuthStyleInParams,
+		Identify: func(context.Context, *oauth2.Token) (string, error) {
+			return "acct-1", nil
+		},
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return m, tokens
+}
+
+func TestRegisterValidates(t *testing.T) {
+	m := NewManager("", nil, nil)
+	valid := Provider{Name: "acme", AuthURL: "a", TokenURL: "t", ClientID: "id", ClientSecret: "s", RedirectURL: "r"}
+
+	bad := valid
+	bad.Name = "Acme!"
+	if err := m.Register(bad); err == nil {
+		t.Error("invalid name accepted")
+	}
+	bad = valid
+	bad.ClientSecret = ""
+	if err := m.Register(bad); err == nil {
+		t.Error("missing client secret accepted")
+	}
+	bad = valid
+	bad.RedirectURL = ""
+	if err := m.Register(bad); err == nil {
+		t.Error("missing redirect URL accepted without a callback base")
+	}
+	if err := m.Register(valid); err != nil {
+		t.Fatalf("valid provider rejected: %v", err)
+	}
+	if err := m.Register(valid); err == nil {
+		t.Error("duplicate provider accepted")
+	}
+}
+
+func TestStartAndComplete(t *testing.T) {
+	m, tokens := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("redirect_uri") != "https://api.example.com/oauth/acme/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"at-1","refresh_token":"rt-1","token_type":"bearer","expires_in":3600,"scope":"read"}`))
+	})
+	ctx := context.Background()
+
+	authURL, err := m.Start(ctx, "acme", "user-1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if q.Get("client_id") != "client" || q.Get("prompt") != "login" || q.Get("scope") != "read profile" {
+		t.Errorf("auth URL params = %v", q)
+	}
+	state := q.Get("state")
+	if len(state) != 32 {
+		t.Fatalf("state = %q, want 32 hex chars", state)
+	}
+
+	if _, err := m.Complete(ctx, "other", state, "good-code"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown provider err = %v", err)
+	}
+	conn, err := m.Complete(ctx, "acme", state, "good-code")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if conn.LogtoSub != "user-1" || conn.AccountID != "acct-1" || conn.Scope != "read" || conn.Token.RefreshToken != "rt-1" {
+		t.Errorf("connection = %+v", conn)
+	}
+	if _, err := tokens.Load(ctx, "acme", "user-1"); err != nil {
+		t.Errorf("connection not stored: %v", err)
+	}
+
+	// State is single-use.
+	if _, err := m.Complete(ctx, "acme", state, "good-code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("replayed state err = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	grant := "rt-1"
+	m, tokens := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("refresh_token") != grant {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"at-2","token_type":"bearer","expires_in":3600}`))
+	})
+	ctx := context.Background()
+	_ = tokens.Save(ctx, &Connection{
+		Provider: "acme", LogtoSub: "user-1", Status: StatusActive,
+		Token: &oauth2.Token{AccessToken: "at-1", RefreshToken: "rt-1", Expiry: time.Now().Add(-time.Minute)},
+	})
+
+	tok, err := m.Token(ctx, "acme", "user-1")
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if tok.AccessToken != "at-2" {
+		t.Errorf("access token = %q, want refreshed at-2", tok.AccessToken)
+	}
+	stored, _ := tokens.Load(ctx, "acme", "user-1")
+	if stored.Token.AccessToken != "at-2" || stored.Token.RefreshToken != "rt-1" {
+		t.Errorf("stored token = %+v, want at-2 with the original refresh token kept", stored.Token)
+	}
+
+	// The provider revokes the grant: the next refresh marks the
+	// connection revoked and later calls fail fast.
+	grant = "something-else"
+	stored.Token.Expiry = time.Now().Add(-time.Minute)
+	_ = tokens.Save(ctx, stored)
+	if _, err := m.Token(ctx, "acme", "user-1"); !errors.Is(err, ErrReauthRequired) {
+		t.Fatalf("revoked refresh err = %v, want ErrReauthRequired", err)
+	}
+	if stored, _ := tokens.Load(ctx, "acme", "user-1"); stored.Status != StatusRevoked {
+		t.Errorf("status = %q, want revoked", stored.Status)
+	}
+	if _, err := m.Token(ctx, "acme", "user-1"); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("revoked connection err = %v, want ErrReauthRequired", err)
+	}
+	if _, err := m.Token(ctx, "acme", "nobody"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("missing connection err = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestSealer(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	s, err := NewSealer(key)
+	if err != nil {
+		t.Fatalf("NewSealer: %v", err)
+	}
+	sealed, err := s.Seal("refresh-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if strings.Contains(sealed, "refresh-token") {
+		t.Error("sealed value contains the plaintext")
+	}
+	if got, err := s.Open(sealed); err != nil || got != "refresh-token" {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+
+	other, _ := NewSealer(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 32))))
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Open with the wrong key succeeded")
+	}
+	if _, err := NewSealer("c2hvcnQ="); err == nil {
+		t.Error("short key accepted")
+	}
+}
+
+func TestPopupHTMLEscapes(t *testing.T) {
+	p := &Provider{Name: "acme"}
+	html := PopupHTML(p, "https://app.example.com/settings?x=1", false, `<img src=x onerror=alert(1)>`)
+	if strings.Contains(html, "<img") {
+		t.Error("message was not escaped")
+	}
+	if !strings.Contains(html, `"https://app.example.com"`) {
+		t.Errorf("postMessage target should be the frontend origin:\n%s", html)
+	}
+	if !strings.Contains(html, `"acme-auth-complete"`) {
+		t.Errorf("missing provider message type:\n%s", html)
+	}
+
+	if html := PopupHTML(p, "not a url", true, "ok"); !strings.Contains(html, `&& ""`) {
+		t.Errorf("unparseable frontend URL should post to no origin:\n%s", html)
+	}
+}
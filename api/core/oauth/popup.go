@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// PopupCloseDelayMs is how long the callback popup stays open before
+// closing itself, matching the Yahoo flow.
+const PopupCloseDelayMs = 1500
+
+// popupTemplate is rendered by html/template so the message, type and
+// origin are escaped for their HTML and JS contexts.
+var popupTemplate = template.Must(template.New("popup").Parse(`<!doctype html><html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: ui-sans-serif, system-ui; max-width: 420px; margin: 2rem auto; line-height: 1.5;">
+<p>{{.Message}}</p>
+<script>(function() { try { if (window.opener && {{.Origin}}) { window.opener.postMessage({ type: {{.Type}}, ok: {{.OK}} }, {{.Origin}}); } } catch(e) { } setTimeout(function(){ window.close(); }, {{.Delay}}); })();</script>
+</body></html>`))
+
+// PopupHTML renders the page the callback returns to the consent popup.
+// It posts {type, ok} to the opener at frontendURL's origin (nothing is
+// posted when that origin can't be determined) and closes itself.
+func PopupHTML(p *Provider, frontendURL string, ok bool, message string) string {
+	title := "Auth Complete"
+	if !ok {
+		title = "Auth Error"
+	}
+	msgType := "oauth-auth-complete"
+	if p != nil {
+		msgType = p.MessageType()
+	}
+
+	var buf bytes.Buffer
+	_ = popupTemplate.Execute(&buf, struct {
+		Title, Message, Type, Origin string
+		OK                           bool
+		Delay                        int
+	}{title, message, msgType, callbackOrigin(frontendURL), ok, PopupCloseDelayMs})
+	return buf.String()
+}
@@ -0,0 +1,256 @@
+package oauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// =============================================================================
+// State
+// =============================================================================
+
+// PendingAuth is what a state token stands for between Start and Complete.
+type PendingAuth struct {
+	Provider string `json:"provider"`
+	LogtoSub string `json:"sub"`
+}
+
+// StateStore issues and consumes single-use CSRF state tokens.
+type StateStore interface {
+	Issue(ctx context.Context, p PendingAuth, ttl time.Duration) (string, error)
+	Consume(ctx context.Context, state string) (PendingAuth, error)
+}
+
+// stateKeyPrefix namespaces state keys away from the Yahoo flow's
+// yahoo_state:* keys.
+const stateKeyPrefix = "oauth:state:"
+
+// RedisStateStore keeps state tokens in Redis with a TTL.
+type RedisStateStore struct {
+	Rdb redis.UniversalClient
+}
+
+func (s RedisStateStore) Issue(ctx context.Context, p PendingAuth, ttl time.Duration) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Rdb.Set(ctx, stateKeyPrefix+state, body, ttl).Err(); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// Consume reads and deletes the state atomically, so a replayed callback
+// can't reuse it.
+func (s RedisStateStore) Consume(ctx context.Context, state string) (PendingAuth, error) {
+	var p PendingAuth
+	body, err := s.Rdb.GetDel(ctx, stateKeyPrefix+state).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return p, ErrInvalidState
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return p, ErrInvalidState
+	}
+	return p, nil
+}
+
+// =============================================================================
+// Tokens
+// =============================================================================
+
+// TokenStore persists connections. Implementations must keep tokens
+// encrypted at rest.
+type TokenStore interface {
+	Save(ctx context.Context, c *Connection) error
+	Load(ctx context.Context, provider, logtoSub string) (*Connection, error)
+	List(ctx context.Context, logtoSub string) ([]Connection, error)
+	SetStatus(ctx context.Context, provider, logtoSub, status string) error
+	Delete(ctx context.Context, provider, logtoSub string) error
+}
+
+// PGTokenStore stores connections in oauth_connections (migration 000037),
+// sealing access and refresh tokens with Sealer.
+type PGTokenStore struct {
+	DB     *pgxpool.Pool
+	Sealer *Sealer
+}
+
+func (s *PGTokenStore) Save(ctx context.Context, c *Connection) error {
+	access, err := s.Sealer.Seal(c.Token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refresh := ""
+	if c.Token.RefreshToken != "" {
+		if refresh, err = s.Sealer.Seal(c.Token.RefreshToken); err != nil {
+			return err
+		}
+	}
+	var expiry *time.Time
+	if !c.Token.Expiry.IsZero() {
+		expiry = &c.Token.Expiry
+	}
+
+	_, err = s.DB.Exec(ctx, `
+		INSERT INTO oauth_connections
+			(logto_sub, provider, account_id, access_token, refresh_token, token_type, expires_at, scope, status)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7, $8, $9)
+		ON CONFLICT (logto_sub, provider) DO UPDATE SET
+			account_id    = COALESCE(EXCLUDED.account_id, oauth_connections.account_id),
+			access_token  = EXCLUDED.access_token,
+			refresh_token = COALESCE(EXCLUDED.refresh_token, oauth_connections.refresh_token),
+			token_type    = EXCLUDED.token_type,
+			expires_at    = EXCLUDED.expires_at,
+			scope         = EXCLUDED.scope,
+			status        = EXCLUDED.status,
+			updated_at    = CURRENT_TIMESTAMP
+	`, c.LogtoSub, c.Provider, c.AccountID, access, refresh, c.Token.TokenType, expiry, c.Scope, c.Status)
+	return err
+}
+
+func (s *PGTokenStore) Load(ctx context.Context, provider, logtoSub string) (*Connection, error) {
+	var (
+		c       = Connection{Provider: provider, LogtoSub: logtoSub}
+		access  string
+		refresh string
+		expiry  *time.Time
+		tok     oauth2.Token
+	)
+	err := s.DB.QueryRow(ctx, `
+		SELECT COALESCE(account_id, ''), access_token, COALESCE(refresh_token, ''), token_type,
+		       expires_at, scope, status, created_at, updated_at
+		FROM oauth_connections WHERE logto_sub = $1 AND provider = $2
+	`, logtoSub, provider).Scan(&c.AccountID, &access, &refresh, &tok.TokenType,
+		&expiry, &c.Scope, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotConnected
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.AccessToken, err = s.Sealer.Open(access); err != nil {
+		return nil, err
+	}
+	if refresh != "" {
+		if tok.RefreshToken, err = s.Sealer.Open(refresh); err != nil {
+			return nil, err
+		}
+	}
+	if expiry != nil {
+		tok.Expiry = *expiry
+	}
+	c.Token = &tok
+	return &c, nil
+}
+
+func (s *PGTokenStore) List(ctx context.Context, logtoSub string) ([]Connection, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT provider, COALESCE(account_id, ''), scope, status, created_at, updated_at
+		FROM oauth_connections WHERE logto_sub = $1 ORDER BY provider
+	`, logtoSub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Connection
+	for rows.Next() {
+		c := Connection{LogtoSub: logtoSub}
+		if err := rows.Scan(&c.Provider, &c.AccountID, &c.Scope, &c.Status, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGTokenStore) SetStatus(ctx context.Context, provider, logtoSub, status string) error {
+	_, err := s.DB.Exec(ctx, `
+		UPDATE oauth_connections SET status = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE logto_sub = $1 AND provider = $2
+	`, logtoSub, provider, status)
+	return err
+}
+
+func (s *PGTokenStore) Delete(ctx context.Context, provider, logtoSub string) error {
+	_, err := s.DB.Exec(ctx,
+		`DELETE FROM oauth_connections WHERE logto_sub = $1 AND provider = $2`,
+		logtoSub, provider)
+	return err
+}
+
+// =============================================================================
+// Encryption
+// =============================================================================
+
+// Sealer encrypts tokens with AES-256-GCM.
+// Wire format: base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer builds a Sealer from a base64-encoded 32-byte key.
+func NewSealer(b64Key string) (*Sealer, error) {
+	key, err := base64.StdEncoding.DecodeString(b64Key)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("oauth: encryption key must be base64 of 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+func (s *Sealer) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func (s *Sealer) Open(sealed string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("oauth: decrypt: invalid base64: %w", err)
+	}
+	n := s.aead.NonceSize()
+	if len(raw) < n {
+		return "", fmt.Errorf("oauth: decrypt: ciphertext too short")
+	}
+	plaintext, err := s.aead.Open(nil, raw[:n], raw[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("oauth: decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,475 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ─── Country Resolution ──────────────────────────────────────────
+
+// CountryResolver maps an incoming request to an ISO 3166-1 alpha-2
+// country code, or "" when unknown. The default reads Cloudflare's
+// CF-IPCountry header; a MaxMind-backed resolver can be installed with
+// SetCountryResolver when the gateway isn't behind Cloudflare.
+type CountryResolver interface {
+	ResolveCountry(c *fiber.Ctx) string
+}
+
+// headerCountryResolver trusts CF-IPCountry. Cloudflare strips any
+// client-supplied copy of the header at the edge.
+type headerCountryResolver struct{}
+
+func (headerCountryResolver) ResolveCountry(c *fiber.Ctx) string {
+	return normalizeCountryCode(c.Get("CF-IPCountry"))
+}
+
+var countryResolver CountryResolver = headerCountryResolver{}
+
+// SetCountryResolver replaces the resolver used for onboarding defaults.
+// Call before Setup; not safe to swap while serving.
+func SetCountryResolver(r CountryResolver) {
+	if r != nil {
+		countryResolver = r
+	}
+}
+
+// normalizeCountryCode upper-cases a two-letter code and drops
+// Cloudflare's non-country sentinels (XX = unknown, T1 = Tor).
+func normalizeCountryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code == "XX" || code == "T1" {
+		return ""
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+	}
+	return code
+}
+
+// ─── Regional Defaults ───────────────────────────────────────────
+
+// geoFeed is a curated RSS feed. Every URL below must exist in
+// tracked_feeds with is_default = true, otherwise accepting the
+// proposal would count it against the custom-feed cap (0 on free).
+type geoFeed struct {
+	Name string
+	URL  string
+}
+
+// geoDefaults is the regional starting point for a new account. Lists
+// are ordered most-local first: accepting prunes each list to the
+// user's tier cap, and the free tier keeps only the first league and
+// first feed.
+type geoDefaults struct {
+	Leagues []string
+	Symbols []string
+	Feeds   []geoFeed
+}
+
+var (
+	feedBBC        = geoFeed{"BBC News", "https://feeds.bbci.co.uk/news/rss.xml"}
+	feedNPR        = geoFeed{"NPR News", "https://feeds.npr.org/1001/rss.xml"}
+	feedAlJazeera  = geoFeed{"Al Jazeera", "https://www.aljazeera.com/xml/rss/all.xml"}
+	feedGuardian   = geoFeed{"The Guardian", "https://www.theguardian.com/world/rss"}
+	feedFrance24   = geoFeed{"France 24", "https://www.france24.com/en/rss"}
+	feedDW         = geoFeed{"DW News", "https://rss.dw.com/rdf/rss-en-all"}
+	feedJapanTimes = geoFeed{"The Japan Times", "https://www.japantimes.co.jp/feed/"}
+)
+
+// Regional templates. League names match the `league` column the sports
+// ingester writes; symbols are from the finance catalog, which carries
+// US-listed country ETFs rather than foreign index tickers.
+var (
+	geoDefaultsUS = geoDefaults{
+		Leagues: []string{"NFL", "NBA", "MLB", "NHL"},
+		Symbols: []string{"SPY", "QQQ", "DIA", "AAPL", "BTC/USD"},
+		Feeds:   []geoFeed{feedNPR, feedBBC},
+	}
+	geoDefaultsUK = geoDefaults{
+		Leagues: []string{"Premier League", "Champions League", "Premiership Rugby", "Formula 1"},
+		Symbols: []string{"VGK", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedBBC, feedGuardian},
+	}
+	geoDefaultsEurope = geoDefaults{
+		Leagues: []string{"Champions League", "Formula 1", "Premier League"},
+		Symbols: []string{"VGK", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedDW, feedFrance24, feedGuardian},
+	}
+	geoDefaultsGlobal = geoDefaults{
+		Leagues: []string{"Champions League", "Formula 1"},
+		Symbols: []string{"ACWI", "VT", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedAlJazeera, feedBBC, feedGuardian},
+	}
+)
+
+// geoDefaultsByCountry holds per-country overrides. Countries not listed
+// fall back to their region (see geoRegionFor), then to global.
+var geoDefaultsByCountry = map[string]geoDefaults{
+	"US": geoDefaultsUS,
+	"CA": {
+		Leagues: []string{"NHL", "NBA", "MLS", "NFL"},
+		Symbols: []string{"SPY", "QQQ", "VTI", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedBBC, feedNPR},
+	},
+	"GB": geoDefaultsUK,
+	"IE": geoDefaultsUK,
+	"ES": {
+		Leagues: []string{"La Liga", "Champions League", "Formula 1"},
+		Symbols: []string{"VGK", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedGuardian, feedDW},
+	},
+	"FR": {
+		Leagues: []string{"Starligue", "Champions League", "Six Nations", "Formula 1"},
+		Symbols: []string{"VGK", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedFrance24, feedDW},
+	},
+	"DE": {
+		Leagues: []string{"Champions League", "Handball Bundesliga", "Formula 1"},
+		Symbols: []string{"VGK", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedDW, feedFrance24},
+	},
+	"AU": {
+		Leagues: []string{"AFL", "Super Rugby", "Formula 1"},
+		Symbols: []string{"ACWI", "SPY", "EFA", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedGuardian, feedBBC},
+	},
+	"NZ": {
+		Leagues: []string{"Super Rugby", "Formula 1"},
+		Symbols: []string{"ACWI", "SPY", "EFA", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedGuardian, feedBBC},
+	},
+	"JP": {
+		Leagues: []string{"MLB", "Formula 1"},
+		Symbols: []string{"EWJ", "EFA", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedJapanTimes, feedBBC},
+	},
+	"CN": {
+		Leagues: []string{"NBA", "Formula 1"},
+		Symbols: []string{"FXI", "EEM", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedBBC, feedAlJazeera},
+	},
+	"HK": {
+		Leagues: []string{"Premier League", "Formula 1"},
+		Symbols: []string{"FXI", "EEM", "SPY", "BTC/USD", "ETH/USD"},
+		Feeds:   []geoFeed{feedBBC, feedAlJazeera},
+	},
+}
+
+// europeCountries uses the European template when no per-country
+// override exists.
+var europeCountries = map[string]bool{
+	"AT": true, "BE": true, "CH": true, "CZ": true, "DK": true, "FI": true,
+	"GR": true, "HU": true, "IT": true, "LU": true, "NL": true, "NO": true,
+	"PL": true, "PT": true, "RO": true, "SE": true,
+}
+
+// geoDefaultsFor returns the regional template for a country code.
+func geoDefaultsFor(country string) geoDefaults {
+	if d, ok := geoDefaultsByCountry[country]; ok {
+		return d
+	}
+	if europeCountries[country] {
+		return geoDefaultsEurope
+	}
+	return geoDefaultsGlobal
+}
+
+// buildOnboardingProposal renders a country's template into the
+// channel-type → config shape stored in user_channels.config. Values
+// are []any so PruneChannelConfig can operate on them directly.
+func buildOnboardingProposal(country string) map[string]map[string]any {
+	d := geoDefaultsFor(country)
+
+	leagues := make([]any, len(d.Leagues))
+	for i, l := range d.Leagues {
+		leagues[i] = l
+	}
+	symbols := make([]any, len(d.Symbols))
+	for i, s := range d.Symbols {
+		symbols[i] = s
+	}
+	feeds := make([]any, len(d.Feeds))
+	for i, f := range d.Feeds {
+		feeds[i] = map[string]any{"name": f.Name, "url": f.URL, "is_custom": false}
+	}
+
+	return map[string]map[string]any{
+		"sports":  {"leagues": leagues},
+		"finance": {"symbols": symbols},
+		"rss":     {"feeds": feeds},
+	}
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// OnboardingDefaults is the response for the onboarding-defaults routes.
+type OnboardingDefaults struct {
+	Country  string                    `json:"country"`
+	Status   string                    `json:"status"`
+	Proposal map[string]map[string]any `json:"proposal"`
+}
+
+func loadOnboardingDefaults(ctx context.Context, logtoSub string) (*OnboardingDefaults, error) {
+	var d OnboardingDefaults
+	var proposal []byte
+	err := DBPool.QueryRow(ctx, `
+		SELECT country, status, proposal FROM onboarding_defaults WHERE logto_sub = $1
+	`, logtoSub).Scan(&d.Country, &d.Status, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(proposal, &d.Proposal); err != nil {
+		d.Proposal = map[string]map[string]any{}
+	}
+	return &d, nil
+}
+
+// HandleGetOnboardingDefaults returns the user's proposed channel
+// defaults, creating them from the request's country on first call.
+// Users who already configured channels before ever reaching
+// onboarding get 404 — there's nothing left to seed.
+//
+// @Summary Get onboarding channel defaults
+// @Description Returns geo-based proposed channel configs for a new account
+// @Tags Onboarding
+// @Produce json
+// @Success 200 {object} OnboardingDefaults
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/onboarding/defaults [get]
+func HandleGetOnboardingDefaults(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	d, err := loadOnboardingDefaults(ctx, userID)
+	if err == nil {
+		return c.JSON(d)
+	}
+	if err != pgx.ErrNoRows {
+		log.Printf("[Onboarding] load defaults for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load onboarding defaults",
+		})
+	}
+
+	var channelCount int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM user_channels WHERE logto_sub = $1`, userID,
+	).Scan(&channelCount); err != nil {
+		log.Printf("[Onboarding] count channels for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load onboarding defaults",
+		})
+	}
+	if channelCount > 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "No onboarding defaults for this account",
+		})
+	}
+
+	country := countryResolver.ResolveCountry(c)
+	proposalJSON, _ := json.Marshal(buildOnboardingProposal(country))
+
+	// DO NOTHING + re-read: two tabs hitting onboarding at once must
+	// both see the same proposal, not whichever country won the race.
+	if _, err := DBPool.Exec(ctx, `
+		INSERT INTO onboarding_defaults (logto_sub, country, proposal)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (logto_sub) DO NOTHING
+	`, userID, country, proposalJSON); err != nil {
+		log.Printf("[Onboarding] store defaults for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create onboarding defaults",
+		})
+	}
+	d, err = loadOnboardingDefaults(ctx, userID)
+	if err != nil {
+		log.Printf("[Onboarding] reload defaults for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load onboarding defaults",
+		})
+	}
+
+	log.Printf("[Onboarding] Proposed defaults for %s (country=%q)", userID, country)
+	return c.JSON(d)
+}
+
+// HandleAcceptOnboardingDefaults creates channels from the proposal.
+// The optional body {"channels": ["sports", "rss"]} accepts a subset;
+// omitted means all. Each config is pruned to the caller's tier rather
+// than rejected, and channel types the user already has are skipped so
+// accepting never overwrites anything they set up themselves.
+//
+// @Summary Accept onboarding channel defaults
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Success 200 {object} object{channels=[]Channel}
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/onboarding/defaults/accept [post]
+func HandleAcceptOnboardingDefaults(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var req struct {
+		Channels []string `json:"channels"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Invalid request body",
+			})
+		}
+	}
+
+	d, err := loadOnboardingDefaults(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "No onboarding defaults for this account",
+		})
+	}
+	if err != nil {
+		log.Printf("[Onboarding] load defaults for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to accept onboarding defaults",
+		})
+	}
+	if d.Status != "proposed" {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Onboarding defaults were already " + d.Status,
+		})
+	}
+
+	wanted := make(map[string]bool, len(req.Channels))
+	for _, ct := range req.Channels {
+		wanted[ct] = true
+	}
+
+	tier := tierFromRoles(GetUserRoles(c))
+	validTypes := GetValidChannelTypes()
+	created := make([]Channel, 0, len(d.Proposal))
+	for channelType, config := range d.Proposal {
+		if len(wanted) > 0 && !wanted[channelType] {
+			continue
+		}
+		// Channel not deployed in this environment — skip silently.
+		if !validTypes[channelType] {
+			continue
+		}
+		pruned, _ := PruneChannelConfig(tier, channelType, config)
+		configJSON, _ := json.Marshal(pruned)
+
+		var ch Channel
+		var configBytes []byte
+		err := DBPool.QueryRow(ctx, `
+			INSERT INTO user_channels (logto_sub, channel_type, config)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (logto_sub, channel_type) DO NOTHING
+			RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
+		`, userID, channelType, configJSON).Scan(
+			&ch.ID, &ch.LogtoSub, &ch.ChannelType, &ch.Enabled, &ch.Visible,
+			&configBytes, &ch.CreatedAt, &ch.UpdatedAt,
+		)
+		if err == pgx.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Onboarding] create %s channel for %s failed: %v", channelType, userID, err)
+			continue
+		}
+		if err := json.Unmarshal(configBytes, &ch.Config); err != nil {
+			ch.Config = map[string]interface{}{}
+		}
+
+		bg := context.Background()
+		if ch.Enabled {
+			addChannelSubscriptions(bg, userID, ch.ChannelType, ch.Config)
+		}
+		callChannelLifecycle(bg, ch.ChannelType, "created", userID, ch.Config, nil, nil)
+		created = append(created, ch)
+	}
+
+	if _, err := DBPool.Exec(ctx, `
+		UPDATE onboarding_defaults SET status = 'accepted', resolved_at = now()
+		 WHERE logto_sub = $1
+	`, userID); err != nil {
+		log.Printf("[Onboarding] mark accepted for %s failed: %v", userID, err)
+	}
+
+	InvalidateDashboardCache(userID)
+	InvalidateOverviewCache(ctx, userID)
+
+	log.Printf("[Onboarding] %s accepted defaults: %d channel(s) created", userID, len(created))
+	return c.JSON(fiber.Map{"channels": created})
+}
+
+// HandleDismissOnboardingDefaults records that the user declined the
+// proposal so onboarding stops offering it.
+//
+// @Summary Dismiss onboarding channel defaults
+// @Tags Onboarding
+// @Produce json
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/onboarding/defaults/dismiss [post]
+func HandleDismissOnboardingDefaults(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE onboarding_defaults SET status = 'dismissed', resolved_at = now()
+		 WHERE logto_sub = $1 AND status = 'proposed'
+	`, userID)
+	if err != nil {
+		log.Printf("[Onboarding] dismiss for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to dismiss onboarding defaults",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "No pending onboarding defaults for this account",
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,55 @@
+package core
+
+import "testing"
+
+func TestNormalizeCountryCode(t *testing.T) {
+	cases := map[string]string{
+		"gb":   "GB",
+		" US ": "US",
+		"XX":   "",
+		"T1":   "",
+		"":     "",
+		"USA":  "",
+		"1A":   "",
+	}
+	for in, want := range cases {
+		if got := normalizeCountryCode(in); got != want {
+			t.Errorf("normalizeCountryCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGeoDefaultsFor_Fallbacks(t *testing.T) {
+	if got := geoDefaultsFor("GB").Leagues[0]; got != "Premier League" {
+		t.Errorf("GB first league = %q, want Premier League", got)
+	}
+	if got := geoDefaultsFor("NL").Feeds[0]; got != feedDW {
+		t.Errorf("NL should use the European template, got first feed %q", got.Name)
+	}
+	if got := geoDefaultsFor("").Symbols[0]; got != "ACWI" {
+		t.Errorf("unknown country should use the global template, got %q", got)
+	}
+}
+
+// Accepting prunes to the caller's tier, so every proposal must survive
+// the free-tier caps with at least one entry per channel and stay
+// within them after pruning.
+func TestBuildOnboardingProposal_FitsFreeTier(t *testing.T) {
+	countries := []string{""}
+	for cc := range geoDefaultsByCountry {
+		countries = append(countries, cc)
+	}
+	for _, cc := range countries {
+		for channelType, config := range buildOnboardingProposal(cc) {
+			pruned, _ := PruneChannelConfig("free", channelType, config)
+			if err := ValidateChannelConfig("free", channelType, pruned); err != nil {
+				t.Errorf("%q/%s: pruned proposal fails free tier: %v", cc, channelType, err)
+			}
+			for _, field := range []string{"leagues", "symbols", "feeds"} {
+				if v, ok := pruned[field]; ok && len(asArray(v)) == 0 {
+					t.Errorf("%q/%s: %s empty after prune", cc, channelType, field)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,994 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Organizations and shared channels.
+//
+// An organization owns channel configs (a trading desk's watchlist, a
+// newsroom's feeds) that every member gets read-only on their dashboard
+// next to their personal channels. The configs are ordinary user_channels
+// rows owned by the synthetic sub org:{id}: channel APIs build
+// /internal/dashboard?user=org:{id} from them, ingestion tracks their
+// symbols and leagues through the usual lifecycle hooks, and CDC updates
+// reach members because their SSE topic set includes the org's topics
+// (userTopics in events.go).
+//
+// Admins manage members and shared channels; members can only read and
+// leave. Entitlements follow the acting admin's tier: creating an org
+// needs Uplink Pro or higher, the member cap is the admin's
+// (orgMemberCap), and shared configs are checked against the admin's
+// channel limits exactly as their own would be. Fantasy is personal
+// (it's tied to a Yahoo account) so it can't be shared.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// Organization is an org as seen by one of its members.
+type Organization struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role"` // the caller's role: "admin" | "member"
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrgMember is one member of an organization.
+type OrgMember struct {
+	LogtoSub string    `json:"logto_sub"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrgDetail is the body for GET /users/me/orgs/{id}.
+type OrgDetail struct {
+	Organization
+	Members  []OrgMember `json:"members"`
+	Channels []Channel   `json:"channels"`
+}
+
+const (
+	orgRoleAdmin  = "admin"
+	orgRoleMember = "member"
+)
+
+// orgShareableChannels are the channel types an org can own.
+var orgShareableChannels = map[string]bool{
+	"finance": true,
+	"sports":  true,
+	"rss":     true,
+}
+
+var errLastOrgAdmin = errors.New("organization needs at least one admin")
+
+// ─── Entitlements ────────────────────────────────────────────────
+
+// orgMemberCap returns how many members an admin on this tier may have
+// in an org. ok is false for tiers that can't manage orgs at all; a cap
+// of 0 means unlimited.
+func orgMemberCap(tier string) (limit int, ok bool) {
+	switch tier {
+	case "uplink_pro":
+		return MaxOrgMembersPro, true
+	case "uplink_ultimate":
+		return MaxOrgMembersUltimate, true
+	case "super_user":
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// orgTierRequired is the 403 for callers whose tier can't manage orgs.
+func orgTierRequired(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+		Status: "error", Error: "Organizations require an Uplink Pro or Uplink Ultimate subscription",
+	})
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────
+
+// orgChannelOwner is the user_channels.logto_sub of an org's shared channels.
+func orgChannelOwner(orgID int64) string {
+	return OrgChannelOwnerPrefix + strconv.FormatInt(orgID, 10)
+}
+
+// normalizeOrgName trims a name and reports whether it's usable.
+func normalizeOrgName(raw string) (string, bool) {
+	name := strings.TrimSpace(raw)
+	return name, name != "" && len([]rune(name)) <= MaxOrgNameLength
+}
+
+// userOrganizations lists the orgs a user belongs to, oldest first.
+func userOrganizations(ctx context.Context, logtoSub string) ([]Organization, error) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT o.id, o.name, m.role, o.created_at,
+		       (SELECT COUNT(*) FROM organization_members c WHERE c.org_id = o.id)
+		  FROM organization_members m
+		  JOIN organizations o ON o.id = m.org_id
+		 WHERE m.logto_sub = $1
+		 ORDER BY m.joined_at`, logtoSub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.Role, &o.CreatedAt, &o.MemberCount); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// orgMemberSubs returns every member of an org.
+func orgMemberSubs(ctx context.Context, orgID int64) ([]string, error) {
+	rows, err := DBPool.Query(ctx,
+		`SELECT logto_sub FROM organization_members WHERE org_id = $1`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []string{}
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err == nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, rows.Err()
+}
+
+// orgSharedChannels returns an org's shared channels that members
+// actually get: enabled and of a shareable type.
+func orgSharedChannels(orgID int64) ([]Channel, error) {
+	channels, err := GetUserChannels(orgChannelOwner(orgID))
+	if err != nil {
+		return nil, err
+	}
+	shared := channels[:0]
+	for _, ch := range channels {
+		if ch.Enabled && orgShareableChannels[ch.ChannelType] {
+			shared = append(shared, ch)
+		}
+	}
+	return shared, nil
+}
+
+// refreshOrgMembers makes a shared-channel or membership change visible:
+// members' dashboards are rebuilt on next load, live connections pick up
+// the new topics, and the channel APIs' per-user caches for the org are
+// dropped.
+func refreshOrgMembers(ctx context.Context, orgID int64) {
+	subs, err := orgMemberSubs(ctx, orgID)
+	if err != nil {
+		log.Printf("[Orgs] Failed to list members of org %d: %v", orgID, err)
+	}
+	for _, sub := range subs {
+		InvalidateDashboardCache(sub)
+		UpdateUserTopicSubscriptions(sub)
+	}
+	InvalidateUserCaches(orgChannelOwner(orgID))
+}
+
+// requireOrgRole resolves the :id route param and checks the caller's
+// membership. With admin set, plain members get 403. When ok is false a
+// response has been written and err should be returned.
+func requireOrgRole(c *fiber.Ctx, admin bool) (orgID int64, role string, ok bool, err error) {
+	if GetUserID(c) == "" {
+		return 0, "", false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+	orgID, perr := strconv.ParseInt(c.Params("id"), 10, 64)
+	if perr != nil || orgID <= 0 {
+		return 0, "", false, c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid organization id",
+		})
+	}
+	qerr := DBPool.QueryRow(c.Context(),
+		`SELECT role FROM organization_members WHERE org_id = $1 AND logto_sub = $2`,
+		orgID, GetUserID(c),
+	).Scan(&role)
+	if qerr == pgx.ErrNoRows {
+		// Non-members can't tell an org they're not in from one that
+		// doesn't exist.
+		return 0, "", false, c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Organization not found",
+		})
+	}
+	if qerr != nil {
+		log.Printf("[Orgs] membership lookup failed for org %d: %v", orgID, qerr)
+		return 0, "", false, c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load organization",
+		})
+	}
+	if admin && role != orgRoleAdmin {
+		return 0, "", false, c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "error", Error: "Organization admin required",
+		})
+	}
+	return orgID, role, true, nil
+}
+
+// createOrganization inserts an org and makes its creator the admin.
+func createOrganization(ctx context.Context, creatorSub, name string) (Organization, error) {
+	org := Organization{Name: name, Role: orgRoleAdmin, MemberCount: 1}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return org, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO organizations (name, created_by) VALUES ($1, $2)
+		RETURNING id, created_at
+	`, name, creatorSub).Scan(&org.ID, &org.CreatedAt); err != nil {
+		return org, fmt.Errorf("insert organization: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO organization_members (org_id, logto_sub, role) VALUES ($1, $2, 'admin')
+	`, org.ID, creatorSub); err != nil {
+		return org, fmt.Errorf("insert admin: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return org, fmt.Errorf("commit tx: %w", err)
+	}
+	return org, nil
+}
+
+// removeOrgMember deletes a membership, refusing to remove the last
+// admin while other members remain. It reports whether the org is now
+// empty, in which case the caller deletes it.
+func removeOrgMember(ctx context.Context, orgID int64, memberSub string) (empty bool, err error) {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the org's member rows so two admins leaving at once can't
+	// both pass the last-admin check.
+	var admins, members int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE role = 'admin'), COUNT(*)
+		  FROM (SELECT role FROM organization_members WHERE org_id = $1 FOR UPDATE) m
+	`, orgID).Scan(&admins, &members); err != nil {
+		return false, fmt.Errorf("count members: %w", err)
+	}
+
+	var role string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM organization_members WHERE org_id = $1 AND logto_sub = $2
+		RETURNING role
+	`, orgID, memberSub).Scan(&role)
+	if err != nil {
+		return false, err
+	}
+	if role == orgRoleAdmin && admins == 1 && members > 1 {
+		return false, errLastOrgAdmin
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
+	}
+	return members == 1, nil
+}
+
+// leaveOrganizationsTx drops a user from every org inside an account
+// purge. Orgs left without an admin promote their longest-standing
+// member. Returns the orgs left empty; the caller deletes them after
+// commit.
+func leaveOrganizationsTx(ctx context.Context, tx pgx.Tx, logtoSub string) ([]int64, error) {
+	rows, err := tx.Query(ctx,
+		`DELETE FROM organization_members WHERE logto_sub = $1 RETURNING org_id`, logtoSub)
+	if err != nil {
+		return nil, err
+	}
+	var left []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			left = append(left, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var empty []int64
+	for _, orgID := range left {
+		var remaining, admins int
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE role = 'admin')
+			  FROM organization_members WHERE org_id = $1
+		`, orgID).Scan(&remaining, &admins); err != nil {
+			return nil, err
+		}
+		switch {
+		case remaining == 0:
+			empty = append(empty, orgID)
+		case admins == 0:
+			if _, err := tx.Exec(ctx, `
+				UPDATE organization_members SET role = 'admin'
+				 WHERE org_id = $1 AND logto_sub = (
+				     SELECT logto_sub FROM organization_members
+				      WHERE org_id = $1 ORDER BY joined_at LIMIT 1)
+			`, orgID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return empty, nil
+}
+
+// deleteOrganization removes an org, its memberships and its shared
+// channels, then runs the same subscription cleanup and lifecycle hooks
+// a personal channel delete would.
+func deleteOrganization(ctx context.Context, orgID int64) error {
+	owner := orgChannelOwner(orgID)
+	channels, err := GetUserChannels(owner)
+	if err != nil {
+		return fmt.Errorf("load channels: %w", err)
+	}
+	members, err := orgMemberSubs(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("load members: %w", err)
+	}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM user_channels WHERE logto_sub = $1`, owner); err != nil {
+		return fmt.Errorf("delete channels: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, orgID); err != nil {
+		return fmt.Errorf("delete organization: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	for _, ch := range channels {
+		removeSubscriberSets(ctx, owner, ch.ChannelType, ch.Config)
+		callChannelLifecycle(ctx, ch.ChannelType, "deleted", owner, ch.Config, nil, nil)
+	}
+	for _, sub := range members {
+		InvalidateDashboardCache(sub)
+		UpdateUserTopicSubscriptions(sub)
+	}
+	InvalidateUserCaches(owner)
+	return nil
+}
+
+// ─── Member handlers ─────────────────────────────────────────────
+
+// HandleListOrganizations returns the orgs the caller belongs to.
+//
+// @Summary List my organizations
+// @Tags Organizations
+// @Produce json
+// @Success 200 {object} object{organizations=[]Organization}
+// @Security LogtoAuth
+// @Router /users/me/orgs [get]
+func HandleListOrganizations(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	orgs, err := userOrganizations(c.Context(), userID)
+	if err != nil {
+		log.Printf("[Orgs] list failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load organizations",
+		})
+	}
+	return c.JSON(fiber.Map{"organizations": orgs})
+}
+
+// HandleCreateOrganization creates an org with the caller as its admin.
+//
+// @Summary Create an organization
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param body body object true "Organization" example({"name":"Trading desk"})
+// @Success 201 {object} Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs [post]
+func HandleCreateOrganization(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	if _, ok := orgMemberCap(tierFromRoles(GetUserRoles(c))); !ok {
+		return orgTierRequired(c)
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	name, ok := normalizeOrgName(req.Name)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("name is required (at most %d characters)", MaxOrgNameLength),
+		})
+	}
+
+	var count int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM organization_members WHERE logto_sub = $1`, userID,
+	).Scan(&count); err == nil && count >= MaxOrgsPerUser {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("You can belong to at most %d organizations", MaxOrgsPerUser),
+		})
+	}
+
+	org, err := createOrganization(ctx, userID, name)
+	if err != nil {
+		log.Printf("[Orgs] create failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create organization",
+		})
+	}
+
+	log.Printf("[Orgs] %s created org %d", userID, org.ID)
+	return c.Status(fiber.StatusCreated).JSON(org)
+}
+
+// HandleGetOrganization returns an org's members and shared channels.
+// Member emails are only shown to admins.
+//
+// @Summary Get an organization
+// @Tags Organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} OrgDetail
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id} [get]
+func HandleGetOrganization(c *fiber.Ctx) error {
+	orgID, role, ok, err := requireOrgRole(c, false)
+	if !ok {
+		return err
+	}
+	ctx := c.Context()
+
+	detail := OrgDetail{Members: []OrgMember{}}
+	detail.ID, detail.Role = orgID, role
+	if err := DBPool.QueryRow(ctx,
+		`SELECT name, created_at FROM organizations WHERE id = $1`, orgID,
+	).Scan(&detail.Name, &detail.CreatedAt); err != nil {
+		log.Printf("[Orgs] load org %d failed: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load organization",
+		})
+	}
+
+	rows, err := DBPool.Query(ctx, `
+		SELECT logto_sub, email, role, joined_at FROM organization_members
+		 WHERE org_id = $1 ORDER BY joined_at`, orgID)
+	if err != nil {
+		log.Printf("[Orgs] members query failed for org %d: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load organization",
+		})
+	}
+	for rows.Next() {
+		var m OrgMember
+		if err := rows.Scan(&m.LogtoSub, &m.Email, &m.Role, &m.JoinedAt); err == nil {
+			if role != orgRoleAdmin {
+				m.Email = ""
+			}
+			detail.Members = append(detail.Members, m)
+		}
+	}
+	rows.Close()
+	detail.MemberCount = len(detail.Members)
+
+	detail.Channels, err = GetUserChannels(orgChannelOwner(orgID))
+	if err != nil {
+		log.Printf("[Orgs] channels query failed for org %d: %v", orgID, err)
+		detail.Channels = []Channel{}
+	}
+	return c.JSON(detail)
+}
+
+// ─── Admin handlers ──────────────────────────────────────────────
+
+// HandleRenameOrganization changes an org's name.
+//
+// @Summary Rename an organization
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param body body object true "New name" example({"name":"Rates desk"})
+// @Success 200 {object} object{status=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id} [put]
+func HandleRenameOrganization(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	name, valid := normalizeOrgName(req.Name)
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("name is required (at most %d characters)", MaxOrgNameLength),
+		})
+	}
+
+	if _, err := DBPool.Exec(c.Context(),
+		`UPDATE organizations SET name = $2, updated_at = now() WHERE id = $1`, orgID, name,
+	); err != nil {
+		log.Printf("[Orgs] rename org %d failed: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to rename organization",
+		})
+	}
+	refreshOrgMembers(context.Background(), orgID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleDeleteOrganization deletes an org and its shared channels.
+//
+// @Summary Delete an organization
+// @Tags Organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} object{status=string}
+// @Failure 403 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id} [delete]
+func HandleDeleteOrganization(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+	if err := deleteOrganization(context.Background(), orgID); err != nil {
+		log.Printf("[Orgs] delete org %d failed: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to delete organization",
+		})
+	}
+	log.Printf("[Orgs] %s deleted org %d", GetUserID(c), orgID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleAddOrgMember adds an existing Scrollr account to an org by email.
+//
+// @Summary Add an organization member
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param body body object true "Member" example({"email":"analyst@example.com","role":"member"})
+// @Success 201 {object} OrgMember
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id}/members [post]
+func HandleAddOrgMember(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+	ctx := c.Context()
+
+	limit, entitled := orgMemberCap(tierFromRoles(GetUserRoles(c)))
+	if !entitled {
+		return orgTierRequired(c)
+	}
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	email, valid := normalizeInviteEmail(req.Email)
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "A valid email is required",
+		})
+	}
+	if req.Role == "" {
+		req.Role = orgRoleMember
+	}
+	if req.Role != orgRoleAdmin && req.Role != orgRoleMember {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "role must be admin or member",
+		})
+	}
+
+	if limit > 0 {
+		subs, err := orgMemberSubs(ctx, orgID)
+		if err == nil && len(subs) >= limit {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Status: "error", Error: fmt.Sprintf("Your plan allows %d members per organization", limit),
+			})
+		}
+	}
+
+	token, err := getM2MToken()
+	if err != nil {
+		log.Printf("[Orgs] Failed to get M2M token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to add member",
+		})
+	}
+	memberSub, _, err := findUserByEmail(getM2MConfig().Endpoint, token, email)
+	if err != nil {
+		log.Printf("[Orgs] member lookup failed for %s: %v", maskEmail(email), err)
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "No Scrollr account uses that email",
+		})
+	}
+
+	var orgs int
+	if err := DBPool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM organization_members WHERE logto_sub = $1`, memberSub,
+	).Scan(&orgs); err == nil && orgs >= MaxOrgsPerUser {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("That account already belongs to %d organizations", MaxOrgsPerUser),
+		})
+	}
+
+	m := OrgMember{LogtoSub: memberSub, Email: email, Role: req.Role}
+	err = DBPool.QueryRow(ctx, `
+		INSERT INTO organization_members (org_id, logto_sub, email, role)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (org_id, logto_sub) DO NOTHING
+		RETURNING joined_at
+	`, orgID, memberSub, email, req.Role).Scan(&m.JoinedAt)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "Already a member",
+		})
+	}
+	if err != nil {
+		log.Printf("[Orgs] add member to org %d failed: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to add member",
+		})
+	}
+
+	InvalidateDashboardCache(memberSub)
+	UpdateUserTopicSubscriptions(memberSub)
+	log.Printf("[Orgs] %s added %s to org %d as %s", GetUserID(c), memberSub, orgID, req.Role)
+	return c.Status(fiber.StatusCreated).JSON(m)
+}
+
+// HandleUpdateOrgMember changes a member's role. The last admin can't be
+// demoted.
+//
+// @Summary Change an organization member's role
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param sub path string true "Member Logto sub"
+// @Param body body object true "Role" example({"role":"admin"})
+// @Success 200 {object} object{status=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id}/members/{sub} [put]
+func HandleUpdateOrgMember(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+	memberSub := c.Params("sub")
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil || (req.Role != orgRoleAdmin && req.Role != orgRoleMember) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "role must be admin or member",
+		})
+	}
+
+	// The NOT EXISTS guard keeps at least one other admin around when
+	// demoting, in the same statement as the update.
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE organization_members SET role = $3
+		 WHERE org_id = $1 AND logto_sub = $2
+		   AND ($3 = 'admin' OR EXISTS (
+		       SELECT 1 FROM organization_members
+		        WHERE org_id = $1 AND role = 'admin' AND logto_sub <> $2))
+	`, orgID, memberSub, req.Role)
+	if err != nil {
+		log.Printf("[Orgs] role change in org %d failed: %v", orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to update member",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		DBPool.QueryRow(c.Context(),
+			`SELECT EXISTS (SELECT 1 FROM organization_members WHERE org_id = $1 AND logto_sub = $2)`,
+			orgID, memberSub).Scan(&exists)
+		if exists {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+				Status: "error", Error: "An organization needs at least one admin",
+			})
+		}
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Member not found",
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleRemoveOrgMember removes a member. Admins can remove anyone;
+// members can only remove themselves (leave). Removing the last member
+// deletes the org.
+//
+// @Summary Remove an organization member or leave
+// @Tags Organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param sub path string true "Member Logto sub ('me' to leave)"
+// @Success 200 {object} object{status=string}
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id}/members/{sub} [delete]
+func HandleRemoveOrgMember(c *fiber.Ctx) error {
+	orgID, role, ok, err := requireOrgRole(c, false)
+	if !ok {
+		return err
+	}
+	userID := GetUserID(c)
+	memberSub := c.Params("sub")
+	if memberSub == "me" {
+		memberSub = userID
+	}
+	if memberSub != userID && role != orgRoleAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "error", Error: "Organization admin required",
+		})
+	}
+
+	ctx := context.Background()
+	empty, err := removeOrgMember(ctx, orgID, memberSub)
+	switch {
+	case err == pgx.ErrNoRows:
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Member not found",
+		})
+	case errors.Is(err, errLastOrgAdmin):
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "Promote another admin first — an organization needs at least one",
+		})
+	case err != nil:
+		log.Printf("[Orgs] remove %s from org %d failed: %v", memberSub, orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to remove member",
+		})
+	}
+
+	if empty {
+		if err := deleteOrganization(ctx, orgID); err != nil {
+			log.Printf("[Orgs] delete of empty org %d failed: %v", orgID, err)
+		}
+	} else {
+		InvalidateDashboardCache(memberSub)
+		UpdateUserTopicSubscriptions(memberSub)
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandlePutOrgChannel creates or replaces one of an org's shared channels.
+// The config is checked against the acting admin's channel limits.
+//
+// @Summary Set a shared channel
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param type path string true "Channel type (finance, sports, rss)"
+// @Param body body object true "Shared channel" example({"enabled":true,"config":{"symbols":["AAPL","MSFT"]}})
+// @Success 200 {object} Channel
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id}/channels/{type} [put]
+func HandlePutOrgChannel(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+	userID := GetUserID(c)
+
+	tier := tierFromRoles(GetUserRoles(c))
+	if _, entitled := orgMemberCap(tier); !entitled {
+		return orgTierRequired(c)
+	}
+
+	channelType := c.Params("type")
+	if !orgShareableChannels[channelType] || !GetValidChannelTypes()[channelType] {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "This channel type can't be shared",
+		})
+	}
+
+	var req struct {
+		Enabled *bool                  `json:"enabled"`
+		Config  map[string]interface{} `json:"config"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	if req.Config == nil {
+		req.Config = map[string]interface{}{}
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	if err := ValidateChannelConfig(tier, channelType, req.Config); err != nil {
+		var tle *TierLimitError
+		if errors.As(err, &tle) {
+			log.Printf("[Orgs] Tier limit exceeded for %s in org %d: %s", userID, orgID, tle.Error())
+			return c.Status(fiber.StatusForbidden).JSON(tierLimitErrorResponse(tle))
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: err.Error(),
+		})
+	}
+
+	owner := orgChannelOwner(orgID)
+	ctx := context.Background()
+
+	// Fetch old config before the upsert so channels can diff
+	var oldConfig map[string]interface{}
+	var oldBytes []byte
+	_ = DBPool.QueryRow(ctx,
+		`SELECT config FROM user_channels WHERE logto_sub = $1 AND channel_type = $2`, owner, channelType,
+	).Scan(&oldBytes)
+	if len(oldBytes) > 0 {
+		json.Unmarshal(oldBytes, &oldConfig)
+	}
+
+	configJSON, _ := json.Marshal(req.Config)
+	var ch Channel
+	var configBytes []byte
+	err = DBPool.QueryRow(ctx, `
+		INSERT INTO user_channels (logto_sub, channel_type, enabled, config)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (logto_sub, channel_type) DO UPDATE
+		   SET enabled = EXCLUDED.enabled, config = EXCLUDED.config, updated_at = now()
+		RETURNING id, logto_sub, channel_type, enabled, visible, config, created_at, updated_at
+	`, owner, channelType, enabled, configJSON).Scan(
+		&ch.ID, &ch.LogtoSub, &ch.ChannelType, &ch.Enabled, &ch.Visible,
+		&configBytes, &ch.CreatedAt, &ch.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("[Orgs] put channel %s for org %d failed: %v", channelType, orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to save shared channel",
+		})
+	}
+	if err := json.Unmarshal(configBytes, &ch.Config); err != nil {
+		ch.Config = map[string]interface{}{}
+	}
+
+	if ch.Enabled {
+		addChannelSubscriptions(ctx, owner, channelType, ch.Config)
+	} else {
+		removeChannelSubscriptions(ctx, owner, channelType, ch.Config)
+	}
+	event := "updated"
+	if oldBytes == nil {
+		event = "created"
+	}
+	callChannelLifecycle(ctx, channelType, event, owner, ch.Config, oldConfig, nil)
+	refreshOrgMembers(ctx, orgID)
+
+	return c.JSON(ch)
+}
+
+// HandleDeleteOrgChannel removes one of an org's shared channels.
+//
+// @Summary Remove a shared channel
+// @Tags Organizations
+// @Produce json
+// @Param id path int true "Organization ID"
+// @Param type path string true "Channel type"
+// @Success 200 {object} object{status=string}
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/orgs/{id}/channels/{type} [delete]
+func HandleDeleteOrgChannel(c *fiber.Ctx) error {
+	orgID, _, ok, err := requireOrgRole(c, true)
+	if !ok {
+		return err
+	}
+	owner := orgChannelOwner(orgID)
+	channelType := c.Params("type")
+	ctx := context.Background()
+
+	var configBytes []byte
+	err = DBPool.QueryRow(ctx, `
+		DELETE FROM user_channels WHERE logto_sub = $1 AND channel_type = $2
+		RETURNING config
+	`, owner, channelType).Scan(&configBytes)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Channel not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Orgs] delete channel %s for org %d failed: %v", channelType, orgID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to delete shared channel",
+		})
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(configBytes, &config); err != nil || config == nil {
+		config = map[string]interface{}{}
+	}
+	removeChannelSubscriptions(ctx, owner, channelType, config)
+	callChannelLifecycle(ctx, channelType, "deleted", owner, config, nil, nil)
+	refreshOrgMembers(ctx, orgID)
+
+	return c.JSON(fiber.Map{"status": "ok"})
+}
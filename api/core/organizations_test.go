@@ -0,0 +1,77 @@
+package core
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOrgMemberCap(t *testing.T) {
+	cases := []struct {
+		tier  string
+		limit int
+		ok    bool
+	}{
+		{"free", 0, false},
+		{"uplink", 0, false},
+		{"uplink_pro", MaxOrgMembersPro, true},
+		{"uplink_ultimate", MaxOrgMembersUltimate, true},
+		{"super_user", 0, true},
+		{"bogus", 0, false},
+	}
+	for _, tc := range cases {
+		limit, ok := orgMemberCap(tc.tier)
+		if limit != tc.limit || ok != tc.ok {
+			t.Errorf("orgMemberCap(%q) = (%d, %v), want (%d, %v)", tc.tier, limit, ok, tc.limit, tc.ok)
+		}
+	}
+}
+
+func TestNormalizeOrgName(t *testing.T) {
+	if name, ok := normalizeOrgName("  Rates desk "); !ok || name != "Rates desk" {
+		t.Errorf("normalizeOrgName trimmed to (%q, %v)", name, ok)
+	}
+	if _, ok := normalizeOrgName("   "); ok {
+		t.Error("blank name accepted")
+	}
+	if _, ok := normalizeOrgName(strings.Repeat("é", MaxOrgNameLength)); !ok {
+		t.Error("name at the limit rejected (length must count runes, not bytes)")
+	}
+	if _, ok := normalizeOrgName(strings.Repeat("a", MaxOrgNameLength+1)); ok {
+		t.Error("over-long name accepted")
+	}
+}
+
+func TestOrgChannelOwner(t *testing.T) {
+	if got := orgChannelOwner(42); got != "org:42" {
+		t.Errorf("orgChannelOwner(42) = %q, want org:42", got)
+	}
+	// Fantasy is tied to a personal Yahoo account and must never be shared.
+	if orgShareableChannels["fantasy"] {
+		t.Error("fantasy is shareable")
+	}
+}
+
+func TestRequireOrgRoleRejectsBadID(t *testing.T) {
+	app := fiber.New()
+	app.Get("/users/me/orgs/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "u1")
+		_, _, ok, err := requireOrgRole(c, false)
+		if ok {
+			t.Error("ok for a non-numeric id")
+		}
+		return err
+	})
+
+	for _, id := range []string{"abc", "0", "-3"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/users/me/orgs/"+id, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("id %q: status = %d, want 400", id, resp.StatusCode)
+		}
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"regexp"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// localeRegex loosely matches a BCP 47 tag: a 2-3 letter language
+// followed by up to three subtags ("en", "en-US", "zh-Hant-TW").
+var localeRegex = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8}){0,3}$`)
+
 // tierFromRoles determines the subscription tier based on JWT roles.
 // Priority: super_user > uplink_ultimate > uplink_pro > uplink > free.
 func tierFromRoles(roles []string) string {
@@ -36,37 +41,43 @@ func tierFromRoles(roles []string) string {
 // If roles are provided, the subscription_tier is synced from JWT roles → DB.
 func GetOrCreatePreferences(logtoSub string, roles ...[]string) (*UserPreferences, error) {
 	var prefs UserPreferences
-	var enabledSites, disabledSites []byte
+	var enabledSites, disabledSites, spoilerWindows, emailDigest []byte
 	var updatedAt time.Time
 
 	err := DBPool.QueryRow(context.Background(),
 		`SELECT logto_sub, feed_mode, feed_position, feed_behavior, feed_enabled,
-		        enabled_sites, disabled_sites, subscription_tier, updated_at
+		        enabled_sites, disabled_sites, subscription_tier, analytics_opt_in, show_trending,
+		        spoiler_windows, locale, is_public, email_digest, updated_at
 		 FROM user_preferences WHERE logto_sub = $1`, logtoSub,
 	).Scan(
 		&prefs.LogtoSub, &prefs.FeedMode, &prefs.FeedPosition, &prefs.FeedBehavior,
-		&prefs.FeedEnabled, &enabledSites, &disabledSites, &prefs.SubscriptionTier, &updatedAt,
+		&prefs.FeedEnabled, &enabledSites, &disabledSites, &prefs.SubscriptionTier,
+		&prefs.AnalyticsOptIn, &prefs.ShowTrending, &spoilerWindows, &prefs.Locale, &prefs.IsPublic, &emailDigest, &updatedAt,
 	)
 
 	if err != nil {
-		var esBytes, dsBytes []byte
+		var esBytes, dsBytes, swBytes, edBytes []byte
 		var insertedAt time.Time
 		err = DBPool.QueryRow(context.Background(),
 			`INSERT INTO user_preferences (logto_sub)
 			 VALUES ($1)
 			 ON CONFLICT (logto_sub) DO UPDATE SET logto_sub = EXCLUDED.logto_sub
 			 RETURNING logto_sub, feed_mode, feed_position, feed_behavior, feed_enabled,
-			           enabled_sites, disabled_sites, subscription_tier, updated_at`,
+			           enabled_sites, disabled_sites, subscription_tier, analytics_opt_in, show_trending,
+			           spoiler_windows, locale, is_public, email_digest, updated_at`,
 			logtoSub,
 		).Scan(
 			&prefs.LogtoSub, &prefs.FeedMode, &prefs.FeedPosition, &prefs.FeedBehavior,
-			&prefs.FeedEnabled, &esBytes, &dsBytes, &prefs.SubscriptionTier, &insertedAt,
+			&prefs.FeedEnabled, &esBytes, &dsBytes, &prefs.SubscriptionTier,
+			&prefs.AnalyticsOptIn, &prefs.ShowTrending, &swBytes, &prefs.Locale, &prefs.IsPublic, &edBytes, &insertedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 		enabledSites = esBytes
 		disabledSites = dsBytes
+		spoilerWindows = swBytes
+		emailDigest = edBytes
 		updatedAt = insertedAt
 	}
 
@@ -76,6 +87,8 @@ func GetOrCreatePreferences(logtoSub string, roles ...[]string) (*UserPreference
 	if err := json.Unmarshal(disabledSites, &prefs.DisabledSites); err != nil {
 		prefs.DisabledSites = []string{}
 	}
+	prefs.SpoilerWindows = decodeSpoilerWindows(spoilerWindows)
+	prefs.EmailDigest = decodeEmailDigestSchedule(emailDigest)
 	prefs.UpdatedAt = updatedAt.Format(time.RFC3339)
 
 	// Sync subscription tier from JWT roles if provided
@@ -188,6 +201,61 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 			})
 		}
 	}
+	if v, ok := body["analytics_opt_in"]; ok {
+		if _, isBool := v.(bool); !isBool {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "analytics_opt_in must be a boolean",
+			})
+		}
+	}
+	if v, ok := body["show_trending"]; ok {
+		if _, isBool := v.(bool); !isBool {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "show_trending must be a boolean",
+			})
+		}
+	}
+	if v, ok := body["spoiler_windows"]; ok {
+		if _, err := parseSpoilerWindows(v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  err.Error(),
+			})
+		}
+	}
+	if v, ok := body["email_digest"]; ok {
+		if _, err := parseEmailDigestSchedule(v); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  err.Error(),
+			})
+		}
+	}
+	if v, ok := body["locale"]; ok {
+		if s, isStr := v.(string); !isStr || (s != "" && !localeRegex.MatchString(s)) {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "locale must be a BCP 47 language tag such as 'en-US', or empty",
+			})
+		}
+	}
+	if v, ok := body["is_public"]; ok {
+		b, isBool := v.(bool)
+		if !isBool {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "is_public must be a boolean",
+			})
+		}
+		if b && GetUsername(c) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Claim a username before making your profile public",
+			})
+		}
+	}
 	if v, ok := body["enabled_sites"]; ok {
 		if _, isArr := v.([]interface{}); !isArr {
 			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
@@ -206,7 +274,7 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 	}
 
 	query := `
-		INSERT INTO user_preferences (logto_sub, feed_mode, feed_position, feed_behavior, feed_enabled, enabled_sites, disabled_sites, updated_at)
+		INSERT INTO user_preferences (logto_sub, feed_mode, feed_position, feed_behavior, feed_enabled, enabled_sites, disabled_sites, analytics_opt_in, show_trending, spoiler_windows, locale, is_public, username, email_digest, updated_at)
 		VALUES ($1,
 			COALESCE($2, 'comfort'),
 			COALESCE($3, 'bottom'),
@@ -214,23 +282,38 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 			COALESCE($5, true),
 			COALESCE($6, '[]'::jsonb),
 			COALESCE($7, '[]'::jsonb),
+			COALESCE($8, false),
+			COALESCE($9, false),
+			COALESCE($10, '{}'::jsonb),
+			COALESCE($11, ''),
+			COALESCE($12, false),
+			CASE WHEN COALESCE($12, false) THEN NULLIF($13, '') END,
+			COALESCE($14, '{"frequency": "off", "hour": 8, "weekday": 1}'::jsonb),
 			now()
 		)
 		ON CONFLICT (logto_sub) DO UPDATE SET
-			feed_mode      = COALESCE($2, user_preferences.feed_mode),
-			feed_position  = COALESCE($3, user_preferences.feed_position),
-			feed_behavior  = COALESCE($4, user_preferences.feed_behavior),
-			feed_enabled   = COALESCE($5, user_preferences.feed_enabled),
-			enabled_sites  = COALESCE($6, user_preferences.enabled_sites),
-			disabled_sites = COALESCE($7, user_preferences.disabled_sites),
-			updated_at     = now()
+			feed_mode        = COALESCE($2, user_preferences.feed_mode),
+			feed_position    = COALESCE($3, user_preferences.feed_position),
+			feed_behavior    = COALESCE($4, user_preferences.feed_behavior),
+			feed_enabled     = COALESCE($5, user_preferences.feed_enabled),
+			enabled_sites    = COALESCE($6, user_preferences.enabled_sites),
+			disabled_sites   = COALESCE($7, user_preferences.disabled_sites),
+			analytics_opt_in = COALESCE($8, user_preferences.analytics_opt_in),
+			show_trending    = COALESCE($9, user_preferences.show_trending),
+			spoiler_windows  = COALESCE($10, user_preferences.spoiler_windows),
+			locale           = COALESCE($11, user_preferences.locale),
+			is_public        = COALESCE($12, user_preferences.is_public),
+			username         = CASE WHEN COALESCE($12, user_preferences.is_public)
+			                        THEN COALESCE(NULLIF($13, ''), user_preferences.username) END,
+			email_digest     = COALESCE($14, user_preferences.email_digest),
+			updated_at       = now()
 		RETURNING logto_sub, feed_mode, feed_position, feed_behavior, feed_enabled,
-		          enabled_sites, disabled_sites, updated_at
+		          enabled_sites, disabled_sites, analytics_opt_in, show_trending, spoiler_windows, locale, is_public, email_digest, updated_at
 	`
 
-	var feedMode, feedPosition, feedBehavior *string
-	var feedEnabled *bool
-	var enabledSitesJSON, disabledSitesJSON []byte
+	var feedMode, feedPosition, feedBehavior, locale *string
+	var feedEnabled, analyticsOptIn, showTrending, isPublic *bool
+	var enabledSitesJSON, disabledSitesJSON, spoilerWindowsJSON, emailDigestJSON []byte
 
 	if v, ok := body["feed_mode"].(string); ok {
 		feedMode = &v
@@ -244,6 +327,26 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 	if v, ok := body["feed_enabled"].(bool); ok {
 		feedEnabled = &v
 	}
+	if v, ok := body["analytics_opt_in"].(bool); ok {
+		analyticsOptIn = &v
+	}
+	if v, ok := body["show_trending"].(bool); ok {
+		showTrending = &v
+	}
+	if v, ok := body["locale"].(string); ok {
+		locale = &v
+	}
+	if v, ok := body["is_public"].(bool); ok {
+		isPublic = &v
+	}
+	if v, ok := body["spoiler_windows"]; ok {
+		windows, _ := parseSpoilerWindows(v)
+		spoilerWindowsJSON, _ = json.Marshal(windows)
+	}
+	if v, ok := body["email_digest"]; ok {
+		schedule, _ := parseEmailDigestSchedule(v)
+		emailDigestJSON, _ = json.Marshal(schedule)
+	}
 	if v, ok := body["enabled_sites"]; ok {
 		b, _ := json.Marshal(v)
 		enabledSitesJSON = b
@@ -254,15 +357,16 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 	}
 
 	var prefs UserPreferences
-	var esBytes, dsBytes []byte
+	var esBytes, dsBytes, swBytes, edBytes []byte
 	var updatedAt time.Time
 
 	err := DBPool.QueryRow(context.Background(), query,
 		userID, feedMode, feedPosition, feedBehavior, feedEnabled,
-		enabledSitesJSON, disabledSitesJSON,
+		enabledSitesJSON, disabledSitesJSON, analyticsOptIn, showTrending, spoilerWindowsJSON, locale,
+		isPublic, GetUsername(c), emailDigestJSON,
 	).Scan(
 		&prefs.LogtoSub, &prefs.FeedMode, &prefs.FeedPosition, &prefs.FeedBehavior,
-		&prefs.FeedEnabled, &esBytes, &dsBytes, &updatedAt,
+		&prefs.FeedEnabled, &esBytes, &dsBytes, &prefs.AnalyticsOptIn, &prefs.ShowTrending, &swBytes, &prefs.Locale, &prefs.IsPublic, &edBytes, &updatedAt,
 	)
 	if err != nil {
 		log.Printf("[Preferences] Error updating preferences for %s: %v", userID, err)
@@ -278,10 +382,23 @@ func HandleUpdatePreferences(c *fiber.Ctx) error {
 	if err := json.Unmarshal(dsBytes, &prefs.DisabledSites); err != nil {
 		prefs.DisabledSites = []string{}
 	}
+	prefs.SpoilerWindows = decodeSpoilerWindows(swBytes)
+	prefs.EmailDigest = decodeEmailDigestSchedule(edBytes)
 	prefs.UpdatedAt = updatedAt.Format(time.RFC3339)
 
 	// Invalidate dashboard cache so next poll gets fresh preferences
 	InvalidateDashboardCache(userID)
+	if spoilerWindowsJSON != nil {
+		forgetSpoilerWindows(userID)
+	}
+	if locale != nil {
+		// Channel caches filtered by the old locale (RSS languages)
+		InvalidateUserCaches(userID)
+	}
+
+	if analyticsOptIn != nil {
+		setTelemetryConsent(c.Context(), userID, *analyticsOptIn)
+	}
 
 	return c.JSON(prefs)
 }
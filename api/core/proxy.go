@@ -13,11 +13,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-var proxyClient = &http.Client{
-	Timeout: 70 * time.Second,
-	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+var proxyClient = func() *http.Client {
+	c := NewInternalHTTPClient("channel_proxy", ChannelProxyTimeout)
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
-	},
+	}
+	return c
+}()
+
+// proxiedResponseHeaders are the channel response headers passed through
+// to the client (Set-Cookie is handled separately). Everything else is
+// dropped so channels can't leak internal headers.
+var proxiedResponseHeaders = map[string]bool{
+	"Location":         true,
+	"Content-Type":     true,
+	"Etag":             true,
+	"Cache-Control":    true,
+	"X-Quota-Resource": true,
+	"X-Quota-Used":     true,
+	"X-Quota-Limit":    true,
 }
 
 // SetupDynamicProxy registers a single catch-all route that dynamically resolves
@@ -54,28 +68,72 @@ func dynamicProxyHandler(c *fiber.Ctx) error {
 
 		// If auth is required, validate the JWT inline (without c.Next())
 		if route.Auth {
-			if err := ValidateAuth(c); err != nil {
-				log.Printf("[Proxy] Auth failed for %s %s: %v", requestMethod, requestPath, err)
+			if ok, err := ValidateAuth(c); !ok {
+				log.Printf("[Proxy] Auth failed for %s %s", requestMethod, requestPath)
+				return err
+			}
+		} else if route.APIKey {
+			if ok, err := ValidateAPIKey(c); !ok {
 				return err
 			}
 		}
 
+		c.Locals(metricsRouteLocal, route.Path)
+		c.Locals(traceChannelLocal, intg.Name)
+
 		// Build the target URL with resolved params
 		targetPath := route.Path
 		for paramName, paramValue := range params {
 			targetPath = strings.Replace(targetPath, ":"+paramName, paramValue, 1)
 		}
 
+		if ttl, ok := proxyCacheTTL(c, route); ok {
+			return cachedProxyRequest(c, intg, route, targetPath, ttl)
+		}
 		return proxyRequest(c, intg, route, targetPath)
 	}
 
 	// No matching route found — return 404
+	c.Locals(metricsRouteLocal, metricsRouteUnmatched)
 	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 		"status": "error",
 		"error":  "Not found",
 	})
 }
 
+// optionalProxyIdentity validates a bearer token or access_token cookie
+// if one is present, without writing a 401 on failure. Used for public
+// proxied routes where identity is a nice-to-have.
+func optionalProxyIdentity(c *fiber.Ctx) (string, []string, bool) {
+	tokenString := ""
+	if authHeader := c.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			tokenString = parts[1]
+		}
+	}
+	if tokenString == "" {
+		tokenString = c.Cookies("access_token")
+	}
+	if tokenString == "" {
+		return "", nil, false
+	}
+
+	sub, claims, err := ValidateToken(tokenString)
+	if err != nil || sub == "" {
+		return "", nil, false
+	}
+	var roles []string
+	if rawRoles, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range rawRoles {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return sub, roles, true
+}
+
 // stripAuthCookies removes the core's own auth cookies (access_token and
 // refresh_token, case-insensitive) from a Cookie header value. Returns the
 // filtered header (empty if no cookies remain) so callers can decide
@@ -139,13 +197,14 @@ func proxyRequest(c *fiber.Ctx, intg *ChannelInfo, route ChannelRoute, targetPat
 	}
 
 	// Create the proxy request with an independent context (Fiber's c.Context()
-	// is pooled and may be recycled before the proxy completes).
+	// is pooled and may be recycled before the proxy completes). The user
+	// context only carries the request's trace span.
 	// POST/PUT/DELETE routes (e.g. league import) may take 60s+; GET routes are fast.
 	proxyTimeout := 25 * time.Second
 	if c.Method() == "POST" || c.Method() == "PUT" || c.Method() == "DELETE" {
 		proxyTimeout = 65 * time.Second
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), proxyTimeout)
+	ctx, cancel := context.WithTimeout(withTraceChannel(c.UserContext(), intg.Name), proxyTimeout)
 	defer cancel()
 
 	var bodyReader io.Reader
@@ -167,6 +226,16 @@ func proxyRequest(c *fiber.Ctx, intg *ChannelInfo, route ChannelRoute, targetPat
 		req.Header.Set("Content-Type", ct)
 	}
 
+	// Forward conditional-request validators so channels can answer 304.
+	if inm := c.Get("If-None-Match"); inm != "" {
+		req.Header.Set("If-None-Match", inm)
+	}
+
+	// Forward WebSub push signatures; the RSS channel verifies them.
+	if sig := c.Get("X-Hub-Signature"); sig != "" {
+		req.Header.Set("X-Hub-Signature", sig)
+	}
+
 	// Forward authorization headers (for Yahoo token etc.)
 	if auth := c.Get("Authorization"); auth != "" {
 		req.Header.Set("Authorization", auth)
@@ -195,6 +264,16 @@ func proxyRequest(c *fiber.Ctx, intg *ChannelInfo, route ChannelRoute, targetPat
 			req.Header.Set("X-User-Sub", userID)
 		}
 		req.Header.Set("X-User-Tier", tierFromRoles(GetUserRoles(c)))
+	} else if route.APIKey {
+		// API keys carry no roles, so no X-User-Tier — channels fall
+		// back to their free-tier defaults.
+		req.Header.Set("X-User-Sub", GetUserID(c))
+	} else if userID, roles, ok := optionalProxyIdentity(c); ok {
+		// Public route, but the caller is signed in. Forward identity so
+		// channels can personalize (e.g. quota headers on catalogs). The
+		// route stays public: a missing or bad token just means anonymous.
+		req.Header.Set("X-User-Sub", userID)
+		req.Header.Set("X-User-Tier", tierFromRoles(roles))
 	}
 
 	// Execute the proxy request
@@ -225,8 +304,7 @@ func proxyRequest(c *fiber.Ctx, intg *ChannelInfo, route ChannelRoute, targetPat
 		for _, value := range values {
 			if strings.EqualFold(key, "Set-Cookie") {
 				c.Response().Header.Add(key, value)
-			} else if strings.EqualFold(key, "Location") ||
-				strings.EqualFold(key, "Content-Type") {
+			} else if proxiedResponseHeaders[http.CanonicalHeaderKey(key)] {
 				c.Set(key, value)
 			}
 		}
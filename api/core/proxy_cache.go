@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Gateway response cache for public proxied routes.
+//
+// Anonymous traffic to public channel routes (/sports/public,
+// /finance/symbols, ...) used to be proxied on every request even though
+// the data changes slowly. Routes opt in by registering a cache_ttl; the
+// gateway then serves repeat anonymous GETs from Redis, keyed by
+// method+path+normalized query.
+//
+// Only anonymous requests are cached — signed-in callers get identity
+// forwarded (see optionalProxyIdentity) and may see personalized headers.
+// Each channel has a generation counter baked into its keys; bumping it
+// (InvalidateProxyCache) orphans every entry at once, and the orphans age
+// out on their own TTL.
+
+// cachedProxyResponse is the stored form of a channel response.
+type cachedProxyResponse struct {
+	Status       int    `json:"status"`
+	ContentType  string `json:"content_type,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	CacheControl string `json:"cache_control,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+// proxyCacheTTL returns the TTL for caching this request, or false if the
+// request must go to the channel.
+func proxyCacheTTL(c *fiber.Ctx, route ChannelRoute) (time.Duration, bool) {
+	if Rdb == nil || route.CacheTTL <= 0 || route.Auth || route.APIKey || c.Method() != fiber.MethodGet {
+		return 0, false
+	}
+	if c.Get("Authorization") != "" || c.Cookies("access_token") != "" {
+		return 0, false
+	}
+	ttl := time.Duration(route.CacheTTL) * time.Second
+	if ttl > ProxyCacheMaxTTL {
+		ttl = ProxyCacheMaxTTL
+	}
+	return ttl, true
+}
+
+// proxyCacheRequestHash normalizes the query (sorted keys) so ?a=1&b=2 and
+// ?b=2&a=1 share an entry.
+func proxyCacheRequestHash(method, path, rawQuery string) string {
+	query := rawQuery
+	if values, err := url.ParseQuery(rawQuery); err == nil {
+		query = values.Encode()
+	}
+	sum := sha256.Sum256([]byte(method + " " + path + "?" + query))
+	return hex.EncodeToString(sum[:])
+}
+
+func proxyCacheKey(ctx context.Context, channel string, c *fiber.Ctx) string {
+	gen, _ := Rdb.Get(ctx, RedisProxyCacheGenPrefix+channel).Int64()
+	hash := proxyCacheRequestHash(c.Method(), c.Path(), string(c.Request().URI().QueryString()))
+	return RedisProxyCachePrefix + channel + ":" + strconv.FormatInt(gen, 10) + ":" + hash
+}
+
+// cachedProxyRequest serves the request from the gateway cache, falling
+// back to proxyRequest and storing a cacheable response.
+func cachedProxyRequest(c *fiber.Ctx, intg *ChannelInfo, route ChannelRoute, targetPath string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	key := proxyCacheKey(ctx, intg.Name, c)
+
+	if raw, err := Rdb.Get(ctx, key).Bytes(); err == nil {
+		var cached cachedProxyResponse
+		if err := json.Unmarshal(raw, &cached); err == nil {
+			return serveCachedProxyResponse(c, cached)
+		}
+	}
+
+	if err := proxyRequest(c, intg, route, targetPath); err != nil {
+		return err
+	}
+	c.Set("X-Gateway-Cache", "MISS")
+	recordCacheLookup("proxy", false)
+
+	resp := c.Response()
+	if resp.StatusCode() != fiber.StatusOK || len(resp.Header.Peek("Set-Cookie")) > 0 ||
+		len(resp.Body()) > ProxyCacheMaxBodyBytes {
+		return nil
+	}
+	data, err := json.Marshal(cachedProxyResponse{
+		Status:       resp.StatusCode(),
+		ContentType:  string(resp.Header.ContentType()),
+		ETag:         string(resp.Header.Peek("ETag")),
+		CacheControl: string(resp.Header.Peek("Cache-Control")),
+		Body:         resp.Body(),
+	})
+	if err != nil {
+		return nil
+	}
+	storeCtx, storeCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer storeCancel()
+	if err := Rdb.Set(storeCtx, key, data, ttl).Err(); err != nil {
+		log.Printf("[ProxyCache] Store failed for %s %s: %v", intg.Name, c.Path(), err)
+	}
+	return nil
+}
+
+func serveCachedProxyResponse(c *fiber.Ctx, cached cachedProxyResponse) error {
+	c.Set("X-Gateway-Cache", "HIT")
+	recordCacheLookup("proxy", true)
+	if cached.CacheControl != "" {
+		c.Set("Cache-Control", cached.CacheControl)
+	}
+	if cached.ETag != "" {
+		c.Set("ETag", cached.ETag)
+		if c.Get("If-None-Match") == cached.ETag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+	}
+	if cached.ContentType != "" {
+		c.Set("Content-Type", cached.ContentType)
+	}
+	return c.Status(cached.Status).Send(cached.Body)
+}
+
+// InvalidateProxyCache drops every cached response for a channel by
+// bumping its generation.
+func InvalidateProxyCache(ctx context.Context, channel string) error {
+	if Rdb == nil {
+		return nil
+	}
+	return Rdb.Incr(ctx, RedisProxyCacheGenPrefix+channel).Err()
+}
+
+// HandleAdminInvalidateProxyCache purges a channel's gateway cache, e.g.
+// after a manual data fix.
+//
+// @Summary Invalidate gateway cache for a channel
+// @Tags Admin
+// @Produce json
+// @Param channel path string true "Channel name"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/proxy-cache/{channel}/invalidate [post]
+func HandleAdminInvalidateProxyCache(c *fiber.Ctx) error {
+	channel := c.Params("channel")
+	if GetChannel(channel) == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Unknown channel",
+		})
+	}
+	if err := InvalidateProxyCache(c.Context(), channel); err != nil {
+		log.Printf("[ProxyCache] Invalidate %s failed: %v", channel, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to invalidate cache",
+		})
+	}
+	log.Printf("[ProxyCache] Invalidated %s by %s", channel, GetUserID(c))
+	return c.JSON(fiber.Map{"status": "ok"})
+}
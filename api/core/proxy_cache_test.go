@@ -0,0 +1,17 @@
+package core
+
+import "testing"
+
+func TestProxyCacheRequestHash_NormalizesQuery(t *testing.T) {
+	a := proxyCacheRequestHash("GET", "/sports/public", "league=NFL&limit=10")
+	b := proxyCacheRequestHash("GET", "/sports/public", "limit=10&league=NFL")
+	if a != b {
+		t.Error("query parameter order should not change the cache key")
+	}
+	if a == proxyCacheRequestHash("GET", "/sports/public", "league=NBA&limit=10") {
+		t.Error("different query values must not share a cache key")
+	}
+	if a == proxyCacheRequestHash("GET", "/finance/public", "league=NFL&limit=10") {
+		t.Error("different paths must not share a cache key")
+	}
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// quotesChannel stands up a fake finance channel serving the API-key
+// route GET /finance/quotes and registers it with discovery. It returns
+// the gateway app and a count of requests that reached the channel.
+func quotesChannel(t *testing.T) (*fiber.App, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	globalDiscovery.mu.Lock()
+	prev := globalDiscovery.channels
+	globalDiscovery.channels = map[string]*ChannelInfo{
+		"finance": {
+			Name:        "finance",
+			InternalURL: srv.URL,
+			Routes:      []ChannelRoute{{Method: "GET", Path: "/finance/quotes", APIKey: true}},
+		},
+	}
+	globalDiscovery.mu.Unlock()
+	t.Cleanup(func() {
+		globalDiscovery.mu.Lock()
+		globalDiscovery.channels = prev
+		globalDiscovery.mu.Unlock()
+	})
+
+	app := fiber.New()
+	app.Use(dynamicProxyHandler)
+	return app, &hits
+}
+
+// cacheAPIKeyOwner seeds resolveAPIKey's Redis cache so a key resolves
+// without Postgres.
+func cacheAPIKeyOwner(t *testing.T, key string, owner apiKeyOwner) {
+	t.Helper()
+	data, err := json.Marshal(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Rdb.Set(t.Context(), RedisAPIKeyPrefix+hashAPIKey(key), data, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func quotesRequest(app *fiber.App, key string) (int, error) {
+	req := httptest.NewRequest("GET", "/finance/quotes?symbols=AAPL", nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func TestProxyAPIKeyRouteRejectsMissingAndBadKeys(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app, hits := quotesChannel(t)
+
+	bad := APIKeyPrefix + "revoked"
+	mr.Set(RedisAPIKeyMissPrefix+hashAPIKey(bad), "1")
+
+	for _, key := range []string{"", "not-a-key", bad} {
+		code, err := quotesRequest(app, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != fiber.StatusUnauthorized {
+			t.Errorf("key %q: status = %d, want 401", key, code)
+		}
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("%d rejected requests reached the channel", n)
+	}
+
+	good := APIKeyPrefix + "good"
+	cacheAPIKeyOwner(t, good, apiKeyOwner{Sub: "owner-1", Scopes: []string{APIKeyScopeChannels}})
+	if code, err := quotesRequest(app, good); err != nil || code != fiber.StatusOK {
+		t.Errorf("valid key: status = %d (%v), want 200", code, err)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("valid key reached the channel %d times, want 1", n)
+	}
+}
+
+func TestProxyAPIKeyRouteEnforcesMonthlyCeiling(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app, hits := quotesChannel(t)
+
+	key := APIKeyPrefix + "capped"
+	cacheAPIKeyOwner(t, key, apiKeyOwner{Sub: "owner-capped", Scopes: []string{APIKeyScopeChannels}})
+	// The month's included requests are already used.
+	mr.Set(apiUsageKey("owner-capped", apiUsagePeriod(time.Now())), strconv.Itoa(APIUsageIncludedPerMonth))
+
+	code, err := quotesRequest(app, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != fiber.StatusTooManyRequests {
+		t.Errorf("status = %d over the monthly ceiling, want 429", code)
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("a capped request reached the channel")
+	}
+}
+
+func TestProxyAPIKeyRouteRefusesFeedOnlyKeys(t *testing.T) {
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app, hits := quotesChannel(t)
+
+	key := APIKeyPrefix + "overlay"
+	cacheAPIKeyOwner(t, key, apiKeyOwner{Sub: "owner-feed", Scopes: []string{APIKeyScopeFeed}})
+
+	code, err := quotesRequest(app, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != fiber.StatusForbidden {
+		t.Errorf("status = %d for a feed-only key, want 403", code)
+	}
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("a feed-only key reached the channel")
+	}
+}
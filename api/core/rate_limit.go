@@ -0,0 +1,98 @@
+package core
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// General request rate limiting.
+//
+// Signed-in callers are counted per account and get their plan's budget
+// (RateLimitTierMax); everyone else is counted per IP at RateLimitMax.
+// Without this, an office or household behind one NAT shared a single
+// bucket and a paying user was throttled exactly like an anonymous one.
+//
+// The tier comes from the Logto roles in the caller's token, the same
+// source RequireTier uses; billing keeps the roles in step with the plan
+// (monthly, annual and lifetime Uplink are all "uplink"). A missing or
+// invalid token counts as anonymous, so forging tokens can't mint fresh
+// buckets.
+//
+// Fiber's limiter takes a fixed Max, so there is one limiter per class;
+// each skips requests that belong to another. The limiter sets
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset on every
+// counted response, and Retry-After on a 429.
+
+// rateLimitAnonymous is the class of callers without a valid token.
+const rateLimitAnonymous = "anonymous"
+
+// RateLimitTierMax is the per-RateLimitExpiration request budget of a
+// signed-in caller, by tier.
+var RateLimitTierMax = map[string]int{
+	"free":            RateLimitFreeMax,
+	"uplink":          RateLimitUplinkMax,
+	"uplink_pro":      RateLimitProMax,
+	"uplink_ultimate": RateLimitUltimateMax,
+	"super_user":      RateLimitSuperUserMax,
+}
+
+// Locals keys for the memoized rate limit identity.
+const (
+	rateLimitKeyLocal   = "rate_limit_key"
+	rateLimitClassLocal = "rate_limit_class"
+)
+
+// rateLimitIdentity returns the bucket key and class for a request:
+// "user:{sub}" and the caller's tier, or "ip:{ip}" and anonymous. The
+// token is verified once per request however many limiters ask.
+func rateLimitIdentity(c *fiber.Ctx) (key, class string) {
+	if k, ok := c.Locals(rateLimitKeyLocal).(string); ok {
+		return k, c.Locals(rateLimitClassLocal).(string)
+	}
+	key, class = "ip:"+c.IP(), rateLimitAnonymous
+	if sub, roles, ok := optionalProxyIdentity(c); ok {
+		key, class = "user:"+sub, tierFromRoles(roles)
+	}
+	c.Locals(rateLimitKeyLocal, key)
+	c.Locals(rateLimitClassLocal, class)
+	return key, class
+}
+
+// rateLimitMax is a class's budget.
+func rateLimitMax(class string) int {
+	if class == rateLimitAnonymous {
+		return RateLimitMax
+	}
+	return RateLimitTierMax[class]
+}
+
+// newRateLimiters returns the general limiter chain. exempt reports
+// requests that are never counted.
+func newRateLimiters(exempt func(*fiber.Ctx) bool) []fiber.Handler {
+	classes := []string{rateLimitAnonymous}
+	for tier := range RateLimitTierMax {
+		classes = append(classes, tier)
+	}
+
+	handlers := make([]fiber.Handler, 0, len(classes))
+	for _, class := range classes {
+		class := class
+		handlers = append(handlers, limiter.New(limiter.Config{
+			Max:        rateLimitMax(class),
+			Expiration: RateLimitExpiration,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				key, _ := rateLimitIdentity(c)
+				return key
+			},
+			Next: func(c *fiber.Ctx) bool {
+				// Class first: exempt walks the channel routes, and only
+				// the one limiter that owns the request needs to ask.
+				if _, got := rateLimitIdentity(c); got != class {
+					return true
+				}
+				return exempt(c)
+			},
+		}))
+	}
+	return handlers
+}
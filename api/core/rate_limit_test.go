@@ -0,0 +1,119 @@
+package core
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitTierMaxCoversTiers(t *testing.T) {
+	for tier := range tierOrder {
+		if _, ok := RateLimitTierMax[tier]; !ok {
+			t.Errorf("tier %q has no rate limit", tier)
+		}
+	}
+	for a, ra := range tierOrder {
+		for b, rb := range tierOrder {
+			if ra < rb && RateLimitTierMax[a] > RateLimitTierMax[b] {
+				t.Errorf("%s (%d/min) allows more than %s (%d/min)", a, RateLimitTierMax[a], b, RateLimitTierMax[b])
+			}
+		}
+	}
+	if RateLimitTierMax["free"] < RateLimitMax {
+		t.Errorf("signed-in free budget %d is below the anonymous %d", RateLimitTierMax["free"], RateLimitMax)
+	}
+}
+
+// rateLimitApp mounts the limiter chain behind a middleware that plays
+// the part of the token lookup when sub is set.
+func rateLimitApp(sub, tier string) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if sub != "" {
+			c.Locals(rateLimitKeyLocal, "user:"+sub)
+			c.Locals(rateLimitClassLocal, tier)
+		}
+		return c.Next()
+	})
+	for _, h := range newRateLimiters(func(c *fiber.Ctx) bool { return c.Path() == "/health" }) {
+		app.Use(h)
+	}
+	app.Get("/*", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	return app
+}
+
+func TestRateLimitHeadersByClass(t *testing.T) {
+	for _, tc := range []struct {
+		name, sub, tier string
+		want            int
+	}{
+		{"anonymous", "", "", RateLimitMax},
+		{"free", "u1", "free", RateLimitFreeMax},
+		{"uplink", "u2", "uplink", RateLimitUplinkMax},
+		{"ultimate", "u3", "uplink_ultimate", RateLimitUltimateMax},
+	} {
+		resp, err := rateLimitApp(tc.sub, tc.tier).Test(httptest.NewRequest("GET", "/dashboard", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != strconv.Itoa(tc.want) {
+			t.Errorf("%s: X-RateLimit-Limit = %q, want %d", tc.name, got, tc.want)
+		}
+		if got := resp.Header.Get("X-RateLimit-Remaining"); got != strconv.Itoa(tc.want-1) {
+			t.Errorf("%s: X-RateLimit-Remaining = %q, want %d", tc.name, got, tc.want-1)
+		}
+	}
+}
+
+func TestRateLimitExemptAndExhausted(t *testing.T) {
+	app := rateLimitApp("", "")
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/health", nil))
+	if resp.Header.Get("X-RateLimit-Limit") != "" {
+		t.Error("exempt path was counted")
+	}
+
+	for i := 0; i < RateLimitMax; i++ {
+		app.Test(httptest.NewRequest("GET", "/x", nil))
+	}
+	resp, _ = app.Test(httptest.NewRequest("GET", "/x", nil))
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("status = %d after %d requests, want 429", resp.StatusCode, RateLimitMax+1)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+}
+
+func TestRateLimitSignedInBucketIsPerAccount(t *testing.T) {
+	// Same IP, different accounts: exhausting one leaves the other alone.
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sub := c.Get("X-Test-Sub")
+		c.Locals(rateLimitKeyLocal, "user:"+sub)
+		c.Locals(rateLimitClassLocal, "free")
+		return c.Next()
+	})
+	for _, h := range newRateLimiters(func(*fiber.Ctx) bool { return false }) {
+		app.Use(h)
+	}
+	app.Get("/x", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	req := func(sub string) int {
+		r := httptest.NewRequest("GET", "/x", nil)
+		r.Header.Set("X-Test-Sub", sub)
+		resp, _ := app.Test(r)
+		return resp.StatusCode
+	}
+	for i := 0; i < RateLimitFreeMax; i++ {
+		req("a")
+	}
+	if got := req("a"); got != fiber.StatusTooManyRequests {
+		t.Errorf("account a status = %d, want 429", got)
+	}
+	if got := req("b"); got != fiber.StatusOK {
+		t.Errorf("account b status = %d, want 200", got)
+	}
+}
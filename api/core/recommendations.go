@@ -0,0 +1,488 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Catalog recommendations.
+//
+// A nightly job reads every enabled channel config, treats each user's
+// symbols, catalog feeds, leagues and favourite teams as a set, and
+// scores unfollowed items by item-to-item co-occurrence ("people who
+// follow X also follow Y", cosine-normalised). Users with little overlap
+// are topped up from a popularity list, which also serves accounts the
+// job hasn't seen yet (stored under logto_sub ''). Ticker clicks from the
+// engagement rollup give popular items a boost.
+//
+// Only items followed by at least RecommendationMinSupport users are
+// ever suggested, so a rare custom symbol or league can't leak one
+// user's config to another. Custom RSS feeds are never considered.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// Recommendation kinds.
+const (
+	RecKindSymbol = "symbol"
+	RecKindFeed   = "feed"
+	RecKindLeague = "league"
+	RecKindTeam   = "team"
+)
+
+var recommendationKinds = []string{RecKindSymbol, RecKindFeed, RecKindLeague, RecKindTeam}
+
+// Recommendation is one suggested catalog item. Key is what the channel
+// config stores: the symbol, feed URL, league name, or "league:teamId"
+// for teams.
+type Recommendation struct {
+	Kind   string  `json:"kind"`
+	Key    string  `json:"key"`
+	Label  string  `json:"label"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"` // "collaborative" or "popular"
+}
+
+// recItem is a followed item as read from a channel config.
+type recItem struct {
+	Kind  string
+	Key   string
+	Label string
+}
+
+func (it recItem) id() string { return it.Kind + "\x00" + it.Key }
+
+// ─── Config extraction ───────────────────────────────────────────
+
+// extractRecItems lists the catalog items in one channel config.
+func extractRecItems(channelType string, config map[string]any) []recItem {
+	var out []recItem
+	switch channelType {
+	case "finance":
+		for _, v := range asArray(config["symbols"]) {
+			if s, ok := v.(string); ok && s != "" {
+				out = append(out, recItem{RecKindSymbol, s, s})
+			}
+		}
+	case "rss":
+		for _, v := range asArray(config["feeds"]) {
+			m, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			if custom, _ := m["is_custom"].(bool); custom {
+				continue
+			}
+			url, _ := m["url"].(string)
+			name, _ := m["name"].(string)
+			if url != "" {
+				if name == "" {
+					name = url
+				}
+				out = append(out, recItem{RecKindFeed, url, name})
+			}
+		}
+	case "sports":
+		for _, v := range asArray(config["leagues"]) {
+			if s, ok := v.(string); ok && s != "" {
+				out = append(out, recItem{RecKindLeague, s, s})
+			}
+		}
+		favs, _ := config["favoriteTeams"].(map[string]any)
+		for league, v := range favs {
+			m, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := m["teamId"].(float64)
+			name, _ := m["teamName"].(string)
+			if id > 0 {
+				if name == "" {
+					name = league
+				}
+				key := league + ":" + strconv.FormatInt(int64(id), 10)
+				out = append(out, recItem{RecKindTeam, key, name})
+			}
+		}
+	}
+	return out
+}
+
+// ─── Scoring ─────────────────────────────────────────────────────
+
+// recModel is the item-to-item model built from every user's follows.
+type recModel struct {
+	items   map[string]recItem
+	support map[string]int            // users following each item
+	cooc    map[string]map[string]int // users following both
+	popular map[string][]Recommendation
+}
+
+// buildRecModel counts follows and co-follows. clicks (keyed by item id)
+// from the engagement rollup boost popularity.
+func buildRecModel(follows map[string][]recItem, clicks map[string]int64) *recModel {
+	m := &recModel{
+		items:   make(map[string]recItem),
+		support: make(map[string]int),
+		cooc:    make(map[string]map[string]int),
+		popular: make(map[string][]Recommendation),
+	}
+	for _, items := range follows {
+		ids := make([]string, 0, len(items))
+		seen := make(map[string]bool, len(items))
+		for _, it := range items {
+			id := it.id()
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			m.items[id] = it
+			m.support[id]++
+		}
+		for _, a := range ids {
+			row := m.cooc[a]
+			if row == nil {
+				row = make(map[string]int)
+				m.cooc[a] = row
+			}
+			for _, b := range ids {
+				if a != b {
+					row[b]++
+				}
+			}
+		}
+	}
+
+	for id, n := range m.support {
+		if n < RecommendationMinSupport {
+			continue
+		}
+		it := m.items[id]
+		score := float64(n) + RecommendationClickWeight*math.Log1p(float64(clicks[id]))
+		m.popular[it.Kind] = append(m.popular[it.Kind], Recommendation{
+			Kind: it.Kind, Key: it.Key, Label: it.Label, Score: score, Reason: "popular",
+		})
+	}
+	for kind := range m.popular {
+		sortRecommendations(m.popular[kind])
+	}
+	return m
+}
+
+func sortRecommendations(recs []Recommendation) {
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].Key < recs[j].Key
+	})
+}
+
+// recommendFor returns up to perKind suggestions per kind for a user
+// following owned: collaborative picks first, then popular ones.
+func (m *recModel) recommendFor(owned []recItem, perKind int) []Recommendation {
+	have := make(map[string]bool, len(owned))
+	for _, it := range owned {
+		have[it.id()] = true
+	}
+
+	scores := make(map[string]float64)
+	for a := range have {
+		for b, both := range m.cooc[a] {
+			if have[b] || m.support[b] < RecommendationMinSupport {
+				continue
+			}
+			scores[b] += float64(both) / math.Sqrt(float64(m.support[a]*m.support[b]))
+		}
+	}
+
+	byKind := make(map[string][]Recommendation)
+	for id, score := range scores {
+		it := m.items[id]
+		byKind[it.Kind] = append(byKind[it.Kind], Recommendation{
+			Kind: it.Kind, Key: it.Key, Label: it.Label, Score: score, Reason: "collaborative",
+		})
+	}
+
+	out := make([]Recommendation, 0)
+	for _, kind := range recommendationKinds {
+		picks := byKind[kind]
+		sortRecommendations(picks)
+		if len(picks) > perKind {
+			picks = picks[:perKind]
+		}
+		picked := make(map[string]bool, len(picks))
+		for _, p := range picks {
+			picked[p.Key] = true
+		}
+		for _, p := range m.popular[kind] {
+			if len(picks) >= perKind {
+				break
+			}
+			if have[(recItem{Kind: kind, Key: p.Key}).id()] || picked[p.Key] {
+				continue
+			}
+			picks = append(picks, p)
+		}
+		out = append(out, picks...)
+	}
+	return out
+}
+
+// ─── Nightly job ─────────────────────────────────────────────────
+
+// StartRecommendationJob recomputes recommendations once per UTC day.
+// Replicas coordinate through a per-day Redis marker.
+func StartRecommendationJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(RecommendationJobInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				day := engagementDay(time.Now())
+				ok, err := Rdb.SetNX(ctx, RedisRecommendationsComputedPrefix+day, "1", 48*time.Hour).Result()
+				if err != nil || !ok {
+					continue
+				}
+				if err := computeRecommendations(ctx); err != nil {
+					log.Printf("[Recommendations] compute failed: %v", err)
+					Rdb.Del(ctx, RedisRecommendationsComputedPrefix+day)
+				}
+			}
+		}
+	}()
+	log.Printf("[Recommendations] Job started (%s interval)", RecommendationJobInterval)
+}
+
+// loadAllFollows scans every enabled follow; a batch read, so it runs on
+// the replica when there is one.
+func loadAllFollows(ctx context.Context) (map[string][]recItem, error) {
+	rows, err := ReadPool().Query(ctx, `
+		SELECT logto_sub, channel_type, config
+		  FROM user_channels
+		 WHERE enabled AND channel_type IN ('finance', 'rss', 'sports')
+		   AND logto_sub NOT LIKE 'org:%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := make(map[string][]recItem)
+	for rows.Next() {
+		var sub, channelType string
+		var raw []byte
+		if err := rows.Scan(&sub, &channelType, &raw); err != nil {
+			return nil, err
+		}
+		var config map[string]any
+		if json.Unmarshal(raw, &config) != nil {
+			continue
+		}
+		follows[sub] = append(follows[sub], extractRecItems(channelType, config)...)
+	}
+	return follows, rows.Err()
+}
+
+// loadRecentClicks maps engagement rollup sources onto item ids.
+func loadRecentClicks(ctx context.Context) map[string]int64 {
+	kindFor := map[string]string{"finance": RecKindSymbol, "rss": RecKindFeed, "sports": RecKindLeague}
+	clicks := make(map[string]int64)
+	rows, err := ReadPool().Query(ctx, `
+		SELECT channel_type, source, SUM(clicks)::bigint
+		  FROM channel_engagement_daily
+		 WHERE source != '' AND day > (now() AT TIME ZONE 'UTC')::date - $1::int
+		 GROUP BY channel_type, source`, RecommendationClickWindowDays)
+	if err != nil {
+		log.Printf("[Recommendations] load clicks failed: %v", err)
+		return clicks
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channelType, source string
+		var n int64
+		if rows.Scan(&channelType, &source, &n) == nil && kindFor[channelType] != "" {
+			clicks[recItem{Kind: kindFor[channelType], Key: source}.id()] = n
+		}
+	}
+	return clicks
+}
+
+// computeRecommendations rebuilds the recommendations table: one row
+// set per user plus the global popular list under an empty logto_sub.
+func computeRecommendations(ctx context.Context) error {
+	start := time.Now()
+	follows, err := loadAllFollows(ctx)
+	if err != nil {
+		return fmt.Errorf("load follows: %w", err)
+	}
+	model := buildRecModel(follows, loadRecentClicks(ctx))
+
+	computedAt := time.Now().UTC()
+	rows := make([][]any, 0)
+	add := func(sub string, recs []Recommendation) {
+		for i, r := range recs {
+			rows = append(rows, []any{sub, r.Kind, r.Key, r.Label, r.Score, r.Reason, i, computedAt})
+		}
+	}
+	global := model.recommendFor(nil, RecommendationsPerKind)
+	add("", global)
+	for sub, owned := range follows {
+		add(sub, model.recommendFor(owned, RecommendationsPerKind))
+	}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM recommendations`); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"recommendations"},
+		[]string{"logto_sub", "kind", "item_key", "label", "score", "reason", "rank", "computed_at"},
+		pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("[Recommendations] Computed %d row(s) for %d user(s) in %s",
+		len(rows), len(follows), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// ─── Reads ───────────────────────────────────────────────────────
+
+func loadRecommendations(ctx context.Context, sub string) ([]Recommendation, error) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT kind, item_key, label, score, reason
+		  FROM recommendations
+		 WHERE logto_sub = $1
+		 ORDER BY kind, rank`, sub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Recommendation, 0)
+	for rows.Next() {
+		var r Recommendation
+		if err := rows.Scan(&r.Kind, &r.Key, &r.Label, &r.Score, &r.Reason); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// cachedRecommendations returns the user's precomputed list (or the
+// global one for users the job hasn't seen), cached per user in Redis.
+func cachedRecommendations(ctx context.Context, sub string) ([]Recommendation, error) {
+	key := RedisRecommendationsPrefix + sub
+	if Rdb != nil {
+		if val, err := Rdb.Get(ctx, key).Bytes(); err == nil {
+			var cached []Recommendation
+			if json.Unmarshal(val, &cached) == nil {
+				return cached, nil
+			}
+		}
+	}
+
+	recs, err := loadRecommendations(ctx, sub)
+	if err == nil && len(recs) == 0 {
+		recs, err = loadRecommendations(ctx, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if Rdb != nil {
+		if data, err := json.Marshal(recs); err == nil {
+			Rdb.Set(ctx, key, data, RecommendationsCacheTTL)
+		}
+	}
+	return recs, nil
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// HandleGetRecommendations suggests catalog items the caller doesn't
+// follow yet. Items added since the nightly run are filtered out.
+//
+// @Summary Get recommendations
+// @Tags Recommendations
+// @Produce json
+// @Param kind query string false "symbol, feed, league or team"
+// @Param limit query int false "Max items per kind (default and max 10)"
+// @Success 200 {object} object{recommendations=[]Recommendation}
+// @Security LogtoAuth
+// @Router /recommendations [get]
+func HandleGetRecommendations(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	kind := c.Query("kind")
+	if kind != "" && kind != RecKindSymbol && kind != RecKindFeed && kind != RecKindLeague && kind != RecKindTeam {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "kind must be one of symbol, feed, league, team",
+		})
+	}
+	limit := RecommendationsPerKind
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error", Error: "limit must be a positive integer",
+			})
+		}
+		limit = min(n, RecommendationsPerKind)
+	}
+
+	recs, err := cachedRecommendations(ctx, userID)
+	if err != nil {
+		log.Printf("[Recommendations] load for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load recommendations",
+		})
+	}
+
+	have := make(map[string]bool)
+	if channels, err := GetUserChannels(userID); err == nil {
+		for _, ch := range channels {
+			for _, it := range extractRecItems(ch.ChannelType, ch.Config) {
+				have[it.id()] = true
+			}
+		}
+	}
+
+	perKind := make(map[string]int)
+	out := make([]Recommendation, 0, len(recs))
+	for _, r := range recs {
+		if (kind != "" && r.Kind != kind) || have[(recItem{Kind: r.Kind, Key: r.Key}).id()] {
+			continue
+		}
+		if perKind[r.Kind] >= limit {
+			continue
+		}
+		perKind[r.Kind]++
+		out = append(out, r)
+	}
+	return c.JSON(fiber.Map{"recommendations": out})
+}
@@ -0,0 +1,64 @@
+package core
+
+import "testing"
+
+func TestExtractRecItems(t *testing.T) {
+	finance := extractRecItems("finance", map[string]any{"symbols": []any{"AAPL", "", "MSFT"}})
+	if len(finance) != 2 || finance[0].Key != "AAPL" || finance[0].Kind != RecKindSymbol {
+		t.Errorf("finance = %+v", finance)
+	}
+
+	rss := extractRecItems("rss", map[string]any{"feeds": []any{
+		map[string]any{"name": "BBC", "url": "https://bbc.example/rss", "is_custom": false},
+		map[string]any{"name": "Mine", "url": "https://private.example/rss", "is_custom": true},
+	}})
+	if len(rss) != 1 || rss[0].Key != "https://bbc.example/rss" || rss[0].Label != "BBC" {
+		t.Errorf("custom feeds should be skipped: %+v", rss)
+	}
+
+	sports := extractRecItems("sports", map[string]any{
+		"leagues":       []any{"NFL"},
+		"favoriteTeams": map[string]any{"NFL": map[string]any{"teamId": 12.0, "teamName": "Chiefs"}},
+	})
+	if len(sports) != 2 || sports[1].Kind != RecKindTeam || sports[1].Key != "NFL:12" || sports[1].Label != "Chiefs" {
+		t.Errorf("sports = %+v", sports)
+	}
+}
+
+func sym(s string) recItem { return recItem{RecKindSymbol, s, s} }
+
+func TestRecommendFor_CollaborativeThenPopular(t *testing.T) {
+	follows := map[string][]recItem{
+		"a": {sym("AAPL"), sym("MSFT")},
+		"b": {sym("AAPL"), sym("MSFT")},
+		"c": {sym("AAPL"), sym("MSFT"), sym("SPY")},
+		"d": {sym("SPY"), sym("QQQ")},
+		"e": {sym("SPY"), sym("QQQ")},
+		"f": {sym("SPY"), sym("QQQ"), sym("RARE")},
+	}
+	model := buildRecModel(follows, nil)
+
+	recs := model.recommendFor([]recItem{sym("AAPL")}, 3)
+	if len(recs) != 3 {
+		t.Fatalf("got %d recs, want 3: %+v", len(recs), recs)
+	}
+	if recs[0].Key != "MSFT" || recs[0].Reason != "collaborative" {
+		t.Errorf("first pick = %+v, want collaborative MSFT", recs[0])
+	}
+	for _, r := range recs {
+		if r.Key == "AAPL" || r.Key == "RARE" {
+			t.Errorf("unexpected pick %q (owned or below min support)", r.Key)
+		}
+	}
+}
+
+func TestRecommendFor_NewUserGetsPopular(t *testing.T) {
+	follows := map[string][]recItem{
+		"a": {sym("SPY")}, "b": {sym("SPY")}, "c": {sym("SPY")},
+		"d": {sym("QQQ")}, "e": {sym("QQQ")}, "f": {sym("QQQ")}, "g": {sym("QQQ")},
+	}
+	recs := buildRecModel(follows, nil).recommendFor(nil, 10)
+	if len(recs) != 2 || recs[0].Key != "QQQ" || recs[0].Reason != "popular" {
+		t.Errorf("recs = %+v, want QQQ then SPY", recs)
+	}
+}
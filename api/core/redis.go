@@ -3,35 +3,136 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
 	"github.com/redis/go-redis/v9"
 )
 
 // Rdb is the global Redis client. Exported so channel packages can access it
-// for direct operations (e.g. cache invalidation).
-var Rdb *redis.Client
+// for direct operations (e.g. cache invalidation). A UniversalClient so the
+// same code runs against a single node, a Sentinel-managed primary or a
+// cluster (REDIS_MODE).
+var Rdb redis.UniversalClient
 
-// ConnectRedis initialises the Redis client from the REDIS_URL env var.
+// ConnectRedis initialises the Redis client from REDIS_MODE and the
+// variables it needs (newRedisClient).
 func ConnectRedis() {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("REDIS_URL must be set")
-	}
-
-	opts, err := redis.ParseURL(redisURL)
+	client, mode, err := newRedisClient(Secret)
 	if err != nil {
-		log.Fatalf("Unable to parse REDIS_URL: %v", err)
+		log.Fatalf("Unable to configure Redis: %v", err)
 	}
-
-	Rdb = redis.NewClient(opts)
+	Rdb = client
 
 	if err := Rdb.Ping(context.Background()).Err(); err != nil {
 		log.Fatalf("Unable to connect to Redis: %v", err)
 	}
 
-	log.Println("Successfully connected to Redis")
+	// Fail fast instead of timing out per command when Redis goes away
+	// (redis_breaker.go).
+	Rdb.AddHook(breakerHook{redisBreaker})
+
+	log.Printf("Successfully connected to Redis (%s)", mode)
+}
+
+// Redis deployment modes (REDIS_MODE).
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// newRedisClient builds the client REDIS_MODE asks for. getenv reads the
+// configuration (Secret here; os.Getenv in the channel APIs, which carry
+// a copy of this function).
+//
+//   - single (default): REDIS_URL, as before.
+//   - sentinel: REDIS_SENTINEL_ADDRS (comma-separated host:port) and
+//     REDIS_SENTINEL_MASTER name the Sentinels and the master set;
+//     REDIS_SENTINEL_PASSWORD authenticates to the Sentinels. REDIS_URL
+//     is optional and supplies the primary's credentials, DB and TLS (its
+//     host is ignored). Failover is followed automatically.
+//   - cluster: REDIS_URL is one seed node, with credentials, TLS and
+//     go-redis query options (e.g. ?read_only=true); REDIS_CLUSTER_ADDRS
+//     adds more seeds. Cluster mode has no DB numbers.
+func newRedisClient(getenv func(string) string) (redis.UniversalClient, string, error) {
+	mode := strings.ToLower(strings.TrimSpace(getenv("REDIS_MODE")))
+	if mode == "" {
+		mode = RedisModeSingle
+	}
+	redisURL := getenv("REDIS_URL")
+
+	switch mode {
+	case RedisModeSingle:
+		if redisURL == "" {
+			return nil, mode, errors.New("REDIS_URL must be set")
+		}
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		return redis.NewClient(opts), mode, nil
+
+	case RedisModeSentinel:
+		master := strings.TrimSpace(getenv("REDIS_SENTINEL_MASTER"))
+		addrs := splitRedisAddrs(getenv("REDIS_SENTINEL_ADDRS"))
+		if master == "" || len(addrs) == 0 {
+			return nil, mode, errors.New("REDIS_SENTINEL_MASTER and REDIS_SENTINEL_ADDRS must be set")
+		}
+		opts := &redis.FailoverOptions{
+			MasterName:       master,
+			SentinelAddrs:    addrs,
+			SentinelPassword: getenv("REDIS_SENTINEL_PASSWORD"),
+		}
+		if redisURL != "" {
+			base, err := redis.ParseURL(redisURL)
+			if err != nil {
+				return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+			}
+			opts.Username = base.Username
+			opts.Password = base.Password
+			opts.DB = base.DB
+			opts.TLSConfig = base.TLSConfig
+		}
+		return redis.NewFailoverClient(opts), mode, nil
+
+	case RedisModeCluster:
+		if redisURL == "" {
+			return nil, mode, errors.New("REDIS_URL must be set")
+		}
+		opts, err := redis.ParseClusterURL(redisURL)
+		if err != nil {
+			return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		opts.Addrs = append(opts.Addrs, splitRedisAddrs(getenv("REDIS_CLUSTER_ADDRS"))...)
+		return redis.NewClusterClient(opts), mode, nil
+	}
+	return nil, mode, fmt.Errorf("unknown REDIS_MODE %q (want single, sentinel or cluster)", mode)
+}
+
+// splitRedisAddrs parses a comma-separated host:port list.
+func splitRedisAddrs(raw string) []string {
+	var addrs []string
+	for _, a := range strings.Split(raw, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// delKeys deletes keys one command per key in a single pipeline. A
+// multi-key DEL is rejected by a cluster when the keys hash to different
+// slots; pipelined single-key DELs work in every mode.
+func delKeys(ctx context.Context, keys ...string) error {
+	pipe := Rdb.Pipeline()
+	for _, k := range keys {
+		pipe.Del(ctx, k)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 // PublishRaw publishes pre-serialised bytes to a Redis channel.
@@ -73,8 +174,18 @@ func PSubscribe(ctx context.Context, patterns ...string) *redis.PubSub {
 
 // InvalidateDashboardCache removes the cached dashboard response for a user.
 // Called after channel CRUD or preference updates to ensure the next poll gets fresh data.
+// The user's materialized snapshot goes too; enrollment is kept, so the
+// next poll rebuilds it. So does their display ticker, which CDC events
+// deliberately leave alone.
 func InvalidateDashboardCache(userSub string) {
-	if err := Rdb.Del(context.Background(), RedisDashboardCachePrefix+userSub).Err(); err != nil {
+	keys := []string{
+		RedisDashboardCachePrefix + userSub,
+		RedisDashboardSnapshotPrefix + userSub,
+		RedisDisplayTickerPrefix + userSub,
+		RedisPublicFeedUserPrefix + userSub,
+	}
+	dropFallbackCache(keys...)
+	if err := delKeys(context.Background(), keys...); err != nil {
 		log.Printf("[Cache] Failed to invalidate dashboard cache for %s: %v", userSub, err)
 	}
 }
@@ -110,7 +221,8 @@ func channelUserCacheKeys(userSub string) []string {
 func InvalidateUserCaches(userSub string) {
 	ctx := context.Background()
 	keys := append([]string{RedisDashboardCachePrefix + userSub}, channelUserCacheKeys(userSub)...)
-	if err := Rdb.Del(ctx, keys...).Err(); err != nil {
+	dropFallbackCache(keys...)
+	if err := delKeys(ctx, keys...); err != nil {
 		log.Printf("[Cache] Failed to invalidate user caches for %s: %v", userSub, err)
 	}
 }
@@ -0,0 +1,332 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis degraded mode.
+//
+// When Redis stops answering, every command otherwise waits out its own
+// dial and read timeouts, so each cache read, subscriber lookup and rate
+// limit check adds seconds to a request. redisBreaker is a go-redis hook
+// that counts consecutive connection-level failures; after
+// RedisBreakerFailureThreshold it opens and commands fail at once with
+// ErrRedisUnavailable. After RedisBreakerCooldown one command is let
+// through as a probe; success closes the breaker, failure re-opens it.
+//
+// While the breaker is open the gateway is in degraded mode: GetCache and
+// SetCache use an in-process LRU instead (fallbackCache), /health reports
+// degraded_mode, and features that need Redis (rate limits, SSE fan-out
+// across replicas) fail the way they already do on a Redis error.
+
+// ErrRedisUnavailable is returned for commands refused while the breaker
+// is open.
+var ErrRedisUnavailable = errors.New("redis unavailable (circuit open)")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+type circuitBreaker struct {
+	name      string // log prefix
+	threshold int
+	cooldown  time.Duration
+	isFailure func(error) bool // which errors count toward opening
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		name:      "Redis",
+		threshold: threshold,
+		cooldown:  cooldown,
+		isFailure: isRedisConnFailure,
+		now:       time.Now,
+	}
+}
+
+var redisBreaker = newCircuitBreaker(RedisBreakerFailureThreshold, RedisBreakerCooldown)
+
+// allow reports whether a command may go to Redis. In half-open state only
+// the single probe is allowed.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record feeds a command's outcome back into the breaker.
+func (b *circuitBreaker) record(err error) {
+	failed := b.isFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+	if !failed {
+		if b.state != breakerClosed {
+			log.Printf("[%s] Circuit closed — reachable again", b.name)
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+		if b.state == breakerClosed {
+			log.Printf("[%s] Circuit open after %d consecutive failures (last: %v)", b.name, b.failures, err)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State returns the breaker's current state.
+func (b *circuitBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// isRedisConnFailure separates an unreachable Redis from ordinary replies:
+// redis.Nil, server error replies and the caller's own cancellation say
+// nothing about Redis health.
+func isRedisConnFailure(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var replyErr redis.Error
+	return !errors.As(err, &replyErr)
+}
+
+// RedisDegraded reports whether the gateway is running without Redis.
+func RedisDegraded() bool {
+	return redisBreaker.State() != breakerClosed
+}
+
+// ─── go-redis hook ───────────────────────────────────────────────
+
+type breakerHook struct{ b *circuitBreaker }
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !h.b.allow() {
+			cmd.SetErr(ErrRedisUnavailable)
+			return ErrRedisUnavailable
+		}
+		err := next(ctx, cmd)
+		h.b.record(err)
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.b.allow() {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrRedisUnavailable)
+			}
+			return ErrRedisUnavailable
+		}
+		err := next(ctx, cmds)
+		h.b.record(err)
+		return err
+	}
+}
+
+// ─── Cache helpers ───────────────────────────────────────────────
+
+// GetCache reads a cache entry from Redis, or from the in-process
+// fallback when Redis is unreachable. Hot keys are served from the local
+// cache first (cache_local.go). ok is false on a miss.
+func GetCache(ctx context.Context, key string) ([]byte, bool) {
+	hot := hotCacheKeys[key]
+	if hot {
+		if val, ok := localCache.get(key, time.Now()); ok {
+			return val, true
+		}
+	}
+	val, err := Rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		if hot {
+			localCache.set(key, val, LocalCacheTTL, time.Now())
+		}
+		return val, true
+	}
+	if isRedisConnFailure(err) {
+		return fallbackCache.get(key, time.Now())
+	}
+	return nil, false
+}
+
+// SetCache writes a cache entry to Redis and to the in-process fallback,
+// so the fallback is warm if Redis goes away. Errors are logged, not
+// returned; a failed cache write never fails a request.
+func SetCache(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	fallbackCache.set(key, data, min(ttl, RedisFallbackMaxTTL), time.Now())
+	if hotCacheKeys[key] {
+		localCache.set(key, data, min(ttl, LocalCacheTTL), time.Now())
+	}
+	if err := Rdb.Set(ctx, key, data, ttl).Err(); err != nil && !errors.Is(err, ErrRedisUnavailable) {
+		log.Printf("[Cache] Failed to set %s: %v", key, err)
+	}
+}
+
+// dropFallbackCache removes keys from the in-process fallback and local
+// caches, and has every other process drop the hot ones. Callers that
+// delete cache keys in Redis call it too, so a later outage can't serve
+// an entry that was invalidated while Redis was up.
+func dropFallbackCache(keys ...string) {
+	fallbackCache.del(keys...)
+	localCache.del(keys...)
+	publishCacheInvalidation(context.Background(), keys...)
+}
+
+// lruCache is a byte-budgeted LRU with per-entry expiry.
+type lruCache struct {
+	maxBytes int
+
+	mu    sync.Mutex
+	ll    *list.List // front = most recently used
+	items map[string]*list.Element
+	bytes int
+}
+
+type lruEntry struct {
+	key     string
+	val     []byte
+	expires time.Time
+}
+
+func newLRUCache(maxBytes int) *lruCache {
+	return &lruCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+var fallbackCache = newLRUCache(RedisFallbackCacheMaxBytes)
+
+func (l *lruCache) get(key string, now time.Time) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if now.After(e.expires) {
+		l.remove(el)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.val, true
+}
+
+func (l *lruCache) set(key string, val []byte, ttl time.Duration, now time.Time) {
+	size := len(key) + len(val)
+	if ttl <= 0 || size > l.maxBytes {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.remove(el)
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, val: val, expires: now.Add(ttl)})
+	l.bytes += size
+	for l.bytes > l.maxBytes {
+		l.remove(l.ll.Back())
+	}
+}
+
+func (l *lruCache) del(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.remove(el)
+		}
+	}
+}
+
+func (l *lruCache) remove(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	l.ll.Remove(el)
+	delete(l.items, e.key)
+	l.bytes -= len(e.key) + len(e.val)
+}
+
+func (l *lruCache) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func init() {
+	metricsRegistry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "scrollr",
+			Name:      "redis_degraded",
+			Help:      "1 while the Redis circuit breaker is open or probing.",
+		}, func() float64 {
+			if RedisDegraded() {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "scrollr",
+			Name:      "redis_fallback_cache_entries",
+			Help:      "Entries in the in-process fallback cache.",
+		}, func() float64 { return float64(fallbackCache.len()) }),
+	)
+}
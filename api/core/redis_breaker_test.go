@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	b := newCircuitBreaker(3, 5*time.Second)
+	b.now = func() time.Time { return now }
+	down := errors.New("dial tcp: connection refused")
+
+	// Replies and misses never count.
+	b.record(redis.Nil)
+	b.record(context.Canceled)
+	for i := 0; i < 2; i++ {
+		b.record(down)
+	}
+	if b.State() != breakerClosed {
+		t.Fatalf("state after 2 failures = %s, want closed", b.State())
+	}
+	b.record(down)
+	if b.State() != breakerOpen {
+		t.Fatalf("state after 3 failures = %s, want open", b.State())
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed a command before the cooldown")
+	}
+
+	// After the cooldown exactly one probe goes through.
+	now = now.Add(5 * time.Second)
+	if !b.allow() {
+		t.Fatal("cooldown elapsed but the probe was refused")
+	}
+	if b.allow() {
+		t.Fatal("second command allowed while the probe is in flight")
+	}
+	b.record(down)
+	if b.State() != breakerOpen {
+		t.Fatalf("failed probe: state = %s, want open", b.State())
+	}
+
+	now = now.Add(5 * time.Second)
+	b.allow()
+	b.record(nil)
+	if b.State() != breakerClosed || !b.allow() {
+		t.Fatalf("successful probe: state = %s, want closed", b.State())
+	}
+}
+
+func TestLRUCacheEvictsAndExpires(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	l := newLRUCache(20)
+
+	l.set("a", []byte("12345678"), time.Minute, now) // 9 bytes
+	l.set("b", []byte("12345678"), time.Minute, now) // 18
+	l.get("a", now)                                  // a is now most recent
+	l.set("c", []byte("12345678"), time.Minute, now) // 27 > 20: evicts b
+
+	if _, ok := l.get("b", now); ok {
+		t.Error("least recently used entry should have been evicted")
+	}
+	if v, ok := l.get("a", now); !ok || string(v) != "12345678" {
+		t.Errorf("get(a) = %q, %v", v, ok)
+	}
+	if _, ok := l.get("c", now.Add(2*time.Minute)); ok {
+		t.Error("expired entry was served")
+	}
+
+	l.set("huge", make([]byte, 64), time.Minute, now)
+	if _, ok := l.get("huge", now); ok {
+		t.Error("entry larger than the budget should not be stored")
+	}
+}
+
+func TestGetCacheFallsBackWhenRedisIsDown(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+
+	SetCache(context.Background(), "cache:test:fallback", []byte("warm"), time.Minute)
+	if v, ok := GetCache(context.Background(), "cache:test:fallback"); !ok || string(v) != "warm" {
+		t.Fatalf("GetCache with Redis up = %q, %v", v, ok)
+	}
+
+	mr.Close()
+	if v, ok := GetCache(context.Background(), "cache:test:fallback"); !ok || string(v) != "warm" {
+		t.Errorf("GetCache with Redis down = %q, %v; want the fallback copy", v, ok)
+	}
+
+	dropFallbackCache("cache:test:fallback")
+	if _, ok := GetCache(context.Background(), "cache:test:fallback"); ok {
+		t.Error("dropped key still served from the fallback")
+	}
+}
+
+func TestBreakerHookFailsFastWhenOpen(t *testing.T) {
+	b := newCircuitBreaker(1, time.Hour)
+	b.record(errors.New("i/o timeout"))
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: time.Second})
+	defer client.Close()
+	client.AddHook(breakerHook{b})
+
+	start := time.Now()
+	err := client.Get(context.Background(), "k").Err()
+	if !errors.Is(err, ErrRedisUnavailable) {
+		t.Fatalf("err = %v, want ErrRedisUnavailable", err)
+	}
+	if _, err := client.Pipelined(context.Background(), func(p redis.Pipeliner) error {
+		p.Get(context.Background(), "k")
+		return nil
+	}); !errors.Is(err, ErrRedisUnavailable) {
+		t.Fatalf("pipeline err = %v, want ErrRedisUnavailable", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("open breaker took %s; should not touch the network", time.Since(start))
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+
+	single, mode, err := newRedisClient(env(map[string]string{"REDIS_URL": "redis://localhost:6379/2"}))
+	if err != nil || mode != RedisModeSingle {
+		t.Fatalf("default mode = %q, err = %v", mode, err)
+	}
+	if c, ok := single.(*redis.Client); !ok || c.Options().DB != 2 {
+		t.Errorf("single mode built %T, want *redis.Client on DB 2", single)
+	}
+	single.Close()
+
+	failover, _, err := newRedisClient(env(map[string]string{
+		"REDIS_MODE":            "Sentinel",
+		"REDIS_SENTINEL_MASTER": "scrollr",
+		"REDIS_SENTINEL_ADDRS":  "s1:26379, s2:26379,",
+		"REDIS_URL":             "redis://:pw@ignored:6379/1",
+	}))
+	if err != nil {
+		t.Fatalf("sentinel mode: %v", err)
+	}
+	if c, ok := failover.(*redis.Client); !ok || c.Options().Password != "pw" || c.Options().DB != 1 {
+		t.Errorf("sentinel mode built %T without the REDIS_URL credentials", failover)
+	}
+	failover.Close()
+
+	cluster, _, err := newRedisClient(env(map[string]string{
+		"REDIS_MODE":          "cluster",
+		"REDIS_URL":           "redis://n1:6379",
+		"REDIS_CLUSTER_ADDRS": "n2:6379,n3:6379",
+	}))
+	if err != nil {
+		t.Fatalf("cluster mode: %v", err)
+	}
+	if c, ok := cluster.(*redis.ClusterClient); !ok || len(c.Options().Addrs) != 3 {
+		t.Errorf("cluster mode built %T, want a ClusterClient with 3 seeds", cluster)
+	}
+	cluster.Close()
+
+	for name, vars := range map[string]map[string]string{
+		"single without url":      {},
+		"sentinel without master": {"REDIS_MODE": "sentinel", "REDIS_SENTINEL_ADDRS": "s1:26379"},
+		"unknown mode":            {"REDIS_MODE": "ring", "REDIS_URL": "redis://localhost:6379"},
+	} {
+		if _, _, err := newRedisClient(env(vars)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
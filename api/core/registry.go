@@ -113,6 +113,57 @@ func (r *topicRegistry) unsubscribeAll(userID string) {
 	r.userToTopics.Delete(userID)
 }
 
+// setUserTopics replaces a user's topics in one step, so routing for
+// topics they keep never lapses.
+func (r *topicRegistry) setUserTopics(userID string, topics []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want := make(map[string]struct{}, len(topics))
+	for _, topic := range topics {
+		want[topic] = struct{}{}
+	}
+	var have map[string]struct{}
+	if existing, ok := r.userToTopics.Load(userID); ok {
+		have = existing.(map[string]struct{})
+	}
+
+	for topic := range have {
+		if _, keep := want[topic]; keep {
+			continue
+		}
+		if existing, ok := r.topicToUsers.Load(topic); ok {
+			old := existing.(map[string]struct{})
+			if len(old) <= 1 {
+				r.topicToUsers.Delete(topic)
+			} else {
+				newUsers := cloneSet(old)
+				delete(newUsers, userID)
+				r.topicToUsers.Store(topic, newUsers)
+			}
+		}
+	}
+	for topic := range want {
+		if _, had := have[topic]; had {
+			continue
+		}
+		var newUsers map[string]struct{}
+		if existing, ok := r.topicToUsers.Load(topic); ok {
+			newUsers = cloneSet(existing.(map[string]struct{}))
+		} else {
+			newUsers = make(map[string]struct{}, 1)
+		}
+		newUsers[userID] = struct{}{}
+		r.topicToUsers.Store(topic, newUsers)
+	}
+
+	if len(want) == 0 {
+		r.userToTopics.Delete(userID)
+	} else {
+		r.userToTopics.Store(userID, want)
+	}
+}
+
 // getUsersForTopic returns an immutable snapshot of user IDs subscribed to a
 // topic. Safe for concurrent iteration -- the returned map is never mutated.
 func (r *topicRegistry) getUsersForTopic(topic string) map[string]struct{} {
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// Persisted topic subscriptions.
+//
+// A user's topics are worked out from their channels and organizations
+// in Postgres (userTopics), which takes a few queries. On a rolling
+// deploy every client reconnects at once, and until that lookup
+// finishes the new replica routes them nothing. So each lookup's result
+// is also written to the RedisSSETopicsKey hash (user -> JSON topic
+// list), and RegisterClient restores the registry from it straight
+// away. The lookup still runs behind it and replaces the restored set.
+//
+// The registry itself only ever holds users connected to this replica:
+// dispatch invalidates each recipient's caches, so filling it with every
+// persisted user at InitHub would cost work for people who aren't there.
+
+// persistUserTopics records a user's topics for the next connection.
+func persistUserTopics(ctx context.Context, userID string, topics []string) {
+	data, err := json.Marshal(topics)
+	if err != nil {
+		return
+	}
+	if err := Rdb.HSet(ctx, RedisSSETopicsKey, userID, data).Err(); err != nil {
+		log.Printf("[EventHub] Failed to persist topics for %s: %v", userID, err)
+	}
+}
+
+// loadPersistedTopics returns the topics last recorded for a user.
+func loadPersistedTopics(ctx context.Context, userID string) ([]string, bool) {
+	data, err := Rdb.HGet(ctx, RedisSSETopicsKey, userID).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var topics []string
+	if err := json.Unmarshal(data, &topics); err != nil {
+		return nil, false
+	}
+	return topics, true
+}
+
+// forgetUserTopics drops a user's recorded topics, so the next
+// connection waits for a fresh lookup rather than restoring stale ones.
+func forgetUserTopics(ctx context.Context, userID string) {
+	if err := Rdb.HDel(ctx, RedisSSETopicsKey, userID).Err(); err != nil {
+		log.Printf("[EventHub] Failed to drop persisted topics for %s: %v", userID, err)
+	}
+}
+
+// restoreUserTopics fills the registry for a user who just connected
+// from their persisted topics, unless the registry already has them
+// (another connection on this replica).
+func restoreUserTopics(userID string) {
+	if _, ok := globalHub.registry.userToTopics.Load(userID); ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), SSETopicRestoreTimeout)
+	defer cancel()
+	if topics, ok := loadPersistedTopics(ctx, userID); ok {
+		globalHub.registry.setUserTopics(userID, topics)
+	}
+}
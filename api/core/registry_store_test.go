@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSetUserTopicsReplaces(t *testing.T) {
+	r := &topicRegistry{}
+	r.subscribe("b", "cdc:finance:AAPL")
+	r.setUserTopics("a", []string{"cdc:finance:AAPL", "cdc:sports:NFL"})
+	r.setUserTopics("a", []string{"cdc:finance:AAPL", "cdc:rss:1"})
+
+	if _, ok := r.getUsersForTopic("cdc:sports:NFL")["a"]; ok {
+		t.Error("dropped topic still routes to a")
+	}
+	if users := r.getUsersForTopic("cdc:finance:AAPL"); len(users) != 2 {
+		t.Errorf("cdc:finance:AAPL users = %v, want a and b", users)
+	}
+	if _, ok := r.getUsersForTopic("cdc:rss:1")["a"]; !ok {
+		t.Error("new topic not routed to a")
+	}
+
+	r.setUserTopics("a", nil)
+	if _, ok := r.userToTopics.Load("a"); ok {
+		t.Error("empty topic list left an entry for a")
+	}
+	if users := r.getUsersForTopic("cdc:finance:AAPL"); len(users) != 1 {
+		t.Errorf("cdc:finance:AAPL users = %v, want only b", users)
+	}
+}
+
+func TestRestoreUserTopics(t *testing.T) {
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	prevHub := globalHub
+	globalHub = &Hub{registry: &topicRegistry{}}
+	defer func() { globalHub = prevHub }()
+
+	ctx := context.Background()
+	topics := []string{"cdc:finance:AAPL", "cdc:sports:NFL"}
+	persistUserTopics(ctx, "user-1", topics)
+	if got, ok := loadPersistedTopics(ctx, "user-1"); !ok || !slices.Equal(got, topics) {
+		t.Fatalf("persisted topics = %v, %v; want %v", got, ok, topics)
+	}
+
+	restoreUserTopics("user-1")
+	if _, ok := globalHub.registry.getUsersForTopic("cdc:sports:NFL")["user-1"]; !ok {
+		t.Error("restored topics are not routed")
+	}
+
+	forgetUserTopics(ctx, "user-1")
+	if _, ok := loadPersistedTopics(ctx, "user-1"); ok {
+		t.Error("topics still persisted after forget")
+	}
+}
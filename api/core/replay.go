@@ -0,0 +1,277 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Replay buffer for reconnecting event-stream clients.
+//
+// Every gateway replica PSubscribes to every topic, so each Hub already
+// sees every event regardless of which replica the client reconnects to.
+// The Hub keeps the last SSEReplayPerTopic envelopes of each topic for
+// SSEReplayRetention, keyed by the envelope's global seq (clock.go). On
+// reconnect, /events reads Last-Event-ID (EventSource sends it
+// automatically once the stream has carried an "id:" line) and /ws reads
+// ?last_event_id=, and the client gets the events it missed on its own
+// topics instead of refetching /dashboard.
+//
+// Replay is best-effort and says so: when events newer than the client's
+// cursor have already been evicted, aged out, or predate this replica's
+// subscription, the client is sent a resync event and does the full
+// refetch it would have done anyway. Envelopes without a seq (seq INCR
+// failed, or non-CDC payloads like incidents) are never buffered.
+
+const (
+	// LastEventIDHeader is set by EventSource on automatic reconnects.
+	LastEventIDHeader = "Last-Event-ID"
+
+	replayResyncReason = "replay-gap"
+)
+
+// replayedEvent is a buffered envelope and its seq.
+type replayedEvent struct {
+	seq     int64
+	at      time.Time
+	payload []byte
+}
+
+// replayRing holds the most recent envelopes published to one topic,
+// oldest first.
+type replayRing struct {
+	mu      sync.Mutex
+	entries []replayedEvent
+	// evicted is the highest seq dropped for capacity. A cursor below it
+	// may have missed events on this topic.
+	evicted int64
+	// dead is set when the sweeper removes the ring from the buffer;
+	// record retries with a fresh ring instead of writing into it.
+	dead bool
+}
+
+// replayBuffer is the Hub's per-topic replay store.
+type replayBuffer struct {
+	topics sync.Map // topic string -> *replayRing
+
+	// floor is the latest seq issued before this Hub subscribed. Events at
+	// or below it may have been published before we were listening.
+	floor int64
+	// horizon is the highest seq pruned for age, on any topic. A cursor
+	// below it means the client has been away longer than the retention.
+	horizon atomic.Int64
+}
+
+func newReplayBuffer(floor int64) *replayBuffer {
+	return &replayBuffer{floor: floor}
+}
+
+// envelopeSeq returns the top-level seq of a CDC envelope, or 0 if the
+// payload has none.
+func envelopeSeq(payload []byte) int64 {
+	seq, _ := envelopeHeader(payload)
+	return seq
+}
+
+// envelopeHeader returns the top-level seq and server_ts (unix ms) of a
+// CDC envelope; either is 0 when absent.
+func envelopeHeader(payload []byte) (seq, serverTS int64) {
+	var env struct {
+		Seq      int64 `json:"seq"`
+		ServerTS int64 `json:"server_ts"`
+	}
+	if json.Unmarshal(payload, &env) != nil {
+		return 0, 0
+	}
+	return env.Seq, env.ServerTS
+}
+
+// record buffers a topic payload if it carries a seq.
+func (b *replayBuffer) record(topic string, payload []byte, now time.Time) {
+	seq := envelopeSeq(payload)
+	if seq <= 0 {
+		return
+	}
+	for {
+		value, _ := b.topics.LoadOrStore(topic, &replayRing{})
+		ring := value.(*replayRing)
+		ring.mu.Lock()
+		if ring.dead {
+			ring.mu.Unlock()
+			continue
+		}
+		b.pruneLocked(ring, now)
+		if len(ring.entries) >= SSEReplayPerTopic {
+			ring.evicted = max(ring.evicted, ring.entries[0].seq)
+			ring.entries = append(ring.entries[:0], ring.entries[1:]...)
+		}
+		ring.entries = append(ring.entries, replayedEvent{seq: seq, at: now, payload: payload})
+		ring.mu.Unlock()
+		return
+	}
+}
+
+// pruneLocked drops entries older than SSEReplayRetention. ring.mu must
+// be held.
+func (b *replayBuffer) pruneLocked(ring *replayRing, now time.Time) {
+	cutoff := now.Add(-SSEReplayRetention)
+	n := 0
+	for n < len(ring.entries) && ring.entries[n].at.Before(cutoff) {
+		b.raiseHorizon(ring.entries[n].seq)
+		n++
+	}
+	if n > 0 {
+		ring.entries = append(ring.entries[:0], ring.entries[n:]...)
+	}
+}
+
+func (b *replayBuffer) raiseHorizon(seq int64) {
+	for {
+		cur := b.horizon.Load()
+		if seq <= cur || b.horizon.CompareAndSwap(cur, seq) {
+			return
+		}
+	}
+}
+
+// since returns the buffered events on topics with seq > lastSeq, in seq
+// order, and whether that is everything the client missed.
+func (b *replayBuffer) since(topics []string, lastSeq int64, now time.Time) ([]replayedEvent, bool) {
+	complete := lastSeq >= b.floor
+	var events []replayedEvent
+	for _, topic := range topics {
+		value, ok := b.topics.Load(topic)
+		if !ok {
+			continue
+		}
+		ring := value.(*replayRing)
+		ring.mu.Lock()
+		b.pruneLocked(ring, now)
+		if lastSeq < ring.evicted {
+			complete = false
+		}
+		for _, e := range ring.entries {
+			if e.seq > lastSeq {
+				events = append(events, e)
+			}
+		}
+		ring.mu.Unlock()
+	}
+	// The horizon is read after pruning so this request's own pruning
+	// counts.
+	if lastSeq < b.horizon.Load() {
+		complete = false
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].seq < events[j].seq })
+	return events, complete
+}
+
+// sweep prunes every ring and drops the ones left empty, so topics that
+// have gone quiet don't hold memory forever.
+func (b *replayBuffer) sweep(now time.Time) {
+	b.topics.Range(func(key, value any) bool {
+		ring := value.(*replayRing)
+		ring.mu.Lock()
+		b.pruneLocked(ring, now)
+		if len(ring.entries) == 0 {
+			// Evictions on this topic are all older than the retention
+			// by now, so the horizon already covers them.
+			b.raiseHorizon(ring.evicted)
+			ring.dead = true
+			b.topics.Delete(key)
+		}
+		ring.mu.Unlock()
+		return true
+	})
+}
+
+// sweepLoop runs sweep every SSEReplayRetention until ctx is done.
+func (b *replayBuffer) sweepLoop(ctx context.Context) {
+	ticker := time.NewTicker(SSEReplayRetention)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.sweep(now)
+		}
+	}
+}
+
+// replayEvents returns what a user missed since lastSeq across their
+// topics: their channel topics, their core topic and the broadcast topic.
+func replayEvents(userID string, lastSeq int64) ([]replayedEvent, bool) {
+	topics := append(userTopics(context.Background(), userID),
+		TopicPrefixCore+userID, TopicBroadcast)
+	events, complete := globalHub.replay.since(topics, lastSeq, time.Now())
+
+	// Same spoiler redaction as live dispatch
+	spoilers := userSpoilerFilter(context.Background(), userID)
+	kept := events[:0]
+	for _, e := range events {
+		if payload, ok := spoilers.filterEventSpoilers(e.payload); ok {
+			e.payload = payload
+			kept = append(kept, e)
+		}
+	}
+	return kept, complete
+}
+
+// lastEventID reads the client's resume cursor from the Last-Event-ID
+// header or, for WebSocket and EventSource polyfills that can't set
+// headers, the last_event_id query parameter. 0 means no cursor.
+func lastEventID(c *fiber.Ctx) int64 {
+	raw := c.Get(LastEventIDHeader)
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	seq, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || seq < 0 {
+		return 0
+	}
+	return seq
+}
+
+// writeSSEData writes one SSE message, with an id line when the payload
+// has a seq so EventSource can send it back as Last-Event-ID.
+func writeSSEData(w *bufio.Writer, seq int64, payload []byte) {
+	if seq > 0 {
+		fmt.Fprintf(w, "id: %d\n", seq)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+}
+
+// replayCursor suppresses live events a client already received through
+// replay: the client is registered before the replay is read, so an
+// event published in between arrives both ways.
+type replayCursor struct {
+	sent map[int64]struct{}
+	max  int64
+}
+
+func newReplayCursor(events []replayedEvent) *replayCursor {
+	cur := &replayCursor{sent: make(map[int64]struct{}, len(events))}
+	for _, e := range events {
+		cur.sent[e.seq] = struct{}{}
+		cur.max = max(cur.max, e.seq)
+	}
+	return cur
+}
+
+// duplicate reports whether seq was already sent during replay.
+func (r *replayCursor) duplicate(seq int64) bool {
+	if r == nil || seq <= 0 || seq > r.max {
+		return false
+	}
+	_, ok := r.sent[seq]
+	return ok
+}
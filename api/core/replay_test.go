@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func envelope(seq int64) []byte {
+	return []byte(fmt.Sprintf(`{"data":[{"record":{"seq":999}}],"seq":%d,"server_ts":1}`, seq))
+}
+
+func seqs(events []replayedEvent) []int64 {
+	out := make([]int64, len(events))
+	for i, e := range events {
+		out[i] = e.seq
+	}
+	return out
+}
+
+func TestEnvelopeSeq(t *testing.T) {
+	if got := envelopeSeq(envelope(42)); got != 42 {
+		t.Errorf("envelopeSeq = %d, want 42 (nested record seq must not win)", got)
+	}
+	if got := envelopeSeq([]byte(`{"data":[]}`)); got != 0 {
+		t.Errorf("envelopeSeq without seq = %d, want 0", got)
+	}
+	if got := envelopeSeq([]byte(`not json`)); got != 0 {
+		t.Errorf("envelopeSeq on garbage = %d, want 0", got)
+	}
+}
+
+func TestReplaySinceMergesTopicsInSeqOrder(t *testing.T) {
+	b := newReplayBuffer(0)
+	now := time.Now()
+	b.record("finance:AAPL", envelope(1), now)
+	b.record("sports:NFL", envelope(2), now)
+	b.record("finance:AAPL", envelope(3), now)
+	b.record("finance:MSFT", envelope(4), now) // not the user's topic
+	b.record("sports:NFL", envelope(5), now)
+	b.record("finance:AAPL", []byte(`{"data":[]}`), now) // no seq, not buffered
+
+	events, complete := b.since([]string{"finance:AAPL", "sports:NFL", "core:u1"}, 2, now)
+	if !complete {
+		t.Error("complete = false, want true")
+	}
+	if got := seqs(events); fmt.Sprint(got) != "[3 5]" {
+		t.Errorf("replayed seqs = %v, want [3 5]", got)
+	}
+}
+
+func TestReplayCapacityEvictionIsAGap(t *testing.T) {
+	b := newReplayBuffer(0)
+	now := time.Now()
+	for seq := int64(1); seq <= SSEReplayPerTopic+2; seq++ {
+		b.record("finance:AAPL", envelope(seq), now)
+	}
+
+	events, complete := b.since([]string{"finance:AAPL"}, 1, now)
+	if complete {
+		t.Error("complete = true after seq 2 was evicted, want false")
+	}
+	if len(events) != SSEReplayPerTopic {
+		t.Errorf("replayed %d events, want %d", len(events), SSEReplayPerTopic)
+	}
+
+	if _, complete := b.since([]string{"finance:AAPL"}, 2, now); !complete {
+		t.Error("cursor at the evicted seq should be complete")
+	}
+	// Evictions on someone else's topic don't affect this user.
+	if _, complete := b.since([]string{"sports:NFL"}, 1, now); !complete {
+		t.Error("eviction on another topic marked the replay incomplete")
+	}
+}
+
+func TestReplayRetentionAndFloor(t *testing.T) {
+	b := newReplayBuffer(10)
+	start := time.Now()
+	b.record("finance:AAPL", envelope(11), start)
+	b.record("finance:AAPL", envelope(12), start.Add(SSEReplayRetention))
+
+	if _, complete := b.since(nil, 9, start); complete {
+		t.Error("cursor below the floor should be incomplete")
+	}
+
+	events, complete := b.since([]string{"finance:AAPL"}, 10, start.Add(SSEReplayRetention+time.Second))
+	if complete {
+		t.Error("aged-out event newer than the cursor should make replay incomplete")
+	}
+	if got := seqs(events); fmt.Sprint(got) != "[12]" {
+		t.Errorf("replayed seqs = %v, want [12]", got)
+	}
+
+	// The horizon outlives the ring: once swept, the gap is still reported.
+	b.sweep(start.Add(3 * SSEReplayRetention))
+	if _, ok := b.topics.Load("finance:AAPL"); ok {
+		t.Error("sweep left an expired ring behind")
+	}
+	if _, complete := b.since([]string{"finance:AAPL"}, 11, start.Add(3*SSEReplayRetention)); complete {
+		t.Error("cursor older than the retention should be incomplete after sweep")
+	}
+
+	// A ring removed by sweep is recreated on the next event.
+	b.record("finance:AAPL", envelope(13), start.Add(3*SSEReplayRetention))
+	events, _ = b.since([]string{"finance:AAPL"}, 12, start.Add(3*SSEReplayRetention))
+	if got := seqs(events); fmt.Sprint(got) != "[13]" {
+		t.Errorf("replayed seqs after sweep = %v, want [13]", got)
+	}
+}
+
+func TestReplayCursorDuplicate(t *testing.T) {
+	cur := newReplayCursor([]replayedEvent{{seq: 3}, {seq: 5}})
+	for seq, want := range map[int64]bool{3: true, 5: true, 4: false, 6: false, 0: false} {
+		if got := cur.duplicate(seq); got != want {
+			t.Errorf("duplicate(%d) = %v, want %v", seq, got, want)
+		}
+	}
+	var none *replayCursor
+	if none.duplicate(3) {
+		t.Error("nil cursor reported a duplicate")
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	cases := []struct {
+		header, query string
+		want          int64
+	}{
+		{"42", "", 42},
+		{"", "17", 17},
+		{"42", "17", 42},
+		{"", "", 0},
+		{"abc", "", 0},
+		{"-5", "", 0},
+	}
+	for _, tc := range cases {
+		app := fiber.New()
+		var got int64
+		app.Get("/", func(c *fiber.Ctx) error {
+			got = lastEventID(c)
+			return nil
+		})
+		req := httptest.NewRequest("GET", "/?last_event_id="+tc.query, nil)
+		if tc.header != "" {
+			req.Header.Set(LastEventIDHeader, tc.header)
+		}
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("header=%q query=%q: lastEventID = %d, want %d", tc.header, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestWriteSSEDataIDLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeSSEData(w, 7, []byte(`{"seq":7}`))
+	writeSSEData(w, 0, []byte(`{"data":[]}`))
+	w.Flush()
+
+	want := "id: 7\ndata: {\"seq\":7}\n\ndata: {\"data\":[]}\n\n"
+	if buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+}
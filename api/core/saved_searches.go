@@ -0,0 +1,846 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Saved searches and the notifications inbox. Other notification
+// sources and per-kind preferences live in notifications.go.
+//
+// A saved search is a keyword query, a list of ticker symbols, or both.
+// New rss_items arriving over CDC are matched against every enabled
+// search whose owner follows the item's feed (directly or through an
+// organization); a match writes a notification and pushes it down the
+// owner's core SSE topic as a "notifications" insert, so an open client
+// can badge the inbox without polling.
+//
+// Matching:
+//
+//   - query: every term must appear as a whole word in the title or
+//     description, case-insensitively. "Quoted phrases" count as one term.
+//   - symbols: at least one must appear as a whole word, upper-case,
+//     optionally with a $ prefix ("AAPL", "$AAPL"). Case matters so
+//     tickers like ON or IT don't match ordinary prose.
+//
+// Each gateway keeps the enabled searches in memory, reloaded every
+// SavedSearchRefreshInterval (and on local edits). Notifications are
+// deduplicated per user and article, and capped per search per hour.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// SavedSearch is a user's saved query.
+type SavedSearch struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Query         string     `json:"query"`
+	Symbols       []string   `json:"symbols"`
+	Enabled       bool       `json:"enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastMatchedAt *time.Time `json:"last_matched_at"`
+}
+
+// Notification is one inbox entry.
+type Notification struct {
+	ID            int64      `json:"id"`
+	Kind          string     `json:"kind"`
+	Title         string     `json:"title"`
+	Body          string     `json:"body"`
+	Link          *string    `json:"link"`
+	SavedSearchID *int64     `json:"saved_search_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReadAt        *time.Time `json:"read_at"`
+}
+
+// savedSearchInput is the POST/PUT body.
+type savedSearchInput struct {
+	Name    string   `json:"name"`
+	Query   string   `json:"query"`
+	Symbols []string `json:"symbols"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// compiledSearch is a saved search ready for matching.
+type compiledSearch struct {
+	ID      int64
+	Owner   string
+	Name    string
+	Terms   []string // lower-cased
+	Symbols []string // upper-cased
+	Feeds   map[string]bool
+}
+
+var savedSearchIndex struct {
+	sync.RWMutex
+	searches []compiledSearch
+}
+
+// ─── Matching ────────────────────────────────────────────────────
+
+// parseSearchTerms splits a query into lower-cased terms, keeping
+// "quoted phrases" together.
+func parseSearchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if p := strings.Join(strings.Fields(strings.ToLower(part)), " "); p != "" {
+				terms = append(terms, p)
+			}
+			continue
+		}
+		for _, f := range strings.Fields(strings.ToLower(part)) {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// normalizeSymbols upper-cases, trims and de-duplicates symbols.
+func normalizeSymbols(in []string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		s = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(s), "$"))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// validateSavedSearch normalizes in and returns a client-facing error
+// when it can't be saved.
+func validateSavedSearch(in *savedSearchInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Query = strings.TrimSpace(in.Query)
+	in.Symbols = normalizeSymbols(in.Symbols)
+	if in.Name == "" || len(in.Name) > 64 {
+		return errors.New("Name must be 1-64 characters")
+	}
+	if len(in.Query) > 500 {
+		return errors.New("Query must be at most 500 characters")
+	}
+	terms := parseSearchTerms(in.Query)
+	if len(terms) == 0 && len(in.Symbols) == 0 {
+		return errors.New("A query or at least one symbol is required")
+	}
+	if len(terms) > SavedSearchMaxTerms {
+		return fmt.Errorf("At most %d search terms", SavedSearchMaxTerms)
+	}
+	if len(in.Symbols) > SavedSearchMaxSymbols {
+		return fmt.Errorf("At most %d symbols", SavedSearchMaxSymbols)
+	}
+	for _, s := range in.Symbols {
+		if len(s) > 20 {
+			return fmt.Errorf("Invalid symbol %q", s)
+		}
+	}
+	return nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// containsWord reports whether word occurs in text with no letter or
+// digit directly on either side.
+func containsWord(text, word string) bool {
+	for from := 0; from <= len(text)-len(word); {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		from = start + 1
+	}
+	return false
+}
+
+// matches reports whether an item with the given text satisfies s.
+func (s compiledSearch) matches(text, lower string) bool {
+	for _, t := range s.Terms {
+		if !containsWord(lower, t) {
+			return false
+		}
+	}
+	if len(s.Symbols) == 0 {
+		return true
+	}
+	for _, sym := range s.Symbols {
+		if containsWord(text, sym) {
+			return true
+		}
+	}
+	return false
+}
+
+// rssItemFields pulls what matching needs out of a CDC rss_items record.
+func rssItemFields(record map[string]interface{}) (feedURL, title, description, link, source, key string) {
+	str := func(k string) string {
+		v, _ := record[k].(string)
+		return v
+	}
+	feedURL, title, description = str("feed_url"), str("title"), str("description")
+	link, source = str("link"), str("source_name")
+	switch id := record["id"].(type) {
+	case float64:
+		key = "rss_item:" + strconv.FormatInt(int64(id), 10)
+	default:
+		if guid := str("guid"); guid != "" {
+			key = "rss_guid:" + feedURL + ":" + guid
+		}
+	}
+	return
+}
+
+// MatchSavedSearches evaluates a CDC record against the saved search
+// index. Only rss_items inserts are considered. It runs on
+// BackgroundPool so the Sequin webhook isn't held up.
+func MatchSavedSearches(rec CDCRecord) {
+	if rec.Metadata.TableName != "rss_items" || rec.Action != "insert" {
+		return
+	}
+	BackgroundPool.Submit("saved-search-match", func(ctx context.Context) {
+		matchRSSItem(ctx, rec.Record)
+	})
+}
+
+func matchRSSItem(ctx context.Context, record map[string]interface{}) {
+	feedURL, title, description, link, source, key := rssItemFields(record)
+	if feedURL == "" || title == "" || key == "" {
+		return
+	}
+	text := title + "\n" + description
+	lower := strings.ToLower(text)
+
+	savedSearchIndex.RLock()
+	var hits []compiledSearch
+	for _, s := range savedSearchIndex.searches {
+		if s.Feeds[feedURL] && s.matches(text, lower) {
+			hits = append(hits, s)
+		}
+	}
+	savedSearchIndex.RUnlock()
+
+	notified := make(map[string]bool)
+	for _, s := range hits {
+		if notified[s.Owner] || !allowSearchNotification(ctx, s.ID) {
+			continue
+		}
+		n := Notification{
+			Kind:          "saved_search",
+			Title:         title,
+			Body:          fmt.Sprintf("Matched %q", s.Name),
+			SavedSearchID: &s.ID,
+		}
+		if source != "" {
+			n.Body += " · " + source
+		}
+		if link != "" {
+			n.Link = &link
+		}
+		created, err := notifyUser(ctx, s.Owner, key, &n)
+		if err != nil {
+			log.Printf("[SavedSearch] notification insert failed for search %d: %v", s.ID, err)
+			continue
+		}
+		notified[s.Owner] = true
+		if !created {
+			continue
+		}
+		if _, err := DBPool.Exec(ctx, `UPDATE saved_searches SET last_matched_at = now() WHERE id = $1`, s.ID); err != nil {
+			log.Printf("[SavedSearch] last_matched_at update failed for search %d: %v", s.ID, err)
+		}
+	}
+}
+
+// allowSearchNotification applies SavedSearchNotifyMaxPerHour. Redis
+// errors let the notification through.
+func allowSearchNotification(ctx context.Context, searchID int64) bool {
+	if Rdb == nil {
+		return true
+	}
+	key := RedisSavedSearchRLPrefix + strconv.FormatInt(searchID, 10)
+	count, err := Rdb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("[SavedSearch] Redis INCR failed (continuing): %v", err)
+		return true
+	}
+	if count == 1 {
+		if err := Rdb.Expire(ctx, key, time.Hour).Err(); err != nil {
+			log.Printf("[SavedSearch] Redis EXPIRE failed for key=%s (key has no TTL): %v", key, err)
+		}
+	}
+	return count <= SavedSearchNotifyMaxPerHour
+}
+
+// insertNotification stores n for sub, filling in ID and CreatedAt.
+// created is false when dedupeKey already produced a notification.
+// digestPending holds it for the next digest (notifications.go).
+func insertNotification(ctx context.Context, sub, dedupeKey string, n *Notification, digestPending bool) (created bool, err error) {
+	err = DBPool.QueryRow(ctx, `
+		INSERT INTO notifications (logto_sub, kind, title, body, link, saved_search_id, dedupe_key, digest_pending)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (logto_sub, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+		RETURNING id, created_at
+	`, sub, n.Kind, n.Title, n.Body, n.Link, n.SavedSearchID, dedupeKey, digestPending).Scan(&n.ID, &n.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// publishNotification pushes a new notification to the owner's open
+// clients as a CDC-shaped insert on the "notifications" table.
+func publishNotification(ctx context.Context, sub string, n Notification) {
+	envelope := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"action": "insert",
+				"record": n,
+				"metadata": map[string]string{
+					"table_schema": "public",
+					"table_name":   "notifications",
+				},
+			},
+		},
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq := nextEventSeq(ctx); seq > 0 {
+		envelope["seq"] = seq
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[SavedSearch] marshal SSE payload failed: %v", err)
+		return
+	}
+	if err := PublishRaw(TopicPrefixCore+sub, payload); err != nil {
+		log.Printf("[SavedSearch] publish for %s failed: %v", sub, err)
+	}
+}
+
+// ─── Index ───────────────────────────────────────────────────────
+
+// StartSavedSearchMatcher keeps the in-memory search index fresh and
+// prunes old notifications. Each replica matches the CDC batches it
+// receives, so every replica runs this.
+func StartSavedSearchMatcher(ctx context.Context) {
+	go func() {
+		reloadSavedSearches(ctx)
+
+		ticker := time.NewTicker(SavedSearchRefreshInterval)
+		defer ticker.Stop()
+		var lastPrune time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloadSavedSearches(ctx)
+				if time.Since(lastPrune) >= time.Hour {
+					lastPrune = time.Now()
+					if _, err := DBPool.Exec(ctx, `DELETE FROM notifications WHERE created_at < $1`,
+						time.Now().Add(-NotificationRetention)); err != nil {
+						log.Printf("[SavedSearch] notification prune failed: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// reloadSavedSearches rebuilds the index from Postgres, resolving each
+// owner's followed feeds once.
+func reloadSavedSearches(ctx context.Context) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT id, logto_sub, name, query, symbols
+		FROM saved_searches
+		WHERE enabled
+	`)
+	if err != nil {
+		log.Printf("[SavedSearch] reload failed: %v", err)
+		return
+	}
+	var searches []compiledSearch
+	for rows.Next() {
+		var s compiledSearch
+		var query string
+		if err := rows.Scan(&s.ID, &s.Owner, &s.Name, &query, &s.Symbols); err != nil {
+			log.Printf("[SavedSearch] scan error: %v", err)
+			continue
+		}
+		s.Terms = parseSearchTerms(query)
+		searches = append(searches, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("[SavedSearch] reload failed: %v", err)
+		return
+	}
+
+	feeds := make(map[string]map[string]bool)
+	for i := range searches {
+		owner := searches[i].Owner
+		if _, ok := feeds[owner]; !ok {
+			feeds[owner] = userFeedURLs(owner)
+		}
+		searches[i].Feeds = feeds[owner]
+	}
+
+	savedSearchIndex.Lock()
+	savedSearchIndex.searches = searches
+	savedSearchIndex.Unlock()
+}
+
+// userFeedURLs returns the RSS feeds sub follows, personally or through
+// an organization's shared channels.
+func userFeedURLs(sub string) map[string]bool {
+	out := make(map[string]bool)
+	add := func(channels []Channel) {
+		for _, ch := range channels {
+			if ch.Enabled && ch.ChannelType == "rss" {
+				for _, u := range extractFeedURLsFromConfig(ch.Config) {
+					out[u] = true
+				}
+			}
+		}
+	}
+	if channels, err := GetUserChannels(sub); err == nil {
+		add(channels)
+	}
+	if orgs, err := userOrganizations(context.Background(), sub); err == nil {
+		for _, org := range orgs {
+			if shared, err := orgSharedChannels(org.ID); err == nil {
+				add(shared)
+			}
+		}
+	}
+	return out
+}
+
+// refreshSavedSearchesAsync applies a local edit without waiting for the
+// next tick.
+func refreshSavedSearchesAsync() {
+	BackgroundPool.Submit("saved-search-reload", reloadSavedSearches)
+}
+
+// ─── Saved search handlers ───────────────────────────────────────
+
+// HandleListSavedSearches returns the caller's saved searches.
+//
+// @Summary List saved searches
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{saved_searches=[]SavedSearch}
+// @Security LogtoAuth
+// @Router /users/me/saved-searches [get]
+func HandleListSavedSearches(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT id, name, query, symbols, enabled, created_at, updated_at, last_matched_at
+		FROM saved_searches
+		WHERE logto_sub = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[SavedSearch] list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list saved searches",
+		})
+	}
+	defer rows.Close()
+
+	searches := make([]SavedSearch, 0)
+	for rows.Next() {
+		var s SavedSearch
+		if err := rows.Scan(&s.ID, &s.Name, &s.Query, &s.Symbols, &s.Enabled, &s.CreatedAt, &s.UpdatedAt, &s.LastMatchedAt); err != nil {
+			log.Printf("[SavedSearch] scan error: %v", err)
+			continue
+		}
+		searches = append(searches, s)
+	}
+	return c.JSON(fiber.Map{"saved_searches": searches})
+}
+
+// HandleCreateSavedSearch saves a search.
+//
+// @Summary Create saved search
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param body body object{name=string,query=string,symbols=[]string,enabled=bool} true "Search definition"
+// @Success 201 {object} SavedSearch
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/saved-searches [post]
+func HandleCreateSavedSearch(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var in savedSearchInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if err := validateSavedSearch(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	var count int
+	if err := DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM saved_searches WHERE logto_sub = $1`, userID).Scan(&count); err != nil {
+		log.Printf("[SavedSearch] count failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to save search",
+		})
+	}
+	if count >= SavedSearchMaxPerUser {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("You can have at most %d saved searches; delete one first", SavedSearchMaxPerUser),
+		})
+	}
+
+	s := SavedSearch{Name: in.Name, Query: in.Query, Symbols: in.Symbols, Enabled: in.Enabled == nil || *in.Enabled}
+	if err := DBPool.QueryRow(ctx, `
+		INSERT INTO saved_searches (logto_sub, name, query, symbols, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, userID, s.Name, s.Query, s.Symbols, s.Enabled).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		log.Printf("[SavedSearch] insert failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to save search",
+		})
+	}
+
+	refreshSavedSearchesAsync()
+	return c.Status(fiber.StatusCreated).JSON(s)
+}
+
+// HandleUpdateSavedSearch replaces one of the caller's saved searches.
+//
+// @Summary Update saved search
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Param body body object{name=string,query=string,symbols=[]string,enabled=bool} true "Search definition"
+// @Success 200 {object} SavedSearch
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/saved-searches/{id} [put]
+func HandleUpdateSavedSearch(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid saved search id",
+		})
+	}
+	var in savedSearchInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if err := validateSavedSearch(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	s := SavedSearch{ID: id, Name: in.Name, Query: in.Query, Symbols: in.Symbols, Enabled: in.Enabled == nil || *in.Enabled}
+	err = DBPool.QueryRow(c.Context(), `
+		UPDATE saved_searches
+		SET name = $3, query = $4, symbols = $5, enabled = $6, updated_at = now()
+		WHERE id = $1 AND logto_sub = $2
+		RETURNING created_at, updated_at, last_matched_at
+	`, id, userID, s.Name, s.Query, s.Symbols, s.Enabled).Scan(&s.CreatedAt, &s.UpdatedAt, &s.LastMatchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Saved search not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[SavedSearch] update %d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update saved search",
+		})
+	}
+
+	refreshSavedSearchesAsync()
+	return c.JSON(s)
+}
+
+// HandleDeleteSavedSearch deletes one of the caller's saved searches.
+// Notifications it produced stay in the inbox.
+//
+// @Summary Delete saved search
+// @Tags Users
+// @Produce json
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/saved-searches/{id} [delete]
+func HandleDeleteSavedSearch(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid saved search id",
+		})
+	}
+	tag, err := DBPool.Exec(c.Context(), `DELETE FROM saved_searches WHERE id = $1 AND logto_sub = $2`, id, userID)
+	if err != nil {
+		log.Printf("[SavedSearch] delete %d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete saved search",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Saved search not found",
+		})
+	}
+
+	refreshSavedSearchesAsync()
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// ─── Notification handlers ───────────────────────────────────────
+
+// HandleListNotifications returns the caller's inbox, newest first,
+// NotificationsPageLimit at a time. ?before= takes the last ID of the
+// previous page; ?unread=true hides read entries; ?kind= keeps one kind.
+// Unread counts cover the whole inbox, overall and per kind.
+//
+// @Summary List notifications
+// @Tags Users
+// @Produce json
+// @Param before query int false "Return notifications older than this ID"
+// @Param unread query bool false "Only unread notifications"
+// @Param kind query string false "Only this kind (price_alert, fantasy_score, breaking_news, saved_search, digest)"
+// @Success 200 {object} object{notifications=[]Notification,unread_count=int,unread_by_kind=map[string]int}
+// @Security LogtoAuth
+// @Router /users/me/notifications [get]
+func HandleListNotifications(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var before *int64
+	if raw := c.Query("before"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Invalid before id",
+			})
+		}
+		before = &n
+	}
+	unreadOnly := c.QueryBool("unread")
+	kind := c.Query("kind")
+
+	ctx := c.Context()
+	rows, err := DBPool.Query(ctx, `
+		SELECT id, kind, title, body, link, saved_search_id, created_at, read_at
+		FROM notifications
+		WHERE logto_sub = $1
+		  AND ($2::bigint IS NULL OR id < $2)
+		  AND (NOT $3 OR read_at IS NULL)
+		  AND ($5 = '' OR kind = $5)
+		ORDER BY id DESC
+		LIMIT $4
+	`, userID, before, unreadOnly, NotificationsPageLimit, kind)
+	if err != nil {
+		log.Printf("[Notifications] list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list notifications",
+		})
+	}
+	defer rows.Close()
+
+	notifications := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.Kind, &n.Title, &n.Body, &n.Link, &n.SavedSearchID, &n.CreatedAt, &n.ReadAt); err != nil {
+			log.Printf("[Notifications] scan error: %v", err)
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+
+	unread := 0
+	byKind := make(map[string]int)
+	countRows, err := DBPool.Query(ctx, `
+		SELECT kind, COUNT(*) FROM notifications
+		WHERE logto_sub = $1 AND read_at IS NULL
+		GROUP BY kind
+	`, userID)
+	if err != nil {
+		log.Printf("[Notifications] unread count failed: %v", err)
+	} else {
+		for countRows.Next() {
+			var k string
+			var n int
+			if countRows.Scan(&k, &n) == nil {
+				byKind[k] = n
+				unread += n
+			}
+		}
+		countRows.Close()
+	}
+	return c.JSON(fiber.Map{"notifications": notifications, "unread_count": unread, "unread_by_kind": byKind})
+}
+
+// HandleMarkNotificationRead marks one notification read.
+//
+// @Summary Mark notification read
+// @Tags Users
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/notifications/{id}/read [post]
+func HandleMarkNotificationRead(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid notification id",
+		})
+	}
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE notifications SET read_at = COALESCE(read_at, now())
+		WHERE id = $1 AND logto_sub = $2
+	`, id, userID)
+	if err != nil {
+		log.Printf("[Notifications] mark read %d failed: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update notification",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Notification not found",
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleMarkAllNotificationsRead clears the caller's unread count, or
+// just one kind's with ?kind=.
+//
+// @Summary Mark all notifications read
+// @Tags Users
+// @Produce json
+// @Param kind query string false "Only this kind"
+// @Success 200 {object} object{status=string,updated=int}
+// @Security LogtoAuth
+// @Router /users/me/notifications/read-all [post]
+func HandleMarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE notifications SET read_at = now()
+		WHERE logto_sub = $1 AND read_at IS NULL
+		  AND ($2 = '' OR kind = $2)
+	`, userID, c.Query("kind"))
+	if err != nil {
+		log.Printf("[Notifications] mark all read failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update notifications",
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok", "updated": tag.RowsAffected()})
+}
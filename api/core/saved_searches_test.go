@@ -0,0 +1,97 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchTerms(t *testing.T) {
+	got := parseSearchTerms(`Rate  "Federal   Reserve" cut`)
+	want := []string{"rate", "federal reserve", "cut"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSearchTerms = %q, want %q", got, want)
+	}
+	if got := parseSearchTerms(`  "" `); len(got) != 0 {
+		t.Errorf("empty query gave %q", got)
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	cases := []struct {
+		text, word string
+		want       bool
+	}{
+		{"apple earnings beat", "apple", true},
+		{"pineapple prices", "apple", false},
+		{"apples rise", "apple", false},
+		{"shares of $AAPL jump", "AAPL", true},
+		{"AAPL.", "AAPL", true},
+		{"XAAPL and AAPL", "AAPL", true},
+		{"café apple", "apple", true},
+		{"éapple", "apple", false},
+	}
+	for _, tc := range cases {
+		if got := containsWord(tc.text, tc.word); got != tc.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tc.text, tc.word, got, tc.want)
+		}
+	}
+}
+
+func TestCompiledSearchMatches(t *testing.T) {
+	match := func(s compiledSearch, text string) bool {
+		return s.matches(text, strings.ToLower(text))
+	}
+	s := compiledSearch{Terms: parseSearchTerms(`"rate cut"`), Symbols: []string{"JPM", "BAC"}}
+
+	if !match(s, "Banks rally on Rate Cut hopes; $JPM leads") {
+		t.Error("phrase plus symbol should match")
+	}
+	if match(s, "Banks rally on rate cut hopes") {
+		t.Error("no listed symbol should not match")
+	}
+	if match(s, "jpm reports a rate cut") {
+		t.Error("symbols are case-sensitive")
+	}
+	if match(s, "JPM on rate hikes") {
+		t.Error("missing phrase should not match")
+	}
+
+	symbolsOnly := compiledSearch{Symbols: []string{"ON"}}
+	if match(symbolsOnly, "Stocks move on news") || !match(symbolsOnly, "ON Semiconductor beats") {
+		t.Error("symbol-only search should match upper-case whole words only")
+	}
+}
+
+func TestValidateSavedSearch(t *testing.T) {
+	in := savedSearchInput{Name: "  Banks ", Symbols: []string{"$jpm", "JPM", " bac "}}
+	if err := validateSavedSearch(&in); err != nil {
+		t.Fatalf("validateSavedSearch: %v", err)
+	}
+	if in.Name != "Banks" || !reflect.DeepEqual(in.Symbols, []string{"JPM", "BAC"}) {
+		t.Errorf("normalized to %+v", in)
+	}
+
+	bad := []savedSearchInput{
+		{Name: "", Query: "x"},
+		{Name: "empty"},
+		{Name: "terms", Query: strings.Repeat("w ", SavedSearchMaxTerms+1)},
+		{Name: "long symbol", Symbols: []string{strings.Repeat("X", 21)}},
+	}
+	for _, b := range bad {
+		if err := validateSavedSearch(&b); err == nil {
+			t.Errorf("validateSavedSearch(%+v) accepted", b)
+		}
+	}
+}
+
+func TestRSSItemFieldsDedupeKey(t *testing.T) {
+	_, _, _, _, _, key := rssItemFields(map[string]interface{}{"id": float64(42), "feed_url": "f"})
+	if key != "rss_item:42" {
+		t.Errorf("key = %q", key)
+	}
+	_, _, _, _, _, key = rssItemFields(map[string]interface{}{"guid": "g", "feed_url": "f"})
+	if key != "rss_guid:f:g" {
+		t.Errorf("key = %q", key)
+	}
+}
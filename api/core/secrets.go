@@ -0,0 +1,360 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Secret management.
+//
+// Credentials are read with Secret(name) instead of os.Getenv. Values come
+// from the provider chosen by SECRETS_PROVIDER, with the process
+// environment as the fallback for anything the provider doesn't hold, so
+// a partial migration (or a local .env) keeps working:
+//
+//   - env (default): the process environment only.
+//   - file: SECRETS_FILE, a JSON or dotenv file. A SOPS-encrypted file is
+//     decrypted with the sops binary, which picks up its key from the usual
+//     SOPS_* / cloud KMS environment.
+//   - vault: a HashiCorp Vault KV secret at VAULT_SECRET_PATH (KV v1 or
+//     v2), read from VAULT_ADDR with VAULT_TOKEN or the token in
+//     VAULT_TOKEN_FILE (a Vault Agent sink).
+//
+// Non-env providers are re-read every SecretsRefreshInterval. Code that
+// calls Secret() per use sees a rotated value on the next call; code that
+// copies a secret at startup (the Stripe SDK key) registers an
+// OnSecretRotate callback instead.
+
+// SecretProvider loads every secret it holds. Load is called at startup
+// and on each refresh; a failed refresh keeps the previous values.
+type SecretProvider interface {
+	Name() string
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+type secretStore struct {
+	mu       sync.RWMutex
+	provider SecretProvider
+	values   map[string]string
+	watchers map[string][]func(string)
+}
+
+var secrets = &secretStore{provider: envSecretProvider{}}
+
+// Secret returns the current value of a named secret, or "" when neither
+// the provider nor the environment has it.
+func Secret(name string) string {
+	secrets.mu.RLock()
+	v, ok := secrets.values[name]
+	secrets.mu.RUnlock()
+	if ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// OnSecretRotate registers fn to run with the new value whenever a refresh
+// changes the named secret.
+func OnSecretRotate(name string, fn func(value string)) {
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+	if secrets.watchers == nil {
+		secrets.watchers = make(map[string][]func(string))
+	}
+	secrets.watchers[name] = append(secrets.watchers[name], fn)
+}
+
+// refresh reloads the provider and runs the callbacks for changed secrets.
+func (s *secretStore) refresh(ctx context.Context) error {
+	values, err := s.provider.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.values
+	s.values = values
+	var changed []string
+	for name := range s.watchers {
+		if old != nil && lookupSecret(old, name) != lookupSecret(values, name) {
+			changed = append(changed, name)
+		}
+	}
+	type rotation struct {
+		name string
+		fns  []func(string)
+	}
+	var rotations []rotation
+	for _, name := range changed {
+		rotations = append(rotations, rotation{name, append([]func(string){}, s.watchers[name]...)})
+	}
+	s.mu.Unlock()
+
+	for _, r := range rotations {
+		log.Printf("[Secrets] %s rotated", r.name)
+		v := Secret(r.name)
+		for _, fn := range r.fns {
+			fn(v)
+		}
+	}
+	return nil
+}
+
+// lookupSecret applies Secret's fallback to a snapshot.
+func lookupSecret(values map[string]string, name string) string {
+	if v, ok := values[name]; ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// InitSecrets selects the provider, loads it, checks required secrets and
+// starts the refresh loop. Call it before anything reads a secret.
+func InitSecrets(ctx context.Context) error {
+	provider, err := secretProviderFromEnv()
+	if err != nil {
+		return err
+	}
+	secrets.provider = provider
+	if err := secrets.refresh(ctx); err != nil {
+		return fmt.Errorf("load secrets from %s: %w", provider.Name(), err)
+	}
+	if missing := missingRequiredSecrets(); len(missing) > 0 {
+		return fmt.Errorf("required secrets not set: %s", strings.Join(missing, ", "))
+	}
+	log.Printf("[Secrets] Loaded from %s provider", provider.Name())
+
+	if _, isEnv := provider.(envSecretProvider); !isEnv {
+		go secrets.refreshLoop(ctx, SecretsRefreshInterval)
+	}
+	return nil
+}
+
+func (s *secretStore) refreshLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.refresh(ctx); err != nil {
+				log.Printf("[Secrets] Refresh from %s failed, keeping previous values: %v", s.provider.Name(), err)
+			}
+		}
+	}
+}
+
+func secretProviderFromEnv() (SecretProvider, error) {
+	switch p := strings.ToLower(strings.TrimSpace(os.Getenv("SECRETS_PROVIDER"))); p {
+	case "", "env":
+		return envSecretProvider{}, nil
+	case "file", "sops":
+		path := os.Getenv("SECRETS_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("SECRETS_PROVIDER=%s requires SECRETS_FILE", p)
+		}
+		return fileSecretProvider{path: path}, nil
+	case "vault":
+		v := vaultSecretProvider{
+			addr:      strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+			path:      strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+			token:     os.Getenv("VAULT_TOKEN"),
+			tokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+			namespace: os.Getenv("VAULT_NAMESPACE"),
+			client:    NewInternalHTTPClient("vault", SecretsFetchTimeout),
+		}
+		if v.addr == "" || v.path == "" || (v.token == "" && v.tokenFile == "") {
+			return nil, fmt.Errorf("SECRETS_PROVIDER=vault requires VAULT_ADDR, VAULT_SECRET_PATH and VAULT_TOKEN or VAULT_TOKEN_FILE")
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown SECRETS_PROVIDER %q (want env, file or vault)", p)
+	}
+}
+
+// ─── Required secrets ────────────────────────────────────────────
+
+// requiredSecret is a secret the gateway can't run without. skip, when
+// set, reports a deployment that has deliberately turned the feature off.
+type requiredSecret struct {
+	Name string
+	skip func() bool
+}
+
+func stripeDisabled() bool { return os.Getenv("STRIPE_DISABLED") == "true" }
+
+var requiredSecrets = []requiredSecret{
+	{Name: "DATABASE_URL"},
+	{Name: "REDIS_URL"},
+	{Name: "SEQUIN_WEBHOOK_SECRET"},
+	{Name: "STRIPE_SECRET_KEY", skip: stripeDisabled},
+	{Name: "STRIPE_WEBHOOK_SECRET", skip: stripeDisabled},
+}
+
+func missingRequiredSecrets() []string {
+	var missing []string
+	for _, r := range requiredSecrets {
+		if r.skip != nil && r.skip() {
+			continue
+		}
+		if Secret(r.Name) == "" {
+			missing = append(missing, r.Name)
+		}
+	}
+	return missing
+}
+
+// ─── Providers ───────────────────────────────────────────────────
+
+// envSecretProvider holds nothing itself; Secret falls through to the
+// environment for every name.
+type envSecretProvider struct{}
+
+func (envSecretProvider) Name() string { return "env" }
+
+func (envSecretProvider) Load(context.Context) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// fileSecretProvider reads a JSON or dotenv file, decrypting it with sops
+// first when it carries SOPS metadata.
+type fileSecretProvider struct{ path string }
+
+func (f fileSecretProvider) Name() string { return "file" }
+
+func (f fileSecretProvider) Load(ctx context.Context) (map[string]string, error) {
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	isJSON := strings.EqualFold(filepath.Ext(f.path), ".json")
+	if isSOPSEncrypted(raw, isJSON) {
+		format := "dotenv"
+		if isJSON {
+			format = "json"
+		}
+		cmd := exec.CommandContext(ctx, "sops", "--decrypt", "--input-type", format, "--output-type", format, f.path)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if raw, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("sops decrypt %s: %v: %s", f.path, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if isJSON {
+		return parseJSONSecrets(raw)
+	}
+	return godotenv.Unmarshal(string(raw))
+}
+
+// isSOPSEncrypted spots the metadata sops adds: a top-level "sops" object
+// in JSON, sops_* keys in dotenv.
+func isSOPSEncrypted(raw []byte, isJSON bool) bool {
+	if isJSON {
+		var probe struct {
+			SOPS json.RawMessage `json:"sops"`
+		}
+		return json.Unmarshal(raw, &probe) == nil && len(probe.SOPS) > 0
+	}
+	return bytes.Contains(raw, []byte("\nsops_")) || bytes.HasPrefix(raw, []byte("sops_"))
+}
+
+// parseJSONSecrets accepts a flat object of strings (numbers and bools
+// are kept as their JSON text).
+func parseJSONSecrets(raw []byte) (map[string]string, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(obj))
+	for k, v := range obj {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			out[k] = s
+			continue
+		}
+		if len(v) > 0 && (v[0] == '{' || v[0] == '[') {
+			return nil, fmt.Errorf("secret %s: nested values are not supported", k)
+		}
+		out[k] = string(v)
+	}
+	return out, nil
+}
+
+// vaultSecretProvider reads one KV secret whose keys are secret names.
+type vaultSecretProvider struct {
+	addr      string
+	path      string // e.g. secret/data/scrollr/core-api for KV v2
+	token     string
+	tokenFile string
+	namespace string
+	client    *http.Client
+}
+
+func (v vaultSecretProvider) Name() string { return "vault" }
+
+func (v vaultSecretProvider) Load(ctx context.Context) (map[string]string, error) {
+	token := v.token
+	if v.tokenFile != "" {
+		// Re-read each time: Vault Agent renews the token in place.
+		b, err := os.ReadFile(v.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read VAULT_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", v.addr+"/v1/"+v.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %d for %s", resp.StatusCode, v.path)
+	}
+	return parseVaultSecret(body)
+}
+
+// parseVaultSecret unwraps a KV v2 ({"data":{"data":{…},"metadata":{…}}})
+// or KV v1 ({"data":{…}}) response.
+func parseVaultSecret(body []byte) (map[string]string, error) {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	var v2 struct {
+		Data     json.RawMessage `json:"data"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if json.Unmarshal(envelope.Data, &v2) == nil && len(v2.Data) > 0 && len(v2.Metadata) > 0 {
+		return parseJSONSecrets(v2.Data)
+	}
+	return parseJSONSecrets(envelope.Data)
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withSecretStore swaps the global store for the duration of a test.
+func withSecretStore(t *testing.T, p SecretProvider) *secretStore {
+	t.Helper()
+	prev := secrets
+	secrets = &secretStore{provider: p}
+	t.Cleanup(func() { secrets = prev })
+	return secrets
+}
+
+func TestSecretFallsBackToEnv(t *testing.T) {
+	t.Setenv("SECRETS_TEST_ONLY_ENV", "from-env")
+	t.Setenv("SECRETS_TEST_BOTH", "from-env")
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	os.WriteFile(path, []byte(`{"SECRETS_TEST_BOTH":"from-file","SECRETS_TEST_PORT":5432}`), 0o600)
+
+	s := withSecretStore(t, fileSecretProvider{path: path})
+	if err := s.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := Secret("SECRETS_TEST_BOTH"); got != "from-file" {
+		t.Errorf("provider value = %q, want from-file", got)
+	}
+	if got := Secret("SECRETS_TEST_ONLY_ENV"); got != "from-env" {
+		t.Errorf("env fallback = %q, want from-env", got)
+	}
+	if got := Secret("SECRETS_TEST_PORT"); got != "5432" {
+		t.Errorf("numeric value = %q, want 5432", got)
+	}
+}
+
+func TestSecretRotationCallbacks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.env")
+	os.WriteFile(path, []byte("SECRETS_TEST_KEY=v1\nSECRETS_TEST_OTHER=x\n"), 0o600)
+
+	s := withSecretStore(t, fileSecretProvider{path: path})
+	if err := s.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var rotated []string
+	OnSecretRotate("SECRETS_TEST_KEY", func(v string) { rotated = append(rotated, v) })
+
+	// Unchanged: no callback.
+	s.refresh(context.Background())
+	os.WriteFile(path, []byte("SECRETS_TEST_KEY=v2\nSECRETS_TEST_OTHER=y\n"), 0o600)
+	s.refresh(context.Background())
+
+	if len(rotated) != 1 || rotated[0] != "v2" {
+		t.Errorf("callbacks = %v, want [v2]", rotated)
+	}
+
+	// A failed refresh keeps the last good values.
+	os.Remove(path)
+	if err := s.refresh(context.Background()); err == nil {
+		t.Error("refresh of a missing file should fail")
+	}
+	if got := Secret("SECRETS_TEST_KEY"); got != "v2" {
+		t.Errorf("after failed refresh = %q, want v2", got)
+	}
+}
+
+func TestIsSOPSEncrypted(t *testing.T) {
+	if !isSOPSEncrypted([]byte(`{"A":"ENC[AES256_GCM,data:x]","sops":{"version":"3.8.1"}}`), true) {
+		t.Error("JSON with sops metadata not detected")
+	}
+	if isSOPSEncrypted([]byte(`{"A":"plain"}`), true) {
+		t.Error("plain JSON detected as encrypted")
+	}
+	if !isSOPSEncrypted([]byte("A=ENC[AES256_GCM,data:x]\nsops_version=3.8.1\n"), false) {
+		t.Error("dotenv with sops metadata not detected")
+	}
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	var gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("X-Vault-Token")
+		switch r.URL.Path {
+		case "/v1/secret/data/scrollr":
+			w.Write([]byte(`{"data":{"data":{"STRIPE_SECRET_KEY":"sk_v2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/scrollr":
+			w.Write([]byte(`{"data":{"STRIPE_SECRET_KEY":"sk_v1"}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("s.agent-token\n"), 0o600)
+
+	for path, want := range map[string]string{"secret/data/scrollr": "sk_v2", "kv/scrollr": "sk_v1"} {
+		v := vaultSecretProvider{addr: srv.URL, path: path, tokenFile: tokenFile, client: &http.Client{Timeout: time.Second}}
+		values, err := v.Load(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if values["STRIPE_SECRET_KEY"] != want {
+			t.Errorf("%s: value = %q, want %q", path, values["STRIPE_SECRET_KEY"], want)
+		}
+	}
+	if gotToken != "s.agent-token" {
+		t.Errorf("token = %q, want the token file's contents", gotToken)
+	}
+
+	v := vaultSecretProvider{addr: srv.URL, path: "nope", token: "t", client: &http.Client{Timeout: time.Second}}
+	if _, err := v.Load(context.Background()); err == nil {
+		t.Error("403 from Vault should be an error")
+	}
+}
+
+func TestMissingRequiredSecrets(t *testing.T) {
+	withSecretStore(t, envSecretProvider{})
+	for _, r := range requiredSecrets {
+		t.Setenv(r.Name, "set")
+	}
+	if missing := missingRequiredSecrets(); len(missing) != 0 {
+		t.Fatalf("missing = %v, want none", missing)
+	}
+
+	t.Setenv("STRIPE_SECRET_KEY", "")
+	if missing := missingRequiredSecrets(); len(missing) != 1 || missing[0] != "STRIPE_SECRET_KEY" {
+		t.Errorf("missing = %v, want [STRIPE_SECRET_KEY]", missing)
+	}
+	t.Setenv("STRIPE_DISABLED", "true")
+	if missing := missingRequiredSecrets(); len(missing) != 0 {
+		t.Errorf("STRIPE_DISABLED: missing = %v, want none", missing)
+	}
+}
@@ -3,7 +3,6 @@ package core
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"os"
 	"strings"
 
 	"github.com/getsentry/sentry-go"
@@ -29,7 +28,7 @@ func HashUserSub(sub string) string {
 	if sub == "" {
 		return ""
 	}
-	salt := os.Getenv("SENTRY_USER_SALT")
+	salt := Secret("SENTRY_USER_SALT")
 	if salt == "" {
 		return ""
 	}
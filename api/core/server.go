@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,6 +18,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/swagger"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -75,6 +77,14 @@ func (s *Server) setupMiddleware() {
 		}))
 	}
 
+	// Server span per request, continuing any incoming traceparent
+	// (tracing.go). Handlers get its context from c.UserContext().
+	s.App.Use(TracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go). Early so
+	// rate-limited and version-gated responses are measured too.
+	s.App.Use(MetricsMiddleware)
+
 	// Security Headers
 	s.App.Use(func(c *fiber.Ctx) error {
 		c.Set("X-XSS-Protection", "1; mode=block")
@@ -84,9 +94,10 @@ func (s *Server) setupMiddleware() {
 		c.Set("X-DNS-Prefetch-Control", "off")
 		if strings.HasPrefix(c.Path(), "/swagger") {
 			c.Set("Content-Security-Policy", "default-src 'self' 'unsafe-inline' https://fonts.googleapis.com https://fonts.gstatic.com; img-src 'self' data:; font-src 'self' https://fonts.gstatic.com; frame-ancestors 'self' https://relentnet.com")
-		} else if c.Path() == "/yahoo/callback" {
-			// Yahoo OAuth callback returns HTML with inline <script> (postMessage + window.close)
-			// and inline style attributes. Allow those while keeping everything else locked down.
+		} else if c.Path() == "/yahoo/callback" || isOAuthCallbackPath(c.Path()) {
+			// OAuth callbacks (Yahoo, and /oauth/:provider/callback) return HTML with inline
+			// <script> (postMessage + window.close) and inline style attributes. Allow those
+			// while keeping everything else locked down.
 			c.Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; frame-ancestors 'self' https://relentnet.com")
 		} else {
 			c.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'self' https://relentnet.com")
@@ -109,12 +120,21 @@ func (s *Server) setupMiddleware() {
 	s.App.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowCredentials: true,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Claim-Token, X-Client-Version",
+		// Catalog endpoints report plan usage in headers; browsers hide
+		// non-safelisted response headers unless exposed here.
+		// The rate limit headers let clients back off before a 429.
+		ExposeHeaders: "X-Quota-Resource, X-Quota-Used, X-Quota-Limit, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After",
 	}))
 
+	// Retire old desktop/extension builds (client_version.go). After CORS
+	// so the 426 carries CORS headers the client can read.
+	s.App.Use(ClientVersionGate)
+
 	// Core paths always exempt from rate limiting
 	coreExemptPaths := map[string]bool{
 		"/health":                           true,
+		"/metrics":                          true,
 		"/events":                           true,
 		"/webhooks/sequin":                  true,
 		"/webhooks/stripe":                  true,
@@ -123,6 +143,8 @@ func (s *Server) setupMiddleware() {
 		"/webhooks/github/pr-closed":        true, // GitHub Action calls this when a PR with [fixes #N] tags merges
 		"/channels":                         true,
 		"/tier-limits":                      true,
+		"/time":                             true, // polled by clients for clock offset estimation
+		"/incidents/active":                 true,
 		"/extension/token":                  true,
 		"/extension/token/refresh":          true,
 		"/support/ticket":                   true,
@@ -144,29 +166,35 @@ func (s *Server) setupMiddleware() {
 		},
 	}))
 
-	s.App.Use(limiter.New(limiter.Config{
-		Max:        RateLimitMax,
-		Expiration: RateLimitExpiration,
-		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.IP()
-		},
-		Next: func(c *fiber.Ctx) bool {
-			path := c.Path()
-			// Always exempt core paths
-			if coreExemptPaths[path] {
+	// General limiter, per account for signed-in callers and per IP
+	// otherwise (rate_limit.go).
+	exempt := func(c *fiber.Ctx) bool {
+		path := c.Path()
+		// Always exempt core paths
+		if coreExemptPaths[path] {
+			return true
+		}
+		// A keyed public feed is limited per API key instead
+		// (handlers_public.go). Keys that don't resolve stay under the
+		// per-IP limit.
+		if path == "/public/feed" {
+			if _, ok := publicFeedKeyOwner(c); ok {
 				return true
 			}
-			// Dynamically check channel routes (handles late-discovered channels)
-			for _, entry := range GetChannelRoutes() {
-				if !entry.Route.Auth {
-					if _, ok := matchRoute(entry.Route.Path, path); ok {
-						return true
-					}
+		}
+		// Dynamically check channel routes (handles late-discovered channels)
+		for _, entry := range GetChannelRoutes() {
+			if !entry.Route.Auth {
+				if _, ok := matchRoute(entry.Route.Path, path); ok {
+					return true
 				}
 			}
-			return false
-		},
-	}))
+		}
+		return false
+	}
+	for _, h := range newRateLimiters(exempt) {
+		s.App.Use(h)
+	}
 }
 
 // setupRoutes mounts core public and protected routes.
@@ -176,9 +204,17 @@ func (s *Server) setupRoutes() {
 
 	// --- Public Routes ---
 	s.App.Get("/health", s.healthCheck)
+	s.App.Get("/metrics", RequireInternalNetwork, HandleMetrics)
 	s.App.Get("/public/feed", HandlePublicFeed)
 	s.App.Get("/events", StreamEvents)
+	s.App.Get("/ws", HandleWebSocketUpgrade, StreamWebSocket)
 	s.App.Get("/events/count", GetActiveViewers)
+	// Kiosk / TV display mode, authenticated by a display token in the path
+	s.App.Get("/display/:token/ticker", HandleDisplayTicker)
+	s.App.Get("/display/:token/events", HandleDisplayEvents)
+	// Short links: public redirect, authenticated creation
+	s.App.Get("/l/:code", HandleShortLinkRedirect)
+	s.App.Post("/links", LogtoAuth, HandleCreateShortLink)
 	s.App.Post("/webhooks/sequin", HandleSequinWebhook)
 	s.App.Post("/webhooks/stripe", HandleStripeWebhook)
 	s.App.Post("/webhooks/osticket/thread-message", HandleOSTicketThreadMessage)
@@ -192,9 +228,20 @@ func (s *Server) setupRoutes() {
 	s.App.Post("/extension/token/refresh", HandleExtensionTokenRefresh)
 
 	s.App.Get("/channels", s.listChannels)
+	s.App.Get("/channels/:name/schema", HandleGetChannelSchema)
+	s.App.Get("/channels/status", LogtoAuth, RequireSuperUser, HandleChannelStatus)
 	s.App.Get("/tier-limits", HandleGetTierLimits)
+	s.App.Get("/time", HandleGetTime)
+	s.App.Get("/incidents/active", HandleGetActiveIncidents)
+	s.App.Get("/trending", HandleGetTrending)
 	s.App.Get("/", s.landingPage)
 
+	// Signed-out channel config, keyed by an opaque claim token that is
+	// merged into the account via /users/me/claim after sign-up.
+	s.App.Post("/anonymous/session", HandleCreateAnonymousSession)
+	s.App.Get("/anonymous/session", HandleGetAnonymousSession)
+	s.App.Put("/anonymous/session", HandleUpdateAnonymousSession)
+
 	// --- Protected Routes ---
 	s.App.Get("/dashboard", LogtoAuth, s.getDashboard)
 
@@ -211,6 +258,44 @@ func (s *Server) setupRoutes() {
 	// the handler.
 	s.App.Post("/business-leads", HandleSubmitBusinessLead)
 
+	// In-product feedback / bug reports. Auth is optional so the
+	// marketing site can post anonymously; signed-in reports are
+	// linked to the user's sub. Per-caller hourly counter inside.
+	s.App.Post("/feedback", OptionalLogtoAuth, HandleSubmitFeedback)
+
+	// Admin: feedback triage + experiment management (super_user only)
+	s.App.Get("/admin/feedback", LogtoAuth, RequireSuperUser, HandleAdminListFeedback)
+	s.App.Patch("/admin/feedback/:id", LogtoAuth, RequireSuperUser, HandleAdminUpdateFeedback)
+	s.App.Get("/admin/experiments", LogtoAuth, RequireSuperUser, HandleAdminListExperiments)
+	s.App.Post("/admin/experiments", LogtoAuth, RequireSuperUser, HandleAdminCreateExperiment)
+	s.App.Put("/admin/experiments/:key", LogtoAuth, RequireSuperUser, HandleAdminUpdateExperiment)
+	s.App.Delete("/admin/experiments/:key", LogtoAuth, RequireSuperUser, HandleAdminDeleteExperiment)
+	s.App.Get("/admin/incidents", LogtoAuth, RequireSuperUser, HandleAdminListIncidents)
+	s.App.Post("/admin/incidents", LogtoAuth, RequireSuperUser, HandleAdminCreateIncident)
+	s.App.Put("/admin/incidents/:id", LogtoAuth, RequireSuperUser, HandleAdminUpdateIncident)
+	s.App.Post("/admin/incidents/:id/resolve", LogtoAuth, RequireSuperUser, HandleAdminResolveIncident)
+	s.App.Get("/admin/client-version", LogtoAuth, RequireSuperUser, HandleAdminGetClientVersion)
+	s.App.Put("/admin/client-version", LogtoAuth, RequireSuperUser, HandleAdminSetClientVersion)
+	s.App.Post("/admin/proxy-cache/:channel/invalidate", LogtoAuth, RequireSuperUser, HandleAdminInvalidateProxyCache)
+	s.App.Get("/admin/engagement", LogtoAuth, RequireSuperUser, HandleAdminEngagement)
+	s.App.Get("/admin/dashboard/snapshots", LogtoAuth, RequireSuperUser, HandleAdminDashboardSnapshots)
+	s.App.Get("/admin/workers", LogtoAuth, RequireSuperUser, HandleAdminWorkers)
+	s.App.Get("/admin/http-clients", LogtoAuth, RequireSuperUser, HandleAdminHTTPClients)
+	s.App.Get("/admin/stats", LogtoAuth, RequireSuperUser, HandleAdminStats)
+	s.App.Get("/admin/cdc/dlq", LogtoAuth, RequireSuperUser, HandleAdminListCDCDeadLetters)
+	s.App.Post("/admin/cdc/dlq", LogtoAuth, RequireSuperUser, HandleAdminReplayCDCDeadLetters)
+	s.App.Get("/admin/signing-keys", LogtoAuth, RequireSuperUser, HandleAdminListSigningKeys)
+	s.App.Post("/admin/signing-keys/rotate", LogtoAuth, RequireSuperUser, HandleAdminRotateSigningKey)
+	s.App.Post("/admin/links/:code/disable", LogtoAuth, RequireSuperUser, HandleAdminDisableShortLink)
+	s.App.Get("/admin/slo", LogtoAuth, RequireSuperUser, HandleAdminSLOStatus)
+	s.App.Get("/admin/slo/rules", LogtoAuth, RequireSuperUser, HandleAdminSLORules)
+	s.App.Get("/admin/billing/users/:sub", LogtoAuth, RequireSuperUser, HandleAdminBillingSnapshot)
+	s.App.Post("/admin/billing/users/:sub/refunds", LogtoAuth, RequireSuperUser, HandleAdminRefund)
+	s.App.Post("/admin/billing/users/:sub/comp", LogtoAuth, RequireSuperUser, HandleAdminGrantCompTime)
+	s.App.Put("/admin/billing/users/:sub/plan", LogtoAuth, RequireSuperUser, HandleAdminChangePlan)
+	s.App.Get("/admin/users/:sub/dashboard", LogtoAuth, RequireSuperUser, HandleAdminUserDashboard)
+	s.App.Get("/admin/users/:sub/channels", LogtoAuth, RequireSuperUser, HandleAdminUserChannels)
+
 	// Partner-approval URLs for AI-drafted replies. No auth — these are
 	// HMAC-signed single-use tokens that the partner clicks from email.
 	s.App.Get("/support/send", HandleSupportSend)
@@ -228,13 +313,37 @@ func (s *Server) setupRoutes() {
 	s.App.Post("/checkout/setup-intent", LogtoAuth, HandleCreateSetupIntent)
 	s.App.Post("/checkout/subscribe", LogtoAuth, HandleConfirmSubscription)
 	s.App.Post("/checkout/payment-intent", LogtoAuth, HandleCreatePaymentIntent)
+	s.App.Post("/checkout/promo/validate", LogtoAuth, HandleValidatePromoCode)
 	s.App.Get("/checkout/return", LogtoAuth, HandleCheckoutReturn)
 	s.App.Get("/users/me/subscription", LogtoAuth, HandleGetSubscription)
 	s.App.Get("/users/me/overview", LogtoAuth, HandleGetOverview)
 	s.App.Get("/users/me/subscription/preview", LogtoAuth, HandlePreviewPlanChange)
 	s.App.Put("/users/me/subscription/plan", LogtoAuth, HandleChangePlan)
+	s.App.Post("/users/me/subscription/change", LogtoAuth, HandleChangePlan)
 	s.App.Post("/users/me/subscription/cancel", LogtoAuth, HandleCancelSubscription)
 	s.App.Post("/users/me/subscription/portal", LogtoAuth, HandleCreatePortalSession)
+	s.App.Post("/users/me/billing-portal", LogtoAuth, HandleCreatePortalSession)
+
+	// Team / family seats
+	s.App.Get("/users/me/team", LogtoAuth, HandleGetTeam)
+	s.App.Put("/users/me/team/seats", LogtoAuth, HandleSetTeamSeats)
+	s.App.Post("/users/me/team/invitations", LogtoAuth, HandleCreateTeamInvitation)
+	s.App.Delete("/users/me/team/invitations/:id", LogtoAuth, HandleRevokeTeamInvitation)
+	s.App.Delete("/users/me/team/members/:sub", LogtoAuth, HandleRemoveTeamMember)
+	s.App.Post("/users/me/team/join", LogtoAuth, HandleJoinTeam)
+	s.App.Post("/users/me/team/leave", LogtoAuth, HandleLeaveTeam)
+
+	// Organizations and shared channels
+	s.App.Get("/users/me/orgs", LogtoAuth, HandleListOrganizations)
+	s.App.Post("/users/me/orgs", LogtoAuth, HandleCreateOrganization)
+	s.App.Get("/users/me/orgs/:id", LogtoAuth, HandleGetOrganization)
+	s.App.Put("/users/me/orgs/:id", LogtoAuth, HandleRenameOrganization)
+	s.App.Delete("/users/me/orgs/:id", LogtoAuth, HandleDeleteOrganization)
+	s.App.Post("/users/me/orgs/:id/members", LogtoAuth, HandleAddOrgMember)
+	s.App.Put("/users/me/orgs/:id/members/:sub", LogtoAuth, HandleUpdateOrgMember)
+	s.App.Delete("/users/me/orgs/:id/members/:sub", LogtoAuth, HandleRemoveOrgMember)
+	s.App.Put("/users/me/orgs/:id/channels/:type", LogtoAuth, HandlePutOrgChannel)
+	s.App.Delete("/users/me/orgs/:id/channels/:type", LogtoAuth, HandleDeleteOrgChannel)
 
 	// Account self-service: profile (name/email) + password reset email
 	s.App.Put("/users/me/profile", LogtoAuth, HandleUpdateProfile)
@@ -243,18 +352,74 @@ func (s *Server) setupRoutes() {
 	// User Routes — specific /users/me/* paths BEFORE parameterized /users/:username
 	s.App.Get("/users/me/preferences", LogtoAuth, HandleGetPreferences)
 	s.App.Put("/users/me/preferences", LogtoAuth, HandleUpdatePreferences)
+	s.App.Post("/telemetry", LogtoAuth, HandleTelemetry)
+	s.App.Post("/ticker/items/:id/click", LogtoAuth, HandleTickerItemClick)
+	s.App.Get("/recommendations", LogtoAuth, HandleGetRecommendations)
 	s.App.Get("/users/me/channels", LogtoAuth, GetChannels)
 	s.App.Post("/users/me/channels", LogtoAuth, CreateChannel)
+	s.App.Post("/users/me/channels/batch", LogtoAuth, BatchChannels)
 	s.App.Put("/users/me/channels/:type", LogtoAuth, UpdateChannel)
 	s.App.Delete("/users/me/channels/:type", LogtoAuth, DeleteChannel)
 
+	s.App.Post("/users/me/claim", LogtoAuth, HandleClaimAnonymousSession)
+
+	// Geo-based channel defaults proposed during onboarding
+	s.App.Get("/users/me/onboarding/defaults", LogtoAuth, HandleGetOnboardingDefaults)
+	s.App.Post("/users/me/onboarding/defaults/accept", LogtoAuth, HandleAcceptOnboardingDefaults)
+	s.App.Post("/users/me/onboarding/defaults/dismiss", LogtoAuth, HandleDismissOnboardingDefaults)
+
+	// A/B experiments: deterministic assignment + first-exposure log
+	s.App.Get("/users/me/experiments", LogtoAuth, HandleGetMyExperiments)
+	s.App.Post("/users/me/experiments/:key/exposure", LogtoAuth, HandleLogExperimentExposure)
+
+	// Personal API keys for API-key authenticated channel routes
+	s.App.Get("/users/me/api-keys", LogtoAuth, HandleListAPIKeys)
+	s.App.Post("/users/me/api-keys", LogtoAuth, HandleCreateAPIKey)
+	s.App.Delete("/users/me/api-keys/:id", LogtoAuth, HandleRevokeAPIKey)
+
+	// Display tokens for kiosk / TV display mode
+	s.App.Get("/users/me/display-tokens", LogtoAuth, HandleListDisplayTokens)
+	s.App.Post("/users/me/display-tokens", LogtoAuth, RequireTier("uplink_ultimate"), HandleCreateDisplayToken)
+	s.App.Delete("/users/me/display-tokens/:id", LogtoAuth, HandleRevokeDisplayToken)
+	s.App.Get("/users/me/links", LogtoAuth, HandleListShortLinks)
+	s.App.Delete("/users/me/links/:code", LogtoAuth, HandleDeleteShortLink)
+
+	// Saved searches and the notifications inbox they feed
+	s.App.Get("/users/me/saved-searches", LogtoAuth, HandleListSavedSearches)
+	s.App.Post("/users/me/saved-searches", LogtoAuth, HandleCreateSavedSearch)
+	s.App.Put("/users/me/saved-searches/:id", LogtoAuth, HandleUpdateSavedSearch)
+	s.App.Delete("/users/me/saved-searches/:id", LogtoAuth, HandleDeleteSavedSearch)
+	s.App.Get("/users/me/notifications", LogtoAuth, HandleListNotifications)
+	s.App.Get("/users/me/notifications/preferences", LogtoAuth, HandleGetNotificationPreferences)
+	s.App.Put("/users/me/notifications/preferences", LogtoAuth, HandleUpdateNotificationPreferences)
+	s.App.Post("/users/me/notifications/read-all", LogtoAuth, HandleMarkAllNotificationsRead)
+	s.App.Post("/users/me/notifications/:id/read", LogtoAuth, HandleMarkNotificationRead)
+	s.App.Get("/users/me/billing/usage", LogtoAuth, HandleGetAPIUsage)
+	s.App.Get("/users/me/billing/invoices", LogtoAuth, HandleListInvoices)
+	s.App.Post("/users/me/billing/retry", LogtoAuth, HandleRetryPayment)
+
 	// GDPR: data export + 30-day soft-delete lifecycle
 	s.App.Get("/users/me/export", LogtoAuth, HandleExportUserData)
+	s.App.Post("/users/me/export", LogtoAuth, HandleCreateDataExport)
+	s.App.Get("/users/me/export/:id", LogtoAuth, HandleGetDataExport)
+	s.App.Get("/users/me/export/:id/download", LogtoAuth, HandleDownloadDataExport)
 	s.App.Post("/users/me/delete", LogtoAuth, HandleRequestAccountDeletion)
 	s.App.Post("/users/me/delete/cancel", LogtoAuth, HandleCancelAccountDeletion)
 	s.App.Get("/users/me/delete/status", LogtoAuth, HandleAccountDeletionStatus)
+	s.App.Get("/users/me/audit", LogtoAuth, HandleListMyAudit)
+	s.App.Get("/users/me/sessions", LogtoAuth, HandleListSessions)
+	s.App.Delete("/users/me/sessions/:id", LogtoAuth, HandleRevokeSession)
+	s.App.Post("/users/me/delete/token", LogtoAuth, HandleIssueAccountDeleteToken)
+	s.App.Delete("/users/me", LogtoAuth, HandleDeleteAccount)
+
+	// Third-party account connections (connections.go)
+	s.App.Get("/users/me/connections", LogtoAuth, HandleListConnections)
+	s.App.Get("/users/me/connections/:provider/start", LogtoAuth, HandleStartConnection)
+	s.App.Delete("/users/me/connections/:provider", LogtoAuth, HandleDeleteConnection)
+	s.App.Get("/oauth/:provider/callback", HandleOAuthCallback)
 
 	s.App.Get("/users/:username", GetProfileByUsername)
+	s.App.Get("/users/:username/watchlist", GetPublicWatchlist)
 }
 
 // healthCheck returns the aggregated health status.
@@ -267,16 +432,17 @@ func (s *Server) setupRoutes() {
 // The body shape is unchanged; only the status code in the degraded case
 // differs.
 func (s *Server) healthCheck(c *fiber.Ctx) error {
-	// Check Redis cache first
-	if val, err := Rdb.Get(context.Background(), HealthCacheKey).Result(); err == nil {
-		return sendHealthCached(c, []byte(val), "HIT")
+	// Check the cache first
+	if val, ok := GetCache(context.Background(), HealthCacheKey); ok {
+		recordCacheLookup("health", true)
+		return sendHealthCached(c, val, "HIT")
 	}
 
 	// Singleflight: only one goroutine computes; others wait and share the result
 	result, err, _ := healthCheckGroup.Do("health", func() (interface{}, error) {
 		// Double-check cache (another goroutine may have populated it)
-		if val, err := Rdb.Get(context.Background(), HealthCacheKey).Result(); err == nil {
-			return []byte(val), nil
+		if val, ok := GetCache(context.Background(), HealthCacheKey); ok {
+			return val, nil
 		}
 
 		res := HealthResponse{Status: "healthy", Services: make(map[string]string)}
@@ -287,14 +453,17 @@ func (s *Server) healthCheck(c *fiber.Ctx) error {
 		} else {
 			res.Database = "healthy"
 		}
+		// A Redis outage alone doesn't fail readiness: with the breaker
+		// open the gateway keeps serving from its fallback cache
+		// (redis_breaker.go), and pulling every replica out of the
+		// Service would turn a degraded gateway into a down one.
 		if err := Rdb.Ping(context.Background()).Err(); err != nil {
 			res.Redis = "unhealthy"
-			res.Status = "degraded"
 		} else {
 			res.Redis = "healthy"
 		}
+		res.DegradedMode = res.Redis != "healthy" || RedisDegraded()
 
-		httpClient := &http.Client{Timeout: HealthCheckTimeout}
 		var healthTargets []*ChannelInfo
 		for _, intg := range GetAllChannels() {
 			if intg.HasCapability("health_checker") {
@@ -308,16 +477,15 @@ func (s *Server) healthCheck(c *fiber.Ctx) error {
 		for _, intg := range healthTargets {
 			go func(ch *ChannelInfo) {
 				defer wg.Done()
-				targetURL := ch.InternalURL + "/internal/health"
-				resp, err := httpClient.Get(targetURL)
+				healthy := checkChannelHealth(ch)
 				mu.Lock()
 				defer mu.Unlock()
-				if err != nil || resp.StatusCode != http.StatusOK {
+				channelStatuses.recordHealth(ch.Name, healthy, time.Now())
+				if !healthy {
 					res.Services[ch.Name] = "down"
 					res.Status = "degraded"
 				} else {
 					res.Services[ch.Name] = "healthy"
-					resp.Body.Close()
 				}
 			}(intg)
 		}
@@ -328,8 +496,8 @@ func (s *Server) healthCheck(c *fiber.Ctx) error {
 		// subsequent probe to re-check so k8s readiness flips NotReady
 		// immediately instead of waiting up to HealthCacheTTL for a stale
 		// "healthy" cache entry to expire.
-		if res.Status == "healthy" {
-			Rdb.Set(context.Background(), HealthCacheKey, cacheData, HealthCacheTTL)
+		if res.Status == "healthy" && !res.DegradedMode {
+			SetCache(context.Background(), HealthCacheKey, cacheData, HealthCacheTTL)
 		}
 		return cacheData, nil
 	})
@@ -338,9 +506,29 @@ func (s *Server) healthCheck(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "health check failed"})
 	}
 
+	recordCacheLookup("health", false)
 	return sendHealthCached(c, result.([]byte), "MISS")
 }
 
+// checkChannelHealth probes a channel's /internal/health through its
+// circuit breaker; while the breaker is open the channel is reported down
+// without a request.
+func checkChannelHealth(ch *ChannelInfo) bool {
+	breaker := channelBreaker(ch.Name)
+	if !breaker.allow() {
+		return false
+	}
+	resp, err := channelHealthClient.Get(ch.InternalURL + "/internal/health")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			err = errChannelStatus(resp.StatusCode)
+		}
+	}
+	breaker.record(err)
+	return err == nil && resp.StatusCode == http.StatusOK
+}
+
 // sendHealthCached writes a cached HealthResponse body, inferring the HTTP
 // status code from the status field inside the JSON. "healthy" → 200,
 // anything else → 503. Extracted so the cache hit and cache miss paths
@@ -360,6 +548,9 @@ func sendHealthCached(c *fiber.Ctx, body []byte, cacheHeader string) error {
 
 // getDashboard retrieves aggregated data for the user dashboard.
 // Results are cached per-user in Redis for 30s to support efficient polling.
+// Heavy users are served from a materialized snapshot instead; see
+// dashboard_snapshots.go. Requests with shaping parameters are served by
+// getShapedDashboard (dashboard_shape.go).
 func (s *Server) getDashboard(c *fiber.Ctx) error {
 	userID := GetUserID(c)
 	if userID == "" {
@@ -368,13 +559,40 @@ func (s *Server) getDashboard(c *fiber.Ctx) error {
 			Error:  "Authentication required",
 		})
 	}
+	shape, err := parseDashboardShape(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+	if shape != nil {
+		return getShapedDashboard(c, userID, shape)
+	}
+	start := time.Now()
+
+	// Materialized snapshot: a pure Redis read for heavy users
+	if snapshot, ok := loadDashboardSnapshot(context.Background(), userID); ok {
+		dashboardLatency.observe(dashboardSourceSnapshot, time.Since(start))
+		recordDashboardLatency(time.Since(start))
+		recordCacheLookup("dashboard", true)
+		c.Set("X-Cache", "SNAPSHOT")
+		return sendTickerBody(c, snapshot)
+	}
 
-	// Check per-user Redis cache first
+	// Check per-user cache first
 	cacheKey := RedisDashboardCachePrefix + userID
-	if val, err := Rdb.Get(context.Background(), cacheKey).Result(); err == nil {
+	if val, ok := GetCache(context.Background(), cacheKey); ok {
 		var cached DashboardResponse
-		if json.Unmarshal([]byte(val), &cached) == nil {
+		if json.Unmarshal(val, &cached) == nil {
+			dashboardLatency.observe(dashboardSourceCache, time.Since(start))
+			recordDashboardLatency(time.Since(start))
+			recordCacheLookup("dashboard", true)
 			c.Set("X-Cache", "HIT")
+			if wantsTickerText(c) {
+				c.Vary(fiber.HeaderAccept)
+				return sendTickerText(c, renderTickerText(cached.Channels, cached.Data, cached.Organizations, cached.Incidents))
+			}
 			return c.JSON(cached)
 		}
 	}
@@ -383,94 +601,256 @@ func (s *Server) getDashboard(c *fiber.Ctx) error {
 	userRoles := GetUserRoles(c)
 	result, err, _ := dashboardGroup.Do(userID, func() (interface{}, error) {
 		// Double-check cache
-		if val, err := Rdb.Get(context.Background(), cacheKey).Result(); err == nil {
-			return []byte(val), nil
+		if val, ok := GetCache(context.Background(), cacheKey); ok {
+			return val, nil
 		}
 
-		res := DashboardResponse{
-			Data: make(map[string]interface{}),
-		}
+		cacheData, enabledChannels, partial := assembleDashboard(c.UserContext(), userID, userRoles)
 
-		// 1. User preferences (sync tier from JWT roles)
-		prefs, err := GetOrCreatePreferences(userID, userRoles)
-		if err == nil {
-			res.Preferences = prefs
+		// Warm Redis subscription sets from current DB state
+		BackgroundPool.SubmitOrRun("sync-subscriptions", func(context.Context) {
+			SyncChannelSubscriptions(userID)
+		})
+
+		// Count today's viewers per channel for engagement CTR
+		BackgroundPool.Submit("record-viewers", func(ctx context.Context) {
+			recordChannelViewers(ctx, userID, enabledChannels)
+		})
+
+		ttl := DashboardCacheTTL
+		if partial {
+			ttl = DashboardPartialCacheTTL
 		}
+		SetCache(context.Background(), cacheKey, cacheData, ttl)
+		maybeEnrollDashboardSnapshot(context.Background(), userID, len(enabledChannels), cacheData)
+		return cacheData, nil
+	})
+
+	if err != nil {
+		sloDashboardLatency.Record(false, time.Now())
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "dashboard fetch failed"})
+	}
+
+	dashboardLatency.observe(dashboardSourceAssembled, time.Since(start))
+	recordDashboardLatency(time.Since(start))
+	recordCacheLookup("dashboard", false)
+	c.Set("X-Cache", "MISS")
+	return sendTickerBody(c, result.([]byte))
+}
+
+// assembleDashboard builds a user's dashboard JSON from preferences,
+// channels, incidents and each enabled channel's /internal/dashboard,
+// plus the shared channels of the user's organizations.
+// Also returns the user's enabled channel types and whether any channel
+// fetch failed (the response's errors map is non-empty). Roles, when
+// given, sync the subscription tier; background rebuilds pass none.
+func assembleDashboard(ctx context.Context, userID string, userRoles ...[]string) ([]byte, map[string]bool, bool) {
+	res := DashboardResponse{
+		Data: make(map[string]interface{}),
+	}
+
+	// 1. User preferences (sync tier from JWT roles)
+	prefs, err := GetOrCreatePreferences(userID, userRoles...)
+	if err == nil {
+		res.Preferences = prefs
+	}
+
+	// 2. User channels + enabled types
+	channels, err := GetUserChannels(userID)
+	if err == nil {
+		res.Channels = channels
+	}
 
-		// 2. User channels + enabled types
-		channels, err := GetUserChannels(userID)
-		if err == nil {
-			res.Channels = channels
+	enabledChannels := make(map[string]bool)
+	for _, ch := range channels {
+		if ch.Enabled {
+			enabledChannels[ch.ChannelType] = true
 		}
+	}
 
-		enabledChannels := make(map[string]bool)
-		for _, ch := range channels {
-			if ch.Enabled {
-				enabledChannels[ch.ChannelType] = true
+	// Active incidents touching this user's channels (or product-wide)
+	if incidents, err := ListActiveIncidents(context.Background()); err == nil {
+		res.Incidents = incidentsForChannels(incidents, enabledChannels)
+	} else {
+		log.Printf("[Dashboard] incidents fetch error: %v", err)
+	}
+
+	// Platform trending for the user's channels, if they opted in
+	if prefs != nil && prefs.ShowTrending {
+		if trending, err := GetTrending(context.Background()); err == nil {
+			res.Trending = make(map[string][]TrendingItem)
+			for channelType, items := range trending {
+				if enabledChannels[channelType] {
+					res.Trending[channelType] = items
+				}
 			}
+		} else {
+			log.Printf("[Dashboard] trending fetch error: %v", err)
 		}
+	}
 
-		// Warm Redis subscription sets from current DB state
-		go SyncChannelSubscriptions(userID)
+	// 3. Fetch dashboard data from each enabled channel via HTTP (parallel)
+	res.Data, res.Errors = fetchChannelDashboards(ctx, userID, enabledChannels)
+	partial := len(res.Errors) > 0
 
-		// 3. Fetch dashboard data from each enabled channel via HTTP (parallel)
-		dashboardClient := &http.Client{Timeout: HealthCheckTimeout}
-		var targets []*ChannelInfo
-		for _, intg := range GetAllChannels() {
-			if enabledChannels[intg.Name] && intg.HasCapability("dashboard_provider") {
-				targets = append(targets, intg)
+	// Scores inside the user's spoiler windows, personal and shared alike
+	spoilers := spoilerFilter{now: time.Now()}
+	if prefs != nil {
+		spoilers.windows = prefs.SpoilerWindows
+	}
+	spoilers.filterDashboardSpoilers(res.Data)
+
+	// 4. Shared channels of the user's organizations, fetched the same way
+	// under the org's synthetic owner
+	if orgs, err := userOrganizations(context.Background(), userID); err == nil {
+		for _, org := range orgs {
+			shared, err := orgSharedChannels(org.ID)
+			if err != nil {
+				log.Printf("[Dashboard] org %d channels error: %v", org.ID, err)
+				continue
+			}
+			types := make(map[string]bool, len(shared))
+			for _, ch := range shared {
+				types[ch.ChannelType] = true
 			}
+			data, errs := fetchChannelDashboards(ctx, orgChannelOwner(org.ID), types)
+			spoilers.filterDashboardSpoilers(data)
+			res.Organizations = append(res.Organizations, OrgDashboard{
+				ID:       org.ID,
+				Name:     org.Name,
+				Role:     org.Role,
+				Channels: shared,
+				Data:     data,
+				Errors:   errs,
+			})
+			partial = partial || len(errs) > 0
 		}
+	} else {
+		log.Printf("[Dashboard] organizations fetch error: %v", err)
+	}
 
-		type channelResult struct {
-			data map[string]interface{}
-		}
-		results := make([]channelResult, len(targets))
-		var wg sync.WaitGroup
-		wg.Add(len(targets))
-		for i, intg := range targets {
-			go func(idx int, ch *ChannelInfo) {
-				defer wg.Done()
-				url := fmt.Sprintf("%s/internal/dashboard?user=%s", ch.InternalURL, userID)
-				resp, err := dashboardClient.Get(url)
-				if err != nil {
-					log.Printf("[Dashboard] %s fetch error: %v", ch.Name, err)
-					return
-				}
-				body, err := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				if err != nil || resp.StatusCode != 200 {
-					log.Printf("[Dashboard] %s returned status %d", ch.Name, resp.StatusCode)
-					return
-				}
-				var data map[string]interface{}
-				if err := json.Unmarshal(body, &data); err != nil {
-					log.Printf("[Dashboard] %s unmarshal error: %v", ch.Name, err)
-					return
-				}
-				results[idx] = channelResult{data: data}
-			}(i, intg)
+	cacheData, _ := json.Marshal(res)
+	return cacheData, enabledChannels, partial
+}
+
+// fetchChannelDashboards calls /internal/dashboard on each enabled
+// channel in parallel and merges the results. owner is a user sub or an
+// org's synthetic owner. ctx carries the caller's trace and any
+// dashboard shape (dashboard_shape.go).
+//
+// Each channel gets its own budget (dashboardBudget), so one slow channel
+// costs the dashboard that channel's data rather than the whole response.
+// Channels that fail are left out of the data and named in the returned
+// errors map, which is nil when every channel answered.
+func fetchChannelDashboards(ctx context.Context, owner string, enabledChannels map[string]bool) (map[string]interface{}, map[string]string) {
+	shape := dashboardShapeFrom(ctx)
+	var targets []*ChannelInfo
+	for _, intg := range GetAllChannels() {
+		if enabledChannels[intg.Name] && shape.includes(intg.Name) && intg.HasCapability("dashboard_provider") {
+			targets = append(targets, intg)
 		}
-		wg.Wait()
+	}
 
-		for _, r := range results {
-			for k, v := range r.data {
-				res.Data[k] = v
+	type channelResult struct {
+		data map[string]interface{}
+		err  string
+	}
+	results := make([]channelResult, len(targets))
+	// Goroutines never return an error: a failed channel is recorded in
+	// its result so the others still finish.
+	var g errgroup.Group
+	for i, intg := range targets {
+		g.Go(func() error {
+			data, reason := fetchChannelDashboard(ctx, intg, owner)
+			results[i] = channelResult{data: data, err: reason}
+			return nil
+		})
+	}
+	g.Wait()
+
+	merged := make(map[string]interface{})
+	var errs map[string]string
+	for i, r := range results {
+		if r.err != "" {
+			if errs == nil {
+				errs = make(map[string]string)
 			}
+			errs[targets[i].Name] = r.err
+			continue
+		}
+		for k, v := range r.data {
+			merged[k] = v
 		}
+	}
+	return merged, errs
+}
 
-		cacheData, _ := json.Marshal(res)
-		Rdb.Set(context.Background(), cacheKey, cacheData, DashboardCacheTTL)
-		return cacheData, nil
-	})
+// fetchChannelDashboard fetches one channel's dashboard data within its
+// budget. On failure it returns a short reason for the errors map. A
+// channel whose circuit breaker is open is skipped as "unavailable".
+func fetchChannelDashboard(ctx context.Context, ch *ChannelInfo, owner string) (map[string]interface{}, string) {
+	breaker := channelBreaker(ch.Name)
+	if !breaker.allow() {
+		return nil, "unavailable"
+	}
+	var callErr error
+	defer func() { breaker.record(callErr) }()
 
+	ctx, cancel := context.WithTimeout(withTraceChannel(ctx, ch.Name), dashboardBudget(ch))
+	defer cancel()
+
+	shape := dashboardShapeFrom(ctx)
+	url := fmt.Sprintf("%s/internal/dashboard?user=%s%s", ch.InternalURL, owner, shape.hintQuery(ch.Name))
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "dashboard fetch failed"})
+		log.Printf("[Dashboard] %s request error: %v", ch.Name, err)
+		return nil, "unavailable"
 	}
+	resp, err := channelDashboardClient.Do(req)
+	if err != nil {
+		callErr = err
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("[Dashboard] %s timed out after %s", ch.Name, dashboardBudget(ch))
+			return nil, "timeout"
+		}
+		log.Printf("[Dashboard] %s fetch error: %v", ch.Name, err)
+		return nil, "unavailable"
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		callErr = err
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("[Dashboard] %s timed out after %s", ch.Name, dashboardBudget(ch))
+			return nil, "timeout"
+		}
+		log.Printf("[Dashboard] %s read error: %v", ch.Name, err)
+		return nil, "unavailable"
+	}
+	if resp.StatusCode != 200 {
+		log.Printf("[Dashboard] %s returned status %d", ch.Name, resp.StatusCode)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			callErr = errChannelStatus(resp.StatusCode)
+		}
+		return nil, fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		log.Printf("[Dashboard] %s unmarshal error: %v", ch.Name, err)
+		return nil, "invalid response"
+	}
+	shape.apply(ch.Name, data)
+	return data, ""
+}
 
-	c.Set("Content-Type", "application/json")
-	c.Set("X-Cache", "MISS")
-	return c.Send(result.([]byte))
+// dashboardBudget is how long the fan-out waits for a channel: its
+// registered dashboard_timeout_ms, else DashboardChannelTimeout, never
+// more than DashboardChannelMaxTimeout.
+func dashboardBudget(ch *ChannelInfo) time.Duration {
+	if ch.DashboardTimeoutMs <= 0 {
+		return DashboardChannelTimeout
+	}
+	return min(time.Duration(ch.DashboardTimeoutMs)*time.Millisecond, DashboardChannelMaxTimeout)
 }
 
 // listChannels returns all discovered channels and their capabilities.
@@ -479,9 +859,10 @@ func (s *Server) listChannels(c *fiber.Ctx) error {
 	infos := make([]fiber.Map, 0, len(channels))
 	for _, ch := range channels {
 		infos = append(infos, fiber.Map{
-			"name":         ch.Name,
-			"display_name": ch.DisplayName,
-			"capabilities": ch.Capabilities,
+			"name":              ch.Name,
+			"display_name":      ch.DisplayName,
+			"capabilities":      ch.Capabilities,
+			"has_config_schema": ch.configSchema != nil,
 		})
 	}
 	return c.JSON(infos)
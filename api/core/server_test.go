@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withDiscoveredChannels swaps the discovery registry for the test, with
+// fresh channel circuit breakers.
+func withDiscoveredChannels(t *testing.T, channels ...*ChannelInfo) {
+	t.Helper()
+	channelBreakers.mu.Lock()
+	prevBreakers := channelBreakers.m
+	channelBreakers.m = make(map[string]*circuitBreaker)
+	channelBreakers.mu.Unlock()
+	globalDiscovery.mu.Lock()
+	prev := globalDiscovery.channels
+	globalDiscovery.channels = make(map[string]*ChannelInfo, len(channels))
+	for _, ch := range channels {
+		globalDiscovery.channels[ch.Name] = ch
+	}
+	globalDiscovery.mu.Unlock()
+	t.Cleanup(func() {
+		globalDiscovery.mu.Lock()
+		globalDiscovery.channels = prev
+		globalDiscovery.mu.Unlock()
+		channelBreakers.mu.Lock()
+		channelBreakers.m = prevBreakers
+		channelBreakers.mu.Unlock()
+	})
+}
+
+func dashboardChannel(name string, handler http.HandlerFunc, t *testing.T) *ChannelInfo {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &ChannelInfo{Name: name, InternalURL: srv.URL, Capabilities: []string{"dashboard_provider"}}
+}
+
+func TestFetchChannelDashboardsPartialResults(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	fast := dashboardChannel("finance", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"finance":[1,2]}`))
+	}, t)
+	slow := dashboardChannel("sports", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}, t)
+	slow.DashboardTimeoutMs = 50
+	broken := dashboardChannel("rss", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, t)
+	withDiscoveredChannels(t, fast, slow, broken)
+
+	start := time.Now()
+	data, errs := fetchChannelDashboards(context.Background(), "user-1",
+		map[string]bool{"finance": true, "sports": true, "rss": true})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("fan-out took %s; the slow channel's budget should have cut it short", elapsed)
+	}
+
+	if _, ok := data["finance"]; !ok {
+		t.Errorf("data = %v, want finance included", data)
+	}
+	if errs["sports"] != "timeout" {
+		t.Errorf("errs[sports] = %q, want timeout", errs["sports"])
+	}
+	if errs["rss"] != "status 502" {
+		t.Errorf("errs[rss] = %q, want status 502", errs["rss"])
+	}
+	if _, ok := errs["finance"]; ok {
+		t.Errorf("errs = %v, finance should not be listed", errs)
+	}
+}
+
+func TestFetchChannelDashboardsNoErrors(t *testing.T) {
+	ok := dashboardChannel("finance", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"finance":[]}`))
+	}, t)
+	withDiscoveredChannels(t, ok)
+
+	_, errs := fetchChannelDashboards(context.Background(), "user-1", map[string]bool{"finance": true})
+	if errs != nil {
+		t.Errorf("errs = %v, want nil so the field is omitted", errs)
+	}
+}
+
+func TestFetchChannelDashboardFailsFastWhenDown(t *testing.T) {
+	var calls atomic.Int32
+	down := dashboardChannel("rss", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, t)
+	withDiscoveredChannels(t, down)
+
+	for i := 0; i < ChannelBreakerFailureThreshold; i++ {
+		if _, reason := fetchChannelDashboard(context.Background(), down, "user-1"); reason != "status 503" {
+			t.Fatalf("call %d: reason = %q, want status 503", i, reason)
+		}
+	}
+	if _, reason := fetchChannelDashboard(context.Background(), down, "user-1"); reason != "unavailable" {
+		t.Fatalf("reason with breaker open = %q, want unavailable", reason)
+	}
+	if checkChannelHealth(down) {
+		t.Fatal("health check reported a down channel healthy")
+	}
+	if got := calls.Load(); got != ChannelBreakerFailureThreshold {
+		t.Errorf("channel got %d requests, want %d (none once the breaker opened)", got, ChannelBreakerFailureThreshold)
+	}
+
+	// After the cooldown a successful probe closes the breaker.
+	b := channelBreaker("rss")
+	b.now = func() time.Time { return time.Now().Add(ChannelBreakerCooldown) }
+	ok := dashboardChannel("rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rss":[]}`))
+	}, t)
+	if _, reason := fetchChannelDashboard(context.Background(), ok, "user-1"); reason != "" {
+		t.Fatalf("probe reason = %q, want success", reason)
+	}
+	if b.State() != breakerClosed {
+		t.Errorf("state after successful probe = %s, want closed", b.State())
+	}
+}
+
+func TestDashboardBudget(t *testing.T) {
+	tests := []struct {
+		ms   int
+		want time.Duration
+	}{
+		{0, DashboardChannelTimeout},
+		{4000, 4 * time.Second},
+		{60000, DashboardChannelMaxTimeout},
+	}
+	for _, tt := range tests {
+		if got := dashboardBudget(&ChannelInfo{DashboardTimeoutMs: tt.ms}); got != tt.want {
+			t.Errorf("dashboardBudget(%d ms) = %s, want %s", tt.ms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Short links for shared ticker items, profiles and leagues.
+//
+// Shares from the extension used to paste the provider's full URL, which
+// for some feeds runs to several hundred characters of tracking params.
+// POST /links trades a URL for a short code and GET /l/:code redirects
+// to it, counting the click.
+//
+// Abuse controls:
+//
+//   - Targets must be absolute http(s) URLs without credentials.
+//     profile and league links must point at the frontend; item links
+//     may point anywhere the provider does.
+//   - SHORT_LINK_DENYLIST (egress rule syntax: hosts, *.wildcards, IPs,
+//     CIDRs) refuses hosts at creation and again at redirect, so adding
+//     a host takes down links already minted for it.
+//   - Creation is rate limited per user, and every link expires (90 days
+//     by default, at most a year).
+//   - Owners can delete their links; admins can disable any link with a
+//     reason. Disabled rows are kept so a code is never reissued.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// ShortLink is the owner's view of a short link.
+type ShortLink struct {
+	Code          string     `json:"code"`
+	ShortURL      string     `json:"short_url"`
+	Kind          string     `json:"kind"`
+	TargetURL     string     `json:"target_url"`
+	Clicks        int64      `json:"clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+}
+
+// shortLinkKinds are the accepted values of kind.
+var shortLinkKinds = map[string]bool{
+	"item":    true,
+	"profile": true,
+	"league":  true,
+}
+
+const shortLinkAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ─── Codes and targets ───────────────────────────────────────────
+
+// generateShortLinkCode returns a random base62 code of
+// ShortLinkCodeLength characters.
+func generateShortLinkCode() (string, error) {
+	max := big.NewInt(int64(len(shortLinkAlphabet)))
+	b := make([]byte, ShortLinkCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("read random: %w", err)
+		}
+		b[i] = shortLinkAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// validShortLinkCode reports whether code could have been generated, so
+// malformed lookups skip the database.
+func validShortLinkCode(code string) bool {
+	if len(code) != ShortLinkCodeLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if !strings.ContainsRune(shortLinkAlphabet, rune(code[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// shortLinkDenylist is read on every call so a hot env reload takes
+// effect without a restart.
+func shortLinkDenylist() egressRules {
+	return parseEgressRules(os.Getenv("SHORT_LINK_DENYLIST"))
+}
+
+// frontendHost is the host of FRONTEND_URL (DefaultFrontendURL unset).
+func frontendHost() string {
+	raw := os.Getenv("FRONTEND_URL")
+	if raw == "" {
+		raw = DefaultFrontendURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// validateShortLinkTarget checks raw as a target for a link of kind and
+// returns it normalised, or a message suitable for the client.
+func validateShortLinkTarget(kind, raw string, deny egressRules, frontend string) (string, error) {
+	if !shortLinkKinds[kind] {
+		return "", errors.New("kind must be item, profile or league")
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > ShortLinkMaxURLLength {
+		return "", fmt.Errorf("url must be 1-%d characters", ShortLinkMaxURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return "", errors.New("url must not contain credentials")
+	}
+	host := strings.ToLower(u.Hostname())
+	if deny.match(host) {
+		return "", errors.New("links to this host are not allowed")
+	}
+	if kind != "item" && host != frontend {
+		return "", fmt.Errorf("%s links must point at %s", kind, frontend)
+	}
+	return u.String(), nil
+}
+
+// shortLinkTTL turns the requested ttl_days into a lifetime: 0 means
+// ShortLinkDefaultTTL, and anything outside 1..ShortLinkMaxTTL is an
+// error.
+func shortLinkTTL(days int) (time.Duration, error) {
+	if days == 0 {
+		return ShortLinkDefaultTTL, nil
+	}
+	ttl := time.Duration(days) * 24 * time.Hour
+	if days < 0 || ttl > ShortLinkMaxTTL {
+		return 0, fmt.Errorf("ttl_days must be 1-%d", int(ShortLinkMaxTTL/(24*time.Hour)))
+	}
+	return ttl, nil
+}
+
+// shortLinkURL is the public URL for code.
+func shortLinkURL(code string) string {
+	base := os.Getenv("SHORT_LINK_BASE_URL")
+	if base == "" {
+		base = "https://api.myscrollr.com"
+	}
+	return strings.TrimSuffix(base, "/") + "/l/" + code
+}
+
+// insertShortLink stores a new link under a fresh code, retrying the
+// rare collision.
+func insertShortLink(ctx context.Context, sub, kind, target string, expiresAt time.Time) (ShortLink, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateShortLinkCode()
+		if err != nil {
+			return ShortLink{}, err
+		}
+		l := ShortLink{Code: code, Kind: kind, TargetURL: target, ExpiresAt: expiresAt}
+		err = DBPool.QueryRow(ctx, `
+			INSERT INTO short_links (code, logto_sub, kind, target_url, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (code) DO NOTHING
+			RETURNING created_at
+		`, code, sub, kind, target, expiresAt).Scan(&l.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return ShortLink{}, err
+		}
+		l.ShortURL = shortLinkURL(code)
+		return l, nil
+	}
+	return ShortLink{}, errors.New("no free short link code after 5 attempts")
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// HandleCreateShortLink shortens a share URL. Sharing the same URL again
+// while the caller's earlier link is still live returns that link rather
+// than minting another.
+//
+// @Summary Create short link
+// @Tags Links
+// @Accept json
+// @Produce json
+// @Param body body object{url=string,kind=string,ttl_days=int} true "Target URL, kind (item|profile|league) and optional lifetime in days"
+// @Success 201 {object} ShortLink
+// @Success 200 {object} ShortLink
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /links [post]
+func HandleCreateShortLink(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		URL     string `json:"url"`
+		Kind    string `json:"kind"`
+		TTLDays int    `json:"ttl_days"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	target, err := validateShortLinkTarget(req.Kind, req.URL, shortLinkDenylist(), frontendHost())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+	ttl, err := shortLinkTTL(req.TTLDays)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+
+	// Soft-fail on Redis errors like the feedback form: a share is worth
+	// more than a perfectly enforced limit.
+	if Rdb != nil {
+		key := RedisShortLinkRLPrefix + userID
+		count, err := Rdb.Incr(ctx, key).Result()
+		if err == nil {
+			if count == 1 {
+				if expErr := Rdb.Expire(ctx, key, time.Hour).Err(); expErr != nil {
+					log.Printf("[Links] Redis EXPIRE failed for key=%s (key has no TTL): %v", key, expErr)
+				}
+			}
+			if count > ShortLinkCreateMaxPerHour {
+				return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+					Status: "error",
+					Error:  "Too many links created; please try again later",
+				})
+			}
+		} else {
+			log.Printf("[Links] Redis INCR failed (continuing): %v", err)
+		}
+	}
+
+	var existing ShortLink
+	err = DBPool.QueryRow(ctx, `
+		SELECT code, kind, target_url, clicks, last_clicked_at, created_at, expires_at
+		FROM short_links
+		WHERE logto_sub = $1 AND target_url = $2 AND kind = $3
+		  AND disabled_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID, target, req.Kind).Scan(&existing.Code, &existing.Kind, &existing.TargetURL,
+		&existing.Clicks, &existing.LastClickedAt, &existing.CreatedAt, &existing.ExpiresAt)
+	if err == nil {
+		existing.ShortURL = shortLinkURL(existing.Code)
+		return c.JSON(existing)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Links] dedupe lookup failed: %v", err)
+	}
+
+	l, err := insertShortLink(ctx, userID, req.Kind, target, time.Now().Add(ttl))
+	if err != nil {
+		log.Printf("[Links] insert failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create short link",
+		})
+	}
+	log.Printf("[Links] Created %s (%s) for user=%s", l.Code, l.Kind, userID)
+	return c.Status(fiber.StatusCreated).JSON(l)
+}
+
+// HandleShortLinkRedirect follows a short link, counting the click.
+// Unknown codes are 404; expired, disabled and denylisted links are 410.
+//
+// @Summary Follow short link
+// @Tags Links
+// @Param code path string true "Short link code"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /l/{code} [get]
+func HandleShortLinkRedirect(c *fiber.Ctx) error {
+	code := c.Params("code")
+	notFound := func() error {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Link not found",
+		})
+	}
+	gone := func() error {
+		return c.Status(fiber.StatusGone).JSON(ErrorResponse{
+			Status: "gone",
+			Error:  "This link has expired or been disabled",
+		})
+	}
+	if !validShortLinkCode(code) {
+		return notFound()
+	}
+
+	ctx := c.Context()
+	var target string
+	err := DBPool.QueryRow(ctx, `
+		UPDATE short_links SET clicks = clicks + 1, last_clicked_at = now()
+		WHERE code = $1 AND disabled_at IS NULL AND expires_at > now()
+		RETURNING target_url
+	`, code).Scan(&target)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := DBPool.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM short_links WHERE code = $1)`, code,
+		).Scan(&exists); err == nil && exists {
+			return gone()
+		}
+		return notFound()
+	}
+	if err != nil {
+		log.Printf("[Links] redirect lookup failed for %s: %v", code, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	if u, err := url.Parse(target); err != nil || shortLinkDenylist().match(u.Hostname()) {
+		return gone()
+	}
+
+	// Clicks are counted here, so nothing in between may cache the hop.
+	c.Set("Cache-Control", "private, no-store")
+	c.Set("Referrer-Policy", "no-referrer")
+	return c.Redirect(target, fiber.StatusFound)
+}
+
+// HandleListShortLinks returns the caller's live short links, newest
+// first, with click counts.
+//
+// @Summary List short links
+// @Tags Users
+// @Produce json
+// @Success 200 {object} object{links=[]ShortLink}
+// @Security LogtoAuth
+// @Router /users/me/links [get]
+func HandleListShortLinks(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT code, kind, target_url, clicks, last_clicked_at, created_at, expires_at
+		FROM short_links
+		WHERE logto_sub = $1 AND disabled_at IS NULL AND expires_at > now()
+		ORDER BY created_at DESC
+		LIMIT 200
+	`, userID)
+	if err != nil {
+		log.Printf("[Links] list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list short links",
+		})
+	}
+	defer rows.Close()
+
+	links := make([]ShortLink, 0)
+	for rows.Next() {
+		var l ShortLink
+		if err := rows.Scan(&l.Code, &l.Kind, &l.TargetURL, &l.Clicks, &l.LastClickedAt, &l.CreatedAt, &l.ExpiresAt); err != nil {
+			log.Printf("[Links] scan error: %v", err)
+			continue
+		}
+		l.ShortURL = shortLinkURL(l.Code)
+		links = append(links, l)
+	}
+	return c.JSON(fiber.Map{"links": links})
+}
+
+// HandleDeleteShortLink disables one of the caller's short links.
+//
+// @Summary Delete short link
+// @Tags Users
+// @Produce json
+// @Param code path string true "Short link code"
+// @Success 200 {object} object{status=string}
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/links/{code} [delete]
+func HandleDeleteShortLink(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	code := c.Params("code")
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE short_links SET disabled_at = now(), disabled_reason = 'deleted by owner'
+		WHERE code = $1 AND logto_sub = $2 AND disabled_at IS NULL
+	`, code, userID)
+	if err != nil {
+		log.Printf("[Links] delete failed for %s: %v", code, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete short link",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Short link not found",
+		})
+	}
+	log.Printf("[Links] Deleted %s for user=%s", code, userID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// HandleAdminDisableShortLink takes down any short link, e.g. one
+// reported for pointing at phishing or malware.
+//
+// @Summary Disable short link
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param code path string true "Short link code"
+// @Param body body object{reason=string} true "Why the link was disabled"
+// @Success 200 {object} object{status=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/links/{code}/disable [post]
+func HandleAdminDisableShortLink(c *fiber.Ctx) error {
+	code := c.Params("code")
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Reason must be 1-500 characters",
+		})
+	}
+
+	tag, err := DBPool.Exec(c.Context(), `
+		UPDATE short_links SET disabled_at = COALESCE(disabled_at, now()), disabled_reason = $2
+		WHERE code = $1
+	`, code, req.Reason)
+	if err != nil {
+		log.Printf("[Links] admin disable failed for %s: %v", code, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to disable short link",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Short link not found",
+		})
+	}
+	log.Printf("[Links] Admin %s disabled %s: %s", GetUserID(c), code, req.Reason)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,434 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Signing keysets.
+//
+// Tokens the gateway mints itself are HMAC-SHA256 signed with a key from
+// a per-purpose keyset (signing_keys, migration 000033) and carry that
+// key's ID:
+//
+//	<kid>.<base64url(claims)>.<base64url(hmac)>
+//
+// Each purpose has one active key, used for signing. Rotation creates a
+// new active key and retires the previous one, which keeps verifying for
+// SigningKeyGracePeriod so tokens already handed out stay valid. Keys
+// rotate every SigningKeyRotationInterval, or on demand through
+// POST /admin/signing-keys/rotate; a key known to have leaked is rotated
+// with revoke_previous, which stops it verifying at once (on other
+// replicas within SigningKeyCacheTTL).
+
+const signingPurposeSupportApproval = "support_approval"
+
+// signingKeyPurposes are the keysets the rotation worker maintains.
+var signingKeyPurposes = []string{signingPurposeSupportApproval}
+
+var (
+	errMalformedToken    = errors.New("malformed token")
+	errUnknownSigningKey = errors.New("unknown or retired signing key")
+	errInvalidSignature  = errors.New("invalid signature")
+)
+
+type signingKey struct {
+	KID       string
+	Secret    []byte
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// usable reports whether k may still verify tokens at now.
+func (k signingKey) usable(now time.Time) bool {
+	return k.RetiredAt == nil || now.Sub(*k.RetiredAt) < SigningKeyGracePeriod
+}
+
+// keyset is one purpose's unrevoked keys, newest first.
+type keyset struct {
+	keys     []signingKey
+	loadedAt time.Time
+}
+
+func (ks *keyset) active() (signingKey, bool) {
+	for _, k := range ks.keys {
+		if k.RetiredAt == nil {
+			return k, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// lookup finds kid; ok is false when it's unknown or past its grace period.
+func (ks *keyset) lookup(kid string, now time.Time) (signingKey, bool) {
+	for _, k := range ks.keys {
+		if k.KID == kid {
+			return k, k.usable(now)
+		}
+	}
+	return signingKey{}, false
+}
+
+var (
+	keysetsMu sync.Mutex
+	keysets   = make(map[string]*keyset)
+)
+
+// loadKeyset returns purpose's keyset, reloading it from Postgres when
+// the cached copy is older than maxAge. A failed reload falls back to the
+// cached copy, so a database blip doesn't fail verification.
+func loadKeyset(ctx context.Context, purpose string, maxAge time.Duration) (*keyset, error) {
+	keysetsMu.Lock()
+	cached := keysets[purpose]
+	keysetsMu.Unlock()
+	if cached != nil && time.Since(cached.loadedAt) < maxAge {
+		return cached, nil
+	}
+
+	keys, err := querySigningKeys(ctx, purpose)
+	if err != nil {
+		if cached != nil {
+			log.Printf("[SigningKeys] Reload of %s failed, using cached keyset: %v", purpose, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	ks := &keyset{keys: keys, loadedAt: time.Now()}
+	keysetsMu.Lock()
+	keysets[purpose] = ks
+	keysetsMu.Unlock()
+	return ks, nil
+}
+
+func querySigningKeys(ctx context.Context, purpose string) ([]signingKey, error) {
+	rows, err := DBPool.Query(ctx, `
+		SELECT kid, secret, created_at, retired_at
+		  FROM signing_keys
+		 WHERE purpose = $1 AND NOT revoked
+		   AND (retired_at IS NULL OR retired_at > now() - make_interval(secs => $2))
+		 ORDER BY created_at DESC`, purpose, SigningKeyGracePeriod.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []signingKey
+	for rows.Next() {
+		var k signingKey
+		if err := rows.Scan(&k.KID, &k.Secret, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func dropCachedKeyset(purpose string) {
+	keysetsMu.Lock()
+	delete(keysets, purpose)
+	keysetsMu.Unlock()
+}
+
+// rotateSigningKey gives purpose a new active key and retires (or, with
+// revokePrevious, revokes) the current one. With ifOlderThan > 0 the
+// current key is kept when younger than that, which is how the schedule
+// and a purpose's first signature share this path. Concurrent callers
+// are serialized by an advisory lock.
+func rotateSigningKey(ctx context.Context, purpose string, revokePrevious bool, ifOlderThan time.Duration) (kid string, rotated bool, err error) {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "signing_keys:"+purpose); err != nil {
+		return "", false, fmt.Errorf("lock keyset: %w", err)
+	}
+	if ifOlderThan > 0 {
+		var current string
+		var createdAt time.Time
+		err := tx.QueryRow(ctx, `
+			SELECT kid, created_at FROM signing_keys
+			 WHERE purpose = $1 AND retired_at IS NULL
+			 ORDER BY created_at DESC LIMIT 1`, purpose).Scan(&current, &createdAt)
+		if err == nil && time.Since(createdAt) < ifOlderThan {
+			return current, false, nil
+		}
+		if err != nil && !strings.Contains(err.Error(), "no rows") {
+			return "", false, fmt.Errorf("read active key: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE signing_keys SET retired_at = now(), revoked = $2
+		 WHERE purpose = $1 AND retired_at IS NULL`, purpose, revokePrevious); err != nil {
+		return "", false, fmt.Errorf("retire active key: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	id := make([]byte, 6)
+	if _, err := rand.Read(secret); err != nil {
+		return "", false, err
+	}
+	if _, err := rand.Read(id); err != nil {
+		return "", false, err
+	}
+	kid = hex.EncodeToString(id)
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO signing_keys (kid, purpose, secret) VALUES ($1, $2, $3)",
+		kid, purpose, secret); err != nil {
+		return "", false, fmt.Errorf("insert key: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", false, fmt.Errorf("commit: %w", err)
+	}
+	dropCachedKeyset(purpose)
+	return kid, true, nil
+}
+
+// activeSigningKey returns the key new tokens for purpose are signed
+// with, creating the keyset's first key if it has none.
+func activeSigningKey(ctx context.Context, purpose string) (signingKey, error) {
+	ks, err := loadKeyset(ctx, purpose, SigningKeyCacheTTL)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("load %s keyset: %w", purpose, err)
+	}
+	if k, ok := ks.active(); ok {
+		return k, nil
+	}
+	if _, _, err := rotateSigningKey(ctx, purpose, false, SigningKeyRotationInterval); err != nil {
+		return signingKey{}, fmt.Errorf("create %s key: %w", purpose, err)
+	}
+	if ks, err = loadKeyset(ctx, purpose, 0); err != nil {
+		return signingKey{}, err
+	}
+	if k, ok := ks.active(); ok {
+		return k, nil
+	}
+	return signingKey{}, fmt.Errorf("no active %s key", purpose)
+}
+
+// verificationKey finds kid in purpose's keyset, reloading once (rate
+// limited) for a kid this replica hasn't seen, e.g. one minted by a
+// replica that just rotated.
+func verificationKey(ctx context.Context, purpose, kid string) (signingKey, error) {
+	ks, err := loadKeyset(ctx, purpose, SigningKeyCacheTTL)
+	if err != nil {
+		return signingKey{}, err
+	}
+	now := time.Now()
+	if k, ok := ks.lookup(kid, now); ok {
+		return k, nil
+	}
+	if ks, err = loadKeyset(ctx, purpose, SigningKeyReloadMinGap); err != nil {
+		return signingKey{}, err
+	}
+	if k, ok := ks.lookup(kid, now); ok {
+		return k, nil
+	}
+	return signingKey{}, errUnknownSigningKey
+}
+
+func signingMAC(secret []byte, signed string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signToken encodes claims and signs them with purpose's active key.
+func signToken(ctx context.Context, purpose string, claims any) (string, error) {
+	k, err := activeSigningKey(ctx, purpose)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := k.KID + "." + base64.RawURLEncoding.EncodeToString(body)
+	return signed + "." + signingMAC(k.Secret, signed), nil
+}
+
+// verifyToken checks raw's signature against purpose's keyset and
+// decodes its claims into out. Expiry is the caller's to check.
+func verifyToken(ctx context.Context, purpose, raw string, out any) error {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return errMalformedToken
+	}
+	k, err := verificationKey(ctx, purpose, parts[0])
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signingMAC(k.Secret, parts[0]+"."+parts[1]))) {
+		return errInvalidSignature
+	}
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("decode body: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("parse claims: %w", err)
+	}
+	return nil
+}
+
+// ─── Scheduled rotation ──────────────────────────────────────────
+
+// StartSigningKeyRotation rotates keys older than
+// SigningKeyRotationInterval and deletes keys past their grace period.
+// One replica does the work per tick.
+func StartSigningKeyRotation(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(SigningKeyCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runSigningKeyRotation(ctx)
+			}
+		}
+	}()
+	log.Printf("[SigningKeys] Rotation worker started (%s interval, keys live %s)", SigningKeyCheckInterval, SigningKeyRotationInterval)
+}
+
+func runSigningKeyRotation(ctx context.Context) {
+	ok, err := Rdb.SetNX(ctx, RedisSigningKeyRotateLock, "1", SigningKeyCheckInterval/2).Result()
+	if err != nil || !ok {
+		return
+	}
+	for _, purpose := range signingKeyPurposes {
+		kid, rotated, err := rotateSigningKey(ctx, purpose, false, SigningKeyRotationInterval)
+		if err != nil {
+			log.Printf("[SigningKeys] Scheduled rotation of %s failed: %v", purpose, err)
+			continue
+		}
+		if rotated {
+			log.Printf("[SigningKeys] Rotated %s, new kid=%s", purpose, kid)
+		}
+	}
+	tag, err := DBPool.Exec(ctx, `
+		DELETE FROM signing_keys
+		 WHERE retired_at < now() - make_interval(secs => $1)`, SigningKeyGracePeriod.Seconds())
+	if err != nil {
+		log.Printf("[SigningKeys] Prune failed: %v", err)
+	} else if n := tag.RowsAffected(); n > 0 {
+		log.Printf("[SigningKeys] Pruned %d expired key(s)", n)
+	}
+}
+
+// ─── Admin ───────────────────────────────────────────────────────
+
+// SigningKeyInfo describes a key for the admin listing. The secret is
+// never returned.
+type SigningKeyInfo struct {
+	KID       string     `json:"kid"`
+	Purpose   string     `json:"purpose"`
+	State     string     `json:"state"` // active | retired (still verifying) | expired | revoked
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// HandleAdminListSigningKeys lists every stored signing key.
+//
+// @Summary List token signing keys (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{keys=[]SigningKeyInfo,grace_period_seconds=int}
+// @Failure 500 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/signing-keys [get]
+func HandleAdminListSigningKeys(c *fiber.Ctx) error {
+	rows, err := DBPool.Query(c.Context(), `
+		SELECT kid, purpose, created_at, retired_at, revoked
+		  FROM signing_keys
+		 ORDER BY purpose, created_at DESC`)
+	if err != nil {
+		log.Printf("[SigningKeys] admin list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list signing keys",
+		})
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	keys := []SigningKeyInfo{}
+	for rows.Next() {
+		var k SigningKeyInfo
+		var revoked bool
+		if err := rows.Scan(&k.KID, &k.Purpose, &k.CreatedAt, &k.RetiredAt, &revoked); err != nil {
+			continue
+		}
+		switch {
+		case revoked:
+			k.State = "revoked"
+		case k.RetiredAt == nil:
+			k.State = "active"
+		case (signingKey{RetiredAt: k.RetiredAt}).usable(now):
+			k.State = "retired"
+		default:
+			k.State = "expired"
+		}
+		keys = append(keys, k)
+	}
+	return c.JSON(fiber.Map{
+		"keys":                 keys,
+		"grace_period_seconds": int(SigningKeyGracePeriod.Seconds()),
+	})
+}
+
+// HandleAdminRotateSigningKey rotates a keyset now. revoke_previous also
+// revokes the outgoing key, invalidating tokens it signed; use it when
+// that key has leaked.
+//
+// @Summary Rotate a token signing key (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body object true "Keyset" example({"purpose":"support_approval","revoke_previous":false})
+// @Success 200 {object} object{purpose=string,kid=string}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /admin/signing-keys/rotate [post]
+func HandleAdminRotateSigningKey(c *fiber.Ctx) error {
+	var req struct {
+		Purpose        string `json:"purpose"`
+		RevokePrevious bool   `json:"revoke_previous"`
+	}
+	if err := c.BodyParser(&req); err != nil || !slices.Contains(signingKeyPurposes, req.Purpose) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "purpose must be one of: " + strings.Join(signingKeyPurposes, ", "),
+		})
+	}
+
+	kid, _, err := rotateSigningKey(c.Context(), req.Purpose, req.RevokePrevious, 0)
+	if err != nil {
+		log.Printf("[SigningKeys] admin rotation of %s failed: %v", req.Purpose, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to rotate signing key",
+		})
+	}
+
+	log.Printf("[SigningKeys] %s rotated by %s (revoke_previous=%t), new kid=%s",
+		req.Purpose, GetUserID(c), req.RevokePrevious, kid)
+	return c.JSON(fiber.Map{"purpose": req.Purpose, "kid": kid})
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedKeyset installs a freshly loaded keyset so sign/verify never reach
+// Postgres.
+func seedKeyset(t *testing.T, purpose string, keys ...signingKey) {
+	t.Helper()
+	keysetsMu.Lock()
+	keysets[purpose] = &keyset{keys: keys, loadedAt: time.Now()}
+	keysetsMu.Unlock()
+	t.Cleanup(func() { dropCachedKeyset(purpose) })
+}
+
+func retiredAgo(d time.Duration) *time.Time {
+	at := time.Now().Add(-d)
+	return &at
+}
+
+func TestSignTokenRoundTrip(t *testing.T) {
+	seedKeyset(t, "test", signingKey{KID: "k1", Secret: []byte("secret-one")})
+
+	raw, err := signToken(context.Background(), "test", SupportApprovalToken{DraftID: 7, Action: "send"})
+	if err != nil {
+		t.Fatalf("signToken: %v", err)
+	}
+	if !strings.HasPrefix(raw, "k1.") || strings.Count(raw, ".") != 2 {
+		t.Fatalf("token %q should be <kid>.<claims>.<sig>", raw)
+	}
+
+	var got SupportApprovalToken
+	if err := verifyToken(context.Background(), "test", raw, &got); err != nil {
+		t.Fatalf("verifyToken: %v", err)
+	}
+	if got.DraftID != 7 || got.Action != "send" {
+		t.Errorf("claims = %+v", got)
+	}
+}
+
+func TestVerifyTokenAcceptsRetiredKeyWithinGrace(t *testing.T) {
+	old := signingKey{KID: "old", Secret: []byte("old-secret")}
+	seedKeyset(t, "test", old)
+	raw, err := signToken(context.Background(), "test", SupportApprovalToken{DraftID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotated since: old is retired but inside the grace period.
+	old.RetiredAt = retiredAgo(time.Hour)
+	seedKeyset(t, "test", signingKey{KID: "new", Secret: []byte("new-secret")}, old)
+
+	var got SupportApprovalToken
+	if err := verifyToken(context.Background(), "test", raw, &got); err != nil {
+		t.Errorf("token from a recently retired key rejected: %v", err)
+	}
+	next, _ := signToken(context.Background(), "test", got)
+	if !strings.HasPrefix(next, "new.") {
+		t.Errorf("new tokens should use the active key, got %q", next)
+	}
+}
+
+func TestVerifyTokenRejectsExpiredKey(t *testing.T) {
+	key := signingKey{KID: "k1", Secret: []byte("secret")}
+	seedKeyset(t, "test", key)
+	raw, _ := signToken(context.Background(), "test", SupportApprovalToken{DraftID: 1})
+
+	key.RetiredAt = retiredAgo(SigningKeyGracePeriod + time.Minute)
+	seedKeyset(t, "test", signingKey{KID: "k2", Secret: []byte("other")}, key)
+
+	var got SupportApprovalToken
+	if err := verifyToken(context.Background(), "test", raw, &got); !errors.Is(err, errUnknownSigningKey) {
+		t.Errorf("err = %v, want errUnknownSigningKey", err)
+	}
+}
+
+func TestVerifyTokenRejectsTampering(t *testing.T) {
+	seedKeyset(t, "test",
+		signingKey{KID: "k1", Secret: []byte("secret-one")},
+		signingKey{KID: "k2", Secret: []byte("secret-two"), RetiredAt: retiredAgo(time.Minute)})
+	raw, _ := signToken(context.Background(), "test", SupportApprovalToken{DraftID: 1, Action: "skip"})
+	parts := strings.Split(raw, ".")
+
+	forged, _ := json.Marshal(SupportApprovalToken{DraftID: 1, Action: "send"})
+	cases := map[string]string{
+		"claims swapped": parts[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[2],
+		"kid swapped":    "k2." + parts[1] + "." + parts[2],
+		"unknown kid":    "nope." + parts[1] + "." + parts[2],
+		"two parts":      parts[1] + "." + parts[2],
+	}
+	for name, tok := range cases {
+		var got SupportApprovalToken
+		if err := verifyToken(context.Background(), "test", tok, &got); err == nil {
+			t.Errorf("%s: token verified", name)
+		}
+	}
+}
+
+func TestVerifyApprovalTokenLegacyFormat(t *testing.T) {
+	t.Setenv("SUPPORT_APPROVAL_HMAC_SECRET", "legacy-secret")
+	body, _ := json.Marshal(SupportApprovalToken{DraftID: 3, Action: "edit", Exp: time.Now().Add(time.Hour).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(body)
+	raw := encoded + "." + signingMAC([]byte("legacy-secret"), encoded)
+
+	got, err := verifyApprovalToken(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("legacy token rejected: %v", err)
+	}
+	if got.DraftID != 3 {
+		t.Errorf("DraftID = %d, want 3", got.DraftID)
+	}
+
+	if _, err := verifyApprovalToken(context.Background(), encoded+"."+signingMAC([]byte("wrong"), encoded)); err == nil {
+		t.Error("legacy token with a bad signature verified")
+	}
+}
+
+func TestVerifyApprovalTokenExpired(t *testing.T) {
+	seedKeyset(t, signingPurposeSupportApproval, signingKey{KID: "k1", Secret: []byte("secret")})
+	raw, err := signApprovalToken(context.Background(), SupportApprovalToken{DraftID: 1, Exp: time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyApprovalToken(context.Background(), raw); err == nil {
+		t.Error("expired token verified")
+	}
+}
@@ -0,0 +1,312 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Service level objectives.
+//
+// The gateway's reliability targets are declared here rather than kept in
+// a runbook. Each SLO classifies events as good or bad as they happen
+// (a /dashboard response, an event written to a live stream, a CDC record
+// routed) and keeps per-minute counts for SLOHistory. From those it
+// derives burn rates: the bad fraction over a window divided by the error
+// budget (1 - objective). A burn rate of 1 spends the budget exactly on
+// schedule; 14.4 over an hour spends 2% of a 30-day budget.
+//
+// Burn rates are exported on /metrics as scrollr_slo_burn_rate, and the
+// multiwindow alerts built on them are served as a Prometheus rule file
+// from GET /admin/slo/rules, so the alerting config is generated from the
+// same definitions the service measures. Counts are per replica; the
+// rules take the worst replica.
+
+// sloWindows are the burn-rate windows exported and alerted on.
+var sloWindows = []struct {
+	Label    string
+	Duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// sloAlert is one multiwindow burn-rate alert: it fires when both the
+// long and the short window burn faster than BurnRate. The short window
+// lets the alert clear soon after the problem does.
+type sloAlert struct {
+	Severity    string
+	LongWindow  string
+	ShortWindow string
+	BurnRate    float64
+}
+
+// sloAlerts follow the usual page/ticket split: page on 2% of a 30-day
+// budget gone in an hour, ticket on 5% gone in six.
+var sloAlerts = []sloAlert{
+	{Severity: "page", LongWindow: "1h", ShortWindow: "5m", BurnRate: 14.4},
+	{Severity: "ticket", LongWindow: "6h", ShortWindow: "30m", BurnRate: 6},
+}
+
+// SLO is one declared objective and its running counts.
+type SLO struct {
+	Name        string
+	Description string
+	Objective   float64 // fraction of events that must be good
+
+	mu      sync.Mutex
+	buckets []sloBucket // ring, one per SLOBucketWidth
+	good    atomic.Int64
+	bad     atomic.Int64
+}
+
+type sloBucket struct {
+	start int64 // unix minute; 0 = unused
+	good  int64
+	bad   int64
+}
+
+func newSLO(name, description string, objective float64) *SLO {
+	return &SLO{
+		Name:        name,
+		Description: description,
+		Objective:   objective,
+		buckets:     make([]sloBucket, int(SLOHistory/SLOBucketWidth)),
+	}
+}
+
+var (
+	sloDashboardLatency = newSLO("dashboard_latency",
+		fmt.Sprintf("GET /dashboard responds within %s", SLODashboardLatencyThreshold),
+		SLODashboardLatencyObjective)
+	sloDeliveryLag = newSLO("event_delivery_lag",
+		fmt.Sprintf("Live events reach the client within %s of the CDC webhook", SLODeliveryLagThreshold),
+		SLODeliveryLagObjective)
+	sloCDCRouting = newSLO("cdc_routing",
+		"CDC records are parsed and published to their topic without error",
+		SLOCDCRoutingObjective)
+
+	registeredSLOs = []*SLO{sloDashboardLatency, sloDeliveryLag, sloCDCRouting}
+)
+
+// Record counts one event.
+func (s *SLO) Record(good bool, now time.Time) {
+	if good {
+		s.good.Add(1)
+	} else {
+		s.bad.Add(1)
+	}
+	minute := now.Unix() / int64(SLOBucketWidth/time.Second)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[minute%int64(len(s.buckets))]
+	if b.start != minute {
+		*b = sloBucket{start: minute}
+	}
+	if good {
+		b.good++
+	} else {
+		b.bad++
+	}
+}
+
+// counts sums the buckets that started within window of now.
+func (s *SLO) counts(window time.Duration, now time.Time) (good, bad int64) {
+	width := int64(SLOBucketWidth / time.Second)
+	current := now.Unix() / width
+	oldest := current - int64(window/SLOBucketWidth) + 1
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.buckets {
+		if b.start != 0 && b.start >= oldest && b.start <= current {
+			good += b.good
+			bad += b.bad
+		}
+	}
+	return good, bad
+}
+
+// BurnRate is the bad fraction over window divided by the error budget.
+// No events means no burn.
+func (s *SLO) BurnRate(window time.Duration, now time.Time) float64 {
+	good, bad := s.counts(window, now)
+	if good+bad == 0 {
+		return 0
+	}
+	return (float64(bad) / float64(good+bad)) / (1 - s.Objective)
+}
+
+// recordDashboardLatency classifies one /dashboard response.
+func recordDashboardLatency(d time.Duration) {
+	sloDashboardLatency.Record(d <= SLODashboardLatencyThreshold, time.Now())
+}
+
+// recordDeliveryLag classifies one event written to a live stream by the
+// age of its server_ts. Payloads without one (broadcasts, control
+// events) aren't counted.
+func recordDeliveryLag(serverTS int64, now time.Time) {
+	if serverTS <= 0 {
+		return
+	}
+	lag := now.Sub(time.UnixMilli(serverTS))
+	sloDeliveryLag.Record(lag <= SLODeliveryLagThreshold, now)
+}
+
+// ─── Status ──────────────────────────────────────────────────────
+
+// SLOAlertStatus is one alert's rule and whether it would fire now on
+// this replica.
+type SLOAlertStatus struct {
+	Severity    string  `json:"severity"`
+	LongWindow  string  `json:"long_window"`
+	ShortWindow string  `json:"short_window"`
+	BurnRate    float64 `json:"burn_rate"`
+	Firing      bool    `json:"firing"`
+	Expr        string  `json:"expr"`
+}
+
+// SLOStatus reports one SLO on this replica.
+type SLOStatus struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Objective   float64            `json:"objective"`
+	Good        int64              `json:"good"` // process lifetime
+	Bad         int64              `json:"bad"`
+	BurnRates   map[string]float64 `json:"burn_rates"`
+	// BudgetRemaining is the share of the SLOHistory error budget left:
+	// 1 - the longest window's burn rate, floored at 0.
+	BudgetRemaining float64          `json:"budget_remaining"`
+	Alerts          []SLOAlertStatus `json:"alerts"`
+}
+
+// Status evaluates the SLO's windows and alerts at now.
+func (s *SLO) Status(now time.Time) SLOStatus {
+	st := SLOStatus{
+		Name:        s.Name,
+		Description: s.Description,
+		Objective:   s.Objective,
+		Good:        s.good.Load(),
+		Bad:         s.bad.Load(),
+		BurnRates:   make(map[string]float64, len(sloWindows)),
+	}
+	for _, w := range sloWindows {
+		st.BurnRates[w.Label] = s.BurnRate(w.Duration, now)
+	}
+	st.BudgetRemaining = max(0, 1-st.BurnRates[sloWindows[len(sloWindows)-1].Label])
+	for _, a := range sloAlerts {
+		st.Alerts = append(st.Alerts, SLOAlertStatus{
+			Severity:    a.Severity,
+			LongWindow:  a.LongWindow,
+			ShortWindow: a.ShortWindow,
+			BurnRate:    a.BurnRate,
+			Firing:      st.BurnRates[a.LongWindow] > a.BurnRate && st.BurnRates[a.ShortWindow] > a.BurnRate,
+			Expr:        sloAlertExpr(s.Name, a),
+		})
+	}
+	return st
+}
+
+func sloAlertExpr(slo string, a sloAlert) string {
+	rate := strconv.FormatFloat(a.BurnRate, 'f', -1, 64)
+	return fmt.Sprintf(
+		`max by (slo) (scrollr_slo_burn_rate{slo=%q,window=%q}) > %s and max by (slo) (scrollr_slo_burn_rate{slo=%q,window=%q}) > %s`,
+		slo, a.LongWindow, rate, slo, a.ShortWindow, rate)
+}
+
+// HandleAdminSLOStatus reports every SLO's burn rates and alert state on
+// this replica.
+//
+// @Summary SLO status (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{slos=[]SLOStatus}
+// @Security LogtoAuth
+// @Router /admin/slo [get]
+func HandleAdminSLOStatus(c *fiber.Ctx) error {
+	now := time.Now()
+	out := make([]SLOStatus, 0, len(registeredSLOs))
+	for _, s := range registeredSLOs {
+		out = append(out, s.Status(now))
+	}
+	return c.JSON(fiber.Map{"slos": out})
+}
+
+// HandleAdminSLORules serves the burn-rate alerts as a Prometheus rule
+// file, ready to load into the cluster's Prometheus.
+//
+// @Summary SLO alert rules (admin)
+// @Tags Admin
+// @Produce plain
+// @Success 200 {string} string "Prometheus rule file (YAML)"
+// @Security LogtoAuth
+// @Router /admin/slo/rules [get]
+func HandleAdminSLORules(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "application/yaml; charset=utf-8")
+	return c.SendString(sloRuleFile())
+}
+
+// sloRuleFile renders the alert rules by hand; the format is small and
+// fixed, and quoting every string keeps it valid YAML.
+func sloRuleFile() string {
+	var b strings.Builder
+	b.WriteString("groups:\n  - name: scrollr-slo-burn-rate\n    rules:\n")
+	for _, s := range registeredSLOs {
+		for _, a := range sloAlerts {
+			fmt.Fprintf(&b, "      - alert: %q\n", "SLOBurnRate_"+s.Name+"_"+a.Severity)
+			fmt.Fprintf(&b, "        expr: %q\n", sloAlertExpr(s.Name, a))
+			fmt.Fprintf(&b, "        for: %q\n", "2m")
+			b.WriteString("        labels:\n")
+			fmt.Fprintf(&b, "          severity: %q\n", a.Severity)
+			fmt.Fprintf(&b, "          slo: %q\n", s.Name)
+			b.WriteString("        annotations:\n")
+			fmt.Fprintf(&b, "          summary: %q\n", fmt.Sprintf("%s burning error budget at over %gx", s.Name, a.BurnRate))
+			fmt.Fprintf(&b, "          description: %q\n", fmt.Sprintf("Objective: %s (%g%% good). Burn rate above %g over %s and %s.",
+				s.Description, s.Objective*100, a.BurnRate, a.LongWindow, a.ShortWindow))
+		}
+	}
+	return b.String()
+}
+
+// ─── Metrics ─────────────────────────────────────────────────────
+
+var (
+	sloObjectiveDesc = prometheus.NewDesc("scrollr_slo_objective",
+		"Target fraction of good events.", []string{"slo"}, nil)
+	sloEventsDesc = prometheus.NewDesc("scrollr_slo_events_total",
+		"Events counted against an SLO by result (good or bad).", []string{"slo", "result"}, nil)
+	sloBurnRateDesc = prometheus.NewDesc("scrollr_slo_burn_rate",
+		"Error budget burn rate over a trailing window.", []string{"slo", "window"}, nil)
+)
+
+// sloCollector computes burn rates at scrape time.
+type sloCollector struct{}
+
+func (sloCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sloObjectiveDesc
+	ch <- sloEventsDesc
+	ch <- sloBurnRateDesc
+}
+
+func (sloCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, s := range registeredSLOs {
+		ch <- prometheus.MustNewConstMetric(sloObjectiveDesc, prometheus.GaugeValue, s.Objective, s.Name)
+		ch <- prometheus.MustNewConstMetric(sloEventsDesc, prometheus.CounterValue, float64(s.good.Load()), s.Name, "good")
+		ch <- prometheus.MustNewConstMetric(sloEventsDesc, prometheus.CounterValue, float64(s.bad.Load()), s.Name, "bad")
+		for _, w := range sloWindows {
+			ch <- prometheus.MustNewConstMetric(sloBurnRateDesc, prometheus.GaugeValue, s.BurnRate(w.Duration, now), s.Name, w.Label)
+		}
+	}
+}
+
+func init() {
+	metricsRegistry.MustRegister(sloCollector{})
+}
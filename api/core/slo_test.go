@@ -0,0 +1,107 @@
+package core
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSLOBurnRate(t *testing.T) {
+	s := newSLO("test", "test objective", 0.99)
+	now := time.Unix(1_800_000_000, 0)
+
+	if got := s.BurnRate(time.Hour, now); got != 0 {
+		t.Fatalf("empty SLO burn = %v, want 0", got)
+	}
+
+	// 2 bad in 100 over the last 5 minutes: 2% bad vs a 1% budget.
+	for i := 0; i < 98; i++ {
+		s.Record(true, now.Add(-time.Duration(i%5)*time.Minute))
+	}
+	s.Record(false, now)
+	s.Record(false, now.Add(-4*time.Minute))
+
+	if got := s.BurnRate(5*time.Minute, now); math.Abs(got-2) > 1e-9 {
+		t.Errorf("5m burn = %v, want 2", got)
+	}
+	// Only the current minute: 19 or 20 good, 1 bad.
+	if got := s.BurnRate(time.Minute, now); got <= 0 {
+		t.Errorf("1m burn = %v, want > 0", got)
+	}
+}
+
+func TestSLOWindowsExpire(t *testing.T) {
+	s := newSLO("test", "test objective", 0.9)
+	now := time.Unix(1_800_000_000, 0)
+
+	s.Record(false, now.Add(-2*time.Hour))
+	s.Record(true, now)
+
+	if got := s.BurnRate(time.Hour, now); got != 0 {
+		t.Errorf("1h burn = %v, want 0 (bad event is 2h old)", got)
+	}
+	if got := s.BurnRate(6*time.Hour, now); math.Abs(got-5) > 1e-9 {
+		t.Errorf("6h burn = %v, want 5", got)
+	}
+
+	// A bucket reused after the ring wraps starts from zero.
+	later := now.Add(SLOHistory)
+	s.Record(true, later)
+	if good, bad := s.counts(SLOHistory, later); good != 1 || bad != 0 {
+		t.Errorf("after wrap counts = %d/%d, want 1/0", good, bad)
+	}
+}
+
+func TestSLOStatusAlerts(t *testing.T) {
+	s := newSLO("test", "test objective", 0.999)
+	now := time.Unix(1_800_000_000, 0)
+	for i := 0; i < 10; i++ {
+		s.Record(i%2 == 0, now)
+	}
+
+	st := s.Status(now)
+	if st.Good != 5 || st.Bad != 5 {
+		t.Fatalf("lifetime counts = %d/%d", st.Good, st.Bad)
+	}
+	if st.BudgetRemaining != 0 {
+		t.Errorf("budget remaining = %v, want 0", st.BudgetRemaining)
+	}
+	if len(st.Alerts) != len(sloAlerts) {
+		t.Fatalf("alerts = %d, want %d", len(st.Alerts), len(sloAlerts))
+	}
+	for _, a := range st.Alerts {
+		if !a.Firing {
+			t.Errorf("%s alert should fire at a 500x burn", a.Severity)
+		}
+	}
+}
+
+func TestRecordDeliveryLagSkipsUnstamped(t *testing.T) {
+	before := sloDeliveryLag.good.Load() + sloDeliveryLag.bad.Load()
+	recordDeliveryLag(0, time.Now())
+	if after := sloDeliveryLag.good.Load() + sloDeliveryLag.bad.Load(); after != before {
+		t.Error("payloads without server_ts should not be counted")
+	}
+
+	now := time.Now()
+	bad := sloDeliveryLag.bad.Load()
+	recordDeliveryLag(now.Add(-5*time.Second).UnixMilli(), now)
+	if sloDeliveryLag.bad.Load() != bad+1 {
+		t.Error("a 5s-old event should count as bad")
+	}
+}
+
+func TestSLORuleFile(t *testing.T) {
+	rules := sloRuleFile()
+	for _, s := range registeredSLOs {
+		for _, a := range sloAlerts {
+			if !strings.Contains(rules, `alert: "SLOBurnRate_`+s.Name+`_`+a.Severity+`"`) {
+				t.Errorf("rule file missing %s/%s alert", s.Name, a.Severity)
+			}
+		}
+	}
+	if !strings.Contains(rules, `scrollr_slo_burn_rate{slo=\"cdc_routing\",window=\"1h\"}) > 14.4`) {
+		t.Errorf("expr not rendered as expected:\n%s", rules)
+	}
+}
@@ -0,0 +1,337 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Spoiler windows.
+//
+// A user who watches games on delay sets a window per league in
+// preferences.spoiler_windows: {"NFL": 12, "423.l.12345": 24}. Keys are
+// sports league names (games.league) or Yahoo fantasy league keys. For
+// that many hours after a game ends — and while it is live — its scores
+// are blanked; fantasy matchups lose their points and winner until that
+// many hours after the scoring week ends.
+//
+// Every path that shows a user scores goes through this file:
+// assembleDashboard (so /dashboard, snapshots, the display ticker and its
+// text rendering) calls filterDashboardSpoilers, and the hub runs each
+// per-user event and replay through filterEventSpoilers before any SSE,
+// WebSocket or display stream sees it. Anything new that surfaces scores
+// to a user — digests, notifications — must do the same.
+
+// sportGameLength is the typical length of a game by sport (games.sport).
+// Games only carry a start time, so the window is counted from start +
+// this. Erring long keeps scores hidden a little extra rather than early.
+var sportGameLength = map[string]time.Duration{
+	"american-football": 4 * time.Hour,
+	"baseball":          4 * time.Hour,
+	"basketball":        3 * time.Hour,
+	"hockey":            3 * time.Hour,
+	"football":          2*time.Hour + 30*time.Minute,
+	"rugby":             2 * time.Hour,
+	"afl":               3 * time.Hour,
+	"handball":          2 * time.Hour,
+	"volleyball":        3 * time.Hour,
+	"formula-1":         3 * time.Hour,
+	"mma":               6 * time.Hour,
+}
+
+// gameScoreFields are the games columns that give a result away.
+var gameScoreFields = []string{"home_team_score", "away_team_score"}
+
+// ─── Preferences ─────────────────────────────────────────────────
+
+// parseSpoilerWindows validates a spoiler_windows value from a
+// preferences update. Zero-hour entries are dropped (zero means off).
+func parseSpoilerWindows(v interface{}) (map[string]int, error) {
+	raw, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("spoiler_windows must be an object of league to hours")
+	}
+	if len(raw) > SpoilerWindowMaxLeagues {
+		return nil, fmt.Errorf("spoiler_windows supports at most %d leagues", SpoilerWindowMaxLeagues)
+	}
+	windows := make(map[string]int, len(raw))
+	for league, hv := range raw {
+		hours, isNum := hv.(float64)
+		if league == "" || !isNum || hours != math.Trunc(hours) || hours < 0 || hours > SpoilerWindowMaxHours {
+			return nil, fmt.Errorf("spoiler_windows values must be whole hours between 0 and %d", SpoilerWindowMaxHours)
+		}
+		if hours > 0 {
+			windows[league] = int(hours)
+		}
+	}
+	return windows, nil
+}
+
+// decodeSpoilerWindows reads the spoiler_windows column; anything
+// unreadable is treated as no windows.
+func decodeSpoilerWindows(b []byte) map[string]int {
+	windows := map[string]int{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &windows); err != nil {
+			return map[string]int{}
+		}
+	}
+	return windows
+}
+
+type spoilerCacheEntry struct {
+	windows map[string]int
+	expires time.Time
+}
+
+// spoilerWindowCache keeps each user's windows in memory for the event
+// fan-out path, which runs once per user per CDC event.
+var spoilerWindowCache sync.Map // userID -> spoilerCacheEntry
+
+// spoilerWindowsFor returns userID's spoiler windows, empty when none.
+func spoilerWindowsFor(ctx context.Context, userID string) map[string]int {
+	if v, ok := spoilerWindowCache.Load(userID); ok {
+		entry := v.(spoilerCacheEntry)
+		if time.Now().Before(entry.expires) {
+			return entry.windows
+		}
+	}
+
+	var raw []byte
+	err := DBPool.QueryRow(ctx,
+		`SELECT spoiler_windows FROM user_preferences WHERE logto_sub = $1`, userID,
+	).Scan(&raw)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Spoilers] Failed to load windows for %s: %v", userID, err)
+		return nil
+	}
+	windows := decodeSpoilerWindows(raw)
+	spoilerWindowCache.Store(userID, spoilerCacheEntry{
+		windows: windows,
+		expires: time.Now().Add(SpoilerWindowCacheTTL),
+	})
+	return windows
+}
+
+// forgetSpoilerWindows drops userID's cached windows. Called on every
+// core-topic event for the user, so all replicas see a preferences
+// change without waiting out the TTL.
+func forgetSpoilerWindows(userID string) {
+	spoilerWindowCache.Delete(userID)
+}
+
+// ─── Filter ──────────────────────────────────────────────────────
+
+// spoilerFilter decides and applies redactions for one user at one
+// instant.
+type spoilerFilter struct {
+	windows map[string]int
+	now     time.Time
+}
+
+func (f spoilerFilter) window(league string) (time.Duration, bool) {
+	hours := f.windows[league]
+	return time.Duration(hours) * time.Hour, hours > 0
+}
+
+// gameSpoiled reports whether a game's score must be hidden: the league
+// has a window and the game is live or ended less than a window ago.
+func (f spoilerFilter) gameSpoiled(game map[string]interface{}) bool {
+	league, _ := game["league"].(string)
+	window, ok := f.window(league)
+	if !ok {
+		return false
+	}
+	switch game["state"] {
+	case "in":
+		return true
+	case "post":
+	default:
+		return false
+	}
+	start, ok := parseSpoilerTime(game["start_time"])
+	if !ok {
+		return true
+	}
+	sport, _ := game["sport"].(string)
+	length, known := sportGameLength[sport]
+	if !known {
+		length = SpoilerDefaultGameLength
+	}
+	return f.now.Before(start.Add(length + window))
+}
+
+// redactGame blanks a game's scores, keeping each field's JSON type:
+// dashboard games carry scores as strings, CDC records as numbers.
+func redactGame(game map[string]interface{}) {
+	for _, field := range gameScoreFields {
+		if _, isStr := game[field].(string); isStr {
+			game[field] = ""
+		} else {
+			game[field] = nil
+		}
+	}
+	game["spoiler_hidden"] = true
+}
+
+// matchupSpoiled reports whether a fantasy matchup's result must be
+// hidden: its week ends at the close of week_end (UTC), then the window
+// runs.
+func (f spoilerFilter) matchupSpoiled(leagueKey string, matchup map[string]interface{}) bool {
+	window, ok := f.window(leagueKey)
+	if !ok {
+		return false
+	}
+	weekEnd, _ := matchup["week_end"].(string)
+	end, err := time.Parse(time.DateOnly, weekEnd)
+	if err != nil {
+		return true
+	}
+	return f.now.Before(end.Add(24*time.Hour + window))
+}
+
+// redactMatchups blanks points and winners in a list of matchups and
+// reports whether any were hidden.
+func (f spoilerFilter) redactMatchups(leagueKey string, matchups []interface{}) bool {
+	hidden := false
+	for _, item := range matchups {
+		m, ok := item.(map[string]interface{})
+		if !ok || !f.matchupSpoiled(leagueKey, m) {
+			continue
+		}
+		hidden = true
+		m["winner_team_key"] = nil
+		m["is_tied"] = false
+		m["spoiler_hidden"] = true
+		teams, _ := m["teams"].([]interface{})
+		for _, t := range teams {
+			if team, ok := t.(map[string]interface{}); ok {
+				team["points"] = nil
+			}
+		}
+	}
+	return hidden
+}
+
+// filterDashboardSpoilers redacts a dashboard's channel data in place.
+// Standings are dropped while last week's result is hidden, since the
+// win/loss columns would give it away.
+func (f spoilerFilter) filterDashboardSpoilers(data map[string]interface{}) {
+	if len(f.windows) == 0 {
+		return
+	}
+	if games, ok := data["sports"].([]interface{}); ok {
+		for _, g := range games {
+			if game, ok := g.(map[string]interface{}); ok && f.gameSpoiled(game) {
+				redactGame(game)
+			}
+		}
+	}
+	fantasy, _ := data["fantasy"].(map[string]interface{})
+	leagues, _ := fantasy["leagues"].([]interface{})
+	for _, l := range leagues {
+		league, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := league["league_key"].(string)
+		current, _ := league["matchups"].([]interface{})
+		previous, _ := league["previous_matchups"].([]interface{})
+		f.redactMatchups(key, current)
+		if f.redactMatchups(key, previous) {
+			delete(league, "standings")
+		}
+	}
+}
+
+// filterEventSpoilers redacts a CDC envelope for one user. It returns
+// the payload to send and false when the event must be withheld
+// entirely. Payloads it doesn't recognize pass through untouched.
+func (f spoilerFilter) filterEventSpoilers(payload []byte) ([]byte, bool) {
+	if len(f.windows) == 0 {
+		return payload, true
+	}
+	var env map[string]interface{}
+	if json.Unmarshal(payload, &env) != nil {
+		return payload, true
+	}
+	records, _ := env["data"].([]interface{})
+	changed := false
+	kept := records[:0]
+	for _, r := range records {
+		rec, _ := r.(map[string]interface{})
+		meta, _ := rec["metadata"].(map[string]interface{})
+		record, _ := rec["record"].(map[string]interface{})
+		if record == nil {
+			kept = append(kept, r)
+			continue
+		}
+		switch meta["table_name"] {
+		case "games":
+			if f.gameSpoiled(record) {
+				redactGame(record)
+				if changes, ok := rec["changes"].(map[string]interface{}); ok {
+					for _, field := range gameScoreFields {
+						delete(changes, field)
+					}
+				}
+				changed = true
+			}
+		case "yahoo_matchups":
+			key, _ := record["league_key"].(string)
+			matchups, _ := record["data"].([]interface{})
+			if f.redactMatchups(key, matchups) {
+				delete(rec, "changes")
+				changed = true
+			}
+		case "yahoo_standings":
+			// A standings row carries no week; while the league has a
+			// window it waits for the next dashboard fetch, which does.
+			key, _ := record["league_key"].(string)
+			if _, ok := f.window(key); ok {
+				changed = true
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	if !changed {
+		return payload, true
+	}
+	if len(kept) == 0 {
+		return nil, false
+	}
+	env["data"] = kept
+	out, err := json.Marshal(env)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// parseSpoilerTime reads a start_time from a dashboard game (RFC 3339)
+// or a CDC record (Postgres text timestamp).
+func parseSpoilerTime(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// userSpoilerFilter builds userID's filter for the current instant.
+func userSpoilerFilter(ctx context.Context, userID string) spoilerFilter {
+	return spoilerFilter{windows: spoilerWindowsFor(ctx, userID), now: time.Now()}
+}
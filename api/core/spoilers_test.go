@@ -0,0 +1,144 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseSpoilerWindows(t *testing.T) {
+	windows, err := parseSpoilerWindows(map[string]interface{}{"NFL": float64(12), "NBA": float64(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 || windows["NFL"] != 12 {
+		t.Errorf("windows = %v, want only NFL: 12", windows)
+	}
+
+	for name, v := range map[string]interface{}{
+		"not an object": []interface{}{"NFL"},
+		"fractional":    map[string]interface{}{"NFL": 1.5},
+		"negative":      map[string]interface{}{"NFL": float64(-1)},
+		"too long":      map[string]interface{}{"NFL": float64(SpoilerWindowMaxHours + 1)},
+		"string hours":  map[string]interface{}{"NFL": "12"},
+		"empty league":  map[string]interface{}{"": float64(1)},
+	} {
+		if _, err := parseSpoilerWindows(v); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGameSpoiled(t *testing.T) {
+	now := time.Date(2026, 10, 12, 22, 0, 0, 0, time.UTC)
+	f := spoilerFilter{windows: map[string]int{"NFL": 2}, now: now}
+	game := func(state string, start time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"league":     "NFL",
+			"sport":      "american-football",
+			"state":      state,
+			"start_time": start.Format(time.RFC3339),
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		game map[string]interface{}
+		want bool
+	}{
+		{"live", game("in", now.Add(-time.Hour)), true},
+		{"upcoming", game("pre", now.Add(time.Hour)), false},
+		// Ends ~4h after start, hidden for 2h more
+		{"final within window", game("post", now.Add(-5*time.Hour)), true},
+		{"final past window", game("post", now.Add(-7*time.Hour)), false},
+		{"other league", map[string]interface{}{"league": "NBA", "state": "in"}, false},
+		{"final without start time", map[string]interface{}{"league": "NFL", "state": "post"}, true},
+	} {
+		if got := f.gameSpoiled(tc.game); got != tc.want {
+			t.Errorf("%s: gameSpoiled = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFilterDashboardSpoilers(t *testing.T) {
+	now := time.Date(2026, 10, 13, 12, 0, 0, 0, time.UTC)
+	f := spoilerFilter{windows: map[string]int{"NFL": 12, "423.l.1": 24}, now: now}
+
+	var data map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"sports": [
+			{"league": "NFL", "state": "in", "home_team_score": "7", "away_team_score": "3"},
+			{"league": "NBA", "state": "in", "home_team_score": "50", "away_team_score": "48"}
+		],
+		"fantasy": {"leagues": [{
+			"league_key": "423.l.1",
+			"standings": [{"wins": 3}],
+			"matchups": [{"week_end": "2026-10-19", "winner_team_key": null, "teams": [{"points": 40.5}]}],
+			"previous_matchups": [{"week_end": "2026-10-12", "winner_team_key": "423.l.1.t.2", "teams": [{"points": 101.2}]}]
+		}]}
+	}`), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.filterDashboardSpoilers(data)
+
+	games := data["sports"].([]interface{})
+	nfl, nba := games[0].(map[string]interface{}), games[1].(map[string]interface{})
+	if nfl["home_team_score"] != "" || nfl["spoiler_hidden"] != true {
+		t.Errorf("NFL game not redacted: %v", nfl)
+	}
+	if nba["home_team_score"] != "50" {
+		t.Errorf("NBA game redacted without a window: %v", nba)
+	}
+
+	league := data["fantasy"].(map[string]interface{})["leagues"].([]interface{})[0].(map[string]interface{})
+	prev := league["previous_matchups"].([]interface{})[0].(map[string]interface{})
+	if prev["winner_team_key"] != nil || prev["teams"].([]interface{})[0].(map[string]interface{})["points"] != nil {
+		t.Errorf("previous matchup not redacted: %v", prev)
+	}
+	if _, ok := league["standings"]; ok {
+		t.Error("standings kept while last week's result is hidden")
+	}
+}
+
+func TestFilterEventSpoilers(t *testing.T) {
+	f := spoilerFilter{windows: map[string]int{"NFL": 12, "423.l.1": 24}, now: time.Now()}
+
+	game := []byte(`{"seq": 9, "data": [{"action": "update",
+		"record": {"league": "NFL", "state": "in", "home_team_score": 7, "away_team_score": 3},
+		"changes": {"home_team_score": 0},
+		"metadata": {"table_name": "games"}}]}`)
+	out, ok := f.filterEventSpoilers(game)
+	if !ok {
+		t.Fatal("game event withheld, want redacted")
+	}
+	var env struct {
+		Seq  int64 `json:"seq"`
+		Data []struct {
+			Record  map[string]interface{} `json:"record"`
+			Changes map[string]interface{} `json:"changes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(out, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Seq != 9 {
+		t.Errorf("seq = %d, want 9", env.Seq)
+	}
+	if rec := env.Data[0].Record; rec["home_team_score"] != nil || rec["spoiler_hidden"] != true {
+		t.Errorf("record not redacted: %v", rec)
+	}
+	if _, ok := env.Data[0].Changes["home_team_score"]; ok {
+		t.Error("old score left in changes")
+	}
+
+	standings := []byte(`{"data": [{"record": {"league_key": "423.l.1"}, "metadata": {"table_name": "yahoo_standings"}}]}`)
+	if _, ok := f.filterEventSpoilers(standings); ok {
+		t.Error("standings event for a windowed league was forwarded")
+	}
+
+	trade := []byte(`{"data": [{"record": {"symbol": "AAPL"}, "metadata": {"table_name": "trades"}}]}`)
+	if out, ok := f.filterEventSpoilers(trade); !ok || string(out) != string(trade) {
+		t.Error("unrelated event was modified")
+	}
+}
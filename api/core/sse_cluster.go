@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Multi-replica SSE.
+//
+// Every gateway replica PSubscribes to every CDC topic, so an event
+// reaches whichever replica holds the client; no sticky sessions are
+// needed. Two pieces of per-replica state are shared through Redis:
+//
+//   - Client counts. Each replica writes its count to the
+//     RedisSSEClientsKey hash (field = replica, value = "count:unix")
+//     every SSEClientCountInterval. /events/count sums the entries
+//     reported within SSEClientCountStale, so a replica that died
+//     without cleaning up drops out on its own.
+//   - Topic refreshes. UpdateUserTopicSubscriptions publishes the user
+//     on TopicRefreshUserTopics, and each replica holding one of their
+//     connections re-reads their topics (listenToTopics).
+
+// sseInstanceID names this replica in RedisSSEClientsKey: the pod name
+// plus a random suffix, so a restarted pod doesn't inherit its
+// predecessor's entry.
+var sseInstanceID = func() string {
+	host, _ := os.Hostname()
+	return host + ":" + uuid.NewString()[:8]
+}()
+
+// StartClientCountReporter publishes this replica's client count until
+// ctx ends, then removes it.
+func StartClientCountReporter(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(SSEClientCountInterval)
+		defer ticker.Stop()
+		reportClientCount(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				cleanup, cancel := context.WithTimeout(context.Background(), time.Second)
+				Rdb.HDel(cleanup, RedisSSEClientsKey, sseInstanceID)
+				cancel()
+				return
+			case <-ticker.C:
+				reportClientCount(ctx)
+			}
+		}
+	}()
+}
+
+func reportClientCount(ctx context.Context) {
+	value := strconv.Itoa(ClientCount()) + ":" + strconv.FormatInt(time.Now().Unix(), 10)
+	if err := Rdb.HSet(ctx, RedisSSEClientsKey, sseInstanceID, value).Err(); err != nil {
+		log.Printf("[EventHub] Failed to report client count: %v", err)
+	}
+}
+
+// TotalClientCount returns the number of connected SSE/WebSocket clients
+// across all gateway replicas. Stale replica entries are skipped and
+// removed. Falls back to this replica's count if Redis can't be read.
+func TotalClientCount(ctx context.Context) int {
+	entries, err := Rdb.HGetAll(ctx, RedisSSEClientsKey).Result()
+	if err != nil {
+		return ClientCount()
+	}
+	total, stale := sumClientCounts(entries, sseInstanceID, time.Now())
+	// This replica's entry may be up to an interval old; count it live.
+	total += ClientCount()
+	if len(stale) > 0 {
+		Rdb.HDel(ctx, RedisSSEClientsKey, stale...)
+	}
+	return total
+}
+
+// sumClientCounts adds up the fresh entries of RedisSSEClientsKey other
+// than self, and lists the stale ones.
+func sumClientCounts(entries map[string]string, self string, now time.Time) (int, []string) {
+	total := 0
+	var stale []string
+	for instance, value := range entries {
+		if instance == self {
+			continue
+		}
+		countStr, tsStr, _ := strings.Cut(value, ":")
+		count, err1 := strconv.Atoi(countStr)
+		ts, err2 := strconv.ParseInt(tsStr, 10, 64)
+		if err1 != nil || err2 != nil || now.Sub(time.Unix(ts, 0)) > SSEClientCountStale {
+			stale = append(stale, instance)
+			continue
+		}
+		total += count
+	}
+	return total, stale
+}
+
+// refreshLocalUserTopics handles a TopicRefreshUserTopics message: if
+// the user has connections on this replica, their topics are re-read.
+func (h *Hub) refreshLocalUserTopics(userID string) {
+	if _, ok := h.clients.Load(userID); !ok {
+		return
+	}
+	BackgroundPool.SubmitOrRun("subscribe-topics", func(context.Context) {
+		subscribeUserToTopics(userID)
+	})
+}
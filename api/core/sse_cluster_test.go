@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSumClientCounts(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	fresh := strconv.FormatInt(now.Add(-5*time.Second).Unix(), 10)
+	old := strconv.FormatInt(now.Add(-SSEClientCountStale-time.Second).Unix(), 10)
+	entries := map[string]string{
+		"self":    "7:" + fresh,
+		"pod-a":   "3:" + fresh,
+		"pod-b":   "4:" + fresh,
+		"pod-old": "9:" + old,
+		"pod-bad": "garbage",
+	}
+
+	total, stale := sumClientCounts(entries, "self", now)
+	if total != 7 {
+		t.Errorf("total = %d, want 7 (pod-a + pod-b)", total)
+	}
+	if len(stale) != 2 {
+		t.Errorf("stale = %v, want pod-old and pod-bad", stale)
+	}
+}
+
+func TestTotalClientCount(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	prevHub := globalHub
+	globalHub = &Hub{registry: &topicRegistry{}}
+	defer func() { globalHub = prevHub }()
+
+	globalHub.register(&Client{UserID: "a", Ch: make(chan []byte, 1)})
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	mr.HSet(RedisSSEClientsKey, "other-pod", "5:"+now)
+	mr.HSet(RedisSSEClientsKey, "dead-pod", "9:1")
+
+	if got := TotalClientCount(context.Background()); got != 6 {
+		t.Errorf("TotalClientCount = %d, want 6", got)
+	}
+	if mr.HGet(RedisSSEClientsKey, "dead-pod") != "" {
+		t.Error("stale replica entry was not removed")
+	}
+}
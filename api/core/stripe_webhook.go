@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,10 +20,31 @@ import (
 // Stripe Webhook Handler
 // =============================================================================
 
+// webhookTolerance returns the signature timestamp tolerance. Invalid or
+// out-of-range values fall back to the default rather than loosening
+// replay protection.
+func webhookTolerance() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("STRIPE_WEBHOOK_TOLERANCE_SECONDS"))
+	if raw == "" {
+		return StripeWebhookTolerance * time.Second
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > StripeWebhookMaxTolerance {
+		log.Printf("[Stripe Webhook] Invalid STRIPE_WEBHOOK_TOLERANCE_SECONDS %q, using %d", raw, StripeWebhookTolerance)
+		return StripeWebhookTolerance * time.Second
+	}
+	return time.Duration(n) * time.Second
+}
+
 // HandleStripeWebhook receives Stripe webhook events, verifies signatures,
 // and dispatches to the appropriate handler.
+//
+// Each event ID is claimed in stripe_webhook_events before dispatch and
+// marked processed after, so a retried delivery is acknowledged without
+// re-applying it. A claim still unprocessed after StripeWebhookClaimTimeout
+// is assumed abandoned and the retry takes it over.
 func HandleStripeWebhook(c *fiber.Ctx) error {
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	webhookSecret := Secret("STRIPE_WEBHOOK_SECRET")
 	if webhookSecret == "" {
 		log.Println("[Stripe Webhook] STRIPE_WEBHOOK_SECRET not set")
 		return c.SendStatus(fiber.StatusInternalServerError)
@@ -33,28 +56,38 @@ func HandleStripeWebhook(c *fiber.Ctx) error {
 	event, err := webhook.ConstructEventWithOptions(payload, sigHeader, webhookSecret,
 		webhook.ConstructEventOptions{
 			IgnoreAPIVersionMismatch: true,
-			Tolerance:                StripeWebhookTolerance * time.Second,
+			Tolerance:                webhookTolerance(),
 		})
 	if err != nil {
 		log.Printf("[Stripe Webhook] Signature verification failed: %v", err)
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	// Idempotency: atomically claim the event via INSERT ... ON CONFLICT DO NOTHING
-	// RETURNING. If RETURNING yields a row, this worker owns the event and should
-	// process it. If it yields no row, another concurrent worker already claimed
-	// it — skip to avoid double-processing. This collapses the previous
-	// check-then-insert flow (which had a race window between the EXISTS probe
-	// and the INSERT) into a single atomic statement.
+	// Idempotency: atomically claim the event via INSERT ... ON CONFLICT
+	// RETURNING. If RETURNING yields a row, this worker owns the event and
+	// should process it: either it's new, or an earlier claim went stale
+	// without finishing. No row means the event was processed already, or
+	// another worker is on it right now.
 	var claimedID string
 	claimErr := DBPool.QueryRow(context.Background(),
-		`INSERT INTO stripe_webhook_events (event_id) VALUES ($1)
-		 ON CONFLICT (event_id) DO NOTHING
+		`INSERT INTO stripe_webhook_events (event_id, event_type) VALUES ($1, $2)
+		 ON CONFLICT (event_id) DO UPDATE SET created_at = now()
+		   WHERE stripe_webhook_events.processed_at IS NULL
+		     AND stripe_webhook_events.created_at < now() - make_interval(secs => $3)
 		 RETURNING event_id`,
-		event.ID,
+		event.ID, string(event.Type), StripeWebhookClaimTimeout.Seconds(),
 	).Scan(&claimedID)
 	if claimErr == pgx.ErrNoRows {
-		// Another worker already claimed this event. Skip.
+		var processed bool
+		if err := DBPool.QueryRow(context.Background(),
+			`SELECT processed_at IS NOT NULL FROM stripe_webhook_events WHERE event_id = $1`,
+			event.ID,
+		).Scan(&processed); err == nil && !processed {
+			// In flight on another worker. A non-2xx makes Stripe retry
+			// later, by which point it's either processed or reclaimable.
+			log.Printf("[Stripe Webhook] Event %s (type: %s) in flight elsewhere, asking Stripe to retry", event.ID, event.Type)
+			return c.SendStatus(fiber.StatusConflict)
+		}
 		log.Printf("[Stripe Webhook] Skipping duplicate event %s (type: %s)", event.ID, event.Type)
 		return c.SendStatus(fiber.StatusOK)
 	}
@@ -72,9 +105,13 @@ func HandleStripeWebhook(c *fiber.Ctx) error {
 	case "customer.subscription.deleted":
 		handleSubscriptionDeleted(event)
 	case "invoice.paid":
+		cacheInvoiceFromEvent(event)
 		handleInvoicePaid(event)
 	case "invoice.payment_failed":
+		cacheInvoiceFromEvent(event)
 		handleInvoicePaymentFailed(event)
+	case "invoice.finalized", "invoice.updated", "invoice.voided", "invoice.marked_uncollectible":
+		cacheInvoiceFromEvent(event)
 	case "customer.subscription.trial_will_end":
 		handleTrialWillEnd(event)
 	case "payment_intent.succeeded":
@@ -83,8 +120,14 @@ func HandleStripeWebhook(c *fiber.Ctx) error {
 		log.Printf("[Stripe Webhook] Unhandled event type: %s", event.Type)
 	}
 
-	// Event slot was already claimed atomically above via INSERT ... ON CONFLICT
-	// RETURNING. No second write needed.
+	if claimErr == nil {
+		if _, err := DBPool.Exec(context.Background(),
+			`UPDATE stripe_webhook_events SET processed_at = now() WHERE event_id = $1`,
+			event.ID,
+		); err != nil {
+			log.Printf("[Stripe Webhook] Failed to mark event %s processed: %v", event.ID, err)
+		}
+	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -175,6 +218,10 @@ func handleCheckoutCompleted(event stripe.Event) {
 		}
 	}
 
+	Audit(context.Background(), AuditActorSystem, AuditBillingSubscribed, logtoSub, map[string]any{
+		"plan": plan, "status": subStatus,
+	})
+
 	// Subscription state changed — overview's tier + subscription
 	// blocks are now stale.
 	InvalidateOverviewCache(context.Background(), logtoSub)
@@ -203,10 +250,14 @@ func handleSubscriptionUpdated(event stripe.Event) {
 	}
 	periodEnd := time.Unix(periodEndUnix, 0)
 
-	// Determine plan from the first line item
+	// Determine plan and seat count from the first line item
 	plan := "unknown"
+	seats := int64(1)
 	if sub.Items != nil && len(sub.Items.Data) > 0 {
 		plan = planFromPriceID(sub.Items.Data[0].Price.ID)
+		if q := sub.Items.Data[0].Quantity; q > 0 {
+			seats = q
+		}
 	}
 
 	log.Printf("[Stripe Webhook] Subscription updated: user=%s status=%s plan=%s cancel_at_period_end=%v",
@@ -219,10 +270,10 @@ func handleSubscriptionUpdated(event stripe.Event) {
 
 	_, err := DBPool.Exec(context.Background(),
 		`UPDATE stripe_customers SET
-		   plan = $2, status = $3, current_period_end = $4,
-		   stripe_subscription_id = $5, updated_at = now()
+		   plan = $2, status = $3, current_period_end = GREATEST($4, comp_until),
+		   stripe_subscription_id = $5, seats = $6, updated_at = now()
 		 WHERE logto_sub = $1`,
-		logtoSub, plan, dbStatus, periodEnd, sub.ID,
+		logtoSub, plan, dbStatus, periodEnd, sub.ID, seats,
 	)
 	if err != nil {
 		log.Printf("[Stripe Webhook] Failed to update subscription for %s: %v", logtoSub, err)
@@ -278,6 +329,8 @@ func handleSubscriptionUpdated(event stripe.Event) {
 	// failed Logto call doesn't block the prune.
 	if newTier != "" {
 		PruneUserChannelsForTier(context.Background(), logtoSub, newTier)
+		// Team members share the owner's tier.
+		SyncTeamMemberTiers(context.Background(), logtoSub, newTier)
 	}
 
 	// Tier and subscription fields in the overview response just changed.
@@ -300,6 +353,19 @@ func handleSubscriptionDeleted(event stripe.Event) {
 
 	log.Printf("[Stripe Webhook] Subscription deleted: user=%s", logtoSub)
 
+	// Complimentary time granted by an admin outlives the Stripe
+	// subscription; the comp-time worker downgrades when it runs out.
+	if deferForCompTime(context.Background(), logtoSub) {
+		return
+	}
+
+	downgradeToFree(logtoSub)
+	Audit(context.Background(), AuditActorSystem, AuditBillingEnded, logtoSub, nil)
+}
+
+// downgradeToFree resets a user's billing row to the free plan, removes
+// paid roles, and dissolves their team. Lifetime rows are left alone.
+func downgradeToFree(logtoSub string) {
 	// Check if user has lifetime (don't remove role if so)
 	var isLifetime bool
 	_ = DBPool.QueryRow(context.Background(),
@@ -310,7 +376,9 @@ func handleSubscriptionDeleted(event stripe.Event) {
 	_, err := DBPool.Exec(context.Background(),
 		`UPDATE stripe_customers SET
 		   plan = 'free', status = 'canceled', stripe_subscription_id = NULL,
-		   current_period_end = NULL, updated_at = now()
+		   current_period_end = NULL, payment_failed_at = NULL, grace_period_ends_at = NULL,
+		   failed_invoice_id = NULL, entitlements_suspended_at = NULL, comp_until = NULL,
+		   updated_at = now()
 		 WHERE logto_sub = $1 AND lifetime = false`,
 		logtoSub,
 	)
@@ -334,6 +402,9 @@ func handleSubscriptionDeleted(event stripe.Event) {
 		PruneUserChannelsForTier(context.Background(), logtoSub, "free")
 	}
 
+	// Seats belong to the subscription, so its members go with it.
+	DissolveTeam(context.Background(), logtoSub)
+
 	// Subscription went away (or downgraded to free) — overview is stale.
 	InvalidateOverviewCache(context.Background(), logtoSub)
 }
@@ -383,14 +454,21 @@ func handleInvoicePaid(event stripe.Event) {
 			log.Printf("[Stripe Webhook] Failed to re-assign uplink role to %s: %v", logtoSub, err)
 		}
 	}
+
+	// Close out any dunning cycle; restores team members if the grace
+	// period had lapsed.
+	handleDunningRecovered(context.Background(), logtoSub, currentPlan)
 }
 
 // handleInvoicePaymentFailed handles failed subscription payments.
 func handleInvoicePaymentFailed(event stripe.Event) {
 	var invoice struct {
-		Customer     string `json:"customer"`
-		Subscription string `json:"subscription"`
-		AttemptCount int    `json:"attempt_count"`
+		ID               string `json:"id"`
+		Customer         string `json:"customer"`
+		Subscription     string `json:"subscription"`
+		AttemptCount     int    `json:"attempt_count"`
+		CustomerEmail    string `json:"customer_email"`
+		HostedInvoiceURL string `json:"hosted_invoice_url"`
 	}
 	if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
 		log.Printf("[Stripe Webhook] Failed to parse invoice.payment_failed: %v", err)
@@ -404,12 +482,10 @@ func handleInvoicePaymentFailed(event stripe.Event) {
 
 	log.Printf("[Stripe Webhook] Payment failed for user=%s (attempt %d)", logtoSub, invoice.AttemptCount)
 
-	// Mark as past_due in our DB
-	_, _ = DBPool.Exec(context.Background(),
-		`UPDATE stripe_customers SET status = 'past_due', updated_at = now()
-		 WHERE logto_sub = $1 AND lifetime = false`,
-		logtoSub,
-	)
+	// Mark as past_due and open the grace period. Roles stay until it
+	// lapses; the dunning worker handles the downgrade.
+	handleDunningPaymentFailed(context.Background(), logtoSub,
+		invoice.ID, invoice.CustomerEmail, invoice.HostedInvoiceURL)
 }
 
 // handleTrialWillEnd is fired ~3 days before a trial expires.
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v82/webhook"
+)
+
+func TestWebhookTolerance(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", StripeWebhookTolerance * time.Second},
+		{"120", 120 * time.Second},
+		{" 600 ", 600 * time.Second},
+		{"0", StripeWebhookTolerance * time.Second},
+		{"-5", StripeWebhookTolerance * time.Second},
+		{"abc", StripeWebhookTolerance * time.Second},
+		{"86400", StripeWebhookTolerance * time.Second},
+	}
+	for _, tt := range tests {
+		t.Setenv("STRIPE_WEBHOOK_TOLERANCE_SECONDS", tt.env)
+		if got := webhookTolerance(); got != tt.want {
+			t.Errorf("webhookTolerance(%q) = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestHandleStripeWebhookRejects(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","object":"event","type":"invoice.paid"}`)
+
+	fresh := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload, Secret: secret, Timestamp: time.Now(),
+	})
+	stale := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload, Secret: secret, Timestamp: time.Now().Add(-time.Hour),
+	})
+	forged := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: payload, Secret: "whsec_other", Timestamp: time.Now(),
+	})
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   int
+	}{
+		{"secret unset", "", fresh.Header, fiber.StatusInternalServerError},
+		{"missing signature", secret, "", fiber.StatusBadRequest},
+		{"wrong secret", secret, forged.Header, fiber.StatusBadRequest},
+		{"replayed timestamp", secret, stale.Header, fiber.StatusBadRequest},
+	}
+
+	app := fiber.New()
+	app.Post("/webhook", HandleStripeWebhook)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STRIPE_WEBHOOK_SECRET", tt.secret)
+			req := httptest.NewRequest("POST", "/webhook", strings.NewReader(string(payload)))
+			req.Header.Set("Stripe-Signature", tt.header)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
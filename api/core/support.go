@@ -474,7 +474,7 @@ func forwardToOSTicket(ctx context.Context, payload OSTicketPayload) (string, er
 // reply flow (postOSTicketReply in support_drafts.go).
 func postOSTicketJSON(ctx context.Context, path string, payloadBytes []byte) (int, []byte, error) {
 	osTicketURL := os.Getenv("OSTICKET_URL")
-	apiKeysRaw := os.Getenv("OSTICKET_API_KEY")
+	apiKeysRaw := Secret("OSTICKET_API_KEY")
 	if osTicketURL == "" || apiKeysRaw == "" {
 		log.Println("[Support] OSTICKET_URL or OSTICKET_API_KEY not configured")
 		return 0, nil, errOSTicketNotConfigured
@@ -482,7 +482,6 @@ func postOSTicketJSON(ctx context.Context, path string, payloadBytes []byte) (in
 
 	fullURL := strings.TrimSuffix(osTicketURL, "/") + path
 	apiKeys := strings.Split(apiKeysRaw, ",")
-	client := &http.Client{Timeout: 15 * time.Second}
 
 	var lastStatus int
 	var lastBody []byte
@@ -501,7 +500,7 @@ func postOSTicketJSON(ctx context.Context, path string, payloadBytes []byte) (in
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("X-API-Key", key)
 
-		resp, err := client.Do(httpReq)
+		resp, err := osTicketHTTPClient.Do(httpReq)
 		if err != nil {
 			log.Printf("[Support] OS Ticket request failed (key %d): %v", i+1, err)
 			continue
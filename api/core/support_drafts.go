@@ -335,7 +335,7 @@ func SendPartnerNotification(ctx context.Context, draft *SupportDraft) error {
 		return nil
 	}
 
-	sendURL, editURL, skipURL, err := buildApprovalURLs(draft.ID)
+	sendURL, editURL, skipURL, err := buildApprovalURLs(ctx, draft.ID)
 	if err != nil {
 		return fmt.Errorf("build approval URLs: %w", err)
 	}
@@ -403,7 +403,7 @@ func SendPartnerNotification(ctx context.Context, draft *SupportDraft) error {
 		html.EscapeString(draft.OriginalSubject),
 	)
 
-	resendKey := os.Getenv("RESEND_API_KEY")
+	resendKey := Secret("RESEND_API_KEY")
 	if resendKey == "" {
 		return fmt.Errorf("RESEND_API_KEY not set")
 	}
@@ -436,8 +436,7 @@ func SendPartnerNotification(ctx context.Context, draft *SupportDraft) error {
 	req.Header.Set("Authorization", "Bearer "+resendKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := emailHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("resend send: %w", err)
 	}
@@ -0,0 +1,916 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/stripe/stripe-go/v82"
+	stripesubscription "github.com/stripe/stripe-go/v82/subscription"
+)
+
+// Team / family seats.
+//
+// An owner with a recurring subscription buys extra seats (PUT
+// /users/me/team/seats, which sets the Stripe subscription item
+// quantity) and invites members by email. A member who accepts gets the
+// owner's plan roles in Logto; the Stripe webhook re-syncs member roles
+// whenever the owner's plan changes and demotes them when it ends.
+//
+// Seat accounting: the owner holds one seat, every member and every
+// pending invitation holds one more. Seats can't drop below that count.
+// Inviting and changing seats both count under a row lock on the
+// owner's stripe_customers row (lockTeamOwnerTx).
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// TeamMember is one accepted member of an owner's team.
+type TeamMember struct {
+	MemberSub string    `json:"member_sub"`
+	Email     string    `json:"email"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// TeamInvitation is a pending invitation. The token is never returned
+// after creation.
+type TeamInvitation struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TeamResponse is the body for GET /users/me/team. Role is "owner",
+// "member", or "none"; only owners see the seat and invitation details.
+type TeamResponse struct {
+	Role        string           `json:"role"`
+	OwnerSub    string           `json:"owner_sub,omitempty"`
+	Plan        string           `json:"plan,omitempty"`
+	Seats       int              `json:"seats,omitempty"`
+	SeatsUsed   int              `json:"seats_used,omitempty"`
+	MaxSeats    int              `json:"max_seats,omitempty"`
+	Members     []TeamMember     `json:"members,omitempty"`
+	Invitations []TeamInvitation `json:"invitations,omitempty"`
+	JoinedAt    *time.Time       `json:"joined_at,omitempty"`
+}
+
+// teamOwner is the billing state an owner needs for team operations.
+type teamOwner struct {
+	Plan           string
+	Status         string
+	SubscriptionID string
+	Seats          int
+}
+
+var (
+	errNotTeamOwner  = errors.New("no active subscription")
+	errInviteInvalid = errors.New("invitation invalid or expired")
+)
+
+// ─── Pure helpers ────────────────────────────────────────────────
+
+// tierForPlan maps a stripe_customers plan to the Logto tier its
+// subscribers get. Matches the role assignment in the Stripe webhook.
+func tierForPlan(plan string) string {
+	switch {
+	case plan == "" || plan == "free" || plan == "unknown":
+		return "free"
+	case plan == "lifetime" || isUltimatePlan(plan):
+		return "uplink_ultimate"
+	case isProPlan(plan):
+		return "uplink_pro"
+	default:
+		return "uplink"
+	}
+}
+
+// memberTierFor returns the tier members of a team get given the
+// owner's subscription. Trials grant Ultimate, as they do for the owner.
+func memberTierFor(status, plan string) string {
+	switch status {
+	case "trialing":
+		return "uplink_ultimate"
+	case "active", "canceling", "past_due":
+		return tierForPlan(plan)
+	}
+	return "free"
+}
+
+// ownerCanHostTeam reports whether a subscription state supports seats.
+// Canceling subscriptions keep existing members but can't grow.
+func ownerCanHostTeam(status, subscriptionID string) bool {
+	return subscriptionID != "" && (status == "active" || status == "trialing")
+}
+
+// validateSeatCount checks a requested seat total against the hard cap
+// and the seats already in use.
+func validateSeatCount(seats, used int) error {
+	if seats < 1 || seats > MaxTeamSeats {
+		return fmt.Errorf("seats must be between 1 and %d", MaxTeamSeats)
+	}
+	if seats < used {
+		return fmt.Errorf("%d seats are in use — remove members or revoke invitations first", used)
+	}
+	return nil
+}
+
+// normalizeInviteEmail lowercases and validates an invite address.
+func normalizeInviteEmail(raw string) (string, bool) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(raw))
+	if err != nil || addr.Name != "" || !strings.Contains(addr.Address, ".") {
+		return "", false
+	}
+	return strings.ToLower(addr.Address), true
+}
+
+func generateTeamInviteToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashTeamInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ─── Queries ─────────────────────────────────────────────────────
+
+func loadTeamOwner(ctx context.Context, ownerSub string) (*teamOwner, error) {
+	return scanTeamOwner(DBPool.QueryRow(ctx,
+		`SELECT plan, status, stripe_subscription_id, seats
+		 FROM stripe_customers WHERE logto_sub = $1`, ownerSub))
+}
+
+// lockTeamOwnerTx loads the owner and holds their stripe_customers row
+// until tx ends. Every seat-changing write takes this lock before
+// counting, so two invitations (or an invitation and a seat decrease)
+// can't both pass the check against the same count.
+func lockTeamOwnerTx(ctx context.Context, tx pgx.Tx, ownerSub string) (*teamOwner, error) {
+	return scanTeamOwner(tx.QueryRow(ctx,
+		`SELECT plan, status, stripe_subscription_id, seats
+		 FROM stripe_customers WHERE logto_sub = $1 FOR UPDATE`, ownerSub))
+}
+
+func scanTeamOwner(row pgx.Row) (*teamOwner, error) {
+	var o teamOwner
+	var subID *string
+	err := row.Scan(&o.Plan, &o.Status, &subID, &o.Seats)
+	if err == pgx.ErrNoRows {
+		return nil, errNotTeamOwner
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subID != nil {
+		o.SubscriptionID = *subID
+	}
+	return &o, nil
+}
+
+// teamSeatsUsedTx counts the owner's seat plus members and pending,
+// unexpired invitations as seen by tx.
+func teamSeatsUsedTx(ctx context.Context, tx pgx.Tx, ownerSub string) (int, error) {
+	var used int
+	err := tx.QueryRow(ctx, `
+		SELECT 1
+		     + (SELECT COUNT(*) FROM team_members WHERE owner_sub = $1)
+		     + (SELECT COUNT(*) FROM team_invitations
+		         WHERE owner_sub = $1 AND status = 'pending' AND expires_at > now())`,
+		ownerSub,
+	).Scan(&used)
+	return used, err
+}
+
+func listTeamMemberSubs(ctx context.Context, ownerSub string) ([]string, error) {
+	rows, err := DBPool.Query(ctx,
+		`SELECT member_sub FROM team_members WHERE owner_sub = $1`, ownerSub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var subs []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// hasOwnPaidPlan reports whether a user has their own live subscription
+// or lifetime purchase, in which case team membership never touches
+// their roles.
+func hasOwnPaidPlan(ctx context.Context, logtoSub string) bool {
+	var paid bool
+	_ = DBPool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM stripe_customers
+			 WHERE logto_sub = $1
+			   AND (lifetime OR (plan != 'free' AND status IN ('active', 'trialing', 'canceling', 'past_due'))))`,
+		logtoSub,
+	).Scan(&paid)
+	return paid
+}
+
+// ─── Role sync ───────────────────────────────────────────────────
+
+// applyMemberTier sets a member's Logto roles to exactly the given tier
+// ("free" removes all paid roles) and trims their channel configs to
+// match. Members with their own paid plan are left alone.
+func applyMemberTier(ctx context.Context, memberSub, tier string) {
+	if hasOwnPaidPlan(ctx, memberSub) {
+		return
+	}
+	if err := RemoveUplinkRole(memberSub); err != nil {
+		log.Printf("[Team] Failed to remove uplink role from %s: %v", memberSub, err)
+	}
+	if err := RemoveProRole(memberSub); err != nil {
+		log.Printf("[Team] Failed to remove uplink_pro role from %s: %v", memberSub, err)
+	}
+	if err := RemoveUltimateRole(memberSub); err != nil {
+		log.Printf("[Team] Failed to remove uplink_ultimate role from %s: %v", memberSub, err)
+	}
+
+	var err error
+	switch tier {
+	case "uplink_ultimate":
+		err = AssignUltimateRole(memberSub)
+	case "uplink_pro":
+		err = AssignProRole(memberSub)
+	case "uplink":
+		err = AssignUplinkRole(memberSub)
+	}
+	if err != nil {
+		log.Printf("[Team] Failed to assign %s role to %s: %v", tier, memberSub, err)
+	}
+
+	PruneUserChannelsForTier(ctx, memberSub, tier)
+}
+
+// SyncTeamMemberTiers pushes the owner's current tier to every member.
+// Called from the Stripe webhook after the owner's roles change.
+func SyncTeamMemberTiers(ctx context.Context, ownerSub, tier string) {
+	members, err := listTeamMemberSubs(ctx, ownerSub)
+	if err != nil {
+		log.Printf("[Team] Failed to list members of %s: %v", ownerSub, err)
+		return
+	}
+	for _, m := range members {
+		applyMemberTier(ctx, m, tier)
+	}
+	if len(members) > 0 {
+		log.Printf("[Team] Synced %d member(s) of %s to tier %s", len(members), ownerSub, tier)
+	}
+}
+
+// DissolveTeam demotes every member to free and drops the team rows.
+// Called when the owner's subscription ends.
+func DissolveTeam(ctx context.Context, ownerSub string) {
+	SyncTeamMemberTiers(ctx, ownerSub, "free")
+	if _, err := DBPool.Exec(ctx,
+		`DELETE FROM team_members WHERE owner_sub = $1`, ownerSub); err != nil {
+		log.Printf("[Team] Failed to delete members of %s: %v", ownerSub, err)
+	}
+	if _, err := DBPool.Exec(ctx,
+		`UPDATE team_invitations SET status = 'revoked' WHERE owner_sub = $1 AND status = 'pending'`,
+		ownerSub); err != nil {
+		log.Printf("[Team] Failed to revoke invitations of %s: %v", ownerSub, err)
+	}
+	if _, err := DBPool.Exec(ctx,
+		`UPDATE stripe_customers SET seats = 1 WHERE logto_sub = $1`, ownerSub); err != nil {
+		log.Printf("[Team] Failed to reset seats for %s: %v", ownerSub, err)
+	}
+}
+
+// setSubscriptionSeats sets the quantity on the subscription's single
+// line item, prorating the change.
+func setSubscriptionSeats(subscriptionID string, seats int) error {
+	sub, err := stripesubscription.Get(subscriptionID, nil)
+	if err != nil {
+		return fmt.Errorf("get subscription: %w", err)
+	}
+	if sub.Items == nil || len(sub.Items.Data) == 0 {
+		return fmt.Errorf("subscription %s has no items", subscriptionID)
+	}
+	_, err = stripesubscription.Update(subscriptionID, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{{
+			ID:       stripe.String(sub.Items.Data[0].ID),
+			Quantity: stripe.Int64(int64(seats)),
+		}},
+		ProrationBehavior: stripe.String("create_prorations"),
+	})
+	if err != nil {
+		return fmt.Errorf("update quantity: %w", err)
+	}
+	return nil
+}
+
+// ─── Email ───────────────────────────────────────────────────────
+
+// sendTeamInviteEmail emails the join link via Resend.
+func sendTeamInviteEmail(ctx context.Context, toEmail, inviterName, joinURL string) error {
+	apiKey := Secret("RESEND_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("RESEND_API_KEY not configured")
+	}
+	from := os.Getenv("RESEND_FROM_EMAIL")
+	if from == "" {
+		from = "MyScrollr <noreply@myscrollr.com>"
+	}
+	if inviterName == "" {
+		inviterName = "A MyScrollr subscriber"
+	}
+
+	body := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;background:#0b0d10;color:#e6e6e6;padding:24px;">
+  <div style="max-width:520px;margin:0 auto;background:#14181d;border:1px solid #1e252d;border-radius:12px;padding:32px;">
+    <h2 style="margin:0 0 16px;font-size:20px;color:#fff;">You're invited to MyScrollr</h2>
+    <p style="margin:0 0 16px;line-height:1.6;color:#b8b8b8;">%s has shared a seat on their MyScrollr subscription with you.</p>
+    <p style="margin:24px 0;text-align:center;">
+      <a href="%s" style="display:inline-block;padding:12px 28px;background:#10b981;color:#fff;text-decoration:none;border-radius:8px;font-weight:600;">Accept invitation</a>
+    </p>
+    <p style="margin:0;line-height:1.6;color:#7a7a7a;font-size:12px;">This invitation expires in %d days. If you weren't expecting it, you can ignore this email.</p>
+  </div>
+  <p style="text-align:center;margin-top:16px;color:#5a5a5a;font-size:11px;">— The MyScrollr Team</p>
+</body>
+</html>`, html.EscapeString(inviterName), html.EscapeString(joinURL), int(TeamInviteTTL.Hours()/24))
+
+	return postToResend(ctx, apiKey, map[string]any{
+		"from":    from,
+		"to":      []string{toEmail},
+		"subject": "You've been invited to share a MyScrollr subscription",
+		"html":    body,
+	})
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// HandleGetTeam returns the caller's team: seats, members and pending
+// invitations for owners, the owner and plan for members.
+//
+// @Summary Get team
+// @Tags Team
+// @Produce json
+// @Success 200 {object} TeamResponse
+// @Security LogtoAuth
+// @Router /users/me/team [get]
+func HandleGetTeam(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var ownerSub string
+	var joinedAt time.Time
+	err := DBPool.QueryRow(ctx,
+		`SELECT owner_sub, joined_at FROM team_members WHERE member_sub = $1`, userID,
+	).Scan(&ownerSub, &joinedAt)
+	if err == nil {
+		resp := TeamResponse{Role: "member", OwnerSub: ownerSub, JoinedAt: &joinedAt}
+		if owner, err := loadTeamOwner(ctx, ownerSub); err == nil {
+			resp.Plan = owner.Plan
+		}
+		return c.JSON(resp)
+	}
+	if err != pgx.ErrNoRows {
+		log.Printf("[Team] membership lookup failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load team",
+		})
+	}
+
+	owner, err := loadTeamOwner(ctx, userID)
+	if err != nil || (owner.Seats <= 1 && !ownerCanHostTeam(owner.Status, owner.SubscriptionID)) {
+		return c.JSON(TeamResponse{Role: "none"})
+	}
+
+	resp := TeamResponse{
+		Role:        "owner",
+		Plan:        owner.Plan,
+		Seats:       owner.Seats,
+		MaxSeats:    MaxTeamSeats,
+		Members:     []TeamMember{},
+		Invitations: []TeamInvitation{},
+	}
+
+	rows, err := DBPool.Query(ctx,
+		`SELECT member_sub, email, joined_at FROM team_members
+		 WHERE owner_sub = $1 ORDER BY joined_at`, userID)
+	if err != nil {
+		log.Printf("[Team] members query failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load team",
+		})
+	}
+	for rows.Next() {
+		var m TeamMember
+		if err := rows.Scan(&m.MemberSub, &m.Email, &m.JoinedAt); err == nil {
+			resp.Members = append(resp.Members, m)
+		}
+	}
+	rows.Close()
+
+	rows, err = DBPool.Query(ctx,
+		`SELECT id, email, created_at, expires_at FROM team_invitations
+		 WHERE owner_sub = $1 AND status = 'pending' AND expires_at > now()
+		 ORDER BY created_at`, userID)
+	if err != nil {
+		log.Printf("[Team] invitations query failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load team",
+		})
+	}
+	for rows.Next() {
+		var inv TeamInvitation
+		if err := rows.Scan(&inv.ID, &inv.Email, &inv.CreatedAt, &inv.ExpiresAt); err == nil {
+			resp.Invitations = append(resp.Invitations, inv)
+		}
+	}
+	rows.Close()
+
+	resp.SeatsUsed = 1 + len(resp.Members) + len(resp.Invitations)
+	return c.JSON(resp)
+}
+
+// HandleSetTeamSeats changes the number of seats on the caller's
+// subscription and syncs the Stripe item quantity.
+//
+// @Summary Set team seats
+// @Tags Team
+// @Accept json
+// @Produce json
+// @Param body body object true "Seat count" example({"seats":4})
+// @Success 200 {object} object{seats=int}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/seats [put]
+func HandleSetTeamSeats(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var req struct {
+		Seats int `json:"seats"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "seats is required",
+		})
+	}
+
+	// The owner row stays locked through the Stripe call so no invitation
+	// can take a seat the new count no longer has.
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		log.Printf("[Team] begin seats tx: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to update seats",
+		})
+	}
+	defer tx.Rollback(ctx)
+
+	owner, err := lockTeamOwnerTx(ctx, tx, userID)
+	if err != nil || !ownerCanHostTeam(owner.Status, owner.SubscriptionID) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "An active subscription is required to manage seats",
+		})
+	}
+
+	used, err := teamSeatsUsedTx(ctx, tx, userID)
+	if err != nil {
+		log.Printf("[Team] seat count failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to update seats",
+		})
+	}
+	if err := validateSeatCount(req.Seats, used); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: err.Error(),
+		})
+	}
+	if req.Seats == owner.Seats {
+		return c.JSON(fiber.Map{"seats": owner.Seats})
+	}
+
+	if err := setSubscriptionSeats(owner.SubscriptionID, req.Seats); err != nil {
+		log.Printf("[Team] Stripe seat update failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to update seats",
+		})
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE stripe_customers SET seats = $2, updated_at = now() WHERE logto_sub = $1`,
+		userID, req.Seats)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		// The subscription.updated webhook carries the quantity and
+		// will reconcile the column.
+		log.Printf("[Team] Failed to record seats for %s: %v", userID, err)
+	}
+
+	log.Printf("[Team] %s changed seats %d -> %d", userID, owner.Seats, req.Seats)
+	InvalidateOverviewCache(ctx, userID)
+	return c.JSON(fiber.Map{"seats": req.Seats})
+}
+
+// HandleCreateTeamInvitation invites an email address to a free seat.
+// The join URL is returned as well as emailed, so owners can share it
+// directly if the email goes astray.
+//
+// @Summary Invite a team member
+// @Tags Team
+// @Accept json
+// @Produce json
+// @Param body body object true "Invitee" example({"email":"sam@example.com"})
+// @Success 201 {object} object{invitation=TeamInvitation,join_url=string,email_sent=bool}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/invitations [post]
+func HandleCreateTeamInvitation(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	_ = c.BodyParser(&req)
+	email, ok := normalizeInviteEmail(req.Email)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "A valid email address is required",
+		})
+	}
+	if own, _ := c.Locals("user_email").(string); strings.EqualFold(own, email) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "You already hold the owner's seat",
+		})
+	}
+
+	token, err := generateTeamInviteToken()
+	if err != nil {
+		log.Printf("[Team] token generation failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create invitation",
+		})
+	}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		log.Printf("[Team] begin invite tx: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create invitation",
+		})
+	}
+	defer tx.Rollback(ctx)
+
+	owner, err := lockTeamOwnerTx(ctx, tx, userID)
+	if err != nil || !ownerCanHostTeam(owner.Status, owner.SubscriptionID) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "An active subscription is required to invite members",
+		})
+	}
+	used, err := teamSeatsUsedTx(ctx, tx, userID)
+	if err != nil {
+		log.Printf("[Team] seat count failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create invitation",
+		})
+	}
+	if used >= owner.Seats {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "No free seats — add a seat first",
+		})
+	}
+
+	// Expired pending rows would otherwise trip the one-pending-per-email
+	// index; revoke them before inserting.
+	if _, err := tx.Exec(ctx, `
+		UPDATE team_invitations SET status = 'revoked'
+		 WHERE owner_sub = $1 AND lower(email) = $2 AND status = 'pending' AND expires_at <= now()`,
+		userID, email); err != nil {
+		log.Printf("[Team] expired invitation cleanup failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create invitation",
+		})
+	}
+
+	inv := TeamInvitation{Email: email}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO team_invitations (owner_sub, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, now() + $4::interval)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, expires_at`,
+		userID, email, hashTeamInviteToken(token), fmt.Sprintf("%d seconds", int(TeamInviteTTL.Seconds())),
+	).Scan(&inv.ID, &inv.CreatedAt, &inv.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "That address already has a pending invitation",
+		})
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		log.Printf("[Team] invitation insert failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to create invitation",
+		})
+	}
+
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = DefaultFrontendURL
+	}
+	joinURL := frontendURL + "/team/join?token=" + token
+
+	inviterName, _ := c.Locals("user_name").(string)
+	emailSent := true
+	if err := sendTeamInviteEmail(ctx, email, inviterName, joinURL); err != nil {
+		log.Printf("[Team] invite email to %s failed: %v", email, err)
+		emailSent = false
+	}
+
+	log.Printf("[Team] %s invited %s (invitation %d)", userID, email, inv.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"invitation": inv,
+		"join_url":   joinURL,
+		"email_sent": emailSent,
+	})
+}
+
+// HandleRevokeTeamInvitation cancels a pending invitation, freeing its seat.
+//
+// @Summary Revoke a team invitation
+// @Tags Team
+// @Param id path int true "Invitation ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/invitations/{id} [delete]
+func HandleRevokeTeamInvitation(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid invitation id",
+		})
+	}
+	tag, err := DBPool.Exec(c.Context(),
+		`UPDATE team_invitations SET status = 'revoked'
+		 WHERE id = $1 AND owner_sub = $2 AND status = 'pending'`, id, userID)
+	if err != nil {
+		log.Printf("[Team] revoke failed for %s/%d: %v", userID, id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to revoke invitation",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Invitation not found",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleRemoveTeamMember removes a member from the caller's team and
+// drops them back to the free tier. The seat stays paid for until the
+// owner lowers the seat count.
+//
+// @Summary Remove a team member
+// @Tags Team
+// @Param sub path string true "Member logto_sub"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/members/{sub} [delete]
+func HandleRemoveTeamMember(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	memberSub := c.Params("sub")
+	ctx := c.Context()
+
+	tag, err := DBPool.Exec(ctx,
+		`DELETE FROM team_members WHERE member_sub = $1 AND owner_sub = $2`, memberSub, userID)
+	if err != nil {
+		log.Printf("[Team] remove member failed for %s/%s: %v", userID, memberSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to remove member",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "Member not found",
+		})
+	}
+
+	applyMemberTier(ctx, memberSub, "free")
+	log.Printf("[Team] %s removed member %s", userID, memberSub)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleJoinTeam accepts an invitation. The caller's email must match
+// the invited address.
+//
+// @Summary Accept a team invitation
+// @Tags Team
+// @Accept json
+// @Produce json
+// @Param body body object true "Invitation token" example({"token":"..."})
+// @Success 200 {object} TeamResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/join [post]
+func HandleJoinTeam(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "token is required",
+		})
+	}
+
+	if hasOwnPaidPlan(ctx, userID) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "You already have your own subscription — cancel it before joining a team",
+		})
+	}
+
+	var inv struct {
+		ID       int64
+		OwnerSub string
+		Email    string
+	}
+	err := DBPool.QueryRow(ctx, `
+		SELECT id, owner_sub, email FROM team_invitations
+		 WHERE token_hash = $1 AND status = 'pending' AND expires_at > now()`,
+		hashTeamInviteToken(req.Token),
+	).Scan(&inv.ID, &inv.OwnerSub, &inv.Email)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			log.Printf("[Team] invitation lookup failed: %v", err)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: errInviteInvalid.Error(),
+		})
+	}
+	if inv.OwnerSub == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "You can't join your own team",
+		})
+	}
+	if own, _ := c.Locals("user_email").(string); !strings.EqualFold(own, inv.Email) {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "error", Error: "This invitation was sent to a different email address",
+		})
+	}
+
+	owner, err := loadTeamOwner(ctx, inv.OwnerSub)
+	if err != nil || !ownerCanHostTeam(owner.Status, owner.SubscriptionID) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "The team's subscription is no longer active",
+		})
+	}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		log.Printf("[Team] begin join tx: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to join team",
+		})
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO team_members (member_sub, owner_sub, email)
+		 VALUES ($1, $2, $3) ON CONFLICT (member_sub) DO NOTHING`,
+		userID, inv.OwnerSub, inv.Email)
+	if err != nil {
+		log.Printf("[Team] member insert failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to join team",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error", Error: "You're already a member of a team",
+		})
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE team_invitations
+		   SET status = 'accepted', accepted_at = now(), accepted_by = $2
+		 WHERE id = $1`, inv.ID, userID); err != nil {
+		log.Printf("[Team] invitation update failed for %d: %v", inv.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to join team",
+		})
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("[Team] commit join for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to join team",
+		})
+	}
+
+	applyMemberTier(ctx, userID, memberTierFor(owner.Status, owner.Plan))
+	log.Printf("[Team] %s joined team of %s", userID, inv.OwnerSub)
+
+	now := time.Now()
+	return c.JSON(TeamResponse{Role: "member", OwnerSub: inv.OwnerSub, Plan: owner.Plan, JoinedAt: &now})
+}
+
+// HandleLeaveTeam removes the caller from their team.
+//
+// @Summary Leave team
+// @Tags Team
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /users/me/team/leave [post]
+func HandleLeaveTeam(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+
+	tag, err := DBPool.Exec(ctx, `DELETE FROM team_members WHERE member_sub = $1`, userID)
+	if err != nil {
+		log.Printf("[Team] leave failed for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to leave team",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error", Error: "You're not a member of a team",
+		})
+	}
+
+	applyMemberTier(ctx, userID, "free")
+	log.Printf("[Team] %s left their team", userID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTierForPlan(t *testing.T) {
+	os.Setenv("STRIPE_PRICE_PRO_MONTHLY", "price_pro_monthly")
+	os.Setenv("STRIPE_PRICE_ULTIMATE_ANNUAL", "price_ultimate_annual")
+	defer os.Unsetenv("STRIPE_PRICE_PRO_MONTHLY")
+	defer os.Unsetenv("STRIPE_PRICE_ULTIMATE_ANNUAL")
+
+	tests := map[string]string{
+		"free":            "free",
+		"":                "free",
+		"lifetime":        "uplink_ultimate",
+		"ultimate_annual": "uplink_ultimate",
+		"pro_monthly":     "uplink_pro",
+		"monthly":         "uplink",
+	}
+	for plan, want := range tests {
+		if got := tierForPlan(plan); got != want {
+			t.Errorf("tierForPlan(%q) = %q, want %q", plan, got, want)
+		}
+	}
+}
+
+func TestMemberTierFor(t *testing.T) {
+	if got := memberTierFor("trialing", "monthly"); got != "uplink_ultimate" {
+		t.Errorf("trialing owner: got %q, want uplink_ultimate", got)
+	}
+	if got := memberTierFor("canceled", "pro_monthly"); got != "free" {
+		t.Errorf("canceled owner: got %q, want free", got)
+	}
+}
+
+func TestValidateSeatCount(t *testing.T) {
+	tests := []struct {
+		seats, used int
+		ok          bool
+	}{
+		{1, 1, true},
+		{4, 3, true},
+		{MaxTeamSeats, 1, true},
+		{0, 1, false},
+		{MaxTeamSeats + 1, 1, false},
+		{2, 3, false},
+	}
+	for _, tt := range tests {
+		err := validateSeatCount(tt.seats, tt.used)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateSeatCount(%d, %d) err = %v, want ok=%v", tt.seats, tt.used, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeInviteEmail(t *testing.T) {
+	tests := map[string]string{
+		" Sam@Example.COM ":     "sam@example.com",
+		"Sam <sam@example.com>": "",
+		"not-an-email":          "",
+		"user@localhost":        "",
+		"":                      "",
+	}
+	for in, want := range tests {
+		got, ok := normalizeInviteEmail(in)
+		if got != want || ok != (want != "") {
+			t.Errorf("normalizeInviteEmail(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestHashTeamInviteToken_Stable(t *testing.T) {
+	tok, err := generateTeamInviteToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashTeamInviteToken(tok) != hashTeamInviteToken(tok) || hashTeamInviteToken(tok) == tok {
+		t.Error("token hash should be deterministic and differ from the token")
+	}
+}
+
+func TestCreateTeamInvitation_ConcurrentInvitesRespectSeats(t *testing.T) {
+	testDBAvailable(t)
+	owner := makeTestUser()
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = DBPool.Exec(ctx, `DELETE FROM team_invitations WHERE owner_sub = $1`, owner)
+		_, _ = DBPool.Exec(ctx, `DELETE FROM stripe_customers WHERE logto_sub = $1`, owner)
+	})
+	// Two seats: the owner's and one to invite into.
+	mustExec(t, `
+		INSERT INTO stripe_customers (logto_sub, stripe_customer_id, stripe_subscription_id, plan, status, seats)
+		VALUES ($1, $1, 'sub_test', 'monthly', 'active', 2)`, owner)
+
+	app := fiber.New()
+	app.Post("/invite", func(c *fiber.Ctx) error {
+		c.Locals("user_id", owner)
+		return HandleCreateTeamInvitation(c)
+	})
+
+	const invites = 5
+	codes := make(chan int, invites)
+	var wg sync.WaitGroup
+	for i := 0; i < invites; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/invite",
+				strings.NewReader(fmt.Sprintf(`{"email":"member%d@example.com"}`, i)))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			codes <- resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		if code == fiber.StatusCreated {
+			created++
+		} else if code != fiber.StatusConflict {
+			t.Errorf("status = %d, want 201 or 409", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d invitations created for one free seat", created)
+	}
+}
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Product analytics intake.
+//
+// Clients post engagement events to POST /telemetry. Consent is enforced
+// here, not in the client: events are only accepted for users whose
+// analytics_opt_in preference is on, and opting out deletes what we
+// stored. Events never carry the caller's IP — we don't read it, and
+// IP-shaped keys and values are stripped from properties.
+//
+// Accepted events are buffered in memory and flushed in batches to the
+// sink selected by TELEMETRY_SINK: "postgres" (default, analytics_events
+// table), "http" (JSON POST to TELEMETRY_COLLECTOR_URL), or "off".
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// TelemetryEvent is one client-reported event.
+type TelemetryEvent struct {
+	Name       string         `json:"name"`
+	Properties map[string]any `json:"properties,omitempty"`
+	SessionID  string         `json:"session_id,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	// Set server-side.
+	LogtoSub   string    `json:"logto_sub"`
+	Client     string    `json:"client,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// TelemetryRequest is the body for POST /telemetry. Client names the
+// app surface ("desktop", "extension", "web") and applies to every event.
+type TelemetryRequest struct {
+	Client string           `json:"client"`
+	Events []TelemetryEvent `json:"events"`
+}
+
+var telemetryEventNameRe = regexp.MustCompile(`^[a-z][a-z0-9_.]{0,63}$`)
+
+// Property keys that identify a network location. Matched
+// case-insensitively after stripping "-" and "_".
+var telemetryIPKeys = map[string]bool{
+	"ip":            true,
+	"ipaddress":     true,
+	"ipv4":          true,
+	"ipv6":          true,
+	"remoteaddr":    true,
+	"remoteip":      true,
+	"clientip":      true,
+	"xforwardedfor": true,
+	"xrealip":       true,
+}
+
+// ─── Sanitising ──────────────────────────────────────────────────
+
+func validTelemetryEventName(name string) bool {
+	return telemetryEventNameRe.MatchString(name)
+}
+
+func isTelemetryIPKey(key string) bool {
+	k := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+	return telemetryIPKeys[k]
+}
+
+// sanitizeTelemetryProperties drops IP-shaped keys and values, walking
+// nested objects and arrays. Returns nil for an empty result.
+func sanitizeTelemetryProperties(props map[string]any) map[string]any {
+	out := make(map[string]any, len(props))
+	for k, v := range props {
+		if isTelemetryIPKey(k) {
+			continue
+		}
+		if clean, ok := sanitizeTelemetryValue(v); ok {
+			out[k] = clean
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func sanitizeTelemetryValue(v any) (any, bool) {
+	switch val := v.(type) {
+	case string:
+		if net.ParseIP(strings.TrimSpace(val)) != nil {
+			return nil, false
+		}
+		return val, true
+	case map[string]any:
+		return sanitizeTelemetryProperties(val), true
+	case []any:
+		out := make([]any, 0, len(val))
+		for _, item := range val {
+			if clean, ok := sanitizeTelemetryValue(item); ok {
+				out = append(out, clean)
+			}
+		}
+		return out, true
+	default:
+		return val, true
+	}
+}
+
+// normalizeTelemetryEvent validates an event and fills server-side
+// fields. Client timestamps outside TelemetryClockSkew are replaced with
+// the receive time.
+func normalizeTelemetryEvent(ev TelemetryEvent, sub, client string, now time.Time) (TelemetryEvent, error) {
+	if !validTelemetryEventName(ev.Name) {
+		return ev, fmt.Errorf("invalid event name %q", ev.Name)
+	}
+	if len(ev.SessionID) > TelemetryMaxFieldLen {
+		ev.SessionID = ev.SessionID[:TelemetryMaxFieldLen]
+	}
+	ev.Properties = sanitizeTelemetryProperties(ev.Properties)
+	if raw, _ := json.Marshal(ev.Properties); len(raw) > TelemetryMaxPropertiesBytes {
+		return ev, fmt.Errorf("properties for %q exceed %d bytes", ev.Name, TelemetryMaxPropertiesBytes)
+	}
+	if ev.OccurredAt.IsZero() || ev.OccurredAt.After(now.Add(TelemetryClockSkew)) ||
+		ev.OccurredAt.Before(now.Add(-TelemetryClockSkew)) {
+		ev.OccurredAt = now
+	}
+	ev.LogtoSub = sub
+	ev.Client = client
+	if len(ev.Client) > TelemetryMaxFieldLen {
+		ev.Client = ev.Client[:TelemetryMaxFieldLen]
+	}
+	ev.ReceivedAt = now
+	return ev, nil
+}
+
+// ─── Consent ─────────────────────────────────────────────────────
+
+// telemetryConsent reports whether the user has opted in. Cached in
+// Redis so the intake doesn't hit Postgres per batch.
+func telemetryConsent(ctx context.Context, sub string) bool {
+	if Rdb != nil {
+		if v, err := Rdb.Get(ctx, RedisTelemetryConsentPrefix+sub).Result(); err == nil {
+			return v == "1"
+		}
+	}
+	var optIn bool
+	err := DBPool.QueryRow(ctx,
+		`SELECT analytics_opt_in FROM user_preferences WHERE logto_sub = $1`, sub,
+	).Scan(&optIn)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Printf("[Telemetry] consent lookup for %s failed: %v", sub, err)
+		return false
+	}
+	if Rdb != nil {
+		val := "0"
+		if optIn {
+			val = "1"
+		}
+		Rdb.Set(ctx, RedisTelemetryConsentPrefix+sub, val, TelemetryConsentCacheTTL)
+	}
+	return optIn
+}
+
+// setTelemetryConsent is called after the preference changes. Opting
+// out also deletes the user's stored events.
+func setTelemetryConsent(ctx context.Context, sub string, optIn bool) {
+	if Rdb != nil {
+		Rdb.Del(ctx, RedisTelemetryConsentPrefix+sub)
+	}
+	if optIn {
+		return
+	}
+	telemetryBuf.dropUser(sub)
+	if _, err := DBPool.Exec(ctx,
+		`DELETE FROM analytics_events WHERE logto_sub = $1`, sub); err != nil {
+		log.Printf("[Telemetry] delete events on opt-out for %s failed: %v", sub, err)
+	}
+}
+
+// ─── Buffer & sinks ──────────────────────────────────────────────
+
+type telemetryBuffer struct {
+	mu     sync.Mutex
+	events []TelemetryEvent
+	full   chan struct{}
+}
+
+var telemetryBuf = &telemetryBuffer{full: make(chan struct{}, 1)}
+
+// add appends events, dropping the oldest beyond TelemetryBufferMax so
+// a dead sink can't grow memory without bound.
+func (b *telemetryBuffer) add(events []TelemetryEvent) {
+	b.mu.Lock()
+	b.events = append(b.events, events...)
+	if over := len(b.events) - TelemetryBufferMax; over > 0 {
+		b.events = b.events[over:]
+	}
+	n := len(b.events)
+	b.mu.Unlock()
+	if n >= TelemetryBatchSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *telemetryBuffer) drain() []TelemetryEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := b.events
+	b.events = nil
+	return out
+}
+
+func (b *telemetryBuffer) dropUser(sub string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.events[:0]
+	for _, ev := range b.events {
+		if ev.LogtoSub != sub {
+			kept = append(kept, ev)
+		}
+	}
+	b.events = kept
+}
+
+type telemetrySink interface {
+	Write(ctx context.Context, events []TelemetryEvent) error
+}
+
+type postgresTelemetrySink struct{}
+
+func (postgresTelemetrySink) Write(ctx context.Context, events []TelemetryEvent) error {
+	rows := make([][]any, 0, len(events))
+	for _, ev := range events {
+		props, _ := json.Marshal(ev.Properties)
+		rows = append(rows, []any{ev.LogtoSub, ev.Name, props, ev.SessionID, ev.Client, ev.OccurredAt, ev.ReceivedAt})
+	}
+	_, err := DBPool.CopyFrom(ctx,
+		pgx.Identifier{"analytics_events"},
+		[]string{"logto_sub", "name", "properties", "session_id", "client", "occurred_at", "received_at"},
+		pgx.CopyFromRows(rows))
+	return err
+}
+
+type httpTelemetrySink struct {
+	url   string
+	token string
+}
+
+var telemetryHTTPClient = NewHTTPClient("telemetry", TelemetryCollectorTimeout)
+
+func (s httpTelemetrySink) Write(ctx context.Context, events []TelemetryEvent) error {
+	body, err := json.Marshal(map[string]any{"events": events})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := telemetryHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// telemetrySinkFromEnv picks the configured sink; nil means disabled.
+func telemetrySinkFromEnv() telemetrySink {
+	switch strings.ToLower(os.Getenv("TELEMETRY_SINK")) {
+	case "", "postgres":
+		return postgresTelemetrySink{}
+	case "http":
+		url := os.Getenv("TELEMETRY_COLLECTOR_URL")
+		if url == "" {
+			log.Println("[Telemetry] TELEMETRY_SINK=http but TELEMETRY_COLLECTOR_URL is unset; intake disabled")
+			return nil
+		}
+		return httpTelemetrySink{url: url, token: Secret("TELEMETRY_COLLECTOR_TOKEN")}
+	case "off":
+		return nil
+	default:
+		log.Printf("[Telemetry] unknown TELEMETRY_SINK %q; intake disabled", os.Getenv("TELEMETRY_SINK"))
+		return nil
+	}
+}
+
+var activeTelemetrySink telemetrySink
+
+func flushTelemetry(ctx context.Context) {
+	events := telemetryBuf.drain()
+	if len(events) == 0 || activeTelemetrySink == nil {
+		return
+	}
+	for start := 0; start < len(events); start += TelemetryBatchSize {
+		end := min(start+TelemetryBatchSize, len(events))
+		if err := activeTelemetrySink.Write(ctx, events[start:end]); err != nil {
+			log.Printf("[Telemetry] sink write of %d event(s) failed: %v", end-start, err)
+		}
+	}
+}
+
+// StartTelemetryFlusher selects the sink and flushes the event buffer on
+// an interval, or sooner when a batch fills. A final flush runs on
+// shutdown.
+func StartTelemetryFlusher(ctx context.Context) {
+	activeTelemetrySink = telemetrySinkFromEnv()
+	if activeTelemetrySink == nil {
+		log.Println("[Telemetry] No sink configured; POST /telemetry will drop events")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(TelemetryFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), TelemetryCollectorTimeout)
+				flushTelemetry(shutdownCtx)
+				cancel()
+				return
+			case <-ticker.C:
+				flushTelemetry(ctx)
+			case <-telemetryBuf.full:
+				flushTelemetry(ctx)
+			}
+		}
+	}()
+	log.Printf("[Telemetry] Flusher started (%s interval)", TelemetryFlushInterval)
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// HandleTelemetry accepts a batch of analytics events for the caller.
+// Users who haven't opted in get 403 and nothing is recorded. Invalid
+// events are skipped and counted rather than failing the batch.
+//
+// @Summary Submit analytics events
+// @Tags Telemetry
+// @Accept json
+// @Produce json
+// @Param body body TelemetryRequest true "Event batch"
+// @Success 202 {object} object{accepted=int,rejected=int}
+// @Failure 403 {object} ErrorResponse
+// @Security LogtoAuth
+// @Router /telemetry [post]
+func HandleTelemetry(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized", Error: "Authentication required",
+		})
+	}
+
+	var req TelemetryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "Invalid request body",
+		})
+	}
+	if len(req.Events) == 0 || len(req.Events) > TelemetryMaxEventsPerRequest {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: fmt.Sprintf("events must contain 1-%d items", TelemetryMaxEventsPerRequest),
+		})
+	}
+
+	if !telemetryConsent(c.Context(), userID) {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "error", Error: "Analytics consent not granted",
+		})
+	}
+
+	now := time.Now().UTC()
+	accepted := make([]TelemetryEvent, 0, len(req.Events))
+	for _, ev := range req.Events {
+		clean, err := normalizeTelemetryEvent(ev, userID, req.Client, now)
+		if err != nil {
+			continue
+		}
+		accepted = append(accepted, clean)
+	}
+	if activeTelemetrySink != nil {
+		telemetryBuf.add(accepted)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"accepted": len(accepted),
+		"rejected": len(req.Events) - len(accepted),
+	})
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidTelemetryEventName(t *testing.T) {
+	tests := map[string]bool{
+		"dashboard.view":        true,
+		"channel_added":         true,
+		"Dashboard.View":        false,
+		"1st_event":             false,
+		"":                      false,
+		"has space":             false,
+		strings.Repeat("a", 65): false,
+	}
+	for name, want := range tests {
+		if got := validTelemetryEventName(name); got != want {
+			t.Errorf("validTelemetryEventName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSanitizeTelemetryProperties_StripsIPs(t *testing.T) {
+	got := sanitizeTelemetryProperties(map[string]any{
+		"ip":              "203.0.113.9",
+		"X-Forwarded-For": "203.0.113.9",
+		"remote_addr":     "whatever",
+		"peer":            "2001:db8::1",
+		"screen":          "finance",
+		"nested":          map[string]any{"client_ip": "10.0.0.1", "tab": "news"},
+		"list":            []any{"10.0.0.2", "ok"},
+		"count":           3.0,
+	})
+
+	for _, k := range []string{"ip", "X-Forwarded-For", "remote_addr", "peer"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("key %q should have been stripped", k)
+		}
+	}
+	if got["screen"] != "finance" || got["count"] != 3.0 {
+		t.Errorf("non-IP values changed: %v", got)
+	}
+	nested := got["nested"].(map[string]any)
+	if _, ok := nested["client_ip"]; ok || nested["tab"] != "news" {
+		t.Errorf("nested = %v, want only tab", nested)
+	}
+	if list := got["list"].([]any); len(list) != 1 || list[0] != "ok" {
+		t.Errorf("list = %v, want [ok]", list)
+	}
+}
+
+func TestNormalizeTelemetryEvent(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ev, err := normalizeTelemetryEvent(TelemetryEvent{
+		Name:       "ticker.click",
+		OccurredAt: now.Add(-48 * time.Hour),
+	}, "user-1", "desktop", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ev.OccurredAt.Equal(now) {
+		t.Errorf("stale timestamp kept: %s", ev.OccurredAt)
+	}
+	if ev.LogtoSub != "user-1" || ev.Client != "desktop" || !ev.ReceivedAt.Equal(now) {
+		t.Errorf("server fields not set: %+v", ev)
+	}
+
+	if _, err := normalizeTelemetryEvent(TelemetryEvent{Name: "Bad Name"}, "user-1", "", now); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	big := map[string]any{"blob": strings.Repeat("x", TelemetryMaxPropertiesBytes)}
+	if _, err := normalizeTelemetryEvent(TelemetryEvent{Name: "big", Properties: big}, "user-1", "", now); err == nil {
+		t.Error("expected error for oversized properties")
+	}
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Plain-text ticker rendering for screen readers.
+//
+// GET /dashboard and GET /display/:token/ticker answer with one short
+// sentence per line when the client sends Accept: text/plain or
+// ?format=text: "AAPL up 1.2 percent to 232.10." Each client used to
+// build its own accessible labels from the JSON and they drifted; the
+// wording now lives here, once.
+//
+// The channel payloads are decoded into the few fields each sentence
+// needs. Core only knows their shape by convention (channels/*/api
+// models) — same as channelUserCacheKeys — so an unknown or malformed
+// channel payload renders nothing rather than failing the request.
+
+// ─── Negotiation ─────────────────────────────────────────────────
+
+// wantsTickerText reports whether the caller asked for the text
+// rendering. ?format=text wins so links and screen-reader browser
+// extensions can request it without setting headers.
+func wantsTickerText(c *fiber.Ctx) bool {
+	switch c.Query("format") {
+	case "text":
+		return true
+	case "json":
+		return false
+	}
+	return c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMETextPlain) == fiber.MIMETextPlain
+}
+
+// sendTickerText writes lines as a text/plain response.
+func sendTickerText(c *fiber.Ctx, lines []string) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	if len(lines) == 0 {
+		return c.SendString("Your ticker has nothing to show right now.\n")
+	}
+	return c.SendString(strings.Join(lines, "\n") + "\n")
+}
+
+// ─── Rendering ───────────────────────────────────────────────────
+
+// textTrade, textGame, textRSSItem and textFantasyLeague mirror the
+// fields of the channel models that the sentences use.
+type textTrade struct {
+	Symbol           string  `json:"symbol"`
+	Price            float64 `json:"price"`
+	PercentageChange float64 `json:"percentage_change"`
+}
+
+type textGame struct {
+	League        string `json:"league"`
+	HomeTeamName  string `json:"home_team_name"`
+	HomeTeamScore string `json:"home_team_score"`
+	AwayTeamName  string `json:"away_team_name"`
+	AwayTeamScore string `json:"away_team_score"`
+	ShortDetail   string `json:"short_detail"`
+	State         string `json:"state"`
+	SpoilerHidden bool   `json:"spoiler_hidden"`
+}
+
+type textRSSItem struct {
+	Title      string `json:"title"`
+	SourceName string `json:"source_name"`
+}
+
+type textFantasyLeague struct {
+	Name     string  `json:"name"`
+	TeamName *string `json:"team_name"`
+}
+
+// renderTickerText renders the personal channels in the caller's channel
+// order, then each organization's shared channels, then incidents.
+// Channels that are disabled or hidden from the ticker are skipped.
+func renderTickerText(channels []Channel, data map[string]interface{}, orgs []OrgDashboard, incidents []Incident) []string {
+	lines := make([]string, 0)
+	for _, inc := range incidents {
+		lines = append(lines, sentence("Service notice: "+inc.Message))
+	}
+	lines = append(lines, renderChannelsText(channels, data)...)
+	for _, org := range orgs {
+		shared := renderChannelsText(org.Channels, org.Data)
+		if len(shared) == 0 {
+			continue
+		}
+		lines = append(lines, sentence("Shared by "+org.Name))
+		lines = append(lines, shared...)
+	}
+	return lines
+}
+
+func renderChannelsText(channels []Channel, data map[string]interface{}) []string {
+	var lines []string
+	for _, ch := range channels {
+		if !ch.Enabled || !ch.Visible {
+			continue
+		}
+		switch ch.ChannelType {
+		case "finance":
+			var trades []textTrade
+			decodeChannelData(data["finance"], &trades)
+			for _, t := range trades {
+				lines = append(lines, tradeSentence(t))
+			}
+		case "sports":
+			var games []textGame
+			decodeChannelData(data["sports"], &games)
+			for _, g := range games {
+				lines = append(lines, gameSentence(g))
+			}
+		case "rss":
+			var items []textRSSItem
+			decodeChannelData(data["rss"], &items)
+			for _, it := range items {
+				lines = append(lines, rssSentence(it))
+			}
+		case "fantasy":
+			var fantasy struct {
+				Leagues []textFantasyLeague `json:"leagues"`
+			}
+			decodeChannelData(data["fantasy"], &fantasy)
+			for _, l := range fantasy.Leagues {
+				lines = append(lines, fantasySentence(l))
+			}
+		}
+	}
+	return lines
+}
+
+// decodeChannelData converts a generically-decoded channel payload into
+// target. Failures leave target empty.
+func decodeChannelData(raw interface{}, target interface{}) {
+	if raw == nil {
+		return
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(b, target)
+}
+
+// spokenNumber formats n with at most decimals places and no trailing
+// zeros, so "1.20" is read as "1.2".
+func spokenNumber(n float64, decimals int) string {
+	s := strconv.FormatFloat(n, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// sentence trims s and ends it with a period unless it already ends in
+// punctuation.
+func sentence(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s
+	}
+	if strings.ContainsRune(".!?", rune(s[len(s)-1])) {
+		return s
+	}
+	return s + "."
+}
+
+func tradeSentence(t textTrade) string {
+	price := strconv.FormatFloat(t.Price, 'f', 2, 64)
+	change := math.Abs(t.PercentageChange)
+	switch {
+	case spokenNumber(change, 2) == "0":
+		return sentence(fmt.Sprintf("%s unchanged at %s", t.Symbol, price))
+	case t.PercentageChange > 0:
+		return sentence(fmt.Sprintf("%s up %s percent to %s", t.Symbol, spokenNumber(change, 2), price))
+	default:
+		return sentence(fmt.Sprintf("%s down %s percent to %s", t.Symbol, spokenNumber(change, 2), price))
+	}
+}
+
+func gameSentence(g textGame) string {
+	detail := strings.TrimSpace(g.ShortDetail)
+	var s string
+	switch {
+	case g.SpoilerHidden && g.State == "in":
+		s = fmt.Sprintf("Live: %s at %s, score hidden", g.AwayTeamName, g.HomeTeamName)
+	case g.SpoilerHidden:
+		s = fmt.Sprintf("Final: %s at %s, score hidden", g.AwayTeamName, g.HomeTeamName)
+	case g.State == "in":
+		s = fmt.Sprintf("Live: %s %s, %s %s", g.AwayTeamName, g.AwayTeamScore, g.HomeTeamName, g.HomeTeamScore)
+		if detail != "" {
+			s += ", " + detail
+		}
+	case g.State == "post":
+		s = fmt.Sprintf("Final: %s %s, %s %s", g.AwayTeamName, g.AwayTeamScore, g.HomeTeamName, g.HomeTeamScore)
+	default:
+		s = fmt.Sprintf("Upcoming: %s at %s", g.AwayTeamName, g.HomeTeamName)
+		if detail != "" {
+			s += ", " + detail
+		}
+	}
+	if g.League != "" {
+		s = g.League + ". " + s
+	}
+	return sentence(s)
+}
+
+func rssSentence(it textRSSItem) string {
+	if it.SourceName == "" {
+		return sentence(it.Title)
+	}
+	return sentence(it.SourceName + ": " + strings.TrimSpace(it.Title))
+}
+
+func fantasySentence(l textFantasyLeague) string {
+	if l.TeamName == nil || *l.TeamName == "" {
+		return sentence("Fantasy league " + l.Name)
+	}
+	return sentence(fmt.Sprintf("Fantasy league %s, your team %s", l.Name, *l.TeamName))
+}
+
+// tickerTextFromJSON renders a DashboardResponse or DisplayTicker body;
+// both carry the same channels, data, organizations and incidents keys.
+func tickerTextFromJSON(body []byte) ([]string, error) {
+	var ticker struct {
+		Channels      []Channel              `json:"channels"`
+		Data          map[string]interface{} `json:"data"`
+		Organizations []OrgDashboard         `json:"organizations"`
+		Incidents     []Incident             `json:"incidents"`
+	}
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return nil, err
+	}
+	return renderTickerText(ticker.Channels, ticker.Data, ticker.Organizations, ticker.Incidents), nil
+}
+
+// sendTickerBody writes a dashboard-shaped JSON body, or its text
+// rendering when the caller asked for one.
+func sendTickerBody(c *fiber.Ctx, body []byte) error {
+	c.Vary(fiber.HeaderAccept)
+	if wantsTickerText(c) {
+		lines, err := tickerTextFromJSON(body)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Failed to render ticker text",
+			})
+		}
+		return sendTickerText(c, lines)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTradeSentence(t *testing.T) {
+	for _, tc := range []struct {
+		trade textTrade
+		want  string
+	}{
+		{textTrade{Symbol: "AAPL", Price: 232.1, PercentageChange: 1.20}, "AAPL up 1.2 percent to 232.10."},
+		{textTrade{Symbol: "TSLA", Price: 180, PercentageChange: -3.456}, "TSLA down 3.46 percent to 180.00."},
+		{textTrade{Symbol: "MSFT", Price: 410.5, PercentageChange: 0.001}, "MSFT unchanged at 410.50."},
+	} {
+		if got := tradeSentence(tc.trade); got != tc.want {
+			t.Errorf("tradeSentence(%+v) = %q, want %q", tc.trade, got, tc.want)
+		}
+	}
+}
+
+func TestGameSentence(t *testing.T) {
+	live := textGame{League: "NFL", AwayTeamName: "Bills", AwayTeamScore: "14", HomeTeamName: "Jets", HomeTeamScore: "10", ShortDetail: "Q3 4:12", State: "in"}
+	if got, want := gameSentence(live), "NFL. Live: Bills 14, Jets 10, Q3 4:12."; got != want {
+		t.Errorf("live = %q, want %q", got, want)
+	}
+	pre := textGame{AwayTeamName: "Bills", HomeTeamName: "Jets", ShortDetail: "Sun 1:00 PM", State: "pre"}
+	if got, want := gameSentence(pre), "Upcoming: Bills at Jets, Sun 1:00 PM."; got != want {
+		t.Errorf("pre = %q, want %q", got, want)
+	}
+}
+
+func TestTickerTextFromJSON(t *testing.T) {
+	body := []byte(`{
+		"channels": [
+			{"channel_type": "finance", "enabled": true, "visible": true},
+			{"channel_type": "rss", "enabled": true, "visible": false}
+		],
+		"data": {
+			"finance": [{"symbol": "AAPL", "price": 232.1, "percentage_change": 1.2}],
+			"rss": [{"title": "Hidden", "source_name": "Feed"}]
+		},
+		"incidents": [{"message": "Sports scores are delayed"}]
+	}`)
+	lines, err := tickerTextFromJSON(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(lines, "|")
+	want := "Service notice: Sports scores are delayed.|AAPL up 1.2 percent to 232.10."
+	if got != want {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestGameSentenceSpoilerHidden(t *testing.T) {
+	g := textGame{AwayTeamName: "Bills", HomeTeamName: "Jets", State: "post", SpoilerHidden: true}
+	if got, want := gameSentence(g), "Final: Bills at Jets, score hidden."; got != want {
+		t.Errorf("gameSentence = %q, want %q", got, want)
+	}
+}
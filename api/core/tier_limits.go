@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,6 +12,7 @@ import (
 // round-trip cleanly through JSON (where Go's Infinity has no analogue)
 // and lets clients treat null as "no cap."
 type ChannelLimits struct {
+	Channels               *int `json:"channels"` // enabled channels, any type
 	Symbols                *int `json:"symbols"`
 	Feeds                  *int `json:"feeds"`
 	CustomFeeds            *int `json:"custom_feeds"`
@@ -39,11 +41,11 @@ type TierLimitsResponse struct {
 // these values directly gate what the DB will accept, so drift is
 // unforgiving.
 var DefaultTierLimits = map[string]ChannelLimits{
-	"free":            {Symbols: intPtr(5), Feeds: intPtr(1), CustomFeeds: intPtr(0), Leagues: intPtr(1), Fantasy: intPtr(0), MaxTickerRows: 1, MaxTickerCustomization: false},
-	"uplink":          {Symbols: intPtr(25), Feeds: intPtr(25), CustomFeeds: intPtr(1), Leagues: intPtr(8), Fantasy: intPtr(1), MaxTickerRows: 2, MaxTickerCustomization: false},
-	"uplink_pro":      {Symbols: intPtr(75), Feeds: intPtr(100), CustomFeeds: intPtr(3), Leagues: intPtr(20), Fantasy: intPtr(3), MaxTickerRows: 3, MaxTickerCustomization: false},
-	"uplink_ultimate": {Symbols: nil, Feeds: nil, CustomFeeds: intPtr(10), Leagues: nil, Fantasy: intPtr(10), MaxTickerRows: 3, MaxTickerCustomization: true},
-	"super_user":      {Symbols: nil, Feeds: nil, CustomFeeds: nil, Leagues: nil, Fantasy: nil, MaxTickerRows: 3, MaxTickerCustomization: true},
+	"free":            {Channels: intPtr(3), Symbols: intPtr(5), Feeds: intPtr(1), CustomFeeds: intPtr(0), Leagues: intPtr(1), Fantasy: intPtr(0), MaxTickerRows: 1, MaxTickerCustomization: false},
+	"uplink":          {Channels: nil, Symbols: intPtr(25), Feeds: intPtr(25), CustomFeeds: intPtr(1), Leagues: intPtr(8), Fantasy: intPtr(1), MaxTickerRows: 2, MaxTickerCustomization: false},
+	"uplink_pro":      {Channels: nil, Symbols: intPtr(75), Feeds: intPtr(100), CustomFeeds: intPtr(3), Leagues: intPtr(20), Fantasy: intPtr(3), MaxTickerRows: 3, MaxTickerCustomization: false},
+	"uplink_ultimate": {Channels: nil, Symbols: nil, Feeds: nil, CustomFeeds: intPtr(10), Leagues: nil, Fantasy: intPtr(10), MaxTickerRows: 3, MaxTickerCustomization: true},
+	"super_user":      {Channels: nil, Symbols: nil, Feeds: nil, CustomFeeds: nil, Leagues: nil, Fantasy: nil, MaxTickerRows: 3, MaxTickerCustomization: true},
 }
 
 // HandleGetTierLimits serves the tier limits map to any caller — clients
@@ -66,6 +68,21 @@ func intPtr(n int) *int {
 	return &n
 }
 
+// tierOrder ranks tiers for RequireTier. super_user clears every gate.
+var tierOrder = map[string]int{
+	"free":            0,
+	"uplink":          1,
+	"uplink_pro":      2,
+	"uplink_ultimate": 3,
+	"super_user":      4,
+}
+
+// tierAtLeast reports whether tier meets or exceeds required. Unknown
+// tiers rank as free.
+func tierAtLeast(tier, required string) bool {
+	return tierOrder[tier] >= tierOrder[required]
+}
+
 // ─── Server-side enforcement ─────────────────────────────────────────
 
 // TierLimitError describes exactly which cap a config submission breached.
@@ -74,13 +91,16 @@ func intPtr(n int) *int {
 // the UI can render a precise message from.
 type TierLimitError struct {
 	Tier        string // "free", "uplink", etc.
-	ChannelType string // "finance", "sports", "rss"
-	Field       string // "symbols" | "feeds" | "custom_feeds" | "leagues"
+	ChannelType string // "finance", "sports", "rss"; empty for "channels"
+	Field       string // "channels" | "symbols" | "feeds" | "custom_feeds" | "leagues"
 	Limit       int
 	Got         int
 }
 
 func (e *TierLimitError) Error() string {
+	if e.ChannelType == "" {
+		return fmt.Sprintf("tier %q allows at most %d %s; got %d", e.Tier, e.Limit, e.Field, e.Got)
+	}
 	return fmt.Sprintf(
 		"tier %q allows at most %d %s for %s; got %d",
 		e.Tier, e.Limit, e.Field, e.ChannelType, e.Got,
@@ -90,6 +110,12 @@ func (e *TierLimitError) Error() string {
 // UserFacingMessage returns copy suitable for the `error` field of a 403
 // response body. Kept short and specific so the UI can show it verbatim.
 func (e *TierLimitError) UserFacingMessage() string {
+	if e.Field == "channels" {
+		return fmt.Sprintf(
+			"Your %s plan allows %d enabled channels; this would make %d. Disable a channel or upgrade your plan.",
+			TierDisplayName(e.Tier), e.Limit, e.Got,
+		)
+	}
 	return fmt.Sprintf(
 		"Your %s plan allows %d %s; you tried to save %d.",
 		TierDisplayName(e.Tier), e.Limit, e.Field, e.Got,
@@ -227,6 +253,38 @@ func validateArrayCap(tier, channelType, field string, raw any, cap *int) error
 	}
 }
 
+// ValidateChannelCount rejects a change that takes the number of enabled
+// channels from before to after when after exceeds the tier's cap.
+// Accounts already over the cap (e.g. after a downgrade) keep what they
+// have; only changes that add to the count are refused.
+func ValidateChannelCount(tier string, before, after int) error {
+	limits, ok := DefaultTierLimits[tier]
+	if !ok {
+		limits = DefaultTierLimits["free"]
+		tier = "free"
+	}
+	if limits.Channels == nil || after <= *limits.Channels || after <= before {
+		return nil
+	}
+	return &TierLimitError{
+		Tier:  tier,
+		Field: "channels",
+		Limit: *limits.Channels,
+		Got:   after,
+	}
+}
+
+// countEnabledChannels returns how many of the user's channels are
+// enabled in total, and how many of those are not channelType.
+func countEnabledChannels(ctx context.Context, userID, channelType string) (total, others int, err error) {
+	err = DBPool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE channel_type <> $2)
+		FROM user_channels
+		WHERE logto_sub = $1 AND enabled
+	`, userID, channelType).Scan(&total, &others)
+	return total, others, err
+}
+
 // tierLimitErrorResponse builds the structured 403 body for a
 // *TierLimitError. Handlers use this so the UI can render a precise
 // message and, if desired, drill into the structured `detail` field.
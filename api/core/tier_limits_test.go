@@ -3,7 +3,11 @@ package core
 import (
 	"encoding/json"
 	"errors"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 // TestDefaultTierLimits_Exact pins the numeric values shipped to production.
@@ -16,6 +20,7 @@ import (
 func TestDefaultTierLimits_Exact(t *testing.T) {
 	cases := []struct {
 		tier                   string
+		channels               *int
 		symbols                *int
 		feeds                  *int
 		customFeeds            *int
@@ -24,11 +29,11 @@ func TestDefaultTierLimits_Exact(t *testing.T) {
 		maxTickerRows          int
 		maxTickerCustomization bool
 	}{
-		{"free", intPtr(5), intPtr(1), intPtr(0), intPtr(1), intPtr(0), 1, false},
-		{"uplink", intPtr(25), intPtr(25), intPtr(1), intPtr(8), intPtr(1), 2, false},
-		{"uplink_pro", intPtr(75), intPtr(100), intPtr(3), intPtr(20), intPtr(3), 3, false},
-		{"uplink_ultimate", nil, nil, intPtr(10), nil, intPtr(10), 3, true},
-		{"super_user", nil, nil, nil, nil, nil, 3, true},
+		{"free", intPtr(3), intPtr(5), intPtr(1), intPtr(0), intPtr(1), intPtr(0), 1, false},
+		{"uplink", nil, intPtr(25), intPtr(25), intPtr(1), intPtr(8), intPtr(1), 2, false},
+		{"uplink_pro", nil, intPtr(75), intPtr(100), intPtr(3), intPtr(20), intPtr(3), 3, false},
+		{"uplink_ultimate", nil, nil, nil, intPtr(10), nil, intPtr(10), 3, true},
+		{"super_user", nil, nil, nil, nil, nil, nil, 3, true},
 	}
 
 	for _, c := range cases {
@@ -37,6 +42,7 @@ func TestDefaultTierLimits_Exact(t *testing.T) {
 			t.Errorf("missing tier: %q", c.tier)
 			continue
 		}
+		assertIntPtrEq(t, c.tier+".channels", c.channels, got.Channels)
 		assertIntPtrEq(t, c.tier+".symbols", c.symbols, got.Symbols)
 		assertIntPtrEq(t, c.tier+".feeds", c.feeds, got.Feeds)
 		assertIntPtrEq(t, c.tier+".custom_feeds", c.customFeeds, got.CustomFeeds)
@@ -344,3 +350,95 @@ func TestPruneChannelConfig_UnknownChannelNoOp(t *testing.T) {
 		t.Error("unknown channel should not register any change")
 	}
 }
+
+func TestValidateChannelCount(t *testing.T) {
+	cases := []struct {
+		name          string
+		tier          string
+		before, after int
+		wantErr       bool
+	}{
+		{"free under cap", "free", 2, 3, false},
+		{"free over cap", "free", 3, 4, true},
+		{"free already over, unchanged", "free", 4, 4, false},
+		{"free already over, shrinking", "free", 4, 3, false},
+		{"unknown tier uses free", "mystery", 3, 4, true},
+		{"uplink unlimited", "uplink", 10, 11, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateChannelCount(tc.tier, tc.before, tc.after)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var tle *TierLimitError
+			if !errors.As(err, &tle) || tle.Field != "channels" || tle.Limit != 3 || tle.Got != tc.after {
+				t.Fatalf("unexpected error: %#v", err)
+			}
+			if tle.Tier != "free" {
+				t.Errorf("tier = %q, want free", tle.Tier)
+			}
+		})
+	}
+}
+
+func TestTierAtLeast(t *testing.T) {
+	cases := []struct {
+		tier, required string
+		want           bool
+	}{
+		{"free", "free", true},
+		{"free", "uplink", false},
+		{"uplink_pro", "uplink", true},
+		{"uplink_pro", "uplink_ultimate", false},
+		{"super_user", "uplink_ultimate", true},
+		{"mystery", "uplink", false},
+	}
+	for _, tc := range cases {
+		if got := tierAtLeast(tc.tier, tc.required); got != tc.want {
+			t.Errorf("tierAtLeast(%q, %q) = %v, want %v", tc.tier, tc.required, got, tc.want)
+		}
+	}
+}
+
+func TestRequireTier(t *testing.T) {
+	app := fiber.New()
+	app.Get("/gated", func(c *fiber.Ctx) error {
+		if roles := c.Get("X-Test-Roles"); roles != "" {
+			c.Locals("user_roles", strings.Split(roles, ","))
+		}
+		return c.Next()
+	}, RequireTier("uplink_pro"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for roles, want := range map[string]int{
+		"":                       fiber.StatusPaymentRequired,
+		"uplink":                 fiber.StatusPaymentRequired,
+		"uplink_pro":             fiber.StatusNoContent,
+		"uplink,uplink_ultimate": fiber.StatusNoContent,
+	} {
+		req := httptest.NewRequest("GET", "/gated", nil)
+		req.Header.Set("X-Test-Roles", roles)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("roles %q: status %d, want %d", roles, resp.StatusCode, want)
+		}
+		if want == fiber.StatusPaymentRequired {
+			var body struct {
+				Error  string
+				Detail struct{ Required string }
+			}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if body.Detail.Required != "uplink_pro" || !strings.Contains(body.Error, "Uplink Pro") {
+				t.Errorf("roles %q: body %+v does not name the required plan", roles, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Distributed tracing.
+//
+// Every gateway request gets an OpenTelemetry server span, continued from
+// an incoming traceparent if there is one. Outbound calls through the
+// shared HTTP clients (httpclient.go) get client spans and carry
+// traceparent onward, so a /dashboard trace shows each channel's
+// /internal/dashboard call, and the channel APIs continue the same trace.
+//
+// Spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT is
+// set; the standard OTEL_* variables (sampler, headers, service name)
+// apply. Without an endpoint, spans are not recorded but traceparent
+// still passes through, so a caller's trace isn't broken by the gateway.
+//
+// Span attributes follow the Sentry privacy rules: the user is the
+// salted hash from HashUserSub, never the raw sub.
+
+const tracerName = "github.com/brandon-relentnet/myscrollr/api/core"
+
+// traceChannelLocal is the Fiber local the proxy sets to the channel
+// serving the request.
+const traceChannelLocal = "trace_channel"
+
+var tracer = otel.Tracer(tracerName)
+
+// InitTracing installs the W3C propagators and, when an OTLP endpoint is
+// configured, an exporting tracer provider. The returned func flushes
+// pending spans on shutdown.
+func InitTracing(ctx context.Context) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		log.Printf("[Tracing] OTLP exporter init failed, spans disabled: %v", err)
+		return func(context.Context) error { return nil }
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "scrollr-core-api"),
+			attribute.String("deployment.environment", envOrDefault("ENVIRONMENT", "development")),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		log.Printf("[Tracing] resource detection: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	log.Println("[Tracing] OTLP span export enabled")
+	return provider.Shutdown
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// TracingMiddleware starts the server span for a request and hands its
+// context to handlers through c.UserContext().
+func TracingMiddleware(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), fiberHeaderCarrier{c})
+	ctx, span := tracer.Start(ctx, c.Method()+" "+c.Path(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", c.Method())))
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	route, _ := c.Locals(metricsRouteLocal).(string)
+	if route == "" {
+		route = c.Route().Path
+	}
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+	span.SetName(c.Method() + " " + route)
+	span.SetAttributes(
+		attribute.String("http.route", route),
+		attribute.Int("http.response.status_code", status),
+	)
+	if channel, ok := c.Locals(traceChannelLocal).(string); ok {
+		span.SetAttributes(attribute.String("scrollr.channel", channel))
+	}
+	if hash := HashUserSub(GetUserID(c)); hash != "" {
+		span.SetAttributes(attribute.String("enduser.id_hash", hash))
+	}
+	if cache := cacheStatusHeader(c); cache != "" {
+		span.SetAttributes(attribute.String("scrollr.cache", cache))
+	}
+	if err != nil || status >= 500 {
+		span.SetStatus(codes.Error, strconv.Itoa(status))
+	}
+	return err
+}
+
+// cacheStatusHeader returns the cache outcome a handler reported, from
+// either the gateway proxy cache or a handler's own Redis cache.
+func cacheStatusHeader(c *fiber.Ctx) string {
+	if v := c.GetRespHeader("X-Gateway-Cache"); v != "" {
+		return v
+	}
+	return c.GetRespHeader("X-Cache")
+}
+
+// fiberHeaderCarrier adapts fasthttp request headers for propagation.
+type fiberHeaderCarrier struct{ c *fiber.Ctx }
+
+func (f fiberHeaderCarrier) Get(key string) string { return f.c.Get(key) }
+func (f fiberHeaderCarrier) Set(key, value string) { f.c.Request().Header.Set(key, value) }
+func (f fiberHeaderCarrier) Keys() []string {
+	var keys []string
+	f.c.Request().Header.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
+
+// ─── Client spans ────────────────────────────────────────────────
+
+type traceChannelKey struct{}
+
+// withTraceChannel tags ctx with the channel an outbound call targets,
+// so its client span carries scrollr.channel.
+func withTraceChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, traceChannelKey{}, channel)
+}
+
+// startClientSpan opens the span for an outbound request and returns a
+// copy of req carrying its context and traceparent.
+func startClientSpan(req *http.Request, purpose string) (*http.Request, trace.Span) {
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method+" "+purpose,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("scrollr.http_client", purpose),
+		))
+	if channel, ok := ctx.Value(traceChannelKey{}).(string); ok {
+		span.SetAttributes(attribute.String("scrollr.channel", channel))
+	}
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, span
+}
+
+// endClientSpan records the outcome of an outbound request.
+func endClientSpan(span trace.Span, resp *http.Response, err error) {
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case resp.StatusCode >= 500:
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	default:
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	span.End()
+}
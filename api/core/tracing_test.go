@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTraceparentPropagatesThroughGateway(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	InitTracing(context.Background())
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var outbound string
+
+	app := fiber.New()
+	app.Use(TracingMiddleware)
+	app.Get("/trace-test", func(c *fiber.Ctx) error {
+		req, _ := http.NewRequestWithContext(withTraceChannel(c.UserContext(), "finance"), "GET", "http://finance-api/internal/dashboard", nil)
+		req, span := startClientSpan(req, "dashboard")
+		endClientSpan(span, &http.Response{StatusCode: 200}, nil)
+		outbound = req.Header.Get("traceparent")
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	req := httptest.NewRequest("GET", "/trace-test", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(outbound, "00-"+traceID+"-") {
+		t.Errorf("outbound traceparent = %q, want trace %s continued", outbound, traceID)
+	}
+}
+
+func TestStartClientSpanWithoutTrace(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://rss-api/internal/health", nil)
+	out, span := startClientSpan(req, "health")
+	endClientSpan(span, nil, context.DeadlineExceeded)
+	if out.Header.Get("traceparent") != "" {
+		t.Error("no parent trace: nothing to propagate")
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("startClientSpan must not modify the caller's request")
+	}
+}
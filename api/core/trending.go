@@ -0,0 +1,368 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Platform-wide trending lists.
+//
+// A daily job snapshots how many users follow each catalog item into
+// item_follow_counts, then ranks per channel:
+//
+//   - finance: symbols with the most net new followers over the week
+//   - rss:     catalog feeds with the most ticker clicks over the week
+//   - sports:  the most-followed favourite teams
+//
+// Counts are anonymised before they leave the job: anything below
+// TrendingMinCount is dropped and the rest are rounded down to a
+// multiple of TrendingCountBucket. Results go to trending_items and a
+// long-lived Redis copy; GET /trending is public and cacheable, and the
+// dashboard includes it for users who enable show_trending.
+
+// ─── Types ───────────────────────────────────────────────────────
+
+// TrendingItem is one entry in a channel's trending list.
+type TrendingItem struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Label  string `json:"label"`
+	Metric string `json:"metric"` // "added_this_week", "reads_this_week" or "followers"
+	Count  int64  `json:"count"`
+}
+
+// trendingChannels are the channels with a trending list, in display
+// order.
+var trendingChannels = []string{"finance", "rss", "sports"}
+
+// ─── Ranking ─────────────────────────────────────────────────────
+
+// anonymizedCount hides small and exact counts. Returns false when the
+// item should be left out entirely.
+func anonymizedCount(n int64) (int64, bool) {
+	if n < TrendingMinCount {
+		return 0, false
+	}
+	return n - n%TrendingCountBucket, true
+}
+
+// rankTrending sorts candidates by raw count, anonymises, and keeps the
+// top TrendingListSize. Ties break on key for a stable order.
+func rankTrending(candidates []TrendingItem) []TrendingItem {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Count != candidates[j].Count {
+			return candidates[i].Count > candidates[j].Count
+		}
+		return candidates[i].Key < candidates[j].Key
+	})
+	out := make([]TrendingItem, 0, TrendingListSize)
+	for _, it := range candidates {
+		if len(out) >= TrendingListSize {
+			break
+		}
+		n, ok := anonymizedCount(it.Count)
+		if !ok {
+			continue
+		}
+		it.Count = n
+		out = append(out, it)
+	}
+	return out
+}
+
+// ─── Job ─────────────────────────────────────────────────────────
+
+// StartTrendingJob recomputes trending lists once per UTC day. Replicas
+// coordinate through a per-day Redis marker.
+func StartTrendingJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(TrendingJobInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				day := engagementDay(time.Now())
+				ok, err := Rdb.SetNX(ctx, RedisTrendingComputedPrefix+day, "1", 48*time.Hour).Result()
+				if err != nil || !ok {
+					continue
+				}
+				if err := computeTrending(ctx, day); err != nil {
+					log.Printf("[Trending] compute failed: %v", err)
+					Rdb.Del(ctx, RedisTrendingComputedPrefix+day)
+				}
+			}
+		}
+	}()
+	log.Printf("[Trending] Job started (%s interval)", TrendingJobInterval)
+}
+
+func computeTrending(ctx context.Context, day string) error {
+	follows, err := loadAllFollows(ctx)
+	if err != nil {
+		return fmt.Errorf("load follows: %w", err)
+	}
+
+	counts := make(map[string]int64)
+	items := make(map[string]recItem)
+	for _, owned := range follows {
+		seen := make(map[string]bool, len(owned))
+		for _, it := range owned {
+			if id := it.id(); !seen[id] {
+				seen[id] = true
+				counts[id]++
+				items[id] = it
+			}
+		}
+	}
+
+	if err := snapshotFollowCounts(ctx, day, items, counts); err != nil {
+		return err
+	}
+
+	lists := map[string][]TrendingItem{
+		"finance": trendingSymbols(ctx, day, items, counts),
+		"rss":     trendingFeeds(ctx, items, counts),
+		"sports":  trendingTeams(items, counts),
+	}
+	return storeTrending(ctx, lists)
+}
+
+// snapshotFollowCounts records today's follower counts and drops
+// snapshots older than the retention window.
+func snapshotFollowCounts(ctx context.Context, day string, items map[string]recItem, counts map[string]int64) error {
+	rows := make([][]any, 0, len(counts))
+	for id, n := range counts {
+		it := items[id]
+		rows = append(rows, []any{it.Kind, it.Key, it.Label, n})
+	}
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM item_follow_counts WHERE day = $1::date`, day); err != nil {
+		return fmt.Errorf("clear snapshot: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE follow_snapshot (kind TEXT, item_key TEXT, label TEXT, followers BIGINT)
+		ON COMMIT DROP`); err != nil {
+		return fmt.Errorf("temp table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"follow_snapshot"},
+		[]string{"kind", "item_key", "label", "followers"}, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("copy snapshot: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO item_follow_counts (day, kind, item_key, label, followers)
+		SELECT $1::date, kind, item_key, label, followers FROM follow_snapshot`, day); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM item_follow_counts WHERE day < $1::date - $2::int`,
+		day, TrendingSnapshotRetentionDays); err != nil {
+		return fmt.Errorf("prune snapshots: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// trendingSymbols ranks symbols by net followers gained since the
+// snapshot a week ago. Symbols with no snapshot then count from zero.
+func trendingSymbols(ctx context.Context, day string, items map[string]recItem, counts map[string]int64) []TrendingItem {
+	before := make(map[string]int64)
+	rows, err := ReadPool().Query(ctx, `
+		SELECT item_key, followers FROM item_follow_counts
+		 WHERE kind = $1 AND day = $2::date - 7`, RecKindSymbol, day)
+	if err != nil {
+		log.Printf("[Trending] load week-old snapshot failed: %v", err)
+		return []TrendingItem{}
+	}
+	for rows.Next() {
+		var key string
+		var n int64
+		if rows.Scan(&key, &n) == nil {
+			before[key] = n
+		}
+	}
+	rows.Close()
+
+	var candidates []TrendingItem
+	for id, n := range counts {
+		it := items[id]
+		if it.Kind != RecKindSymbol {
+			continue
+		}
+		if gained := n - before[it.Key]; gained > 0 {
+			candidates = append(candidates, TrendingItem{
+				Kind: it.Kind, Key: it.Key, Label: it.Label, Metric: "added_this_week", Count: gained,
+			})
+		}
+	}
+	return rankTrending(candidates)
+}
+
+// trendingFeeds ranks catalog feeds by ticker clicks over the last week.
+// Only feeds that enough users follow are eligible, so a custom feed URL
+// reported as a click source never surfaces.
+func trendingFeeds(ctx context.Context, items map[string]recItem, counts map[string]int64) []TrendingItem {
+	rows, err := ReadPool().Query(ctx, `
+		SELECT source, SUM(clicks)::bigint
+		  FROM channel_engagement_daily
+		 WHERE channel_type = 'rss' AND source != ''
+		   AND day > (now() AT TIME ZONE 'UTC')::date - 7
+		 GROUP BY source`)
+	if err != nil {
+		log.Printf("[Trending] load feed clicks failed: %v", err)
+		return []TrendingItem{}
+	}
+	defer rows.Close()
+
+	var candidates []TrendingItem
+	for rows.Next() {
+		var url string
+		var clicks int64
+		if rows.Scan(&url, &clicks) != nil {
+			continue
+		}
+		id := recItem{Kind: RecKindFeed, Key: url}.id()
+		if counts[id] < TrendingMinCount {
+			continue
+		}
+		candidates = append(candidates, TrendingItem{
+			Kind: RecKindFeed, Key: url, Label: items[id].Label, Metric: "reads_this_week", Count: clicks,
+		})
+	}
+	return rankTrending(candidates)
+}
+
+func trendingTeams(items map[string]recItem, counts map[string]int64) []TrendingItem {
+	var candidates []TrendingItem
+	for id, n := range counts {
+		if it := items[id]; it.Kind == RecKindTeam {
+			candidates = append(candidates, TrendingItem{
+				Kind: it.Kind, Key: it.Key, Label: it.Label, Metric: "followers", Count: n,
+			})
+		}
+	}
+	return rankTrending(candidates)
+}
+
+func storeTrending(ctx context.Context, lists map[string][]TrendingItem) error {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM trending_items`); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+	for channelType, list := range lists {
+		for rank, it := range list {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO trending_items (channel_type, rank, kind, item_key, label, metric, count)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				channelType, rank, it.Kind, it.Key, it.Label, it.Metric, it.Count); err != nil {
+				return fmt.Errorf("insert %s: %w", channelType, err)
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if data, err := json.Marshal(lists); err == nil {
+		Rdb.Set(ctx, RedisTrendingKey, data, TrendingCacheTTL)
+	}
+	log.Printf("[Trending] Stored %d symbol(s), %d feed(s), %d team(s)",
+		len(lists["finance"]), len(lists["rss"]), len(lists["sports"]))
+	return nil
+}
+
+// ─── Reads ───────────────────────────────────────────────────────
+
+// GetTrending returns every channel's trending list, from Redis when
+// warm and from trending_items otherwise.
+func GetTrending(ctx context.Context) (map[string][]TrendingItem, error) {
+	if Rdb != nil {
+		if val, err := Rdb.Get(ctx, RedisTrendingKey).Bytes(); err == nil {
+			var cached map[string][]TrendingItem
+			if json.Unmarshal(val, &cached) == nil {
+				return cached, nil
+			}
+		}
+	}
+
+	rows, err := ReadPool().Query(ctx, `
+		SELECT channel_type, kind, item_key, label, metric, count
+		  FROM trending_items
+		 ORDER BY channel_type, rank`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make(map[string][]TrendingItem, len(trendingChannels))
+	for _, ch := range trendingChannels {
+		lists[ch] = []TrendingItem{}
+	}
+	for rows.Next() {
+		var channelType string
+		var it TrendingItem
+		if err := rows.Scan(&channelType, &it.Kind, &it.Key, &it.Label, &it.Metric, &it.Count); err != nil {
+			return nil, err
+		}
+		lists[channelType] = append(lists[channelType], it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if Rdb != nil {
+		if data, err := json.Marshal(lists); err == nil {
+			Rdb.Set(ctx, RedisTrendingKey, data, TrendingCacheTTL)
+		}
+	}
+	return lists, nil
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// HandleGetTrending returns trending items for one channel (?channel) or
+// all of them. Counts are anonymised and refreshed daily.
+//
+// @Summary Trending items
+// @Tags Trending
+// @Produce json
+// @Param channel query string false "finance, rss or sports"
+// @Success 200 {object} object{trending=map[string][]TrendingItem}
+// @Router /trending [get]
+func HandleGetTrending(c *fiber.Ctx) error {
+	channel := c.Query("channel")
+	if channel != "" && channel != "finance" && channel != "rss" && channel != "sports" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error", Error: "channel must be one of finance, rss, sports",
+		})
+	}
+
+	lists, err := GetTrending(c.Context())
+	if err != nil {
+		log.Printf("[Trending] load failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to load trending",
+		})
+	}
+	if channel != "" {
+		lists = map[string][]TrendingItem{channel: lists[channel]}
+	}
+
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(TrendingHTTPMaxAge.Seconds())))
+	return c.JSON(fiber.Map{"trending": lists})
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnonymizedCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want int64
+		ok   bool
+	}{
+		{0, 0, false},
+		{TrendingMinCount - 1, 0, false},
+		{5, 5, true},
+		{9, 5, true},
+		{10, 10, true},
+		{137, 135, true},
+	}
+	for _, tt := range tests {
+		got, ok := anonymizedCount(tt.n)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("anonymizedCount(%d) = (%d, %v), want (%d, %v)", tt.n, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRankTrending_OrderAndSuppression(t *testing.T) {
+	got := rankTrending([]TrendingItem{
+		{Key: "MSFT", Count: 12},
+		{Key: "TINY", Count: 2},
+		{Key: "AAPL", Count: 41},
+		{Key: "GOOG", Count: 12},
+	})
+	want := []struct {
+		key   string
+		count int64
+	}{{"AAPL", 40}, {"GOOG", 10}, {"MSFT", 10}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Key != w.key || got[i].Count != w.count {
+			t.Errorf("item %d = %s/%d, want %s/%d", i, got[i].Key, got[i].Count, w.key, w.count)
+		}
+	}
+}
+
+func TestRankTrending_Truncates(t *testing.T) {
+	var candidates []TrendingItem
+	for i := 0; i < TrendingListSize+5; i++ {
+		candidates = append(candidates, TrendingItem{Key: fmt.Sprintf("k%02d", i), Count: int64(100 + i)})
+	}
+	if got := rankTrending(candidates); len(got) != TrendingListSize {
+		t.Errorf("got %d items, want %d", len(got), TrendingListSize)
+	}
+}
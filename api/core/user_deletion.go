@@ -2,6 +2,9 @@ package core
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"time"
@@ -9,6 +12,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	stripesubscription "github.com/stripe/stripe-go/v82/subscription"
 )
 
 // ─── Constants ──────────────────────────────────────────────────────
@@ -34,13 +39,20 @@ const GDPRPurgeInterval = time.Hour
 // account deletion request. Mirrored on the client side.
 const gdprConfirmPhrase = "DELETE MY ACCOUNT"
 
+// AccountDeleteTokenTTL is how long the token from
+// POST /users/me/delete/token stays valid for DELETE /users/me.
+const AccountDeleteTokenTTL = 10 * time.Minute
+
+// redisAccountDeleteTokenPrefix keys the pending immediate-deletion
+// token: gdpr:delete_token:{logto_sub} -> sha256(token).
+const redisAccountDeleteTokenPrefix = "gdpr:delete_token:"
+
 // ─── Data export ────────────────────────────────────────────────────
 
 // HandleExportUserData serves a JSON archive of everything we store about
 // the authenticated user. Returned as an attachment so browsers download
-// rather than display it inline. Security-sensitive fields (Yahoo OAuth
-// refresh tokens, Stripe IDs that are server-internal) are intentionally
-// omitted.
+// rather than display it inline. The archive is built synchronously; the
+// queued variant (POST /users/me/export) lives in data_export.go.
 func HandleExportUserData(c *fiber.Ctx) error {
 	userID := GetUserID(c)
 	if userID == "" {
@@ -50,88 +62,10 @@ func HandleExportUserData(c *fiber.Ctx) error {
 		})
 	}
 
-	ctx := context.Background()
-	archive := map[string]any{
-		"exported_at": time.Now().UTC().Format(time.RFC3339),
-		"user": map[string]any{
-			"logto_sub": userID,
-			"email":     c.Locals("user_email"),
-			"roles":     GetUserRoles(c),
-		},
-		"notes": "Yahoo OAuth tokens are omitted from this export for security.",
-	}
-
-	// preferences
-	if prefs, err := GetOrCreatePreferences(userID); err == nil {
-		archive["preferences"] = prefs
-	} else {
-		log.Printf("[Export] preferences for %s: %v", userID, err)
-	}
-
-	// channels
-	if chans, err := GetUserChannels(userID); err == nil {
-		archive["channels"] = chans
-	} else {
-		log.Printf("[Export] channels for %s: %v", userID, err)
-		archive["channels"] = []any{}
-	}
-
-	// subscription (stripe_customers minus server-internal IDs)
-	subscription := map[string]any{}
-	var plan, status string
-	var currentPeriodEnd *time.Time
-	var lifetime bool
-	err := DBPool.QueryRow(ctx,
-		`SELECT plan, status, current_period_end, lifetime
-		   FROM stripe_customers WHERE logto_sub = $1`,
-		userID,
-	).Scan(&plan, &status, &currentPeriodEnd, &lifetime)
-	if err == nil {
-		subscription["plan"] = plan
-		subscription["status"] = status
-		if currentPeriodEnd != nil {
-			subscription["current_period_end"] = currentPeriodEnd
-		}
-		subscription["lifetime"] = lifetime
-	} else if err != pgx.ErrNoRows {
-		log.Printf("[Export] subscription for %s: %v", userID, err)
-	}
-	archive["subscription"] = subscription
-
-	// fantasy leagues (key + name + season; no tokens)
-	fantasyRows, err := DBPool.Query(ctx, `
-		SELECT yul.league_key, COALESCE(yl.name, '') AS name, COALESCE(yl.season, '') AS season
-		FROM yahoo_user_leagues yul
-		LEFT JOIN yahoo_users yu ON yu.guid = yul.guid
-		LEFT JOIN yahoo_leagues yl ON yl.league_key = yul.league_key
-		WHERE yu.logto_sub = $1
-	`, userID)
-	leagues := make([]map[string]any, 0)
-	if err == nil {
-		defer fantasyRows.Close()
-		for fantasyRows.Next() {
-			var key, name, season string
-			if err := fantasyRows.Scan(&key, &name, &season); err == nil {
-				leagues = append(leagues, map[string]any{
-					"league_key": key,
-					"name":       name,
-					"season":     season,
-				})
-			}
-		}
-	} else {
-		log.Printf("[Export] fantasy leagues for %s: %v", userID, err)
-	}
-	archive["fantasy_leagues"] = leagues
-
-	// deletion status, if any
-	if status, _ := getUserDeletionStatus(ctx, userID); status != nil {
-		archive["account_deletion"] = status
-	}
+	archive := buildUserDataExport(context.Background(), exportIdentityFromContext(c))
 
-	filename := fmt.Sprintf("myscrollr-export-%s.json", time.Now().UTC().Format("2006-01-02"))
 	c.Set("Content-Type", "application/json")
-	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", dataExportFilename(DataExportJSON, time.Now())))
 
 	return c.JSON(archive)
 }
@@ -211,6 +145,7 @@ func HandleRequestAccountDeletion(c *fiber.Ctx) error {
 	}
 
 	log.Printf("[GDPR] Account deletion scheduled: user=%s purge_at=%s", userID, purgeAt.Format(time.RFC3339))
+	AuditSelf(c, AuditAccountDeleteRequest, map[string]any{"purge_at": purgeAt})
 
 	// Overview's gdpr block flipped from "none" to "pending".
 	InvalidateOverviewCache(ctx, userID)
@@ -255,6 +190,7 @@ func HandleCancelAccountDeletion(c *fiber.Ctx) error {
 	}
 
 	log.Printf("[GDPR] Account deletion canceled: user=%s", userID)
+	AuditSelf(c, AuditAccountDeleteCancel, nil)
 
 	// Overview's gdpr block flipped back from "pending" to "canceled".
 	InvalidateOverviewCache(context.Background(), userID)
@@ -321,6 +257,180 @@ func getUserDeletionStatus(ctx context.Context, logtoSub string) (map[string]any
 	return out, nil
 }
 
+// ─── Immediate deletion ─────────────────────────────────────────────
+
+// HandleIssueAccountDeleteToken is step one of immediate deletion: the
+// user types the confirmation phrase and gets a single-use token, valid
+// for AccountDeleteTokenTTL, to pass to DELETE /users/me. Issuing a new
+// token replaces the previous one.
+func HandleIssueAccountDeleteToken(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if req.Confirm != gdprConfirmPhrase {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Confirmation must be exactly %q", gdprConfirmPhrase),
+		})
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("[GDPR] delete token for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to issue confirmation token",
+		})
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := time.Now().UTC().Add(AccountDeleteTokenTTL)
+	if err := Rdb.Set(context.Background(), redisAccountDeleteTokenPrefix+userID,
+		hashAPIKey(token), AccountDeleteTokenTTL).Err(); err != nil {
+		log.Printf("[GDPR] store delete token for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to issue confirmation token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+// HandleDeleteAccount permanently deletes the account now, skipping the
+// 30-day grace window of POST /users/me/delete. Requires a token from
+// POST /users/me/delete/token. A live subscription is canceled in
+// Stripe first so the user isn't billed again; lifetime rows are
+// anonymized by the purge as usual.
+func HandleDeleteAccount(c *fiber.Ctx) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "A confirmation token from POST /users/me/delete/token is required",
+		})
+	}
+
+	ctx := context.Background()
+
+	// Single use: GETDEL burns the token whether or not it matches.
+	stored, err := Rdb.GetDel(ctx, redisAccountDeleteTokenPrefix+userID).Result()
+	if err != nil && err != redis.Nil {
+		log.Printf("[GDPR] read delete token for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete account",
+		})
+	}
+	if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(hashAPIKey(req.Token))) != 1 {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid or expired confirmation token",
+		})
+	}
+
+	if err := cancelSubscriptionForDeletion(ctx, userID); err != nil {
+		log.Printf("[GDPR] cancel subscription for %s: %v", userID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to cancel your subscription; your account was not deleted",
+		})
+	}
+
+	// purgeUserAccount finishes by marking this row purged. requested_at
+	// is now, so the grace-window worker never picks it up.
+	now := time.Now().UTC()
+	if _, err := DBPool.Exec(ctx, `
+		INSERT INTO user_deletion_requests (logto_sub, requested_at, purge_at, status)
+		VALUES ($1, $2, $2, 'pending')
+		ON CONFLICT (logto_sub) DO UPDATE SET
+			requested_at = EXCLUDED.requested_at,
+			purge_at     = EXCLUDED.purge_at,
+			status       = 'pending',
+			canceled_at  = NULL,
+			purged_at    = NULL
+	`, userID, now); err != nil {
+		log.Printf("[GDPR] record deletion for %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete account",
+		})
+	}
+
+	if err := purgeUserAccount(ctx, userID); err != nil {
+		// The row stays pending; the purge worker retries it once it
+		// clears the GDPRMinGraceForPurge floor.
+		log.Printf("[GDPR] Immediate purge for %s failed: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Account deletion did not complete; it will be retried automatically",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":    "purged",
+		"purged_at": time.Now().UTC(),
+	})
+}
+
+// cancelSubscriptionForDeletion ends a live, non-lifetime Stripe
+// subscription immediately (not at period end: the account is about to
+// stop existing).
+func cancelSubscriptionForDeletion(ctx context.Context, logtoSub string) error {
+	var subID *string
+	var status string
+	var lifetime bool
+	err := DBPool.QueryRow(ctx,
+		`SELECT stripe_subscription_id, status, lifetime FROM stripe_customers WHERE logto_sub = $1`,
+		logtoSub,
+	).Scan(&subID, &status, &lifetime)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lifetime || subID == nil {
+		return nil
+	}
+	switch status {
+	case "active", "trialing", "canceling", "past_due":
+	default:
+		return nil
+	}
+	if _, err := stripesubscription.Cancel(*subID, nil); err != nil {
+		return fmt.Errorf("stripe cancel %s: %w", *subID, err)
+	}
+	log.Printf("[GDPR] Canceled subscription %s for %s ahead of deletion", *subID, logtoSub)
+	return nil
+}
+
 // ─── Background purge worker ────────────────────────────────────────
 
 // StartGDPRPurgeWorker kicks off a background goroutine that scans
@@ -398,6 +508,13 @@ func purgeUserAccount(ctx context.Context, logtoSub string) error {
 		return fmt.Errorf("delete logto user: %w", err)
 	}
 
+	// Read the channels before they're deleted so their Redis subscriber
+	// sets can be cleaned up after commit.
+	channels, err := GetUserChannels(logtoSub)
+	if err != nil {
+		return fmt.Errorf("read user_channels: %w", err)
+	}
+
 	// Step 2: Local DB cascade in a transaction.
 	tx, err := DBPool.Begin(ctx)
 	if err != nil {
@@ -412,19 +529,61 @@ func purgeUserAccount(ctx context.Context, logtoSub string) error {
 		return fmt.Errorf("delete user_channels: %w", err)
 	}
 
-	// Fantasy junction + OAuth tokens. Junction row is keyed on guid,
-	// which maps via yahoo_users.logto_sub. Delete junction rows first
-	// then the yahoo_users row.
-	if _, err := tx.Exec(ctx, `
-		DELETE FROM yahoo_user_leagues
-		 WHERE guid IN (SELECT guid FROM yahoo_users WHERE logto_sub = $1)
-	`, logtoSub); err != nil {
-		return fmt.Errorf("delete yahoo_user_leagues: %w", err)
+	if err := deleteFantasyUserTx(ctx, tx, logtoSub); err != nil {
+		return err
 	}
+
+	// Finance price alerts (table owned by the finance channel; the
+	// webhook URLs and secrets are the user's).
 	if _, err := tx.Exec(ctx,
-		`DELETE FROM yahoo_users WHERE logto_sub = $1`, logtoSub,
+		`DELETE FROM price_alerts WHERE logto_sub = $1`, logtoSub,
 	); err != nil {
-		return fmt.Errorf("delete yahoo_users: %w", err)
+		return fmt.Errorf("delete price_alerts: %w", err)
+	}
+
+	// Third-party OAuth connections (connections.go).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM oauth_connections WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete oauth_connections: %w", err)
+	}
+
+	// Notification inbox and preferences (notifications.go).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM notifications WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete notifications: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM notification_preferences WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete notification_preferences: %w", err)
+	}
+
+	// Saved searches and share links (saved_searches.go, short_links.go).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM saved_searches WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete saved_searches: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM short_links WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete short_links: %w", err)
+	}
+
+	// Extension/desktop sessions (extension_sessions.go).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM extension_sessions WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete extension_sessions: %w", err)
+	}
+
+	// Data export archives (data_export.go).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM data_exports WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete data_exports: %w", err)
 	}
 
 	// Stripe customers: anonymize if lifetime, delete otherwise.
@@ -456,7 +615,110 @@ func purgeUserAccount(ctx context.Context, logtoSub string) error {
 		return fmt.Errorf("read stripe_customers: %w", err)
 	}
 
+	// Admin billing actions stay for accounting but lose the link to
+	// the account and any free-text note.
+	if _, err := tx.Exec(ctx, `
+		UPDATE billing_audit_log SET logto_sub = NULL, note = NULL WHERE logto_sub = $1
+	`, logtoSub); err != nil {
+		return fmt.Errorf("anonymize billing_audit_log: %w", err)
+	}
+
+	// Account activity goes with the account.
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM audit_events WHERE target_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete audit_events: %w", err)
+	}
+
+	// Support-mode reads of this account stay in the admin trail,
+	// detached from it.
+	if _, err := tx.Exec(ctx,
+		`UPDATE admin_audit SET target_sub = NULL WHERE target_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("anonymize admin_audit: %w", err)
+	}
+
+	// Feedback rows are kept for triage history but detached from the
+	// account. Diagnostics go too — the snapshot carries channel config.
+	if _, err := tx.Exec(ctx, `
+		UPDATE feedback
+		   SET logto_sub = NULL, diagnostics = NULL, updated_at = now()
+		 WHERE logto_sub = $1
+	`, logtoSub); err != nil {
+		return fmt.Errorf("anonymize feedback: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM api_keys WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete api_keys: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM display_tokens WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete display_tokens: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM api_usage WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete api_usage: %w", err)
+	}
+	// Invoices stay in Stripe for accounting; only the local cache goes.
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM invoices WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete invoices: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM experiment_exposures WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete experiment_exposures: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM onboarding_defaults WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete onboarding_defaults: %w", err)
+	}
+
+	// Team seats: leaving a team frees the seat. Owners can't get here
+	// with members — deletion requires the subscription to be canceled,
+	// which dissolves the team.
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM team_members WHERE member_sub = $1 OR owner_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete team_members: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM team_invitations WHERE owner_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete team_invitations: %w", err)
+	}
+
+	// Organizations: leave all of them. Orgs this leaves adminless get a
+	// new admin; empty ones are deleted after commit.
+	emptyOrgs, err := leaveOrganizationsTx(ctx, tx, logtoSub)
+	if err != nil {
+		return fmt.Errorf("leave organizations: %w", err)
+	}
+
 	// Preferences (must come after anything that might reference them).
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM recommendations WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete recommendations: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM ticker_clicks WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete ticker_clicks: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM analytics_events WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete analytics_events: %w", err)
+	}
 	if _, err := tx.Exec(ctx,
 		`DELETE FROM user_preferences WHERE logto_sub = $1`, logtoSub,
 	); err != nil {
@@ -479,7 +741,83 @@ func purgeUserAccount(ctx context.Context, logtoSub string) error {
 	// User row is gone; drop any cached overview so a stale background
 	// poll doesn't briefly return data for a purged account.
 	InvalidateOverviewCache(ctx, logtoSub)
+	InvalidateUserCaches(logtoSub)
+	dropDashboardSnapshot(ctx, logtoSub)
+	forgetUserTopics(ctx, logtoSub)
+
+	// Stop CDC fan-out to the user and let channels drop their own state.
+	for _, ch := range channels {
+		removeSubscriberSets(ctx, logtoSub, ch.ChannelType, ch.Config)
+		callChannelLifecycle(ctx, ch.ChannelType, "deleted", logtoSub, ch.Config, nil, nil)
+	}
+
+	// Open SSE/WebSocket streams would otherwise keep serving until the
+	// access token expires.
+	if err := DisconnectUser(logtoSub); err != nil {
+		log.Printf("[GDPR Purge] Failed to disconnect streams for %s: %v", logtoSub, err)
+	}
+
+	for _, orgID := range emptyOrgs {
+		if err := deleteOrganization(ctx, orgID); err != nil {
+			log.Printf("[GDPR Purge] Failed to delete empty org %d: %v", orgID, err)
+		}
+	}
 
 	log.Printf("[GDPR Purge] Completed purge for %s", logtoSub)
 	return nil
 }
+
+// deleteFantasyUserTx drops the user's fantasy links (tables owned by the
+// fantasy channel). Yahoo leagues are keyed on guid, which maps via
+// yahoo_users.logto_sub, so the junction rows go first. Sleeper leagues
+// are shared; one no other user follows is dropped with its rosters, as
+// unlinking does.
+func deleteFantasyUserTx(ctx context.Context, tx pgx.Tx, logtoSub string) error {
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM yahoo_user_leagues
+		 WHERE guid IN (SELECT guid FROM yahoo_users WHERE logto_sub = $1)
+	`, logtoSub); err != nil {
+		return fmt.Errorf("delete yahoo_user_leagues: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM yahoo_users WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete yahoo_users: %w", err)
+	}
+
+	var leagueIDs []string
+	rows, err := tx.Query(ctx, `
+		DELETE FROM sleeper_user_leagues WHERE logto_sub = $1
+		RETURNING league_id
+	`, logtoSub)
+	if err != nil {
+		return fmt.Errorf("delete sleeper_user_leagues: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("delete sleeper_user_leagues: %w", err)
+		}
+		leagueIDs = append(leagueIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("delete sleeper_user_leagues: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM sleeper_users WHERE logto_sub = $1`, logtoSub,
+	); err != nil {
+		return fmt.Errorf("delete sleeper_users: %w", err)
+	}
+	if len(leagueIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM sleeper_leagues l
+			 WHERE l.league_id = ANY($1)
+			   AND NOT EXISTS (SELECT 1 FROM sleeper_user_leagues ul WHERE ul.league_id = l.league_id)
+		`, leagueIDs); err != nil {
+			return fmt.Errorf("delete sleeper_leagues: %w", err)
+		}
+	}
+	return nil
+}
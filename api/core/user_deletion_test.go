@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v82"
+)
+
+func TestDeleteFantasyUserTx_RemovesSleeperLinks(t *testing.T) {
+	if !testDBAvailable(t) {
+		return
+	}
+	userID := makeTestUser()
+	otherID := userID + "-other"
+	ctx := context.Background()
+	defer func() {
+		DBPool.Exec(ctx, `DELETE FROM sleeper_users WHERE logto_sub IN ($1, $2)`, userID, otherID)
+		DBPool.Exec(ctx, `DELETE FROM sleeper_leagues WHERE league_id IN ('del-solo', 'del-shared')`)
+	}()
+
+	mustExec(t, `INSERT INTO sleeper_users (logto_sub, user_id, username) VALUES ($1, 'u1', 'one'), ($2, 'u2', 'two')`, userID, otherID)
+	mustExec(t, `
+		INSERT INTO sleeper_leagues (league_id, name, sport, season, data)
+		VALUES ('del-solo', 'Solo', 'nfl', '2026', '{}'), ('del-shared', 'Shared', 'nfl', '2026', '{}')`)
+	mustExec(t, `
+		INSERT INTO sleeper_user_leagues (logto_sub, league_id)
+		VALUES ($1, 'del-solo'), ($1, 'del-shared'), ($2, 'del-shared')`, userID, otherID)
+
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if err := deleteFantasyUserTx(ctx, tx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var users, links, leagues int
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM sleeper_users WHERE logto_sub = $1`, userID).Scan(&users)
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM sleeper_user_leagues WHERE logto_sub = $1`, userID).Scan(&links)
+	if users != 0 || links != 0 {
+		t.Errorf("%d sleeper_users and %d sleeper_user_leagues rows left for the deleted user", users, links)
+	}
+
+	// The league only the deleted user followed goes; the shared one stays.
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM sleeper_leagues WHERE league_id IN ('del-solo', 'del-shared')`).Scan(&leagues)
+	if leagues != 1 {
+		t.Errorf("%d leagues left, want only the shared one", leagues)
+	}
+}
+
+// accountDeleteApp mounts the two immediate-deletion steps for userID.
+func accountDeleteApp(userID string) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	app.Post("/users/me/delete/token", HandleIssueAccountDeleteToken)
+	app.Delete("/users/me", HandleDeleteAccount)
+	return app
+}
+
+func accountDeleteRequest(t *testing.T, app *fiber.App, method, path, body string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func issueAccountDeleteToken(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	resp := accountDeleteRequest(t, app, "POST", "/users/me/delete/token", `{"confirm":"`+gdprConfirmPhrase+`"}`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("issue token: status = %d, want 200", resp.StatusCode)
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Token == "" {
+		t.Fatalf("issue token: no token in response (%v)", err)
+	}
+	return out.Token
+}
+
+func TestIssueAccountDeleteToken_RequiresConfirmPhrase(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app := accountDeleteApp("user-phrase")
+
+	for _, body := range []string{`{}`, `{"confirm":""}`, `{"confirm":"delete my account"}`, `{"confirm":"DELETE MY ACCOUNT "}`} {
+		resp := accountDeleteRequest(t, app, "POST", "/users/me/delete/token", body)
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("body %s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+	if mr.Exists(redisAccountDeleteTokenPrefix + "user-phrase") {
+		t.Error("a token was stored without the confirmation phrase")
+	}
+}
+
+func TestDeleteAccount_TokenIsSingleUse(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app := accountDeleteApp("user-reuse")
+
+	// A wrong guess burns the token, so the real one no longer works.
+	token := issueAccountDeleteToken(t, app)
+	resp := accountDeleteRequest(t, app, "DELETE", "/users/me", `{"token":"guess"}`)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("wrong token: status = %d, want 403", resp.StatusCode)
+	}
+	if mr.Exists(redisAccountDeleteTokenPrefix + "user-reuse") {
+		t.Fatal("token survived a failed attempt")
+	}
+	resp = accountDeleteRequest(t, app, "DELETE", "/users/me", `{"token":"`+token+`"}`)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("burned token: status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestDeleteAccount_RejectsExpiredToken(t *testing.T) {
+	mr, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	app := accountDeleteApp("user-expired")
+
+	token := issueAccountDeleteToken(t, app)
+	mr.FastForward(AccountDeleteTokenTTL + time.Second)
+
+	resp := accountDeleteRequest(t, app, "DELETE", "/users/me", `{"token":"`+token+`"}`)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("expired token: status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestDeleteAccount_StripeFailureKeepsAccount(t *testing.T) {
+	if !testDBAvailable(t) {
+		return
+	}
+	_, cleanup := setupMiniRedis(t)
+	defer cleanup()
+	userID := makeTestUser()
+	ctx := context.Background()
+	t.Cleanup(func() {
+		_, _ = DBPool.Exec(ctx, `DELETE FROM stripe_customers WHERE logto_sub = $1`, userID)
+		_, _ = DBPool.Exec(ctx, `DELETE FROM user_deletion_requests WHERE logto_sub = $1`, userID)
+	})
+	mustExec(t, `
+		INSERT INTO stripe_customers (logto_sub, stripe_customer_id, stripe_subscription_id, plan, status)
+		VALUES ($1, $1, 'sub_delete_test', 'monthly', 'active')`, userID)
+
+	// Every Stripe call fails.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"type":"api_error","message":"boom"}}`))
+	}))
+	defer srv.Close()
+	prevKey, prevBackend := stripe.Key, stripe.GetBackend(stripe.APIBackend)
+	stripe.Key = "sk_test_delete"
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend,
+		&stripe.BackendConfig{URL: stripe.String(srv.URL), MaxNetworkRetries: stripe.Int64(0)}))
+	t.Cleanup(func() {
+		stripe.Key = prevKey
+		stripe.SetBackend(stripe.APIBackend, prevBackend)
+	})
+
+	app := accountDeleteApp(userID)
+	token := issueAccountDeleteToken(t, app)
+	resp := accountDeleteRequest(t, app, "DELETE", "/users/me", `{"token":"`+token+`"}`)
+	if resp.StatusCode != fiber.StatusBadGateway {
+		t.Fatalf("status = %d when Stripe fails, want 502", resp.StatusCode)
+	}
+
+	var customers, requests int
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM stripe_customers WHERE logto_sub = $1`, userID).Scan(&customers)
+	DBPool.QueryRow(ctx, `SELECT COUNT(*) FROM user_deletion_requests WHERE logto_sub = $1`, userID).Scan(&requests)
+	if customers != 1 || requests != 0 {
+		t.Errorf("after a failed cancel: %d stripe_customers and %d deletion requests, want 1 and 0", customers, requests)
+	}
+}
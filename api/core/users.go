@@ -1,6 +1,11 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -12,6 +17,15 @@ func GetUserID(c *fiber.Ctx) string {
 	return ""
 }
 
+// GetUsername returns the Logto username claim, or "" when the user has
+// not claimed one yet.
+func GetUsername(c *fiber.Ctx) string {
+	if username, ok := c.Locals("user_username").(string); ok {
+		return username
+	}
+	return ""
+}
+
 // GetUserRoles extracts the user roles from the Fiber context (set by LogtoAuth middleware).
 func GetUserRoles(c *fiber.Ctx) []string {
 	if roles, ok := c.Locals("user_roles").([]string); ok {
@@ -44,3 +58,97 @@ func GetProfileByUsername(c *fiber.Ctx) error {
 		"username": username,
 	})
 }
+
+// PublicWatchlist is what a public profile shares. Only channels whose
+// config sets "show_on_profile": true appear; the rest of the config
+// (API keys, display settings) is never exposed.
+type PublicWatchlist struct {
+	Username string   `json:"username"`
+	Symbols  []string `json:"symbols,omitempty"`
+	Leagues  []string `json:"leagues,omitempty"`
+}
+
+// sharedOnProfile reports whether a channel config opted in to the
+// public watchlist.
+func sharedOnProfile(config map[string]interface{}) bool {
+	v, _ := config["show_on_profile"].(bool)
+	return v
+}
+
+// buildPublicWatchlist folds the user's opted-in channel configs into
+// the public shape. Channel types other than finance and sports are
+// ignored.
+func buildPublicWatchlist(username string, configs map[string]map[string]interface{}) PublicWatchlist {
+	w := PublicWatchlist{Username: username}
+	if cfg := configs["finance"]; sharedOnProfile(cfg) {
+		w.Symbols = extractSymbolsFromConfig(cfg)
+	}
+	if cfg := configs["sports"]; sharedOnProfile(cfg) {
+		w.Leagues = extractSportsLeaguesFromConfig(cfg)
+	}
+	return w
+}
+
+// GetPublicWatchlist returns the finance symbols and sports leagues a
+// user shares on their public profile.
+// @Summary Get a user's public watchlist
+// @Description Returns the symbols and leagues a public profile opted in to share. Private and unknown profiles both return 404.
+// @Tags Users
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} PublicWatchlist
+// @Failure 404 {object} ErrorResponse
+// @Router /users/{username}/watchlist [get]
+func GetPublicWatchlist(c *fiber.Ctx) error {
+	username := strings.ToLower(c.Params("username"))
+	if !usernameRegex.MatchString(username) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Profile not found",
+		})
+	}
+
+	ctx := context.Background()
+	var logtoSub string
+	err := DBPool.QueryRow(ctx,
+		`SELECT logto_sub FROM user_preferences WHERE username = $1 AND is_public`,
+		username,
+	).Scan(&logtoSub)
+	if err != nil {
+		// Same answer for private and unknown so the endpoint cannot be
+		// used to enumerate accounts.
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Profile not found",
+		})
+	}
+
+	rows, err := DBPool.Query(ctx,
+		`SELECT channel_type, config FROM user_channels
+		 WHERE logto_sub = $1 AND enabled AND channel_type IN ('finance', 'sports')`,
+		logtoSub,
+	)
+	if err != nil {
+		log.Printf("[Users] Watchlist query failed for %s: %v", username, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to fetch watchlist",
+		})
+	}
+	defer rows.Close()
+
+	configs := make(map[string]map[string]interface{})
+	for rows.Next() {
+		var channelType string
+		var raw []byte
+		if err := rows.Scan(&channelType, &raw); err != nil {
+			continue
+		}
+		var cfg map[string]interface{}
+		if json.Unmarshal(raw, &cfg) == nil {
+			configs[channelType] = cfg
+		}
+	}
+
+	return c.JSON(buildPublicWatchlist(username, configs))
+}
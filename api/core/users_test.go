@@ -0,0 +1,34 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildPublicWatchlist(t *testing.T) {
+	configs := map[string]map[string]interface{}{
+		"finance": {"symbols": []interface{}{"AAPL", "", "MSFT"}, "show_on_profile": true},
+		"sports":  {"leagues": []interface{}{"NFL"}},
+		"rss":     {"feeds": []interface{}{}, "show_on_profile": true},
+	}
+	w := buildPublicWatchlist("alice", configs)
+	if !reflect.DeepEqual(w.Symbols, []string{"AAPL", "MSFT"}) {
+		t.Errorf("symbols = %v", w.Symbols)
+	}
+	if w.Leagues != nil {
+		t.Errorf("sports did not opt in but leagues = %v", w.Leagues)
+	}
+
+	configs["sports"]["show_on_profile"] = "true" // must be a real boolean
+	if w := buildPublicWatchlist("alice", configs); w.Leagues != nil {
+		t.Errorf("string flag shared leagues %v", w.Leagues)
+	}
+	configs["sports"]["show_on_profile"] = true
+	if w := buildPublicWatchlist("alice", configs); !reflect.DeepEqual(w.Leagues, []string{"NFL"}) {
+		t.Errorf("leagues = %v", w.Leagues)
+	}
+
+	if w := buildPublicWatchlist("bob", nil); w.Username != "bob" || w.Symbols != nil || w.Leagues != nil {
+		t.Errorf("empty watchlist = %+v", w)
+	}
+}
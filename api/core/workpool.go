@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Bounded background work.
+//
+// Request handlers used to start a goroutine per side effect (subscription
+// syncs, cache invalidation, webhook follow-ups), so a burst of traffic
+// meant a burst of goroutines with nothing to stop them. Side effects now
+// go through one of two WorkPools with a fixed number of workers and a
+// bounded queue:
+//
+//   - BackgroundPool: short Redis/Postgres work on the request path.
+//   - ExternalPool:   slow calls to third parties (osTicket, Discord,
+//     the triage model), kept apart so they can't starve the former.
+//
+// Tasks receive the pool's context, which is cancelled on shutdown. When
+// a queue is full, Submit drops the task and counts it; SubmitOrRun runs
+// it on the caller instead, for work that must not be lost. Queue depth
+// and counters are reported at GET /admin/workers.
+
+// ─── WorkPool ────────────────────────────────────────────────────
+
+// WorkPool runs fire-and-forget tasks on a fixed set of workers.
+type WorkPool struct {
+	name    string
+	workers int
+	tasks   chan poolTask
+
+	mu      sync.RWMutex
+	ctx     context.Context
+	started bool
+
+	submitted atomic.Int64
+	completed atomic.Int64
+	dropped   atomic.Int64
+	inline    atomic.Int64
+	panics    atomic.Int64
+	running   atomic.Int64
+
+	dropLogMu   sync.Mutex
+	dropLogLast time.Time
+}
+
+type poolTask struct {
+	name string
+	fn   func(ctx context.Context)
+}
+
+// WorkPoolStats is a point-in-time view of a pool.
+type WorkPoolStats struct {
+	Name       string `json:"name"`
+	Workers    int    `json:"workers"`
+	Running    int64  `json:"running"`
+	QueueDepth int    `json:"queue_depth"`
+	QueueCap   int    `json:"queue_capacity"`
+	Submitted  int64  `json:"submitted"`
+	Completed  int64  `json:"completed"`
+	Dropped    int64  `json:"dropped"`
+	RanInline  int64  `json:"ran_inline"`
+	Panics     int64  `json:"panics"`
+}
+
+// NewWorkPool creates a pool. It does nothing until Start.
+func NewWorkPool(name string, workers, queueSize int) *WorkPool {
+	return &WorkPool{
+		name:    name,
+		workers: workers,
+		tasks:   make(chan poolTask, queueSize),
+	}
+}
+
+// Start launches the workers. They stop, abandoning queued tasks, when
+// ctx is cancelled.
+func (p *WorkPool) Start(ctx context.Context) {
+	p.mu.Lock()
+	p.ctx = ctx
+	p.started = true
+	p.mu.Unlock()
+
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+	log.Printf("[WorkPool] %s started (%d workers, queue %d)", p.name, p.workers, cap(p.tasks))
+}
+
+func (p *WorkPool) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-p.tasks:
+			p.run(ctx, t)
+		}
+	}
+}
+
+func (p *WorkPool) run(ctx context.Context, t poolTask) {
+	p.running.Add(1)
+	defer func() {
+		p.running.Add(-1)
+		p.completed.Add(1)
+		if r := recover(); r != nil {
+			p.panics.Add(1)
+			log.Printf("[WorkPool] %s: task %s panicked: %v", p.name, t.name, r)
+		}
+	}()
+	t.fn(ctx)
+}
+
+// Submit queues fn and reports whether it was accepted. A full queue
+// drops the task. Before Start (tests, tools) fn runs on its own
+// goroutine, as it did before pools existed.
+func (p *WorkPool) Submit(name string, fn func(ctx context.Context)) bool {
+	ctx, started := p.context()
+	if !started {
+		go fn(context.Background())
+		return true
+	}
+	if ctx.Err() != nil {
+		p.dropped.Add(1)
+		return false
+	}
+	select {
+	case p.tasks <- poolTask{name: name, fn: fn}:
+		p.submitted.Add(1)
+		return true
+	default:
+		p.dropped.Add(1)
+		p.logDrop(name)
+		return false
+	}
+}
+
+// SubmitOrRun queues fn, or runs it on the calling goroutine when the
+// queue is full. Use it for work whose loss would leave state wrong.
+func (p *WorkPool) SubmitOrRun(name string, fn func(ctx context.Context)) {
+	ctx, started := p.context()
+	if !started {
+		go fn(context.Background())
+		return
+	}
+	select {
+	case p.tasks <- poolTask{name: name, fn: fn}:
+		p.submitted.Add(1)
+	default:
+		p.inline.Add(1)
+		p.run(ctx, poolTask{name: name, fn: fn})
+	}
+}
+
+func (p *WorkPool) context() (context.Context, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ctx, p.started
+}
+
+func (p *WorkPool) logDrop(name string) {
+	p.dropLogMu.Lock()
+	defer p.dropLogMu.Unlock()
+	if time.Since(p.dropLogLast) < WorkPoolDropLogInterval {
+		return
+	}
+	p.dropLogLast = time.Now()
+	log.Printf("[WorkPool] %s queue full, dropped %s (rate-limited log)", p.name, name)
+}
+
+// Stats returns the pool's queue depth and counters.
+func (p *WorkPool) Stats() WorkPoolStats {
+	return WorkPoolStats{
+		Name:       p.name,
+		Workers:    p.workers,
+		Running:    p.running.Load(),
+		QueueDepth: len(p.tasks),
+		QueueCap:   cap(p.tasks),
+		Submitted:  p.submitted.Load(),
+		Completed:  p.completed.Load(),
+		Dropped:    p.dropped.Load(),
+		RanInline:  p.inline.Load(),
+		Panics:     p.panics.Load(),
+	}
+}
+
+// ─── Semaphore ───────────────────────────────────────────────────
+
+// Semaphore bounds concurrency within a single fan-out.
+type Semaphore chan struct{}
+
+// NewSemaphore returns a semaphore admitting n holders.
+func NewSemaphore(n int) Semaphore {
+	return make(Semaphore, n)
+}
+
+// Acquire blocks for a slot or until ctx is done.
+func (s Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s Semaphore) Release() {
+	<-s
+}
+
+// ─── Gateway pools ───────────────────────────────────────────────
+
+var (
+	BackgroundPool = NewWorkPool("background", BackgroundPoolWorkers, BackgroundPoolQueueSize)
+	ExternalPool   = NewWorkPool("external", ExternalPoolWorkers, ExternalPoolQueueSize)
+)
+
+// StartWorkPools starts the gateway's pools.
+func StartWorkPools(ctx context.Context) {
+	BackgroundPool.Start(ctx)
+	ExternalPool.Start(ctx)
+}
+
+// HandleAdminWorkers reports queue depth and counters for each pool on
+// this replica.
+//
+// @Summary Background worker pool stats (admin)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} object{pools=[]WorkPoolStats}
+// @Security LogtoAuth
+// @Router /admin/workers [get]
+func HandleAdminWorkers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"pools": []WorkPoolStats{BackgroundPool.Stats(), ExternalPool.Stats()},
+	})
+}
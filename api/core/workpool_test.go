@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkPoolSubmitDropsWhenFull(t *testing.T) {
+	p := NewWorkPool("test", 0, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx) // no workers, so the queue never drains
+
+	if !p.Submit("first", func(context.Context) {}) {
+		t.Fatal("first Submit rejected with an empty queue")
+	}
+	if p.Submit("second", func(context.Context) {}) {
+		t.Fatal("second Submit accepted with a full queue")
+	}
+	s := p.Stats()
+	if s.Submitted != 1 || s.Dropped != 1 || s.QueueDepth != 1 {
+		t.Errorf("stats = %+v, want submitted=1 dropped=1 depth=1", s)
+	}
+}
+
+func TestWorkPoolSubmitOrRunRunsInlineWhenFull(t *testing.T) {
+	p := NewWorkPool("test", 0, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	p.Submit("filler", func(context.Context) {})
+	var ran bool
+	p.SubmitOrRun("inline", func(context.Context) { ran = true })
+	if !ran {
+		t.Fatal("SubmitOrRun did not run the task on the caller")
+	}
+	if s := p.Stats(); s.RanInline != 1 {
+		t.Errorf("RanInline = %d, want 1", s.RanInline)
+	}
+}
+
+func TestWorkPoolRecoversPanics(t *testing.T) {
+	p := NewWorkPool("test", 1, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	var after atomic.Bool
+	p.Submit("boom", func(context.Context) { panic("boom") })
+	p.Submit("after", func(context.Context) { after.Store(true) })
+
+	deadline := time.Now().Add(time.Second)
+	for !after.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !after.Load() {
+		t.Fatal("worker stopped after a panicking task")
+	}
+	if s := p.Stats(); s.Panics != 1 {
+		t.Errorf("Panics = %d, want 1", s.Panics)
+	}
+}
+
+func TestWorkPoolRejectsAfterShutdown(t *testing.T) {
+	p := NewWorkPool("test", 1, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	cancel()
+
+	if p.Submit("late", func(context.Context) {}) {
+		t.Error("Submit accepted a task after shutdown")
+	}
+}
+
+func TestSemaphoreAcquireRespectsContext(t *testing.T) {
+	sem := NewSemaphore(1)
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := sem.Acquire(ctx); err == nil {
+		t.Fatal("second Acquire succeeded on a full semaphore")
+	}
+	sem.Release()
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+}
@@ -62,19 +62,51 @@ func main() {
 		}
 	}
 
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing := core.InitTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Printf("[Tracing] shutdown: %v", err)
+		}
+	}()
+
 	// Root context — cancelled on shutdown signal
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Secrets — before anything reads a credential (core/secrets.go)
+	if err := core.InitSecrets(ctx); err != nil {
+		log.Fatalf("[Secrets] %v", err)
+	}
+
 	// Infrastructure
 	core.ConnectDB()
 	defer core.DBPool.Close()
+	if core.ReplicaPool != nil {
+		defer core.ReplicaPool.Close()
+	}
 
 	core.ConnectRedis()
 	defer core.Rdb.Close()
 
+	// Bounded pools for background fan-out (ctx-aware)
+	core.StartWorkPools(ctx)
+
 	core.InitHub(ctx)
+
+	// Share this replica's SSE client count for /events/count (ctx-aware)
+	core.StartClientCountReporter(ctx)
+
+	// Drop hot-key local cache entries other processes invalidate
+	core.StartCacheInvalidationListener(ctx)
 	core.InitAuth()
+	core.InitOAuth()
+
+	// Read replica health: ReadPool falls back to the primary while the
+	// replica is down or lagging (ctx-aware)
+	core.StartReplicaMonitor(ctx)
 
 	// Start Redis-based channel discovery (ctx-aware)
 	core.StartDiscovery(ctx)
@@ -84,10 +116,62 @@ func main() {
 	// delete across local DB + Logto.
 	core.StartGDPRPurgeWorker(ctx)
 
+	// Queued data exports (POST /users/me/export) and archive expiry.
+	core.StartDataExportWorker(ctx)
+
+	// Daily prune of audit_events past AuditRetention.
+	core.StartAuditPruner(ctx)
+
+	// Daily prune of revoked and idle extension sessions.
+	core.StartExtensionSessionPruner(ctx)
+
 	// Periodic prune of the Stripe webhook idempotency table. Long-lived
 	// pods otherwise grow this table unboundedly between restarts.
 	core.StartWebhookEventsPruner(ctx)
 
+	// Replay channel lifecycle events that failed to deliver (channel
+	// restarting, unregistered, or returning errors) with backoff.
+	core.StartLifecycleRetryWorker(ctx)
+
+	// Retry CDC records whose topic publish failed (dead-letter queue).
+	core.StartCDCDeadLetterWorker(ctx)
+
+	// Flush API-key usage counters and report overage to Stripe.
+	core.StartAPIUsageReporter(ctx)
+
+	// Downgrade users whose failed-payment grace period has lapsed.
+	core.StartDunningWorker(ctx)
+
+	// End admin-granted complimentary time that outlived its subscription.
+	core.StartCompTimeWorker(ctx)
+
+	// Batch consented analytics events to the configured sink.
+	core.StartTelemetryFlusher(ctx)
+
+	// Roll up yesterday's ticker clicks into per-channel CTR metrics.
+	core.StartEngagementAggregator(ctx)
+
+	// Nightly "users who follow X also follow Y" recommendations.
+	core.StartRecommendationJob(ctx)
+
+	// Daily anonymised trending lists for GET /trending.
+	core.StartTrendingJob(ctx)
+
+	// Debounced rebuilds of materialized dashboards for heavy users.
+	core.StartDashboardSnapshotWorker(ctx)
+
+	// Rotate token signing keys on schedule and prune expired ones.
+	core.StartSigningKeyRotation(ctx)
+
+	// Match new CDC items against saved searches; prune old notifications.
+	core.StartSavedSearchMatcher(ctx)
+
+	// Breaking-news subscribers and daily notification digests.
+	core.StartNotificationWorker(ctx)
+
+	// Scheduled email digests (Resend, or SMTP with EMAIL_PROVIDER=smtp).
+	core.StartEmailDigestWorker(ctx)
+
 	// Register Discord slash commands (idempotent on every boot when
 	// configured). No-op if Discord env vars aren't set.
 	core.RegisterDiscordSlashCommandsAtBoot(ctx)
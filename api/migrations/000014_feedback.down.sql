@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS feedback_logto_sub_idx;
+DROP INDEX IF EXISTS feedback_status_id_idx;
+DROP TABLE IF EXISTS feedback;
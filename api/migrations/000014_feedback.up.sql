@@ -0,0 +1,36 @@
+-- In-product feedback and bug reports from the desktop app and website.
+--
+-- One row per submission. `logto_sub` is NULL for anonymous submissions
+-- (POST /feedback accepts both). `diagnostics` is only populated when the
+-- client explicitly sent `consent: true` — the handler drops the snapshot
+-- otherwise, so a NULL here means "user declined", not "client forgot".
+--
+-- `status` drives the admin triage workflow:
+--   new → triaged → in_progress → resolved | wont_fix
+-- Transitions are validated in Go (handlers_feedback.go); the CHECK below
+-- only guards against typos from ad-hoc SQL.
+
+CREATE TABLE IF NOT EXISTS feedback (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    logto_sub      TEXT,
+    category       TEXT NOT NULL,
+    message        TEXT NOT NULL,
+    client_version TEXT,
+    diagnostics    JSONB,
+    ip_redacted    TEXT,
+    user_agent     TEXT,
+    status         TEXT NOT NULL DEFAULT 'new'
+        CHECK (status IN ('new', 'triaged', 'in_progress', 'resolved', 'wont_fix')),
+    admin_note     TEXT
+);
+
+-- Admin listing is "newest first, optionally filtered by status", paged
+-- by id (keyset on `id < cursor`).
+CREATE INDEX IF NOT EXISTS feedback_status_id_idx
+    ON feedback (status, id DESC);
+
+CREATE INDEX IF NOT EXISTS feedback_logto_sub_idx
+    ON feedback (logto_sub)
+    WHERE logto_sub IS NOT NULL;
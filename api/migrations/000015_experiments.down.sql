@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS experiment_exposures_logto_sub_idx;
+DROP TABLE IF EXISTS experiment_exposures;
+DROP TABLE IF EXISTS experiments;
@@ -0,0 +1,41 @@
+-- In-product A/B experiments.
+--
+-- `experiments` is the admin-managed definition. Assignment is NOT stored:
+-- the gateway buckets each user deterministically from
+-- sha256(salt || ':' || key || ':' || logto_sub), so the same user always
+-- lands in the same variant without a write on every read. The salt is
+-- 'enroll' for the `traffic_percent` gate (0-100) and 'variant' for the
+-- pick among `variants`, a JSON array of {"name", "weight"} splitting the
+-- enrolled population (experimentHash in experiments.go).
+--
+-- Changing `variants` or `traffic_percent` on a running experiment
+-- reshuffles users — the admin API only allows it while status = 'draft'.
+--
+-- `experiment_exposures` records the first time a client actually showed
+-- a variant to a user (POST /users/me/experiments/:key/exposure). The
+-- UNIQUE constraint dedups repeat exposures; analysis joins this table
+-- against engagement data, not the theoretical assignment.
+
+CREATE TABLE IF NOT EXISTS experiments (
+    key             TEXT PRIMARY KEY,
+    description     TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'running', 'stopped')),
+    traffic_percent INTEGER NOT NULL DEFAULT 0
+        CHECK (traffic_percent BETWEEN 0 AND 100),
+    variants        JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS experiment_exposures (
+    id             BIGSERIAL PRIMARY KEY,
+    experiment_key TEXT NOT NULL REFERENCES experiments(key) ON DELETE CASCADE,
+    logto_sub      TEXT NOT NULL,
+    variant        TEXT NOT NULL,
+    exposed_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (experiment_key, logto_sub)
+);
+
+CREATE INDEX IF NOT EXISTS experiment_exposures_logto_sub_idx
+    ON experiment_exposures (logto_sub);
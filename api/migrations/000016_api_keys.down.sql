@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS api_keys_logto_sub_idx;
+DROP TABLE IF EXISTS api_keys;
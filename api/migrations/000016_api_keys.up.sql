@@ -0,0 +1,26 @@
+-- Personal API keys for scripts and external tools.
+--
+-- Keys authenticate channel routes that opt in with `api_key: true` in
+-- their discovery registration (e.g. GET /finance/quotes). The gateway
+-- resolves the key to a logto_sub and forwards it as X-User-Sub, so
+-- channels never see the key itself.
+--
+-- Only a SHA-256 of the key is stored. `key_prefix` is the first few
+-- characters of the plaintext, kept so the dashboard can show
+-- "scrollr_ab12…" next to each key without being able to reconstruct it.
+-- Revocation is a soft delete (`revoked_at`) so usage history survives.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           BIGSERIAL PRIMARY KEY,
+    logto_sub    TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    key_prefix   TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_logto_sub_idx
+    ON api_keys (logto_sub)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,21 @@
+-- Geo-based onboarding defaults.
+--
+-- On a new user's first onboarding request the gateway resolves their
+-- country (CF-IPCountry, or a MaxMind lookup when configured) and stores
+-- a proposed set of channel configs here: local sports league, local
+-- index ETF, country-appropriate news feeds. Nothing is written to
+-- user_channels until the user accepts. `proposal` is a JSON object
+-- keyed by channel type, each value a user_channels.config document.
+--
+-- One row per user. `status` moves proposed -> accepted | dismissed and
+-- never back, so onboarding doesn't re-prompt after a decision.
+
+CREATE TABLE IF NOT EXISTS onboarding_defaults (
+    logto_sub   TEXT PRIMARY KEY,
+    country     TEXT NOT NULL DEFAULT '',
+    proposal    JSONB NOT NULL,
+    status      TEXT NOT NULL DEFAULT 'proposed'
+        CHECK (status IN ('proposed', 'accepted', 'dismissed')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
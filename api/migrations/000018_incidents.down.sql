@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS incidents_active_idx;
+DROP TABLE IF EXISTS incidents;
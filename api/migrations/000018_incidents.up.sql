@@ -0,0 +1,26 @@
+-- Admin-managed incident banners.
+--
+-- An incident is shown to users while status = 'active' — on
+-- GET /incidents/active, in the /dashboard envelope, and pushed over SSE
+-- when created, edited or resolved. `affected_channels` is a JSON array
+-- of channel types ("finance", "sports", ...); empty means the whole
+-- product. `eta` is the admin's best guess at recovery and is display
+-- only — incidents never auto-resolve.
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id                BIGSERIAL PRIMARY KEY,
+    severity          TEXT NOT NULL
+        CHECK (severity IN ('info', 'minor', 'major', 'critical')),
+    message           TEXT NOT NULL,
+    affected_channels JSONB NOT NULL DEFAULT '[]',
+    eta               TIMESTAMPTZ,
+    status            TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'resolved')),
+    created_by        TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS incidents_active_idx
+    ON incidents (created_at DESC) WHERE status = 'active';
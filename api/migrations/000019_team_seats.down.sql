@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS team_invitations_pending_idx;
+DROP TABLE IF EXISTS team_invitations;
+DROP INDEX IF EXISTS team_members_owner_idx;
+DROP TABLE IF EXISTS team_members;
+ALTER TABLE stripe_customers DROP COLUMN IF EXISTS seats;
@@ -0,0 +1,39 @@
+-- Team / family seats.
+--
+-- A subscriber can buy extra seats on their existing subscription; the
+-- Stripe subscription item quantity always equals stripe_customers.seats.
+-- One seat is the owner's own, the rest are filled by members (who get
+-- the owner's plan roles in Logto) or held by pending invitations.
+--
+-- A user belongs to at most one team, enforced by team_members' primary
+-- key. Invitation tokens are stored as SHA-256 hashes; the plaintext
+-- only ever appears in the emailed join link.
+
+ALTER TABLE stripe_customers
+    ADD COLUMN IF NOT EXISTS seats INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS team_members (
+    member_sub TEXT PRIMARY KEY,
+    owner_sub  TEXT NOT NULL,
+    email      TEXT NOT NULL DEFAULT '',
+    joined_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS team_members_owner_idx ON team_members (owner_sub);
+
+CREATE TABLE IF NOT EXISTS team_invitations (
+    id          BIGSERIAL PRIMARY KEY,
+    owner_sub   TEXT NOT NULL,
+    email       TEXT NOT NULL,
+    token_hash  TEXT NOT NULL UNIQUE,
+    status      TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'revoked')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    accepted_by TEXT
+);
+
+-- One live invitation per address per team.
+CREATE UNIQUE INDEX IF NOT EXISTS team_invitations_pending_idx
+    ON team_invitations (owner_sub, lower(email)) WHERE status = 'pending';
@@ -0,0 +1,16 @@
+-- Metered billing for the public API.
+--
+-- Requests made with API keys are counted per user per calendar month
+-- (UTC) in Redis on the hot path. A background reporter flushes the
+-- count here and sends any overage beyond the monthly allowance to a
+-- Stripe billing meter. `reported_units` is the overage Stripe has
+-- acknowledged, so a retry after a failed flush only reports the delta.
+
+CREATE TABLE IF NOT EXISTS api_usage (
+    logto_sub      TEXT NOT NULL,
+    period         TEXT NOT NULL, -- "2026-10"
+    request_count  BIGINT NOT NULL DEFAULT 0,
+    reported_units BIGINT NOT NULL DEFAULT 0,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (logto_sub, period)
+);
@@ -0,0 +1,25 @@
+-- Local cache of Stripe invoices for GET /users/me/billing/invoices.
+--
+-- Rows are upserted from invoice.* webhooks; a user's first listing
+-- backfills from the Stripe API. Draft invoices are never stored — they
+-- have no number, PDF or hosted page yet.
+
+CREATE TABLE IF NOT EXISTS invoices (
+    stripe_invoice_id  TEXT PRIMARY KEY,
+    logto_sub          TEXT NOT NULL,
+    stripe_customer_id TEXT NOT NULL,
+    number             TEXT NOT NULL DEFAULT '',
+    status             TEXT NOT NULL,
+    billing_reason     TEXT NOT NULL DEFAULT '',
+    total              BIGINT NOT NULL DEFAULT 0,
+    amount_paid        BIGINT NOT NULL DEFAULT 0,
+    currency           TEXT NOT NULL DEFAULT '',
+    hosted_invoice_url TEXT NOT NULL DEFAULT '',
+    invoice_pdf        TEXT NOT NULL DEFAULT '',
+    period_start       TIMESTAMPTZ,
+    period_end         TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL,
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS invoices_logto_sub_idx ON invoices (logto_sub, created_at DESC);
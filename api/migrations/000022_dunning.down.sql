@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS stripe_customers_grace_idx;
+ALTER TABLE stripe_customers
+    DROP COLUMN IF EXISTS entitlements_suspended_at,
+    DROP COLUMN IF EXISTS failed_invoice_id,
+    DROP COLUMN IF EXISTS grace_period_ends_at,
+    DROP COLUMN IF EXISTS payment_failed_at;
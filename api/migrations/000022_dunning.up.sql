@@ -0,0 +1,17 @@
+-- Dunning state for failed subscription payments.
+--
+-- The first invoice.payment_failed starts a grace period: the user keeps
+-- their plan's roles until grace_period_ends_at while Stripe retries and
+-- we nudge them to update their card. If the period lapses unpaid, the
+-- dunning worker strips paid roles and stamps entitlements_suspended_at.
+-- invoice.paid clears every column and restores the roles.
+
+ALTER TABLE stripe_customers
+    ADD COLUMN IF NOT EXISTS payment_failed_at         TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS grace_period_ends_at      TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS failed_invoice_id         TEXT,
+    ADD COLUMN IF NOT EXISTS entitlements_suspended_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS stripe_customers_grace_idx
+    ON stripe_customers (grace_period_ends_at)
+    WHERE grace_period_ends_at IS NOT NULL AND entitlements_suspended_at IS NULL;
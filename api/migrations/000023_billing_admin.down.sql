@@ -0,0 +1,2 @@
+ALTER TABLE stripe_customers DROP COLUMN IF EXISTS comp_until;
+DROP TABLE IF EXISTS billing_audit_log;
@@ -0,0 +1,21 @@
+-- Admin billing tooling: an audit trail for support actions, and
+-- complimentary time that outlives the Stripe subscription.
+
+CREATE TABLE IF NOT EXISTS billing_audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    logto_sub   TEXT,
+    admin_sub   TEXT NOT NULL,
+    action      TEXT NOT NULL CHECK (action IN ('refund', 'comp_time', 'plan_change')),
+    details     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    note        TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS billing_audit_log_sub_idx
+    ON billing_audit_log (logto_sub, created_at DESC);
+
+-- Set by an admin comp grant. subscription.updated never moves
+-- current_period_end below it, and subscription.deleted defers the
+-- downgrade until it passes.
+ALTER TABLE stripe_customers
+    ADD COLUMN IF NOT EXISTS comp_until TIMESTAMPTZ;
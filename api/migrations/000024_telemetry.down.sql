@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS analytics_events;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS analytics_opt_in;
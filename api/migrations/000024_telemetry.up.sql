@@ -0,0 +1,22 @@
+-- Consent-gated product analytics. analytics_opt_in defaults off; the
+-- gateway rejects events from users who haven't opted in and deletes
+-- their rows when they opt out. No IP address is ever stored.
+
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS analytics_opt_in BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS analytics_events (
+    id          BIGSERIAL PRIMARY KEY,
+    logto_sub   TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    properties  JSONB,
+    session_id  TEXT NOT NULL DEFAULT '',
+    client      TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS analytics_events_name_time_idx
+    ON analytics_events (name, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS analytics_events_sub_idx
+    ON analytics_events (logto_sub);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS channel_engagement_daily;
+DROP TABLE IF EXISTS ticker_clicks;
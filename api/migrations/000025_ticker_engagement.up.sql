@@ -0,0 +1,26 @@
+-- Ticker item clicks, deduped per user/item/day, and the nightly
+-- per-channel rollup. source = '' marks the channel-wide row; other rows
+-- break the channel down by symbol, feed or league.
+
+CREATE TABLE IF NOT EXISTS ticker_clicks (
+    logto_sub    TEXT NOT NULL,
+    channel_type TEXT NOT NULL,
+    item_id      TEXT NOT NULL,
+    source       TEXT NOT NULL DEFAULT '',
+    click_date   DATE NOT NULL,
+    clicked_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (logto_sub, channel_type, item_id, click_date)
+);
+
+CREATE INDEX IF NOT EXISTS ticker_clicks_date_idx
+    ON ticker_clicks (click_date, channel_type);
+
+CREATE TABLE IF NOT EXISTS channel_engagement_daily (
+    day          DATE NOT NULL,
+    channel_type TEXT NOT NULL,
+    source       TEXT NOT NULL DEFAULT '',
+    clicks       INT NOT NULL DEFAULT 0,
+    unique_users INT NOT NULL DEFAULT 0,
+    viewers      INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, channel_type, source)
+);
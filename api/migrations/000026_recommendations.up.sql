@@ -0,0 +1,14 @@
+-- Precomputed catalog recommendations, rebuilt nightly. logto_sub = ''
+-- holds the global popularity list served to users the job hasn't seen.
+
+CREATE TABLE IF NOT EXISTS recommendations (
+    logto_sub   TEXT NOT NULL,
+    kind        TEXT NOT NULL CHECK (kind IN ('symbol', 'feed', 'league', 'team')),
+    item_key    TEXT NOT NULL,
+    label       TEXT NOT NULL,
+    score       DOUBLE PRECISION NOT NULL,
+    reason      TEXT NOT NULL,
+    rank        INT NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (logto_sub, kind, item_key)
+);
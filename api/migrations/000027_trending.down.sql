@@ -0,0 +1,3 @@
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS show_trending;
+DROP TABLE IF EXISTS trending_items;
+DROP TABLE IF EXISTS item_follow_counts;
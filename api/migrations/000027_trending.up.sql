@@ -0,0 +1,28 @@
+-- Platform-wide trending lists. item_follow_counts keeps a daily
+-- snapshot of follower counts so week-over-week growth can be ranked;
+-- trending_items holds the current anonymised lists per channel.
+
+CREATE TABLE IF NOT EXISTS item_follow_counts (
+    day       DATE NOT NULL,
+    kind      TEXT NOT NULL,
+    item_key  TEXT NOT NULL,
+    label     TEXT NOT NULL DEFAULT '',
+    followers BIGINT NOT NULL,
+    PRIMARY KEY (day, kind, item_key)
+);
+
+CREATE TABLE IF NOT EXISTS trending_items (
+    channel_type TEXT NOT NULL,
+    rank         INT NOT NULL,
+    kind         TEXT NOT NULL,
+    item_key     TEXT NOT NULL,
+    label        TEXT NOT NULL DEFAULT '',
+    metric       TEXT NOT NULL,
+    count        BIGINT NOT NULL,
+    computed_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (channel_type, rank)
+);
+
+-- Opt-in dashboard section.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS show_trending BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DELETE FROM user_channels WHERE logto_sub LIKE 'org:%';
+DROP INDEX IF EXISTS organization_members_sub_idx;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,32 @@
+-- Organizations with shared channel configurations.
+--
+-- An organization (a trading desk, a newsroom) owns channel configs that
+-- every member sees read-only next to their personal channels. The
+-- configs themselves live in user_channels under the synthetic owner
+-- 'org:<id>', so channel APIs, ingestion and lifecycle hooks treat them
+-- like any other user's channels; only membership is stored here.
+--
+-- Admins manage membership and the shared configs. The last admin can't
+-- leave or be demoted, so an organization always has someone who can
+-- delete it.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id    BIGINT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    logto_sub TEXT NOT NULL,
+    email     TEXT NOT NULL DEFAULT '',
+    role      TEXT NOT NULL DEFAULT 'member'
+        CHECK (role IN ('admin', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, logto_sub)
+);
+
+CREATE INDEX IF NOT EXISTS organization_members_sub_idx
+    ON organization_members (logto_sub);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS display_tokens_logto_sub_idx;
+DROP TABLE IF EXISTS display_tokens;
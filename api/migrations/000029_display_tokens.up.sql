@@ -0,0 +1,26 @@
+-- Long-lived display tokens for kiosk / wall-mounted TV tickers.
+--
+-- A display token authenticates only the read-only display routes
+-- (GET /display/:token/ticker and /display/:token/events), never the
+-- user's account or channel routes. It resolves to the owner's
+-- logto_sub, so a TV shows exactly what the owner's ticker shows,
+-- including their organizations' shared channels.
+--
+-- Same storage model as api_keys: only a SHA-256 of the token is kept,
+-- `token_prefix` is a non-secret stub for the management UI, and
+-- revocation is a soft delete.
+
+CREATE TABLE IF NOT EXISTS display_tokens (
+    id           BIGSERIAL PRIMARY KEY,
+    logto_sub    TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    token_prefix TEXT NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS display_tokens_logto_sub_idx
+    ON display_tokens (logto_sub)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,4 @@
+-- Per-league spoiler windows: {"NFL": 12, "423.l.12345": 24} hides
+-- scores for that many hours after a game or fantasy week ends.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS spoiler_windows JSONB NOT NULL DEFAULT '{}'::jsonb;
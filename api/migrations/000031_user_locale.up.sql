@@ -0,0 +1,4 @@
+-- The user's locale (BCP 47, e.g. "en-US"); '' when unset. Channels
+-- read it for defaults such as the RSS content-language filter.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE stripe_webhook_events DROP COLUMN IF EXISTS processed_at;
+ALTER TABLE stripe_webhook_events DROP COLUMN IF EXISTS event_type;
@@ -0,0 +1,10 @@
+-- Track whether a claimed Stripe event finished processing.
+--
+-- Event IDs used to be inserted before dispatch and never revisited, so a
+-- pod dying mid-event left a row that made every Stripe retry skip it.
+-- created_at now doubles as the claim time: a claim with no processed_at
+-- older than StripeWebhookClaimTimeout may be taken over by a retry.
+-- Existing rows predate the split and are treated as processed.
+ALTER TABLE stripe_webhook_events ADD COLUMN IF NOT EXISTS event_type TEXT;
+ALTER TABLE stripe_webhook_events ADD COLUMN IF NOT EXISTS processed_at TIMESTAMPTZ;
+UPDATE stripe_webhook_events SET processed_at = created_at WHERE processed_at IS NULL;
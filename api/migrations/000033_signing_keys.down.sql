@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS signing_keys_purpose_idx;
+DROP TABLE IF EXISTS signing_keys;
@@ -0,0 +1,20 @@
+-- HMAC keysets for tokens the gateway signs itself (support approval
+-- links today).
+--
+-- Tokens carry the kid of the key that signed them. A purpose has one
+-- active key (retired_at IS NULL), the newest; rotation retires the
+-- previous one, which keeps verifying for SigningKeyGracePeriod so
+-- tokens already handed out stay valid. `revoked` drops a key at once,
+-- for a key known to have leaked.
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid        TEXT PRIMARY KEY,
+    purpose    TEXT NOT NULL,
+    secret     BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    retired_at TIMESTAMPTZ,
+    revoked    BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS signing_keys_purpose_idx
+    ON signing_keys (purpose, created_at DESC);
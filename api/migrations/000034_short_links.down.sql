@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS short_links_logto_sub_idx;
+DROP TABLE IF EXISTS short_links;
@@ -0,0 +1,22 @@
+-- Short links for shared ticker items, profiles and leagues.
+--
+-- POST /links mints a code; GET /l/:code redirects to target_url and
+-- counts the click. Links expire at expires_at. disabled_at is set when
+-- the owner deletes a link or an admin takes one down for abuse; the
+-- row is kept so the code is never reissued.
+
+CREATE TABLE IF NOT EXISTS short_links (
+    code            TEXT PRIMARY KEY,
+    logto_sub       TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    target_url      TEXT NOT NULL,
+    clicks          BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    disabled_at     TIMESTAMPTZ,
+    disabled_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS short_links_logto_sub_idx
+    ON short_links (logto_sub, created_at DESC);
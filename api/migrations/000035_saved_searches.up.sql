@@ -0,0 +1,39 @@
+-- Saved searches and the notifications inbox.
+--
+-- A saved search is a keyword query and/or a list of ticker symbols. The
+-- gateway matches new rss_items from CDC against each user's enabled
+-- searches (only items from feeds the user follows) and writes a
+-- notification per match. dedupe_key makes Sequin redeliveries and
+-- repeated matches of the same article a no-op.
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id              BIGSERIAL PRIMARY KEY,
+    logto_sub       TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    query           TEXT NOT NULL DEFAULT '',
+    symbols         TEXT[] NOT NULL DEFAULT '{}',
+    enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_matched_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_logto_sub_idx ON saved_searches (logto_sub);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id              BIGSERIAL PRIMARY KEY,
+    logto_sub       TEXT NOT NULL,
+    kind            TEXT NOT NULL,
+    title           TEXT NOT NULL,
+    body            TEXT NOT NULL DEFAULT '',
+    link            TEXT,
+    saved_search_id BIGINT REFERENCES saved_searches(id) ON DELETE SET NULL,
+    dedupe_key      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_logto_sub_idx
+    ON notifications (logto_sub, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_dedupe_idx
+    ON notifications (logto_sub, dedupe_key) WHERE dedupe_key IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_preferences_username;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS username;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS is_public;
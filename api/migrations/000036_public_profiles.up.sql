@@ -0,0 +1,10 @@
+-- Public profiles. Usernames live in Logto; the copy here is stamped from
+-- the JWT claim when a user makes their profile public, so
+-- GET /users/:username/watchlist can resolve a username without a
+-- Management API round-trip. Private profiles keep username NULL.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS username  TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_preferences_username
+    ON user_preferences (username) WHERE username IS NOT NULL;
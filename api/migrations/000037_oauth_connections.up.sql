@@ -0,0 +1,19 @@
+-- Third-party OAuth connections (core/connections.go, core/oauth).
+-- access_token / refresh_token are AES-256-GCM ciphertexts; one row per
+-- user and provider. status flips to 'revoked' when the provider rejects
+-- the refresh token, until the user connects again.
+CREATE TABLE IF NOT EXISTS oauth_connections (
+    logto_sub     TEXT        NOT NULL,
+    provider      TEXT        NOT NULL,
+    account_id    TEXT,
+    access_token  TEXT        NOT NULL,
+    refresh_token TEXT,
+    token_type    TEXT        NOT NULL DEFAULT '',
+    expires_at    TIMESTAMPTZ,
+    scope         TEXT        NOT NULL DEFAULT '',
+    status        TEXT        NOT NULL DEFAULT 'active'
+                  CHECK (status IN ('active', 'revoked')),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (logto_sub, provider)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notifications_digest_pending_idx;
+ALTER TABLE notifications DROP COLUMN IF EXISTS digest_pending;
+DROP TABLE IF EXISTS notification_preferences;
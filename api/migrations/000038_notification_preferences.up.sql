@@ -0,0 +1,22 @@
+-- Per-user notification preferences (core/notifications.go).
+--
+-- modes maps a notification kind (price_alert, fantasy_score,
+-- breaking_news, saved_search) to 'instant', 'digest' or 'off'; kinds
+-- missing from it use the defaults in code, so a user with no row gets
+-- the defaults too. digest_hour is the UTC hour the daily digest goes out.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    logto_sub      TEXT        PRIMARY KEY,
+    modes          JSONB       NOT NULL DEFAULT '{}',
+    digest_hour    SMALLINT    NOT NULL DEFAULT 8
+                   CHECK (digest_hour BETWEEN 0 AND 23),
+    last_digest_at TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Notifications of a kind set to 'digest' are stored with digest_pending
+-- and not pushed; the digest worker summarizes and clears them.
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS digest_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS notifications_digest_pending_idx
+    ON notifications (logto_sub) WHERE digest_pending;
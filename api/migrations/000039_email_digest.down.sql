@@ -0,0 +1,3 @@
+ALTER TABLE user_preferences
+    DROP COLUMN IF EXISTS email_digest_last_sent_at,
+    DROP COLUMN IF EXISTS email_digest;
@@ -0,0 +1,6 @@
+-- Email digest schedule (core/email_digest.go):
+-- {"frequency": "off" | "daily" | "weekly", "hour": 0-23, "weekday": 0-6}.
+-- hour is UTC; weekday (0 = Sunday) only applies to weekly digests.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS email_digest JSONB NOT NULL DEFAULT '{"frequency": "off", "hour": 8, "weekday": 1}'::jsonb,
+    ADD COLUMN IF NOT EXISTS email_digest_last_sent_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+-- API key scopes.
+--
+-- A key now names what it may open: "channels" (channel routes that opt
+-- in with `api_key: true`) and/or "feed" (GET /public/feed?key=, the
+-- owner's ticker data for OBS overlays and other embeds). Existing keys
+-- keep exactly what they could do before.
+
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{channels}';
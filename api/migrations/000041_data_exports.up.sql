@@ -0,0 +1,26 @@
+-- Asynchronous data exports (core/data_export.go).
+--
+-- POST /users/me/export queues a job; a worker assembles the archive
+-- (JSON, or a zip with one file per section) and stores it here until
+-- expires_at. identity carries the token claims captured at request
+-- time (email, name, username, roles), which the worker can't see.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id           UUID PRIMARY KEY,
+    logto_sub    VARCHAR(255) NOT NULL,
+    format       VARCHAR(8) NOT NULL CHECK (format IN ('json', 'zip')),
+    status       VARCHAR(16) NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'running', 'ready', 'failed')),
+    identity     JSONB NOT NULL DEFAULT '{}'::jsonb,
+    archive      BYTEA,
+    size_bytes   INTEGER,
+    error        TEXT,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at   TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_idx ON data_exports (logto_sub, created_at DESC);
+CREATE INDEX IF NOT EXISTS data_exports_queue_idx ON data_exports (created_at)
+    WHERE status IN ('pending', 'running');
@@ -0,0 +1,18 @@
+-- Admin reads of another user's data (core/admin_impersonation.go).
+--
+-- One row per impersonated read, written before the data is returned.
+-- target_sub is nulled when the target account is purged so the trail
+-- survives without pointing at a deleted user.
+CREATE TABLE IF NOT EXISTS admin_audit (
+    id          BIGSERIAL PRIMARY KEY,
+    admin_sub   TEXT NOT NULL,
+    target_sub  TEXT,
+    action      TEXT NOT NULL,
+    path        TEXT NOT NULL,
+    reason      TEXT,
+    ip          TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS admin_audit_target_idx ON admin_audit (target_sub, created_at DESC);
+CREATE INDEX IF NOT EXISTS admin_audit_admin_idx ON admin_audit (admin_sub, created_at DESC);
@@ -0,0 +1,19 @@
+-- Security-relevant account activity (core/audit.go).
+--
+-- target_sub is the account the event concerns and is what
+-- GET /users/me/audit filters on. actor_sub is who did it: the user
+-- themselves, an admin, 'system' (Stripe webhooks, workers), or NULL
+-- when unauthenticated (auth failures). Rows older than AuditRetention
+-- are pruned; a purged account's rows go with it.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          BIGSERIAL PRIMARY KEY,
+    actor_sub   TEXT,
+    action      TEXT NOT NULL,
+    target_sub  TEXT,
+    meta        JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (target_sub, id DESC)
+    WHERE target_sub IS NOT NULL;
+CREATE INDEX IF NOT EXISTS audit_events_created_idx ON audit_events (created_at);
@@ -0,0 +1,20 @@
+-- Sessions issued through the extension/desktop token proxy
+-- (core/extension_sessions.go).
+--
+-- Logto rotates the refresh token on every refresh; refresh_token_hash
+-- always holds the SHA-256 of the current one so the proxy can find the
+-- session and refuse it once revoked_at is set.
+CREATE TABLE IF NOT EXISTS extension_sessions (
+    id                  UUID PRIMARY KEY,
+    logto_sub           VARCHAR(255) NOT NULL,
+    refresh_token_hash  CHAR(64) NOT NULL,
+    device_label        TEXT NOT NULL,
+    ip                  TEXT,
+    user_agent          TEXT,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at          TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS extension_sessions_token_idx ON extension_sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS extension_sessions_user_idx ON extension_sessions (logto_sub, last_used_at DESC);
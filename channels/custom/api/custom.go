@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Constants
+// =============================================================================
+
+const (
+	// CacheKeyCustomPrefix is the per-user dashboard cache: cache:custom:{sub}.
+	// Dropped on every write so a webhook post shows up on the next fetch.
+	CacheKeyCustomPrefix = "cache:custom:"
+
+	// CustomCacheTTL bounds how stale a dashboard read can be if an
+	// invalidation is lost.
+	CustomCacheTTL = 30 * time.Second
+
+	// ExpiryPruneInterval is how often items past their ttl_seconds are
+	// deleted. Reads already filter them out, so this only reclaims rows
+	// and emits the CDC delete that removes them from open tickers.
+	ExpiryPruneInterval = time.Minute
+)
+
+// App holds shared dependencies for all handlers.
+type App struct {
+	db  *pgxpool.Pool
+	rdb redis.UniversalClient
+}
+
+// =============================================================================
+// Public Routes (proxied by core gateway)
+// =============================================================================
+
+// getItems returns the caller's live items with their quota in headers.
+func (a *App) getItems(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	items, err := a.userItems(c.Context(), userSub)
+	if err != nil {
+		log.Printf("[Custom] Failed to load items for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load items",
+		})
+	}
+
+	limit := CustomItemCap(GetUserTier(c))
+	c.Set("X-Custom-Items-Limit", fmt.Sprint(limit))
+	c.Set("X-Custom-Items-Remaining", fmt.Sprint(max(limit-len(items), 0)))
+	return c.JSON(fiber.Map{"items": items})
+}
+
+// deleteItem removes one of the caller's items by its external ID.
+func (a *App) deleteItem(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+	tag, err := a.db.Exec(ctx,
+		"DELETE FROM custom_items WHERE logto_sub = $1 AND external_id = $2",
+		userSub, c.Params("id"))
+	if err != nil {
+		log.Printf("[Custom] Failed to delete item for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete item",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Item not found",
+		})
+	}
+	a.rdb.Del(ctx, CacheKeyCustomPrefix+userSub)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// userItems loads userSub's unexpired items, most recently updated first.
+func (a *App) userItems(ctx context.Context, userSub string) ([]CustomItem, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT external_id, title, value, status, link, expires_at, updated_at
+		FROM custom_items
+		WHERE logto_sub = $1 AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY updated_at DESC, id DESC`, userSub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]CustomItem, 0)
+	for rows.Next() {
+		var it CustomItem
+		if err := rows.Scan(&it.ID, &it.Title, &it.Value, &it.Status, &it.Link, &it.ExpiresAt, &it.UpdatedAt); err != nil {
+			log.Printf("[Custom] Item scan error: %v", err)
+			continue
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// =============================================================================
+// Internal Routes (called by core gateway)
+// =============================================================================
+
+// handleInternalDashboard returns the user's items for the dashboard
+// response. Called by core gateway with ?user={sub}.
+func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userSub := c.Query("user")
+	if userSub == "" {
+		return c.JSON(fiber.Map{"custom": []CustomItem{}})
+	}
+
+	cacheKey := CacheKeyCustomPrefix + userSub
+	var items []CustomItem
+	if countCache("dashboard", GetCache(a.rdb, ctx, cacheKey, &items)) {
+		return c.JSON(fiber.Map{"custom": items})
+	}
+
+	items, err := a.userItems(ctx, userSub)
+	if err != nil {
+		log.Printf("[Custom] Dashboard query failed for %s: %v", userSub, err)
+		return c.JSON(fiber.Map{"custom": []CustomItem{}})
+	}
+
+	SetCache(a.rdb, ctx, cacheKey, items, CustomCacheTTL)
+	return c.JSON(fiber.Map{"custom": items})
+}
+
+// handleInternalHealth is the endpoint the core gateway and k8s probes hit.
+// There is no ingestion service behind this channel, so health is just
+// this API's own Postgres and Redis.
+func (a *App) handleInternalHealth(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), InternalHealthTimeout)
+	defer cancel()
+
+	result := fiber.Map{"status": "healthy"}
+	degraded := false
+
+	if err := a.db.Ping(ctx); err != nil {
+		result["database"] = "unhealthy: " + err.Error()
+		degraded = true
+	} else {
+		result["database"] = "healthy"
+	}
+
+	if err := a.rdb.Ping(ctx).Err(); err != nil {
+		result["redis"] = "unhealthy: " + err.Error()
+		degraded = true
+	} else {
+		result["redis"] = "healthy"
+	}
+
+	if degraded {
+		result["status"] = "degraded"
+		return c.Status(fiber.StatusServiceUnavailable).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+// =============================================================================
+// Channel Lifecycle
+// =============================================================================
+
+// lifecycleEvent is one channel lifecycle event from the core gateway.
+type lifecycleEvent struct {
+	Event     string                 `json:"event"`
+	User      string                 `json:"user"`
+	Config    map[string]interface{} `json:"config"`
+	OldConfig map[string]interface{} `json:"old_config"`
+	Enabled   bool                   `json:"enabled"`
+}
+
+// handleChannelLifecycle handles channel lifecycle events dispatched by the core
+// gateway. Events: created, updated, deleted, sync.
+func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
+	var req lifecycleEvent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	a.applyLifecycleEvent(c.Context(), req)
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// handleLifecycleReplay applies lifecycle events the gateway queued while
+// this channel was unreachable, in the order they were originally sent.
+func (a *App) handleLifecycleReplay(c *fiber.Ctx) error {
+	var req struct {
+		Events []lifecycleEvent `json:"events"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	ctx := context.Background()
+	for _, ev := range req.Events {
+		a.applyLifecycleEvent(ctx, ev)
+	}
+	log.Printf("[Custom Lifecycle] Replayed %d queued events", len(req.Events))
+	return c.JSON(fiber.Map{"processed": len(req.Events)})
+}
+
+// applyLifecycleEvent only acts on "deleted": removing the channel revokes
+// the webhook and drops every item, so a stale URL can't keep writing to
+// a ticker the user no longer has. The other events carry no state this
+// channel keeps.
+func (a *App) applyLifecycleEvent(ctx context.Context, ev lifecycleEvent) {
+	switch ev.Event {
+	case "created", "updated", "sync":
+		// Nothing to sync — items arrive through the webhook.
+
+	case "deleted":
+		if ev.User == "" {
+			return
+		}
+		if _, err := a.db.Exec(ctx, "DELETE FROM custom_hooks WHERE logto_sub = $1", ev.User); err != nil {
+			log.Printf("[Custom Lifecycle] Failed to delete hook for %s: %v", ev.User, err)
+		}
+		if _, err := a.db.Exec(ctx, "DELETE FROM custom_items WHERE logto_sub = $1", ev.User); err != nil {
+			log.Printf("[Custom Lifecycle] Failed to delete items for %s: %v", ev.User, err)
+		}
+		a.rdb.Del(ctx, CacheKeyCustomPrefix+ev.User)
+
+	default:
+		log.Printf("[Custom Lifecycle] Unknown event: %s", ev.Event)
+	}
+}
+
+// =============================================================================
+// Expiry
+// =============================================================================
+
+// startExpiryPruner deletes items past expires_at every ExpiryPruneInterval.
+// Every replica runs it; the DELETE is idempotent.
+func (a *App) startExpiryPruner(ctx context.Context) {
+	ticker := time.NewTicker(ExpiryPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rows, err := a.db.Query(ctx, `
+				WITH expired AS (
+					DELETE FROM custom_items WHERE expires_at <= now() RETURNING logto_sub
+				)
+				SELECT DISTINCT logto_sub FROM expired`)
+			if err != nil {
+				log.Printf("[Custom] Expiry prune failed: %v", err)
+				continue
+			}
+			var subs []string
+			for rows.Next() {
+				var sub string
+				if rows.Scan(&sub) == nil {
+					subs = append(subs, sub)
+				}
+			}
+			rows.Close()
+			for _, sub := range subs {
+				a.rdb.Del(ctx, CacheKeyCustomPrefix+sub)
+			}
+		}
+	}
+}
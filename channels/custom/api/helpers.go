@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// InternalHealthTimeout is the aggregate timeout for a /internal/health
+// request, covering DB and Redis pings. Shorter than the k8s readiness
+// probe timeout so a slow downstream doesn't hold up the probe.
+const InternalHealthTimeout = 3 * time.Second
+
+// GetCache attempts to retrieve and deserialize a value from Redis.
+// Returns true if the cache hit was successful.
+func GetCache(rdb redis.UniversalClient, ctx context.Context, key string, target interface{}) bool {
+	val, err := rdb.Get(ctx, key).Result()
+	if err != nil {
+		return false
+	}
+
+	err = json.Unmarshal([]byte(val), target)
+	return err == nil
+}
+
+// SetCache serializes and stores a value in Redis with an expiration.
+func SetCache(rdb redis.UniversalClient, ctx context.Context, key string, value interface{}, expiration time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[Redis Error] Failed to marshal cache data for %s: %v", key, err)
+		return
+	}
+
+	err = rdb.Set(ctx, key, data, expiration).Err()
+	if err != nil {
+		log.Printf("[Redis Error] Failed to set cache for %s: %v", key, err)
+	}
+}
+
+// GetUserSub reads the X-User-Sub header set by the core gateway for
+// authenticated requests. Returns empty string if not present.
+func GetUserSub(c *fiber.Ctx) string {
+	return c.Get("X-User-Sub")
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// Webhook
+// =============================================================================
+//
+// Each user has at most one inbound webhook. Its URL carries a random
+// secret (POST {CUSTOM_HOOK_BASE_URL}/custom/hooks/scrollr_hook_…) so it can
+// be pasted into cron jobs, Zapier, Home Assistant and the like without
+// those tools holding a Scrollr session. Only the SHA-256 of the token is
+// stored; rotating replaces it and the old URL stops working at once.
+//
+// The body is either one item or a batch:
+//
+//	{"id": "build", "title": "main", "value": "passing", "status": "ok"}
+//	{"items": [{...}, {...}], "delete": ["old-id"]}
+//
+// Items are keyed by the caller's id, so re-posting an id updates it in
+// place. Rows land in custom_items and reach open tickers through the
+// normal CDC path (core routes custom_items to the owner's topic).
+
+const (
+	// HookTokenPrefix marks webhook tokens so they're recognisable in logs
+	// and secret scanners, like the display and API key prefixes.
+	HookTokenPrefix = "scrollr_hook_"
+
+	// DefaultHookBaseURL is where the gateway serves /custom/hooks/:token
+	// when CUSTOM_HOOK_BASE_URL is unset.
+	DefaultHookBaseURL = "https://api.myscrollr.com"
+
+	// MaxWebhookBodyBytes caps a webhook request body. A full batch of
+	// maximum-size items fits comfortably.
+	MaxWebhookBodyBytes = 256 * 1024
+
+	// MaxWebhookItems caps items plus deletes in one request.
+	MaxWebhookItems = 50
+
+	// WebhookRateLimit is how many requests one hook may make per minute.
+	WebhookRateLimit = 60
+
+	// RedisHookRLPrefix keys the per-hook rate limit: custom:rl:{hash}.
+	RedisHookRLPrefix = "custom:rl:"
+
+	MaxItemIDLength    = 128
+	MaxItemTitleLength = 140
+	MaxItemValueLength = 64
+	MaxItemLinkLength  = 2048
+	MinItemTTL         = 60 * time.Second
+	MaxItemTTL         = 30 * 24 * time.Hour
+)
+
+// itemStatuses are the accepted item "status" values; clients colour the
+// ticker chip by them.
+var itemStatuses = map[string]bool{"ok": true, "info": true, "warn": true, "error": true}
+
+// ─── Tokens ──────────────────────────────────────────────────────
+
+// generateHookToken returns a new plaintext token of the form
+// "scrollr_hook_<43 url-safe chars>" (32 random bytes).
+func generateHookToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return HookTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashHookToken is what custom_hooks.token_hash stores.
+func hashHookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hookTokenDisplayPrefix is the non-secret stub shown in the dashboard.
+func hookTokenDisplayPrefix(token string) string {
+	n := len(HookTokenPrefix) + 4
+	if len(token) < n {
+		return token
+	}
+	return token[:n]
+}
+
+// hookURL is the public URL for token.
+func hookURL(token string) string {
+	base := os.Getenv("CUSTOM_HOOK_BASE_URL")
+	if base == "" {
+		base = DefaultHookBaseURL
+	}
+	return strings.TrimSuffix(base, "/") + "/custom/hooks/" + token
+}
+
+// ─── Payload ─────────────────────────────────────────────────────
+
+// itemValue accepts a JSON string or number, so callers can post
+// {"value": 42.5} without quoting it. Numbers keep their literal form.
+type itemValue string
+
+func (v *itemValue) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*v = itemValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return errors.New("value must be a string or number")
+	}
+	*v = itemValue(n.String())
+	return nil
+}
+
+// itemInput is one item as posted to the webhook.
+type itemInput struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Value      itemValue `json:"value"`
+	Status     string    `json:"status"`
+	Link       string    `json:"link"`
+	TTLSeconds int       `json:"ttl_seconds"`
+}
+
+// webhookBatch is the batch form of a webhook body.
+type webhookBatch struct {
+	Items  []itemInput `json:"items"`
+	Delete []string    `json:"delete"`
+}
+
+// customRow is a validated item ready to upsert.
+type customRow struct {
+	ID        string
+	Title     string
+	Value     *string
+	Status    *string
+	Link      *string
+	ExpiresAt *time.Time
+}
+
+// decodeStrict decodes body into v, rejecting unknown fields and
+// trailing data.
+func decodeStrict(body []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON object")
+	}
+	return nil
+}
+
+// parseWebhookBody decodes either a single item or a batch. A body with an
+// "items" or "delete" key is a batch; anything else must be one item.
+func parseWebhookBody(body []byte) (webhookBatch, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return webhookBatch{}, errors.New("body must be a JSON object")
+	}
+	_, hasItems := probe["items"]
+	_, hasDelete := probe["delete"]
+
+	var batch webhookBatch
+	if hasItems || hasDelete {
+		if err := decodeStrict(body, &batch); err != nil {
+			return webhookBatch{}, err
+		}
+		return batch, nil
+	}
+
+	var item itemInput
+	if err := decodeStrict(body, &item); err != nil {
+		return webhookBatch{}, err
+	}
+	batch.Items = []itemInput{item}
+	return batch, nil
+}
+
+// validItemID reports whether id is a usable item key: non-empty, within
+// MaxItemIDLength bytes and free of whitespace and control characters.
+func validItemID(id string) bool {
+	if id == "" || len(id) > MaxItemIDLength || !utf8.ValidString(id) {
+		return false
+	}
+	for _, r := range id {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateItem checks one item and normalises it for storage. field
+// prefixes every problem ("items[2].title: …") so a batch caller can find
+// the bad entry.
+func validateItem(in itemInput, field string, now time.Time) (customRow, []string) {
+	var problems []string
+	fail := func(name, msg string) {
+		problems = append(problems, field+name+": "+msg)
+	}
+
+	row := customRow{ID: in.ID}
+	if !validItemID(in.ID) {
+		fail("id", fmt.Sprintf("required, up to %d characters, no spaces", MaxItemIDLength))
+	}
+
+	row.Title = strings.TrimSpace(in.Title)
+	switch {
+	case row.Title == "":
+		fail("title", "required")
+	case utf8.RuneCountInString(row.Title) > MaxItemTitleLength:
+		fail("title", fmt.Sprintf("must be at most %d characters", MaxItemTitleLength))
+	}
+
+	if v := strings.TrimSpace(string(in.Value)); v != "" {
+		if utf8.RuneCountInString(v) > MaxItemValueLength {
+			fail("value", fmt.Sprintf("must be at most %d characters", MaxItemValueLength))
+		}
+		row.Value = &v
+	}
+
+	if in.Status != "" {
+		if !itemStatuses[in.Status] {
+			fail("status", "must be one of ok, info, warn, error")
+		}
+		s := in.Status
+		row.Status = &s
+	}
+
+	if in.Link != "" {
+		u, err := url.Parse(in.Link)
+		switch {
+		case len(in.Link) > MaxItemLinkLength:
+			fail("link", fmt.Sprintf("must be at most %d characters", MaxItemLinkLength))
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			fail("link", "must be an absolute http(s) URL")
+		}
+		l := in.Link
+		row.Link = &l
+	}
+
+	if in.TTLSeconds != 0 {
+		ttl := time.Duration(in.TTLSeconds) * time.Second
+		if ttl < MinItemTTL || ttl > MaxItemTTL {
+			fail("ttl_seconds", fmt.Sprintf("must be between %d and %d", int(MinItemTTL/time.Second), int(MaxItemTTL/time.Second)))
+		}
+		exp := now.Add(ttl)
+		row.ExpiresAt = &exp
+	}
+
+	return row, problems
+}
+
+// validateBatch validates every item and delete ID. Duplicate IDs within
+// one request are rejected rather than resolved last-write-wins, since
+// that's almost always a bug on the caller's side.
+func validateBatch(batch webhookBatch, now time.Time) ([]customRow, []string) {
+	var problems []string
+	if len(batch.Items)+len(batch.Delete) == 0 {
+		return nil, []string{"nothing to do: send an item, items, or delete"}
+	}
+	if len(batch.Items)+len(batch.Delete) > MaxWebhookItems {
+		return nil, []string{fmt.Sprintf("at most %d items and deletes per request", MaxWebhookItems)}
+	}
+
+	seen := make(map[string]bool, len(batch.Items)+len(batch.Delete))
+	rows := make([]customRow, 0, len(batch.Items))
+	for i, in := range batch.Items {
+		field := fmt.Sprintf("items[%d].", i)
+		row, errs := validateItem(in, field, now)
+		problems = append(problems, errs...)
+		if seen[in.ID] && in.ID != "" {
+			problems = append(problems, field+"id: duplicate in request")
+		}
+		seen[in.ID] = true
+		rows = append(rows, row)
+	}
+	for i, id := range batch.Delete {
+		field := fmt.Sprintf("delete[%d]", i)
+		if !validItemID(id) {
+			problems = append(problems, field+": not a valid item id")
+		} else if seen[id] {
+			problems = append(problems, field+": also present in items")
+		}
+		seen[id] = true
+	}
+	if len(batch.Items) == 1 && len(batch.Delete) == 0 {
+		// Single-item form: drop the "items[0]." prefix the caller never wrote.
+		for i, p := range problems {
+			problems[i] = strings.TrimPrefix(p, "items[0].")
+		}
+	}
+	return rows, problems
+}
+
+// ─── Ingest ──────────────────────────────────────────────────────
+
+// errItemCapReached aborts an ingest transaction that would leave the user
+// over their tier's item cap.
+var errItemCapReached = errors.New("item cap reached")
+
+// handleWebhook handles POST /custom/hooks/:token. Public — the token is
+// the credential — so unknown tokens get a plain 404 that doesn't say
+// whether the token was ever valid.
+func (a *App) handleWebhook(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if !strings.HasPrefix(token, HookTokenPrefix) || len(token) > 128 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Unknown webhook",
+		})
+	}
+	tokenHash := hashHookToken(token)
+	ctx := c.Context()
+
+	var userSub, tier string
+	err := a.db.QueryRow(ctx, `
+		SELECT h.logto_sub, COALESCE(p.subscription_tier, 'free')
+		FROM custom_hooks h
+		LEFT JOIN user_preferences p ON p.logto_sub = h.logto_sub
+		WHERE h.token_hash = $1`, tokenHash).Scan(&userSub, &tier)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Unknown webhook",
+		})
+	}
+	if err != nil {
+		log.Printf("[Custom] Hook lookup failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	if !a.allowWebhook(ctx, tokenHash) {
+		c.Set("Retry-After", "60")
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("Rate limit exceeded: %d requests per minute", WebhookRateLimit),
+		})
+	}
+
+	batch, err := parseWebhookBody(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid JSON: " + err.Error(),
+		})
+	}
+	rows, problems := validateBatch(batch, time.Now())
+	if len(problems) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"status":  "error",
+			"error":   "Validation failed",
+			"details": problems,
+		})
+	}
+
+	limit := CustomItemCap(tier)
+	current, deleted, err := a.applyBatch(ctx, userSub, rows, batch.Delete, limit)
+	if errors.Is(err, errItemCapReached) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "item limit reached for your tier",
+			"current": current,
+			"max":     limit,
+			"tier":    tier,
+		})
+	}
+	if err != nil {
+		log.Printf("[Custom] Ingest failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to store items",
+		})
+	}
+	a.rdb.Del(ctx, CacheKeyCustomPrefix+userSub)
+
+	return c.JSON(fiber.Map{
+		"status":    "ok",
+		"upserted":  len(rows),
+		"deleted":   deleted,
+		"items":     current,
+		"remaining": max(limit-current, 0),
+	})
+}
+
+// allowWebhook applies the per-hook WebhookRateLimit. Fails open when
+// Redis is unavailable — the item cap still bounds what can be stored.
+func (a *App) allowWebhook(ctx context.Context, tokenHash string) bool {
+	key := RedisHookRLPrefix + tokenHash
+	count, err := a.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return true
+	}
+	if count == 1 {
+		a.rdb.Expire(ctx, key, time.Minute)
+	}
+	return count <= WebhookRateLimit
+}
+
+// applyBatch upserts rows and deletes deleteIDs for userSub in one
+// transaction, then checks the user's live item count against limit. A
+// batch that would push the count over the cap (or further over it, after
+// a downgrade) is rolled back and errItemCapReached returned with the count
+// it would have produced; updates that don't grow the set always go through.
+func (a *App) applyBatch(ctx context.Context, userSub string, rows []customRow, deleteIDs []string, limit int) (int, int, error) {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := countLiveItems(ctx, tx, userSub)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count items: %w", err)
+	}
+
+	if len(rows) > 0 {
+		b := &pgx.Batch{}
+		for _, r := range rows {
+			b.Queue(`
+				INSERT INTO custom_items (logto_sub, external_id, title, value, status, link, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (logto_sub, external_id) DO UPDATE SET
+					title = EXCLUDED.title,
+					value = EXCLUDED.value,
+					status = EXCLUDED.status,
+					link = EXCLUDED.link,
+					expires_at = EXCLUDED.expires_at,
+					updated_at = now()`,
+				userSub, r.ID, r.Title, r.Value, r.Status, r.Link, r.ExpiresAt)
+		}
+		if err := tx.SendBatch(ctx, b).Close(); err != nil {
+			return 0, 0, fmt.Errorf("upsert items: %w", err)
+		}
+	}
+
+	deleted := 0
+	if len(deleteIDs) > 0 {
+		tag, err := tx.Exec(ctx,
+			"DELETE FROM custom_items WHERE logto_sub = $1 AND external_id = ANY($2)",
+			userSub, deleteIDs)
+		if err != nil {
+			return 0, 0, fmt.Errorf("delete items: %w", err)
+		}
+		deleted = int(tag.RowsAffected())
+	}
+
+	current, err := countLiveItems(ctx, tx, userSub)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count items: %w", err)
+	}
+	if current > limit && current > before {
+		return current, 0, errItemCapReached
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE custom_hooks SET last_used_at = now() WHERE logto_sub = $1", userSub); err != nil {
+		return 0, 0, fmt.Errorf("touch hook: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return current, deleted, nil
+}
+
+// countLiveItems counts userSub's unexpired items.
+func countLiveItems(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, userSub string) (int, error) {
+	var n int
+	err := q.QueryRow(ctx, `
+		SELECT count(*) FROM custom_items
+		WHERE logto_sub = $1 AND (expires_at IS NULL OR expires_at > now())`,
+		userSub).Scan(&n)
+	return n, err
+}
+
+// ─── Hook Management (authenticated) ─────────────────────────────
+
+// getHook handles GET /custom/hook.
+func (a *App) getHook(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := c.Context()
+	status := HookStatus{ItemLimit: CustomItemCap(GetUserTier(c))}
+	var prefix string
+	var created time.Time
+	err := a.db.QueryRow(ctx, `
+		SELECT token_prefix, created_at, last_used_at FROM custom_hooks WHERE logto_sub = $1`,
+		userSub).Scan(&prefix, &created, &status.LastUsedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		log.Printf("[Custom] Hook status failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load webhook",
+		})
+	default:
+		status.Configured = true
+		status.TokenPrefix = prefix
+		status.CreatedAt = &created
+	}
+
+	if status.Items, err = countLiveItems(ctx, a.db, userSub); err != nil {
+		log.Printf("[Custom] Item count failed for %s: %v", userSub, err)
+	}
+	return c.JSON(status)
+}
+
+// rotateHook handles POST /custom/hook/rotate: creates the user's webhook
+// or replaces its token. The plaintext URL is returned only here.
+func (a *App) rotateHook(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	token, err := generateHookToken()
+	if err != nil {
+		log.Printf("[Custom] Token generation failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create webhook",
+		})
+	}
+
+	_, err = a.db.Exec(c.Context(), `
+		INSERT INTO custom_hooks (logto_sub, token_hash, token_prefix)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (logto_sub) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			token_prefix = EXCLUDED.token_prefix,
+			created_at = now(),
+			last_used_at = NULL`,
+		userSub, hashHookToken(token), hookTokenDisplayPrefix(token))
+	if err != nil {
+		log.Printf("[Custom] Failed to store hook for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create webhook",
+		})
+	}
+
+	c.Set("Cache-Control", "no-store")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"url":                   hookURL(token),
+		"token":                 token,
+		"token_prefix":          hookTokenDisplayPrefix(token),
+		"rate_limit_per_minute": WebhookRateLimit,
+	})
+}
+
+// deleteHook handles DELETE /custom/hook. Items already posted stay until
+// they expire or are deleted; only the URL is revoked.
+func (a *App) deleteHook(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	if _, err := a.db.Exec(c.Context(), "DELETE FROM custom_hooks WHERE logto_sub = $1", userSub); err != nil {
+		log.Printf("[Custom] Failed to delete hook for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete webhook",
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHookToken(t *testing.T) {
+	a, err := generateHookToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generateHookToken()
+	if a == b {
+		t.Fatal("two tokens were identical")
+	}
+	if !strings.HasPrefix(a, HookTokenPrefix) || len(a) != len(HookTokenPrefix)+43 {
+		t.Errorf("token = %q; want %s + 43 chars", a, HookTokenPrefix)
+	}
+	if got := hookTokenDisplayPrefix(a); got != a[:len(HookTokenPrefix)+4] {
+		t.Errorf("display prefix = %q", got)
+	}
+	if hashHookToken(a) == hashHookToken(b) || len(hashHookToken(a)) != 64 {
+		t.Error("hash is not a distinct sha256 hex digest")
+	}
+}
+
+func TestParseWebhookBody(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		items   int
+		deletes int
+		wantErr bool
+	}{
+		{"single item", `{"id":"a","title":"A","value":1.50}`, 1, 0, false},
+		{"batch", `{"items":[{"id":"a","title":"A"},{"id":"b","title":"B"}],"delete":["c"]}`, 2, 1, false},
+		{"delete only", `{"delete":["a"]}`, 0, 1, false},
+		{"unknown item field", `{"id":"a","title":"A","colour":"red"}`, 0, 0, true},
+		{"unknown batch field", `{"items":[],"extra":1}`, 0, 0, true},
+		{"not an object", `[{"id":"a"}]`, 0, 0, true},
+		{"bool value", `{"id":"a","title":"A","value":true}`, 0, 0, true},
+		{"trailing data", `{"id":"a","title":"A"} {}`, 0, 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			batch, err := parseWebhookBody([]byte(tc.body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v; wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && (len(batch.Items) != tc.items || len(batch.Delete) != tc.deletes) {
+				t.Errorf("got %d items, %d deletes; want %d, %d", len(batch.Items), len(batch.Delete), tc.items, tc.deletes)
+			}
+		})
+	}
+
+	batch, _ := parseWebhookBody([]byte(`{"id":"a","title":"A","value":1.50}`))
+	if batch.Items[0].Value != "1.50" {
+		t.Errorf("numeric value = %q; want literal 1.50", batch.Items[0].Value)
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rows, problems := validateBatch(webhookBatch{Items: []itemInput{{
+		ID: "build", Title: "  main  ", Value: "passing", Status: "ok",
+		Link: "https://ci.example.com/1", TTLSeconds: 3600,
+	}}}, now)
+	if len(problems) != 0 {
+		t.Fatalf("valid item rejected: %v", problems)
+	}
+	r := rows[0]
+	if r.Title != "main" || *r.Value != "passing" || *r.Status != "ok" || !r.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("row = %+v", r)
+	}
+
+	cases := []struct {
+		name  string
+		batch webhookBatch
+		want  string
+	}{
+		{"empty", webhookBatch{}, "nothing to do"},
+		{"missing id", webhookBatch{Items: []itemInput{{Title: "x"}}}, "id: required"},
+		{"space in id", webhookBatch{Items: []itemInput{{ID: "a b", Title: "x"}}}, "id: required"},
+		{"missing title", webhookBatch{Items: []itemInput{{ID: "a"}}}, "title: required"},
+		{"long title", webhookBatch{Items: []itemInput{{ID: "a", Title: strings.Repeat("é", MaxItemTitleLength+1)}}}, "title: must be at most"},
+		{"bad status", webhookBatch{Items: []itemInput{{ID: "a", Title: "x", Status: "green"}}}, "status: must be one of"},
+		{"bad link", webhookBatch{Items: []itemInput{{ID: "a", Title: "x", Link: "javascript:alert(1)"}}}, "link: must be an absolute"},
+		{"short ttl", webhookBatch{Items: []itemInput{{ID: "a", Title: "x", TTLSeconds: 5}}}, "ttl_seconds: must be between"},
+		{"duplicate", webhookBatch{Items: []itemInput{{ID: "a", Title: "x"}, {ID: "a", Title: "y"}}}, "items[1].id: duplicate"},
+		{"delete conflicts", webhookBatch{Items: []itemInput{{ID: "a", Title: "x"}}, Delete: []string{"a"}}, "delete[0]: also present"},
+		{"too many", webhookBatch{Delete: make([]string, MaxWebhookItems+1)}, "at most"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, problems := validateBatch(tc.batch, now)
+			joined := strings.Join(problems, "; ")
+			if !strings.Contains(joined, tc.want) {
+				t.Errorf("problems = %q; want one containing %q", joined, tc.want)
+			}
+		})
+	}
+
+	// The single-item form reports bare field names.
+	_, problems = validateBatch(webhookBatch{Items: []itemInput{{ID: "a"}}}, now)
+	if len(problems) != 1 || problems[0] != "title: required" {
+		t.Errorf("single-item problems = %q", problems)
+	}
+}
+
+func TestCustomItemCap(t *testing.T) {
+	if CustomItemCap("bogus") != CustomItemCap(TierFree) {
+		t.Error("unknown tier must get the free cap")
+	}
+	prev := 0
+	for _, tier := range []string{TierFree, TierUplink, TierUplinkPro, TierUplinkUltimate} {
+		if n := CustomItemCap(tier); n <= prev {
+			t.Errorf("%s cap %d not above previous tier's %d", tier, n, prev)
+		} else {
+			prev = n
+		}
+	}
+}
+
+func TestRedactHookToken(t *testing.T) {
+	got := redactHookToken("https://api.myscrollr.com/custom/hooks/scrollr_hook_secret")
+	if strings.Contains(got, "secret") || !strings.HasSuffix(got, "/custom/hooks/[redacted]") {
+		t.Errorf("redacted URL = %q", got)
+	}
+	if got := redactHookToken("http://custom-api:8085/custom/items"); got != "http://custom-api:8085/custom/items" {
+		t.Errorf("unrelated URL changed: %q", got)
+	}
+}
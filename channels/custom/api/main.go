@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Registration Constants
+// =============================================================================
+
+const (
+	// RegistrationKey is the Redis key where this channel registers itself.
+	RegistrationKey = "channel:custom"
+
+	// RegistrationTTL is how long the registration lives in Redis before expiring.
+	RegistrationTTL = 30 * time.Second
+
+	// RegistrationRefresh is how often we refresh the registration.
+	RegistrationRefresh = 20 * time.Second
+
+	// DefaultPort is the default HTTP listen port.
+	DefaultPort = "8085"
+
+	// DefaultChannelURL is the default internal URL for this service.
+	DefaultChannelURL = "http://localhost:8085"
+)
+
+// registrationPayload is the JSON structure stored in Redis for service discovery.
+type registrationPayload struct {
+	Name         string              `json:"name"`
+	DisplayName  string              `json:"display_name"`
+	InternalURL  string              `json:"internal_url"`
+	Capabilities []string            `json:"capabilities"`
+	CDCTables    []string            `json:"cdc_tables"`
+	Routes       []registrationRoute `json:"routes"`
+	StartedAt    int64               `json:"started_at"` // Unix ms; lets the gateway detect restarts
+	Version      string              `json:"version"`    // build (GIT_SHA); lets the gateway spot rollouts
+}
+
+type registrationRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Auth   bool   `json:"auth"`
+}
+
+func main() {
+	// Load .env (optional — don't fatal if missing)
+	_ = godotenv.Load()
+
+	// Sentry init — before any other infrastructure. No-op when
+	// SENTRY_DSN is unset.
+	if initSentry() {
+		defer sentry.Flush(2 * time.Second)
+	}
+
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set (tracing.go).
+	shutdownTracing := initTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+
+	// -------------------------------------------------------------------------
+	// Connect to PostgreSQL
+	// -------------------------------------------------------------------------
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL must be set")
+	}
+
+	poolConfig, err := dbPoolConfig(databaseURL)
+	if err != nil {
+		log.Fatalf("[DB] parse config: %v", err)
+	}
+	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		log.Fatalf("[DB] new pool: %v", err)
+	}
+	defer dbPool.Close()
+
+	if err := dbPool.Ping(context.Background()); err != nil {
+		log.Fatalf("PostgreSQL ping failed: %v", err)
+	}
+	log.Println("Connected to PostgreSQL")
+
+	// -------------------------------------------------------------------------
+	// Run database migrations
+	// -------------------------------------------------------------------------
+	// golang-migrate uses lib/pq which requires explicit sslmode parameter
+	// Append sslmode=disable if not already specified (internal Docker network)
+	migrateURL := databaseURL
+	if !strings.Contains(migrateURL, "sslmode=") {
+		if strings.Contains(migrateURL, "?") {
+			migrateURL += "&sslmode=disable"
+		} else {
+			migrateURL += "?sslmode=disable"
+		}
+	}
+	if strings.Contains(migrateURL, "?") {
+		migrateURL += "&x-migrations-table=schema_migrations_custom"
+	} else {
+		migrateURL += "?x-migrations-table=schema_migrations_custom"
+	}
+
+	m, err := migrate.New("file://migrations", migrateURL)
+	if err != nil {
+		log.Fatalf("[Custom] Failed to create migrator: %v", err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		m.Close()
+		log.Fatalf("[Custom] Migration failed: %v", err)
+	}
+	m.Close()
+	log.Println("[Custom] Database migrations applied")
+
+	// -------------------------------------------------------------------------
+	// Connect to Redis
+	// -------------------------------------------------------------------------
+	rdb, redisMode, err := newRedisClient(os.Getenv)
+	if err != nil {
+		log.Fatalf("Unable to configure Redis: %v", err)
+	}
+	defer rdb.Close()
+
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("Unable to connect to Redis: %v", err)
+	}
+	log.Printf("Connected to Redis (%s)", redisMode)
+
+	// -------------------------------------------------------------------------
+	// Start Redis self-registration heartbeat
+	// -------------------------------------------------------------------------
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go startRegistration(ctx, rdb)
+
+	// -------------------------------------------------------------------------
+	// Setup Fiber HTTP server
+	// -------------------------------------------------------------------------
+	fiberApp := fiber.New(fiber.Config{
+		AppName:               "Scrollr Custom API",
+		DisableStartupMessage: false,
+		BodyLimit:             MaxWebhookBodyBytes,
+	})
+
+	// Sentry middleware MUST be the first middleware so panics from
+	// anything below are captured. Followed immediately by the user-hook
+	// which attaches a hashed anonymous user ID to every request scope.
+	if os.Getenv("SENTRY_DSN") != "" {
+		fiberApp.Use(sentryMiddleware())
+		fiberApp.Use(sentryUserHook())
+	}
+
+	// Server span per request, continuing the gateway's trace (tracing.go)
+	fiberApp.Use(tracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go)
+	fiberApp.Use(metricsMiddleware)
+
+	app := &App{db: dbPool, rdb: rdb}
+
+	// Drop items past their ttl_seconds (custom.go)
+	go app.startExpiryPruner(ctx)
+
+	// Internal routes (called by core gateway only)
+	fiberApp.Use("/internal", requireInternalSignature) // HMAC from core (internal_auth.go)
+	fiberApp.Get("/internal/dashboard", app.handleInternalDashboard)
+	fiberApp.Get("/internal/health", app.handleInternalHealth)
+	fiberApp.Post("/internal/channel-lifecycle", app.handleChannelLifecycle)
+	fiberApp.Post("/internal/channel-lifecycle/replay", app.handleLifecycleReplay)
+
+	// Prometheus scrape endpoint, cluster-internal only (metrics.go)
+	registerPoolMetrics(app.db, app.rdb)
+	fiberApp.Get("/metrics", requireInternalNetwork, handleMetrics)
+
+	// Public webhook, authenticated by the secret in the path (hooks.go)
+	fiberApp.Post("/custom/hooks/:token", app.handleWebhook)
+
+	// Protected routes (core gateway sets X-User-Sub header)
+	fiberApp.Get("/custom/hook", app.getHook)
+	fiberApp.Post("/custom/hook/rotate", app.rotateHook)
+	fiberApp.Delete("/custom/hook", app.deleteHook)
+	fiberApp.Get("/custom/items", app.getItems)
+	fiberApp.Delete("/custom/items/:id", app.deleteItem)
+
+	// -------------------------------------------------------------------------
+	// Start server with graceful shutdown
+	// -------------------------------------------------------------------------
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = DefaultPort
+	}
+
+	go func() {
+		if err := fiberApp.Listen(":" + port); err != nil {
+			log.Fatalf("Fiber server error: %v", err)
+		}
+	}()
+
+	log.Printf("Custom API listening on port %s", port)
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down Custom API...")
+	cancel()
+
+	// Deregister from Redis on shutdown
+	rdb.Del(context.Background(), RegistrationKey)
+	log.Println("Removed registration from Redis")
+
+	if err := fiberApp.Shutdown(); err != nil {
+		log.Printf("Fiber shutdown error: %v", err)
+	}
+}
+
+// startRegistration registers this service in Redis with a TTL and refreshes
+// the registration on a ticker. This allows the core gateway to discover
+// available channel services.
+func startRegistration(ctx context.Context, rdb redis.UniversalClient) {
+	channelURL := os.Getenv("CHANNEL_URL")
+	if channelURL == "" {
+		channelURL = DefaultChannelURL
+	}
+
+	payload := registrationPayload{
+		Name:         "custom",
+		DisplayName:  "Custom",
+		InternalURL:  channelURL,
+		Capabilities: []string{"dashboard_provider", "health_checker", "channel_lifecycle", "lifecycle_replay"},
+		CDCTables:    []string{"custom_items"},
+		Routes: []registrationRoute{
+			{Method: "POST", Path: "/custom/hooks/:token", Auth: false},
+			{Method: "GET", Path: "/custom/hook", Auth: true},
+			{Method: "POST", Path: "/custom/hook/rotate", Auth: true},
+			{Method: "DELETE", Path: "/custom/hook", Auth: true},
+			{Method: "GET", Path: "/custom/items", Auth: true},
+			{Method: "DELETE", Path: "/custom/items/:id", Auth: true},
+		},
+		StartedAt: time.Now().UnixMilli(),
+		Version:   envOr("GIT_SHA", "unknown"),
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Fatalf("Failed to marshal registration payload: %v", err)
+	}
+
+	// Register immediately on startup
+	if err := rdb.Set(ctx, RegistrationKey, data, RegistrationTTL).Err(); err != nil {
+		log.Printf("[Registration] Initial registration failed: %v", err)
+	} else {
+		log.Printf("[Registration] Registered as %s (TTL %s)", RegistrationKey, RegistrationTTL)
+	}
+
+	ticker := time.NewTicker(RegistrationRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[Registration] Stopping heartbeat")
+			return
+		case <-ticker.C:
+			if err := rdb.Set(ctx, RegistrationKey, data, RegistrationTTL).Err(); err != nil {
+				log.Printf("[Registration] Heartbeat refresh failed: %v", err)
+			}
+		}
+	}
+}
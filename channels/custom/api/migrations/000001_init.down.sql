@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS custom_items;
+DROP TABLE IF EXISTS custom_hooks;
@@ -0,0 +1,32 @@
+-- Custom channel: one inbound webhook per user and the items posted to it.
+--
+-- The webhook URL carries a random secret; only its SHA-256 is stored.
+-- Items are keyed by the caller's own id so re-posting updates in place.
+CREATE TABLE IF NOT EXISTS custom_hooks (
+    logto_sub    VARCHAR(255) PRIMARY KEY,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(32) NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS custom_items (
+    id          BIGSERIAL PRIMARY KEY,
+    logto_sub   VARCHAR(255) NOT NULL,
+    external_id VARCHAR(128) NOT NULL,
+    title       VARCHAR(140) NOT NULL,
+    value       VARCHAR(64),
+    status      VARCHAR(10),
+    link        TEXT,
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (logto_sub, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_items_expires
+    ON custom_items(expires_at) WHERE expires_at IS NOT NULL;
+
+-- The gateway routes custom_items CDC events by logto_sub; FULL makes
+-- deletes carry it.
+ALTER TABLE custom_items REPLICA IDENTITY FULL;
@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// CustomItem is one user-posted ticker item as stored in custom_items.
+// ID is the caller's own identifier; re-posting the same ID updates it.
+type CustomItem struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Value     *string    `json:"value,omitempty"`
+	Status    *string    `json:"status,omitempty"`
+	Link      *string    `json:"link,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// HookStatus is the payload of GET /custom/hook. The token itself is only
+// ever returned once, by POST /custom/hook/rotate.
+type HookStatus struct {
+	Configured  bool       `json:"configured"`
+	TokenPrefix string     `json:"token_prefix,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	Items       int        `json:"items"`
+	ItemLimit   int        `json:"item_limit"`
+}
+
+// ErrorResponse is the standard error envelope.
+type ErrorResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
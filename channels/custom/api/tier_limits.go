@@ -0,0 +1,52 @@
+package main
+
+import "github.com/gofiber/fiber/v2"
+
+// =============================================================================
+// Tier Limits — Custom Items
+// =============================================================================
+//
+// Custom items are the only channel cap that isn't in api/core/tier_limits.go
+// DefaultTierLimits yet; the clients don't render it, so it lives here until
+// they do. Kept package-local on purpose: each Go module is independently
+// deployable and cross-module imports are banned by AGENTS.md.
+//
+// Every tier gets some items (unlike fantasy) so free users can try the
+// webhook. Unknown tiers fall through to "free" — a bogus tier value should
+// get the strictest cap, not a higher one.
+
+const (
+	TierFree           = "free"
+	TierUplink         = "uplink"
+	TierUplinkPro      = "uplink_pro"
+	TierUplinkUltimate = "uplink_ultimate"
+	TierSuperUser      = "super_user"
+)
+
+// CustomItemCap returns how many live items a user on tier may store.
+func CustomItemCap(tier string) int {
+	switch tier {
+	case TierSuperUser, TierUplinkUltimate:
+		return 2000
+	case TierUplinkPro:
+		return 500
+	case TierUplink:
+		return 100
+	case TierFree:
+		return 20
+	default:
+		// Unknown / missing tier — apply the strictest cap.
+		return 20
+	}
+}
+
+// GetUserTier reads the X-User-Tier header set by the core gateway for
+// authenticated requests. Returns "free" if the header is not present —
+// callers should treat a missing tier as the least-privileged one.
+func GetUserTier(c *fiber.Ctx) string {
+	tier := c.Get("X-User-Tier")
+	if tier == "" {
+		return TierFree
+	}
+	return tier
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// Database Pool
+// =============================================================================
+
+// Bounded pool — pgxpool's default sizing varies with runtime.NumCPU and
+// sets no connect deadline. These defaults keep one channel from running
+// the shared Postgres dry; DB_MAX_CONNS, DB_MIN_CONNS,
+// DB_MAX_CONN_IDLE_TIME and DB_MAX_CONN_LIFETIME override them, as they
+// do for the core gateway (core's database.go).
+const (
+	DBMaxConns        = 10
+	DBMinConns        = 2
+	DBMaxConnLifetime = 30 * time.Minute
+	DBMaxConnIdleTime = 5 * time.Minute
+	DBConnectTimeout  = 5 * time.Second
+)
+
+// dbPoolConfig parses dbURL and applies the pool sizing.
+func dbPoolConfig(dbURL string) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxConns = int32(envPositiveInt("DB_MAX_CONNS", DBMaxConns))
+	config.MinConns = int32(envPositiveInt("DB_MIN_CONNS", DBMinConns))
+	if config.MinConns > config.MaxConns {
+		config.MinConns = config.MaxConns
+	}
+	config.MaxConnLifetime = envPositiveDuration("DB_MAX_CONN_LIFETIME", DBMaxConnLifetime)
+	config.MaxConnIdleTime = envPositiveDuration("DB_MAX_CONN_IDLE_TIME", DBMaxConnIdleTime)
+	config.ConnConfig.ConnectTimeout = DBConnectTimeout
+	return config, nil
+}
+
+// envPositiveInt reads a positive integer, falling back (with a log line
+// for a bad value) to def.
+func envPositiveInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("[DB] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return n
+}
+
+// envPositiveDuration is envPositiveInt for durations ("15m").
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("[DB] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Constants
+// =============================================================================
+
+const (
+	// RedisTestPrefix throttles POST /discord/test: discord:test:{sub}.
+	RedisTestPrefix  = "discord:test:"
+	TestPostCooldown = 10 * time.Second
+)
+
+// App holds shared dependencies for all handlers.
+type App struct {
+	db    *pgxpool.Pool
+	rdb   redis.UniversalClient
+	index *subscriberIndex
+	queue chan delivery
+}
+
+// =============================================================================
+// Public Routes (proxied by core gateway)
+// =============================================================================
+
+// getStatus reports the caller's forwarding settings and the outcome of
+// the last post, so the settings page can flag a broken webhook.
+func (a *App) getStatus(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	ctx := c.Context()
+
+	status := WebhookStatus{Events: []string{}, Leagues: []string{}}
+	var cfgJSON, sportsJSON []byte
+	err := a.db.QueryRow(ctx, `
+		SELECT d.config, s.config
+		FROM user_channels d
+		LEFT JOIN user_channels s
+		  ON s.logto_sub = d.logto_sub AND s.channel_type = 'sports' AND s.enabled
+		WHERE d.logto_sub = $1 AND d.channel_type = 'discord'
+	`, userSub).Scan(&cfgJSON, &sportsJSON)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Discord] Status lookup failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load status",
+		})
+	}
+
+	var cfg discordConfig
+	if len(cfgJSON) > 0 {
+		_ = json.Unmarshal(cfgJSON, &cfg)
+	}
+	var sports struct {
+		Leagues []string `json:"leagues"`
+	}
+	if len(sportsJSON) > 0 {
+		_ = json.Unmarshal(sportsJSON, &sports)
+	}
+	if s := newSubscriber(userSub, cfg, sports.Leagues); s != nil {
+		status.Configured = true
+		status.Events = sortedKeys(s.Events)
+		status.Leagues = sortedKeys(s.Leagues)
+	}
+
+	var goneHash *string
+	err = a.db.QueryRow(ctx, `
+		SELECT last_success_at, last_error, last_error_at, gone_url_hash
+		FROM discord_webhooks WHERE logto_sub = $1
+	`, userSub).Scan(&status.LastSuccessAt, &status.LastError, &status.LastErrorAt, &goneHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Discord] Delivery status lookup failed for %s: %v", userSub, err)
+	}
+	status.WebhookGone = goneHash != nil && cfg.WebhookURL != "" && *goneHash == webhookURLHash(cfg.WebhookURL)
+
+	return c.JSON(status)
+}
+
+// sendTest posts a sample embed to the caller's configured webhook.
+func (a *App) sendTest(c *fiber.Ctx) error {
+	userSub := GetUserSub(c)
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	ctx := c.Context()
+
+	var cfgJSON []byte
+	err := a.db.QueryRow(ctx,
+		"SELECT config FROM user_channels WHERE logto_sub = $1 AND channel_type = 'discord'",
+		userSub).Scan(&cfgJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Discord channel not configured",
+		})
+	}
+	if err != nil {
+		log.Printf("[Discord] Test lookup failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load channel",
+		})
+	}
+	var cfg discordConfig
+	if err := json.Unmarshal(cfgJSON, &cfg); err != nil || !validWebhookURL(cfg.WebhookURL) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "No valid Discord webhook URL configured",
+		})
+	}
+
+	ok, err := a.rdb.SetNX(ctx, RedisTestPrefix+userSub, "1", TestPostCooldown).Result()
+	if err == nil && !ok {
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Wait a few seconds before sending another test",
+		})
+	}
+
+	err = postWebhook(ctx, cfg.WebhookURL, discordEmbed{
+		Title:       "MyScrollr is connected",
+		Description: "Price alerts, final scores and fantasy results you've selected will appear here.",
+		Color:       colorNeutral,
+	})
+	discordDeliveries.WithLabelValues("test", deliveryOutcome(err)).Inc()
+	a.recordDelivery(ctx, userSub, cfg.WebhookURL, err)
+	switch {
+	case err == nil:
+		return c.JSON(fiber.Map{"ok": true})
+	case errors.Is(err, errWebhookGone):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Discord says this webhook no longer exists",
+		})
+	default:
+		log.Printf("[Discord] Test post failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Discord did not accept the message",
+		})
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// =============================================================================
+// Internal Routes (called by core gateway)
+// =============================================================================
+
+// handleInternalCDC accepts games records through the CDC handler
+// interface and returns the users a final-score post was queued for.
+// The gateway currently fans out over Redis topics instead (events.go),
+// where the same dispatch runs; the claim key keeps the two from posting
+// twice.
+func (a *App) handleInternalCDC(c *fiber.Ctx) error {
+	var req struct {
+		Records []CDCRecord `json:"records"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	seen := make(map[string]bool)
+	users := []string{}
+	for _, rec := range req.Records {
+		for _, sub := range a.dispatchGameRecord(rec) {
+			if !seen[sub] {
+				seen[sub] = true
+				users = append(users, sub)
+			}
+		}
+	}
+	return c.JSON(fiber.Map{"users": users})
+}
+
+// handleInternalHealth pings this service's dependencies.
+func (a *App) handleInternalHealth(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), InternalHealthTimeout)
+	defer cancel()
+
+	result := fiber.Map{"status": "healthy"}
+	degraded := false
+
+	if err := a.db.Ping(ctx); err != nil {
+		result["database"] = "unhealthy: " + err.Error()
+		degraded = true
+	} else {
+		result["database"] = "healthy"
+	}
+
+	if err := a.rdb.Ping(ctx).Err(); err != nil {
+		result["redis"] = "unhealthy: " + err.Error()
+		degraded = true
+	} else {
+		result["redis"] = "healthy"
+	}
+
+	if degraded {
+		result["status"] = "degraded"
+		return c.Status(fiber.StatusServiceUnavailable).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+// =============================================================================
+// Channel Lifecycle
+// =============================================================================
+
+// lifecycleEvent is one channel lifecycle event from the core gateway.
+type lifecycleEvent struct {
+	Event     string                 `json:"event"`
+	User      string                 `json:"user"`
+	Config    map[string]interface{} `json:"config"`
+	OldConfig map[string]interface{} `json:"old_config"`
+	Enabled   *bool                  `json:"enabled"`
+}
+
+// handleChannelLifecycle handles channel lifecycle events dispatched by the core
+// gateway. Events: created, updated, deleted, sync.
+func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
+	var req lifecycleEvent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	a.applyLifecycleEvent(c.Context(), req)
+	a.requestRefresh(c.Context())
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// handleLifecycleReplay applies lifecycle events the gateway queued while
+// this channel was unreachable, in the order they were originally sent.
+func (a *App) handleLifecycleReplay(c *fiber.Ctx) error {
+	var req struct {
+		Events []lifecycleEvent `json:"events"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	ctx := context.Background()
+	for _, ev := range req.Events {
+		a.applyLifecycleEvent(ctx, ev)
+	}
+	a.requestRefresh(ctx)
+	log.Printf("[Discord Lifecycle] Replayed %d queued events", len(req.Events))
+	return c.JSON(fiber.Map{"processed": len(req.Events)})
+}
+
+// applyLifecycleEvent keeps the local index in step with user_channels.
+// Forwarding settings live in the config itself, so created, updated and
+// sync only need the reload the caller requests afterwards; deleted also
+// drops the delivery history.
+func (a *App) applyLifecycleEvent(ctx context.Context, ev lifecycleEvent) {
+	switch ev.Event {
+	case "created", "updated", "sync":
+		// Picked up by the refresh.
+
+	case "deleted":
+		if ev.User == "" {
+			return
+		}
+		a.index.remove(ev.User)
+		if _, err := a.db.Exec(ctx, "DELETE FROM discord_webhooks WHERE logto_sub = $1", ev.User); err != nil {
+			log.Printf("[Discord Lifecycle] Failed to delete webhook status for %s: %v", ev.User, err)
+		}
+
+	default:
+		log.Printf("[Discord Lifecycle] Unknown event: %s", ev.Event)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidWebhookURL(t *testing.T) {
+	for u, want := range map[string]bool{
+		"https://discord.com/api/webhooks/123456789/abc_DEF-123":       true,
+		"https://canary.discord.com/api/webhooks/1/token":              true,
+		"https://discordapp.com/api/webhooks/1/token":                  true,
+		"http://discord.com/api/webhooks/1/token":                      false,
+		"https://discord.com.evil.example/api/webhooks/1/token":        false,
+		"https://evil.example/?https://discord.com/api/webhooks/1/tok": false,
+		"https://discord.com/api/webhooks/1/token?wait=true":           false,
+		"https://discord.com/api/webhooks/abc/token":                   false,
+		"discord.com/api/webhooks/1/token":                             false,
+	} {
+		if got := validWebhookURL(u); got != want {
+			t.Errorf("validWebhookURL(%q) = %v, want %v", u, got, want)
+		}
+	}
+}
+
+func TestNewSubscriberDefaults(t *testing.T) {
+	const hook = "https://discord.com/api/webhooks/1/token"
+
+	s := newSubscriber("u1", discordConfig{WebhookURL: hook}, []string{"NFL", "NBA"})
+	if s == nil {
+		t.Fatal("valid config rejected")
+	}
+	for _, e := range allEvents {
+		if !s.Events[e] {
+			t.Errorf("default events missing %s", e)
+		}
+	}
+	if !s.Leagues["NFL"] || !s.Leagues["NBA"] {
+		t.Errorf("leagues = %v, want the sports channel's", s.Leagues)
+	}
+
+	s = newSubscriber("u1", discordConfig{WebhookURL: hook, Events: []string{EventPriceAlerts}, Leagues: []string{"MLB"}}, []string{"NFL"})
+	if s.Events[EventGameFinals] || !s.Leagues["MLB"] || s.Leagues["NFL"] {
+		t.Errorf("explicit selection not honoured: %+v", s)
+	}
+
+	if newSubscriber("u1", discordConfig{WebhookURL: "https://example.com/hook"}, nil) != nil {
+		t.Error("non-Discord URL accepted")
+	}
+}
+
+func TestSubscriberIndexTopics(t *testing.T) {
+	const hook = "https://discord.com/api/webhooks/1/token"
+	ix := newSubscriberIndex()
+	ix.replace([]*subscriber{
+		newSubscriber("a", discordConfig{WebhookURL: hook}, []string{"NFL"}),
+		newSubscriber("b", discordConfig{WebhookURL: hook, Events: []string{EventGameFinals}}, []string{"NBA"}),
+	})
+
+	want := "cdc:core:user:a,cdc:sports:NBA,cdc:sports:NFL"
+	if got := strings.Join(ix.topics(), ","); got != want {
+		t.Errorf("topics = %s, want %s", got, want)
+	}
+
+	ix.remove("b")
+	if got := strings.Join(ix.topics(), ","); got != "cdc:core:user:a,cdc:sports:NFL" {
+		t.Errorf("topics after remove = %s", got)
+	}
+}
+
+func gameUpdate(changes map[string]interface{}, state string) CDCRecord {
+	rec := CDCRecord{
+		Action: "update",
+		Record: map[string]interface{}{
+			"id": float64(42), "league": "NFL", "state": state,
+			"home_team_name": "Jets", "home_team_score": float64(10),
+			"away_team_name": "Bills", "away_team_score": float64(24),
+			"link": "https://www.espn.com/nfl/game/_/gameId/42",
+		},
+		Changes: changes,
+	}
+	rec.Metadata.TableName = "games"
+	return rec
+}
+
+func TestGameFinalEmbed(t *testing.T) {
+	key, league, embed, ok := gameFinalEmbed(gameUpdate(map[string]interface{}{"state": "post"}, "post"))
+	if !ok {
+		t.Fatal("final not detected")
+	}
+	if key != "game:42" || league != "NFL" {
+		t.Errorf("key, league = %s, %s", key, league)
+	}
+	if embed.Title != "NFL final: Bills 24, Jets 10" {
+		t.Errorf("title = %q", embed.Title)
+	}
+
+	if _, _, _, ok := gameFinalEmbed(gameUpdate(map[string]interface{}{"home_team_score": 10}, "post")); ok {
+		t.Error("score correction on a finished game posted again")
+	}
+	if _, _, _, ok := gameFinalEmbed(gameUpdate(nil, "in")); ok {
+		t.Error("in-progress game posted")
+	}
+}
+
+func TestUserRecordEmbed(t *testing.T) {
+	alert := CDCRecord{Record: map[string]interface{}{
+		"event": "price_alert", "alert_id": float64(7), "symbol": "TSLA", "condition": "below",
+		"threshold": float64(200), "price": 198.5, "percentage_change": -3.2,
+		"triggered_at": "2026-10-14T15:04:05Z",
+	}}
+	alert.Metadata.TableName = "price_alert_events"
+	kind, key, embed, ok := userRecordEmbed(alert)
+	if !ok || kind != EventPriceAlerts {
+		t.Fatalf("alert not recognised: %v %s", ok, kind)
+	}
+	if embed.Title != "TSLA fell below 200.00" || embed.Color != colorDown {
+		t.Errorf("embed = %+v", embed)
+	}
+	if !strings.HasPrefix(key, "alert:7:") {
+		t.Errorf("key = %s", key)
+	}
+
+	score := CDCRecord{Record: map[string]interface{}{
+		"league_key": "nfl.l.1", "week": float64(6), "status": "postevent", "team_key": "nfl.l.1.t.3",
+		"points": 101.4, "opponent_points": 99.9,
+	}}
+	score.Metadata.TableName = "fantasy_matchup_scores"
+	kind, key, embed, ok = userRecordEmbed(score)
+	if !ok || kind != EventFantasyResults || key != "fantasy:nfl.l.1.t.3:6" {
+		t.Fatalf("fantasy result = %v %s %s", ok, kind, key)
+	}
+	if embed.Title != "Week 6 final: you won 101.40–99.90" {
+		t.Errorf("title = %q", embed.Title)
+	}
+
+	score.Record["status"] = "midevent"
+	if _, _, _, ok := userRecordEmbed(score); ok {
+		t.Error("live score posted as a result")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter(http.Header{}, []byte(`{"retry_after": 1.5}`)); got != 1500*time.Millisecond {
+		t.Errorf("body retry_after = %s", got)
+	}
+	h := http.Header{}
+	h.Set("Retry-After", "3")
+	if got := retryAfter(h, nil); got != 3*time.Second {
+		t.Errorf("header Retry-After = %s", got)
+	}
+}
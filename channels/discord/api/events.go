@@ -0,0 +1,550 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Event Forwarding
+// =============================================================================
+
+// The gateway publishes every CDC record to a Redis topic rather than
+// calling channels, and finance and fantasy publish their synthetic
+// records (price alerts, live matchup scores) on the owner's core topic.
+// This service subscribes to the topics its users need:
+//
+//   - cdc:core:user:{sub} for users who want price alerts or fantasy
+//     results (price_alert_events, fantasy_matchup_scores with status
+//     "postevent");
+//   - cdc:sports:{league} for every league someone wants finals for
+//     (games updates whose state changed to "post").
+//
+// Who wants what comes from user_channels (channel_type 'discord'),
+// reloaded every IndexRefreshInterval and whenever a lifecycle event
+// arrives. Every replica receives every message, so a post is claimed
+// with SETNX first and only one replica sends it.
+
+// Event kinds a user can select in config.events.
+const (
+	EventPriceAlerts    = "price_alerts"
+	EventGameFinals     = "game_finals"
+	EventFantasyResults = "fantasy_results"
+)
+
+var allEvents = []string{EventPriceAlerts, EventGameFinals, EventFantasyResults}
+
+const (
+	// CoreUserTopicPrefix is core's per-user topic (cdc:core:user:{sub}).
+	CoreUserTopicPrefix = "cdc:core:user:"
+
+	// SportsTopicPrefix is the per-league games topic (cdc:sports:{league}).
+	SportsTopicPrefix = "cdc:sports:"
+
+	// RefreshChannel tells every replica to reload subscribers now. A
+	// lifecycle event reaches one replica; the others learn of it here.
+	RefreshChannel = "discord:refresh"
+
+	// IndexRefreshInterval is how often subscribers are reloaded anyway.
+	IndexRefreshInterval = time.Minute
+
+	// RedisSentPrefix claims a post: discord:sent:{sub}:{event key}.
+	RedisSentPrefix   = "discord:sent:"
+	DeliveryDedupeTTL = 48 * time.Hour
+
+	// DeliveryWorkers and DeliveryQueueSize bound concurrent posts and
+	// how many may wait. A full queue drops the post.
+	DeliveryWorkers   = 4
+	DeliveryQueueSize = 1024
+)
+
+// discordConfig is user_channels.config for a discord channel.
+type discordConfig struct {
+	WebhookURL string   `json:"webhook_url"`
+	Events     []string `json:"events"`
+	Leagues    []string `json:"leagues"`
+}
+
+// subscriber is one user's effective forwarding settings.
+type subscriber struct {
+	Sub        string
+	WebhookURL string
+	Events     map[string]bool
+	Leagues    map[string]bool
+}
+
+// newSubscriber resolves a config: events default to all of them, and
+// leagues default to the user's sports channel leagues. Returns nil when
+// there is no valid webhook URL.
+func newSubscriber(sub string, cfg discordConfig, sportsLeagues []string) *subscriber {
+	if !validWebhookURL(cfg.WebhookURL) {
+		return nil
+	}
+	s := &subscriber{
+		Sub:        sub,
+		WebhookURL: cfg.WebhookURL,
+		Events:     make(map[string]bool),
+		Leagues:    make(map[string]bool),
+	}
+	events := cfg.Events
+	if len(events) == 0 {
+		events = allEvents
+	}
+	for _, e := range events {
+		s.Events[e] = true
+	}
+	leagues := cfg.Leagues
+	if len(leagues) == 0 {
+		leagues = sportsLeagues
+	}
+	for _, l := range leagues {
+		if l != "" {
+			s.Leagues[l] = true
+		}
+	}
+	return s
+}
+
+// subscriberIndex is the in-memory view of who gets what.
+type subscriberIndex struct {
+	mu       sync.RWMutex
+	bySub    map[string]*subscriber
+	byLeague map[string][]*subscriber
+}
+
+func newSubscriberIndex() *subscriberIndex {
+	return &subscriberIndex{bySub: map[string]*subscriber{}, byLeague: map[string][]*subscriber{}}
+}
+
+// replace swaps in a freshly loaded subscriber list.
+func (ix *subscriberIndex) replace(subs []*subscriber) {
+	bySub := make(map[string]*subscriber, len(subs))
+	byLeague := make(map[string][]*subscriber)
+	for _, s := range subs {
+		bySub[s.Sub] = s
+		if s.Events[EventGameFinals] {
+			for l := range s.Leagues {
+				byLeague[l] = append(byLeague[l], s)
+			}
+		}
+	}
+	ix.mu.Lock()
+	ix.bySub, ix.byLeague = bySub, byLeague
+	ix.mu.Unlock()
+}
+
+// remove drops sub until the next reload (its webhook is gone).
+func (ix *subscriberIndex) remove(sub string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.bySub, sub)
+	for l, list := range ix.byLeague {
+		kept := list[:0:0]
+		for _, s := range list {
+			if s.Sub != sub {
+				kept = append(kept, s)
+			}
+		}
+		ix.byLeague[l] = kept
+	}
+}
+
+func (ix *subscriberIndex) get(sub string) *subscriber {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.bySub[sub]
+}
+
+func (ix *subscriberIndex) forLeague(league string) []*subscriber {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.byLeague[league]
+}
+
+// topics lists the Redis channels the index needs, sorted.
+func (ix *subscriberIndex) topics() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var out []string
+	for sub, s := range ix.bySub {
+		if s.Events[EventPriceAlerts] || s.Events[EventFantasyResults] {
+			out = append(out, CoreUserTopicPrefix+sub)
+		}
+	}
+	for league, list := range ix.byLeague {
+		if len(list) > 0 {
+			out = append(out, SportsTopicPrefix+league)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// loadSubscribers reads every enabled discord channel, its owner's
+// sports leagues, and skips webhooks Discord has reported gone.
+func (a *App) loadSubscribers(ctx context.Context) ([]*subscriber, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT d.logto_sub, d.config, s.config, w.gone_url_hash
+		FROM user_channels d
+		LEFT JOIN user_channels s
+		  ON s.logto_sub = d.logto_sub AND s.channel_type = 'sports' AND s.enabled
+		LEFT JOIN discord_webhooks w ON w.logto_sub = d.logto_sub
+		WHERE d.channel_type = 'discord' AND d.enabled
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*subscriber
+	for rows.Next() {
+		var sub string
+		var cfgJSON, sportsJSON []byte
+		var goneHash *string
+		if err := rows.Scan(&sub, &cfgJSON, &sportsJSON, &goneHash); err != nil {
+			log.Printf("[Discord] Subscriber scan error: %v", err)
+			continue
+		}
+		var cfg discordConfig
+		if err := json.Unmarshal(cfgJSON, &cfg); err != nil {
+			continue
+		}
+		if goneHash != nil && *goneHash == webhookURLHash(cfg.WebhookURL) {
+			continue
+		}
+		var sports struct {
+			Leagues []string `json:"leagues"`
+		}
+		if len(sportsJSON) > 0 {
+			_ = json.Unmarshal(sportsJSON, &sports)
+		}
+		if s := newSubscriber(sub, cfg, sports.Leagues); s != nil {
+			subs = append(subs, s)
+		}
+	}
+	return subs, rows.Err()
+}
+
+// requestRefresh asks every replica to reload subscribers.
+func (a *App) requestRefresh(ctx context.Context) {
+	if err := a.rdb.Publish(ctx, RefreshChannel, "1").Err(); err != nil {
+		log.Printf("[Discord] Refresh publish failed: %v", err)
+	}
+}
+
+// startEventForwarder subscribes to the topics the index needs and
+// forwards matching events until ctx is cancelled.
+func (a *App) startEventForwarder(ctx context.Context) {
+	ps := a.rdb.Subscribe(ctx, RefreshChannel)
+	defer ps.Close()
+
+	subscribed := make(map[string]bool)
+	refresh := func() {
+		subs, err := a.loadSubscribers(ctx)
+		if err != nil {
+			log.Printf("[Discord] Failed to load subscribers: %v", err)
+			return
+		}
+		a.index.replace(subs)
+		a.syncTopics(ctx, ps, subscribed)
+	}
+	refresh()
+
+	ticker := time.NewTicker(IndexRefreshInterval)
+	defer ticker.Stop()
+	msgs := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if msg.Channel == RefreshChannel {
+				refresh()
+				continue
+			}
+			a.handleTopicMessage(ctx, msg.Channel, []byte(msg.Payload))
+		}
+	}
+}
+
+// syncTopics subscribes to topics the index gained and drops the ones it
+// lost.
+func (a *App) syncTopics(ctx context.Context, ps *redis.PubSub, subscribed map[string]bool) {
+	want := make(map[string]bool)
+	var add []string
+	for _, t := range a.index.topics() {
+		want[t] = true
+		if !subscribed[t] {
+			add = append(add, t)
+		}
+	}
+	var drop []string
+	for t := range subscribed {
+		if !want[t] {
+			drop = append(drop, t)
+		}
+	}
+	if len(add) > 0 {
+		if err := ps.Subscribe(ctx, add...); err != nil {
+			log.Printf("[Discord] Subscribe failed: %v", err)
+			return
+		}
+		for _, t := range add {
+			subscribed[t] = true
+		}
+	}
+	if len(drop) > 0 {
+		if err := ps.Unsubscribe(ctx, drop...); err != nil {
+			log.Printf("[Discord] Unsubscribe failed: %v", err)
+			return
+		}
+		for _, t := range drop {
+			delete(subscribed, t)
+		}
+	}
+}
+
+// handleTopicMessage routes one topic payload.
+func (a *App) handleTopicMessage(ctx context.Context, channel string, payload []byte) {
+	var env cdcEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return
+	}
+	switch {
+	case strings.HasPrefix(channel, CoreUserTopicPrefix):
+		s := a.index.get(strings.TrimPrefix(channel, CoreUserTopicPrefix))
+		if s == nil {
+			return
+		}
+		for _, rec := range env.Data {
+			if kind, key, embed, ok := userRecordEmbed(rec); ok && s.Events[kind] {
+				a.enqueue(delivery{sub: s, kind: kind, key: key, embed: embed})
+			}
+		}
+	case strings.HasPrefix(channel, SportsTopicPrefix):
+		for _, rec := range env.Data {
+			a.dispatchGameRecord(rec)
+		}
+	}
+}
+
+// dispatchGameRecord queues a final-score post for everyone following
+// the game's league and returns who it was queued for.
+func (a *App) dispatchGameRecord(rec CDCRecord) []string {
+	key, league, embed, ok := gameFinalEmbed(rec)
+	if !ok {
+		return nil
+	}
+	var users []string
+	for _, s := range a.index.forLeague(league) {
+		a.enqueue(delivery{sub: s, kind: EventGameFinals, key: key, embed: embed})
+		users = append(users, s.Sub)
+	}
+	return users
+}
+
+// =============================================================================
+// Delivery Queue
+// =============================================================================
+
+type delivery struct {
+	sub   *subscriber
+	kind  string
+	key   string
+	embed discordEmbed
+}
+
+func (a *App) enqueue(d delivery) {
+	select {
+	case a.queue <- d:
+	default:
+		discordDeliveries.WithLabelValues(d.kind, "dropped").Inc()
+		log.Printf("[Discord] Delivery queue full; dropped %s for %s", d.kind, d.sub.Sub)
+	}
+}
+
+// startDeliveryWorkers drains the queue with DeliveryWorkers posters.
+func (a *App) startDeliveryWorkers(ctx context.Context) {
+	for i := 0; i < DeliveryWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d := <-a.queue:
+					a.deliver(ctx, d)
+				}
+			}
+		}()
+	}
+}
+
+// deliver claims and sends one post.
+func (a *App) deliver(ctx context.Context, d delivery) {
+	claim := RedisSentPrefix + d.sub.Sub + ":" + d.key
+	ok, err := a.rdb.SetNX(ctx, claim, "1", DeliveryDedupeTTL).Result()
+	if err != nil {
+		log.Printf("[Discord] Redis SETNX failed (continuing): %v", err)
+	} else if !ok {
+		return
+	}
+
+	err = postWebhook(ctx, d.sub.WebhookURL, d.embed)
+	discordDeliveries.WithLabelValues(d.kind, deliveryOutcome(err)).Inc()
+	if err != nil {
+		log.Printf("[Discord] %s post for %s failed: %v", d.kind, d.sub.Sub, err)
+	}
+	a.recordDelivery(ctx, d.sub.Sub, d.sub.WebhookURL, err)
+}
+
+// =============================================================================
+// Messages
+// =============================================================================
+
+// priceAlertEvent mirrors finance's AlertEvent.
+type priceAlertEvent struct {
+	AlertID          int64     `json:"alert_id"`
+	Symbol           string    `json:"symbol"`
+	Condition        string    `json:"condition"`
+	Threshold        float64   `json:"threshold"`
+	Price            float64   `json:"price"`
+	PercentageChange float64   `json:"percentage_change"`
+	TriggeredAt      time.Time `json:"triggered_at"`
+}
+
+// fantasyScoreEvent mirrors fantasy's MatchupScoreDelta.
+type fantasyScoreEvent struct {
+	LeagueKey      string   `json:"league_key"`
+	Week           int      `json:"week"`
+	Status         string   `json:"status"`
+	TeamKey        string   `json:"team_key"`
+	Points         *float64 `json:"points"`
+	OpponentPoints *float64 `json:"opponent_points"`
+}
+
+// decodeRecord converts a generically-decoded record into target.
+func decodeRecord(raw map[string]interface{}, target interface{}) bool {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, target) == nil
+}
+
+// userRecordEmbed builds the post for a record on a user's core topic:
+// a fired price alert or a fantasy matchup going final. ok is false for
+// anything else.
+func userRecordEmbed(rec CDCRecord) (kind, key string, embed discordEmbed, ok bool) {
+	switch rec.Metadata.TableName {
+	case "price_alert_events":
+		var ev priceAlertEvent
+		if !decodeRecord(rec.Record, &ev) || ev.AlertID == 0 || ev.Symbol == "" {
+			return
+		}
+		embed = discordEmbed{
+			Description: fmt.Sprintf("Last trade %.2f (%+.2f%% today)", ev.Price, ev.PercentageChange),
+			Color:       colorUp,
+			Footer:      &discordFooter{Text: "Price alert"},
+		}
+		if !ev.TriggeredAt.IsZero() {
+			embed.Timestamp = ev.TriggeredAt.UTC().Format(time.RFC3339)
+		}
+		switch ev.Condition {
+		case "above":
+			embed.Title = fmt.Sprintf("%s rose above %.2f", ev.Symbol, ev.Threshold)
+		case "below":
+			embed.Title = fmt.Sprintf("%s fell below %.2f", ev.Symbol, ev.Threshold)
+			embed.Color = colorDown
+		default:
+			embed.Title = fmt.Sprintf("%s moved %+.2f%% today", ev.Symbol, ev.PercentageChange)
+			if ev.PercentageChange < 0 {
+				embed.Color = colorDown
+			}
+		}
+		return EventPriceAlerts, fmt.Sprintf("alert:%d:%d", ev.AlertID, ev.TriggeredAt.UnixMilli()), embed, true
+
+	case "fantasy_matchup_scores":
+		var ev fantasyScoreEvent
+		if !decodeRecord(rec.Record, &ev) || ev.Status != "postevent" || ev.TeamKey == "" || ev.Points == nil || ev.OpponentPoints == nil {
+			return
+		}
+		us, them := *ev.Points, *ev.OpponentPoints
+		embed = discordEmbed{Color: colorNeutral, Footer: &discordFooter{Text: "Fantasy · " + ev.LeagueKey}}
+		switch {
+		case us > them:
+			embed.Title = fmt.Sprintf("Week %d final: you won %.2f–%.2f", ev.Week, us, them)
+			embed.Color = colorUp
+		case us < them:
+			embed.Title = fmt.Sprintf("Week %d final: you lost %.2f–%.2f", ev.Week, us, them)
+			embed.Color = colorDown
+		default:
+			embed.Title = fmt.Sprintf("Week %d final: tied at %.2f", ev.Week, us)
+		}
+		return EventFantasyResults, fmt.Sprintf("fantasy:%s:%d", ev.TeamKey, ev.Week), embed, true
+	}
+	return
+}
+
+// gameRecord is the subset of a games row the post uses. Scores are
+// numbers in CDC records.
+type gameRecord struct {
+	ID            int64    `json:"id"`
+	League        string   `json:"league"`
+	Link          string   `json:"link"`
+	HomeTeamName  string   `json:"home_team_name"`
+	HomeTeamScore *float64 `json:"home_team_score"`
+	AwayTeamName  string   `json:"away_team_name"`
+	AwayTeamScore *float64 `json:"away_team_score"`
+	ShortDetail   string   `json:"short_detail"`
+	State         string   `json:"state"`
+}
+
+// gameFinalEmbed builds the post for a games update that moved the game
+// to "post". Records without a changes map can't show the transition, so
+// the claim key (per game) is what stops repeats for those.
+func gameFinalEmbed(rec CDCRecord) (key, league string, embed discordEmbed, ok bool) {
+	if rec.Metadata.TableName != "games" || rec.Action != "update" {
+		return
+	}
+	if rec.Changes != nil {
+		if _, stateChanged := rec.Changes["state"]; !stateChanged {
+			return
+		}
+	}
+	var g gameRecord
+	if !decodeRecord(rec.Record, &g) || g.State != "post" || g.ID == 0 || g.League == "" {
+		return
+	}
+	embed = discordEmbed{
+		Title: fmt.Sprintf("%s final: %s %s, %s %s", g.League,
+			g.AwayTeamName, scoreText(g.AwayTeamScore), g.HomeTeamName, scoreText(g.HomeTeamScore)),
+		Description: g.ShortDetail,
+		Color:       colorNeutral,
+		Footer:      &discordFooter{Text: "Final score"},
+	}
+	if strings.HasPrefix(g.Link, "https://") {
+		embed.URL = g.Link
+	}
+	return "game:" + strconv.FormatInt(g.ID, 10), g.League, embed, true
+}
+
+func scoreText(score *float64) string {
+	if score == nil {
+		return "–"
+	}
+	return strconv.FormatFloat(*score, 'f', -1, 64)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// InternalHealthTimeout is the aggregate timeout for a /internal/health
+// request, covering DB and Redis pings. Shorter than the k8s readiness
+// probe timeout so a slow downstream doesn't hold up the probe.
+const InternalHealthTimeout = 3 * time.Second
+
+// GetUserSub reads the X-User-Sub header set by the core gateway for
+// authenticated requests. Returns empty string if not present.
+func GetUserSub(c *fiber.Ctx) string {
+	return c.Get("X-User-Sub")
+}
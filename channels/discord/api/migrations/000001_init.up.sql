@@ -0,0 +1,15 @@
+-- Discord channel: delivery state per user.
+--
+-- The webhook URL and event selection live in user_channels.config; this
+-- table only records how delivery to that URL is going. When Discord
+-- says the webhook is gone (401/404) the URL's SHA-256 is kept in
+-- gone_url_hash and nothing more is sent until the user saves a
+-- different URL.
+CREATE TABLE IF NOT EXISTS discord_webhooks (
+    logto_sub       VARCHAR(255) PRIMARY KEY,
+    last_success_at TIMESTAMP WITH TIME ZONE,
+    last_error      TEXT,
+    last_error_at   TIMESTAMP WITH TIME ZONE,
+    gone_url_hash   VARCHAR(64),
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
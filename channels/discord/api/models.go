@@ -0,0 +1,41 @@
+package main
+
+import "time"
+
+// WebhookStatus is the payload of GET /discord/status. The webhook URL is
+// a credential and is never echoed back.
+type WebhookStatus struct {
+	Configured    bool       `json:"configured"`
+	Events        []string   `json:"events"`
+	Leagues       []string   `json:"leagues"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	// WebhookGone is set once Discord reports the webhook deleted; saving
+	// a new URL clears it.
+	WebhookGone bool `json:"webhook_gone"`
+}
+
+// cdcEnvelope is a topic message from the gateway (or a channel's
+// synthetic record): the same shape the SSE stream carries.
+type cdcEnvelope struct {
+	Data []CDCRecord `json:"data"`
+}
+
+// CDCRecord is one change record, as published on a topic or posted to
+// /internal/cdc.
+type CDCRecord struct {
+	Action   string                 `json:"action"`
+	Record   map[string]interface{} `json:"record"`
+	Changes  map[string]interface{} `json:"changes"`
+	Metadata struct {
+		TableSchema string `json:"table_schema"`
+		TableName   string `json:"table_name"`
+	} `json:"metadata"`
+}
+
+// ErrorResponse is the standard error envelope.
+type ErrorResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// OpenTelemetry Tracing
+// =============================================================================
+
+// The core gateway sends a traceparent header with every proxied and
+// internal call; tracingMiddleware continues that trace, so a gateway
+// request and the work it caused here show up as one trace. Spans are
+// exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT is set.
+//
+// Same privacy rules as Sentry: the user is hashUserSub(X-User-Sub).
+
+const traceChannelName = "discord"
+
+var tracer = otel.Tracer("github.com/brandon-relentnet/myscrollr/channels/discord/api")
+
+// initTracing installs the W3C propagators and, when an OTLP endpoint is
+// configured, an exporting tracer provider. The returned func flushes
+// pending spans.
+func initTracing(ctx context.Context) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		log.Printf("[Tracing] OTLP exporter init failed, spans disabled: %v", err)
+		return func(context.Context) error { return nil }
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", sentryServiceTag),
+			attribute.String("deployment.environment", envOr("ENVIRONMENT", "development")),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		log.Printf("[Tracing] resource detection: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	log.Println("[Tracing] OTLP span export enabled")
+	return provider.Shutdown
+}
+
+// tracingMiddleware starts the server span for a request, continuing the
+// caller's trace, and hands its context to handlers via c.UserContext().
+func tracingMiddleware(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), fiberHeaderCarrier{c})
+	ctx, span := tracer.Start(ctx, c.Method()+" "+c.Path(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("scrollr.channel", traceChannelName),
+		))
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fe, ok := err.(*fiber.Error); ok {
+		status = fe.Code
+	}
+	span.SetName(c.Method() + " " + c.Route().Path)
+	span.SetAttributes(
+		attribute.String("http.route", c.Route().Path),
+		attribute.Int("http.response.status_code", status),
+	)
+	if hash := hashUserSub(c.Get("X-User-Sub")); hash != "" {
+		span.SetAttributes(attribute.String("enduser.id_hash", hash))
+	}
+	if cache := c.GetRespHeader("X-Cache"); cache != "" {
+		span.SetAttributes(attribute.String("scrollr.cache", cache))
+	}
+	if err != nil || status >= 500 {
+		span.SetStatus(codes.Error, strconv.Itoa(status))
+	}
+	return err
+}
+
+// fiberHeaderCarrier adapts fasthttp request headers for propagation.
+type fiberHeaderCarrier struct{ c *fiber.Ctx }
+
+func (f fiberHeaderCarrier) Get(key string) string { return f.c.Get(key) }
+func (f fiberHeaderCarrier) Set(key, value string) { f.c.Request().Header.Set(key, value) }
+func (f fiberHeaderCarrier) Keys() []string {
+	var keys []string
+	f.c.Request().Header.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
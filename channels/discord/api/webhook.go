@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// =============================================================================
+// Discord Webhook Delivery
+// =============================================================================
+
+const (
+	// DiscordPostTimeout bounds one webhook POST.
+	DiscordPostTimeout = 10 * time.Second
+
+	// DiscordMaxRetryAfter is the longest 429 back-off honoured inline. A
+	// longer one drops the message rather than stall a delivery worker.
+	DiscordMaxRetryAfter = 5 * time.Second
+
+	// DiscordUsername overrides the webhook's display name on our posts.
+	DiscordUsername = "MyScrollr"
+)
+
+// webhookURLRegex accepts only Discord's own webhook endpoints, so a
+// config value can never point the service at an arbitrary host.
+var webhookURLRegex = regexp.MustCompile(`^https://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/api/webhooks/[0-9]{1,32}/[A-Za-z0-9_-]{1,128}$`)
+
+// validWebhookURL reports whether u is a Discord webhook URL.
+func validWebhookURL(u string) bool {
+	return webhookURLRegex.MatchString(u)
+}
+
+// webhookURLHash identifies a URL in discord_webhooks without storing it.
+func webhookURLHash(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:])
+}
+
+// errWebhookGone means Discord answered 401 or 404: the webhook was
+// deleted or its token regenerated. Retrying can't help.
+var errWebhookGone = errors.New("discord webhook no longer exists")
+
+// errRateLimited means Discord asked us to wait longer than
+// DiscordMaxRetryAfter.
+var errRateLimited = errors.New("discord rate limited the webhook")
+
+// discordHTTPClient never follows redirects; a webhook URL that redirects
+// isn't one we validated.
+var discordHTTPClient = &http.Client{
+	Timeout: DiscordPostTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// discordEmbed is the subset of Discord's embed object we send.
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordMessage is a webhook execute body. AllowedMentions is always
+// empty so nothing in a team name or title can ping @everyone.
+type discordMessage struct {
+	Username        string         `json:"username"`
+	Embeds          []discordEmbed `json:"embeds"`
+	AllowedMentions struct {
+		Parse []string `json:"parse"`
+	} `json:"allowed_mentions"`
+}
+
+// Embed colours.
+const (
+	colorUp      = 0x10b981
+	colorDown    = 0xef4444
+	colorNeutral = 0x5865f2
+)
+
+// postWebhook sends one embed. A 429 with a short retry_after is retried
+// once; 401/404 return errWebhookGone.
+func postWebhook(ctx context.Context, webhookURL string, embed discordEmbed) error {
+	msg := discordMessage{Username: DiscordUsername, Embeds: []discordEmbed{embed}}
+	msg.AllowedMentions.Parse = []string{}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal discord message: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build discord request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := discordHTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("discord request: %w", err)
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNotFound:
+			return errWebhookGone
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := retryAfter(resp.Header, respBody)
+			if attempt > 0 || wait > DiscordMaxRetryAfter {
+				return errRateLimited
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		default:
+			return fmt.Errorf("discord returned %d: %s", resp.StatusCode, string(respBody))
+		}
+	}
+}
+
+// retryAfter reads Discord's back-off from the JSON body (seconds, may be
+// fractional) or the Retry-After header.
+func retryAfter(h http.Header, body []byte) time.Duration {
+	var parsed struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && parsed.RetryAfter > 0 {
+		return time.Duration(parsed.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return time.Second
+}
+
+// recordDelivery stores the outcome of a post in discord_webhooks.
+func (a *App) recordDelivery(ctx context.Context, sub, webhookURL string, err error) {
+	var q string
+	var args []interface{}
+	switch {
+	case err == nil:
+		q = `INSERT INTO discord_webhooks (logto_sub, last_success_at, updated_at)
+			VALUES ($1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT (logto_sub) DO UPDATE SET last_success_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP`
+		args = []interface{}{sub}
+	case errors.Is(err, errWebhookGone):
+		q = `INSERT INTO discord_webhooks (logto_sub, last_error, last_error_at, gone_url_hash, updated_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP, $3, CURRENT_TIMESTAMP)
+			ON CONFLICT (logto_sub) DO UPDATE SET last_error = $2, last_error_at = CURRENT_TIMESTAMP,
+				gone_url_hash = $3, updated_at = CURRENT_TIMESTAMP`
+		args = []interface{}{sub, err.Error(), webhookURLHash(webhookURL)}
+		a.index.remove(sub)
+	default:
+		q = `INSERT INTO discord_webhooks (logto_sub, last_error, last_error_at, updated_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT (logto_sub) DO UPDATE SET last_error = $2, last_error_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP`
+		args = []interface{}{sub, err.Error()}
+	}
+	if _, dbErr := a.db.Exec(ctx, q, args...); dbErr != nil {
+		log.Printf("[Discord] Failed to record delivery for %s: %v", sub, dbErr)
+	}
+}
+
+// deliveryOutcome labels a post result for discordDeliveries.
+func deliveryOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "sent"
+	case errors.Is(err, errWebhookGone):
+		return "gone"
+	case errors.Is(err, errRateLimited):
+		return "rate_limited"
+	default:
+		return "failed"
+	}
+}
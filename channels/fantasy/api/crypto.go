@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// Encryption
+// =============================================================================
+//
+// Yahoo refresh tokens are sealed with AES-256-GCM before they reach
+// yahoo_users. Keys are configured as:
+//
+//	ENCRYPTION_KEY     base64 32-byte key. Ciphertexts with no key-ID prefix
+//	                   were written with it (the original, pre-rotation format).
+//	ENCRYPTION_KEYS    optional keyring, "id:base64key,id:base64key".
+//	ENCRYPTION_KEY_ID  which keyring entry new ciphertexts use; defaults to
+//	                   the first entry.
+//
+// With only ENCRYPTION_KEY set, Encrypt keeps writing the unprefixed
+// format so replicas running older builds can still read it mid-rollout.
+// Once a keyring is configured, output is "<id>:" + base64 and Decrypt
+// picks the key by that ID. To rotate: add the new key to the front of
+// ENCRYPTION_KEYS, deploy, then run `scrollr-fantasy encrypt-tokens`
+// (encrypt_tokens.go) to re-seal rows still under an old key. Rows also
+// migrate on their own whenever the sync worker refreshes their token.
+
+// keyIDRegex bounds key IDs to characters that can never appear in
+// standard base64, so the ":" separator is unambiguous.
+var keyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// encryptionKeyring is the parsed key configuration. The legacy
+// ENCRYPTION_KEY is stored under the empty ID.
+type encryptionKeyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+func decodeKey(name, b64 string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return decoded, nil
+}
+
+// loadKeyring reads the key configuration from the environment. It is
+// re-read on every call so tests (and a restarted pod) see env changes.
+func loadKeyring() (*encryptionKeyring, error) {
+	kr := &encryptionKeyring{keys: make(map[string][]byte)}
+
+	if legacy := os.Getenv("ENCRYPTION_KEY"); legacy != "" {
+		key, err := decodeKey("ENCRYPTION_KEY", legacy)
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[""] = key
+	}
+
+	first := ""
+	if ring := os.Getenv("ENCRYPTION_KEYS"); ring != "" {
+		for _, entry := range strings.Split(ring, ",") {
+			id, b64, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || !keyIDRegex.MatchString(id) {
+				return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q (want id:base64key)", id)
+			}
+			if _, dup := kr.keys[id]; dup {
+				return nil, fmt.Errorf("duplicate ENCRYPTION_KEYS id %q", id)
+			}
+			key, err := decodeKey("ENCRYPTION_KEYS["+id+"]", b64)
+			if err != nil {
+				return nil, err
+			}
+			kr.keys[id] = key
+			if first == "" {
+				first = id
+			}
+		}
+	}
+
+	kr.active = first
+	if id := os.Getenv("ENCRYPTION_KEY_ID"); id != "" {
+		if _, ok := kr.keys[id]; !ok {
+			return nil, fmt.Errorf("ENCRYPTION_KEY_ID %q is not in ENCRYPTION_KEYS", id)
+		}
+		kr.active = id
+	}
+
+	if _, ok := kr.keys[kr.active]; !ok {
+		return nil, fmt.Errorf("invalid ENCRYPTION_KEY")
+	}
+	return kr, nil
+}
+
+// splitKeyID separates the key-ID prefix from a stored ciphertext.
+// Unprefixed (legacy) values return an empty ID.
+func splitKeyID(encrypted string) (id, body string) {
+	if id, body, ok := strings.Cut(encrypted, ":"); ok {
+		return id, body
+	}
+	return "", encrypted
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts a plaintext string using AES-256-GCM under the active
+// key and returns "<key-id>:" + base64 ciphertext (no prefix for the
+// legacy key).
+// Wire format: base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
+func Encrypt(plaintext string) (string, error) {
+	kr, err := loadKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(kr.keys[kr.active])
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+	if kr.active == "" {
+		return sealed, nil
+	}
+	return kr.active + ":" + sealed, nil
+}
+
+// Decrypt reverses Encrypt: picks the key named by the prefix, decodes
+// base64, splits nonce from ciphertext+tag, and returns the original
+// plaintext.
+func Decrypt(encrypted string) (string, error) {
+	kr, err := loadKeyring()
+	if err != nil {
+		return "", err
+	}
+	return kr.decrypt(encrypted)
+}
+
+func (kr *encryptionKeyring) decrypt(encrypted string) (string, error) {
+	id, body := splitKeyID(encrypted)
+	key, ok := kr.keys[id]
+	if !ok {
+		return "", fmt.Errorf("decrypt: unknown key id %q", id)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: invalid base64: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(raw) < nonceSize {
+		return "", fmt.Errorf("decrypt: ciphertext too short")
+	}
+
+	plaintext, err := gcm.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+
+	return string(plaintext), nil
+}
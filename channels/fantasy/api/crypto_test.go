@@ -137,3 +137,119 @@ func TestEncryptInvalidKey(t *testing.T) {
 		})
 	}
 }
+
+const (
+	testKeyA = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	testKeyB = "HxodHBsaGRgXFhUUExIREA8ODQwLCgkIBwYFBAMCAQA="
+)
+
+func TestKeyringRotation(t *testing.T) {
+	// Legacy only: unprefixed output, as before key IDs existed.
+	t.Setenv("ENCRYPTION_KEY", testKeyA)
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	legacy, err := Encrypt("refresh-me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(legacy, ":") {
+		t.Fatalf("legacy ciphertext %q carries a key id", legacy)
+	}
+
+	// Rotate: new key first in the ring, legacy key still readable.
+	t.Setenv("ENCRYPTION_KEYS", "k2:"+testKeyB+", k1:"+testKeyA)
+	rotated, err := Encrypt("refresh-me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rotated, "k2:") {
+		t.Errorf("rotated ciphertext %q; want k2: prefix", rotated)
+	}
+	for _, enc := range []string{legacy, rotated} {
+		if got, err := Decrypt(enc); err != nil || got != "refresh-me" {
+			t.Errorf("Decrypt(%q) = %q, %v", enc, got, err)
+		}
+	}
+
+	// Explicit active key ID.
+	t.Setenv("ENCRYPTION_KEY_ID", "k1")
+	if enc, _ := Encrypt("x"); !strings.HasPrefix(enc, "k1:") {
+		t.Errorf("ENCRYPTION_KEY_ID=k1 produced %q", enc)
+	}
+
+	// Dropping a key makes its ciphertexts unreadable, not misread.
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	t.Setenv("ENCRYPTION_KEYS", "k3:"+testKeyA)
+	if _, err := Decrypt(rotated); err == nil || !strings.Contains(err.Error(), "unknown key id") {
+		t.Errorf("Decrypt with k2 removed: err = %v", err)
+	}
+}
+
+func TestLoadKeyringInvalid(t *testing.T) {
+	cases := []struct{ name, keys, id string }{
+		{"missing separator", testKeyA, ""},
+		{"bad id", "k 1:" + testKeyA, ""},
+		{"duplicate id", "k1:" + testKeyA + ",k1:" + testKeyB, ""},
+		{"short key", "k1:c2hvcnQ=", ""},
+		{"unknown active id", "k1:" + testKeyA, "k9"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ENCRYPTION_KEY", "")
+			t.Setenv("ENCRYPTION_KEYS", tc.keys)
+			t.Setenv("ENCRYPTION_KEY_ID", tc.id)
+			if _, err := loadKeyring(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestClassifyStoredToken(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", testKeyA)
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	legacy, _ := Encrypt("tok")
+
+	t.Setenv("ENCRYPTION_KEYS", "k2:"+testKeyB)
+	current, _ := Encrypt("tok")
+	kr, err := loadKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sealed under a key that is not configured at all.
+	t.Setenv("ENCRYPTION_KEYS", "k9:"+testKeyA)
+	foreign, _ := Encrypt("tok")
+
+	cases := []struct {
+		name   string
+		stored string
+		want   tokenState
+	}{
+		{"current", current, tokenCurrent},
+		{"legacy key", legacy, tokenStale},
+		{"yahoo plaintext", "AOxYZ.abc~001~Qq_-9", tokenPlaintext},
+		{"seed placeholder", "seed-not-a-token", tokenPlaintext},
+		{"unknown key id", foreign, tokenUnreadable},
+		{"tampered", current[:len(current)-4] + "AAAA", tokenUnreadable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, plaintext := kr.classifyStoredToken(tc.stored)
+			if state != tc.want {
+				t.Fatalf("state = %d; want %d", state, tc.want)
+			}
+			switch state {
+			case tokenCurrent, tokenStale:
+				if plaintext != "tok" {
+					t.Errorf("plaintext = %q", plaintext)
+				}
+			case tokenPlaintext:
+				if plaintext != tc.stored {
+					t.Errorf("plaintext = %q; want the stored value", plaintext)
+				}
+			}
+		})
+	}
+}
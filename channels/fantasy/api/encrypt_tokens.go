@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// encrypt-tokens — one-shot backfill / key rotation for yahoo_users
+// =============================================================================
+//
+//	scrollr-fantasy encrypt-tokens [-dry-run]
+//
+// Walks every yahoo_users row and makes sure its refresh_token is sealed
+// under the active key (crypto.go): plaintext rows (written before
+// encryption, or by the dev seeder) are encrypted, and rows under an
+// older key are re-sealed. Rows that look like ciphertext but don't
+// decrypt — usually a key missing from ENCRYPTION_KEYS — are reported
+// and left alone rather than double-encrypted. Safe to re-run.
+
+// tokenState classifies a stored refresh_token.
+type tokenState int
+
+const (
+	tokenCurrent    tokenState = iota // sealed under the active key
+	tokenStale                        // sealed under another configured key
+	tokenPlaintext                    // never encrypted
+	tokenUnreadable                   // ciphertext-shaped but undecryptable
+	tokenRaced                        // rewritten by the sync worker mid-run
+)
+
+// minSealedLen is nonce + GCM tag: the shortest raw ciphertext Encrypt
+// can produce.
+const minSealedLen = 12 + 16
+
+// classifyStoredToken decides what encrypt-tokens should do with a row.
+// For every state but tokenUnreadable it also returns the plaintext.
+func (kr *encryptionKeyring) classifyStoredToken(stored string) (tokenState, string) {
+	if plaintext, err := kr.decrypt(stored); err == nil {
+		id, _ := splitKeyID(stored)
+		if id == kr.active {
+			return tokenCurrent, plaintext
+		}
+		return tokenStale, plaintext
+	}
+
+	// Anything that parses as an (optionally key-prefixed) sealed blob is
+	// treated as ciphertext we lack the key for. Yahoo refresh tokens
+	// contain characters outside standard base64, so they never match.
+	id, body := splitKeyID(stored)
+	if id == "" || keyIDRegex.MatchString(id) {
+		if raw, err := base64.StdEncoding.DecodeString(body); err == nil && len(raw) >= minSealedLen {
+			return tokenUnreadable, ""
+		}
+	}
+	return tokenPlaintext, stored
+}
+
+func runEncryptTokens(args []string) int {
+	fs := flag.NewFlagSet("encrypt-tokens", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	kr, err := loadKeyring()
+	if err != nil {
+		log.Printf("[EncryptTokens] %v", err)
+		return 1
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Print("[EncryptTokens] DATABASE_URL is required")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, normalizeDatabaseURL(dbURL))
+	if err != nil {
+		log.Printf("[EncryptTokens] Failed to connect to PostgreSQL: %v", err)
+		return 1
+	}
+	defer pool.Close()
+
+	counts, err := encryptStoredTokens(ctx, pool, kr, *dryRun)
+	if err != nil {
+		log.Printf("[EncryptTokens] %v", err)
+		return 1
+	}
+
+	mode := ""
+	if *dryRun {
+		mode = " (dry run, nothing written)"
+	}
+	fmt.Printf("current=%d re-sealed=%d encrypted=%d unreadable=%d skipped=%d%s\n",
+		counts[tokenCurrent], counts[tokenStale], counts[tokenPlaintext], counts[tokenUnreadable], counts[tokenRaced], mode)
+
+	if counts[tokenUnreadable] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// encryptStoredTokens rewrites every row that is not already current.
+// Each UPDATE is guarded on the old value so a token the sync worker
+// refreshed mid-run is skipped (tokenRaced) instead of clobbered.
+func encryptStoredTokens(ctx context.Context, pool *pgxpool.Pool, kr *encryptionKeyring, dryRun bool) (map[tokenState]int, error) {
+	rows, err := pool.Query(ctx, `SELECT guid, refresh_token FROM yahoo_users ORDER BY guid`)
+	if err != nil {
+		return nil, fmt.Errorf("list yahoo_users: %w", err)
+	}
+	type row struct{ guid, token string }
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.guid, &r.token); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan yahoo_users: %w", err)
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list yahoo_users: %w", err)
+	}
+
+	counts := make(map[tokenState]int)
+	for _, r := range all {
+		state, plaintext := kr.classifyStoredToken(r.token)
+		switch state {
+		case tokenCurrent:
+			counts[state]++
+			continue
+		case tokenUnreadable:
+			log.Printf("[EncryptTokens] %s: ciphertext does not decrypt with any configured key; left unchanged", r.guid)
+			counts[state]++
+			continue
+		}
+
+		if dryRun {
+			counts[state]++
+			continue
+		}
+
+		sealed, err := Encrypt(plaintext)
+		if err != nil {
+			return counts, fmt.Errorf("encrypt %s: %w", r.guid, err)
+		}
+		tag, err := pool.Exec(ctx,
+			`UPDATE yahoo_users SET refresh_token = $2 WHERE guid = $1 AND refresh_token = $3`,
+			r.guid, sealed, r.token)
+		if err != nil {
+			return counts, fmt.Errorf("update %s: %w", r.guid, err)
+		}
+		if tag.RowsAffected() == 0 {
+			counts[tokenRaced]++
+			continue
+		}
+		counts[state]++
+	}
+	return counts, nil
+}
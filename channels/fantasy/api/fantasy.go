@@ -27,6 +27,16 @@ const (
 	// OAuth state management
 	RedisCSRFPrefix            = "csrf:"
 	RedisYahooStateLogtoPrefix = "yahoo_state_logto:"
+	RedisYahooStateScopePrefix = "yahoo_state_scope:"
+
+	// Yahoo Fantasy OAuth scopes. Linking defaults to read-only; write is
+	// an explicit re-consent (/yahoo/start?scope=write) for lineup changes.
+	YahooScopeRead  = "fspt-r"
+	YahooScopeWrite = "fspt-w"
+
+	// League providers, reported as LeagueResponse.Provider
+	ProviderYahoo   = "yahoo"
+	ProviderSleeper = "sleeper"
 
 	// Timeouts and expiries
 	YahooAPITimeout       = 10 * time.Second
@@ -43,7 +53,7 @@ const (
 // App holds the shared dependencies for all handlers.
 type App struct {
 	db          *pgxpool.Pool
-	rdb         *redis.Client
+	rdb         redis.UniversalClient
 	yahooConfig *oauth2.Config
 	syncState   *syncHealth
 
@@ -87,9 +97,10 @@ const LeagueCachePrefix = "fantasy:leagues:"
 // the 4-query sequence, eliminating duplication between handleInternalDashboard
 // and GetMyYahooLeagues.
 func (a *App) fetchLeagueBundle(ctx context.Context, guid string) ([]LeagueResponse, error) {
-	// Fetch all leagues via the user_leagues junction table
+	// Fetch all leagues via the user_leagues junction table. The data blob
+	// comes from the league blob cache below.
 	leagueRows, err := a.db.Query(ctx, `
-		SELECT l.league_key, l.name, l.game_code, l.season, l.data,
+		SELECT l.league_key, l.name, l.game_code, l.season,
 		       ul.team_key, ul.team_name
 		FROM yahoo_leagues l
 		JOIN yahoo_user_leagues ul ON l.league_key = ul.league_key
@@ -106,12 +117,13 @@ func (a *App) fetchLeagueBundle(ctx context.Context, guid string) ([]LeagueRespo
 	for leagueRows.Next() {
 		var lr LeagueResponse
 		if err := leagueRows.Scan(
-			&lr.LeagueKey, &lr.Name, &lr.GameCode, &lr.Season, &lr.Data,
+			&lr.LeagueKey, &lr.Name, &lr.GameCode, &lr.Season,
 			&lr.TeamKey, &lr.TeamName,
 		); err != nil {
 			log.Printf("[LeagueBundle] Scan error: %v", err)
 			continue
 		}
+		lr.Provider = ProviderYahoo
 		leagues = append(leagues, lr)
 		leagueKeys = append(leagueKeys, lr.LeagueKey)
 	}
@@ -120,19 +132,15 @@ func (a *App) fetchLeagueBundle(ctx context.Context, guid string) ([]LeagueRespo
 		return leagues, nil
 	}
 
-	// Batch-fetch standings
-	standingsMap := make(map[string]json.RawMessage)
-	standingsRows, err := a.db.Query(ctx,
-		"SELECT league_key, data FROM yahoo_standings WHERE league_key = ANY($1)", leagueKeys)
-	if err == nil {
-		defer standingsRows.Close()
-		for standingsRows.Next() {
-			var lk string
-			var data json.RawMessage
-			if err := standingsRows.Scan(&lk, &data); err == nil {
-				standingsMap[lk] = data
-			}
-		}
+	// League and standings blobs (Redis, Postgres on miss)
+	dataMap, err := a.getLeagueBlobs(ctx, leagueBlobLeague, leagueKeys)
+	if err != nil {
+		return nil, fmt.Errorf("league blobs: %w", err)
+	}
+	standingsMap, err := a.getLeagueBlobs(ctx, leagueBlobStandings, leagueKeys)
+	if err != nil {
+		log.Printf("[LeagueBundle] Standings blobs: %v", err)
+		standingsMap = map[string]json.RawMessage{}
 	}
 
 	// Batch-fetch matchups for the two most recent weeks per league.
@@ -187,6 +195,7 @@ func (a *App) fetchLeagueBundle(ctx context.Context, guid string) ([]LeagueRespo
 	// Attach associated data to each league
 	for i := range leagues {
 		lk := leagues[i].LeagueKey
+		leagues[i].Data = dataMap[lk]
 		if s, ok := standingsMap[lk]; ok {
 			leagues[i].Standings = s
 		}
@@ -216,9 +225,11 @@ func (a *App) fetchLeagueBundleCached(ctx context.Context, guid string) ([]Leagu
 	if err == nil {
 		var leagues []LeagueResponse
 		if json.Unmarshal(cached, &leagues) == nil {
+			countCache("leagues", true)
 			return leagues, nil
 		}
 	}
+	countCache("leagues", false)
 
 	// Cache miss — use singleflight to collapse concurrent requests for same guid.
 	// If 100 requests hit this simultaneously for the same user, only 1 runs the
@@ -270,6 +281,7 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 		})
 	}
 
+	cdcBatchSize.Observe(float64(len(req.Records)))
 	ctx := context.Background()
 	userSet := make(map[string]struct{})
 
@@ -297,6 +309,10 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 			continue
 		}
 
+		if kind := blobKindForTable(record.Metadata.TableName); kind != "" {
+			a.invalidateLeagueBlob(ctx, kind, leagueKey)
+		}
+
 		subs, err := GetSubscribers(a.rdb, ctx, RedisLeagueUsersPrefix+leagueKey)
 		if err != nil {
 			log.Printf("[Fantasy CDC] Failed to get subscribers for league=%s: %v", leagueKey, err)
@@ -324,8 +340,10 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"users": users})
 }
 
-// handleInternalDashboard returns fantasy data for a user's dashboard.
-// Uses the shared fetchLeagueBundleCached to avoid query duplication.
+// handleInternalDashboard returns fantasy data for a user's dashboard:
+// Yahoo leagues (via the shared fetchLeagueBundleCached) followed by
+// Sleeper leagues. A provider that fails is left out rather than failing
+// the whole payload.
 // Query param: user={logto_sub}
 func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	userSub := c.Query("user")
@@ -333,20 +351,34 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"fantasy": nil})
 	}
 
+	ctx := context.Background()
+	leagues := make([]LeagueResponse, 0)
+	linked := false
+
 	// Resolve logto_sub → guid
 	var guid string
-	err := a.db.QueryRow(context.Background(),
-		"SELECT guid FROM yahoo_users WHERE logto_sub = $1", userSub).Scan(&guid)
-	if err != nil {
-		return c.JSON(fiber.Map{"fantasy": nil})
+	if err := a.db.QueryRow(ctx,
+		"SELECT guid FROM yahoo_users WHERE logto_sub = $1", userSub).Scan(&guid); err == nil {
+		linked = true
+		yahooLeagues, err := a.fetchLeagueBundleCached(ctx, guid)
+		if err != nil {
+			log.Printf("[Dashboard] fetchLeagueBundle error for guid=%s: %v", guid, err)
+		} else {
+			leagues = append(leagues, yahooLeagues...)
+		}
 	}
 
-	leagues, err := a.fetchLeagueBundleCached(context.Background(), guid)
+	sleeperLeagues, err := a.fetchSleeperLeagues(ctx, userSub)
 	if err != nil {
-		log.Printf("[Dashboard] fetchLeagueBundle error for guid=%s: %v", guid, err)
-		return c.JSON(fiber.Map{"fantasy": nil})
+		log.Printf("[Dashboard] fetchSleeperLeagues error for user=%s: %v", userSub, err)
+	} else if len(sleeperLeagues) > 0 {
+		linked = true
+		leagues = append(leagues, sleeperLeagues...)
 	}
 
+	if !linked {
+		return c.JSON(fiber.Map{"fantasy": nil})
+	}
 	return c.JSON(fiber.Map{"fantasy": MyLeaguesResponse{Leagues: leagues}})
 }
 
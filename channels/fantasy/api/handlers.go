@@ -17,8 +17,10 @@ const InternalHealthTimeout = 3 * time.Second
 // Database Helpers
 // =============================================================================
 
-// UpsertYahooUser inserts or updates a Yahoo user with an encrypted refresh token.
-func (a *App) UpsertYahooUser(guid, logtoSub, refreshToken string) error {
+// UpsertYahooUser inserts or updates a Yahoo user with an encrypted refresh
+// token and the OAuth scope that token was granted. A fresh token makes
+// the link active again (token_status.go).
+func (a *App) UpsertYahooUser(guid, logtoSub, refreshToken, scope string) error {
 	encryptedToken, err := Encrypt(refreshToken)
 	if err != nil {
 		log.Printf("[Security Error] Failed to encrypt refresh token for user %s: %v", guid, err)
@@ -26,11 +28,14 @@ func (a *App) UpsertYahooUser(guid, logtoSub, refreshToken string) error {
 	}
 
 	_, err = a.db.Exec(context.Background(), `
-		INSERT INTO yahoo_users (guid, logto_sub, refresh_token)
-		VALUES ($1, $2, $3)
+		INSERT INTO yahoo_users (guid, logto_sub, refresh_token, scope)
+		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (guid) DO UPDATE
-		SET logto_sub = EXCLUDED.logto_sub, refresh_token = EXCLUDED.refresh_token;
-	`, guid, logtoSub, encryptedToken)
+		SET logto_sub = EXCLUDED.logto_sub, refresh_token = EXCLUDED.refresh_token, scope = EXCLUDED.scope,
+		    token_status = 'active', token_checked_at = CURRENT_TIMESTAMP,
+		    token_status_changed_at = CASE WHEN yahoo_users.token_status <> 'active'
+		                                   THEN CURRENT_TIMESTAMP ELSE yahoo_users.token_status_changed_at END;
+	`, guid, logtoSub, encryptedToken, scope)
 
 	return err
 }
@@ -2,11 +2,7 @@ package main
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
-	"fmt"
+	"encoding/json"
 	"io"
 	"log"
 	"net/http"
@@ -26,7 +22,7 @@ const HealthProxyTimeout = 5 * time.Second
 // =============================================================================
 
 // GetSubscribers returns all user subs in a Redis subscription set.
-func GetSubscribers(rdb *redis.Client, ctx context.Context, setKey string) ([]string, error) {
+func GetSubscribers(rdb redis.UniversalClient, ctx context.Context, setKey string) ([]string, error) {
 	return rdb.SMembers(ctx, setKey).Result()
 }
 
@@ -36,7 +32,7 @@ func GetSubscribers(rdb *redis.Client, ctx context.Context, setKey string) ([]st
 const SubscriberSetTTL = 7 * 24 * time.Hour
 
 // AddSubscriber adds a user to a Redis subscriber set with a TTL.
-func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) {
+func AddSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) {
 	pipe := rdb.Pipeline()
 	pipe.SAdd(ctx, setKey, userSub)
 	pipe.Expire(ctx, setKey, SubscriberSetTTL)
@@ -46,7 +42,7 @@ func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub strin
 }
 
 // RemoveSubscriber removes a user from a Redis subscriber set.
-func RemoveSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) {
+func RemoveSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) {
 	if err := rdb.SRem(ctx, setKey, userSub).Err(); err != nil {
 		log.Printf("[Redis] Failed to remove subscriber %s from %s: %v", userSub, setKey, err)
 	}
@@ -90,88 +86,6 @@ func ProxyInternalHealth(c *fiber.Ctx, internalURL string) error {
 	return c.Status(resp.StatusCode).Send(body)
 }
 
-// =============================================================================
-// Encryption
-// =============================================================================
-
-// decodeEncryptionKey reads and decodes the ENCRYPTION_KEY env var.
-func decodeEncryptionKey() ([]byte, error) {
-	key := os.Getenv("ENCRYPTION_KEY")
-	decoded, err := base64.StdEncoding.DecodeString(key)
-	if err != nil || len(decoded) != 32 {
-		return nil, fmt.Errorf("invalid ENCRYPTION_KEY")
-	}
-	return decoded, nil
-}
-
-// Encrypt encrypts a plaintext string using AES-256-GCM and returns a
-// base64-encoded ciphertext.
-// Wire format: base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
-func Encrypt(plaintext string) (string, error) {
-	decodedKey, err := decodeEncryptionKey()
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(decodedKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-// Decrypt reverses Encrypt: decodes base64, splits nonce from ciphertext+tag,
-// and returns the original plaintext.
-// Wire format: base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
-func Decrypt(encrypted string) (string, error) {
-	decodedKey, err := decodeEncryptionKey()
-	if err != nil {
-		return "", err
-	}
-
-	raw, err := base64.StdEncoding.DecodeString(encrypted)
-	if err != nil {
-		return "", fmt.Errorf("decrypt: invalid base64: %w", err)
-	}
-
-	block, err := aes.NewCipher(decodedKey)
-	if err != nil {
-		return "", err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(raw) < nonceSize {
-		return "", fmt.Errorf("decrypt: ciphertext too short")
-	}
-
-	nonce := raw[:nonceSize]
-	ciphertext := raw[nonceSize:]
-
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", fmt.Errorf("decrypt: %w", err)
-	}
-
-	return string(plaintext), nil
-}
-
 // =============================================================================
 // URL / Environment Helpers
 // =============================================================================
@@ -208,3 +122,30 @@ func ValidateURL(urlStr, fallback string) string {
 func GetUserSub(c *fiber.Ctx) string {
 	return c.Get("X-User-Sub")
 }
+
+// =============================================================================
+// Account Audit Events
+// =============================================================================
+
+// Yahoo link events written to core's audit_events table, which backs the
+// user's account activity view (GET /users/me/audit on the gateway).
+const (
+	AuditYahooLinked   = "yahoo.linked"
+	AuditYahooUnlinked = "yahoo.unlinked"
+)
+
+// recordAudit writes an audit event for logtoSub. actor is the user's own
+// sub, or "system" when the change wasn't theirs (a takeover by another
+// account). Best-effort: failures are logged only.
+func (a *App) recordAudit(ctx context.Context, actor, logtoSub, action string, meta map[string]any) {
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	metaJSON, _ := json.Marshal(meta)
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO audit_events (actor_sub, action, target_sub, meta)
+		VALUES ($1, $2, $3, $4)`,
+		actor, action, logtoSub, metaJSON); err != nil {
+		log.Printf("[Audit] write failed (%s on %s): %v", action, logtoSub, err)
+	}
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Matchup History — League Record Book
+// =============================================================================
+//
+// The sync loop archives every finished ("postevent") matchup into
+// fantasy_matchup_history. The record-book endpoints read only from that
+// table, so they keep working for past seasons after the league itself is
+// finished and no longer synced.
+
+const (
+	// RecordBookDefaultLimit / RecordBookMaxLimit bound the streak and
+	// high-score leaderboards.
+	RecordBookDefaultLimit = 10
+	RecordBookMaxLimit     = 50
+)
+
+// matchupHistoryRow is one team's result for one week.
+type matchupHistoryRow struct {
+	LeagueKey       string
+	LeagueFamily    string
+	Season          int
+	Week            int
+	TeamKey         string
+	TeamID          int
+	TeamName        string
+	ManagerName     string
+	OpponentTeamKey *string
+	Points          *float64
+	OpponentPoints  *float64
+	Result          string // W, L, T
+	IsPlayoffs      bool
+	IsConsolation   bool
+}
+
+// FranchiseRecord is one franchise's all-time line in the record book.
+type FranchiseRecord struct {
+	TeamID        int     `json:"team_id"`
+	TeamName      string  `json:"team_name"`
+	ManagerName   string  `json:"manager_name"`
+	Seasons       int     `json:"seasons"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	Ties          int     `json:"ties"`
+	WinPct        float64 `json:"win_pct"`
+	PointsFor     float64 `json:"points_for"`
+	PointsAgainst float64 `json:"points_against"`
+}
+
+// WinStreak is a run of consecutive wins by one franchise. Streaks carry
+// across season boundaries, the way leagues usually count them.
+type WinStreak struct {
+	TeamID      int    `json:"team_id"`
+	TeamName    string `json:"team_name"`
+	ManagerName string `json:"manager_name"`
+	Length      int    `json:"length"`
+	StartSeason int    `json:"start_season"`
+	StartWeek   int    `json:"start_week"`
+	EndSeason   int    `json:"end_season"`
+	EndWeek     int    `json:"end_week"`
+	Active      bool   `json:"active"`
+}
+
+// HighScore is a single-week team score.
+type HighScore struct {
+	TeamID         int      `json:"team_id"`
+	TeamName       string   `json:"team_name"`
+	ManagerName    string   `json:"manager_name"`
+	Season         int      `json:"season"`
+	Week           int      `json:"week"`
+	Points         float64  `json:"points"`
+	OpponentPoints *float64 `json:"opponent_points"`
+	Result         string   `json:"result"`
+	IsPlayoffs     bool     `json:"is_playoffs"`
+}
+
+// ---------------------------------------------------------------------------
+// Archiving (called from sync + import)
+// ---------------------------------------------------------------------------
+
+// buildHistoryRows converts serialized scoreboard matchups into history
+// rows. Only two-team matchups with status "postevent" are archived —
+// in-progress weeks would record a provisional result.
+func buildHistoryRows(leagueKey, family string, season int, matchups []map[string]any) []matchupHistoryRow {
+	var rows []matchupHistoryRow
+	for _, m := range matchups {
+		if status, _ := m["status"].(string); status != "postevent" {
+			continue
+		}
+		teams, _ := m["teams"].([]map[string]any)
+		if len(teams) != 2 {
+			continue
+		}
+		week, _ := m["week"].(int)
+		if week <= 0 {
+			continue
+		}
+		isTied, _ := m["is_tied"].(bool)
+		isPlayoffs, _ := m["is_playoffs"].(bool)
+		isConsolation, _ := m["is_consolation"].(bool)
+		var winner string
+		if wk, ok := m["winner_team_key"].(*string); ok && wk != nil {
+			winner = *wk
+		}
+
+		for i, t := range teams {
+			opp := teams[1-i]
+			teamKey, _ := t["team_key"].(string)
+			oppKey, _ := opp["team_key"].(string)
+			points, _ := t["points"].(*float64)
+			oppPoints, _ := opp["points"].(*float64)
+
+			result := matchupResult(teamKey, winner, isTied, points, oppPoints)
+			if result == "" {
+				continue
+			}
+
+			teamID, _ := t["team_id"].(int)
+			name, _ := t["name"].(string)
+			manager, _ := t["manager_name"].(string)
+			row := matchupHistoryRow{
+				LeagueKey:      leagueKey,
+				LeagueFamily:   family,
+				Season:         season,
+				Week:           week,
+				TeamKey:        teamKey,
+				TeamID:         teamID,
+				TeamName:       name,
+				ManagerName:    manager,
+				Points:         points,
+				OpponentPoints: oppPoints,
+				Result:         result,
+				IsPlayoffs:     isPlayoffs,
+				IsConsolation:  isConsolation,
+			}
+			if oppKey != "" {
+				row.OpponentTeamKey = &oppKey
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// matchupResult decides W/L/T for one side. Yahoo's winner_team_key is
+// authoritative; points are the fallback for payloads without it.
+// Returns "" when the result can't be determined.
+func matchupResult(teamKey, winner string, isTied bool, points, oppPoints *float64) string {
+	switch {
+	case isTied:
+		return "T"
+	case winner != "":
+		if winner == teamKey {
+			return "W"
+		}
+		return "L"
+	case points != nil && oppPoints != nil:
+		switch {
+		case *points > *oppPoints:
+			return "W"
+		case *points < *oppPoints:
+			return "L"
+		default:
+			return "T"
+		}
+	}
+	return ""
+}
+
+// renewToLeagueKey converts Yahoo's `renew` pointer ("{game_id}_{league_id}")
+// into the predecessor's league_key ("{game_id}.l.{league_id}").
+func renewToLeagueKey(renew string) string {
+	parts := strings.SplitN(renew, "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + ".l." + parts[1]
+}
+
+// resolveLeagueFamily returns the family id for a league: the family of
+// its predecessor if we have archived one, else the predecessor's key (so
+// a later archive of that season joins the same family), else its own key.
+func (a *App) resolveLeagueFamily(ctx context.Context, leagueKey, renew string) string {
+	var family string
+	err := a.db.QueryRow(ctx,
+		`SELECT league_family FROM fantasy_matchup_history WHERE league_key = $1 LIMIT 1`,
+		leagueKey,
+	).Scan(&family)
+	if err == nil && family != "" {
+		return family
+	}
+
+	prev := renewToLeagueKey(renew)
+	if prev == "" {
+		return leagueKey
+	}
+	err = a.db.QueryRow(ctx,
+		`SELECT league_family FROM fantasy_matchup_history WHERE league_key = $1 LIMIT 1`,
+		prev,
+	).Scan(&family)
+	if err == nil && family != "" {
+		return family
+	}
+	return prev
+}
+
+// archiveMatchups persists finished matchups for a league week. Errors are
+// logged, not returned — the archive must never fail a sync.
+func (a *App) archiveMatchups(ctx context.Context, leagueKey string, leagueData map[string]any, matchups []map[string]any) {
+	season, _ := leagueData["season"].(int)
+	renew, _ := leagueData["renew"].(string)
+
+	rows := buildHistoryRows(leagueKey, "", season, matchups)
+	if len(rows) == 0 {
+		return
+	}
+	family := a.resolveLeagueFamily(ctx, leagueKey, renew)
+	for i := range rows {
+		rows[i].LeagueFamily = family
+	}
+
+	for _, r := range rows {
+		// DO UPDATE rather than DO NOTHING: Yahoo applies stat corrections
+		// for a few days after a week closes.
+		_, err := a.db.Exec(ctx, `
+			INSERT INTO fantasy_matchup_history
+				(league_key, league_family, season, week, team_key, team_id, team_name,
+				 manager_name, opponent_team_key, points, opponent_points, result,
+				 is_playoffs, is_consolation)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (league_key, week, team_key) DO UPDATE SET
+				team_name = EXCLUDED.team_name,
+				manager_name = EXCLUDED.manager_name,
+				points = EXCLUDED.points,
+				opponent_points = EXCLUDED.opponent_points,
+				result = EXCLUDED.result,
+				recorded_at = CURRENT_TIMESTAMP`,
+			r.LeagueKey, r.LeagueFamily, r.Season, r.Week, r.TeamKey, r.TeamID, r.TeamName,
+			r.ManagerName, r.OpponentTeamKey, r.Points, r.OpponentPoints, r.Result,
+			r.IsPlayoffs, r.IsConsolation,
+		)
+		if err != nil {
+			log.Printf("[History] Failed to archive %s week %d team %s: %v", r.LeagueKey, r.Week, r.TeamKey, err)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Streaks (pure)
+// ---------------------------------------------------------------------------
+
+// longestWinStreaks finds each franchise's win streaks and returns the
+// longest `limit` across the family. `rows` must be ordered by team_id,
+// season, week. Consolation games are expected to be filtered out already.
+func longestWinStreaks(rows []matchupHistoryRow, limit int) []WinStreak {
+	var streaks []WinStreak
+	var cur *WinStreak
+
+	flush := func(active bool) {
+		if cur != nil && cur.Length > 0 {
+			cur.Active = active
+			streaks = append(streaks, *cur)
+		}
+		cur = nil
+	}
+
+	for i, r := range rows {
+		if i > 0 && rows[i-1].TeamID != r.TeamID {
+			// The previous franchise's last game ended whatever run it had.
+			flush(true)
+		}
+		if r.Result != "W" {
+			flush(false)
+			continue
+		}
+		if cur == nil {
+			cur = &WinStreak{TeamID: r.TeamID, StartSeason: r.Season, StartWeek: r.Week}
+		}
+		cur.Length++
+		cur.EndSeason, cur.EndWeek = r.Season, r.Week
+		cur.TeamName, cur.ManagerName = r.TeamName, r.ManagerName
+	}
+	flush(true)
+
+	sort.SliceStable(streaks, func(i, j int) bool {
+		if streaks[i].Length != streaks[j].Length {
+			return streaks[i].Length > streaks[j].Length
+		}
+		if streaks[i].EndSeason != streaks[j].EndSeason {
+			return streaks[i].EndSeason > streaks[j].EndSeason
+		}
+		return streaks[i].EndWeek > streaks[j].EndWeek
+	})
+	if len(streaks) > limit {
+		streaks = streaks[:limit]
+	}
+	return streaks
+}
+
+// ---------------------------------------------------------------------------
+// HTTP handlers
+// ---------------------------------------------------------------------------
+
+// recordBookFamily authorizes the caller for a league and returns its
+// family id. Writes the error response itself when ok is false.
+func (a *App) recordBookFamily(ctx context.Context, c *fiber.Ctx) (family string, ok bool, err error) {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return "", false, c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	leagueKey := c.Params("league_key")
+
+	var member bool
+	if qErr := a.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM yahoo_user_leagues ul
+			JOIN yahoo_users u ON u.guid = ul.guid
+			WHERE u.logto_sub = $1 AND ul.league_key = $2
+		)`, userID, leagueKey,
+	).Scan(&member); qErr != nil {
+		log.Printf("[History] Membership check failed for %s: %v", leagueKey, qErr)
+		return "", false, c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load record book",
+		})
+	}
+	if !member {
+		return "", false, c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "League not found",
+		})
+	}
+
+	if qErr := a.db.QueryRow(ctx,
+		`SELECT league_family FROM fantasy_matchup_history WHERE league_key = $1 LIMIT 1`,
+		leagueKey,
+	).Scan(&family); qErr != nil {
+		// Nothing archived yet for this league — empty record book.
+		family = leagueKey
+	}
+	return family, true, nil
+}
+
+// recordBookLimit parses ?limit= with a default and ceiling.
+func recordBookLimit(c *fiber.Ctx) int {
+	n, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || n <= 0 {
+		return RecordBookDefaultLimit
+	}
+	if n > RecordBookMaxLimit {
+		return RecordBookMaxLimit
+	}
+	return n
+}
+
+// GetLeagueFranchiseRecords returns every franchise's all-time W-L-T and
+// points across all archived seasons of the league. Consolation games are
+// excluded; ?include_playoffs=false restricts to the regular season.
+func (a *App) GetLeagueFranchiseRecords(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	family, ok, err := a.recordBookFamily(ctx, c)
+	if !ok {
+		return err
+	}
+	includePlayoffs := c.Query("include_playoffs") != "false"
+
+	rows, err := a.db.Query(ctx, `
+		SELECT team_id,
+		       (array_agg(team_name ORDER BY season DESC, week DESC))[1],
+		       (array_agg(manager_name ORDER BY season DESC, week DESC))[1],
+		       COUNT(DISTINCT season),
+		       COUNT(*) FILTER (WHERE result = 'W'),
+		       COUNT(*) FILTER (WHERE result = 'L'),
+		       COUNT(*) FILTER (WHERE result = 'T'),
+		       COALESCE(SUM(points), 0),
+		       COALESCE(SUM(opponent_points), 0)
+		FROM fantasy_matchup_history
+		WHERE league_family = $1
+		  AND NOT is_consolation
+		  AND ($2 OR NOT is_playoffs)
+		GROUP BY team_id`, family, includePlayoffs)
+	if err != nil {
+		log.Printf("[History] Franchise records query failed for %s: %v", family, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load record book",
+		})
+	}
+	defer rows.Close()
+
+	records := make([]FranchiseRecord, 0)
+	for rows.Next() {
+		var r FranchiseRecord
+		if err := rows.Scan(&r.TeamID, &r.TeamName, &r.ManagerName, &r.Seasons,
+			&r.Wins, &r.Losses, &r.Ties, &r.PointsFor, &r.PointsAgainst); err != nil {
+			log.Printf("[History] Franchise scan error: %v", err)
+			continue
+		}
+		if games := r.Wins + r.Losses + r.Ties; games > 0 {
+			r.WinPct = (float64(r.Wins) + 0.5*float64(r.Ties)) / float64(games)
+		}
+		records = append(records, r)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].WinPct != records[j].WinPct {
+			return records[i].WinPct > records[j].WinPct
+		}
+		return records[i].PointsFor > records[j].PointsFor
+	})
+
+	return c.JSON(fiber.Map{"league_family": family, "franchises": records})
+}
+
+// GetLeagueWinStreaks returns the longest win streaks in league history.
+func (a *App) GetLeagueWinStreaks(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	family, ok, err := a.recordBookFamily(ctx, c)
+	if !ok {
+		return err
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT team_id, team_name, manager_name, season, week, result
+		FROM fantasy_matchup_history
+		WHERE league_family = $1 AND NOT is_consolation
+		ORDER BY team_id, season, week`, family)
+	if err != nil {
+		log.Printf("[History] Streaks query failed for %s: %v", family, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load record book",
+		})
+	}
+	defer rows.Close()
+
+	var history []matchupHistoryRow
+	for rows.Next() {
+		var r matchupHistoryRow
+		if err := rows.Scan(&r.TeamID, &r.TeamName, &r.ManagerName, &r.Season, &r.Week, &r.Result); err != nil {
+			log.Printf("[History] Streak scan error: %v", err)
+			continue
+		}
+		history = append(history, r)
+	}
+
+	streaks := longestWinStreaks(history, recordBookLimit(c))
+	if streaks == nil {
+		streaks = make([]WinStreak, 0)
+	}
+	return c.JSON(fiber.Map{"league_family": family, "streaks": streaks})
+}
+
+// GetLeagueHighScores returns the highest single-week team scores in
+// league history (consolation games included — points are points).
+func (a *App) GetLeagueHighScores(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	family, ok, err := a.recordBookFamily(ctx, c)
+	if !ok {
+		return err
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT team_id, team_name, manager_name, season, week, points,
+		       opponent_points, result, is_playoffs
+		FROM fantasy_matchup_history
+		WHERE league_family = $1 AND points IS NOT NULL
+		ORDER BY points DESC, season DESC, week DESC
+		LIMIT $2`, family, recordBookLimit(c))
+	if err != nil {
+		log.Printf("[History] High scores query failed for %s: %v", family, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load record book",
+		})
+	}
+	defer rows.Close()
+
+	scores := make([]HighScore, 0)
+	for rows.Next() {
+		var s HighScore
+		if err := rows.Scan(&s.TeamID, &s.TeamName, &s.ManagerName, &s.Season, &s.Week,
+			&s.Points, &s.OpponentPoints, &s.Result, &s.IsPlayoffs); err != nil {
+			log.Printf("[History] High score scan error: %v", err)
+			continue
+		}
+		scores = append(scores, s)
+	}
+
+	return c.JSON(fiber.Map{"league_family": family, "high_scores": scores})
+}
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func fptr(v float64) *float64 { return &v }
+func sptr(v string) *string   { return &v }
+
+func TestMatchupResult(t *testing.T) {
+	tests := []struct {
+		name            string
+		teamKey, winner string
+		tied            bool
+		pts, opp        *float64
+		want            string
+	}{
+		{"tied flag wins over points", "t1", "", true, fptr(100), fptr(90), "T"},
+		{"winner key match", "t1", "t1", false, nil, nil, "W"},
+		{"winner key other", "t1", "t2", false, fptr(120), fptr(90), "L"},
+		{"points fallback win", "t1", "", false, fptr(101.5), fptr(99), "W"},
+		{"points fallback loss", "t1", "", false, fptr(80), fptr(99), "L"},
+		{"points fallback equal", "t1", "", false, fptr(99), fptr(99), "T"},
+		{"undeterminable", "t1", "", false, nil, fptr(99), ""},
+	}
+	for _, tt := range tests {
+		if got := matchupResult(tt.teamKey, tt.winner, tt.tied, tt.pts, tt.opp); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenewToLeagueKey(t *testing.T) {
+	tests := map[string]string{
+		"423_12345": "423.l.12345",
+		"":          "",
+		"423":       "",
+		"_12345":    "",
+	}
+	for in, want := range tests {
+		if got := renewToLeagueKey(in); got != want {
+			t.Errorf("renewToLeagueKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildHistoryRows(t *testing.T) {
+	matchups := []map[string]any{
+		{
+			"week":            3,
+			"status":          "postevent",
+			"is_playoffs":     false,
+			"is_consolation":  false,
+			"is_tied":         false,
+			"winner_team_key": sptr("449.l.1.t.2"),
+			"teams": []map[string]any{
+				{"team_key": "449.l.1.t.1", "team_id": 1, "name": "A", "manager_name": "amy", "points": fptr(88.2)},
+				{"team_key": "449.l.1.t.2", "team_id": 2, "name": "B", "manager_name": "bob", "points": fptr(104.6)},
+			},
+		},
+		{
+			// In progress — must not be archived.
+			"week":   4,
+			"status": "midevent",
+			"teams": []map[string]any{
+				{"team_key": "449.l.1.t.1", "team_id": 1, "points": fptr(10)},
+				{"team_key": "449.l.1.t.2", "team_id": 2, "points": fptr(5)},
+			},
+		},
+	}
+
+	rows := buildHistoryRows("449.l.1", "fam", 2025, matchups)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Result != "L" || rows[1].Result != "W" {
+		t.Errorf("results = %s/%s, want L/W", rows[0].Result, rows[1].Result)
+	}
+	if rows[0].OpponentTeamKey == nil || *rows[0].OpponentTeamKey != "449.l.1.t.2" {
+		t.Errorf("opponent = %v, want 449.l.1.t.2", rows[0].OpponentTeamKey)
+	}
+	if *rows[1].OpponentPoints != 88.2 || rows[1].Season != 2025 || rows[1].Week != 3 {
+		t.Errorf("row[1] = %+v", rows[1])
+	}
+}
+
+func TestLongestWinStreaks(t *testing.T) {
+	r := func(team, season, week int, res string) matchupHistoryRow {
+		return matchupHistoryRow{TeamID: team, Season: season, Week: week, Result: res}
+	}
+	rows := []matchupHistoryRow{
+		// Team 1: 3-game streak spanning a season boundary, then a loss.
+		r(1, 2024, 13, "W"), r(1, 2024, 14, "W"), r(1, 2025, 1, "W"), r(1, 2025, 2, "L"),
+		// Team 2: 1-game streak, tie breaks it, then a 2-game active streak.
+		r(2, 2025, 1, "W"), r(2, 2025, 2, "T"), r(2, 2025, 3, "W"), r(2, 2025, 4, "W"),
+	}
+
+	got := longestWinStreaks(rows, 10)
+	if len(got) != 3 {
+		t.Fatalf("got %d streaks, want 3: %+v", len(got), got)
+	}
+	top := got[0]
+	if top.TeamID != 1 || top.Length != 3 || top.StartSeason != 2024 || top.EndSeason != 2025 || top.Active {
+		t.Errorf("top streak = %+v", top)
+	}
+	if got[1].TeamID != 2 || got[1].Length != 2 || !got[1].Active {
+		t.Errorf("second streak = %+v", got[1])
+	}
+
+	if limited := longestWinStreaks(rows, 1); len(limited) != 1 {
+		t.Errorf("limit 1: got %d streaks", len(limited))
+	}
+}
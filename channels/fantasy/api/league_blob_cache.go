@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// League Blob Cache
+// =============================================================================
+//
+// yahoo_leagues.data and yahoo_standings.data are large JSON documents that
+// every dashboard build reads. They are cached in Redis per league_key,
+// zstd-compressed, and read through: a miss loads from Postgres and fills
+// the cache. Core republishes CDC for the fantasy tables on
+// cdc:fantasy:{league_key}; a subscriber here drops the matching blob as
+// soon as a row changes, so Postgres only serves cold leagues.
+//
+// A read that misses just before a sync writes the league can refill the
+// cache with the old document after the CDC delete; LeagueBlobCacheTTL
+// bounds how long that can last.
+
+const (
+	// LeagueBlobCachePrefix keys blobs as fantasy:blob:{kind}:{league_key}.
+	LeagueBlobCachePrefix = "fantasy:blob:"
+
+	// LeagueBlobCacheTTL bounds staleness if a CDC event is missed.
+	LeagueBlobCacheTTL = 30 * time.Minute
+
+	// FantasyCDCTopicPrefix is core's pub/sub topic for fantasy CDC events
+	// (cdc:fantasy:{league_key}). Known here by convention only.
+	FantasyCDCTopicPrefix = "cdc:fantasy:"
+
+	leagueBlobLeague    = "league"
+	leagueBlobStandings = "standings"
+)
+
+// leagueBlobTables maps a blob kind to the table whose data column it
+// caches, and back.
+var leagueBlobTables = map[string]string{
+	leagueBlobLeague:    "yahoo_leagues",
+	leagueBlobStandings: "yahoo_standings",
+}
+
+var (
+	blobEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	blobDecoder, _ = zstd.NewReader(nil)
+)
+
+func leagueBlobKey(kind, leagueKey string) string {
+	return LeagueBlobCachePrefix + kind + ":" + leagueKey
+}
+
+func compressBlob(data []byte) []byte {
+	return blobEncoder.EncodeAll(data, make([]byte, 0, len(data)/4))
+}
+
+func decompressBlob(data []byte) ([]byte, error) {
+	return blobDecoder.DecodeAll(data, nil)
+}
+
+// blobKindForTable returns the blob kind cached for a CDC table, or "".
+func blobKindForTable(table string) string {
+	for kind, t := range leagueBlobTables {
+		if t == table {
+			return kind
+		}
+	}
+	return ""
+}
+
+// getLeagueBlobs returns the data column of kind for each league key,
+// from Redis where cached and Postgres otherwise. Leagues without a row
+// are absent from the result.
+func (a *App) getLeagueBlobs(ctx context.Context, kind string, leagueKeys []string) (map[string]json.RawMessage, error) {
+	table, ok := leagueBlobTables[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown league blob kind %q", kind)
+	}
+	blobs := make(map[string]json.RawMessage, len(leagueKeys))
+	if len(leagueKeys) == 0 {
+		return blobs, nil
+	}
+
+	keys := make([]string, len(leagueKeys))
+	for i, lk := range leagueKeys {
+		keys[i] = leagueBlobKey(kind, lk)
+	}
+	// Pipelined GETs rather than MGET: the keys hash to different slots,
+	// which a cluster refuses in one MGET.
+	misses := leagueKeys
+	reads := a.rdb.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	for i, k := range keys {
+		gets[i] = reads.Get(ctx, k)
+	}
+	if _, err := reads.Exec(ctx); err == nil || err == redis.Nil {
+		misses = nil
+		for i, get := range gets {
+			s, err := get.Result()
+			if err != nil {
+				misses = append(misses, leagueKeys[i])
+				continue
+			}
+			data, err := decompressBlob([]byte(s))
+			if err != nil {
+				log.Printf("[LeagueBlob] Corrupt %s blob for %s: %v", kind, leagueKeys[i], err)
+				misses = append(misses, leagueKeys[i])
+				continue
+			}
+			blobs[leagueKeys[i]] = data
+		}
+	} else {
+		log.Printf("[LeagueBlob] Redis read failed, falling back to Postgres: %v", err)
+	}
+	cacheLookups.WithLabelValues("league_blob", "hit").Add(float64(len(leagueKeys) - len(misses)))
+	cacheLookups.WithLabelValues("league_blob", "miss").Add(float64(len(misses)))
+	if len(misses) == 0 {
+		return blobs, nil
+	}
+
+	rows, err := a.db.Query(ctx,
+		fmt.Sprintf("SELECT league_key, data FROM %s WHERE league_key = ANY($1)", table), misses)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	pipe := a.rdb.Pipeline()
+	for rows.Next() {
+		var lk string
+		var data json.RawMessage
+		if err := rows.Scan(&lk, &data); err != nil {
+			log.Printf("[LeagueBlob] Scan error: %v", err)
+			continue
+		}
+		blobs[lk] = data
+		pipe.Set(ctx, leagueBlobKey(kind, lk), compressBlob(data), LeagueBlobCacheTTL)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("[LeagueBlob] Cache fill failed: %v", err)
+	}
+	return blobs, nil
+}
+
+// invalidateLeagueBlob drops one cached blob.
+func (a *App) invalidateLeagueBlob(ctx context.Context, kind, leagueKey string) {
+	if err := a.rdb.Del(ctx, leagueBlobKey(kind, leagueKey)).Err(); err != nil {
+		log.Printf("[LeagueBlob] Invalidate %s/%s failed: %v", kind, leagueKey, err)
+	}
+}
+
+// startLeagueBlobInvalidator listens to core's fantasy CDC topics and
+// drops the blob for each changed league row until ctx is cancelled.
+func (a *App) startLeagueBlobInvalidator(ctx context.Context) {
+	pubsub := a.rdb.PSubscribe(ctx, FantasyCDCTopicPrefix+"*")
+	defer pubsub.Close()
+	log.Printf("[LeagueBlob] Listening for CDC on %s*", FantasyCDCTopicPrefix)
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			leagueKey := strings.TrimPrefix(msg.Channel, FantasyCDCTopicPrefix)
+			for _, kind := range blobKindsInEnvelope([]byte(msg.Payload)) {
+				a.invalidateLeagueBlob(ctx, kind, leagueKey)
+			}
+		}
+	}
+}
+
+// blobKindsInEnvelope returns the blob kinds touched by a core CDC
+// envelope ({"data":[{"metadata":{"table_name":...}}]}).
+func blobKindsInEnvelope(payload []byte) []string {
+	var envelope struct {
+		Data []struct {
+			Metadata struct {
+				TableName string `json:"table_name"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil
+	}
+	var kinds []string
+	for _, rec := range envelope.Data {
+		if kind := blobKindForTable(rec.Metadata.TableName); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompressBlobRoundTrip(t *testing.T) {
+	data := []byte(`{"name":"League","settings":` + strings.Repeat(`{"stat":"HR"},`, 200) + `null}`)
+	compressed := compressBlob(data)
+	if len(compressed) >= len(data) {
+		t.Errorf("compressed %d bytes to %d; expected a reduction", len(data), len(compressed))
+	}
+	got, err := decompressBlob(compressed)
+	if err != nil {
+		t.Fatalf("decompressBlob: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("round trip changed the blob")
+	}
+}
+
+func TestDecompressBlobRejectsGarbage(t *testing.T) {
+	if _, err := decompressBlob([]byte(`{"not":"zstd"}`)); err == nil {
+		t.Error("expected error for uncompressed input")
+	}
+}
+
+func TestBlobKindsInEnvelope(t *testing.T) {
+	payload := []byte(`{"data":[
+		{"action":"update","metadata":{"table_name":"yahoo_leagues"}},
+		{"action":"update","metadata":{"table_name":"yahoo_rosters"}},
+		{"action":"insert","metadata":{"table_name":"yahoo_standings"}}
+	],"server_ts":1}`)
+	got := blobKindsInEnvelope(payload)
+	want := []string{leagueBlobLeague, leagueBlobStandings}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blobKindsInEnvelope = %v, want %v", got, want)
+	}
+	if got := blobKindsInEnvelope([]byte(`not json`)); got != nil {
+		t.Errorf("invalid payload = %v, want nil", got)
+	}
+}
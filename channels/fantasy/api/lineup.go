@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Lineup Changes — first Yahoo write feature
+// =============================================================================
+//
+// PUT /fantasy/team/:team_key/lineup moves players between lineup slots so
+// users can bench an injured starter straight from an alert. It requires the
+// fspt-w scope (re-consent via /yahoo/start?scope=write), is validated
+// against the synced roster and the league's roster_positions before it
+// reaches Yahoo, and every attempt lands in fantasy_lineup_changes.
+
+const (
+	// LineupMaxChanges bounds one request; no Yahoo roster is this large.
+	LineupMaxChanges = 40
+
+	// LineupHistoryLimit caps GET .../lineup/history.
+	LineupHistoryLimit = 50
+
+	// LineupWriteTimeout covers the settings fetch plus the PUT.
+	LineupWriteTimeout = 30 * time.Second
+
+	// BenchPosition is always a legal target for any rostered player.
+	BenchPosition = "BN"
+)
+
+// LineupChange moves one player into one slot.
+type LineupChange struct {
+	PlayerKey string `json:"player_key"`
+	Position  string `json:"position"`
+}
+
+// SetLineupRequest is the body of PUT /fantasy/team/:team_key/lineup.
+// Exactly one of Week (weekly leagues, i.e. NFL) or Date (daily leagues,
+// YYYY-MM-DD) must be set.
+type SetLineupRequest struct {
+	Week    int            `json:"week"`
+	Date    string         `json:"date"`
+	Players []LineupChange `json:"players"`
+}
+
+// LineupChangeRecord is one audit row.
+type LineupChangeRecord struct {
+	ID           int64          `json:"id"`
+	TeamKey      string         `json:"team_key"`
+	CoverageType *string        `json:"coverage_type"`
+	Coverage     *string        `json:"coverage"`
+	Changes      []LineupChange `json:"changes"`
+	Status       string         `json:"status"`
+	Error        *string        `json:"error,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// rosterSlotPlayer is the subset of a serialized roster player (see
+// serializeRoster) that lineup validation needs.
+type rosterSlotPlayer struct {
+	PlayerKey         string   `json:"player_key"`
+	SelectedPosition  string   `json:"selected_position"`
+	EligiblePositions []string `json:"eligible_positions"`
+}
+
+// lineupCoverage validates the week/date selector and returns Yahoo's
+// coverage_type and value.
+func lineupCoverage(req SetLineupRequest) (string, string, error) {
+	switch {
+	case req.Week > 0 && req.Date != "":
+		return "", "", fmt.Errorf("set either week or date, not both")
+	case req.Week > 0:
+		return "week", strconv.Itoa(req.Week), nil
+	case req.Date != "":
+		if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+			return "", "", fmt.Errorf("date must be YYYY-MM-DD")
+		}
+		return "date", req.Date, nil
+	default:
+		return "", "", fmt.Errorf("week or date is required")
+	}
+}
+
+// validateLineup checks the requested moves against the team's roster and
+// the league's slot counts. It validates the resulting lineup — current
+// positions with the requested moves applied — so swapping two starters
+// doesn't trip the slot count halfway through. slots may be empty when
+// Yahoo didn't return roster_positions; capacity is then left to Yahoo.
+func validateLineup(changes []LineupChange, roster []rosterSlotPlayer, slots map[string]int) error {
+	if len(changes) == 0 {
+		return fmt.Errorf("players is required")
+	}
+	if len(changes) > LineupMaxChanges {
+		return fmt.Errorf("at most %d players per change", LineupMaxChanges)
+	}
+
+	byKey := make(map[string]rosterSlotPlayer, len(roster))
+	for _, p := range roster {
+		byKey[p.PlayerKey] = p
+	}
+
+	result := make(map[string]string, len(roster))
+	for _, p := range roster {
+		result[p.PlayerKey] = p.SelectedPosition
+	}
+
+	seen := make(map[string]bool, len(changes))
+	for _, ch := range changes {
+		if ch.PlayerKey == "" || ch.Position == "" {
+			return fmt.Errorf("each player needs player_key and position")
+		}
+		if seen[ch.PlayerKey] {
+			return fmt.Errorf("player %s listed more than once", ch.PlayerKey)
+		}
+		seen[ch.PlayerKey] = true
+
+		p, ok := byKey[ch.PlayerKey]
+		if !ok {
+			return fmt.Errorf("player %s is not on this roster", ch.PlayerKey)
+		}
+		if ch.Position != BenchPosition && !containsString(p.EligiblePositions, ch.Position) {
+			return fmt.Errorf("player %s is not eligible at %s", ch.PlayerKey, ch.Position)
+		}
+		if len(slots) > 0 {
+			if _, ok := slots[ch.Position]; !ok {
+				return fmt.Errorf("league has no %s slot", ch.Position)
+			}
+		}
+		result[ch.PlayerKey] = ch.Position
+	}
+
+	if len(slots) == 0 {
+		return nil
+	}
+	filled := map[string]int{}
+	for _, pos := range result {
+		filled[pos]++
+	}
+	for pos, n := range filled {
+		if limit, ok := slots[pos]; ok && n > limit {
+			return fmt.Errorf("%d players at %s but the league allows %d", n, pos, limit)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// recordLineupChange writes the audit row. Failures are logged, never
+// surfaced — the lineup outcome is what the caller cares about.
+func (a *App) recordLineupChange(ctx context.Context, guid, teamKey, coverageType, coverage string, changes []LineupChange, status string, cause error) {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		changesJSON = []byte("[]")
+	}
+	var errMsg *string
+	if cause != nil {
+		msg := truncate(cause.Error(), 500)
+		errMsg = &msg
+	}
+	// Coverage is NULL when the request was rejected before the week/date
+	// selector parsed.
+	var covType, cov *string
+	if coverageType != "" {
+		covType, cov = &coverageType, &coverage
+	}
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO fantasy_lineup_changes (guid, team_key, coverage_type, coverage, changes, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, guid, teamKey, covType, cov, changesJSON, status, errMsg); err != nil {
+		log.Printf("[Lineup] Failed to record %s change for team %s: %v", status, teamKey, err)
+	}
+}
+
+// SetTeamLineup applies lineup changes for one of the caller's teams.
+func (a *App) SetTeamLineup(c *fiber.Ctx) error {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	teamKey := c.Params("team_key")
+
+	var guid, scope, encryptedToken, leagueKey string
+	err := a.db.QueryRow(context.Background(), `
+		SELECT u.guid, u.scope, u.refresh_token, ul.league_key
+		FROM yahoo_users u
+		JOIN yahoo_user_leagues ul ON ul.guid = u.guid
+		WHERE u.logto_sub = $1 AND ul.team_key = $2
+	`, userID, teamKey).Scan(&guid, &scope, &encryptedToken, &leagueKey)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Team not found in your leagues",
+		})
+	}
+
+	if scope != YahooScopeWrite {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":      "forbidden",
+			"error":       "Yahoo write access required",
+			"reauthorize": "/yahoo/start?scope=write",
+		})
+	}
+
+	var req SetLineupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), LineupWriteTimeout)
+	defer cancel()
+
+	coverageType, coverage, err := lineupCoverage(req)
+	if err != nil {
+		a.recordLineupChange(ctx, guid, teamKey, "", "", req.Players, "rejected", err)
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Status: "error", Error: err.Error()})
+	}
+
+	var rosterJSON []byte
+	if err := a.db.QueryRow(ctx,
+		`SELECT data FROM yahoo_rosters WHERE team_key = $1`, teamKey,
+	).Scan(&rosterJSON); err != nil {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Roster not synced yet; try again shortly",
+		})
+	}
+	var roster struct {
+		Players []rosterSlotPlayer `json:"players"`
+	}
+	if err := json.Unmarshal(rosterJSON, &roster); err != nil {
+		log.Printf("[Lineup] Failed to parse roster for %s: %v", teamKey, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to read roster",
+		})
+	}
+
+	refreshToken, err := Decrypt(encryptedToken)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to decrypt token",
+		})
+	}
+	client := NewYahooClient(os.Getenv("YAHOO_CLIENT_ID"), os.Getenv("YAHOO_CLIENT_SECRET"), refreshToken)
+	defer func() {
+		if newToken := client.RefreshedToken(); newToken != "" && newToken != refreshToken {
+			if encrypted, err := Encrypt(newToken); err == nil {
+				a.updateRefreshToken(context.Background(), guid, encrypted)
+			}
+		}
+	}()
+
+	slots, err := client.GetRosterPositions(ctx, leagueKey)
+	if err != nil {
+		log.Printf("[Lineup] Failed to fetch roster positions for %s: %v", leagueKey, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to fetch league roster rules from Yahoo",
+		})
+	}
+
+	if err := validateLineup(req.Players, roster.Players, slots); err != nil {
+		a.recordLineupChange(ctx, guid, teamKey, coverageType, coverage, req.Players, "rejected", err)
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(ErrorResponse{Status: "error", Error: err.Error()})
+	}
+
+	if err := client.SetLineup(ctx, teamKey, coverageType, coverage, req.Players); err != nil {
+		log.Printf("[Lineup] Yahoo rejected lineup for team %s: %v", teamKey, err)
+		a.recordLineupChange(ctx, guid, teamKey, coverageType, coverage, req.Players, "failed", err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Yahoo rejected the lineup change",
+		})
+	}
+
+	a.recordLineupChange(ctx, guid, teamKey, coverageType, coverage, req.Players, "applied", nil)
+	a.invalidateLeagueCache(context.Background(), guid)
+	log.Printf("[Lineup] Applied %d moves for team %s (%s %s)", len(req.Players), teamKey, coverageType, coverage)
+
+	return c.JSON(fiber.Map{"status": "ok", "applied": len(req.Players)})
+}
+
+// GetLineupHistory returns the caller's recent lineup changes for a team.
+func (a *App) GetLineupHistory(c *fiber.Ctx) error {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := a.db.Query(context.Background(), `
+		SELECT lc.id, lc.team_key, lc.coverage_type, lc.coverage, lc.changes, lc.status, lc.error, lc.created_at
+		FROM fantasy_lineup_changes lc
+		JOIN yahoo_users u ON u.guid = lc.guid
+		WHERE u.logto_sub = $1 AND lc.team_key = $2
+		ORDER BY lc.created_at DESC
+		LIMIT $3
+	`, userID, c.Params("team_key"), LineupHistoryLimit)
+	if err != nil {
+		log.Printf("[Lineup] History query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load lineup history",
+		})
+	}
+	defer rows.Close()
+
+	history := make([]LineupChangeRecord, 0)
+	for rows.Next() {
+		var r LineupChangeRecord
+		var changesJSON []byte
+		if err := rows.Scan(&r.ID, &r.TeamKey, &r.CoverageType, &r.Coverage, &changesJSON, &r.Status, &r.Error, &r.CreatedAt); err != nil {
+			log.Printf("[Lineup] History scan error: %v", err)
+			continue
+		}
+		if err := json.Unmarshal(changesJSON, &r.Changes); err != nil {
+			r.Changes = []LineupChange{}
+		}
+		history = append(history, r)
+	}
+	return c.JSON(fiber.Map{"history": history})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testRoster() []rosterSlotPlayer {
+	return []rosterSlotPlayer{
+		{PlayerKey: "p.qb1", SelectedPosition: "QB", EligiblePositions: []string{"QB"}},
+		{PlayerKey: "p.qb2", SelectedPosition: "BN", EligiblePositions: []string{"QB"}},
+		{PlayerKey: "p.wr1", SelectedPosition: "WR", EligiblePositions: []string{"WR", "W/R/T"}},
+		{PlayerKey: "p.wr2", SelectedPosition: "BN", EligiblePositions: []string{"WR", "W/R/T", "IR"}},
+	}
+}
+
+var testSlots = map[string]int{"QB": 1, "WR": 1, "W/R/T": 1, "BN": 2, "IR": 1}
+
+func TestValidateLineup(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []LineupChange
+		wantErr string
+	}{
+		{"swap starters", []LineupChange{{"p.qb1", "BN"}, {"p.qb2", "QB"}}, ""},
+		{"flex start", []LineupChange{{"p.wr2", "W/R/T"}}, ""},
+		{"injured to IR", []LineupChange{{"p.wr2", "IR"}}, ""},
+		{"empty", nil, "required"},
+		{"not on roster", []LineupChange{{"p.other", "BN"}}, "not on this roster"},
+		{"ineligible", []LineupChange{{"p.qb2", "WR"}}, "not eligible"},
+		{"duplicate", []LineupChange{{"p.qb1", "BN"}, {"p.qb1", "QB"}}, "more than once"},
+		{"over slot count", []LineupChange{{"p.qb2", "QB"}}, "allows 1"},
+		{"bench overflow", []LineupChange{{"p.qb1", "BN"}, {"p.wr1", "BN"}}, "allows 2"},
+	}
+	for _, tt := range tests {
+		err := validateLineup(tt.changes, testRoster(), testSlots)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLineupCoverage(t *testing.T) {
+	if typ, v, err := lineupCoverage(SetLineupRequest{Week: 7}); err != nil || typ != "week" || v != "7" {
+		t.Errorf("week: got %q %q %v", typ, v, err)
+	}
+	if typ, v, err := lineupCoverage(SetLineupRequest{Date: "2026-10-15"}); err != nil || typ != "date" || v != "2026-10-15" {
+		t.Errorf("date: got %q %q %v", typ, v, err)
+	}
+	for _, req := range []SetLineupRequest{{}, {Week: 3, Date: "2026-10-15"}, {Date: "10/15/2026"}} {
+		if _, _, err := lineupCoverage(req); err == nil {
+			t.Errorf("%+v: expected error", req)
+		}
+	}
+}
+
+func TestBuildLineupXML(t *testing.T) {
+	out, err := buildLineupXML("date", "2026-10-15", []LineupChange{{"p.qb1", "BN"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, want := range []string{
+		"<fantasy_content><roster>",
+		"<coverage_type>date</coverage_type>",
+		"<date>2026-10-15</date>",
+		"<players><player><player_key>p.qb1</player_key><position>BN</position></player></players>",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q in %s", want, s)
+		}
+	}
+	if strings.Contains(s, "<week>") {
+		t.Errorf("date coverage should not include <week>: %s", s)
+	}
+}
@@ -52,6 +52,11 @@ type registrationPayload struct {
 	Capabilities []string            `json:"capabilities"`
 	CDCTables    []string            `json:"cdc_tables"`
 	Routes       []registrationRoute `json:"routes"`
+	// DashboardTimeoutMs asks the gateway for a longer /internal/dashboard
+	// budget than its default.
+	DashboardTimeoutMs int    `json:"dashboard_timeout_ms,omitempty"`
+	StartedAt          int64  `json:"started_at"` // Unix ms; lets the gateway detect restarts
+	Version            string `json:"version"`    // build (GIT_SHA); lets the gateway spot rollouts
 }
 
 type registrationRoute struct {
@@ -64,16 +69,44 @@ type registrationRoute struct {
 // Main
 // =============================================================================
 
+// normalizeDatabaseURL strips quotes and whitespace from DATABASE_URL and
+// repairs a missing "//" after the scheme.
+func normalizeDatabaseURL(dbURL string) string {
+	dbURL = strings.TrimSpace(dbURL)
+	dbURL = strings.Trim(dbURL, "\"")
+	dbURL = strings.Trim(dbURL, "'")
+	if strings.HasPrefix(dbURL, "postgres:") && !strings.HasPrefix(dbURL, "postgres://") {
+		dbURL = strings.Replace(dbURL, "postgres:", "postgres://", 1)
+	} else if strings.HasPrefix(dbURL, "postgresql:") && !strings.HasPrefix(dbURL, "postgresql://") {
+		dbURL = strings.Replace(dbURL, "postgresql:", "postgresql://", 1)
+	}
+	return dbURL
+}
+
 func main() {
 	// Load .env (optional — don't fatal if missing)
 	_ = godotenv.Load()
 
+	// One-shot maintenance commands run instead of the server.
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-tokens" {
+		os.Exit(runEncryptTokens(os.Args[2:]))
+	}
+
 	// Sentry init — before any other infrastructure. No-op when
 	// SENTRY_DSN is unset.
 	if initSentry() {
 		defer sentry.Flush(2 * time.Second)
 	}
 
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set (tracing.go).
+	shutdownTracing := initTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+
 	// -------------------------------------------------------------------------
 	// Connect to PostgreSQL
 	// -------------------------------------------------------------------------
@@ -82,23 +115,13 @@ func main() {
 		log.Fatal("[Fantasy] DATABASE_URL is required")
 	}
 
-	// Clean up DATABASE_URL
-	dbURL = strings.TrimSpace(dbURL)
-	dbURL = strings.Trim(dbURL, "\"")
-	dbURL = strings.Trim(dbURL, "'")
-	if strings.HasPrefix(dbURL, "postgres:") && !strings.HasPrefix(dbURL, "postgres://") {
-		dbURL = strings.Replace(dbURL, "postgres:", "postgres://", 1)
-	} else if strings.HasPrefix(dbURL, "postgresql:") && !strings.HasPrefix(dbURL, "postgresql://") {
-		dbURL = strings.Replace(dbURL, "postgresql:", "postgresql://", 1)
-	}
+	dbURL = normalizeDatabaseURL(dbURL)
 
-	poolConfig, err := pgxpool.ParseConfig(dbURL)
+	// Bounded pool sized by DB_* env (db.go).
+	poolConfig, err := dbPoolConfig(dbURL)
 	if err != nil {
 		log.Fatalf("[Fantasy] Failed to parse DATABASE_URL: %v", err)
 	}
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
-	poolConfig.MaxConnLifetime = 30 * time.Minute
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -115,23 +138,16 @@ func main() {
 	// -------------------------------------------------------------------------
 	// Connect to Redis
 	// -------------------------------------------------------------------------
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("[Fantasy] REDIS_URL is required")
-	}
-
-	opts, err := redis.ParseURL(redisURL)
+	rdb, redisMode, err := newRedisClient(os.Getenv)
 	if err != nil {
-		log.Fatalf("[Fantasy] Invalid REDIS_URL: %v", err)
+		log.Fatalf("[Fantasy] Unable to configure Redis: %v", err)
 	}
-
-	rdb := redis.NewClient(opts)
 	defer rdb.Close()
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		log.Fatalf("[Fantasy] Redis ping failed: %v", err)
 	}
-	log.Println("[Fantasy] Connected to Redis")
+	log.Printf("[Fantasy] Connected to Redis (%s)", redisMode)
 
 	// -------------------------------------------------------------------------
 	// Yahoo OAuth2 Config
@@ -235,12 +251,23 @@ func main() {
 		syncState:   &syncHealth{status: "starting"},
 	}
 
+	// Drop cached league blobs as CDC for their rows arrives
+	go app.startLeagueBlobInvalidator(ctx)
+
+	// Record Yahoo links still keyed by their GUID (yahoo_relink.go)
+	go app.startLegacyYahooDetection(ctx)
+
+	// Token reconciler — flags links whose refresh token Yahoo rejected.
+	go app.startTokenReconciler(ctx)
+
 	// -------------------------------------------------------------------------
 	// Start background Yahoo sync loop (feature-flagged via SYNC_ENABLED)
 	// -------------------------------------------------------------------------
 	syncEnabled := os.Getenv("SYNC_ENABLED")
 	if syncEnabled == "" || syncEnabled == "true" || syncEnabled == "1" {
 		go app.startSyncWithRestart(ctx)
+		go app.startSleeperSync(ctx)
+		go app.startLiveMatchupPoller(ctx)
 		log.Println("[Fantasy] Background sync loop started")
 	} else {
 		log.Println("[Fantasy] Background sync loop DISABLED (SYNC_ENABLED != true)")
@@ -258,6 +285,12 @@ func main() {
 		fiberApp.Use(sentryUserHook())
 	}
 
+	// Server span per request, continuing the gateway's trace (tracing.go)
+	fiberApp.Use(tracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go)
+	fiberApp.Use(metricsMiddleware)
+
 	// Yahoo OAuth routes.
 	//   /yahoo/start    — Auth REQUIRED. Core gateway verifies the Scrollr
 	//                     session and sets X-User-Sub before proxying.
@@ -280,11 +313,34 @@ func main() {
 	fiberApp.Post("/users/me/yahoo-leagues/import", app.ImportYahooLeague)
 	fiberApp.Delete("/users/me/yahoo", app.DisconnectYahoo)
 
+	// Sleeper (public read-only API, linked by username)
+	fiberApp.Get("/users/me/sleeper/link", app.GetSleeperStatus)
+	fiberApp.Post("/users/me/sleeper/link", app.LinkSleeper)
+	fiberApp.Delete("/users/me/sleeper/link", app.UnlinkSleeper)
+
+	// Admin routes (proxied by core gateway, super_user only — see yahoo_relink.go)
+	fiberApp.Get("/admin/fantasy/yahoo-relink", app.adminListYahooRelinks)
+	fiberApp.Put("/admin/fantasy/yahoo-relink", app.adminSetYahooRelinkCandidate)
+
+	// League record book (archived matchup history)
+	fiberApp.Get("/users/me/yahoo-leagues/:league_key/records/franchises", app.GetLeagueFranchiseRecords)
+	fiberApp.Get("/users/me/yahoo-leagues/:league_key/records/streaks", app.GetLeagueWinStreaks)
+	fiberApp.Get("/users/me/yahoo-leagues/:league_key/records/high-scores", app.GetLeagueHighScores)
+
+	// Lineup changes (Yahoo write scope, fspt-w)
+	fiberApp.Put("/fantasy/team/:team_key/lineup", app.SetTeamLineup)
+	fiberApp.Get("/fantasy/team/:team_key/lineup/history", app.GetLineupHistory)
+
 	// Internal routes (called by core gateway directly, not proxied)
+	fiberApp.Use("/internal", requireInternalSignature) // HMAC from core (internal_auth.go)
 	fiberApp.Post("/internal/cdc", app.handleInternalCDC)
 	fiberApp.Get("/internal/dashboard", app.handleInternalDashboard)
 	fiberApp.Get("/internal/health", app.handleInternalHealth)
 
+	// Prometheus scrape endpoint, cluster-internal only (metrics.go)
+	registerPoolMetrics(app.db, app.rdb)
+	fiberApp.Get("/metrics", requireInternalNetwork, handleMetrics)
+
 	// -------------------------------------------------------------------------
 	// Start server with graceful shutdown
 	// -------------------------------------------------------------------------
@@ -321,7 +377,7 @@ func main() {
 // startRegistration registers this service in Redis with a TTL and refreshes
 // the registration on a ticker. This allows the core gateway to discover
 // available channel services.
-func startRegistration(ctx context.Context, rdb *redis.Client) {
+func startRegistration(ctx context.Context, rdb redis.UniversalClient) {
 	channelURL := os.Getenv("CHANNEL_URL")
 	if channelURL == "" {
 		channelURL = DefaultChannelURL
@@ -333,6 +389,9 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 		InternalURL:  channelURL,
 		Capabilities: []string{"cdc_handler", "dashboard_provider", "health_checker"},
 		CDCTables:    []string{"yahoo_leagues", "yahoo_standings", "yahoo_matchups", "yahoo_rosters"},
+		// Sleeper leagues are fetched live on a cache miss, which can
+		// outlast the gateway's default dashboard budget.
+		DashboardTimeoutMs: 4000,
 		Routes: []registrationRoute{
 			// Auth required: initiating Yahoo OAuth binds the Yahoo
 			// identity to the authenticated Scrollr user. Must be a
@@ -349,7 +408,21 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 			{Method: "POST", Path: "/users/me/yahoo-leagues/discover", Auth: true},
 			{Method: "POST", Path: "/users/me/yahoo-leagues/import", Auth: true},
 			{Method: "DELETE", Path: "/users/me/yahoo", Auth: true},
+			{Method: "GET", Path: "/users/me/sleeper/link", Auth: true},
+			{Method: "POST", Path: "/users/me/sleeper/link", Auth: true},
+			{Method: "DELETE", Path: "/users/me/sleeper/link", Auth: true},
+			{Method: "GET", Path: "/users/me/yahoo-leagues/:league_key/records/franchises", Auth: true},
+			{Method: "GET", Path: "/users/me/yahoo-leagues/:league_key/records/streaks", Auth: true},
+			{Method: "GET", Path: "/users/me/yahoo-leagues/:league_key/records/high-scores", Auth: true},
+			{Method: "PUT", Path: "/fantasy/team/:team_key/lineup", Auth: true},
+			{Method: "GET", Path: "/fantasy/team/:team_key/lineup/history", Auth: true},
+			// Legacy Yahoo link reconciliation. Auth: true so the gateway
+			// forwards X-User-Tier; handlers require super_user.
+			{Method: "GET", Path: "/admin/fantasy/yahoo-relink", Auth: true},
+			{Method: "PUT", Path: "/admin/fantasy/yahoo-relink", Auth: true},
 		},
+		StartedAt: time.Now().UnixMilli(),
+		Version:   envOr("GIT_SHA", "unknown"),
 	}
 
 	data, err := json.Marshal(payload)
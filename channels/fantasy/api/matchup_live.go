@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+// =============================================================================
+// Live Matchup Scoring
+// =============================================================================
+
+// The regular sync walks every user's leagues every couple of minutes and
+// spends most of that on rosters and stat catalogs, so a touchdown can take
+// several minutes to reach the ticker. During NFL/NBA/NHL game windows the
+// live poller re-fetches just the current week's scoreboard for each active
+// league on a tighter interval.
+//
+// A changed scoreboard is written to yahoo_matchups (whose CDC already
+// fans out to the league topic) and, for each team whose score moved, a
+// fantasy_matchup_scores record is published straight to the owning
+// user's core topic so their own matchup updates without a refetch.
+// Unchanged scoreboards are not rewritten, so quiet windows cost no CDC.
+
+const (
+	// LiveMatchupTick is how often the poller wakes to check windows.
+	LiveMatchupTick = 15 * time.Second
+
+	// LiveMatchupInterval is the per-league poll interval inside a game
+	// window — the "tightened" rate versus defaultSyncInterval.
+	LiveMatchupInterval = 45 * time.Second
+)
+
+// gameWindow is a recurring span, in US Eastern time, when a sport's games
+// are usually in progress. A window may run past midnight.
+type gameWindow struct {
+	days     []time.Weekday
+	startMin int // minutes after midnight
+	duration time.Duration
+}
+
+var (
+	weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekends = []time.Weekday{time.Saturday, time.Sunday}
+)
+
+// liveGameWindows are deliberately generous: polling through a quiet
+// stretch costs a few unchanged scoreboards, missing a game costs
+// minutes of stale scores.
+var liveGameWindows = map[string][]gameWindow{
+	"nfl": {
+		{days: []time.Weekday{time.Sunday}, startMin: 9*60 + 15, duration: 15 * time.Hour}, // London kickoff → SNF
+		{days: []time.Weekday{time.Monday, time.Thursday}, startMin: 19*60 + 45, duration: 5 * time.Hour},
+		{days: []time.Weekday{time.Saturday}, startMin: 12 * 60, duration: 12 * time.Hour}, // late-season Saturdays
+	},
+	"nba": {
+		{days: weekdays, startMin: 18*60 + 45, duration: 6*time.Hour + 30*time.Minute},
+		{days: weekends, startMin: 12 * 60, duration: 13 * time.Hour},
+	},
+	"nhl": {
+		{days: weekdays, startMin: 18*60 + 45, duration: 6*time.Hour + 15*time.Minute},
+		{days: weekends, startMin: 12 * 60, duration: 13 * time.Hour},
+	},
+}
+
+// contains reports whether t (any zone) falls inside the window.
+func (w gameWindow) contains(t time.Time) bool {
+	et := t.In(easternLocation)
+	midnight := time.Date(et.Year(), et.Month(), et.Day(), 0, 0, 0, 0, easternLocation)
+	// Check today's occurrence and yesterday's, which may spill past midnight.
+	for _, dayStart := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		start := dayStart.Add(time.Duration(w.startMin) * time.Minute)
+		if et.Before(start) || !et.Before(start.Add(w.duration)) {
+			continue
+		}
+		for _, d := range w.days {
+			if dayStart.Weekday() == d {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// liveGameCodes returns the Yahoo game codes with a window open at t.
+func liveGameCodes(t time.Time) []string {
+	var codes []string
+	for _, code := range SupportedGameCodes {
+		for _, w := range liveGameWindows[code] {
+			if w.contains(t) {
+				codes = append(codes, code)
+				break
+			}
+		}
+	}
+	return codes
+}
+
+// liveLeague is one league due for a live poll, with a linked member
+// whose token the poll borrows.
+type liveLeague struct {
+	leagueKey   string
+	currentWeek int
+	guid        string
+	stored      string // encrypted refresh token
+}
+
+// matchupPoller holds per-process poll state. Only its own goroutine
+// touches it.
+type matchupPoller struct {
+	app          *App
+	clientID     string
+	clientSecret string
+	lastPolled   map[string]time.Time
+	clients      map[string]*YahooClient // by guid; reused so access tokens are too
+}
+
+// startLiveMatchupPoller runs until ctx is cancelled. Started alongside
+// the regular sync (SYNC_ENABLED).
+func (a *App) startLiveMatchupPoller(ctx context.Context) {
+	p := &matchupPoller{
+		app:          a,
+		clientID:     os.Getenv("YAHOO_CLIENT_ID"),
+		clientSecret: os.Getenv("YAHOO_CLIENT_SECRET"),
+		lastPolled:   make(map[string]time.Time),
+		clients:      make(map[string]*YahooClient),
+	}
+	log.Printf("[LiveMatchups] Poller started (interval=%v in game windows)", LiveMatchupInterval)
+
+	ticker := time.NewTicker(LiveMatchupTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			codes := liveGameCodes(now)
+			if len(codes) == 0 {
+				// Between windows: drop clients so stale access tokens and
+				// poll times don't carry into the next one.
+				clear(p.clients)
+				clear(p.lastPolled)
+				continue
+			}
+			p.pollOnce(ctx, codes, now)
+		}
+	}
+}
+
+func (p *matchupPoller) pollOnce(ctx context.Context, codes []string, now time.Time) {
+	leagues, err := p.app.liveLeagues(ctx, codes)
+	if err != nil {
+		log.Printf("[LiveMatchups] Failed to list leagues: %v", err)
+		return
+	}
+	for _, l := range leagues {
+		if ctx.Err() != nil {
+			return
+		}
+		if now.Sub(p.lastPolled[l.leagueKey]) < LiveMatchupInterval {
+			continue
+		}
+		p.lastPolled[l.leagueKey] = now
+		p.pollLeague(ctx, l)
+	}
+}
+
+// liveLeagues lists unfinished leagues for the given sports that have a
+// current week and at least one member with a working token.
+func (a *App) liveLeagues(ctx context.Context, codes []string) ([]liveLeague, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT l.league_key, (l.data->>'current_week')::int, u.guid, u.refresh_token
+		FROM yahoo_leagues l
+		JOIN LATERAL (
+			SELECT yu.guid, yu.refresh_token
+			FROM yahoo_user_leagues ul
+			JOIN yahoo_users yu ON yu.guid = ul.guid
+			WHERE ul.league_key = l.league_key AND yu.token_status = 'active'
+			ORDER BY yu.last_sync DESC NULLS LAST
+			LIMIT 1
+		) u ON true
+		WHERE l.game_code = ANY($1)
+		  AND COALESCE((l.data->>'is_finished')::boolean, false) = false
+		  AND COALESCE((l.data->>'current_week')::int, 0) > 0
+	`, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []liveLeague
+	for rows.Next() {
+		var l liveLeague
+		if err := rows.Scan(&l.leagueKey, &l.currentWeek, &l.guid, &l.stored); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// client returns the cached Yahoo client for a member, building one from
+// their stored token on first use.
+func (p *matchupPoller) client(l liveLeague) (*YahooClient, error) {
+	if c, ok := p.clients[l.guid]; ok {
+		return c, nil
+	}
+	refreshToken, err := Decrypt(l.stored)
+	if err != nil {
+		return nil, err
+	}
+	c := NewYahooClient(p.clientID, p.clientSecret, refreshToken)
+	p.clients[l.guid] = c
+	return c, nil
+}
+
+func (p *matchupPoller) pollLeague(ctx context.Context, l liveLeague) {
+	client, err := p.client(l)
+	if err != nil {
+		log.Printf("[LiveMatchups] Failed to decrypt token for %s: %v", l.guid, err)
+		return
+	}
+	before := client.RefreshedToken()
+
+	wk, matchups, err := client.GetScoreboard(ctx, l.leagueKey, l.currentWeek)
+	if err != nil {
+		delete(p.clients, l.guid)
+		if status := tokenStatusForError(err); status != "" {
+			p.app.markTokenDead(ctx, l.guid, status)
+		}
+		log.Printf("[LiveMatchups] Scoreboard failed for %s week %d: %v", l.leagueKey, l.currentWeek, err)
+		return
+	}
+	p.app.persistRotatedToken(ctx, l.guid, before, client.RefreshedToken())
+
+	if wk <= 0 {
+		wk = l.currentWeek
+	}
+	if matchups == nil {
+		return
+	}
+	if err := p.app.applyLiveMatchups(ctx, l.leagueKey, wk, matchups); err != nil {
+		log.Printf("[LiveMatchups] Failed to apply %s week %d: %v", l.leagueKey, wk, err)
+	}
+}
+
+// persistRotatedToken stores a refresh token Yahoo rotated during a poll.
+func (a *App) persistRotatedToken(ctx context.Context, guid, before, after string) {
+	if after == "" || after == before {
+		return
+	}
+	encrypted, err := Encrypt(after)
+	if err != nil {
+		log.Printf("[LiveMatchups] Failed to encrypt rotated token for %s: %v", guid, err)
+		return
+	}
+	if err := a.updateRefreshToken(ctx, guid, encrypted); err != nil {
+		log.Printf("[LiveMatchups] Failed to persist rotated token for %s: %v", guid, err)
+	}
+}
+
+// applyLiveMatchups writes a scoreboard if it changed and pushes per-team
+// score deltas to the owners of the teams that moved.
+func (a *App) applyLiveMatchups(ctx context.Context, leagueKey string, week int, matchups []map[string]any) error {
+	newJSON, err := json.Marshal(matchups)
+	if err != nil {
+		return err
+	}
+
+	var oldJSON []byte
+	_ = a.db.QueryRow(ctx,
+		`SELECT data FROM yahoo_matchups WHERE league_key = $1 AND week = $2`,
+		leagueKey, week).Scan(&oldJSON)
+
+	deltas := matchupDeltas(leagueKey, oldJSON, newJSON)
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	// Same upsert as sync, but skipped when nothing changed so CDC only
+	// fires on real score movement.
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO yahoo_matchups (league_key, week, data, updated_at)
+		VALUES ($1, $2, $3::jsonb, CURRENT_TIMESTAMP)
+		ON CONFLICT (league_key, week) DO UPDATE
+		SET data = EXCLUDED.data, updated_at = CURRENT_TIMESTAMP
+		WHERE yahoo_matchups.data IS DISTINCT FROM EXCLUDED.data
+	`, leagueKey, week, string(newJSON)); err != nil {
+		return err
+	}
+
+	a.publishMatchupDeltas(ctx, leagueKey, deltas)
+	return nil
+}
+
+// MatchupScoreDelta is one team's side of a matchup whose score moved,
+// as published to the team owner's SSE topic.
+type MatchupScoreDelta struct {
+	LeagueKey               string   `json:"league_key"`
+	Week                    int      `json:"week"`
+	Status                  string   `json:"status"`
+	TeamKey                 string   `json:"team_key"`
+	Points                  *float64 `json:"points"`
+	ProjectedPoints         *float64 `json:"projected_points"`
+	OpponentTeamKey         string   `json:"opponent_team_key,omitempty"`
+	OpponentPoints          *float64 `json:"opponent_points"`
+	OpponentProjectedPoints *float64 `json:"opponent_projected_points"`
+}
+
+// matchupSnapshot is the subset of a stored scoreboard the delta needs.
+type matchupSnapshot struct {
+	Week   int    `json:"week"`
+	Status string `json:"status"`
+	Teams  []struct {
+		TeamKey         string   `json:"team_key"`
+		Points          *float64 `json:"points"`
+		ProjectedPoints *float64 `json:"projected_points"`
+	} `json:"teams"`
+}
+
+// matchupDeltas compares two serialized scoreboards and returns one delta
+// per team (from that team's perspective) in every matchup whose scores,
+// projections or status changed. With no previous scoreboard every
+// matchup counts as changed.
+func matchupDeltas(leagueKey string, oldJSON, newJSON []byte) []MatchupScoreDelta {
+	var before, after []matchupSnapshot
+	_ = json.Unmarshal(oldJSON, &before)
+	if json.Unmarshal(newJSON, &after) != nil {
+		return nil
+	}
+
+	prev := make(map[string]string) // team_key → fingerprint of its matchup
+	for _, m := range before {
+		fp := matchupFingerprint(m)
+		for _, t := range m.Teams {
+			prev[t.TeamKey] = fp
+		}
+	}
+
+	var deltas []MatchupScoreDelta
+	for _, m := range after {
+		if len(m.Teams) == 0 {
+			continue
+		}
+		fp := matchupFingerprint(m)
+		if prev[m.Teams[0].TeamKey] == fp {
+			continue
+		}
+		for i, t := range m.Teams {
+			d := MatchupScoreDelta{
+				LeagueKey:       leagueKey,
+				Week:            m.Week,
+				Status:          m.Status,
+				TeamKey:         t.TeamKey,
+				Points:          t.Points,
+				ProjectedPoints: t.ProjectedPoints,
+			}
+			if len(m.Teams) == 2 {
+				opp := m.Teams[1-i]
+				d.OpponentTeamKey = opp.TeamKey
+				d.OpponentPoints = opp.Points
+				d.OpponentProjectedPoints = opp.ProjectedPoints
+			}
+			deltas = append(deltas, d)
+		}
+	}
+	return deltas
+}
+
+func matchupFingerprint(m matchupSnapshot) string {
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// publishMatchupDeltas sends each delta to the core topic of every user
+// whose team it describes.
+func (a *App) publishMatchupDeltas(ctx context.Context, leagueKey string, deltas []MatchupScoreDelta) {
+	teamKeys := make([]string, 0, len(deltas))
+	for _, d := range deltas {
+		teamKeys = append(teamKeys, d.TeamKey)
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT ul.team_key, yu.logto_sub
+		FROM yahoo_user_leagues ul
+		JOIN yahoo_users yu ON yu.guid = ul.guid
+		WHERE ul.league_key = $1 AND ul.team_key = ANY($2)
+		  AND yu.logto_sub IS NOT NULL AND yu.logto_sub <> yu.guid
+	`, leagueKey, teamKeys)
+	if err != nil {
+		log.Printf("[LiveMatchups] Failed to resolve owners for %s: %v", leagueKey, err)
+		return
+	}
+	owners := make(map[string][]string)
+	for rows.Next() {
+		var teamKey, sub string
+		if rows.Scan(&teamKey, &sub) == nil {
+			owners[teamKey] = append(owners[teamKey], sub)
+		}
+	}
+	rows.Close()
+
+	for _, d := range deltas {
+		for _, sub := range owners[d.TeamKey] {
+			a.publishUserRecord(ctx, sub, "fantasy_matchup_scores", d)
+		}
+	}
+}
+
+// publishUserRecord sends one synthetic record on a user's core topic,
+// which the gateway's SSE hub forwards like any CDC event.
+func (a *App) publishUserRecord(ctx context.Context, logtoSub, table string, record any) {
+	envelope := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"action": "update",
+				"record": record,
+				"metadata": map[string]string{
+					"table_schema": "public",
+					"table_name":   table,
+				},
+			},
+		},
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq, err := a.rdb.Incr(ctx, RedisEventSeqKey).Result(); err == nil {
+		envelope["seq"] = seq
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[SSE] marshal %s payload failed: %v", table, err)
+		return
+	}
+	if err := a.rdb.Publish(ctx, CoreUserTopicPrefix+logtoSub, payload).Err(); err != nil {
+		log.Printf("[SSE] publish %s for %s failed: %v", table, logtoSub, err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLiveGameCodes(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, easternLocation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	cases := []struct {
+		when string
+		want []string
+	}{
+		{"2026-10-18 13:05", []string{"nfl", "nba", "nhl"}}, // Sunday afternoon
+		{"2026-10-18 08:00", nil},                           // Sunday morning
+		{"2026-10-19 21:00", []string{"nfl", "nba", "nhl"}}, // Monday night
+		{"2026-10-20 00:30", []string{"nfl", "nba", "nhl"}}, // MNF and late tips spill past midnight
+		{"2026-10-21 01:10", []string{"nba"}},               // Wednesday 01:10: NBA window still open, NHL closed
+		{"2026-10-21 12:00", nil},                           // Wednesday noon
+		{"2026-10-22 20:30", []string{"nfl", "nba", "nhl"}}, // Thursday night
+	}
+	for _, tc := range cases {
+		if got := liveGameCodes(at(tc.when)); !slices.Equal(got, tc.want) {
+			t.Errorf("liveGameCodes(%s) = %v; want %v", tc.when, got, tc.want)
+		}
+	}
+}
+
+func TestMatchupDeltas(t *testing.T) {
+	board := func(a, b float64, status string) []byte {
+		raw, _ := json.Marshal([]map[string]any{
+			{"week": 6, "status": status, "teams": []map[string]any{
+				{"team_key": "t.1", "name": "One", "points": a, "projected_points": 100.0},
+				{"team_key": "t.2", "name": "Two", "points": b, "projected_points": 95.5},
+			}},
+			{"week": 6, "status": status, "teams": []map[string]any{
+				{"team_key": "t.3", "points": 10.0},
+				{"team_key": "t.4", "points": 12.0},
+			}},
+		})
+		return raw
+	}
+
+	if d := matchupDeltas("l", board(50, 40, "midevent"), board(50, 40, "midevent")); len(d) != 0 {
+		t.Errorf("unchanged board produced %d deltas", len(d))
+	}
+
+	d := matchupDeltas("l", board(50, 40, "midevent"), board(56.5, 40, "midevent"))
+	if len(d) != 2 {
+		t.Fatalf("got %d deltas; want both sides of the changed matchup", len(d))
+	}
+	if d[0].TeamKey != "t.1" || *d[0].Points != 56.5 || d[0].OpponentTeamKey != "t.2" || *d[0].OpponentPoints != 40 {
+		t.Errorf("t.1 delta = %+v", d[0])
+	}
+	if d[1].TeamKey != "t.2" || d[1].OpponentTeamKey != "t.1" || *d[1].OpponentPoints != 56.5 || d[1].LeagueKey != "l" {
+		t.Errorf("t.2 delta = %+v", d[1])
+	}
+
+	// Status flips count as movement even with the same score.
+	if d := matchupDeltas("l", board(50, 40, "midevent"), board(50, 40, "postevent")); len(d) != 4 {
+		t.Errorf("status change produced %d deltas; want 4", len(d))
+	}
+
+	// First sight of a week: everything is new.
+	if d := matchupDeltas("l", nil, board(0, 0, "preevent")); len(d) != 4 {
+		t.Errorf("first scoreboard produced %d deltas; want 4", len(d))
+	}
+}
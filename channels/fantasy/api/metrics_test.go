@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":  true,
+		"10.244.1.7": true,
+		"::1":        true,
+		"0.0.0.0":    false,
+		"8.8.8.8":    false,
+	}
+	for addr, want := range cases {
+		if got := isInternalIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isInternalIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestRequireInternalNetworkRejectsForwarded(t *testing.T) {
+	app := fiber.New()
+	app.Get("/metrics", requireInternalNetwork, func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.5")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
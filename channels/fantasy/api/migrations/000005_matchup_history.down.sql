@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_fantasy_matchup_history_family_points;
+DROP INDEX IF EXISTS idx_fantasy_matchup_history_family;
+DROP TABLE IF EXISTS fantasy_matchup_history;
@@ -0,0 +1,33 @@
+-- Weekly matchup results archived across seasons for the league record book.
+--
+-- yahoo_matchups only keeps the latest payload per (league_key, week) and
+-- disappears with the league row; this table is append-only history written
+-- by the sync loop once a week's matchup reaches status "postevent".
+--
+-- One row per team per week. Yahoo issues a new league_key every season, so
+-- `league_family` groups a league with its predecessors via Yahoo's `renew`
+-- pointer (see resolveLeagueFamily in history.go). Franchises are identified
+-- by `team_id`, which Yahoo carries over when a league renews.
+CREATE TABLE IF NOT EXISTS fantasy_matchup_history (
+    league_key        VARCHAR(50) NOT NULL,
+    league_family     VARCHAR(50) NOT NULL,
+    season            SMALLINT NOT NULL,
+    week              SMALLINT NOT NULL,
+    team_key          VARCHAR(50) NOT NULL,
+    team_id           INTEGER NOT NULL,
+    team_name         VARCHAR(255) NOT NULL DEFAULT '',
+    manager_name      VARCHAR(255) NOT NULL DEFAULT '',
+    opponent_team_key VARCHAR(50),
+    points            DOUBLE PRECISION,
+    opponent_points   DOUBLE PRECISION,
+    result            CHAR(1) NOT NULL CHECK (result IN ('W', 'L', 'T')),
+    is_playoffs       BOOLEAN NOT NULL DEFAULT FALSE,
+    is_consolation    BOOLEAN NOT NULL DEFAULT FALSE,
+    recorded_at       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (league_key, week, team_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fantasy_matchup_history_family
+    ON fantasy_matchup_history(league_family, team_id, season, week);
+CREATE INDEX IF NOT EXISTS idx_fantasy_matchup_history_family_points
+    ON fantasy_matchup_history(league_family, points DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_fantasy_lineup_changes_team;
+DROP TABLE IF EXISTS fantasy_lineup_changes;
+ALTER TABLE yahoo_users DROP COLUMN IF EXISTS scope;
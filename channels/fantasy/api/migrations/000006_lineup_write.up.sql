@@ -0,0 +1,26 @@
+-- Opt-in Yahoo write access (fspt-w) and the lineup change audit trail.
+--
+-- `scope` records what the stored refresh token was granted. Existing rows
+-- were all linked through the read-only consent screen. Re-consenting via
+-- /yahoo/start?scope=write upgrades it; a plain re-link downgrades it again
+-- because the new token really is read-only.
+--
+-- Every lineup write attempt (applied, rejected by validation, or failed at
+-- Yahoo) is recorded. Rows cascade with yahoo_users so a disconnect or
+-- account purge removes them.
+ALTER TABLE yahoo_users ADD COLUMN IF NOT EXISTS scope VARCHAR(64) NOT NULL DEFAULT 'fspt-r';
+
+CREATE TABLE IF NOT EXISTS fantasy_lineup_changes (
+    id            BIGSERIAL PRIMARY KEY,
+    guid          VARCHAR(100) NOT NULL REFERENCES yahoo_users(guid) ON DELETE CASCADE,
+    team_key      VARCHAR(50) NOT NULL,
+    coverage_type VARCHAR(8) CHECK (coverage_type IN ('week', 'date')),
+    coverage      VARCHAR(16),
+    changes       JSONB NOT NULL,
+    status        VARCHAR(16) NOT NULL CHECK (status IN ('applied', 'rejected', 'failed')),
+    error         TEXT,
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fantasy_lineup_changes_team
+    ON fantasy_lineup_changes(team_key, created_at DESC);
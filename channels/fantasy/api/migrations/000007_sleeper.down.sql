@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sleeper_user_leagues_league_id;
+DROP TABLE IF EXISTS sleeper_user_leagues;
+DROP TABLE IF EXISTS sleeper_rosters;
+DROP TABLE IF EXISTS sleeper_leagues;
+DROP TABLE IF EXISTS sleeper_users;
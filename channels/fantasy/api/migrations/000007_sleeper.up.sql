@@ -0,0 +1,51 @@
+-- Sleeper fantasy leagues, merged with Yahoo under the dashboard's fantasy key.
+--
+-- Sleeper's API is public and read-only, so linking only records the
+-- username the user gave us and the user_id it resolved to — there is no
+-- token to store. Leagues and rosters are shared across every user in the
+-- league; sleeper_user_leagues says which roster is the user's own.
+--
+-- Matchups are small and only the current and previous week are shown, so
+-- they live on the league row instead of a table of their own.
+CREATE TABLE IF NOT EXISTS sleeper_users (
+    logto_sub     VARCHAR(255) PRIMARY KEY,
+    user_id       VARCHAR(32) NOT NULL,
+    username      VARCHAR(64) NOT NULL,
+    display_name  VARCHAR(255) NOT NULL DEFAULT '',
+    last_sync     TIMESTAMP WITH TIME ZONE,
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sleeper_leagues (
+    league_id         VARCHAR(32) PRIMARY KEY,
+    name              VARCHAR(255) NOT NULL,
+    sport             VARCHAR(10) NOT NULL,
+    season            VARCHAR(10) NOT NULL,
+    status            VARCHAR(20) NOT NULL DEFAULT '',
+    data              JSONB NOT NULL,
+    standings         JSONB,
+    week              SMALLINT NOT NULL DEFAULT 0,
+    matchups          JSONB,
+    previous_matchups JSONB,
+    updated_at        TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sleeper_rosters (
+    league_id  VARCHAR(32) NOT NULL REFERENCES sleeper_leagues(league_id) ON DELETE CASCADE,
+    roster_id  INTEGER NOT NULL,
+    owner_id   VARCHAR(32),
+    data       JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (league_id, roster_id)
+);
+
+CREATE TABLE IF NOT EXISTS sleeper_user_leagues (
+    logto_sub  VARCHAR(255) NOT NULL REFERENCES sleeper_users(logto_sub) ON DELETE CASCADE,
+    league_id  VARCHAR(32) NOT NULL REFERENCES sleeper_leagues(league_id) ON DELETE CASCADE,
+    roster_id  INTEGER,
+    team_name  VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (logto_sub, league_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sleeper_user_leagues_league_id ON sleeper_user_leagues(league_id);
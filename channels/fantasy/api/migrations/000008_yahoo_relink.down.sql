@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_yahoo_relink_requests_candidate;
+DROP TABLE IF EXISTS yahoo_relink_requests;
@@ -0,0 +1,20 @@
+-- Yahoo links made when the OAuth callback had no Scrollr session (the
+-- state key expired before the user finished on Yahoo) stored the Yahoo
+-- GUID as logto_sub. No Scrollr user has that sub, so status and league
+-- lookups for the real owner find nothing.
+--
+-- One row per such link. candidate_sub is the account an operator believes
+-- owns it; that user is prompted to relink. Only the relink itself — the
+-- user proving they own the GUID through Yahoo OAuth — moves the row to
+-- their sub (migrated_sub), keeping the imported leagues.
+CREATE TABLE IF NOT EXISTS yahoo_relink_requests (
+    guid          VARCHAR(100) PRIMARY KEY REFERENCES yahoo_users(guid) ON DELETE CASCADE,
+    candidate_sub VARCHAR(255),
+    detected_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    notified_at   TIMESTAMP WITH TIME ZONE,
+    migrated_sub  VARCHAR(255),
+    migrated_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_yahoo_relink_requests_candidate
+    ON yahoo_relink_requests(candidate_sub) WHERE migrated_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_yahoo_users_token_check;
+ALTER TABLE yahoo_users
+    DROP COLUMN IF EXISTS token_status_changed_at,
+    DROP COLUMN IF EXISTS token_checked_at,
+    DROP COLUMN IF EXISTS token_status;
@@ -0,0 +1,14 @@
+-- Refresh-token health for each Yahoo link (token_status.go).
+--
+-- `token_status` starts 'active'. The reconciler exchanges each active
+-- token every few hours; when Yahoo rejects it the row becomes 'expired'
+-- or 'revoked', sync skips it, and the owner is prompted to reconnect.
+-- Relinking writes a fresh token and resets the row to 'active'.
+ALTER TABLE yahoo_users
+    ADD COLUMN IF NOT EXISTS token_status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (token_status IN ('active', 'expired', 'revoked')),
+    ADD COLUMN IF NOT EXISTS token_checked_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS token_status_changed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_yahoo_users_token_check
+    ON yahoo_users(token_checked_at NULLS FIRST) WHERE token_status = 'active';
@@ -71,6 +71,7 @@ type XMLLeague struct {
 	EndWeek     *string `xml:"end_week" json:"end_week"`
 	IsFinished  *string `xml:"is_finished" json:"is_finished"`
 	Season      string  `xml:"season" json:"season"`
+	Renew       string  `xml:"renew" json:"renew"` // "{game_id}_{league_id}" of the previous season, if renewed
 
 	// Nested resources (populated by standings/teams endpoints)
 	Standings  *XMLStandings      `xml:"standings,omitempty" json:"standings,omitempty"`
@@ -270,10 +271,21 @@ type XMLStat struct {
 }
 
 // XMLLeagueSettings mirrors <league_settings> from the league/settings endpoint.
-// We only need the stat_categories + stat_modifiers to compute synthetic points.
+// stat_categories + stat_modifiers compute synthetic points; roster_positions
+// bounds lineup changes.
 type XMLLeagueSettings struct {
-	StatCategories XMLStatCategories `xml:"stat_categories" json:"stat_categories"`
-	StatModifiers  XMLStatModifiers  `xml:"stat_modifiers" json:"stat_modifiers"`
+	StatCategories  XMLStatCategories  `xml:"stat_categories" json:"stat_categories"`
+	StatModifiers   XMLStatModifiers   `xml:"stat_modifiers" json:"stat_modifiers"`
+	RosterPositions XMLRosterPositions `xml:"roster_positions" json:"roster_positions"`
+}
+
+type XMLRosterPositions struct {
+	RosterPosition []XMLRosterPosition `xml:"roster_position" json:"roster_position"`
+}
+
+type XMLRosterPosition struct {
+	Position string `xml:"position" json:"position"`
+	Count    string `xml:"count" json:"count"`
 }
 
 type XMLStatCategories struct {
@@ -330,6 +342,76 @@ type XMLStatModifier struct {
 	Value  string `xml:"value" json:"value"`
 }
 
+// =============================================================================
+// Sleeper JSON Types — Parsed from api.sleeper.app/v1 responses
+//
+// Only the fields we store or serialize are declared; the raw league object
+// is kept separately as the league's data blob.
+// =============================================================================
+
+// SleeperUser is GET /user/{username}. Sleeper answers an unknown username
+// with 200 and a null body.
+type SleeperUser struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+// SleeperState is GET /state/{sport}.
+type SleeperState struct {
+	Week            int    `json:"week"`
+	Season          string `json:"season"`
+	SeasonType      string `json:"season_type"`
+	SeasonStartDate string `json:"season_start_date"`
+}
+
+// SleeperLeague is one entry of GET /user/{user_id}/leagues/{sport}/{season}.
+type SleeperLeague struct {
+	LeagueID     string `json:"league_id"`
+	Name         string `json:"name"`
+	Sport        string `json:"sport"`
+	Season       string `json:"season"`
+	Status       string `json:"status"`
+	TotalRosters int    `json:"total_rosters"`
+}
+
+// SleeperLeagueUser is one entry of GET /league/{league_id}/users.
+type SleeperLeagueUser struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name"`
+	Metadata    struct {
+		TeamName string `json:"team_name"`
+	} `json:"metadata"`
+}
+
+// SleeperRoster is one entry of GET /league/{league_id}/rosters. Points are
+// split into whole and hundredths fields by Sleeper.
+type SleeperRoster struct {
+	RosterID int      `json:"roster_id"`
+	OwnerID  *string  `json:"owner_id"`
+	Players  []string `json:"players"`
+	Starters []string `json:"starters"`
+	Settings struct {
+		Wins               int `json:"wins"`
+		Losses             int `json:"losses"`
+		Ties               int `json:"ties"`
+		Fpts               int `json:"fpts"`
+		FptsDecimal        int `json:"fpts_decimal"`
+		FptsAgainst        int `json:"fpts_against"`
+		FptsAgainstDecimal int `json:"fpts_against_decimal"`
+	} `json:"settings"`
+}
+
+// SleeperMatchup is one entry of GET /league/{league_id}/matchups/{week}:
+// one roster's side of a matchup. Rosters sharing a MatchupID play each
+// other; a nil MatchupID is a bye.
+type SleeperMatchup struct {
+	RosterID  int      `json:"roster_id"`
+	MatchupID *int     `json:"matchup_id"`
+	Points    float64  `json:"points"`
+	Starters  []string `json:"starters"`
+}
+
 // =============================================================================
 // API Response Types — Postgres-backed
 // =============================================================================
@@ -338,10 +420,34 @@ type XMLStatModifier struct {
 type YahooStatusResponse struct {
 	Connected bool `json:"connected"`
 	Synced    bool `json:"synced"`
-}
-
-// LeagueResponse is a single league with all associated data.
+	// CanWrite is true once the user has re-consented with fspt-w, which
+	// lineup changes require.
+	CanWrite bool `json:"can_write"`
+	// RelinkRequired asks the user to reconnect Yahoo so a legacy link
+	// can move to their account (yahoo_relink.go).
+	RelinkRequired bool `json:"relink_required,omitempty"`
+	// TokenStatus is the stored refresh token's health: active, expired
+	// or revoked (token_status.go). Empty when not connected.
+	TokenStatus string `json:"token_status,omitempty"`
+	// ReauthRequired is true when Yahoo rejected the stored token; sync
+	// is paused until the user reconnects.
+	ReauthRequired bool `json:"reauth_required,omitempty"`
+}
+
+// SleeperStatusResponse returns whether user has Sleeper linked.
+type SleeperStatusResponse struct {
+	Linked      bool   `json:"linked"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Synced      bool   `json:"synced"`
+	Leagues     int    `json:"leagues"`
+}
+
+// LeagueResponse is a single league with all associated data. Sleeper
+// leagues use the same shape with provider "sleeper" and league keys of the
+// form sleeper.l.{league_id}.
 type LeagueResponse struct {
+	Provider         string          `json:"provider"`
 	LeagueKey        string          `json:"league_key"`
 	Name             string          `json:"name"`
 	GameCode         string          `json:"game_code"`
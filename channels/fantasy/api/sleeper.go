@@ -0,0 +1,813 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Sleeper Fantasy
+// =============================================================================
+//
+// Sleeper exposes a public, read-only API keyed by username — no OAuth, no
+// tokens. Linking resolves the username to a Sleeper user_id and imports
+// that user's leagues for the current season; a background loop keeps
+// league, roster and matchup data fresh. Sleeper leagues are served to the
+// dashboard alongside Yahoo's under the same fantasy key, serialized into
+// the same standings/matchups shapes so clients and the core spoiler
+// filter treat both providers alike.
+
+const (
+	defaultSleeperBaseURL = "https://api.sleeper.app/v1"
+
+	// SleeperSport is the only sport Sleeper's league API serves in full.
+	SleeperSport = "nfl"
+
+	// SleeperLeagueKeyPrefix namespaces Sleeper league IDs so they can't
+	// collide with Yahoo league keys in the merged league list.
+	SleeperLeagueKeyPrefix = "sleeper.l."
+
+	// SleeperSyncInterval is how often linked leagues are refreshed.
+	SleeperSyncInterval = 5 * time.Minute
+
+	// SleeperAPITimeout bounds a single Sleeper request.
+	SleeperAPITimeout = 15 * time.Second
+
+	// sleeperMaxBody caps a response body; league payloads are a few KB.
+	sleeperMaxBody = 2 << 20
+)
+
+// sleeperUsernameRegex matches Sleeper usernames and numeric user IDs, both
+// of which GET /user/{id} accepts.
+var sleeperUsernameRegex = regexp.MustCompile(`^[A-Za-z0-9_]{1,40}$`)
+
+// errSleeperNotFound is returned for unknown users and leagues.
+var errSleeperNotFound = errors.New("sleeper: not found")
+
+// getSleeperBaseURL returns the Sleeper API base URL, overridable via
+// SLEEPER_API_BASE_URL for local testing with mock servers.
+func getSleeperBaseURL() string {
+	if v := os.Getenv("SLEEPER_API_BASE_URL"); v != "" {
+		return v
+	}
+	return defaultSleeperBaseURL
+}
+
+// SleeperClient is a Sleeper API client. It holds no per-user state.
+type SleeperClient struct {
+	httpClient *http.Client
+}
+
+// NewSleeperClient creates a Sleeper API client.
+func NewSleeperClient() *SleeperClient {
+	return &SleeperClient{httpClient: &http.Client{Timeout: SleeperAPITimeout}}
+}
+
+// get fetches path and decodes the JSON body into out. A 404 or a literal
+// null body maps to errSleeperNotFound.
+func (sc *SleeperClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getSleeperBaseURL()+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := sc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sleeper %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errSleeperNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sleeper %s: HTTP %d", path, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, sleeperMaxBody))
+	if err != nil {
+		return fmt.Errorf("sleeper %s: read body: %w", path, err)
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return errSleeperNotFound
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("sleeper %s: decode: %w", path, err)
+	}
+	return nil
+}
+
+// GetUser resolves a username (or user ID) to a Sleeper user.
+func (sc *SleeperClient) GetUser(ctx context.Context, username string) (*SleeperUser, error) {
+	var u SleeperUser
+	if err := sc.get(ctx, "/user/"+username, &u); err != nil {
+		return nil, err
+	}
+	if u.UserID == "" {
+		return nil, errSleeperNotFound
+	}
+	return &u, nil
+}
+
+// GetState returns the current season and week for a sport.
+func (sc *SleeperClient) GetState(ctx context.Context, sport string) (*SleeperState, error) {
+	var s SleeperState
+	if err := sc.get(ctx, "/state/"+sport, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetUserLeagues lists a user's leagues for a season as raw league objects.
+func (sc *SleeperClient) GetUserLeagues(ctx context.Context, userID, sport, season string) ([]json.RawMessage, error) {
+	var leagues []json.RawMessage
+	err := sc.get(ctx, fmt.Sprintf("/user/%s/leagues/%s/%s", userID, sport, season), &leagues)
+	if errors.Is(err, errSleeperNotFound) {
+		return nil, nil
+	}
+	return leagues, err
+}
+
+// GetLeague returns a single league as a raw league object.
+func (sc *SleeperClient) GetLeague(ctx context.Context, leagueID string) (json.RawMessage, error) {
+	var league json.RawMessage
+	if err := sc.get(ctx, "/league/"+leagueID, &league); err != nil {
+		return nil, err
+	}
+	return league, nil
+}
+
+// GetLeagueUsers returns the members of a league.
+func (sc *SleeperClient) GetLeagueUsers(ctx context.Context, leagueID string) ([]SleeperLeagueUser, error) {
+	var users []SleeperLeagueUser
+	err := sc.get(ctx, "/league/"+leagueID+"/users", &users)
+	return users, err
+}
+
+// GetRosters returns every roster in a league.
+func (sc *SleeperClient) GetRosters(ctx context.Context, leagueID string) ([]SleeperRoster, error) {
+	var rosters []SleeperRoster
+	err := sc.get(ctx, "/league/"+leagueID+"/rosters", &rosters)
+	return rosters, err
+}
+
+// GetMatchups returns each roster's side of a week's matchups.
+func (sc *SleeperClient) GetMatchups(ctx context.Context, leagueID string, week int) ([]SleeperMatchup, error) {
+	var matchups []SleeperMatchup
+	err := sc.get(ctx, fmt.Sprintf("/league/%s/matchups/%d", leagueID, week), &matchups)
+	if errors.Is(err, errSleeperNotFound) {
+		return nil, nil
+	}
+	return matchups, err
+}
+
+// ---------------------------------------------------------------------------
+// Serialization — Sleeper responses → Yahoo-shaped blobs
+// ---------------------------------------------------------------------------
+
+// sleeperTeam is the display identity of a roster.
+type sleeperTeam struct {
+	Name    string
+	Manager string
+}
+
+func sleeperLeagueKey(leagueID string) string {
+	return SleeperLeagueKeyPrefix + leagueID
+}
+
+func sleeperTeamKey(leagueID string, rosterID int) string {
+	return sleeperLeagueKey(leagueID) + ".t." + strconv.Itoa(rosterID)
+}
+
+// sleeperPoints joins Sleeper's whole and hundredths point fields.
+func sleeperPoints(whole, decimal int) float64 {
+	return float64(whole) + float64(decimal)/100
+}
+
+// sleeperTeams names each roster after its owner's team name, falling back
+// to their display name and then the roster number.
+func sleeperTeams(rosters []SleeperRoster, users []SleeperLeagueUser) map[int]sleeperTeam {
+	byID := make(map[string]SleeperLeagueUser, len(users))
+	for _, u := range users {
+		byID[u.UserID] = u
+	}
+	teams := make(map[int]sleeperTeam, len(rosters))
+	for _, r := range rosters {
+		team := sleeperTeam{Name: fmt.Sprintf("Team %d", r.RosterID)}
+		if r.OwnerID != nil {
+			if u, ok := byID[*r.OwnerID]; ok {
+				team.Manager = u.DisplayName
+				team.Name = u.DisplayName
+				if u.Metadata.TeamName != "" {
+					team.Name = u.Metadata.TeamName
+				}
+			}
+		}
+		teams[r.RosterID] = team
+	}
+	return teams
+}
+
+// sleeperWeekEnd returns the Monday that closes an NFL week, given the
+// Thursday the season opened on. Empty when the start date is unknown.
+func sleeperWeekEnd(seasonStart string, week int) string {
+	start, err := time.Parse(time.DateOnly, seasonStart)
+	if err != nil || week < 1 {
+		return ""
+	}
+	return start.AddDate(0, 0, (week-1)*7+4).Format(time.DateOnly)
+}
+
+// serializeSleeperStandings ranks rosters by wins, ties, then points for.
+func serializeSleeperStandings(leagueID string, rosters []SleeperRoster, teams map[int]sleeperTeam) []map[string]any {
+	sorted := append([]SleeperRoster(nil), rosters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Settings, sorted[j].Settings
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Ties != b.Ties {
+			return a.Ties > b.Ties
+		}
+		return sleeperPoints(a.Fpts, a.FptsDecimal) > sleeperPoints(b.Fpts, b.FptsDecimal)
+	})
+
+	result := make([]map[string]any, 0, len(sorted))
+	for i, r := range sorted {
+		s := r.Settings
+		percentage := "0.000"
+		if games := s.Wins + s.Losses + s.Ties; games > 0 {
+			percentage = fmt.Sprintf("%.3f", (float64(s.Wins)+float64(s.Ties)/2)/float64(games))
+		}
+		team := teams[r.RosterID]
+		result = append(result, map[string]any{
+			"team_key":       sleeperTeamKey(leagueID, r.RosterID),
+			"team_id":        r.RosterID,
+			"name":           team.Name,
+			"manager_name":   team.Manager,
+			"rank":           i + 1,
+			"wins":           s.Wins,
+			"losses":         s.Losses,
+			"ties":           s.Ties,
+			"percentage":     percentage,
+			"points_for":     strconv.FormatFloat(sleeperPoints(s.Fpts, s.FptsDecimal), 'f', 2, 64),
+			"points_against": strconv.FormatFloat(sleeperPoints(s.FptsAgainst, s.FptsAgainstDecimal), 'f', 2, 64),
+		})
+	}
+	return result
+}
+
+// serializeSleeperMatchups pairs rosters by matchup_id into Yahoo-style
+// matchups. Winners are only decided once the week is final; byes are
+// dropped.
+func serializeSleeperMatchups(leagueID string, week int, weekEnd string, final bool, sides []SleeperMatchup, teams map[int]sleeperTeam) []map[string]any {
+	byMatchup := make(map[int][]SleeperMatchup)
+	var ids []int
+	for _, side := range sides {
+		if side.MatchupID == nil {
+			continue
+		}
+		id := *side.MatchupID
+		if _, ok := byMatchup[id]; !ok {
+			ids = append(ids, id)
+		}
+		byMatchup[id] = append(byMatchup[id], side)
+	}
+	sort.Ints(ids)
+
+	result := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		pair := byMatchup[id]
+		sort.Slice(pair, func(i, j int) bool { return pair[i].RosterID < pair[j].RosterID })
+
+		status := "preevent"
+		teamsOut := make([]map[string]any, 0, len(pair))
+		for _, side := range pair {
+			if side.Points > 0 {
+				status = "midevent"
+			}
+			points := side.Points
+			team := teams[side.RosterID]
+			teamsOut = append(teamsOut, map[string]any{
+				"team_key":     sleeperTeamKey(leagueID, side.RosterID),
+				"team_id":      side.RosterID,
+				"name":         team.Name,
+				"manager_name": team.Manager,
+				"points":       &points,
+			})
+		}
+
+		var winnerKey *string
+		tied := false
+		if final {
+			status = "postevent"
+			if len(pair) == 2 {
+				switch {
+				case pair[0].Points > pair[1].Points:
+					k := sleeperTeamKey(leagueID, pair[0].RosterID)
+					winnerKey = &k
+				case pair[1].Points > pair[0].Points:
+					k := sleeperTeamKey(leagueID, pair[1].RosterID)
+					winnerKey = &k
+				default:
+					tied = true
+				}
+			}
+		}
+
+		m := map[string]any{
+			"week":            week,
+			"status":          status,
+			"is_tied":         tied,
+			"winner_team_key": winnerKey,
+			"teams":           teamsOut,
+		}
+		if weekEnd != "" {
+			m["week_end"] = weekEnd
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// serializeSleeperRoster converts a roster to the blob stored in
+// sleeper_rosters.data. Players are Sleeper player IDs.
+func serializeSleeperRoster(leagueID string, r SleeperRoster, team sleeperTeam) map[string]any {
+	players := r.Players
+	if players == nil {
+		players = []string{}
+	}
+	starters := r.Starters
+	if starters == nil {
+		starters = []string{}
+	}
+	return map[string]any{
+		"team_key":     sleeperTeamKey(leagueID, r.RosterID),
+		"roster_id":    r.RosterID,
+		"name":         team.Name,
+		"manager_name": team.Manager,
+		"players":      players,
+		"starters":     starters,
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Persistence
+// ---------------------------------------------------------------------------
+
+// syncSleeperLeague fetches a league with its members, rosters and the
+// current and previous week's matchups, and writes it all. Returns the
+// rosters and their team names so callers can find a user's own team.
+func (a *App) syncSleeperLeague(ctx context.Context, sc *SleeperClient, leagueID string, state *SleeperState) ([]SleeperRoster, map[int]sleeperTeam, error) {
+	raw, err := sc.GetLeague(ctx, leagueID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("league: %w", err)
+	}
+	var league SleeperLeague
+	if err := json.Unmarshal(raw, &league); err != nil {
+		return nil, nil, fmt.Errorf("decode league: %w", err)
+	}
+	users, err := sc.GetLeagueUsers(ctx, leagueID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("users: %w", err)
+	}
+	rosters, err := sc.GetRosters(ctx, leagueID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rosters: %w", err)
+	}
+	teams := sleeperTeams(rosters, users)
+	standings := serializeSleeperStandings(leagueID, rosters, teams)
+
+	// Matchups only exist for the live season; a league from an earlier
+	// season keeps whatever was stored while it was current.
+	week := 0
+	var matchups, previous []map[string]any
+	if league.Season == state.Season && league.Status == "in_season" && state.Week > 0 {
+		week = state.Week
+		current, err := sc.GetMatchups(ctx, leagueID, week)
+		if err != nil {
+			log.Printf("[Sleeper] Matchups failed for %s week %d: %v", leagueID, week, err)
+		} else {
+			matchups = serializeSleeperMatchups(leagueID, week, sleeperWeekEnd(state.SeasonStartDate, week), false, current, teams)
+		}
+		if week > 1 {
+			last, err := sc.GetMatchups(ctx, leagueID, week-1)
+			if err != nil {
+				log.Printf("[Sleeper] Matchups failed for %s week %d: %v", leagueID, week-1, err)
+			} else {
+				previous = serializeSleeperMatchups(leagueID, week-1, sleeperWeekEnd(state.SeasonStartDate, week-1), true, last, teams)
+			}
+		}
+	}
+
+	standingsJSON, _ := json.Marshal(standings)
+	var matchupsJSON, previousJSON []byte
+	if matchups != nil {
+		matchupsJSON, _ = json.Marshal(matchups)
+	}
+	if previous != nil {
+		previousJSON, _ = json.Marshal(previous)
+	}
+
+	_, err = a.db.Exec(ctx, `
+		INSERT INTO sleeper_leagues (league_id, name, sport, season, status, data, standings, week, matchups, previous_matchups, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP)
+		ON CONFLICT (league_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			status = EXCLUDED.status,
+			data = EXCLUDED.data,
+			standings = EXCLUDED.standings,
+			week = CASE WHEN EXCLUDED.week > 0 THEN EXCLUDED.week ELSE sleeper_leagues.week END,
+			matchups = COALESCE(EXCLUDED.matchups, sleeper_leagues.matchups),
+			previous_matchups = COALESCE(EXCLUDED.previous_matchups, sleeper_leagues.previous_matchups),
+			updated_at = CURRENT_TIMESTAMP
+	`, leagueID, league.Name, league.Sport, league.Season, league.Status, raw, standingsJSON, week, matchupsJSON, previousJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upsert league: %w", err)
+	}
+
+	for _, r := range rosters {
+		data, _ := json.Marshal(serializeSleeperRoster(leagueID, r, teams[r.RosterID]))
+		if _, err := a.db.Exec(ctx, `
+			INSERT INTO sleeper_rosters (league_id, roster_id, owner_id, data, updated_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+			ON CONFLICT (league_id, roster_id) DO UPDATE SET
+				owner_id = EXCLUDED.owner_id, data = EXCLUDED.data, updated_at = CURRENT_TIMESTAMP
+		`, leagueID, r.RosterID, r.OwnerID, data); err != nil {
+			log.Printf("[Sleeper] Failed upsert roster %s/%d: %v", leagueID, r.RosterID, err)
+		}
+	}
+
+	return rosters, teams, nil
+}
+
+// fetchSleeperLeagues loads a user's Sleeper leagues in LeagueResponse form.
+func (a *App) fetchSleeperLeagues(ctx context.Context, logtoSub string) ([]LeagueResponse, error) {
+	rows, err := a.db.Query(ctx, `
+		SELECT l.league_id, l.name, l.sport, l.season, ul.roster_id, ul.team_name,
+		       l.data, l.standings, l.matchups, l.previous_matchups,
+		       (SELECT json_agg(json_build_object('team_key', r.data->>'team_key', 'data', r.data) ORDER BY r.roster_id)
+		        FROM sleeper_rosters r WHERE r.league_id = l.league_id)
+		FROM sleeper_user_leagues ul
+		JOIN sleeper_leagues l ON l.league_id = ul.league_id
+		WHERE ul.logto_sub = $1
+		ORDER BY l.sport, l.season DESC, l.name
+	`, logtoSub)
+	if err != nil {
+		return nil, fmt.Errorf("query sleeper leagues: %w", err)
+	}
+	defer rows.Close()
+
+	leagues := make([]LeagueResponse, 0)
+	for rows.Next() {
+		var leagueID string
+		var rosterID *int
+		var data, standings, matchups, previous, rosters []byte
+		lr := LeagueResponse{Provider: ProviderSleeper}
+		if err := rows.Scan(
+			&leagueID, &lr.Name, &lr.GameCode, &lr.Season, &rosterID, &lr.TeamName,
+			&data, &standings, &matchups, &previous, &rosters,
+		); err != nil {
+			log.Printf("[Sleeper] Scan error: %v", err)
+			continue
+		}
+		lr.LeagueKey = sleeperLeagueKey(leagueID)
+		lr.Data, lr.Standings, lr.Matchups, lr.PreviousMatchups, lr.Rosters = data, standings, matchups, previous, rosters
+		if rosterID != nil {
+			teamKey := sleeperTeamKey(leagueID, *rosterID)
+			lr.TeamKey = &teamKey
+		}
+		leagues = append(leagues, lr)
+	}
+	return leagues, rows.Err()
+}
+
+// countUserLeagues counts a user's imported leagues across both providers,
+// for tier cap enforcement.
+func (a *App) countUserLeagues(ctx context.Context, logtoSub string) (yahoo, sleeper int, err error) {
+	err = a.db.QueryRow(ctx, `
+		SELECT
+			(SELECT count(*) FROM yahoo_user_leagues ul JOIN yahoo_users u ON u.guid = ul.guid WHERE u.logto_sub = $1),
+			(SELECT count(*) FROM sleeper_user_leagues WHERE logto_sub = $1)
+	`, logtoSub).Scan(&yahoo, &sleeper)
+	return yahoo, sleeper, err
+}
+
+// ---------------------------------------------------------------------------
+// Background sync
+// ---------------------------------------------------------------------------
+
+// startSleeperSync refreshes every linked Sleeper league on a fixed
+// interval until ctx is cancelled. Failures are per-league and logged; the
+// next cycle retries.
+func (a *App) startSleeperSync(ctx context.Context) {
+	sc := NewSleeperClient()
+	for {
+		a.runSleeperSyncCycle(ctx, sc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(SleeperSyncInterval):
+		}
+	}
+}
+
+func (a *App) runSleeperSyncCycle(ctx context.Context, sc *SleeperClient) {
+	rows, err := a.db.Query(ctx, "SELECT DISTINCT league_id FROM sleeper_user_leagues")
+	if err != nil {
+		log.Printf("[Sleeper Sync] Failed to list leagues: %v", err)
+		return
+	}
+	var leagueIDs []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			leagueIDs = append(leagueIDs, id)
+		}
+	}
+	rows.Close()
+	if len(leagueIDs) == 0 {
+		return
+	}
+
+	state, err := sc.GetState(ctx, SleeperSport)
+	if err != nil {
+		log.Printf("[Sleeper Sync] Failed to fetch state: %v", err)
+		return
+	}
+
+	synced := make([]string, 0, len(leagueIDs))
+	for _, id := range leagueIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, _, err := a.syncSleeperLeague(ctx, sc, id, state); err != nil {
+			log.Printf("[Sleeper Sync] League %s failed: %v", id, err)
+			continue
+		}
+		synced = append(synced, id)
+	}
+
+	if _, err := a.db.Exec(ctx, `
+		UPDATE sleeper_users SET last_sync = CURRENT_TIMESTAMP
+		WHERE logto_sub IN (SELECT logto_sub FROM sleeper_user_leagues WHERE league_id = ANY($1))
+	`, synced); err != nil {
+		log.Printf("[Sleeper Sync] Failed to update sync times: %v", err)
+	}
+	log.Printf("[Sleeper Sync] Cycle complete: %d/%d leagues synced", len(synced), len(leagueIDs))
+}
+
+// ---------------------------------------------------------------------------
+// User routes
+// ---------------------------------------------------------------------------
+
+// GetSleeperStatus returns whether the current user has Sleeper linked.
+func (a *App) GetSleeperStatus(c *fiber.Ctx) error {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var resp SleeperStatusResponse
+	var lastSync *time.Time
+	err := a.db.QueryRow(context.Background(), `
+		SELECT username, display_name, last_sync,
+		       (SELECT count(*) FROM sleeper_user_leagues WHERE logto_sub = $1)
+		FROM sleeper_users WHERE logto_sub = $1
+	`, userID).Scan(&resp.Username, &resp.DisplayName, &lastSync, &resp.Leagues)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return c.JSON(SleeperStatusResponse{})
+		}
+		log.Printf("[GetSleeperStatus] DB error for logto_sub=%s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to check Sleeper status",
+		})
+	}
+	resp.Linked = true
+	resp.Synced = lastSync != nil
+	return c.JSON(resp)
+}
+
+// LinkSleeper links a Sleeper username to the current user and imports
+// their leagues for the current season. Re-linking (same or different
+// username) replaces the previous import. Imports stop at the user's tier
+// cap, which Sleeper and Yahoo leagues share.
+func (a *App) LinkSleeper(c *fiber.Ctx) error {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var incoming struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&incoming); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	username := strings.TrimSpace(incoming.Username)
+	if !sleeperUsernameRegex.MatchString(username) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "username must be a Sleeper username",
+		})
+	}
+
+	// 60s timeout for the entire import (one request per league resource)
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	sc := NewSleeperClient()
+	user, err := sc.GetUser(ctx, username)
+	if errors.Is(err, errSleeperNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Sleeper user not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[Sleeper] GetUser failed for %q: %v", username, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to reach Sleeper",
+		})
+	}
+
+	state, err := sc.GetState(ctx, SleeperSport)
+	if err != nil {
+		log.Printf("[Sleeper] GetState failed: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to reach Sleeper",
+		})
+	}
+	rawLeagues, err := sc.GetUserLeagues(ctx, user.UserID, SleeperSport, state.Season)
+	if err != nil {
+		log.Printf("[Sleeper] GetUserLeagues failed for %s: %v", user.UserID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to fetch leagues from Sleeper",
+		})
+	}
+	leagues := make([]SleeperLeague, 0, len(rawLeagues))
+	for _, raw := range rawLeagues {
+		var l SleeperLeague
+		if json.Unmarshal(raw, &l) == nil && l.LeagueID != "" {
+			leagues = append(leagues, l)
+		}
+	}
+
+	// -------------------------------------------------------------------------
+	// Tier enforcement — Sleeper leagues count against the same cap as
+	// Yahoo's. A re-link replaces the previous Sleeper import, so only the
+	// Yahoo count is already spent.
+	// -------------------------------------------------------------------------
+	tier := GetUserTier(c)
+	cap := FantasyLeagueCap(tier)
+	skipped := 0
+	if cap != -1 {
+		yahooCount, _, err := a.countUserLeagues(ctx, userID)
+		if err != nil {
+			log.Printf("[Sleeper] Failed to count leagues for %s: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Failed to verify league count",
+			})
+		}
+		remaining := max(cap-yahooCount, 0)
+		if len(leagues) > 0 && remaining == 0 {
+			log.Printf("[Sleeper] Tier cap reached — user=%s tier=%s current=%d cap=%d", userID, tier, yahooCount, cap)
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "league limit reached for your tier",
+				"current": yahooCount,
+				"max":     cap,
+				"tier":    tier,
+			})
+		}
+		if len(leagues) > remaining {
+			skipped = len(leagues) - remaining
+			leagues = leagues[:remaining]
+		}
+	}
+
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO sleeper_users (logto_sub, user_id, username, display_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (logto_sub) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			username = EXCLUDED.username,
+			display_name = EXCLUDED.display_name
+	`, userID, user.UserID, user.Username, user.DisplayName); err != nil {
+		log.Printf("[Sleeper] Failed upsert sleeper_user %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "Failed to save Sleeper link",
+		})
+	}
+
+	imported := make([]fiber.Map, 0, len(leagues))
+	importedIDs := make([]string, 0, len(leagues))
+	for _, l := range leagues {
+		rosters, teams, err := a.syncSleeperLeague(ctx, sc, l.LeagueID, state)
+		if err != nil {
+			log.Printf("[Sleeper] Import of league %s failed: %v", l.LeagueID, err)
+			continue
+		}
+		var rosterID *int
+		var teamName *string
+		for _, r := range rosters {
+			if r.OwnerID != nil && *r.OwnerID == user.UserID {
+				id, name := r.RosterID, teams[r.RosterID].Name
+				rosterID, teamName = &id, &name
+				break
+			}
+		}
+		if _, err := a.db.Exec(ctx, `
+			INSERT INTO sleeper_user_leagues (logto_sub, league_id, roster_id, team_name)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (logto_sub, league_id) DO UPDATE SET
+				roster_id = EXCLUDED.roster_id, team_name = EXCLUDED.team_name
+		`, userID, l.LeagueID, rosterID, teamName); err != nil {
+			log.Printf("[Sleeper] Failed upsert user_league %s/%s: %v", userID, l.LeagueID, err)
+			continue
+		}
+		importedIDs = append(importedIDs, l.LeagueID)
+		imported = append(imported, fiber.Map{
+			"league_key": sleeperLeagueKey(l.LeagueID),
+			"name":       l.Name,
+			"season":     l.Season,
+			"team_name":  teamName,
+		})
+	}
+
+	// Drop leagues from a previous link that this import didn't cover
+	if _, err := a.db.Exec(ctx,
+		"DELETE FROM sleeper_user_leagues WHERE logto_sub = $1 AND NOT (league_id = ANY($2))",
+		userID, importedIDs,
+	); err != nil {
+		log.Printf("[Sleeper] Failed to prune old leagues for %s: %v", userID, err)
+	}
+	a.db.Exec(ctx, "UPDATE sleeper_users SET last_sync = CURRENT_TIMESTAMP WHERE logto_sub = $1", userID)
+
+	log.Printf("[Sleeper] Linked %s to Sleeper user %s, imported %d leagues (%d skipped by tier cap)",
+		userID, user.Username, len(imported), skipped)
+
+	return c.JSON(fiber.Map{
+		"username":     user.Username,
+		"display_name": user.DisplayName,
+		"leagues":      imported,
+		"skipped":      skipped,
+	})
+}
+
+// UnlinkSleeper removes the user's Sleeper link and their league imports.
+// Leagues no other user follows are deleted along with their rosters.
+func (a *App) UnlinkSleeper(c *fiber.Ctx) error {
+	userID := GetUserSub(c)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	ctx := context.Background()
+	tag, err := a.db.Exec(ctx, "DELETE FROM sleeper_users WHERE logto_sub = $1", userID)
+	if err != nil {
+		log.Printf("[Sleeper] Failed to unlink %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to unlink Sleeper",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.JSON(fiber.Map{"status": "ok", "message": "No Sleeper account linked"})
+	}
+
+	if _, err := a.db.Exec(ctx, `
+		DELETE FROM sleeper_leagues l
+		WHERE NOT EXISTS (SELECT 1 FROM sleeper_user_leagues ul WHERE ul.league_id = l.league_id)
+	`); err != nil {
+		log.Printf("[Sleeper] Failed to delete orphaned leagues: %v", err)
+	}
+
+	return c.JSON(fiber.Map{"status": "ok", "message": "Sleeper account unlinked"})
+}
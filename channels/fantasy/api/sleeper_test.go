@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func sleeperRoster(id int, owner string, wins, losses, fpts int) SleeperRoster {
+	r := SleeperRoster{RosterID: id}
+	if owner != "" {
+		r.OwnerID = &owner
+	}
+	r.Settings.Wins, r.Settings.Losses, r.Settings.Fpts = wins, losses, fpts
+	return r
+}
+
+func TestSleeperTeams(t *testing.T) {
+	rosters := []SleeperRoster{sleeperRoster(1, "u1", 0, 0, 0), sleeperRoster(2, "u2", 0, 0, 0), sleeperRoster(3, "", 0, 0, 0)}
+	users := []SleeperLeagueUser{{UserID: "u1", DisplayName: "alice"}, {UserID: "u2", DisplayName: "bob"}}
+	users[0].Metadata.TeamName = "Gridiron Ghosts"
+
+	teams := sleeperTeams(rosters, users)
+	if got := teams[1]; got.Name != "Gridiron Ghosts" || got.Manager != "alice" {
+		t.Errorf("roster 1 = %+v", got)
+	}
+	if got := teams[2].Name; got != "bob" {
+		t.Errorf("roster 2 name = %q, want display name fallback", got)
+	}
+	if got := teams[3].Name; got != "Team 3" {
+		t.Errorf("ownerless roster name = %q", got)
+	}
+}
+
+func TestSerializeSleeperStandings(t *testing.T) {
+	rosters := []SleeperRoster{
+		sleeperRoster(1, "u1", 2, 3, 500),
+		sleeperRoster(2, "u2", 4, 1, 450),
+		sleeperRoster(3, "u3", 2, 3, 520),
+	}
+	rosters[0].Settings.FptsDecimal = 25
+
+	standings := serializeSleeperStandings("99", rosters, map[int]sleeperTeam{})
+	order := []string{"sleeper.l.99.t.2", "sleeper.l.99.t.3", "sleeper.l.99.t.1"}
+	for i, want := range order {
+		if got := standings[i]["team_key"]; got != want {
+			t.Errorf("rank %d = %v, want %s", i+1, got, want)
+		}
+		if got := standings[i]["rank"]; got != i+1 {
+			t.Errorf("%s rank = %v, want %d", want, got, i+1)
+		}
+	}
+	if got := standings[0]["percentage"]; got != "0.800" {
+		t.Errorf("percentage = %v, want 0.800", got)
+	}
+	if got := standings[2]["points_for"]; got != "500.25" {
+		t.Errorf("points_for = %v, want 500.25", got)
+	}
+}
+
+func TestSerializeSleeperMatchups(t *testing.T) {
+	one, two := 1, 2
+	sides := []SleeperMatchup{
+		{RosterID: 3, MatchupID: &two, Points: 90},
+		{RosterID: 1, MatchupID: &one, Points: 101.5},
+		{RosterID: 2, MatchupID: &one, Points: 88},
+		{RosterID: 4, MatchupID: &two, Points: 90},
+		{RosterID: 5}, // bye
+	}
+
+	final := serializeSleeperMatchups("99", 6, "2026-10-12", true, sides, map[int]sleeperTeam{})
+	if len(final) != 2 {
+		t.Fatalf("got %d matchups, want 2 (bye dropped)", len(final))
+	}
+	if w := final[0]["winner_team_key"].(*string); w == nil || *w != "sleeper.l.99.t.1" {
+		t.Errorf("winner = %v, want roster 1", w)
+	}
+	if final[1]["is_tied"] != true || final[1]["winner_team_key"].(*string) != nil {
+		t.Errorf("tied matchup = %v", final[1])
+	}
+	if final[0]["status"] != "postevent" || final[0]["week_end"] != "2026-10-12" {
+		t.Errorf("final matchup = %v", final[0])
+	}
+
+	live := serializeSleeperMatchups("99", 7, "", false, sides, map[int]sleeperTeam{})
+	if live[0]["status"] != "midevent" || live[0]["winner_team_key"].(*string) != nil {
+		t.Errorf("live matchup = %v", live[0])
+	}
+	if _, ok := live[0]["week_end"]; ok {
+		t.Error("week_end set without a season start date")
+	}
+}
+
+func TestSleeperWeekEnd(t *testing.T) {
+	if got := sleeperWeekEnd("2026-09-10", 1); got != "2026-09-14" {
+		t.Errorf("week 1 = %q, want 2026-09-14", got)
+	}
+	if got := sleeperWeekEnd("2026-09-10", 6); got != "2026-10-19" {
+		t.Errorf("week 6 = %q, want 2026-10-19", got)
+	}
+	if got := sleeperWeekEnd("", 3); got != "" {
+		t.Errorf("unknown start = %q, want empty", got)
+	}
+}
+
+func TestSleeperClientGetUser(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/alice":
+			w.Write([]byte(`{"user_id":"123","username":"alice","display_name":"Alice"}`))
+		case "/user/ghost":
+			w.Write([]byte(`null`))
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("SLEEPER_API_BASE_URL", srv.URL)
+
+	sc := NewSleeperClient()
+	u, err := sc.GetUser(context.Background(), "alice")
+	if err != nil || u.UserID != "123" {
+		t.Fatalf("GetUser(alice) = %+v, %v", u, err)
+	}
+	if _, err := sc.GetUser(context.Background(), "ghost"); !errors.Is(err, errSleeperNotFound) {
+		t.Errorf("GetUser(ghost) err = %v, want errSleeperNotFound", err)
+	}
+	if _, err := sc.GetUser(context.Background(), "broken"); err == nil || errors.Is(err, errSleeperNotFound) {
+		t.Errorf("GetUser(broken) err = %v, want HTTP error", err)
+	}
+}
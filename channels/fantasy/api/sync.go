@@ -237,6 +237,16 @@ func (a *App) syncUser(ctx context.Context, user yahooUser, clientID, clientSecr
 		return nil // nothing to sync
 	}
 
+	// Exchange the refresh token up front: a dead one would otherwise fail
+	// every call below. A definitive rejection is recorded so sync stops
+	// retrying it and the owner is asked to reconnect (token_status.go).
+	if err := client.ensureToken(ctx); err != nil {
+		if status := tokenStatusForError(err); status != "" {
+			a.markTokenDead(ctx, user.guid, status)
+		}
+		return fmt.Errorf("token refresh: %w", err)
+	}
+
 	// Fetch leagues from Yahoo across all game codes and recent seasons.
 	// Include currentYear+1 to catch Yahoo-side early rollover (e.g. 2026 NFL
 	// leagues created while it's still 2025 in real-world time, or MLB 2026
@@ -353,6 +363,7 @@ func (a *App) syncUser(ctx context.Context, user yahooUser, clientID, clientSecr
 					} else {
 						log.Printf("[Sync] Synced %d matchups for %s week %d", len(matchups), lk, wk)
 					}
+					a.archiveMatchups(ctx, lk, item.data, matchups)
 				}
 			}
 		}
@@ -462,6 +473,7 @@ func (a *App) fetchUserBatch(ctx context.Context, limit, offset int) ([]yahooUse
 	rows, err := a.db.Query(ctx,
 		`SELECT guid, logto_sub, refresh_token, last_sync
 		 FROM yahoo_users
+		 WHERE token_status = 'active'
 		 ORDER BY last_sync ASC NULLS FIRST
 		 LIMIT $1 OFFSET $2`,
 		limit, offset,
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// Yahoo Refresh-Token Reconciliation
+// =============================================================================
+
+// Yahoo refresh tokens die when the user revokes the app or goes long
+// enough without using it. Sync used to keep trying them every cycle and
+// log a failure nobody read, so the user's fantasy data just froze.
+//
+// startTokenReconciler exchanges each active token on a schedule. When
+// Yahoo answers invalid_grant the row's token_status flips to expired or
+// revoked (migration 000009), sync stops picking it up, GetYahooStatus
+// reports it, and the owner gets one SSE event asking them to reconnect.
+// Relinking (UpsertYahooUser) resets the row to active.
+
+const (
+	TokenStatusActive  = "active"
+	TokenStatusExpired = "expired"
+	TokenStatusRevoked = "revoked"
+
+	// TokenCheckInterval is how stale a row's last check must be before
+	// the reconciler exchanges its token again.
+	TokenCheckInterval = 6 * time.Hour
+
+	// TokenReconcileTick is how often the reconciler looks for stale rows.
+	TokenReconcileTick = 10 * time.Minute
+
+	// TokenCheckBatchSize caps the rows checked per tick.
+	TokenCheckBatchSize = 50
+
+	// TokenCheckSpacing paces token exchanges so a large batch doesn't
+	// burst Yahoo's OAuth endpoint.
+	TokenCheckSpacing = time.Second
+
+	// CoreUserTopicPrefix is core's per-user SSE topic (cdc:core:user:{sub}).
+	// Reauth prompts are published there as synthetic records.
+	CoreUserTopicPrefix = "cdc:core:user:"
+
+	// RedisEventSeqKey is core's SSE envelope sequence counter, bumped so
+	// clients that track gaps don't see a hole.
+	RedisEventSeqKey = "sse:seq"
+)
+
+// yahooTokenError is a non-200 answer from Yahoo's token endpoint.
+type yahooTokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+	body        string
+}
+
+func (e *yahooTokenError) Error() string {
+	return fmt.Sprintf("yahoo token refresh failed (status %d): %s", e.StatusCode, e.body)
+}
+
+// newYahooTokenError parses Yahoo's OAuth error body. Yahoo usually sends
+// the RFC 6749 shape ({"error":"invalid_grant","error_description":...})
+// but some failures nest it ({"error":{"errorId":...,"message":...}}).
+func newYahooTokenError(status int, body []byte) *yahooTokenError {
+	e := &yahooTokenError{StatusCode: status, body: string(body)}
+	var parsed struct {
+		Error       json.RawMessage `json:"error"`
+		Description string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &parsed) != nil {
+		return e
+	}
+	e.Description = parsed.Description
+	if json.Unmarshal(parsed.Error, &e.Code) != nil {
+		var nested struct {
+			ErrorID string `json:"errorId"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(parsed.Error, &nested) == nil {
+			e.Code = nested.ErrorID
+			if e.Description == "" {
+				e.Description = nested.Message
+			}
+		}
+	}
+	return e
+}
+
+// tokenStatusForError maps a token-exchange failure to the status it
+// proves, or "" when the failure says nothing about the token (network
+// errors, 5xx, or invalid_client from our own misconfiguration).
+func tokenStatusForError(err error) string {
+	var te *yahooTokenError
+	if !errors.As(err, &te) || te.StatusCode >= 500 {
+		return ""
+	}
+	switch strings.ToLower(te.Code) {
+	case "invalid_grant", "invalid_refresh_token":
+	default:
+		return ""
+	}
+	if strings.Contains(strings.ToLower(te.Description), "expire") {
+		return TokenStatusExpired
+	}
+	return TokenStatusRevoked
+}
+
+// startTokenReconciler checks stale active tokens every TokenReconcileTick
+// until ctx is cancelled. It runs whether or not SYNC_ENABLED is set:
+// GetYahooStatus should be truthful even when sync is paused.
+func (a *App) startTokenReconciler(ctx context.Context) {
+	clientID := os.Getenv("YAHOO_CLIENT_ID")
+	clientSecret := os.Getenv("YAHOO_CLIENT_SECRET")
+
+	ticker := time.NewTicker(TokenReconcileTick)
+	defer ticker.Stop()
+
+	for {
+		a.reconcileTokens(ctx, clientID, clientSecret)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileTokens checks up to TokenCheckBatchSize active rows whose last
+// check is older than TokenCheckInterval, oldest first.
+func (a *App) reconcileTokens(ctx context.Context, clientID, clientSecret string) {
+	rows, err := a.db.Query(ctx, `
+		SELECT guid, refresh_token FROM yahoo_users
+		WHERE token_status = 'active'
+		  AND (token_checked_at IS NULL OR token_checked_at < $1)
+		ORDER BY token_checked_at NULLS FIRST
+		LIMIT $2
+	`, time.Now().Add(-TokenCheckInterval), TokenCheckBatchSize)
+	if err != nil {
+		log.Printf("[TokenCheck] Failed to list tokens: %v", err)
+		return
+	}
+	type due struct{ guid, stored string }
+	var batch []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.guid, &d.stored); err == nil {
+			batch = append(batch, d)
+		}
+	}
+	rows.Close()
+
+	for i, d := range batch {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(TokenCheckSpacing):
+			}
+		}
+		a.checkYahooToken(ctx, d.guid, d.stored, clientID, clientSecret)
+	}
+	if len(batch) > 0 {
+		log.Printf("[TokenCheck] Checked %d Yahoo token(s)", len(batch))
+	}
+}
+
+// checkYahooToken exchanges one stored token and records the outcome.
+// Yahoo may rotate the refresh token on exchange, so a success persists
+// the new one — guarded on the old value in case sync rotated it first.
+func (a *App) checkYahooToken(ctx context.Context, guid, stored, clientID, clientSecret string) {
+	refreshToken, err := Decrypt(stored)
+	if err != nil {
+		// Not Yahoo's verdict; leave the row for an operator (encrypt-tokens).
+		log.Printf("[TokenCheck] Failed to decrypt token for %s: %v", guid, err)
+		return
+	}
+
+	client := NewYahooClient(clientID, clientSecret, refreshToken)
+	if err := client.ensureToken(ctx); err != nil {
+		if status := tokenStatusForError(err); status != "" {
+			a.markTokenDead(ctx, guid, status)
+			return
+		}
+		log.Printf("[TokenCheck] Inconclusive check for %s: %v", guid, err)
+		return
+	}
+
+	newStored := stored
+	if rotated := client.RefreshedToken(); rotated != refreshToken {
+		if enc, err := Encrypt(rotated); err == nil {
+			newStored = enc
+		} else {
+			log.Printf("[TokenCheck] Failed to encrypt rotated token for %s: %v", guid, err)
+		}
+	}
+	if _, err := a.db.Exec(ctx, `
+		UPDATE yahoo_users SET refresh_token = $3, token_checked_at = CURRENT_TIMESTAMP
+		WHERE guid = $1 AND refresh_token = $2
+	`, guid, stored, newStored); err != nil {
+		log.Printf("[TokenCheck] Failed to record check for %s: %v", guid, err)
+	}
+}
+
+// markTokenDead moves an active row to status and notifies its owner.
+// The UPDATE only matches active rows, so the prompt goes out once per
+// transition even if sync and the reconciler both notice.
+func (a *App) markTokenDead(ctx context.Context, guid, status string) {
+	var logtoSub string
+	err := a.db.QueryRow(ctx, `
+		UPDATE yahoo_users
+		SET token_status = $2, token_checked_at = CURRENT_TIMESTAMP, token_status_changed_at = CURRENT_TIMESTAMP
+		WHERE guid = $1 AND token_status = 'active'
+		RETURNING COALESCE(logto_sub, '')
+	`, guid, status).Scan(&logtoSub)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no rows") {
+			log.Printf("[TokenCheck] Failed to mark %s %s: %v", guid, status, err)
+		}
+		return
+	}
+	log.Printf("[TokenCheck] Yahoo token for %s is %s; prompting reconnect", guid, status)
+
+	// Legacy GUID-as-sub rows have no Scrollr owner to notify.
+	if logtoSub == "" || isLegacyYahooLink(guid, logtoSub) {
+		return
+	}
+	a.publishReauthRequired(ctx, logtoSub, status)
+}
+
+// YahooReauthEvent is the SSE record asking a user to reconnect Yahoo.
+type YahooReauthEvent struct {
+	Event       string    `json:"event"`
+	TokenStatus string    `json:"token_status"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// publishReauthRequired sends a yahoo_token_events record on the owner's
+// core topic (publishUserRecord, matchup_live.go).
+func (a *App) publishReauthRequired(ctx context.Context, logtoSub, status string) {
+	a.publishUserRecord(ctx, logtoSub, "yahoo_token_events", YahooReauthEvent{
+		Event:       "yahoo_reauth_required",
+		TokenStatus: status,
+		DetectedAt:  time.Now().UTC(),
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTokenStatusForError(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"revoked", 400, `{"error":"invalid_grant","error_description":"token has been revoked"}`, TokenStatusRevoked},
+		{"expired", 400, `{"error":"invalid_grant","error_description":"refresh token expired"}`, TokenStatusExpired},
+		{"nested yahoo shape", 401, `{"error":{"errorId":"INVALID_REFRESH_TOKEN","message":"Invalid refresh token"}}`, TokenStatusRevoked},
+		{"our client config", 401, `{"error":"invalid_client"}`, ""},
+		{"yahoo outage", 503, `{"error":"invalid_grant"}`, ""},
+		{"html error page", 400, `<html>Bad Request</html>`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", newYahooTokenError(tc.status, []byte(tc.body)))
+			if got := tokenStatusForError(err); got != tc.want {
+				t.Errorf("tokenStatusForError = %q; want %q", got, tc.want)
+			}
+		})
+	}
+	if tokenStatusForError(fmt.Errorf("dial tcp: connection refused")) != "" {
+		t.Error("network error classified as a token verdict")
+	}
+}
+
+func TestRefreshAccessTokenTypedError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant","error_description":"revoked"}`))
+	}))
+	defer srv.Close()
+	t.Setenv("YAHOO_TOKEN_URL", srv.URL)
+
+	err := NewYahooClient("id", "secret", "dead").ensureToken(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("err = %v", err)
+	}
+	if tokenStatusForError(err) != TokenStatusRevoked {
+		t.Errorf("status = %q; want revoked", tokenStatusForError(err))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -38,6 +39,10 @@ import (
 //     URL externally.
 //   - Otherwise (HTML / wildcard) → 307 redirect to Yahoo. Used when a
 //     logged-in browser session hits the URL directly.
+//
+// `?scope=write` requests fspt-w instead of the default read-only scope.
+// It's the re-consent path for lineup changes; the granted scope is
+// recorded against the state and persisted by the callback.
 func (a *App) YahooStart(c *fiber.Ctx) error {
 	logtoSub := GetUserSub(c)
 	if logtoSub == "" {
@@ -59,10 +64,16 @@ func (a *App) YahooStart(c *fiber.Ctx) error {
 	}
 	state := fmt.Sprintf("%x", b)
 
-	// Store state and logto_sub mapping
+	scope := YahooScopeRead
+	if c.Query("scope") == "write" {
+		scope = YahooScopeWrite
+	}
+
+	// Store state, logto_sub and requested scope mappings
 	pipe := a.rdb.Pipeline()
 	pipe.Set(context.Background(), RedisCSRFPrefix+state, "1", OAuthStateExpiry)
 	pipe.Set(context.Background(), RedisYahooStateLogtoPrefix+state, logtoSub, OAuthStateExpiry)
+	pipe.Set(context.Background(), RedisYahooStateScopePrefix+state, scope, OAuthStateExpiry)
 	_, err := pipe.Exec(context.Background())
 	if err != nil {
 		log.Printf("[YahooStart] Redis pipeline failed: %v", err)
@@ -71,7 +82,11 @@ func (a *App) YahooStart(c *fiber.Ctx) error {
 
 	// Force Yahoo to show the login screen every time so the user can pick
 	// the correct Yahoo account.
-	authURL := a.yahooConfig.AuthCodeURL(state, oauth2.SetAuthURLParam("prompt", "login"))
+	authOpts := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("prompt", "login")}
+	if scope == YahooScopeWrite {
+		authOpts = append(authOpts, oauth2.SetAuthURLParam("scope", YahooScopeWrite))
+	}
+	authURL := a.yahooConfig.AuthCodeURL(state, authOpts...)
 
 	// Content negotiation: JSON for programmatic callers (desktop app),
 	// 307 redirect for browser navigations.
@@ -119,6 +134,13 @@ func (a *App) YahooCallback(c *fiber.Ctx) error {
 		log.Printf("[YahooCallback] Retrieved logto_sub=%s for state=%s…", logtoSub, state[:8])
 	}
 
+	// Scope requested at /yahoo/start. A missing key (pre-upgrade state or
+	// expired) falls back to read-only, the conservative assumption.
+	scope, err := a.rdb.GetDel(context.Background(), RedisYahooStateScopePrefix+state).Result()
+	if err != nil || scope != YahooScopeWrite {
+		scope = YahooScopeRead
+	}
+
 	// Yahoo intermittently rejects the first token exchange with INVALID_REDIRECT_URI
 	// even when the redirect URI is correct. Retry once after a brief delay.
 	log.Printf("[YahooCallback] Exchanging code for token (redirect_uri=%s)…", a.yahooConfig.RedirectURL)
@@ -146,12 +168,15 @@ func (a *App) YahooCallback(c *fiber.Ctx) error {
 	if token.RefreshToken != "" {
 		// Fetch GUID and persist — synchronous so we can return an error page
 		// if linking fails.
-		log.Printf("[YahooCallback] Linking Yahoo account (logto_sub=%s)…", logtoSub)
-		linkErr := a.fetchAndLinkYahooUser(token.AccessToken, token.RefreshToken, logtoSub)
+		log.Printf("[YahooCallback] Linking Yahoo account (logto_sub=%s scope=%s)…", logtoSub, scope)
+		linkErr := a.fetchAndLinkYahooUser(token.AccessToken, token.RefreshToken, logtoSub, scope)
 		if linkErr != nil {
 			log.Printf("[YahooCallback] Failed to link Yahoo account: %v", linkErr)
 
 			userMsg := "Yahoo authentication succeeded, but we failed to link your account. Please try again."
+			if errors.Is(linkErr, errNoScrollrSession) {
+				userMsg = "Your Scrollr sign-in expired before Yahoo finished. Please connect Yahoo again from Scrollr."
+			}
 
 			html := fmt.Sprintf(`<!doctype html><html><head><meta charset="utf-8"><title>Auth Error</title></head>
 				<body style="font-family: ui-sans-serif, system-ui; max-width: 420px; margin: 2rem auto; line-height: 1.5;">
@@ -162,6 +187,7 @@ func (a *App) YahooCallback(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusConflict).SendString(html)
 		}
 		log.Printf("[YahooCallback] Yahoo account linked successfully")
+		a.recordAudit(context.Background(), logtoSub, logtoSub, AuditYahooLinked, nil)
 	} else {
 		log.Println("[YahooCallback] Warning: No refresh token received from Yahoo")
 	}
@@ -182,8 +208,11 @@ func (a *App) YahooCallback(c *fiber.Ctx) error {
 
 // fetchAndLinkYahooUser fetches the Yahoo GUID for the given access token,
 // upserts the yahoo_users row, and populates the Redis guid→user CDC set.
-func (a *App) fetchAndLinkYahooUser(accessToken, refreshToken, logtoSub string) error {
+func (a *App) fetchAndLinkYahooUser(accessToken, refreshToken, logtoSub, scope string) error {
 	log.Printf("[fetchAndLinkYahooUser] Starting — logto_sub=%s access_token_len=%d", logtoSub, len(accessToken))
+	if logtoSub == "" {
+		return errNoScrollrSession
+	}
 
 	client := &http.Client{Timeout: YahooAPITimeout}
 	req, err := http.NewRequest("GET", getYahooBaseURL()+"/users;use_login=1", nil)
@@ -228,28 +257,35 @@ func (a *App) fetchAndLinkYahooUser(accessToken, refreshToken, logtoSub string)
 		return fmt.Errorf("empty GUID in Yahoo response")
 	}
 
-	// Use the logto_sub if available, otherwise fall back to Yahoo GUID
 	logtoIdentifier := logtoSub
-	if logtoIdentifier == "" {
-		log.Printf("[fetchAndLinkYahooUser] No logto_sub, falling back to GUID=%s as identifier", guid)
-		logtoIdentifier = guid
-	}
 
 	// If this Yahoo account is linked to a *different* Scrollr user, take over.
 	// The person authenticating with Yahoo IS the account owner — they just
 	// proved it via OAuth — so they should control where it's linked.
+	// A legacy link (GUID stored as logto_sub, see yahoo_relink.go) is kept
+	// and moved to this user by the upsert below, so its imported leagues
+	// survive the relink.
 	var existingSub string
+	legacyLink := false
 	checkErr := a.db.QueryRow(context.Background(),
 		"SELECT logto_sub FROM yahoo_users WHERE guid = $1", guid,
 	).Scan(&existingSub)
 	if checkErr == nil && existingSub != logtoIdentifier {
-		log.Printf("[fetchAndLinkYahooUser] Takeover — Yahoo GUID %s was linked to logto_sub=%s, reassigning to logto_sub=%s",
-			guid, existingSub, logtoIdentifier)
 		a.CleanupLeagueSubscribers(context.Background(), guid, existingSub)
-		_, delErr := a.db.Exec(context.Background(),
-			"DELETE FROM yahoo_users WHERE guid = $1", guid)
-		if delErr != nil {
-			log.Printf("[fetchAndLinkYahooUser] Warning: failed to delete old link for takeover guid=%s: %v", guid, delErr)
+		if isLegacyYahooLink(guid, existingSub) {
+			log.Printf("[fetchAndLinkYahooUser] Relink — legacy Yahoo GUID %s claimed by logto_sub=%s", guid, logtoIdentifier)
+			legacyLink = true
+		} else {
+			log.Printf("[fetchAndLinkYahooUser] Takeover — Yahoo GUID %s was linked to logto_sub=%s, reassigning to logto_sub=%s",
+				guid, existingSub, logtoIdentifier)
+			_, delErr := a.db.Exec(context.Background(),
+				"DELETE FROM yahoo_users WHERE guid = $1", guid)
+			if delErr != nil {
+				log.Printf("[fetchAndLinkYahooUser] Warning: failed to delete old link for takeover guid=%s: %v", guid, delErr)
+			} else {
+				a.recordAudit(context.Background(), "system", existingSub, AuditYahooUnlinked,
+					map[string]any{"reason": "linked_to_another_account"})
+			}
 		}
 	}
 
@@ -274,12 +310,17 @@ func (a *App) fetchAndLinkYahooUser(accessToken, refreshToken, logtoSub string)
 	}
 
 	log.Printf("[fetchAndLinkYahooUser] Upserting user — guid=%s logto_sub=%s", guid, logtoIdentifier)
-	if err := a.UpsertYahooUser(guid, logtoIdentifier, refreshToken); err != nil {
+	if err := a.UpsertYahooUser(guid, logtoIdentifier, refreshToken, scope); err != nil {
 		return fmt.Errorf("upsert Yahoo user: %w", err)
 	}
 
 	log.Printf("[Yahoo Sync] Registered user %s (Logto: %s) for active sync", guid, logtoIdentifier)
 
+	if legacyLink {
+		a.markYahooRelinked(context.Background(), guid, logtoIdentifier)
+		a.invalidateLeagueCache(context.Background(), guid)
+	}
+
 	// Restore Redis league subscriber sets for any previously-imported leagues
 	if err := a.PopulateLeagueSubscribers(context.Background(), guid, logtoIdentifier); err != nil {
 		log.Printf("[fetchAndLinkYahooUser] Warning: failed to populate league subscribers: %v", err)
@@ -304,14 +345,21 @@ func (a *App) GetYahooStatus(c *fiber.Ctx) error {
 	}
 
 	var lastSync sql.NullTime
+	var scope, tokenStatus string
 	err := a.db.QueryRow(context.Background(), `
-		SELECT last_sync FROM yahoo_users WHERE logto_sub = $1
-	`, userID).Scan(&lastSync)
+		SELECT last_sync, scope, token_status FROM yahoo_users WHERE logto_sub = $1
+	`, userID).Scan(&lastSync, &scope, &tokenStatus)
 
 	if err != nil {
 		errStr := err.Error()
 		if err == sql.ErrNoRows || strings.Contains(errStr, "no rows") {
-			return c.JSON(YahooStatusResponse{Connected: false, Synced: false})
+			// An operator may have matched a legacy link to this user;
+			// prompt them to reconnect so it can move to their account.
+			relink, relinkErr := a.pendingYahooRelink(context.Background(), userID)
+			if relinkErr != nil {
+				log.Printf("[GetYahooStatus] Relink lookup failed for logto_sub=%s: %v", userID, relinkErr)
+			}
+			return c.JSON(YahooStatusResponse{Connected: false, Synced: false, RelinkRequired: relink})
 		}
 		log.Printf("[GetYahooStatus] DB error for logto_sub=%s: %v", userID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -321,8 +369,11 @@ func (a *App) GetYahooStatus(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(YahooStatusResponse{
-		Connected: true,
-		Synced:    lastSync.Valid,
+		Connected:      true,
+		Synced:         lastSync.Valid,
+		CanWrite:       scope == YahooScopeWrite,
+		TokenStatus:    tokenStatus,
+		ReauthRequired: tokenStatus != TokenStatusActive,
 	})
 }
 
@@ -511,16 +562,16 @@ func (a *App) ImportYahooLeague(c *fiber.Ctx) error {
 			})
 		}
 		if !alreadyLinked {
-			var currentCount int
-			if err := a.db.QueryRow(context.Background(),
-				"SELECT count(*) FROM yahoo_user_leagues WHERE guid = $1", guid,
-			).Scan(&currentCount); err != nil {
+			// Sleeper leagues share the cap
+			yahooCount, sleeperCount, err := a.countUserLeagues(context.Background(), userID)
+			if err != nil {
 				log.Printf("[Import] Failed to count leagues for guid=%s: %v", guid, err)
 				return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 					Status: "error",
 					Error:  "Failed to verify league count",
 				})
 			}
+			currentCount := yahooCount + sleeperCount
 			if currentCount >= cap {
 				log.Printf("[Import] Tier cap reached — guid=%s tier=%s current=%d cap=%d", guid, tier, currentCount, cap)
 				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -667,6 +718,7 @@ func (a *App) ImportYahooLeague(c *fiber.Ctx) error {
 					} else {
 						log.Printf("[Import] Synced %d matchups for %s week %d", len(matchups), incoming.LeagueKey, wk)
 					}
+					a.archiveMatchups(ctx, incoming.LeagueKey, targetLeague, matchups)
 				}
 			}
 		}
@@ -783,5 +835,6 @@ func (a *App) DisconnectYahoo(c *fiber.Ctx) error {
 	}
 
 	log.Printf("[DisconnectYahoo] User %s disconnected Yahoo (GUID: %s)", userID, guid)
+	a.recordAudit(context.Background(), userID, userID, AuditYahooUnlinked, nil)
 	return c.JSON(fiber.Map{"status": "ok", "message": "Yahoo account disconnected"})
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"encoding/xml"
@@ -128,7 +129,7 @@ func (yc *YahooClient) refreshAccessToken(ctx context.Context) error {
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("yahoo token refresh failed (status %d): %s", resp.StatusCode, string(body))
+		return newYahooTokenError(resp.StatusCode, body)
 	}
 
 	var tokenResp struct {
@@ -206,6 +207,44 @@ func (yc *YahooClient) makeRequest(ctx context.Context, urlPath string) ([]byte,
 	return body, nil
 }
 
+// makeWriteRequest sends an authenticated XML write (PUT/POST) to the Yahoo
+// Fantasy API. Yahoo answers successful writes with 200 or 201.
+func (yc *YahooClient) makeWriteRequest(ctx context.Context, method, urlPath string, xmlBody []byte) ([]byte, error) {
+	if err := yc.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, getYahooBaseURL()+"/"+urlPath, bytes.NewReader(xmlBody))
+	if err != nil {
+		return nil, fmt.Errorf("yahoo request build: %w", err)
+	}
+
+	yc.mu.Lock()
+	token := yc.accessToken
+	yc.mu.Unlock()
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", yahooUA)
+	req.Header.Set("Content-Type", "application/xml")
+
+	resp, err := yc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("yahoo request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("yahoo read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("yahoo API error (status %d) for %s %s: %s", resp.StatusCode, method, urlPath, truncate(string(body), 200))
+	}
+
+	return body, nil
+}
+
 // withRetry wraps a function with exponential backoff retry and per-user API delay.
 func (yc *YahooClient) withRetry(ctx context.Context, label string, fn func() error) error {
 	var lastErr error
@@ -551,6 +590,90 @@ func (yc *YahooClient) GetTeamDailyStats(
 	return out, nil
 }
 
+// GetRosterPositions returns the league's lineup slots as position → count
+// (e.g. {"QB": 1, "WR": 3, "BN": 6}).
+func (yc *YahooClient) GetRosterPositions(ctx context.Context, leagueKey string) (map[string]int, error) {
+	urlPath := fmt.Sprintf("league/%s/settings", leagueKey)
+
+	var xmlBody []byte
+	err := yc.withRetry(ctx, fmt.Sprintf("roster-positions(%s)", leagueKey), func() error {
+		var reqErr error
+		xmlBody, reqErr = yc.makeRequest(ctx, urlPath)
+		return reqErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FantasyContent
+	if err := xml.Unmarshal(xmlBody, &fc); err != nil {
+		return nil, fmt.Errorf("parse league settings XML: %w", err)
+	}
+
+	slots := map[string]int{}
+	if fc.League == nil || fc.League.Settings == nil {
+		return slots, nil
+	}
+	for _, rp := range fc.League.Settings.RosterPositions.RosterPosition {
+		if rp.Position == "" {
+			continue
+		}
+		slots[rp.Position] += safeAtoi(rp.Count)
+	}
+	return slots, nil
+}
+
+// SetLineup moves players into the given positions for one week (NFL) or
+// one date (everything else). Requires a token granted fspt-w.
+// PUT https://fantasysports.yahooapis.com/fantasy/v2/team/{team_key}/roster
+//
+// Not retried: a timed-out PUT may still have been applied, and Yahoo
+// rejects the replay if a player's game has since locked.
+func (yc *YahooClient) SetLineup(ctx context.Context, teamKey, coverageType, coverage string, changes []LineupChange) error {
+	body, err := buildLineupXML(coverageType, coverage, changes)
+	if err != nil {
+		return err
+	}
+	_, err = yc.makeWriteRequest(ctx, "PUT", fmt.Sprintf("team/%s/roster", teamKey), body)
+	return err
+}
+
+// buildLineupXML renders the roster PUT payload Yahoo expects.
+func buildLineupXML(coverageType, coverage string, changes []LineupChange) ([]byte, error) {
+	type xmlPlayer struct {
+		PlayerKey string `xml:"player_key"`
+		Position  string `xml:"position"`
+	}
+	type xmlRoster struct {
+		CoverageType string      `xml:"coverage_type"`
+		Week         string      `xml:"week,omitempty"`
+		Date         string      `xml:"date,omitempty"`
+		Players      []xmlPlayer `xml:"players>player"`
+	}
+	payload := struct {
+		XMLName xml.Name  `xml:"fantasy_content"`
+		Roster  xmlRoster `xml:"roster"`
+	}{Roster: xmlRoster{CoverageType: coverageType}}
+
+	switch coverageType {
+	case "week":
+		payload.Roster.Week = coverage
+	case "date":
+		payload.Roster.Date = coverage
+	default:
+		return nil, fmt.Errorf("unknown coverage type %q", coverageType)
+	}
+	for _, ch := range changes {
+		payload.Roster.Players = append(payload.Roster.Players, xmlPlayer{PlayerKey: ch.PlayerKey, Position: ch.Position})
+	}
+
+	out, err := xml.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal lineup XML: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
 // GetUserGUID fetches the authenticated user's Yahoo GUID.
 func (yc *YahooClient) GetUserGUID(ctx context.Context) (string, error) {
 	xmlBody, err := yc.makeRequest(ctx, "users;use_login=1")
@@ -596,6 +719,7 @@ func serializeLeague(l XMLLeague, gameCode string) map[string]any {
 		"is_finished":  isFinished,
 		"season":       season,
 		"game_code":    gameCode,
+		"renew":        l.Renew,
 	}
 }
 
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Legacy Yahoo Links (GUID stored as logto_sub)
+// =============================================================================
+
+// Before the callback required a Scrollr session, a Yahoo link whose OAuth
+// state had expired was saved with the Yahoo GUID as its logto_sub. The
+// owner's status and league lookups (all keyed by their real sub) find
+// nothing, though the row keeps syncing.
+//
+// Reconciliation (migration 000008):
+//
+//   - detectLegacyYahooLinks records every such row in yahoo_relink_requests.
+//     It runs at startup and on each GET of the admin route.
+//   - An operator sets candidate_sub to the account they believe owns the
+//     link (from a support ticket, say). GetYahooStatus then reports
+//     relink_required to that user, and the client prompts them to
+//     reconnect Yahoo.
+//   - Reconnecting is the verified mapping: fetchAndLinkYahooUser moves the
+//     legacy row to the user's sub in place, keeping its imported leagues,
+//     and marks the request migrated. Nothing else moves a row; the
+//     operator's candidate only decides who sees the prompt.
+//
+// Admin routes (proxied by the core gateway, super_user only):
+//
+//   - GET /admin/fantasy/yahoo-relink — detect, then list every request
+//   - PUT /admin/fantasy/yahoo-relink — set or clear a request's candidate
+
+// errNoScrollrSession is returned when a Yahoo callback arrives without the
+// Scrollr sub captured at /yahoo/start. Linking anyway would create a new
+// GUID-as-sub row.
+var errNoScrollrSession = errors.New("no Scrollr session for this Yahoo link; start again from Scrollr")
+
+// isLegacyYahooLink reports whether a yahoo_users row stores the GUID in
+// place of a Logto sub.
+func isLegacyYahooLink(guid, logtoSub string) bool {
+	return guid != "" && logtoSub == guid
+}
+
+// detectLegacyYahooLinks records any legacy rows not yet tracked and
+// returns how many were new.
+func (a *App) detectLegacyYahooLinks(ctx context.Context) (int64, error) {
+	tag, err := a.db.Exec(ctx, `
+		INSERT INTO yahoo_relink_requests (guid)
+		SELECT guid FROM yahoo_users WHERE logto_sub = guid
+		ON CONFLICT (guid) DO NOTHING
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// markYahooRelinked closes a legacy link's request after the row moved to
+// logtoSub. Links nobody had detected yet are recorded as migrated too, so
+// the admin list stays a complete history.
+func (a *App) markYahooRelinked(ctx context.Context, guid, logtoSub string) {
+	_, err := a.db.Exec(ctx, `
+		INSERT INTO yahoo_relink_requests (guid, migrated_sub, migrated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (guid) DO UPDATE
+		SET migrated_sub = EXCLUDED.migrated_sub, migrated_at = EXCLUDED.migrated_at
+	`, guid, logtoSub)
+	if err != nil {
+		log.Printf("[YahooRelink] Failed to mark guid=%s migrated: %v", guid, err)
+		return
+	}
+	log.Printf("[YahooRelink] Migrated legacy link guid=%s to logto_sub=%s", guid, logtoSub)
+}
+
+// pendingYahooRelink reports whether an operator has matched an
+// unmigrated legacy link to userID, stamping notified_at the first time.
+func (a *App) pendingYahooRelink(ctx context.Context, userID string) (bool, error) {
+	var guid string
+	err := a.db.QueryRow(ctx, `
+		UPDATE yahoo_relink_requests
+		SET notified_at = COALESCE(notified_at, CURRENT_TIMESTAMP)
+		WHERE candidate_sub = $1 AND migrated_at IS NULL
+		RETURNING guid
+	`, userID).Scan(&guid)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// startLegacyYahooDetection logs how many legacy links exist at startup so
+// the backlog is visible without calling the admin route.
+func (a *App) startLegacyYahooDetection(ctx context.Context) {
+	added, err := a.detectLegacyYahooLinks(ctx)
+	if err != nil {
+		log.Printf("[YahooRelink] Detection failed: %v", err)
+		return
+	}
+	var pending int
+	if err := a.db.QueryRow(ctx,
+		"SELECT count(*) FROM yahoo_relink_requests WHERE migrated_at IS NULL").Scan(&pending); err != nil {
+		log.Printf("[YahooRelink] Count failed: %v", err)
+		return
+	}
+	if pending > 0 {
+		log.Printf("[YahooRelink] %d legacy Yahoo link(s) awaiting relink (%d newly detected)", pending, added)
+	}
+}
+
+// =============================================================================
+// Admin Routes
+// =============================================================================
+
+// YahooRelinkRequest is one legacy link as the admin routes report it.
+type YahooRelinkRequest struct {
+	GUID         string     `json:"guid"`
+	CandidateSub string     `json:"candidate_sub,omitempty"`
+	Leagues      int        `json:"leagues"`
+	DetectedAt   *time.Time `json:"detected_at,omitempty"`
+	NotifiedAt   *time.Time `json:"notified_at,omitempty"`
+	MigratedSub  string     `json:"migrated_sub,omitempty"`
+	MigratedAt   *time.Time `json:"migrated_at,omitempty"`
+}
+
+// requireSuperUser rejects callers the gateway didn't identify as super
+// users. Returns false after writing the response.
+func requireSuperUser(c *fiber.Ctx) bool {
+	if GetUserSub(c) == "" {
+		c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+		return false
+	}
+	if GetUserTier(c) != TierSuperUser {
+		c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "Admin access required",
+		})
+		return false
+	}
+	return true
+}
+
+// adminListYahooRelinks detects new legacy links, then lists every relink
+// request, pending first.
+func (a *App) adminListYahooRelinks(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	ctx := c.UserContext()
+	added, err := a.detectLegacyYahooLinks(ctx)
+	if err != nil {
+		log.Printf("[YahooRelink] Detection failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to detect legacy Yahoo links",
+		})
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT r.guid, r.candidate_sub, r.detected_at, r.notified_at, r.migrated_sub, r.migrated_at,
+		       (SELECT count(*) FROM yahoo_user_leagues ul WHERE ul.guid = r.guid)
+		FROM yahoo_relink_requests r
+		ORDER BY r.migrated_at IS NOT NULL, r.detected_at
+	`)
+	if err != nil {
+		log.Printf("[YahooRelink] List failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list Yahoo relink requests",
+		})
+	}
+	defer rows.Close()
+
+	requests := []YahooRelinkRequest{}
+	pending := 0
+	for rows.Next() {
+		var r YahooRelinkRequest
+		var candidate, migrated sql.NullString
+		if err := rows.Scan(&r.GUID, &candidate, &r.DetectedAt, &r.NotifiedAt, &migrated, &r.MigratedAt, &r.Leagues); err != nil {
+			log.Printf("[YahooRelink] Scan failed: %v", err)
+			continue
+		}
+		r.CandidateSub = candidate.String
+		r.MigratedSub = migrated.String
+		if r.MigratedAt == nil {
+			pending++
+		}
+		requests = append(requests, r)
+	}
+
+	return c.JSON(fiber.Map{
+		"requests":       requests,
+		"pending":        pending,
+		"newly_detected": added,
+	})
+}
+
+// adminSetYahooRelinkCandidate records which Scrollr account should be
+// prompted to relink a legacy link. An empty candidate_sub clears it.
+func (a *App) adminSetYahooRelinkCandidate(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	var req struct {
+		GUID         string `json:"guid"`
+		CandidateSub string `json:"candidate_sub"`
+	}
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.GUID) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "guid is required",
+		})
+	}
+	candidate := strings.TrimSpace(req.CandidateSub)
+	if candidate == req.GUID {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "candidate_sub must be a Scrollr account, not the Yahoo GUID",
+		})
+	}
+
+	tag, err := a.db.Exec(c.UserContext(), `
+		UPDATE yahoo_relink_requests
+		SET candidate_sub = NULLIF($2, ''), notified_at = NULL
+		WHERE guid = $1 AND migrated_at IS NULL
+	`, req.GUID, candidate)
+	if err != nil {
+		log.Printf("[YahooRelink] Set candidate failed for guid=%s: %v", req.GUID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update relink request",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "No pending relink request for that guid",
+		})
+	}
+	log.Printf("[YahooRelink] Candidate for guid=%s set to %q", req.GUID, candidate)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
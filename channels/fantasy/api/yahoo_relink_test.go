@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIsLegacyYahooLink(t *testing.T) {
+	cases := []struct {
+		guid, sub string
+		want      bool
+	}{
+		{"ABCDEF1234567890QRSTUVWXYZ", "ABCDEF1234567890QRSTUVWXYZ", true},
+		{"ABCDEF1234567890QRSTUVWXYZ", "u1a2b3c4d5e6", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		if got := isLegacyYahooLink(tc.guid, tc.sub); got != tc.want {
+			t.Errorf("isLegacyYahooLink(%q, %q) = %v, want %v", tc.guid, tc.sub, got, tc.want)
+		}
+	}
+}
+
+// TestFetchAndLinkYahooUser_RequiresScrollrSession guards the root cause of
+// legacy links: a callback with no sub must fail before touching Yahoo or
+// the database, instead of falling back to the GUID.
+func TestFetchAndLinkYahooUser_RequiresScrollrSession(t *testing.T) {
+	a := &App{}
+	err := a.fetchAndLinkYahooUser("access", "refresh", "", YahooScopeRead)
+	if !errors.Is(err, errNoScrollrSession) {
+		t.Fatalf("err = %v, want errNoScrollrSession", err)
+	}
+}
+
+func TestYahooRelinkAdminRequiresSuperUser(t *testing.T) {
+	a := &App{}
+	app := fiber.New()
+	app.Get("/admin/fantasy/yahoo-relink", a.adminListYahooRelinks)
+
+	cases := []struct {
+		sub, tier string
+		want      int
+	}{
+		{"", "", fiber.StatusUnauthorized},
+		{"user-1", TierUplinkUltimate, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/admin/fantasy/yahoo-relink", nil)
+		if tc.sub != "" {
+			req.Header.Set("X-User-Sub", tc.sub)
+		}
+		if tc.tier != "" {
+			req.Header.Set("X-User-Tier", tc.tier)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("sub=%q tier=%q: status = %d, want %d", tc.sub, tc.tier, resp.StatusCode, tc.want)
+		}
+	}
+}
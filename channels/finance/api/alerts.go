@@ -0,0 +1,764 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// Price Alerts
+// =============================================================================
+//
+// Users define alerts under /users/me/alerts: a symbol plus a condition
+// (price above / below a threshold, or a daily move of at least ±N%).
+// The watcher listens to core's cdc:finance:{SYMBOL} topics — the same
+// trade updates the ticker streams — and when an alert's condition
+// becomes true it publishes an event on the owner's SSE topic and, if the
+// alert has one, POSTs a signed payload to its webhook.
+//
+// Alerts are edge-triggered through price_alerts.armed: firing disarms,
+// and the watcher re-arms an alert once its condition is false again, so
+// a price hovering above a threshold notifies once rather than on every
+// tick. Every replica watches every topic; the disarming UPDATE only
+// succeeds for one of them.
+
+const (
+	// FinanceCDCTopicPrefix is core's pub/sub topic for trade CDC events
+	// (cdc:finance:{SYMBOL}). Known here by convention only.
+	FinanceCDCTopicPrefix = "cdc:finance:"
+
+	// CoreUserTopicPrefix is core's per-user SSE topic
+	// (cdc:core:user:{logto_sub}); alert events are published there.
+	CoreUserTopicPrefix = "cdc:core:user:"
+
+	// RedisEventSeqKey is core's SSE envelope sequence counter. Alert
+	// events take a number from it so clients' gap detection still holds.
+	RedisEventSeqKey = "sse:seq"
+
+	// AlertRefreshInterval is how often each replica reloads the alert
+	// index, picking up alerts created or deleted through other replicas.
+	AlertRefreshInterval = 30 * time.Second
+
+	// AlertWebhookTimeout bounds one webhook delivery.
+	AlertWebhookTimeout = 5 * time.Second
+
+	// MaxAlertWebhooksInFlight caps concurrent webhook deliveries per
+	// replica; deliveries beyond it are dropped and logged.
+	MaxAlertWebhooksInFlight = 16
+
+	// MaxAlertWebhookURLLength caps webhook_url.
+	MaxAlertWebhookURLLength = 2048
+
+	// MaxAlertChangePct caps a change_pct threshold.
+	MaxAlertChangePct = 100
+
+	// AlertSignatureHeader carries "sha256=<hex HMAC of the body>",
+	// keyed with the webhook_secret returned when the alert was created.
+	AlertSignatureHeader = "X-Scrollr-Signature"
+)
+
+// Alert conditions.
+const (
+	AlertAbove     = "above"
+	AlertBelow     = "below"
+	AlertChangePct = "change_pct"
+)
+
+// PriceAlert is one alert as returned by /users/me/alerts. The webhook
+// secret is only returned once, by POST.
+type PriceAlert struct {
+	ID              int64      `json:"id"`
+	Symbol          string     `json:"symbol"`
+	Condition       string     `json:"condition"`
+	Threshold       float64    `json:"threshold"`
+	WebhookURL      *string    `json:"webhook_url,omitempty"`
+	WebhookSecret   string     `json:"webhook_secret,omitempty"`
+	Armed           bool       `json:"armed"`
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AlertEvent is what a firing alert sends to SSE and to its webhook.
+type AlertEvent struct {
+	Event            string    `json:"event"` // always "price_alert"
+	AlertID          int64     `json:"alert_id"`
+	Symbol           string    `json:"symbol"`
+	Condition        string    `json:"condition"`
+	Threshold        float64   `json:"threshold"`
+	Price            float64   `json:"price"`
+	PercentageChange float64   `json:"percentage_change"`
+	TriggeredAt      time.Time `json:"triggered_at"`
+}
+
+// createAlertRequest is the body of POST /users/me/alerts.
+type createAlertRequest struct {
+	Symbol     string  `json:"symbol"`
+	Condition  string  `json:"condition"`
+	Threshold  float64 `json:"threshold"`
+	WebhookURL string  `json:"webhook_url"`
+}
+
+// validateAlertRequest normalises req in place.
+func validateAlertRequest(req *createAlertRequest) error {
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if req.Symbol == "" || len(req.Symbol) > 30 {
+		return errors.New("symbol is required")
+	}
+	if math.IsNaN(req.Threshold) || math.IsInf(req.Threshold, 0) || req.Threshold <= 0 {
+		return errors.New("threshold must be a positive number")
+	}
+	switch req.Condition {
+	case AlertAbove, AlertBelow:
+		if req.Threshold >= 1e8 {
+			return errors.New("threshold is out of range")
+		}
+	case AlertChangePct:
+		if req.Threshold > MaxAlertChangePct {
+			return fmt.Errorf("change_pct threshold must be at most %d", MaxAlertChangePct)
+		}
+	default:
+		return errors.New("condition must be one of above, below, change_pct")
+	}
+
+	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
+	if req.WebhookURL != "" {
+		if err := validateAlertWebhookURL(req.WebhookURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAlertWebhookURL accepts absolute https URLs without
+// credentials. Private and loopback targets are refused again at dial
+// time, once the host has resolved.
+func validateAlertWebhookURL(raw string) error {
+	if len(raw) > MaxAlertWebhookURLLength {
+		return fmt.Errorf("webhook_url must be at most %d characters", MaxAlertWebhookURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("webhook_url must be an absolute https URL")
+	}
+	if u.User != nil {
+		return errors.New("webhook_url must not contain credentials")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicAddress(ip) {
+		return errors.New("webhook_url must point to a public address")
+	}
+	return nil
+}
+
+// conditionMet reports whether a trade at price, pct% on the day,
+// satisfies an alert.
+func conditionMet(condition string, threshold, price, pct float64) bool {
+	switch condition {
+	case AlertAbove:
+		return price > threshold
+	case AlertBelow:
+		return price > 0 && price < threshold
+	case AlertChangePct:
+		return math.Abs(pct) >= threshold
+	default:
+		return false
+	}
+}
+
+// =============================================================================
+// Routes
+// =============================================================================
+
+// listAlerts handles GET /users/me/alerts.
+func (a *App) listAlerts(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	rows, err := a.db.Query(c.Context(), `
+		SELECT id, symbol, condition, threshold::FLOAT8, webhook_url, armed,
+			trigger_count, last_triggered_at, created_at
+		FROM price_alerts
+		WHERE logto_sub = $1
+		ORDER BY created_at DESC, id DESC`, userSub)
+	if err != nil {
+		log.Printf("[Alerts] list failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to load alerts",
+		})
+	}
+	defer rows.Close()
+
+	alerts := make([]PriceAlert, 0)
+	for rows.Next() {
+		var p PriceAlert
+		if err := rows.Scan(&p.ID, &p.Symbol, &p.Condition, &p.Threshold, &p.WebhookURL,
+			&p.Armed, &p.TriggerCount, &p.LastTriggeredAt, &p.CreatedAt); err != nil {
+			log.Printf("[Alerts] scan error: %v", err)
+			continue
+		}
+		alerts = append(alerts, p)
+	}
+
+	setQuotaHeaders(c, "alerts", len(alerts), AlertCap(GetUserTier(c)))
+	return c.JSON(alerts)
+}
+
+// createAlert handles POST /users/me/alerts.
+func (a *App) createAlert(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req createAlertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if err := validateAlertRequest(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	var tracked bool
+	if err := a.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM trades WHERE symbol = $1)", req.Symbol).Scan(&tracked); err != nil {
+		log.Printf("[Alerts] symbol lookup failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	if !tracked {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Unknown symbol",
+		})
+	}
+
+	tier := GetUserTier(c)
+	if limit := AlertCap(tier); limit != -1 {
+		var current int
+		if err := a.db.QueryRow(ctx, "SELECT count(*) FROM price_alerts WHERE logto_sub = $1", userSub).Scan(&current); err != nil {
+			log.Printf("[Alerts] count failed for %s: %v", userSub, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Failed to verify alert count",
+			})
+		}
+		if current >= limit {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "alert limit reached for your tier",
+				"current": current,
+				"max":     limit,
+				"tier":    tier,
+			})
+		}
+	}
+
+	var webhookURL, secret *string
+	if req.WebhookURL != "" {
+		s, err := newAlertWebhookSecret()
+		if err != nil {
+			log.Printf("[Alerts] secret generation failed: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Internal server error",
+			})
+		}
+		webhookURL, secret = &req.WebhookURL, &s
+	}
+
+	alert := PriceAlert{Symbol: req.Symbol, Condition: req.Condition, Threshold: req.Threshold, WebhookURL: webhookURL, Armed: true}
+	err := a.db.QueryRow(ctx, `
+		INSERT INTO price_alerts (logto_sub, symbol, condition, threshold, webhook_url, webhook_secret)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, threshold::FLOAT8, created_at`,
+		userSub, req.Symbol, req.Condition, req.Threshold, webhookURL, secret,
+	).Scan(&alert.ID, &alert.Threshold, &alert.CreatedAt)
+	if err != nil {
+		log.Printf("[Alerts] insert failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create alert",
+		})
+	}
+
+	a.alerts.add(&watchedAlert{
+		id: alert.ID, sub: userSub, symbol: alert.Symbol, condition: alert.Condition,
+		threshold: alert.Threshold, webhookURL: req.WebhookURL, secret: deref(secret),
+	}, true)
+	if secret != nil {
+		alert.WebhookSecret = *secret
+	}
+	return c.Status(fiber.StatusCreated).JSON(alert)
+}
+
+// deleteAlert handles DELETE /users/me/alerts/:id.
+func (a *App) deleteAlert(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid alert id",
+		})
+	}
+
+	tag, err := a.db.Exec(c.Context(), "DELETE FROM price_alerts WHERE id = $1 AND logto_sub = $2", id, userSub)
+	if err != nil {
+		log.Printf("[Alerts] delete failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to delete alert",
+		})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Alert not found",
+		})
+	}
+	a.alerts.remove(id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// deleteUserAlerts drops all of userSub's alerts; called when the finance
+// channel is removed.
+func (a *App) deleteUserAlerts(ctx context.Context, userSub string) {
+	if _, err := a.db.Exec(ctx, "DELETE FROM price_alerts WHERE logto_sub = $1", userSub); err != nil {
+		log.Printf("[Alerts] delete for %s failed: %v", userSub, err)
+		return
+	}
+	a.alerts.removeUser(userSub)
+}
+
+func newAlertWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// =============================================================================
+// Index
+// =============================================================================
+
+// watchedAlert is an alert as the watcher holds it. armed mirrors the
+// column; it may lag another replica's write, which only costs a no-op
+// UPDATE.
+type watchedAlert struct {
+	id         int64
+	sub        string
+	symbol     string
+	condition  string
+	threshold  float64
+	webhookURL string
+	secret     string
+	armed      atomic.Bool
+}
+
+// alertIndex holds every alert by symbol.
+type alertIndex struct {
+	mu       sync.RWMutex
+	bySymbol map[string][]*watchedAlert
+}
+
+func newAlertIndex() *alertIndex {
+	return &alertIndex{bySymbol: make(map[string][]*watchedAlert)}
+}
+
+func (x *alertIndex) add(w *watchedAlert, armed bool) {
+	w.armed.Store(armed)
+	x.mu.Lock()
+	x.bySymbol[w.symbol] = append(x.bySymbol[w.symbol], w)
+	x.mu.Unlock()
+}
+
+func (x *alertIndex) remove(id int64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for sym, list := range x.bySymbol {
+		for i, w := range list {
+			if w.id == id {
+				x.bySymbol[sym] = append(list[:i:i], list[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (x *alertIndex) removeUser(sub string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for sym, list := range x.bySymbol {
+		kept := list[:0:0]
+		for _, w := range list {
+			if w.sub != sub {
+				kept = append(kept, w)
+			}
+		}
+		x.bySymbol[sym] = kept
+	}
+}
+
+func (x *alertIndex) forSymbol(symbol string) []*watchedAlert {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.bySymbol[symbol]
+}
+
+func (x *alertIndex) replace(bySymbol map[string][]*watchedAlert) {
+	x.mu.Lock()
+	x.bySymbol = bySymbol
+	x.mu.Unlock()
+}
+
+// reloadAlerts rebuilds the index from price_alerts.
+func (a *App) reloadAlerts(ctx context.Context) {
+	rows, err := a.db.Query(ctx, `
+		SELECT id, logto_sub, symbol, condition, threshold::FLOAT8,
+			COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), armed
+		FROM price_alerts`)
+	if err != nil {
+		log.Printf("[Alerts] reload failed: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	bySymbol := make(map[string][]*watchedAlert)
+	for rows.Next() {
+		w := &watchedAlert{}
+		var armed bool
+		if err := rows.Scan(&w.id, &w.sub, &w.symbol, &w.condition, &w.threshold,
+			&w.webhookURL, &w.secret, &armed); err != nil {
+			log.Printf("[Alerts] reload scan error: %v", err)
+			continue
+		}
+		w.armed.Store(armed)
+		bySymbol[w.symbol] = append(bySymbol[w.symbol], w)
+	}
+	if rows.Err() != nil {
+		log.Printf("[Alerts] reload failed: %v", rows.Err())
+		return
+	}
+	a.alerts.replace(bySymbol)
+}
+
+// =============================================================================
+// Watcher
+// =============================================================================
+
+// startAlertWatcher loads the alert index, then evaluates alerts against
+// every trade update on core's finance CDC topics until ctx is cancelled.
+func (a *App) startAlertWatcher(ctx context.Context) {
+	a.reloadAlerts(ctx)
+
+	pubsub := a.rdb.PSubscribe(ctx, FinanceCDCTopicPrefix+"*")
+	defer pubsub.Close()
+	log.Printf("[Alerts] Listening for CDC on %s*", FinanceCDCTopicPrefix)
+
+	ticker := time.NewTicker(AlertRefreshInterval)
+	defer ticker.Stop()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.reloadAlerts(ctx)
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			for _, t := range tradesInEnvelope([]byte(msg.Payload)) {
+				a.evaluateAlerts(ctx, t)
+			}
+		}
+	}
+}
+
+// tradeTick is the part of a trades CDC record alerts look at.
+type tradeTick struct {
+	Symbol           string
+	Price            float64
+	PercentageChange float64
+}
+
+// tradesInEnvelope extracts trade ticks from a core CDC envelope
+// ({"data":[{"action":...,"record":{...},"metadata":{"table_name":...}}]}).
+// Deletes and non-trades records are skipped.
+func tradesInEnvelope(payload []byte) []tradeTick {
+	var envelope struct {
+		Data []CDCRecord `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil
+	}
+	var ticks []tradeTick
+	for _, rec := range envelope.Data {
+		if rec.Metadata.TableName != "trades" || rec.Action == "delete" {
+			continue
+		}
+		symbol, _ := rec.Record["symbol"].(string)
+		price, ok := numericField(rec.Record["price"])
+		if symbol == "" || !ok || price <= 0 {
+			continue
+		}
+		pct, _ := numericField(rec.Record["percentage_change"])
+		ticks = append(ticks, tradeTick{Symbol: symbol, Price: price, PercentageChange: pct})
+	}
+	return ticks
+}
+
+// numericField reads a DECIMAL column from a CDC record, which arrives
+// as either a JSON number or a string depending on the sink.
+func numericField(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// alertStep is the edge trigger shared by the watcher and the preview:
+// an armed alert fires when its condition is met, and a disarmed one
+// re-arms once its condition is false again.
+func alertStep(armed, met bool) (fire, rearm bool) {
+	return armed && met, !armed && !met
+}
+
+// evaluateAlerts fires armed alerts whose condition t meets and re-arms
+// disarmed ones whose condition no longer holds.
+func (a *App) evaluateAlerts(ctx context.Context, t tradeTick) {
+	for _, w := range a.alerts.forSymbol(t.Symbol) {
+		fire, rearm := alertStep(w.armed.Load(), conditionMet(w.condition, w.threshold, t.Price, t.PercentageChange))
+		switch {
+		case fire:
+			a.fireAlert(ctx, w, t)
+		case rearm:
+			if _, err := a.db.Exec(ctx, "UPDATE price_alerts SET armed = true WHERE id = $1 AND NOT armed", w.id); err != nil {
+				log.Printf("[Alerts] re-arm %d failed: %v", w.id, err)
+				continue
+			}
+			w.armed.Store(true)
+		}
+	}
+}
+
+// fireAlert disarms w and, if this replica won the disarm, delivers it.
+func (a *App) fireAlert(ctx context.Context, w *watchedAlert, t tradeTick) {
+	w.armed.Store(false)
+
+	var triggeredAt time.Time
+	err := a.db.QueryRow(ctx, `
+		UPDATE price_alerts
+		SET armed = false, trigger_count = trigger_count + 1, last_triggered_at = now()
+		WHERE id = $1 AND armed
+		RETURNING last_triggered_at`, w.id).Scan(&triggeredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return // deleted, or another replica fired it
+	}
+	if err != nil {
+		log.Printf("[Alerts] fire %d failed: %v", w.id, err)
+		w.armed.Store(true)
+		return
+	}
+
+	ev := AlertEvent{
+		Event:            "price_alert",
+		AlertID:          w.id,
+		Symbol:           w.symbol,
+		Condition:        w.condition,
+		Threshold:        w.threshold,
+		Price:            t.Price,
+		PercentageChange: t.PercentageChange,
+		TriggeredAt:      triggeredAt.UTC(),
+	}
+	a.publishAlertEvent(ctx, w.sub, ev)
+	if w.webhookURL != "" {
+		a.deliverAlertWebhook(w.webhookURL, w.secret, ev)
+	}
+}
+
+// publishAlertEvent sends ev to the owner's SSE topic in core's CDC
+// envelope shape, as a price_alert_events insert.
+func (a *App) publishAlertEvent(ctx context.Context, sub string, ev AlertEvent) {
+	envelope := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"action": "insert",
+				"record": ev,
+				"metadata": map[string]string{
+					"table_schema": "public",
+					"table_name":   "price_alert_events",
+				},
+			},
+		},
+		"server_ts": time.Now().UnixMilli(),
+	}
+	if seq, err := a.rdb.Incr(ctx, RedisEventSeqKey).Result(); err == nil {
+		envelope["seq"] = seq
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("[Alerts] marshal SSE payload failed: %v", err)
+		return
+	}
+	if err := a.rdb.Publish(ctx, CoreUserTopicPrefix+sub, payload).Err(); err != nil {
+		log.Printf("[Alerts] publish for %s failed: %v", sub, err)
+	}
+}
+
+// =============================================================================
+// Webhook Delivery
+// =============================================================================
+
+var (
+	errBlockedAddress = errors.New("address is not public")
+
+	alertWebhookClient = newAlertWebhookClient()
+	alertWebhookSlots  = make(chan struct{}, MaxAlertWebhooksInFlight)
+)
+
+// publicAddress reports whether ip is safe to connect to from inside the
+// cluster.
+func publicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// newAlertWebhookClient returns the HTTP client for webhook deliveries.
+// The dialer's Control hook sees every resolved address, and redirects
+// aren't followed, so a webhook can't be pointed into the cluster.
+func newAlertWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: AlertWebhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: AlertWebhookTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: AlertWebhookTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// signAlertPayload returns the AlertSignatureHeader value for body.
+func signAlertPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverAlertWebhook POSTs ev to target in the background. Delivery is
+// best-effort: one attempt, failures logged, and nothing sent when
+// MaxAlertWebhooksInFlight deliveries are already running.
+func (a *App) deliverAlertWebhook(target, secret string, ev AlertEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[Alerts] marshal webhook payload failed: %v", err)
+		return
+	}
+
+	select {
+	case alertWebhookSlots <- struct{}{}:
+	default:
+		log.Printf("[Alerts] webhook for alert %d dropped: %d deliveries in flight", ev.AlertID, MaxAlertWebhooksInFlight)
+		return
+	}
+
+	go func() {
+		defer func() { <-alertWebhookSlots }()
+
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("[Alerts] webhook for alert %d: %v", ev.AlertID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Scrollr-Alerts/1.0")
+		req.Header.Set("X-Scrollr-Event", ev.Event)
+		if secret != "" {
+			req.Header.Set(AlertSignatureHeader, signAlertPayload(secret, body))
+		}
+
+		resp, err := alertWebhookClient.Do(req)
+		if err != nil {
+			log.Printf("[Alerts] webhook for alert %d failed: %v", ev.AlertID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("[Alerts] webhook for alert %d returned HTTP %d", ev.AlertID, resp.StatusCode)
+		}
+	}()
+}
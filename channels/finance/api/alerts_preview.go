@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Alert Preview
+// =============================================================================
+
+// POST /users/me/alerts/preview takes the same body as POST
+// /users/me/alerts and reports how often the alert would have fired over
+// the last AlertPreviewWindow, without saving anything. It replays the
+// symbol's minute buckets from trades_history through conditionMet and
+// alertStep, the evaluator the watcher runs on live ticks, so a preview
+// can't drift from what the saved alert will do.
+//
+// Minute buckets are coarser than the live tick stream. Each bucket is
+// replayed as four ticks — open, low, high, close — so a threshold
+// crossed inside the minute still counts. A change_pct alert measures
+// against the last close of the previous UTC day in history, standing in
+// for the previous_close the live ticks carry.
+
+const (
+	// AlertPreviewWindow is how far back a preview replays. It must stay
+	// within HistoryRetention, less a day for change_pct's reference close.
+	AlertPreviewWindow = 30 * 24 * time.Hour
+)
+
+// historyBucket is one minute of trades_history.
+type historyBucket struct {
+	Time                   time.Time
+	Open, High, Low, Close float64
+}
+
+// AlertPreviewDay is the trigger count for one UTC day.
+type AlertPreviewDay struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int    `json:"count"`
+}
+
+// AlertPreview is the payload of POST /users/me/alerts/preview.
+type AlertPreview struct {
+	Symbol      string            `json:"symbol"`
+	Condition   string            `json:"condition"`
+	Threshold   float64           `json:"threshold"`
+	WindowDays  int               `json:"window_days"`
+	HistoryFrom *time.Time        `json:"history_from"` // oldest bucket replayed; nil when there is none
+	Triggers    int               `json:"triggers"`
+	ByDay       []AlertPreviewDay `json:"by_day"`      // days with at least one trigger, oldest first
+	ByHourUTC   [24]int           `json:"by_hour_utc"` // triggers per UTC hour of day
+}
+
+// replayAlert runs an alert, armed from since, over buckets (oldest
+// first) and returns the times it would have fired. Buckets before since
+// only supply change_pct's reference close.
+func replayAlert(condition string, threshold float64, buckets []historyBucket, since time.Time) []time.Time {
+	var fired []time.Time
+	armed := true
+	var day string
+	var dayClose, prevClose float64
+	for _, b := range buckets {
+		if d := b.Time.UTC().Format("2006-01-02"); d != day {
+			day, prevClose = d, dayClose
+		}
+		dayClose = b.Close
+		if b.Time.Before(since) {
+			continue
+		}
+		for _, price := range [4]float64{b.Open, b.Low, b.High, b.Close} {
+			var pct float64
+			if prevClose > 0 {
+				pct = (price - prevClose) / prevClose * 100
+			}
+			fire, rearm := alertStep(armed, conditionMet(condition, threshold, price, pct))
+			switch {
+			case fire:
+				armed = false
+				fired = append(fired, b.Time)
+			case rearm:
+				armed = true
+			}
+		}
+	}
+	return fired
+}
+
+// summarizeAlertPreview buckets trigger times by UTC day and hour.
+func summarizeAlertPreview(p *AlertPreview, fired []time.Time) {
+	p.Triggers = len(fired)
+	p.ByDay = make([]AlertPreviewDay, 0)
+	for _, t := range fired {
+		t = t.UTC()
+		p.ByHourUTC[t.Hour()]++
+		d := t.Format("2006-01-02")
+		if n := len(p.ByDay); n > 0 && p.ByDay[n-1].Date == d {
+			p.ByDay[n-1].Count++
+		} else {
+			p.ByDay = append(p.ByDay, AlertPreviewDay{Date: d, Count: 1})
+		}
+	}
+}
+
+// previewAlert handles POST /users/me/alerts/preview.
+func (a *App) previewAlert(c *fiber.Ctx) error {
+	if c.Get("X-User-Sub") == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req createAlertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	req.WebhookURL = "" // nothing is delivered
+	if err := validateAlertRequest(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	var tracked bool
+	if err := a.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM trades WHERE symbol = $1)", req.Symbol).Scan(&tracked); err != nil {
+		log.Printf("[Alerts] symbol lookup failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	if !tracked {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Unknown symbol",
+		})
+	}
+
+	now := time.Now()
+	since := now.Add(-AlertPreviewWindow)
+	buckets, err := a.queryHistoryBuckets(ctx, req.Symbol, since.Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("[Alerts] preview history query failed for %s: %v", req.Symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	preview := AlertPreview{
+		Symbol:     req.Symbol,
+		Condition:  req.Condition,
+		Threshold:  req.Threshold,
+		WindowDays: int(AlertPreviewWindow / (24 * time.Hour)),
+	}
+	for _, b := range buckets {
+		if !b.Time.Before(since) {
+			from := b.Time.UTC()
+			preview.HistoryFrom = &from
+			break
+		}
+	}
+	summarizeAlertPreview(&preview, replayAlert(req.Condition, req.Threshold, buckets, since))
+	return c.JSON(preview)
+}
+
+// queryHistoryBuckets returns symbol's minute buckets from since on,
+// oldest first.
+func (a *App) queryHistoryBuckets(ctx context.Context, symbol string, since time.Time) ([]historyBucket, error) {
+	rows, err := a.readDB().Query(ctx, `
+		SELECT bucket, open::FLOAT8, high::FLOAT8, low::FLOAT8, close::FLOAT8
+		FROM trades_history
+		WHERE symbol = $1 AND bucket >= $2
+		ORDER BY bucket`, symbol, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []historyBucket
+	for rows.Next() {
+		var b historyBucket
+		if err := rows.Scan(&b.Time, &b.Open, &b.High, &b.Low, &b.Close); err != nil {
+			log.Printf("[Alerts] preview scan error: %v", err)
+			continue
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
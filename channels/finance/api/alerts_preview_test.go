@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// flatBucket is a minute in which the price didn't move.
+func flatBucket(at time.Time, price float64) historyBucket {
+	return historyBucket{Time: at, Open: price, High: price, Low: price, Close: price}
+}
+
+func TestReplayAlertEdgeTriggers(t *testing.T) {
+	start := time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC)
+	buckets := []historyBucket{
+		flatBucket(start, 99),
+		flatBucket(start.Add(time.Minute), 101),   // fires
+		flatBucket(start.Add(2*time.Minute), 102), // still above: no repeat
+		flatBucket(start.Add(3*time.Minute), 98),  // re-arms
+		// Crosses and falls back inside one minute: fires on the high.
+		{Time: start.Add(4 * time.Minute), Open: 99, High: 103, Low: 98, Close: 99},
+	}
+	got := replayAlert(AlertAbove, 100, buckets, start)
+	want := []time.Time{start.Add(time.Minute), start.Add(4 * time.Minute)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fired at %v, want %v", got, want)
+	}
+
+	// Buckets before since don't fire.
+	if got := replayAlert(AlertAbove, 100, buckets, start.Add(3*time.Minute)); len(got) != 1 {
+		t.Errorf("fired %d times from minute 3, want 1", len(got))
+	}
+}
+
+func TestReplayAlertChangePct(t *testing.T) {
+	day1 := time.Date(2026, 10, 1, 20, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 10, 2, 14, 0, 0, 0, time.UTC)
+	buckets := []historyBucket{
+		flatBucket(day1, 90),
+		flatBucket(day1.Add(time.Minute), 100), // day 1 closes at 100
+		flatBucket(day2, 103),
+		flatBucket(day2.Add(time.Minute), 94), // -6% on the day
+	}
+	got := replayAlert(AlertChangePct, 5, buckets, day2)
+	if want := []time.Time{day2.Add(time.Minute)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fired at %v, want %v", got, want)
+	}
+
+	// With no previous day to measure against, change_pct never fires.
+	if got := replayAlert(AlertChangePct, 5, buckets[2:], day2); len(got) != 0 {
+		t.Errorf("fired %v without a reference close", got)
+	}
+}
+
+func TestSummarizeAlertPreview(t *testing.T) {
+	var p AlertPreview
+	summarizeAlertPreview(&p, []time.Time{
+		time.Date(2026, 10, 1, 14, 5, 0, 0, time.UTC),
+		time.Date(2026, 10, 1, 14, 50, 0, 0, time.UTC),
+		time.Date(2026, 10, 3, 9, 0, 0, 0, time.UTC),
+	})
+	if p.Triggers != 3 {
+		t.Errorf("triggers = %d, want 3", p.Triggers)
+	}
+	wantDays := []AlertPreviewDay{{Date: "2026-10-01", Count: 2}, {Date: "2026-10-03", Count: 1}}
+	if !reflect.DeepEqual(p.ByDay, wantDays) {
+		t.Errorf("by_day = %v, want %v", p.ByDay, wantDays)
+	}
+	if p.ByHourUTC[14] != 2 || p.ByHourUTC[9] != 1 {
+		t.Errorf("by_hour_utc = %v", p.ByHourUTC)
+	}
+}
+
+func TestAlertPreviewWindowFitsRetention(t *testing.T) {
+	// A day more is read for change_pct's reference close.
+	if AlertPreviewWindow+24*time.Hour > HistoryRetention {
+		t.Error("AlertPreviewWindow plus a reference day reaches past HistoryRetention")
+	}
+}
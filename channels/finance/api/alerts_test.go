@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestValidateAlertRequest(t *testing.T) {
+	ok := createAlertRequest{Symbol: " aapl ", Condition: AlertAbove, Threshold: 200, WebhookURL: "https://hooks.example.com/x"}
+	if err := validateAlertRequest(&ok); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	if ok.Symbol != "AAPL" {
+		t.Errorf("symbol = %q; want AAPL", ok.Symbol)
+	}
+
+	cases := []struct {
+		name string
+		req  createAlertRequest
+		want string
+	}{
+		{"no symbol", createAlertRequest{Condition: AlertAbove, Threshold: 1}, "symbol"},
+		{"zero threshold", createAlertRequest{Symbol: "AAPL", Condition: AlertBelow}, "positive"},
+		{"bad condition", createAlertRequest{Symbol: "AAPL", Condition: "crosses", Threshold: 1}, "condition"},
+		{"pct too large", createAlertRequest{Symbol: "AAPL", Condition: AlertChangePct, Threshold: 150}, "at most"},
+		{"http webhook", createAlertRequest{Symbol: "AAPL", Condition: AlertAbove, Threshold: 1, WebhookURL: "http://hooks.example.com"}, "https"},
+		{"credentials", createAlertRequest{Symbol: "AAPL", Condition: AlertAbove, Threshold: 1, WebhookURL: "https://u:p@hooks.example.com"}, "credentials"},
+		{"private ip", createAlertRequest{Symbol: "AAPL", Condition: AlertAbove, Threshold: 1, WebhookURL: "https://10.0.0.5/hook"}, "public"},
+		{"loopback", createAlertRequest{Symbol: "AAPL", Condition: AlertAbove, Threshold: 1, WebhookURL: "https://127.0.0.1:8081/internal/cdc"}, "public"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAlertRequest(&tc.req)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v; want one mentioning %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestConditionMet(t *testing.T) {
+	cases := []struct {
+		condition  string
+		threshold  float64
+		price, pct float64
+		want       bool
+	}{
+		{AlertAbove, 100, 100.01, 0, true},
+		{AlertAbove, 100, 100, 0, false},
+		{AlertBelow, 100, 99.99, 0, true},
+		{AlertBelow, 100, 0, 0, false}, // unpriced row
+		{AlertChangePct, 5, 0, -5.2, true},
+		{AlertChangePct, 5, 0, 4.99, false},
+		{"unknown", 1, 2, 2, false},
+	}
+	for _, tc := range cases {
+		if got := conditionMet(tc.condition, tc.threshold, tc.price, tc.pct); got != tc.want {
+			t.Errorf("conditionMet(%s, %v, %v, %v) = %v; want %v", tc.condition, tc.threshold, tc.price, tc.pct, got, tc.want)
+		}
+	}
+}
+
+func TestTradesInEnvelope(t *testing.T) {
+	payload := `{"data":[
+		{"action":"update","record":{"symbol":"AAPL","price":"201.50","percentage_change":1.25},"metadata":{"table_name":"trades"}},
+		{"action":"update","record":{"symbol":"MSFT","price":0},"metadata":{"table_name":"trades"}},
+		{"action":"delete","record":{"symbol":"TSLA","price":10},"metadata":{"table_name":"trades"}},
+		{"action":"insert","record":{"symbol":"X","price":10},"metadata":{"table_name":"games"}}
+	],"seq":7}`
+	ticks := tradesInEnvelope([]byte(payload))
+	if len(ticks) != 1 {
+		t.Fatalf("got %d ticks; want 1: %+v", len(ticks), ticks)
+	}
+	if ticks[0] != (tradeTick{Symbol: "AAPL", Price: 201.5, PercentageChange: 1.25}) {
+		t.Errorf("tick = %+v", ticks[0])
+	}
+	if tradesInEnvelope([]byte("not json")) != nil {
+		t.Error("malformed payload produced ticks")
+	}
+}
+
+func TestAlertIndex(t *testing.T) {
+	x := newAlertIndex()
+	x.add(&watchedAlert{id: 1, sub: "a", symbol: "AAPL"}, true)
+	x.add(&watchedAlert{id: 2, sub: "b", symbol: "AAPL"}, false)
+	x.add(&watchedAlert{id: 3, sub: "a", symbol: "MSFT"}, true)
+
+	held := x.forSymbol("AAPL")
+	x.remove(1)
+	if len(held) != 2 || held[0].id != 1 {
+		t.Error("remove mutated a slice a reader already holds")
+	}
+	if got := x.forSymbol("AAPL"); len(got) != 1 || got[0].id != 2 || got[0].armed.Load() {
+		t.Errorf("after remove: %+v", got)
+	}
+
+	x.removeUser("a")
+	if len(x.forSymbol("MSFT")) != 0 || len(x.forSymbol("AAPL")) != 1 {
+		t.Error("removeUser left the user's alerts or dropped another user's")
+	}
+}
+
+func TestSignAlertPayload(t *testing.T) {
+	body := []byte(`{"event":"price_alert"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := signAlertPayload("s3cret", body); got != want {
+		t.Errorf("signature = %q; want %q", got, want)
+	}
+}
+
+func TestAlertCap(t *testing.T) {
+	if AlertCap("bogus") != AlertCap(TierFree) {
+		t.Error("unknown tier must get the free cap")
+	}
+	if AlertCap(TierSuperUser) != -1 {
+		t.Error("super_user should be unlimited")
+	}
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
 // =============================================================================
@@ -33,6 +37,14 @@ const (
 	// FinanceCatalogCacheTTL is how long the symbol catalog is cached.
 	FinanceCatalogCacheTTL = 5 * time.Minute
 
+	// MaxQuoteSymbols caps GET /finance/quotes?symbols=.
+	MaxQuoteSymbols = 100
+
+	// QuotesMaxAge is the Cache-Control max-age for /finance/quotes.
+	// Matches FinanceCacheTTL — the response is built from that cache, so
+	// a client re-fetching sooner would just get the same bytes.
+	QuotesMaxAge = 30
+
 	// RedisFinanceSubscribersPrefix is the Redis key prefix for per-symbol
 	// subscriber sets (e.g. "finance:subscribers:AAPL").
 	RedisFinanceSubscribersPrefix = "finance:subscribers:"
@@ -41,6 +53,8 @@ const (
 	// COALESCE guards against NULL columns for rows that have been inserted
 	// but not yet updated by the Rust ingestion service.
 	// JOINs with tracked_symbols to include the link field.
+	// previous_close and the change columns of crypto rows are against the
+	// price 24 hours ago (asset_type = 'crypto'), not a market close.
 	TradesQuery = `
 		SELECT 
 			t.symbol, 
@@ -50,7 +64,8 @@ const (
 			COALESCE(t.percentage_change, 0), 
 			COALESCE(t.direction, 'flat'), 
 			COALESCE(t.last_updated, t.created_at),
-			COALESCE(ts.link, 'https://www.google.com/search?q=' || t.symbol || '+stock')
+			COALESCE(ts.link, 'https://www.google.com/search?q=' || t.symbol || '+stock'),
+			t.asset_type
 		FROM trades t
 		LEFT JOIN tracked_symbols ts ON t.symbol = ts.symbol
 		ORDER BY t.symbol ASC`
@@ -62,8 +77,14 @@ const (
 
 // App holds the shared dependencies for all handlers.
 type App struct {
-	db  *pgxpool.Pool
-	rdb *redis.Client
+	db      *pgxpool.Pool
+	replica *readReplica // optional; read through readDB (db.go)
+	rdb     redis.UniversalClient
+	alerts  *alertIndex // price alerts by symbol (alerts.go)
+
+	// sfGroup collapses concurrent cache misses on a shared key into one
+	// DB query, so an expiry under load costs a single query.
+	sfGroup singleflight.Group
 }
 
 // =============================================================================
@@ -74,12 +95,12 @@ type App struct {
 // The core gateway adds X-User-Sub header for authenticated requests.
 func (a *App) getFinance(c *fiber.Ctx) error {
 	var trades []Trade
-	if GetCache(a.rdb, CacheKeyFinance, &trades) {
+	if countCache("trades", GetCache(a.rdb, CacheKeyFinance, &trades)) {
 		c.Set("X-Cache", "HIT")
 		return c.JSON(trades)
 	}
 
-	trades, err := a.queryTrades(context.Background())
+	trades, err := a.fetchTrades()
 	if err != nil {
 		log.Printf("[Finance] getFinance query failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -88,22 +109,156 @@ func (a *App) getFinance(c *fiber.Ctx) error {
 		})
 	}
 
-	SetCache(a.rdb, CacheKeyFinance, trades, FinanceCacheTTL)
 	c.Set("X-Cache", "MISS")
 	return c.JSON(trades)
 }
 
+// fetchTrades queries the latest trades and refreshes the shared
+// CacheKeyFinance entry behind /finance and /finance/quotes. Concurrent
+// misses wait on the first caller's query instead of issuing their own.
+func (a *App) fetchTrades() ([]Trade, error) {
+	result, err, _ := a.sfGroup.Do(CacheKeyFinance, func() (interface{}, error) {
+		trades, qErr := a.queryTrades(context.Background())
+		if qErr != nil {
+			return nil, qErr
+		}
+		SetCache(a.rdb, CacheKeyFinance, trades, FinanceCacheTTL)
+		return trades, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]Trade), nil
+}
+
+// getQuotes returns the latest trade for each requested symbol, for
+// scripts that would otherwise pull the whole /finance list and filter.
+// Authenticated by API key at the gateway (X-User-Sub is the key owner).
+//
+// Served from the same all-trades cache as /finance, so a burst of
+// quote requests costs at most one DB query per FinanceCacheTTL. The
+// response carries an ETag; a matching If-None-Match gets a 304.
+func (a *App) getQuotes(c *fiber.Ctx) error {
+	if c.Get("X-User-Sub") == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "API key required",
+		})
+	}
+
+	symbols, err := parseQuoteSymbols(c.Query("symbols"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	var trades []Trade
+	if countCache("trades", GetCache(a.rdb, CacheKeyFinance, &trades)) {
+		c.Set("X-Cache", "HIT")
+	} else {
+		trades, err = a.fetchTrades()
+		if err != nil {
+			log.Printf("[Finance] getQuotes query failed: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Internal server error",
+			})
+		}
+		c.Set("X-Cache", "MISS")
+	}
+
+	resp := selectQuotes(trades, symbols)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	c.Set("ETag", etag)
+	c.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", QuotesMaxAge))
+	if c.Get("If-None-Match") == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set("Content-Type", "application/json")
+	return c.Send(body)
+}
+
+// parseQuoteSymbols normalizes the comma-separated ?symbols= list:
+// trimmed, upper-cased, de-duplicated, order preserved.
+func parseQuoteSymbols(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		if len(s) > 20 {
+			return nil, fmt.Errorf("invalid symbol %q", s)
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("symbols query parameter is required")
+	}
+	if len(out) > MaxQuoteSymbols {
+		return nil, fmt.Errorf("at most %d symbols per request", MaxQuoteSymbols)
+	}
+	return out, nil
+}
+
+// selectQuotes picks the requested symbols out of the full trade list,
+// in request order. Symbols we don't track are listed in Missing rather
+// than failing the whole request.
+func selectQuotes(trades []Trade, symbols []string) QuotesResponse {
+	bySymbol := make(map[string]Trade, len(trades))
+	for _, t := range trades {
+		bySymbol[t.Symbol] = t
+	}
+	resp := QuotesResponse{Quotes: make([]Trade, 0, len(symbols)), Missing: make([]string, 0)}
+	for _, s := range symbols {
+		if t, ok := bySymbol[s]; ok {
+			resp.Quotes = append(resp.Quotes, t)
+		} else {
+			resp.Missing = append(resp.Missing, s)
+		}
+	}
+	return resp
+}
+
 // getSymbolCatalog returns all enabled tracked symbols for the dashboard
 // symbol browser.
 func (a *App) getSymbolCatalog(c *fiber.Ctx) error {
+	// Public route: the gateway only forwards X-User-Sub when the caller
+	// happens to be signed in. Quota headers are per-user, so they're set
+	// outside the shared catalog cache.
+	if userSub := c.Get("X-User-Sub"); userSub != "" {
+		setQuotaHeaders(c, "symbols", len(a.getUserFinanceSymbols(userSub)), SymbolCap(GetUserTier(c)))
+	}
+
 	var catalog []TrackedSymbol
-	if GetCache(a.rdb, CacheKeyFinanceCatalog, &catalog) {
+	if countCache("catalog", GetCache(a.rdb, CacheKeyFinanceCatalog, &catalog)) {
 		c.Set("X-Cache", "HIT")
 		return c.JSON(catalog)
 	}
 
-	rows, err := a.db.Query(context.Background(),
-		"SELECT symbol, COALESCE(name, symbol), COALESCE(category, 'Other') FROM tracked_symbols WHERE is_enabled = true ORDER BY category, symbol")
+	// Singleflight: collapse concurrent cache-miss requests into one DB query
+	result, err, _ := a.sfGroup.Do(CacheKeyFinanceCatalog, func() (interface{}, error) {
+		symbols, qErr := a.querySymbolCatalog(context.Background())
+		if qErr != nil {
+			return nil, qErr
+		}
+		SetCache(a.rdb, CacheKeyFinanceCatalog, symbols, FinanceCatalogCacheTTL)
+		return symbols, nil
+	})
 	if err != nil {
 		log.Printf("[Finance] Catalog query failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -111,21 +266,30 @@ func (a *App) getSymbolCatalog(c *fiber.Ctx) error {
 			Error:  "Failed to fetch symbol catalog",
 		})
 	}
+
+	c.Set("X-Cache", "MISS")
+	return c.JSON(result.([]TrackedSymbol))
+}
+
+// querySymbolCatalog loads every enabled tracked symbol.
+func (a *App) querySymbolCatalog(ctx context.Context) ([]TrackedSymbol, error) {
+	rows, err := a.readDB().Query(ctx,
+		"SELECT symbol, COALESCE(name, symbol), COALESCE(category, 'Other'), asset_type FROM tracked_symbols WHERE is_enabled = true ORDER BY category, symbol")
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	catalog = make([]TrackedSymbol, 0)
+	catalog := make([]TrackedSymbol, 0)
 	for rows.Next() {
 		var s TrackedSymbol
-		if err := rows.Scan(&s.Symbol, &s.Name, &s.Category); err != nil {
+		if err := rows.Scan(&s.Symbol, &s.Name, &s.Category, &s.AssetType); err != nil {
 			log.Printf("[Finance] Catalog scan error: %v", err)
 			continue
 		}
 		catalog = append(catalog, s)
 	}
-
-	SetCache(a.rdb, CacheKeyFinanceCatalog, catalog, FinanceCatalogCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.JSON(catalog)
+	return catalog, rows.Err()
 }
 
 // healthHandler proxies a health check to the internal Rust finance service.
@@ -155,6 +319,7 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 		})
 	}
 
+	cdcBatchSize.Observe(float64(len(req.Records)))
 	ctx := context.Background()
 	userSet := make(map[string]bool)
 
@@ -192,7 +357,7 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	// Check per-user cache first
 	cacheKey := CacheKeyFinancePrefix + userSub
 	var trades []Trade
-	if GetCache(a.rdb, cacheKey, &trades) {
+	if countCache("dashboard", GetCache(a.rdb, cacheKey, &trades)) {
 		return c.JSON(fiber.Map{"finance": trades})
 	}
 
@@ -265,15 +430,35 @@ func (a *App) handleInternalHealth(c *fiber.Ctx) error {
 // Channel Lifecycle
 // =============================================================================
 
+// lifecycleEvent is the body of a lifecycle call from the core gateway.
+type lifecycleEvent struct {
+	Event     string                 `json:"event"`
+	User      string                 `json:"user"`
+	Config    map[string]interface{} `json:"config"`
+	OldConfig map[string]interface{} `json:"old_config"`
+	Enabled   bool                   `json:"enabled"`
+}
+
 // handleChannelLifecycle handles channel lifecycle events dispatched by the core
 // gateway. Events: created, updated, deleted, sync.
 func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
+	var req lifecycleEvent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	a.applyLifecycleEvent(context.Background(), req)
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// handleLifecycleReplay applies lifecycle events the gateway queued while
+// this channel was unreachable, in the order they were originally sent.
+func (a *App) handleLifecycleReplay(c *fiber.Ctx) error {
 	var req struct {
-		Event     string                 `json:"event"`
-		User      string                 `json:"user"`
-		Config    map[string]interface{} `json:"config"`
-		OldConfig map[string]interface{} `json:"old_config"`
-		Enabled   bool                   `json:"enabled"`
+		Events []lifecycleEvent `json:"events"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
@@ -283,26 +468,31 @@ func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
+	for _, ev := range req.Events {
+		a.applyLifecycleEvent(ctx, ev)
+	}
+	log.Printf("[Finance Lifecycle] Replayed %d queued events", len(req.Events))
+	return c.JSON(fiber.Map{"processed": len(req.Events)})
+}
 
-	switch req.Event {
+func (a *App) applyLifecycleEvent(ctx context.Context, ev lifecycleEvent) {
+	switch ev.Event {
 	case "created":
 		// No special action needed on create — sync event handles subscriber sets
-		log.Printf("[Finance Lifecycle] Channel created for user %s", req.User)
+		log.Printf("[Finance Lifecycle] Channel created for user %s", ev.User)
 
 	case "updated":
-		a.onChannelUpdated(ctx, req.User, req.OldConfig, req.Config)
+		a.onChannelUpdated(ctx, ev.User, ev.OldConfig, ev.Config)
 
 	case "deleted":
-		a.onChannelDeleted(ctx, req.User, req.Config)
+		a.onChannelDeleted(ctx, ev.User, ev.Config)
 
 	case "sync":
-		a.onSyncSubscriptions(ctx, req.User, req.Config, req.Enabled)
+		a.onSyncSubscriptions(ctx, ev.User, ev.Config, ev.Enabled)
 
 	default:
-		log.Printf("[Finance Lifecycle] Unknown event: %s", req.Event)
+		log.Printf("[Finance Lifecycle] Unknown event: %s", ev.Event)
 	}
-
-	return c.JSON(fiber.Map{"ok": true})
 }
 
 // onChannelUpdated handles symbol list changes when a channel is updated.
@@ -329,14 +519,16 @@ func (a *App) onChannelUpdated(ctx context.Context, userSub string, oldConfig, n
 	a.rdb.Del(ctx, CacheKeyFinancePrefix+userSub)
 }
 
-// onChannelDeleted removes the user from all symbol subscriber sets and
-// invalidates per-user cache when a channel is removed.
+// onChannelDeleted removes the user from all symbol subscriber sets,
+// invalidates per-user cache and drops their price alerts when a channel
+// is removed.
 func (a *App) onChannelDeleted(ctx context.Context, userSub string, config map[string]interface{}) {
 	symbols := extractSymbolsFromChannelConfig(config)
 	for _, s := range symbols {
 		RemoveSubscriber(a.rdb, ctx, RedisFinanceSubscribersPrefix+s, userSub)
 	}
 	a.rdb.Del(ctx, CacheKeyFinancePrefix+userSub)
+	a.deleteUserAlerts(ctx, userSub)
 }
 
 // onSyncSubscriptions adds or removes the user from per-symbol subscriber
@@ -358,7 +550,7 @@ func (a *App) onSyncSubscriptions(ctx context.Context, userSub string, config ma
 
 // queryTrades fetches all trades from PostgreSQL.
 func (a *App) queryTrades(ctx context.Context) ([]Trade, error) {
-	rows, err := a.db.Query(ctx, TradesQuery)
+	rows, err := a.readDB().Query(ctx, TradesQuery)
 	if err != nil {
 		return nil, fmt.Errorf("finance query failed: %w", err)
 	}
@@ -367,7 +559,7 @@ func (a *App) queryTrades(ctx context.Context) ([]Trade, error) {
 	trades := make([]Trade, 0)
 	for rows.Next() {
 		var t Trade
-		if err := rows.Scan(&t.Symbol, &t.Price, &t.PreviousClose, &t.PriceChange, &t.PercentageChange, &t.Direction, &t.LastUpdated, &t.Link); err != nil {
+		if err := rows.Scan(&t.Symbol, &t.Price, &t.PreviousClose, &t.PriceChange, &t.PercentageChange, &t.Direction, &t.LastUpdated, &t.Link, &t.AssetType); err != nil {
 			log.Printf("[Finance] Row scan failed: %v", err)
 			continue
 		}
@@ -383,7 +575,7 @@ func (a *App) queryTradesBySymbols(symbols []string) []Trade {
 		return nil
 	}
 
-	rows, err := a.db.Query(context.Background(), `
+	rows, err := a.readDB().Query(context.Background(), `
 		SELECT 
 			t.symbol, 
 			COALESCE(t.price, 0), 
@@ -392,7 +584,8 @@ func (a *App) queryTradesBySymbols(symbols []string) []Trade {
 			COALESCE(t.percentage_change, 0), 
 			COALESCE(t.direction, 'flat'), 
 			COALESCE(t.last_updated, t.created_at),
-			COALESCE(ts.link, 'https://www.google.com/search?q=' || t.symbol || '+stock')
+			COALESCE(ts.link, 'https://www.google.com/search?q=' || t.symbol || '+stock'),
+			t.asset_type
 		FROM trades t
 		LEFT JOIN tracked_symbols ts ON t.symbol = ts.symbol
 		WHERE t.symbol = ANY($1)
@@ -407,7 +600,7 @@ func (a *App) queryTradesBySymbols(symbols []string) []Trade {
 	trades := make([]Trade, 0)
 	for rows.Next() {
 		var t Trade
-		if err := rows.Scan(&t.Symbol, &t.Price, &t.PreviousClose, &t.PriceChange, &t.PercentageChange, &t.Direction, &t.LastUpdated, &t.Link); err != nil {
+		if err := rows.Scan(&t.Symbol, &t.Price, &t.PreviousClose, &t.PriceChange, &t.PercentageChange, &t.Direction, &t.LastUpdated, &t.Link, &t.AssetType); err != nil {
 			log.Printf("[Finance] Row scan failed: %v", err)
 			continue
 		}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 func TestExtractSymbolsFromConfig(t *testing.T) {
@@ -149,3 +154,80 @@ func TestExtractSymbolsFromConfigJSON(t *testing.T) {
 		t.Errorf("got %d, want 2", len(got))
 	}
 }
+
+// SymbolCap mirrors the Symbols column of api/core/tier_limits.go. If
+// this fails after a core change, update tier_limits.go here to match.
+func TestSymbolCap(t *testing.T) {
+	tests := []struct {
+		tier string
+		want int
+	}{
+		{TierFree, 5},
+		{TierUplink, 25},
+		{TierUplinkPro, 75},
+		{TierUplinkUltimate, -1},
+		{TierSuperUser, -1},
+		{"", 5},
+		{"made_up_tier", 5},
+	}
+
+	for _, tt := range tests {
+		if got := SymbolCap(tt.tier); got != tt.want {
+			t.Errorf("SymbolCap(%q) = %d, want %d", tt.tier, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuoteSymbols(t *testing.T) {
+	got, err := parseQuoteSymbols(" aapl, MSFT,,aapl ,btc/usd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"AAPL", "MSFT", "BTC/USD"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if _, err := parseQuoteSymbols(" , "); err == nil {
+		t.Error("empty list: expected error")
+	}
+
+	many := make([]string, MaxQuoteSymbols+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("S%d", i)
+	}
+	if _, err := parseQuoteSymbols(strings.Join(many, ",")); err == nil {
+		t.Errorf("%d symbols: expected error", len(many))
+	}
+}
+
+func TestSelectQuotes(t *testing.T) {
+	trades := []Trade{{Symbol: "AAPL", Price: 1}, {Symbol: "MSFT", Price: 2}}
+	resp := selectQuotes(trades, []string{"MSFT", "NOPE", "AAPL"})
+
+	if len(resp.Quotes) != 2 || resp.Quotes[0].Symbol != "MSFT" || resp.Quotes[1].Symbol != "AAPL" {
+		t.Errorf("quotes = %+v, want MSFT then AAPL", resp.Quotes)
+	}
+	if len(resp.Missing) != 1 || resp.Missing[0] != "NOPE" {
+		t.Errorf("missing = %v, want [NOPE]", resp.Missing)
+	}
+}
+
+func TestGetQuotesRequiresUser(t *testing.T) {
+	a := &App{}
+	app := fiber.New()
+	app.Get("/finance/quotes", a.getQuotes)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/finance/quotes?symbols=AAPL", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("status = %d without X-User-Sub, want 401", resp.StatusCode)
+	}
+}
@@ -21,26 +21,40 @@ const HealthProxyTimeout = 5 * time.Second
 // k8s readiness probe timeout so a slow downstream doesn't hold up the probe.
 const InternalHealthTimeout = 3 * time.Second
 
-// GetCache attempts to retrieve and deserialize a value from Redis.
+// GetCache attempts to retrieve and deserialize a value from Redis, or
+// for a hot key from the local cache first (local_cache.go).
 // Returns true if the cache hit was successful.
-func GetCache(rdb *redis.Client, key string, target interface{}) bool {
-	val, err := rdb.Get(context.Background(), key).Result()
+func GetCache(rdb redis.UniversalClient, key string, target interface{}) bool {
+	hot := hotCacheKeys[key]
+	if hot {
+		if val, ok := localCache.get(key, time.Now()); ok {
+			return json.Unmarshal(val, target) == nil
+		}
+	}
+
+	val, err := rdb.Get(context.Background(), key).Bytes()
 	if err != nil {
 		return false
 	}
+	if hot {
+		localCache.set(key, val, LocalCacheTTL, time.Now())
+	}
 
-	err = json.Unmarshal([]byte(val), target)
+	err = json.Unmarshal(val, target)
 	return err == nil
 }
 
 // SetCache serializes and stores a value in Redis with an expiration.
-func SetCache(rdb *redis.Client, key string, value interface{}, expiration time.Duration) {
+func SetCache(rdb redis.UniversalClient, key string, value interface{}, expiration time.Duration) {
 	data, err := json.Marshal(value)
 	if err != nil {
 		log.Printf("[Redis Error] Failed to marshal cache data for %s: %v", key, err)
 		return
 	}
 
+	if hotCacheKeys[key] {
+		localCache.set(key, data, min(expiration, LocalCacheTTL), time.Now())
+	}
 	err = rdb.Set(context.Background(), key, data, expiration).Err()
 	if err != nil {
 		log.Printf("[Redis Error] Failed to set cache for %s: %v", key, err)
@@ -48,7 +62,7 @@ func SetCache(rdb *redis.Client, key string, value interface{}, expiration time.
 }
 
 // GetSubscribers returns all user subs in a Redis subscription set.
-func GetSubscribers(rdb *redis.Client, ctx context.Context, setKey string) ([]string, error) {
+func GetSubscribers(rdb redis.UniversalClient, ctx context.Context, setKey string) ([]string, error) {
 	return rdb.SMembers(ctx, setKey).Result()
 }
 
@@ -62,7 +76,7 @@ func GetSubscribers(rdb *redis.Client, ctx context.Context, setKey string) ([]st
 const SubscriberSetTTL = 7 * 24 * time.Hour
 
 // AddSubscriber adds a user to a Redis subscriber set and (re)sets its TTL.
-func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) {
+func AddSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) {
 	pipe := rdb.Pipeline()
 	pipe.SAdd(ctx, setKey, userSub)
 	pipe.Expire(ctx, setKey, SubscriberSetTTL)
@@ -72,7 +86,7 @@ func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub strin
 }
 
 // RemoveSubscriber removes a user from a Redis subscriber set.
-func RemoveSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) {
+func RemoveSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) {
 	if err := rdb.SRem(ctx, setKey, userSub).Err(); err != nil {
 		log.Printf("[Redis] Failed to remove subscriber %s from %s: %v", userSub, setKey, err)
 	}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Price History
+// =============================================================================
+
+// GET /finance/history/:symbol?range=1d|1w|1m serves OHLC candles for
+// dashboard sparklines. The finance service migration adds a trigger that
+// folds every trades price update into a per-minute bucket in
+// trades_history; here those buckets are rolled up to a width that gives
+// each range a couple of hundred points.
+
+const (
+	// CacheKeyFinanceHistoryPrefix is the Redis key prefix for rolled-up
+	// history: cache:finance:history:{AAPL}:{1d}.
+	CacheKeyFinanceHistoryPrefix = "cache:finance:history:"
+
+	// FinanceHistoryCacheTTL matches the smallest bucket anyone would
+	// notice changing; HistoryMaxAge is the matching Cache-Control.
+	FinanceHistoryCacheTTL = 60 * time.Second
+	HistoryMaxAge          = 60
+
+	// HistoryRetention is how long minute buckets are kept. A little
+	// over the longest range so the oldest 1m candle is always full.
+	HistoryRetention = 32 * 24 * time.Hour
+
+	// HistoryPruneInterval is how often expired buckets are deleted.
+	HistoryPruneInterval = time.Hour
+)
+
+// historyRange is one accepted ?range= value.
+type historyRange struct {
+	Span   time.Duration // how far back the range reaches
+	Bucket time.Duration // candle width
+	Label  string        // candle width as reported to clients
+}
+
+var historyRanges = map[string]historyRange{
+	"1d": {Span: 24 * time.Hour, Bucket: 5 * time.Minute, Label: "5m"},
+	"1w": {Span: 7 * 24 * time.Hour, Bucket: time.Hour, Label: "1h"},
+	"1m": {Span: 30 * 24 * time.Hour, Bucket: 4 * time.Hour, Label: "4h"},
+}
+
+// Candle is one OHLC bucket. Time is the bucket start.
+type Candle struct {
+	Time  time.Time `json:"time"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+}
+
+// HistoryResponse is the payload of GET /finance/history/:symbol.
+type HistoryResponse struct {
+	Symbol   string   `json:"symbol"`
+	Range    string   `json:"range"`
+	Interval string   `json:"interval"`
+	Candles  []Candle `json:"candles"`
+}
+
+// parseHistoryRange validates ?range=, defaulting to 1d.
+func parseHistoryRange(raw string) (string, historyRange, error) {
+	if raw == "" {
+		raw = "1d"
+	}
+	r, ok := historyRanges[raw]
+	if !ok {
+		return "", historyRange{}, fmt.Errorf("range must be one of 1d, 1w, 1m")
+	}
+	return raw, r, nil
+}
+
+// getHistory handles GET /finance/history/:symbol.
+func (a *App) getHistory(c *fiber.Ctx) error {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Params("symbol")))
+	if symbol == "" || len(symbol) > 20 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid symbol",
+		})
+	}
+	name, r, err := parseHistoryRange(c.Query("range"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  err.Error(),
+		})
+	}
+
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", HistoryMaxAge))
+
+	cacheKey := CacheKeyFinanceHistoryPrefix + symbol + ":" + name
+	var resp HistoryResponse
+	if countCache("history", GetCache(a.rdb, cacheKey, &resp)) {
+		c.Set("X-Cache", "HIT")
+		return c.JSON(resp)
+	}
+
+	ctx := context.Background()
+	candles, err := a.queryCandles(ctx, symbol, r, time.Now())
+	if err != nil {
+		log.Printf("[Finance] history query failed for %s: %v", symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	if len(candles) == 0 {
+		var tracked bool
+		if err := a.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM trades WHERE symbol = $1)", symbol).Scan(&tracked); err == nil && !tracked {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Status: "not_found",
+				Error:  "Unknown symbol",
+			})
+		}
+	}
+
+	resp = HistoryResponse{Symbol: symbol, Range: name, Interval: r.Label, Candles: candles}
+	SetCache(a.rdb, cacheKey, resp, FinanceHistoryCacheTTL)
+	c.Set("X-Cache", "MISS")
+	return c.JSON(resp)
+}
+
+// queryCandles rolls symbol's minute buckets in r up to r.Bucket-wide
+// candles, oldest first. Buckets are aligned to the Unix epoch, so a
+// candle's bounds don't move between requests. Empty buckets (market
+// closed) are omitted rather than filled.
+func (a *App) queryCandles(ctx context.Context, symbol string, r historyRange, now time.Time) ([]Candle, error) {
+	width := int64(r.Bucket / time.Second)
+	rows, err := a.readDB().Query(ctx, `
+		SELECT to_timestamp(floor(extract(epoch FROM bucket) / $3::bigint) * $3::bigint) AS b,
+			(array_agg(open ORDER BY bucket))[1]::FLOAT8,
+			max(high)::FLOAT8,
+			min(low)::FLOAT8,
+			(array_agg(close ORDER BY bucket DESC))[1]::FLOAT8
+		FROM trades_history
+		WHERE symbol = $1 AND bucket >= $2
+		GROUP BY b
+		ORDER BY b`, symbol, now.Add(-r.Span), width)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := make([]Candle, 0)
+	for rows.Next() {
+		var k Candle
+		if err := rows.Scan(&k.Time, &k.Open, &k.High, &k.Low, &k.Close); err != nil {
+			log.Printf("[Finance] history scan error: %v", err)
+			continue
+		}
+		k.Time = k.Time.UTC()
+		candles = append(candles, k)
+	}
+	return candles, rows.Err()
+}
+
+// startHistoryPruner deletes minute buckets older than HistoryRetention
+// every HistoryPruneInterval. Every replica runs it; the DELETE is
+// idempotent, so overlapping runs only cost a redundant scan.
+func startHistoryPruner(ctx context.Context, a *App) {
+	ticker := time.NewTicker(HistoryPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tag, err := a.db.Exec(ctx, "DELETE FROM trades_history WHERE bucket < $1", time.Now().Add(-HistoryRetention))
+			if err != nil {
+				log.Printf("[Finance] history prune failed: %v", err)
+				continue
+			}
+			if n := tag.RowsAffected(); n > 0 {
+				log.Printf("[Finance] Pruned %d history buckets", n)
+			}
+		}
+	}
+}
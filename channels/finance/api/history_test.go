@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHistoryRange(t *testing.T) {
+	name, r, err := parseHistoryRange("")
+	if err != nil || name != "1d" || r.Bucket != 5*time.Minute {
+		t.Errorf("default range = %q %+v %v, want 1d with 5m buckets", name, r, err)
+	}
+	for _, ok := range []string{"1d", "1w", "1m"} {
+		if _, _, err := parseHistoryRange(ok); err != nil {
+			t.Errorf("parseHistoryRange(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"1y", "1D", "24h"} {
+		if _, _, err := parseHistoryRange(bad); err == nil {
+			t.Errorf("parseHistoryRange(%q) accepted", bad)
+		}
+	}
+}
+
+func TestHistoryRangesFitRetention(t *testing.T) {
+	for name, r := range historyRanges {
+		if r.Span+r.Bucket > HistoryRetention {
+			t.Errorf("range %s reaches past HistoryRetention", name)
+		}
+		if n := int(r.Span / r.Bucket); n < 100 || n > 400 {
+			t.Errorf("range %s has %d candles, want a sparkline-sized count", name, n)
+		}
+	}
+}
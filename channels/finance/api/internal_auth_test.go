@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestInternalSignatureVector pins the canonical string; core's
+// internal_signing_test.go checks the same vector.
+func TestInternalSignatureVector(t *testing.T) {
+	got := internalSignature("test-secret", 1700000000, "POST", "/internal/channel-lifecycle?x=1", []byte(`{"event":"enabled"}`))
+	want := "v1=f8d4a55d975064413765cb4223b1795c68ef010c5249c389cba6528a25e96193"
+	if got != want {
+		t.Errorf("internalSignature = %s; want %s", got, want)
+	}
+}
+
+func TestRequireInternalSignature(t *testing.T) {
+	app := fiber.New()
+	app.Use("/internal", requireInternalSignature)
+	app.Get("/internal/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Post("/internal/cdc", func(c *fiber.Ctx) error { return c.SendString(string(c.Body())) })
+
+	body := `{"records":[]}`
+	now := time.Now().Unix()
+	send := func(ts int64, secret, sendBody string) int {
+		req := httptest.NewRequest("POST", "/internal/cdc?batch=1", strings.NewReader(sendBody))
+		req.Header.Set(InternalTimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(InternalSignatureHeader, internalSignature(secret, ts, "POST", "/internal/cdc?batch=1", []byte(body)))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+
+	t.Setenv("INTERNAL_SIGNING_SECRET", "")
+	t.Setenv("INTERNAL_SIGNING_SECRET_PREVIOUS", "")
+	if got := send(now, "s1", body); got != fiber.StatusServiceUnavailable {
+		t.Errorf("no secret configured: status %d; want 503", got)
+	}
+
+	t.Setenv("INTERNAL_SIGNING_SECRET", "s2")
+	t.Setenv("INTERNAL_SIGNING_SECRET_PREVIOUS", "s1")
+	cases := []struct {
+		name   string
+		ts     int64
+		secret string
+		body   string
+		want   int
+	}{
+		{"current secret", now, "s2", body, 200},
+		{"previous secret", now, "s1", body, 200},
+		{"unknown secret", now, "s0", body, 401},
+		{"tampered body", now, "s2", `{"records":[{}]}`, 401},
+		{"stale", now - int64(InternalSignatureMaxSkew/time.Second) - 60, "s2", body, 401},
+		{"future", now + int64(InternalSignatureMaxSkew/time.Second) + 60, "s2", body, 401},
+	}
+	for _, tc := range cases {
+		if got := send(tc.ts, tc.secret, tc.body); got != tc.want {
+			t.Errorf("%s: status %d; want %d", tc.name, got, tc.want)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/internal/health", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("unsigned /internal/health: status %d; want 200", resp.StatusCode)
+	}
+}
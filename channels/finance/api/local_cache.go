@@ -0,0 +1,131 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Hot-Key Local Cache
+// =============================================================================
+
+// The all-trades cache (CacheKeyFinance) is the same for every caller
+// and read on every public poll (the public feed, quotes). GetCache
+// keeps it in an in-process LRU for at most LocalCacheTTL in front of
+// Redis, so a burst of polls costs one Redis GET per pod per
+// LocalCacheTTL.
+// It only expires by TTL today; a key published on TopicCacheInvalidate
+// (core's cache_local.go contract) is dropped at once.
+
+const (
+	LocalCacheMaxBytes   = 16 << 20
+	LocalCacheTTL        = 2 * time.Second
+	TopicCacheInvalidate = "cache:invalidate"
+)
+
+// hotCacheKeys are the keys GetCache/SetCache keep locally.
+var hotCacheKeys = map[string]bool{
+	CacheKeyFinance: true,
+}
+
+var localCache = newLRUCache(LocalCacheMaxBytes)
+
+// startCacheInvalidationListener drops local entries named on
+// TopicCacheInvalidate until ctx ends.
+func startCacheInvalidationListener(ctx context.Context, rdb redis.UniversalClient) {
+	sub := rdb.Subscribe(ctx, TopicCacheInvalidate)
+	defer sub.Close()
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var keys []string
+			if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+				log.Printf("[Cache] Malformed invalidation %q: %v", msg.Payload, err)
+				continue
+			}
+			localCache.del(keys...)
+		}
+	}
+}
+
+// lruCache is a byte-budgeted LRU with per-entry expiry.
+type lruCache struct {
+	maxBytes int
+
+	mu    sync.Mutex
+	ll    *list.List // front = most recently used
+	items map[string]*list.Element
+	bytes int
+}
+
+type lruEntry struct {
+	key     string
+	val     []byte
+	expires time.Time
+}
+
+func newLRUCache(maxBytes int) *lruCache {
+	return &lruCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lruCache) get(key string, now time.Time) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if now.After(e.expires) {
+		l.remove(el)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.val, true
+}
+
+func (l *lruCache) set(key string, val []byte, ttl time.Duration, now time.Time) {
+	size := len(key) + len(val)
+	if ttl <= 0 || size > l.maxBytes {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.remove(el)
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, val: val, expires: now.Add(ttl)})
+	l.bytes += size
+	for l.bytes > l.maxBytes {
+		l.remove(l.ll.Back())
+	}
+}
+
+func (l *lruCache) del(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.remove(el)
+		}
+	}
+}
+
+func (l *lruCache) remove(el *list.Element) {
+	e := el.Value.(*lruEntry)
+	l.ll.Remove(el)
+	delete(l.items, e.key)
+	l.bytes -= len(e.key) + len(e.val)
+}
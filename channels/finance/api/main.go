@@ -37,6 +37,25 @@ const (
 	DefaultChannelURL = "http://localhost:8081"
 )
 
+// configSchema describes user_channels.config for this channel. The
+// gateway validates config writes against it (api/core/config_schema.go)
+// and serves it at /channels/{name}/schema, where title and description
+// label the generated settings form. Keys not listed here are accepted
+// unchecked.
+const configSchema = `{
+	"type": "object",
+	"properties": {
+		"symbols": {
+			"type": "array",
+			"title": "Symbols",
+			"description": "Ticker symbols to follow, e.g. AAPL or BTC-USD.",
+			"uniqueItems": true,
+			"items": {"type": "string", "minLength": 1, "maxLength": 20}
+		},
+		"show_on_profile": {"type": "boolean", "title": "Show on public profile"}
+	}
+}`
+
 // registrationPayload is the JSON structure stored in Redis for service discovery.
 type registrationPayload struct {
 	Name         string              `json:"name"`
@@ -45,12 +64,18 @@ type registrationPayload struct {
 	Capabilities []string            `json:"capabilities"`
 	CDCTables    []string            `json:"cdc_tables"`
 	Routes       []registrationRoute `json:"routes"`
+	StartedAt    int64               `json:"started_at"` // Unix ms; lets the gateway detect restarts
+	Version      string              `json:"version"`    // build (GIT_SHA); lets the gateway spot rollouts
+	ConfigSchema json.RawMessage     `json:"config_schema,omitempty"`
 }
 
 type registrationRoute struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 	Auth   bool   `json:"auth"`
+	APIKey bool   `json:"api_key,omitempty"` // gateway authenticates via X-API-Key
+	// CacheTTL (seconds) lets the gateway cache anonymous GETs of a public route.
+	CacheTTL int `json:"cache_ttl,omitempty"`
 }
 
 func main() {
@@ -63,6 +88,15 @@ func main() {
 		defer sentry.Flush(2 * time.Second)
 	}
 
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set (tracing.go).
+	shutdownTracing := initTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+
 	// -------------------------------------------------------------------------
 	// Connect to PostgreSQL
 	// -------------------------------------------------------------------------
@@ -73,18 +107,13 @@ func main() {
 
 	// Bounded pool — the default pgxpool.New sizing (max=4 from runtime.NumCPU)
 	// is too variable across environments and doesn't expose a connect
-	// timeout. A 10-conn ceiling with fast-fail on connect prevents this
-	// service from running the shared Postgres dry when Coolify schedules
-	// multiple channel APIs on the same node.
-	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	// timeout. A 10-conn ceiling (DB_MAX_CONNS, db.go) with fast-fail on
+	// connect prevents this service from running the shared Postgres dry
+	// when Coolify schedules multiple channel APIs on the same node.
+	poolConfig, err := dbPoolConfig(databaseURL)
 	if err != nil {
 		log.Fatalf("[DB] parse config: %v", err)
 	}
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
-	poolConfig.MaxConnLifetime = 30 * time.Minute
-	poolConfig.MaxConnIdleTime = 5 * time.Minute
-	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
 	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatalf("[DB] new pool: %v", err)
@@ -96,26 +125,23 @@ func main() {
 	}
 	log.Println("Connected to PostgreSQL")
 
+	// Optional read replica for catalog, public and dashboard reads (db.go)
+	replica := openReadReplica(context.Background())
+	defer replica.Close()
+
 	// -------------------------------------------------------------------------
 	// Connect to Redis
 	// -------------------------------------------------------------------------
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("REDIS_URL must be set")
-	}
-
-	redisOpts, err := redis.ParseURL(redisURL)
+	rdb, redisMode, err := newRedisClient(os.Getenv)
 	if err != nil {
-		log.Fatalf("Unable to parse REDIS_URL: %v", err)
+		log.Fatalf("Unable to configure Redis: %v", err)
 	}
-
-	rdb := redis.NewClient(redisOpts)
 	defer rdb.Close()
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		log.Fatalf("Unable to connect to Redis: %v", err)
 	}
-	log.Println("Connected to Redis")
+	log.Printf("Connected to Redis (%s)", redisMode)
 
 	// -------------------------------------------------------------------------
 	// Start Redis self-registration heartbeat
@@ -123,8 +149,14 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Read replica health checks; readDB falls back while it lags (db.go)
+	go replica.monitor(ctx)
+
 	go startRegistration(ctx, rdb)
 
+	// Drop hot-key local cache entries other pods invalidate (local_cache.go)
+	go startCacheInvalidationListener(ctx, rdb)
+
 	// -------------------------------------------------------------------------
 	// Setup Fiber HTTP server
 	// -------------------------------------------------------------------------
@@ -142,19 +174,51 @@ func main() {
 		fiberApp.Use(sentryUserHook())
 	}
 
-	app := &App{db: dbPool, rdb: rdb}
+	// Server span per request, continuing the gateway's trace (tracing.go)
+	fiberApp.Use(tracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go)
+	fiberApp.Use(metricsMiddleware)
+
+	app := &App{db: dbPool, replica: replica, rdb: rdb, alerts: newAlertIndex()}
+
+	// Expire old price history buckets (history.go)
+	go startHistoryPruner(ctx, app)
+
+	// Evaluate price alerts against trade CDC events (alerts.go)
+	go app.startAlertWatcher(ctx)
 
 	// Internal routes (called by core gateway only)
+	fiberApp.Use("/internal", requireInternalSignature) // HMAC from core (internal_auth.go)
 	fiberApp.Post("/internal/cdc", app.handleInternalCDC)
 	fiberApp.Get("/internal/dashboard", app.handleInternalDashboard)
 	fiberApp.Get("/internal/health", app.handleInternalHealth)
 	fiberApp.Post("/internal/channel-lifecycle", app.handleChannelLifecycle)
+	fiberApp.Post("/internal/channel-lifecycle/replay", app.handleLifecycleReplay)
+
+	// Prometheus scrape endpoint, cluster-internal only (metrics.go)
+	registerPoolMetrics(app.db, app.rdb)
+	fiberApp.Get("/metrics", requireInternalNetwork, handleMetrics)
 
 	// Public routes (proxied by core gateway)
 	fiberApp.Get("/finance", app.getFinance)
 	fiberApp.Get("/finance/public", app.getFinance) // Unauthenticated: returns all trades (same handler, same cache)
 	fiberApp.Get("/finance/health", app.healthHandler)
 	fiberApp.Get("/finance/symbols", app.getSymbolCatalog)
+	fiberApp.Get("/finance/symbols/search", app.searchSymbolCatalog)
+	fiberApp.Post("/finance/symbols/request", app.requestSymbol)
+	fiberApp.Get("/finance/quotes", app.getQuotes)
+	fiberApp.Get("/finance/history/:symbol", app.getHistory)
+
+	// Protected routes (core gateway sets X-User-Sub header)
+	fiberApp.Get("/users/me/alerts", app.listAlerts)
+	fiberApp.Post("/users/me/alerts", app.createAlert)
+	fiberApp.Post("/users/me/alerts/preview", app.previewAlert)
+	fiberApp.Delete("/users/me/alerts/:id", app.deleteAlert)
+
+	// Admin routes (proxied by core gateway, super_user only — see symbol_requests.go)
+	fiberApp.Get("/admin/finance/symbols/requests", app.adminListSymbolRequests)
+	fiberApp.Put("/admin/finance/symbols/requests", app.adminReviewSymbolRequest)
 
 	// -------------------------------------------------------------------------
 	// Start server with graceful shutdown
@@ -192,7 +256,7 @@ func main() {
 // startRegistration registers this service in Redis with a TTL and refreshes
 // the registration on a ticker. This allows the core gateway to discover
 // available channel services.
-func startRegistration(ctx context.Context, rdb *redis.Client) {
+func startRegistration(ctx context.Context, rdb redis.UniversalClient) {
 	channelURL := os.Getenv("CHANNEL_URL")
 	if channelURL == "" {
 		channelURL = DefaultChannelURL
@@ -202,14 +266,30 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 		Name:         "finance",
 		DisplayName:  "Finance",
 		InternalURL:  channelURL,
-		Capabilities: []string{"cdc_handler", "dashboard_provider", "health_checker", "channel_lifecycle"},
+		Capabilities: []string{"cdc_handler", "dashboard_provider", "health_checker", "channel_lifecycle", "lifecycle_replay"},
 		CDCTables:    []string{"trades"},
 		Routes: []registrationRoute{
 			{Method: "GET", Path: "/finance", Auth: true},
-			{Method: "GET", Path: "/finance/public", Auth: false},
+			{Method: "GET", Path: "/finance/public", Auth: false, CacheTTL: 30},
 			{Method: "GET", Path: "/finance/health", Auth: false},
-			{Method: "GET", Path: "/finance/symbols", Auth: false},
+			{Method: "GET", Path: "/finance/symbols", Auth: false, CacheTTL: 300},
+			// Signed-in only: every lookup spends shared Finnhub quota.
+			{Method: "GET", Path: "/finance/symbols/search", Auth: true},
+			{Method: "POST", Path: "/finance/symbols/request", Auth: true},
+			{Method: "GET", Path: "/finance/quotes", APIKey: true},
+			{Method: "GET", Path: "/finance/history/:symbol", Auth: false, CacheTTL: 60},
+			{Method: "GET", Path: "/users/me/alerts", Auth: true},
+			{Method: "POST", Path: "/users/me/alerts", Auth: true},
+			{Method: "POST", Path: "/users/me/alerts/preview", Auth: true},
+			{Method: "DELETE", Path: "/users/me/alerts/:id", Auth: true},
+			// Symbol request review. Auth: true so the gateway forwards
+			// X-User-Tier; the handlers require super_user.
+			{Method: "GET", Path: "/admin/finance/symbols/requests", Auth: true},
+			{Method: "PUT", Path: "/admin/finance/symbols/requests", Auth: true},
 		},
+		StartedAt:    time.Now().UnixMilli(),
+		Version:      envOr("GIT_SHA", "unknown"),
+		ConfigSchema: json.RawMessage(configSchema),
 	}
 
 	data, err := json.Marshal(payload)
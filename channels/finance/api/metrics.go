@@ -0,0 +1,174 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Prometheus Metrics
+// =============================================================================
+
+// GET /metrics serves this service's metrics in the Prometheus text format.
+// Metric names match the core gateway's, so one scrape job (selected by the
+// prometheus.io/* pod annotations) covers the fleet and the job label tells
+// services apart. Only callers inside the cluster may read it.
+
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "scrollr",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	cdcBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "scrollr",
+		Subsystem: "cdc",
+		Name:      "batch_size",
+		Help:      "Records per /internal/cdc call from the gateway.",
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "scrollr",
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Redis response cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		cdcBatchSize,
+		cacheLookups,
+	)
+}
+
+// registerPoolMetrics exposes the app's Postgres and Redis pool stats.
+func registerPoolMetrics(db *pgxpool.Pool, rdb redis.UniversalClient) {
+	metricsRegistry.MustRegister(&poolStatsCollector{db: db, rdb: rdb})
+}
+
+// countCache records a cache lookup and passes hit through, so it can wrap
+// a GetCache call in place.
+func countCache(cache string, hit bool) bool {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+	return hit
+}
+
+// metricsMiddleware records request latency, labelled by route template
+// rather than raw path to keep cardinality bounded.
+func metricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		if fe, ok := err.(*fiber.Error); ok {
+			status = fe.Code
+		} else {
+			status = fiber.StatusInternalServerError
+		}
+	}
+	httpRequestDuration.WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).
+		Observe(time.Since(start).Seconds())
+	return err
+}
+
+// requireInternalNetwork admits only direct callers from private or
+// loopback addresses. Anything forwarded by a proxy gets a 404.
+func requireInternalNetwork(c *fiber.Ctx) error {
+	forwarded := c.Get(fiber.HeaderXForwardedFor) != "" || c.Get("X-Real-IP") != ""
+	if forwarded || !isInternalIP(c.Context().RemoteIP()) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Not found",
+		})
+	}
+	return c.Next()
+}
+
+func isInternalIP(ip net.IP) bool {
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// handleMetrics serves the Prometheus exposition.
+var handleMetrics = adaptor.HTTPHandler(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+var (
+	dbPoolConnsDesc = prometheus.NewDesc("scrollr_db_pool_conns",
+		"Postgres pool connections by state (acquired, idle, constructing, total, max).", []string{"state"}, nil)
+	dbPoolAcquiresDesc = prometheus.NewDesc("scrollr_db_pool_acquires_total",
+		"Connections acquired from the Postgres pool.", nil, nil)
+	dbPoolEmptyAcquiresDesc = prometheus.NewDesc("scrollr_db_pool_empty_acquires_total",
+		"Acquires that had to wait because the Postgres pool was empty.", nil, nil)
+	dbPoolAcquireSecondsDesc = prometheus.NewDesc("scrollr_db_pool_acquire_seconds_total",
+		"Total time spent acquiring Postgres connections.", nil, nil)
+
+	redisPoolConnsDesc = prometheus.NewDesc("scrollr_redis_pool_conns",
+		"Redis pool connections by state (idle, stale, total).", []string{"state"}, nil)
+	redisPoolLookupsDesc = prometheus.NewDesc("scrollr_redis_pool_lookups_total",
+		"Redis pool connection lookups by result (hit, miss, timeout).", []string{"result"}, nil)
+)
+
+// poolStatsCollector reads pool counters at scrape time.
+type poolStatsCollector struct {
+	db  *pgxpool.Pool
+	rdb redis.UniversalClient
+}
+
+func (p *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbPoolConnsDesc
+	ch <- dbPoolAcquiresDesc
+	ch <- dbPoolEmptyAcquiresDesc
+	ch <- dbPoolAcquireSecondsDesc
+	ch <- redisPoolConnsDesc
+	ch <- redisPoolLookupsDesc
+}
+
+func (p *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	if p.db != nil {
+		s := p.db.Stat()
+		for state, n := range map[string]int32{
+			"acquired":     s.AcquiredConns(),
+			"idle":         s.IdleConns(),
+			"constructing": s.ConstructingConns(),
+			"total":        s.TotalConns(),
+			"max":          s.MaxConns(),
+		} {
+			ch <- prometheus.MustNewConstMetric(dbPoolConnsDesc, prometheus.GaugeValue, float64(n), state)
+		}
+		ch <- prometheus.MustNewConstMetric(dbPoolAcquiresDesc, prometheus.CounterValue, float64(s.AcquireCount()))
+		ch <- prometheus.MustNewConstMetric(dbPoolEmptyAcquiresDesc, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+		ch <- prometheus.MustNewConstMetric(dbPoolAcquireSecondsDesc, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	}
+	if p.rdb != nil {
+		s := p.rdb.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.IdleConns), "idle")
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.StaleConns), "stale")
+		ch <- prometheus.MustNewConstMetric(redisPoolConnsDesc, prometheus.GaugeValue, float64(s.TotalConns), "total")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Hits), "hit")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Misses), "miss")
+		ch <- prometheus.MustNewConstMetric(redisPoolLookupsDesc, prometheus.CounterValue, float64(s.Timeouts), "timeout")
+	}
+}
@@ -12,6 +12,15 @@ type Trade struct {
 	Direction        string    `json:"direction"`
 	LastUpdated      time.Time `json:"last_updated"`
 	Link             string    `json:"link"`
+	// AssetType is "equity" or "crypto". For crypto, PreviousClose is the
+	// price 24 hours ago and the change fields are a rolling 24h change.
+	AssetType string `json:"asset_type"`
+}
+
+// QuotesResponse is the payload of GET /finance/quotes.
+type QuotesResponse struct {
+	Quotes  []Trade  `json:"quotes"`
+	Missing []string `json:"missing"`
 }
 
 // CDCRecord represents a Change Data Capture record from Sequin.
@@ -27,9 +36,10 @@ type CDCRecord struct {
 
 // TrackedSymbol represents a symbol entry from the catalog.
 type TrackedSymbol struct {
-	Symbol   string `json:"symbol"`
-	Name     string `json:"name"`
-	Category string `json:"category"`
+	Symbol    string `json:"symbol"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	AssetType string `json:"asset_type"`
 }
 
 // ErrorResponse represents a standard API error.
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// Symbol Search & Requests
+// =============================================================================
+//
+// Users could only pick from the seeded catalog. These routes let the
+// tracked universe grow from what people actually want:
+//
+//   - GET  /finance/symbols/search?q=    — Finnhub symbol lookup (US
+//     listings), annotated with each result's catalog status
+//   - POST /finance/symbols/request      — ask for a symbol to be tracked;
+//     adds it to tracked_symbols as pending
+//   - GET  /admin/finance/symbols/requests — pending (or ?status=rejected)
+//     requests, oldest first
+//   - PUT  /admin/finance/symbols/requests — approve or reject a request
+//
+// A pending row has is_enabled = FALSE, so the catalog and the ingestion
+// worker ignore it. Approving enables it; the worker picks enabled
+// symbols up on its next catalog refresh without a restart. Requests are
+// equities only: Finnhub's lookup doesn't list crypto pairs.
+//
+// The admin routes follow the rss channel's: registered Auth: true so the
+// gateway forwards X-User-Tier, and each handler requires super_user.
+
+const (
+	// DefaultFinnhubURL is Finnhub's REST base; FINNHUB_API_URL overrides it.
+	DefaultFinnhubURL = "https://finnhub.io/api/v1"
+
+	// FinnhubTimeout bounds one lookup.
+	FinnhubTimeout = 5 * time.Second
+
+	// CacheKeyFinanceSearchPrefix is the Redis key prefix for cached
+	// lookups: cache:finance:search:{QUERY}. Finnhub's free plan allows
+	// 60 calls a minute, shared by every user.
+	CacheKeyFinanceSearchPrefix = "cache:finance:search:"
+
+	// FinanceSearchCacheTTL is how long a lookup is cached. Listings
+	// change rarely; catalog status is read fresh on every request.
+	FinanceSearchCacheTTL = time.Hour
+
+	// MaxSearchQueryLength caps ?q=.
+	MaxSearchQueryLength = 32
+
+	// MaxSearchResults caps the results returned per lookup.
+	MaxSearchResults = 20
+
+	// MaxPendingSymbolRequests caps how many requests one user may have
+	// awaiting review.
+	MaxPendingSymbolRequests = 5
+
+	// RequestedSymbolCategory files approved requests until an admin
+	// picks a category.
+	RequestedSymbolCategory = "Other"
+)
+
+// Catalog states of a tracked_symbols row.
+const (
+	SymbolApproved = "approved"
+	SymbolPending  = "pending"
+	SymbolRejected = "rejected"
+)
+
+// requestableSymbol matches plain US tickers, including class shares
+// (BRK.B) and hyphenated preferreds.
+var requestableSymbol = regexp.MustCompile(`^[A-Z0-9][A-Z0-9.\-]{0,9}$`)
+
+// SymbolSearchResult is one result of GET /finance/symbols/search.
+type SymbolSearchResult struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	// Status is the symbol's catalog state (approved, pending, rejected),
+	// empty when it isn't in the catalog.
+	Status string `json:"status,omitempty"`
+}
+
+// SymbolRequest is a requested symbol as returned by the request and
+// admin routes.
+type SymbolRequest struct {
+	Symbol      string     `json:"symbol"`
+	Name        string     `json:"name"`
+	Category    string     `json:"category"`
+	Status      string     `json:"status"`
+	RequestedBy *string    `json:"requested_by,omitempty"`
+	RequestedAt *time.Time `json:"requested_at,omitempty"`
+}
+
+// reviewSymbolRequest is the body of PUT /admin/finance/symbols/requests.
+// Name and category are optional overrides applied on approval.
+type reviewSymbolRequest struct {
+	Symbol   string  `json:"symbol"`
+	Status   string  `json:"status"`
+	Name     *string `json:"name"`
+	Category *string `json:"category"`
+}
+
+// finnhubSearchResponse is Finnhub's /search payload.
+type finnhubSearchResponse struct {
+	Count  int `json:"count"`
+	Result []struct {
+		Description   string `json:"description"`
+		DisplaySymbol string `json:"displaySymbol"`
+		Symbol        string `json:"symbol"`
+		Type          string `json:"type"`
+	} `json:"result"`
+}
+
+var errFinnhubUnconfigured = errors.New("symbol search is not configured")
+
+var finnhubClient = &http.Client{Timeout: FinnhubTimeout}
+
+// normalizeSymbol uppercases and trims a requested ticker, returning ""
+// when it isn't one.
+func normalizeSymbol(raw string) string {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if !requestableSymbol.MatchString(s) {
+		return ""
+	}
+	return s
+}
+
+// finnhubLookup queries Finnhub's symbol lookup for US listings.
+func finnhubLookup(ctx context.Context, baseURL, apiKey, query string) ([]SymbolSearchResult, error) {
+	u := fmt.Sprintf("%s/search?q=%s&exchange=US&token=%s",
+		strings.TrimRight(baseURL, "/"), url.QueryEscape(query), url.QueryEscape(apiKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := finnhubClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("finnhub returned %d", resp.StatusCode)
+	}
+
+	var body finnhubSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("finnhub decode: %w", err)
+	}
+	results := make([]SymbolSearchResult, 0, len(body.Result))
+	for _, r := range body.Result {
+		if len(results) == MaxSearchResults {
+			break
+		}
+		symbol := normalizeSymbol(r.Symbol)
+		if symbol == "" {
+			continue
+		}
+		results = append(results, SymbolSearchResult{Symbol: symbol, Name: r.Description, Type: r.Type})
+	}
+	return results, nil
+}
+
+// searchSymbols returns the Finnhub results for query, from the Redis
+// cache when warm.
+func (a *App) searchSymbols(ctx context.Context, query string) ([]SymbolSearchResult, error) {
+	apiKey := strings.TrimSpace(os.Getenv("FINNHUB_API_KEY"))
+	if apiKey == "" {
+		return nil, errFinnhubUnconfigured
+	}
+
+	cacheKey := CacheKeyFinanceSearchPrefix + strings.ToUpper(query)
+	var results []SymbolSearchResult
+	if countCache("search", GetCache(a.rdb, cacheKey, &results)) {
+		return results, nil
+	}
+	results, err := finnhubLookup(ctx, envOr("FINNHUB_API_URL", DefaultFinnhubURL), apiKey, query)
+	if err != nil {
+		return nil, err
+	}
+	SetCache(a.rdb, cacheKey, results, FinanceSearchCacheTTL)
+	return results, nil
+}
+
+// catalogStatuses returns the catalog state of each given symbol that is
+// in tracked_symbols.
+func (a *App) catalogStatuses(ctx context.Context, symbols []string) (map[string]string, error) {
+	rows, err := a.readDB().Query(ctx, "SELECT symbol, status FROM tracked_symbols WHERE symbol = ANY($1)", symbols)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]string, len(symbols))
+	for rows.Next() {
+		var symbol, status string
+		if err := rows.Scan(&symbol, &status); err != nil {
+			return nil, err
+		}
+		statuses[symbol] = status
+	}
+	return statuses, rows.Err()
+}
+
+// searchErrorResponse maps a lookup failure to a response.
+func searchErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errFinnhubUnconfigured) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Symbol search is not available",
+		})
+	}
+	log.Printf("[Symbols] Finnhub lookup failed: %v", err)
+	return c.Status(fiber.StatusBadGateway).JSON(ErrorResponse{
+		Status: "error",
+		Error:  "Symbol search failed",
+	})
+}
+
+// searchSymbolCatalog handles GET /finance/symbols/search?q=.
+func (a *App) searchSymbolCatalog(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || len(query) > MaxSearchQueryLength {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  fmt.Sprintf("q must be 1-%d characters", MaxSearchQueryLength),
+		})
+	}
+
+	ctx := c.Context()
+	results, err := a.searchSymbols(ctx, query)
+	if err != nil {
+		return searchErrorResponse(c, err)
+	}
+
+	symbols := make([]string, len(results))
+	for i, r := range results {
+		symbols[i] = r.Symbol
+	}
+	statuses, err := a.catalogStatuses(ctx, symbols)
+	if err != nil {
+		// Results are still useful without the annotation.
+		log.Printf("[Symbols] catalog status lookup failed: %v", err)
+	}
+	for i := range results {
+		results[i].Status = statuses[results[i].Symbol]
+	}
+	return c.JSON(results)
+}
+
+// requestSymbol handles POST /finance/symbols/request with {"symbol"}.
+// A symbol already in the catalog is reported as-is (200); a new one is
+// added as pending (202).
+func (a *App) requestSymbol(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var body struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	symbol := normalizeSymbol(body.Symbol)
+	if symbol == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "symbol must be a US ticker of up to 10 characters",
+		})
+	}
+
+	ctx := c.Context()
+	if existing, err := a.getSymbolRequest(ctx, symbol); err == nil {
+		return c.JSON(existing)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[Symbols] lookup of %s failed: %v", symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	var pending int
+	if err := a.db.QueryRow(ctx,
+		"SELECT count(*) FROM tracked_symbols WHERE status = $1 AND requested_by = $2",
+		SymbolPending, userSub,
+	).Scan(&pending); err != nil {
+		log.Printf("[Symbols] pending count failed for %s: %v", userSub, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	if pending >= MaxPendingSymbolRequests {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":   "too many symbol requests awaiting review",
+			"current": pending,
+			"max":     MaxPendingSymbolRequests,
+		})
+	}
+
+	// Only symbols Finnhub lists as US listings can be requested, so the
+	// review queue holds real tickers with a name attached.
+	results, err := a.searchSymbols(ctx, symbol)
+	if err != nil {
+		return searchErrorResponse(c, err)
+	}
+	var listing *SymbolSearchResult
+	for i := range results {
+		if results[i].Symbol == symbol {
+			listing = &results[i]
+			break
+		}
+	}
+	if listing == nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "Unknown symbol",
+		})
+	}
+
+	name := listing.Name
+	if name == "" {
+		name = symbol
+	}
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO tracked_symbols (symbol, name, category, is_enabled, status, requested_by, requested_at)
+		VALUES ($1, $2, $3, FALSE, $4, $5, now())
+		ON CONFLICT (symbol) DO NOTHING`,
+		symbol, name, RequestedSymbolCategory, SymbolPending, userSub,
+	); err != nil {
+		log.Printf("[Symbols] request insert failed for %s: %v", symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to request symbol",
+		})
+	}
+
+	// Re-read rather than trusting the insert: a concurrent request for
+	// the same symbol may have won the race.
+	req, err := a.getSymbolRequest(ctx, symbol)
+	if err != nil {
+		log.Printf("[Symbols] re-read of %s failed: %v", symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	log.Printf("[Symbols] %s requested %s", userSub, symbol)
+	return c.Status(fiber.StatusAccepted).JSON(req)
+}
+
+// getSymbolRequest reads one tracked_symbols row as a SymbolRequest. The
+// requester is left out; it is only shown to admins.
+func (a *App) getSymbolRequest(ctx context.Context, symbol string) (SymbolRequest, error) {
+	r := SymbolRequest{Symbol: symbol}
+	err := a.db.QueryRow(ctx, `
+		SELECT COALESCE(name, symbol), COALESCE(category, 'Other'), status, requested_at
+		FROM tracked_symbols WHERE symbol = $1`, symbol,
+	).Scan(&r.Name, &r.Category, &r.Status, &r.RequestedAt)
+	return r, err
+}
+
+// adminListSymbolRequests handles GET /admin/finance/symbols/requests.
+func (a *App) adminListSymbolRequests(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	status := c.Query("status", SymbolPending)
+	if status != SymbolPending && status != SymbolRejected {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "status must be pending or rejected",
+		})
+	}
+
+	rows, err := a.db.Query(c.Context(), `
+		SELECT symbol, COALESCE(name, symbol), COALESCE(category, 'Other'), status, requested_by, requested_at
+		FROM tracked_symbols
+		WHERE status = $1
+		ORDER BY requested_at ASC NULLS LAST, symbol`, status)
+	if err != nil {
+		log.Printf("[Symbols] admin list failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list symbol requests",
+		})
+	}
+	defer rows.Close()
+
+	requests := make([]SymbolRequest, 0)
+	for rows.Next() {
+		var r SymbolRequest
+		if err := rows.Scan(&r.Symbol, &r.Name, &r.Category, &r.Status, &r.RequestedBy, &r.RequestedAt); err != nil {
+			log.Printf("[Symbols] admin list scan error: %v", err)
+			continue
+		}
+		requests = append(requests, r)
+	}
+	return c.JSON(requests)
+}
+
+// normalizeReview trims and validates a review in place. Returns a
+// user-facing error message, or "" when the review is valid.
+func normalizeReview(req *reviewSymbolRequest) string {
+	req.Symbol = normalizeSymbol(req.Symbol)
+	if req.Symbol == "" {
+		return "Request body must include a valid 'symbol' field"
+	}
+	if req.Status != SymbolApproved && req.Status != SymbolRejected {
+		return "status must be approved or rejected"
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len([]rune(name)) > 100 {
+			return "name must be 1-100 characters"
+		}
+		req.Name = &name
+	}
+	if req.Category != nil {
+		category := strings.TrimSpace(*req.Category)
+		if category == "" || len([]rune(category)) > 50 {
+			return "category must be 1-50 characters"
+		}
+		req.Category = &category
+	}
+	return ""
+}
+
+// adminReviewSymbolRequest handles PUT /admin/finance/symbols/requests.
+// Pending and previously rejected requests can be reviewed; seeded
+// symbols can't be rejected through here.
+func (a *App) adminReviewSymbolRequest(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	var req reviewSymbolRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if msg := normalizeReview(&req); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  msg,
+		})
+	}
+
+	ctx := c.Context()
+	var r SymbolRequest
+	err := a.db.QueryRow(ctx, `
+		UPDATE tracked_symbols
+		SET status = $2,
+		    is_enabled = ($2 = 'approved'),
+		    name = COALESCE($3, name),
+		    category = COALESCE($4, category)
+		WHERE symbol = $1 AND status IN ('pending', 'rejected')
+		RETURNING symbol, COALESCE(name, symbol), COALESCE(category, 'Other'), status, requested_by, requested_at`,
+		req.Symbol, req.Status, req.Name, req.Category,
+	).Scan(&r.Symbol, &r.Name, &r.Category, &r.Status, &r.RequestedBy, &r.RequestedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "not_found",
+			Error:  "No pending or rejected request for that symbol",
+		})
+	}
+	if err != nil {
+		log.Printf("[Symbols] review of %s failed: %v", req.Symbol, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to review symbol request",
+		})
+	}
+
+	// Approved symbols appear in the catalog straight away.
+	if err := a.rdb.Del(ctx, CacheKeyFinanceCatalog).Err(); err != nil {
+		log.Printf("[Symbols] catalog cache invalidation failed: %v", err)
+	}
+	log.Printf("[Symbols] Admin %s marked %s %s", c.Get("X-User-Sub"), r.Symbol, r.Status)
+	return c.JSON(r)
+}
+
+// requireSuperUser rejects callers the gateway didn't identify as super
+// users. Returns false after writing the response.
+func requireSuperUser(c *fiber.Ctx) bool {
+	if c.Get("X-User-Sub") == "" {
+		c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+		return false
+	}
+	if GetUserTier(c) != TierSuperUser {
+		c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "Admin access required",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	for raw, want := range map[string]string{
+		" aapl ":      "AAPL",
+		"BRK.B":       "BRK.B",
+		"PBR-A":       "PBR-A",
+		"":            "",
+		"BTC/USD":     "",
+		".SPX":        "",
+		"AAPL.MX:BMV": "",
+		"TOOLONGTICK": "",
+	} {
+		if got := normalizeSymbol(raw); got != want {
+			t.Errorf("normalizeSymbol(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestFinnhubLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("q") != "apple inc" ||
+			r.URL.Query().Get("exchange") != "US" || r.URL.Query().Get("token") != "k" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"count":3,"result":[
+			{"description":"APPLE INC","displaySymbol":"AAPL","symbol":"AAPL","type":"Common Stock"},
+			{"description":"APPLE INC","displaySymbol":"AAPL.SW","symbol":"AAPL.SW:XSWX","type":"Common Stock"},
+			{"description":"APPLE HOSPITALITY REIT","displaySymbol":"APLE","symbol":"APLE","type":"REIT"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	results, err := finnhubLookup(context.Background(), srv.URL+"/", "k", "apple inc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want AAPL and APLE", results)
+	}
+	if results[0] != (SymbolSearchResult{Symbol: "AAPL", Name: "APPLE INC", Type: "Common Stock"}) {
+		t.Errorf("first result = %+v", results[0])
+	}
+
+	if _, err := finnhubLookup(context.Background(), srv.URL, "wrong", "apple inc"); err == nil {
+		t.Error("non-200 response not reported")
+	}
+}
+
+func TestNormalizeReview(t *testing.T) {
+	name := " Palantir "
+	req := reviewSymbolRequest{Symbol: "pltr", Status: SymbolApproved, Name: &name}
+	if msg := normalizeReview(&req); msg != "" {
+		t.Fatalf("valid review rejected: %s", msg)
+	}
+	if req.Symbol != "PLTR" || *req.Name != "Palantir" {
+		t.Errorf("fields not normalised: %+v", req)
+	}
+
+	for _, bad := range []reviewSymbolRequest{
+		{Symbol: "", Status: SymbolApproved},
+		{Symbol: "PLTR", Status: SymbolPending},
+		{Symbol: "PLTR", Status: SymbolRejected, Category: new(string)},
+	} {
+		if msg := normalizeReview(&bad); msg == "" {
+			t.Errorf("invalid review accepted: %+v", bad)
+		}
+	}
+}
+
+func TestRequireSuperUser(t *testing.T) {
+	app := fiber.New()
+	app.Get("/admin/finance/symbols/requests", func(c *fiber.Ctx) error {
+		if !requireSuperUser(c) {
+			return nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		sub, tier string
+		want      int
+	}{
+		{"", "", fiber.StatusUnauthorized},
+		{"u1", "", fiber.StatusForbidden},
+		{"u1", TierUplinkUltimate, fiber.StatusForbidden},
+		{"u1", TierSuperUser, fiber.StatusNoContent},
+	} {
+		req := httptest.NewRequest("GET", "/admin/finance/symbols/requests", nil)
+		if tc.sub != "" {
+			req.Header.Set("X-User-Sub", tc.sub)
+		}
+		if tc.tier != "" {
+			req.Header.Set("X-User-Tier", tc.tier)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("sub=%q tier=%q: status = %d, want %d", tc.sub, tc.tier, resp.StatusCode, tc.want)
+		}
+	}
+}
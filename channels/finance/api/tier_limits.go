@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Tier Limits — Symbols
+// =============================================================================
+//
+// Mirrors the `Symbols` column of api/core/tier_limits.go DefaultTierLimits.
+// Kept package-local on purpose: each Go module is independently deployable
+// and cross-module imports are banned by AGENTS.md. When the authoritative
+// table in api/core/tier_limits.go changes, update this file too.
+//
+// Only used for reporting (quota headers on the catalog endpoint) — the
+// core gateway enforces the cap when the user saves their channel config.
+//
+// Semantics:
+//   - Positive integer: hard cap.
+//   - -1: unlimited (matches the JSON `null` cap exposed by /tier-limits).
+//
+// Unknown tiers fall through to "free" as a defensive default.
+
+const (
+	TierFree           = "free"
+	TierUplink         = "uplink"
+	TierUplinkPro      = "uplink_pro"
+	TierUplinkUltimate = "uplink_ultimate"
+	TierSuperUser      = "super_user"
+)
+
+// SymbolCap returns the symbols cap for a tier. -1 means unlimited.
+func SymbolCap(tier string) int {
+	switch tier {
+	case TierSuperUser, TierUplinkUltimate:
+		return -1
+	case TierUplinkPro:
+		return 75
+	case TierUplink:
+		return 25
+	default:
+		return 5
+	}
+}
+
+// AlertCap returns how many price alerts a tier may keep. -1 means
+// unlimited. Unlike symbols this cap is enforced here, by POST
+// /users/me/alerts; it isn't part of the core table yet.
+func AlertCap(tier string) int {
+	switch tier {
+	case TierSuperUser:
+		return -1
+	case TierUplinkUltimate:
+		return 100
+	case TierUplinkPro:
+		return 25
+	case TierUplink:
+		return 10
+	default:
+		return 3
+	}
+}
+
+// GetUserTier reads the X-User-Tier header set by the core gateway.
+// Returns "free" if the header is not present.
+func GetUserTier(c *fiber.Ctx) string {
+	tier := c.Get("X-User-Tier")
+	if tier == "" {
+		return TierFree
+	}
+	return tier
+}
+
+// setQuotaHeaders reports the caller's usage against their plan cap so
+// clients can disable "add" buttons before the save is rejected. Headers
+// rather than body fields keep the catalog payload shape (a bare array)
+// unchanged for existing clients.
+func setQuotaHeaders(c *fiber.Ctx, resource string, used, limit int) {
+	c.Set("X-Quota-Resource", resource)
+	c.Set("X-Quota-Used", strconv.Itoa(used))
+	if limit < 0 {
+		c.Set("X-Quota-Limit", "unlimited")
+	} else {
+		c.Set("X-Quota-Limit", strconv.Itoa(limit))
+	}
+}
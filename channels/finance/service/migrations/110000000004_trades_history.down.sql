@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trades_history_record ON trades;
+DROP FUNCTION IF EXISTS record_trade_history();
+DROP TABLE IF EXISTS trades_history;
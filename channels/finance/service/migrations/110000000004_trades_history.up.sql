@@ -0,0 +1,37 @@
+-- Per-minute OHLC history for each symbol, the source for
+-- GET /finance/history/:symbol. A trigger on trades folds every price
+-- update from the ingestion worker into the current minute's bucket, so
+-- the worker's write path is unchanged. The finance API downsamples
+-- buckets per range and prunes rows past the longest range.
+CREATE TABLE IF NOT EXISTS trades_history (
+    symbol VARCHAR(30) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    open DECIMAL(10,2) NOT NULL,
+    high DECIMAL(10,2) NOT NULL,
+    low DECIMAL(10,2) NOT NULL,
+    close DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (symbol, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS trades_history_bucket_idx ON trades_history (bucket);
+
+CREATE OR REPLACE FUNCTION record_trade_history() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO trades_history (symbol, bucket, open, high, low, close)
+    VALUES (NEW.symbol, date_trunc('minute', COALESCE(NEW.last_updated, now())),
+            NEW.price, NEW.price, NEW.price, NEW.price)
+    ON CONFLICT (symbol, bucket) DO UPDATE SET
+        high = GREATEST(trades_history.high, EXCLUDED.high),
+        low = LEAST(trades_history.low, EXCLUDED.low),
+        close = EXCLUDED.close;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Zero is the placeholder price insert_symbol seeds before the first tick.
+DROP TRIGGER IF EXISTS trades_history_record ON trades;
+CREATE TRIGGER trades_history_record
+    AFTER UPDATE OF price ON trades
+    FOR EACH ROW
+    WHEN (NEW.price > 0)
+    EXECUTE FUNCTION record_trade_history();
@@ -0,0 +1,25 @@
+-- User-defined price alerts, served by the finance API under
+-- /users/me/alerts. The API's watcher listens to the same cdc:finance:*
+-- topics the gateway publishes trade updates on and fires an alert when
+-- its condition becomes true.
+--
+-- armed makes alerts edge-triggered: firing clears it and the watcher
+-- only re-arms an alert once its condition is false again. The clearing
+-- UPDATE is conditional on armed, so with several API replicas watching
+-- the same topics exactly one of them delivers each alert.
+CREATE TABLE IF NOT EXISTS price_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    logto_sub VARCHAR(255) NOT NULL,
+    symbol VARCHAR(30) NOT NULL,
+    condition VARCHAR(12) NOT NULL CHECK (condition IN ('above', 'below', 'change_pct')),
+    threshold DECIMAL(12,4) NOT NULL,
+    webhook_url TEXT,
+    webhook_secret VARCHAR(64),
+    armed BOOLEAN NOT NULL DEFAULT TRUE,
+    trigger_count INTEGER NOT NULL DEFAULT 0,
+    last_triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS price_alerts_user_idx ON price_alerts (logto_sub, created_at DESC);
+CREATE INDEX IF NOT EXISTS price_alerts_symbol_idx ON price_alerts (symbol);
@@ -0,0 +1,3 @@
+ALTER TABLE trades DROP COLUMN IF EXISTS asset_type;
+ALTER TABLE tracked_symbols DROP COLUMN IF EXISTS crypto_exchange;
+ALTER TABLE tracked_symbols DROP COLUMN IF EXISTS asset_type;
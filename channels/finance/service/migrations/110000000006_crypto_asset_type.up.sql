@@ -0,0 +1,16 @@
+-- Crypto trades around the clock and has no official close, so it is
+-- ingested from an exchange ticker (Binance or Coinbase) instead of
+-- TwelveData, and its change is measured against the price 24 hours ago.
+-- asset_type routes each symbol to its ingestion path and tells clients
+-- which kind of change they are looking at.
+ALTER TABLE tracked_symbols ADD COLUMN IF NOT EXISTS asset_type VARCHAR(10) NOT NULL DEFAULT 'equity';
+-- Exchange whose ticker feeds a crypto symbol; NULL uses CRYPTO_EXCHANGE.
+ALTER TABLE tracked_symbols ADD COLUMN IF NOT EXISTS crypto_exchange VARCHAR(20);
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS asset_type VARCHAR(10) NOT NULL DEFAULT 'equity';
+
+UPDATE tracked_symbols SET asset_type = 'crypto'
+WHERE category = 'Crypto' OR symbol LIKE '%/%';
+
+UPDATE trades t SET asset_type = ts.asset_type
+FROM tracked_symbols ts
+WHERE ts.symbol = t.symbol AND t.asset_type IS DISTINCT FROM ts.asset_type;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS tracked_symbols_requests_idx;
+ALTER TABLE tracked_symbols DROP COLUMN IF EXISTS requested_at;
+ALTER TABLE tracked_symbols DROP COLUMN IF EXISTS requested_by;
+ALTER TABLE tracked_symbols DROP COLUMN IF EXISTS status;
@@ -0,0 +1,11 @@
+-- User-requested symbols. POST /finance/symbols/request adds a row with
+-- status 'pending' and is_enabled = FALSE, so neither the catalog nor the
+-- ingestion worker sees it until an admin approves it (status
+-- 'approved', is_enabled = TRUE) through the finance API. Seeded and
+-- existing symbols are 'approved'.
+ALTER TABLE tracked_symbols ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'approved';
+ALTER TABLE tracked_symbols ADD COLUMN IF NOT EXISTS requested_by VARCHAR(255);
+ALTER TABLE tracked_symbols ADD COLUMN IF NOT EXISTS requested_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS tracked_symbols_requests_idx
+    ON tracked_symbols (status, requested_at) WHERE status <> 'approved';
@@ -0,0 +1,378 @@
+//! Crypto ingestion from exchange tickers (Binance, Coinbase).
+//!
+//! Crypto trades around the clock and has no official close, so the
+//! TwelveData path (subscribe, then compare against previous_close) is a
+//! poor fit: the "change" froze at whatever close TwelveData reported and
+//! drifted further from reality every hour the equity market was shut.
+//! Instead each crypto symbol streams from its exchange's 24h ticker, and
+//! previous_close is rewritten with the ticker's price from 24 hours ago,
+//! so price_change / percentage_change are a rolling 24h change.
+//!
+//! `CRYPTO_EXCHANGE` picks the default exchange (coinbase); a symbol's
+//! `crypto_exchange` column overrides it. Binance has no USD books, so a
+//! BTC/USD symbol streams BTCUSDT there.
+
+use std::{collections::HashMap, env, sync::Arc, time::Duration};
+
+use anyhow::Result;
+use futures_util::{SinkExt, StreamExt};
+use serde::Deserialize;
+use tokio::time;
+use tokio_tungstenite::{
+    connect_async_with_config,
+    tungstenite::protocol::{Message, WebSocketConfig},
+};
+use crate::database::{PgPool, get_tracked_crypto, insert_symbol, update_crypto_trade};
+use crate::log::{error, info, warn};
+
+/// Ticks are coalesced per symbol and written at most this often; the
+/// Coinbase ticker fires on every trade, far faster than a ticker scrolls.
+const FLUSH_INTERVAL: Duration = Duration::from_secs(2);
+
+/// Delay before reconnecting a dropped feed. Binance closes every
+/// connection after 24 hours, so reconnects are routine.
+const RECONNECT_DELAY: Duration = Duration::from_secs(30);
+
+/// Same cap as the TwelveData socket: ticker messages are well under 1 KiB.
+const MAX_WS_MESSAGE_BYTES: usize = 1 << 20;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum CryptoExchange {
+    Binance,
+    Coinbase,
+}
+
+impl CryptoExchange {
+    pub fn parse(name: &str) -> Option<Self> {
+        match name.trim().to_lowercase().as_str() {
+            "binance" => Some(Self::Binance),
+            "coinbase" => Some(Self::Coinbase),
+            _ => None,
+        }
+    }
+
+    pub fn name(self) -> &'static str {
+        match self {
+            Self::Binance => "binance",
+            Self::Coinbase => "coinbase",
+        }
+    }
+
+    /// WebSocket base URL, overridable per exchange (e.g. to point Binance
+    /// at stream.binance.us, or either at a local mock).
+    fn ws_url(self) -> String {
+        let (var, default) = match self {
+            Self::Binance => ("CRYPTO_BINANCE_WS_URL", "wss://stream.binance.com:9443"),
+            Self::Coinbase => ("CRYPTO_COINBASE_WS_URL", "wss://ws-feed.exchange.coinbase.com"),
+        };
+        env::var(var)
+            .ok()
+            .map(|u| u.trim().trim_end_matches('/').to_string())
+            .filter(|u| !u.is_empty())
+            .unwrap_or_else(|| default.to_string())
+    }
+
+    /// The exchange's market id for a tracked pair: BTC/USD is "BTC-USD"
+    /// on Coinbase and "btcusdt" on Binance. None when the symbol isn't a
+    /// pair or the exchange can't list it (USDT/USD on Binance).
+    pub fn market_id(self, symbol: &str) -> Option<String> {
+        let (base, quote) = symbol.split_once('/')?;
+        let (base, quote) = (base.trim().to_uppercase(), quote.trim().to_uppercase());
+        if base.is_empty() || quote.is_empty() {
+            return None;
+        }
+        match self {
+            Self::Coinbase => Some(format!("{}-{}", base, quote)),
+            Self::Binance => {
+                let quote = if quote == "USD" { "USDT".to_string() } else { quote };
+                if base == quote {
+                    return None;
+                }
+                Some(format!("{}{}", base, quote).to_lowercase())
+            }
+        }
+    }
+}
+
+/// The default exchange from `CRYPTO_EXCHANGE`, Coinbase when unset or
+/// unrecognised.
+fn default_exchange() -> CryptoExchange {
+    match env::var("CRYPTO_EXCHANGE") {
+        Ok(name) if !name.trim().is_empty() => CryptoExchange::parse(&name).unwrap_or_else(|| {
+            warn!("[Crypto] Unknown CRYPTO_EXCHANGE '{}', using coinbase", name);
+            CryptoExchange::Coinbase
+        }),
+        _ => CryptoExchange::Coinbase,
+    }
+}
+
+/// Splits tracked crypto symbols by exchange, as market id -> symbol.
+/// Symbols the chosen exchange can't list are logged and left out.
+pub fn group_by_exchange(
+    symbols: &[(String, Option<String>)],
+    default: CryptoExchange,
+) -> HashMap<CryptoExchange, HashMap<String, String>> {
+    let mut groups: HashMap<CryptoExchange, HashMap<String, String>> = HashMap::new();
+    for (symbol, choice) in symbols {
+        let exchange = match choice.as_deref() {
+            Some(name) => CryptoExchange::parse(name).unwrap_or_else(|| {
+                warn!("[Crypto] Unknown exchange '{}' for {}, using {}", name, symbol, default.name());
+                default
+            }),
+            None => default,
+        };
+        match exchange.market_id(symbol) {
+            Some(market) => {
+                groups.entry(exchange).or_default().insert(market, symbol.clone());
+            }
+            None => warn!("[Crypto] {} has no {} market, skipping", symbol, exchange.name()),
+        }
+    }
+    groups
+}
+
+// =============================================================================
+// Ticker messages
+// =============================================================================
+
+/// One ticker update: the last price and the price 24 hours ago.
+#[derive(Debug, Clone, PartialEq)]
+pub struct Tick {
+    pub market: String,
+    pub price: f64,
+    pub open_24h: f64,
+}
+
+/// Coinbase `ticker` channel message.
+#[derive(Debug, Deserialize)]
+struct CoinbaseTicker {
+    #[serde(rename = "type")]
+    kind: String,
+    product_id: Option<String>,
+    price: Option<String>,
+    open_24h: Option<String>,
+    message: Option<String>,
+}
+
+/// Binance combined-stream envelope around a `<symbol>@ticker` event.
+#[derive(Debug, Deserialize)]
+struct BinanceEnvelope {
+    data: BinanceTicker,
+}
+
+#[derive(Debug, Deserialize)]
+struct BinanceTicker {
+    /// Market, e.g. "BTCUSDT".
+    s: String,
+    /// Last price.
+    c: String,
+    /// Open price 24 hours ago.
+    o: String,
+}
+
+/// Parses a ticker message. Subscription acks, heartbeats and anything
+/// without both prices yield None.
+pub fn parse_tick(exchange: CryptoExchange, text: &str) -> Option<Tick> {
+    match exchange {
+        CryptoExchange::Coinbase => {
+            let msg: CoinbaseTicker = serde_json::from_str(text).ok()?;
+            if msg.kind == "error" {
+                warn!("[Crypto] coinbase error: {}", msg.message.unwrap_or_default());
+                return None;
+            }
+            if msg.kind != "ticker" {
+                return None;
+            }
+            Some(Tick {
+                market: msg.product_id?,
+                price: msg.price?.parse().ok()?,
+                open_24h: msg.open_24h?.parse().ok()?,
+            })
+        }
+        CryptoExchange::Binance => {
+            let msg: BinanceEnvelope = serde_json::from_str(text).ok()?;
+            Some(Tick {
+                market: msg.data.s.to_lowercase(),
+                price: msg.data.c.parse().ok()?,
+                open_24h: msg.data.o.parse().ok()?,
+            })
+        }
+    }
+}
+
+/// The rolling 24h change of a tick as (price_change, percentage_change,
+/// direction). None when either price is missing.
+pub fn rolling_change(price: f64, open_24h: f64) -> Option<(f64, f64, &'static str)> {
+    if price <= 0.0 || open_24h <= 0.0 {
+        return None;
+    }
+    let change = price - open_24h;
+    let direction = if change >= 0.0 { "up" } else { "down" };
+    Some((change, change / open_24h * 100.0, direction))
+}
+
+// =============================================================================
+// Feeds
+// =============================================================================
+
+/// Starts one ticker feed per exchange with tracked crypto symbols. Each
+/// feed reconnects on its own; a failing exchange never touches the
+/// TwelveData connection.
+pub async fn start_crypto_feeds(pool: Arc<PgPool>) {
+    let symbols = get_tracked_crypto(pool.clone()).await;
+    if symbols.is_empty() {
+        info!("[Crypto] No crypto symbols tracked");
+        return;
+    }
+    for (symbol, _) in &symbols {
+        let _ = insert_symbol(pool.clone(), symbol.clone()).await;
+    }
+
+    for (exchange, markets) in group_by_exchange(&symbols, default_exchange()) {
+        let pool = pool.clone();
+        tokio::spawn(async move {
+            loop {
+                match run_feed(exchange, &markets, &pool).await {
+                    Ok(()) => warn!("[Crypto] {} ticker disconnected, reconnecting...", exchange.name()),
+                    Err(e) => error!("[Crypto] {} ticker failed: {e:#}, reconnecting...", exchange.name()),
+                }
+                time::sleep(RECONNECT_DELAY).await;
+            }
+        });
+    }
+}
+
+/// Streams one exchange's tickers until the connection drops.
+async fn run_feed(exchange: CryptoExchange, markets: &HashMap<String, String>, pool: &Arc<PgPool>) -> Result<()> {
+    let mut ids: Vec<&String> = markets.keys().collect();
+    ids.sort();
+
+    let url = match exchange {
+        CryptoExchange::Binance => {
+            let streams: Vec<String> = ids.iter().map(|m| format!("{}@ticker", m)).collect();
+            format!("{}/stream?streams={}", exchange.ws_url(), streams.join("/"))
+        }
+        CryptoExchange::Coinbase => exchange.ws_url(),
+    };
+    let ws_config = WebSocketConfig::default()
+        .max_message_size(Some(MAX_WS_MESSAGE_BYTES))
+        .max_frame_size(Some(MAX_WS_MESSAGE_BYTES));
+    let (ws_stream, _) = connect_async_with_config(url, Some(ws_config), false).await?;
+    let (mut writer, mut reader) = ws_stream.split();
+
+    // Binance subscribes through the URL; Coinbase wants a message.
+    if exchange == CryptoExchange::Coinbase {
+        let sub = serde_json::json!({
+            "type": "subscribe",
+            "product_ids": ids,
+            "channels": ["ticker"],
+        });
+        writer.send(Message::Text(sub.to_string().into())).await?;
+    }
+    info!("[Crypto] Streaming {} symbol(s) from {}", markets.len(), exchange.name());
+
+    let mut pending: HashMap<String, Tick> = HashMap::new();
+    let mut flush = time::interval(FLUSH_INTERVAL);
+    loop {
+        tokio::select! {
+            _ = flush.tick() => flush_ticks(pool, markets, &mut pending).await,
+            msg = reader.next() => match msg {
+                Some(Ok(Message::Text(text))) => {
+                    if let Some(tick) = parse_tick(exchange, &text) {
+                        pending.insert(tick.market.clone(), tick);
+                    }
+                }
+                Some(Ok(Message::Close(_))) | None => break,
+                Some(Ok(_)) => {}
+                Some(Err(e)) => {
+                    flush_ticks(pool, markets, &mut pending).await;
+                    return Err(e.into());
+                }
+            }
+        }
+    }
+    flush_ticks(pool, markets, &mut pending).await;
+    Ok(())
+}
+
+/// Writes the latest tick of each symbol that moved since the last flush.
+async fn flush_ticks(pool: &Arc<PgPool>, markets: &HashMap<String, String>, pending: &mut HashMap<String, Tick>) {
+    for (market, tick) in pending.drain() {
+        let Some(symbol) = markets.get(&market) else {
+            continue;
+        };
+        let Some((change, pct, direction)) = rolling_change(tick.price, tick.open_24h) else {
+            continue;
+        };
+        if let Err(e) = update_crypto_trade(pool.clone(), symbol, tick.price, tick.open_24h, change, pct, direction).await {
+            warn!("[Crypto] Failed to update {}: {}", symbol, e);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_market_id() {
+        assert_eq!(CryptoExchange::Coinbase.market_id("BTC/USD").as_deref(), Some("BTC-USD"));
+        assert_eq!(CryptoExchange::Binance.market_id("BTC/USD").as_deref(), Some("btcusdt"));
+        assert_eq!(CryptoExchange::Binance.market_id("eth/btc").as_deref(), Some("ethbtc"));
+        assert_eq!(CryptoExchange::Binance.market_id("USDT/USD"), None);
+        assert_eq!(CryptoExchange::Coinbase.market_id("AAPL"), None);
+        assert_eq!(CryptoExchange::Coinbase.market_id("/USD"), None);
+    }
+
+    #[test]
+    fn test_group_by_exchange() {
+        let symbols = vec![
+            ("BTC/USD".to_string(), None),
+            ("ETH/USD".to_string(), Some("Binance".to_string())),
+            ("USDT/USD".to_string(), Some("binance".to_string())),
+            ("SOL/USD".to_string(), Some("kraken".to_string())),
+        ];
+        let groups = group_by_exchange(&symbols, CryptoExchange::Coinbase);
+        let coinbase = &groups[&CryptoExchange::Coinbase];
+        assert_eq!(coinbase.len(), 2);
+        assert_eq!(coinbase["BTC-USD"], "BTC/USD");
+        assert_eq!(coinbase["SOL-USD"], "SOL/USD");
+        let binance = &groups[&CryptoExchange::Binance];
+        assert_eq!(binance.len(), 1);
+        assert_eq!(binance["ethusdt"], "ETH/USD");
+    }
+
+    #[test]
+    fn test_parse_coinbase_tick() {
+        let text = r#"{"type":"ticker","sequence":1,"product_id":"BTC-USD","price":"67012.34","open_24h":"65000.00","volume_24h":"1.0"}"#;
+        let tick = parse_tick(CryptoExchange::Coinbase, text).unwrap();
+        assert_eq!(tick, Tick { market: "BTC-USD".to_string(), price: 67012.34, open_24h: 65000.0 });
+
+        let ack = r#"{"type":"subscriptions","channels":[{"name":"ticker","product_ids":["BTC-USD"]}]}"#;
+        assert!(parse_tick(CryptoExchange::Coinbase, ack).is_none());
+    }
+
+    #[test]
+    fn test_parse_binance_tick() {
+        let text = r#"{"stream":"btcusdt@ticker","data":{"e":"24hrTicker","E":1,"s":"BTCUSDT","p":"12.3","c":"67012.34","o":"65000.00"}}"#;
+        let tick = parse_tick(CryptoExchange::Binance, text).unwrap();
+        assert_eq!(tick, Tick { market: "btcusdt".to_string(), price: 67012.34, open_24h: 65000.0 });
+
+        assert!(parse_tick(CryptoExchange::Binance, r#"{"result":null,"id":1}"#).is_none());
+    }
+
+    #[test]
+    fn test_rolling_change() {
+        let (change, pct, direction) = rolling_change(110.0, 100.0).unwrap();
+        assert!((change - 10.0).abs() < 1e-9);
+        assert!((pct - 10.0).abs() < 1e-9);
+        assert_eq!(direction, "up");
+
+        let (_, pct, direction) = rolling_change(95.0, 100.0).unwrap();
+        assert!((pct + 5.0).abs() < 1e-9);
+        assert_eq!(direction, "down");
+
+        assert!(rolling_change(100.0, 0.0).is_none());
+        assert!(rolling_change(0.0, 100.0).is_none());
+    }
+}
@@ -191,8 +191,46 @@ pub async fn get_tracked_symbols(pool: Arc<PgPool>) -> Vec<String> {
     }
 }
 
+/// Returns the enabled equity symbols: the TwelveData subscription list.
+/// Crypto symbols stream from an exchange ticker instead (crypto.rs).
+pub async fn get_tracked_equities(pool: Arc<PgPool>) -> Vec<String> {
+    let statement = "SELECT symbol FROM tracked_symbols WHERE is_enabled = TRUE AND asset_type = 'equity'";
+    let res: Result<Vec<(String,)>, sqlx::Error> = async {
+        let mut connection = pool.acquire().await?;
+        let data = query_as(statement).fetch_all(&mut *connection).await?;
+        Ok(data)
+    }.await;
+
+    match res {
+        Ok(data) => data.into_iter().map(|(s,)| s).collect(),
+        Err(e) => {
+            log::error!("Failed to get tracked equities: {}", e);
+            Vec::new()
+        }
+    }
+}
+
+/// Returns the enabled crypto symbols with their per-symbol exchange
+/// override (NULL means the CRYPTO_EXCHANGE default).
+pub async fn get_tracked_crypto(pool: Arc<PgPool>) -> Vec<(String, Option<String>)> {
+    let statement = "SELECT symbol, crypto_exchange FROM tracked_symbols WHERE is_enabled = TRUE AND asset_type = 'crypto'";
+    let res: Result<Vec<(String, Option<String>)>, sqlx::Error> = async {
+        let mut connection = pool.acquire().await?;
+        let data = query_as(statement).fetch_all(&mut *connection).await?;
+        Ok(data)
+    }.await;
+
+    match res {
+        Ok(data) => data,
+        Err(e) => {
+            log::error!("Failed to get tracked crypto symbols: {}", e);
+            Vec::new()
+        }
+    }
+}
+
 pub async fn seed_tracked_symbols(pool: Arc<PgPool>, symbols: Vec<crate::types::TrackedSymbolConfig>) -> Result<()> {
-    let statement = "INSERT INTO tracked_symbols (symbol, name, category, exchange) VALUES ($1, $2, $3, $4) ON CONFLICT (symbol) DO UPDATE SET name = EXCLUDED.name, category = EXCLUDED.category, exchange = COALESCE(EXCLUDED.exchange, tracked_symbols.exchange)";
+    let statement = "INSERT INTO tracked_symbols (symbol, name, category, exchange, asset_type, crypto_exchange) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (symbol) DO UPDATE SET name = EXCLUDED.name, category = EXCLUDED.category, exchange = COALESCE(EXCLUDED.exchange, tracked_symbols.exchange), asset_type = EXCLUDED.asset_type, crypto_exchange = COALESCE(EXCLUDED.crypto_exchange, tracked_symbols.crypto_exchange)";
     let mut connection = pool.acquire().await?;
     for entry in symbols {
         query(statement)
@@ -200,6 +238,8 @@ pub async fn seed_tracked_symbols(pool: Arc<PgPool>, symbols: Vec<crate::types::
             .bind(&entry.name)
             .bind(&entry.category)
             .bind(&entry.exchange)
+            .bind(entry.asset_type())
+            .bind(&entry.crypto_exchange)
             .execute(&mut *connection)
             .await?;
     }
@@ -207,7 +247,9 @@ pub async fn seed_tracked_symbols(pool: Arc<PgPool>, symbols: Vec<crate::types::
 }
 
 pub async fn insert_symbol(pool: Arc<PgPool>, symbol: String) -> Result<()> {
-    let statement = "INSERT INTO trades (symbol, price, previous_close, price_change, percentage_change, direction) VALUES ($1, 0, 0, 0, 0, 'flat') ON CONFLICT (symbol) DO NOTHING";
+    // asset_type is copied from the catalog so clients can tell a crypto
+    // row's rolling 24h change from an equity's change since the close.
+    let statement = "INSERT INTO trades (symbol, price, previous_close, price_change, percentage_change, direction, asset_type) VALUES ($1, 0, 0, 0, 0, 'flat', COALESCE((SELECT asset_type FROM tracked_symbols WHERE symbol = $1), 'equity')) ON CONFLICT (symbol) DO NOTHING";
     let mut connection = pool.acquire().await?;
     query(statement).bind(symbol).execute(&mut *connection).await?;
     Ok(())
@@ -227,6 +269,16 @@ pub async fn update_trade(pool: Arc<PgPool>, symbol: String, price: f64, price_c
     Ok(())
 }
 
+/// Writes a crypto tick. previous_close holds the price 24 hours ago, so
+/// the change columns are the rolling 24h change rather than a change
+/// since a close crypto doesn't have.
+pub async fn update_crypto_trade(pool: Arc<PgPool>, symbol: &str, price: f64, open_24h: f64, price_change: f64, percentage_change: f64, direction: &str) -> Result<()> {
+    let statement = "UPDATE trades SET price = $1, previous_close = $2, price_change = $3, percentage_change = $4, direction = $5, last_updated = CURRENT_TIMESTAMP WHERE symbol = $6";
+    let mut connection = pool.acquire().await?;
+    query(statement).bind(price).bind(open_24h).bind(price_change).bind(percentage_change).bind(direction).bind(symbol).execute(&mut *connection).await?;
+    Ok(())
+}
+
 pub async fn get_trades(pool: Arc<PgPool>) -> Vec<DatabaseTradeData> {
     let statement = "
         SELECT
@@ -7,7 +7,7 @@ use tokio::{sync::Mutex, time::{self, sleep}};
 use crate::log::{error, info, warn};
 use crate::database::{
     PgPool, insert_symbol, update_previous_close, update_trade, get_tracked_symbols,
-    seed_tracked_symbols, get_symbols_without_exchange, get_all_enabled_symbols,
+    seed_tracked_symbols, get_symbols_without_exchange, get_all_enabled_symbols, get_tracked_equities,
     update_symbol_exchange_link,
 };
 
@@ -15,6 +15,7 @@ use crate::{types::{FinanceHealth, FinanceState, QuoteResponse, TrackedSymbolCon
 
 pub mod types;
 mod websocket;
+pub mod crypto;
 pub mod log;
 pub mod database;
 pub mod init;
@@ -38,6 +39,10 @@ pub async fn start_finance_services(pool: Arc<PgPool>, health_state: Arc<Mutex<F
     // Initialization with database-driven state
     let state = FinanceState::new(Arc::clone(&pool)).await;
     initialize_symbols(state.clone()).await;
+
+    // Crypto streams from exchange tickers rather than TwelveData; the
+    // feeds run on their own tasks and reconnect independently.
+    crypto::start_crypto_feeds(pool.clone()).await;
     
     // Fetch exchange metadata for symbols that don't have it yet
     fetch_exchange_metadata(state.clone()).await;
@@ -69,7 +74,10 @@ pub async fn start_finance_services(pool: Arc<PgPool>, health_state: Arc<Mutex<F
     });
 
     loop {
-        match connect(state.subscriptions.clone(), state.api_key.clone(), state.client.clone(), pool.clone(), health_state.clone()).await {
+        // Re-read the catalog on every (re)connect so symbols approved
+        // since startup are part of the subscription.
+        let subscriptions = get_tracked_equities(pool.clone()).await;
+        match connect(subscriptions, state.api_key.clone(), state.client.clone(), pool.clone(), health_state.clone()).await {
             Ok(()) => {
                 error!("WebSocket disconnected, attempting reconnect in 5 minutes...");
             }
@@ -100,12 +108,15 @@ async fn initialize_symbols(state: FinanceState) {
 }
 
 pub async fn update_all_previous_closes(state: FinanceState) {
-    info!("Updating previous closes for {} symbols...", state.subscriptions.len());
+    // Read the catalog fresh: symbols approved since startup need their
+    // close refreshed too.
+    let symbols = get_tracked_equities(state.pool.clone()).await;
+    info!("Updating previous closes for {} symbols...", symbols.len());
 
     // TwelveData Pro tier: 610 API credits/min, 500 WS symbols.
     // Batch 8 at a time with 1s delay to stay within limits.
     let batch_size = 8;
-    for batch in state.subscriptions.chunks(batch_size) {
+    for batch in symbols.chunks(batch_size) {
         time::sleep(Duration::from_millis(1_000)).await;
         let futures: Vec<_> = batch.iter().map(|symbol| {
             let client = state.client.clone();
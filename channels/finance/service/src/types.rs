@@ -14,6 +14,27 @@ pub struct TrackedSymbolConfig {
     pub category: String,
     #[serde(default)]
     pub exchange: Option<String>,
+    /// "equity" or "crypto"; inferred from the entry when omitted or
+    /// unrecognised.
+    #[serde(default)]
+    pub asset_type: Option<String>,
+    /// Exchange whose ticker feeds a crypto symbol ("binance" or
+    /// "coinbase"); omitted uses CRYPTO_EXCHANGE.
+    #[serde(default)]
+    pub crypto_exchange: Option<String>,
+}
+
+impl TrackedSymbolConfig {
+    /// The entry's asset type. Entries without a valid one are crypto when filed
+    /// under "Crypto" or written as a pair (BTC/USD), equities otherwise.
+    pub fn asset_type(&self) -> &str {
+        match self.asset_type.as_deref() {
+            Some("crypto") => "crypto",
+            Some("equity") => "equity",
+            _ if self.category == "Crypto" || self.symbol.contains('/') => "crypto",
+            _ => "equity",
+        }
+    }
 }
 
 /// TwelveData /stocks endpoint response.
@@ -160,8 +181,9 @@ impl FinanceState {
             }
         };
 
-        // Load symbols from database instead of file
-        let subscriptions = crate::database::get_tracked_symbols(pool.clone()).await;
+        // Load symbols from database instead of file. Only equities go to
+        // TwelveData; crypto symbols stream from an exchange (crypto.rs).
+        let subscriptions = crate::database::get_tracked_equities(pool.clone()).await;
 
         Self {
             api_key,
@@ -221,6 +243,26 @@ mod tests {
     use super::*;
 
 
+    fn symbol_config(symbol: &str, category: &str, asset_type: Option<&str>) -> TrackedSymbolConfig {
+        TrackedSymbolConfig {
+            symbol: symbol.to_string(),
+            name: symbol.to_string(),
+            category: category.to_string(),
+            exchange: None,
+            asset_type: asset_type.map(str::to_string),
+            crypto_exchange: None,
+        }
+    }
+
+    #[test]
+    fn test_tracked_symbol_asset_type() {
+        assert_eq!(symbol_config("AAPL", "Technology", None).asset_type(), "equity");
+        assert_eq!(symbol_config("BTC/USD", "Crypto", None).asset_type(), "crypto");
+        assert_eq!(symbol_config("SOL/EUR", "Other", None).asset_type(), "crypto");
+        assert_eq!(symbol_config("COIN", "Crypto", Some("equity")).asset_type(), "equity");
+        assert_eq!(symbol_config("BTC/USD", "Crypto", Some("bogus")).asset_type(), "crypto");
+    }
+
     #[test]
     fn test_quote_response_success() {
         let qr = QuoteResponse {
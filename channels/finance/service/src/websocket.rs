@@ -1,4 +1,4 @@
-use std::{collections::HashMap, sync::{Arc, atomic::{AtomicU64, Ordering}}, time::{Duration, Instant}};
+use std::{collections::{HashMap, HashSet}, sync::{Arc, atomic::{AtomicU64, Ordering}}, time::{Duration, Instant}};
 
 use reqwest::Client;
 use tokio::{net::TcpStream, sync::{Mutex, RwLock}, time};
@@ -7,7 +7,7 @@ use tokio_tungstenite::{
     tungstenite::protocol::{Message, WebSocketConfig},
 };
 use futures_util::{SinkExt, StreamExt, stream::{self, SplitSink, SplitStream}};
-use crate::{database::{PgPool, DatabaseTradeData, Utc, get_trades, insert_symbol, update_previous_close, update_trade}, log::{error, info, warn}};
+use crate::{database::{PgPool, DatabaseTradeData, Utc, get_tracked_equities, get_trades, insert_symbol, update_previous_close, update_trade}, log::{error, info, warn}};
 
 /// Maximum WebSocket message / frame size we will accept from TwelveData.
 /// The real feed sends ~200 byte price events; anything larger is either a
@@ -26,6 +26,10 @@ const LOG_THROTTLE_INTERVAL: Duration = Duration::from_secs(5);
 /// Interval between heartbeat messages sent to TwelveData (30 seconds).
 const HEARTBEAT_INTERVAL: Duration = Duration::from_secs(30);
 
+/// Interval between checks for symbols enabled since the connection was
+/// opened, e.g. user requests an admin approved through the finance API.
+const CATALOG_REFRESH_INTERVAL: Duration = Duration::from_secs(300);
+
 pub(crate) async fn connect(subscriptions: Vec<String>, api_key: String, client: Arc<Client>, pool: Arc<PgPool>, health_state: Arc<Mutex<FinanceHealth>>) -> Result<(), anyhow::Error> {
     let state = Arc::new(RwLock::new(WebSocketState::new()));
 
@@ -64,11 +68,14 @@ pub(crate) async fn connect(subscriptions: Vec<String>, api_key: String, client:
     // Subscribe to all symbols in one message. Done inline rather than
     // via `tokio::spawn` — the send is a few microseconds and we want its
     // failure to surface here instead of vanishing into a detached task.
-    ws_send(Arc::clone(&writer), subscriptions).await?;
+    ws_send(Arc::clone(&writer), subscriptions.clone()).await?;
 
     // Spawn heartbeat task
     tokio::spawn(ws_heartbeat(Arc::clone(&writer)));
 
+    // Subscribe symbols approved while connected (approved requests)
+    tokio::spawn(ws_watch_catalog(Arc::clone(&writer), Arc::clone(&pool), subscriptions));
+
     ws_read(reader, Arc::clone(&state), client, api_key, pool, health_state.clone()).await;
 
     Ok(())
@@ -111,6 +118,38 @@ async fn ws_heartbeat(writer: Arc<Mutex<SplitSink<WebSocketStream<MaybeTlsStream
     }
 }
 
+/// Subscribe symbols that were enabled in tracked_symbols after connect,
+/// so an approved symbol request starts streaming without a restart.
+/// Exits, like the heartbeat, once a send fails.
+async fn ws_watch_catalog(
+    writer: Arc<Mutex<SplitSink<WebSocketStream<MaybeTlsStream<TcpStream>>, Message>>>,
+    pool: Arc<PgPool>,
+    subscribed: Vec<String>,
+) {
+    let mut known: HashSet<String> = subscribed.into_iter().collect();
+    loop {
+        time::sleep(CATALOG_REFRESH_INTERVAL).await;
+        let added = new_symbols(&known, get_tracked_equities(Arc::clone(&pool)).await);
+        if added.is_empty() {
+            continue;
+        }
+        info!("Catalog gained {} symbol(s): {}", added.len(), added.join(","));
+        for symbol in &added {
+            let _ = insert_symbol(Arc::clone(&pool), symbol.clone()).await;
+        }
+        if let Err(e) = ws_send(Arc::clone(&writer), added.clone()).await {
+            warn!("Catalog subscribe failed (connection may be closing): {e}");
+            break;
+        }
+        known.extend(added);
+    }
+}
+
+/// Symbols in `current` that aren't subscribed yet, in catalog order.
+fn new_symbols(known: &HashSet<String>, current: Vec<String>) -> Vec<String> {
+    current.into_iter().filter(|s| !known.contains(s)).collect()
+}
+
 async fn ws_read(
     mut reader: SplitStream<WebSocketStream<MaybeTlsStream<TcpStream>>>,
     state: Arc<RwLock<WebSocketState>>,
@@ -490,3 +529,16 @@ async fn process_single_trade(trade: TradeData, trades_map: Arc<HashMap<String,
 
     Ok(())
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_new_symbols() {
+        let known: HashSet<String> = ["AAPL", "MSFT"].iter().map(|s| s.to_string()).collect();
+        let current = vec!["AAPL".to_string(), "PLTR".to_string(), "MSFT".to_string(), "RKLB".to_string()];
+        assert_eq!(new_symbols(&known, current), vec!["PLTR".to_string(), "RKLB".to_string()]);
+        assert!(new_symbols(&known, vec!["AAPL".to_string()]).is_empty());
+    }
+}
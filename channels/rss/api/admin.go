@@ -0,0 +1,336 @@
+// Package main — operator API for the curated feed catalog.
+//
+// The curated catalog is the set of tracked_feeds rows with
+// is_default = true. Before this file the only way to change it was SQL
+// against the prod DB (or a feeds.json edit plus an ingestion restart).
+// These routes let super users curate it through the gateway:
+//
+//   - GET    /admin/rss/feeds        — every curated feed, including
+//     disabled and failing ones, with full health columns
+//   - POST   /admin/rss/feeds        — add a curated feed, or promote an
+//     existing custom feed to curated
+//   - PUT    /admin/rss/feeds        — rename, recategorise, or
+//     enable/disable a curated feed
+//   - POST   /admin/rss/feeds/reset  — clear consecutive_failures and the
+//     last error on any tracked feed
+//
+// Curated feeds are not deleted here: users may have them pinned in
+// user_channels.config, and disabling hides them from every catalog
+// without orphaning those configs (the same call the janitor makes for
+// broken curated feeds).
+//
+// Auth: the gateway forwards X-User-Tier derived from the caller's JWT
+// roles and never passes a client-supplied value through, so the routes
+// are registered Auth: true and each handler requires the super_user tier.
+//
+// Caveat: the ingestion service re-seeds feeds.json on startup and forces
+// those rows back to is_enabled = true. Disabling a feed that ships in
+// feeds.json only lasts until the next ingestion restart; remove it from
+// feeds.json to make that permanent.
+
+package main
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultFeedCategory matches the tracked_feeds.category column default.
+	DefaultFeedCategory = "General"
+
+	// MaxFeedNameLength and MaxFeedCategoryLength bound operator input so
+	// a paste accident can't put a novel in every user's catalog picker.
+	MaxFeedNameLength     = 200
+	MaxFeedCategoryLength = 64
+)
+
+// adminFeedColumns is the column list scanned by scanAdminFeed.
+const adminFeedColumns = `url, name, category, is_default, is_enabled, consecutive_failures,
+	last_error, last_error_at, last_success_at, added_by, language, created_at,
+	requested_interval_secs, publisher_interval_secs, effective_interval_secs, next_poll_at`
+
+// adminFeedRequest is the body for the admin write routes. Only URL is
+// required on PUT and reset; nil fields are left unchanged on PUT.
+type adminFeedRequest struct {
+	URL       string  `json:"url"`
+	Name      *string `json:"name"`
+	Category  *string `json:"category"`
+	IsEnabled *bool   `json:"is_enabled"`
+}
+
+// requireSuperUser rejects callers the gateway didn't identify as super
+// users. Returns false after writing the response.
+func requireSuperUser(c *fiber.Ctx) bool {
+	if c.Get("X-User-Sub") == "" {
+		c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+		return false
+	}
+	if GetUserTier(c) != TierSuperUser {
+		c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Status: "forbidden",
+			Error:  "Admin access required",
+		})
+		return false
+	}
+	return true
+}
+
+// validFeedURL reports whether raw is an absolute http(s) URL with a host.
+func validFeedURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// normalizeAdminFeed trims the request's text fields and validates them.
+// create requires a name and fills in the default category; otherwise
+// only the fields present are checked. Returns a user-facing error
+// message, or "" when the request is valid.
+func normalizeAdminFeed(req *adminFeedRequest, create bool) string {
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" {
+		return "Request body must include a non-empty 'url' field"
+	}
+	if create && !validFeedURL(req.URL) {
+		return "url must be an absolute http or https URL"
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len([]rune(name)) > MaxFeedNameLength {
+			return "name must be 1-200 characters"
+		}
+		req.Name = &name
+	} else if create {
+		return "Request body must include a non-empty 'name' field"
+	}
+
+	if req.Category != nil {
+		category := strings.TrimSpace(*req.Category)
+		if category == "" || len([]rune(category)) > MaxFeedCategoryLength {
+			return "category must be 1-64 characters"
+		}
+		req.Category = &category
+	} else if create {
+		category := DefaultFeedCategory
+		req.Category = &category
+	}
+
+	if !create && req.Name == nil && req.Category == nil && req.IsEnabled == nil {
+		return "Nothing to update: provide name, category, or is_enabled"
+	}
+	return ""
+}
+
+// scanAdminFeed scans one row selected with adminFeedColumns.
+func scanAdminFeed(row pgx.Row) (AdminFeed, error) {
+	var f AdminFeed
+	err := row.Scan(&f.URL, &f.Name, &f.Category, &f.IsDefault, &f.IsEnabled, &f.ConsecutiveFailures,
+		&f.LastError, &f.LastErrorAt, &f.LastSuccessAt, &f.AddedBy, &f.Language, &f.CreatedAt,
+		&f.RequestedIntervalSecs, &f.PublisherIntervalSecs, &f.EffectiveIntervalSecs, &f.NextPollAt)
+	return f, err
+}
+
+// adminListFeeds returns the whole curated catalog, health filters off.
+func (a *App) adminListFeeds(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	ctx := c.Context()
+
+	rows, err := a.db.Query(ctx,
+		"SELECT "+adminFeedColumns+" FROM tracked_feeds WHERE is_default = true ORDER BY category, name")
+	if err != nil {
+		log.Printf("[RSS Admin] List curated feeds failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to list feeds",
+		})
+	}
+	defer rows.Close()
+
+	feeds := make([]AdminFeed, 0)
+	for rows.Next() {
+		f, err := scanAdminFeed(rows)
+		if err != nil {
+			log.Printf("[RSS Admin] Feed scan error: %v", err)
+			continue
+		}
+		feeds = append(feeds, f)
+	}
+	return c.JSON(feeds)
+}
+
+// adminCreateFeed adds a curated feed. A URL already tracked as a custom
+// feed is promoted in place so its rss_items and health history survive;
+// a URL that is already curated is a 409.
+func (a *App) adminCreateFeed(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	ctx := c.Context()
+
+	var req adminFeedRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if msg := normalizeAdminFeed(&req, true); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  msg,
+		})
+	}
+	enabled := req.IsEnabled == nil || *req.IsEnabled
+
+	// The conditional DO UPDATE returns no row when the URL is already
+	// curated, which is how the 409 is detected without a second query.
+	feed, err := scanAdminFeed(a.db.QueryRow(ctx, `
+		INSERT INTO tracked_feeds (url, name, category, is_default, is_enabled, added_by)
+		VALUES ($1, $2, $3, true, $4, $5)
+		ON CONFLICT (url) DO UPDATE SET
+			name = EXCLUDED.name,
+			category = EXCLUDED.category,
+			is_default = true,
+			is_enabled = EXCLUDED.is_enabled
+		WHERE tracked_feeds.is_default = false
+		RETURNING `+adminFeedColumns,
+		req.URL, *req.Name, *req.Category, enabled, c.Get("X-User-Sub")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feed is already in the curated catalog",
+		})
+	}
+	if err != nil {
+		log.Printf("[RSS Admin] Create curated feed %s failed: %v", req.URL, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to create feed",
+		})
+	}
+
+	// A promoted custom feed would otherwise appear twice for its
+	// subscribers: once curated, once from user_custom_feeds. The catalog
+	// query already filters that case; dropping the rows keeps
+	// user_custom_feeds honest (and the Feeds quota count accurate).
+	if cmd, err := a.db.Exec(ctx, "DELETE FROM user_custom_feeds WHERE url = $1", req.URL); err != nil {
+		log.Printf("[RSS Admin] Failed to clear custom-feed rows for promoted %s: %v", req.URL, err)
+	} else if cmd.RowsAffected() > 0 {
+		log.Printf("[RSS Admin] Promoted custom feed %s to curated (%d subscriber rows cleared)", req.URL, cmd.RowsAffected())
+	}
+
+	a.invalidateAllCatalogCaches(ctx)
+	log.Printf("[RSS Admin] %s added curated feed %s", c.Get("X-User-Sub"), req.URL)
+	return c.Status(fiber.StatusCreated).JSON(feed)
+}
+
+// adminUpdateFeed renames, recategorises, or enables/disables a curated
+// feed. Custom feeds are per-user and not editable here.
+func (a *App) adminUpdateFeed(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	ctx := c.Context()
+
+	var req adminFeedRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	if msg := normalizeAdminFeed(&req, false); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  msg,
+		})
+	}
+
+	feed, err := scanAdminFeed(a.db.QueryRow(ctx, `
+		UPDATE tracked_feeds SET
+			name = COALESCE($2::text, name),
+			category = COALESCE($3::text, category),
+			is_enabled = COALESCE($4::boolean, is_enabled)
+		WHERE url = $1 AND is_default = true
+		RETURNING `+adminFeedColumns,
+		req.URL, req.Name, req.Category, req.IsEnabled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Curated feed not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[RSS Admin] Update curated feed %s failed: %v", req.URL, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to update feed",
+		})
+	}
+
+	a.invalidateAllCatalogCaches(ctx)
+	log.Printf("[RSS Admin] %s updated curated feed %s (enabled=%v)", c.Get("X-User-Sub"), req.URL, feed.IsEnabled)
+	return c.JSON(feed)
+}
+
+// adminResetFeedFailures clears a feed's failure counter and last error
+// so the ingestion service polls it again (quarantine is
+// consecutive_failures >= 288) and the catalog health filter stops hiding
+// it. Works on custom feeds too, since operators may want to rescue one
+// before the janitor removes it. Does not re-enable a disabled feed; use
+// PUT for that.
+func (a *App) adminResetFeedFailures(c *fiber.Ctx) error {
+	if !requireSuperUser(c) {
+		return nil
+	}
+	ctx := c.Context()
+
+	var req adminFeedRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.URL) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Request body must include a non-empty 'url' field",
+		})
+	}
+	req.URL = strings.TrimSpace(req.URL)
+
+	feed, err := scanAdminFeed(a.db.QueryRow(ctx, `
+		UPDATE tracked_feeds SET
+			consecutive_failures = 0,
+			last_error = NULL,
+			last_error_at = NULL
+		WHERE url = $1
+		RETURNING `+adminFeedColumns,
+		req.URL))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feed not found",
+		})
+	}
+	if err != nil {
+		log.Printf("[RSS Admin] Reset failures for %s failed: %v", req.URL, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to reset feed",
+		})
+	}
+
+	a.invalidateAllCatalogCaches(ctx)
+	log.Printf("[RSS Admin] %s reset failure tracking for %s", c.Get("X-User-Sub"), req.URL)
+	return c.JSON(feed)
+}
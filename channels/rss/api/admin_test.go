@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestRequireSuperUser(t *testing.T) {
+	app := fiber.New()
+	app.Get("/admin/rss/feeds", func(c *fiber.Ctx) error {
+		if !requireSuperUser(c) {
+			return nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	tests := []struct {
+		name string
+		sub  string
+		tier string
+		want int
+	}{
+		{"anonymous", "", "", fiber.StatusUnauthorized},
+		{"tier without sub", "", TierSuperUser, fiber.StatusUnauthorized},
+		{"free user", "u1", "", fiber.StatusForbidden},
+		{"ultimate user", "u1", TierUplinkUltimate, fiber.StatusForbidden},
+		{"super user", "u1", TierSuperUser, fiber.StatusNoContent},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/rss/feeds", nil)
+			if tc.sub != "" {
+				req.Header.Set("X-User-Sub", tc.sub)
+			}
+			if tc.tier != "" {
+				req.Header.Set("X-User-Tier", tc.tier)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tc.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAdminFeedCreate(t *testing.T) {
+	req := adminFeedRequest{URL: "  https://example.com/rss  ", Name: strPtr(" Example ")}
+	if msg := normalizeAdminFeed(&req, true); msg != "" {
+		t.Fatalf("valid create rejected: %s", msg)
+	}
+	if req.URL != "https://example.com/rss" || *req.Name != "Example" {
+		t.Errorf("fields not trimmed: url=%q name=%q", req.URL, *req.Name)
+	}
+	if req.Category == nil || *req.Category != DefaultFeedCategory {
+		t.Errorf("category = %v, want default %q", req.Category, DefaultFeedCategory)
+	}
+
+	rejected := []adminFeedRequest{
+		{URL: "", Name: strPtr("x")},
+		{URL: "ftp://example.com/rss", Name: strPtr("x")},
+		{URL: "example.com/rss", Name: strPtr("x")},
+		{URL: "https://example.com/rss"},
+		{URL: "https://example.com/rss", Name: strPtr("   ")},
+		{URL: "https://example.com/rss", Name: strPtr(strings.Repeat("a", MaxFeedNameLength+1))},
+		{URL: "https://example.com/rss", Name: strPtr("x"), Category: strPtr("")},
+	}
+	for _, r := range rejected {
+		r := r
+		if msg := normalizeAdminFeed(&r, true); msg == "" {
+			t.Errorf("create accepted %+v", r)
+		}
+	}
+}
+
+func TestNormalizeAdminFeedUpdate(t *testing.T) {
+	enabled := false
+	req := adminFeedRequest{URL: "https://example.com/rss", IsEnabled: &enabled}
+	if msg := normalizeAdminFeed(&req, false); msg != "" {
+		t.Errorf("toggle-only update rejected: %s", msg)
+	}
+	if req.Name != nil || req.Category != nil {
+		t.Error("update filled in fields that were not sent")
+	}
+
+	empty := adminFeedRequest{URL: "https://example.com/rss"}
+	if msg := normalizeAdminFeed(&empty, false); msg == "" {
+		t.Error("update with no fields accepted")
+	}
+}
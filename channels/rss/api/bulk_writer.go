@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// Bulk Writer
+// =============================================================================
+//
+// BulkWriter is the upsert path for ingestion bursts. Rows are buffered and
+// flushed when either BulkWriterMaxRows accumulate or BulkWriterMaxDelay
+// passes. Each flush COPYs the batch into a transaction-scoped staging table
+// and merges it into the target with a single INSERT ... ON CONFLICT, so a
+// burst costs one round-trip per batch instead of one statement per row.
+//
+// Duplicate keys within a batch are collapsed before the COPY (last row
+// wins); Postgres refuses to update the same row twice in one statement.
+//
+// Nothing writes through it yet — ingestion still lives in the Rust
+// service. Writers are registered on the App so /internal/bulk-writer/stats
+// can report throughput once they are.
+
+const (
+	// BulkWriterMaxRows flushes a batch once this many rows are buffered.
+	BulkWriterMaxRows = 5000
+
+	// BulkWriterMaxDelay flushes a non-empty batch after this long even if
+	// it is below BulkWriterMaxRows.
+	BulkWriterMaxDelay = 500 * time.Millisecond
+
+	// BulkWriterQueueSize bounds rows waiting for the batcher. Add blocks
+	// when it is full, pushing back on the producer.
+	BulkWriterQueueSize = 4 * BulkWriterMaxRows
+
+	// BulkWriterFlushTimeout bounds a single COPY + merge.
+	BulkWriterFlushTimeout = 30 * time.Second
+)
+
+// BulkTable describes an upsert target. KeyColumns must match a unique
+// index on the table. A nil UpdateColumns updates every non-key column;
+// an empty one makes conflicts DO NOTHING.
+type BulkTable struct {
+	Name          string
+	Columns       []string
+	KeyColumns    []string
+	UpdateColumns []string
+}
+
+// BulkWriterStats is the throughput snapshot for one writer.
+type BulkWriterStats struct {
+	Table         string  `json:"table"`
+	RowsQueued    int64   `json:"rows_queued"`
+	RowsWritten   int64   `json:"rows_written"`
+	RowsCollapsed int64   `json:"rows_collapsed"`
+	Batches       int64   `json:"batches"`
+	FailedBatches int64   `json:"failed_batches"`
+	LastBatchRows int64   `json:"last_batch_rows"`
+	LastFlushMs   float64 `json:"last_flush_ms"`
+	RowsPerSecond float64 `json:"rows_per_second"` // rows written per second spent flushing
+}
+
+// BulkWriter batches rows for one table. Create with NewBulkWriter and
+// start with Run.
+type BulkWriter struct {
+	db    *pgxpool.Pool
+	table BulkTable
+	in    chan []any
+
+	rowsQueued    atomic.Int64
+	rowsWritten   atomic.Int64
+	rowsCollapsed atomic.Int64
+	batches       atomic.Int64
+	failedBatches atomic.Int64
+	lastBatchRows atomic.Int64
+	lastFlushNs   atomic.Int64
+	flushNs       atomic.Int64
+}
+
+// NewBulkWriter validates the table description and returns a writer.
+func NewBulkWriter(db *pgxpool.Pool, table BulkTable) (*BulkWriter, error) {
+	if len(table.Columns) == 0 || len(table.KeyColumns) == 0 {
+		return nil, fmt.Errorf("bulk writer %s: columns and key columns are required", table.Name)
+	}
+	for _, k := range table.KeyColumns {
+		if !slices.Contains(table.Columns, k) {
+			return nil, fmt.Errorf("bulk writer %s: key column %q not in columns", table.Name, k)
+		}
+	}
+	if table.UpdateColumns == nil {
+		for _, col := range table.Columns {
+			if !slices.Contains(table.KeyColumns, col) {
+				table.UpdateColumns = append(table.UpdateColumns, col)
+			}
+		}
+	}
+	return &BulkWriter{
+		db:    db,
+		table: table,
+		in:    make(chan []any, BulkWriterQueueSize),
+	}, nil
+}
+
+// Add queues one row, with values in table.Columns order. Blocks while
+// the queue is full.
+func (w *BulkWriter) Add(ctx context.Context, row []any) error {
+	if len(row) != len(w.table.Columns) {
+		return fmt.Errorf("bulk writer %s: got %d values, want %d", w.table.Name, len(row), len(w.table.Columns))
+	}
+	select {
+	case w.in <- row:
+		w.rowsQueued.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run batches and flushes rows until ctx is cancelled, then flushes what
+// is left.
+func (w *BulkWriter) Run(ctx context.Context) {
+	ticker := time.NewTicker(BulkWriterMaxDelay)
+	defer ticker.Stop()
+
+	batch := make([][]any, 0, BulkWriterMaxRows)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.flush(ctx, batch); err != nil {
+			log.Printf("[BulkWriter] %s: flush of %d rows failed: %v", w.table.Name, len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Drain what producers already queued.
+		drain:
+			for {
+				select {
+				case row := <-w.in:
+					batch = append(batch, row)
+				default:
+					break drain
+				}
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), BulkWriterFlushTimeout)
+			flush(shutdownCtx)
+			cancel()
+			return
+		case row := <-w.in:
+			batch = append(batch, row)
+			if len(batch) >= BulkWriterMaxRows {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+func (w *BulkWriter) flush(ctx context.Context, batch [][]any) error {
+	start := time.Now()
+	rows := collapseBulkRows(batch, w.table.Columns, w.table.KeyColumns)
+	w.rowsCollapsed.Add(int64(len(batch) - len(rows)))
+
+	err := w.copyAndMerge(ctx, rows)
+	elapsed := time.Since(start)
+	w.batches.Add(1)
+	w.lastFlushNs.Store(int64(elapsed))
+	if err != nil {
+		w.failedBatches.Add(1)
+		return err
+	}
+	w.rowsWritten.Add(int64(len(rows)))
+	w.lastBatchRows.Store(int64(len(rows)))
+	w.flushNs.Add(int64(elapsed))
+	return nil
+}
+
+func (w *BulkWriter) copyAndMerge(ctx context.Context, rows [][]any) error {
+	ctx, cancel := context.WithTimeout(ctx, BulkWriterFlushTimeout)
+	defer cancel()
+
+	stage := bulkStageTable(w.table.Name)
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(
+		`CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP`,
+		pgx.Identifier{stage}.Sanitize(), pgx.Identifier{w.table.Name}.Sanitize(),
+	)); err != nil {
+		return fmt.Errorf("create staging table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{stage}, w.table.Columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+	if _, err := tx.Exec(ctx, bulkMergeSQL(w.table, stage)); err != nil {
+		return fmt.Errorf("merge: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the writer's counters.
+func (w *BulkWriter) Stats() BulkWriterStats {
+	s := BulkWriterStats{
+		Table:         w.table.Name,
+		RowsQueued:    w.rowsQueued.Load(),
+		RowsWritten:   w.rowsWritten.Load(),
+		RowsCollapsed: w.rowsCollapsed.Load(),
+		Batches:       w.batches.Load(),
+		FailedBatches: w.failedBatches.Load(),
+		LastBatchRows: w.lastBatchRows.Load(),
+		LastFlushMs:   float64(w.lastFlushNs.Load()) / float64(time.Millisecond),
+	}
+	if spent := time.Duration(w.flushNs.Load()); spent > 0 {
+		s.RowsPerSecond = float64(s.RowsWritten) / spent.Seconds()
+	}
+	return s
+}
+
+// collapseBulkRows drops earlier rows that share a key with a later one,
+// keeping the surviving rows in arrival order.
+func collapseBulkRows(batch [][]any, columns, keyColumns []string) [][]any {
+	keyIdx := make([]int, len(keyColumns))
+	for i, k := range keyColumns {
+		keyIdx[i] = slices.Index(columns, k)
+	}
+
+	last := make(map[string]int, len(batch))
+	keys := make([]string, len(batch))
+	for i, row := range batch {
+		parts := make([]string, len(keyIdx))
+		for j, idx := range keyIdx {
+			parts[j] = fmt.Sprint(row[idx])
+		}
+		keys[i] = strings.Join(parts, "\x00")
+		last[keys[i]] = i
+	}
+	if len(last) == len(batch) {
+		return batch
+	}
+
+	out := make([][]any, 0, len(last))
+	for i, row := range batch {
+		if last[keys[i]] == i {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+func bulkStageTable(table string) string {
+	return "bulk_stage_" + strings.ReplaceAll(table, ".", "_")
+}
+
+// bulkMergeSQL builds the INSERT ... SELECT ... ON CONFLICT that moves a
+// staged batch into the target table.
+func bulkMergeSQL(table BulkTable, stage string) string {
+	quote := func(cols []string) []string {
+		out := make([]string, len(cols))
+		for i, c := range cols {
+			out[i] = pgx.Identifier{c}.Sanitize()
+		}
+		return out
+	}
+	cols := strings.Join(quote(table.Columns), ", ")
+	keys := strings.Join(quote(table.KeyColumns), ", ")
+
+	conflict := "DO NOTHING"
+	if len(table.UpdateColumns) > 0 {
+		sets := make([]string, len(table.UpdateColumns))
+		for i, c := range quote(table.UpdateColumns) {
+			sets[i] = c + " = EXCLUDED." + c
+		}
+		conflict = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT (%s) %s",
+		pgx.Identifier{table.Name}.Sanitize(), cols, cols, pgx.Identifier{stage}.Sanitize(), keys, conflict)
+}
+
+// =============================================================================
+// Bulk Writer Registry
+// =============================================================================
+
+// bulkWriters holds the writers started by startBulkWriter, for stats.
+type bulkWriters struct {
+	mu      sync.Mutex
+	writers []*BulkWriter
+}
+
+// startBulkWriter creates a writer for table, runs it until ctx is
+// cancelled, and registers it for /internal/bulk-writer/stats.
+func (a *App) startBulkWriter(ctx context.Context, table BulkTable) (*BulkWriter, error) {
+	w, err := NewBulkWriter(a.db, table)
+	if err != nil {
+		return nil, err
+	}
+	a.bulk.mu.Lock()
+	a.bulk.writers = append(a.bulk.writers, w)
+	a.bulk.mu.Unlock()
+	go w.Run(ctx)
+	log.Printf("[BulkWriter] %s started (%d rows / %s batches)", table.Name, BulkWriterMaxRows, BulkWriterMaxDelay)
+	return w, nil
+}
+
+// handleBulkWriterStats reports throughput for every registered writer.
+func (a *App) handleBulkWriterStats(c *fiber.Ctx) error {
+	a.bulk.mu.Lock()
+	defer a.bulk.mu.Unlock()
+	stats := make([]BulkWriterStats, 0, len(a.bulk.writers))
+	for _, w := range a.bulk.writers {
+		stats = append(stats, w.Stats())
+	}
+	return c.JSON(fiber.Map{"writers": stats})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testItemsTable = BulkTable{
+	Name:       "rss_items",
+	Columns:    []string{"feed_url", "guid", "title", "published_at"},
+	KeyColumns: []string{"feed_url", "guid"},
+}
+
+func TestNewBulkWriterDefaultsUpdateColumns(t *testing.T) {
+	w, err := NewBulkWriter(nil, testItemsTable)
+	if err != nil {
+		t.Fatalf("NewBulkWriter: %v", err)
+	}
+	want := []string{"title", "published_at"}
+	if !reflect.DeepEqual(w.table.UpdateColumns, want) {
+		t.Errorf("UpdateColumns = %v, want %v", w.table.UpdateColumns, want)
+	}
+}
+
+func TestNewBulkWriterRejectsUnknownKey(t *testing.T) {
+	table := testItemsTable
+	table.KeyColumns = []string{"id"}
+	if _, err := NewBulkWriter(nil, table); err == nil {
+		t.Error("expected error for key column outside columns")
+	}
+}
+
+func TestCollapseBulkRowsLastWins(t *testing.T) {
+	batch := [][]any{
+		{"https://a.example/feed", "g1", "Draft", nil},
+		{"https://b.example/feed", "g1", "Other feed", nil},
+		{"https://a.example/feed", "g1", "Final", nil},
+		{"https://a.example/feed", "g2", "Second", nil},
+	}
+	got := collapseBulkRows(batch, testItemsTable.Columns, testItemsTable.KeyColumns)
+	want := [][]any{
+		{"https://b.example/feed", "g1", "Other feed", nil},
+		{"https://a.example/feed", "g1", "Final", nil},
+		{"https://a.example/feed", "g2", "Second", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collapseBulkRows = %v, want %v", got, want)
+	}
+}
+
+func TestBulkMergeSQL(t *testing.T) {
+	table := testItemsTable
+	table.UpdateColumns = []string{"title"}
+	got := bulkMergeSQL(table, bulkStageTable(table.Name))
+	want := `INSERT INTO "rss_items" ("feed_url", "guid", "title", "published_at") ` +
+		`SELECT "feed_url", "guid", "title", "published_at" FROM "bulk_stage_rss_items" ` +
+		`ON CONFLICT ("feed_url", "guid") DO UPDATE SET "title" = EXCLUDED."title"`
+	if got != want {
+		t.Errorf("bulkMergeSQL =\n%s\nwant\n%s", got, want)
+	}
+
+	table.UpdateColumns = []string{}
+	if got := bulkMergeSQL(table, "s"); !strings.HasSuffix(got, "DO NOTHING") {
+		t.Errorf("empty UpdateColumns should DO NOTHING, got %s", got)
+	}
+}
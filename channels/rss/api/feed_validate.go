@@ -0,0 +1,540 @@
+// Package main — custom feed validation and preview.
+//
+// POST /rss/feeds/validate fetches a URL the user is about to add, checks
+// that it parses as RSS 2.0, RSS 1.0 (RDF), Atom or JSON Feed, and
+// returns the feed title, item count and the latest few items. Nothing
+// is written: the feed only reaches tracked_feeds when the user saves
+// their channel config (syncRSSFeedsToTracked). Before this, a typo'd
+// or HTML URL was accepted silently and just never produced items.
+//
+// The preview also reports the feed's WebSub hub, if it advertises one
+// (websub.go), so the client can show that updates will arrive instantly.
+//
+// The fetch runs inside the cluster on a user-supplied URL, so the dialer
+// refuses loopback, private, link-local and other non-public addresses
+// (checked after DNS resolution, which also covers redirects and DNS
+// rebinding), and the body is capped at FeedValidateMaxBytes.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+const (
+	// FeedValidateTimeout bounds the whole fetch, redirects included.
+	FeedValidateTimeout = 10 * time.Second
+
+	// FeedValidateMaxBytes caps how much of the response is read. Real
+	// feeds are well under this; anything bigger is not worth previewing.
+	FeedValidateMaxBytes = 5 << 20
+
+	// FeedPreviewItems is how many of the latest items are returned.
+	FeedPreviewItems = 5
+
+	// JSONFeedFallbackTitleChars caps titles made from an untitled JSON
+	// Feed item's text. Matches the ingestion service (parse.rs).
+	JSONFeedFallbackTitleChars = 100
+
+	// FeedValidateRateLimit caps validations per user per minute. Each one
+	// is an outbound fetch, so the endpoint shouldn't double as a free
+	// crawler.
+	FeedValidateRateLimit = 20
+	FeedValidateRateKey   = "rss:validate:rl:"
+
+	// feedUserAgent and feedAccept match the ingestion service's client so
+	// a feed that validates here is fetched the same way later.
+	feedUserAgent = "Scrollr/1.0 RSS Fetcher (+https://myscrollr.com)"
+	feedAccept    = "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5"
+)
+
+// errBlockedAddress is returned by the dialer for non-public addresses.
+var errBlockedAddress = errors.New("address is not publicly routable")
+
+// FeedPreview is the POST /rss/feeds/validate response.
+type FeedPreview struct {
+	URL       string            `json:"url"`
+	Title     string            `json:"title"`
+	Format    string            `json:"format"` // rss, rdf, atom or json
+	ItemCount int               `json:"item_count"`
+	Items     []FeedPreviewItem `json:"items"`
+	// IsDefault is true when the URL is already a curated feed, so the
+	// client can offer to pin that instead of adding a custom copy.
+	IsDefault bool `json:"is_default"`
+	// WebSubHub is the hub the feed advertises for push updates.
+	WebSubHub string `json:"websub_hub,omitempty"`
+
+	// topic is the feed's self link, the URL to subscribe to at the hub.
+	topic string
+}
+
+// FeedPreviewItem is one of the latest items of a previewed feed.
+type FeedPreviewItem struct {
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// ─── Fetch ───────────────────────────────────────────────────────
+
+// publicAddress reports whether ip is safe to fetch from inside the
+// cluster.
+func publicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// newFeedValidateClient returns the HTTP client used for previews. The
+// dialer's Control hook sees the resolved address of every connection,
+// redirects included.
+func newFeedValidateClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: FeedValidateTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// parseFeedURL checks that raw is an absolute http(s) URL with a host.
+func parseFeedURL(raw string) (*url.URL, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// fetchFeed downloads a feed body, up to FeedValidateMaxBytes.
+func fetchFeed(ctx context.Context, client *http.Client, feedURL string) ([]byte, error) {
+	body, _, err := fetchFeedResponse(ctx, client, feedURL)
+	return body, err
+}
+
+// fetchFeedResponse is fetchFeed plus the response headers, which may
+// carry WebSub Link headers.
+func fetchFeedResponse(ctx context.Context, client *http.Client, feedURL string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+	req.Header.Set("Accept", feedAccept)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, errBlockedAddress) {
+			return nil, nil, errBlockedAddress
+		}
+		return nil, nil, fmt.Errorf("request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("server responded with HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, FeedValidateMaxBytes+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading response failed")
+	}
+	if len(body) > FeedValidateMaxBytes {
+		return nil, nil, fmt.Errorf("response is larger than %d MB", FeedValidateMaxBytes>>20)
+	}
+	return body, resp.Header, nil
+}
+
+// ─── Parse ───────────────────────────────────────────────────────
+
+// feedDocument decodes the parts of RSS 2.0, RSS 1.0 and Atom that the
+// preview needs. The root element name picks the format; the unused
+// fields stay empty.
+type feedDocument struct {
+	XMLName xml.Name
+	// RSS 2.0 nests items in channel; RSS 1.0 puts them beside it.
+	Channel *struct {
+		Title string     `xml:"title"`
+		Items []feedRaw  `xml:"item"`
+		Links []feedLink `xml:"link"` // RSS <link> and <atom:link>
+	} `xml:"channel"`
+	Items []feedRaw `xml:"item"`
+	// Atom
+	Title   string     `xml:"title"`
+	Entries []feedRaw  `xml:"entry"`
+	Links   []feedLink `xml:"link"`
+}
+
+// feedLink is an RSS <link> (element text) or an Atom-style link
+// (attributes). An element may carry several, e.g. alternate, self,
+// enclosure, hub.
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedRaw is an RSS item or Atom entry.
+type feedRaw struct {
+	Title     string     `xml:"title"`
+	PubDate   string     `xml:"pubDate"`
+	DCDate    string     `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Links     []feedLink `xml:"link"`
+}
+
+func (r feedRaw) link() string {
+	for _, l := range r.Links {
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// feedDateLayouts covers RFC 822/1123 as feeds actually write it, plus
+// the ISO 8601 forms used by Atom and Dublin Core.
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseFeedDate(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+func (r feedRaw) published() *time.Time {
+	for _, raw := range []string{r.PubDate, r.Published, r.DCDate, r.Updated} {
+		if t := parseFeedDate(raw); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// feedCharsetReader decodes the non-UTF-8 encodings feeds declare in
+// their XML prolog (ISO-8859-1 and windows-1252 are still common).
+func feedCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported encoding %q", label)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// parseFeed turns a response body into a preview, or explains why it
+// isn't a feed.
+func parseFeed(body []byte) (FeedPreview, error) {
+	if bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(body, utf8BOM), " \t\r\n"), []byte("{")) {
+		return parseJSONFeed(body)
+	}
+
+	var doc feedDocument
+	// Non-strict with HTML entities: plenty of live feeds use &nbsp; and
+	// friends undeclared, and the ingestion parser tolerates them. No
+	// HTMLAutoClose: it treats <link> as a void element, which is exactly
+	// wrong for RSS.
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.CharsetReader = feedCharsetReader
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return FeedPreview{}, errors.New("response is not valid XML (is this a web page rather than a feed?)")
+	}
+
+	var preview FeedPreview
+	var raws []feedRaw
+	var links []feedLink
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		if doc.Channel == nil {
+			return FeedPreview{}, errors.New("RSS document has no channel")
+		}
+		preview.Format = "rss"
+		preview.Title = doc.Channel.Title
+		raws = doc.Channel.Items
+		links = doc.Channel.Links
+	case "rdf":
+		if doc.Channel == nil {
+			return FeedPreview{}, errors.New("RSS 1.0 document has no channel")
+		}
+		preview.Format = "rdf"
+		preview.Title = doc.Channel.Title
+		raws = doc.Items
+		links = doc.Channel.Links
+	case "feed":
+		preview.Format = "atom"
+		preview.Title = doc.Title
+		raws = doc.Entries
+		links = doc.Links
+	default:
+		return FeedPreview{}, fmt.Errorf("document root is <%s>, not an RSS or Atom feed", doc.XMLName.Local)
+	}
+	preview.Title = strings.TrimSpace(preview.Title)
+	preview.WebSubHub = relLink(links, "hub")
+	preview.topic = relLink(links, "self")
+
+	items := make([]FeedPreviewItem, 0, len(raws))
+	for _, r := range raws {
+		items = append(items, FeedPreviewItem{
+			Title:       strings.TrimSpace(r.Title),
+			Link:        r.link(),
+			PublishedAt: r.published(),
+		})
+	}
+	return finishPreview(preview, items), nil
+}
+
+// utf8BOM is stripped before sniffing for JSON.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// jsonFeedDocument is the part of JSON Feed 1.0/1.1 the preview needs.
+type jsonFeedDocument struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	FeedURL string `json:"feed_url"`
+	Hubs    []struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"hubs"`
+	Items []struct {
+		URL           string `json:"url"`
+		ExternalURL   string `json:"external_url"`
+		Title         string `json:"title"`
+		Summary       string `json:"summary"`
+		ContentText   string `json:"content_text"`
+		DatePublished string `json:"date_published"`
+		DateModified  string `json:"date_modified"`
+	} `json:"items"`
+}
+
+// parseJSONFeed previews a JSON Feed. Untitled items (microblog posts)
+// are titled from their summary or text, as ingestion does.
+func parseJSONFeed(body []byte) (FeedPreview, error) {
+	var doc jsonFeedDocument
+	if err := json.Unmarshal(bytes.TrimPrefix(body, utf8BOM), &doc); err != nil {
+		return FeedPreview{}, errors.New("response is not valid JSON")
+	}
+	if !strings.Contains(doc.Version, "jsonfeed.org/version/") {
+		return FeedPreview{}, errors.New("JSON document is not a JSON Feed (missing jsonfeed.org version)")
+	}
+
+	preview := FeedPreview{
+		Format: "json",
+		Title:  strings.TrimSpace(doc.Title),
+		topic:  strings.TrimSpace(doc.FeedURL),
+	}
+	for _, h := range doc.Hubs {
+		if strings.EqualFold(h.Type, "websub") && h.URL != "" {
+			preview.WebSubHub = strings.TrimSpace(h.URL)
+			break
+		}
+	}
+
+	items := make([]FeedPreviewItem, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		title := strings.TrimSpace(it.Title)
+		if title == "" {
+			title = jsonFeedFallbackTitle(it.Summary, it.ContentText)
+		}
+		link := it.URL
+		if link == "" {
+			link = it.ExternalURL
+		}
+		published := parseFeedDate(it.DatePublished)
+		if published == nil {
+			published = parseFeedDate(it.DateModified)
+		}
+		items = append(items, FeedPreviewItem{Title: title, Link: link, PublishedAt: published})
+	}
+	return finishPreview(preview, items), nil
+}
+
+// jsonFeedFallbackTitle is the first non-empty candidate, whitespace
+// collapsed and cut to JSONFeedFallbackTitleChars.
+func jsonFeedFallbackTitle(candidates ...string) string {
+	for _, c := range candidates {
+		text := strings.Join(strings.Fields(c), " ")
+		if text == "" {
+			continue
+		}
+		if runes := []rune(text); len(runes) > JSONFeedFallbackTitleChars {
+			return string(runes[:JSONFeedFallbackTitleChars]) + "…"
+		}
+		return text
+	}
+	return ""
+}
+
+// finishPreview orders items newest first and caps them at
+// FeedPreviewItems.
+func finishPreview(preview FeedPreview, items []FeedPreviewItem) FeedPreview {
+	// Most feeds are newest-first already; sorting makes "latest" true for
+	// the ones that aren't. Undated items keep document order at the end.
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].PublishedAt, items[j].PublishedAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+
+	preview.ItemCount = len(items)
+	if len(items) > FeedPreviewItems {
+		items = items[:FeedPreviewItems]
+	}
+	preview.Items = items
+	return preview
+}
+
+// ─── Handler ─────────────────────────────────────────────────────
+
+// allowFeedValidation applies the per-user validation rate limit. Fails
+// open on Redis errors.
+func (a *App) allowFeedValidation(ctx context.Context, userSub string) bool {
+	key := FeedValidateRateKey + userSub
+	count, err := a.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("[RSS] validate rate limit INCR failed (allowing): %v", err)
+		return true
+	}
+	if count == 1 {
+		a.rdb.Expire(ctx, key, time.Minute)
+	}
+	return count <= FeedValidateRateLimit
+}
+
+// validateFeed fetches and parses a feed URL without saving anything.
+// The core gateway sets X-User-Sub (the route is Auth: true).
+//
+// Responses:
+//   - 200 with a FeedPreview when the URL is a parseable feed
+//   - 400 for a malformed URL
+//   - 422 when the URL can't be fetched or isn't a feed, with the reason
+//   - 429 past FeedValidateRateLimit per minute
+func (a *App) validateFeed(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+	u, ok := parseFeedURL(req.URL)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "url must be an absolute http or https URL",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), FeedValidateTimeout)
+	defer cancel()
+
+	if !a.allowFeedValidation(ctx, userSub) {
+		c.Set("Retry-After", "60")
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Too many feed validations; try again in a minute",
+		})
+	}
+
+	body, header, err := fetchFeedResponse(ctx, a.feedClient, u.String())
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feed could not be fetched: " + err.Error(),
+		})
+	}
+	preview, err := parseFeed(body)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Not a valid feed: " + err.Error(),
+		})
+	}
+	preview.URL = u.String()
+	// Link headers take precedence over links in the document.
+	if hub, _ := linkHeaderWebSub(header); hub != "" {
+		preview.WebSubHub = hub
+	}
+
+	// Best effort: a lookup failure just leaves is_default false.
+	if err := a.db.QueryRow(ctx,
+		"SELECT is_default FROM tracked_feeds WHERE url = $1", preview.URL,
+	).Scan(&preview.IsDefault); err != nil {
+		preview.IsDefault = false
+	}
+
+	return c.JSON(preview)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const rss2Sample = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel>
+  <title> Example News </title>
+  <item><title>Older</title><link>https://example.com/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate></item>
+  <item><title>Undated</title><link>https://example.com/2</link></item>
+  <item><title>Newer &nbsp;story</title><link>https://example.com/3</link><pubDate>Tue, 3 Jan 2006 15:04:05 GMT</pubDate></item>
+</channel></rss>`
+
+const atomSample = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Blog</title>
+  <entry><title>First</title><link rel="self" href="https://blog.example/self"/><link rel="alternate" href="https://blog.example/first"/><updated>2024-05-01T10:00:00Z</updated></entry>
+  <entry><title>Second</title><link href="https://blog.example/second"/><published>2024-05-02T10:00:00Z</published></entry>
+</feed>`
+
+const rdfSample = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>RDF Feed</title></channel>
+  <item><title>One</title><link>https://rdf.example/1</link><dc:date>2024-01-01T00:00:00Z</dc:date></item>
+</rdf:RDF>`
+
+const latin1Sample = "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss version=\"2.0\"><channel><title>Caf\xe9</title></channel></rss>"
+
+func TestParseFeedFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		format    string
+		title     string
+		count     int
+		firstItem string
+		firstLink string
+	}{
+		{"rss 2.0 sorted newest first", rss2Sample, "rss", "Example News", 3, "Newer  story", "https://example.com/3"},
+		{"atom prefers alternate link", atomSample, "atom", "Atom Blog", 2, "Second", "https://blog.example/second"},
+		{"rss 1.0", rdfSample, "rdf", "RDF Feed", 1, "One", "https://rdf.example/1"},
+		{"latin-1 encoding", latin1Sample, "rss", "Café", 0, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFeed([]byte(tc.body))
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if got.Format != tc.format || got.Title != tc.title || got.ItemCount != tc.count {
+				t.Errorf("got format=%q title=%q count=%d, want %q %q %d", got.Format, got.Title, got.ItemCount, tc.format, tc.title, tc.count)
+			}
+			if tc.count == 0 {
+				return
+			}
+			if got.Items[0].Title != tc.firstItem || got.Items[0].Link != tc.firstLink {
+				t.Errorf("first item = %+v, want %q %q", got.Items[0], tc.firstItem, tc.firstLink)
+			}
+		})
+	}
+}
+
+func TestParseFeedAtomSecondEntryLink(t *testing.T) {
+	got, err := parseFeed([]byte(atomSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The self link must not win over the alternate one.
+	if got.Items[1].Link != "https://blog.example/first" {
+		t.Errorf("link = %q, want the alternate link", got.Items[1].Link)
+	}
+}
+
+func TestParseFeedUndatedItemsLast(t *testing.T) {
+	got, err := parseFeed([]byte(rss2Sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := got.Items[len(got.Items)-1]; last.Title != "Undated" || last.PublishedAt != nil {
+		t.Errorf("last item = %+v, want the undated one", last)
+	}
+}
+
+func TestParseFeedCapsPreview(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<rss><channel><title>Many</title>`)
+	for i := 0; i < FeedPreviewItems+3; i++ {
+		b.WriteString(`<item><title>x</title></item>`)
+	}
+	b.WriteString(`</channel></rss>`)
+
+	got, err := parseFeed([]byte(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ItemCount != FeedPreviewItems+3 || len(got.Items) != FeedPreviewItems {
+		t.Errorf("item_count=%d items=%d, want %d and %d", got.ItemCount, len(got.Items), FeedPreviewItems+3, FeedPreviewItems)
+	}
+}
+
+func TestParseFeedRejectsNonFeeds(t *testing.T) {
+	for name, body := range map[string]string{
+		"html page":      `<!DOCTYPE html><html><head><title>Home</title></head><body><p>hi<br></body></html>`,
+		"json":           `{"items":[]}`,
+		"rss no channel": `<rss version="2.0"></rss>`,
+		"empty":          ``,
+	} {
+		if _, err := parseFeed([]byte(body)); err == nil {
+			t.Errorf("%s: parseFeed accepted it", name)
+		}
+	}
+}
+
+func TestParseFeedURL(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"https://example.com/feed.xml": true,
+		" http://example.com/rss ":     true,
+		"ftp://example.com/rss":        false,
+		"example.com/rss":              false,
+		"file:///etc/passwd":           false,
+		"":                             false,
+	} {
+		if _, ok := parseFeedURL(raw); ok != want {
+			t.Errorf("parseFeedURL(%q) ok = %v, want %v", raw, ok, want)
+		}
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.0.0.5":        false,
+		"172.16.3.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false, // cloud metadata
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+	} {
+		if got := publicAddress(net.ParseIP(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestFetchFeedRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(rss2Sample))
+	}))
+	defer srv.Close()
+
+	_, err := fetchFeed(context.Background(), newFeedValidateClient(), srv.URL)
+	if !errors.Is(err, errBlockedAddress) {
+		t.Errorf("fetchFeed(loopback) err = %v, want errBlockedAddress", err)
+	}
+}
+
+func TestFetchFeedStatusAndSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != feedUserAgent {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/huge":
+			w.Write(make([]byte, FeedValidateMaxBytes+1))
+		default:
+			w.Write([]byte(rss2Sample))
+		}
+	}))
+	defer srv.Close()
+
+	// The guarded client refuses loopback, so exercise the rest of fetchFeed
+	// with a plain one.
+	client := srv.Client()
+	if body, err := fetchFeed(context.Background(), client, srv.URL+"/feed"); err != nil || len(body) == 0 {
+		t.Errorf("fetchFeed(ok) = %d bytes, %v", len(body), err)
+	}
+	if _, err := fetchFeed(context.Background(), client, srv.URL+"/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("fetchFeed(404) err = %v", err)
+	}
+	if _, err := fetchFeed(context.Background(), client, srv.URL+"/huge"); err == nil {
+		t.Error("fetchFeed accepted an oversized body")
+	}
+}
+
+const jsonFeedSample = "\xef\xbb\xbf" + `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": " Micro Notes ",
+  "feed_url": "https://micro.example/feed.json",
+  "hubs": [{"type": "rssCloud", "url": "https://cloud.example/"}, {"type": "WebSub", "url": "https://hub.example/"}],
+  "items": [
+    {"id": 1, "url": "https://micro.example/1", "content_text": "An untitled   microblog post", "date_published": "2024-05-01T10:00:00Z"},
+    {"id": "2", "external_url": "https://elsewhere.example/2", "title": "Linked", "date_modified": "2024-05-02T10:00:00Z"}
+  ]
+}`
+
+func TestParseJSONFeed(t *testing.T) {
+	got, err := parseFeed([]byte(jsonFeedSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != "json" || got.Title != "Micro Notes" || got.ItemCount != 2 {
+		t.Errorf("preview = %+v", got)
+	}
+	if got.WebSubHub != "https://hub.example/" || got.topic != "https://micro.example/feed.json" {
+		t.Errorf("hub, topic = %q, %q", got.WebSubHub, got.topic)
+	}
+	if first := got.Items[0]; first.Title != "Linked" || first.Link != "https://elsewhere.example/2" || first.PublishedAt == nil {
+		t.Errorf("first item = %+v, want the date_modified item", first)
+	}
+	if second := got.Items[1]; second.Title != "An untitled microblog post" || second.Link != "https://micro.example/1" {
+		t.Errorf("second item = %+v, want title from content_text", second)
+	}
+}
+
+func TestParseJSONFeedRejects(t *testing.T) {
+	for _, body := range []string{`{"title": "no version"}`, `{"version": "https://jsonfeed.org/version/1.1"`, `{"version": 1}`} {
+		if _, err := parseFeed([]byte(body)); err == nil {
+			t.Errorf("parseFeed(%s) accepted a non-feed", body)
+		}
+	}
+}
+
+func TestJSONFeedFallbackTitle(t *testing.T) {
+	long := strings.Repeat("é", JSONFeedFallbackTitleChars+10)
+	if got := jsonFeedFallbackTitle("", long); got != strings.Repeat("é", JSONFeedFallbackTitleChars)+"…" {
+		t.Errorf("long title = %q", got)
+	}
+	if got := jsonFeedFallbackTitle("  ", "\n"); got != "" {
+		t.Errorf("empty candidates = %q", got)
+	}
+	if got := jsonFeedFallbackTitle(" summary ", "text"); got != "summary" {
+		t.Errorf("summary first = %q", got)
+	}
+}
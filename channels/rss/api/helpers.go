@@ -22,7 +22,7 @@ const InternalHealthTimeout = 3 * time.Second
 
 // GetCache attempts to retrieve and deserialize a value from Redis.
 // Returns true if the cache hit was successful.
-func GetCache(rdb *redis.Client, ctx context.Context, key string, target interface{}) bool {
+func GetCache(rdb redis.UniversalClient, ctx context.Context, key string, target interface{}) bool {
 	val, err := rdb.Get(ctx, key).Result()
 	if err != nil {
 		return false
@@ -33,7 +33,7 @@ func GetCache(rdb *redis.Client, ctx context.Context, key string, target interfa
 }
 
 // SetCache serializes and stores a value in Redis with an expiration.
-func SetCache(rdb *redis.Client, ctx context.Context, key string, value interface{}, expiration time.Duration) {
+func SetCache(rdb redis.UniversalClient, ctx context.Context, key string, value interface{}, expiration time.Duration) {
 	data, err := json.Marshal(value)
 	if err != nil {
 		log.Printf("[Redis Error] Failed to marshal cache data for %s: %v", key, err)
@@ -47,7 +47,7 @@ func SetCache(rdb *redis.Client, ctx context.Context, key string, value interfac
 }
 
 // GetSubscribers returns all user subs in a Redis subscription set.
-func GetSubscribers(rdb *redis.Client, ctx context.Context, setKey string) ([]string, error) {
+func GetSubscribers(rdb redis.UniversalClient, ctx context.Context, setKey string) ([]string, error) {
 	return rdb.SMembers(ctx, setKey).Result()
 }
 
@@ -60,7 +60,7 @@ const SubscriberSetTTL = 7 * 24 * time.Hour
 // AddSubscriber adds a user sub to a Redis subscription set and (re)sets
 // its TTL. Returns the pipeline error, if any — the SAdd error surfaces
 // here because it runs before Expire.
-func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) error {
+func AddSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) error {
 	pipe := rdb.Pipeline()
 	pipe.SAdd(ctx, setKey, userSub)
 	pipe.Expire(ctx, setKey, SubscriberSetTTL)
@@ -69,7 +69,7 @@ func AddSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub strin
 }
 
 // RemoveSubscriber removes a user sub from a Redis subscription set.
-func RemoveSubscriber(rdb *redis.Client, ctx context.Context, setKey, userSub string) error {
+func RemoveSubscriber(rdb redis.UniversalClient, ctx context.Context, setKey, userSub string) error {
 	return rdb.SRem(ctx, setKey, userSub).Err()
 }
 
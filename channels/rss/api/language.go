@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Content Language
+// =============================================================================
+//
+// Ingestion stores an ISO 639-1 language on tracked_feeds and rss_items
+// (see service/src/language.rs); an item without one inherits its
+// feed's. Two filters read it:
+//
+//   - The catalog takes ?language=xx and keeps feeds in that language
+//     plus feeds whose language isn't known yet, so a just-added custom
+//     feed doesn't vanish before its first poll.
+//   - The dashboard keeps items in the user's languages plus unknowns.
+//     The user's languages are the RSS channel config's "languages"
+//     list when the key is present (an empty list turns filtering off),
+//     otherwise the language of their locale preference; no locale
+//     means no filter.
+
+// normalizeLanguage reduces a tag ("en-US", "PT_br") to its lowercase
+// primary subtag, or "" if it isn't a plausible language tag. Mirrors
+// normalize_language_tag in the ingestion service.
+func normalizeLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	if len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	for _, r := range primary {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return ""
+		}
+	}
+	return strings.ToLower(primary)
+}
+
+// resolveUserLanguages picks the dashboard languages from an RSS channel
+// config and the user's locale. nil means show every language.
+func resolveUserLanguages(configJSON []byte, locale string) []string {
+	var config struct {
+		Languages *[]string `json:"languages"`
+	}
+	if len(configJSON) > 0 && json.Unmarshal(configJSON, &config) == nil && config.Languages != nil {
+		var langs []string
+		for _, tag := range *config.Languages {
+			if lang := normalizeLanguage(tag); lang != "" && !slices.Contains(langs, lang) {
+				langs = append(langs, lang)
+			}
+		}
+		return langs
+	}
+	if lang := normalizeLanguage(locale); lang != "" {
+		return []string{lang}
+	}
+	return nil
+}
+
+// userLanguages loads the user's RSS config and locale and resolves the
+// dashboard languages. Lookup failures fall back to no filter.
+func (a *App) userLanguages(ctx context.Context, logtoSub string) []string {
+	var configJSON []byte
+	var locale *string
+	err := a.db.QueryRow(ctx, `
+		SELECT
+			(SELECT config FROM user_channels WHERE logto_sub = $1 AND channel_type = 'rss'),
+			(SELECT locale FROM user_preferences WHERE logto_sub = $1)
+	`, logtoSub).Scan(&configJSON, &locale)
+	if err != nil {
+		return nil
+	}
+	if locale == nil {
+		return resolveUserLanguages(configJSON, "")
+	}
+	return resolveUserLanguages(configJSON, *locale)
+}
+
+// filterCatalogByLanguage keeps feeds in lang plus those not yet
+// classified.
+func filterCatalogByLanguage(feeds []TrackedFeed, lang string) []TrackedFeed {
+	filtered := make([]TrackedFeed, 0, len(feeds))
+	for _, f := range feeds {
+		if f.Language == nil || *f.Language == lang {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
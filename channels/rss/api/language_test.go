@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeLanguage(t *testing.T) {
+	for in, want := range map[string]string{
+		"en":      "en",
+		"en-US":   "en",
+		" PT_br ": "pt",
+		"fil":     "fil",
+		"":        "",
+		"english": "",
+		"e1":      "",
+	} {
+		if got := normalizeLanguage(in); got != want {
+			t.Errorf("normalizeLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolveUserLanguages(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		locale string
+		want   []string
+	}{
+		{"config list wins", `{"languages":["es","en-GB","es-MX"]}`, "fr-FR", []string{"es", "en"}},
+		{"empty list disables filter", `{"languages":[]}`, "fr-FR", nil},
+		{"locale fallback", `{"feeds":[]}`, "fr-FR", []string{"fr"}},
+		{"null list falls back to locale", `{"languages":null}`, "de", []string{"de"}},
+		{"no config no locale", ``, "", nil},
+		{"bad config uses locale", `not json`, "it", []string{"it"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveUserLanguages([]byte(tt.config), tt.locale)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCatalogByLanguage(t *testing.T) {
+	en, es := "en", "es"
+	feeds := []TrackedFeed{
+		{URL: "a", Language: &en},
+		{URL: "b", Language: &es},
+		{URL: "c"},
+	}
+	got := filterCatalogByLanguage(feeds, "en")
+	if len(got) != 2 || got[0].URL != "a" || got[1].URL != "c" {
+		t.Errorf("filtered = %+v, want feeds a and c", got)
+	}
+}
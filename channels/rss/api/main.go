@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -38,6 +39,31 @@ const (
 	DefaultChannelURL = "http://localhost:8083"
 )
 
+// configSchema describes user_channels.config for this channel. The
+// gateway validates config writes against it (api/core/config_schema.go)
+// and serves it at /channels/{name}/schema, where title and description
+// label the generated settings form. Keys not listed here are accepted
+// unchecked.
+const configSchema = `{
+	"type": "object",
+	"properties": {
+		"feeds": {
+			"type": "array",
+			"title": "Feeds",
+			"description": "RSS or Atom feeds to follow.",
+			"items": {
+				"type": "object",
+				"required": ["url"],
+				"properties": {
+					"url": {"type": "string", "format": "uri", "maxLength": 2048, "title": "Feed URL"},
+					"name": {"type": "string", "maxLength": 200, "title": "Name"},
+					"is_custom": {"type": "boolean", "readOnly": true}
+				}
+			}
+		}
+	}
+}`
+
 // registrationPayload is the JSON structure stored in Redis for service discovery.
 type registrationPayload struct {
 	Name         string              `json:"name"`
@@ -46,6 +72,9 @@ type registrationPayload struct {
 	Capabilities []string            `json:"capabilities"`
 	CDCTables    []string            `json:"cdc_tables"`
 	Routes       []registrationRoute `json:"routes"`
+	StartedAt    int64               `json:"started_at"` // Unix ms; lets the gateway detect restarts
+	Version      string              `json:"version"`    // build (GIT_SHA); lets the gateway spot rollouts
+	ConfigSchema json.RawMessage     `json:"config_schema,omitempty"`
 }
 
 type registrationRoute struct {
@@ -64,6 +93,15 @@ func main() {
 		defer sentry.Flush(2 * time.Second)
 	}
 
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set (tracing.go).
+	shutdownTracing := initTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+
 	// -------------------------------------------------------------------------
 	// Connect to PostgreSQL
 	// -------------------------------------------------------------------------
@@ -75,15 +113,10 @@ func main() {
 	// Bounded pool — see finance/sports for rationale. Defaults vary
 	// across boxes and don't set a connect deadline, which allows a
 	// stalled Postgres to block startup indefinitely.
-	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	poolConfig, err := dbPoolConfig(databaseURL)
 	if err != nil {
 		log.Fatalf("[DB] parse config: %v", err)
 	}
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
-	poolConfig.MaxConnLifetime = 30 * time.Minute
-	poolConfig.MaxConnIdleTime = 5 * time.Minute
-	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
 	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatalf("[DB] new pool: %v", err)
@@ -95,26 +128,23 @@ func main() {
 	}
 	log.Println("Connected to PostgreSQL")
 
+	// Optional read replica for catalog, public and dashboard reads (db.go)
+	replica := openReadReplica(context.Background())
+	defer replica.Close()
+
 	// -------------------------------------------------------------------------
 	// Connect to Redis
 	// -------------------------------------------------------------------------
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("REDIS_URL must be set")
-	}
-
-	redisOpts, err := redis.ParseURL(redisURL)
+	rdb, redisMode, err := newRedisClient(os.Getenv)
 	if err != nil {
-		log.Fatalf("Unable to parse REDIS_URL: %v", err)
+		log.Fatalf("Unable to configure Redis: %v", err)
 	}
-
-	rdb := redis.NewClient(redisOpts)
 	defer rdb.Close()
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		log.Fatalf("Unable to connect to Redis: %v", err)
 	}
-	log.Println("Connected to Redis")
+	log.Printf("Connected to Redis (%s)", redisMode)
 
 	// -------------------------------------------------------------------------
 	// Start Redis self-registration heartbeat
@@ -122,6 +152,9 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Read replica health checks; readDB falls back while it lags (db.go)
+	go replica.monitor(ctx)
+
 	go startRegistration(ctx, rdb)
 
 	// -------------------------------------------------------------------------
@@ -137,8 +170,12 @@ func main() {
 
 	app := &App{
 		db:         dbPool,
+		replica:    replica,
 		rdb:        rdb,
 		httpClient: &http.Client{Timeout: HealthProxyTimeout},
+		feedSyncs:  make(chan struct{}, MaxConcurrentFeedSyncs),
+		feedClient: newFeedValidateClient(),
+		websubBase: strings.TrimSuffix(os.Getenv("WEBSUB_CALLBACK_BASE_URL"), "/"),
 	}
 
 	// Sentry middleware MUST be first so panics from anything below are
@@ -148,16 +185,40 @@ func main() {
 		fiberApp.Use(sentryUserHook())
 	}
 
+	// Server span per request, continuing the gateway's trace (tracing.go)
+	fiberApp.Use(tracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go)
+	fiberApp.Use(metricsMiddleware)
+
 	// Internal routes (called by core gateway only)
+	fiberApp.Use("/internal", requireInternalSignature) // HMAC from core (internal_auth.go)
 	fiberApp.Post("/internal/cdc", app.handleInternalCDC)
 	fiberApp.Get("/internal/dashboard", app.handleInternalDashboard)
 	fiberApp.Get("/internal/health", app.handleInternalHealth)
 	fiberApp.Post("/internal/channel-lifecycle", app.handleChannelLifecycle)
+	fiberApp.Post("/internal/channel-lifecycle/replay", app.handleLifecycleReplay)
+	fiberApp.Get("/internal/bulk-writer/stats", app.handleBulkWriterStats)
+
+	// Prometheus scrape endpoint, cluster-internal only (metrics.go)
+	registerPoolMetrics(app.db, app.rdb)
+	fiberApp.Get("/metrics", requireInternalNetwork, handleMetrics)
 
 	// Public routes (proxied by core gateway)
 	fiberApp.Get("/rss/feeds", app.getRSSFeedCatalog)
+	fiberApp.Get("/rss/items", app.getRSSItems)
 	fiberApp.Delete("/rss/feeds", app.deleteCustomFeed)
+	fiberApp.Post("/rss/feeds/validate", app.validateFeed)
+	fiberApp.Put("/rss/feeds/refresh", app.setFeedRefresh)
 	fiberApp.Get("/rss/health", app.healthHandler)
+	fiberApp.Get(WebSubCallbackPath, app.websubVerify)
+	fiberApp.Post(WebSubCallbackPath, app.websubPush)
+
+	// Admin routes (proxied by core gateway, super_user only — see admin.go)
+	fiberApp.Get("/admin/rss/feeds", app.adminListFeeds)
+	fiberApp.Post("/admin/rss/feeds", app.adminCreateFeed)
+	fiberApp.Put("/admin/rss/feeds", app.adminUpdateFeed)
+	fiberApp.Post("/admin/rss/feeds/reset", app.adminResetFeedFailures)
 
 	// -------------------------------------------------------------------------
 	// Start the auto-cleanup janitor (background goroutine)
@@ -167,6 +228,10 @@ func main() {
 	// curated feeds for operator follow-up. See janitor.go.
 	app.startJanitor(ctx)
 
+	// Subscribe to WebSub hubs for push updates (see websub.go). No-op
+	// without WEBSUB_CALLBACK_BASE_URL.
+	app.startWebSub(ctx)
+
 	// -------------------------------------------------------------------------
 	// Start server with graceful shutdown
 	// -------------------------------------------------------------------------
@@ -203,7 +268,7 @@ func main() {
 // startRegistration registers this service in Redis with a TTL and refreshes
 // the registration on a ticker. This allows the core gateway to discover
 // available channel services.
-func startRegistration(ctx context.Context, rdb *redis.Client) {
+func startRegistration(ctx context.Context, rdb redis.UniversalClient) {
 	channelURL := os.Getenv("CHANNEL_URL")
 	if channelURL == "" {
 		channelURL = DefaultChannelURL
@@ -213,7 +278,7 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 		Name:         "rss",
 		DisplayName:  "RSS",
 		InternalURL:  channelURL,
-		Capabilities: []string{"cdc_handler", "dashboard_provider", "channel_lifecycle", "health_checker"},
+		Capabilities: []string{"cdc_handler", "dashboard_provider", "channel_lifecycle", "lifecycle_replay", "health_checker"},
 		CDCTables:    []string{"rss_items"},
 		Routes: []registrationRoute{
 			// /rss/feeds is now Auth: true — the catalog is per-user
@@ -221,9 +286,25 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 			// only). The pre-isolation public endpoint leaked custom
 			// feeds across users.
 			{Method: "GET", Path: "/rss/feeds", Auth: true},
+			{Method: "GET", Path: "/rss/items", Auth: true},
 			{Method: "DELETE", Path: "/rss/feeds", Auth: true},
+			{Method: "POST", Path: "/rss/feeds/validate", Auth: true},
+			{Method: "PUT", Path: "/rss/feeds/refresh", Auth: true},
 			{Method: "GET", Path: "/rss/health", Auth: false},
+			// WebSub hub callbacks: verification (GET) and pushes (POST).
+			// Authenticated by the per-subscription token and signature.
+			{Method: "GET", Path: WebSubCallbackPath, Auth: false},
+			{Method: "POST", Path: WebSubCallbackPath, Auth: false},
+			// Curated-catalog admin. Auth: true so the gateway forwards
+			// X-User-Tier; the handlers require super_user.
+			{Method: "GET", Path: "/admin/rss/feeds", Auth: true},
+			{Method: "POST", Path: "/admin/rss/feeds", Auth: true},
+			{Method: "PUT", Path: "/admin/rss/feeds", Auth: true},
+			{Method: "POST", Path: "/admin/rss/feeds/reset", Auth: true},
 		},
+		StartedAt:    time.Now().UnixMilli(),
+		Version:      envOr("GIT_SHA", "unknown"),
+		ConfigSchema: json.RawMessage(configSchema),
 	}
 
 	data, err := json.Marshal(payload)
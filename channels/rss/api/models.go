@@ -11,6 +11,7 @@ type RssItem struct {
 	Link        string     `json:"link"`
 	Description string     `json:"description"`
 	SourceName  string     `json:"source_name"`
+	Language    *string    `json:"language,omitempty"` // ISO 639-1; the feed's when the item has none
 	PublishedAt *time.Time `json:"published_at"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
@@ -22,9 +23,36 @@ type TrackedFeed struct {
 	Name                string     `json:"name"`
 	Category            string     `json:"category"`
 	IsDefault           bool       `json:"is_default"`
+	Language            *string    `json:"language,omitempty"` // ISO 639-1, set by ingestion
 	ConsecutiveFailures int        `json:"consecutive_failures"`
 	LastError           *string    `json:"last_error,omitempty"`
 	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	RefreshIntervalSecs int        `json:"refresh_interval_secs"` // effective, set by ingestion
+}
+
+// AdminFeed is a tracked_feeds row as seen by the operator API: the
+// catalog fields plus the enable flag and audit columns the public
+// catalog hides.
+type AdminFeed struct {
+	URL                 string     `json:"url"`
+	Name                string     `json:"name"`
+	Category            string     `json:"category"`
+	IsDefault           bool       `json:"is_default"`
+	IsEnabled           bool       `json:"is_enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	AddedBy             *string    `json:"added_by,omitempty"`
+	Language            *string    `json:"language,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+
+	// Refresh scheduling: the fastest subscriber request, the publisher's
+	// ttl / sy:updatePeriod hint, and what ingestion last applied.
+	RequestedIntervalSecs *int       `json:"requested_interval_secs,omitempty"`
+	PublisherIntervalSecs *int       `json:"publisher_interval_secs,omitempty"`
+	EffectiveIntervalSecs int        `json:"effective_interval_secs"`
+	NextPollAt            *time.Time `json:"next_poll_at,omitempty"`
 }
 
 // CDCRecord represents a Change Data Capture record from Sequin.
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Cursor Pagination
+// =============================================================================
+
+// GET /rss/items pages through the signed-in user's articles, newest
+// first; /internal/dashboard does the same when given ?cursor= or
+// ?limit=. Items sort by published_at, falling back to created_at for
+// items without one. next_cursor (rss_next_cursor in the dashboard
+// envelope) fetches the following page and is absent on the last one.
+// Cursors are keyset positions, so newly ingested items don't shift or
+// duplicate later pages.
+
+const (
+	// MaxRSSItemsPageLimit bounds ?limit=; DefaultRSSItemsLimit is the default.
+	MaxRSSItemsPageLimit = 200
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the sort key of the last row on a page.
+type pageCursor struct {
+	At time.Time
+	ID int
+}
+
+// encodeCursor makes an opaque cursor from the last row's sort key.
+func encodeCursor(at time.Time, id int) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + ":" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return pageCursor{}, errInvalidCursor
+	}
+	nanos, err1 := strconv.ParseInt(ts, 10, 64)
+	n, err2 := strconv.Atoi(id)
+	if err1 != nil || err2 != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	return pageCursor{At: time.Unix(0, nanos).UTC(), ID: n}, nil
+}
+
+// pageParams is a parsed ?cursor=&limit= pair.
+type pageParams struct {
+	After *pageCursor // nil for the first page
+	Limit int
+}
+
+// parsePageParams reads ?cursor= and ?limit=. ok is false when neither is
+// present, so the caller keeps its unpaged response.
+func parsePageParams(c *fiber.Ctx, def, max int) (p pageParams, ok bool, err error) {
+	cursor, limit := c.Query("cursor"), c.Query("limit")
+	if cursor == "" && limit == "" {
+		return pageParams{}, false, nil
+	}
+	p.Limit = def
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return pageParams{}, true, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = min(n, max)
+	}
+	if cursor != "" {
+		cur, err := decodeCursor(cursor)
+		if err != nil {
+			return pageParams{}, true, err
+		}
+		p.After = &cur
+	}
+	return p, true, nil
+}
+
+// itemSortTime is the time an item sorts by in paged listings.
+func itemSortTime(item RssItem) time.Time {
+	if item.PublishedAt != nil {
+		return *item.PublishedAt
+	}
+	return item.CreatedAt
+}
+
+// queryRSSItemsPage returns one page of items from feedURLs, filtered by
+// languages as in queryRSSItems, and the cursor for the next page ("" on
+// the last).
+func (a *App) queryRSSItemsPage(ctx context.Context, feedURLs, languages []string, p pageParams) ([]RssItem, string, error) {
+	if len(feedURLs) == 0 {
+		return []RssItem{}, "", nil
+	}
+	var afterTime *time.Time
+	var afterID int
+	if p.After != nil {
+		afterTime, afterID = &p.After.At, p.After.ID
+	}
+
+	// One extra row tells us whether another page exists.
+	rows, err := a.readDB().Query(ctx, `
+		SELECT i.id, i.feed_url, i.guid, i.title, i.link, i.description, i.source_name,
+		       COALESCE(i.language, tf.language), i.published_at, i.created_at, i.updated_at
+		FROM rss_items i
+		LEFT JOIN tracked_feeds tf ON tf.url = i.feed_url
+		WHERE i.feed_url = ANY($1)
+		  AND (
+			$2::text[] IS NULL
+			OR COALESCE(i.language, tf.language) IS NULL
+			OR COALESCE(i.language, tf.language) = ANY($2)
+		  )
+		  AND ($3::timestamptz IS NULL OR (COALESCE(i.published_at, i.created_at), i.id) < ($3, $4))
+		ORDER BY COALESCE(i.published_at, i.created_at) DESC, i.id DESC
+		LIMIT $5
+	`, feedURLs, languages, afterTime, afterID, p.Limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("items page query failed: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]RssItem, 0, p.Limit)
+	for rows.Next() {
+		var item RssItem
+		if err := rows.Scan(
+			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
+			&item.Description, &item.SourceName, &item.Language, &item.PublishedAt,
+			&item.CreatedAt, &item.UpdatedAt,
+		); err != nil {
+			log.Printf("[RSS] Items page scan error: %v", err)
+			continue
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	items, next := trimItemsPage(items, p.Limit)
+	return items, next, nil
+}
+
+// trimItemsPage drops the look-ahead row and returns the next cursor.
+func trimItemsPage(items []RssItem, limit int) ([]RssItem, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	last := items[limit-1]
+	return items, encodeCursor(itemSortTime(last), last.ID)
+}
+
+// getRSSItems handles GET /rss/items?cursor=&limit= for the signed-in user.
+func (a *App) getRSSItems(c *fiber.Ctx) error {
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+	p, paged, err := parsePageParams(c, DefaultRSSItemsLimit, MaxRSSItemsPageLimit)
+	if err != nil {
+		return badPageParams(c, err)
+	}
+	if !paged {
+		p.Limit = DefaultRSSItemsLimit
+	}
+
+	items, next, err := a.userItemsPage(c.Context(), userSub, p)
+	if err != nil {
+		log.Printf("[RSS] %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	resp := fiber.Map{"rss": items}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	return c.JSON(resp)
+}
+
+// userItemsPage loads one page of userSub's items.
+func (a *App) userItemsPage(ctx context.Context, userSub string, p pageParams) ([]RssItem, string, error) {
+	feedURLs := a.getUserRSSFeedURLs(ctx, userSub)
+	return a.queryRSSItemsPage(ctx, feedURLs, a.userLanguages(ctx, userSub), p)
+}
+
+// badPageParams writes the 400 for a malformed ?cursor= or ?limit=.
+func badPageParams(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+		Status: "error",
+		Error:  err.Error(),
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Redis Connection
+// =============================================================================
+
+// Same REDIS_MODE contract as the core gateway (core's redis.go):
+//
+//   - single (default): REDIS_URL.
+//   - sentinel: REDIS_SENTINEL_ADDRS (comma-separated host:port) and
+//     REDIS_SENTINEL_MASTER; REDIS_SENTINEL_PASSWORD authenticates to the
+//     Sentinels. REDIS_URL is optional and supplies the primary's
+//     credentials, DB and TLS (its host is ignored).
+//   - cluster: REDIS_URL is one seed node; REDIS_CLUSTER_ADDRS adds more.
+
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// newRedisClient builds the client REDIS_MODE asks for from getenv.
+func newRedisClient(getenv func(string) string) (redis.UniversalClient, string, error) {
+	mode := strings.ToLower(strings.TrimSpace(getenv("REDIS_MODE")))
+	if mode == "" {
+		mode = RedisModeSingle
+	}
+	redisURL := getenv("REDIS_URL")
+
+	switch mode {
+	case RedisModeSingle:
+		if redisURL == "" {
+			return nil, mode, errors.New("REDIS_URL must be set")
+		}
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		return redis.NewClient(opts), mode, nil
+
+	case RedisModeSentinel:
+		master := strings.TrimSpace(getenv("REDIS_SENTINEL_MASTER"))
+		addrs := splitRedisAddrs(getenv("REDIS_SENTINEL_ADDRS"))
+		if master == "" || len(addrs) == 0 {
+			return nil, mode, errors.New("REDIS_SENTINEL_MASTER and REDIS_SENTINEL_ADDRS must be set")
+		}
+		opts := &redis.FailoverOptions{
+			MasterName:       master,
+			SentinelAddrs:    addrs,
+			SentinelPassword: getenv("REDIS_SENTINEL_PASSWORD"),
+		}
+		if redisURL != "" {
+			base, err := redis.ParseURL(redisURL)
+			if err != nil {
+				return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+			}
+			opts.Username = base.Username
+			opts.Password = base.Password
+			opts.DB = base.DB
+			opts.TLSConfig = base.TLSConfig
+		}
+		return redis.NewFailoverClient(opts), mode, nil
+
+	case RedisModeCluster:
+		if redisURL == "" {
+			return nil, mode, errors.New("REDIS_URL must be set")
+		}
+		opts, err := redis.ParseClusterURL(redisURL)
+		if err != nil {
+			return nil, mode, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		opts.Addrs = append(opts.Addrs, splitRedisAddrs(getenv("REDIS_CLUSTER_ADDRS"))...)
+		return redis.NewClusterClient(opts), mode, nil
+	}
+	return nil, mode, fmt.Errorf("unknown REDIS_MODE %q (want single, sentinel or cluster)", mode)
+}
+
+// splitRedisAddrs parses a comma-separated host:port list.
+func splitRedisAddrs(raw string) []string {
+	var addrs []string
+	for _, a := range strings.Split(raw, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,175 @@
+// Package main — per-feed refresh requests.
+//
+// Ingestion reschedules every feed by its own interval: a subscriber's
+// faster-refresh request, else the publisher's ttl / sy:updatePeriod
+// hint, else the 5-minute default (service/src/schedule.rs). This file
+// owns the request half:
+//
+//   - PUT /rss/feeds/refresh {url, interval_secs} records the caller's
+//     request for a feed in their RSS config; interval_secs 0 or null
+//     withdraws it. Only Uplink Pro and above may ask, bounded by
+//     MinRefreshIntervalSecs.
+//   - tracked_feeds.requested_interval_secs is kept at the fastest
+//     request across users, and next_poll_at is pulled in so a new
+//     request takes effect without waiting out the old interval.
+//   - Requests are dropped when the feed leaves the user's config.
+//
+// The effective interval ingestion applied is reported back through
+// the catalog (refresh_interval_secs) and /admin/rss/feeds.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultRefreshIntervalSecs mirrors DEFAULT_POLL_INTERVAL_SECS in the
+// ingestion service. Requests must be faster than it.
+const DefaultRefreshIntervalSecs = 300
+
+// refreshRequest is the body for PUT /rss/feeds/refresh.
+type refreshRequest struct {
+	URL          string `json:"url"`
+	IntervalSecs *int   `json:"interval_secs"`
+}
+
+// validateRefreshInterval checks a requested interval against the tier's
+// floor. Returns the HTTP status and a user-facing message, or 0 and ""
+// when the request is allowed.
+func validateRefreshInterval(tier string, secs int) (int, string) {
+	minSecs := MinRefreshIntervalSecs(tier)
+	if minSecs == 0 {
+		return fiber.StatusForbidden, "Faster feed refresh requires Uplink Pro or above"
+	}
+	if secs < minSecs || secs >= DefaultRefreshIntervalSecs {
+		return fiber.StatusBadRequest, fmt.Sprintf(
+			"interval_secs must be between %d and %d for your plan", minSecs, DefaultRefreshIntervalSecs-1)
+	}
+	return 0, ""
+}
+
+// setFeedRefresh records or withdraws the caller's refresh request for a
+// feed in their RSS config.
+func (a *App) setFeedRefresh(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	userSub := c.Get("X-User-Sub")
+	if userSub == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Status: "unauthorized",
+			Error:  "Authentication required",
+		})
+	}
+
+	var req refreshRequest
+	if err := c.BodyParser(&req); err != nil || req.URL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Request body must include a non-empty 'url' field",
+		})
+	}
+
+	clearing := req.IntervalSecs == nil || *req.IntervalSecs == 0
+	if !clearing {
+		if status, msg := validateRefreshInterval(GetUserTier(c), *req.IntervalSecs); status != 0 {
+			return c.Status(status).JSON(ErrorResponse{Status: "error", Error: msg})
+		}
+	}
+
+	if clearing {
+		a.clearRefreshRequests(ctx, userSub, []string{req.URL})
+		return c.JSON(fiber.Map{"status": "ok", "url": req.URL, "interval_secs": nil})
+	}
+
+	if !slices.Contains(a.getUserRSSFeedURLs(ctx, userSub), req.URL) {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feed is not in your RSS channel",
+		})
+	}
+
+	cmd, err := a.db.Exec(ctx, `
+		INSERT INTO rss_refresh_requests (logto_sub, feed_url, interval_secs)
+		SELECT $1, url, $3 FROM tracked_feeds WHERE url = $2
+		ON CONFLICT (logto_sub, feed_url) DO UPDATE SET interval_secs = EXCLUDED.interval_secs
+	`, userSub, req.URL, *req.IntervalSecs)
+	if err != nil {
+		log.Printf("[RSS] Failed to save refresh request (%s, %s): %v", userSub, req.URL, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Failed to save refresh interval",
+		})
+	}
+	if cmd.RowsAffected() == 0 {
+		// In the config but not yet synced to tracked_feeds
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Feed is not tracked yet; try again shortly",
+		})
+	}
+
+	a.recomputeRequestedInterval(ctx, req.URL)
+	a.invalidateUserCatalogCache(ctx, userSub)
+
+	log.Printf("[RSS] User %s requested %ds refresh for %s", userSub, *req.IntervalSecs, req.URL)
+	return c.JSON(fiber.Map{"status": "ok", "url": req.URL, "interval_secs": *req.IntervalSecs})
+}
+
+// recomputeRequestedInterval sets a feed's requested_interval_secs to the
+// fastest remaining request and brings next_poll_at forward when the new
+// interval says the feed is due sooner.
+func (a *App) recomputeRequestedInterval(ctx context.Context, feedURL string) {
+	_, err := a.db.Exec(ctx, `
+		WITH req AS (
+			SELECT MIN(interval_secs) AS secs FROM rss_refresh_requests WHERE feed_url = $1
+		)
+		UPDATE tracked_feeds tf
+		SET requested_interval_secs = req.secs,
+			next_poll_at = CASE
+				WHEN req.secs IS NULL THEN tf.next_poll_at
+				ELSE LEAST(tf.next_poll_at, COALESCE(tf.last_success_at, NOW()) + make_interval(secs => req.secs))
+			END
+		FROM req
+		WHERE tf.url = $1
+	`, feedURL)
+	if err != nil {
+		log.Printf("[RSS] Failed to recompute requested interval for %s: %v", feedURL, err)
+	}
+}
+
+// clearRefreshRequests drops userSub's refresh requests for the given
+// feeds and recomputes each affected feed.
+func (a *App) clearRefreshRequests(ctx context.Context, userSub string, feedURLs []string) {
+	if len(feedURLs) == 0 {
+		return
+	}
+	rows, err := a.db.Query(ctx, `
+		DELETE FROM rss_refresh_requests
+		WHERE logto_sub = $1 AND feed_url = ANY($2)
+		RETURNING feed_url
+	`, userSub, feedURLs)
+	if err != nil {
+		log.Printf("[RSS] Failed to clear refresh requests for %s: %v", userSub, err)
+		return
+	}
+	var cleared []string
+	for rows.Next() {
+		var u string
+		if rows.Scan(&u) == nil {
+			cleared = append(cleared, u)
+		}
+	}
+	rows.Close()
+
+	for _, u := range cleared {
+		a.recomputeRequestedInterval(ctx, u)
+	}
+	if len(cleared) > 0 {
+		a.invalidateUserCatalogCache(ctx, userSub)
+	}
+}
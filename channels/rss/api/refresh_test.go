@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestValidateRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		tier   string
+		secs   int
+		status int
+	}{
+		{"free tier", TierFree, 120, fiber.StatusForbidden},
+		{"uplink tier", TierUplink, 120, fiber.StatusForbidden},
+		{"pro at floor", TierUplinkPro, 120, 0},
+		{"pro below floor", TierUplinkPro, 60, fiber.StatusBadRequest},
+		{"ultimate at floor", TierUplinkUltimate, 60, 0},
+		{"not faster than default", TierSuperUser, DefaultRefreshIntervalSecs, fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := validateRefreshInterval(tt.tier, tt.secs)
+			if status != tt.status {
+				t.Errorf("status = %d (%q), want %d", status, msg, tt.status)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -43,6 +45,10 @@ const (
 	// RedisRSSSubscribersPrefix is the Redis key prefix for per-feed-URL
 	// subscriber sets.
 	RedisRSSSubscribersPrefix = "rss:subscribers:"
+
+	// MaxConcurrentFeedSyncs bounds background tracked_feeds syncs started
+	// by lifecycle events. Past it, the sync runs on the caller.
+	MaxConcurrentFeedSyncs = 16
 )
 
 // =============================================================================
@@ -52,9 +58,16 @@ const (
 // App holds the shared dependencies for all handlers.
 type App struct {
 	db         *pgxpool.Pool
-	rdb        *redis.Client
+	replica    *readReplica // optional; read through readDB (db.go)
+	rdb        redis.UniversalClient
 	httpClient *http.Client
 	sfGroup    singleflight.Group
+	bulk       bulkWriters
+	feedSyncs  chan struct{} // semaphore, MaxConcurrentFeedSyncs slots
+	feedClient *http.Client  // feed previews; refuses non-public addresses
+
+	websubBase     string       // public base URL for hub callbacks; "" disables WebSub
+	lastIngestWake atomic.Int64 // unix nanos of the last ingestion wake-up
 }
 
 // =============================================================================
@@ -97,6 +110,10 @@ func (a *App) getRSSFeedCatalog(c *fiber.Ctx) error {
 		})
 	}
 
+	// Quota is computed per request (not cached with the catalog) so the
+	// count reflects a save the user made seconds ago.
+	setQuotaHeaders(c, "feeds", len(a.getUserRSSFeedURLs(ctx, userSub)), FeedCap(GetUserTier(c)))
+
 	// Per-user cache key. The catalog content depends on which custom
 	// feeds the user owns, so user A and user B can't share an entry.
 	cacheKey := CacheKeyRSSCatalog + ":" + userSub
@@ -104,9 +121,21 @@ func (a *App) getRSSFeedCatalog(c *fiber.Ctx) error {
 		cacheKey += ":all"
 	}
 
+	// ?language= narrows the cached catalog rather than keying the cache
+	language := normalizeLanguage(c.Query("language"))
+	if c.Query("language") != "" && language == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "language must be a language code such as 'en'",
+		})
+	}
+
 	var catalog []TrackedFeed
-	if GetCache(a.rdb, ctx, cacheKey, &catalog) {
+	if countCache("catalog", GetCache(a.rdb, ctx, cacheKey, &catalog)) {
 		c.Set("X-Cache", "HIT")
+		if language != "" {
+			catalog = filterCatalogByLanguage(catalog, language)
+		}
 		return c.JSON(catalog)
 	}
 
@@ -132,6 +161,9 @@ func (a *App) getRSSFeedCatalog(c *fiber.Ctx) error {
 
 	SetCache(a.rdb, ctx, cacheKey, catalog, RSSCatalogCacheTTL)
 	c.Set("X-Cache", "MISS")
+	if language != "" {
+		catalog = filterCatalogByLanguage(catalog, language)
+	}
 	return c.JSON(catalog)
 }
 
@@ -194,7 +226,8 @@ func (a *App) queryUserCatalog(ctx context.Context, userSub string, includeFaili
 	// $1 = userSub (used in the custom-feeds half)
 	// $2 = MaxConsecutiveFailures (used by both halves when !includeFailing)
 	query := `
-		SELECT url, name, category, is_default, consecutive_failures, last_error, last_success_at
+		SELECT url, name, category, is_default, language, consecutive_failures, last_error, last_success_at,
+			effective_interval_secs
 		FROM tracked_feeds
 		` + curatedClauses + `
 
@@ -205,9 +238,11 @@ func (a *App) queryUserCatalog(ctx context.Context, userSub string, includeFaili
 			ucf.name,
 			ucf.category,
 			false AS is_default,
+			tf.language,
 			COALESCE(tf.consecutive_failures, 0) AS consecutive_failures,
 			tf.last_error,
-			tf.last_success_at
+			tf.last_success_at,
+			COALESCE(tf.effective_interval_secs, ` + strconv.Itoa(DefaultRefreshIntervalSecs) + `) AS effective_interval_secs
 		FROM user_custom_feeds ucf
 		LEFT JOIN tracked_feeds tf ON tf.url = ucf.url
 		` + customClauses + `
@@ -230,7 +265,7 @@ func (a *App) queryUserCatalog(ctx context.Context, userSub string, includeFaili
 	var feeds []TrackedFeed
 	for rows.Next() {
 		var f TrackedFeed
-		if err := rows.Scan(&f.URL, &f.Name, &f.Category, &f.IsDefault, &f.ConsecutiveFailures, &f.LastError, &f.LastSuccessAt); err != nil {
+		if err := rows.Scan(&f.URL, &f.Name, &f.Category, &f.IsDefault, &f.Language, &f.ConsecutiveFailures, &f.LastError, &f.LastSuccessAt, &f.RefreshIntervalSecs); err != nil {
 			log.Printf("[RSS] Catalog scan error: %v", err)
 			continue
 		}
@@ -424,6 +459,7 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 		})
 	}
 
+	cdcBatchSize.Observe(float64(len(req.Records)))
 	ctx := c.Context()
 
 	// Collect unique feed URLs first
@@ -471,7 +507,7 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 }
 
 // handleInternalDashboard returns RSS items for a user's dashboard.
-// Query param: user={logto_sub}
+// Query params: user={logto_sub}, optional cursor/limit (pagination.go)
 func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	ctx := c.Context()
 
@@ -480,10 +516,27 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"rss": []RssItem{}})
 	}
 
+	page, paged, err := parsePageParams(c, DefaultRSSItemsLimit, MaxRSSItemsPageLimit)
+	if err != nil {
+		return badPageParams(c, err)
+	}
+	if paged {
+		items, next, err := a.userItemsPage(ctx, userSub, page)
+		if err != nil {
+			log.Printf("[RSS] Dashboard %v", err)
+			return c.JSON(fiber.Map{"rss": []RssItem{}})
+		}
+		resp := fiber.Map{"rss": items}
+		if next != "" {
+			resp["rss_next_cursor"] = next
+		}
+		return c.JSON(resp)
+	}
+
 	// Check per-user cache first
 	cacheKey := CacheKeyRSSPrefix + userSub
 	var items []RssItem
-	if GetCache(a.rdb, ctx, cacheKey, &items) {
+	if countCache("dashboard", GetCache(a.rdb, ctx, cacheKey, &items)) {
 		return c.JSON(fiber.Map{"rss": items})
 	}
 
@@ -493,7 +546,7 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"rss": []RssItem{}})
 	}
 
-	items = a.queryRSSItems(ctx, feedURLs)
+	items = a.queryRSSItems(ctx, feedURLs, a.userLanguages(ctx, userSub))
 	if items == nil {
 		items = make([]RssItem, 0)
 	}
@@ -554,15 +607,35 @@ func (a *App) handleInternalHealth(c *fiber.Ctx) error {
 // Channel Lifecycle (RSS is the ONLY channel that implements this)
 // =============================================================================
 
+// lifecycleEvent is the body of a lifecycle call from the core gateway.
+type lifecycleEvent struct {
+	Event     string                 `json:"event"`
+	User      string                 `json:"user"`
+	Config    map[string]interface{} `json:"config"`
+	OldConfig map[string]interface{} `json:"old_config"`
+	Enabled   bool                   `json:"enabled"`
+}
+
 // handleChannelLifecycle handles channel lifecycle events dispatched by the core
 // gateway. Events: created, updated, deleted, sync.
 func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
+	var req lifecycleEvent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	a.applyLifecycleEvent(c.Context(), req)
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// handleLifecycleReplay applies lifecycle events the gateway queued while
+// this channel was unreachable, in the order they were originally sent.
+func (a *App) handleLifecycleReplay(c *fiber.Ctx) error {
 	var req struct {
-		Event     string                 `json:"event"`
-		User      string                 `json:"user"`
-		Config    map[string]interface{} `json:"config"`
-		OldConfig map[string]interface{} `json:"old_config"`
-		Enabled   bool                   `json:"enabled"`
+		Events []lifecycleEvent `json:"events"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
@@ -571,32 +644,52 @@ func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
 		})
 	}
 
-	ctx := c.Context()
+	ctx := context.Background()
+	for _, ev := range req.Events {
+		a.applyLifecycleEvent(ctx, ev)
+	}
+	log.Printf("[RSS Lifecycle] Replayed %d queued events", len(req.Events))
+	return c.JSON(fiber.Map{"processed": len(req.Events)})
+}
 
-	switch req.Event {
+func (a *App) applyLifecycleEvent(ctx context.Context, ev lifecycleEvent) {
+	switch ev.Event {
 	case "created":
-		a.onChannelCreated(req.User, req.Config)
+		a.onChannelCreated(ev.User, ev.Config)
 
 	case "updated":
-		a.onChannelUpdated(ctx, req.User, req.OldConfig, req.Config)
+		a.onChannelUpdated(ctx, ev.User, ev.OldConfig, ev.Config)
 
 	case "deleted":
-		a.onChannelDeleted(ctx, req.User, req.Config)
+		a.onChannelDeleted(ctx, ev.User, ev.Config)
 
 	case "sync":
-		a.onSyncSubscriptions(ctx, req.User, req.Config, req.Enabled)
+		a.onSyncSubscriptions(ctx, ev.User, ev.Config, ev.Enabled)
 
 	default:
-		log.Printf("[RSS Lifecycle] Unknown event: %s", req.Event)
+		log.Printf("[RSS Lifecycle] Unknown event: %s", ev.Event)
 	}
-
-	return c.JSON(fiber.Map{"ok": true})
 }
 
 // onChannelCreated syncs feeds to tracked_feeds table when a new RSS channel
-// is created. Runs in a goroutine so it doesn't block the response.
+// is created. Runs in the background so it doesn't block the response.
 func (a *App) onChannelCreated(userSub string, config map[string]interface{}) {
-	go a.syncRSSFeedsToTracked(userSub, config)
+	a.syncRSSFeedsInBackground(userSub, config)
+}
+
+// syncRSSFeedsInBackground runs syncRSSFeedsToTracked on its own goroutine
+// while a feedSyncs slot is free, and on the caller otherwise, so a burst
+// of lifecycle events can't spawn an unbounded number of syncs.
+func (a *App) syncRSSFeedsInBackground(userSub string, config map[string]interface{}) {
+	select {
+	case a.feedSyncs <- struct{}{}:
+		go func() {
+			defer func() { <-a.feedSyncs }()
+			a.syncRSSFeedsToTracked(userSub, config)
+		}()
+	default:
+		a.syncRSSFeedsToTracked(userSub, config)
+	}
 }
 
 // onChannelUpdated handles feed list changes when a channel is updated.
@@ -615,17 +708,20 @@ func (a *App) onChannelUpdated(ctx context.Context, userSub string, oldConfig, n
 	for _, u := range newFeedURLs {
 		newURLSet[u] = true
 	}
+	var removed []string
 	for _, u := range oldFeedURLs {
 		if !newURLSet[u] {
 			RemoveSubscriber(a.rdb, ctx, RedisRSSSubscribersPrefix+u, userSub)
+			removed = append(removed, u)
 		}
 	}
+	a.clearRefreshRequests(ctx, userSub, removed)
 
 	// Invalidate per-user RSS cache
 	a.rdb.Del(ctx, CacheKeyRSSPrefix+userSub)
 
 	// Sync new feed URLs to tracked_feeds
-	go a.syncRSSFeedsToTracked(userSub, newConfig)
+	a.syncRSSFeedsInBackground(userSub, newConfig)
 }
 
 // onChannelDeleted removes the user from all per-feed-URL subscriber sets and
@@ -635,6 +731,7 @@ func (a *App) onChannelDeleted(ctx context.Context, userSub string, config map[s
 	for _, url := range feedURLs {
 		RemoveSubscriber(a.rdb, ctx, RedisRSSSubscribersPrefix+url, userSub)
 	}
+	a.clearRefreshRequests(ctx, userSub, feedURLs)
 	a.rdb.Del(ctx, CacheKeyRSSPrefix+userSub)
 }
 
@@ -668,19 +765,28 @@ func (a *App) getUserRSSFeedURLs(ctx context.Context, logtoSub string) []string
 	return extractFeedURLsFromConfig(configJSON)
 }
 
-// queryRSSItems fetches the latest RSS items for the given feed URLs.
-func (a *App) queryRSSItems(ctx context.Context, feedURLs []string) []RssItem {
+// queryRSSItems fetches the latest RSS items for the given feed URLs,
+// keeping only items in languages (plus unknown-language items) when
+// languages is non-nil.
+func (a *App) queryRSSItems(ctx context.Context, feedURLs []string, languages []string) []RssItem {
 	if len(feedURLs) == 0 {
 		return nil
 	}
 
-	rows, err := a.db.Query(ctx, `
-		SELECT id, feed_url, guid, title, link, description, source_name, published_at, created_at, updated_at
-		FROM rss_items
-		WHERE feed_url = ANY($1)
-		ORDER BY published_at DESC NULLS LAST
+	rows, err := a.readDB().Query(ctx, `
+		SELECT i.id, i.feed_url, i.guid, i.title, i.link, i.description, i.source_name,
+		       COALESCE(i.language, tf.language), i.published_at, i.created_at, i.updated_at
+		FROM rss_items i
+		LEFT JOIN tracked_feeds tf ON tf.url = i.feed_url
+		WHERE i.feed_url = ANY($1)
+		  AND (
+			$3::text[] IS NULL
+			OR COALESCE(i.language, tf.language) IS NULL
+			OR COALESCE(i.language, tf.language) = ANY($3)
+		  )
+		ORDER BY i.published_at DESC NULLS LAST
 		LIMIT $2
-	`, feedURLs, DefaultRSSItemsLimit)
+	`, feedURLs, DefaultRSSItemsLimit, languages)
 	if err != nil {
 		log.Printf("[RSS] Items query failed: %v", err)
 		return nil
@@ -692,7 +798,7 @@ func (a *App) queryRSSItems(ctx context.Context, feedURLs []string) []RssItem {
 		var item RssItem
 		if err := rows.Scan(
 			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
-			&item.Description, &item.SourceName, &item.PublishedAt,
+			&item.Description, &item.SourceName, &item.Language, &item.PublishedAt,
 			&item.CreatedAt, &item.UpdatedAt,
 		); err != nil {
 			log.Printf("[RSS] Items scan error: %v", err)
@@ -0,0 +1,565 @@
+// Package main — WebSub (PubSubHubbub) subscriber.
+//
+// Feeds that advertise a hub get pushed updates instead of waiting out
+// their poll interval:
+//
+//   - Discovery: the hub and self (topic) URLs come from an HTTP Link
+//     header or the feed's own <link rel="hub">/<atom:link rel="hub">.
+//     POST /rss/feeds/validate reports them in the preview; the manager
+//     loop below discovers them for every tracked feed and records the
+//     result in rss_websub_subscriptions (hub_url NULL = none found).
+//   - Subscription: the manager asks the hub to subscribe
+//     {WEBSUB_CALLBACK_BASE_URL}/rss/websub/callback?token=…, with a
+//     per-feed secret, and renews the lease before it runs out.
+//   - Verification: the hub GETs the callback with hub.challenge, which
+//     is echoed back once the token and topic match.
+//   - Content: the hub POSTs the updated feed, signed with the secret
+//     (X-Hub-Signature). A valid push marks the feed due and wakes the
+//     ingestion service, which re-polls it within seconds. Ingestion
+//     keeps parsing in one place; the push is only the trigger.
+//
+// Polling continues unchanged underneath, so a hub that goes quiet or a
+// subscription that lapses costs latency, never items. Without
+// WEBSUB_CALLBACK_BASE_URL the manager doesn't run and every feed is
+// polled as before.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// WebSubCallbackPath is the public route hubs call back on.
+	WebSubCallbackPath = "/rss/websub/callback"
+
+	// WebSubLeaseSecs is the lease requested from hubs, and assumed when
+	// a verification doesn't state one. Hubs may grant less.
+	WebSubLeaseSecs = 10 * 24 * 60 * 60
+
+	// WebSubManagerInterval is how often discovery and renewal run.
+	WebSubManagerInterval = 10 * time.Minute
+
+	// WebSubRunTimeout caps a single manager pass.
+	WebSubRunTimeout = 5 * time.Minute
+
+	// WebSubBatchSize bounds feeds discovered, and subscriptions
+	// renewed, per pass. Each is an outbound request.
+	WebSubBatchSize = 25
+
+	// WebSubLockKey keeps one pod's manager running per interval.
+	WebSubLockKey = "rss:websub:lock"
+
+	// SQL intervals: renew this long before a lease ends; resend a
+	// subscription the hub hasn't verified after WebSubVerifyTimeout;
+	// look for a hub again on feeds without one after
+	// WebSubRediscoverAfter, and after a denial or failure after
+	// WebSubRetryAfter.
+	WebSubRenewBefore     = "1 day"
+	WebSubVerifyTimeout   = "1 hour"
+	WebSubRediscoverAfter = "7 days"
+	WebSubRetryAfter      = "1 day"
+
+	// WebSubMaxPushBytes caps a pushed body; pushes are full feeds.
+	WebSubMaxPushBytes = FeedValidateMaxBytes
+
+	// WebSubWakeMinGap collapses bursts of pushes into one ingestion
+	// wake-up. A push inside the gap is still marked due and is picked up
+	// by that wake-up or the next scheduler tick.
+	WebSubWakeMinGap = 2 * time.Second
+)
+
+// ─── Discovery ───────────────────────────────────────────────────
+
+// linkHeaderWebSub returns the hub and self URLs from RFC 8288 Link
+// headers, e.g. `<https://hub.example/>; rel="hub"`.
+func linkHeaderWebSub(header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, part := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			href := strings.TrimSpace(target[1 : len(target)-1])
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					switch strings.ToLower(rel) {
+					case "hub":
+						if hub == "" {
+							hub = href
+						}
+					case "self":
+						if self == "" {
+							self = href
+						}
+					}
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// relLink returns the href of the first feed-level link with the given
+// rel, which may be one of several space-separated values.
+func relLink(links []feedLink, rel string) string {
+	for _, l := range links {
+		if l.Href == "" {
+			continue
+		}
+		for _, r := range strings.Fields(l.Rel) {
+			if strings.EqualFold(r, rel) {
+				return strings.TrimSpace(l.Href)
+			}
+		}
+	}
+	return ""
+}
+
+// ─── Manager ─────────────────────────────────────────────────────
+
+// startWebSub launches the discovery/renewal loop. No-op when
+// WEBSUB_CALLBACK_BASE_URL is unset: hubs would have nowhere to call.
+func (a *App) startWebSub(rootCtx context.Context) {
+	if a.websubBase == "" {
+		log.Printf("[RSS WebSub] WEBSUB_CALLBACK_BASE_URL not set; push updates disabled")
+		return
+	}
+	go func() {
+		select {
+		case <-time.After(30 * time.Second):
+		case <-rootCtx.Done():
+			return
+		}
+		log.Printf("[RSS WebSub] starting; callback=%s%s, interval=%s", a.websubBase, WebSubCallbackPath, WebSubManagerInterval)
+
+		for {
+			a.runWebSubOnce(rootCtx)
+
+			select {
+			case <-time.After(WebSubManagerInterval):
+			case <-rootCtx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// runWebSubOnce discovers hubs for feeds not yet checked and renews
+// subscriptions close to expiry.
+func (a *App) runWebSubOnce(parent context.Context) {
+	ctx, cancel := context.WithTimeout(parent, WebSubRunTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[RSS WebSub] PANIC during pass: %v", r)
+		}
+	}()
+
+	// Fails open on Redis errors: a duplicate subscribe is harmless.
+	if ok, err := a.rdb.SetNX(ctx, WebSubLockKey, "1", WebSubManagerInterval/2).Result(); err == nil && !ok {
+		return
+	}
+
+	discovered := a.discoverWebSubHubs(ctx)
+	renewed := a.renewWebSubSubscriptions(ctx)
+	if discovered > 0 || renewed > 0 {
+		log.Printf("[RSS WebSub] pass complete: %d hub(s) discovered, %d subscription(s) requested", discovered, renewed)
+	}
+}
+
+// discoverWebSubHubs checks a batch of tracked feeds for a hub and
+// subscribes to the ones that have one. Returns the hubs found.
+func (a *App) discoverWebSubHubs(ctx context.Context) int {
+	rows, err := a.db.Query(ctx, `
+		SELECT tf.url
+		FROM tracked_feeds tf
+		LEFT JOIN rss_websub_subscriptions ws ON ws.feed_url = tf.url
+		WHERE tf.is_enabled = TRUE
+		  AND tf.consecutive_failures < $1
+		  AND (ws.feed_url IS NULL
+		       OR (ws.state = 'none' AND ws.discovered_at < NOW() - $2::interval)
+		       OR (ws.state IN ('denied', 'failed') AND ws.discovered_at < NOW() - $3::interval))
+		ORDER BY ws.discovered_at NULLS FIRST
+		LIMIT $4
+	`, MaxConsecutiveFailures, WebSubRediscoverAfter, WebSubRetryAfter, WebSubBatchSize)
+	if err != nil {
+		log.Printf("[RSS WebSub] discovery query failed: %v", err)
+		return 0
+	}
+	var feedURLs []string
+	for rows.Next() {
+		var u string
+		if rows.Scan(&u) == nil {
+			feedURLs = append(feedURLs, u)
+		}
+	}
+	rows.Close()
+
+	found := 0
+	for _, feedURL := range feedURLs {
+		body, header, err := fetchFeedResponse(ctx, a.feedClient, feedURL)
+		if err != nil {
+			// Left for the next pass; persistent failures drop out via
+			// consecutive_failures.
+			continue
+		}
+		hub, topic := linkHeaderWebSub(header)
+		if preview, err := parseFeed(body); err == nil {
+			if hub == "" {
+				hub = preview.WebSubHub
+			}
+			if topic == "" {
+				topic = preview.topic
+			}
+		}
+		if _, ok := parseFeedURL(hub); !ok {
+			hub = ""
+		}
+		if _, ok := parseFeedURL(topic); !ok {
+			topic = feedURL
+		}
+
+		var hubArg, topicArg *string
+		if hub != "" {
+			hubArg, topicArg = &hub, &topic
+		}
+		if _, err := a.db.Exec(ctx, `
+			INSERT INTO rss_websub_subscriptions (feed_url, hub_url, topic_url, state, discovered_at)
+			VALUES ($1, $2, $3, 'none', NOW())
+			ON CONFLICT (feed_url) DO UPDATE SET
+				hub_url = EXCLUDED.hub_url,
+				topic_url = EXCLUDED.topic_url,
+				state = 'none',
+				discovered_at = NOW()
+		`, feedURL, hubArg, topicArg); err != nil {
+			log.Printf("[RSS WebSub] failed to record discovery for %s: %v", feedURL, err)
+			continue
+		}
+		if hub != "" {
+			found++
+			a.subscribeWebSub(ctx, feedURL)
+		}
+	}
+	return found
+}
+
+// renewWebSubSubscriptions re-requests leases ending within
+// WebSubRenewBefore and subscriptions the hub never verified. Returns
+// how many were requested.
+func (a *App) renewWebSubSubscriptions(ctx context.Context) int {
+	rows, err := a.db.Query(ctx, `
+		SELECT feed_url
+		FROM rss_websub_subscriptions
+		WHERE hub_url IS NOT NULL
+		  AND (requested_at IS NULL OR requested_at < NOW() - $1::interval)
+		  AND (state = 'pending'
+		       OR (state = 'verified' AND lease_expires_at < NOW() + $2::interval))
+		ORDER BY lease_expires_at NULLS FIRST
+		LIMIT $3
+	`, WebSubVerifyTimeout, WebSubRenewBefore, WebSubBatchSize)
+	if err != nil {
+		log.Printf("[RSS WebSub] renewal query failed: %v", err)
+		return 0
+	}
+	var feedURLs []string
+	for rows.Next() {
+		var u string
+		if rows.Scan(&u) == nil {
+			feedURLs = append(feedURLs, u)
+		}
+	}
+	rows.Close()
+
+	for _, feedURL := range feedURLs {
+		a.subscribeWebSub(ctx, feedURL)
+	}
+	return len(feedURLs)
+}
+
+// newWebSubToken returns 32 random bytes, hex-encoded. Used for both the
+// callback token and the signing secret.
+func newWebSubToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// subscribeWebSub sends a subscription request to the feed's hub. The
+// callback token and secret are created on first use and kept across
+// renewals. A renewal leaves a verified subscription accepting pushes
+// until the hub re-verifies it or the lease runs out.
+func (a *App) subscribeWebSub(ctx context.Context, feedURL string) {
+	token, err := newWebSubToken()
+	if err != nil {
+		log.Printf("[RSS WebSub] token generation failed: %v", err)
+		return
+	}
+	secret, err := newWebSubToken()
+	if err != nil {
+		log.Printf("[RSS WebSub] secret generation failed: %v", err)
+		return
+	}
+
+	var hub, topic string
+	if err := a.db.QueryRow(ctx, `
+		UPDATE rss_websub_subscriptions
+		SET callback_token = COALESCE(callback_token, $2),
+			secret = COALESCE(secret, $3)
+		WHERE feed_url = $1 AND hub_url IS NOT NULL
+		RETURNING callback_token, secret, hub_url, topic_url
+	`, feedURL, token, secret).Scan(&token, &secret, &hub, &topic); err != nil {
+		log.Printf("[RSS WebSub] failed to load subscription for %s: %v", feedURL, err)
+		return
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {a.websubBase + WebSubCallbackPath + "?token=" + token},
+		"hub.lease_seconds": {strconv.Itoa(WebSubLeaseSecs)},
+		"hub.secret":        {secret},
+	}
+	reqErr := postWebSubRequest(ctx, a.feedClient, hub, form)
+
+	if reqErr != nil {
+		log.Printf("[RSS WebSub] subscribe to %s for %s failed: %v", hub, feedURL, reqErr)
+		_, err = a.db.Exec(ctx, `
+			UPDATE rss_websub_subscriptions
+			SET state = CASE WHEN state = 'verified' AND lease_expires_at > NOW() THEN 'verified' ELSE 'failed' END,
+				last_error = $2,
+				requested_at = NOW()
+			WHERE feed_url = $1
+		`, feedURL, reqErr.Error())
+	} else {
+		_, err = a.db.Exec(ctx, `
+			UPDATE rss_websub_subscriptions
+			SET state = CASE WHEN state = 'verified' THEN 'verified' ELSE 'pending' END,
+				last_error = NULL,
+				requested_at = NOW()
+			WHERE feed_url = $1
+		`, feedURL)
+	}
+	if err != nil {
+		log.Printf("[RSS WebSub] failed to record subscribe request for %s: %v", feedURL, err)
+	}
+}
+
+// postWebSubRequest sends a form-encoded subscription request. Hubs
+// answer 202 Accepted and verify asynchronously; any 2xx is success.
+func postWebSubRequest(ctx context.Context, client *http.Client, hubURL string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", feedUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub responded with HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ─── Callback ────────────────────────────────────────────────────
+
+// websubLease parses hub.lease_seconds, defaulting to WebSubLeaseSecs.
+func websubLease(raw string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n <= 0 {
+		return WebSubLeaseSecs
+	}
+	return n
+}
+
+// validWebSubSignature checks an X-Hub-Signature header ("sha256=<hex>",
+// or sha1/sha384/sha512) against the body.
+func validWebSubSignature(secret, header string, body []byte) bool {
+	algo, sig, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok || secret == "" {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(algo) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// websubVerify answers a hub's intent verification (GET). The challenge
+// is echoed only for a subscribe whose token and topic match a row, or
+// for an unsubscribe of a token we no longer hold.
+func (a *App) websubVerify(c *fiber.Ctx) error {
+	ctx := c.Context()
+	token := c.Query("token")
+	mode := c.Query("hub.mode")
+	challenge := c.Query("hub.challenge")
+
+	var feedURL, topic string
+	err := a.db.QueryRow(ctx, `
+		SELECT feed_url, COALESCE(topic_url, '')
+		FROM rss_websub_subscriptions
+		WHERE callback_token = $1
+	`, token).Scan(&feedURL, &topic)
+	if token == "" || err != nil {
+		if mode == "unsubscribe" && challenge != "" {
+			return c.Type("txt").SendString(challenge)
+		}
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	switch mode {
+	case "subscribe":
+		if challenge == "" || c.Query("hub.topic") != topic {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		lease := websubLease(c.Query("hub.lease_seconds"))
+		if _, err := a.db.Exec(ctx, `
+			UPDATE rss_websub_subscriptions
+			SET state = 'verified',
+				verified_at = NOW(),
+				lease_expires_at = NOW() + make_interval(secs => $2),
+				last_error = NULL
+			WHERE feed_url = $1
+		`, feedURL, float64(lease)); err != nil {
+			log.Printf("[RSS WebSub] failed to record verification for %s: %v", feedURL, err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		log.Printf("[RSS WebSub] subscription verified for %s (lease %ds)", feedURL, lease)
+		return c.Type("txt").SendString(challenge)
+
+	case "denied":
+		reason := c.Query("hub.reason")
+		if _, err := a.db.Exec(ctx, `
+			UPDATE rss_websub_subscriptions
+			SET state = 'denied', last_error = NULLIF($2, '')
+			WHERE feed_url = $1
+		`, feedURL, reason); err != nil {
+			log.Printf("[RSS WebSub] failed to record denial for %s: %v", feedURL, err)
+		}
+		log.Printf("[RSS WebSub] hub denied subscription for %s: %s", feedURL, reason)
+		return c.SendStatus(fiber.StatusOK)
+
+	default:
+		// We never unsubscribe a live subscription; refuse anyone who asks.
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+}
+
+// websubPush receives a content distribution (POST). Per the spec a push
+// with a bad signature is acknowledged but ignored, so a forger learns
+// nothing. An unknown token gets 410 so the hub drops the subscription.
+func (a *App) websubPush(c *fiber.Ctx) error {
+	ctx := c.Context()
+	token := c.Query("token")
+
+	var feedURL, state, secret string
+	err := a.db.QueryRow(ctx, `
+		SELECT feed_url, state, COALESCE(secret, '')
+		FROM rss_websub_subscriptions
+		WHERE callback_token = $1
+	`, token).Scan(&feedURL, &state, &secret)
+	if token == "" || err != nil {
+		return c.SendStatus(fiber.StatusGone)
+	}
+
+	body := c.Body()
+	if len(body) > WebSubMaxPushBytes {
+		return c.SendStatus(fiber.StatusRequestEntityTooLarge)
+	}
+	if state != "verified" || !validWebSubSignature(secret, c.Get("X-Hub-Signature"), body) {
+		log.Printf("[RSS WebSub] ignoring unverified push for %s (state %s)", feedURL, state)
+		return c.SendStatus(fiber.StatusAccepted)
+	}
+
+	if _, err := a.db.Exec(ctx,
+		"UPDATE tracked_feeds SET next_poll_at = NOW() WHERE url = $1", feedURL,
+	); err != nil {
+		log.Printf("[RSS WebSub] failed to mark %s due: %v", feedURL, err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if _, err := a.db.Exec(ctx,
+		"UPDATE rss_websub_subscriptions SET last_push_at = NOW() WHERE feed_url = $1", feedURL,
+	); err != nil {
+		log.Printf("[RSS WebSub] failed to record push for %s: %v", feedURL, err)
+	}
+	a.wakeIngestion()
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// wakeIngestion asks the ingestion service to run a scheduler tick now
+// rather than at the end of its sleep. Best effort and debounced by
+// WebSubWakeMinGap; a missed wake-up costs at most one tick of latency.
+func (a *App) wakeIngestion() {
+	internalURL := os.Getenv("INTERNAL_RSS_URL")
+	if internalURL == "" {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := a.lastIngestWake.Load()
+	if now-last < int64(WebSubWakeMinGap) || !a.lastIngestWake.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), HealthProxyTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+			strings.TrimSuffix(internalURL, "/")+"/ingest/wake", nil)
+		if err != nil {
+			return
+		}
+		resp, err := a.httpClient.Do(req)
+		if err != nil {
+			log.Printf("[RSS WebSub] ingestion wake-up failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
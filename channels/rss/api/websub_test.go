@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestParseFeedWebSubLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		hub   string
+		topic string
+	}{
+		{"rss atom:link", `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>T</title>
+			<link>https://site.example/</link>
+			<atom:link rel="self" href="https://site.example/feed"/>
+			<atom:link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+			</channel></rss>`, "https://pubsubhubbub.appspot.com/", "https://site.example/feed"},
+		{"atom feed links", `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title>
+			<link rel="hub" href="https://hub.example/"/>
+			<link rel="self" href="https://blog.example/atom"/>
+			<entry><title>E</title><link rel="hub" href="https://wrong.example/"/></entry>
+			</feed>`, "https://hub.example/", "https://blog.example/atom"},
+		{"no hub", rss2Sample, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseFeed([]byte(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.WebSubHub != tc.hub || got.topic != tc.topic {
+				t.Errorf("hub, topic = %q, %q; want %q, %q", got.WebSubHub, got.topic, tc.hub, tc.topic)
+			}
+		})
+	}
+}
+
+func TestLinkHeaderWebSub(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://hub.example/>; rel="hub", <https://site.example/feed>; rel="self"`)
+	header.Add("Link", `<https://other.example/>; rel="hub"`)
+	hub, self := linkHeaderWebSub(header)
+	if hub != "https://hub.example/" || self != "https://site.example/feed" {
+		t.Errorf("got %q, %q", hub, self)
+	}
+
+	header = http.Header{"Link": {`<https://both.example/>; rel="hub self"`, `garbage; rel=hub`}}
+	if hub, self := linkHeaderWebSub(header); hub != "https://both.example/" || self != "https://both.example/" {
+		t.Errorf("multi-rel got %q, %q", hub, self)
+	}
+
+	if hub, self := linkHeaderWebSub(http.Header{}); hub != "" || self != "" {
+		t.Errorf("no header got %q, %q", hub, self)
+	}
+}
+
+func TestValidWebSubSignature(t *testing.T) {
+	body := []byte("<feed/>")
+	sign := func(h func() []byte) string { return hex.EncodeToString(h()) }
+	sha1Sig := sign(func() []byte { m := hmac.New(sha1.New, []byte("s3cret")); m.Write(body); return m.Sum(nil) })
+	sha256Sig := sign(func() []byte { m := hmac.New(sha256.New, []byte("s3cret")); m.Write(body); return m.Sum(nil) })
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"sha1", "s3cret", "sha1=" + sha1Sig, true},
+		{"sha256", "s3cret", "sha256=" + sha256Sig, true},
+		{"wrong secret", "other", "sha256=" + sha256Sig, false},
+		{"wrong algorithm", "s3cret", "sha512=" + sha256Sig, false},
+		{"unknown algorithm", "s3cret", "md5=" + sha256Sig, false},
+		{"missing", "s3cret", "", false},
+		{"not hex", "s3cret", "sha1=zz", false},
+		{"no secret", "", "sha1=" + sha1Sig, false},
+	}
+	for _, tc := range tests {
+		if got := validWebSubSignature(tc.secret, tc.header, body); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWebSubLease(t *testing.T) {
+	for raw, want := range map[string]int{
+		"86400": 86400,
+		" 3600": 3600,
+		"":      WebSubLeaseSecs,
+		"0":     WebSubLeaseSecs,
+		"-5":    WebSubLeaseSecs,
+		"soon":  WebSubLeaseSecs,
+	} {
+		if got := websubLease(raw); got != want {
+			t.Errorf("websubLease(%q) = %d, want %d", raw, got, want)
+		}
+	}
+}
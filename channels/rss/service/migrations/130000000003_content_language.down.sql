@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tracked_feeds_language;
+ALTER TABLE rss_items DROP COLUMN IF EXISTS language;
+ALTER TABLE tracked_feeds DROP COLUMN IF EXISTS language;
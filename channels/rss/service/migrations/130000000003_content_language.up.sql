@@ -0,0 +1,13 @@
+-- Content language for the shared catalog, introduced 2026-10-16.
+--
+-- The catalog now mixes languages, so ingestion records one per feed
+-- and per item: ISO 639-1 primary subtags ("en", "es"), NULL when
+-- neither declared nor detectable. An item's language falls back to
+-- its feed's when NULL. The API filters the catalog on
+-- tracked_feeds.language and drops other-language items from the
+-- dashboard (channels/rss/api/language.go).
+ALTER TABLE tracked_feeds ADD COLUMN IF NOT EXISTS language TEXT;
+ALTER TABLE rss_items ADD COLUMN IF NOT EXISTS language TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tracked_feeds_language
+  ON tracked_feeds(language);
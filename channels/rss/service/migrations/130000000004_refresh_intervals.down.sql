@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_rss_refresh_requests_feed_url;
+DROP TABLE IF EXISTS rss_refresh_requests;
+DROP INDEX IF EXISTS idx_tracked_feeds_next_poll_at;
+ALTER TABLE tracked_feeds DROP COLUMN IF EXISTS next_poll_at;
+ALTER TABLE tracked_feeds DROP COLUMN IF EXISTS effective_interval_secs;
+ALTER TABLE tracked_feeds DROP COLUMN IF EXISTS publisher_interval_secs;
+ALTER TABLE tracked_feeds DROP COLUMN IF EXISTS requested_interval_secs;
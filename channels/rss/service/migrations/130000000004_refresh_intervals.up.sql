@@ -0,0 +1,33 @@
+-- Per-feed refresh intervals, introduced 2026-10-16.
+--
+-- Feeds used to refresh on one global 5-minute cadence. Ingestion now
+-- ticks every minute and polls only feeds whose next_poll_at has passed,
+-- rescheduling each by its effective interval (service/src/schedule.rs):
+--
+--   requested_interval_secs  fastest refresh a premium subscriber asked
+--                            for; maintained by the API from
+--                            rss_refresh_requests
+--   publisher_interval_secs  the feed's own ttl / sy:updatePeriod hint,
+--                            recorded on each successful poll
+--   effective_interval_secs  what the scheduler last applied, for the
+--                            feed health views
+ALTER TABLE tracked_feeds ADD COLUMN IF NOT EXISTS requested_interval_secs INT;
+ALTER TABLE tracked_feeds ADD COLUMN IF NOT EXISTS publisher_interval_secs INT;
+ALTER TABLE tracked_feeds ADD COLUMN IF NOT EXISTS effective_interval_secs INT NOT NULL DEFAULT 300;
+ALTER TABLE tracked_feeds ADD COLUMN IF NOT EXISTS next_poll_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_tracked_feeds_next_poll_at
+  ON tracked_feeds(next_poll_at) WHERE is_enabled = TRUE;
+
+-- One row per (user, feed) faster-refresh request. Cascades with the
+-- feed; the API removes a user's rows when the feed leaves their config.
+CREATE TABLE IF NOT EXISTS rss_refresh_requests (
+  logto_sub     TEXT NOT NULL,
+  feed_url      TEXT NOT NULL REFERENCES tracked_feeds(url) ON DELETE CASCADE,
+  interval_secs INT NOT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (logto_sub, feed_url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rss_refresh_requests_feed_url
+  ON rss_refresh_requests(feed_url);
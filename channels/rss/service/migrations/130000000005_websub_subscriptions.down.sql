@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_rss_websub_subscriptions_lease;
+DROP TABLE IF EXISTS rss_websub_subscriptions;
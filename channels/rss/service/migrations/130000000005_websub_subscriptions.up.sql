@@ -0,0 +1,28 @@
+-- WebSub (PubSubHubbub) subscriptions, introduced 2026-10-16.
+--
+-- Feeds that advertise a hub (Atom/RSS <link rel="hub"> or an HTTP Link
+-- header) are subscribed by the API (api/websub.go). A verified push
+-- marks the feed due and wakes ingestion, so new items land in seconds;
+-- the regular poll schedule keeps running as the fallback.
+--
+-- One row per tracked feed once hub discovery has run. hub_url NULL
+-- records "no hub" so discovery isn't repeated every pass. callback_token
+-- identifies the subscription in the callback URL; secret signs pushes.
+CREATE TABLE IF NOT EXISTS rss_websub_subscriptions (
+  feed_url         TEXT PRIMARY KEY REFERENCES tracked_feeds(url) ON DELETE CASCADE,
+  hub_url          TEXT,
+  topic_url        TEXT,
+  callback_token   TEXT UNIQUE,
+  secret           TEXT,
+  state            TEXT NOT NULL DEFAULT 'none'
+                   CHECK (state IN ('none', 'pending', 'verified', 'denied', 'failed')),
+  lease_expires_at TIMESTAMPTZ,
+  last_error       TEXT,
+  last_push_at     TIMESTAMPTZ,
+  discovered_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  requested_at     TIMESTAMPTZ,
+  verified_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_rss_websub_subscriptions_lease
+  ON rss_websub_subscriptions(lease_expires_at) WHERE state = 'verified';
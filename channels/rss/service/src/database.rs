@@ -5,6 +5,7 @@ pub use sqlx::PgPool;
 use sqlx::{FromRow, query, query_as};
 use serde::Deserialize;
 use chrono::{DateTime, Utc};
+use crate::schedule::MIN_FAILURE_RETRY_SECS;
 
 /// Build the sqlx migrator for this service.
 ///
@@ -170,6 +171,8 @@ pub struct TrackedFeed {
     pub is_default: bool,
     pub is_enabled: bool,
     pub consecutive_failures: i32,
+    pub requested_interval_secs: Option<i32>,
+    pub effective_interval_secs: i32,
 }
 
 // ── Parsed article ready for DB insertion ────────────────────────
@@ -182,6 +185,7 @@ pub struct ParsedArticle {
     pub description: String,
     pub source_name: String,
     pub published_at: Option<DateTime<Utc>>,
+    pub language: Option<String>,
 }
 
 // ── Seed default feeds from config file (batched) ───────────────
@@ -231,13 +235,18 @@ pub async fn seed_tracked_feeds(pool: Arc<PgPool>, feeds: Vec<FeedConfig>) -> Re
     Ok(())
 }
 
-// ── Get all enabled, non-quarantined feeds ──────────────────────
+// ── Get enabled, non-quarantined feeds that are due ─────────────
 
+/// Feeds whose `next_poll_at` has passed, most overdue first so the poll
+/// semaphore (which admits in spawn order) serves them first; ties go to
+/// the shorter interval.
 pub async fn get_tracked_feeds(pool: Arc<PgPool>) -> Vec<TrackedFeed> {
     let statement = "
-        SELECT url, name, category, is_default, is_enabled, consecutive_failures
+        SELECT url, name, category, is_default, is_enabled, consecutive_failures,
+               requested_interval_secs, effective_interval_secs
         FROM tracked_feeds
-        WHERE is_enabled = TRUE AND consecutive_failures < 288
+        WHERE is_enabled = TRUE AND consecutive_failures < 288 AND next_poll_at <= NOW()
+        ORDER BY next_poll_at, effective_interval_secs
     ";
     let res: Result<Vec<TrackedFeed>, sqlx::Error> = async {
         let mut connection = pool.acquire().await?;
@@ -258,7 +267,8 @@ pub async fn get_tracked_feeds(pool: Arc<PgPool>) -> Vec<TrackedFeed> {
 
 pub async fn get_quarantined_feeds(pool: Arc<PgPool>) -> Vec<TrackedFeed> {
     let statement = "
-        SELECT url, name, category, is_default, is_enabled, consecutive_failures
+        SELECT url, name, category, is_default, is_enabled, consecutive_failures,
+               requested_interval_secs, effective_interval_secs
         FROM tracked_feeds
         WHERE is_enabled = TRUE AND consecutive_failures >= 288
     ";
@@ -279,18 +289,41 @@ pub async fn get_quarantined_feeds(pool: Arc<PgPool>) -> Vec<TrackedFeed> {
 
 // ── Batch record feed poll successes ─────────────────────────────
 
-pub async fn batch_record_feed_successes(pool: &Arc<PgPool>, feed_urls: &[String]) {
+/// The slices pair with `feed_urls`. A `None` language keeps the stored
+/// language so one poll with no detectable articles doesn't erase it; the
+/// publisher hint is replaced outright since a feed may drop it. Each feed
+/// is rescheduled by its effective interval.
+pub async fn batch_record_feed_successes(
+    pool: &Arc<PgPool>,
+    feed_urls: &[String],
+    languages: &[Option<String>],
+    publisher_intervals: &[Option<i32>],
+    effective_intervals: &[i32],
+) {
     if feed_urls.is_empty() {
         return;
     }
     let statement = "
-        UPDATE tracked_feeds
-        SET consecutive_failures = 0, last_success_at = NOW()
-        WHERE url = ANY($1)
+        UPDATE tracked_feeds AS tf
+        SET consecutive_failures = 0,
+            last_success_at = NOW(),
+            language = COALESCE(u.language, tf.language),
+            publisher_interval_secs = u.publisher_interval,
+            effective_interval_secs = u.effective_interval,
+            next_poll_at = NOW() + make_interval(secs => u.effective_interval)
+        FROM UNNEST($1::text[], $2::text[], $3::int[], $4::int[])
+            AS u(url, language, publisher_interval, effective_interval)
+        WHERE tf.url = u.url
     ";
     let res: Result<(), sqlx::Error> = async {
         let mut connection = pool.acquire().await?;
-        query(statement).bind(feed_urls).execute(&mut *connection).await?;
+        query(statement)
+            .bind(feed_urls)
+            .bind(languages)
+            .bind(publisher_intervals)
+            .bind(effective_intervals)
+            .execute(&mut *connection)
+            .await?;
         Ok(())
     }.await;
 
@@ -305,12 +338,14 @@ pub async fn batch_record_feed_failures(pool: &Arc<PgPool>, feed_urls: &[String]
     if feed_urls.is_empty() {
         return;
     }
-    // Each feed gets its own error message, and we increment consecutive_failures
+    // Each feed gets its own error message, and we increment consecutive_failures.
+    // Retries wait at least MIN_FAILURE_RETRY_SECS regardless of interval.
     let statement = "
         UPDATE tracked_feeds AS tf
         SET consecutive_failures = tf.consecutive_failures + 1,
             last_error = u.error_msg,
-            last_error_at = NOW()
+            last_error_at = NOW(),
+            next_poll_at = NOW() + make_interval(secs => GREATEST(tf.effective_interval_secs, $3))
         FROM UNNEST($1::text[], $2::text[]) AS u(url, error_msg)
         WHERE tf.url = u.url
     ";
@@ -319,6 +354,7 @@ pub async fn batch_record_feed_failures(pool: &Arc<PgPool>, feed_urls: &[String]
         query(statement)
             .bind(feed_urls)
             .bind(errors)
+            .bind(MIN_FAILURE_RETRY_SECS)
             .execute(&mut *connection)
             .await?;
         Ok(())
@@ -343,15 +379,16 @@ pub async fn batch_upsert_rss_items(pool: &Arc<PgPool>, articles: Vec<ParsedArti
     let descriptions: Vec<&str> = articles.iter().map(|a| a.description.as_str()).collect();
     let source_names: Vec<&str> = articles.iter().map(|a| a.source_name.as_str()).collect();
     let published_ats: Vec<Option<DateTime<Utc>>> = articles.iter().map(|a| a.published_at).collect();
+    let languages: Vec<Option<&str>> = articles.iter().map(|a| a.language.as_deref()).collect();
 
     // Only touch the row when content actually changed — unchanged articles
     // are skipped so Sequin CDC won't fire redundant UPDATE events on repoll.
     let statement = "
-        INSERT INTO rss_items (feed_url, guid, title, link, description, source_name, published_at)
+        INSERT INTO rss_items (feed_url, guid, title, link, description, source_name, published_at, language)
         SELECT * FROM UNNEST(
             $1::text[], $2::text[], $3::text[], $4::text[],
-            $5::text[], $6::text[], $7::timestamptz[]
-        ) AS t(feed_url, guid, title, link, description, source_name, published_at)
+            $5::text[], $6::text[], $7::timestamptz[], $8::text[]
+        ) AS t(feed_url, guid, title, link, description, source_name, published_at, language)
         ON CONFLICT (feed_url, guid)
         DO UPDATE SET
             title = EXCLUDED.title,
@@ -359,6 +396,7 @@ pub async fn batch_upsert_rss_items(pool: &Arc<PgPool>, articles: Vec<ParsedArti
             description = EXCLUDED.description,
             source_name = EXCLUDED.source_name,
             published_at = EXCLUDED.published_at,
+            language = EXCLUDED.language,
             updated_at = CURRENT_TIMESTAMP
         WHERE
             rss_items.title        IS DISTINCT FROM EXCLUDED.title
@@ -366,6 +404,7 @@ pub async fn batch_upsert_rss_items(pool: &Arc<PgPool>, articles: Vec<ParsedArti
             OR rss_items.description  IS DISTINCT FROM EXCLUDED.description
             OR rss_items.source_name  IS DISTINCT FROM EXCLUDED.source_name
             OR rss_items.published_at IS DISTINCT FROM EXCLUDED.published_at
+            OR rss_items.language     IS DISTINCT FROM EXCLUDED.language
     ";
     let mut connection = pool.acquire().await?;
     query(statement)
@@ -376,6 +415,7 @@ pub async fn batch_upsert_rss_items(pool: &Arc<PgPool>, articles: Vec<ParsedArti
         .bind(&descriptions)
         .bind(&source_names)
         .bind(&published_ats)
+        .bind(&languages)
         .execute(&mut *connection)
         .await
         .context("Failed to batch upsert RSS items")?;
@@ -0,0 +1,169 @@
+//! Content language for feeds and items.
+//!
+//! Languages are stored as lowercase ISO 639-1 primary subtags ("en",
+//! "pt"). A declared language (RSS `<language>`, Atom `xml:lang`) is
+//! trusted when present; otherwise the title and summary are scored
+//! against short stop-word lists, with script detection for languages
+//! that don't share the Latin alphabet. Anything inconclusive is `None`
+//! — the API shows unknown-language items to everyone rather than
+//! hiding them on a guess.
+
+use std::collections::HashMap;
+
+/// Minimum stop-word hits before a Latin-script guess is trusted.
+const MIN_STOPWORD_HITS: usize = 2;
+
+/// Stop words per language. Kept to words that are frequent in headlines
+/// and rare in the other listed languages.
+const STOPWORDS: &[(&str, &[&str])] = &[
+    ("en", &["the", "and", "of", "to", "in", "is", "for", "with", "on", "that", "from", "after", "how", "what", "says"]),
+    ("es", &["el", "los", "las", "del", "y", "que", "por", "con", "para", "una", "según", "tras", "sobre", "más"]),
+    ("fr", &["le", "les", "des", "et", "est", "pour", "dans", "sur", "une", "du", "aux", "avec", "qui", "après"]),
+    ("de", &["der", "die", "das", "und", "ist", "mit", "für", "auf", "den", "dem", "ein", "eine", "nicht", "nach"]),
+    ("it", &["il", "gli", "della", "di", "che", "per", "con", "una", "sono", "nel", "alla", "dopo", "anche"]),
+    ("pt", &["o", "os", "as", "do", "da", "dos", "das", "que", "com", "para", "uma", "não", "após", "em"]),
+    ("nl", &["het", "een", "van", "en", "is", "op", "voor", "niet", "met", "zijn", "bij", "naar", "ook"]),
+];
+
+/// Normalizes a declared language tag ("en-US", "EN_gb", "pt-BR") to its
+/// primary subtag. Returns `None` for empty or malformed tags.
+pub fn normalize_language_tag(tag: &str) -> Option<String> {
+    let primary = tag.trim().split(['-', '_']).next()?.to_ascii_lowercase();
+    if (2..=3).contains(&primary.len()) && primary.chars().all(|c| c.is_ascii_alphabetic()) {
+        Some(primary)
+    } else {
+        None
+    }
+}
+
+/// Guesses the language of `text`, or `None` when it can't tell.
+pub fn detect_language(text: &str) -> Option<String> {
+    if let Some(lang) = detect_script(text) {
+        return Some(lang.to_string());
+    }
+
+    let mut scores: HashMap<&str, usize> = HashMap::new();
+    for word in text
+        .split(|c: char| !c.is_alphabetic())
+        .filter(|w| !w.is_empty())
+        .map(|w| w.to_lowercase())
+    {
+        for &(lang, words) in STOPWORDS {
+            if words.contains(&word.as_str()) {
+                *scores.entry(lang).or_default() += 1;
+            }
+        }
+    }
+
+    let mut ranked: Vec<(&str, usize)> = scores.into_iter().collect();
+    ranked.sort_by(|a, b| b.1.cmp(&a.1).then(a.0.cmp(b.0)));
+    let (best, best_hits) = *ranked.first()?;
+    let runner_up = ranked.get(1).map_or(0, |r| r.1);
+    // A clear winner only: half again as many hits as the runner-up.
+    if best_hits >= MIN_STOPWORD_HITS && best_hits * 2 >= runner_up * 3 {
+        Some(best.to_string())
+    } else {
+        None
+    }
+}
+
+/// Detects languages identifiable by script alone. Kana wins over Han so
+/// Japanese isn't read as Chinese.
+fn detect_script(text: &str) -> Option<&'static str> {
+    let (mut letters, mut kana, mut hangul, mut han, mut cyrillic, mut arabic, mut greek, mut hebrew) =
+        (0usize, 0, 0, 0, 0, 0, 0, 0);
+    for c in text.chars().filter(|c| c.is_alphabetic()) {
+        letters += 1;
+        match c as u32 {
+            0x3040..=0x30FF => kana += 1,
+            0xAC00..=0xD7AF | 0x1100..=0x11FF => hangul += 1,
+            0x4E00..=0x9FFF => han += 1,
+            0x0400..=0x04FF => cyrillic += 1,
+            0x0600..=0x06FF => arabic += 1,
+            0x0370..=0x03FF => greek += 1,
+            0x0590..=0x05FF => hebrew += 1,
+            _ => {}
+        }
+    }
+    if letters == 0 {
+        return None;
+    }
+    // The script must carry at least a third of the letters; brand names
+    // in Latin script are common in otherwise non-Latin headlines.
+    let dominant = |n: usize| n * 3 >= letters;
+    if kana > 0 && dominant(kana + han) {
+        Some("ja")
+    } else if dominant(hangul) {
+        Some("ko")
+    } else if dominant(han) {
+        Some("zh")
+    } else if dominant(cyrillic) {
+        Some("ru")
+    } else if dominant(arabic) {
+        Some("ar")
+    } else if dominant(greek) {
+        Some("el")
+    } else if dominant(hebrew) {
+        Some("he")
+    } else {
+        None
+    }
+}
+
+/// The most common language among `items`, ignoring unknowns. Used as the
+/// feed's language when the feed doesn't declare one.
+pub fn majority_language<'a>(items: impl IntoIterator<Item = &'a Option<String>>) -> Option<String> {
+    let mut counts: HashMap<&str, usize> = HashMap::new();
+    for lang in items.into_iter().flatten() {
+        *counts.entry(lang.as_str()).or_default() += 1;
+    }
+    counts
+        .into_iter()
+        .max_by(|a, b| a.1.cmp(&b.1).then(b.0.cmp(a.0)))
+        .map(|(lang, _)| lang.to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_normalize_language_tag() {
+        assert_eq!(normalize_language_tag("en-US"), Some("en".into()));
+        assert_eq!(normalize_language_tag(" PT_br "), Some("pt".into()));
+        assert_eq!(normalize_language_tag("fil"), Some("fil".into()));
+        assert_eq!(normalize_language_tag(""), None);
+        assert_eq!(normalize_language_tag("english"), None);
+        assert_eq!(normalize_language_tag("e1"), None);
+    }
+
+    #[test]
+    fn test_detect_language_latin() {
+        assert_eq!(detect_language("The Fed holds rates steady as inflation cools in the US"), Some("en".into()));
+        assert_eq!(detect_language("El gobierno anuncia nuevas medidas para los autónomos tras la crisis"), Some("es".into()));
+        assert_eq!(detect_language("Die Regierung plant eine Reform der Rente für das nächste Jahr"), Some("de".into()));
+        assert_eq!(detect_language("Le président annonce des mesures pour les agriculteurs"), Some("fr".into()));
+    }
+
+    #[test]
+    fn test_detect_language_scripts() {
+        assert_eq!(detect_language("東京で新しい駅が開業しました"), Some("ja".into()));
+        assert_eq!(detect_language("서울 아파트 가격 상승"), Some("ko".into()));
+        assert_eq!(detect_language("中国经济增长放缓"), Some("zh".into()));
+        assert_eq!(detect_language("Правительство одобрило бюджет"), Some("ru".into()));
+    }
+
+    #[test]
+    fn test_detect_language_inconclusive() {
+        assert_eq!(detect_language(""), None);
+        assert_eq!(detect_language("iPhone 17 Pro"), None);
+        assert_eq!(detect_language("12:30 — 4-2"), None);
+    }
+
+    #[test]
+    fn test_majority_language() {
+        let items = [Some("en".to_string()), None, Some("es".to_string()), Some("en".to_string())];
+        assert_eq!(majority_language(&items), Some("en".into()));
+        assert_eq!(majority_language(&[None, None]), None);
+    }
+}
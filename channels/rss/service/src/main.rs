@@ -1,10 +1,10 @@
 use anyhow::{Context, Result};
-use axum::{extract::State, http::StatusCode, routing::get, Json, Router};
+use axum::{extract::State, http::StatusCode, routing::{get, post}, Json, Router};
 use dotenvy::dotenv;
 use reqwest::header::{HeaderMap, HeaderValue, ACCEPT, USER_AGENT};
 use serde::Serialize;
 use std::{sync::Arc, time::Duration};
-use tokio::sync::Mutex;
+use tokio::sync::{Mutex, Notify};
 use tokio_util::sync::CancellationToken;
 use rss_service::{
     database::initialize_pool,
@@ -13,9 +13,11 @@ use rss_service::{
     start_rss_service, RssHealth,
 };
 
-/// RSS polls on a 5-minute loop. Staleness = 2x that, giving one full cycle
-/// of runway before the pod drops out of the ready pool.
-const INGEST_INTERVAL: Duration = Duration::from_secs(300);
+/// The scheduler ticks every minute and polls the feeds that are due (see
+/// schedule.rs); idle ticks still count as progress. Staleness = 2x the
+/// default 5-minute feed interval, giving one full default cycle of runway
+/// before the pod drops out of the ready pool.
+const INGEST_INTERVAL: Duration = Duration::from_secs(60);
 const MAX_POLL_STALENESS: Duration = Duration::from_secs(300 * 2);
 
 /// How often the bridge loop checks `RssHealth.last_poll` for progress.
@@ -25,6 +27,9 @@ const READINESS_BRIDGE_INTERVAL: Duration = Duration::from_secs(10);
 struct AppState {
     health: Arc<Mutex<RssHealth>>,
     readiness: Arc<ReadinessGate>,
+    /// Cuts the ingest loop's sleep short. The API pokes it when a WebSub
+    /// hub pushes an update and it has marked the feed due.
+    wake: Arc<Notify>,
 }
 
 #[derive(Serialize)]
@@ -123,6 +128,7 @@ async fn run_service() -> Result<()> {
 
     let health = Arc::new(Mutex::new(RssHealth::new()));
     let readiness = Arc::new(ReadinessGate::new(Some(MAX_POLL_STALENESS)));
+    let wake = Arc::new(Notify::new());
 
     // Cancellation token for coordinated shutdown
     let cancel = CancellationToken::new();
@@ -132,11 +138,13 @@ async fn run_service() -> Result<()> {
     let state = AppState {
         health: health.clone(),
         readiness: readiness.clone(),
+        wake: wake.clone(),
     };
     let app = Router::new()
         .route("/health", get(health_ready_handler))
         .route("/health/live", get(health_live_handler))
         .route("/health/ready", get(health_ready_handler))
+        .route("/ingest/wake", post(ingest_wake_handler))
         .with_state(state);
 
     let port = std::env::var("PORT").unwrap_or_else(|_| "3004".to_string());
@@ -151,6 +159,7 @@ async fn run_service() -> Result<()> {
     let health_bg = health.clone();
     let readiness_bg = readiness.clone();
     let cancel_bg = cancel.clone();
+    let wake_bg = wake.clone();
     spawn_supervised("rss-init", async move {
         const RETRIES: u32 = 5;
         let mut remaining = RETRIES;
@@ -239,7 +248,7 @@ async fn run_service() -> Result<()> {
         });
 
         // Periodic ingest loop.
-        println!("Starting periodic RSS ingest loop (5 minute interval)...");
+        println!("Starting periodic RSS ingest loop (1 minute scheduler tick)...");
         let mut cycle: u64 = 0;
         loop {
             tokio::select! {
@@ -250,7 +259,12 @@ async fn run_service() -> Result<()> {
                 _ = async {
                     start_rss_service(pool.clone(), health_bg.clone(), &http_client, cycle).await;
                     cycle += 1;
-                    tokio::time::sleep(INGEST_INTERVAL).await;
+                    // A wake-up during the cycle is stored as a permit, so
+                    // a push that lands mid-cycle still gets its own tick.
+                    tokio::select! {
+                        _ = tokio::time::sleep(INGEST_INTERVAL) => {}
+                        _ = wake_bg.notified() => {}
+                    }
                 } => {}
             }
         }
@@ -295,6 +309,13 @@ async fn health_live_handler() -> (StatusCode, Json<serde_json::Value>) {
     (StatusCode::OK, Json(serde_json::json!({"status": "alive"})))
 }
 
+/// Runs the next scheduler tick now. Called by the API after a WebSub push
+/// marks a feed due; only due feeds are polled, so spurious calls are cheap.
+async fn ingest_wake_handler(State(state): State<AppState>) -> StatusCode {
+    state.wake.notify_one();
+    StatusCode::ACCEPTED
+}
+
 /// Readiness probe: 200 only when init succeeded AND the first poll cycle
 /// completed within `MAX_POLL_STALENESS`.
 async fn health_ready_handler(
@@ -0,0 +1,375 @@
+//! Feed parsing and normalization.
+//!
+//! feed-rs does the format-specific work for RSS 0.9x/2.0, RSS 1.0 (RDF),
+//! Atom 1.0 and JSON Feed 1.0/1.1; this module turns its model into
+//! `rss_items` rows the same way for all of them:
+//!
+//! - the entry link is the alternate (or unlabelled) link, not whichever
+//!   came first — Atom entries often lead with `self`, `replies` or an
+//!   enclosure
+//! - entries without a title (JSON Feed microblog posts, some Atom) are
+//!   titled from the start of their text instead of being dropped
+//! - descriptions fall back from summary to content and are stripped of
+//!   markup and truncated
+//!
+//! `detect_format` sniffs the body first, so a URL serving an HTML page
+//! fails with a message that says so rather than a parser error.
+
+use chrono::{DateTime, Utc};
+use feed_rs::model::{Entry, Link};
+
+use crate::database::ParsedArticle;
+use crate::language::{detect_language, majority_language, normalize_language_tag};
+use crate::schedule::publisher_interval;
+
+/// Descriptions are cut to this many characters before storage.
+const MAX_DESCRIPTION_CHARS: usize = 500;
+
+/// Titles synthesized from an untitled entry's text are cut to this many
+/// characters.
+const MAX_FALLBACK_TITLE_CHARS: usize = 100;
+
+/// Articles older than this are skipped so we never re-insert rows that
+/// cleanup already deleted — avoids a CDC INSERT→DELETE storm every poll.
+const MAX_ARTICLE_AGE_DAYS: i64 = 7;
+
+/// Body format, sniffed from the first meaningful bytes.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum FeedFormat {
+    Rss,
+    Rdf,
+    Atom,
+    JsonFeed,
+    Html,
+    Unknown,
+}
+
+/// A parsed feed, normalized for storage.
+pub struct ParsedFeed {
+    pub format: FeedFormat,
+    pub articles: Vec<ParsedArticle>,
+    /// Declared language, else the most common among its articles.
+    pub language: Option<String>,
+    /// Publisher refresh hint in seconds (`<ttl>` / `sy:updatePeriod`).
+    pub publisher_interval: Option<i32>,
+}
+
+/// Guesses the format from the root element (or `{` for JSON). Skips a
+/// BOM, whitespace, the XML prolog, comments, doctypes and processing
+/// instructions.
+pub fn detect_format(body: &[u8]) -> FeedFormat {
+    let head = &body[..body.len().min(4096)];
+    let text = String::from_utf8_lossy(head);
+    let mut rest = text.trim_start_matches('\u{feff}').trim_start();
+
+    if rest.starts_with('{') {
+        return FeedFormat::JsonFeed;
+    }
+    loop {
+        if let Some(after) = rest.strip_prefix("<?") {
+            match after.find("?>") {
+                Some(end) => rest = after[end + 2..].trim_start(),
+                None => return FeedFormat::Unknown,
+            }
+        } else if let Some(after) = rest.strip_prefix("<!--") {
+            match after.find("-->") {
+                Some(end) => rest = after[end + 3..].trim_start(),
+                None => return FeedFormat::Unknown,
+            }
+        } else if let Some(after) = rest.get(..9).filter(|p| p.eq_ignore_ascii_case("<!doctype")).map(|_| &rest[9..]) {
+            if after.trim_start().to_ascii_lowercase().starts_with("html") {
+                return FeedFormat::Html;
+            }
+            match rest.find('>') {
+                Some(end) => rest = rest[end + 1..].trim_start(),
+                None => return FeedFormat::Unknown,
+            }
+        } else {
+            break;
+        }
+    }
+
+    let Some(tag) = rest.strip_prefix('<') else {
+        return FeedFormat::Unknown;
+    };
+    let name: String = tag
+        .chars()
+        .take_while(|c| !c.is_whitespace() && *c != '>' && *c != '/')
+        .collect();
+    let local = name.rsplit(':').next().unwrap_or("").to_ascii_lowercase();
+    match local.as_str() {
+        "rss" => FeedFormat::Rss,
+        "rdf" => FeedFormat::Rdf,
+        "feed" => FeedFormat::Atom,
+        "html" => FeedFormat::Html,
+        _ => FeedFormat::Unknown,
+    }
+}
+
+/// Parses a feed body into articles for `feed_url`. `fallback_name` is
+/// the source name used when the feed has no title.
+pub fn parse_feed(body: &[u8], feed_url: &str, fallback_name: &str) -> anyhow::Result<ParsedFeed> {
+    let format = detect_format(body);
+    if format == FeedFormat::Html {
+        anyhow::bail!("response is an HTML page, not an RSS, Atom or JSON feed");
+    }
+
+    let parsed = feed_rs::parser::parse(body)?;
+
+    let source_name = parsed.title
+        .as_ref()
+        .map(|t| collapse_whitespace(&t.content))
+        .filter(|t| !t.is_empty())
+        .unwrap_or_else(|| fallback_name.to_string());
+
+    let declared_language = parsed.language.as_deref().and_then(normalize_language_tag);
+    let publisher_interval = publisher_interval(parsed.ttl, body);
+
+    let cutoff = Utc::now() - chrono::Duration::days(MAX_ARTICLE_AGE_DAYS);
+    let articles: Vec<ParsedArticle> = parsed.entries
+        .into_iter()
+        .filter_map(|entry| normalize_entry(entry, feed_url, &source_name, declared_language.as_deref(), cutoff))
+        .collect();
+
+    let language = declared_language
+        .or_else(|| majority_language(articles.iter().map(|a| &a.language)));
+
+    Ok(ParsedFeed { format, articles, language, publisher_interval })
+}
+
+/// Turns one entry into an article, or `None` when it has no id, no
+/// usable title or text, or is older than `cutoff`.
+fn normalize_entry(
+    entry: Entry,
+    feed_url: &str,
+    source_name: &str,
+    declared_language: Option<&str>,
+    cutoff: DateTime<Utc>,
+) -> Option<ParsedArticle> {
+    let guid = entry.id.trim().to_string();
+    if guid.is_empty() {
+        return None;
+    }
+
+    let published_at = entry.published.or(entry.updated);
+    if let Some(pub_date) = &published_at
+        && *pub_date < cutoff
+    {
+        return None;
+    }
+
+    let link = pick_link(&entry.links).unwrap_or_default();
+
+    let raw_description = entry.summary
+        .map(|s| s.content)
+        .filter(|s| !s.trim().is_empty())
+        .or_else(|| entry.content.and_then(|c| c.body))
+        .unwrap_or_default();
+    // Strip before truncating so markup doesn't eat the character budget.
+    let description = truncate_chars(&strip_html_tags(&raw_description), MAX_DESCRIPTION_CHARS, "...");
+
+    // Atom titles may be type="html"; plain-text titles keep any literal
+    // angle brackets ("5 < 6").
+    let title = entry.title
+        .map(|t| if t.content_type.essence_str() == "text/html" {
+            strip_html_tags(&t.content)
+        } else {
+            collapse_whitespace(&t.content)
+        })
+        .filter(|t| !t.is_empty())
+        .or_else(|| fallback_title(&description))?;
+
+    // Per-item language: the entry's own xml:lang, then detection,
+    // then the feed's declaration (often a CMS default, so it's last).
+    let language = entry.language
+        .as_deref()
+        .and_then(normalize_language_tag)
+        .or_else(|| detect_language(&format!("{} {}", title, description)))
+        .or_else(|| declared_language.map(str::to_string));
+
+    Some(ParsedArticle {
+        feed_url: feed_url.to_string(),
+        guid,
+        title,
+        link,
+        description,
+        source_name: source_name.to_string(),
+        published_at,
+        language,
+    })
+}
+
+/// The entry's page link: the first `alternate` or unlabelled link, else
+/// the first that isn't `self`, else nothing.
+fn pick_link(links: &[Link]) -> Option<String> {
+    let usable = |l: &&Link| !l.href.trim().is_empty();
+    links.iter()
+        .filter(usable)
+        .find(|l| matches!(l.rel.as_deref(), None | Some("alternate")))
+        .or_else(|| links.iter().filter(usable).find(|l| l.rel.as_deref() != Some("self")))
+        .map(|l| l.href.trim().to_string())
+}
+
+/// A title for an untitled entry: the start of its text, cut at a word
+/// boundary. `None` when there's no text either.
+pub fn fallback_title(text: &str) -> Option<String> {
+    let text = text.trim().trim_end_matches("...");
+    if text.is_empty() {
+        return None;
+    }
+    if text.chars().count() <= MAX_FALLBACK_TITLE_CHARS {
+        return Some(text.to_string());
+    }
+    let cut: String = text.chars().take(MAX_FALLBACK_TITLE_CHARS).collect();
+    let cut = match cut.rfind(' ') {
+        Some(i) if i > MAX_FALLBACK_TITLE_CHARS / 2 => &cut[..i],
+        _ => cut.as_str(),
+    };
+    Some(format!("{}…", cut.trim_end()))
+}
+
+/// Truncates to `max` characters (char-based to avoid panicking on
+/// multi-byte UTF-8 like smart quotes), appending `suffix` when cut.
+fn truncate_chars(s: &str, max: usize, suffix: &str) -> String {
+    if s.chars().count() <= max {
+        return s.to_string();
+    }
+    let mut truncated: String = s.chars().take(max).collect();
+    truncated.push_str(suffix);
+    truncated
+}
+
+/// Basic HTML tag stripper — removes angle-bracketed tags.
+pub fn strip_html_tags(input: &str) -> String {
+    let mut result = String::with_capacity(input.len());
+    let mut in_tag = false;
+
+    for ch in input.chars() {
+        match ch {
+            '<' => in_tag = true,
+            '>' => in_tag = false,
+            _ if !in_tag => result.push(ch),
+            _ => {}
+        }
+    }
+
+    collapse_whitespace(&result)
+}
+
+/// Collapses runs of whitespace to single spaces and trims.
+fn collapse_whitespace(input: &str) -> String {
+    input.split_whitespace().collect::<Vec<&str>>().join(" ")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_detect_format() {
+        assert_eq!(detect_format(b"<?xml version=\"1.0\"?>\n<rss version=\"2.0\">"), FeedFormat::Rss);
+        assert_eq!(detect_format(b"\xef\xbb\xbf  <rss>"), FeedFormat::Rss);
+        assert_eq!(detect_format(b"<?xml version=\"1.0\"?><?xml-stylesheet href=\"x.xsl\"?><!-- hi --><feed xmlns=\"http://www.w3.org/2005/Atom\">"), FeedFormat::Atom);
+        assert_eq!(detect_format(b"<rdf:RDF xmlns:rdf=\"x\">"), FeedFormat::Rdf);
+        assert_eq!(detect_format(b"  {\"version\": \"https://jsonfeed.org/version/1.1\"}"), FeedFormat::JsonFeed);
+        assert_eq!(detect_format(b"<!DOCTYPE html><html><body>"), FeedFormat::Html);
+        assert_eq!(detect_format(b"<html lang=\"en\">"), FeedFormat::Html);
+        assert_eq!(detect_format(b"<!DOCTYPE rss PUBLIC \"-//Netscape\" \"x\"><rss>"), FeedFormat::Rss);
+        assert_eq!(detect_format(b"not a feed"), FeedFormat::Unknown);
+        assert_eq!(detect_format(b""), FeedFormat::Unknown);
+    }
+
+    #[test]
+    fn test_fallback_title() {
+        assert_eq!(fallback_title("Short note"), Some("Short note".to_string()));
+        assert_eq!(fallback_title("   "), None);
+        let long = "word ".repeat(40);
+        let title = fallback_title(&long).unwrap();
+        assert!(title.ends_with('…'));
+        assert!(title.chars().count() <= MAX_FALLBACK_TITLE_CHARS + 1);
+        assert!(!title.contains("wor…"));
+    }
+
+    #[test]
+    fn test_truncate_chars() {
+        assert_eq!(truncate_chars("abc", 5, "..."), "abc");
+        assert_eq!(truncate_chars("“quoted”", 3, "..."), "“qu...");
+    }
+
+    #[test]
+    fn test_strip_html_tags_simple() {
+        assert_eq!(strip_html_tags("<p>Hello World</p>"), "Hello World");
+        assert_eq!(strip_html_tags("<b>Bold</b> and <i>italic</i>"), "Bold and italic");
+    }
+
+    #[test]
+    fn test_strip_html_tags_nested() {
+        let input = "<div><p><strong>Nested</strong> text</p></div>";
+        assert_eq!(strip_html_tags(input), "Nested text");
+    }
+
+    #[test]
+    fn test_strip_html_tags_whitespace_collapsed() {
+        let input = "Hello    World\n\nMore   Text";
+        assert_eq!(strip_html_tags(input), "Hello World More Text");
+    }
+
+    #[test]
+    fn test_strip_html_tags_leading_trailing_whitespace() {
+        let input = "   <p>  Text  </p>   ";
+        assert_eq!(strip_html_tags(input), "Text");
+    }
+
+    #[test]
+    fn test_strip_html_tags_no_tags() {
+        assert_eq!(strip_html_tags("Plain text"), "Plain text");
+    }
+
+    #[test]
+    fn test_strip_html_tags_empty() {
+        assert_eq!(strip_html_tags(""), "");
+        assert_eq!(strip_html_tags("<><><>"), "");
+    }
+
+    #[test]
+    fn test_strip_html_tags_unclosed_tag() {
+        // Unclosed tag at end: "<p>Hello" → "Hello"
+        assert_eq!(strip_html_tags("<p>Hello"), "Hello");
+        // Unclosed tag at start: "Hello</p>" → "Hello"
+        assert_eq!(strip_html_tags("Hello</p>"), "Hello");
+    }
+
+    #[test]
+    fn test_strip_html_tags_entity_like() {
+        // &lt; and &gt; are NOT HTML entities here — they remain as chars
+        assert_eq!(strip_html_tags("a &lt; b"), "a &lt; b");
+        assert_eq!(strip_html_tags("a &gt; b"), "a &gt; b");
+        assert_eq!(strip_html_tags("a &amp; b"), "a &amp; b");
+    }
+
+    #[test]
+    fn test_strip_html_tags_multiline() {
+        let input = "<html>\n<body>\n<p>Line1\nLine2</p>\n</body>\n</html>";
+        assert_eq!(strip_html_tags(input), "Line1 Line2");
+    }
+
+    #[test]
+    fn test_strip_html_tags_with_attributes() {
+        let input = r#"<a href="https://example.com" title="Link">Click</a>"#;
+        assert_eq!(strip_html_tags(input), "Click");
+    }
+
+    #[test]
+    fn test_strip_html_tags_script_style() {
+        let input = "<script>alert('xss')</script>Safe text";
+        assert_eq!(strip_html_tags(input), "alert('xss')Safe text");
+        let input2 = "<style>body{color:red}</style>Visible";
+        assert_eq!(strip_html_tags(input2), "body{color:red}Visible");
+    }
+
+    #[test]
+    fn test_strip_html_tags_unicode() {
+        assert_eq!(strip_html_tags("<p>こんにちは</p>"), "こんにちは");
+        assert_eq!(strip_html_tags("<span>日本語</span>"), "日本語");
+    }
+}
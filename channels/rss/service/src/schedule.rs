@@ -0,0 +1,118 @@
+//! Per-feed refresh intervals.
+//!
+//! Every feed is rescheduled after each poll by its effective interval:
+//!
+//! 1. A premium subscriber's requested interval, when one exists. The API
+//!    enforces per-tier minimums; `MIN_REQUESTED_INTERVAL_SECS` is the
+//!    last line of defence if a bad value reaches the table anyway.
+//! 2. Otherwise the publisher's hint — RSS `<ttl>` (minutes) or the
+//!    syndication module's `sy:updatePeriod` / `sy:updateFrequency` —
+//!    clamped so a tiny hint can't make us poll faster than the default
+//!    and a huge one can't leave a feed stale for days.
+//! 3. Otherwise `DEFAULT_POLL_INTERVAL_SECS`.
+
+/// Cadence for feeds with no request and no publisher hint. Matches the
+/// old global loop interval.
+pub const DEFAULT_POLL_INTERVAL_SECS: i32 = 300;
+
+/// Floor for subscriber-requested intervals.
+pub const MIN_REQUESTED_INTERVAL_SECS: i32 = 60;
+
+/// Ceiling for publisher hints: a feed declaring `<ttl>10080</ttl>` is
+/// still checked a few times a day.
+pub const MAX_PUBLISHER_INTERVAL_SECS: i32 = 6 * 60 * 60;
+
+/// Failing feeds are retried no faster than this, whatever their interval,
+/// so the failure thresholds keep their time meaning (3 ≈ 15 minutes,
+/// 288 ≈ 24 hours) and a broken host isn't hammered every minute.
+pub const MIN_FAILURE_RETRY_SECS: i32 = DEFAULT_POLL_INTERVAL_SECS;
+
+/// The interval a feed should be rescheduled by.
+pub fn effective_interval(requested: Option<i32>, publisher: Option<i32>) -> i32 {
+    if let Some(secs) = requested {
+        return secs.max(MIN_REQUESTED_INTERVAL_SECS);
+    }
+    match publisher {
+        Some(secs) => secs.clamp(DEFAULT_POLL_INTERVAL_SECS, MAX_PUBLISHER_INTERVAL_SECS),
+        None => DEFAULT_POLL_INTERVAL_SECS,
+    }
+}
+
+/// The publisher's refresh hint in seconds. `ttl_minutes` is feed-rs's
+/// parsed `<ttl>`; the syndication module isn't parsed by feed-rs, so it
+/// is read from the raw body. `<ttl>` wins when both are present.
+pub fn publisher_interval(ttl_minutes: Option<u32>, body: &[u8]) -> Option<i32> {
+    if let Some(minutes) = ttl_minutes.filter(|m| *m > 0) {
+        return Some(minutes.saturating_mul(60).min(i32::MAX as u32) as i32);
+    }
+    syndication_interval(body)
+}
+
+/// Reads `sy:updatePeriod` and `sy:updateFrequency` from a feed body. The
+/// period defaults to daily and the frequency to 1, per the module spec,
+/// but at least one of the two must be present.
+fn syndication_interval(body: &[u8]) -> Option<i32> {
+    let text = std::str::from_utf8(body).ok()?;
+    let period = tag_text(text, "sy:updatePeriod");
+    let frequency = tag_text(text, "sy:updateFrequency");
+    if period.is_none() && frequency.is_none() {
+        return None;
+    }
+
+    let period_secs: i64 = match period.map(|p| p.to_ascii_lowercase()).as_deref() {
+        Some("hourly") => 3600,
+        Some("daily") | None => 86_400,
+        Some("weekly") => 7 * 86_400,
+        Some("monthly") => 30 * 86_400,
+        Some("yearly") => 365 * 86_400,
+        Some(_) => return None,
+    };
+    let frequency: i64 = match frequency {
+        Some(f) => f.parse().ok().filter(|n| *n > 0)?,
+        None => 1,
+    };
+    Some((period_secs / frequency).clamp(1, i32::MAX as i64) as i32)
+}
+
+/// The trimmed text of the first `<name>…</name>` element, if any.
+fn tag_text<'a>(text: &'a str, name: &str) -> Option<&'a str> {
+    let open = format!("<{}>", name);
+    let close = format!("</{}>", name);
+    let start = text.find(&open)? + open.len();
+    let end = start + text[start..].find(&close)?;
+    Some(text[start..end].trim()).filter(|s| !s.is_empty())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_effective_interval() {
+        assert_eq!(effective_interval(None, None), DEFAULT_POLL_INTERVAL_SECS);
+        assert_eq!(effective_interval(Some(120), Some(3600)), 120);
+        assert_eq!(effective_interval(Some(5), None), MIN_REQUESTED_INTERVAL_SECS);
+        assert_eq!(effective_interval(None, Some(60)), DEFAULT_POLL_INTERVAL_SECS);
+        assert_eq!(effective_interval(None, Some(1800)), 1800);
+        assert_eq!(effective_interval(None, Some(7 * 86_400)), MAX_PUBLISHER_INTERVAL_SECS);
+    }
+
+    #[test]
+    fn test_publisher_interval_ttl() {
+        assert_eq!(publisher_interval(Some(30), b""), Some(1800));
+        assert_eq!(publisher_interval(Some(0), b""), None);
+        let body = b"<sy:updatePeriod>daily</sy:updatePeriod>";
+        assert_eq!(publisher_interval(Some(15), body), Some(900));
+    }
+
+    #[test]
+    fn test_publisher_interval_syndication() {
+        let body = b"<channel><sy:updatePeriod> hourly </sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency></channel>";
+        assert_eq!(publisher_interval(None, body), Some(1800));
+        assert_eq!(publisher_interval(None, b"<sy:updateFrequency>4</sy:updateFrequency>"), Some(21_600));
+        assert_eq!(publisher_interval(None, b"<sy:updatePeriod>weekly</sy:updatePeriod>"), Some(604_800));
+        assert_eq!(publisher_interval(None, b"<sy:updatePeriod>sometimes</sy:updatePeriod>"), None);
+        assert_eq!(publisher_interval(None, b"<sy:updateFrequency>0</sy:updateFrequency>"), None);
+        assert_eq!(publisher_interval(None, b"<rss><channel></channel></rss>"), None);
+    }
+}
@@ -40,6 +40,12 @@ impl RssHealth {
         self.status = String::from("healthy");
     }
 
+    /// A cycle with no feeds due: nothing was polled, but ingestion is
+    /// caught up, so readiness should see it as progress.
+    pub fn record_idle_cycle(&mut self) {
+        self.last_poll = Some(Utc::now());
+    }
+
     pub fn record_error(&mut self, error: String) {
         self.error_count += 1;
         self.last_error = Some(error);
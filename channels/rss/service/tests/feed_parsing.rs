@@ -0,0 +1,95 @@
+//! Fixture-based parsing tests for the formats users have reported
+//! failing to import: Atom with `self` links listed first and HTML
+//! titles, JSON Feed 1.0 and 1.1 (including untitled microblog posts),
+//! and RSS 2.0 with CDATA and no guids.
+//!
+//! Fixtures use `{{NOW_RFC3339}}` / `{{NOW_RFC2822}}` placeholders for
+//! dates so their items stay inside the 7-day ingestion window.
+
+use rss_service::parse::{parse_feed, FeedFormat, ParsedFeed};
+
+fn fixture(body: &str) -> Vec<u8> {
+    let now = chrono::Utc::now();
+    body.replace("{{NOW_RFC3339}}", &now.to_rfc3339())
+        .replace("{{NOW_RFC2822}}", &now.to_rfc2822())
+        .into_bytes()
+}
+
+fn parse(body: &str) -> ParsedFeed {
+    parse_feed(&fixture(body), "https://feed.example/", "Fallback Name").expect("fixture should parse")
+}
+
+#[test]
+fn atom_prefers_alternate_link_and_strips_html_title() {
+    let feed = parse(include_str!("fixtures/atom_self_link_first.xml"));
+    assert_eq!(feed.format, FeedFormat::Atom);
+    assert_eq!(feed.articles.len(), 2, "the 2001 entry is outside the ingestion window");
+
+    let first = &feed.articles[0];
+    assert_eq!(first.title, "Shipping faster builds");
+    assert_eq!(first.link, "https://static.example/posts/faster-builds");
+    assert_eq!(first.description, "We cut build times in half by caching the toolchain.");
+    assert_eq!(first.source_name, "Static Blog");
+    assert_eq!(first.feed_url, "https://feed.example/");
+
+    // No alternate link: anything beats the self link.
+    let second = &feed.articles[1];
+    assert_eq!(second.link, "https://static.example/shows/podcast-1");
+    assert_eq!(second.description, "Episode one.");
+}
+
+#[test]
+fn json_feed_1_1_titles_untitled_posts() {
+    let feed = parse(include_str!("fixtures/jsonfeed_1_1_microblog.json"));
+    assert_eq!(feed.format, FeedFormat::JsonFeed);
+    assert_eq!(feed.language.as_deref(), Some("en"));
+    assert_eq!(feed.articles.len(), 3);
+
+    let untitled = feed.articles.iter().find(|a| a.link.ends_with("/untitled")).unwrap();
+    assert_eq!(untitled.title, "Trying out the new tool today and it is great.");
+    assert_eq!(untitled.description, untitled.title);
+    assert_eq!(untitled.source_name, "Micro Notes");
+
+    let titled = feed.articles.iter().find(|a| a.guid == "2").unwrap();
+    assert_eq!(titled.title, "A titled post");
+    assert_eq!(titled.description, "The summary wins over content.");
+    assert!(titled.published_at.is_some());
+
+    let text_only = feed.articles.iter().find(|a| a.guid == "3").unwrap();
+    assert_eq!(text_only.description, "Plain text body with no markup.");
+    assert!(text_only.published_at.is_some(), "date_modified is used when date_published is missing");
+}
+
+#[test]
+fn json_feed_1_0() {
+    let feed = parse(include_str!("fixtures/jsonfeed_1_0.json"));
+    assert_eq!(feed.format, FeedFormat::JsonFeed);
+    assert_eq!(feed.articles.len(), 1);
+    assert_eq!(feed.articles[0].guid, "legacy-1");
+    assert_eq!(feed.articles[0].link, "https://legacy.example/posts/1");
+}
+
+#[test]
+fn rss_cdata_without_guids() {
+    let feed = parse(include_str!("fixtures/rss_cdata_no_guid.xml"));
+    assert_eq!(feed.format, FeedFormat::Rss);
+    assert_eq!(feed.articles.len(), 2);
+
+    let budget = &feed.articles[0];
+    assert_eq!(budget.title, "Council approves budget");
+    assert_eq!(budget.link, "https://paper.example/news/budget");
+    assert_eq!(budget.description, "The council voted 7&ndash;2 on Tuesday.");
+    assert_eq!(budget.source_name, "Local Paper");
+    assert!(!budget.guid.is_empty(), "feed-rs derives an id when guid is missing");
+    assert_ne!(budget.guid, feed.articles[1].guid);
+
+    assert!(feed.articles[1].published_at.is_some(), "dc:date is honoured");
+}
+
+#[test]
+fn html_page_is_rejected_with_a_clear_error() {
+    let err = parse_feed(include_bytes!("fixtures/html_page.html"), "https://paper.example/", "Paper")
+        .err()
+        .expect("an HTML page is not a feed");
+    assert!(err.to_string().contains("HTML page"), "got: {err}");
+}
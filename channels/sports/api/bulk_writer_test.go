@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testGamesTable = BulkTable{
+	Name:       "games",
+	Columns:    []string{"league", "external_game_id", "state", "home_team_score"},
+	KeyColumns: []string{"league", "external_game_id"},
+}
+
+func TestNewBulkWriterDefaultsUpdateColumns(t *testing.T) {
+	w, err := NewBulkWriter(nil, testGamesTable)
+	if err != nil {
+		t.Fatalf("NewBulkWriter: %v", err)
+	}
+	want := []string{"state", "home_team_score"}
+	if !reflect.DeepEqual(w.table.UpdateColumns, want) {
+		t.Errorf("UpdateColumns = %v, want %v", w.table.UpdateColumns, want)
+	}
+}
+
+func TestNewBulkWriterRejectsUnknownKey(t *testing.T) {
+	table := testGamesTable
+	table.KeyColumns = []string{"id"}
+	if _, err := NewBulkWriter(nil, table); err == nil {
+		t.Error("expected error for key column outside columns")
+	}
+}
+
+func TestCollapseBulkRowsLastWins(t *testing.T) {
+	batch := [][]any{
+		{"NFL", "1", "pre", 0},
+		{"NBA", "1", "in", 10},
+		{"NFL", "1", "in", 7},
+		{"NFL", "2", "pre", 0},
+	}
+	got := collapseBulkRows(batch, testGamesTable.Columns, testGamesTable.KeyColumns)
+	want := [][]any{
+		{"NBA", "1", "in", 10},
+		{"NFL", "1", "in", 7},
+		{"NFL", "2", "pre", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collapseBulkRows = %v, want %v", got, want)
+	}
+}
+
+func TestBulkMergeSQL(t *testing.T) {
+	table := testGamesTable
+	table.UpdateColumns = []string{"state"}
+	got := bulkMergeSQL(table, bulkStageTable(table.Name))
+	want := `INSERT INTO "games" ("league", "external_game_id", "state", "home_team_score") ` +
+		`SELECT "league", "external_game_id", "state", "home_team_score" FROM "bulk_stage_games" ` +
+		`ON CONFLICT ("league", "external_game_id") DO UPDATE SET "state" = EXCLUDED."state"`
+	if got != want {
+		t.Errorf("bulkMergeSQL =\n%s\nwant\n%s", got, want)
+	}
+
+	table.UpdateColumns = []string{}
+	if got := bulkMergeSQL(table, "s"); !strings.HasSuffix(got, "DO NOTHING") {
+		t.Errorf("empty UpdateColumns should DO NOTHING, got %s", got)
+	}
+}
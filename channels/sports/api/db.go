@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// Database Pool
+// =============================================================================
+
+// Bounded pool — pgxpool's default sizing varies with runtime.NumCPU and
+// sets no connect deadline. These defaults keep one channel from running
+// the shared Postgres dry; DB_MAX_CONNS, DB_MIN_CONNS,
+// DB_MAX_CONN_IDLE_TIME and DB_MAX_CONN_LIFETIME override them, as they
+// do for the core gateway (core's database.go).
+const (
+	DBMaxConns        = 10
+	DBMinConns        = 2
+	DBMaxConnLifetime = 30 * time.Minute
+	DBMaxConnIdleTime = 5 * time.Minute
+	DBConnectTimeout  = 5 * time.Second
+)
+
+// dbPoolConfig parses dbURL and applies the pool sizing.
+func dbPoolConfig(dbURL string) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxConns = int32(envPositiveInt("DB_MAX_CONNS", DBMaxConns))
+	config.MinConns = int32(envPositiveInt("DB_MIN_CONNS", DBMinConns))
+	if config.MinConns > config.MaxConns {
+		config.MinConns = config.MaxConns
+	}
+	config.MaxConnLifetime = envPositiveDuration("DB_MAX_CONN_LIFETIME", DBMaxConnLifetime)
+	config.MaxConnIdleTime = envPositiveDuration("DB_MAX_CONN_IDLE_TIME", DBMaxConnIdleTime)
+	config.ConnConfig.ConnectTimeout = DBConnectTimeout
+	return config, nil
+}
+
+// envPositiveInt reads a positive integer, falling back (with a log line
+// for a bad value) to def.
+func envPositiveInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("[DB] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return n
+}
+
+// envPositiveDuration is envPositiveInt for durations ("15m").
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("[DB] Ignoring invalid %s=%q", key, raw)
+		return def
+	}
+	return d
+}
+
+// =============================================================================
+// Read Replica
+// =============================================================================
+
+// With DATABASE_REPLICA_URL set, catalog, public and dashboard data reads
+// go through readDB to the replica while it's reachable and at most
+// ReplicaMaxLag behind, and to the primary otherwise. User config and
+// anything read back right after a write stay on a.db. ReplicaMaxLag is
+// kept under the dashboard cache TTLs so a lagging replica can't pin
+// stale data in the cache for longer than the TTL already allows.
+const (
+	ReplicaCheckInterval = 10 * time.Second
+	ReplicaCheckTimeout  = 2 * time.Second
+	ReplicaMaxLag        = 5 * time.Second
+)
+
+type readReplica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// openReadReplica connects to DATABASE_REPLICA_URL. It returns nil when
+// none is configured or the URL is unusable; an unreachable replica is
+// returned unhealthy and picked up by monitor once it answers.
+func openReadReplica(ctx context.Context) *readReplica {
+	replicaURL := os.Getenv("DATABASE_REPLICA_URL")
+	if replicaURL == "" {
+		return nil
+	}
+	config, err := dbPoolConfig(replicaURL)
+	if err != nil {
+		log.Printf("[DB] Unable to parse DATABASE_REPLICA_URL; reads stay on the primary")
+		return nil
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		log.Printf("[DB] Replica pool failed: %v; reads stay on the primary", err)
+		return nil
+	}
+	r := &readReplica{pool: pool}
+	if r.check(ctx) {
+		log.Println("Connected to PostgreSQL read replica")
+	}
+	return r
+}
+
+// check pings the replica and measures its replay lag. A replica that has
+// replayed everything it received is caught up even if the primary has
+// been idle.
+func (r *readReplica) check(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, ReplicaCheckTimeout)
+	defer cancel()
+
+	var lagSeconds float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT CASE
+		         WHEN NOT pg_is_in_recovery() THEN 0
+		         WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		         ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		       END`).Scan(&lagSeconds)
+	healthy := err == nil && time.Duration(lagSeconds*float64(time.Second)) <= ReplicaMaxLag
+
+	if was := r.healthy.Swap(healthy); was != healthy {
+		switch {
+		case healthy:
+			log.Println("[DB] Read replica healthy; routing reads to it")
+		case err != nil:
+			log.Printf("[DB] Read replica unreachable, reads fall back to the primary: %v", err)
+		default:
+			log.Printf("[DB] Read replica %.0fs behind, reads fall back to the primary", lagSeconds)
+		}
+	}
+	return healthy
+}
+
+// monitor re-checks the replica every ReplicaCheckInterval until ctx ends.
+func (r *readReplica) monitor(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(ReplicaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+func (r *readReplica) Close() {
+	if r != nil {
+		r.pool.Close()
+	}
+}
+
+// readDB returns the pool for lag-tolerant reads: the replica while it's
+// healthy, else the primary.
+func (a *App) readDB() *pgxpool.Pool {
+	if a.replica != nil && a.replica.healthy.Load() {
+		return a.replica.pool
+	}
+	return a.db
+}
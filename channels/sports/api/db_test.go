@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestDBPoolConfigEnv(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "25")
+	t.Setenv("DB_MAX_CONN_IDLE_TIME", "bogus")
+	config, err := dbPoolConfig("postgres://u:p@localhost:5432/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxConns != 25 || config.MinConns != DBMinConns {
+		t.Errorf("max/min = %d/%d, want 25/%d", config.MaxConns, config.MinConns, DBMinConns)
+	}
+	if config.MaxConnIdleTime != DBMaxConnIdleTime {
+		t.Errorf("invalid idle time not ignored: %s", config.MaxConnIdleTime)
+	}
+	if config.ConnConfig.ConnectTimeout != 5*time.Second {
+		t.Errorf("connect timeout = %s", config.ConnConfig.ConnectTimeout)
+	}
+}
+
+func TestReadDBFallsBackToPrimary(t *testing.T) {
+	a := &App{db: &pgxpool.Pool{}}
+	if a.readDB() != a.db {
+		t.Error("readDB without a replica should be the primary")
+	}
+
+	a.replica = &readReplica{pool: &pgxpool.Pool{}}
+	if a.readDB() != a.db {
+		t.Error("readDB used an unhealthy replica")
+	}
+	a.replica.healthy.Store(true)
+	if a.readDB() != a.replica.pool {
+		t.Error("readDB ignored a healthy replica")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsAndExpires(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	l := newLRUCache(20)
+
+	l.set("a", []byte("12345"), time.Second, now) // 6 bytes
+	l.set("b", []byte("12345"), time.Second, now) // 12
+	l.get("a", now)                               // a is now most recent
+	l.set("c", []byte("1234567890"), time.Second, now)
+
+	if _, ok := l.get("b", now); ok {
+		t.Error("least recently used entry survived an over-budget set")
+	}
+	if _, ok := l.get("a", now); !ok {
+		t.Error("recently read entry was evicted")
+	}
+	if _, ok := l.get("c", now.Add(2*time.Second)); ok {
+		t.Error("expired entry served")
+	}
+
+	l.del("a")
+	if _, ok := l.get("a", now); ok {
+		t.Error("deleted entry served")
+	}
+}
@@ -37,6 +37,34 @@ const (
 	DefaultChannelURL = "http://localhost:8082"
 )
 
+// configSchema describes user_channels.config for this channel. The
+// gateway validates config writes against it (api/core/config_schema.go)
+// and serves it at /channels/{name}/schema, where title and description
+// label the generated settings form. Keys not listed here are accepted
+// unchecked.
+const configSchema = `{
+	"type": "object",
+	"properties": {
+		"leagues": {
+			"type": "array",
+			"title": "Leagues",
+			"description": "Leagues to show scores for.",
+			"uniqueItems": true,
+			"items": {"type": "string", "minLength": 1, "maxLength": 64}
+		},
+		"teams": {
+			"type": "array",
+			"title": "Teams",
+			"description": "Team codes (e.g. KC, BOS) to limit the selected leagues to. Leave empty for every game.",
+			"uniqueItems": true,
+			"items": {"type": "string", "minLength": 1, "maxLength": 10}
+		},
+		"show_odds": {"type": "boolean", "title": "Show betting lines", "description": "Moneyline, spread and total on upcoming and live games, where available."},
+		"display": {"type": "object", "title": "Display"},
+		"show_on_profile": {"type": "boolean", "title": "Show on public profile"}
+	}
+}`
+
 // registrationPayload is the JSON structure stored in Redis for service discovery.
 type registrationPayload struct {
 	Name         string              `json:"name"`
@@ -45,12 +73,17 @@ type registrationPayload struct {
 	Capabilities []string            `json:"capabilities"`
 	CDCTables    []string            `json:"cdc_tables"`
 	Routes       []registrationRoute `json:"routes"`
+	StartedAt    int64               `json:"started_at"` // Unix ms; lets the gateway detect restarts
+	Version      string              `json:"version"`    // build (GIT_SHA); lets the gateway spot rollouts
+	ConfigSchema json.RawMessage     `json:"config_schema,omitempty"`
 }
 
 type registrationRoute struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 	Auth   bool   `json:"auth"`
+	// CacheTTL (seconds) lets the gateway cache anonymous GETs of a public route.
+	CacheTTL int `json:"cache_ttl,omitempty"`
 }
 
 // =============================================================================
@@ -67,6 +100,15 @@ func main() {
 		defer sentry.Flush(2 * time.Second)
 	}
 
+	// Tracing — spans are exported only when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set (tracing.go).
+	shutdownTracing := initTracing(context.Background())
+	defer func() {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer flushCancel()
+		_ = shutdownTracing(flushCtx)
+	}()
+
 	// -------------------------------------------------------------------------
 	// Connect to PostgreSQL
 	// -------------------------------------------------------------------------
@@ -77,16 +119,11 @@ func main() {
 
 	// Bounded pool — default sizing varies with runtime.NumCPU and doesn't
 	// set a connect timeout. Capping at 10 with a 5s connect deadline keeps
-	// this channel from starving the shared Postgres.
-	poolConfig, err := pgxpool.ParseConfig(dbURL)
+	// this channel from starving the shared Postgres (db.go; DB_* env).
+	poolConfig, err := dbPoolConfig(dbURL)
 	if err != nil {
 		log.Fatalf("[DB] parse config: %v", err)
 	}
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
-	poolConfig.MaxConnLifetime = 30 * time.Minute
-	poolConfig.MaxConnIdleTime = 5 * time.Minute
-	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatalf("[DB] new pool: %v", err)
@@ -98,26 +135,23 @@ func main() {
 	}
 	log.Println("[Sports] Connected to PostgreSQL")
 
+	// Optional read replica for catalog, public and dashboard reads (db.go)
+	replica := openReadReplica(context.Background())
+	defer replica.Close()
+
 	// -------------------------------------------------------------------------
 	// Connect to Redis
 	// -------------------------------------------------------------------------
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("[Sports] REDIS_URL is required")
-	}
-
-	opts, err := redis.ParseURL(redisURL)
+	rdb, redisMode, err := newRedisClient(os.Getenv)
 	if err != nil {
-		log.Fatalf("[Sports] Invalid REDIS_URL: %v", err)
+		log.Fatalf("[Sports] Unable to configure Redis: %v", err)
 	}
-
-	rdb := redis.NewClient(opts)
 	defer rdb.Close()
 
 	if err := rdb.Ping(context.Background()).Err(); err != nil {
 		log.Fatalf("[Sports] Redis ping failed: %v", err)
 	}
-	log.Println("[Sports] Connected to Redis")
+	log.Printf("[Sports] Connected to Redis (%s)", redisMode)
 
 	// -------------------------------------------------------------------------
 	// Start Redis self-registration heartbeat
@@ -125,12 +159,18 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Read replica health checks; readDB falls back while it lags (db.go)
+	go replica.monitor(ctx)
+
 	go startRegistration(ctx, rdb)
 
+	// Drop hot-key local cache entries other pods invalidate (local_cache.go)
+	go startCacheInvalidationListener(ctx, rdb)
+
 	// -------------------------------------------------------------------------
 	// Fiber HTTP Server
 	// -------------------------------------------------------------------------
-	app := &App{db: pool, rdb: rdb}
+	app := &App{db: pool, replica: replica, rdb: rdb}
 
 	fiberApp := fiber.New(fiber.Config{
 		AppName:               "Scrollr Sports API",
@@ -144,11 +184,24 @@ func main() {
 		fiberApp.Use(sentryUserHook())
 	}
 
+	// Server span per request, continuing the gateway's trace (tracing.go)
+	fiberApp.Use(tracingMiddleware)
+
+	// Request latency histograms for /metrics (metrics.go)
+	fiberApp.Use(metricsMiddleware)
+
 	// Internal routes (called by core gateway only)
+	fiberApp.Use("/internal", requireInternalSignature) // HMAC from core (internal_auth.go)
 	fiberApp.Post("/internal/cdc", app.handleInternalCDC)
 	fiberApp.Get("/internal/dashboard", app.handleInternalDashboard)
 	fiberApp.Get("/internal/health", app.handleInternalHealth)
 	fiberApp.Post("/internal/channel-lifecycle", app.handleChannelLifecycle)
+	fiberApp.Post("/internal/channel-lifecycle/replay", app.handleLifecycleReplay)
+	fiberApp.Get("/internal/bulk-writer/stats", app.handleBulkWriterStats)
+
+	// Prometheus scrape endpoint, cluster-internal only (metrics.go)
+	registerPoolMetrics(app.db, app.rdb)
+	fiberApp.Get("/metrics", requireInternalNetwork, handleMetrics)
 
 	// Public routes (proxied by core gateway)
 	fiberApp.Get("/sports", app.getSports)
@@ -157,6 +210,7 @@ func main() {
 	fiberApp.Get("/sports/standings", app.getStandings)
 	fiberApp.Get("/sports/teams", app.getTeams)
 	fiberApp.Get("/sports/health", app.healthHandler)
+	fiberApp.Get("/public/scoreboard", app.getPublicScoreboard) // Marketing-site scoreboard (scoreboard.go)
 
 	// -------------------------------------------------------------------------
 	// Start server with graceful shutdown
@@ -194,7 +248,7 @@ func main() {
 // startRegistration registers this service in Redis with a TTL and refreshes
 // the registration on a ticker. This allows the core gateway to discover
 // available channel services.
-func startRegistration(ctx context.Context, rdb *redis.Client) {
+func startRegistration(ctx context.Context, rdb redis.UniversalClient) {
 	channelURL := os.Getenv("CHANNEL_URL")
 	if channelURL == "" {
 		channelURL = DefaultChannelURL
@@ -204,16 +258,20 @@ func startRegistration(ctx context.Context, rdb *redis.Client) {
 		Name:         "sports",
 		DisplayName:  "Sports",
 		InternalURL:  channelURL,
-		Capabilities: []string{"cdc_handler", "dashboard_provider", "health_checker", "channel_lifecycle"},
+		Capabilities: []string{"cdc_handler", "dashboard_provider", "health_checker", "channel_lifecycle", "lifecycle_replay"},
 		CDCTables:    []string{"games"},
 		Routes: []registrationRoute{
 			{Method: "GET", Path: "/sports", Auth: true},
-			{Method: "GET", Path: "/sports/public", Auth: false},
-			{Method: "GET", Path: "/sports/leagues", Auth: false},
+			{Method: "GET", Path: "/sports/public", Auth: false, CacheTTL: 15},
+			{Method: "GET", Path: "/sports/leagues", Auth: false, CacheTTL: 300},
 			{Method: "GET", Path: "/sports/standings", Auth: true},
 			{Method: "GET", Path: "/sports/teams", Auth: true},
 			{Method: "GET", Path: "/sports/health", Auth: false},
+			{Method: "GET", Path: "/public/scoreboard", Auth: false, CacheTTL: 15},
 		},
+		StartedAt:    time.Now().UnixMilli(),
+		Version:      envOr("GIT_SHA", "unknown"),
+		ConfigSchema: json.RawMessage(configSchema),
 	}
 
 	data, err := json.Marshal(payload)
@@ -25,6 +25,21 @@ type Game struct {
 	Timer          string    `json:"timer,omitempty"`
 	Venue          string    `json:"venue,omitempty"`
 	Season         string    `json:"season,omitempty"`
+
+	// Betting lines, set only for users with show_odds on (odds.go).
+	Odds *GameOdds `json:"odds,omitempty"`
+}
+
+// GameOdds is one bookmaker's lines for a game, from the ingestion
+// service's odds poll. Moneylines are American odds.
+type GameOdds struct {
+	HomeMoneyline      *int      `json:"home_moneyline,omitempty"`
+	AwayMoneyline      *int      `json:"away_moneyline,omitempty"`
+	HomeSpread         *float64  `json:"home_spread,omitempty"`
+	Total              *float64  `json:"total,omitempty"`
+	HomeWinProbability *float64  `json:"home_win_probability,omitempty"`
+	Provider           string    `json:"provider"`
+	UpdatedAt          time.Time `json:"updated_at"`
 }
 
 // TrackedLeague represents a league entry from the catalog, enriched with
@@ -62,6 +77,9 @@ type LeagueMeta struct {
 type SportsResponse struct {
 	Sports []Game     `json:"sports"`
 	Meta   SportsMeta `json:"meta"`
+	// NextCursor is set on paged responses (pagination.go) that have
+	// another page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // SportsMeta wraps per-league context.
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+// =============================================================================
+// Betting Lines
+// =============================================================================
+
+// Lines come from the ingestion service's odds poll (service/src/odds.rs),
+// which writes them onto the games rows. They are opt-in per user (the
+// show_odds config flag), so the game queries leave them out and
+// attachOdds adds them afterwards for users who asked.
+
+// attachOdds sets Odds on the games that have lines. Lookup failures are
+// logged and the games are returned without lines.
+func (a *App) attachOdds(ctx context.Context, games []Game) {
+	if len(games) == 0 {
+		return
+	}
+	ids := make([]int, len(games))
+	byID := make(map[int]*Game, len(games))
+	for i := range games {
+		ids[i] = games[i].ID
+		byID[games[i].ID] = &games[i]
+	}
+
+	rows, err := a.readDB().Query(ctx, `
+		SELECT id, home_moneyline, away_moneyline, home_spread, total_points,
+			COALESCE(odds_provider, ''), odds_updated_at
+		FROM games
+		WHERE id = ANY($1) AND odds_updated_at IS NOT NULL`, ids)
+	if err != nil {
+		log.Printf("[Sports] Odds lookup failed: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var odds GameOdds
+		var updatedAt *time.Time
+		if err := rows.Scan(&id, &odds.HomeMoneyline, &odds.AwayMoneyline,
+			&odds.HomeSpread, &odds.Total, &odds.Provider, &updatedAt); err != nil {
+			log.Printf("[Sports] Odds row scan failed: %v", err)
+			continue
+		}
+		if updatedAt != nil {
+			odds.UpdatedAt = *updatedAt
+		}
+		if odds.HomeMoneyline != nil && odds.AwayMoneyline != nil {
+			p := winProbability(*odds.HomeMoneyline, *odds.AwayMoneyline)
+			odds.HomeWinProbability = &p
+		}
+		if g := byID[id]; g != nil {
+			g.Odds = &odds
+		}
+	}
+}
+
+// impliedProbability converts an American moneyline to the win
+// probability it prices in, bookmaker margin included.
+func impliedProbability(moneyline int) float64 {
+	if moneyline < 0 {
+		return float64(-moneyline) / float64(-moneyline+100)
+	}
+	return 100 / float64(moneyline+100)
+}
+
+// winProbability is the home side's chance to win implied by the two
+// moneylines, with the bookmaker margin removed, rounded to a tenth of a
+// percent. In three-way markets (soccer) the draw is ignored, so it reads
+// as "chance to win, given someone wins".
+func winProbability(home, away int) float64 {
+	h, a := impliedProbability(home), impliedProbability(away)
+	if h+a == 0 {
+		return 0.5
+	}
+	return math.Round(h/(h+a)*1000) / 1000
+}
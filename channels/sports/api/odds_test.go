@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestWinProbability(t *testing.T) {
+	tests := []struct {
+		home, away int
+		want       float64
+	}{
+		{-150, 125, 0.574},
+		{-110, -110, 0.5},
+		{200, -250, 0.318},
+	}
+	for _, tc := range tests {
+		if got := winProbability(tc.home, tc.away); got != tc.want {
+			t.Errorf("winProbability(%d, %d) = %v, want %v", tc.home, tc.away, got, tc.want)
+		}
+	}
+}
+
+func TestExtractShowOddsFromConfig(t *testing.T) {
+	if !extractShowOddsFromConfig([]byte(`{"leagues":["NFL"],"show_odds":true}`)) {
+		t.Error("show_odds true not read")
+	}
+	if extractShowOddsFromConfig([]byte(`{"leagues":["NFL"]}`)) {
+		t.Error("odds shown without opting in")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Cursor Pagination
+// =============================================================================
+
+// Passing ?cursor= or ?limit= to /sports, /sports/public or
+// /internal/dashboard switches them from the ranked "what matters now"
+// list to a plain history, newest start_time first, one page at a time.
+// The response's next_cursor (sports_next_cursor in the dashboard
+// envelope) fetches the following page and is absent on the last one. Cursors are keyset positions, so rows arriving between
+// requests don't shift or duplicate later pages.
+
+const (
+	// DefaultSportsPageLimit / MaxSportsPageLimit bound ?limit= in paged mode.
+	DefaultSportsPageLimit = 50
+	MaxSportsPageLimit     = DefaultSportsLimit
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the sort key of the last row on a page.
+type pageCursor struct {
+	At time.Time
+	ID int
+}
+
+// encodeCursor makes an opaque cursor from the last row's sort key.
+func encodeCursor(at time.Time, id int) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + ":" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return pageCursor{}, errInvalidCursor
+	}
+	nanos, err1 := strconv.ParseInt(ts, 10, 64)
+	n, err2 := strconv.Atoi(id)
+	if err1 != nil || err2 != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	return pageCursor{At: time.Unix(0, nanos).UTC(), ID: n}, nil
+}
+
+// pageParams is a parsed ?cursor=&limit= pair.
+type pageParams struct {
+	After *pageCursor // nil for the first page
+	Limit int
+}
+
+// parsePageParams reads ?cursor= and ?limit=. ok is false when neither is
+// present, so the caller keeps its unpaged response.
+func parsePageParams(c *fiber.Ctx, def, max int) (p pageParams, ok bool, err error) {
+	cursor, limit := c.Query("cursor"), c.Query("limit")
+	if cursor == "" && limit == "" {
+		return pageParams{}, false, nil
+	}
+	p.Limit = def
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return pageParams{}, true, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = min(n, max)
+	}
+	if cursor != "" {
+		cur, err := decodeCursor(cursor)
+		if err != nil {
+			return pageParams{}, true, err
+		}
+		p.After = &cur
+	}
+	return p, true, nil
+}
+
+// queryGamesPage returns one page of games, newest start_time first, and
+// the cursor for the next page ("" when this is the last). leagues nil
+// means every league; teams nil means every team.
+func (a *App) queryGamesPage(ctx context.Context, leagues, teams []string, p pageParams) ([]Game, string, error) {
+	var afterTime *time.Time
+	var afterID int
+	if p.After != nil {
+		afterTime, afterID = &p.After.At, p.After.ID
+	}
+
+	// One extra row tells us whether another page exists.
+	rows, err := a.readDB().Query(ctx, `
+		SELECT id, league, COALESCE(sport, ''), external_game_id, COALESCE(link, ''),
+			home_team_name, COALESCE(home_team_logo, ''), COALESCE(home_team_score::text, ''), COALESCE(home_team_code, ''),
+			away_team_name, COALESCE(away_team_logo, ''), COALESCE(away_team_score::text, ''), COALESCE(away_team_code, ''),
+			start_time, COALESCE(short_detail, ''), state,
+			COALESCE(status_short, ''), COALESCE(status_long, ''),
+			COALESCE(timer, ''), COALESCE(venue, ''), COALESCE(season, '')
+		FROM games
+		WHERE ($1::text[] IS NULL OR league = ANY($1))
+		  AND ($5::text[] IS NULL OR upper(home_team_code) = ANY($5) OR upper(away_team_code) = ANY($5))
+		  AND ($2::timestamptz IS NULL OR (start_time, id) < ($2, $3))
+		ORDER BY start_time DESC, id DESC
+		LIMIT $4`, leagues, afterTime, afterID, p.Limit+1, teams)
+	if err != nil {
+		return nil, "", fmt.Errorf("sports page query failed: %w", err)
+	}
+	defer rows.Close()
+
+	games := make([]Game, 0, p.Limit)
+	for rows.Next() {
+		var g Game
+		if err := rows.Scan(
+			&g.ID, &g.League, &g.Sport, &g.ExternalGameID, &g.Link,
+			&g.HomeTeamName, &g.HomeTeamLogo, &g.HomeTeamScore, &g.HomeTeamCode,
+			&g.AwayTeamName, &g.AwayTeamLogo, &g.AwayTeamScore, &g.AwayTeamCode,
+			&g.StartTime, &g.ShortDetail, &g.State,
+			&g.StatusShort, &g.StatusLong, &g.Timer, &g.Venue, &g.Season,
+		); err != nil {
+			log.Printf("[Sports] Page row scan failed: %v", err)
+			continue
+		}
+		games = append(games, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	games, next := trimGamesPage(games, p.Limit)
+	return games, next, nil
+}
+
+// trimGamesPage drops the look-ahead row and returns the next cursor.
+func trimGamesPage(games []Game, limit int) ([]Game, string) {
+	if len(games) <= limit {
+		return games, ""
+	}
+	games = games[:limit]
+	last := games[limit-1]
+	return games, encodeCursor(last.StartTime, last.ID)
+}
+
+// getGamesPage serves a paged /sports, /sports/public or
+// /internal/dashboard request for the games cfg selects (the zero value
+// for /sports/public); dashboard selects the /internal/dashboard envelope.
+func (a *App) getGamesPage(c *fiber.Ctx, cfg userSportsConfig, p pageParams, dashboard bool) error {
+	ctx := context.Background()
+	games, next, err := a.queryGamesPage(ctx, cfg.Leagues, cfg.Teams, p)
+	if err != nil {
+		log.Printf("[Sports] %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+	if cfg.ShowOdds {
+		a.attachOdds(ctx, games)
+	}
+	leagues := cfg.Leagues
+	if leagues == nil {
+		leagues = a.allEnabledLeagueNames(ctx)
+	}
+	meta := SportsMeta{Leagues: a.loadLeagueMeta(ctx, leagues)}
+
+	if dashboard {
+		// Sibling keys, like the unpaged dashboard envelope, so the
+		// gateway can merge channels.
+		resp := fiber.Map{"sports": games, "sports_meta": meta}
+		if next != "" {
+			resp["sports_next_cursor"] = next
+		}
+		return c.JSON(resp)
+	}
+	return c.JSON(SportsResponse{Sports: games, Meta: meta, NextCursor: next})
+}
+
+// badPageParams writes the 400 for a malformed ?cursor= or ?limit=.
+func badPageParams(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+		Status: "error",
+		Error:  err.Error(),
+	})
+}
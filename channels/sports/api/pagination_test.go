@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 4, 17, 30, 0, 123456789, time.UTC)
+	cur, err := decodeCursor(encodeCursor(at, 812))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if !cur.At.Equal(at) || cur.ID != 812 {
+		t.Errorf("cursor = %+v, want %s / 812", cur, at)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"!!", "bm9jb2xvbg", "YWJjOjEy"} { // bad base64, no colon, non-numeric time
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded", s)
+		}
+	}
+}
+
+func TestTrimGamesPage(t *testing.T) {
+	base := time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC)
+	games := []Game{
+		{ID: 3, StartTime: base.Add(2 * time.Hour)},
+		{ID: 2, StartTime: base.Add(time.Hour)},
+		{ID: 1, StartTime: base},
+	}
+
+	page, next := trimGamesPage(games, 2)
+	if len(page) != 2 || next == "" {
+		t.Fatalf("page = %d games, next = %q; want 2 and a cursor", len(page), next)
+	}
+	cur, _ := decodeCursor(next)
+	if cur.ID != 2 || !cur.At.Equal(base.Add(time.Hour)) {
+		t.Errorf("next cursor = %+v, want the last returned game", cur)
+	}
+
+	if _, next := trimGamesPage(games, 3); next != "" {
+		t.Errorf("last page next = %q, want empty", next)
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPaged bool
+		wantLimit int
+		wantErr   bool
+	}{
+		{"", false, 0, false},
+		{"?limit=10", true, 10, false},
+		{"?limit=5000", true, MaxSportsPageLimit, false},
+		{"?limit=0", true, 0, true},
+		{"?cursor=" + encodeCursor(time.Now(), 1), true, DefaultSportsPageLimit, false},
+		{"?cursor=bogus!", true, 0, true},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			p, paged, err := parsePageParams(c, DefaultSportsPageLimit, MaxSportsPageLimit)
+			if paged != tt.wantPaged || (err != nil) != tt.wantErr {
+				t.Errorf("%q: paged=%v err=%v", tt.query, paged, err)
+			}
+			if err == nil && paged && p.Limit != tt.wantLimit {
+				t.Errorf("%q: limit = %d, want %d", tt.query, p.Limit, tt.wantLimit)
+			}
+			return nil
+		})
+		if _, err := app.Test(httptest.NewRequest("GET", "/"+tt.query, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Public Scoreboard
+// =============================================================================
+
+// GET /public/scoreboard?league=nfl serves one league's games for today to
+// the marketing site. Unlike /sports/public it has a fixed, lightweight
+// shape with no internal IDs, and it's built to sit behind a CDN: a short
+// shared Cache-Control with stale-while-revalidate, an ETag, and a Redis
+// copy per league that CDC invalidates.
+
+const (
+	// CacheKeyScoreboardPrefix is the Redis key prefix for per-league
+	// scoreboards: cache:sports:scoreboard:{nfl}.
+	CacheKeyScoreboardPrefix = "cache:sports:scoreboard:"
+
+	// ScoreboardMaxAge / ScoreboardSharedMaxAge are the browser and CDN
+	// Cache-Control lifetimes. Live scores tolerate half a minute at the
+	// edge; stale-while-revalidate hides the refetch.
+	ScoreboardMaxAge               = 15
+	ScoreboardSharedMaxAge         = 30
+	ScoreboardStaleWhileRevalidate = 60
+)
+
+// Scoreboard is the GET /public/scoreboard body.
+type Scoreboard struct {
+	League    string           `json:"league"`
+	Date      string           `json:"date"` // YYYY-MM-DD, US Eastern
+	UpdatedAt time.Time        `json:"updated_at"`
+	Games     []ScoreboardGame `json:"games"`
+}
+
+// ScoreboardGame is one game on the public scoreboard.
+type ScoreboardGame struct {
+	StartTime time.Time      `json:"start_time"`
+	State     string         `json:"state"`            // pre | in | post
+	Status    string         `json:"status,omitempty"` // e.g. "Q3 5:12", "Final"
+	Home      ScoreboardTeam `json:"home"`
+	Away      ScoreboardTeam `json:"away"`
+	Venue     string         `json:"venue,omitempty"`
+}
+
+// ScoreboardTeam is one side of a scoreboard game. Score is empty before
+// kickoff.
+type ScoreboardTeam struct {
+	Name  string `json:"name"`
+	Code  string `json:"code,omitempty"`
+	Logo  string `json:"logo,omitempty"`
+	Score string `json:"score,omitempty"`
+}
+
+// easternLocation defines "today" for the scoreboard; US leagues schedule
+// their days in Eastern time.
+var easternLocation = func() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.FixedZone("ET", -5*3600)
+	}
+	return loc
+}()
+
+// scoreboardDay returns the bounds of the Eastern calendar day holding now.
+func scoreboardDay(now time.Time) (start, end time.Time) {
+	local := now.In(easternLocation)
+	start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, easternLocation)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// buildScoreboard maps games to the public shape.
+func buildScoreboard(league string, day time.Time, games []Game, now time.Time) Scoreboard {
+	sb := Scoreboard{
+		League:    league,
+		Date:      day.Format("2006-01-02"),
+		UpdatedAt: now.UTC(),
+		Games:     make([]ScoreboardGame, 0, len(games)),
+	}
+	for _, g := range games {
+		sb.Games = append(sb.Games, ScoreboardGame{
+			StartTime: g.StartTime.UTC(),
+			State:     g.State,
+			Status:    g.ShortDetail,
+			Home:      ScoreboardTeam{Name: g.HomeTeamName, Code: g.HomeTeamCode, Logo: g.HomeTeamLogo, Score: g.HomeTeamScore},
+			Away:      ScoreboardTeam{Name: g.AwayTeamName, Code: g.AwayTeamCode, Logo: g.AwayTeamLogo, Score: g.AwayTeamScore},
+			Venue:     g.Venue,
+		})
+	}
+	return sb
+}
+
+// getPublicScoreboard handles GET /public/scoreboard?league=.
+func (a *App) getPublicScoreboard(c *fiber.Ctx) error {
+	requested := strings.TrimSpace(c.Query("league"))
+	if requested == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "league is required",
+		})
+	}
+
+	// Keyed by the lower-cased name so a hit skips resolving the league.
+	cacheKey := scoreboardCacheKey(requested)
+	var sb Scoreboard
+	if countCache("scoreboard", GetCache(a.rdb, cacheKey, &sb)) {
+		c.Set("X-Cache", "HIT")
+	} else {
+		ctx := context.Background()
+		league, ok := a.resolveEnabledLeague(ctx, requested)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
+				Status: "not_found",
+				Error:  "Unknown league",
+			})
+		}
+		now := time.Now()
+		start, end := scoreboardDay(now)
+		games, err := a.queryScoreboardGames(ctx, league, start, end)
+		if err != nil {
+			log.Printf("[Sports] scoreboard query failed for %s: %v", league, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Status: "error",
+				Error:  "Internal server error",
+			})
+		}
+		sb = buildScoreboard(league, start, games, now)
+		SetCache(a.rdb, cacheKey, sb, SportsCacheTTL)
+		c.Set("X-Cache", "MISS")
+	}
+
+	body, err := json.Marshal(sb)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Internal server error",
+		})
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	c.Set("ETag", etag)
+	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d, stale-while-revalidate=%d",
+		ScoreboardMaxAge, ScoreboardSharedMaxAge, ScoreboardStaleWhileRevalidate))
+	if c.Get("If-None-Match") == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set("Content-Type", "application/json")
+	return c.Send(body)
+}
+
+func scoreboardCacheKey(league string) string {
+	return CacheKeyScoreboardPrefix + strings.ToLower(league)
+}
+
+// resolveEnabledLeague matches a league name case-insensitively against
+// the enabled tracked leagues and returns its canonical name.
+func (a *App) resolveEnabledLeague(ctx context.Context, name string) (string, bool) {
+	for _, l := range a.allEnabledLeagueNames(ctx) {
+		if strings.EqualFold(l, name) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// queryScoreboardGames returns league's games starting in [start, end),
+// plus any still in progress from the night before.
+func (a *App) queryScoreboardGames(ctx context.Context, league string, start, end time.Time) ([]Game, error) {
+	rows, err := a.readDB().Query(ctx, `
+		SELECT home_team_name, COALESCE(home_team_logo, ''), COALESCE(home_team_score::text, ''), COALESCE(home_team_code, ''),
+			away_team_name, COALESCE(away_team_logo, ''), COALESCE(away_team_score::text, ''), COALESCE(away_team_code, ''),
+			start_time, COALESCE(short_detail, ''), state, COALESCE(venue, '')
+		FROM games
+		WHERE league = $1
+		  AND ((start_time >= $2 AND start_time < $3) OR (state = 'in' AND start_time < $2))
+		ORDER BY start_time, home_team_name`, league, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	games := make([]Game, 0)
+	for rows.Next() {
+		g := Game{League: league}
+		if err := rows.Scan(
+			&g.HomeTeamName, &g.HomeTeamLogo, &g.HomeTeamScore, &g.HomeTeamCode,
+			&g.AwayTeamName, &g.AwayTeamLogo, &g.AwayTeamScore, &g.AwayTeamCode,
+			&g.StartTime, &g.ShortDetail, &g.State, &g.Venue,
+		); err != nil {
+			log.Printf("[Sports] scoreboard row scan failed: %v", err)
+			continue
+		}
+		games = append(games, g)
+	}
+	return games, rows.Err()
+}
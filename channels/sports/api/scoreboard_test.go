@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoreboardDayUsesEasternCalendar(t *testing.T) {
+	// 02:30 UTC on the 7th is still the evening of the 6th in New York.
+	now := time.Date(2026, 10, 7, 2, 30, 0, 0, time.UTC)
+	start, end := scoreboardDay(now)
+
+	if got := start.Format("2006-01-02"); got != "2026-10-06" {
+		t.Errorf("day = %s, want 2026-10-06", got)
+	}
+	if !start.Before(now) || !end.After(now) {
+		t.Errorf("[%s, %s) does not contain %s", start, end, now)
+	}
+	if end.Sub(start) != 24*time.Hour {
+		t.Errorf("day length = %s, want 24h", end.Sub(start))
+	}
+}
+
+func TestBuildScoreboardOmitsInternalIDs(t *testing.T) {
+	start, _ := scoreboardDay(time.Date(2026, 10, 6, 18, 0, 0, 0, time.UTC))
+	games := []Game{{
+		ID:             42,
+		League:         "NFL",
+		ExternalGameID: "ext-99",
+		Link:           "https://example.com/game/99",
+		HomeTeamName:   "Kansas City Chiefs",
+		HomeTeamCode:   "KC",
+		HomeTeamScore:  "21",
+		AwayTeamName:   "Buffalo Bills",
+		AwayTeamCode:   "BUF",
+		AwayTeamScore:  "17",
+		StartTime:      time.Date(2026, 10, 6, 17, 0, 0, 0, time.UTC),
+		ShortDetail:    "Q4 2:00",
+		State:          "in",
+	}}
+
+	sb := buildScoreboard("NFL", start, games, time.Date(2026, 10, 6, 18, 5, 0, 0, time.UTC))
+	if sb.Date != "2026-10-06" || len(sb.Games) != 1 {
+		t.Fatalf("scoreboard = %+v", sb)
+	}
+	g := sb.Games[0]
+	if g.Home.Code != "KC" || g.Away.Score != "17" || g.Status != "Q4 2:00" {
+		t.Errorf("game = %+v", g)
+	}
+
+	body, _ := json.Marshal(sb)
+	for _, leaked := range []string{"42", "ext-99", "external_game_id", "example.com"} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("body contains %q: %s", leaked, body)
+		}
+	}
+}
+
+func TestBuildScoreboardEmptyGamesIsArray(t *testing.T) {
+	sb := buildScoreboard("NHL", time.Now(), nil, time.Now())
+	body, _ := json.Marshal(sb)
+	if !strings.Contains(string(body), `"games":[]`) {
+		t.Errorf("body = %s, want an empty games array", body)
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
 // =============================================================================
@@ -46,6 +48,17 @@ const (
 	// Keys: sports:subscribers:league:{NFL}, sports:subscribers:league:{NBA}, etc.
 	SportsLeagueSubscribersPrefix = "sports:subscribers:league:"
 
+	// SportsTeamSubscribersPrefix is the per-team subscriber set prefix,
+	// for users whose config narrows a league to some teams.
+	// Keys: sports:subscribers:team:{NFL}:{KC}, etc.
+	SportsTeamSubscribersPrefix = "sports:subscribers:team:"
+
+	// SportsTeamFilteredPrefix is the per-league set of users following it
+	// through a team filter. They stay in the league set (the gateway
+	// maintains that too) and are subtracted from it when routing.
+	// Keys: sports:subscribers:filtered:{NFL}, etc.
+	SportsTeamFilteredPrefix = "sports:subscribers:filtered:"
+
 	// DefaultSportsLimit caps the number of games returned for /sports
 	// (authenticated full channel page + public route). High enough to fit
 	// a week of MLB (~105 rows) plus other leagues with headroom. The full
@@ -71,8 +84,14 @@ const (
 
 // App holds the shared dependencies for all handlers.
 type App struct {
-	db  *pgxpool.Pool
-	rdb *redis.Client
+	db      *pgxpool.Pool
+	replica *readReplica // optional; read through readDB (db.go)
+	rdb     redis.UniversalClient
+	bulk    bulkWriters
+
+	// sfGroup collapses concurrent cache misses on a shared key into one
+	// DB query, so an expiry under load costs a single query.
+	sfGroup singleflight.Group
 }
 
 // =============================================================================
@@ -81,10 +100,26 @@ type App struct {
 
 // getSports retrieves the latest sports games.
 // If X-User-Sub is set (authenticated), returns per-user filtered games.
-// Otherwise returns all games (public).
+// Otherwise returns all games (public). ?cursor= / ?limit= page through
+// game history instead (pagination.go).
 func (a *App) getSports(c *fiber.Ctx) error {
 	userSub := c.Get("X-User-Sub")
 
+	page, paged, err := parsePageParams(c, DefaultSportsPageLimit, MaxSportsPageLimit)
+	if err != nil {
+		return badPageParams(c, err)
+	}
+	if paged {
+		var cfg userSportsConfig
+		if userSub != "" {
+			cfg = a.getUserSportsConfig(userSub)
+			if len(cfg.Leagues) == 0 {
+				return c.JSON(SportsResponse{Sports: []Game{}, Meta: SportsMeta{Leagues: []LeagueMeta{}}})
+			}
+		}
+		return a.getGamesPage(c, cfg, page, false)
+	}
+
 	// Authenticated: return per-user filtered games
 	if userSub != "" {
 		return a.getUserGames(c, userSub, DefaultSportsLimit)
@@ -92,13 +127,24 @@ func (a *App) getSports(c *fiber.Ctx) error {
 
 	// Public: return all games + meta for every enabled league.
 	var resp SportsResponse
-	if GetCache(a.rdb, CacheKeySports, &resp) {
+	if countCache("games", GetCache(a.rdb, CacheKeySports, &resp)) {
 		c.Set("X-Cache", "HIT")
 		return c.JSON(resp)
 	}
 
-	ctx := context.Background()
-	games, err := a.queryGames(ctx, DefaultSportsLimit, nil)
+	// Singleflight: collapse concurrent cache-miss requests into one DB query
+	result, err, _ := a.sfGroup.Do(CacheKeySports, func() (interface{}, error) {
+		ctx := context.Background()
+		games, qErr := a.queryGames(ctx, DefaultSportsLimit, nil)
+		if qErr != nil {
+			return nil, qErr
+		}
+		meta := a.loadLeagueMeta(ctx, a.allEnabledLeagueNames(ctx))
+
+		fresh := SportsResponse{Sports: games, Meta: SportsMeta{Leagues: meta}}
+		SetCache(a.rdb, CacheKeySports, fresh, SportsCacheTTL)
+		return fresh, nil
+	})
 	if err != nil {
 		log.Printf("[Sports] getSports query failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -106,12 +152,9 @@ func (a *App) getSports(c *fiber.Ctx) error {
 			Error:  "Internal server error",
 		})
 	}
-	meta := a.loadLeagueMeta(ctx, a.allEnabledLeagueNames(ctx))
 
-	resp = SportsResponse{Sports: games, Meta: SportsMeta{Leagues: meta}}
-	SetCache(a.rdb, CacheKeySports, resp, SportsCacheTTL)
 	c.Set("X-Cache", "MISS")
-	return c.JSON(resp)
+	return c.JSON(result.(SportsResponse))
 }
 
 // leagueStatus holds the per-league activity computed from the games table.
@@ -146,7 +189,7 @@ func (a *App) loadLeagueStatus(ctx context.Context, names []string) (map[string]
 	var rows pgx.Rows
 	var err error
 	if len(names) == 0 {
-		rows, err = a.db.Query(ctx, `
+		rows, err = a.readDB().Query(ctx, `
 			SELECT league,
 			       COUNT(*) AS game_count,
 			       COUNT(*) FILTER (WHERE state = 'in') AS live_count,
@@ -154,7 +197,7 @@ func (a *App) loadLeagueStatus(ctx context.Context, names []string) (map[string]
 			FROM games
 			GROUP BY league`)
 	} else {
-		rows, err = a.db.Query(ctx, `
+		rows, err = a.readDB().Query(ctx, `
 			SELECT league,
 			       COUNT(*) AS game_count,
 			       COUNT(*) FILTER (WHERE state = 'in') AS live_count,
@@ -200,7 +243,7 @@ func (a *App) loadLeagueMeta(ctx context.Context, names []string) []LeagueMeta {
 	currentMonth := int32(time.Now().Month())
 
 	// Query tracked_leagues for off-season + polling-health columns.
-	rows, err := a.db.Query(ctx, `
+	rows, err := a.readDB().Query(ctx, `
 		SELECT name, offseason_months, last_poll_success_at
 		FROM tracked_leagues
 		WHERE name = ANY($1)`, names)
@@ -257,7 +300,7 @@ func (a *App) loadLeagueMeta(ctx context.Context, names []string) []LeagueMeta {
 // Errors are logged and a nil slice is returned so the public endpoint
 // degrades to an empty meta rather than 500-ing.
 func (a *App) allEnabledLeagueNames(ctx context.Context) []string {
-	rows, err := a.db.Query(ctx,
+	rows, err := a.readDB().Query(ctx,
 		`SELECT name FROM tracked_leagues WHERE is_enabled = true ORDER BY name`)
 	if err != nil {
 		log.Printf("[Sports] allEnabledLeagueNames query failed: %v", err)
@@ -279,19 +322,28 @@ func (a *App) allEnabledLeagueNames(ctx context.Context) []string {
 // getLeagueCatalog returns all enabled tracked leagues for the dashboard
 // league browser, enriched with per-league game counts and activity status.
 func (a *App) getLeagueCatalog(c *fiber.Ctx) error {
+	// Public route: the gateway only forwards X-User-Sub when the caller
+	// happens to be signed in. Quota headers are per-user, so they're set
+	// outside the shared catalog cache.
+	if userSub := c.Get("X-User-Sub"); userSub != "" {
+		setQuotaHeaders(c, "leagues", len(a.getUserSportsLeagues(userSub)), LeagueCap(GetUserTier(c)))
+	}
+
 	var catalog []TrackedLeague
-	if GetCache(a.rdb, CacheKeySportsCatalog, &catalog) {
+	if countCache("catalog", GetCache(a.rdb, CacheKeySportsCatalog, &catalog)) {
 		c.Set("X-Cache", "HIT")
 		return c.JSON(catalog)
 	}
 
-	ctx := context.Background()
-	currentMonth := int32(time.Now().Month())
-
-	rows, err := a.db.Query(ctx,
-		`SELECT name, COALESCE(sport_api, ''), COALESCE(category, 'Other'), COALESCE(country, ''), COALESCE(logo_url, ''),
-		        offseason_months, last_polled_at, last_poll_success_at
-		 FROM tracked_leagues WHERE is_enabled = true ORDER BY category, name`)
+	// Singleflight: collapse concurrent cache-miss requests into one DB query
+	result, err, _ := a.sfGroup.Do(CacheKeySportsCatalog, func() (interface{}, error) {
+		leagues, qErr := a.queryLeagueCatalog(context.Background())
+		if qErr != nil {
+			return nil, qErr
+		}
+		SetCache(a.rdb, CacheKeySportsCatalog, leagues, SportsCatalogCacheTTL)
+		return leagues, nil
+	})
 	if err != nil {
 		log.Printf("[Sports] Catalog query failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -299,9 +351,26 @@ func (a *App) getLeagueCatalog(c *fiber.Ctx) error {
 			Error:  "Failed to fetch league catalog",
 		})
 	}
+
+	c.Set("X-Cache", "MISS")
+	return c.JSON(result.([]TrackedLeague))
+}
+
+// queryLeagueCatalog loads every enabled tracked league with its
+// offseason/polling health and per-league game activity.
+func (a *App) queryLeagueCatalog(ctx context.Context) ([]TrackedLeague, error) {
+	currentMonth := int32(time.Now().Month())
+
+	rows, err := a.readDB().Query(ctx,
+		`SELECT name, COALESCE(sport_api, ''), COALESCE(category, 'Other'), COALESCE(country, ''), COALESCE(logo_url, ''),
+		        offseason_months, last_polled_at, last_poll_success_at
+		 FROM tracked_leagues WHERE is_enabled = true ORDER BY category, name`)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	catalog = make([]TrackedLeague, 0)
+	catalog := make([]TrackedLeague, 0)
 	for rows.Next() {
 		var l TrackedLeague
 		if err := rows.Scan(
@@ -333,10 +402,7 @@ func (a *App) getLeagueCatalog(c *fiber.Ctx) error {
 			catalog[i].NextGame = s.NextGame
 		}
 	}
-
-	SetCache(a.rdb, CacheKeySportsCatalog, catalog, SportsCatalogCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.JSON(catalog)
+	return catalog, nil
 }
 
 // containsMonth checks if the given month is in the offseason_months slice.
@@ -364,7 +430,8 @@ func (a *App) healthHandler(c *fiber.Ctx) error {
 //
 // Per-league routing: each CDC record contains a "league" field (e.g. "NFL",
 // "NBA"). The handler looks up per-league subscriber sets to determine which
-// users follow that league.
+// users follow that league. Users whose config narrows the league to some
+// teams only get games those teams play (gameSubscribers).
 func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 	var req struct {
 		Records []CDCRecord `json:"records"`
@@ -376,8 +443,10 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 		})
 	}
 
+	cdcBatchSize.Observe(float64(len(req.Records)))
 	ctx := context.Background()
 	userSet := make(map[string]struct{})
+	leagues := make(map[string]struct{})
 
 	for _, rec := range req.Records {
 		league, ok := rec.Record["league"].(string)
@@ -385,7 +454,11 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 			continue
 		}
 
-		subs, err := GetSubscribers(a.rdb, ctx, SportsLeagueSubscribersPrefix+league)
+		leagues[league] = struct{}{}
+
+		home, _ := rec.Record["home_team_code"].(string)
+		away, _ := rec.Record["away_team_code"].(string)
+		subs, err := a.gameSubscribers(ctx, league, home, away)
 		if err != nil {
 			log.Printf("[Sports CDC] Failed to get league subscribers for %s: %v", league, err)
 			continue
@@ -399,6 +472,9 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 	// Bust caches so the next request serves fresh data instead of stale scores.
 	// Without this, CDC notifies clients of changes but re-fetches return cached data.
 	DeleteCache(a.rdb, CacheKeySports) // public cache
+	for league := range leagues {
+		DeleteCache(a.rdb, scoreboardCacheKey(league)) // public scoreboard
+	}
 	for sub := range userSet {
 		DeleteCache(a.rdb, CacheKeySportsPrefix+sub) // per-user cache
 	}
@@ -411,8 +487,50 @@ func (a *App) handleInternalCDC(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"users": users})
 }
 
+// gameSubscribers returns the users a game update in league is for: the
+// league's subscribers without a team filter, plus the subscribers of
+// either team.
+func (a *App) gameSubscribers(ctx context.Context, league, home, away string) ([]string, error) {
+	// Single-key reads, combined here: the sets hash to different slots,
+	// so SDIFF/SUNION would be refused in cluster mode.
+	pipe := a.rdb.Pipeline()
+	leagueSubs := pipe.SMembers(ctx, SportsLeagueSubscribersPrefix+league)
+	filtered := pipe.SMembers(ctx, SportsTeamFilteredPrefix+league)
+	var fans []*redis.StringSliceCmd
+	for _, code := range []string{home, away} {
+		if code = normalizeTeamCode(code); code != "" {
+			fans = append(fans, pipe.SMembers(ctx, teamSubscriberKey(league, code)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	teamFiltered := make(map[string]bool)
+	for _, sub := range filtered.Val() {
+		teamFiltered[sub] = true
+	}
+	added := make(map[string]bool)
+	var subs []string
+	for _, sub := range leagueSubs.Val() {
+		if !teamFiltered[sub] && !added[sub] {
+			added[sub] = true
+			subs = append(subs, sub)
+		}
+	}
+	for _, cmd := range fans {
+		for _, sub := range cmd.Val() {
+			if !added[sub] {
+				added[sub] = true
+				subs = append(subs, sub)
+			}
+		}
+	}
+	return subs, nil
+}
+
 // handleInternalDashboard returns sports data for a user's dashboard.
-// Query param: user={logto_sub}
+// Query params: user={logto_sub}, optional cursor/limit (pagination.go)
 func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	userSub := c.Query("user")
 	if userSub == "" {
@@ -422,9 +540,24 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 		})
 	}
 
+	page, paged, err := parsePageParams(c, DefaultSportsPageLimit, MaxSportsPageLimit)
+	if err != nil {
+		return badPageParams(c, err)
+	}
+	if paged {
+		cfg := a.getUserSportsConfig(userSub)
+		if len(cfg.Leagues) == 0 {
+			return c.JSON(fiber.Map{
+				"sports":      []Game{},
+				"sports_meta": SportsMeta{Leagues: []LeagueMeta{}},
+			})
+		}
+		return a.getGamesPage(c, cfg, page, true)
+	}
+
 	cacheKey := CacheKeySportsPrefix + userSub
 	var resp SportsResponse
-	if GetCache(a.rdb, cacheKey, &resp) {
+	if countCache("dashboard", GetCache(a.rdb, cacheKey, &resp)) {
 		return c.JSON(fiber.Map{
 			"sports":      resp.Sports,
 			"sports_meta": resp.Meta,
@@ -432,7 +565,8 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	leagues := a.getUserSportsLeagues(userSub)
+	cfg := a.getUserSportsConfig(userSub)
+	leagues := cfg.Leagues
 	if len(leagues) == 0 {
 		return c.JSON(fiber.Map{
 			"sports":      []Game{},
@@ -443,7 +577,7 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 	favoriteTeams := a.getUserFavoriteTeams(userSub)
 	// Home dashboard uses fair-share so every selected league is visible
 	// within the 20-row glanceable preview, regardless of relative volume.
-	games, err := a.queryGamesByLeagues(ctx, leagues, DashboardSportsLimit, favoriteTeams, true)
+	games, err := a.queryGamesByLeagues(ctx, leagues, cfg.Teams, DashboardSportsLimit, favoriteTeams, true)
 	if err != nil {
 		log.Printf("[Sports] Dashboard query failed: %v", err)
 		return c.JSON(fiber.Map{
@@ -451,6 +585,9 @@ func (a *App) handleInternalDashboard(c *fiber.Ctx) error {
 			"sports_meta": SportsMeta{Leagues: []LeagueMeta{}},
 		})
 	}
+	if cfg.ShowOdds {
+		a.attachOdds(ctx, games)
+	}
 	meta := a.loadLeagueMeta(ctx, leagues)
 
 	resp = SportsResponse{Sports: games, Meta: SportsMeta{Leagues: meta}}
@@ -520,15 +657,35 @@ func (a *App) handleInternalHealth(c *fiber.Ctx) error {
 // Channel Lifecycle
 // =============================================================================
 
+// lifecycleEvent is the body of a lifecycle call from the core gateway.
+type lifecycleEvent struct {
+	Event     string                 `json:"event"`
+	User      string                 `json:"user"`
+	Config    map[string]interface{} `json:"config"`
+	OldConfig map[string]interface{} `json:"old_config"`
+	Enabled   bool                   `json:"enabled"`
+}
+
 // handleChannelLifecycle handles channel lifecycle events dispatched by the core
 // gateway. Events: created, updated, deleted, sync.
 func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
+	var req lifecycleEvent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
+			Status: "error",
+			Error:  "Invalid request body",
+		})
+	}
+
+	a.applyLifecycleEvent(context.Background(), req)
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// handleLifecycleReplay applies lifecycle events the gateway queued while
+// this channel was unreachable, in the order they were originally sent.
+func (a *App) handleLifecycleReplay(c *fiber.Ctx) error {
 	var req struct {
-		Event     string                 `json:"event"`
-		User      string                 `json:"user"`
-		Config    map[string]interface{} `json:"config"`
-		OldConfig map[string]interface{} `json:"old_config"`
-		Enabled   bool                   `json:"enabled"`
+		Events []lifecycleEvent `json:"events"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{
@@ -538,28 +695,35 @@ func (a *App) handleChannelLifecycle(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
+	for _, ev := range req.Events {
+		a.applyLifecycleEvent(ctx, ev)
+	}
+	log.Printf("[Sports Lifecycle] Replayed %d queued events", len(req.Events))
+	return c.JSON(fiber.Map{"processed": len(req.Events)})
+}
 
-	switch req.Event {
+func (a *App) applyLifecycleEvent(ctx context.Context, ev lifecycleEvent) {
+	switch ev.Event {
 	case "created":
-		log.Printf("[Sports Lifecycle] Channel created for user %s", req.User)
+		log.Printf("[Sports Lifecycle] Channel created for user %s", ev.User)
 
 	case "updated":
-		a.onChannelUpdated(ctx, req.User, req.OldConfig, req.Config)
+		a.onChannelUpdated(ctx, ev.User, ev.OldConfig, ev.Config)
 
 	case "deleted":
-		a.onChannelDeleted(ctx, req.User, req.Config)
+		a.onChannelDeleted(ctx, ev.User, ev.Config)
 
 	case "sync":
-		a.onSyncSubscriptions(ctx, req.User, req.Config, req.Enabled)
+		a.onSyncSubscriptions(ctx, ev.User, ev.Config, ev.Enabled)
 
 	default:
-		log.Printf("[Sports Lifecycle] Unknown event: %s", req.Event)
+		log.Printf("[Sports Lifecycle] Unknown event: %s", ev.Event)
 	}
-
-	return c.JSON(fiber.Map{"ok": true})
 }
 
-// onChannelUpdated handles league list changes when a channel is updated.
+// onChannelUpdated handles league and team list changes when a channel is
+// updated. The gateway sends a sync with the new config first, which adds
+// the new memberships; this drops the ones the new config no longer has.
 func (a *App) onChannelUpdated(ctx context.Context, userSub string, oldConfig, newConfig map[string]interface{}) {
 	if newConfig == nil {
 		return
@@ -577,30 +741,57 @@ func (a *App) onChannelUpdated(ctx context.Context, userSub string, oldConfig, n
 		}
 	}
 
+	oldKeys := teamFilterKeys(oldLeagues, extractTeamsFromChannelConfig(oldConfig))
+	newKeys := make(map[string]bool)
+	for _, k := range teamFilterKeys(newLeagues, extractTeamsFromChannelConfig(newConfig)) {
+		newKeys[k] = true
+	}
+	for _, k := range oldKeys {
+		if !newKeys[k] {
+			RemoveSubscriber(a.rdb, ctx, k, userSub)
+		}
+	}
+
 	// Invalidate per-user cache
 	DeleteCache(a.rdb, CacheKeySportsPrefix+userSub)
 }
 
-// onChannelDeleted removes the user from all league subscriber sets.
+// onChannelDeleted removes the user from all league and team subscriber sets.
 func (a *App) onChannelDeleted(ctx context.Context, userSub string, config map[string]interface{}) {
 	leagues := extractLeaguesFromChannelConfig(config)
 	for _, l := range leagues {
 		RemoveSubscriber(a.rdb, ctx, SportsLeagueSubscribersPrefix+l, userSub)
 	}
+	for _, k := range teamFilterKeys(leagues, extractTeamsFromChannelConfig(config)) {
+		RemoveSubscriber(a.rdb, ctx, k, userSub)
+	}
 	DeleteCache(a.rdb, CacheKeySportsPrefix+userSub)
 }
 
-// onSyncSubscriptions adds or removes the user from per-league subscriber
-// sets based on the enabled flag.
+// onSyncSubscriptions adds or removes the user from per-league and per-team
+// subscriber sets based on the enabled flag.
 func (a *App) onSyncSubscriptions(ctx context.Context, userSub string, config map[string]interface{}, enabled bool) {
 	leagues := extractLeaguesFromChannelConfig(config)
+	teamKeys := teamFilterKeys(leagues, extractTeamsFromChannelConfig(config))
 	for _, l := range leagues {
 		if enabled {
 			AddSubscriber(a.rdb, ctx, SportsLeagueSubscribersPrefix+l, userSub)
+			if len(teamKeys) == 0 {
+				// A filter dropped while this service missed the update
+				// would otherwise keep the user off the league's games.
+				RemoveSubscriber(a.rdb, ctx, SportsTeamFilteredPrefix+l, userSub)
+			}
 		} else {
 			RemoveSubscriber(a.rdb, ctx, SportsLeagueSubscribersPrefix+l, userSub)
 		}
 	}
+	for _, k := range teamKeys {
+		if enabled {
+			AddSubscriber(a.rdb, ctx, k, userSub)
+		} else {
+			RemoveSubscriber(a.rdb, ctx, k, userSub)
+		}
+	}
 }
 
 // =============================================================================
@@ -613,7 +804,7 @@ func (a *App) onSyncSubscriptions(ctx context.Context, userSub string, config ma
 func (a *App) queryGames(ctx context.Context, limit int, favoriteTeams map[string]FavoriteTeam) ([]Game, error) {
 	favNames := extractFavoriteTeamNames(favoriteTeams)
 
-	rows, err := a.db.Query(ctx, fmt.Sprintf(`
+	rows, err := a.readDB().Query(ctx, fmt.Sprintf(`
 		SELECT id, league, COALESCE(sport, ''), external_game_id, COALESCE(link, ''),
 			home_team_name, COALESCE(home_team_logo, ''), COALESCE(home_team_score::text, ''), COALESCE(home_team_code, ''),
 			away_team_name, COALESCE(away_team_logo, ''), COALESCE(away_team_score::text, ''), COALESCE(away_team_code, ''),
@@ -671,8 +862,9 @@ const MinPerLeagueShare = 2
 // Users see every game for every selected league and can filter client-side
 // with the page's league/status chips. The user-controlled experience.
 //
-// If favoriteTeams is provided, those teams' games are prioritized.
-func (a *App) queryGamesByLeagues(ctx context.Context, leagues []string, limit int, favoriteTeams map[string]FavoriteTeam, fairShare bool) ([]Game, error) {
+// If teams is non-empty, only games one of those team codes plays in are
+// returned. If favoriteTeams is provided, those teams' games are prioritized.
+func (a *App) queryGamesByLeagues(ctx context.Context, leagues, teams []string, limit int, favoriteTeams map[string]FavoriteTeam, fairShare bool) ([]Game, error) {
 	if len(leagues) == 0 {
 		return make([]Game, 0), nil
 	}
@@ -704,6 +896,7 @@ func (a *App) queryGamesByLeagues(ctx context.Context, leagues []string, limit i
 					) AS rn
 				FROM games
 				WHERE league = ANY($1)
+				  AND ($3::text[] IS NULL OR upper(home_team_code) = ANY($3) OR upper(away_team_code) = ANY($3))
 			)
 			SELECT id, league, COALESCE(sport, ''), external_game_id, COALESCE(link, ''),
 				home_team_name, COALESCE(home_team_logo, ''), COALESCE(home_team_score::text, ''), COALESCE(home_team_code, ''),
@@ -730,6 +923,7 @@ func (a *App) queryGamesByLeagues(ctx context.Context, leagues []string, limit i
 				COALESCE(timer, ''), COALESCE(venue, ''), COALESCE(season, '')
 			FROM games
 			WHERE league = ANY($1)
+			  AND ($3::text[] IS NULL OR upper(home_team_code) = ANY($3) OR upper(away_team_code) = ANY($3))
 			ORDER BY
 				CASE state WHEN 'in' THEN 0 WHEN 'pre' THEN 1 ELSE 2 END,
 				CASE WHEN home_team_name = ANY($2) OR away_team_name = ANY($2) THEN 0 ELSE 1 END,
@@ -738,7 +932,7 @@ func (a *App) queryGamesByLeagues(ctx context.Context, leagues []string, limit i
 			LIMIT %d`, limit)
 	}
 
-	rows, err := a.db.Query(ctx, query, leagues, favNames)
+	rows, err := a.readDB().Query(ctx, query, leagues, favNames, teams)
 	if err != nil {
 		return nil, fmt.Errorf("sports league query failed: %w", err)
 	}
@@ -767,13 +961,14 @@ func (a *App) queryGamesByLeagues(ctx context.Context, leagues []string, limit i
 func (a *App) getUserGames(c *fiber.Ctx, userSub string, limit int) error {
 	cacheKey := CacheKeySportsPrefix + userSub
 	var resp SportsResponse
-	if GetCache(a.rdb, cacheKey, &resp) {
+	if countCache("user_games", GetCache(a.rdb, cacheKey, &resp)) {
 		c.Set("X-Cache", "HIT")
 		return c.JSON(resp)
 	}
 
 	ctx := context.Background()
-	leagues := a.getUserSportsLeagues(userSub)
+	cfg := a.getUserSportsConfig(userSub)
+	leagues := cfg.Leagues
 	if len(leagues) == 0 {
 		// Even with no leagues, return the new shape — empty arrays both sides.
 		return c.JSON(SportsResponse{Sports: []Game{}, Meta: SportsMeta{Leagues: []LeagueMeta{}}})
@@ -783,7 +978,7 @@ func (a *App) getUserGames(c *fiber.Ctx, userSub string, limit int) error {
 	// /sports (full channel page) returns every game for every selected
 	// league. The page already has league + status filter chips for the
 	// user to narrow down — we surface all the data and let them control it.
-	games, err := a.queryGamesByLeagues(ctx, leagues, limit, favoriteTeams, false)
+	games, err := a.queryGamesByLeagues(ctx, leagues, cfg.Teams, limit, favoriteTeams, false)
 	if err != nil {
 		log.Printf("[Sports] getUserGames query failed: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
@@ -791,6 +986,9 @@ func (a *App) getUserGames(c *fiber.Ctx, userSub string, limit int) error {
 			Error:  "Internal server error",
 		})
 	}
+	if cfg.ShowOdds {
+		a.attachOdds(ctx, games)
+	}
 	meta := a.loadLeagueMeta(ctx, leagues)
 
 	resp = SportsResponse{Sports: games, Meta: SportsMeta{Leagues: meta}}
@@ -812,6 +1010,32 @@ func (a *App) getUserSportsLeagues(logtoSub string) []string {
 	return extractLeaguesFromConfig(configJSON)
 }
 
+// userSportsConfig is what a user's sports channel config says about the
+// games to return. The zero value means every league, every team, no odds.
+type userSportsConfig struct {
+	Leagues  []string
+	Teams    []string // nil when there is no team filter
+	ShowOdds bool
+}
+
+// getUserSportsConfig reads the game filter and display options from a
+// user's sports channel config.
+func (a *App) getUserSportsConfig(logtoSub string) userSportsConfig {
+	var configJSON []byte
+	err := a.db.QueryRow(context.Background(), `
+		SELECT config FROM user_channels
+		WHERE logto_sub = $1 AND channel_type = 'sports'
+	`, logtoSub).Scan(&configJSON)
+	if err != nil {
+		return userSportsConfig{}
+	}
+	return userSportsConfig{
+		Leagues:  extractLeaguesFromConfig(configJSON),
+		Teams:    extractTeamsFromConfig(configJSON),
+		ShowOdds: extractShowOddsFromConfig(configJSON),
+	}
+}
+
 // getUserFavoriteTeams extracts favorite teams from a user's sports channel config.
 func (a *App) getUserFavoriteTeams(logtoSub string) map[string]FavoriteTeam {
 	var configJSON []byte
@@ -840,11 +1064,29 @@ func (a *App) getStandings(c *fiber.Ctx) error {
 
 	cacheKey := "cache:sports:standings:" + league
 	var standings []Standing
-	if GetCache(a.rdb, cacheKey, &standings) {
+	if countCache("standings", GetCache(a.rdb, cacheKey, &standings)) {
 		return c.JSON(fiber.Map{"standings": standings})
 	}
 
-	rows, err := a.db.Query(c.Context(), `
+	result, err, _ := a.sfGroup.Do(cacheKey, func() (interface{}, error) {
+		fresh, qErr := a.queryStandings(context.Background(), league)
+		if qErr != nil {
+			return nil, qErr
+		}
+		SetCache(a.rdb, cacheKey, fresh, StandingsCacheTTL)
+		return fresh, nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "failed to query standings",
+		})
+	}
+	return c.JSON(fiber.Map{"standings": result.([]Standing)})
+}
+
+// queryStandings loads a league's standings table, best rank first.
+func (a *App) queryStandings(ctx context.Context, league string) ([]Standing, error) {
+	rows, err := a.readDB().Query(ctx, `
 		SELECT league, team_name, COALESCE(team_code, ''), COALESCE(team_logo, ''),
 			COALESCE(rank, 0), wins, losses, draws, COALESCE(points, 0),
 			games_played, COALESCE(goal_diff, 0),
@@ -856,13 +1098,11 @@ func (a *App) getStandings(c *fiber.Ctx) error {
 		WHERE league = $1
 		ORDER BY COALESCE(rank, 9999) ASC`, league)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-			Status: "error", Error: "failed to query standings",
-		})
+		return nil, err
 	}
 	defer rows.Close()
 
-	standings = make([]Standing, 0)
+	standings := make([]Standing, 0)
 	for rows.Next() {
 		var s Standing
 		if err := rows.Scan(
@@ -877,9 +1117,7 @@ func (a *App) getStandings(c *fiber.Ctx) error {
 		}
 		standings = append(standings, s)
 	}
-
-	SetCache(a.rdb, cacheKey, standings, StandingsCacheTTL)
-	return c.JSON(fiber.Map{"standings": standings})
+	return standings, rows.Err()
 }
 
 // getTeams returns teams for a given league.
@@ -893,24 +1131,40 @@ func (a *App) getTeams(c *fiber.Ctx) error {
 
 	cacheKey := "cache:sports:teams:" + league
 	var teams []TeamInfo
-	if GetCache(a.rdb, cacheKey, &teams) {
+	if countCache("teams", GetCache(a.rdb, cacheKey, &teams)) {
 		return c.JSON(fiber.Map{"teams": teams})
 	}
 
-	rows, err := a.db.Query(c.Context(), `
+	result, err, _ := a.sfGroup.Do(cacheKey, func() (interface{}, error) {
+		fresh, qErr := a.queryTeams(context.Background(), league)
+		if qErr != nil {
+			return nil, qErr
+		}
+		SetCache(a.rdb, cacheKey, fresh, TeamsCacheTTL)
+		return fresh, nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Status: "error", Error: "failed to query teams",
+		})
+	}
+	return c.JSON(fiber.Map{"teams": result.([]TeamInfo)})
+}
+
+// queryTeams loads a league's teams in name order.
+func (a *App) queryTeams(ctx context.Context, league string) ([]TeamInfo, error) {
+	rows, err := a.readDB().Query(ctx, `
 		SELECT league, external_id, name, COALESCE(code, ''), COALESCE(logo, ''),
 			COALESCE(country, '')
 		FROM teams
 		WHERE league = $1
 		ORDER BY name ASC`, league)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-			Status: "error", Error: "failed to query teams",
-		})
+		return nil, err
 	}
 	defer rows.Close()
 
-	teams = make([]TeamInfo, 0)
+	teams := make([]TeamInfo, 0)
 	for rows.Next() {
 		var t TeamInfo
 		if err := rows.Scan(&t.League, &t.ExternalID, &t.Name, &t.Code, &t.Logo, &t.Country); err != nil {
@@ -919,9 +1173,7 @@ func (a *App) getTeams(c *fiber.Ctx) error {
 		}
 		teams = append(teams, t)
 	}
-
-	SetCache(a.rdb, cacheKey, teams, TeamsCacheTTL)
-	return c.JSON(fiber.Map{"teams": teams})
+	return teams, rows.Err()
 }
 
 // =============================================================================
@@ -958,6 +1210,79 @@ func extractLeaguesFromConfig(configJSON []byte) []string {
 	return leagues
 }
 
+// extractTeamsFromChannelConfig extracts team codes from a channel's config map.
+func extractTeamsFromChannelConfig(config map[string]interface{}) []string {
+	if config == nil {
+		return nil
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	return extractTeamsFromConfig(configJSON)
+}
+
+// extractTeamsFromConfig parses a config JSONB blob and returns its team
+// filter: upper-cased, de-duplicated team codes ("KC", "BOS"), or nil when
+// every team in the selected leagues is wanted. A code matches in any of
+// the selected leagues.
+func extractTeamsFromConfig(configJSON []byte) []string {
+	var config struct {
+		Teams []string `json:"teams"`
+	}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil
+	}
+
+	var teams []string
+	seen := make(map[string]bool, len(config.Teams))
+	for _, t := range config.Teams {
+		if code := normalizeTeamCode(t); code != "" && !seen[code] {
+			seen[code] = true
+			teams = append(teams, code)
+		}
+	}
+	return teams
+}
+
+// extractShowOddsFromConfig reports whether a config turns on betting lines.
+func extractShowOddsFromConfig(configJSON []byte) bool {
+	var config struct {
+		ShowOdds bool `json:"show_odds"`
+	}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return false
+	}
+	return config.ShowOdds
+}
+
+// normalizeTeamCode makes a configured or ingested team code comparable.
+func normalizeTeamCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// teamSubscriberKey is the subscriber set of a team within a league.
+func teamSubscriberKey(league, code string) string {
+	return SportsTeamSubscribersPrefix + league + ":" + code
+}
+
+// teamFilterKeys returns the subscriber sets a team filter puts its user
+// in: the filtered set of every selected league and the set of every
+// selected team within it. Empty when there is no team filter.
+func teamFilterKeys(leagues, teams []string) []string {
+	if len(teams) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(leagues)*(len(teams)+1))
+	for _, l := range leagues {
+		keys = append(keys, SportsTeamFilteredPrefix+l)
+		for _, t := range teams {
+			keys = append(keys, teamSubscriberKey(l, t))
+		}
+	}
+	return keys
+}
+
 // extractFavoriteTeamsFromConfig parses config JSON and returns favorite teams per league.
 func extractFavoriteTeamsFromConfig(configJSON []byte) map[string]FavoriteTeam {
 	if len(configJSON) == 0 {
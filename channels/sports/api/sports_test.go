@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -152,3 +153,39 @@ func TestExtractLeaguesFromConfigJSON(t *testing.T) {
 		t.Errorf("got %d, want 2", len(got))
 	}
 }
+
+func TestExtractTeamsFromConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"codes normalised and deduplicated", `{"teams":["kc"," BOS ","KC",""]}`, "KC,BOS"},
+		{"no teams field", `{"leagues":["NFL"]}`, ""},
+		{"invalid JSON", `not json`, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := extractTeamsFromConfig([]byte(tc.input))
+			if strings.Join(got, ",") != tc.want {
+				t.Errorf("extractTeamsFromConfig = %v, want %s", got, tc.want)
+			}
+			if tc.want == "" && got != nil {
+				t.Errorf("extractTeamsFromConfig = %#v, want nil for no filter", got)
+			}
+		})
+	}
+}
+
+func TestTeamFilterKeys(t *testing.T) {
+	if keys := teamFilterKeys([]string{"NFL"}, nil); keys != nil {
+		t.Errorf("no team filter produced keys %v", keys)
+	}
+
+	got := strings.Join(teamFilterKeys([]string{"NFL", "NBA"}, []string{"KC", "BOS"}), " ")
+	want := "sports:subscribers:filtered:NFL sports:subscribers:team:NFL:KC sports:subscribers:team:NFL:BOS " +
+		"sports:subscribers:filtered:NBA sports:subscribers:team:NBA:KC sports:subscribers:team:NBA:BOS"
+	if got != want {
+		t.Errorf("teamFilterKeys =\n  %s\nwant\n  %s", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// =============================================================================
+// Tier Limits — Leagues
+// =============================================================================
+//
+// Mirrors the `Leagues` column of api/core/tier_limits.go DefaultTierLimits.
+// Kept package-local on purpose: each Go module is independently deployable
+// and cross-module imports are banned by AGENTS.md. When the authoritative
+// table in api/core/tier_limits.go changes, update this file too.
+//
+// Only used for reporting (quota headers on the catalog endpoint) — the
+// core gateway enforces the cap when the user saves their channel config.
+//
+// Semantics:
+//   - Positive integer: hard cap.
+//   - -1: unlimited (matches the JSON `null` cap exposed by /tier-limits).
+//
+// Unknown tiers fall through to "free" as a defensive default.
+
+const (
+	TierFree           = "free"
+	TierUplink         = "uplink"
+	TierUplinkPro      = "uplink_pro"
+	TierUplinkUltimate = "uplink_ultimate"
+	TierSuperUser      = "super_user"
+)
+
+// LeagueCap returns the leagues cap for a tier. -1 means unlimited.
+func LeagueCap(tier string) int {
+	switch tier {
+	case TierSuperUser, TierUplinkUltimate:
+		return -1
+	case TierUplinkPro:
+		return 20
+	case TierUplink:
+		return 8
+	default:
+		return 1
+	}
+}
+
+// GetUserTier reads the X-User-Tier header set by the core gateway.
+// Returns "free" if the header is not present.
+func GetUserTier(c *fiber.Ctx) string {
+	tier := c.Get("X-User-Tier")
+	if tier == "" {
+		return TierFree
+	}
+	return tier
+}
+
+// setQuotaHeaders reports the caller's usage against their plan cap so
+// clients can disable "add" buttons before the save is rejected. Headers
+// rather than body fields keep the catalog payload shape (a bare array)
+// unchanged for existing clients.
+func setQuotaHeaders(c *fiber.Ctx, resource string, used, limit int) {
+	c.Set("X-Quota-Resource", resource)
+	c.Set("X-Quota-Used", strconv.Itoa(used))
+	if limit < 0 {
+		c.Set("X-Quota-Limit", "unlimited")
+	} else {
+		c.Set("X-Quota-Limit", strconv.Itoa(limit))
+	}
+}
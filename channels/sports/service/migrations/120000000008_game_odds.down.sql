@@ -0,0 +1,6 @@
+ALTER TABLE games DROP COLUMN IF EXISTS odds_updated_at;
+ALTER TABLE games DROP COLUMN IF EXISTS odds_provider;
+ALTER TABLE games DROP COLUMN IF EXISTS total_points;
+ALTER TABLE games DROP COLUMN IF EXISTS home_spread;
+ALTER TABLE games DROP COLUMN IF EXISTS away_moneyline;
+ALTER TABLE games DROP COLUMN IF EXISTS home_moneyline;
@@ -0,0 +1,16 @@
+-- Betting lines from The Odds API (src/odds.rs), written onto the game
+-- they belong to so they ride the existing games CDC stream.
+-- home_moneyline / away_moneyline: American odds (-150, +130)
+-- home_spread:                     point spread from the home side (-3.5)
+-- total_points:                    over/under line
+-- odds_provider / odds_updated_at: bookmaker and its last line change
+--
+-- Only upcoming and live games are enriched; a game whose lines were
+-- never found keeps these NULL.
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS home_moneyline INTEGER;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS away_moneyline INTEGER;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS home_spread DOUBLE PRECISION;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS total_points DOUBLE PRECISION;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS odds_provider TEXT;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS odds_updated_at TIMESTAMPTZ;
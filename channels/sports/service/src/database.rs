@@ -564,3 +564,84 @@ pub async fn upsert_team(pool: &Arc<PgPool>, t: TeamData) -> Result<()> {
     .await?;
     Ok(())
 }
+
+// =============================================================================
+// Betting lines (see odds.rs)
+// =============================================================================
+
+/// One bookmaker's lines for a game.
+#[derive(Debug, Clone, PartialEq)]
+pub struct GameOdds {
+    pub home_moneyline: Option<i32>,
+    pub away_moneyline: Option<i32>,
+    pub home_spread: Option<f64>,
+    pub total_points: Option<f64>,
+    pub provider: String,
+    pub updated_at: chrono::DateTime<Utc>,
+}
+
+/// Leagues with upcoming or live games — the only ones worth spending
+/// odds quota on. On DB error this returns empty and the cycle is skipped.
+pub async fn get_odds_candidate_leagues(pool: &Arc<PgPool>) -> Vec<String> {
+    let result: Result<Vec<(String,)>, sqlx::Error> = async {
+        let mut conn = pool.acquire().await?;
+        let rows = sqlx::query_as(
+            "SELECT DISTINCT league FROM games WHERE state IN ('pre', 'in')"
+        )
+        .fetch_all(&mut *conn)
+        .await?;
+        Ok(rows)
+    }.await;
+
+    match result {
+        Ok(rows) => rows.into_iter().map(|(league,)| league).collect(),
+        Err(e) => {
+            log::warn!("Failed to query odds candidate leagues, skipping odds poll: {}", e);
+            Vec::new()
+        }
+    }
+}
+
+/// Write lines onto the upcoming or live game they belong to. The odds
+/// feed has no api-sports.io ids, so the game is matched on league, team
+/// names (case-insensitive) and a start time within 12 hours of the feed's.
+/// Unchanged lines are skipped so they don't emit CDC events. Returns the
+/// number of games updated.
+pub async fn update_game_odds(
+    pool: &Arc<PgPool>,
+    league: &str,
+    home_team: &str,
+    away_team: &str,
+    commence_time: chrono::DateTime<Utc>,
+    odds: &GameOdds,
+) -> Result<u64> {
+    let mut conn = pool.acquire().await?;
+    let result = query(
+        "UPDATE games SET
+            home_moneyline = $5,
+            away_moneyline = $6,
+            home_spread = $7,
+            total_points = $8,
+            odds_provider = $9,
+            odds_updated_at = $10
+        WHERE league = $1
+          AND lower(home_team_name) = lower($2)
+          AND lower(away_team_name) = lower($3)
+          AND start_time BETWEEN $4 - INTERVAL '12 hours' AND $4 + INTERVAL '12 hours'
+          AND state IN ('pre', 'in')
+          AND odds_updated_at IS DISTINCT FROM $10"
+    )
+    .bind(league)
+    .bind(home_team)
+    .bind(away_team)
+    .bind(commence_time)
+    .bind(odds.home_moneyline)
+    .bind(odds.away_moneyline)
+    .bind(odds.home_spread)
+    .bind(odds.total_points)
+    .bind(&odds.provider)
+    .bind(odds.updated_at)
+    .execute(&mut *conn)
+    .await?;
+    Ok(result.rows_affected())
+}
@@ -16,6 +16,7 @@ pub use crate::types::{SportsHealth, RateLimiter};
 pub mod log;
 pub mod database;
 pub mod init;
+pub mod odds;
 pub mod types;
 
 /// Number of days ahead to poll in the schedule task. 7 days covers a full
@@ -1194,6 +1195,7 @@ fn parse_football_fixture(item: &serde_json::Value, league: &TrackedLeague) -> O
     let status_short = status.get("short").and_then(|s| s.as_str()).unwrap_or("NS");
     let status_long = status.get("long").and_then(|s| s.as_str());
     let elapsed = status.get("elapsed").and_then(|e| e.as_i64());
+    let extra = status.get("extra").and_then(|e| e.as_i64());
 
     let home = teams.get("home")?;
     let away = teams.get("away")?;
@@ -1204,8 +1206,8 @@ fn parse_football_fixture(item: &serde_json::Value, league: &TrackedLeague) -> O
         .and_then(|n| n.as_str())
         .map(|s| s.to_string());
 
-    let timer = elapsed.map(|e| format!("{}′", e));
-    let detail = build_detail(status_short, status_long, timer.as_deref());
+    let timer = soccer_timer(elapsed, extra);
+    let detail = soccer_detail(status_short, status_long, timer.as_deref());
 
     Some(CleanedData {
         league: league.name.clone(),
@@ -1235,6 +1237,37 @@ fn parse_football_fixture(item: &serde_json::Value, league: &TrackedLeague) -> O
     })
 }
 
+/// Soccer match clock. The v3 API reports stoppage time separately, so a
+/// goal in the 4th added minute of the second half arrives as elapsed=90,
+/// extra=4 and reads "90+4′".
+fn soccer_timer(elapsed: Option<i64>, extra: Option<i64>) -> Option<String> {
+    let elapsed = elapsed?;
+    match extra {
+        Some(extra) if extra > 0 => Some(format!("{}+{}′", elapsed, extra)),
+        _ => Some(format!("{}′", elapsed)),
+    }
+}
+
+/// Soccer detail string: the half instead of the raw status code while
+/// the ball is in play ("2nd Half · 90+4′"), and no stale clock during
+/// breaks (the API keeps elapsed=45 through half time). Other states fall
+/// back to `build_detail`.
+fn soccer_detail(status_short: &str, status_long: Option<&str>, timer: Option<&str>) -> Option<String> {
+    let period = match status_short {
+        "1H" => "1st Half",
+        "2H" => "2nd Half",
+        "ET" => "Extra Time",
+        "HT" => return Some("Halftime".to_string()),
+        "BT" => return Some("Break Before Extra Time".to_string()),
+        "P" => return Some("Penalty Shootout".to_string()),
+        _ => return build_detail(status_short, status_long, timer),
+    };
+    Some(match timer {
+        Some(t) => format!("{} · {}", period, t),
+        None => period.to_string(),
+    })
+}
+
 // =============================================================================
 // American Football (NFL / NCAA) — v1.american-football.api-sports.io
 // =============================================================================
@@ -2056,4 +2089,31 @@ mod tests {
         let detail = build_detail("???", None, None);
         assert!(detail.is_none());
     }
+
+    #[test]
+    fn test_soccer_timer_stoppage_time() {
+        assert_eq!(soccer_timer(Some(90), Some(4)).as_deref(), Some("90+4′"));
+        assert_eq!(soccer_timer(Some(45), Some(0)).as_deref(), Some("45′"));
+        assert_eq!(soccer_timer(Some(23), None).as_deref(), Some("23′"));
+        assert!(soccer_timer(None, Some(2)).is_none());
+    }
+
+    #[test]
+    fn test_soccer_detail_halves() {
+        assert_eq!(soccer_detail("1H", Some("First Half"), Some("23′")).as_deref(), Some("1st Half · 23′"));
+        assert_eq!(soccer_detail("2H", Some("Second Half"), Some("90+4′")).as_deref(), Some("2nd Half · 90+4′"));
+        assert_eq!(soccer_detail("ET", Some("Extra Time"), None).as_deref(), Some("Extra Time"));
+    }
+
+    #[test]
+    fn test_soccer_detail_breaks_drop_clock() {
+        assert_eq!(soccer_detail("HT", Some("Halftime"), Some("45′")).as_deref(), Some("Halftime"));
+        assert_eq!(soccer_detail("P", Some("Penalty In Progress"), Some("120′")).as_deref(), Some("Penalty Shootout"));
+    }
+
+    #[test]
+    fn test_soccer_detail_falls_back() {
+        assert_eq!(soccer_detail("FT", Some("Match Finished"), Some("90′")).as_deref(), Some("Match Finished"));
+        assert_eq!(soccer_detail("NS", Some("Not Started"), None).as_deref(), Some("Not Started"));
+    }
 }
@@ -10,6 +10,7 @@ use sports_service::{
     init::{fatal, spawn_supervised, ReadinessGate, ReadinessSnapshot},
     init_sports_service,
     log::init_async_logger,
+    odds::{poll_odds, OddsClient, ODDS_POLL_SECS},
     poll_live, poll_schedule, poll_standings, poll_teams,
     RateLimiter, SportsHealth,
 };
@@ -355,6 +356,34 @@ async fn run_service() -> Result<()> {
             }
         });
 
+        // ── Odds poll: betting lines (every 15 min, optional) ─────────────
+        // Separate quota from api-sports.io, so it doesn't touch the rate
+        // limiter. Without ODDS_API_KEY the loop never starts.
+        match OddsClient::from_env() {
+            Ok(Some(odds_client)) => {
+                let pool_odds = pool.clone();
+                let cancel_odds = cancel_bg.clone();
+                spawn_supervised("sports-odds-poll", async move {
+                    println!("Starting odds poll loop (every {} min)...", ODDS_POLL_SECS / 60);
+                    poll_odds(&pool_odds, &odds_client).await;
+                    loop {
+                        tokio::select! {
+                            _ = cancel_odds.cancelled() => {
+                                println!("Odds poll loop shutting down...");
+                                break;
+                            }
+                            _ = async {
+                                tokio::time::sleep(std::time::Duration::from_secs(ODDS_POLL_SECS)).await;
+                                poll_odds(&pool_odds, &odds_client).await;
+                            } => {}
+                        }
+                    }
+                });
+            }
+            Ok(None) => println!("ODDS_API_KEY not set; betting lines disabled"),
+            Err(e) => eprintln!("[Odds] Client init failed, betting lines disabled: {e:#}"),
+        }
+
         // ── Daily reset: rate budgets at UTC midnight ─────────────────────
         let leagues_reset = leagues.clone();
         let rl_reset = rate_limiter.clone();
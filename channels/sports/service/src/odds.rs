@@ -0,0 +1,315 @@
+//! Betting-line enrichment from The Odds API (the-odds-api.com).
+//!
+//! Optional: without `ODDS_API_KEY` the poll loop never starts and the odds
+//! columns on `games` stay NULL. Lines are written onto the matching game
+//! row, so they reach clients through the same games CDC stream as scores;
+//! the Go API only shows them to users who turned on `show_odds`.
+//!
+//! Quota: one request per league with upcoming or live games per cycle,
+//! each costing 3 credits (h2h + spreads + totals, one region). At
+//! `ODDS_POLL_SECS` that is ~96 requests/day per active league.
+
+use std::{env, sync::Arc, time::Duration};
+use anyhow::{Context, Result};
+use chrono::{DateTime, Utc};
+use reqwest::Client;
+use serde::Deserialize;
+use crate::database::{PgPool, GameOdds, get_odds_candidate_leagues, update_game_odds};
+use crate::log::{error, info, warn};
+
+/// Interval between odds polls. Lines move slowly before kickoff; live
+/// lines move faster but a 15-minute picture is enough for a ticker.
+pub const ODDS_POLL_SECS: u64 = 15 * 60;
+
+const DEFAULT_ODDS_BASE_URL: &str = "https://api.the-odds-api.com";
+
+/// Bookmaker whose lines are preferred when an event lists several.
+const DEFAULT_ODDS_BOOKMAKER: &str = "draftkings";
+
+/// The Odds API sport key for a tracked league, or None for leagues it
+/// doesn't price (F1, rugby, handball, ...).
+pub fn odds_sport_key(league: &str) -> Option<&'static str> {
+    match league {
+        "NFL" => Some("americanfootball_nfl"),
+        "NCAA Football" => Some("americanfootball_ncaaf"),
+        "NBA" => Some("basketball_nba"),
+        "NCAA Basketball" => Some("basketball_ncaab"),
+        "NHL" => Some("icehockey_nhl"),
+        "MLB" => Some("baseball_mlb"),
+        "Premier League" => Some("soccer_epl"),
+        "La Liga" => Some("soccer_spain_la_liga"),
+        "MLS" => Some("soccer_usa_mls"),
+        "Champions League" => Some("soccer_uefa_champs_league"),
+        "UFC" => Some("mma_mixed_martial_arts"),
+        "AFL" => Some("aussierules_afl"),
+        _ => None,
+    }
+}
+
+// =============================================================================
+// Response types (v4 /sports/{sport}/odds)
+// =============================================================================
+
+#[derive(Debug, Deserialize)]
+pub struct OddsEvent {
+    pub home_team: String,
+    pub away_team: String,
+    pub commence_time: DateTime<Utc>,
+    #[serde(default)]
+    pub bookmakers: Vec<Bookmaker>,
+}
+
+#[derive(Debug, Deserialize)]
+pub struct Bookmaker {
+    pub key: String,
+    pub title: String,
+    pub last_update: DateTime<Utc>,
+    #[serde(default)]
+    pub markets: Vec<Market>,
+}
+
+#[derive(Debug, Deserialize)]
+pub struct Market {
+    pub key: String,
+    #[serde(default)]
+    pub outcomes: Vec<Outcome>,
+}
+
+#[derive(Debug, Deserialize)]
+pub struct Outcome {
+    pub name: String,
+    pub price: f64,
+    pub point: Option<f64>,
+}
+
+// =============================================================================
+// Client
+// =============================================================================
+
+pub struct OddsClient {
+    client: Client,
+    api_key: String,
+    base_url: String,
+    bookmaker: String,
+}
+
+impl OddsClient {
+    /// Build the client from the environment. Returns Ok(None) when
+    /// `ODDS_API_KEY` is unset: odds are an optional enrichment and must
+    /// never keep the service from polling scores.
+    ///
+    /// `ODDS_API_BASE_URL` redirects requests (e.g. to a local mock) and
+    /// `ODDS_BOOKMAKER` picks the preferred bookmaker key.
+    pub fn from_env() -> Result<Option<Self>> {
+        let api_key = env::var("ODDS_API_KEY").unwrap_or_default().trim().to_string();
+        if api_key.is_empty() {
+            return Ok(None);
+        }
+        let base_url = env::var("ODDS_API_BASE_URL")
+            .ok()
+            .map(|u| u.trim().trim_end_matches('/').to_string())
+            .filter(|u| !u.is_empty())
+            .unwrap_or_else(|| DEFAULT_ODDS_BASE_URL.to_string());
+        let bookmaker = env::var("ODDS_BOOKMAKER")
+            .ok()
+            .map(|b| b.trim().to_lowercase())
+            .filter(|b| !b.is_empty())
+            .unwrap_or_else(|| DEFAULT_ODDS_BOOKMAKER.to_string());
+        let client = Client::builder()
+            .timeout(Duration::from_secs(15))
+            .build()
+            .context("odds reqwest client build failed")?;
+        Ok(Some(Self { client, api_key, base_url, bookmaker }))
+    }
+
+    /// Fetch current lines for every event of a sport.
+    async fn fetch(&self, sport_key: &str) -> Result<Vec<OddsEvent>> {
+        let url = format!(
+            "{}/v4/sports/{}/odds?apiKey={}&regions=us&markets=h2h,spreads,totals&oddsFormat=american",
+            self.base_url, sport_key, self.api_key
+        );
+        let resp = self.client.get(&url).send().await?;
+
+        if let Some(remaining) = resp.headers()
+            .get("x-requests-remaining")
+            .and_then(|v| v.to_str().ok())
+        {
+            info!("[Odds] {} fetched, {} requests remaining", sport_key, remaining);
+        }
+
+        let status = resp.status();
+        if !status.is_success() {
+            let body = resp.text().await.unwrap_or_default();
+            anyhow::bail!("odds API returned {} for {}: {}", status, sport_key, body);
+        }
+        Ok(resp.json().await?)
+    }
+}
+
+/// Pick one bookmaker's lines for an event: the preferred one if it
+/// prices the event, else the first listed. None when no bookmaker has
+/// any of the three markets.
+pub fn extract_odds(event: &OddsEvent, preferred: &str) -> Option<GameOdds> {
+    let book = event.bookmakers.iter()
+        .find(|b| b.key == preferred)
+        .or_else(|| event.bookmakers.first())?;
+
+    let mut odds = GameOdds {
+        home_moneyline: None,
+        away_moneyline: None,
+        home_spread: None,
+        total_points: None,
+        provider: book.title.clone(),
+        updated_at: book.last_update,
+    };
+    for market in &book.markets {
+        match market.key.as_str() {
+            // Soccer's three-way market also has a "Draw" outcome; the
+            // ticker only shows the two sides.
+            "h2h" => {
+                for o in &market.outcomes {
+                    if o.name == event.home_team {
+                        odds.home_moneyline = Some(o.price.round() as i32);
+                    } else if o.name == event.away_team {
+                        odds.away_moneyline = Some(o.price.round() as i32);
+                    }
+                }
+            }
+            "spreads" => {
+                odds.home_spread = market.outcomes.iter()
+                    .find(|o| o.name == event.home_team)
+                    .and_then(|o| o.point);
+            }
+            "totals" => {
+                odds.total_points = market.outcomes.iter()
+                    .find(|o| o.name == "Over")
+                    .and_then(|o| o.point);
+            }
+            _ => {}
+        }
+    }
+
+    if odds.home_moneyline.is_none() && odds.home_spread.is_none() && odds.total_points.is_none() {
+        return None;
+    }
+    Some(odds)
+}
+
+// =============================================================================
+// Polling
+// =============================================================================
+
+/// Poll lines for every league with upcoming or live games and write them
+/// onto the matching games. Failures are logged per league; the next
+/// cycle retries.
+pub async fn poll_odds(pool: &Arc<PgPool>, odds: &OddsClient) {
+    let leagues = get_odds_candidate_leagues(pool).await;
+
+    for league in &leagues {
+        let Some(sport_key) = odds_sport_key(league) else {
+            continue;
+        };
+        let events = match odds.fetch(sport_key).await {
+            Ok(events) => events,
+            Err(e) => {
+                warn!("[{}] Odds poll error: {}", league, e);
+                continue;
+            }
+        };
+
+        let mut updated = 0u64;
+        for event in &events {
+            let Some(lines) = extract_odds(event, &odds.bookmaker) else {
+                continue;
+            };
+            match update_game_odds(pool, league, &event.home_team, &event.away_team, event.commence_time, &lines).await {
+                Ok(n) => updated += n,
+                Err(e) => error!("[{}] Failed to update odds for {} @ {}: {}",
+                    league, event.away_team, event.home_team, e),
+            }
+        }
+        if updated > 0 {
+            info!("[{}] Odds updated on {} game(s) from {} event(s)", league, updated, events.len());
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const EVENT: &str = r#"{
+        "id": "e1",
+        "sport_key": "americanfootball_nfl",
+        "commence_time": "2026-10-18T17:00:00Z",
+        "home_team": "Kansas City Chiefs",
+        "away_team": "Buffalo Bills",
+        "bookmakers": [
+            {
+                "key": "fanduel",
+                "title": "FanDuel",
+                "last_update": "2026-10-17T12:00:00Z",
+                "markets": [
+                    {"key": "h2h", "outcomes": [
+                        {"name": "Kansas City Chiefs", "price": -145},
+                        {"name": "Buffalo Bills", "price": 122}
+                    ]}
+                ]
+            },
+            {
+                "key": "draftkings",
+                "title": "DraftKings",
+                "last_update": "2026-10-17T12:30:00Z",
+                "markets": [
+                    {"key": "h2h", "outcomes": [
+                        {"name": "Buffalo Bills", "price": 125},
+                        {"name": "Kansas City Chiefs", "price": -150}
+                    ]},
+                    {"key": "spreads", "outcomes": [
+                        {"name": "Buffalo Bills", "price": -110, "point": 3.0},
+                        {"name": "Kansas City Chiefs", "price": -110, "point": -3.0}
+                    ]},
+                    {"key": "totals", "outcomes": [
+                        {"name": "Over", "price": -108, "point": 47.5},
+                        {"name": "Under", "price": -112, "point": 47.5}
+                    ]}
+                ]
+            }
+        ]
+    }"#;
+
+    #[test]
+    fn test_extract_odds_prefers_bookmaker() {
+        let event: OddsEvent = serde_json::from_str(EVENT).unwrap();
+        let odds = extract_odds(&event, "draftkings").unwrap();
+        assert_eq!(odds.provider, "DraftKings");
+        assert_eq!(odds.home_moneyline, Some(-150));
+        assert_eq!(odds.away_moneyline, Some(125));
+        assert_eq!(odds.home_spread, Some(-3.0));
+        assert_eq!(odds.total_points, Some(47.5));
+    }
+
+    #[test]
+    fn test_extract_odds_falls_back_to_first_bookmaker() {
+        let event: OddsEvent = serde_json::from_str(EVENT).unwrap();
+        let odds = extract_odds(&event, "betmgm").unwrap();
+        assert_eq!(odds.provider, "FanDuel");
+        assert_eq!(odds.home_moneyline, Some(-145));
+        assert_eq!(odds.home_spread, None);
+    }
+
+    #[test]
+    fn test_extract_odds_without_bookmakers() {
+        let event: OddsEvent = serde_json::from_str(
+            r#"{"home_team": "A", "away_team": "B", "commence_time": "2026-10-18T17:00:00Z"}"#,
+        ).unwrap();
+        assert!(extract_odds(&event, "draftkings").is_none());
+    }
+
+    #[test]
+    fn test_odds_sport_key() {
+        assert_eq!(odds_sport_key("NFL"), Some("americanfootball_nfl"));
+        assert_eq!(odds_sport_key("Premier League"), Some("soccer_epl"));
+        assert_eq!(odds_sport_key("Formula 1"), None);
+    }
+}
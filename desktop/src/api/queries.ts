@@ -231,6 +231,8 @@ export function rssCatalogOptions(opts?: { includeFailing?: boolean }) {
 interface YahooStatusResponse {
   connected: boolean;
   synced: boolean;
+  /** An older Yahoo link may belong to this user; reconnecting moves it over. */
+  relink_required?: boolean;
 }
 
 // MyLeaguesResponse imported from canonical source to avoid duplicate types.
@@ -71,6 +71,16 @@ export default function FantasyConfigPanel({
     [statusData, yahooConnected],
   );
 
+  // Legacy Yahoo links need the owner to reconnect before they show up.
+  const relinkRequired = statusData?.relink_required ?? false;
+  useEffect(() => {
+    if (relinkRequired) {
+      toast.warning("Reconnect Yahoo to restore your fantasy leagues", {
+        id: "yahoo-relink",
+      });
+    }
+  }, [relinkRequired]);
+
   // Sync phase from query data on initial load
   useEffect(() => {
     if (statusData && !phaseInitRef.current) {
@@ -13,6 +13,7 @@ import type { SubscriptionTier } from "./auth";
 
 describe("getLimit", () => {
   it("returns the correct numeric limit for free tier", () => {
+    expect(getLimit("free", "channels")).toBe(3);
     expect(getLimit("free", "symbols")).toBe(5);
     expect(getLimit("free", "feeds")).toBe(1);
     expect(getLimit("free", "customFeeds")).toBe(0);
@@ -22,6 +23,7 @@ describe("getLimit", () => {
   });
 
   it("returns the correct numeric limit for uplink tier", () => {
+    expect(getLimit("uplink", "channels")).toBe(Infinity);
     expect(getLimit("uplink", "symbols")).toBe(25);
     expect(getLimit("uplink", "feeds")).toBe(25);
     expect(getLimit("uplink", "customFeeds")).toBe(1);
@@ -154,7 +156,7 @@ describe("TIER_LIMITS table", () => {
   });
 
   it("super_user matches or exceeds every other tier on every numeric key", () => {
-    const keys = ["symbols", "feeds", "customFeeds", "leagues", "fantasy", "maxTickerRows"] as const;
+    const keys = ["channels", "symbols", "feeds", "customFeeds", "leagues", "fantasy", "maxTickerRows"] as const;
     const tiers: SubscriptionTier[] = ["free", "uplink", "uplink_pro", "uplink_ultimate"];
     for (const key of keys) {
       const superVal = TIER_LIMITS.super_user[key];
@@ -21,6 +21,8 @@ import type { SubscriptionTier } from "./auth";
 // =====================================================================
 
 interface ChannelLimits {
+  /** Max enabled channels of any type. */
+  channels: number;
   symbols: number;
   feeds: number;
   customFeeds: number;
@@ -34,6 +36,7 @@ interface ChannelLimits {
 
 export const TIER_LIMITS: Record<SubscriptionTier, ChannelLimits> = {
   free: {
+    channels: 3,
     symbols: 5,
     feeds: 1,
     customFeeds: 0,
@@ -43,6 +46,7 @@ export const TIER_LIMITS: Record<SubscriptionTier, ChannelLimits> = {
     maxTickerCustomization: false,
   },
   uplink: {
+    channels: Infinity,
     symbols: 25,
     feeds: 25,
     customFeeds: 1,
@@ -52,6 +56,7 @@ export const TIER_LIMITS: Record<SubscriptionTier, ChannelLimits> = {
     maxTickerCustomization: false,
   },
   uplink_pro: {
+    channels: Infinity,
     symbols: 75,
     feeds: 100,
     customFeeds: 3,
@@ -61,6 +66,7 @@ export const TIER_LIMITS: Record<SubscriptionTier, ChannelLimits> = {
     maxTickerCustomization: false,
   },
   uplink_ultimate: {
+    channels: Infinity,
     symbols: Infinity,
     feeds: Infinity,
     customFeeds: 10,
@@ -70,6 +76,7 @@ export const TIER_LIMITS: Record<SubscriptionTier, ChannelLimits> = {
     maxTickerCustomization: true,
   },
   super_user: {
+    channels: Infinity,
     symbols: Infinity,
     feeds: Infinity,
     customFeeds: Infinity,
@@ -86,6 +86,8 @@ export interface DashboardResponse {
     updated_at: string;
   };
   channels?: Array<Channel & { logto_sub: string }>;
+  /** Channels missing from `data` because their fetch failed, with the reason. */
+  errors?: Record<string, string>;
 }
 
 // ── Enums ────────────────────────────────────────────────────────
@@ -92,6 +92,7 @@ export type TierKey =
   | 'super_user'
 
 export interface ChannelLimits {
+  channels: number | null
   symbols: number | null
   feeds: number | null
   custom_feeds: number | null
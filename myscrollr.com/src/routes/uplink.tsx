@@ -596,6 +596,7 @@ function tierFromPlan(plan: string): TierKey | null {
 const FALLBACK_LIMITS: TierLimitsResponse = {
   tiers: {
     free: {
+      channels: 3,
       symbols: 5,
       feeds: 1,
       custom_feeds: 0,
@@ -605,6 +606,7 @@ const FALLBACK_LIMITS: TierLimitsResponse = {
       max_ticker_customization: false,
     },
     uplink: {
+      channels: null,
       symbols: 25,
       feeds: 25,
       custom_feeds: 1,
@@ -614,6 +616,7 @@ const FALLBACK_LIMITS: TierLimitsResponse = {
       max_ticker_customization: false,
     },
     uplink_pro: {
+      channels: null,
       symbols: 75,
       feeds: 100,
       custom_feeds: 3,
@@ -623,6 +626,7 @@ const FALLBACK_LIMITS: TierLimitsResponse = {
       max_ticker_customization: false,
     },
     uplink_ultimate: {
+      channels: null,
       symbols: null,
       feeds: null,
       custom_feeds: 10,
@@ -632,6 +636,7 @@ const FALLBACK_LIMITS: TierLimitsResponse = {
       max_ticker_customization: true,
     },
     super_user: {
+      channels: null,
       symbols: null,
       feeds: null,
       custom_feeds: null,
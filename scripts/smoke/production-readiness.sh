@@ -82,6 +82,8 @@ SERVICES=(
     "finance-api     18081  8081  /internal/health"
     "rss-api         18083  8083  /internal/health"
     "fantasy-api     18084  8084  /internal/health"
+    "custom-api      18085  8085  /internal/health"
+    "discord-api     18086  8086  /internal/health"
 )
 
 # ─── Helpers ──────────────────────────────────────────────────────────────
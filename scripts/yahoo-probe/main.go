@@ -209,9 +209,13 @@ func fetchXML(accessToken, path string) ([]byte, int, error) {
 	return body, resp.StatusCode, err
 }
 
-// decryptToken mirrors the Decrypt helper in channels/fantasy/api/helpers.go.
-// Wire format: base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
+// decryptToken mirrors the Decrypt helper in channels/fantasy/api/crypto.go.
+// Wire format: [key-id ":"] base64( 12-byte-nonce || ciphertext || 16-byte-GCM-tag )
+// The key-ID prefix is dropped; ENCRYPTION_KEY must be the key it names.
 func decryptToken(encrypted, base64Key string) (string, error) {
+	if _, body, ok := strings.Cut(encrypted, ":"); ok {
+		encrypted = body
+	}
 	key, err := base64.StdEncoding.DecodeString(base64Key)
 	if err != nil || len(key) != 32 {
 		return "", fmt.Errorf("invalid ENCRYPTION_KEY (expected base64-encoded 32 bytes)")